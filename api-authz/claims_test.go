@@ -0,0 +1,42 @@
+package client
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyToken(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Now()
+	token := SignToken(Claims{Subject: "ops1", Scope: "admin sms.text.read", ExpiresAt: now.Add(time.Minute).Unix()}, secret)
+
+	claims, err := VerifyToken(token, secret, now)
+	require.NoError(t, err)
+	assert.Equal(t, "ops1", claims.Subject)
+	assert.True(t, claims.HasScope("sms.text.read"))
+	assert.False(t, claims.HasScope("sms.text"))
+
+	_, err = VerifyToken(token, []byte("other"), now)
+	assert.ErrorIs(t, err, errTokenSignature)
+	_, err = VerifyToken(token, secret, now.Add(time.Minute))
+	assert.ErrorIs(t, err, errTokenExpired)
+	_, err = VerifyToken("not-a-token", secret, now)
+	assert.ErrorIs(t, err, errMalformedToken)
+
+	// A token claiming no algorithm is never accepted unsigned
+	parts := strings.Split(token, ".")
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + "."
+	_, err = VerifyToken(unsigned, secret, now)
+	assert.ErrorIs(t, err, errTokenSignature)
+}
+
+func TestClaimsHasScope(t *testing.T) {
+	assert.True(t, (&Claims{Scope: "reports, admin"}).HasScope("admin"))
+	assert.False(t, (&Claims{Scope: "admin"}).HasScope(""))
+	assert.False(t, (*Claims)(nil).HasScope("admin"))
+}
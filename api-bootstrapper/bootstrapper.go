@@ -55,6 +55,7 @@ func New() *Bootstrapper {
 	return &Bootstrapper{
 		context: context.Background(),
 		options: []fx.Option{
+			fxShutdown,
 			fxconfig,
 			fxlog,
 			fxDB,
@@ -209,6 +210,7 @@ func dbreadconfig(c *config.Config) db.DBConfig {
 		HealthCheckPeriod: time.Duration(c.GetInt("db.read.healthcheckperiod")),
 		Trace:             trace,
 		AppName:           c.AppName(),
+		ReadMaxRetries:    c.GetInt("db.read.maxretries"),
 	}
 
 	// return fx.Annotated{
@@ -261,9 +263,11 @@ var FxReadDB = fx.Module(
 
 type readDBLifecycleParams struct {
 	fx.In
-	Ctx context.Context // Signal-aware context from bootstrapper
-	DB  *db.DB          `name:"read_db"`
-	LC  fx.Lifecycle
+	Ctx      context.Context // Signal-aware context from bootstrapper
+	DB       *db.DB          `name:"read_db"`
+	Config   *config.Config
+	LC       fx.Lifecycle
+	Shutdown *ShutdownSequence
 }
 
 func readdblifecycle(p readDBLifecycleParams) {
@@ -281,69 +285,72 @@ func readdblifecycle(p readDBLifecycleParams) {
 				log.GetBaseLoggerInstance().ToZerolog().Info().Msg("Successfully connected to read database")
 				return nil
 			},
-			OnStop: func(ctx context.Context) error {
-				logger := log.GetBaseLoggerInstance().ToZerolog()
+		},
+	)
+
+	// Drained once the router has stopped, so that in-flight requests finish their queries
+	drainTimeout := dbDrainTimeout(p.Config)
+	p.Shutdown.Add(ShutdownPhaseDB, "read-db", func(ctx context.Context) error {
+		logger := log.GetBaseLoggerInstance().ToZerolog()
+
+		// Log connection stats before shutdown
+		if count := p.DB.Stat(); count != nil {
+			logger.Info().
+				Int32("total_conns", count.TotalConns()).
+				Int32("idle_conns", count.IdleConns()).
+				Int32("acquired_conns", count.AcquiredConns()).
+				Dur("drain_timeout", drainTimeout).
+				Msg("Read database connection stats at shutdown start")
+		}
+
+		// Wait for active connections to drain with timeout
+		// This allows in-flight HTTP requests to complete their DB operations
+		drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+		defer cancel()
+
+		logger.Info().
+			Dur("drain_timeout", drainTimeout).
+			Msg("Waiting for read database connections to drain...")
+
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
 
-				// Log connection stats before shutdown
+		for {
+			select {
+			case <-drainCtx.Done():
+				// Timeout reached, force close
 				if count := p.DB.Stat(); count != nil {
-					logger.Info().
-						Int32("total_conns", count.TotalConns()).
-						Int32("idle_conns", count.IdleConns()).
-						Int32("acquired_conns", count.AcquiredConns()).
-						Msg("Read database connection stats at shutdown start")
+					logger.Warn().
+						Int32("remaining_acquired", count.AcquiredConns()).
+						Msg("Read DB drain timeout reached, forcing database closure")
 				}
+				goto closeDB
 
-				// Wait for active connections to drain with timeout
-				// This allows in-flight HTTP requests to complete their DB operations
-				drainTimeout := 5 * time.Second
-				drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
-				defer cancel()
-
-				logger.Info().
-					Dur("drain_timeout", drainTimeout).
-					Msg("Waiting for read database connections to drain...")
-
-				ticker := time.NewTicker(100 * time.Millisecond)
-				defer ticker.Stop()
-
-				for {
-					select {
-					case <-drainCtx.Done():
-						// Timeout reached, force close
-						if count := p.DB.Stat(); count != nil {
-							logger.Warn().
-								Int32("remaining_acquired", count.AcquiredConns()).
-								Msg("Read DB drain timeout reached, forcing database closure")
-						}
+			case <-ticker.C:
+				// Check if all connections are idle
+				if count := p.DB.Stat(); count != nil {
+					if count.AcquiredConns() == 0 {
+						logger.Info().Msg("All read database connections drained successfully")
 						goto closeDB
-
-					case <-ticker.C:
-						// Check if all connections are idle
-						if count := p.DB.Stat(); count != nil {
-							if count.AcquiredConns() == 0 {
-								logger.Info().Msg("All read database connections drained successfully")
-								goto closeDB
-							}
-						}
 					}
 				}
+			}
+		}
 
-			closeDB:
-				// Close the database connection pool
-				p.DB.Close()
+	closeDB:
+		// Close the database connection pool
+		p.DB.Close()
 
-				// Log final stats
-				if count := p.DB.Stat(); count != nil {
-					logger.Info().
-						Int32("final_total_conns", count.TotalConns()).
-						Msg("Read database connection pool closed")
-				}
+		// Log final stats
+		if count := p.DB.Stat(); count != nil {
+			logger.Info().
+				Int32("final_total_conns", count.TotalConns()).
+				Msg("Read database connection pool closed")
+		}
 
-				logger.Info().Msg("Read database shutdown complete")
-				return nil
-			},
-		},
-	)
+		logger.Info().Msg("Read database shutdown complete")
+		return nil
+	})
 }
 
 func dbconfig(c *config.Config) db.DBConfig {
@@ -376,6 +383,7 @@ func dbconfig(c *config.Config) db.DBConfig {
 		SSLMode:           sslmode,
 		Trace:             trace,
 		AppName:           c.AppName(),
+		ReadMaxRetries:    c.GetInt("db.read.maxretries"),
 	}
 
 	// return fx.Annotated{
@@ -443,9 +451,23 @@ type readDBProbeParams struct {
 
 type writeDBLifecycleParams struct {
 	fx.In
-	Ctx context.Context // Signal-aware context from bootstrapper
-	DB  *db.DB          `name:"write_db"`
-	LC  fx.Lifecycle
+	Ctx      context.Context // Signal-aware context from bootstrapper
+	DB       *db.DB          `name:"write_db"`
+	Config   *config.Config
+	LC       fx.Lifecycle
+	Shutdown *ShutdownSequence
+}
+
+// defaultDBDrainTimeout is the wait for the in-flight queries when db.drainTimeout is not set
+const defaultDBDrainTimeout = 5 * time.Second
+
+// dbDrainTimeout returns how long the shutdown of a database pool waits for its connections to
+// be released before closing it, db.drainTimeout, applied to the read and write pools
+func dbDrainTimeout(c *config.Config) time.Duration {
+	if c.Exists("db.drainTimeout") {
+		return c.GetDuration("db.drainTimeout")
+	}
+	return defaultDBDrainTimeout
 }
 
 func dblifecycle(p writeDBLifecycleParams) {
@@ -463,69 +485,72 @@ func dblifecycle(p writeDBLifecycleParams) {
 				log.GetBaseLoggerInstance().ToZerolog().Info().Msg("Successfully connected to the database")
 				return nil
 			},
-			OnStop: func(ctx context.Context) error {
-				logger := log.GetBaseLoggerInstance().ToZerolog()
+		},
+	)
+
+	// Drained once the router has stopped, so that in-flight requests finish their queries
+	drainTimeout := dbDrainTimeout(p.Config)
+	p.Shutdown.Add(ShutdownPhaseDB, "write-db", func(ctx context.Context) error {
+		logger := log.GetBaseLoggerInstance().ToZerolog()
+
+		// Log connection stats before shutdown
+		if count := p.DB.Stat(); count != nil {
+			logger.Info().
+				Int32("total_conns", count.TotalConns()).
+				Int32("idle_conns", count.IdleConns()).
+				Int32("acquired_conns", count.AcquiredConns()).
+				Dur("drain_timeout", drainTimeout).
+				Msg("Database connection stats at shutdown start")
+		}
+
+		// Wait for active connections to drain with timeout
+		// This allows in-flight HTTP requests to complete their DB operations
+		drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+		defer cancel()
+
+		logger.Info().
+			Dur("drain_timeout", drainTimeout).
+			Msg("Waiting for active database connections to drain...")
 
-				// Log connection stats before shutdown
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-drainCtx.Done():
+				// Timeout reached, force close
 				if count := p.DB.Stat(); count != nil {
-					logger.Info().
-						Int32("total_conns", count.TotalConns()).
-						Int32("idle_conns", count.IdleConns()).
-						Int32("acquired_conns", count.AcquiredConns()).
-						Msg("Database connection stats at shutdown start")
+					logger.Warn().
+						Int32("remaining_acquired", count.AcquiredConns()).
+						Msg("Drain timeout reached, forcing database closure")
 				}
+				goto closeDB
 
-				// Wait for active connections to drain with timeout
-				// This allows in-flight HTTP requests to complete their DB operations
-				drainTimeout := 5 * time.Second
-				drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
-				defer cancel()
-
-				logger.Info().
-					Dur("drain_timeout", drainTimeout).
-					Msg("Waiting for active database connections to drain...")
-
-				ticker := time.NewTicker(100 * time.Millisecond)
-				defer ticker.Stop()
-
-				for {
-					select {
-					case <-drainCtx.Done():
-						// Timeout reached, force close
-						if count := p.DB.Stat(); count != nil {
-							logger.Warn().
-								Int32("remaining_acquired", count.AcquiredConns()).
-								Msg("Drain timeout reached, forcing database closure")
-						}
+			case <-ticker.C:
+				// Check if all connections are idle
+				if count := p.DB.Stat(); count != nil {
+					if count.AcquiredConns() == 0 {
+						logger.Info().Msg("All database connections drained successfully")
 						goto closeDB
-
-					case <-ticker.C:
-						// Check if all connections are idle
-						if count := p.DB.Stat(); count != nil {
-							if count.AcquiredConns() == 0 {
-								logger.Info().Msg("All database connections drained successfully")
-								goto closeDB
-							}
-						}
 					}
 				}
+			}
+		}
 
-			closeDB:
-				// Close the database connection pool
-				p.DB.Close()
+	closeDB:
+		// Close the database connection pool
+		p.DB.Close()
 
-				// Log final stats
-				if count := p.DB.Stat(); count != nil {
-					logger.Info().
-						Int32("final_total_conns", count.TotalConns()).
-						Msg("Database connection pool closed")
-				}
+		// Log final stats
+		if count := p.DB.Stat(); count != nil {
+			logger.Info().
+				Int32("final_total_conns", count.TotalConns()).
+				Msg("Database connection pool closed")
+		}
 
-				logger.Info().Msg("Database shutdown complete")
-				return nil
-			},
-		},
-	)
+		logger.Info().Msg("Database shutdown complete")
+		return nil
+	})
 }
 
 var Fxclient = fx.Module(
@@ -563,15 +588,13 @@ var fxrouter = fx.Module(
 	fx.Invoke(startServer),
 )
 
-func startServer(lc fx.Lifecycle, sv *router.Router) {
+func startServer(shutdown *ShutdownSequence, sv *router.Router) {
 	eg, _ := errgroup.WithContext(context.Background())
 
-	lc.Append(fx.Hook{
-		OnStop: func(ctx context.Context) error {
-			ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-			defer cancel()
-			return sv.Shutdown(ctx)
-		},
+	shutdown.Add(ShutdownPhaseRouter, "router", func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		return sv.Shutdown(ctx)
 	})
 
 	eg.Go(func() error {
@@ -618,6 +641,17 @@ func newRouterAdapter(p routerAdapterParams) (routeradapter.RouterAdapter, error
 	}
 	cfg.Type = routerType
 
+	// Route normalization (trailing slash and version prefix case)
+	if p.Config.Exists("router.normalization") {
+		cfg.Normalization = &routeradapter.NormalizationConfig{
+			TrailingSlash:          p.Config.GetBool("router.normalization.trailingslash"),
+			CaseInsensitiveVersion: p.Config.GetBool("router.normalization.caseinsensitiveversion"),
+		}
+		if routeradapter.NewRouteNormalizer(cfg.Normalization).Enabled() && !cfg.NormalizationSupported() {
+			log.Warn(nil, "router.normalization is ignored by the %s router, only the gin and nethttp routers apply it", cfg.Type)
+		}
+	}
+
 	// Set server configuration
 	if p.Config.Exists("server.addr") {
 		cfg.Port = p.Config.GetInt("server.port")
@@ -640,9 +674,10 @@ func newRouterAdapter(p routerAdapterParams) (routeradapter.RouterAdapter, error
 // routerAdapterLifecycleParams holds dependencies for router adapter lifecycle
 type routerAdapterLifecycleParams struct {
 	fx.In
-	LC      fx.Lifecycle
-	Adapter routeradapter.RouterAdapter
-	Config  *config.Config
+	LC       fx.Lifecycle
+	Adapter  routeradapter.RouterAdapter
+	Config   *config.Config
+	Shutdown *ShutdownSequence
 }
 
 // startRouterAdapter manages the router adapter lifecycle
@@ -672,19 +707,21 @@ func startRouterAdapter(p routerAdapterLifecycleParams) {
 
 			return nil
 		},
-		OnStop: func(ctx context.Context) error {
-			shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-			defer cancel()
+	})
 
-			if err := p.Adapter.Shutdown(shutdownCtx); err != nil {
-				return err
-			}
+	// Stopped before the database pools are drained
+	p.Shutdown.Add(ShutdownPhaseRouter, "router-adapter", func(ctx context.Context) error {
+		shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
 
-			log.GetBaseLoggerInstance().ToZerolog().Info().
-				Msg("Router adapter shutdown complete")
+		if err := p.Adapter.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
 
-			return nil
-		},
+		log.GetBaseLoggerInstance().ToZerolog().Info().
+			Msg("Router adapter shutdown complete")
+
+		return nil
 	})
 }
 
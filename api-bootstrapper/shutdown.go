@@ -0,0 +1,94 @@
+package bootstrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	log "MgApplication/api-log"
+
+	"go.uber.org/fx"
+)
+
+// ShutdownPhase orders the steps of a ShutdownSequence
+type ShutdownPhase int
+
+// Shutdown phases, run in this order when the app stops
+const (
+	// ShutdownPhaseRouter stops accepting requests and waits for the in-flight ones
+	ShutdownPhaseRouter ShutdownPhase = iota
+	// ShutdownPhaseDB drains and closes the database pools
+	ShutdownPhaseDB
+
+	shutdownPhases = iota
+)
+
+var shutdownPhaseNames = [shutdownPhases]string{"router", "db"}
+
+func (p ShutdownPhase) String() string {
+	if p >= 0 && p < shutdownPhases {
+		return shutdownPhaseNames[p]
+	}
+	return fmt.Sprintf("ShutdownPhase(%d)", int(p))
+}
+
+type shutdownStep struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// ShutdownSequence runs the stop steps of the modules in phases, so that the router stops
+// accepting requests before the database pools are drained. fx runs OnStop hooks in reverse
+// order of registration, which depends on the order of the modules and of their dependencies;
+// the phases do not. The sequence runs from a single OnStop hook registered when it is created.
+type ShutdownSequence struct {
+	mu     sync.Mutex
+	phases [shutdownPhases][]shutdownStep
+}
+
+// NewShutdownSequence creates a new ShutdownSequence instance run when lc stops
+func NewShutdownSequence(lc fx.Lifecycle) *ShutdownSequence {
+	s := &ShutdownSequence{}
+	lc.Append(fx.Hook{OnStop: s.Stop})
+	return s
+}
+
+// Add registers stop to run in phase. The steps of a phase run in reverse order of registration,
+// like fx hooks.
+func (s *ShutdownSequence) Add(phase ShutdownPhase, name string, stop func(ctx context.Context) error) {
+	if phase < 0 || phase >= shutdownPhases {
+		panic(fmt.Sprintf("unknown shutdown phase %d of %s", int(phase), name))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.phases[phase] = append(s.phases[phase], shutdownStep{name: name, stop: stop})
+}
+
+// Stop runs the phases in order. A failed step does not keep the later steps from running, the
+// errors are returned joined.
+func (s *ShutdownSequence) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	phases := s.phases
+	s.mu.Unlock()
+
+	var errs []error
+	for phase, steps := range phases {
+		for i := len(steps) - 1; i >= 0; i-- {
+			step := steps[i]
+			log.GetBaseLoggerInstance().ToZerolog().Info().
+				Stringer("phase", ShutdownPhase(phase)).
+				Str("step", step.name).
+				Msg("Shutting down")
+			if err := step.stop(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("shutdown %s: %w", step.name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var fxShutdown = fx.Module(
+	"shutdown",
+	fx.Provide(NewShutdownSequence),
+)
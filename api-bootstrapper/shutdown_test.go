@@ -0,0 +1,92 @@
+package bootstrapper
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+)
+
+// TestShutdownSequenceOrder verifies that the router phase completes before the DB phase starts,
+// whichever order the modules are registered in
+func TestShutdownSequenceOrder(t *testing.T) {
+	for _, dbFirst := range []bool{true, false} {
+		var (
+			mu    sync.Mutex
+			steps []string
+		)
+		record := func(step string) func(ctx context.Context) error {
+			return func(ctx context.Context) error {
+				mu.Lock()
+				defer mu.Unlock()
+				steps = append(steps, step)
+				return nil
+			}
+		}
+
+		db := fx.Module("test-db", fx.Invoke(func(s *ShutdownSequence) {
+			s.Add(ShutdownPhaseDB, "db", record("db"))
+		}))
+		router := fx.Module("test-router", fx.Invoke(func(s *ShutdownSequence) {
+			s.Add(ShutdownPhaseRouter, "router", record("router"))
+		}))
+		modules := []fx.Option{db, router}
+		if !dbFirst {
+			modules = []fx.Option{router, db}
+		}
+
+		app := fxtest.New(t, fx.NopLogger, fxShutdown, fx.Options(modules...))
+		app.RequireStart()
+		app.RequireStop()
+
+		if got := strings.Join(steps, ","); got != "router,db" {
+			t.Errorf("db module first %v: shutdown order %s, want router,db", dbFirst, got)
+		}
+	}
+}
+
+// TestShutdownSequenceFailure verifies that a failed router step does not keep the database
+// pools from being drained and that its error is returned
+func TestShutdownSequenceFailure(t *testing.T) {
+	errStop := errors.New("router stop failed")
+	dbStopped := false
+
+	s := &ShutdownSequence{}
+	s.Add(ShutdownPhaseDB, "db", func(ctx context.Context) error {
+		dbStopped = true
+		return nil
+	})
+	s.Add(ShutdownPhaseRouter, "router", func(ctx context.Context) error {
+		return errStop
+	})
+
+	err := s.Stop(context.Background())
+	if !errors.Is(err, errStop) {
+		t.Errorf("Stop() error = %v, want %v", err, errStop)
+	}
+	if !dbStopped {
+		t.Error("DB phase did not run after the router phase failed")
+	}
+}
+
+// TestDBDrainTimeout verifies that the drain timeout of the database pools is read from
+// db.drainTimeout, 5s when it is not set
+func TestDBDrainTimeout(t *testing.T) {
+	c := config.NewConfig(viper.New())
+	if got := dbDrainTimeout(c); got != 5*time.Second {
+		t.Errorf("dbDrainTimeout() without db.drainTimeout = %v, want 5s", got)
+	}
+
+	c.Set("db.drainTimeout", "30s")
+	if got := dbDrainTimeout(c); got != 30*time.Second {
+		t.Errorf("dbDrainTimeout() = %v, want 30s", got)
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -29,6 +30,19 @@ func NewConfig(v *viper.Viper) *Config {
 func (c *Config) Exists(key string) bool {
 	return c.IsSet(key)
 }
+
+// MissingKeys returns the keys that are not set or whose value is empty. Keys must hold
+// scalar values, maps and lists always count as missing.
+func (c *Config) MissingKeys(keys ...string) []string {
+	var missing []string
+	for _, key := range keys {
+		if !c.IsSet(key) || strings.TrimSpace(c.GetString(key)) == "" {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
 func (c *Config) Of(section string) (*Config, error) {
 	subViper := c.Sub(section)
 	if subViper == nil {
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingKeys(t *testing.T) {
+	c := NewConfig(viper.New())
+	c.Set("sms.cdac.url", "https://cdac.example")
+	c.Set("sms.cdac.username", "  ")
+	c.Set("sms.dltEntityID", 1001081725895192800)
+	c.Set("stats.costpersms", map[string]any{"1": 0})
+
+	missing := c.MissingKeys("sms.cdac.url", "sms.cdac.username", "sms.cdac.password", "sms.dltEntityID", "stats.costpersms")
+
+	assert.Equal(t, []string{"sms.cdac.username", "sms.cdac.password", "stats.costpersms"}, missing)
+	assert.Empty(t, c.MissingKeys("sms.cdac.url"))
+}
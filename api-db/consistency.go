@@ -0,0 +1,158 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	l "MgApplication/api-log"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LSN is a PostgreSQL write-ahead log location, written as two hexadecimal halves, 16/B374D848
+type LSN uint64
+
+// ParseLSN parses the text form of an LSN returned by pg_current_wal_lsn()
+func ParseLSN(s string) (LSN, error) {
+	hi, lo, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, fmt.Errorf("invalid LSN %q", s)
+	}
+	h, err := strconv.ParseUint(hi, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN %q", s)
+	}
+	o, err := strconv.ParseUint(lo, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN %q", s)
+	}
+	return LSN(h<<32 | o), nil
+}
+
+func (lsn LSN) String() string {
+	return fmt.Sprintf("%X/%X", uint64(lsn)>>32, uint32(lsn))
+}
+
+// CurrentLSN returns the current write-ahead log location of the primary db, which every
+// transaction committed so far precedes
+func CurrentLSN(ctx context.Context, db *DB) (LSN, error) {
+	var s string
+	if err := db.QueryRow(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&s); err != nil {
+		return 0, err
+	}
+	return ParseLSN(s)
+}
+
+// replayLSN returns the last write-ahead log location replayed by the replica db, false when
+// db is not in recovery and so sees every committed transaction
+func replayLSN(ctx context.Context, db *DB) (LSN, bool, error) {
+	var s *string
+	if err := db.QueryRow(ctx, "SELECT pg_last_wal_replay_lsn()::text").Scan(&s); err != nil {
+		return 0, false, err
+	}
+	if s == nil {
+		return 0, false, nil
+	}
+	lsn, err := ParseLSN(*s)
+	return lsn, true, err
+}
+
+type consistencyTokenKey struct{}
+
+// WithConsistencyToken returns a copy of ctx whose reads routed by a ReadRouter see the writes
+// up to lsn
+func WithConsistencyToken(ctx context.Context, lsn LSN) context.Context {
+	return context.WithValue(ctx, consistencyTokenKey{}, lsn)
+}
+
+// ConsistencyToken returns the LSN the reads of ctx must see, false when they may be stale
+func ConsistencyToken(ctx context.Context) (LSN, bool) {
+	lsn, ok := ctx.Value(consistencyTokenKey{}).(LSN)
+	return lsn, ok
+}
+
+// ConsistentReadsTotal counts the reads sent with a consistency token by where they were run
+var ConsistentReadsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "db_consistent_reads_total",
+		Help: "Total number of reads with a consistency token, by outcome: run on the replica at once, after waiting for it, or on the primary",
+	},
+	[]string{"outcome"},
+)
+
+// Outcomes of a read with a consistency token, the outcome label of ConsistentReadsTotal
+const (
+	consistentReadReplica         = "replica"
+	consistentReadReplicaWaited   = "replica_waited"
+	consistentReadPrimaryFallback = "primary_fallback"
+)
+
+// ReadRouter picks the db of a read query. Reads go to the replica, except the reads with a
+// consistency token (WithConsistencyToken) the replica has not replayed yet: they wait up to
+// MaxWait for the replica to catch up, then fall back to the primary.
+type ReadRouter struct {
+	Primary *DB
+	// Replica is the read replica, nil to run every read on the primary
+	Replica      *DB
+	MaxWait      time.Duration
+	PollInterval time.Duration
+	replayLSN    func(ctx context.Context) (LSN, bool, error)
+}
+
+// NewReadRouter creates the ReadRouter of primary and replica, replica nil until the read/write
+// split is deployed
+func NewReadRouter(primary, replica *DB, maxWait, pollInterval time.Duration) *ReadRouter {
+	r := &ReadRouter{Primary: primary, Replica: replica, MaxWait: maxWait, PollInterval: pollInterval}
+	if replica != nil {
+		r.replayLSN = func(ctx context.Context) (LSN, bool, error) {
+			return replayLSN(ctx, replica)
+		}
+	}
+	return r
+}
+
+// DB returns the db the read of ctx runs on
+func (r *ReadRouter) DB(ctx context.Context) *DB {
+	if r.Replica == nil {
+		return r.Primary
+	}
+	token, ok := ConsistencyToken(ctx)
+	if !ok {
+		return r.Replica
+	}
+
+	deadline := time.Now().Add(r.MaxWait)
+wait:
+	for waited := false; ; waited = true {
+		replayed, inRecovery, err := r.replayLSN(ctx)
+		if err != nil {
+			l.Warn(ctx, "Reading the replay LSN of the replica failed, reading from the primary: %s", err.Error())
+			break wait
+		}
+		if !inRecovery || replayed >= token {
+			if waited {
+				ConsistentReadsTotal.WithLabelValues(consistentReadReplicaWaited).Inc()
+			} else {
+				ConsistentReadsTotal.WithLabelValues(consistentReadReplica).Inc()
+			}
+			return r.Replica
+		}
+		if !time.Now().Before(deadline) {
+			l.Debug(ctx, "Replica at %s behind the consistency token %s, reading from the primary", replayed, token)
+			break wait
+		}
+
+		timer := time.NewTimer(min(r.PollInterval, time.Until(deadline)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			break wait
+		case <-timer.C:
+		}
+	}
+	ConsistentReadsTotal.WithLabelValues(consistentReadPrimaryFallback).Inc()
+	return r.Primary
+}
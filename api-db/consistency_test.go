@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestParseLSN(t *testing.T) {
+	for _, s := range []string{"0/0", "16/B374D848", "FFFFFFFF/FFFFFFFF"} {
+		lsn, err := ParseLSN(s)
+		if err != nil {
+			t.Fatalf("ParseLSN(%q): %v", s, err)
+		}
+		if lsn.String() != s {
+			t.Errorf("ParseLSN(%q).String() = %q", s, lsn.String())
+		}
+	}
+	if lsn, _ := ParseLSN("16/B374D848"); lsn != 0x16B374D848 {
+		t.Errorf("ParseLSN(16/B374D848) = %#x", uint64(lsn))
+	}
+	if a, b := LSN(0x1FFFFFFFF), LSN(0x200000000); !(a < b) {
+		t.Errorf("%s should precede %s", a, b)
+	}
+
+	for _, s := range []string{"", "16", "16/", "/B374D848", "16/XYZ", "100000000/0"} {
+		if _, err := ParseLSN(s); err == nil {
+			t.Errorf("ParseLSN(%q) should fail", s)
+		}
+	}
+}
+
+// stubReplica returns a ReadRouter whose replica replays the LSNs of replayed in turn, the last
+// one staying, and the number of times the replay LSN was read
+func stubReplica(replayed ...LSN) (*ReadRouter, *int) {
+	r := NewReadRouter(&DB{}, &DB{}, 50*time.Millisecond, time.Millisecond)
+	calls := 0
+	r.replayLSN = func(context.Context) (LSN, bool, error) {
+		lsn := replayed[min(calls, len(replayed)-1)]
+		calls++
+		return lsn, true, nil
+	}
+	return r, &calls
+}
+
+func consistentReads(outcome string) float64 {
+	return testutil.ToFloat64(ConsistentReadsTotal.WithLabelValues(outcome))
+}
+
+func TestReadRouterWithoutToken(t *testing.T) {
+	r, calls := stubReplica(100)
+	if r.DB(context.Background()) != r.Replica {
+		t.Error("a read without a token should run on the replica")
+	}
+	if *calls != 0 {
+		t.Error("a read without a token should not read the replay LSN")
+	}
+
+	// before the read/write split every read runs on the primary
+	primary := NewReadRouter(&DB{}, nil, time.Second, time.Millisecond)
+	if primary.DB(WithConsistencyToken(context.Background(), 100)) != primary.Primary {
+		t.Error("without a replica reads should run on the primary")
+	}
+}
+
+func TestReadRouterReplicaCaughtUp(t *testing.T) {
+	before := consistentReads(consistentReadReplica)
+	r, calls := stubReplica(200)
+	if r.DB(WithConsistencyToken(context.Background(), 200)) != r.Replica {
+		t.Error("the replica has replayed the token, the read should run on it")
+	}
+	if *calls != 1 {
+		t.Errorf("replay LSN read %d times, want 1", *calls)
+	}
+	if got := consistentReads(consistentReadReplica) - before; got != 1 {
+		t.Errorf("replica reads counted %v, want 1", got)
+	}
+}
+
+func TestReadRouterWaitsForReplica(t *testing.T) {
+	before := consistentReads(consistentReadReplicaWaited)
+	r, calls := stubReplica(100, 150, 200)
+	if r.DB(WithConsistencyToken(context.Background(), 200)) != r.Replica {
+		t.Error("the replica catches up within the max wait, the read should run on it")
+	}
+	if *calls != 3 {
+		t.Errorf("replay LSN read %d times, want 3", *calls)
+	}
+	if got := consistentReads(consistentReadReplicaWaited) - before; got != 1 {
+		t.Errorf("waited replica reads counted %v, want 1", got)
+	}
+}
+
+func TestReadRouterFallsBackToPrimary(t *testing.T) {
+	before := consistentReads(consistentReadPrimaryFallback)
+	r, _ := stubReplica(100)
+	start := time.Now()
+	if r.DB(WithConsistencyToken(context.Background(), 200)) != r.Primary {
+		t.Error("the replica lags beyond the max wait, the read should run on the primary")
+	}
+	if elapsed := time.Since(start); elapsed < r.MaxWait || elapsed > 10*r.MaxWait {
+		t.Errorf("fell back after %s, want about %s", elapsed, r.MaxWait)
+	}
+
+	// nor does it wait when the replay LSN cannot be read
+	r.replayLSN = func(context.Context) (LSN, bool, error) {
+		return 0, false, errors.New("connection reset by peer")
+	}
+	if r.DB(WithConsistencyToken(context.Background(), 200)) != r.Primary {
+		t.Error("the replay LSN is unknown, the read should run on the primary")
+	}
+
+	// a replica promoted to primary sees every write
+	r.replayLSN = func(context.Context) (LSN, bool, error) {
+		return 0, false, nil
+	}
+	if r.DB(WithConsistencyToken(context.Background(), 200)) != r.Replica {
+		t.Error("the replica is not in recovery, the read should run on it")
+	}
+
+	if got := consistentReads(consistentReadPrimaryFallback) - before; got != 2 {
+		t.Errorf("primary fallbacks counted %v, want 2", got)
+	}
+}
+
+func TestReadRouterStopsWaitingWithTheRequest(t *testing.T) {
+	r, _ := stubReplica(100)
+	r.MaxWait = time.Minute
+	ctx, cancel := context.WithTimeout(WithConsistencyToken(context.Background(), 200), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if r.DB(ctx) != r.Primary {
+		t.Error("the request is done, the read should fall back to the primary")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waited %s after the request was done", elapsed)
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 /**
@@ -18,6 +19,10 @@ import (
 
 type DB struct {
 	*pgxpool.Pool
+	// ReadMaxRetries is the number of times a read query opted in with WithReadRetry
+	// is retried after a transient connection error
+	ReadMaxRetries int
+	readRetries    prometheus.Counter
 }
 
 type DBInterface interface {
@@ -59,6 +59,7 @@ func (f *DefaultDbFactory) NewPreparedDBConfig(input DBConfig) *DBConfig {
 		AppName:           input.AppName,
 		SSLMode:           input.SSLMode,
 		Trace:             input.Trace,
+		ReadMaxRetries:    input.ReadMaxRetries,
 	}
 
 	// Set defaults and validate the configuration
@@ -72,14 +73,14 @@ func (f *DefaultDbFactory) CreateConnection(dbConfig *DBConfig, osdktrace *otels
 	// Prepare the pgxpool.Config
 	pgxConfig, err := Pgxconfig(dbConfig, osdktrace)
 	if err != nil {
-		appError := apierrors.NewAppError("pgxConfig Error", "500", err)
+		appError := apierrors.NewAppError("pgxConfig Error", 500, err)
 		return nil, &appError
 	}
 
 	// Create and return the DB connection
 	conn, err := NewDB(dbConfig, pgxConfig, Registry, f.CollectorName)
 	if err != nil {
-		appError := apierrors.NewAppError("Error occurred while creating db connection", "500", err)
+		appError := apierrors.NewAppError("Error occurred while creating db connection", 500, err)
 		return nil, &appError
 	}
 
@@ -143,15 +144,19 @@ func NewDB(cfg *DBConfig, pcfg *pgxpool.Config, Registry *prometheus.Registry, c
 		return nil, err
 	}
 
-	collector := NewCollector(db, map[string]string{
+	labels := map[string]string{
 		"db_name":        cfg.DBDatabase,
 		"collector_name": collectorName,
-	})
-	Registry.MustRegister(collector)
+	}
+	collector := NewCollector(db, labels)
+	readRetries := newReadRetryCounter(labels)
+	Registry.MustRegister(collector, readRetries)
 	//	log.Info(nil, "collector in db:", collector)
 
 	return &DB{
-		db,
+		Pool:           db,
+		ReadMaxRetries: cfg.ReadMaxRetries,
+		readRetries:    readRetries,
 	}, nil
 }
 
@@ -180,4 +185,8 @@ func validateDBConfig(cfg *DBConfig) {
 		cfg.HealthCheckPeriod = 5 // Default 5 minutes
 	}
 
+	if cfg.ReadMaxRetries < 0 {
+		cfg.ReadMaxRetries = 0 // Read queries are not retried
+	}
+
 }
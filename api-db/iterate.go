@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// defaultIterateBatchSize is the page size of Iterate when none is given
+const defaultIterateBatchSize = 500
+
+// IterateError is the failure of Iterate: of a page query, of a row or of the callback. Cursor is
+// the key of the last row the callback handled, nil when it handled none, which IterateAfter
+// resumes the traversal from.
+type IterateError struct {
+	Cursor any
+	Err    error
+}
+
+func (e *IterateError) Error() string {
+	if e.Cursor == nil {
+		return "iteration failed before the first row: " + e.Err.Error()
+	}
+	return fmt.Sprintf("iteration failed after key %v: %s", e.Cursor, e.Err.Error())
+}
+
+func (e *IterateError) Unwrap() error {
+	return e.Err
+}
+
+// Iterate calls fn with every row of builder, scanned by name into T, paging through them by
+// keyset: each page of batchSize rows is the rows whose keyColumn is greater than the key of the
+// last row handled, in keyColumn order. Unlike skip and limit pages, a row inserted during the
+// traversal never shifts the pages, so every row existing when the traversal started is handled
+// exactly once; rows inserted meanwhile are handled when their key is beyond the cursor.
+//
+// keyColumn must be unique and not null, T having a field tagged with its name, and builder must
+// not be ordered nor limited. Each page is a query of its own, no transaction is held across the
+// traversal. It stops at the first error, of a page or returned by fn, or once ctx is done,
+// returning an *IterateError with the cursor to resume from.
+func Iterate[T any](ctx context.Context, db *DB, builder sq.SelectBuilder, keyColumn string, batchSize int, fn func(T) error) error {
+	return IterateAfter(ctx, db, builder, keyColumn, nil, batchSize, fn)
+}
+
+// IterateAfter is Iterate resumed after the key after, e.g. the Cursor of an IterateError, nil
+// to start from the first row
+func IterateAfter[T any](ctx context.Context, db *DB, builder sq.SelectBuilder, keyColumn string, after any, batchSize int, fn func(T) error) error {
+	page := func(ctx context.Context, after any, limit int) ([]T, error) {
+		return SelectRows(ctx, db, keysetQuery(builder, keyColumn, after, limit), pgx.RowToStructByNameLax[T])
+	}
+	return iteratePages(ctx, page, keyColumn, after, batchSize, fn)
+}
+
+// keysetQuery returns the query of the page of limit rows of builder after the key after
+func keysetQuery(builder sq.SelectBuilder, keyColumn string, after any, limit int) sq.SelectBuilder {
+	if after != nil {
+		builder = builder.Where(sq.Gt{keyColumn: after})
+	}
+	return builder.OrderBy(keyColumn).Limit(uint64(limit))
+}
+
+// iteratePages calls fn with the rows of the pages returned by page, the page after a key being
+// the one of the last row of the previous page, until a page is not full
+func iteratePages[T any](ctx context.Context, page func(ctx context.Context, after any, limit int) ([]T, error), keyColumn string, after any, batchSize int, fn func(T) error) error {
+	if batchSize <= 0 {
+		batchSize = defaultIterateBatchSize
+	}
+	// a qualified column, e.g. mr.request_id, is tagged by its name
+	column := keyColumn[strings.LastIndex(keyColumn, ".")+1:]
+
+	cursor := after
+	for {
+		if err := ctx.Err(); err != nil {
+			return &IterateError{Cursor: cursor, Err: err}
+		}
+		rows, err := page(ctx, cursor, batchSize)
+		if err != nil {
+			return &IterateError{Cursor: cursor, Err: err}
+		}
+		for _, row := range rows {
+			if err := ctx.Err(); err != nil {
+				return &IterateError{Cursor: cursor, Err: err}
+			}
+			key, err := structKey(row, column)
+			if err != nil {
+				return &IterateError{Cursor: cursor, Err: err}
+			}
+			if err := fn(row); err != nil {
+				return &IterateError{Cursor: cursor, Err: err}
+			}
+			cursor = key
+		}
+		if len(rows) < batchSize {
+			return nil
+		}
+	}
+}
+
+// structKey returns the value of the field of row tagged with column
+func structKey(row any, column string) (any, error) {
+	columns, values, err := structColumns(row)
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range columns {
+		if name != column {
+			continue
+		}
+		key := reflect.ValueOf(values[i])
+		if key.Kind() == reflect.Pointer {
+			if key.IsNil() {
+				return nil, fmt.Errorf("key %s of %T is null", column, row)
+			}
+			key = key.Elem()
+		}
+		return key.Interface(), nil
+	}
+	return nil, fmt.Errorf("%T has no field tagged %s", row, column)
+}
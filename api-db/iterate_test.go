@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type iterateTestRow struct {
+	RequestID uint64 `db:"request_id"`
+	Status    string `db:"status"`
+}
+
+// iterateTestTable is a table of rows keyed by request_id, paged like keysetQuery pages
+type iterateTestTable struct {
+	mu   sync.Mutex
+	rows map[uint64]iterateTestRow
+	// failAt fails the page query with that number, counting from 1
+	failAt int
+	pages  int
+}
+
+func newIterateTestTable(keys ...uint64) *iterateTestTable {
+	table := &iterateTestTable{rows: make(map[uint64]iterateTestRow)}
+	for _, key := range keys {
+		table.insert(key)
+	}
+	return table
+}
+
+func (tt *iterateTestTable) insert(key uint64) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	tt.rows[key] = iterateTestRow{RequestID: key, Status: "pending"}
+}
+
+func (tt *iterateTestTable) page(ctx context.Context, after any, limit int) ([]iterateTestRow, error) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	tt.pages++
+	if tt.pages == tt.failAt {
+		return nil, errors.New("connection reset by peer")
+	}
+	var page []iterateTestRow
+	for key, row := range tt.rows {
+		if after == nil || key > after.(uint64) {
+			page = append(page, row)
+		}
+	}
+	sort.Slice(page, func(i, j int) bool { return page[i].RequestID < page[j].RequestID })
+	if len(page) > limit {
+		page = page[:limit]
+	}
+	return page, nil
+}
+
+func TestIterateConcurrentInserts(t *testing.T) {
+	var snapshot []uint64
+	for key := uint64(1); key <= 100; key++ {
+		if key%7 != 0 {
+			snapshot = append(snapshot, key)
+		}
+	}
+	table := newIterateTestTable(snapshot...)
+
+	// rows are inserted while the traversal runs, in the gaps of the keys and beyond them
+	inserts := make(chan uint64)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for key := range inserts {
+			table.insert(key)
+		}
+	}()
+
+	seen := make(map[uint64]int)
+	var order []uint64
+	next := uint64(101)
+	err := iteratePages(context.Background(), table.page, "mr.request_id", nil, 8, func(row iterateTestRow) error {
+		seen[row.RequestID]++
+		order = append(order, row.RequestID)
+		if row.RequestID%10 == 0 {
+			// behind the cursor, filling a gap of the snapshot when 7 divides it
+			inserts <- row.RequestID - 3
+			inserts <- next
+			next++
+		}
+		return nil
+	})
+	close(inserts)
+	<-done
+	if err != nil {
+		t.Fatalf("iteratePages() error = %v", err)
+	}
+
+	for _, key := range snapshot {
+		if seen[key] != 1 {
+			t.Errorf("row %d of the snapshot handled %d times", key, seen[key])
+		}
+	}
+	for key, n := range seen {
+		if n != 1 {
+			t.Errorf("row %d handled %d times", key, n)
+		}
+	}
+	if !sort.SliceIsSorted(order, func(i, j int) bool { return order[i] < order[j] }) {
+		t.Errorf("rows not handled in key order: %v", order)
+	}
+}
+
+func TestIterateResumesAfterAFailedPage(t *testing.T) {
+	table := newIterateTestTable(1, 2, 3, 4, 5, 6, 7)
+	table.failAt = 3
+
+	var handled []uint64
+	collect := func(row iterateTestRow) error {
+		handled = append(handled, row.RequestID)
+		return nil
+	}
+	err := iteratePages(context.Background(), table.page, "request_id", nil, 2, collect)
+	var iterErr *IterateError
+	if !errors.As(err, &iterErr) {
+		t.Fatalf("expected an *IterateError, got %v", err)
+	}
+	if iterErr.Cursor != uint64(4) {
+		t.Fatalf("expected the cursor of the last page, 4, got %v", iterErr.Cursor)
+	}
+
+	if err := iteratePages(context.Background(), table.page, "request_id", iterErr.Cursor, 2, collect); err != nil {
+		t.Fatalf("resumed iteratePages() error = %v", err)
+	}
+	if want := []uint64{1, 2, 3, 4, 5, 6, 7}; !reflect.DeepEqual(handled, want) {
+		t.Errorf("handled %v, want %v", handled, want)
+	}
+}
+
+func TestIterateStopsOnCancellation(t *testing.T) {
+	table := newIterateTestTable(1, 2, 3, 4, 5, 6, 7)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var handled []uint64
+	err := iteratePages(ctx, table.page, "request_id", nil, 5, func(row iterateTestRow) error {
+		handled = append(handled, row.RequestID)
+		if row.RequestID == 3 {
+			cancel()
+		}
+		return nil
+	})
+	var iterErr *IterateError
+	if !errors.As(err, &iterErr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a canceled *IterateError, got %v", err)
+	}
+	if iterErr.Cursor != uint64(3) || len(handled) != 3 {
+		t.Errorf("expected to stop after row 3, handled %v with cursor %v", handled, iterErr.Cursor)
+	}
+	if table.pages != 1 {
+		t.Errorf("expected no page after the cancellation, queried %d", table.pages)
+	}
+
+	// a failing callback stops the traversal at the row before
+	failed := errors.New("row rejected")
+	err = iteratePages(context.Background(), table.page, "request_id", nil, 5, func(row iterateTestRow) error {
+		if row.RequestID == 2 {
+			return failed
+		}
+		return nil
+	})
+	if !errors.As(err, &iterErr) || !errors.Is(err, failed) || iterErr.Cursor != uint64(1) {
+		t.Errorf("expected the failure with cursor 1, got %v", err)
+	}
+}
+
+func TestKeysetQuery(t *testing.T) {
+	builder := Psql.Select("request_id", "status").From("msg_request").Where(sq.Eq{"status": "pending"})
+	for _, tc := range []struct {
+		after    any
+		wantSQL  string
+		wantArgs []any
+	}{
+		{nil, "SELECT request_id, status FROM msg_request WHERE status = $1 ORDER BY request_id LIMIT 100", []any{"pending"}},
+		{uint64(42), "SELECT request_id, status FROM msg_request WHERE status = $1 AND request_id > $2 ORDER BY request_id LIMIT 100", []any{"pending", uint64(42)}},
+	} {
+		sql, args, err := keysetQuery(builder, "request_id", tc.after, 100).ToSql()
+		if err != nil {
+			t.Fatalf("ToSql() error = %v", err)
+		}
+		if sql != tc.wantSQL || !reflect.DeepEqual(args, tc.wantArgs) {
+			t.Errorf("keysetQuery(%v) = %s %v, want %s %v", tc.after, sql, args, tc.wantSQL, tc.wantArgs)
+		}
+	}
+
+	if _, err := structKey(iterateTestRow{RequestID: 7}, "id"); err == nil {
+		t.Error("expected an error for a key without a tagged field")
+	}
+}
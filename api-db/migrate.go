@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	l "MgApplication/api-log"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	migratepgx "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Migrate applies to db the migrations of the directory dir of fsys not applied yet, with
+// golang-migrate like the schema of the tests: files named <version>_<name>.up.sql, run in
+// version order, the version applied being recorded in the tracking table table. The
+// migrations run on connections of the pool of db, the write pool.
+//
+// golang-migrate holds a Postgres advisory lock while migrating, so the instances starting
+// together wait for the first to apply the migrations, then find none to apply. The wait for
+// the lock is bounded by the deadline of ctx; golang-migrate runs the migrations themselves
+// without a context, they are not interrupted once started. A failed migration leaves the
+// schema dirty at its version, refusing the next migrations until it is fixed by hand, so a
+// migration of several statements wraps them in a transaction.
+//
+// It returns the versions migrated from and to, 0 for none.
+func Migrate(ctx context.Context, db *DB, fsys fs.FS, dir string, table string) (uint, uint, error) {
+	src, err := iofs.New(fsys, dir)
+	if err != nil {
+		return 0, 0, fmt.Errorf("reading the migrations: %w", err)
+	}
+	sqlDB := stdlib.OpenDBFromPool(db.Pool)
+	defer sqlDB.Close()
+	driver, err := migratepgx.WithInstance(sqlDB, &migratepgx.Config{MigrationsTable: table})
+	if err != nil {
+		return 0, 0, fmt.Errorf("opening the migrations table: %w", err)
+	}
+	return migrateUp(ctx, src, driver)
+}
+
+// migrateUp applies the migrations of src not applied to driver yet
+func migrateUp(ctx context.Context, src source.Driver, driver database.Driver) (uint, uint, error) {
+	m, err := migrate.NewWithInstance("iofs", src, "pgx", driver)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer m.Close()
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		m.LockTimeout = time.Until(deadline)
+	}
+
+	from, err := migrationVersion(m)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		to, _ := migrationVersion(m)
+		return from, to, err
+	}
+	to, err := migrationVersion(m)
+	if err != nil {
+		return from, from, err
+	}
+	if to != from {
+		l.Info(ctx, "Migrated the schema from version %d to %d", from, to)
+	}
+	return from, to, nil
+}
+
+// migrationVersion returns the version of the last migration applied, 0 for none
+func migrationVersion(m *migrate.Migrate) (uint, error) {
+	version, _, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, nil
+	}
+	return version, err
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/stub"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+var sampleMigrations = fstest.MapFS{
+	"migrations/0001_baseline.up.sql":      {Data: []byte("-- the schema of db/schema\n")},
+	"migrations/0002_template_note.up.sql": {Data: []byte("ALTER TABLE msg_template ADD COLUMN note text;\n")},
+}
+
+// migrateSample applies the migrations of fsys to the stub database tdb
+func migrateSample(t *testing.T, ctx context.Context, fsys fstest.MapFS, tdb *stub.Stub) (uint, uint, error) {
+	t.Helper()
+	src, err := iofs.New(fsys, "migrations")
+	if err != nil {
+		t.Fatalf("iofs.New() error = %v", err)
+	}
+	return migrateUp(ctx, src, tdb)
+}
+
+func newMigrateTestDB(t *testing.T) *stub.Stub {
+	t.Helper()
+	driver, err := stub.WithInstance(nil, &stub.Config{})
+	if err != nil {
+		t.Fatalf("stub.WithInstance() error = %v", err)
+	}
+	return driver.(*stub.Stub)
+}
+
+func TestMigrateAppliesSampleMigration(t *testing.T) {
+	tdb := newMigrateTestDB(t)
+	from, to, err := migrateSample(t, context.Background(), sampleMigrations, tdb)
+	if err != nil {
+		t.Fatalf("migrateUp() error = %v", err)
+	}
+	if from != 0 || to != 2 {
+		t.Errorf("migrated from %d to %d, want 0 to 2", from, to)
+	}
+
+	// only the migration added since runs on the next startup
+	next := fstest.MapFS{"migrations/0010_request_index.up.sql": {Data: []byte("CREATE INDEX ON msg_request (created_date);\n")}}
+	for name, file := range sampleMigrations {
+		next[name] = file
+	}
+	from, to, err = migrateSample(t, context.Background(), next, tdb)
+	if err != nil {
+		t.Fatalf("migrateUp() error = %v", err)
+	}
+	if from != 2 || to != 10 {
+		t.Errorf("migrated from %d to %d, want 2 to 10", from, to)
+	}
+	want := []string{
+		"-- the schema of db/schema\n",
+		"ALTER TABLE msg_template ADD COLUMN note text;\n",
+		"CREATE INDEX ON msg_request (created_date);\n",
+	}
+	if !reflect.DeepEqual(tdb.MigrationSequence, want) {
+		t.Errorf("migrations run %q, want %q", tdb.MigrationSequence, want)
+	}
+
+	// nothing left to apply
+	from, to, err = migrateSample(t, context.Background(), next, tdb)
+	if err != nil || from != 10 || to != 10 || len(tdb.MigrationSequence) != 3 {
+		t.Errorf("expected no migration to apply, migrated from %d to %d: %v", from, to, err)
+	}
+}
+
+func TestMigrateRefusesDirtySchema(t *testing.T) {
+	tdb := newMigrateTestDB(t)
+	tdb.CurrentVersion, tdb.IsDirty = 1, true
+
+	_, _, err := migrateSample(t, context.Background(), sampleMigrations, tdb)
+	var dirty migrate.ErrDirty
+	if !errors.As(err, &dirty) || dirty.Version != 1 {
+		t.Fatalf("expected the dirty version 1, got %v", err)
+	}
+	if len(tdb.MigrationSequence) != 0 {
+		t.Errorf("expected no migration run on a dirty schema, ran %q", tdb.MigrationSequence)
+	}
+}
+
+func TestMigrateStopsOnCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tdb := newMigrateTestDB(t)
+	_, _, err := migrateSample(t, ctx, sampleMigrations, tdb)
+	if !errors.Is(err, context.Canceled) || len(tdb.MigrationSequence) != 0 {
+		t.Errorf("expected the cancellation before any migration, got %v running %q", err, tdb.MigrationSequence)
+	}
+}
@@ -17,4 +17,5 @@ type DBConfig struct {
 	AppName           string        `mapstructure:"appname"`
 	SSLMode           string        `mapstructure:"sslmode"`
 	Trace             bool          `mapstructure:"trace"`
+	ReadMaxRetries    int           `mapstructure:"readmaxretries"`
 }
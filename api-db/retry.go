@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"syscall"
+	"time"
+
+	l "MgApplication/api-log"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// readRetryBackoff is the base delay before retrying a read query; each attempt waits
+// attempt*readRetryBackoff plus up to the same amount of jitter.
+const readRetryBackoff = 20 * time.Millisecond
+
+// ReadOption configures a single call to a read-only helper.
+type ReadOption func(*readOptions)
+
+type readOptions struct {
+	retry bool
+}
+
+// WithReadRetry retries the query up to db.read.maxretries times when it fails with a
+// transient connection error, such as the connection resets seen during a primary
+// failover. Only read-only helpers accept it; writes are never retried.
+func WithReadRetry() ReadOption {
+	return func(o *readOptions) {
+		o.retry = true
+	}
+}
+
+func newReadOptions(opts []ReadOption) readOptions {
+	var o readOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// newReadRetryCounter creates the counter of retried read queries for a pool
+func newReadRetryCounter(labels map[string]string) prometheus.Counter {
+	return prometheus.NewCounter(prometheus.CounterOpts{
+		Name:        "pgxpool_read_retry_count",
+		Help:        "Cumulative count of read queries retried after a transient connection error.",
+		ConstLabels: labels,
+	})
+}
+
+// withReadRetry runs fn, running it again on transient connection errors when the
+// caller opted in. It never retries once the caller's context is done.
+func withReadRetry(ctx context.Context, db *DB, o readOptions, fn func() error) error {
+	err := fn()
+	if !o.retry {
+		return err
+	}
+
+	for attempt := 1; attempt <= db.ReadMaxRetries && isTransientReadError(ctx, err); attempt++ {
+		l.Warn(ctx, "Retrying read query after transient error (attempt %d of %d): %s", attempt, db.ReadMaxRetries, err.Error())
+		if db.readRetries != nil {
+			db.readRetries.Inc()
+		}
+
+		backoff := readRetryBackoff * time.Duration(attempt)
+		timer := time.NewTimer(backoff + rand.N(backoff))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		err = fn()
+	}
+	return err
+}
+
+// isTransientReadError reports whether err is a connection level failure that is
+// likely to succeed on a fresh connection. Errors caused by the caller's context
+// are never transient.
+func isTransientReadError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgerrcode.IsConnectionException(pgErr.Code)
+	}
+
+	var connectErr *pgconn.ConnectError
+	switch {
+	case pgconn.SafeToRetry(err), strings.Contains(err.Error(), "conn busy"):
+		return true
+	case errors.As(err, &connectErr):
+		return true
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return true
+	}
+	return isSocketError(err)
+}
@@ -0,0 +1,341 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// fakePostgres is a minimal PostgreSQL server speaking the simple query protocol.
+// SELECT statements return a single row with id 1; other statements affect one row.
+type fakePostgres struct {
+	listener net.Listener
+	queries  atomic.Int64
+}
+
+func newFakePostgres(t *testing.T) *fakePostgres {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	fp := &fakePostgres{listener: ln}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go fp.serve(conn)
+		}
+	}()
+	return fp
+}
+
+func (fp *fakePostgres) serve(conn net.Conn) {
+	defer conn.Close()
+	backend := pgproto3.NewBackend(conn, conn)
+
+	if _, err := backend.ReceiveStartupMessage(); err != nil {
+		return
+	}
+	backend.Send(&pgproto3.AuthenticationOk{})
+	backend.Send(&pgproto3.ParameterStatus{Name: "server_version", Value: "14.0"})
+	backend.Send(&pgproto3.ParameterStatus{Name: "client_encoding", Value: "UTF8"})
+	backend.Send(&pgproto3.ParameterStatus{Name: "standard_conforming_strings", Value: "on"})
+	backend.Send(&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: 1})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+	if err := backend.Flush(); err != nil {
+		return
+	}
+
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return
+		}
+		switch msg := msg.(type) {
+		case *pgproto3.Query:
+			fp.queries.Add(1)
+			if strings.HasPrefix(strings.ToUpper(msg.String), "SELECT") {
+				backend.Send(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+					{Name: []byte("id"), DataTypeOID: 20, DataTypeSize: 8, TypeModifier: -1},
+				}})
+				backend.Send(&pgproto3.DataRow{Values: [][]byte{[]byte("1")}})
+				backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")})
+			} else {
+				backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("INSERT 0 1")})
+			}
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+			if err := backend.Flush(); err != nil {
+				return
+			}
+		case *pgproto3.Terminate:
+			return
+		}
+	}
+}
+
+// killProxy forwards connections to the target, closing the first kill connection
+// attempts immediately as a failing over primary would.
+type killProxy struct {
+	listener net.Listener
+	attempts atomic.Int64
+}
+
+func newKillProxy(t *testing.T, target string, kill int64) *killProxy {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	kp := &killProxy{listener: ln}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if kp.attempts.Add(1) <= kill {
+				conn.Close()
+				continue
+			}
+			go forward(conn, target)
+		}
+	}()
+	return kp
+}
+
+func forward(client net.Conn, target string) {
+	defer client.Close()
+	server, err := net.Dial("tcp", target)
+	if err != nil {
+		return
+	}
+	defer server.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(server, client)
+		server.Close()
+	}()
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(client, server)
+		client.Close()
+	}()
+	wg.Wait()
+}
+
+// countingCounter records increments of the read retry counter
+type countingCounter struct {
+	prometheus.Counter
+	count atomic.Int64
+}
+
+func (c *countingCounter) Inc() {
+	c.count.Add(1)
+}
+
+func newRetryTestDB(t *testing.T, addr string, maxRetries int) (*DB, *countingCounter) {
+	t.Helper()
+	host, port, _ := net.SplitHostPort(addr)
+	config, err := pgxpool.ParseConfig(fmt.Sprintf("user=test dbname=test host=%s port=%s sslmode=disable", host, port))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	config.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+	config.ConnConfig.ConnectTimeout = time.Second
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	counter := &countingCounter{}
+	return &DB{Pool: pool, ReadMaxRetries: maxRetries, readRetries: counter}, counter
+}
+
+type retryTestRow struct {
+	ID int64 `db:"id"`
+}
+
+func TestSelectOne_RetriesTransientConnectionError(t *testing.T) {
+	fp := newFakePostgres(t)
+	proxy := newKillProxy(t, fp.listener.Addr().String(), 1)
+	db, counter := newRetryTestDB(t, proxy.listener.Addr().String(), 3)
+
+	row, err := SelectOne(context.Background(), db, Psql.Select("id").From("t"), pgx.RowToStructByName[retryTestRow], WithReadRetry())
+	if err != nil {
+		t.Fatalf("expected read to succeed after a retry, got %v", err)
+	}
+	if row.ID != 1 {
+		t.Errorf("expected id 1, got %d", row.ID)
+	}
+	if got := counter.count.Load(); got != 1 {
+		t.Errorf("expected exactly one retry, got %d", got)
+	}
+	if got := fp.queries.Load(); got != 1 {
+		t.Errorf("expected 1 query to reach the server, got %d", got)
+	}
+}
+
+func TestSelectRows_RetriesTransientConnectionError(t *testing.T) {
+	fp := newFakePostgres(t)
+	proxy := newKillProxy(t, fp.listener.Addr().String(), 1)
+	db, counter := newRetryTestDB(t, proxy.listener.Addr().String(), 1)
+
+	rows, err := SelectRows(context.Background(), db, Psql.Select("id").From("t"), pgx.RowToStructByName[retryTestRow], WithReadRetry())
+	if err != nil {
+		t.Fatalf("expected read to succeed after a retry, got %v", err)
+	}
+	if len(rows) != 1 {
+		t.Errorf("expected 1 row, got %d", len(rows))
+	}
+	if got := counter.count.Load(); got != 1 {
+		t.Errorf("expected exactly one retry, got %d", got)
+	}
+}
+
+func TestSelectOneOK_RetriesTransientConnectionError(t *testing.T) {
+	fp := newFakePostgres(t)
+	proxy := newKillProxy(t, fp.listener.Addr().String(), 1)
+	db, counter := newRetryTestDB(t, proxy.listener.Addr().String(), 1)
+
+	_, ok, err := SelectOneOK(context.Background(), db, Psql.Select("id").From("t"), pgx.RowToStructByName[retryTestRow], WithReadRetry())
+	if err != nil || !ok {
+		t.Fatalf("expected read to succeed after a retry, got ok=%t err=%v", ok, err)
+	}
+	if got := counter.count.Load(); got != 1 {
+		t.Errorf("expected exactly one retry, got %d", got)
+	}
+}
+
+func TestSelectOne_NoRetryWithoutOption(t *testing.T) {
+	fp := newFakePostgres(t)
+	proxy := newKillProxy(t, fp.listener.Addr().String(), 1)
+	db, counter := newRetryTestDB(t, proxy.listener.Addr().String(), 3)
+
+	if _, err := SelectOne(context.Background(), db, Psql.Select("id").From("t"), pgx.RowToStructByName[retryTestRow]); err == nil {
+		t.Fatal("expected the killed connection to fail the read")
+	}
+	if got := counter.count.Load(); got != 0 {
+		t.Errorf("expected no retries, got %d", got)
+	}
+	if got := proxy.attempts.Load(); got != 1 {
+		t.Errorf("expected 1 connection attempt, got %d", got)
+	}
+}
+
+func TestSelectOne_RetriesExhausted(t *testing.T) {
+	fp := newFakePostgres(t)
+	proxy := newKillProxy(t, fp.listener.Addr().String(), 100)
+	db, counter := newRetryTestDB(t, proxy.listener.Addr().String(), 2)
+
+	if _, err := SelectOne(context.Background(), db, Psql.Select("id").From("t"), pgx.RowToStructByName[retryTestRow], WithReadRetry()); err == nil {
+		t.Fatal("expected the read to fail once retries are exhausted")
+	}
+	if got := counter.count.Load(); got != 2 {
+		t.Errorf("expected 2 retries, got %d", got)
+	}
+	if got := fp.queries.Load(); got != 0 {
+		t.Errorf("expected no query to reach the server, got %d", got)
+	}
+}
+
+func TestSelectOne_NoRetryAfterContextDone(t *testing.T) {
+	fp := newFakePostgres(t)
+	proxy := newKillProxy(t, fp.listener.Addr().String(), 1)
+	db, counter := newRetryTestDB(t, proxy.listener.Addr().String(), 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := SelectOne(ctx, db, Psql.Select("id").From("t"), pgx.RowToStructByName[retryTestRow], WithReadRetry()); err == nil {
+		t.Fatal("expected the read to fail with a cancelled context")
+	}
+	if got := counter.count.Load(); got != 0 {
+		t.Errorf("expected no retries, got %d", got)
+	}
+}
+
+func TestWriteHelpers_NotRetried(t *testing.T) {
+	fp := newFakePostgres(t)
+	proxy := newKillProxy(t, fp.listener.Addr().String(), 1)
+	db, counter := newRetryTestDB(t, proxy.listener.Addr().String(), 3)
+
+	query := Psql.Insert("t").Columns("id").Values(1)
+	if _, err := Insert(context.Background(), db, query); err == nil {
+		t.Fatal("expected the killed connection to fail the write")
+	}
+	if got := counter.count.Load(); got != 0 {
+		t.Errorf("expected writes not to be retried, got %d retries", got)
+	}
+	if got := proxy.attempts.Load(); got != 1 {
+		t.Errorf("expected 1 connection attempt, got %d", got)
+	}
+	if got := fp.queries.Load(); got != 0 {
+		t.Errorf("expected no query to reach the server, got %d", got)
+	}
+
+	// The next write goes through on a fresh connection
+	if _, err := Insert(context.Background(), db, query); err != nil {
+		t.Fatalf("expected write to succeed, got %v", err)
+	}
+	if got := fp.queries.Load(); got != 1 {
+		t.Errorf("expected 1 query to reach the server, got %d", got)
+	}
+}
+
+func TestIsTransientReadError(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{name: "nil", ctx: context.Background(), err: nil, want: false},
+		{name: "connection exception", ctx: context.Background(), err: &pgconn.PgError{Code: pgerrcode.ConnectionFailure}, want: true},
+		{name: "unique violation", ctx: context.Background(), err: &pgconn.PgError{Code: pgerrcode.UniqueViolation}, want: false},
+		{name: "conn busy", ctx: context.Background(), err: errors.New("conn busy"), want: true},
+		{name: "connection reset", ctx: context.Background(), err: fmt.Errorf("read: %w", syscall.ECONNRESET), want: true},
+		{name: "unexpected eof", ctx: context.Background(), err: io.ErrUnexpectedEOF, want: true},
+		{name: "no rows", ctx: context.Background(), err: pgx.ErrNoRows, want: false},
+		{name: "context cancelled", ctx: context.Background(), err: context.Canceled, want: false},
+		{name: "deadline exceeded", ctx: context.Background(), err: fmt.Errorf("query: %w", context.DeadlineExceeded), want: false},
+		{name: "reset after caller context done", ctx: cancelled, err: syscall.ECONNRESET, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientReadError(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("expected %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
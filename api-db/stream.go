@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"iter"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// SelectSeq runs the query of builder when the returned sequence is iterated and yields its
+// rows one at a time as they are scanned, so that a large result is never held in memory.
+// An error of the query, of a scan or of the rows is yielded with the zero T and ends the
+// sequence. Stopping the iteration early closes the rows. Unlike SelectRows the query is never
+// retried, rows already yielded cannot be taken back.
+func SelectSeq[T any](ctx context.Context, db *DB, builder sq.SelectBuilder, scanFn pgx.RowToFunc[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		sql, args, err := builder.ToSql()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		rows, err := db.Query(ctx, sql, args...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			row, err := scanFn(rows)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(row, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
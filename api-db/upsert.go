@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// UpsertQuery builds the statement inserting row into table or, when a row with the same
+// conflictColumns exists, updating its other columns, and returning the resulting row. The
+// columns are the db tags of the fields of row, including embedded structs; fields without a db
+// tag or tagged "-" are left out.
+//
+// Within a transaction:
+//
+//	query, err := dblib.UpsertQuery("msg_gateway_code", code, "gateway", "code")
+//	...
+//	err = dblib.TxReturnRow(ctx, tx, query, pgx.RowToStructByNameLax[domain.GatewayCode], &saved)
+func UpsertQuery(table string, row any, conflictColumns ...string) (sq.InsertBuilder, error) {
+	if len(conflictColumns) == 0 {
+		return sq.InsertBuilder{}, errors.New("upsert requires conflict columns")
+	}
+	columns, values, err := structColumns(row)
+	if err != nil {
+		return sq.InsertBuilder{}, err
+	}
+
+	conflict := make(map[string]bool, len(conflictColumns))
+	for _, column := range conflictColumns {
+		conflict[column] = true
+	}
+	set := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if conflict[column] {
+			delete(conflict, column)
+			continue
+		}
+		set = append(set, column+" = EXCLUDED."+column)
+	}
+	if len(conflict) > 0 {
+		return sq.InsertBuilder{}, fmt.Errorf("upsert conflict columns %v are not all columns of %T", conflictColumns, row)
+	}
+	if len(set) == 0 {
+		// DO NOTHING would return no row for an existing key
+		set = append(set, conflictColumns[0]+" = EXCLUDED."+conflictColumns[0])
+	}
+
+	return Psql.Insert(table).
+		Columns(columns...).
+		Values(values...).
+		Suffix(fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(conflictColumns, ", "), strings.Join(set, ", "))).
+		Suffix("RETURNING " + strings.Join(columns, ", ")), nil
+}
+
+// Upsert inserts row into table or, when a row with the same conflictColumns exists, updates its
+// other columns, and returns the resulting row. See UpsertQuery for the columns.
+func Upsert[T any](ctx context.Context, db *DB, table string, row T, conflictColumns ...string) (T, error) {
+	var zero T
+	query, err := UpsertQuery(table, row, conflictColumns...)
+	if err != nil {
+		return zero, err
+	}
+	return InsertReturning(ctx, db, query, pgx.RowToStructByNameLax[T])
+}
+
+// structColumns returns the db tags of the fields of row along with their values
+func structColumns(row any) ([]string, []any, error) {
+	val := reflect.Indirect(reflect.ValueOf(row))
+	if val.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("expected a struct, got %T", row)
+	}
+
+	var columns []string
+	var values []any
+	var collect func(v reflect.Value)
+	collect = func(v reflect.Value) {
+		typ := v.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			column, _, _ := strings.Cut(field.Tag.Get("db"), ",")
+			switch {
+			case column == "-":
+			case column == "" && field.Anonymous && field.Type.Kind() == reflect.Struct:
+				collect(v.Field(i))
+			case column != "" && field.IsExported():
+				columns = append(columns, column)
+				values = append(values, v.Field(i).Interface())
+			}
+		}
+	}
+	collect(val)
+
+	if len(columns) == 0 {
+		return nil, nil, fmt.Errorf("%T has no db tagged fields", row)
+	}
+	return columns, values, nil
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type upsertTestAudit struct {
+	UpdatedBy string `db:"updated_by"`
+}
+
+type upsertTestRow struct {
+	Gateway     string  `db:"gateway"`
+	Code        string  `db:"code"`
+	Description string  `db:"description"`
+	Action      *string `db:"recommended_action,omitempty"`
+	Cached      bool    `db:"-"`
+	Label       string
+	upsertTestAudit
+}
+
+func TestUpsertQuery(t *testing.T) {
+	action := "Retry later"
+	query, err := UpsertQuery("msg_gateway_code", &upsertTestRow{Gateway: "1", Code: "402", Description: "Submitted", Action: &action, upsertTestAudit: upsertTestAudit{UpdatedBy: "admin"}}, "gateway", "code")
+	if err != nil {
+		t.Fatalf("UpsertQuery() error = %v", err)
+	}
+	sql, args, err := query.ToSql()
+	if err != nil {
+		t.Fatalf("ToSql() error = %v", err)
+	}
+
+	want := "INSERT INTO msg_gateway_code (gateway,code,description,recommended_action,updated_by) VALUES ($1,$2,$3,$4,$5) " +
+		"ON CONFLICT (gateway, code) DO UPDATE SET description = EXCLUDED.description, recommended_action = EXCLUDED.recommended_action, updated_by = EXCLUDED.updated_by " +
+		"RETURNING gateway, code, description, recommended_action, updated_by"
+	if sql != want {
+		t.Errorf("UpsertQuery() sql =\n%s\nwant\n%s", sql, want)
+	}
+	if len(args) != 5 || args[0] != "1" || args[1] != "402" || args[3] != &action || args[4] != "admin" {
+		t.Errorf("UpsertQuery() args = %v", args)
+	}
+}
+
+func TestUpsertQueryOnlyConflictColumns(t *testing.T) {
+	row := struct {
+		MobileNumber string `db:"mobile_number"`
+	}{"9876543210"}
+	query, err := UpsertQuery("msg_dnd_registry", row, "mobile_number")
+	if err != nil {
+		t.Fatalf("UpsertQuery() error = %v", err)
+	}
+	sql, _, _ := query.ToSql()
+	want := "INSERT INTO msg_dnd_registry (mobile_number) VALUES ($1) ON CONFLICT (mobile_number) DO UPDATE SET mobile_number = EXCLUDED.mobile_number RETURNING mobile_number"
+	if sql != want {
+		t.Errorf("UpsertQuery() sql =\n%s\nwant\n%s", sql, want)
+	}
+}
+
+func TestUpsertQueryErrors(t *testing.T) {
+	tests := []struct {
+		name            string
+		row             any
+		conflictColumns []string
+	}{
+		{"No conflict columns", upsertTestRow{}, nil},
+		{"Unknown conflict column", upsertTestRow{}, []string{"gateway", "severity"}},
+		{"Not a struct", "402", []string{"code"}},
+		{"No db tags", struct{ Code string }{"402"}, []string{"code"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := UpsertQuery("msg_gateway_code", tt.row, tt.conflictColumns...); err == nil {
+				t.Error("UpsertQuery() error = nil, want an error")
+			}
+		})
+	}
+}
+
+// returnedRow is the single row returned by an upsert, with the given columns and values
+type returnedRow struct {
+	pgx.Rows
+	columns []string
+	values  []any
+	read    bool
+}
+
+func (r *returnedRow) Next() bool {
+	next := !r.read
+	r.read = true
+	return next
+}
+
+func (r *returnedRow) FieldDescriptions() []pgconn.FieldDescription {
+	fields := make([]pgconn.FieldDescription, len(r.columns))
+	for i, column := range r.columns {
+		fields[i].Name = column
+	}
+	return fields
+}
+
+func (r *returnedRow) Scan(dest ...any) error {
+	for i, d := range dest {
+		reflect.ValueOf(d).Elem().Set(reflect.ValueOf(r.values[i]))
+	}
+	return nil
+}
+
+func (r *returnedRow) Close()     {}
+func (r *returnedRow) Err() error { return nil }
+
+// TestUpsertReturnedRow checks that the row returned by the statement of UpsertQuery is read
+// back into the struct by Upsert, the RETURNING columns being the db tags pgx scans by
+func TestUpsertReturnedRow(t *testing.T) {
+	action := "Retry later"
+	row := upsertTestRow{Gateway: "1", Code: "402", Description: "Submitted", Action: &action, upsertTestAudit: upsertTestAudit{UpdatedBy: "admin"}}
+	columns, values, err := structColumns(row)
+	if err != nil {
+		t.Fatalf("structColumns() error = %v", err)
+	}
+
+	returned, err := pgx.CollectOneRow(&returnedRow{columns: columns, values: values}, pgx.RowToStructByNameLax[upsertTestRow])
+	if err != nil {
+		t.Fatalf("CollectOneRow() error = %v", err)
+	}
+	if !reflect.DeepEqual(returned, row) {
+		t.Errorf("Upsert() returned %+v, want %+v", returned, row)
+	}
+}
@@ -14,7 +14,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
-	//l "MgApplication/api-log"
+
+	l "MgApplication/api-log"
 )
 
 type TimedBatch struct {
@@ -194,7 +195,7 @@ func ExecRow(ctx context.Context, db *DB, sql string, args ...any) (pgconn.Comma
 	return ct, nil
 }
 
-func SelectOneOK[T any](ctx context.Context, db *DB, builder sq.SelectBuilder, scanFn pgx.RowToFunc[T]) (T, bool, error) {
+func SelectOneOK[T any](ctx context.Context, db *DB, builder sq.SelectBuilder, scanFn pgx.RowToFunc[T], opts ...ReadOption) (T, bool, error) {
 
 	var zero T
 	sql, args, err := builder.ToSql()
@@ -202,7 +203,18 @@ func SelectOneOK[T any](ctx context.Context, db *DB, builder sq.SelectBuilder, s
 		//l.Error(ctx, err)
 		return zero, false, err
 	}
-	rows, err := db.Query(ctx, sql, args...)
+
+	var collectedRow T
+	var b bool
+	err = withReadRetry(ctx, db, newReadOptions(opts), func() error {
+		rows, err := db.Query(ctx, sql, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		collectedRow, b, err = CollectOneRowOK(rows, scanFn)
+		return err
+	})
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			//l.Error(ctx, err)
@@ -210,31 +222,28 @@ func SelectOneOK[T any](ctx context.Context, db *DB, builder sq.SelectBuilder, s
 		}
 		return zero, false, err
 	}
-	defer rows.Close()
-	collectedRow, b, err := CollectOneRowOK(rows, scanFn)
-	if err != nil {
-		//l.Error(ctx, err)
-		return zero, false, err
-	}
 
 	return collectedRow, b, nil
 }
 
-func SelectOne[T any](ctx context.Context, db *DB, builder sq.SelectBuilder, scanFn pgx.RowToFunc[T]) (T, error) {
+func SelectOne[T any](ctx context.Context, db *DB, builder sq.SelectBuilder, scanFn pgx.RowToFunc[T], opts ...ReadOption) (T, error) {
 	var zero T
 	sql, args, err := builder.ToSql()
 	if err != nil {
 		//l.Error(ctx, err)
 		return zero, err
 	}
-	rows, err := db.Query(ctx, sql, args...)
-	if err != nil {
-		//l.Error(ctx, err)
-		return zero, err
-	}
-	defer rows.Close()
 
-	collectedRow, err := pgx.CollectOneRow(rows, scanFn)
+	var collectedRow T
+	err = withReadRetry(ctx, db, newReadOptions(opts), func() error {
+		rows, err := db.Query(ctx, sql, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		collectedRow, err = pgx.CollectOneRow(rows, scanFn)
+		return err
+	})
 	if err != nil {
 		//l.Error(ctx, err)
 		return zero, err
@@ -259,21 +268,24 @@ func InsertReturning[T any](ctx context.Context, db *DB, builder sq.InsertBuilde
 
 }
 
-func SelectRows[T any](ctx context.Context, db *DB, builder sq.SelectBuilder, scanFn pgx.RowToFunc[T]) ([]T, error) {
+func SelectRows[T any](ctx context.Context, db *DB, builder sq.SelectBuilder, scanFn pgx.RowToFunc[T], opts ...ReadOption) ([]T, error) {
 
 	sql, args, err := builder.ToSql()
 	if err != nil {
 		//l.Error(ctx, err)
 		return nil, err
 	}
-	rows, err := db.Query(ctx, sql, args...)
-	if err != nil {
-		//l.Error(ctx, err)
-		return nil, err
-	}
 
-	defer rows.Close()
-	collectedRows, err := pgx.CollectRows(rows, scanFn)
+	var collectedRows []T
+	err = withReadRetry(ctx, db, newReadOptions(opts), func() error {
+		rows, err := db.Query(ctx, sql, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		collectedRows, err = pgx.CollectRows(rows, scanFn)
+		return err
+	})
 	if err != nil {
 		//l.Error(ctx, err)
 		return nil, err
@@ -856,7 +868,7 @@ func DBQueryMultipleRows(ctx context.Context, query sq.SelectBuilder, dbs *DB, s
 
 		// Scan the row into the pointers of each field in the struct
 		if err := rows.Scan(scanArgs...); err != nil {
-			fmt.Println("Error scanning row:", err)
+			l.Error(ctx, "Error scanning row in DBQueryMultipleRows, row skipped: %s", err.Error())
 			continue
 		}
 
@@ -1004,7 +1016,7 @@ func TimedQueueReturnBulk[T any](batch *TimedBatch, builder sq.Sqlizer, scanFn p
 func validateOutputVariable[T any](output *T) error {
 	if output == nil {
 		err := fmt.Errorf("the output variable cannot be nil. Please provide a valid reference")
-		appError := apierrors.NewAppError("Error occurred while validating the output variable", "400", err)
+		appError := apierrors.NewAppError("Error occurred while validating the output variable", 400, err)
 		return &appError
 	}
 	return nil
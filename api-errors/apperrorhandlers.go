@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -16,7 +17,8 @@ import (
 )
 
 // respondWithError is a helper function to reduce code duplication in error handlers.
-// It creates an AppError and APIErrorResponse, then sends the JSON response.
+// It creates an AppError and APIErrorResponse, then sends the response in the format
+// negotiated from the Accept header (JSON by default).
 //
 // Parameters:
 //   - ctx: The Gin context for the current request.
@@ -31,7 +33,7 @@ func respondWithError(
 ) {
 	appError := NewAppError(message, statusCodeAndMessage.StatusCode, err)
 	apiErrorResponse := NewHTTPAPIErrorResponse(statusCodeAndMessage, appError)
-	ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+	writeErrorResponse(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 }
 
 // HandleNoRouteError handles requests to non-existent routes.
@@ -80,7 +82,7 @@ func HandleBindingError(ctx *gin.Context, err error) {
 	// Check if the error is of type AppError.
 	if appErr, ok := Find[*AppError](err); ok {
 		apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorBadRequest, *appErr)
-		ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+		writeErrorResponse(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 		return
 	}
 
@@ -94,18 +96,7 @@ func HandleBindingError(ctx *gin.Context, err error) {
 		appErr = &newAppErr // Get a pointer to the newly created AppError
 
 		// Extract field-specific errors.
-		var fieldErrors []FieldError
-		for _, err := range ve {
-			fieldError := appErr.NewFieldError(
-				err.Field(),
-				err.Value(),
-				fmt.Sprintf("Validation failed for '%s' field", err.Field()),
-				err.Tag(),
-			)
-			fieldErrors = append(fieldErrors, fieldError)
-		}
-
-		appErr.SetFieldErrors(fieldErrors)
+		appErr.SetFieldErrors(newValidationFieldErrors(appErr, ve))
 	} else {
 		// var syntaxError *json.SyntaxError
 		// var unmarshalTypeError *json.UnmarshalTypeError
@@ -144,12 +135,13 @@ func HandleBindingError(ctx *gin.Context, err error) {
 	}
 
 	apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorBadRequest, *appErr)
-	ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+	writeErrorResponse(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 }
 
-// HandleValidationError handles validation errors by checking if the error is of type AppError.
-// If it is, it creates an HTTP API error response with a bad request status code and sends it as a JSON response.
-// If the error is not of type AppError, it delegates the error handling to the HandleError function.
+// HandleValidationError handles validation errors and responds with a structured error payload.
+// If the error is not of type AppError, it is wrapped in a new AppError. When no field errors are set
+// and the error wraps validator.ValidationErrors, the field errors are derived from them, so that every
+// failing field is reported with its field, value, message and tag.
 //
 // Parameters:
 //   - ctx: The Gin context for the current request.
@@ -161,17 +153,44 @@ func HandleValidationError(ctx *gin.Context, err error) {
 	if err == nil {
 		return
 	}
+	apiErrorResponse := newValidationErrorResponse(err)
+	writeErrorResponse(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
+}
+
+// newValidationErrorResponse returns the 422 response of a validation error, with the field
+// errors of its AppError or of the validator errors it wraps
+func newValidationErrorResponse(err error) APIErrorResponse {
 	// Assert that the error is of the custom type that contains app error
 	// appError, ok := err.(*AppError)
-	appError, ok := Find[*AppError](err)
-	if !ok {
-		apperror := NewAppError(err.Error(), http.StatusUnprocessableEntity, err)
-		apiErrorResponse := NewHTTPAPIErrorResponse(AppErrorValidationError, apperror)
-		ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
-		return
+	var apperror AppError
+	if appError, ok := Find[*AppError](err); ok {
+		apperror = *appError
+	} else {
+		apperror = NewAppError(err.Error(), http.StatusUnprocessableEntity, err)
+	}
+	// Derive field errors from the underlying validator errors when they were not set explicitly
+	if len(apperror.FieldErrors) == 0 {
+		if ve, ok := Find[validator.ValidationErrors](err); ok {
+			apperror.SetFieldErrors(newValidationFieldErrors(&apperror, ve))
+		}
 	}
-	apiErrorResponse := NewHTTPAPIErrorResponse(AppErrorValidationError, *appError)
-	ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+	return NewHTTPAPIErrorResponse(AppErrorValidationError, apperror)
+}
+
+// newValidationFieldErrors converts validator.ValidationErrors into field errors, so that
+// binding and validation failures share the same field error structure.
+func newValidationFieldErrors(appErr *AppError, ve validator.ValidationErrors) []FieldError {
+	fieldErrors := make([]FieldError, 0, len(ve))
+	for _, err := range ve {
+		fieldError := appErr.NewFieldError(
+			err.Field(),
+			err.Value(),
+			fmt.Sprintf("Validation failed for '%s' field", err.Field()),
+			err.Tag(),
+		)
+		fieldErrors = append(fieldErrors, fieldError)
+	}
+	return fieldErrors
 }
 
 // HandleDBError handles database-related errors and maps them to appropriate HTTP responses.
@@ -188,6 +207,8 @@ func HandleValidationError(ctx *gin.Context, err error) {
 //   - HTTP 400 Bad Request: For data-related issues, such as invalid input or data exceptions.
 //   - HTTP 503 Service Unavailable: For database connection exceptions or service unavailability.
 //   - HTTP 409 Conflict: For integrity constraint violations or duplicate records.
+//   - 499 Client Closed Request: For the cancellation of the request by its client, recorded
+//     in the metrics and logs but never received.
 //
 // The function distinguishes between different types of PostgreSQL errors and maps them to
 // corresponding HTTP status codes and error messages. It also handles non-database-related
@@ -197,104 +218,36 @@ func HandleDBError(ctx *gin.Context, err error) {
 		return
 	}
 
-	// Check if the error is of type AppError.
-	if appErr, ok := Find[*AppError](err); ok {
-		statusCode := appErr.Code
-		statusCodeAndMessage := mapErrorToHTTP(statusCode)
+	apiErrorResponse := classifyError(err)
+	writeErrorResponse(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
+}
 
-		apiErrorResponse := NewHTTPAPIErrorResponse(statusCodeAndMessage, *appErr)
-		ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
-		return
+// classifyError maps err to its API error response by the most specific error found in its
+// tree, searched depth-first through both Unwrap() error and Unwrap() []error:
+//
+//  1. validator.ValidationErrors, or an AppError with field errors: 422 with the field errors.
+//  2. A *pgconn.PgError: the status of its SQLSTATE, as mapped by checkDBError. When the
+//     PgError is wrapped in an AppError the message and ID of the AppError are kept.
+//  3. Any other AppError: the status of its code.
+//  4. Anything else: as mapped by checkDBError, 500 unless a deadline, a cancellation or a
+//     missing row.
+func classifyError(err error) APIErrorResponse {
+	appErr, isAppErr := Find[*AppError](err)
+	_, isValidationErr := Find[validator.ValidationErrors](err)
+	if isValidationErr || (isAppErr && len(appErr.FieldErrors) > 0) {
+		return newValidationErrorResponse(err)
 	}
 
-	var appError AppError
-
-	// Handle specific PostgreSQL error types using a switch statement.
-	switch {
-	case Is(err, context.DeadlineExceeded):
-		appError = NewAppError(DBConnectionException.Message, DBConnectionException.HTTPStatusCode, err)
-		apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-		ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
-
-	case Is(err, pgx.ErrNoRows):
-		appError = NewAppError(DBNoData.Message, DBNoData.HTTPStatusCode, err)
-		apiErrorResponse := NewHTTPAPIErrorResponse(DBErrorRecordNotFound, appError)
-		ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
-
-	default:
-		// Check if the error is a PostgreSQL error.
-		if pgErr, ok := Find[*pgconn.PgError](err); ok {
-			// Map PostgreSQL error codes to custom dbError codes and messages.
-			switch {
-
-			case pgErr.Code == "42P01": // SQLSTATE for "relation does not exist"
-				appError = NewAppError(DBSyntaxErrororAccessRuleViolation.Message, DBSyntaxErrororAccessRuleViolation.HTTPStatusCode, err)
-				apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-				ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
-
-			case pgerrcode.IsCardinalityViolation(pgErr.Code):
-				appError = NewAppError(DBCardinalityViolation.Message, DBCardinalityViolation.HTTPStatusCode, err)
-				apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-				ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
-
-			case pgerrcode.IsWarning(pgErr.Code):
-				appError = NewAppError(DBWarning.Message, DBWarning.HTTPStatusCode, err)
-				apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-				ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
-
-			case pgerrcode.IsNoData(pgErr.Code):
-				appError = NewAppError(DBNoData.Message, DBNoData.HTTPStatusCode, err)
-				apiErrorResponse := NewHTTPAPIErrorResponse(DBErrorRecordNotFound, appError)
-				ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
-
-			case pgerrcode.IsIntegrityConstraintViolation(pgErr.Code):
-				appError = NewAppError(DBIntegrityConstraintViolation.Message, DBIntegrityConstraintViolation.HTTPStatusCode, err)
-				apiErrorResponse := NewHTTPAPIErrorResponse(DBErrorDuplicateRecord, appError)
-				ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
-
-			case pgerrcode.IsSQLStatementNotYetComplete(pgErr.Code):
-				appError = NewAppError(DBSQLStatementNotYetComplete.Message, DBSQLStatementNotYetComplete.HTTPStatusCode, err)
-				apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-				ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
-
-			case pgerrcode.IsConnectionException(pgErr.Code):
-				appError = NewAppError(DBConnectionException.Message, DBConnectionException.HTTPStatusCode, err)
-				apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorServiceUnavailable, appError)
-				ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
-
-			case pgerrcode.IsDataException(pgErr.Code):
-				appError = NewAppError(DBDataException.Message, DBDataException.HTTPStatusCode, err)
-				apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorBadRequest, appError)
-				ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
-
-			case pgerrcode.IsTransactionRollback(pgErr.Code):
-				appError = NewAppError(DBTransactionRollback.Message, DBTransactionRollback.HTTPStatusCode, err)
-				apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-				ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
-
-			case pgerrcode.IsSyntaxErrororAccessRuleViolation(pgErr.Code):
-				appError = NewAppError(DBSyntaxErrororAccessRuleViolation.Message, DBSyntaxErrororAccessRuleViolation.HTTPStatusCode, err)
-				apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-				ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
-
-			case pgerrcode.IsInsufficientResources(pgErr.Code):
-				appError = NewAppError(DBInsufficientResources.Message, DBInsufficientResources.HTTPStatusCode, err)
-				apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-				ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
-
-			// Catch any other PostgreSQL-related errors with a generic message.
-			default:
-				appError = NewAppError(DBGenericError.Message, DBGenericError.HTTPStatusCode, err)
-				apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-				ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
-			}
-		} else {
-			// Handle non-database-related errors or unknown errors.
-			appError = NewAppError(err.Error(), http.StatusInternalServerError, err)
-			apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-			ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+	if _, isPgErr := Find[*pgconn.PgError](err); isPgErr || !isAppErr {
+		apiErrorResponse := checkDBError(err)
+		if isAppErr {
+			apiErrorResponse.AppError.Message = appErr.Message
+			apiErrorResponse.AppError.ID = appErr.ID
 		}
+		return apiErrorResponse
 	}
+
+	return NewHTTPAPIErrorResponse(mapErrorToHTTP(appErr.Code), *appErr)
 }
 
 // HandleError handles errors by creating an application error and an API error response,
@@ -318,7 +271,7 @@ func HandleError(ctx *gin.Context, err error) {
 	if appErr, ok := Find[*AppError](err); ok {
 		// Create a structured HTTP response using the AppError.
 		apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorServerError, *appErr)
-		ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+		writeErrorResponse(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 		return
 	}
 
@@ -326,7 +279,7 @@ func HandleError(ctx *gin.Context, err error) {
 	// Here you can log the error if needed.
 	appError := NewAppError(err.Error(), http.StatusInternalServerError, err)
 	apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-	ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+	writeErrorResponse(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 }
 
 // HandleErrorWithCustomMessage handles an error by creating a custom application error
@@ -352,13 +305,13 @@ func HandleErrorWithCustomMessage(ctx *gin.Context, message string, err error) {
 	if appErr, ok := Find[*AppError](err); ok {
 		// Create a structured HTTP response using the AppError.
 		apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorServerError, *appErr)
-		ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+		writeErrorResponse(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 		return
 	}
 
 	appError := NewAppError(message, http.StatusInternalServerError, err)
 	apiErrorResponse := NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-	ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+	writeErrorResponse(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 }
 
 // HandleWithMessage handles an error by creating an application error with a given message,
@@ -432,6 +385,19 @@ func ValidateContentType(allowedTypes []string) gin.HandlerFunc {
 	}
 }
 
+// HandleNotAcceptableError handles requests whose Accept header does not allow any of the
+// supported response formats. The error response itself is sent as JSON.
+//
+// Parameters:
+//   - ctx: The Gin context for the current request.
+//   - supportedTypes: The response media types supported by the server.
+//
+// Returns:
+//   - HTTP 406 Not Acceptable
+func HandleNotAcceptableError(ctx *gin.Context, supportedTypes []string) {
+	respondWithError(ctx, HTTPErrorNotAcceptable, fmt.Sprintf("Supported response types are: %v", supportedTypes), nil)
+}
+
 // HandleSizeError handles errors related to payload size exceeding the allowed limit.
 // It creates a new application error with a "Payload too large" message and a "413" status code.
 // The function then constructs an HTTP API error response and sends it as a JSON response.
@@ -446,9 +412,29 @@ func HandleSizeError(ctx *gin.Context) {
 	respondWithError(ctx, FileErrorTooLarge, "Payload too large.", nil)
 }
 
+// HandleFileError responds to a rejected file upload with the given status, typically
+// FileErrorTooLarge, FileErrorUnsupportedType or AppErrorValidationError, the field errors
+// identifying the offending files.
+//
+// Parameters:
+//   - ctx: The Gin context for the current request.
+//   - statusCodeAndMessage: The status of the response.
+//   - message: The error message.
+//   - fieldErrors: The files rejected.
+//
+// Returns:
+//
+//	The status of statusCodeAndMessage
+func HandleFileError(ctx *gin.Context, statusCodeAndMessage statusCodeAndMessage, message string, fieldErrors []FieldError) {
+	appError := NewAppError(message, statusCodeAndMessage.StatusCode, errors.New(message))
+	appError.SetFieldErrors(fieldErrors)
+	apiErrorResponse := NewHTTPAPIErrorResponse(statusCodeAndMessage, appError)
+	writeErrorResponse(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
+}
+
 // HandleRateLimitingError handles rate limiting errors by creating an application error
-// with a "Too many requests" message and a 429 status code. It then constructs an HTTP
-// API error response and sends it as a JSON response with the appropriate status code.
+// with a "Too many requests" message, the RATE_LIMITED id and a 429 status code. It then
+// constructs an HTTP API error response and sends it with the shortest Retry-After.
 //
 // Parameters:
 // - ctx: The Gin context for the current request.
@@ -456,7 +442,38 @@ func HandleSizeError(ctx *gin.Context) {
 //
 //	HTTP 429 Too Many Requests
 func HandleRateLimitingError(ctx *gin.Context) {
-	respondWithError(ctx, HTTPErrorTooManyRequests, "Too many requests. Please try again later.", nil)
+	HandleBackpressureError(ctx, NewBackpressureError(SourceRateLimited, time.Time{}, "Too many requests. Please try again later.", nil))
+}
+
+// ErrorIDConcurrencyLimit is the error id of the requests rejected by HandleConcurrencyLimitError,
+// telling them apart from the rate limited ones
+const ErrorIDConcurrencyLimit = SourceConcurrencyLimit
+
+// HandleConcurrencyLimitError handles the requests rejected because too many requests are in
+// flight, by creating an application error with the CONCURRENCY_LIMIT id and a 429 status code.
+//
+// Parameters:
+//   - ctx: The Gin context for the current request.
+//
+// Returns:
+//   - HTTP 429 Too Many Requests
+func HandleConcurrencyLimitError(ctx *gin.Context) {
+	HandleBackpressureError(ctx, NewBackpressureError(SourceConcurrencyLimit, time.Time{}, "Too many concurrent requests. Please retry once the pending requests complete.", nil))
+}
+
+// HandleBackpressureError responds to a request rejected by a limiting feature with the
+// backpressure error err built by NewBackpressureError, its wait in the Retry-After header and
+// the retry_after_seconds of the body.
+//
+// Parameters:
+//   - ctx: The Gin context for the current request.
+//   - err: The backpressure error of the request.
+//
+// Returns:
+//   - HTTP 429 Too Many Requests, or 503 Service Unavailable
+func HandleBackpressureError(ctx *gin.Context, err *AppError) {
+	apiErrorResponse := NewHTTPAPIErrorResponse(mapErrorToHTTP(err.Code), *err)
+	writeErrorResponse(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 }
 
 // HandleDuplicateEntryError handles errors related to duplicate entries in the application.
@@ -594,6 +611,34 @@ func HandleGatewayTimeoutError(ctx *gin.Context) {
 	respondWithError(ctx, HTTPErrorGatewayTimeout, "Server/Gateway timeout occurred.", nil)
 }
 
+// HandleGatewayTimeoutErrorWithMessage responds like HandleGatewayTimeoutError with a message
+// naming the upstream that timed out and the original error.
+//
+// Parameters:
+//   - ctx: The Gin context for the current request.
+//   - message: The message of the application error.
+//   - err: The timeout of the upstream call.
+//
+// Returns:
+//   - HTTP 504 Gateway Timeout
+func HandleGatewayTimeoutErrorWithMessage(ctx *gin.Context, message string, err error) {
+	respondWithError(ctx, HTTPErrorGatewayTimeout, message, err)
+}
+
+// HandleServiceUnavailableErrorWithMessage responds with 503 and a message naming the upstream
+// that is unavailable, for the failures the client should retry later.
+//
+// Parameters:
+//   - ctx: The Gin context for the current request.
+//   - message: The message of the application error.
+//   - err: The failure of the upstream call.
+//
+// Returns:
+//   - HTTP 503 Service Unavailable
+func HandleServiceUnavailableErrorWithMessage(ctx *gin.Context, message string, err error) {
+	respondWithError(ctx, HTTPErrorServiceUnavailable, message, err)
+}
+
 // HandleBulkErrors processes a slice of AppError and sends a JSON response with the appropriate HTTP status code.
 //
 // Parameters:
@@ -605,7 +650,7 @@ func HandleGatewayTimeoutError(ctx *gin.Context) {
 //   - The status code may vary if different error mapping logic is used in the implementation.
 func HandleBulkErrors(ctx *gin.Context, err []AppError) {
 	apiErrorResponse := NewHTTPAPIBulkErrorResponse(HTTPErrorBadRequest, err)
-	ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+	writeErrorResponse(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 }
 
 // HandleErrorWithStatusCodeAndMessage handles an error by creating an AppError and an HTTPAPIErrorResponse,
@@ -630,15 +675,20 @@ func checkDBError(err error) APIErrorResponse {
 
 	// Handle specific PostgreSQL error types using a switch statement.
 	switch {
+	case Is(err, context.Canceled):
+		// the client closed the connection, nobody reads the response
+		appError = NewAppError("The client closed the request before it was answered", HTTPErrorClientClosedRequest.StatusCode, err)
+		apiErrorResponse = NewHTTPAPIErrorResponse(HTTPErrorClientClosedRequest, appError)
+
 	case Is(err, context.DeadlineExceeded):
 		appError = NewAppError(DBConnectionException.Message, DBConnectionException.HTTPStatusCode, err)
 		apiErrorResponse = NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-		// ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+		// response.Respond(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 
 	case Is(err, pgx.ErrNoRows):
 		appError = NewAppError(DBNoData.Message, DBNoData.HTTPStatusCode, err)
 		apiErrorResponse = NewHTTPAPIErrorResponse(DBErrorRecordNotFound, appError)
-		// ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+		// response.Respond(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 
 	default:
 		// Check if the error is a PostgreSQL error.
@@ -649,69 +699,69 @@ func checkDBError(err error) APIErrorResponse {
 			case pgErr.Code == "42P01": // SQLSTATE for "relation does not exist"
 				appError = NewAppError(DBSyntaxErrororAccessRuleViolation.Message, DBSyntaxErrororAccessRuleViolation.HTTPStatusCode, err)
 				apiErrorResponse = NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-				// ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+				// response.Respond(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 
 			case pgerrcode.IsCardinalityViolation(pgErr.Code):
 				appError = NewAppError(DBCardinalityViolation.Message, DBCardinalityViolation.HTTPStatusCode, err)
 				apiErrorResponse = NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-				// ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+				// response.Respond(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 
 			case pgerrcode.IsWarning(pgErr.Code):
 				appError = NewAppError(DBWarning.Message, DBWarning.HTTPStatusCode, err)
 				apiErrorResponse = NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-				// ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+				// response.Respond(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 
 			case pgerrcode.IsNoData(pgErr.Code):
 				appError = NewAppError(DBNoData.Message, DBNoData.HTTPStatusCode, err)
 				apiErrorResponse = NewHTTPAPIErrorResponse(DBErrorRecordNotFound, appError)
-				// ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+				// response.Respond(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 
 			case pgerrcode.IsIntegrityConstraintViolation(pgErr.Code):
 				appError = NewAppError(DBIntegrityConstraintViolation.Message, DBIntegrityConstraintViolation.HTTPStatusCode, err)
 				apiErrorResponse = NewHTTPAPIErrorResponse(DBErrorDuplicateRecord, appError)
-				// ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+				// response.Respond(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 
 			case pgerrcode.IsSQLStatementNotYetComplete(pgErr.Code):
 				appError = NewAppError(DBSQLStatementNotYetComplete.Message, DBSQLStatementNotYetComplete.HTTPStatusCode, err)
 				apiErrorResponse = NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-				// ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+				// response.Respond(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 
 			case pgerrcode.IsConnectionException(pgErr.Code):
 				appError = NewAppError(DBConnectionException.Message, DBConnectionException.HTTPStatusCode, err)
 				apiErrorResponse = NewHTTPAPIErrorResponse(HTTPErrorServiceUnavailable, appError)
-				// ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+				// response.Respond(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 
 			case pgerrcode.IsDataException(pgErr.Code):
 				appError = NewAppError(DBDataException.Message, DBDataException.HTTPStatusCode, err)
 				apiErrorResponse = NewHTTPAPIErrorResponse(HTTPErrorBadRequest, appError)
-				// ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+				// response.Respond(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 
 			case pgerrcode.IsTransactionRollback(pgErr.Code):
 				appError = NewAppError(DBTransactionRollback.Message, DBTransactionRollback.HTTPStatusCode, err)
 				apiErrorResponse = NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-				// ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+				// response.Respond(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 
 			case pgerrcode.IsSyntaxErrororAccessRuleViolation(pgErr.Code):
 				appError = NewAppError(DBSyntaxErrororAccessRuleViolation.Message, DBSyntaxErrororAccessRuleViolation.HTTPStatusCode, err)
 				apiErrorResponse = NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-				// ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+				// response.Respond(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 
 			case pgerrcode.IsInsufficientResources(pgErr.Code):
 				appError = NewAppError(DBInsufficientResources.Message, DBInsufficientResources.HTTPStatusCode, err)
 				apiErrorResponse = NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-				// ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+				// response.Respond(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 
 			// Catch any other PostgreSQL-related errors with a generic message.
 			default:
 				appError = NewAppError(DBGenericError.Message, DBGenericError.HTTPStatusCode, err)
 				apiErrorResponse = NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-				// ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+				// response.Respond(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 			}
 		} else {
 			// Handle non-database-related errors or unknown errors.
 			appError = NewAppError(HTTPErrorServerError.Message, http.StatusInternalServerError, err)
 			apiErrorResponse = NewHTTPAPIErrorResponse(HTTPErrorServerError, appError)
-			// ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+			// response.Respond(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 		}
 	}
 
@@ -719,28 +769,14 @@ func checkDBError(err error) APIErrorResponse {
 
 }
 
+// HandleCommonError handles any error of a handler, classified as by HandleDBError.
 func HandleCommonError(ctx *gin.Context, err error) {
 	if err == nil {
 		return
 	}
 
-	// Check if the error is of type AppError.
-	if appErr, ok := Find[*AppError](err); ok {
-		if len(appErr.FieldErrors) > 0 {
-			HandleValidationError(ctx, err)
-			return
-		}
-
-		statusCode := appErr.Code
-		statusCodeAndMessage := mapErrorToHTTP(statusCode)
-
-		apiErrorResponse := NewHTTPAPIErrorResponse(statusCodeAndMessage, *appErr)
-		ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
-		return
-	}
-
-	apiErrorResponse := checkDBError(err)
-	ctx.JSON(apiErrorResponse.StatusCode, apiErrorResponse)
+	apiErrorResponse := classifyError(err)
+	writeErrorResponse(ctx, apiErrorResponse.StatusCode, apiErrorResponse)
 }
 
 // ErrorResponseWithStatusCodeAndMessage handles an error by creating an AppError and an HTTPAPIErrorResponse,
@@ -0,0 +1,254 @@
+package apierrors
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type fieldErrorTestRequest struct {
+	MobileNumber string `json:"mobile_number" validate:"required"`
+	Priority     int    `json:"priority" validate:"min=1,max=4"`
+}
+
+// validationErrors returns the validator errors for an invalid fieldErrorTestRequest
+func validationErrors(t *testing.T) validator.ValidationErrors {
+	t.Helper()
+	err := validator.New().Struct(fieldErrorTestRequest{Priority: 7})
+	ve, ok := Find[validator.ValidationErrors](err)
+	if !ok {
+		t.Fatalf("expected validator.ValidationErrors, got %v", err)
+	}
+	return ve
+}
+
+// serveError runs the handler against a test context and decodes the error response
+func serveError(t *testing.T, handle func(*gin.Context, error), err error) (int, APIErrorResponse) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+
+	handle(ctx, err)
+
+	var resp APIErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return w.Code, resp
+}
+
+// assertFieldErrors checks that every failing field is reported with field, value, message and tag
+func assertFieldErrors(t *testing.T, body []FieldError) {
+	t.Helper()
+	want := map[string]string{"MobileNumber": "required", "Priority": "max"}
+	if len(body) != len(want) {
+		t.Fatalf("expected %d field errors, got %d: %+v", len(want), len(body), body)
+	}
+	for _, fe := range body {
+		tag, ok := want[fe.Field]
+		if !ok {
+			t.Errorf("unexpected field error for %q", fe.Field)
+			continue
+		}
+		if fe.Tag != tag {
+			t.Errorf("field %q: expected tag %q, got %q", fe.Field, tag, fe.Tag)
+		}
+		if fe.Message == "" {
+			t.Errorf("field %q: expected a message", fe.Field)
+		}
+	}
+}
+
+// TestFieldErrorJSON tests that the field error payload exposes all fields
+func TestFieldErrorJSON(t *testing.T) {
+	appErr := NewAppError("validation failed", http.StatusUnprocessableEntity, nil)
+	data, err := json.Marshal(appErr.NewFieldError("priority", 7, "invalid", "max"))
+	if err != nil {
+		t.Fatalf("failed to marshal field error: %v", err)
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("failed to unmarshal field error: %v", err)
+	}
+	for _, key := range []string{"field", "value", "message", "tag"} {
+		if _, ok := payload[key]; !ok {
+			t.Errorf("expected key %q in %s", key, data)
+		}
+	}
+}
+
+// TestHandleBindingError_FieldErrors tests field errors on the binding path
+func TestHandleBindingError_FieldErrors(t *testing.T) {
+	code, resp := serveError(t, HandleBindingError, validationErrors(t))
+
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, code)
+	}
+	assertFieldErrors(t, resp.AppError.FieldErrors)
+}
+
+// TestHandleValidationError_FieldErrors tests that the validation path produces the
+// same field errors regardless of how the error reaches the handler
+func TestHandleValidationError_FieldErrors(t *testing.T) {
+	ve := validationErrors(t)
+
+	appErr := NewAppError("validation failed", http.StatusUnprocessableEntity, ve)
+	appErr.SetFieldErrors(newValidationFieldErrors(&appErr, ve))
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "raw validation errors", err: ve},
+		{name: "wrapped validation errors", err: fmt.Errorf("validate: %w", ve)},
+		{name: "app error without field errors", err: &AppError{Code: http.StatusUnprocessableEntity, Message: "validation failed", OriginalError: ve}},
+		{name: "app error with field errors", err: &appErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := serveError(t, HandleValidationError, tt.err)
+
+			if code != http.StatusUnprocessableEntity {
+				t.Fatalf("expected status %d, got %d", http.StatusUnprocessableEntity, code)
+			}
+			assertFieldErrors(t, resp.AppError.FieldErrors)
+		})
+	}
+}
+
+// TestHandleValidationError_NoFieldErrors tests that errors without failing fields omit field errors
+func TestHandleValidationError_NoFieldErrors(t *testing.T) {
+	code, resp := serveError(t, HandleValidationError, fmt.Errorf("invalid request"))
+
+	if code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d", http.StatusUnprocessableEntity, code)
+	}
+	if len(resp.AppError.FieldErrors) != 0 {
+		t.Errorf("expected no field errors, got %+v", resp.AppError.FieldErrors)
+	}
+}
+
+// TestHandleValidationError_XML tests that error envelopes follow the negotiated response format
+func TestHandleValidationError_XML(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	ctx.Request.Header.Set("Accept", "application/xml")
+
+	HandleValidationError(ctx, validationErrors(t))
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/xml") {
+		t.Fatalf("expected XML content type, got %q", ct)
+	}
+
+	var envelope struct {
+		XMLName    xml.Name `xml:"response"`
+		StatusCode int      `xml:"status_code"`
+		Success    bool     `xml:"success"`
+		Error      struct {
+			Code        int `xml:"code"`
+			FieldErrors []struct {
+				Field string `xml:"field"`
+				Tag   string `xml:"tag"`
+			} `xml:"field_errors>item"`
+		} `xml:"error"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode XML response: %v\n%s", err, w.Body.String())
+	}
+	if envelope.StatusCode != http.StatusUnprocessableEntity || envelope.Success {
+		t.Errorf("unexpected envelope: %+v", envelope)
+	}
+	if len(envelope.Error.FieldErrors) != 2 {
+		t.Errorf("expected 2 field errors, got %+v", envelope.Error.FieldErrors)
+	}
+}
+
+// TestHandleNotAcceptableError tests the not acceptable error response
+func TestHandleNotAcceptableError(t *testing.T) {
+	code, resp := serveError(t, func(ctx *gin.Context, _ error) {
+		HandleNotAcceptableError(ctx, []string{"application/json", "application/xml"})
+	}, nil)
+
+	if code != http.StatusNotAcceptable {
+		t.Fatalf("expected status %d, got %d", http.StatusNotAcceptable, code)
+	}
+	if resp.AppError.Code != http.StatusNotAcceptable {
+		t.Errorf("expected error code %d, got %d", http.StatusNotAcceptable, resp.AppError.Code)
+	}
+}
+
+// TestClassifyError_NestingShapes tests that HandleDBError and HandleCommonError classify an
+// error by the most specific error of its tree, however it is wrapped or joined
+func TestClassifyError_NestingShapes(t *testing.T) {
+	uniqueViolation := &pgconn.PgError{Code: pgerrcode.UniqueViolation, Message: "duplicate key value violates unique constraint"}
+	invalidText := &pgconn.PgError{Code: pgerrcode.InvalidTextRepresentation, Message: "invalid input syntax for type bigint"}
+	appErr := func(code int, message string, cause error) error {
+		appError := NewAppError(message, code, cause)
+		return &appError
+	}
+
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantMessage string
+		wantFields  bool
+	}{
+		{"PgError", uniqueViolation, http.StatusConflict, DBIntegrityConstraintViolation.Message, false},
+		{"AppError(PgError)", appErr(http.StatusBadRequest, "template already exists", uniqueViolation), http.StatusConflict, "template already exists", false},
+		{"%w(%w(AppError(PgError)))", fmt.Errorf("create: %w", fmt.Errorf("insert: %w", appErr(http.StatusBadRequest, "template already exists", uniqueViolation))), http.StatusConflict, "template already exists", false},
+		{"AppError(%w(PgError))", appErr(http.StatusInternalServerError, "invalid template id", fmt.Errorf("select: %w", invalidText)), http.StatusBadRequest, "invalid template id", false},
+		{"AppError(AppError(PgError))", appErr(http.StatusBadRequest, "outer", appErr(http.StatusBadRequest, "inner", uniqueViolation)), http.StatusConflict, "outer", false},
+		{"Join(ValidationErrors, PgError)", errors.Join(validationErrors(t), uniqueViolation), http.StatusUnprocessableEntity, "", true},
+		{"Join(error, %w(PgError))", errors.Join(errors.New("audit failed"), fmt.Errorf("insert: %w", uniqueViolation)), http.StatusConflict, DBIntegrityConstraintViolation.Message, false},
+		{"AppError(ValidationErrors)", appErr(http.StatusUnprocessableEntity, "validation failed", validationErrors(t)), http.StatusUnprocessableEntity, "validation failed", true},
+		{"AppError(error)", appErr(http.StatusForbidden, "not allowed", errors.New("denied")), http.StatusForbidden, "not allowed", false},
+		{"AppError(ErrNoRows)", appErr(http.StatusNotFound, "template not found", pgx.ErrNoRows), http.StatusNotFound, "template not found", false},
+		{"%w(ErrNoRows)", fmt.Errorf("select: %w", pgx.ErrNoRows), http.StatusNotFound, DBNoData.Message, false},
+		{"Join(error, DeadlineExceeded)", errors.Join(errors.New("select"), context.DeadlineExceeded), http.StatusInternalServerError, DBConnectionException.Message, false},
+		{"%w(Canceled)", fmt.Errorf("select: %w", context.Canceled), HTTPErrorClientClosedRequest.StatusCode, "The client closed the request before it was answered", false},
+		{"error", errors.New("connection reset by peer"), http.StatusInternalServerError, HTTPErrorServerError.Message, false},
+	}
+
+	handlers := map[string]func(*gin.Context, error){"HandleDBError": HandleDBError, "HandleCommonError": HandleCommonError}
+	for _, tt := range tests {
+		for name, handle := range handlers {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				code, resp := serveError(t, handle, tt.err)
+
+				if code != tt.wantStatus || resp.StatusCode != tt.wantStatus {
+					t.Fatalf("expected status %d, got %d (body %d)", tt.wantStatus, code, resp.StatusCode)
+				}
+				if tt.wantMessage != "" && resp.AppError.Message != tt.wantMessage {
+					t.Errorf("expected message %q, got %q", tt.wantMessage, resp.AppError.Message)
+				}
+				if tt.wantFields {
+					assertFieldErrors(t, resp.AppError.FieldErrors)
+				} else if len(resp.AppError.FieldErrors) != 0 {
+					t.Errorf("expected no field errors, got %+v", resp.AppError.FieldErrors)
+				}
+			})
+		}
+	}
+}
@@ -15,16 +15,20 @@ type AppError struct {
 	FieldErrors   []FieldError `json:"field_errors,omitempty"`
 	Stack         *stackTrace  `json:"-"`
 	OriginalError error        `json:"-"`
+
+	// RetryAfterSeconds is the wait of a request rejected by a limiting feature, also sent in the
+	// Retry-After header, see NewBackpressureError
+	RetryAfterSeconds int64 `json:"retry_after_seconds,omitempty"`
 }
 
 // FieldError represents an error related to a specific field in a request or response.
 // It contains information about the field name, the value that caused the error,
-// and a message describing the error.
+// a message describing the error, and the validation tag that failed.
 type FieldError struct {
 	Field   string      `json:"field"`
-	Tag     string      `json:"-"`
 	Value   interface{} `json:"value"`
 	Message string      `json:"message"`
+	Tag     string      `json:"tag"`
 }
 
 // NewAppError creates a new instance of AppError with the provided message, code, and original error.
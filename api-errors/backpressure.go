@@ -0,0 +1,86 @@
+package apierrors
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HeaderRetryAfter carries the seconds a client rejected by a limiting feature waits before
+// retrying
+const HeaderRetryAfter = "Retry-After"
+
+// Sources of backpressure, the limiting features rejecting requests with 429 or 503. The source
+// is the error id of the response, so that the clients can back off per source.
+const (
+	// SourceRateLimited is the rate limit of a resource of the caller, such as the OTPs generated
+	// for a mobile number
+	SourceRateLimited = "RATE_LIMITED"
+	// SourceTrafficShaped is the traffic shaping of all the requests of the server
+	SourceTrafficShaped = "TRAFFIC_SHAPED"
+	// SourceConcurrencyLimit is the limit of the requests in flight
+	SourceConcurrencyLimit = "CONCURRENCY_LIMIT"
+	// SourceConnectionLimit is the limit of the open connections, answered with 503
+	SourceConnectionLimit = "CONNECTION_LIMIT"
+	// SourceSendQuotaExceeded is the daily send quota of an application
+	SourceSendQuotaExceeded = "SEND_QUOTA_EXCEEDED"
+	// SourceSenderThrottled and SourceTemplateThrottled are the hourly send caps of a sender ID
+	// and of a template
+	SourceSenderThrottled   = "SENDER_THROTTLED"
+	SourceTemplateThrottled = "TEMPLATE_THROTTLED"
+	// SourceGatewayRateLimited is the rate limit of an SMS gateway, whose sends are held back
+	SourceGatewayRateLimited = "GATEWAY_RATE_LIMITED"
+	// SourceGatewayMaintenance is the maintenance of an SMS gateway, answered with 503
+	SourceGatewayMaintenance = "GATEWAY_MAINTENANCE"
+)
+
+// unavailableSources are the sources answered with 503, the others with 429
+var unavailableSources = map[string]bool{
+	SourceConnectionLimit:    true,
+	SourceGatewayMaintenance: true,
+}
+
+// Bounds of the wait told to a client: at least minBackpressureWait, plus a random jitter of up
+// to a tenth of the wait, at least minBackpressureJitter and at most maxBackpressureJitter, so
+// that the clients rejected together do not all retry at the same instant
+const (
+	minBackpressureWait   = time.Second
+	backpressureJitterPct = 10
+	minBackpressureJitter = time.Second
+	maxBackpressureJitter = 30 * time.Second
+)
+
+// NewBackpressureError returns the error of a request rejected by the limiting feature source
+// until resetAt, a zero or past resetAt asking for the shortest wait. Its id is source, its code
+// 429, or 503 for the sources meaning the service is unavailable, and its RetryAfterSeconds the
+// wait until resetAt rounded up to the second plus a bounded random jitter, never less. Every
+// 429 and 503 of a limiting feature is built by it: the writers of the error responses send
+// RetryAfterSeconds in the Retry-After header as in the body.
+func NewBackpressureError(source string, resetAt time.Time, message string, err error) *AppError {
+	code := http.StatusTooManyRequests
+	if unavailableSources[source] {
+		code = http.StatusServiceUnavailable
+	}
+	appError := NewAppErrorWithId(message, code, err, source)
+	appError.RetryAfterSeconds = backpressureSeconds(time.Until(resetAt))
+	return &appError
+}
+
+// backpressureSeconds returns the whole seconds a client waits before retrying after wait, with
+// a random jitter
+func backpressureSeconds(wait time.Duration) int64 {
+	wait = max(wait, minBackpressureWait)
+	jitter := min(max(wait*backpressureJitterPct/100, minBackpressureJitter), maxBackpressureJitter)
+	return ceilSeconds(wait) + rand.Int64N(ceilSeconds(jitter)+1)
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+// BackpressureResponse returns the API error response of the backpressure error err with the
+// value of its Retry-After header, for the writers outside gin
+func BackpressureResponse(err *AppError) (APIErrorResponse, string) {
+	return NewHTTPAPIErrorResponse(mapErrorToHTTP(err.Code), *err), strconv.FormatInt(err.RetryAfterSeconds, 10)
+}
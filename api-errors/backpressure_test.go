@@ -0,0 +1,105 @@
+package apierrors
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBackpressureJitterBounds(t *testing.T) {
+	cases := []struct {
+		name     string
+		wait     time.Duration
+		min, max int64
+	}{
+		// the shortest wait for the sources without a reset time, and the past resets
+		{name: "no reset", wait: 0, min: 1, max: 2},
+		{name: "past reset", wait: -time.Minute, min: 1, max: 2},
+		// never earlier than the reset, rounded up to the second
+		{name: "sub-second wait", wait: 300 * time.Millisecond, min: 1, max: 2},
+		// a tenth of the wait
+		{name: "two minutes", wait: 2 * time.Minute, min: 120, max: 132},
+		// at most maxBackpressureJitter
+		{name: "an hour", wait: time.Hour, min: 3600, max: 3630},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := map[int64]bool{}
+			for i := 0; i < 500; i++ {
+				seconds := backpressureSeconds(tt.wait)
+				if seconds < tt.min || seconds > tt.max {
+					t.Fatalf("Retry-After %d outside [%d, %d]", seconds, tt.min, tt.max)
+				}
+				seen[seconds] = true
+			}
+			if len(seen) < 2 {
+				t.Errorf("no jitter: every Retry-After is %v", seen)
+			}
+		})
+	}
+}
+
+func TestBackpressureSources(t *testing.T) {
+	cases := []struct {
+		source string
+		status int
+	}{
+		{SourceRateLimited, http.StatusTooManyRequests},
+		{SourceTrafficShaped, http.StatusTooManyRequests},
+		{SourceConcurrencyLimit, http.StatusTooManyRequests},
+		{SourceConnectionLimit, http.StatusServiceUnavailable},
+		{SourceSendQuotaExceeded, http.StatusTooManyRequests},
+		{SourceSenderThrottled, http.StatusTooManyRequests},
+		{SourceTemplateThrottled, http.StatusTooManyRequests},
+		{SourceGatewayRateLimited, http.StatusTooManyRequests},
+		{SourceGatewayMaintenance, http.StatusServiceUnavailable},
+	}
+	for _, tt := range cases {
+		t.Run(tt.source, func(t *testing.T) {
+			cause := errors.New("limited")
+			w := serveNegotiatedError(t, "", func(ctx *gin.Context) {
+				HandleBackpressureError(ctx, NewBackpressureError(tt.source, time.Now().Add(time.Minute), "limited", cause))
+			})
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+			var body APIErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if body.AppError.ID != tt.source {
+				t.Errorf("expected error id %s, got %s", tt.source, body.AppError.ID)
+			}
+			// the header and the body tell the same wait
+			if got := w.Header().Get(HeaderRetryAfter); got != strconv.FormatInt(body.AppError.RetryAfterSeconds, 10) {
+				t.Errorf("Retry-After %q does not match retry_after_seconds %d", got, body.AppError.RetryAfterSeconds)
+			}
+			if body.AppError.RetryAfterSeconds < 60 || body.AppError.RetryAfterSeconds > 66 {
+				t.Errorf("retry_after_seconds %d outside [60, 66]", body.AppError.RetryAfterSeconds)
+			}
+		})
+	}
+}
+
+// A backpressure error returned by a handler is classified like any AppError, keeping its wait,
+// and a problem details document carries the source and the wait too
+func TestBackpressureErrorReturned(t *testing.T) {
+	w := serveNegotiatedError(t, MediaTypeProblemJSON, func(ctx *gin.Context) {
+		HandleCommonError(ctx, NewBackpressureError(SourceGatewayMaintenance, time.Now().Add(90*time.Second), "SMS Gateway CDAC is under maintenance", nil))
+	})
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", w.Code)
+	}
+	problem := decodeProblem(t, w)
+	if problem.ID != SourceGatewayMaintenance {
+		t.Errorf("expected id %s, got %s", SourceGatewayMaintenance, problem.ID)
+	}
+	if got := w.Header().Get(HeaderRetryAfter); got != strconv.FormatInt(problem.RetryAfterSeconds, 10) || problem.RetryAfterSeconds < 90 {
+		t.Errorf("Retry-After %q, retry_after_seconds %d", got, problem.RetryAfterSeconds)
+	}
+}
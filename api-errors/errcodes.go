@@ -1,6 +1,10 @@
 package apierrors
 
-import "net/http"
+import (
+	"net/http"
+
+	"MgApplication/api-server/response"
+)
 
 // statusCodeAndMessage represents a structured error with an associated HTTP status code and message.
 // This struct is used to standardize error responses across the application.
@@ -26,11 +30,13 @@ var (
 	HTTPErrorForbidden          statusCodeAndMessage = statusCodeAndMessage{StatusCode: http.StatusForbidden, Message: "Forbidden", Success: false}                   // 403 - Client does not have permission to access this resource.
 	HTTPErrorNotFound           statusCodeAndMessage = statusCodeAndMessage{StatusCode: http.StatusNotFound, Message: "Not Found", Success: false}                    // 404 - Requested resource could not be found.
 	HTTPErrorMethodNotAllowed   statusCodeAndMessage = statusCodeAndMessage{StatusCode: http.StatusMethodNotAllowed, Message: "Method Not Allowed", Success: false}   // 405 - HTTP method not supported.
+	HTTPErrorNotAcceptable      statusCodeAndMessage = statusCodeAndMessage{StatusCode: http.StatusNotAcceptable, Message: "Not Acceptable", Success: false}           // 406 - None of the requested response formats is supported.
 	HTTPErrorRequestTimeout     statusCodeAndMessage = statusCodeAndMessage{StatusCode: http.StatusRequestTimeout, Message: "Request Timeout", Success: false}        // 408 - Request took too long.
 	HTTPErrorConflict           statusCodeAndMessage = statusCodeAndMessage{StatusCode: http.StatusConflict, Message: "Conflict", Success: false}                     // 409 - Resource conflict, like duplicate data.
 	HTTPErrorGone               statusCodeAndMessage = statusCodeAndMessage{StatusCode: http.StatusGone, Message: "Gone", Success: false}                             // 410 - Resource is no longer available.
 	HTTPErrorTooManyRequests    statusCodeAndMessage = statusCodeAndMessage{StatusCode: http.StatusTooManyRequests, Message: "Too Many Requests", Success: false}    // 429 - Rate limiting error.
 	HTTPErrorInvalidContentType statusCodeAndMessage = statusCodeAndMessage{StatusCode: http.StatusUnsupportedMediaType, Message: "Invalid Content Type", Success: false} // 415 - Unsupported content type in request.
+	HTTPErrorClientClosedRequest statusCodeAndMessage = statusCodeAndMessage{StatusCode: response.StatusClientClosedRequest, Message: "Client Closed Request", Success: false} // 499 - Client closed the connection before the response, never sent.
 
 	// Server-side errors (500 range).
 	HTTPErrorServerError        statusCodeAndMessage = statusCodeAndMessage{StatusCode: http.StatusInternalServerError, Message: "Internal Server Error", Success: false} // 500 - Generic server error.
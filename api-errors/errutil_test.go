@@ -391,3 +391,30 @@ func TestMultipleWrapping(t *testing.T) {
 		t.Errorf("Find() = %+v, want {msg:base, code:100}", found)
 	}
 }
+
+// TestFind_Joined tests that Find searches joined errors depth-first, returning the first match
+func TestFind_Joined(t *testing.T) {
+	first := &customError{msg: "first", code: 1}
+	second := &customError{msg: "second", code: 2}
+
+	tests := []struct {
+		name string
+		err  error
+		want *customError
+	}{
+		{"joined", errors.Join(errBase, first, second), first},
+		{"wrapped in the first branch", errors.Join(wrapError(wrapError(first, "level2"), "level1"), second), first},
+		{"nested join", errors.Join(errors.Join(errBase, wrapError(first, "level1")), second), first},
+		{"joined and wrapped", wrapError(errors.Join(errSentinel1, second), "level1"), second},
+		{"app error", wrapError(&AppError{Message: "app", OriginalError: errors.Join(errSentinel1, first)}, "level1"), first},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Find[*customError](tt.err)
+			if !ok || got != tt.want {
+				t.Errorf("Find() = %+v, %v, want %+v", got, ok, tt.want)
+			}
+		})
+	}
+}
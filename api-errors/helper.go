@@ -11,8 +11,12 @@ func mapErrorToHTTP(statusCode int) statusCodeAndMessage {
 		return HTTPErrorForbidden
 	case 404:
 		return HTTPErrorNotFound
+	case 406:
+		return HTTPErrorNotAcceptable
 	case 409:
 		return HTTPErrorConflict
+	case 410:
+		return HTTPErrorGone
 	case 422:
 		return AppErrorValidationError
 	case 429:
@@ -21,6 +25,8 @@ func mapErrorToHTTP(statusCode int) statusCodeAndMessage {
 		return HTTPErrorServerError
 	case 501:
 		return HTTPErrorNotImplemented
+	case 502:
+		return HTTPErrorBadGateway
 	case 503:
 		return HTTPErrorServiceUnavailable
 	case 504:
@@ -0,0 +1,161 @@
+package apierrors
+
+import (
+	"encoding/json"
+	"mime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"MgApplication/api-server/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MediaTypeProblemJSON is the media type of the RFC 7807 problem details documents
+const MediaTypeProblemJSON = "application/problem+json"
+
+// ProblemDetails is the RFC 7807 problem details document of an API error response, sent
+// instead of APIErrorResponse to the clients accepting application/problem+json
+type ProblemDetails struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// InvalidParams is the extension member of the field errors of the request
+	InvalidParams []InvalidParam `json:"invalid-params,omitempty"`
+	// ID and RetryAfterSeconds are the extension members of the error id and of the wait of a
+	// request rejected by a limiting feature
+	ID                string `json:"id,omitempty"`
+	RetryAfterSeconds int64  `json:"retry_after_seconds,omitempty"`
+}
+
+// InvalidParam is a field error of a problem details document
+type InvalidParam struct {
+	Name   string      `json:"name"`
+	Reason string      `json:"reason"`
+	Value  interface{} `json:"value,omitempty"`
+	Tag    string      `json:"tag,omitempty"`
+}
+
+var (
+	// problemTypeBase is the base URI of the problem types, access through
+	// SetProblemTypeBase and problemType
+	problemTypeBase string
+	problemTypeMu   sync.RWMutex
+)
+
+// SetProblemTypeBase sets the base URI of the type of the problem details documents, followed by
+// the code of their error, e.g. https://errors.example.gov.in/mgapp/422. Without a base the type
+// is about:blank, the problem being described by its status.
+func SetProblemTypeBase(base string) {
+	problemTypeMu.Lock()
+	defer problemTypeMu.Unlock()
+	problemTypeBase = strings.TrimSuffix(base, "/")
+}
+
+// problemType returns the type URI of the problems of code
+func problemType(code int) string {
+	problemTypeMu.RLock()
+	defer problemTypeMu.RUnlock()
+	if problemTypeBase == "" {
+		return "about:blank"
+	}
+	return problemTypeBase + "/" + strconv.Itoa(code)
+}
+
+// NewProblemDetails transforms the API error response r into the problem details document of
+// the occurrence instance
+func NewProblemDetails(r APIErrorResponse, instance string) ProblemDetails {
+	code := r.AppError.Code
+	if code == 0 {
+		code = r.StatusCode
+	}
+	return ProblemDetails{
+		Type:              problemType(code),
+		Title:             r.Message,
+		Status:            r.StatusCode,
+		Detail:            r.AppError.Message,
+		Instance:          instance,
+		InvalidParams:     newInvalidParams(r.AppError.FieldErrors),
+		ID:                r.AppError.ID,
+		RetryAfterSeconds: r.AppError.RetryAfterSeconds,
+	}
+}
+
+// newBulkProblemDetails transforms the bulk API error response r into the problem details
+// document of the occurrence instance, carrying the field errors of every error
+func newBulkProblemDetails(r APIBulkErrorResponse, instance string) ProblemDetails {
+	problem := ProblemDetails{
+		Type:     problemType(r.StatusCode),
+		Title:    r.Message,
+		Status:   r.StatusCode,
+		Instance: instance,
+	}
+	details := make([]string, 0, len(r.Errors))
+	for _, appErr := range r.Errors {
+		details = append(details, appErr.Message)
+		problem.InvalidParams = append(problem.InvalidParams, newInvalidParams(appErr.FieldErrors)...)
+	}
+	problem.Detail = strings.Join(details, "; ")
+	return problem
+}
+
+func newInvalidParams(fieldErrors []FieldError) []InvalidParam {
+	if len(fieldErrors) == 0 {
+		return nil
+	}
+	params := make([]InvalidParam, 0, len(fieldErrors))
+	for _, fe := range fieldErrors {
+		params = append(params, InvalidParam{Name: fe.Field, Reason: fe.Message, Value: fe.Value, Tag: fe.Tag})
+	}
+	return params
+}
+
+// acceptsProblemJSON reports whether the Accept header of the request lists
+// application/problem+json with a non zero quality
+func acceptsProblemJSON(ctx *gin.Context) bool {
+	for _, accepted := range strings.Split(ctx.GetHeader("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil || mediaType != MediaTypeProblemJSON {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// problemInstance identifies the occurrence of a problem by the request path and its
+// correlation ID
+func problemInstance(ctx *gin.Context) string {
+	return ctx.Request.URL.Path + "#" + response.CorrelationID(ctx)
+}
+
+// writeErrorResponse writes the API error response payload, an APIErrorResponse or an
+// APIBulkErrorResponse, as a problem details document when the request accepts
+// application/problem+json, and in the format negotiated by response.Respond otherwise. Every
+// Handle* helper writes its response through it. The wait of a request rejected by a limiting
+// feature is sent in the Retry-After header.
+func writeErrorResponse(ctx *gin.Context, status int, payload any) {
+	if r, ok := payload.(APIErrorResponse); ok && r.AppError.RetryAfterSeconds > 0 {
+		ctx.Header(HeaderRetryAfter, strconv.FormatInt(r.AppError.RetryAfterSeconds, 10))
+	}
+	if acceptsProblemJSON(ctx) {
+		var problem ProblemDetails
+		switch r := payload.(type) {
+		case APIErrorResponse:
+			problem = NewProblemDetails(r, problemInstance(ctx))
+		case APIBulkErrorResponse:
+			problem = newBulkProblemDetails(r, problemInstance(ctx))
+		}
+		if data, err := json.Marshal(problem); err == nil {
+			ctx.Data(status, MediaTypeProblemJSON, data)
+			return
+		}
+	}
+	response.Respond(ctx, status, payload)
+}
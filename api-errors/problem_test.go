@@ -0,0 +1,184 @@
+package apierrors
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// serveNegotiatedError runs handle for a GET /v1/sms-templates/7 request with the given Accept
+// header and correlation ID
+func serveNegotiatedError(t *testing.T, accept string, handle func(*gin.Context)) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/v1/sms-templates/7", nil)
+	ctx.Request.Header.Set("X-Request-Id", "req-1")
+	if accept != "" {
+		ctx.Request.Header.Set("Accept", accept)
+	}
+	handle(ctx)
+	return w
+}
+
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) ProblemDetails {
+	t.Helper()
+	if got := w.Header().Get("Content-Type"); got != MediaTypeProblemJSON {
+		t.Fatalf("expected Content-Type %s, got %q", MediaTypeProblemJSON, got)
+	}
+	var problem ProblemDetails
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("failed to decode problem: %v", err)
+	}
+	return problem
+}
+
+func TestProblemDetailsNegotiation(t *testing.T) {
+	SetProblemTypeBase("https://errors.example.gov.in/mgapp/")
+	defer SetProblemTypeBase("")
+
+	cases := []struct {
+		name   string
+		handle func(*gin.Context)
+		status int
+		title  string
+		detail string
+		params []string
+	}{
+		{
+			name:   "validation error",
+			handle: func(ctx *gin.Context) { HandleValidationError(ctx, validationErrors(t)) },
+			status: http.StatusUnprocessableEntity,
+			title:  "Validation Error",
+			params: []string{"MobileNumber", "Priority"},
+		},
+		{
+			name:   "db error",
+			handle: func(ctx *gin.Context) { HandleDBError(ctx, pgx.ErrTxClosed) },
+			status: http.StatusInternalServerError,
+		},
+		{
+			name:   "not found",
+			handle: func(ctx *gin.Context) { HandleDBError(ctx, pgx.ErrNoRows) },
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "no route",
+			handle: HandleNoRouteError,
+			status: http.StatusNotFound,
+			title:  "Not Found",
+			detail: "The requested path does not exist",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// the default shape is unchanged
+			w := serveNegotiatedError(t, "", tc.handle)
+			if w.Code != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+				t.Errorf("expected a JSON Content-Type, got %q", got)
+			}
+			var resp APIErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.StatusCode != tc.status || resp.AppError.Message == "" {
+				t.Errorf("unexpected default response: %s", w.Body.String())
+			}
+			if strings.Contains(w.Body.String(), `"type"`) {
+				t.Errorf("the default response should not be a problem document: %s", w.Body.String())
+			}
+
+			w = serveNegotiatedError(t, "application/json, application/problem+json", tc.handle)
+			if w.Code != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, w.Code)
+			}
+			problem := decodeProblem(t, w)
+			if problem.Status != tc.status {
+				t.Errorf("expected status member %d, got %d", tc.status, problem.Status)
+			}
+			if problem.Type != "https://errors.example.gov.in/mgapp/"+strconv.Itoa(resp.AppError.Code) {
+				t.Errorf("expected the type of code %d, got %q", resp.AppError.Code, problem.Type)
+			}
+			if problem.Title != resp.Message || (tc.title != "" && problem.Title != tc.title) {
+				t.Errorf("unexpected title %q", problem.Title)
+			}
+			if problem.Detail != resp.AppError.Message || (tc.detail != "" && problem.Detail != tc.detail) {
+				t.Errorf("unexpected detail %q", problem.Detail)
+			}
+			if problem.Instance != "/v1/sms-templates/7#req-1" {
+				t.Errorf("unexpected instance %q", problem.Instance)
+			}
+			var names []string
+			for _, param := range problem.InvalidParams {
+				names = append(names, param.Name)
+				if param.Reason == "" {
+					t.Errorf("invalid param %q without a reason", param.Name)
+				}
+			}
+			if strings.Join(names, ",") != strings.Join(tc.params, ",") {
+				t.Errorf("expected invalid params %v, got %v", tc.params, names)
+			}
+		})
+	}
+}
+
+func TestProblemDetailsAboutBlank(t *testing.T) {
+	w := serveNegotiatedError(t, "application/problem+json", HandleNoRouteError)
+	problem := decodeProblem(t, w)
+	if problem.Type != "about:blank" {
+		t.Errorf("expected about:blank without a type base, got %q", problem.Type)
+	}
+}
+
+func TestAcceptsProblemJSON(t *testing.T) {
+	cases := map[string]bool{
+		"":                         false,
+		"*/*":                      false,
+		"application/json":         false,
+		"application/problem+json": true,
+		"application/json, application/problem+json;q=0.9": true,
+		"application/problem+json;q=0":                     false,
+		"APPLICATION/PROBLEM+JSON":                         true,
+		"application/xml, application/problem+xml":         false,
+	}
+	for accept, want := range cases {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		ctx.Request.Header.Set("Accept", accept)
+		if got := acceptsProblemJSON(ctx); got != want {
+			t.Errorf("acceptsProblemJSON(%q) = %v, want %v", accept, got, want)
+		}
+	}
+}
+
+func TestBulkProblemDetails(t *testing.T) {
+	first := NewAppError("first template invalid", http.StatusBadRequest, nil)
+	first.SetFieldErrors([]FieldError{first.NewFieldError("template_id", "x", "must be numeric", "numeric")})
+	second := NewAppError("second template invalid", http.StatusBadRequest, nil)
+
+	w := serveNegotiatedError(t, "application/problem+json", func(ctx *gin.Context) {
+		HandleBulkErrors(ctx, []AppError{first, second})
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+	problem := decodeProblem(t, w)
+	if problem.Detail != "first template invalid; second template invalid" {
+		t.Errorf("unexpected detail %q", problem.Detail)
+	}
+	if len(problem.InvalidParams) != 1 || problem.InvalidParams[0].Name != "template_id" {
+		t.Errorf("unexpected invalid params %+v", problem.InvalidParams)
+	}
+}
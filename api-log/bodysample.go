@@ -0,0 +1,270 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"mime"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bodySampleCaptureLimit is the largest body captured for redaction. Larger bodies are not logged,
+// they cannot be redacted once cut.
+const bodySampleCaptureLimit = 1 << 20
+
+// redactedValue replaces the redacted values in the logged bodies
+const redactedValue = "[REDACTED]"
+
+// BodySampleConfig configures the body sampling middleware, which logs the full request and
+// response bodies of a fraction of the requests for debugging.
+type BodySampleConfig struct {
+	// Rate is the fraction of requests whose bodies are logged (0.0 to 1.0)
+	// 0.0 = only requests carrying Header, 1.0 = every request
+	Rate float64
+
+	// Header logs the bodies of the requests carrying it with a non-empty value, whatever Rate
+	// (e.g., "X-Debug-Body"). Empty disables it.
+	Header string
+
+	// HeaderAllowed reports whether a request may ask for its bodies to be logged with Header,
+	// e.g. only requests of administrators. If nil, every request may.
+	HeaderAllowed func(c *gin.Context) bool
+
+	// MaxBytes truncates every logged body after redaction
+	// Default: 16384
+	MaxBytes int
+
+	// RedactFields are the names of the JSON fields and of the form and query parameters whose
+	// values are replaced by [REDACTED], matched case-insensitively at any depth.
+	// Bodies other than JSON, forms and plain text are never logged, only their size.
+	RedactFields []string
+
+	// SkipPaths configures the paths whose bodies are never logged
+	SkipPaths *MiddlewareConfig
+
+	// Rand is the random source used for sampling decisions
+	// Can be set to a custom source for deterministic testing
+	// If nil, a default source is created
+	Rand *rand.Rand
+}
+
+// DefaultBodySampleConfig returns a BodySampleConfig logging no bodies, redacting credentials,
+// message texts and mobile numbers
+func DefaultBodySampleConfig() *BodySampleConfig {
+	return &BodySampleConfig{
+		Rate:         0,
+		Header:       "",
+		MaxBytes:     16384,
+		RedactFields: []string{"password", "pwd", "pin", "otp", "secret_key", "securekey", "token", "authorization", "message_text", "mobile_numbers", "mobile_number"},
+		SkipPaths:    DefaultMiddlewareConfig(),
+		Rand:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// bodySampler decides which requests are sampled and redacts their bodies
+type bodySampler struct {
+	config *BodySampleConfig
+	redact map[string]bool
+	mu     sync.Mutex // guards config.Rand, which is not safe for concurrent use
+}
+
+func newBodySampler(config *BodySampleConfig) *bodySampler {
+	if config == nil {
+		config = DefaultBodySampleConfig()
+	}
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = 16384
+	}
+	if config.Rand == nil {
+		config.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	redact := make(map[string]bool, len(config.RedactFields))
+	for _, field := range config.RedactFields {
+		redact[strings.ToLower(field)] = true
+	}
+	return &bodySampler{config: config, redact: redact}
+}
+
+// sample returns why the bodies of c are logged, empty when they are not
+func (s *bodySampler) sample(c *gin.Context) string {
+	if s.config.SkipPaths.ShouldSkip(c.Request.Method, c.Request.URL.Path) {
+		return ""
+	}
+	if s.config.Header != "" && c.GetHeader(s.config.Header) != "" &&
+		(s.config.HeaderAllowed == nil || s.config.HeaderAllowed(c)) {
+		return "header"
+	}
+	switch {
+	case s.config.Rate <= 0:
+		return ""
+	case s.config.Rate >= 1:
+		return "rate"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.config.Rand.Float64() < s.config.Rate {
+		return "rate"
+	}
+	return ""
+}
+
+// redactBody returns the body to log: JSON and form bodies with the RedactFields values
+// replaced, plain text as is, and only the size of any other body, truncated to MaxBytes
+func (s *bodySampler) redactBody(body []byte, contentType string, truncated bool) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if truncated {
+		return fmt.Sprintf("[%d+ bytes not logged: too large to redact]", len(body))
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	var logged string
+	switch {
+	case strings.HasSuffix(mediaType, "json") || (mediaType == "" && json.Valid(body)):
+		var value any
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return fmt.Sprintf("[%d bytes not logged: invalid JSON]", len(body))
+		}
+		redacted, err := json.Marshal(s.redactJSON(value))
+		if err != nil {
+			return fmt.Sprintf("[%d bytes not logged: %s]", len(body), err.Error())
+		}
+		logged = string(redacted)
+	case mediaType == "application/x-www-form-urlencoded":
+		logged = s.redactQuery(string(body))
+	case strings.HasPrefix(mediaType, "text/"):
+		logged = string(body)
+	default:
+		return fmt.Sprintf("[%d bytes of %s not logged]", len(body), mediaType)
+	}
+
+	if len(logged) > s.config.MaxBytes {
+		logged = logged[:s.config.MaxBytes] + "...(truncated)"
+	}
+	return logged
+}
+
+func (s *bodySampler) redactJSON(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if s.redact[strings.ToLower(key)] {
+				v[key] = redactedValue
+			} else {
+				v[key] = s.redactJSON(field)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = s.redactJSON(item)
+		}
+	}
+	return value
+}
+
+// redactQuery redacts the RedactFields parameters of a query string or form body
+func (s *bodySampler) redactQuery(query string) string {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return fmt.Sprintf("[%d bytes not logged: invalid form]", len(query))
+	}
+	for key := range values {
+		if s.redact[strings.ToLower(key)] {
+			values[key] = []string{redactedValue}
+		}
+	}
+	return values.Encode()
+}
+
+// bodySampleWriter captures the response body up to bodySampleCaptureLimit
+type bodySampleWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *bodySampleWriter) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodySampleWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *bodySampleWriter) capture(b []byte) {
+	if room := bodySampleCaptureLimit - w.body.Len(); len(b) > room {
+		w.body.Write(b[:max(room, 0)])
+		w.truncated = true
+		return
+	}
+	w.body.Write(b)
+}
+
+// BodySampleMiddleware logs the full request and response bodies of the requests sampled
+// following config, redacted of the RedactFields values. If config is nil,
+// DefaultBodySampleConfig() is used.
+//
+// Example usage:
+//
+//	router.Use(log.BodySampleMiddleware(&log.BodySampleConfig{
+//		Rate:         0.01,
+//		Header:       "X-Debug-Body",
+//		RedactFields: []string{"password", "otp"},
+//	}))
+func BodySampleMiddleware(config *BodySampleConfig) gin.HandlerFunc {
+	sampler := newBodySampler(config)
+
+	return func(c *gin.Context) {
+		reason := sampler.sample(c)
+		if reason == "" {
+			c.Next()
+			return
+		}
+
+		var requestBody []byte
+		requestTruncated := false
+		if c.Request.Body != nil {
+			captured, err := io.ReadAll(io.LimitReader(c.Request.Body, bodySampleCaptureLimit+1))
+			if err == nil {
+				requestTruncated = len(captured) > bodySampleCaptureLimit
+				c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(captured), c.Request.Body), c.Request.Body}
+				requestBody = captured
+			}
+		}
+
+		writer := &bodySampleWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		path := c.Request.URL.Path
+		if raw := c.Request.URL.RawQuery; raw != "" {
+			path += "?" + sampler.redactQuery(raw)
+		}
+		getCtxLogger(c).ToZerolog().Info().
+			Str("method", c.Request.Method).
+			Str("path", path).
+			Int("status", writer.Status()).
+			Str("sample-reason", reason).
+			Str("request-body", sampler.redactBody(requestBody, c.ContentType(), requestTruncated)).
+			Str("response-body", sampler.redactBody(writer.body.Bytes(), writer.Header().Get("Content-Type"), writer.truncated)).
+			Msg("request body sample")
+	}
+}
+
+// readCloser reads from a replayed body and closes the original one
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
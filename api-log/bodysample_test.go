@@ -0,0 +1,209 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newBodySampleRouter(config *BodySampleConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(BodySampleMiddleware(config))
+	router.POST("/echo", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Data(http.StatusOK, "application/json", body)
+	})
+	router.GET("/healthz", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+// bodySamples returns the body sample entries written to buf
+func bodySamples(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var samples []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if !strings.Contains(line, "request body sample") {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Invalid log entry %q: %v", line, err)
+		}
+		samples = append(samples, entry)
+	}
+	return samples
+}
+
+func TestBodySampleMiddleware_SamplingRate(t *testing.T) {
+	tests := []struct {
+		name string
+		rate float64
+		min  int
+		max  int
+	}{
+		{"Rate 0 logs nothing", 0, 0, 0},
+		{"Rate 0.1 logs about a tenth", 0.1, 150, 250},
+		{"Rate 0.5 logs about half", 0.5, 900, 1100},
+		{"Rate 1 logs everything", 1, 2000, 2000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := setupTestLoggerForMiddleware()
+			router := newBodySampleRouter(&BodySampleConfig{
+				Rate: tt.rate,
+				Rand: rand.New(rand.NewSource(12345)),
+			})
+
+			for range 2000 {
+				req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"a":1}`))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				if w.Body.String() != `{"a":1}` {
+					t.Fatalf("Expected the body to reach the handler, got %q", w.Body.String())
+				}
+			}
+
+			if got := len(bodySamples(t, buf)); got < tt.min || got > tt.max {
+				t.Errorf("Expected between %d and %d samples, got %d", tt.min, tt.max, got)
+			}
+		})
+	}
+}
+
+func TestBodySampleMiddleware_Header(t *testing.T) {
+	buf := setupTestLoggerForMiddleware()
+	router := newBodySampleRouter(&BodySampleConfig{Header: "X-Debug-Body"})
+
+	for _, debug := range []string{"", "1", ""} {
+		req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"a":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		if debug != "" {
+			req.Header.Set("X-Debug-Body", debug)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	samples := bodySamples(t, buf)
+	if len(samples) != 1 {
+		t.Fatalf("Expected only the request with the header to be logged, got %d samples", len(samples))
+	}
+	if samples[0]["sample-reason"] != "header" {
+		t.Errorf("Expected sample-reason header, got %v", samples[0]["sample-reason"])
+	}
+}
+
+func TestBodySampleMiddleware_HeaderAllowed(t *testing.T) {
+	buf := setupTestLoggerForMiddleware()
+	router := newBodySampleRouter(&BodySampleConfig{
+		Header:        "X-Debug-Body",
+		HeaderAllowed: func(c *gin.Context) bool { return c.GetHeader("X-Admin") != "" },
+	})
+
+	for _, admin := range []string{"", "1"} {
+		req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"a":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Debug-Body", "1")
+		if admin != "" {
+			req.Header.Set("X-Admin", admin)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if samples := bodySamples(t, buf); len(samples) != 1 {
+		t.Fatalf("Expected only the allowed request to be logged, got %d samples", len(samples))
+	}
+}
+
+func TestDefaultBodySampleConfig(t *testing.T) {
+	buf := setupTestLoggerForMiddleware()
+	config := DefaultBodySampleConfig()
+	config.Rate = 1
+	router := newBodySampleRouter(config)
+
+	req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"message_text":"OTP is 4321","mobile_numbers":"9000000001","priority":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	samples := bodySamples(t, buf)
+	if len(samples) != 1 {
+		t.Fatalf("Expected 1 sample, got %d", len(samples))
+	}
+	body := samples[0]["request-body"].(string)
+	if strings.Contains(body, "4321") || strings.Contains(body, "9000000001") || !strings.Contains(body, `"priority":1`) {
+		t.Errorf("Expected the message text and the mobile numbers redacted, got %s", body)
+	}
+	if config.Header != "" {
+		t.Errorf("Expected no header trigger by default, got %q", config.Header)
+	}
+}
+
+func TestBodySampleMiddleware_Redaction(t *testing.T) {
+	buf := setupTestLoggerForMiddleware()
+	router := newBodySampleRouter(&BodySampleConfig{
+		Rate:         1,
+		RedactFields: []string{"password", "OTP"},
+		SkipPaths:    DefaultMiddlewareConfig(),
+	})
+
+	body := `{"user":"appost","password":"secret","items":[{"otp":"123456","id":7}]}`
+	req := httptest.NewRequest(http.MethodPost, "/echo?password=secret&page=2", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	samples := bodySamples(t, buf)
+	if len(samples) != 1 {
+		t.Fatalf("Expected 1 sample, the health check being skipped, got %d", len(samples))
+	}
+	want := `{"items":[{"id":7,"otp":"[REDACTED]"}],"password":"[REDACTED]","user":"appost"}`
+	for _, field := range []string{"request-body", "response-body"} {
+		if samples[0][field] != want {
+			t.Errorf("Expected %s %s, got %v", field, want, samples[0][field])
+		}
+	}
+	if path := samples[0]["path"]; path != "/echo?page=2&password=%5BREDACTED%5D" {
+		t.Errorf("Expected the query to be redacted, got %v", path)
+	}
+	if strings.Contains(buf.String(), "secret") || strings.Contains(buf.String(), "123456") {
+		t.Errorf("Redacted values were logged: %s", buf.String())
+	}
+}
+
+func TestBodySampler_RedactBody(t *testing.T) {
+	sampler := newBodySampler(&BodySampleConfig{MaxBytes: 24, RedactFields: []string{"pin"}})
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		truncated   bool
+		want        string
+	}{
+		{"Empty body", "", "application/json", false, ""},
+		{"Form", "pin=1234&a=b", "application/x-www-form-urlencoded", false, "a=b&pin=%5BREDACTED%5D"},
+		{"Plain text", "hello", "text/plain; charset=utf-8", false, "hello"},
+		{"Long text is truncated", "the quick brown fox jumps over", "text/plain", false, "the quick brown fox jump...(truncated)"},
+		{"Multipart is not logged", "--x\r\n", "multipart/form-data; boundary=x", false, "[5 bytes of multipart/form-data not logged]"},
+		{"Invalid JSON is not logged", `{"pin":`, "application/json", false, "[7 bytes not logged: invalid JSON]"},
+		{"Oversized body is not logged", `{"pin":"1"}`, "application/json", true, "[11+ bytes not logged: too large to redact]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sampler.redactBody([]byte(tt.body), tt.contentType, tt.truncated); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package log
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"sync"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type (
@@ -33,6 +35,7 @@ var (
 
 type LoggerFactory interface {
 	Create(options ...loggerOption) error
+	SetLevel(level zerolog.Level, duration time.Duration) error
 }
 
 type DefaultLoggerFactory struct{}
@@ -76,6 +79,11 @@ func (f *DefaultLoggerFactory) Create(options ...loggerOption) error {
 	return createErr
 }
 
+// SetLevel temporarily overrides the level of the created logger, see [SetLevel].
+func (f *DefaultLoggerFactory) SetLevel(level zerolog.Level, duration time.Duration) error {
+	return SetLevel(level, duration)
+}
+
 // SetCtxLoggerMiddleware creates a sublogger with request metadata and embed this inside ginCtx
 func SetCtxLoggerMiddleware(c *gin.Context) {
 	if baseLogger != nil {
@@ -88,6 +96,17 @@ func SetCtxLoggerMiddleware(c *gin.Context) {
 	c.Next()
 }
 
+// SetCtxTraceIDMiddleware stores the trace ID of the active OpenTelemetry span of the request in
+// its context, so that the logger of SetCtxLoggerMiddleware logs it as trace_id on every line of
+// the request. It must run after the tracing middleware and before SetCtxLoggerMiddleware.
+func SetCtxTraceIDMiddleware(c *gin.Context) {
+	if sc := trace.SpanContextFromContext(c.Request.Context()); sc.HasTraceID() {
+		ctx := context.WithValue(c.Request.Context(), traceIDKey, sc.TraceID().String())
+		c.Request = c.Request.WithContext(ctx)
+	}
+	c.Next()
+}
+
 // getCtxLogger returns the logger embedded in the context.
 // If no logger is found, it returns the base logger instance.
 // This function handles both gin.Context and context.Context types.
@@ -187,7 +206,15 @@ func RequestResponseLoggerMiddlewareWithConfig(config *MiddlewareConfig) gin.Han
 		}
 		fullPath := pathBuilder.String()
 
-		getCtxLogger(c).ToZerolog().Info().Str("user-agent", c.Request.UserAgent()).Str("client-ip", c.ClientIP()).Str("method", method).Str("path", fullPath).Str("protocol", c.Request.Proto).Str("http-referer", c.Request.Referer()).Int("status", c.Writer.Status()).Dur("latency-ms", timeStamp.Sub(start).Round(time.Millisecond)).Int64("bytes-received", c.Request.ContentLength).
+		// a request whose client closed the connection is logged with the 499 status and the
+		// client_aborted outcome of response.ClientAborted, which imports this package
+		status := c.Writer.Status()
+		event := getCtxLogger(c).ToZerolog().Info()
+		if errors.Is(c.Request.Context().Err(), context.Canceled) {
+			status = 499
+			event = event.Str("outcome", "client_aborted")
+		}
+		event.Str("user-agent", c.Request.UserAgent()).Str("client-ip", c.ClientIP()).Str("method", method).Str("path", fullPath).Str("protocol", c.Request.Proto).Str("http-referer", c.Request.Referer()).Int("status", status).Dur("latency-ms", timeStamp.Sub(start).Round(time.Millisecond)).Int64("bytes-received", c.Request.ContentLength).
 			Int("bytes-sent", c.Writer.Size()).Msg("request")
 	}
 }
@@ -201,13 +228,10 @@ func (l *Logger) setRequestMetadata(c *gin.Context) zerolog.Logger {
 		c.Writer.Header().Set("X-Request-ID", requestID)
 	}
 
-	traceID := c.Request.Header.Get("X-Trace-ID")
+	// the trace ID of the active span comes first, the X-Trace-ID header is set by the client
+	traceID, _ := c.Request.Context().Value(traceIDKey).(string)
 	if traceID == "" {
-		if ctxTraceID, ok := c.Request.Context().Value(traceIDKey).(string); ok && ctxTraceID != "" {
-			traceID = ctxTraceID
-		} else {
-			traceID = ""
-		}
+		traceID = c.Request.Header.Get("X-Trace-ID")
 	}
 
 	officeID := c.Request.Header.Get("x-office-id")
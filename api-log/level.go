@@ -0,0 +1,152 @@
+package log
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// levelOverride holds the state of a temporary runtime log level change.
+// Only a single override is active at a time; applying a new one replaces it.
+type levelOverride struct {
+	mu          sync.Mutex
+	active      atomic.Bool
+	level       atomic.Int32
+	timer       *time.Timer
+	expiresAt   time.Time
+	generation  uint64
+	globalLevel zerolog.Level // zerolog global level in place before the override
+}
+
+var override levelOverride
+
+// LevelStatus describes the effective log level and any active runtime override.
+type LevelStatus struct {
+	Level           zerolog.Level
+	ConfiguredLevel zerolog.Level
+	OverrideActive  bool
+	Remaining       time.Duration
+}
+
+// SetLevel switches the log level at runtime for the given duration, after which the
+// configured level is restored automatically. Calling it again while an override is
+// active replaces the level and restarts the timer, extending the override.
+//
+// The new level applies to all logging done through this package, including child
+// loggers already embedded in request contexts, and to the zerolog global level.
+func SetLevel(level zerolog.Level, duration time.Duration) error {
+	if duration <= 0 {
+		return errors.New("log level override duration must be positive")
+	}
+
+	override.mu.Lock()
+	defer override.mu.Unlock()
+
+	if override.timer != nil {
+		override.timer.Stop()
+	}
+	if !override.active.Load() {
+		override.globalLevel = zerolog.GlobalLevel()
+	}
+
+	override.generation++
+	generation := override.generation
+	override.expiresAt = time.Now().Add(duration)
+	override.timer = time.AfterFunc(duration, func() {
+		revertLevel(generation)
+	})
+
+	override.level.Store(int32(level))
+	override.active.Store(true)
+	zerolog.SetGlobalLevel(level)
+
+	logLevelChange().
+		Str("new_level", level.String()).
+		Str("configured_level", configuredLevel().String()).
+		Dur("duration", duration).
+		Time("expires_at", override.expiresAt).
+		Msg("Log level overridden at runtime")
+	return nil
+}
+
+// ResetLevel removes any active runtime override and restores the configured level.
+func ResetLevel() {
+	override.mu.Lock()
+	defer override.mu.Unlock()
+
+	resetLevelLocked("Log level override cleared")
+}
+
+// GetLevelStatus returns the effective and configured log levels along with the
+// remaining time of the active override, if any.
+func GetLevelStatus() LevelStatus {
+	override.mu.Lock()
+	defer override.mu.Unlock()
+
+	status := LevelStatus{
+		Level:           configuredLevel(),
+		ConfiguredLevel: configuredLevel(),
+	}
+	if override.active.Load() {
+		status.Level = zerolog.Level(override.level.Load())
+		status.OverrideActive = true
+		status.Remaining = max(time.Until(override.expiresAt), 0)
+	}
+	return status
+}
+
+// revertLevel is invoked by the override timer. Timers from replaced overrides are
+// ignored, so that an extended override is not cut short.
+func revertLevel(generation uint64) {
+	override.mu.Lock()
+	defer override.mu.Unlock()
+
+	if generation != override.generation {
+		return
+	}
+	resetLevelLocked("Log level override expired")
+}
+
+// resetLevelLocked restores the configured level. The caller must hold override.mu.
+func resetLevelLocked(msg string) {
+	if override.timer != nil {
+		override.timer.Stop()
+		override.timer = nil
+	}
+	if !override.active.Load() {
+		return
+	}
+
+	override.generation++
+	override.active.Store(false)
+	override.expiresAt = time.Time{}
+	zerolog.SetGlobalLevel(override.globalLevel)
+
+	logLevelChange().
+		Str("new_level", configuredLevel().String()).
+		Msg(msg)
+}
+
+// overrideLevel returns the runtime override level if one is active.
+func overrideLevel() (zerolog.Level, bool) {
+	if !override.active.Load() {
+		return zerolog.NoLevel, false
+	}
+	return zerolog.Level(override.level.Load()), true
+}
+
+// configuredLevel returns the level the base logger was created with.
+func configuredLevel() zerolog.Level {
+	return GetBaseLoggerInstance().logger.GetLevel()
+}
+
+// logLevelChange returns an event reported at info level irrespective of the
+// current log level, so that level changes are always recorded.
+func logLevelChange() *zerolog.Event {
+	logger := GetBaseLoggerInstance().logger.Level(zerolog.TraceLevel)
+	return logger.WithLevel(zerolog.NoLevel).
+		Str(zerolog.LevelFieldName, zerolog.InfoLevel.String())
+}
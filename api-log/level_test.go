@@ -0,0 +1,189 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// setupLevelTest creates a base logger at info level writing to a buffer and
+// clears any override left behind by the test.
+func setupLevelTest(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	once = sync.Once{}
+	createErr = nil
+	baseLogger = nil
+	samplingConfig = nil
+
+	var buf bytes.Buffer
+	if err := NewDefaultLoggerFactory().Create(
+		WithServiceName("test-service"),
+		WithLevel(zerolog.InfoLevel),
+		WithOutputWriter(&buf),
+	); err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+
+	t.Cleanup(ResetLevel)
+	return &buf
+}
+
+func TestSetLevel_Apply(t *testing.T) {
+	buf := setupLevelTest(t)
+
+	// Child logger cached in a context before the override
+	childLogger := baseLogger.logger.With().Str("request-id", "req-1").Logger()
+	ctx := context.WithValue(context.Background(), ctxLoggerKey, &Logger{logger: &childLogger})
+
+	Debug(ctx, "debug before override")
+	if strings.Contains(buf.String(), "debug before override") {
+		t.Fatal("debug message should not be logged at info level")
+	}
+
+	factory := NewDefaultLoggerFactory()
+	if err := factory.SetLevel(zerolog.DebugLevel, time.Minute); err != nil {
+		t.Fatalf("SetLevel() failed: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "Log level overridden at runtime") {
+		t.Error("level change should be logged")
+	}
+	if zerolog.GlobalLevel() != zerolog.DebugLevel {
+		t.Errorf("expected zerolog global level debug, got %v", zerolog.GlobalLevel())
+	}
+
+	Debug(ctx, "debug from cached child")
+	Debug(context.Background(), "debug from base")
+	if !strings.Contains(buf.String(), "debug from cached child") {
+		t.Error("cached child logger should observe the override")
+	}
+	if !strings.Contains(buf.String(), "debug from base") {
+		t.Error("base logger should observe the override")
+	}
+
+	status := GetLevelStatus()
+	if status.Level != zerolog.DebugLevel || status.ConfiguredLevel != zerolog.InfoLevel {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if !status.OverrideActive || status.Remaining <= 0 || status.Remaining > time.Minute {
+		t.Errorf("expected active override with remaining time, got %+v", status)
+	}
+}
+
+func TestSetLevel_RaiseLevelStillLogsChange(t *testing.T) {
+	buf := setupLevelTest(t)
+
+	if err := SetLevel(zerolog.ErrorLevel, time.Minute); err != nil {
+		t.Fatalf("SetLevel() failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"level":"info"`) || !strings.Contains(buf.String(), "Log level overridden at runtime") {
+		t.Errorf("level change should be logged at info, got: %s", buf.String())
+	}
+
+	Warn(context.Background(), "warn during override")
+	if strings.Contains(buf.String(), "warn during override") {
+		t.Error("warn message should be suppressed at error level")
+	}
+}
+
+func TestSetLevel_InvalidDuration(t *testing.T) {
+	setupLevelTest(t)
+
+	if err := SetLevel(zerolog.DebugLevel, 0); err == nil {
+		t.Error("expected error for non-positive duration")
+	}
+	if GetLevelStatus().OverrideActive {
+		t.Error("override should not be active after a rejected call")
+	}
+}
+
+func TestSetLevel_AutoRevert(t *testing.T) {
+	buf := setupLevelTest(t)
+	globalLevel := zerolog.GlobalLevel()
+
+	if err := SetLevel(zerolog.DebugLevel, 50*time.Millisecond); err != nil {
+		t.Fatalf("SetLevel() failed: %v", err)
+	}
+
+	waitFor(t, time.Second, func() bool { return !GetLevelStatus().OverrideActive })
+
+	status := GetLevelStatus()
+	if status.Level != zerolog.InfoLevel || status.Remaining != 0 {
+		t.Errorf("expected configured level after revert, got %+v", status)
+	}
+	if zerolog.GlobalLevel() != globalLevel {
+		t.Errorf("expected zerolog global level %v after revert, got %v", globalLevel, zerolog.GlobalLevel())
+	}
+	if !strings.Contains(buf.String(), "Log level override expired") {
+		t.Error("revert should be logged")
+	}
+
+	Debug(context.Background(), "debug after revert")
+	if strings.Contains(buf.String(), "debug after revert") {
+		t.Error("debug message should not be logged after revert")
+	}
+}
+
+func TestSetLevel_Extend(t *testing.T) {
+	setupLevelTest(t)
+
+	if err := SetLevel(zerolog.DebugLevel, 50*time.Millisecond); err != nil {
+		t.Fatalf("SetLevel() failed: %v", err)
+	}
+	if err := SetLevel(zerolog.TraceLevel, time.Minute); err != nil {
+		t.Fatalf("SetLevel() failed: %v", err)
+	}
+
+	// The first timer would have fired by now
+	time.Sleep(150 * time.Millisecond)
+
+	status := GetLevelStatus()
+	if !status.OverrideActive || status.Level != zerolog.TraceLevel {
+		t.Errorf("override should be extended, got %+v", status)
+	}
+	if status.Remaining <= 50*time.Millisecond {
+		t.Errorf("expected remaining time to be extended, got %v", status.Remaining)
+	}
+}
+
+func TestSetLevel_Concurrent(t *testing.T) {
+	setupLevelTest(t)
+	globalLevel := zerolog.GlobalLevel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			level := zerolog.DebugLevel
+			if i%2 == 0 {
+				level = zerolog.WarnLevel
+			}
+			_ = SetLevel(level, 20*time.Millisecond)
+			_ = GetLevelStatus()
+		}(i)
+	}
+	wg.Wait()
+
+	waitFor(t, time.Second, func() bool { return !GetLevelStatus().OverrideActive })
+	if zerolog.GlobalLevel() != globalLevel {
+		t.Errorf("expected zerolog global level %v after revert, got %v", globalLevel, zerolog.GlobalLevel())
+	}
+}
+
+// waitFor polls cond until it holds or the timeout elapses
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
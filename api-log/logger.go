@@ -233,6 +233,10 @@ func getEventLoggerWithSkip(ctx context.Context, level zerolog.Level, skipFrames
 	// - 2 for direct Event API usage: getEventLoggerWithSkip -> InfoEvent -> caller
 	// - 3 for simple API usage: getEventLoggerWithSkip -> Info -> caller
 	lw := logger.logger.With().CallerWithSkipFrameCount(zerolog.CallerSkipFrameCount + skipFrames).Logger()
+	// A runtime level override also applies to child loggers created before it
+	if overrideLvl, ok := overrideLevel(); ok {
+		lw = lw.Level(overrideLvl)
+	}
 	event := lw.WithLevel(level)
 
 	// Add tags from context if present
@@ -2,6 +2,7 @@ package log
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"sync"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func setupTestLoggerForMiddleware() *bytes.Buffer {
@@ -123,6 +125,65 @@ func TestSetCtxLoggerMiddleware_CapturesTraceID(t *testing.T) {
 	}
 }
 
+// withTestSpan starts the requests in the context of a span of traceID, as the tracer middleware does
+func withTestSpan(traceID trace.TraceID) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: trace.SpanID{1}, TraceFlags: trace.FlagsSampled})
+		c.Request = c.Request.WithContext(trace.ContextWithSpanContext(c.Request.Context(), sc))
+		c.Next()
+	}
+}
+
+func TestSetCtxTraceIDMiddleware_LogsSpanTraceID(t *testing.T) {
+	buf := setupTestLoggerForMiddleware()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	traceID := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	router.Use(withTestSpan(traceID), SetCtxTraceIDMiddleware, SetCtxLoggerMiddleware)
+
+	router.GET("/test", func(c *gin.Context) {
+		Info(c, "handled")
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	// the span wins over a trace ID sent by the client
+	req.Header.Set("X-Trace-ID", "client-trace-id")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	output := buf.String()
+	if !contains(output, `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`) {
+		t.Errorf("Log lines should carry the trace ID of the span, got %s", output)
+	}
+	if contains(output, "client-trace-id") {
+		t.Error("The trace ID of the span should replace the X-Trace-ID header")
+	}
+}
+
+func TestSetCtxTraceIDMiddleware_NoSpan(t *testing.T) {
+	buf := setupTestLoggerForMiddleware()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(SetCtxTraceIDMiddleware, SetCtxLoggerMiddleware)
+
+	router.GET("/test", func(c *gin.Context) {
+		Info(c, "handled")
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Trace-ID", "client-trace-id")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if !contains(buf.String(), `"trace_id":"client-trace-id"`) {
+		t.Errorf("Without a span the X-Trace-ID header should be logged, got %s", buf.String())
+	}
+}
+
 func TestSetCtxLoggerMiddleware_CapturesOfficeID(t *testing.T) {
 	setupTestLoggerForMiddleware()
 
@@ -269,6 +330,35 @@ func TestRequestResponseLoggerMiddleware_LogsStatusCode(t *testing.T) {
 	}
 }
 
+func TestRequestResponseLoggerMiddleware_LogsClientAborted(t *testing.T) {
+	buf := setupTestLoggerForMiddleware()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(SetCtxLoggerMiddleware)
+	router.Use(RequestResponseLoggerMiddleware)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	router.GET("/send", func(c *gin.Context) {
+		// the client closes the connection while the request is served
+		cancel()
+		c.Status(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest("GET", "/send", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	output := buf.String()
+
+	if !contains(output, `"status":499`) || !contains(output, `"outcome":"client_aborted"`) {
+		t.Errorf("Client aborted request should be logged with status 499 and its outcome, got %s", output)
+	}
+	if contains(output, `"status":500`) {
+		t.Error("Client aborted request should not be logged as a server error")
+	}
+}
+
 func TestRequestResponseLoggerMiddleware_LogsLatency(t *testing.T) {
 	buf := setupTestLoggerForMiddleware()
 
@@ -14,9 +14,11 @@ type Handler interface {
 }
 
 type Base struct {
-	prefix string
-	name   string
-	mws    []gin.HandlerFunc
+	prefix      string
+	name        string
+	mws         []gin.HandlerFunc
+	description string
+	order       int
 }
 
 func New(name string) *Base {
@@ -35,6 +37,18 @@ func (b *Base) Middlewares() []gin.HandlerFunc {
 	return b.mws
 }
 
+// Description is the description of the tag the routes of the handler are grouped under in the
+// generated docs
+func (b *Base) Description() string {
+	return b.description
+}
+
+// Order is the display order weight of the tag of the handler in the generated docs, lower
+// weights first and tags of equal weight by name
+func (b *Base) Order() int {
+	return b.order
+}
+
 func (b *Base) Routes() []route.Route {
 	panic("need to declare routes for controller: " + b.name)
 }
@@ -48,6 +62,16 @@ func (b *Base) SetPrefix(p string) *Base {
 	return b
 }
 
+func (b *Base) SetDescription(d string) *Base {
+	b.description = d
+	return b
+}
+
+func (b *Base) SetOrder(n int) *Base {
+	b.order = n
+	return b
+}
+
 func (b *Base) AddMiddleware(mw gin.HandlerFunc) *Base {
 	b.mws = append(b.mws, mw)
 	return b
@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	apierrors "MgApplication/api-errors"
+	"MgApplication/api-server/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// cancelKey holds the function canceling the request in its context, closing the connection
+// of the client in TestClientAbortedRequests
+type cancelKey struct{}
+
+type recordingSampleSink struct {
+	mu      sync.Mutex
+	samples []ErrorSample
+}
+
+func (s *recordingSampleSink) SampleError(c *gin.Context, sample ErrorSample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.samples = append(s.samples, sample)
+}
+
+// TestClientAbortedRequests serves requests failing with 500 because their client closed the
+// connection, and one failing on its own. The aborted ones are counted as client_aborted and not
+// sampled.
+func TestClientAbortedRequests(t *testing.T) {
+	sink := &recordingSampleSink{}
+	SetErrorSampleSink(sink)
+	t.Cleanup(func() { SetErrorSampleSink(nil) })
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(RequestMetricsMiddlewareWithConfig(RequestMetricsMiddlewareConfig{
+		Registry:                prometheus.NewRegistry(),
+		NormalizeRequestPath:    true,
+		NormalizeResponseStatus: true,
+	}), ErrorSampleMiddleware())
+	// /abort fails with the cancellation of its request, as a query would once the client left
+	engine.GET("/abort", func(c *gin.Context) {
+		c.Request.Context().Value(cancelKey{}).(context.CancelFunc)()
+		apierrors.HandleDBError(c, c.Request.Context().Err())
+	})
+	engine.GET("/fail", func(c *gin.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest(http.MethodGet, "/abort", nil)
+		req = req.WithContext(context.WithValue(ctx, cancelKey{}, cancel))
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		assert.Equal(t, response.StatusClientClosedRequest, rec.Code)
+	}
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	assert.Equal(t, 3.0, testutil.ToFloat64(httpRequestsCounter.WithLabelValues(response.OutcomeClientAborted, http.MethodGet, "/abort")))
+	assert.Zero(t, testutil.ToFloat64(httpRequestsCounter.WithLabelValues("4xx", http.MethodGet, "/abort")))
+	assert.Zero(t, testutil.ToFloat64(httpRequestsCounter.WithLabelValues("5xx", http.MethodGet, "/abort")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpRequestsCounter.WithLabelValues("5xx", http.MethodGet, "/fail")))
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if assert.Len(t, sink.samples, 1) {
+		assert.Equal(t, "/fail", sink.samples[0].Route)
+	}
+}
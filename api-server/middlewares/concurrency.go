@@ -0,0 +1,186 @@
+package middlewares
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	auth "MgApplication/api-authz"
+	apierrors "MgApplication/api-errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The limits of ConcurrencyLimiter a request is rejected by, the label of
+// ConcurrencyLimitRejectionsTotal
+const (
+	ConcurrencyLimitGlobal      = "global"
+	ConcurrencyLimitRoute       = "route"
+	ConcurrencyLimitApplication = "application"
+)
+
+var (
+	InFlightRequests = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Number of requests being served, counted against the global concurrency limit",
+	})
+	ApplicationInFlightRequests = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_application_requests_in_flight",
+		Help: "Number of requests being served per application, the subject of their bearer token",
+	}, []string{"application"})
+	ConcurrencyLimitRejectionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_concurrency_limit_rejections_total",
+		Help: "Total number of requests rejected because too many requests were in flight",
+	}, []string{"limit"})
+)
+
+// ConcurrencyLimits are the maximum numbers of requests in flight, 0 for no limit
+type ConcurrencyLimits struct {
+	// Global caps the requests of every application together
+	Global int64
+	// Default caps the requests of each application without an override
+	Default int64
+	// Applications overrides Default per application ID
+	Applications map[string]int64
+	// Routes caps the requests of a route, keyed by "METHOD /route/:pattern"
+	Routes map[string]int64
+}
+
+// ConcurrencyLimiter counts the requests in flight, globally, per route and per application,
+// unlike the rate limiter that counts the requests started per second: an application opening
+// many slow requests stays under its rate limit while holding the server. The counters are
+// atomics, one per application in a sync.Map, so the applications never wait for each other.
+type ConcurrencyLimiter struct {
+	limits ConcurrencyLimits
+	global atomic.Int64
+	// routes holds a counter per limited route, created with the limiter and only read after
+	routes       map[string]*atomic.Int64
+	applications sync.Map // application ID -> *atomic.Int64
+}
+
+// NewConcurrencyLimiter creates a new ConcurrencyLimiter instance enforcing limits
+func NewConcurrencyLimiter(limits ConcurrencyLimits) *ConcurrencyLimiter {
+	cl := &ConcurrencyLimiter{limits: limits, routes: make(map[string]*atomic.Int64)}
+	cl.limits.Applications = make(map[string]int64, len(limits.Applications))
+	for application, limit := range limits.Applications {
+		cl.limits.Applications[strings.ToLower(application)] = limit
+	}
+	cl.limits.Routes = make(map[string]int64, len(limits.Routes))
+	for route, limit := range limits.Routes {
+		key := concurrencyRouteKey(route)
+		cl.limits.Routes[key] = limit
+		cl.routes[key] = new(atomic.Int64)
+	}
+	return cl
+}
+
+// concurrencyRouteKey normalizes "METHOD /route" to compare it with the route of a request, the
+// configuration keys being lowercased
+func concurrencyRouteKey(route string) string {
+	return strings.ToLower(strings.Join(strings.Fields(route), " "))
+}
+
+// tryAcquire counts a request in counter unless it would exceed limit
+func tryAcquire(counter *atomic.Int64, limit int64) bool {
+	if counter.Add(1) > limit && limit > 0 {
+		counter.Add(-1)
+		return false
+	}
+	return true
+}
+
+// Acquire counts a request of application, empty for an anonymous request, on route in flight.
+// It returns the function releasing it, called exactly once when the request completes, or
+// the limit it exceeds, the request then not being counted.
+func (cl *ConcurrencyLimiter) Acquire(application string, route string) (release func(), exceeded string) {
+	if !tryAcquire(&cl.global, cl.limits.Global) {
+		return nil, ConcurrencyLimitGlobal
+	}
+	routeCounter := cl.routes[concurrencyRouteKey(route)]
+	if routeCounter != nil && !tryAcquire(routeCounter, cl.limits.Routes[concurrencyRouteKey(route)]) {
+		cl.global.Add(-1)
+		return nil, ConcurrencyLimitRoute
+	}
+	var appCounter *atomic.Int64
+	if application != "" {
+		appCounter = cl.applicationCounter(application)
+		if !tryAcquire(appCounter, cl.applicationLimit(application)) {
+			if routeCounter != nil {
+				routeCounter.Add(-1)
+			}
+			cl.global.Add(-1)
+			return nil, ConcurrencyLimitApplication
+		}
+		ApplicationInFlightRequests.WithLabelValues(application).Inc()
+	}
+	InFlightRequests.Inc()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			InFlightRequests.Dec()
+			if appCounter != nil {
+				ApplicationInFlightRequests.WithLabelValues(application).Dec()
+				appCounter.Add(-1)
+			}
+			if routeCounter != nil {
+				routeCounter.Add(-1)
+			}
+			cl.global.Add(-1)
+		})
+	}, ""
+}
+
+// InFlight returns the number of requests in flight, of application when it is not empty
+func (cl *ConcurrencyLimiter) InFlight(application string) int64 {
+	if application == "" {
+		return cl.global.Load()
+	}
+	if counter, ok := cl.applications.Load(application); ok {
+		return counter.(*atomic.Int64).Load()
+	}
+	return 0
+}
+
+func (cl *ConcurrencyLimiter) applicationCounter(application string) *atomic.Int64 {
+	if counter, ok := cl.applications.Load(application); ok {
+		return counter.(*atomic.Int64)
+	}
+	counter, _ := cl.applications.LoadOrStore(application, new(atomic.Int64))
+	return counter.(*atomic.Int64)
+}
+
+func (cl *ConcurrencyLimiter) applicationLimit(application string) int64 {
+	if limit, ok := cl.limits.Applications[strings.ToLower(application)]; ok {
+		return limit
+	}
+	return cl.limits.Default
+}
+
+// ConcurrencyLimitMiddleware rejects with 429 and the CONCURRENCY_LIMIT error id the requests
+// exceeding a limit of limiter. The application of a request is the subject of its bearer
+// token, so it must run after the token verification; the anonymous requests are only counted
+// against the global and route limits. The slot of a request is released when the rest of the
+// chain returns, panicking included.
+func ConcurrencyLimitMiddleware(limiter *ConcurrencyLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var application string
+		if claims := auth.ClaimsFrom(c.Request.Context()); claims != nil {
+			application = claims.Subject
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		release, exceeded := limiter.Acquire(application, c.Request.Method+" "+route)
+		if release == nil {
+			ConcurrencyLimitRejectionsTotal.WithLabelValues(exceeded).Inc()
+			apierrors.HandleConcurrencyLimitError(c)
+			c.Abort()
+			return
+		}
+		defer release()
+		c.Next()
+	}
+}
@@ -0,0 +1,178 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	auth "MgApplication/api-authz"
+	apierrors "MgApplication/api-errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimiterParallelChurn(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyLimits{
+		Global:       40,
+		Default:      5,
+		Applications: map[string]int64{"app-3": 2},
+		Routes:       map[string]int64{"POST /v1/sms-request": 12},
+	})
+	applications := []string{"app-0", "app-1", "app-2", "app-3", ""}
+	limits := map[string]int64{"app-0": 5, "app-1": 5, "app-2": 5, "app-3": 2, "": 40}
+
+	var over atomic.Int64
+	var wg sync.WaitGroup
+	for worker := 0; worker < 64; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			application := applications[worker%len(applications)]
+			route := "GET /v1/templates"
+			if worker%3 == 0 {
+				route = "POST /v1/sms-request"
+			}
+			for i := 0; i < 500; i++ {
+				release, _ := limiter.Acquire(application, route)
+				if release == nil {
+					continue
+				}
+				if limiter.InFlight(application) > limits[application] || limiter.InFlight("") > 40 {
+					over.Add(1)
+				}
+				if i%2 == 0 {
+					// releasing twice releases once
+					release()
+				}
+				release()
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	assert.Zero(t, over.Load(), "limits exceeded while in flight")
+	for _, application := range applications {
+		assert.Zero(t, limiter.InFlight(application), "application %q still in flight", application)
+	}
+	assert.Zero(t, limiter.routes[concurrencyRouteKey("POST /v1/sms-request")].Load())
+}
+
+// floodServer serves GET /slow, blocking until release is closed, with the application of a
+// request given by its X-App header
+func floodServer(limiter *ConcurrencyLimiter, started chan<- struct{}, release <-chan struct{}) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(gin.Recovery(), func(c *gin.Context) {
+		if app := c.GetHeader("X-App"); app != "" {
+			c.Request = c.Request.WithContext(auth.WithClaims(c.Request.Context(), &auth.Claims{Subject: app}))
+		}
+	}, ConcurrencyLimitMiddleware(limiter))
+	engine.GET("/slow", func(c *gin.Context) {
+		started <- struct{}{}
+		<-release
+		c.Status(http.StatusNoContent)
+	})
+	engine.GET("/panic", func(c *gin.Context) {
+		panic("handler failed")
+	})
+	return engine
+}
+
+func serveApp(engine *gin.Engine, path string, app string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-App", app)
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+// flood sends n concurrent requests of app, returning once limit of them are being served and
+// the others rejected
+func flood(t *testing.T, engine *gin.Engine, started <-chan struct{}, app string, n int, limit int) *sync.WaitGroup {
+	t.Helper()
+	var wg sync.WaitGroup
+	rejected := make(chan *httptest.ResponseRecorder, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rec := serveApp(engine, "/slow", app); rec.Code != http.StatusNoContent {
+				rejected <- rec
+			}
+		}()
+	}
+	for i := 0; i < limit; i++ {
+		<-started
+	}
+	for i := 0; i < n-limit; i++ {
+		rec := <-rejected
+		require.Equal(t, http.StatusTooManyRequests, rec.Code)
+		var body apierrors.APIErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, apierrors.ErrorIDConcurrencyLimit, body.AppError.ID)
+		assert.Equal(t, strconv.FormatInt(body.AppError.RetryAfterSeconds, 10), rec.Header().Get("Retry-After"))
+	}
+	return &wg
+}
+
+func TestConcurrencyLimitGlobalCapProtectsServer(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyLimits{Global: 10})
+	started := make(chan struct{}, 100)
+	release := make(chan struct{})
+	engine := floodServer(limiter, started, release)
+
+	// a single application without a limit of its own floods the server
+	wg := flood(t, engine, started, "7", 40, 10)
+	assert.EqualValues(t, 10, limiter.InFlight(""))
+	rec := serveApp(engine, "/slow", "9")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, "the global cap covers every application")
+
+	close(release)
+	wg.Wait()
+	assert.Zero(t, limiter.InFlight(""))
+	assert.Equal(t, http.StatusNoContent, serveApp(engine, "/slow", "9").Code)
+}
+
+func TestConcurrencyLimitPerApplication(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyLimits{Global: 100, Default: 5, Applications: map[string]int64{"9": 1}})
+	started := make(chan struct{}, 100)
+	release := make(chan struct{})
+	engine := floodServer(limiter, started, release)
+
+	wg := flood(t, engine, started, "7", 20, 5)
+	assert.EqualValues(t, 5, limiter.InFlight("7"))
+
+	// the other applications are still served, up to their own limit
+	var other sync.WaitGroup
+	other.Add(1)
+	go func() {
+		defer other.Done()
+		assert.Equal(t, http.StatusNoContent, serveApp(engine, "/slow", "9").Code)
+	}()
+	<-started
+	assert.Equal(t, http.StatusTooManyRequests, serveApp(engine, "/slow", "9").Code)
+
+	close(release)
+	wg.Wait()
+	other.Wait()
+	assert.Zero(t, limiter.InFlight("7"))
+	assert.Zero(t, limiter.InFlight("9"))
+}
+
+func TestConcurrencyLimitReleasedOnPanic(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyLimits{Global: 2, Default: 1})
+	engine := floodServer(limiter, make(chan struct{}, 1), make(chan struct{}))
+
+	for i := 0; i < 5; i++ {
+		rec := serveApp(engine, "/panic", "7")
+		assert.Equal(t, http.StatusInternalServerError, rec.Code, "request %d", i)
+	}
+	assert.Zero(t, limiter.InFlight("7"))
+	assert.Zero(t, limiter.InFlight(""))
+}
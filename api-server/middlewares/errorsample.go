@@ -0,0 +1,95 @@
+package middlewares
+
+import (
+	"sync/atomic"
+	"time"
+
+	"MgApplication/api-server/middlewares/reqid"
+	"MgApplication/api-server/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recoveredPanicKey is the context key of the stack of a panic recovered by Recover
+const recoveredPanicKey = "recovered-panic-stack"
+
+// ErrorSample describes a request that panicked or was answered with a 5xx status
+type ErrorSample struct {
+	Method string
+	// Route is the route pattern of the request, its path when no route matched
+	Route         string
+	Status        int
+	CorrelationID string
+	// Panicked is set when the handler panicked, Stack then holding the stack of the panic
+	Panicked bool
+	Stack    string
+	At       time.Time
+}
+
+// ErrorSampleSink receives the error samples of the requests. SampleError is called on the
+// request goroutine once the response is written and must not block.
+type ErrorSampleSink interface {
+	SampleError(c *gin.Context, sample ErrorSample)
+}
+
+type errorSampleSinkRef struct {
+	sink ErrorSampleSink
+}
+
+var errorSampleSink atomic.Pointer[errorSampleSinkRef]
+
+// SetErrorSampleSink sets the sink receiving the error samples of ErrorSampleMiddleware, nil
+// disables the sampling
+func SetErrorSampleSink(sink ErrorSampleSink) {
+	if sink == nil {
+		errorSampleSink.Store(nil)
+		return
+	}
+	errorSampleSink.Store(&errorSampleSinkRef{sink: sink})
+}
+
+// ErrorSampleMiddleware hands every request that panicked or was answered with a 5xx status to
+// the sink set by SetErrorSampleSink, which decides which are kept. It must run outside Recover
+// to see the panics it recovers. The 5xx of a request whose client closed the connection is
+// not sampled, being the consequence of the client leaving; its panic still is.
+func ErrorSampleMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		ref := errorSampleSink.Load()
+		if ref == nil {
+			return
+		}
+		stack := c.GetString(recoveredPanicKey)
+		status := c.Writer.Status()
+		if stack == "" && (status < 500 || response.ClientAborted(c)) {
+			return
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		ref.sink.SampleError(c, ErrorSample{
+			Method:        c.Request.Method,
+			Route:         route,
+			Status:        status,
+			CorrelationID: sampleCorrelationID(c),
+			Panicked:      stack != "",
+			Stack:         stack,
+			At:            time.Now(),
+		})
+	}
+}
+
+// sampleCorrelationID returns the correlation ID the response was sent with, else the one of
+// the request, without creating one
+func sampleCorrelationID(c *gin.Context) string {
+	if id := c.Writer.Header().Get(response.HeaderRequestID); id != "" {
+		return id
+	}
+	if id := c.GetHeader(response.HeaderRequestID); id != "" {
+		return id
+	}
+	id, _ := c.Request.Context().Value(reqid.CtxRequestIdKey{}).(string)
+	return id
+}
@@ -3,8 +3,10 @@ package middlewares
 import (
 	"fmt"
 	"net/http"
+	"runtime/debug"
 	"strings"
 
+	auth "MgApplication/api-authz"
 	config "MgApplication/api-config"
 	apierrors "MgApplication/api-errors"
 	log "MgApplication/api-log"
@@ -21,13 +23,13 @@ func Recover(cfg *config.Config) gin.HandlerFunc {
 				var err apierrors.AppError
 				if e, ok := r.(error); ok {
 
-					err = apierrors.NewAppError("500", "500", e)
+					err = apierrors.NewAppError("500", 500, e)
 				} else {
-					err = apierrors.NewAppError("500", "500", fmt.Errorf("%v", r))
+					err = apierrors.NewAppError("500", 500, fmt.Errorf("%v", r))
 				}
 				// Log a concise panic header
 				zl := log.GetBaseLoggerInstance().ToZerolog()
-				zl.Error().Str("code", err.Code).Msgf("Panic: %s", err.Error())
+				zl.Error().Int("code", err.Code).Msgf("Panic: %s", err.Error())
 
 				if cfg.GetString("log.level") == "debug" {
 
@@ -51,6 +53,8 @@ func Recover(cfg *config.Config) gin.HandlerFunc {
 					span.SetStatus(codes.Error, fmt.Sprintf("panic: %v", err.Stack))
 				}
 
+				// the error sample middleware records the panic with its stack
+				c.Set(recoveredPanicKey, string(debug.Stack()))
 				c.AbortWithStatus(http.StatusInternalServerError)
 			}
 		}()
@@ -64,8 +68,41 @@ func SetCtxLoggerMiddleware() gin.HandlerFunc {
 	}
 }
 
+// SetCtxTraceIDMiddleware makes the request logger log the trace ID of the active span as
+// trace_id. It must run between the tracer middleware and SetCtxLoggerMiddleware.
+func SetCtxTraceIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log.SetCtxTraceIDMiddleware(c)
+	}
+}
+
 func RequestResponseLoggerMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log.RequestResponseLoggerMiddleware(c)
 	}
 }
+
+// BodySampleMiddleware logs the request and response bodies of a sample of the requests using the
+// log.bodysample configuration. In the prod environment only the requests whose bearer token has
+// the admin.scope scope may ask for their bodies with the header, so it must run after the
+// token verification.
+func BodySampleMiddleware(cfg *config.Config) gin.HandlerFunc {
+	sampleCfg := log.DefaultBodySampleConfig()
+	sampleCfg.Rate = cfg.GetFloat64("log.bodysample.rate")
+	if cfg.Exists("log.bodysample.header") {
+		sampleCfg.Header = cfg.GetString("log.bodysample.header")
+	}
+	if cfg.IsProdEnv() {
+		adminScope := cfg.GetString("admin.scope")
+		sampleCfg.HeaderAllowed = func(c *gin.Context) bool {
+			return auth.ClaimsFrom(c.Request.Context()).HasScope(adminScope)
+		}
+	}
+	if maxBytes := cfg.GetInt("log.bodysample.maxbytes"); maxBytes > 0 {
+		sampleCfg.MaxBytes = maxBytes
+	}
+	if fields := cfg.GetStringSlice("log.bodysample.redactfields"); len(fields) > 0 {
+		sampleCfg.RedactFields = fields
+	}
+	return log.BodySampleMiddleware(sampleCfg)
+}
@@ -5,6 +5,8 @@ import (
 	"strconv"
 	"sync"
 
+	"MgApplication/api-server/response"
+
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -123,11 +125,14 @@ func RequestMetricsMiddlewareWithConfig(config RequestMetricsMiddlewareConfig) g
 		c.Next()
 		timer.ObserveDuration()
 
+		// the requests whose client left are counted apart from the errors of the server
 		status := ""
-		if config.NormalizeResponseStatus {
+		if config.NormalizeResponseStatus && response.ClientAborted(c) {
+			status = response.OutcomeClientAborted
+		} else if config.NormalizeResponseStatus {
 			status = StatusNormalisation(c.Writer.Status())
 		} else {
-			status = strconv.Itoa(c.Writer.Status())
+			status = strconv.Itoa(response.ResponseStatus(c))
 		}
 
 		httpRequestsCounter.WithLabelValues(status, req.Method, path).Inc()
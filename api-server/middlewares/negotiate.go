@@ -0,0 +1,21 @@
+package middlewares
+
+import (
+	apierrors "MgApplication/api-errors"
+	"MgApplication/api-server/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentNegotiation rejects requests whose Accept header allows none of the supported
+// response formats. Requests without an Accept header, or accepting any type, are served JSON.
+func ContentNegotiation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if response.NegotiateFormat(c) == "" {
+			apierrors.HandleNotAcceptableError(c, []string{response.MediaTypeJSON, response.MediaTypeXML})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
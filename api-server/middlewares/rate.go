@@ -1,14 +1,17 @@
 package middlewares
 
 import (
-	"net/http"
+	"time"
 	//r "testencrypt/rate-gin/ratelimiter"
 
+	apierrors "MgApplication/api-errors"
 	rate "MgApplication/api-server/ratelimiter"
 
 	"github.com/gin-gonic/gin"
 )
 
+// RateMiddleware rejects with 429 and the TRAFFIC_SHAPED error id the requests the global
+// bucket does not allow
 func RateMiddleware(globalBucket *rate.LeakyBucket) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
@@ -16,9 +19,8 @@ func RateMiddleware(globalBucket *rate.LeakyBucket) gin.HandlerFunc {
 			c.Next()
 		} else {
 
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error": "Traffic shaping limit exceeded",
-			})
+			apierrors.HandleBackpressureError(c, apierrors.NewBackpressureError(apierrors.SourceTrafficShaped, time.Time{}, "Traffic shaping limit exceeded", nil))
+			c.Abort()
 		}
 
 	}
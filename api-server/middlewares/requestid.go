@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"MgApplication/api-server/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxRequestIDLength is the longest X-Request-Id kept from a client
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware echoes the X-Request-Id of every request in its response, generating one
+// when the client sent none, or one longer than maxRequestIDLength or with characters other than
+// printable ASCII. The ID is set on the request too, so that response.CorrelationID, the logs and
+// the error samples all give the one echoed. It comes first, before the middlewares answering
+// on their own.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(response.HeaderRequestID)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+			c.Request.Header.Set(response.HeaderRequestID, id)
+		}
+		c.Header(response.HeaderRequestID, id)
+		c.Next()
+	}
+}
+
+// validRequestID reports whether the X-Request-Id of a client is kept
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
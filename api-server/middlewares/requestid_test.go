@@ -0,0 +1,76 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apierrors "MgApplication/api-errors"
+	"MgApplication/api-server/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRequestIDMiddleware serves successful and failed requests with and without an
+// X-Request-Id, each response echoing the ID the request is correlated with
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(RequestIDMiddleware())
+	var correlated string
+	engine.GET("/ok", func(c *gin.Context) {
+		correlated = response.CorrelationID(c)
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+	engine.GET("/fail", func(c *gin.Context) {
+		correlated = response.CorrelationID(c)
+		apierrors.ErrorResponseWithStatusCodeAndMessage(c, apierrors.HTTPErrorBadRequest, "bad request", errors.New("bad request"))
+	})
+	engine.GET("/abort", func(c *gin.Context) {
+		correlated = ""
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		code   int
+		echoed bool
+	}{
+		{name: "success with an id", path: "/ok", header: "client-req-1", code: http.StatusOK, echoed: true},
+		{name: "success without an id", path: "/ok", code: http.StatusOK},
+		{name: "error with an id", path: "/fail", header: "client-req-2", code: http.StatusBadRequest, echoed: true},
+		{name: "error without an id", path: "/fail", code: http.StatusBadRequest},
+		{name: "aborted", path: "/abort", header: "client-req-3", code: http.StatusInternalServerError, echoed: true},
+		{name: "unknown route", path: "/missing", header: "client-req-4", code: http.StatusNotFound, echoed: true},
+		{name: "id too long", path: "/ok", header: strings.Repeat("a", maxRequestIDLength+1), code: http.StatusOK},
+		{name: "id with spaces", path: "/ok", header: "client req", code: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			correlated = ""
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(response.HeaderRequestID, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.code, rec.Code)
+			id := rec.Header().Get(response.HeaderRequestID)
+			assert.NotEmpty(t, id)
+			if tt.echoed {
+				assert.Equal(t, tt.header, id)
+			} else {
+				assert.NotEqual(t, tt.header, id)
+			}
+			if correlated != "" {
+				assert.Equal(t, correlated, id, "the handler correlates the request with the echoed id")
+			}
+		})
+	}
+}
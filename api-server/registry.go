@@ -96,12 +96,23 @@ func (r *registry) SwaggerDefs() []swagger.EndpointDef {
 }
 
 func (r *registry) toSwagDefinition(m route.Meta) swagger.EndpointDef {
-	return swagger.EndpointDef{
+	ed := swagger.EndpointDef{
 		RequestType:  m.Req,
 		ResponseType: m.Res,
 		Group:        r.name,
 		Name:         m.Name,
 		Endpoint:     m.Path,
 		Method:       m.Method,
+		Summary:      m.Summary,
+		Description:  m.Desc,
+		Order:        m.Order,
 	}
+	// the tag metadata is declared by handlers embedding handler.Base
+	if d, ok := r.ct.(interface{ Description() string }); ok {
+		ed.GroupDescription = d.Description()
+	}
+	if o, ok := r.ct.(interface{ Order() int }); ok {
+		ed.GroupOrder = o.Order()
+	}
+	return ed
 }
@@ -0,0 +1,34 @@
+package response
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatusClientClosedRequest is the status a request is recorded with when its client closed
+// the connection before it was answered, the 499 of nginx. It is never sent, the client being
+// gone.
+const StatusClientClosedRequest = 499
+
+// OutcomeClientAborted is the outcome of the requests whose client closed the connection, the
+// status label of their request metrics and the outcome of their access log
+const OutcomeClientAborted = "client_aborted"
+
+// ClientAborted reports whether the client of c closed the connection before it was answered:
+// net/http cancels the context of a request once its connection closes. The response of a
+// request being buffered until its handler returns, a client gone before the middlewares
+// unwind did not receive it, even when it was written.
+func ClientAborted(c *gin.Context) bool {
+	return errors.Is(c.Request.Context().Err(), context.Canceled)
+}
+
+// ResponseStatus returns the status c was answered with, StatusClientClosedRequest when its
+// client closed the connection before
+func ResponseStatus(c *gin.Context) int {
+	if ClientAborted(c) {
+		return StatusClientClosedRequest
+	}
+	return c.Writer.Status()
+}
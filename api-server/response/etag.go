@@ -0,0 +1,83 @@
+package response
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tagger is implemented by responses that carry an entity tag, enabling conditional
+// GET requests with If-None-Match.
+type Tagger interface {
+	ETag() string
+}
+
+// NewETag returns a strong entity tag computed from the JSON encoding of the entity,
+// so that the tag changes whenever the representation does. An empty string is
+// returned if the entity cannot be encoded.
+func NewETag(entity any) string {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// NotModified sets the ETag header and, for GET and HEAD requests whose If-None-Match
+// header matches the tag, writes a 304 Not Modified response without a body.
+// It reports whether the 304 response was written.
+//
+// The JSON and XML representations of an entity differ, so the tag is qualified with the
+// media type negotiated from the Accept header and the response varies on Accept.
+func NotModified(c *gin.Context, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	etag = representationETag(etag, NegotiateFormat(c))
+	c.Writer.Header().Add("Vary", "Accept")
+	c.Header("ETag", etag)
+
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return false
+	}
+	if !ETagMatches(c.GetHeader("If-None-Match"), etag) {
+		return false
+	}
+	c.Status(http.StatusNotModified)
+	c.Writer.WriteHeaderNow()
+	return true
+}
+
+// representationETag qualifies an entity tag with the subtype of the media type the entity
+// is rendered in, e.g. "<hash>+xml". JSON is assumed when no media type was negotiated.
+func representationETag(etag, mediaType string) string {
+	if mediaType == "" {
+		mediaType = MediaTypeJSON
+	}
+	subtype := mediaType[strings.IndexByte(mediaType, '/')+1:]
+	return strings.TrimSuffix(etag, `"`) + "+" + subtype + `"`
+}
+
+// ETagMatches reports whether an If-None-Match header value matches the tag, using
+// the weak comparison required for If-None-Match.
+func ETagMatches(ifNoneMatch, etag string) bool {
+	ifNoneMatch = strings.TrimSpace(ifNoneMatch)
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	if ifNoneMatch == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewETag(t *testing.T) {
+	first := NewETag(map[string]any{"application_id": 4, "status": 1})
+
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, first)
+	assert.Equal(t, first, NewETag(map[string]any{"application_id": 4, "status": 1}), "tag should be stable for the same content")
+	assert.NotEqual(t, first, NewETag(map[string]any{"application_id": 4, "status": 0}), "tag should change with the content")
+	assert.Empty(t, NewETag(func() {}), "unencodable entities have no tag")
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := `"abc"`
+	tests := []struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		{ifNoneMatch: `"abc"`, want: true},
+		{ifNoneMatch: `W/"abc"`, want: true},
+		{ifNoneMatch: `"xyz", "abc"`, want: true},
+		{ifNoneMatch: `*`, want: true},
+		{ifNoneMatch: `"xyz"`, want: false},
+		{ifNoneMatch: `abc`, want: false},
+		{ifNoneMatch: ``, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ifNoneMatch, func(t *testing.T) {
+			assert.Equal(t, tt.want, ETagMatches(tt.ifNoneMatch, etag))
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	etag := NewETag("entity")
+
+	tests := []struct {
+		name        string
+		method      string
+		ifNoneMatch string
+		wantNotMod  bool
+		wantStatus  int
+	}{
+		{name: "matching tag", method: http.MethodGet, ifNoneMatch: representationETag(etag, MediaTypeJSON), wantNotMod: true, wantStatus: http.StatusNotModified},
+		{name: "entity tag without media type", method: http.MethodGet, ifNoneMatch: etag, wantStatus: http.StatusOK},
+		{name: "stale tag", method: http.MethodGet, ifNoneMatch: `"stale"`, wantStatus: http.StatusOK},
+		{name: "no tag", method: http.MethodGet, wantStatus: http.StatusOK},
+		{name: "non GET request", method: http.MethodPut, ifNoneMatch: representationETag(etag, MediaTypeJSON), wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(tt.method, "/", nil)
+			if tt.ifNoneMatch != "" {
+				c.Request.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+
+			notModified := NotModified(c, etag)
+			if !notModified {
+				c.String(http.StatusOK, "body")
+			}
+
+			assert.Equal(t, tt.wantNotMod, notModified)
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, representationETag(etag, MediaTypeJSON), w.Header().Get("ETag"))
+			assert.Equal(t, "Accept", w.Header().Get("Vary"))
+			if tt.wantNotMod {
+				assert.Empty(t, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestNotModified_TagsEachRepresentation(t *testing.T) {
+	etag := NewETag("entity")
+	tags := map[string]string{}
+	for _, accept := range []string{"", "application/json", "application/xml", "text/xml"} {
+		gin.SetMode(gin.TestMode)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		if accept != "" {
+			c.Request.Header.Set("Accept", accept)
+		}
+		NotModified(c, etag)
+		tags[accept] = w.Header().Get("ETag")
+	}
+
+	assert.Equal(t, tags[""], tags["application/json"])
+	assert.Equal(t, tags["application/xml"], tags["text/xml"])
+	assert.NotEqual(t, tags["application/json"], tags["application/xml"])
+	assert.Equal(t, strings.TrimSuffix(etag, `"`)+`+xml"`, tags["application/xml"])
+}
@@ -0,0 +1,27 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderLocation carries the URL of the resource created by a request
+const HeaderLocation = "Location"
+
+// Locator is implemented by envelopes that know the URL the resource they describe is
+// fetched from
+type Locator interface {
+	Location() string
+}
+
+// locate sets the Location header of a 201 response to the URL of the resource created, for
+// payloads implementing Locator
+func locate(c *gin.Context, status int, payload any) {
+	if status != http.StatusCreated {
+		return
+	}
+	if locator, ok := payload.(Locator); ok && locator.Location() != "" {
+		c.Header(HeaderLocation, locator.Location())
+	}
+}
@@ -0,0 +1,46 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type locatedTestPayload struct {
+	Message  string `json:"message"`
+	location string
+}
+
+func (p locatedTestPayload) Location() string {
+	return p.location
+}
+
+func TestRespondLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		location string
+		want     string
+	}{
+		{name: "created", status: http.StatusCreated, location: "/v1/applications/7", want: "/v1/applications/7"},
+		{name: "created without location", status: http.StatusCreated},
+		{name: "not created", status: http.StatusOK, location: "/v1/applications/7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1/applications", nil)
+
+			Respond(c, tt.status, locatedTestPayload{Message: "created", location: tt.location})
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.want, w.Header().Get(HeaderLocation))
+			assert.JSONEq(t, `{"message":"created"}`, w.Body.String())
+		})
+	}
+}
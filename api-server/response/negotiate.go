@@ -0,0 +1,146 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	MediaTypeJSON    = "application/json"
+	MediaTypeXML     = "application/xml"
+	MediaTypeTextXML = "text/xml"
+
+	// xmlRootElement and xmlItemElement name the envelope root and the entries of arrays in XML responses
+	xmlRootElement = "response"
+	xmlItemElement = "item"
+)
+
+// offeredMediaTypes lists the response formats in order of preference; the first is the default
+var offeredMediaTypes = []string{MediaTypeJSON, MediaTypeXML, MediaTypeTextXML}
+
+// NegotiateFormat returns the response media type matching the request Accept header.
+// JSON is returned when the header is absent or accepts any type, and an empty string
+// when none of the supported formats is acceptable.
+func NegotiateFormat(c *gin.Context) string {
+	switch c.NegotiateFormat(offeredMediaTypes...) {
+	case MediaTypeJSON:
+		return MediaTypeJSON
+	case MediaTypeXML, MediaTypeTextXML:
+		return MediaTypeXML
+	default:
+		return ""
+	}
+}
+
+// Respond writes the payload with the given status in the format negotiated from the
+// Accept header. Payloads are serialized as JSON unless XML is requested, in which case
+// the same envelope is rendered as XML using the JSON field names. Envelopes implementing
+// Traced are stamped with the correlation ID and the time first, and the timestamps of the
+// payload are normalized to RFC3339 in UTC.
+func Respond(c *gin.Context, status int, payload any) {
+	stamp(c, payload)
+	locate(c, status, payload)
+	NormalizeTimes(payload)
+	if NegotiateFormat(c) == MediaTypeXML {
+		data, err := MarshalXML(payload)
+		if err == nil {
+			c.Data(status, MediaTypeXML+"; charset=utf-8", data)
+			return
+		}
+		_ = c.Error(err)
+	}
+	c.JSON(status, payload)
+}
+
+// MarshalXML renders the payload as an XML document rooted at a <response> element.
+// The payload is first encoded as JSON so that XML responses carry the same field
+// names and omissions as JSON responses; arrays are rendered as repeated <item> elements.
+func MarshalXML(payload any) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	if err := encodeXMLElement(enc, dec, xmlRootElement); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeXMLElement reads the next JSON value from dec and writes it as an element named name
+func encodeXMLElement(enc *xml.Encoder, dec *json.Decoder, name string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		if tok == nil {
+			return encodeTokens(enc, start, start.End())
+		}
+		return enc.EncodeElement(fmt.Sprint(tok), start)
+	}
+
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	for dec.More() {
+		childName := xmlItemElement
+		if delim == '{' {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			childName = xmlElementName(keyTok.(string))
+		}
+		if err := encodeXMLElement(enc, dec, childName); err != nil {
+			return err
+		}
+	}
+	// consume the closing delimiter
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	return enc.EncodeToken(start.End())
+}
+
+func encodeTokens(enc *xml.Encoder, tokens ...xml.Token) error {
+	for _, t := range tokens {
+		if err := enc.EncodeToken(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// xmlElementName converts a JSON key into a valid XML element name
+func xmlElementName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, key)
+	if first, _ := utf8.DecodeRuneInString(name); !unicode.IsLetter(first) && first != '_' {
+		name = "_" + name
+	}
+	return name
+}
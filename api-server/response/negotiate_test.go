@@ -0,0 +1,106 @@
+package response
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type negotiateTestPayload struct {
+	StatusCode int      `json:"status_code"`
+	Success    bool     `json:"success"`
+	Message    string   `json:"message"`
+	Data       []string `json:"data"`
+	Skipped    string   `json:"skipped,omitempty"`
+	Internal   string   `json:"-"`
+}
+
+func serveRespond(accept string, payload any) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	if accept != "" {
+		c.Request.Header.Set("Accept", accept)
+	}
+	Respond(c, http.StatusCreated, payload)
+	return w
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{accept: "", want: MediaTypeJSON},
+		{accept: "*/*", want: MediaTypeJSON},
+		{accept: "application/json", want: MediaTypeJSON},
+		{accept: "application/xml", want: MediaTypeXML},
+		{accept: "text/xml", want: MediaTypeXML},
+		{accept: "application/xml, application/json", want: MediaTypeXML},
+		{accept: "text/html", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				c.Request.Header.Set("Accept", tt.accept)
+			}
+			assert.Equal(t, tt.want, NegotiateFormat(c))
+		})
+	}
+}
+
+func TestRespond_DefaultsToJSON(t *testing.T) {
+	w := serveRespond("", negotiateTestPayload{StatusCode: 201, Success: true, Message: "created"})
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), MediaTypeJSON))
+	assert.Contains(t, w.Body.String(), `"status_code":201`)
+}
+
+func TestRespond_XMLEnvelope(t *testing.T) {
+	payload := negotiateTestPayload{
+		StatusCode: 201,
+		Success:    true,
+		Message:    "created <ok>",
+		Data:       []string{"a", "b"},
+		Internal:   "secret",
+	}
+	w := serveRespond("application/xml", payload)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), MediaTypeXML))
+
+	var envelope struct {
+		XMLName    xml.Name `xml:"response"`
+		StatusCode int      `xml:"status_code"`
+		Success    bool     `xml:"success"`
+		Message    string   `xml:"message"`
+		Data       []string `xml:"data>item"`
+	}
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &envelope))
+	assert.Equal(t, 201, envelope.StatusCode)
+	assert.True(t, envelope.Success)
+	assert.Equal(t, "created <ok>", envelope.Message)
+	assert.Equal(t, []string{"a", "b"}, envelope.Data)
+
+	// Fields omitted from JSON are omitted from XML as well
+	assert.NotContains(t, w.Body.String(), "skipped")
+	assert.NotContains(t, w.Body.String(), "secret")
+}
+
+func TestMarshalXML_ElementNames(t *testing.T) {
+	data, err := MarshalXML(map[string]any{"1st value": nil})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "<_1st_value></_1st_value>")
+}
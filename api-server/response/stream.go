@@ -0,0 +1,205 @@
+package response
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"iter"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "MgApplication/api-log"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// StreamFlushEvery is the number of elements written between two flushes of a streamed array
+	StreamFlushEvery = 100
+
+	// StreamStatusTrailer is the trailer carrying the outcome of a streamed array: 200 when it is
+	// complete, 500 when it was cut short by an error after the 200 status line was sent
+	StreamStatusTrailer = "X-Stream-Status"
+
+	// MediaTypeNDJSON is the media type of newline delimited JSON, one JSON value per line
+	MediaTypeNDJSON = "application/x-ndjson"
+)
+
+// streamError is the last element of a streamed array cut short by an error, the error itself
+// being logged only
+type streamError struct {
+	Error string `json:"error"`
+}
+
+const streamErrorMessage = "the list is incomplete, it was interrupted by an internal error"
+
+// StreamJSONArray writes the elements of items to the response as a JSON array as they are
+// produced, flushing every StreamFlushEvery elements, so that lists too large to be held in
+// memory can be answered. Time values of the elements are normalized as by Respond. The array is
+// always JSON, whatever the Accept header.
+//
+// An error yielded by items before the first element is returned without anything written, for
+// the caller to answer as usual. Once the status line is sent an error can no longer change it:
+// the array is closed with a trailing {"error": "..."} element, StreamStatusTrailer is set to 500
+// and the failure is logged, before the error is returned.
+func StreamJSONArray[T any](c *gin.Context, items iter.Seq2[T, error]) error {
+	next, stop := iter.Pull2(items)
+	defer stop()
+
+	item, err, ok := next()
+	if err != nil {
+		return err
+	}
+
+	rc := http.NewResponseController(c.Writer)
+	// Large arrays outlive the server write timeout
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", MediaTypeJSON+"; charset=utf-8")
+	header.Set("X-Accel-Buffering", "no")
+	header.Set("Trailer", StreamStatusTrailer)
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	enc := json.NewEncoder(w)
+	if _, err := w.WriteString("["); err != nil {
+		return err
+	}
+	for n := 0; ok; n++ {
+		if err != nil {
+			return failStream(c, w, enc, n, err)
+		}
+		if n > 0 {
+			if _, err := w.WriteString(","); err != nil {
+				return err
+			}
+		}
+		NormalizeTimes(&item)
+		if err := enc.Encode(item); err != nil {
+			return failStream(c, w, enc, n, err)
+		}
+		if (n+1)%StreamFlushEvery == 0 {
+			if err := rc.Flush(); err != nil {
+				// the client went away
+				return err
+			}
+		}
+		item, err, ok = next()
+	}
+	if _, err := w.WriteString("]"); err != nil {
+		return err
+	}
+	header.Set(StreamStatusTrailer, strconv.Itoa(http.StatusOK))
+	return nil
+}
+
+// StreamNDJSON writes the elements of items to the response as newline delimited JSON, one
+// object per line, as they are produced, so that clients can process them as they arrive. As for
+// the PDF exports, the lines are encoded by a goroutine into an io.Pipe the response is copied
+// from, flushed every StreamFlushEvery lines, and the memory held stays bounded whatever the
+// number of elements. Time values of the elements are normalized as by Respond.
+//
+// Errors are handled as by StreamJSONArray: an error yielded before the first element is
+// returned without anything written, a later one ends the stream with an {"error": "..."} line,
+// sets StreamStatusTrailer to 500 and is logged before being returned.
+func StreamNDJSON[T any](c *gin.Context, items iter.Seq2[T, error]) error {
+	next, stop := iter.Pull2(items)
+	defer stop()
+
+	item, err, ok := next()
+	if err != nil {
+		return err
+	}
+
+	rc := http.NewResponseController(c.Writer)
+	// Large exports outlive the server write timeout
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", MediaTypeNDJSON)
+	header.Set("X-Accel-Buffering", "no")
+	header.Set("Trailer", StreamStatusTrailer)
+	c.Status(http.StatusOK)
+
+	type encoded struct {
+		lines int
+		err   error
+	}
+	r, w := io.Pipe()
+	done := make(chan encoded, 1)
+	go func() {
+		buf := bufio.NewWriterSize(w, 32*1024)
+		enc := json.NewEncoder(buf)
+		var result encoded
+		for ; ok; item, err, ok = next() {
+			if err == nil {
+				NormalizeTimes(&item)
+				err = enc.Encode(item)
+			}
+			if err != nil {
+				result.err = err
+				_ = enc.Encode(streamError{Error: streamErrorMessage})
+				break
+			}
+			result.lines++
+			if result.lines%StreamFlushEvery == 0 && buf.Flush() != nil {
+				// the client went away, the copy reports it
+				break
+			}
+		}
+		_ = buf.Flush()
+		done <- result
+		_ = w.Close()
+	}()
+
+	if err := copyFlushed(c.Writer, rc, r); err != nil {
+		// the client went away, unblock the encoding
+		_ = r.CloseWithError(err)
+		<-done
+		return err
+	}
+	result := <-done
+	if result.err != nil {
+		log.Error(c, "Streamed lines failed after %d lines, answered with status 200 and trailer %s %d: %s",
+			result.lines, StreamStatusTrailer, http.StatusInternalServerError, result.err.Error())
+		header.Set(StreamStatusTrailer, strconv.Itoa(http.StatusInternalServerError))
+		return result.err
+	}
+	header.Set(StreamStatusTrailer, strconv.Itoa(http.StatusOK))
+	return nil
+}
+
+// copyFlushed copies r to w until r ends, flushing w after every read. It returns the error of a
+// write or flush only, the end of r being its writer's to report.
+func copyFlushed(w io.Writer, rc *http.ResponseController, r io.Reader) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+			if err := rc.Flush(); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// failStream closes an array cut short after written elements with the error element
+func failStream(c *gin.Context, w gin.ResponseWriter, enc *json.Encoder, written int, err error) error {
+	log.Error(c, "Streamed array failed after %d elements, answered with status 200 and trailer %s %d: %s",
+		written, StreamStatusTrailer, http.StatusInternalServerError, err.Error())
+	c.Writer.Header().Set(StreamStatusTrailer, strconv.Itoa(http.StatusInternalServerError))
+	if written > 0 {
+		_, _ = w.WriteString(",")
+	}
+	_ = enc.Encode(streamError{Error: streamErrorMessage})
+	_, _ = w.WriteString("]")
+	return err
+}
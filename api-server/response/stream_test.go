@@ -0,0 +1,241 @@
+package response
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type streamTestItem struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// streamTestItems yields n items, then failure when it is set
+func streamTestItems(n int, failure error) iter.Seq2[streamTestItem, error] {
+	ist := time.FixedZone("IST", 5*60*60+30*60)
+	return func(yield func(streamTestItem, error) bool) {
+		for i := 0; i < n; i++ {
+			item := streamTestItem{ID: i, Name: fmt.Sprintf("template %d", i), CreatedAt: time.Date(2024, 8, 27, 10, 0, 0, 5, ist)}
+			if !yield(item, nil) {
+				return
+			}
+		}
+		if failure != nil {
+			yield(streamTestItem{}, failure)
+		}
+	}
+}
+
+func serveStream(items iter.Seq2[streamTestItem, error]) (*httptest.ResponseRecorder, *gin.Context, error) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/?stream=true", nil)
+	err := StreamJSONArray(c, items)
+	return w, c, err
+}
+
+func TestStreamJSONArray(t *testing.T) {
+	w, _, err := serveStream(streamTestItems(StreamFlushEvery+1, nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.True(t, w.Flushed)
+	var items []streamTestItem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items), w.Body.String())
+	require.Len(t, items, StreamFlushEvery+1)
+	assert.Equal(t, "template 100", items[100].Name)
+	assert.Contains(t, w.Body.String(), `"created_at":"2024-08-27T04:30:00Z"`)
+	assert.Equal(t, "200", w.Result().Trailer.Get(StreamStatusTrailer))
+}
+
+func TestStreamJSONArray_Empty(t *testing.T) {
+	w, _, err := serveStream(streamTestItems(0, nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+}
+
+// An error before the first element leaves the response to the caller
+func TestStreamJSONArray_ErrorBeforeFirstElement(t *testing.T) {
+	failure := errors.New("connection refused")
+	w, c, err := serveStream(streamTestItems(0, failure))
+	assert.ErrorIs(t, err, failure)
+	assert.False(t, c.Writer.Written())
+	assert.Empty(t, w.Body.String())
+}
+
+// An error once the status is sent closes the array with an error element
+func TestStreamJSONArray_ErrorMidStream(t *testing.T) {
+	failure := errors.New("canceling statement due to statement timeout")
+	w, _, err := serveStream(streamTestItems(3, failure))
+	assert.ErrorIs(t, err, failure)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var items []map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items), w.Body.String())
+	require.Len(t, items, 4)
+	assert.Equal(t, map[string]any{"error": streamErrorMessage}, items[3])
+	assert.NotContains(t, w.Body.String(), "statement timeout")
+	assert.Equal(t, "500", w.Result().Trailer.Get(StreamStatusTrailer))
+}
+
+// discardResponseWriter counts the bytes written to it without keeping them
+type discardResponseWriter struct {
+	header  http.Header
+	written int
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+func (w *discardResponseWriter) Flush()                      {}
+func (w *discardResponseWriter) Write(p []byte) (int, error) { w.written += len(p); return len(p), nil }
+
+// The memory held while streaming stays flat however many elements are written
+func TestStreamJSONArray_MemoryStaysFlat(t *testing.T) {
+	const (
+		elements    = 100_000
+		sampleEvery = 10_000
+		heapBudget  = 4 << 20 // 100k elements are about 8 MiB of JSON
+	)
+
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	baseline := stats.HeapAlloc
+
+	var peak uint64
+	items := func(yield func(streamTestItem, error) bool) {
+		for item, err := range streamTestItems(elements, nil) {
+			if item.ID%sampleEvery == 0 {
+				runtime.GC()
+				runtime.ReadMemStats(&stats)
+				peak = max(peak, stats.HeapAlloc)
+			}
+			if !yield(item, err) {
+				return
+			}
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := &discardResponseWriter{header: http.Header{}}
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/?stream=true", nil)
+	require.NoError(t, StreamJSONArray(c, items))
+
+	assert.Greater(t, w.written, elements*len(`{"id":0,"name":"template 0","created_at":"2024-08-27T04:30:00Z"}`))
+	if peak > baseline {
+		assert.Less(t, peak-baseline, uint64(heapBudget), "heap grew while streaming")
+	}
+}
+
+func serveNDJSON(items iter.Seq2[streamTestItem, error]) (*httptest.ResponseRecorder, *gin.Context, error) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/?format=ndjson", nil)
+	err := StreamNDJSON(c, items)
+	return w, c, err
+}
+
+// ndjsonLines decodes the lines of body, one JSON object each
+func ndjsonLines(t *testing.T, body []byte) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line), scanner.Text())
+		lines = append(lines, line)
+	}
+	require.NoError(t, scanner.Err())
+	return lines
+}
+
+func TestStreamNDJSON(t *testing.T) {
+	const elements = 3*StreamFlushEvery + 7
+	w, _, err := serveNDJSON(streamTestItems(elements, nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, MediaTypeNDJSON, w.Header().Get("Content-Type"))
+	assert.True(t, w.Flushed)
+	assert.Equal(t, elements, bytes.Count(w.Body.Bytes(), []byte("\n")))
+	lines := ndjsonLines(t, w.Body.Bytes())
+	require.Len(t, lines, elements)
+	assert.Equal(t, "template 306", lines[306]["name"])
+	assert.Equal(t, "2024-08-27T04:30:00Z", lines[0]["created_at"])
+	assert.Equal(t, "200", w.Result().Trailer.Get(StreamStatusTrailer))
+}
+
+func TestStreamNDJSON_Empty(t *testing.T) {
+	w, _, err := serveNDJSON(streamTestItems(0, nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+// An error before the first element leaves the response to the caller
+func TestStreamNDJSON_ErrorBeforeFirstElement(t *testing.T) {
+	failure := errors.New("connection refused")
+	w, c, err := serveNDJSON(streamTestItems(0, failure))
+	assert.ErrorIs(t, err, failure)
+	assert.False(t, c.Writer.Written())
+	assert.Empty(t, w.Body.String())
+}
+
+// An error once the status is sent ends the lines with an error line
+func TestStreamNDJSON_ErrorMidStream(t *testing.T) {
+	failure := errors.New("canceling statement due to statement timeout")
+	w, _, err := serveNDJSON(streamTestItems(StreamFlushEvery+2, failure))
+	assert.ErrorIs(t, err, failure)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	lines := ndjsonLines(t, w.Body.Bytes())
+	require.Len(t, lines, StreamFlushEvery+3)
+	assert.Equal(t, map[string]any{"error": streamErrorMessage}, lines[StreamFlushEvery+2])
+	assert.NotContains(t, w.Body.String(), "statement timeout")
+	assert.Equal(t, "500", w.Result().Trailer.Get(StreamStatusTrailer))
+}
+
+// failingResponseWriter fails every write, as the connection of a client gone away
+type failingResponseWriter struct {
+	discardResponseWriter
+}
+
+func (w *failingResponseWriter) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+// A client gone away stops the encoding of the lines without waiting for the end of items
+func TestStreamNDJSON_ClientGone(t *testing.T) {
+	pulled := 0
+	items := func(yield func(streamTestItem, error) bool) {
+		for item, err := range streamTestItems(100*StreamFlushEvery, nil) {
+			pulled++
+			if !yield(item, err) {
+				return
+			}
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(&failingResponseWriter{discardResponseWriter{header: http.Header{}}})
+	c.Request = httptest.NewRequest(http.MethodGet, "/?format=ndjson", nil)
+	assert.Error(t, StreamNDJSON(c, items))
+	assert.Less(t, pulled, 100*StreamFlushEvery)
+}
@@ -0,0 +1,67 @@
+package response
+
+import (
+	"reflect"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// NormalizeTimes rewrites the time.Time values reachable from payload to UTC truncated to
+// the second, so that every timestamp of a response is rendered as RFC3339 in UTC
+// ("2024-08-27T04:30:00Z") whatever the zone and precision it was read with. Fields left out
+// of the JSON encoding and values not reachable through exported fields, pointers, slices
+// or maps are left as is.
+func NormalizeTimes(payload any) {
+	normalizeTimes(reflect.ValueOf(payload), map[uintptr]bool{})
+}
+
+func normalizeTimes(v reflect.Value, seen map[uintptr]bool) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() || seen[v.Pointer()] {
+			return
+		}
+		seen[v.Pointer()] = true
+		normalizeTimes(v.Elem(), seen)
+	case reflect.Interface:
+		if !v.IsNil() {
+			normalizeTimes(v.Elem(), seen)
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			if v.CanSet() {
+				v.Set(reflect.ValueOf(normalizeTime(v.Interface().(time.Time))))
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.IsExported() && field.Tag.Get("json") != "-" {
+				normalizeTimes(v.Field(i), seen)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			normalizeTimes(v.Index(i), seen)
+		}
+	case reflect.Map:
+		// map values are not addressable, time values are replaced and the others walked
+		iter := v.MapRange()
+		for iter.Next() {
+			value := iter.Value()
+			if value.Type() == timeType {
+				v.SetMapIndex(iter.Key(), reflect.ValueOf(normalizeTime(value.Interface().(time.Time))))
+				continue
+			}
+			normalizeTimes(value, seen)
+		}
+	}
+}
+
+func normalizeTime(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.UTC().Truncate(time.Second)
+}
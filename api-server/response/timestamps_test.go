@@ -0,0 +1,48 @@
+package response
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type timestampsTestPayload struct {
+	CreatedDate time.Time            `json:"created_date"`
+	ExpiresAt   *time.Time           `json:"expires_at"`
+	Items       []timestampsTestItem `json:"items"`
+	Data        any                  `json:"data"`
+	Dates       map[string]time.Time `json:"dates"`
+}
+
+type timestampsTestItem struct {
+	UpdatedDate time.Time `json:"updated_date"`
+}
+
+func TestRespond_NormalizesTimestamps(t *testing.T) {
+	ist := time.FixedZone("IST", 5*60*60+30*60)
+	at := time.Date(2024, 8, 27, 10, 0, 0, 123456789, ist)
+	expiresAt := at
+	payload := &timestampsTestPayload{
+		CreatedDate: at,
+		ExpiresAt:   &expiresAt,
+		Items:       []timestampsTestItem{{UpdatedDate: at}},
+		Data:        &timestampsTestItem{UpdatedDate: at},
+		Dates:       map[string]time.Time{"sent": at},
+	}
+
+	w := serveRespond("", payload)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `"created_date":"2024-08-27T04:30:00Z"`)
+	assert.Contains(t, body, `"expires_at":"2024-08-27T04:30:00Z"`)
+	assert.Contains(t, body, `"items":[{"updated_date":"2024-08-27T04:30:00Z"}]`)
+	assert.Contains(t, body, `"data":{"updated_date":"2024-08-27T04:30:00Z"}`)
+	assert.Contains(t, body, `"dates":{"sent":"2024-08-27T04:30:00Z"}`)
+}
+
+func TestNormalizeTimes_KeepsZeroTimes(t *testing.T) {
+	payload := &timestampsTestItem{}
+	NormalizeTimes(payload)
+	assert.True(t, payload.UpdatedDate.IsZero())
+}
@@ -0,0 +1,41 @@
+package response
+
+import (
+	"time"
+
+	"MgApplication/api-server/middlewares/reqid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// HeaderRequestID carries the correlation ID of a request and its response
+const HeaderRequestID = "X-Request-Id"
+
+// Traced is implemented by envelopes carrying the correlation ID of the request they
+// answer and the time they were written
+type Traced interface {
+	SetTrace(correlationID string, at time.Time)
+}
+
+// CorrelationID returns the ID correlating the request with its response and logs: the
+// X-Request-Id sent by the client, else the ID set by the tracing middleware, else a new
+// one. The ID is echoed in the X-Request-Id response header.
+func CorrelationID(c *gin.Context) string {
+	id := c.GetHeader(HeaderRequestID)
+	if id == "" {
+		id, _ = c.Request.Context().Value(reqid.CtxRequestIdKey{}).(string)
+	}
+	if id == "" {
+		id = uuid.New().String()
+	}
+	c.Header(HeaderRequestID, id)
+	return id
+}
+
+// stamp sets the correlation ID and the time of payloads implementing Traced
+func stamp(c *gin.Context, payload any) {
+	if traced, ok := payload.(Traced); ok {
+		traced.SetTrace(CorrelationID(c), time.Now())
+	}
+}
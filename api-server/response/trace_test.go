@@ -0,0 +1,63 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"MgApplication/api-server/middlewares/reqid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type tracedTestPayload struct {
+	CorrelationID string    `json:"correlation_id"`
+	At            time.Time `json:"-"`
+}
+
+func (p *tracedTestPayload) SetTrace(correlationID string, at time.Time) {
+	p.CorrelationID = correlationID
+	p.At = at
+}
+
+func TestCorrelationID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		traced   string
+		expected string
+	}{
+		{name: "client request ID", header: "client-1", traced: "trace-1", expected: "client-1"},
+		{name: "tracing middleware ID", traced: "trace-1", expected: "trace-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				c.Request.Header.Set(HeaderRequestID, tt.header)
+			}
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), reqid.CtxRequestIdKey{}, tt.traced))
+
+			assert.Equal(t, tt.expected, CorrelationID(c))
+			assert.Equal(t, tt.expected, w.Header().Get(HeaderRequestID))
+		})
+	}
+}
+
+func TestRespond_StampsTracedEnvelopes(t *testing.T) {
+	payload := &tracedTestPayload{}
+	before := time.Now()
+	w := serveRespond("", payload)
+
+	assert.NotEmpty(t, payload.CorrelationID)
+	assert.Equal(t, payload.CorrelationID, w.Header().Get(HeaderRequestID))
+	assert.False(t, payload.At.Before(before))
+	assert.Contains(t, w.Body.String(), `"correlation_id":"`+payload.CorrelationID+`"`)
+}
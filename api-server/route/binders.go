@@ -130,14 +130,15 @@ func bindPlainText[Req any](c *gin.Context, ctx *Context, req *Req) error {
 	return nil
 }
 
-// bindMultipartForm binds multipart/form-data request body to the request struct
+// bindMultipartForm binds multipart/form-data request body to the request struct, the files
+// being checked against the upload limits (see SetUploadLimits)
 func bindMultipartForm[Req any](c *gin.Context, ctx *Context, req *Req) error {
 	if err := c.ShouldBind(req); err != nil {
 		log.Debug(ctx.Ctx, "Multipart form bind failed: %v", err)
 		apierrors.HandleBindingError(c, err)
 		return err
 	}
-	return nil
+	return checkUploads(c, ctx)
 }
 
 // bindYAML binds YAML request body to the request struct
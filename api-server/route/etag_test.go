@@ -0,0 +1,65 @@
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"MgApplication/api-server/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type taggedTestResponse struct {
+	response.Response[string]
+}
+
+func (r taggedTestResponse) ETag() string {
+	return response.NewETag(r.Data)
+}
+
+func serveTagged(t *testing.T, ifNoneMatch string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/entity", build(func(ctx *Context, _ NoParam) (taggedTestResponse, error) {
+		return taggedTestResponse{response.Response[string]{Success: true, Data: "entity"}}, nil
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/entity", nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+// TestHandleResponse_ETag tests that tagged responses carry an ETag and that a
+// matching If-None-Match is answered with 304 and no body
+func TestHandleResponse_ETag(t *testing.T) {
+	first := serveTagged(t, "")
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, first.Code)
+	}
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag header")
+	}
+
+	notModified := serveTagged(t, etag)
+	if notModified.Code != http.StatusNotModified {
+		t.Fatalf("expected status %d, got %d", http.StatusNotModified, notModified.Code)
+	}
+	if notModified.Body.Len() != 0 {
+		t.Errorf("expected no body, got %q", notModified.Body.String())
+	}
+	if notModified.Header().Get("ETag") != etag {
+		t.Errorf("expected ETag %s on 304, got %s", etag, notModified.Header().Get("ETag"))
+	}
+
+	stale := serveTagged(t, `"stale"`)
+	if stale.Code != http.StatusOK || stale.Body.Len() == 0 {
+		t.Errorf("expected full response for a stale tag, got %d with %q", stale.Code, stale.Body.String())
+	}
+}
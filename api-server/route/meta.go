@@ -7,13 +7,20 @@ import (
 )
 
 type Meta struct {
-	Method        string
-	Path          string
-	Name          string
-	Desc          string
+	Method string
+	Path   string
+	Name   string
+	Desc   string
+	// Summary overrides Name as the summary of the route in the generated docs
+	Summary string
+	// Order is the display order weight of the route within its tag in the generated docs,
+	// lower weights first and routes of equal weight by path and method
+	Order         int
 	Func          gin.HandlerFunc
 	Req           reflect.Type
 	Res           reflect.Type
 	Middlewares   []gin.HandlerFunc
 	DefaultStatus int
+	// Raw is set for routes registered with Raw, which write their response themselves
+	Raw bool
 }
@@ -75,6 +75,8 @@ type Route interface {
 	Meta() Meta
 	Desc(s string) Route
 	Name(s string) Route
+	Summary(s string) Route
+	Order(n int) Route
 	AddMiddlewares(mws ...gin.HandlerFunc) Route
 }
 
@@ -86,7 +88,7 @@ func New[Req, Res any](method, path string, h HandlerFunc[Req, Res], ds ...int)
 	return newRoute[Req, Res](method, path, buildImproved(h, ds...))
 }
 
-func newRoute[Req, Res any](method, path string, h gin.HandlerFunc) Route {
+func newRoute[Req, Res any](method, path string, h gin.HandlerFunc) *route[Req, Res] {
 	return &route[Req, Res]{
 		meta: Meta{
 			Method: method,
@@ -117,6 +119,16 @@ func (h *route[Req, Res]) Name(d string) Route {
 	return h
 }
 
+func (h *route[Req, Res]) Summary(s string) Route {
+	h.meta.Summary = s
+	return h
+}
+
+func (h *route[Req, Res]) Order(n int) Route {
+	h.meta.Order = n
+	return h
+}
+
 // FileConsumer optionally implemented by request DTOs that want direct access to file headers.
 type FileConsumer interface {
 	AcceptFiles(map[string][]*multipart.FileHeader) error
@@ -322,8 +334,13 @@ func handleResponse(c *gin.Context, res any, ds int) {
 			return
 		}
 
-		// Standard JSON response
-		c.JSON(status, res)
+		// Conditional GET: responses carrying an entity tag short-circuit with 304
+		if tagger, ok := any(res).(response.Tagger); ok && response.NotModified(c, tagger.ETag()) {
+			return
+		}
+
+		// Standard response, serialized as JSON or XML depending on the Accept header
+		response.Respond(c, status, res)
 		return
 	}
 
@@ -333,7 +350,7 @@ func handleResponse(c *gin.Context, res any, ds int) {
 		"Response type %T does not implement Stature interface for %s %s - using default 200 OK. "+
 			"Consider wrapping response in response.Response[T] for consistent API responses",
 		res, c.Request.Method, c.Request.URL.Path)
-	c.JSON(http.StatusOK, res)
+	response.Respond(c, http.StatusOK, res)
 }
 
 func isStructEmpty(v interface{}) bool {
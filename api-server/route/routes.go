@@ -1,6 +1,10 @@
 package route
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
 
 func GET[Req, Res any](path string, h HandlerFunc[Req, Res], ds ...int) Route {
 	return New[Req, Res](http.MethodGet, path, h, ds...)
@@ -21,3 +25,12 @@ func PUT[Req, Res any](path string, h HandlerFunc[Req, Res], ds ...int) Route {
 func DELETE[Req, Res any](path string, h HandlerFunc[Req, Res], ds ...int) Route {
 	return New[Req, Res](http.MethodDelete, path, h, ds...)
 }
+
+// Raw registers h as is, skipping request binding, validation, the handler timeout and response
+// rendering. It is the escape hatch for handlers that write the response themselves, such as
+// event streams.
+func Raw(method, path string, h gin.HandlerFunc) Route {
+	r := newRoute[NoParam, NoParam](method, path, h)
+	r.meta.Raw = true
+	return r
+}
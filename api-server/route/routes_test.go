@@ -0,0 +1,34 @@
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRawRouteWritesResponseItself(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := Raw(http.MethodGet, "/stream/:id", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.String(http.StatusOK, "data: %s\n\n", c.Param("id"))
+	}).Name("stream")
+
+	meta := r.Meta()
+	if meta.Method != http.MethodGet || meta.Path != "/stream/:id" || meta.Name != "stream" {
+		t.Fatalf("unexpected meta: %+v", meta)
+	}
+
+	engine := gin.New()
+	engine.Handle(meta.Method, meta.Path, meta.Func)
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream/7", nil))
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "data: 7\n\n" {
+		t.Fatalf("unexpected response %d %q", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+}
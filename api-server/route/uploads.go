@@ -0,0 +1,213 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	apierrors "MgApplication/api-errors"
+	log "MgApplication/api-log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sniffLength is the number of bytes http.DetectContentType looks at
+const sniffLength = 512
+
+// UploadLimits bound the files of the multipart requests
+type UploadLimits struct {
+	// MaxFiles is the maximum number of files per request, across all fields
+	MaxFiles int
+	// MaxFileSize and MaxTotalSize are the maximum size in bytes of a file and of all the files
+	MaxFileSize  int64
+	MaxTotalSize int64
+	// MaxFilenameLength is the maximum length of a sanitized file name, the extension being kept
+	MaxFilenameLength int
+	// AllowedTypes are the content types accepted, as detected from the content of the files;
+	// empty accepts every type
+	AllowedTypes []string
+}
+
+// DefaultUploadLimits are the upload limits applied until SetUploadLimits is called
+var DefaultUploadLimits = UploadLimits{
+	MaxFiles:          10,
+	MaxFileSize:       5 << 20,
+	MaxTotalSize:      20 << 20,
+	MaxFilenameLength: 100,
+	AllowedTypes:      []string{"application/pdf", "image/gif", "image/jpeg", "image/png", "text/plain"},
+}
+
+var uploadLimits atomic.Pointer[UploadLimits]
+
+// SetUploadLimits sets the limits checked on the files of the multipart requests, the zero
+// fields taking the value of DefaultUploadLimits
+func SetUploadLimits(limits UploadLimits) {
+	if limits.MaxFiles <= 0 {
+		limits.MaxFiles = DefaultUploadLimits.MaxFiles
+	}
+	if limits.MaxFileSize <= 0 {
+		limits.MaxFileSize = DefaultUploadLimits.MaxFileSize
+	}
+	if limits.MaxTotalSize <= 0 {
+		limits.MaxTotalSize = DefaultUploadLimits.MaxTotalSize
+	}
+	if limits.MaxFilenameLength <= 0 {
+		limits.MaxFilenameLength = DefaultUploadLimits.MaxFilenameLength
+	}
+	uploadLimits.Store(&limits)
+}
+
+func currentUploadLimits() UploadLimits {
+	if limits := uploadLimits.Load(); limits != nil {
+		return *limits
+	}
+	return DefaultUploadLimits
+}
+
+// uploadRejection is an upload violating the limits
+type uploadRejection struct {
+	status  int
+	message string
+	field   apierrors.FieldError
+}
+
+// checkUploads enforces the upload limits on the files of the multipart form of c, sanitizing
+// their names in place so that the handlers never see the names sent by the client. On a
+// violation it responds with 422 (too many files), 413 (too large) or 415 (type not allowed or
+// not matching the declared type), the field error naming the offending file as field[index],
+// and returns the rejection.
+func checkUploads(c *gin.Context, ctx *Context) error {
+	if c.Request.MultipartForm == nil || len(c.Request.MultipartForm.File) == 0 {
+		return nil
+	}
+	rejection := inspectUploads(c.Request.MultipartForm.File, currentUploadLimits())
+	if rejection == nil {
+		return nil
+	}
+
+	log.Warn(ctx.Ctx, "Upload rejected, %s: %s", rejection.field.Field, rejection.message)
+	rejected := []apierrors.FieldError{rejection.field}
+	switch rejection.status {
+	case http.StatusRequestEntityTooLarge:
+		apierrors.HandleFileError(c, apierrors.FileErrorTooLarge, rejection.message, rejected)
+	case http.StatusUnsupportedMediaType:
+		apierrors.HandleFileError(c, apierrors.FileErrorUnsupportedType, rejection.message, rejected)
+	default:
+		apierrors.HandleFileError(c, apierrors.AppErrorValidationError, rejection.message, rejected)
+	}
+	return errors.New(rejection.message)
+}
+
+// inspectUploads sanitizes the names of files and returns the first violation of limits, the
+// fields being checked in name order
+func inspectUploads(files map[string][]*multipart.FileHeader, limits UploadLimits) *uploadRejection {
+	fields := make([]string, 0, len(files))
+	for field := range files {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var count int
+	var total int64
+	for _, field := range fields {
+		for i, fh := range files[field] {
+			fh.Filename = SanitizeFilename(fh.Filename, limits.MaxFilenameLength)
+			name := fmt.Sprintf("%s[%d]", field, i)
+			reject := func(status int, tag string, value any, message string) *uploadRejection {
+				return &uploadRejection{status: status, message: message, field: apierrors.FieldError{Field: name, Value: value, Message: message, Tag: tag}}
+			}
+
+			count++
+			if count > limits.MaxFiles {
+				return reject(http.StatusUnprocessableEntity, "max", fh.Filename, fmt.Sprintf("at most %d files are accepted per request", limits.MaxFiles))
+			}
+			if fh.Size > limits.MaxFileSize {
+				return reject(http.StatusRequestEntityTooLarge, "maxsize", fh.Size, fmt.Sprintf("file %s exceeds %d bytes", fh.Filename, limits.MaxFileSize))
+			}
+			total += fh.Size
+			if total > limits.MaxTotalSize {
+				return reject(http.StatusRequestEntityTooLarge, "maxtotalsize", total, fmt.Sprintf("files exceed %d bytes in total", limits.MaxTotalSize))
+			}
+
+			detected, err := sniffContentType(fh)
+			if err != nil {
+				return reject(http.StatusUnprocessableEntity, "readable", fh.Filename, fmt.Sprintf("file %s cannot be read", fh.Filename))
+			}
+			if len(limits.AllowedTypes) > 0 && !slices.Contains(limits.AllowedTypes, detected) {
+				return reject(http.StatusUnsupportedMediaType, "content_type", detected, fmt.Sprintf("file %s is %s, which is not accepted", fh.Filename, detected))
+			}
+			declared, _, _ := mime.ParseMediaType(fh.Header.Get("Content-Type"))
+			if !contentTypeMatches(strings.ToLower(declared), detected) {
+				return reject(http.StatusUnsupportedMediaType, "content_type", declared, fmt.Sprintf("file %s is declared %s but its content is %s", fh.Filename, declared, detected))
+			}
+			// the handlers see the detected type only
+			fh.Header.Set("Content-Type", detected)
+		}
+	}
+	return nil
+}
+
+// sniffContentType detects the media type of the file from its first bytes
+func sniffContentType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	buf := make([]byte, sniffLength)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	detected, _, _ := mime.ParseMediaType(http.DetectContentType(buf[:n]))
+	return detected, nil
+}
+
+// contentTypeMatches reports whether the declared type of a file agrees with the type detected.
+// Files declared without a type or as application/octet-stream take the detected type, and the
+// text formats cannot be told apart from their content.
+func contentTypeMatches(declared string, detected string) bool {
+	switch {
+	case declared == "" || declared == "application/octet-stream":
+		return true
+	case detected == "text/plain":
+		return strings.HasPrefix(declared, "text/")
+	default:
+		return declared == detected
+	}
+}
+
+// SanitizeFilename returns the base name of name, with the characters other than letters,
+// digits, dots, dashes and underscores replaced by underscores, without leading dots and
+// shortened to maxLength keeping the extension
+func SanitizeFilename(name string, maxLength int) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	name = strings.TrimLeft(name, ".")
+	if name == "" {
+		name = "file"
+	}
+	if maxLength > 0 && len(name) > maxLength {
+		ext := path.Ext(name)
+		if len(ext) >= maxLength {
+			ext = ""
+		}
+		name = name[:maxLength-len(ext)] + ext
+	}
+	return name
+}
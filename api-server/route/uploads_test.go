@@ -0,0 +1,202 @@
+package route
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	validation "MgApplication/api-validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+type uploadTestRequest struct {
+	Name        string                  `form:"name"`
+	Attachments []*multipart.FileHeader `form:"attachments"`
+}
+
+type uploadTestFile struct {
+	name        string
+	contentType string
+	content     []byte
+}
+
+var (
+	pngContent = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 24)...)
+	pdfContent = []byte("%PDF-1.4\n%âãÏÓ\n1 0 obj\n<<>>\nendobj\n")
+)
+
+// serveUpload posts files as attachments to a route answering with the names and types the
+// handler sees
+func serveUpload(t *testing.T, limits UploadLimits, files ...uploadTestFile) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	if err := validation.Create(); err != nil {
+		t.Fatal(err)
+	}
+	SetUploadLimits(limits)
+	t.Cleanup(func() { uploadLimits.Store(nil) })
+
+	meta := POST("/upload", func(ctx *Context, req uploadTestRequest) (*uploadTestResponse, error) {
+		rsp := &uploadTestResponse{}
+		for _, fh := range req.Attachments {
+			rsp.Files = append(rsp.Files, fh.Filename+" "+fh.Header.Get("Content-Type"))
+		}
+		return rsp, nil
+	}).Meta()
+	engine := gin.New()
+	engine.Handle(meta.Method, meta.Path, meta.Func)
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	_ = w.WriteField("name", "upload")
+	for _, file := range files {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="attachments"; filename="%s"`, file.name))
+		if file.contentType != "" {
+			header.Set("Content-Type", file.contentType)
+		}
+		part, err := w.CreatePart(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = part.Write(file.content)
+	}
+	_ = w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+type uploadTestResponse struct {
+	Files []string `json:"files"`
+}
+
+type uploadErrorResponse struct {
+	Error struct {
+		FieldErrors []struct {
+			Field string `json:"field"`
+			Tag   string `json:"tag"`
+		} `json:"field_errors"`
+	} `json:"error"`
+}
+
+func TestUploadLimits(t *testing.T) {
+	limits := UploadLimits{MaxFiles: 2, MaxFileSize: 64, MaxTotalSize: 96, MaxFilenameLength: 12, AllowedTypes: DefaultUploadLimits.AllowedTypes}
+	text := func(size int) []byte { return bytes.Repeat([]byte("a"), size) }
+
+	tests := []struct {
+		name   string
+		files  []uploadTestFile
+		status int
+		seen   []string // names and types seen by the handler
+		field  string   // offending file
+		tag    string
+	}{
+		{
+			name:   "traversal names are reduced to their base name",
+			files:  []uploadTestFile{{"../../etc/passwd", "text/plain", text(8)}, {`..\..\boot.ini`, "", text(8)}},
+			status: http.StatusOK,
+			seen:   []string{"passwd text/plain", "boot.ini text/plain"},
+		},
+		{
+			name:   "names are restricted and shortened keeping the extension",
+			files:  []uploadTestFile{{"<script>alert(1)</script>.png", "image/png", pngContent}, {"..", "application/pdf", pdfContent}},
+			status: http.StatusOK,
+			seen:   []string{"script_.png image/png", "file application/pdf"},
+		},
+		{
+			name:   "spoofed type",
+			files:  []uploadTestFile{{"logo.png", "image/png", pngContent}, {"invoice.pdf", "application/pdf", pngContent}},
+			status: http.StatusUnsupportedMediaType,
+			field:  "attachments[1]",
+			tag:    "content_type",
+		},
+		{
+			name:   "type not allowed",
+			files:  []uploadTestFile{{"page.html", "text/html", []byte("<html><body>hi</body></html>")}},
+			status: http.StatusUnsupportedMediaType,
+			field:  "attachments[0]",
+			tag:    "content_type",
+		},
+		{
+			name:   "too many files",
+			files:  []uploadTestFile{{"a.txt", "", text(1)}, {"b.txt", "", text(1)}, {"c.txt", "", text(1)}},
+			status: http.StatusUnprocessableEntity,
+			field:  "attachments[2]",
+			tag:    "max",
+		},
+		{
+			name:   "oversize file",
+			files:  []uploadTestFile{{"a.txt", "", text(65)}},
+			status: http.StatusRequestEntityTooLarge,
+			field:  "attachments[0]",
+			tag:    "maxsize",
+		},
+		{
+			name:   "oversize total",
+			files:  []uploadTestFile{{"a.txt", "", text(64)}, {"b.txt", "", text(33)}},
+			status: http.StatusRequestEntityTooLarge,
+			field:  "attachments[1]",
+			tag:    "maxtotalsize",
+		},
+		{
+			name:   "exactly at the limits",
+			files:  []uploadTestFile{{"a.txt", "", text(64)}, {"b.txt", "", text(32)}},
+			status: http.StatusOK,
+			seen:   []string{"a.txt text/plain", "b.txt text/plain"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveUpload(t, limits, tt.files...)
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.status == http.StatusOK {
+				var rsp uploadTestResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &rsp); err != nil {
+					t.Fatal(err)
+				}
+				if strings.Join(rsp.Files, ",") != strings.Join(tt.seen, ",") {
+					t.Errorf("expected the handler to see %q, got %q", tt.seen, rsp.Files)
+				}
+				return
+			}
+			var rsp uploadErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &rsp); err != nil {
+				t.Fatal(err)
+			}
+			if len(rsp.Error.FieldErrors) != 1 || rsp.Error.FieldErrors[0].Field != tt.field || rsp.Error.FieldErrors[0].Tag != tt.tag {
+				t.Errorf("expected %s rejected for %s, got %s", tt.field, tt.tag, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := map[string]string{
+		"report.pdf":             "report.pdf",
+		"../../etc/passwd":       "passwd",
+		`C:\Users\me\photo.jpg`:  "photo.jpg",
+		".htaccess":              "htaccess",
+		"/":                      "_",
+		"":                       "file",
+		"résumé 2024.pdf":        "r_sum__2.pdf",
+		"averyveryverylong.jpeg": "averyve.jpeg",
+	}
+	for name, want := range tests {
+		if got := SanitizeFilename(name, 12); got != want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
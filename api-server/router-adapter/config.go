@@ -55,6 +55,9 @@ type RouterConfig struct {
 	// Compression configuration
 	EnableCompression bool `yaml:"enableCompression" json:"enableCompression"`
 	CompressionLevel  int  `yaml:"compressionLevel" json:"compressionLevel"` // 1-9, default is 6
+
+	// Route normalization configuration (gin and nethttp adapters)
+	Normalization *NormalizationConfig `yaml:"normalization,omitempty" json:"normalization,omitempty"`
 }
 
 // GinConfig contains Gin-specific configuration
@@ -124,6 +127,28 @@ type NetHTTPConfig struct {
 	EnableHTTP2 bool `yaml:"enableHTTP2" json:"enableHTTP2"`
 }
 
+// NormalizationConfig contains route normalization configuration
+type NormalizationConfig struct {
+	// TrailingSlash serves paths that differ from a registered route only by a trailing
+	// slash: GET and HEAD are redirected with 308, other methods are rewritten internally
+	TrailingSlash bool `yaml:"trailingSlash" json:"trailingSlash"`
+
+	// CaseInsensitiveVersion matches the version prefix segment case-insensitively
+	// (/V1/... is served as /v1/...). The rest of the path stays case-sensitive.
+	CaseInsensitiveVersion bool `yaml:"caseInsensitiveVersion" json:"caseInsensitiveVersion"`
+}
+
+// NormalizationSupported reports whether the adapter of the configured router type applies
+// the route normalization. Only the gin and nethttp adapters do.
+func (c *RouterConfig) NormalizationSupported() bool {
+	switch RouterType(strings.ToLower(string(c.Type))) {
+	case RouterTypeGin, RouterTypeNetHTTP, "":
+		return true
+	default:
+		return false
+	}
+}
+
 // Validate validates the router configuration
 func (c *RouterConfig) Validate() error {
 	// Normalize router type
@@ -3,6 +3,7 @@ package echoadapter
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"sync"
 
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	apierrors "MgApplication/api-errors"
+	log "MgApplication/api-log"
 )
 
 // ErrorHandler handles errors in a framework-agnostic way
@@ -23,7 +24,15 @@ func (h *DefaultErrorHandler) HandleError(ctx *RouterContext, err error) {
 	// Check if response was already written
 	if ctx.IsResponseWritten() {
 		// Log error but can't send response
-		fmt.Printf("Error after response written: %v\n", err)
+		log.Error(ctx.Context(), "Error after response written: %s", err.Error())
+		return
+	}
+
+	// A request rejected by a limiting feature is answered with its wait in Retry-After
+	if apiErr, ok := apierrors.Find[*apierrors.AppError](err); ok && apiErr.RetryAfterSeconds > 0 {
+		rsp, retryAfter := apierrors.BackpressureResponse(apiErr)
+		ctx.SetHeader(apierrors.HeaderRetryAfter, retryAfter)
+		_ = ctx.JSON(rsp.StatusCode, rsp)
 		return
 	}
 
@@ -37,8 +46,8 @@ func (h *DefaultErrorHandler) HandleError(ctx *RouterContext, err error) {
 		if apiErr.Message != "" {
 			message = apiErr.Message
 		}
-		if apiErr.Code != "" {
-			errorCode = apiErr.Code
+		if apiErr.Code != 0 {
+			errorCode = fmt.Sprint(apiErr.Code)
 		}
 	}
 
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	log "MgApplication/api-log"
 	"MgApplication/api-server/route"
 	"MgApplication/api-server/router-adapter"
 
@@ -209,7 +210,7 @@ func (a *FiberAdapter) Start(addr string) error {
 	// Start server in goroutine
 	go func() {
 		if err := a.app.Listen(addr); err != nil {
-			fmt.Printf("Fiber server error: %v\n", err)
+			log.Error(nil, "Fiber server error: %s", err.Error())
 		}
 	}()
 
@@ -4,7 +4,9 @@ import (
 	"compress/gzip"
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"path"
 	"strings"
 	"sync"
 
@@ -32,6 +34,7 @@ type GinAdapter struct {
 	server       *http.Server
 	config       *routeradapter.RouterConfig
 	errorHandler routeradapter.ErrorHandler
+	normalizer   *routeradapter.RouteNormalizer
 	ctx          context.Context // Signal-aware application context
 	mu           sync.RWMutex
 }
@@ -73,6 +76,16 @@ func NewGinAdapter(cfg *routeradapter.RouterConfig) (*GinAdapter, error) {
 		engine.ForwardedByClientIP = true
 	}
 
+	// Trailing slashes are handled by the route normalizer instead of Gin's 301/307 redirects,
+	// and routes are matched on the escaped path like the normalizer does
+	normalizer := routeradapter.NewRouteNormalizer(cfg.Normalization)
+	if normalizer.Enabled() {
+		engine.UseRawPath = true
+		if cfg.Normalization.TrailingSlash {
+			engine.RedirectTrailingSlash = false
+		}
+	}
+
 	// Set trusted proxies if specified
 	if len(cfg.Gin.TrustedProxies) > 0 {
 		if err := engine.SetTrustedProxies(cfg.Gin.TrustedProxies); err != nil {
@@ -84,6 +97,7 @@ func NewGinAdapter(cfg *routeradapter.RouterConfig) (*GinAdapter, error) {
 		engine:       engine,
 		config:       cfg,
 		errorHandler: routeradapter.NewGinErrorHandler(),
+		normalizer:   normalizer,
 	}
 
 	// Enable gzip compression if configured
@@ -119,6 +133,7 @@ func (a *GinAdapter) RegisterRoute(meta route.Meta) error {
 
 	// Register route with Gin
 	a.engine.Handle(meta.Method, meta.Path, handlers...)
+	a.normalizer.AddRoute(meta.Path)
 
 	return nil
 }
@@ -175,9 +190,9 @@ func (a *GinAdapter) UseNative(middleware interface{}) error {
 }
 
 // ServeHTTP implements http.Handler interface
-// Delegates to Gin's ServeHTTP implementation
+// Delegates to Gin's ServeHTTP implementation after route normalization
 func (a *GinAdapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	a.engine.ServeHTTP(w, r)
+	a.normalizer.Handler(a.engine).ServeHTTP(w, r)
 }
 
 // Start starts the HTTP server on the specified address
@@ -192,7 +207,7 @@ func (a *GinAdapter) Start(addr string) error {
 	// Create HTTP server
 	a.server = &http.Server{
 		Addr:              addr,
-		Handler:           a.engine,
+		Handler:           a.normalizer.Handler(a.engine),
 		ReadTimeout:       a.config.ReadTimeout,
 		WriteTimeout:      a.config.WriteTimeout,
 		IdleTimeout:       a.config.IdleTimeout,
@@ -410,6 +425,7 @@ func (g *GinGroup) RegisterRoute(meta route.Meta) error {
 
 	// Register route with Gin group
 	g.group.Handle(meta.Method, meta.Path, handlers...)
+	g.adapter.normalizer.AddRoute(joinPaths(g.group.BasePath(), meta.Path))
 
 	return nil
 }
@@ -462,6 +478,15 @@ func (g *GinGroup) UseNative(middleware interface{}) error {
 	return nil
 }
 
+// joinPaths joins a group prefix and a route path the way Gin does, keeping the trailing slash
+func joinPaths(absolutePath, relativePath string) string {
+	joined := path.Join(absolutePath, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(joined, "/") {
+		return joined + "/"
+	}
+	return joined
+}
+
 // setupGzipCompression configures gzip compression middleware for Gin
 func (a *GinAdapter) setupGzipCompression() {
 	level := a.config.CompressionLevel
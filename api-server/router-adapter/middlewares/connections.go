@@ -3,6 +3,9 @@ package middlewares
 import (
 	"net/http"
 	"sync/atomic"
+	"time"
+
+	apierrors "MgApplication/api-errors"
 
 	"MgApplication/api-server/router-adapter"
 
@@ -89,17 +92,14 @@ func ConnectionLimiter(config ConnectionLimiterConfig) routeradapter.MiddlewareF
 				rejectedConnectionsCounter.Inc()
 			}
 
-			// Return 503 Service Unavailable
-			statusCode := config.RejectStatusCode
-			if statusCode == 0 {
-				statusCode = http.StatusServiceUnavailable
+			// Return 503 Service Unavailable, with the CONNECTION_LIMIT error id and a Retry-After
+			rejection := apierrors.NewBackpressureError(apierrors.SourceConnectionLimit, time.Time{}, config.RejectMessage, nil)
+			if config.RejectStatusCode != 0 {
+				rejection.Code = config.RejectStatusCode
 			}
-
-			return ctx.JSON(statusCode, map[string]interface{}{
-				"error":              config.RejectMessage,
-				"active_connections": current,
-				"max_connections":    config.MaxConnections,
-			})
+			rsp, retryAfter := apierrors.BackpressureResponse(rejection)
+			ctx.SetHeader(apierrors.HeaderRetryAfter, retryAfter)
+			return ctx.JSON(rsp.StatusCode, rsp)
 		}
 
 		// Increment active connections
@@ -1,14 +1,16 @@
 package middlewares
 
 import (
-	"net/http"
+	"time"
 
+	apierrors "MgApplication/api-errors"
 	"MgApplication/api-server/ratelimiter"
 	"MgApplication/api-server/router-adapter"
 )
 
 // RateLimiter returns a middleware that implements rate limiting using LeakyBucket algorithm
-// If the rate limit is exceeded, it returns 429 Too Many Requests
+// If the rate limit is exceeded, it returns 429 Too Many Requests with the TRAFFIC_SHAPED
+// error id and a Retry-After
 func RateLimiter(bucket *ratelimiter.LeakyBucket) routeradapter.MiddlewareFunc {
 	return func(ctx *routeradapter.RouterContext, next func() error) error {
 		// Check if request is allowed by rate limiter
@@ -18,9 +20,8 @@ func RateLimiter(bucket *ratelimiter.LeakyBucket) routeradapter.MiddlewareFunc {
 		}
 
 		// Rate limit exceeded, return 429
-		return ctx.JSON(http.StatusTooManyRequests, map[string]string{
-			"error": "rate limit exceeded",
-			"message": "too many requests, please try again later",
-		})
+		rsp, retryAfter := apierrors.BackpressureResponse(apierrors.NewBackpressureError(apierrors.SourceTrafficShaped, time.Time{}, "too many requests, please try again later", nil))
+		ctx.SetHeader(apierrors.HeaderRetryAfter, retryAfter)
+		return ctx.JSON(rsp.StatusCode, rsp)
 	}
 }
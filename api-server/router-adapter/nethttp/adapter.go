@@ -3,7 +3,9 @@ package nethttpadapter
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 	"sync"
@@ -30,6 +32,7 @@ type NetHTTPAdapter struct {
 	noRouteHandler   routeradapter.HandlerFunc
 	noMethodHandler  routeradapter.HandlerFunc
 	middlewares      []routeradapter.MiddlewareFunc
+	normalizer       *routeradapter.RouteNormalizer
 	ctx              context.Context // Signal-aware application context
 	mu               sync.RWMutex
 }
@@ -51,6 +54,7 @@ func NewNetHTTPAdapter(cfg *routeradapter.RouterConfig) (*NetHTTPAdapter, error)
 		config:       cfg,
 		errorHandler: routeradapter.NewNetHTTPErrorHandler(),
 		middlewares:  make([]routeradapter.MiddlewareFunc, 0),
+		normalizer:   routeradapter.NewRouteNormalizer(cfg.Normalization),
 	}
 
 	// Enable gzip compression if configured
@@ -76,6 +80,8 @@ func (a *NetHTTPAdapter) RegisterRoute(meta route.Meta) error {
 		return fmt.Errorf("route handler function is required")
 	}
 
+	a.normalizer.AddRoute(meta.Path)
+
 	// Register route with custom router
 	a.router.AddRoute(meta.Method, meta.Path, func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 		// Create RouterContext
@@ -156,16 +162,25 @@ func (a *NetHTTPAdapter) UseNative(middleware interface{}) error {
 }
 
 // ServeHTTP implements http.Handler interface
+// Requests are routed after route normalization
 func (a *NetHTTPAdapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.normalizer.Handler(http.HandlerFunc(a.serveHTTP)).ServeHTTP(w, r)
+}
+
+// serveHTTP routes the request to the matching handler, or the no-method/no-route handler
+func (a *NetHTTPAdapter) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	// Match on the escaped path so encoded slashes stay inside their path parameter
+	path := r.URL.EscapedPath()
+
 	// Try to match route
-	handler, params := a.router.Match(r.Method, r.URL.Path)
+	handler, params := a.router.Match(r.Method, path)
 	if handler != nil {
 		handler(w, r, params)
 		return
 	}
 
 	// No route found for this method, check if route exists for other methods
-	methodMismatch := a.router.PathExists(r.URL.Path)
+	methodMismatch := a.router.PathExists(path)
 
 	if methodMismatch {
 		// Route exists but method not allowed (405)
@@ -441,7 +456,11 @@ func (r *Router) Match(method, path string) (func(http.ResponseWriter, *http.Req
 			params := make(map[string]string)
 			for i, name := range route.Params {
 				if i+1 < len(matches) {
-					params[name] = matches[i+1]
+					value, err := url.PathUnescape(matches[i+1])
+					if err != nil {
+						value = matches[i+1]
+					}
+					params[name] = value
 				}
 			}
 			return route.Handler, params
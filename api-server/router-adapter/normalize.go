@@ -0,0 +1,166 @@
+package routeradapter
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// versionSegmentPattern matches the API version prefix segment, e.g. "v1" or "V2"
+var versionSegmentPattern = regexp.MustCompile(`^[vV][0-9]+$`)
+
+// RouteNormalizer recovers requests whose path differs from a registered route only by a
+// trailing slash or by the case of the version prefix (/V1 instead of /v1).
+//
+// It wraps the adapter's http.Handler and only acts when the request path does not match
+// a registered route as-is. GET and HEAD requests are redirected to the canonical path with
+// 308 Permanent Redirect, other methods are rewritten internally so that request bodies are
+// not replayed by clients. Requests that still do not match after normalization are passed
+// through unchanged and reach the adapter's no-route handler.
+//
+// Matching is done on the escaped path, so encoded slashes (%2F) inside path parameters are
+// never treated as segment separators.
+type RouteNormalizer struct {
+	config NormalizationConfig
+	routes [][]string
+	mu     sync.RWMutex
+}
+
+// NewRouteNormalizer creates a new route normalizer. A nil config disables normalization.
+func NewRouteNormalizer(cfg *NormalizationConfig) *RouteNormalizer {
+	n := &RouteNormalizer{}
+	if cfg != nil {
+		n.config = *cfg
+	}
+	return n
+}
+
+// Enabled reports whether any normalization is configured
+func (n *RouteNormalizer) Enabled() bool {
+	return n != nil && (n.config.TrailingSlash || n.config.CaseInsensitiveVersion)
+}
+
+// AddRoute records a registered route path pattern such as "/v1/templates/:id"
+func (n *RouteNormalizer) AddRoute(path string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.routes = append(n.routes, splitPath(path))
+}
+
+// Handler wraps next with route normalization
+func (n *RouteNormalizer) Handler(next http.Handler) http.Handler {
+	if !n.Enabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		escaped := r.URL.EscapedPath()
+		if n.matches(escaped) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		canonical := n.CanonicalPath(escaped)
+		if canonical == escaped || !n.matches(canonical) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			location := canonical
+			if r.URL.RawQuery != "" {
+				location += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, location, http.StatusPermanentRedirect)
+			return
+		}
+
+		unescaped, err := url.PathUnescape(canonical)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = unescaped
+		r2.URL.RawPath = ""
+		if unescaped != canonical {
+			r2.URL.RawPath = canonical
+		}
+		r2.RequestURI = r2.URL.RequestURI()
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// CanonicalPath returns the canonical form of an escaped request path, with the trailing
+// slash removed and the version prefix lowercased as configured
+func (n *RouteNormalizer) CanonicalPath(escapedPath string) string {
+	canonical := escapedPath
+	if n.config.TrailingSlash && len(canonical) > 1 {
+		canonical = strings.TrimRight(canonical, "/")
+		if canonical == "" {
+			canonical = "/"
+		}
+	}
+
+	if n.config.CaseInsensitiveVersion {
+		segments := strings.SplitN(canonical, "/", 3)
+		if len(segments) > 1 && versionSegmentPattern.MatchString(segments[1]) {
+			segments[1] = strings.ToLower(segments[1])
+			canonical = strings.Join(segments, "/")
+		}
+	}
+
+	return canonical
+}
+
+// matches reports whether the escaped path matches a registered route for any method, so
+// that a normalized request for the wrong method still reaches the no-method handler
+func (n *RouteNormalizer) matches(escapedPath string) bool {
+	segments := splitPath(escapedPath)
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	for _, route := range n.routes {
+		if matchSegments(route, segments) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches path segments against a route pattern, where ":name" matches a
+// single segment and "*name" matches the remainder of the path
+func matchSegments(route, segments []string) bool {
+	for i, part := range route {
+		if strings.HasPrefix(part, "*") {
+			return true
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if strings.HasPrefix(part, ":") {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if part != segments[i] {
+			return false
+		}
+	}
+	return len(route) == len(segments)
+}
+
+// splitPath splits a path into its segments, keeping an empty last segment for a
+// trailing slash so that "/a/" does not match "/a"
+func splitPath(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
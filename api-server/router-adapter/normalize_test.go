@@ -0,0 +1,186 @@
+package routeradapter_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"MgApplication/api-server/route"
+	"MgApplication/api-server/router-adapter"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newNormalizingAdapter creates an adapter of the given type with route normalization enabled
+// and records the escaped path of every request that reaches the adapter's router
+func newNormalizingAdapter(t *testing.T, routerType routeradapter.RouterType) (routeradapter.RouterAdapter, *string) {
+	t.Helper()
+
+	cfg := routeradapter.DefaultRouterConfig()
+	cfg.Type = routerType
+	cfg.Gin.Mode = "test"
+	cfg.Normalization = &routeradapter.NormalizationConfig{
+		TrailingSlash:          true,
+		CaseInsensitiveVersion: true,
+	}
+
+	adapter, err := routeradapter.NewRouterAdapter(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create %s adapter: %v", routerType, err)
+	}
+
+	var routed string
+	if err := adapter.RegisterMiddleware(func(ctx *routeradapter.RouterContext, next func() error) error {
+		routed = ctx.Request.URL.EscapedPath()
+		return next()
+	}); err != nil {
+		t.Fatalf("Failed to register middleware: %v", err)
+	}
+
+	adapter.SetNoRouteHandler(func(ctx *routeradapter.RouterContext) error {
+		routed = ctx.Request.URL.EscapedPath()
+		return ctx.JSON(http.StatusNotFound, map[string]string{"error": "route not found"})
+	})
+
+	handler := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	}
+	group := adapter.RegisterGroup("/v1", nil)
+	for _, method := range []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"} {
+		if err := group.RegisterRoute(route.Meta{Method: method, Path: "/sms-templates", Func: handler}); err != nil {
+			t.Fatalf("Failed to register route: %v", err)
+		}
+		if err := group.RegisterRoute(route.Meta{Method: method, Path: "/sms-templates/:template-id", Func: handler}); err != nil {
+			t.Fatalf("Failed to register route: %v", err)
+		}
+	}
+
+	return adapter, &routed
+}
+
+// TestRouteNormalization tests trailing slash and version prefix handling for each method
+// on the gin and net/http adapters
+func TestRouteNormalization(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		wantStatus   int
+		wantLocation string
+		wantRouted   string
+	}{
+		{name: "canonical", path: "/v1/sms-templates", wantStatus: http.StatusOK, wantRouted: "/v1/sms-templates"},
+		{name: "trailing slash", path: "/v1/sms-templates/", wantStatus: http.StatusOK, wantLocation: "/v1/sms-templates", wantRouted: "/v1/sms-templates"},
+		{name: "uppercase version", path: "/V1/sms-templates", wantStatus: http.StatusOK, wantLocation: "/v1/sms-templates", wantRouted: "/v1/sms-templates"},
+		{name: "uppercase version and trailing slash", path: "/V1/sms-templates/12/?page=2", wantStatus: http.StatusOK, wantLocation: "/v1/sms-templates/12?page=2", wantRouted: "/v1/sms-templates/12"},
+		{name: "encoded slash in param", path: "/V1/sms-templates/a%2Fb/", wantStatus: http.StatusOK, wantLocation: "/v1/sms-templates/a%2Fb", wantRouted: "/v1/sms-templates/a%2Fb"},
+		{name: "other segments stay case sensitive", path: "/v1/SMS-templates/", wantStatus: http.StatusNotFound, wantRouted: "/v1/SMS-templates/"},
+		{name: "unknown route", path: "/v1/unknown/", wantStatus: http.StatusNotFound, wantRouted: "/v1/unknown/"},
+	}
+
+	for _, routerType := range []routeradapter.RouterType{routeradapter.RouterTypeGin, routeradapter.RouterTypeNetHTTP} {
+		for _, method := range []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"} {
+			for _, tt := range tests {
+				t.Run(string(routerType)+"/"+method+"/"+tt.name, func(t *testing.T) {
+					adapter, routed := newNormalizingAdapter(t, routerType)
+
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest(method, tt.path, strings.NewReader(`{}`))
+					adapter.ServeHTTP(w, req)
+
+					redirect := tt.wantLocation != "" && (method == "GET" || method == "HEAD")
+					switch {
+					case redirect:
+						if w.Code != http.StatusPermanentRedirect {
+							t.Fatalf("Expected status 308, got %d", w.Code)
+						}
+						if got := w.Header().Get("Location"); got != tt.wantLocation {
+							t.Errorf("Expected Location %q, got %q", tt.wantLocation, got)
+						}
+						if *routed != "" {
+							t.Errorf("Expected redirect before routing, request was routed to %q", *routed)
+						}
+					default:
+						if w.Code != tt.wantStatus {
+							t.Fatalf("Expected status %d, got %d", tt.wantStatus, w.Code)
+						}
+						if *routed != tt.wantRouted {
+							t.Errorf("Expected request routed to %q, got %q", tt.wantRouted, *routed)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// TestRouteNormalizationDisabled tests that paths are routed unchanged without normalization
+func TestRouteNormalizationDisabled(t *testing.T) {
+	cfg := &routeradapter.RouterConfig{
+		Type:    routeradapter.RouterTypeNetHTTP,
+		Port:    8080,
+		NetHTTP: &routeradapter.NetHTTPConfig{},
+	}
+
+	adapter, err := routeradapter.NewRouterAdapter(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	if err := adapter.RegisterRoute(route.Meta{Method: "POST", Path: "/v1/sms-templates", Func: func(c *gin.Context) {}}); err != nil {
+		t.Fatalf("Failed to register route: %v", err)
+	}
+
+	for _, path := range []string{"/v1/sms-templates/", "/V1/sms-templates"} {
+		w := httptest.NewRecorder()
+		adapter.ServeHTTP(w, httptest.NewRequest("POST", path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for %s, got %d", path, w.Code)
+		}
+	}
+}
+
+// TestSwaggerPathsAreCanonical tests that the documented paths are already in canonical
+// form, so clients following the API docs are never redirected
+func TestSwaggerPathsAreCanonical(t *testing.T) {
+	data, err := os.ReadFile("../../docs/swagger.json")
+	if err != nil {
+		t.Fatalf("Failed to read swagger.json: %v", err)
+	}
+
+	var doc struct {
+		BasePath string                     `json:"basePath"`
+		Paths    map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Failed to parse swagger.json: %v", err)
+	}
+
+	normalizer := routeradapter.NewRouteNormalizer(&routeradapter.NormalizationConfig{
+		TrailingSlash:          true,
+		CaseInsensitiveVersion: true,
+	})
+	for path := range doc.Paths {
+		documented := doc.BasePath + path
+		if canonical := normalizer.CanonicalPath(documented); canonical != documented {
+			t.Errorf("Swagger path %s is not canonical, expected %s", documented, canonical)
+		}
+	}
+}
+
+func TestNormalizationSupported(t *testing.T) {
+	supported := map[routeradapter.RouterType]bool{
+		routeradapter.RouterTypeGin:     true,
+		routeradapter.RouterTypeNetHTTP: true,
+		routeradapter.RouterTypeFiber:   false,
+		routeradapter.RouterTypeEcho:    false,
+	}
+	for routerType, want := range supported {
+		cfg := routeradapter.DefaultRouterConfig()
+		cfg.Type = routerType
+		if got := cfg.NormalizationSupported(); got != want {
+			t.Errorf("NormalizationSupported() for %s = %v, want %v", routerType, got, want)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	auth "MgApplication/api-authz"
 	config "MgApplication/api-config"
 	apierrors "MgApplication/api-errors"
 
@@ -106,7 +107,12 @@ func (s *Router) RegisterRoutes() {
 		metas := slc.Map(r.routes, r.toMeta)
 
 		slc.ForEach(metas, func(m route.Meta) {
-			handlers := []gin.HandlerFunc{}
+			// Negotiate the response format before any controller middleware runs. Raw routes
+			// choose their own format, e.g. event streams answering text/event-stream.
+			var handlers []gin.HandlerFunc
+			if !m.Raw {
+				handlers = append(handlers, middlewares.ContentNegotiation())
+			}
 
 			// Add middlewares from registry
 			for _, mw := range r.mws {
@@ -153,6 +159,12 @@ func (s *Router) Shutdown(ctx context.Context) error {
 
 }
 
+// ServeHTTP serves a request through the registered routes and middlewares without starting the
+// server, for tests
+func (s *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.app.ServeHTTP(w, req)
+}
+
 func NewRouter(app *gin.Engine, cfg *config.Config, registries []*registry) *Router {
 	return &Router{
 		app:        app,
@@ -209,7 +221,7 @@ func configureRateLimiting(app *gin.Engine, cfg *config.Config, metricsRegistry
 	ratelimiter.InitMetrics(globalBucket, metricsRegistry)
 }
 
-// registerCoreMiddlewares adds body limiter, rate limiter, CORS, recovery, and error handler
+// registerCoreMiddlewares adds request id, body limiter, rate limiter, CORS, error sampling, recovery, and error handler
 func registerCoreMiddlewares(app *gin.Engine, cfg *config.Config, metricsRegistry *prometheus.Registry) {
 	// Get server config with fallback
 	serverCfg, err := cfg.Of("server")
@@ -232,6 +244,7 @@ func registerCoreMiddlewares(app *gin.Engine, cfg *config.Config, metricsRegistr
 	}
 
 	app.Use(
+		middlewares.RequestIDMiddleware(),
 		middlewares.BodyLimiter(sizelimit),
 		middlewares.BodyLimitErrorHandler())
 
@@ -241,12 +254,13 @@ func registerCoreMiddlewares(app *gin.Engine, cfg *config.Config, metricsRegistr
 	// Add core middlewares
 	app.Use(
 		middlewares.CORSMiddleware(corsCfg),
+		middlewares.ErrorSampleMiddleware(),
 		middlewares.Recover(cfg),
 		middlewares.ErrorHandler(),
 	)
 }
 
-// registerSecurityMiddlewares adds encryption/decryption middleware if enabled
+// registerSecurityMiddlewares adds encryption/decryption middleware if enabled and the bearer token verification
 func registerSecurityMiddlewares(app *gin.Engine, cfg *config.Config) {
 	encryptenabled := false
 	if cfg.Exists("server.encrypt") {
@@ -257,6 +271,34 @@ func registerSecurityMiddlewares(app *gin.Engine, cfg *config.Config) {
 		app.Use(middlewares.DecryptMiddleware())
 		app.Use(middlewares.ResponseSignatureMiddleware())
 	}
+
+	// Bearer tokens are only trusted once verified, without a key every request is anonymous
+	if secret := cfg.GetString("server.auth.jwtsecret"); secret != "" {
+		app.Use(auth.Authenticate([]byte(secret)))
+	}
+}
+
+// configureConcurrencyLimiting sets up the limits of the requests in flight of sms.concurrency,
+// after the bearer token verification identifying the application of a request
+func configureConcurrencyLimiting(app *gin.Engine, cfg *config.Config, metricsRegistry *prometheus.Registry) {
+	limits := middlewares.ConcurrencyLimits{
+		Global:       cfg.GetInt64("sms.concurrency.global"),
+		Default:      cfg.GetInt64("sms.concurrency.default"),
+		Applications: make(map[string]int64),
+		Routes:       make(map[string]int64),
+	}
+	for application := range cfg.GetStringMap("sms.concurrency.applications") {
+		limits.Applications[application] = cfg.GetInt64("sms.concurrency.applications." + application)
+	}
+	for route := range cfg.GetStringMap("sms.concurrency.routes") {
+		limits.Routes[route] = cfg.GetInt64("sms.concurrency.routes." + route)
+	}
+	if limits.Global <= 0 && limits.Default <= 0 && len(limits.Applications) == 0 && len(limits.Routes) == 0 {
+		return
+	}
+
+	app.Use(middlewares.ConcurrencyLimitMiddleware(middlewares.NewConcurrencyLimiter(limits)))
+	metricsRegistry.MustRegister(middlewares.InFlightRequests, middlewares.ApplicationInFlightRequests, middlewares.ConcurrencyLimitRejectionsTotal)
 }
 
 // parseMetricBuckets parses metric bucket configuration from config string
@@ -279,12 +321,16 @@ func registerObservabilityMiddlewares(app *gin.Engine, cfg *config.Config,
 
 	// Configure tracing
 	if cfg.GetBool("trace.enabled") {
-		app.Use(middlewares.RequestTracerMiddleware(
-			cfg.AppName(),
-			middlewares.RequestTracerMiddlewareConfig{
-				TracerProvider: AnnotateTracerProvider(osdktrace),
-			},
-		))
+		app.Use(
+			middlewares.RequestTracerMiddleware(
+				cfg.AppName(),
+				middlewares.RequestTracerMiddlewareConfig{
+					TracerProvider: AnnotateTracerProvider(osdktrace),
+				},
+			),
+			// the request logger logs the trace ID of the request span
+			middlewares.SetCtxTraceIDMiddleware(),
+		)
 	}
 
 	// Configure logging
@@ -292,6 +338,11 @@ func registerObservabilityMiddlewares(app *gin.Engine, cfg *config.Config,
 		middlewares.SetCtxLoggerMiddleware(),
 		middlewares.RequestResponseLoggerMiddleware())
 
+	// Log the bodies of a sample of the requests, for debugging
+	if cfg.GetFloat64("log.bodysample.rate") > 0 || cfg.GetString("log.bodysample.header") != "" {
+		app.Use(middlewares.BodySampleMiddleware(cfg))
+	}
+
 	// Configure metrics
 	if cfg.GetBool("metrics.collect.routes") {
 		buckets := parseMetricBuckets(cfg)
@@ -500,10 +551,12 @@ func Defaultgin(ctx context.Context, cfg *config.Config, osdktrace *otelsdktrace
 	// Register middlewares in order
 	registerCoreMiddlewares(app, cfg, MetricsRegistry)
 	registerSecurityMiddlewares(app, cfg)
+	configureConcurrencyLimiting(app, cfg, MetricsRegistry)
 	registerObservabilityMiddlewares(app, cfg, osdktrace, MetricsRegistry)
 
 	// Register global routes: healthz, NoRoute, NoMethod
 	Setup(app)
+	app.GET(DefaultVersionPath, VersionHandler(cfg))
 
 	// Register debug and monitoring endpoints
 	registerDebugEndpoints(app, cfg, MetricsRegistry)
@@ -0,0 +1,59 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	serverHandler "MgApplication/api-server/handler"
+	"MgApplication/api-server/route"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type negotiationController struct {
+	*serverHandler.Base
+}
+
+type negotiationResponse struct {
+	Status string `json:"status"`
+}
+
+func (c *negotiationController) Routes() []route.Route {
+	return []route.Route{
+		route.GET("/status", func(ctx *route.Context, _ route.NoParam) (*negotiationResponse, error) {
+			return &negotiationResponse{Status: "ok"}, nil
+		}),
+		route.Raw(http.MethodGet, "/stream", func(ctx *gin.Context) {
+			ctx.Header("Content-Type", "text/event-stream")
+			ctx.String(http.StatusOK, "data: ok\n\n")
+		}),
+	}
+}
+
+func TestRegisterRoutesNegotiatesOnlyRenderedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := &Router{
+		app:        gin.New(),
+		registries: ParseControllers(&negotiationController{serverHandler.New("Negotiation").SetPrefix("/v1")}),
+	}
+	r.RegisterRoutes()
+
+	cases := []struct {
+		path   string
+		accept string
+		code   int
+	}{
+		{"/v1/status", "application/json", http.StatusOK},
+		{"/v1/status", "text/event-stream", http.StatusNotAcceptable},
+		{"/v1/stream", "text/event-stream", http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("Accept", tc.accept)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, tc.code, rec.Code, "%s accepting %s: %s", tc.path, tc.accept, rec.Body.String())
+	}
+}
@@ -0,0 +1,121 @@
+package sse
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultBufferSize is the number of events buffered per subscriber when none is configured
+const DefaultBufferSize = 64
+
+var DroppedSubscribersTotal = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "sse_dropped_subscribers_total",
+		Help: "Total number of event stream subscribers dropped for falling behind",
+	},
+)
+
+// Event is a server-sent event
+type Event struct {
+	ID   uint64
+	Name string
+	Data []byte
+}
+
+// Subscriber receives the events published to one topic
+type Subscriber struct {
+	topic  string
+	events chan Event
+	once   sync.Once
+}
+
+// Events returns the events of the subscription. The channel is closed when the subscriber is
+// unsubscribed or dropped for falling behind.
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscriber) close() {
+	s.once.Do(func() { close(s.events) })
+}
+
+// Hub is an in-process publish/subscribe hub for event streams.
+//
+// Publishing never blocks: every subscriber has a bounded buffer, and a subscriber whose buffer
+// is full is dropped, so a slow client cannot hold up the publishers. Dropped clients reconnect
+// and resynchronise from a fresh snapshot.
+type Hub struct {
+	mu     sync.Mutex
+	buffer int
+	seq    uint64
+	topics map[string]map[*Subscriber]struct{}
+}
+
+// NewHub creates a new hub buffering up to buffer events per subscriber
+func NewHub(buffer int) *Hub {
+	if buffer <= 0 {
+		buffer = DefaultBufferSize
+	}
+	return &Hub{
+		buffer: buffer,
+		topics: make(map[string]map[*Subscriber]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber to topic
+func (h *Hub) Subscribe(topic string) *Subscriber {
+	s := &Subscriber{topic: topic, events: make(chan Event, h.buffer)}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[*Subscriber]struct{})
+	}
+	h.topics[topic][s] = struct{}{}
+	return s
+}
+
+// Unsubscribe removes the subscriber and closes its event channel
+func (h *Hub) Unsubscribe(s *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(s)
+}
+
+// Publish sends an event to all subscribers of topic. Event IDs increase with every publish.
+func (h *Hub) Publish(topic string, name string, data []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	event := Event{ID: h.seq, Name: name, Data: data}
+	for s := range h.topics[topic] {
+		select {
+		case s.events <- event:
+		default:
+			h.remove(s)
+			DroppedSubscribersTotal.Inc()
+		}
+	}
+}
+
+// Subscribers returns the number of subscribers to topic
+func (h *Hub) Subscribers(topic string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.topics[topic])
+}
+
+// remove must be called with h.mu held
+func (h *Hub) remove(s *Subscriber) {
+	subscribers := h.topics[s.topic]
+	if _, ok := subscribers[s]; !ok {
+		return
+	}
+	delete(subscribers, s)
+	if len(subscribers) == 0 {
+		delete(h.topics, s.topic)
+	}
+	s.close()
+}
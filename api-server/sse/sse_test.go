@@ -0,0 +1,121 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readEvent reads one event or comment block from an event stream
+func readEvent(t *testing.T, r *bufio.Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+// streamServer serves topic from hub with the given snapshot
+func streamServer(t *testing.T, hub *Hub, topic string, heartbeat time.Duration) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sub := hub.Subscribe(topic)
+		defer hub.Unsubscribe(sub)
+		_ = Stream(w, r, sub, Event{Name: "snapshot", Data: []byte(`{"sent":10}`)}, heartbeat)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func connect(t *testing.T, ctx context.Context, url string) *bufio.Reader {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	return bufio.NewReader(resp.Body)
+}
+
+func TestStreamSnapshotThenEventsInOrder(t *testing.T) {
+	hub := NewHub(8)
+	srv := streamServer(t, hub, "campaign:1", time.Minute)
+
+	stream := connect(t, context.Background(), srv.URL)
+	assert.Equal(t, []string{"event: snapshot", `data: {"sent":10}`}, readEvent(t, stream))
+
+	hub.Publish("campaign:1", "progress", []byte(`{"delivered":1}`))
+	hub.Publish("campaign:2", "progress", []byte(`{"delivered":5}`))
+	hub.Publish("campaign:1", "progress", []byte(`{"failed":1}`))
+
+	assert.Equal(t, []string{"id: 1", "event: progress", `data: {"delivered":1}`}, readEvent(t, stream))
+	assert.Equal(t, []string{"id: 3", "event: progress", `data: {"failed":1}`}, readEvent(t, stream))
+}
+
+func TestStreamHeartbeat(t *testing.T) {
+	hub := NewHub(8)
+	srv := streamServer(t, hub, "campaign:1", 20*time.Millisecond)
+
+	stream := connect(t, context.Background(), srv.URL)
+	readEvent(t, stream)
+	assert.Equal(t, []string{": heartbeat"}, readEvent(t, stream))
+}
+
+func TestStreamCleanupOnDisconnect(t *testing.T) {
+	hub := NewHub(8)
+	srv := streamServer(t, hub, "campaign:1", time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := connect(t, ctx, srv.URL)
+	readEvent(t, stream)
+	assert.Equal(t, 1, hub.Subscribers("campaign:1"))
+
+	cancel()
+	assert.Eventually(t, func() bool { return hub.Subscribers("campaign:1") == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestHubDropsSlowestSubscriber(t *testing.T) {
+	hub := NewHub(2)
+	slow := hub.Subscribe("application:4")
+	fast := hub.Subscribe("application:4")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			hub.Publish("application:4", "progress", []byte("{}"))
+			<-fast.Events()
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a full subscriber")
+	}
+
+	// The slow subscriber keeps its buffered events and is then closed
+	assert.Equal(t, uint64(1), (<-slow.Events()).ID)
+	assert.Equal(t, uint64(2), (<-slow.Events()).ID)
+	_, open := <-slow.Events()
+	assert.False(t, open)
+	assert.Equal(t, 1, hub.Subscribers("application:4"))
+
+	hub.Unsubscribe(fast)
+	hub.Unsubscribe(fast)
+	assert.Equal(t, 0, hub.Subscribers("application:4"))
+}
@@ -0,0 +1,83 @@
+package sse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultHeartbeat is the interval of the keep-alive comments sent on idle streams
+const DefaultHeartbeat = 15 * time.Second
+
+// ErrDropped is returned by Stream when the subscriber was dropped for falling behind
+var ErrDropped = errors.New("event stream subscriber dropped")
+
+// Stream writes snapshot followed by the events of sub to w as a text/event-stream, sending a
+// heartbeat comment whenever the stream was idle for heartbeat. It returns when the client
+// disconnects (nil) or the subscriber is dropped (ErrDropped). The caller unsubscribes sub.
+func Stream(w http.ResponseWriter, r *http.Request, sub *Subscriber, snapshot Event, heartbeat time.Duration) error {
+	if heartbeat <= 0 {
+		heartbeat = DefaultHeartbeat
+	}
+
+	rc := http.NewResponseController(w)
+	// Streams outlive the server write timeout
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeEvent(w, rc, snapshot); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case event, ok := <-sub.Events():
+			if !ok {
+				return ErrDropped
+			}
+			if err := writeEvent(w, rc, event); err != nil {
+				return err
+			}
+			ticker.Reset(heartbeat)
+		case <-ticker.C:
+			if _, err := w.Write([]byte(": heartbeat\n\n")); err != nil {
+				return err
+			}
+			if err := rc.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, rc *http.ResponseController, event Event) error {
+	var buf bytes.Buffer
+	if event.ID > 0 {
+		fmt.Fprintf(&buf, "id: %d\n", event.ID)
+	}
+	if event.Name != "" {
+		fmt.Fprintf(&buf, "event: %s\n", event.Name)
+	}
+	for _, line := range bytes.Split(event.Data, []byte("\n")) {
+		fmt.Fprintf(&buf, "data: %s\n", line)
+	}
+	buf.WriteByte('\n')
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
@@ -3,6 +3,7 @@ package swagger
 import (
 	"database/sql"
 	"reflect"
+	"strconv"
 	"strings"
 
 	errors "MgApplication/api-errors"
@@ -175,7 +176,7 @@ func buildModelDefinition(defs m, t reflect.Type, isReq bool) {
 				//fmt.Println("After changing type inside Nullstring: ", f)
 			}
 
-			smp[getFieldName(f)] = getPropertyField(f.Type)
+			smp[getFieldName(f)] = withEnum(getPropertyField(f.Type), f)
 
 			if vts, ok := f.Tag.Lookup("validate"); isReq && ok {
 				if slc.Contains(strings.Split(vts, ","), "required") {
@@ -210,3 +211,36 @@ func getFieldName(f reflect.StructField) string {
 
 	return f.Name
 }
+
+// withEnum adds the allowed values of f, listed in its enum tag such as `enum:"1,2,3,4"`, to the
+// property pi. The values are typed like the field, so integer enums are emitted as numbers.
+func withEnum(pi m, f reflect.StructField) m {
+	raw, ok := f.Tag.Lookup("enum")
+	if !ok || raw == "" {
+		return pi
+	}
+	ft := f.Type
+	if ft.Kind() == reflect.Pointer {
+		ft = ft.Elem()
+	}
+
+	var values []any
+	for _, v := range strings.Split(raw, ",") {
+		v = strings.TrimSpace(v)
+		switch ft.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				continue
+			}
+			values = append(values, n)
+		default:
+			values = append(values, v)
+		}
+	}
+	if len(values) > 0 {
+		pi["enum"] = values
+	}
+	return pi
+}
@@ -0,0 +1,46 @@
+package swagger
+
+import (
+	"reflect"
+	"testing"
+)
+
+type enumTestRequest struct {
+	Priority    int    `json:"priority" validate:"required,priority" enum:"1,2,3,4"`
+	MessageType string `json:"message_type" validate:"omitempty,message_type" enum:"PM,UC"`
+	Gateway     string `form:"gateway" enum:"1,2"`
+	SenderID    string `json:"sender_id"`
+}
+
+func TestBuildModelDefinitionEnum(t *testing.T) {
+	defs := make(m)
+	buildModelDefinition(defs, reflect.TypeOf(enumTestRequest{}), true)
+
+	props := defs["enumTestRequest"].(m)["properties"].(m)
+	if got := props["priority"].(m)["enum"]; !reflect.DeepEqual(got, []any{int64(1), int64(2), int64(3), int64(4)}) {
+		t.Errorf("priority enum = %v", got)
+	}
+	if got := props["message_type"].(m)["enum"]; !reflect.DeepEqual(got, []any{"PM", "UC"}) {
+		t.Errorf("message_type enum = %v", got)
+	}
+	if _, ok := props["sender_id"].(m)["enum"]; ok {
+		t.Error("sender_id has no enum tag but got an enum")
+	}
+}
+
+func TestGetParametersEnum(t *testing.T) {
+	params := getParameters(reflect.TypeOf(enumTestRequest{}))
+
+	var gateway m
+	for _, p := range params {
+		if p["name"] == "gateway" {
+			gateway = p
+		}
+	}
+	if gateway == nil {
+		t.Fatal("gateway query parameter not found")
+	}
+	if got := gateway["enum"]; !reflect.DeepEqual(got, []any{"1", "2"}) {
+		t.Errorf("gateway enum = %v", got)
+	}
+}
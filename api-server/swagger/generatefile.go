@@ -25,10 +25,24 @@ func generatejson(v3 *openapi3.T) {
 		log.Fatalf("Failed to read file: %v", err)
 	}
 
+	resolved, err := resolveRefs(data)
+	if err != nil {
+		log.Fatalf("Failed to resolve JSON: %v", err)
+	}
+
+	err = ioutil.WriteFile("./docs/resolved_swagger.json", resolved, 0644)
+	if err != nil {
+		log.Fatalf("Failed to write file: %v", err)
+	}
+}
+
+// resolveRefs returns the v3 document data with its $refs replaced by the schemas they point
+// to, marshalled by marshalDoc
+func resolveRefs(data []byte) ([]byte, error) {
 	// Parse the JSON into a Gabs container
 	jsonParsed, err := gabs.ParseJSON(data)
 	if err != nil {
-		log.Fatalf("Failed to parse JSON: %v", err)
+		return nil, err
 	}
 
 	// Start by processing components.schemas
@@ -43,10 +57,7 @@ func generatejson(v3 *openapi3.T) {
 		jsonParsed.DeleteP(nullStringPath)
 	}
 
-	err = ioutil.WriteFile("./docs/resolved_swagger.json", []byte(jsonParsed.StringIndent("", "  ")), 0644)
-	if err != nil {
-		log.Fatalf("Failed to write file: %v", err)
-	}
+	return marshalDoc(jsonParsed.Data())
 }
 
 
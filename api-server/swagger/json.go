@@ -37,9 +37,29 @@ const (
 
 // func buildDocs(eds []EndpointDef, cfg *config.Config) Docs {
 func buildDocs(eds []EndpointDef, cfg *config.Config) *openapi3.T {
+	v3Doc := generateDocs(eds, cfg)
+	if v3Doc == nil {
+		return nil
+	}
+
+	// Persist generated v3 document to file (ignore error)
+	if err := storeV3DocToFile(v3Doc); err != nil {
+		report().Error("storing the v3 doc to file: %s", err.Error())
+	}
+	return v3Doc
+}
+
+// exampleTime is the example of time values, fixed so that the generated docs do not change
+// from one generation to the next
+var exampleTime = time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+
+// generateDocs builds the OpenAPI 3 document of eds, with its tags and operations in the order
+// given by sortEndpoints
+func generateDocs(eds []EndpointDef, cfg *config.Config) *openapi3.T {
 	// Load any nullable type override mappings from config before generating docs
 	loadNullableOverrides(cfg)
 	dj := baseJSON(cfg)
+	dj["tags"] = buildTags(eds)
 	dj["definitions"] = buildDefinitions(eds)
 	dj["paths"] = buildPaths(eds)
 
@@ -53,19 +73,13 @@ func buildDocs(eds []EndpointDef, cfg *config.Config) *openapi3.T {
 	}
 	v3Doc, err := openapi2conv.ToV3(&v2Doc)
 	if err != nil {
-		fmt.Println("Error converting to v3:", err)
+		report().Error("converting the docs to v3: %s", err.Error())
 		return nil
 	}
 
 	// // Attach success & error examples (overrides any missing examples)
 	attachErrorExamples(v3Doc)
 
-	// Persist generated v3 document to file (ignore error)
-	err = storeV3DocToFile(v3Doc)
-	if err != nil {
-		fmt.Println("Error storing v3 doc to file:", err)
-	}
-
 	return v3Doc
 
 	// Populate servers for OpenAPI 3 so tools (Swagger UI/Editor) build correct curl / request URL.
@@ -161,7 +175,7 @@ func buildDocs(eds []EndpointDef, cfg *config.Config) *openapi3.T {
 }
 func storeV3DocToFile(v3Doc *openapi3.T) error {
 	// Marshal v3Doc to JSON
-	v3DocJSON, err := json.MarshalIndent(v3Doc, "", "  ")
+	v3DocJSON, err := marshalDoc(v3Doc)
 	if err != nil {
 		return fmt.Errorf("error marshaling v3Doc to JSON: %w", err)
 	}
@@ -186,7 +200,7 @@ func storeV3DocToFile(v3Doc *openapi3.T) error {
 	//     log.Fatal(err)
 	// }
 
-	fmt.Println("v3Doc has been successfully stored in v3Doc.json")
+	report().Info("v3 doc stored in docs/v3Doc.json")
 	return nil
 }
 
@@ -209,7 +223,7 @@ func baseJSON(cfg *config.Config) m {
 	of, err := cfg.Of("info")
 	//TODO
 	if err != nil {
-		fmt.Println("Error getting info:", err)
+		report().Error("reading the info configuration: %s", err.Error())
 	}
 	//fmt.Println("info value:", of.GetString("version"))
 	// Host/basePath/schemes (Swagger 2) can be configured; fallback to sensible defaults.
@@ -323,7 +337,7 @@ func getPropertyField(t reflect.Type) m {
 	}
 
 	if t == typlect.TypeTime {
-		b, _ := time.Now().MarshalJSON()
+		b, _ := exampleTime.MarshalJSON()
 		return m{"type": "string", "example": strings.Trim(string(b), "\"")}
 	}
 
@@ -622,7 +636,7 @@ func buildSchemaExample(sr *openapi3.SchemaRef, comp *openapi3.Components, depth
 	switch t {
 	case "string":
 		if s.Format == "date-time" {
-			return time.Now().UTC().Format(time.RFC3339)
+			return exampleTime.Format(time.RFC3339)
 		}
 		return "string"
 	case "integer":
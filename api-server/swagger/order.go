@@ -0,0 +1,65 @@
+package swagger
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// documented reports whether ed is listed in the generated docs
+func documented(ed EndpointDef) bool {
+	return !strings.HasPrefix(ed.Endpoint, "/__")
+}
+
+// sortEndpoints returns the documented endpoints of eds in the order of the generated docs: by
+// the weight and name of their tag, then by their own weight, path and method
+func sortEndpoints(eds []EndpointDef) []EndpointDef {
+	sorted := slices.DeleteFunc(slices.Clone(eds), func(ed EndpointDef) bool { return !documented(ed) })
+	slices.SortStableFunc(sorted, func(a, b EndpointDef) int {
+		return cmp.Or(
+			cmp.Compare(a.GroupOrder, b.GroupOrder),
+			cmp.Compare(a.Group, b.Group),
+			cmp.Compare(a.Order, b.Order),
+			cmp.Compare(a.Endpoint, b.Endpoint),
+			cmp.Compare(a.Method, b.Method),
+		)
+	})
+	return sorted
+}
+
+// buildTags returns the tags of the documented endpoints of eds in display order, each with
+// its description and its position in x-order
+func buildTags(eds []EndpointDef) []m {
+	var tags []m
+	seen := make(map[string]bool)
+	for _, ed := range sortEndpoints(eds) {
+		if seen[ed.Group] {
+			continue
+		}
+		seen[ed.Group] = true
+		tag := m{"name": ed.Group, "x-order": len(tags) + 1}
+		if ed.GroupDescription != "" {
+			tag["description"] = ed.GroupDescription
+		}
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// marshalDoc marshals doc indented with the keys of every object sorted, whatever the
+// marshallers of its types, so that generating the docs twice gives byte-identical files
+func marshalDoc(doc any) ([]byte, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(tree, "", "  ")
+}
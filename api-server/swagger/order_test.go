@@ -0,0 +1,122 @@
+package swagger
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+
+	"github.com/spf13/viper"
+)
+
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+type orderTestRequest struct {
+	ApplicationID string `uri:"application-id" validate:"required"`
+	Status        bool   `json:"status" validate:"required"`
+}
+
+type orderTestResponse struct {
+	ID        uint64    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// orderTestEndpoints are endpoints of three tags declared out of display order, with path
+// parameters missing from their request and time values in their response
+func orderTestEndpoints() []EndpointDef {
+	req, res, none := reflect.TypeOf(orderTestRequest{}), reflect.TypeOf(orderTestResponse{}), reflect.TypeOf(struct{}{})
+	return []EndpointDef{
+		{RequestType: none, ResponseType: res, Group: "Templates", Name: "List templates", Endpoint: "/v1/sms-templates", Method: "GET", GroupOrder: 2},
+		{RequestType: req, ResponseType: res, Group: "Applications", Name: "/v1/applications/:application-id", Endpoint: "/v1/applications/:application-id", Method: "PUT", GroupOrder: 1,
+			GroupDescription: "Applications sending messages", Summary: "Update an application"},
+		{RequestType: none, ResponseType: res, Group: "Templates", Name: "Fetch template", Endpoint: "/v1/sms-templates/:template-local-id/:version", Method: "GET", GroupOrder: 2},
+		{RequestType: none, ResponseType: res, Group: "Applications", Name: "List applications", Endpoint: "/v1/applications", Method: "GET", GroupOrder: 1,
+			GroupDescription: "Applications sending messages", Order: 1, Description: "Lists the applications"},
+		{RequestType: req, ResponseType: res, Group: "Applications", Name: "Create application", Endpoint: "/v1/applications/:application-id", Method: "POST", GroupOrder: 1,
+			GroupDescription: "Applications sending messages"},
+		{RequestType: none, ResponseType: res, Group: "Admin", Name: "Status", Endpoint: "/v1/admin/status", Method: "GET"},
+		{RequestType: none, ResponseType: none, Group: "Admin", Name: "Internal", Endpoint: "/__internal", Method: "GET"},
+	}
+}
+
+func generateTestDocs(t *testing.T, eds []EndpointDef) []byte {
+	t.Helper()
+	v3Doc := generateDocs(eds, config.NewConfig(viper.New()))
+	if v3Doc == nil {
+		t.Fatal("generateDocs returned nil")
+	}
+	data, err := marshalDoc(v3Doc)
+	if err != nil {
+		t.Fatalf("marshalDoc: %v", err)
+	}
+	resolved, err := resolveRefs(data)
+	if err != nil {
+		t.Fatalf("resolveRefs: %v", err)
+	}
+	return append(append(data, '\n'), resolved...)
+}
+
+// TestGenerateDocsDeterministic tests that two consecutive generations give byte-identical docs,
+// whatever the order the endpoints are registered in, matching the golden file
+func TestGenerateDocsDeterministic(t *testing.T) {
+	eds := orderTestEndpoints()
+	first := generateTestDocs(t, eds)
+
+	reversed := orderTestEndpoints()
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+	if second := generateTestDocs(t, reversed); !bytes.Equal(first, second) {
+		t.Fatal("two generations of the docs differ")
+	}
+
+	golden := filepath.Join("testdata", "docs.golden.json")
+	if *update {
+		if err := os.WriteFile(golden, first, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("reading the golden file, run with -update to create it: %v", err)
+	}
+	if !bytes.Equal(first, want) {
+		t.Error("generated docs differ from testdata/docs.golden.json, run with -update if the change is intended")
+	}
+}
+
+func TestSortEndpoints(t *testing.T) {
+	var got []string
+	for _, ed := range sortEndpoints(orderTestEndpoints()) {
+		got = append(got, ed.Method+" "+ed.Endpoint)
+	}
+	want := []string{
+		"GET /v1/admin/status",
+		"POST /v1/applications/:application-id",
+		"PUT /v1/applications/:application-id",
+		"GET /v1/applications",
+		"GET /v1/sms-templates",
+		"GET /v1/sms-templates/:template-local-id/:version",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sortEndpoints() = %v, want %v", got, want)
+	}
+}
+
+func TestBuildTags(t *testing.T) {
+	tags := buildTags(orderTestEndpoints())
+	want := []m{
+		{"name": "Admin", "x-order": 1},
+		{"name": "Applications", "x-order": 2, "description": "Applications sending messages"},
+		{"name": "Templates", "x-order": 3},
+	}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("buildTags() = %v, want %v", tags, want)
+	}
+}
@@ -2,7 +2,9 @@ package swagger
 
 import (
 	//"fmt"
+	"maps"
 	"reflect"
+	"slices"
 	"strings"
 
 	errors "MgApplication/api-errors"
@@ -11,15 +13,12 @@ import (
 
 func buildPaths(eds []EndpointDef) m {
 	p := make(m)
-	for _, ed := range eds {
-		if strings.HasPrefix(ed.Endpoint, "/__") {
-			continue
-		}
-
+	for i, ed := range sortEndpoints(eds) {
 		desc := m{
 			"tags":        []string{ed.Group},
-			"summary":     ed.Name,
-			"description": "",
+			"summary":     firstNonEmpty(ed.Summary, ed.Name),
+			"description": ed.Description,
+			"x-order":     i + 1,
 			"consumes": []string{
 				"application/json",
 			},
@@ -46,7 +45,7 @@ func buildPaths(eds []EndpointDef) m {
 				}
 			}
 		}
-		for seg := range missing {
+		for _, seg := range slices.Sorted(maps.Keys(missing)) {
 			params = append(params, m{
 				"in":       "path",
 				"name":     seg,
@@ -173,14 +172,14 @@ func getParameters(t reflect.Type) []m {
 
 			// path param: support `param` or `uri` tag
 			if n := firstNonEmpty(f.Tag.Get("param"), f.Tag.Get("uri")); n != "" {
-				pi := getPropertyField(ft)
+				pi := withEnum(getPropertyField(ft), f)
 				pi["in"], pi["name"], pi["description"], pi["required"] = "path", n, "", true
 				params = append(params, pi)
 			}
 
 			// explicit query tag
 			if n := f.Tag.Get("query"); n != "" {
-				pi := getPropertyField(ft)
+				pi := withEnum(getPropertyField(ft), f)
 				pi["in"], pi["name"], pi["description"] = "query", n, ""
 				if required {
 					pi["required"] = true
@@ -193,7 +192,7 @@ func getParameters(t reflect.Type) []m {
 				parts := strings.Split(raw, ",")
 				name := parts[0]
 				if name != "" { // ignore default or other options after comma
-					pi := getPropertyField(ft)
+					pi := withEnum(getPropertyField(ft), f)
 					pi["in"], pi["name"], pi["description"] = "query", name, ""
 					if required {
 						pi["required"] = true
@@ -0,0 +1,69 @@
+package swagger
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	log "MgApplication/api-log"
+)
+
+// Reporter receives the progress and the errors of the docs generation
+type Reporter interface {
+	Info(format string, args ...any)
+	Error(format string, args ...any)
+}
+
+// logReporter reports through the structured logger, the default
+type logReporter struct{}
+
+func (logReporter) Info(format string, args ...any) {
+	log.Info(nil, "swagger: "+format, args...)
+}
+
+func (logReporter) Error(format string, args ...any) {
+	log.Error(nil, "swagger: "+format, args...)
+}
+
+// writerReporter prints one human readable line per report
+type writerReporter struct {
+	w io.Writer
+}
+
+// NewWriterReporter returns a Reporter printing the reports to w, for a command line tool
+// generating the docs to give its progress on stdout
+func NewWriterReporter(w io.Writer) Reporter {
+	return writerReporter{w: w}
+}
+
+func (r writerReporter) Info(format string, args ...any) {
+	_, _ = fmt.Fprintf(r.w, format+"\n", args...)
+}
+
+func (r writerReporter) Error(format string, args ...any) {
+	_, _ = fmt.Fprintf(r.w, "error: "+format+"\n", args...)
+}
+
+// reporterRef holds the Reporter set
+type reporterRef struct {
+	reporter Reporter
+}
+
+var reporter atomic.Pointer[reporterRef]
+
+// SetReporter sets the Reporter of the docs generation, nil restoring the structured logger
+func SetReporter(r Reporter) {
+	if r == nil {
+		reporter.Store(nil)
+		return
+	}
+	reporter.Store(&reporterRef{reporter: r})
+}
+
+// report returns the Reporter set, the structured logger by default
+func report() Reporter {
+	if ref := reporter.Load(); ref != nil {
+		return ref.reporter
+	}
+	return logReporter{}
+}
@@ -0,0 +1,24 @@
+package swagger
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriterReporter(t *testing.T) {
+	var out bytes.Buffer
+	SetReporter(NewWriterReporter(&out))
+	t.Cleanup(func() { SetReporter(nil) })
+
+	report().Info("v3 doc stored in %s", "docs/v3Doc.json")
+	report().Error("converting the docs to v3: %s", "invalid ref")
+	want := "v3 doc stored in docs/v3Doc.json\nerror: converting the docs to v3: invalid ref\n"
+	if out.String() != want {
+		t.Errorf("reported %q, want %q", out.String(), want)
+	}
+
+	SetReporter(nil)
+	if _, ok := report().(logReporter); !ok {
+		t.Errorf("reporter %T after SetReporter(nil), want the structured logger", report())
+	}
+}
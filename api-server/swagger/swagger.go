@@ -11,4 +11,12 @@ type EndpointDef struct {
 	Name         string
 	Endpoint     string
 	Method       string
+	// GroupDescription and GroupOrder are the description and display order weight of the tag
+	GroupDescription string
+	GroupOrder       int
+	// Summary overrides Name as the summary of the operation
+	Summary     string
+	Description string
+	// Order is the display order weight of the operation within its tag
+	Order int
 }
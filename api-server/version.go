@@ -0,0 +1,59 @@
+package router
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	config "MgApplication/api-config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultVersionPath is the path of the build information endpoint
+const DefaultVersionPath = "/version"
+
+// Build information of the binary, injected at build time:
+//
+//	go build -ldflags "-X MgApplication/api-server.GitCommit=$(git rev-parse HEAD) -X MgApplication/api-server.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	GitCommit string
+	BuildTime string
+)
+
+// VersionInfo describes the running build.
+type VersionInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildTime string `json:"build_time"`
+}
+
+// NewVersionInfo returns the build information of the running binary. Without the ldflags, the
+// commit and time recorded by the go toolchain are used, else "unknown".
+func NewVersionInfo(cfg *config.Config) VersionInfo {
+	info := VersionInfo{Version: cfg.AppVersion(), GitCommit: GitCommit, BuildTime: BuildTime}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.GitCommit == "":
+				info.GitCommit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+	if info.GitCommit == "" {
+		info.GitCommit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
+
+// VersionHandler answers with the build information of the running binary, computed once.
+func VersionHandler(cfg *config.Config) gin.HandlerFunc {
+	info := NewVersionInfo(cfg)
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, info)
+	}
+}
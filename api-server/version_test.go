@@ -0,0 +1,39 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	config "MgApplication/api-config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionHandler(t *testing.T) {
+	GitCommit, BuildTime = "3f2c1ab", "2024-08-27T10:00:00Z"
+	t.Cleanup(func() { GitCommit, BuildTime = "", "" })
+	cfg := config.NewConfig(viper.New())
+	cfg.Set("info.version", "1.2.3")
+
+	gin.SetMode(gin.TestMode)
+	app := gin.New()
+	app.GET(DefaultVersionPath, VersionHandler(cfg))
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, DefaultVersionPath, nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, map[string]string{"version": "1.2.3", "git_commit": "3f2c1ab", "build_time": "2024-08-27T10:00:00Z"}, body)
+}
+
+func TestNewVersionInfoDefaultsToUnknown(t *testing.T) {
+	info := NewVersionInfo(config.NewConfig(viper.New()))
+	assert.NotEmpty(t, info.GitCommit)
+	assert.NotEmpty(t, info.BuildTime)
+}
@@ -7,8 +7,11 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"MgApplication/core/idgen"
+
 	"github.com/go-playground/validator/v10"
 )
 
@@ -32,6 +35,8 @@ var (
 	// Bank Identification Patterns
 	bankUserIDPattern = regexp.MustCompile(`^[A-Z0-9]{1,50}$`)
 	bankIDPattern     = regexp.MustCompile(`^[A-Z0-9]{1,50}$`)
+	// swiftBICPattern is a BIC: bank (4 letters), country (2 letters), location (2) and optional branch (3)
+	swiftBICPattern = regexp.MustCompile(`^[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
 
 	// Facility Identification Patterns
 	csiFacilityIDPattern = regexp.MustCompile(`^[A-Z]{2}\d{11}$`)
@@ -99,7 +104,7 @@ var (
 	allZerosRegex                            = regexp.MustCompile("^0+$")
 	customValidateAnyStringLengthto50Pattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9]{0,48}[a-zA-Z]$`)
 	solIdPattern                             = regexp.MustCompile(`^\d{6}\d{2}$`)
-	stringFieldPattern                       = regexp.MustCompile(`^[A-Za-z0-9\s,_.\/\-\(\)]{1,50}$`)
+	stringFieldPattern                       = regexp.MustCompile(`^[A-Za-z0-9\s,_.\/\-\(\)]+$`)
 )
 
 var statesOfIndia = map[string]struct{}{
@@ -151,6 +156,30 @@ var statesOfIndia = map[string]struct{}{
 	"99 apo":                      {},
 }
 
+// currencyCodes are the active ISO 4217 currency codes
+var currencyCodes = map[string]struct{}{
+	"AED": {}, "AFN": {}, "ALL": {}, "AMD": {}, "ANG": {}, "AOA": {}, "ARS": {}, "AUD": {},
+	"AWG": {}, "AZN": {}, "BAM": {}, "BBD": {}, "BDT": {}, "BGN": {}, "BHD": {}, "BIF": {},
+	"BMD": {}, "BND": {}, "BOB": {}, "BRL": {}, "BSD": {}, "BTN": {}, "BWP": {}, "BYN": {},
+	"BZD": {}, "CAD": {}, "CDF": {}, "CHF": {}, "CLP": {}, "CNY": {}, "COP": {}, "CRC": {},
+	"CUP": {}, "CVE": {}, "CZK": {}, "DJF": {}, "DKK": {}, "DOP": {}, "DZD": {}, "EGP": {},
+	"ERN": {}, "ETB": {}, "EUR": {}, "FJD": {}, "FKP": {}, "GBP": {}, "GEL": {}, "GHS": {},
+	"GIP": {}, "GMD": {}, "GNF": {}, "GTQ": {}, "GYD": {}, "HKD": {}, "HNL": {}, "HTG": {},
+	"HUF": {}, "IDR": {}, "ILS": {}, "INR": {}, "IQD": {}, "IRR": {}, "ISK": {}, "JMD": {},
+	"JOD": {}, "JPY": {}, "KES": {}, "KGS": {}, "KHR": {}, "KMF": {}, "KPW": {}, "KRW": {},
+	"KWD": {}, "KYD": {}, "KZT": {}, "LAK": {}, "LBP": {}, "LKR": {}, "LRD": {}, "LSL": {},
+	"LYD": {}, "MAD": {}, "MDL": {}, "MGA": {}, "MKD": {}, "MMK": {}, "MNT": {}, "MOP": {},
+	"MRU": {}, "MUR": {}, "MVR": {}, "MWK": {}, "MXN": {}, "MYR": {}, "MZN": {}, "NAD": {},
+	"NGN": {}, "NIO": {}, "NOK": {}, "NPR": {}, "NZD": {}, "OMR": {}, "PAB": {}, "PEN": {},
+	"PGK": {}, "PHP": {}, "PKR": {}, "PLN": {}, "PYG": {}, "QAR": {}, "RON": {}, "RSD": {},
+	"RUB": {}, "RWF": {}, "SAR": {}, "SBD": {}, "SCR": {}, "SDG": {}, "SEK": {}, "SGD": {},
+	"SHP": {}, "SLE": {}, "SOS": {}, "SRD": {}, "SSP": {}, "STN": {}, "SVC": {}, "SYP": {},
+	"SZL": {}, "THB": {}, "TJS": {}, "TMT": {}, "TND": {}, "TOP": {}, "TRY": {}, "TTD": {},
+	"TWD": {}, "TZS": {}, "UAH": {}, "UGX": {}, "USD": {}, "UYU": {}, "UZS": {}, "VES": {},
+	"VND": {}, "VUV": {}, "WST": {}, "XAF": {}, "XCD": {}, "XOF": {}, "XPF": {}, "YER": {},
+	"ZAR": {}, "ZMW": {}, "ZWL": {},
+}
+
 func newValidateBeatNamePatternValidator() validationRule {
 	return newRule("beat_name", validateBeatNamePattern, "field %s must be in the format 'BEAT_XX', where 'XX' is a number between 0 and 99, but received %v")
 }
@@ -283,6 +312,20 @@ func newvalidatePinCodeGlobalValidator() validationRule {
 func newValidateMobileNumberPatternValidator() validationRule {
 	return newRule("mobile_number", validateMobileNumberStringPattern, "field %s must be a valid 10-digit phone number starting with a digit between 6 and 9, but received %v")
 }
+func newMobileNumberListValidator() validationRule {
+	return newRuleWithMessage("mobile_number_list", validateMobileNumberList, mobileNumberListMessage)
+}
+
+// mobileNumberListMessage names the first invalid number of the list
+func mobileNumberListMessage(field string, value any) string {
+	const msg = "field %s must be a comma-separated list of 10-digit phone numbers starting with a digit between 6 and 9"
+	if list, ok := value.(string); ok {
+		if i, number := firstInvalidMobileNumber(list); i >= 0 {
+			return fmt.Sprintf(msg+", but the number at index %d is %q", field, i, number)
+		}
+	}
+	return fmt.Sprintf(msg+", but received %v", field, value)
+}
 func newCustomofficeidGlobalValidator() validationRule {
 	return newRule("office_id", customofficeidGlobal, "field %s must be between 10000000 & 99999999, but received %v")
 }
@@ -331,6 +374,26 @@ func newIsValidTimestampGlobalValidator() validationRule {
 func newIsValidStateValidator() validationRule {
 	return newRule("state", validatedStateGlobal, "field %s must be a valid Indian state, but received %v")
 }
+func newCurrencyCodeValidator() validationRule {
+	return newRule("currency_code", validateCurrencyCode, "field %s must be a 3-letter uppercase ISO 4217 currency code such as INR or USD, but received %v")
+}
+
+func newSwiftBICValidator() validationRule {
+	return newRule("swift_bic", validateSwiftBIC, "field %s must be an 8 or 11 character SWIFT/BIC code of uppercase letters and digits such as SBININBB or SBININBB104, but received %v")
+}
+
+func newLatitudeValidator() validationRule {
+	return newRule("latitude", validateLatitude, "field %s must be a latitude in degrees between -90 and 90, but received %v")
+}
+
+func newLongitudeValidator() validationRule {
+	return newRule("longitude", validateLongitude, "field %s must be a longitude in degrees between -180 and 180, but received %v")
+}
+
+// coordinatePairMessage is the message for a coordinate missing while the other one of its pair is given
+func coordinatePairMessage(field string, value any) string {
+	return fmt.Sprintf("field %s is required when the other coordinate of the pair is given, but received %v", field, value)
+}
 
 func newvalidateCityNameValidator() validationRule {
 	return newRule("city_name", validateCityNamePattern, "field %s must start and end with  character, and may contain letters,digits and  spaces, commas, periods, and hyphens in between. The total length should be between 3 and 50 characters. , but received %v")
@@ -368,12 +431,33 @@ func newOptionalFieldValidator() validationRule {
 	return newRule("optional", optionalField, "field %s must be empty or a valid value, but received %v")
 }
 
+// stringFieldMaxLength is the length string_field accepts unless the tag sets another, as in string_field=64
+const stringFieldMaxLength = 50
+
 func newStringFieldValidator() validationRule {
-	return newRule("string_field", validateStringField, "field %s must start and end with an alphanumeric character, and may contain letters, digits, spaces, commas, periods, parentheses and hyphens. The total length should be between 1 and 50 characters, but received %v")
+	return newRule("string_field", validateStringField, "field %s must start and end with an alphanumeric character, and may contain letters, digits, spaces, commas, periods, parentheses and hyphens. The total length should be between 1 and 50 characters, or the length set as string_field=<n>, but received %v")
 }
 
 func validateStringField(fl validator.FieldLevel) bool {
-	return validateWithGlobalRegex(fl, stringFieldPattern)
+	maxLength := stringFieldMaxLength
+	if param := fl.Param(); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 1 {
+			return false
+		}
+		maxLength = n
+	}
+	return len(fl.Field().String()) <= maxLength && validateWithGlobalRegex(fl, stringFieldPattern)
+}
+
+func newCommunicationIDValidator() validationRule {
+	return newRule("communication_id", validateCommunicationID, "field %s must be a communication ID, a 26 character ULID such as 01ARZ3NDEKTSV4RRFFQ69G5FAV or an earlier ID of 20 lowercase letters and digits, but received %v")
+}
+
+// validateCommunicationID accepts the IDs of the idgen generators and the legacy IDs generated
+// by the database
+func validateCommunicationID(fl validator.FieldLevel) bool {
+	return fl.Field().Kind() == reflect.String && idgen.Valid(fl.Field().String())
 }
 
 func optionalField(fl validator.FieldLevel) bool {
@@ -522,9 +606,52 @@ func validateGSTINPattern(fl validator.FieldLevel) bool {
 	return validateWithGlobalRegex(fl, gstINPattern)
 }
 
+// reservedMobileNumbers matches the numbers accepted besides the mobile numbers, see
+// ReserveMobileNumbers
+var reservedMobileNumbers atomic.Pointer[func(string) bool]
+
+// ReserveMobileNumbers makes mobile_number and mobile_number_list also accept the numbers
+// matched by reserved, such as the numbers of a test hook that never reach a gateway. A nil
+// reserved removes them.
+func ReserveMobileNumbers(reserved func(string) bool) {
+	if reserved == nil {
+		reservedMobileNumbers.Store(nil)
+		return
+	}
+	reservedMobileNumbers.Store(&reserved)
+}
+
+// isMobileNumber reports whether number is a mobile number or a reserved number
+func isMobileNumber(number string) bool {
+	if mobileNumberStringPattern.MatchString(number) {
+		return true
+	}
+	reserved := reservedMobileNumbers.Load()
+	return reserved != nil && (*reserved)(number)
+}
+
 func validateMobileNumberStringPattern(fl validator.FieldLevel) bool {
-	return validateWithGlobalRegex(fl, mobileNumberStringPattern)
+	return isMobileNumber(fl.Field().String())
 }
+func validateMobileNumberList(fl validator.FieldLevel) bool {
+	if fl.Field().Kind() != reflect.String {
+		return false
+	}
+	i, _ := firstInvalidMobileNumber(fl.Field().String())
+	return i < 0
+}
+
+// firstInvalidMobileNumber returns the index and value of the first entry of the comma-separated
+// list that is not a mobile number, -1 when all are valid. Empty entries are invalid.
+func firstInvalidMobileNumber(list string) (int, string) {
+	for i, number := range strings.Split(list, ",") {
+		if !isMobileNumber(number) {
+			return i, number
+		}
+	}
+	return -1, ""
+}
+
 func validateBagIdPattern(fl validator.FieldLevel) bool {
 	return validateWithGlobalRegex(fl, bagIdPattern)
 }
@@ -1174,6 +1301,90 @@ func validatedStateGlobal(fl validator.FieldLevel) bool {
 	return ok
 }
 
+func validateCurrencyCode(fl validator.FieldLevel) bool {
+	if fl.Field().Kind() != reflect.String {
+		return false
+	}
+	_, ok := currencyCodes[fl.Field().String()]
+	return ok
+}
+
+func validateSwiftBIC(fl validator.FieldLevel) bool {
+	if fl.Field().Kind() != reflect.String {
+		return false
+	}
+	return validateWithGlobalRegex(fl, swiftBICPattern)
+}
+
+func validateLatitude(fl validator.FieldLevel) bool {
+	return validateCoordinate(fl, 90)
+}
+
+func validateLongitude(fl validator.FieldLevel) bool {
+	return validateCoordinate(fl, 180)
+}
+
+// validateCoordinate accepts a float, an integer or a numeric string between -limit and limit degrees
+func validateCoordinate(fl validator.FieldLevel, limit float64) bool {
+	field := fl.Field()
+
+	var degrees float64
+	switch field.Kind() {
+	case reflect.Float32, reflect.Float64:
+		degrees = field.Float()
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		degrees = float64(field.Int())
+	case reflect.String:
+		v, err := strconv.ParseFloat(field.String(), 64)
+		if err != nil {
+			return false
+		}
+		degrees = v
+	default:
+		return false
+	}
+	return degrees >= -limit && degrees <= limit
+}
+
+// validateCoordinatePair is a struct-level validation requiring the fields validated with the
+// latitude and longitude tags to be given together. A coordinate is given when its field is not
+// the zero value.
+func validateCoordinatePair(sl validator.StructLevel) {
+	current := sl.Current()
+	latitude, longitude := -1, -1
+	for i := 0; i < current.NumField(); i++ {
+		fld := current.Type().Field(i)
+		if !fld.IsExported() {
+			continue
+		}
+		for _, tag := range strings.Split(fld.Tag.Get("validate"), ",") {
+			switch tag {
+			case "latitude":
+				latitude = i
+			case "longitude":
+				longitude = i
+			}
+		}
+	}
+	if latitude < 0 || longitude < 0 {
+		return
+	}
+
+	latitudeGiven := !current.Field(latitude).IsZero()
+	longitudeGiven := !current.Field(longitude).IsZero()
+	switch {
+	case latitudeGiven && !longitudeGiven:
+		reportMissingCoordinate(sl, longitude)
+	case longitudeGiven && !latitudeGiven:
+		reportMissingCoordinate(sl, latitude)
+	}
+}
+
+func reportMissingCoordinate(sl validator.StructLevel, i int) {
+	fld := sl.Current().Type().Field(i)
+	sl.ReportError(sl.Current().Field(i).Interface(), getStructFieldName(fld), fld.Name, coordinatePairTag, "")
+}
+
 func generateDynamicStringValidationPattern(minLength, maxLength uint, additionalChars ...rune) (*regexp.Regexp, error) {
 	// Base pattern with existing allowed characters (properly escaped)
 	basePattern := `A-Za-z0-9\s,_.\/\-\(\)`
@@ -0,0 +1,337 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	appError "MgApplication/api-errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validatorCase is a value expected to pass or fail the validator registered as tag
+type validatorCase struct {
+	tag   string
+	value any
+	valid bool
+}
+
+func runValidatorCases(t *testing.T, cases []validatorCase) {
+	t.Helper()
+	require.NoError(t, Create())
+	for _, tc := range cases {
+		err := validate.Var(tc.value, tc.tag)
+		if tc.valid {
+			assert.NoError(t, err, "%s should accept %#v", tc.tag, tc.value)
+		} else {
+			assert.Error(t, err, "%s should reject %#v", tc.tag, tc.value)
+		}
+	}
+}
+
+func TestDefaultRulesRegistered(t *testing.T) {
+	require.NoError(t, Create())
+	for _, r := range getDefaultRules() {
+		_, ok := customValidationMessages[r.Name()]
+		assert.True(t, ok, "rule %s is not registered", r.Name())
+	}
+}
+
+func TestIdentityValidators(t *testing.T) {
+	runValidatorCases(t, []validatorCase{
+		{"pan_number", "ABCDE1234F", true},
+		{"pan_number", "abcde1234f", false},
+		{"pan_number", "ABCD1234F", false},
+		{"aadhaar_no", "123456789012", true},
+		{"aadhaar_no", "12345678901", false},
+		{"aadhaar_no", "12345678901A", false},
+		{"pran_no", "123456789012", true},
+		{"pran_no", int64(123456789012), true},
+		{"pran_no", int64(12345678901), false},
+		{"driving_license", "DL0420110012345", true},
+		{"driving_license", "1L0420110012345", false},
+		{"driving_license", "DL04201", false},
+		{"passport_no", "G1234567", true},
+		{"passport_no", "G123456", false},
+		{"passport_no", "12345678", false},
+		{"voter_id", "XYZ3300779", true},
+		{"voter_id", "XYZ330077A", false},
+		{"voter_id", "XYZ33007", false},
+		{"gst_in", "27AAPFU0939F1ZV", true},
+		{"gst_in", "27AAPFU0939F1XV", false},
+		{"gst_in", "AAPFU0939F1ZV", false},
+		{"customer_id", "1234567890", true},
+		{"customer_id", 1234567890, true},
+		{"customer_id", "123456789", false},
+		{"employee_id", 12345678, true},
+		{"employee_id", 1234567, false},
+		{"employee_id", "12345678", false},
+		{"personnel_name", "Ravi K. Sharma", true},
+		{"personnel_name", "R", false},
+		{"personnel_name", "Ravi1", false},
+	})
+}
+
+func TestContactValidators(t *testing.T) {
+	runValidatorCases(t, []validatorCase{
+		{"mobile_number", "9876543210", true},
+		{"mobile_number", "5876543210", false},
+		{"mobile_number", "987654321", false},
+		{"mobile_number", "+919876543210", false},
+		{"mobile_number_list", "9876543210", true},
+		{"mobile_number_list", "9876543210,6123456789,7000000001", true},
+		{"mobile_number_list", "9876543210,5876543210", false},
+		{"mobile_number_list", "9876543210,,6123456789", false},
+		{"mobile_number_list", "9876543210,", false},
+		{"mobile_number_list", "9876543210, 6123456789", false},
+		{"mobile_number_list", "", false},
+		{"mobile_number_list", 9876543210, false},
+		{"phone_length", "0123456789", true},
+		{"phone_length", uint64(9876543210), true},
+		{"phone_length", 987654321, false},
+		{"phone_number", "01123-456789", true},
+		{"phone_number", "01123 456789", true},
+		{"phone_number", "01123_456789", false},
+		{"simple_email", "first.last+sms@indiapost.gov.in", true},
+		{"simple_email", "first.last@indiapost", false},
+		{"simple_email", "@indiapost.gov.in", false},
+		{"address", "12/4, Park Street", true},
+		{"address", "12/4, Park Street,", false},
+		{"city_name", "New Delhi", true},
+		{"city_name", "New Delhi!", false},
+		{"pincode", 110001, true},
+		{"pincode", 100000, false},
+		{"pincode", 11000, false},
+		{"pincode", "110001", false},
+		{"state", "Tamil Nadu", true},
+		{"state", "Atlantis", false},
+	})
+}
+
+func TestReserveMobileNumbers(t *testing.T) {
+	ReserveMobileNumbers(func(number string) bool { return strings.HasPrefix(number, "5000") })
+	t.Cleanup(func() { ReserveMobileNumbers(nil) })
+	runValidatorCases(t, []validatorCase{
+		{"mobile_number", "5000000401", true},
+		{"mobile_number", "5876543210", false},
+		{"mobile_number_list", "9876543210,5000000TMO", true},
+		{"mobile_number_list", "9876543210,5876543210", false},
+	})
+
+	ReserveMobileNumbers(nil)
+	runValidatorCases(t, []validatorCase{
+		{"mobile_number", "5000000401", false},
+	})
+}
+
+func TestMobileNumberListMessage(t *testing.T) {
+	require.NoError(t, Create())
+	type smsRecipients struct {
+		MobileNumbers string `json:"mobile_numbers" validate:"mobile_number_list"`
+	}
+
+	tests := []struct {
+		name    string
+		numbers string
+		message string
+	}{
+		{"all valid", "9876543210,6123456789", ""},
+		{"one invalid", "9876543210,6123456789,12345", `the number at index 2 is "12345"`},
+		{"first invalid reported", "9876543210,5876543210,12345", `the number at index 1 is "5876543210"`},
+		{"empty entry", "9876543210,,6123456789", `the number at index 1 is ""`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateStruct(smsRecipients{MobileNumbers: tc.numbers})
+			if tc.message == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var appErr *appError.AppError
+			require.ErrorAs(t, err, &appErr)
+			require.Len(t, appErr.FieldErrors, 1)
+			assert.Equal(t, "mobile_numbers", appErr.FieldErrors[0].Field)
+			assert.Contains(t, appErr.FieldErrors[0].Message, tc.message)
+		})
+	}
+}
+
+func TestStringFieldValidator(t *testing.T) {
+	runValidatorCases(t, []validatorCase{
+		{"string_field", "Head Post Office (North)", true},
+		{"string_field", strings.Repeat("B", 50), true},
+		{"string_field", strings.Repeat("B", 51), false},
+		{"string_field", "BKG#1", false},
+		{"string_field", "", false},
+		{"string_field=64", strings.Repeat("B", 64), true},
+		{"string_field=64", strings.Repeat("B", 65), false},
+		{"string_field=64", "BKG#1", false},
+	})
+}
+
+func TestCommunicationIDValidator(t *testing.T) {
+	runValidatorCases(t, []validatorCase{
+		{"communication_id", "01ARZ3NDEKTSV4RRFFQ69G5FAV", true},
+		{"communication_id", "p4w15qumdd8o1apiyqwp", true},
+		{"communication_id", "01arz3ndektsv4rrffq69g5fav", false},
+		{"communication_id", "01ARZ3NDEKTSV4RRFFQ69G5FA", false},
+		{"communication_id", "Not Applicable", false},
+		{"communication_id", "", false},
+		{"communication_id", 12345, false},
+	})
+}
+
+func TestTransportValidators(t *testing.T) {
+	runValidatorCases(t, []validatorCase{
+		{"vehicle_registration_number", "KA01AB1234", true},
+		{"vehicle_registration_number", "22BH1234567", true},
+		{"vehicle_registration_number", "KA01AB12", false},
+		{"awb_number", "ABCD123456789", true},
+		{"awb_number", "ABC123456789", false},
+		{"pnr_no", "ABC123456", true},
+		{"pnr_no", "AB1234567", false},
+		{"flight_no", "AI 101", true},
+		{"flight_no", "AI-101", false},
+		{"train_no", "12951", true},
+		{"train_no", uint64(12951), true},
+		{"train_no", uint64(1295), false},
+		{"train_no", "1295A", false},
+		{"bag_id", "ABC1234567890", true},
+		{"bag_id", "ABCDEFGHIJKLMNO12345678901234", true},
+		{"bag_id", "AB1234567890", false},
+		{"bar_code_number", "EE123456789IN", true},
+		{"bar_code_number", "EE12345678IN", false},
+		{"order_number", "AB1234567890123456789", true},
+		{"order_number", "AB123456789012345678", false},
+		{"pos_booking_order_number", "AB1234567890123456789", true},
+		{"pos_booking_order_number", "ab1234567890123456789", false},
+		{"facility_id", "PO21101100001", true},
+		{"facility_id", "PO2110110000", false},
+		{"office_id", 21101100, true},
+		{"office_id", 2110110, false},
+	})
+}
+
+func TestPaymentValidators(t *testing.T) {
+	runValidatorCases(t, []validatorCase{
+		{"payment_trans_id", "12f47ac10b-58cc-4372-a567-0e02b2c3d479", true},
+		{"payment_trans_id", "12f47ac10b-58cc-3372-a567-0e02b2c3d479", false},
+		{"payment_trans_id", "f47ac10b-58cc-4372-a567-0e02b2c3d479", false},
+		{"bank_id", "SBIN0001234", true},
+		{"bank_id", "sbin0001234", false},
+		{"bank_user_id", "USER01", true},
+		{"bank_user_id", "USER-01", false},
+		{"gl_code", "GL12345678901", true},
+		{"gl_code", "GL1234567890", false},
+		{"head_of_account", "123456789012345", true},
+		{"head_of_account", "12345678901234", false},
+		{"account_no", "1234567890", true},
+		{"account_no", uint64(1234567890), true},
+		{"account_no", "123456789", false},
+		{"sol_id", "11000101", true},
+		{"sol_id", "1100010", false},
+		{"receiver_kyc_reference", "KYCREF00A1", true},
+		{"receiver_kyc_reference", "KYC00A1", false},
+		{"currency_code", "INR", true},
+		{"currency_code", "USD", true},
+		{"currency_code", "XYZ", false},
+		{"currency_code", "inr", false},
+		{"currency_code", "INRS", false},
+		{"currency_code", 356, false},
+		{"swift_bic", "SBININBB", true},
+		{"swift_bic", "SBININBB104", true},
+		{"swift_bic", "DEUTDEFF500", true},
+		{"swift_bic", "SBININB", false},
+		{"swift_bic", "SBININBB10", false},
+		{"swift_bic", "SBININBB1045", false},
+		{"swift_bic", "sbininbb", false},
+		{"swift_bic", "SBI1INBB", false},
+		{"swift_bic", "SBININ-B", false},
+		{"swift_bic", 12345678, false},
+	})
+}
+
+func TestCoordinateValidators(t *testing.T) {
+	runValidatorCases(t, []validatorCase{
+		{"latitude", 90.0, true},
+		{"latitude", -90.0, true},
+		{"latitude", float32(28.6139), true},
+		{"latitude", 90.000001, false},
+		{"latitude", -90.000001, false},
+		{"latitude", "-90", true},
+		{"latitude", "28.6139", true},
+		{"latitude", "90.5", false},
+		{"latitude", "north", false},
+		{"latitude", "NaN", false},
+		{"latitude", 45, true},
+		{"latitude", 91, false},
+		{"longitude", 180.0, true},
+		{"longitude", -180.0, true},
+		{"longitude", 77.2090, true},
+		{"longitude", 180.000001, false},
+		{"longitude", -180.000001, false},
+		{"longitude", "-180", true},
+		{"longitude", "180.1", false},
+		{"longitude", "", false},
+		{"longitude", true, false},
+	})
+}
+
+type facilityLocation struct {
+	Latitude  *float64 `json:"latitude" validate:"omitempty,latitude"`
+	Longitude *float64 `json:"longitude" validate:"omitempty,longitude"`
+}
+
+type officeLocation struct {
+	Latitude  string `form:"lat" validate:"omitempty,latitude"`
+	Longitude string `form:"lng" validate:"omitempty,longitude"`
+}
+
+func TestCoordinatePair(t *testing.T) {
+	require.NoError(t, Create())
+	require.NoError(t, RegisterCoordinatePair(facilityLocation{}, officeLocation{}))
+
+	lat, lng, zero := 28.6139, 77.2090, 0.0
+	tests := []struct {
+		name    string
+		value   any
+		missing string
+	}{
+		{"both given", facilityLocation{Latitude: &lat, Longitude: &lng}, ""},
+		{"neither given", facilityLocation{}, ""},
+		{"zero coordinates", facilityLocation{Latitude: &zero, Longitude: &zero}, ""},
+		{"latitude only", facilityLocation{Latitude: &lat}, "longitude"},
+		{"longitude only", facilityLocation{Longitude: &lng}, "latitude"},
+		{"strings given", officeLocation{Latitude: "28.6139", Longitude: "77.2090"}, ""},
+		{"string latitude only", officeLocation{Latitude: "28.6139"}, "lng"},
+		{"string longitude only", officeLocation{Longitude: "77.2090"}, "lat"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateStruct(tc.value)
+			if tc.missing == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var appErr *appError.AppError
+			require.ErrorAs(t, err, &appErr)
+			require.Len(t, appErr.FieldErrors, 1)
+			assert.Equal(t, tc.missing, appErr.FieldErrors[0].Field)
+			assert.Contains(t, appErr.FieldErrors[0].Message, "required when the other coordinate of the pair is given")
+		})
+	}
+}
+
+func TestCoordinatePairReportsInvalidCoordinate(t *testing.T) {
+	require.NoError(t, Create())
+	require.NoError(t, RegisterCoordinatePair(facilityLocation{}))
+
+	lat, lng := 91.0, 77.2090
+	err := ValidateStruct(facilityLocation{Latitude: &lat, Longitude: &lng})
+	var appErr *appError.AppError
+	require.ErrorAs(t, err, &appErr)
+	require.Len(t, appErr.FieldErrors, 1)
+	assert.Equal(t, "latitude", appErr.FieldErrors[0].Field)
+	assert.Contains(t, appErr.FieldErrors[0].Message, "between -90 and 90")
+}
@@ -0,0 +1,54 @@
+package validation
+
+import (
+	"reflect"
+	"strconv"
+
+	"MgApplication/core/domain"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func newPriorityValidator() validationRule {
+	return newRule("priority", validatePriority, "field %s must be one of "+domain.AllowedPriorities()+", but received %v")
+}
+
+func newGatewayIDValidator() validationRule {
+	return newRule("gateway_id", validateGatewayID, "field %s must be one of "+domain.AllowedGateways()+", but received %v")
+}
+
+func newMessageTypeValidator() validationRule {
+	return newRule("message_type", validateMessageType, "field %s must be one of "+domain.AllowedMessageTypes()+", but received %v")
+}
+
+// validatePriority accepts domain.Priority values given as a number or a numeric string
+func validatePriority(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return domain.Priority(field.Int()).Valid()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return domain.Priority(field.Uint()).Valid()
+	case reflect.String:
+		_, err := domain.ParsePriority(field.String())
+		return err == nil
+	}
+	return false
+}
+
+func validateGatewayID(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return domain.GatewayID(strconv.FormatInt(field.Int(), 10)).Valid()
+	case reflect.String:
+		return domain.GatewayID(field.String()).Valid()
+	}
+	return false
+}
+
+// validateMessageType accepts the domain.MessageType values exactly; an absent message type is
+// left to omitempty
+func validateMessageType(fl validator.FieldLevel) bool {
+	return fl.Field().Kind() == reflect.String && domain.MessageType(fl.Field().String()).Valid()
+}
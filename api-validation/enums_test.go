@@ -0,0 +1,35 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnumValidation(t *testing.T) {
+	require.NoError(t, Create())
+	type enumRequest struct {
+		Priority    int    `json:"priority" validate:"priority"`
+		Gateway     string `json:"gateway" validate:"gateway_id"`
+		MessageType string `json:"message_type" validate:"omitempty,message_type"`
+	}
+	tests := []struct {
+		req   enumRequest
+		valid bool
+	}{
+		{enumRequest{1, "1", "PM"}, true},
+		{enumRequest{4, "2", "UC"}, true},
+		{enumRequest{2, "2", ""}, true},
+		{enumRequest{0, "1", "PM"}, false},
+		{enumRequest{5, "1", "PM"}, false},
+		{enumRequest{1, "3", "PM"}, false},
+		{enumRequest{1, "", "PM"}, false},
+		{enumRequest{1, "1", "uc"}, false},
+		{enumRequest{1, "1", "XX"}, false},
+	}
+	for _, tt := range tests {
+		err := ValidateStruct(tt.req)
+		assert.Equal(t, tt.valid, err == nil, "%+v", tt.req)
+	}
+}
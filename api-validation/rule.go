@@ -16,6 +16,7 @@ type rule struct {
 	name     string
 	apply    func(fl validator.FieldLevel) bool
 	msgTempl string
+	message  func(field string, value any) string
 }
 
 func newRule(name string, apply func(validator.FieldLevel) bool, msgTempl string) validationRule {
@@ -26,6 +27,16 @@ func newRule(name string, apply func(validator.FieldLevel) bool, msgTempl string
 	}
 }
 
+// newRuleWithMessage creates a rule whose message is built by message, for messages that need
+// more than the field name and value
+func newRuleWithMessage(name string, apply func(validator.FieldLevel) bool, message func(field string, value any) string) validationRule {
+	return &rule{
+		name:    name,
+		apply:   apply,
+		message: message,
+	}
+}
+
 func (r *rule) Name() string {
 	return r.name
 }
@@ -35,5 +46,8 @@ func (r *rule) Apply(fl validator.FieldLevel) bool {
 }
 
 func (r *rule) Message(field string, value any) string {
+	if r.message != nil {
+		return r.message(field, value)
+	}
 	return fmt.Sprintf(r.msgTempl, field, value)
 }
@@ -0,0 +1,166 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	registeredStructsMu sync.Mutex
+	registeredStructs   []any
+)
+
+// tagKeywords are the parts of a validate tag that are instructions of the validator rather
+// than rules, they need no registration
+var tagKeywords = map[string]bool{
+	"-":             true,
+	"omitempty":     true,
+	"omitnil":       true,
+	"required":      true,
+	"dive":          true,
+	"keys":          true,
+	"endkeys":       true,
+	"structonly":    true,
+	"nostructlevel": true,
+}
+
+// undefinedValidationPanic starts the panic of the validator on a tag without registered rule
+const undefinedValidationPanic = "Undefined validation function"
+
+// UnknownTag is a validate tag used by a struct field whose rule is not registered.
+type UnknownTag struct {
+	Struct string
+	Field  string
+	Tag    string
+}
+
+func (u UnknownTag) String() string {
+	return fmt.Sprintf("%s.%s: %s", u.Struct, u.Field, u.Tag)
+}
+
+// UnknownTagsError lists the validate tags without registered rule.
+type UnknownTagsError struct {
+	Tags []UnknownTag
+}
+
+func (e *UnknownTagsError) Error() string {
+	tags := make([]string, len(e.Tags))
+	for i, tag := range e.Tags {
+		tags[i] = tag.String()
+	}
+	return "unregistered validate tags: " + strings.Join(tags, ", ")
+}
+
+// RegisterStructs adds struct types to the ones whose validate tags are checked by
+// CheckRegisteredStructs. Packages register their request and model structs with a value
+// of each type, usually from an init function.
+func RegisterStructs(types ...any) {
+	registeredStructsMu.Lock()
+	defer registeredStructsMu.Unlock()
+	registeredStructs = append(registeredStructs, types...)
+}
+
+// RegisteredStructs returns the struct types added with RegisterStructs.
+func RegisteredStructs() []any {
+	registeredStructsMu.Lock()
+	defer registeredStructsMu.Unlock()
+	return append([]any(nil), registeredStructs...)
+}
+
+// CheckRegisteredStructs checks the validate tags of the registered struct types, see CheckTags.
+func CheckRegisteredStructs() error {
+	return CheckTags(RegisteredStructs()...)
+}
+
+// CheckTags verifies that every validate tag of the given struct types, and of the structs
+// they nest, is a rule known to the validator. The validator only finds an unknown tag by
+// panicking at the first request touching the struct, so CheckTags is meant to run at startup.
+//
+// Returns:
+//   - error: An *UnknownTagsError naming the struct, field and tag of every unknown tag, or
+//     an error if the validator is not initialized.
+func CheckTags(types ...any) error {
+	if validate == nil {
+		return errors.New(validatorErrorMessage)
+	}
+	var unknown []UnknownTag
+	seen := map[reflect.Type]bool{}
+	for _, t := range types {
+		unknown = append(unknown, structUnknownTags(reflect.TypeOf(t), seen)...)
+	}
+	if len(unknown) > 0 {
+		return &UnknownTagsError{Tags: unknown}
+	}
+	return nil
+}
+
+// TestingT is the part of testing.TB used by AssertAllTagsRegistered.
+type TestingT interface {
+	Helper()
+	Fatalf(format string, args ...any)
+}
+
+// AssertAllTagsRegistered fails the test when a validate tag of the given struct types is not
+// registered, so packages can check their structs in their own tests:
+//
+//	validation.AssertAllTagsRegistered(t, createSMSRequest{}, createTemplateRequest{})
+func AssertAllTagsRegistered(t TestingT, types ...any) {
+	t.Helper()
+	if err := CheckTags(types...); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+}
+
+func structUnknownTags(typ reflect.Type, seen map[reflect.Type]bool) []UnknownTag {
+	for typ != nil && (typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array || typ.Kind() == reflect.Map) {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct || seen[typ] {
+		return nil
+	}
+	seen[typ] = true
+
+	var unknown []UnknownTag
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		for _, name := range tagRuleNames(field.Tag.Get("validate")) {
+			if !isRegisteredTag(name, field.Type) {
+				unknown = append(unknown, UnknownTag{Struct: typ.String(), Field: field.Name, Tag: name})
+			}
+		}
+		unknown = append(unknown, structUnknownTags(field.Type, seen)...)
+	}
+	return unknown
+}
+
+// tagRuleNames returns the rule names of a validate tag, without parameters and keywords
+func tagRuleNames(tag string) []string {
+	var names []string
+	for _, part := range strings.Split(tag, ",") {
+		for _, alternative := range strings.Split(part, "|") {
+			name, _, _ := strings.Cut(strings.TrimSpace(alternative), "=")
+			if name == "" || tagKeywords[name] {
+				continue
+			}
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// isRegisteredTag reports whether the validator knows the rule name. The validator has no
+// lookup of its rules, it panics when parsing a tag naming an unknown one; other panics, such
+// as a rule missing its parameter, mean the rule exists.
+func isRegisteredTag(name string, typ reflect.Type) (registered bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			msg, _ := r.(string)
+			registered = !strings.HasPrefix(msg, undefinedValidationPanic)
+		}
+	}()
+	_ = validate.Var(reflect.Zero(typ).Interface(), name)
+	return true
+}
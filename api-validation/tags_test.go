@@ -0,0 +1,70 @@
+package validation
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tagsTestAddress struct {
+	City string `validate:"required,city_name_bogus"`
+}
+
+type tagsTestRequest struct {
+	MobileNumber string            `validate:"required,mobile_number"`
+	Priority     int               `validate:"omitempty,priority|gateway_id"`
+	Name         string            `validate:"omitempty,max=64,string_field=64"`
+	Items        []string          `validate:"dive,oneof=a b"`
+	Kind         string            `validate:"required,kind_bogus"`
+	Addresses    []tagsTestAddress `validate:"dive"`
+}
+
+type tagsTestValidRequest struct {
+	MobileNumber string `validate:"required,mobile_number"`
+	Metadata     map[string]string
+}
+
+// recordingT records the failure of AssertAllTagsRegistered
+type recordingT struct {
+	failure string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Fatalf(format string, args ...any) {
+	r.failure = fmt.Sprintf(format, args...)
+}
+
+func TestCheckTags(t *testing.T) {
+	require.NoError(t, Create())
+
+	require.NoError(t, CheckTags(tagsTestValidRequest{}, &tagsTestValidRequest{}))
+
+	err := CheckTags(tagsTestRequest{})
+	var unknown *UnknownTagsError
+	require.ErrorAs(t, err, &unknown)
+	assert.Equal(t, []UnknownTag{
+		{Struct: "validation.tagsTestRequest", Field: "Kind", Tag: "kind_bogus"},
+		{Struct: "validation.tagsTestAddress", Field: "City", Tag: "city_name_bogus"},
+	}, unknown.Tags)
+	assert.Equal(t, "unregistered validate tags: validation.tagsTestRequest.Kind: kind_bogus, validation.tagsTestAddress.City: city_name_bogus", err.Error())
+}
+
+func TestAssertAllTagsRegistered(t *testing.T) {
+	require.NoError(t, Create())
+
+	rt := &recordingT{}
+	AssertAllTagsRegistered(rt, tagsTestValidRequest{})
+	assert.Empty(t, rt.failure)
+
+	AssertAllTagsRegistered(rt, tagsTestRequest{})
+	assert.Contains(t, rt.failure, "validation.tagsTestRequest.Kind: kind_bogus")
+}
+
+func TestTagRuleNames(t *testing.T) {
+	assert.Equal(t, []string{"max", "string_field"}, tagRuleNames("omitempty,max=64,string_field=64"))
+	assert.Equal(t, []string{"priority", "gateway_id"}, tagRuleNames("required,priority|gateway_id"))
+	assert.Empty(t, tagRuleNames("-"))
+}
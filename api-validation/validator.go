@@ -24,8 +24,8 @@ var (
 	message                  = "validation error"
 	validatorErrorMessage    = "validator not initialized"
 	translatorErrorMessage   = "translator not initialized"
-	unprocessibleEntityCode  = "422"
-	serverErrorCode          = "500"
+	unprocessibleEntityCode  = 422
+	serverErrorCode          = 500
 )
 
 var structFieldTags = []string{"json", "param", "form"}
@@ -87,6 +87,7 @@ func getDefaultRules() []validationRule {
 		newvalidatePinCodeGlobalValidator(),
 
 		newValidateMobileNumberPatternValidator(),
+		newMobileNumberListValidator(),
 		newBatchNumberPatternValidator(),
 		newPhoneNumberValidator(),
 		newTimePatternValidator(),
@@ -106,6 +107,10 @@ func getDefaultRules() []validationRule {
 		newvalidateAccountNoGlobalValidator(),
 		newIsValidTimestampGlobalValidator(),
 		newIsValidStateValidator(),
+		newCurrencyCodeValidator(),
+		newSwiftBICValidator(),
+		newLatitudeValidator(),
+		newLongitudeValidator(),
 		newvalidateCityNameValidator(),
 		newvalidateAadharValidator(),
 		newvalidateDrivingLicenseNoValidator(),
@@ -116,6 +121,10 @@ func getDefaultRules() []validationRule {
 		newvalidateYearValidator(),
 		newOptionalFieldValidator(),
 		newDateyyyymmddPatternValidatorWithddmmyyyMessage(),
+		newPriorityValidator(),
+		newGatewayIDValidator(),
+		newMessageTypeValidator(),
+		newCommunicationIDValidator(),
 	}
 }
 
@@ -215,6 +224,31 @@ func RegisterCustomValidation(tag string, fn validator.Func, message string) err
 	return nil
 }
 
+// coordinatePairTag is the tag reported when only one coordinate of a latitude/longitude pair is given
+const coordinatePairTag = "coordinate_pair"
+
+// RegisterCoordinatePair requires the latitude and longitude fields of the given struct types to be
+// given together. The fields are the ones validated with the latitude and longitude tags. A zero
+// value counts as missing, so use pointer fields where 0 is a valid coordinate.
+//
+// Parameters:
+//   - types: Values of the struct types to validate.
+//
+// Returns:
+//   - error: An error if the validator is not initialized or no type is given.
+func RegisterCoordinatePair(types ...any) error {
+	if validate == nil {
+		return errors.New(validatorErrorMessage)
+	}
+	if len(types) == 0 {
+		return errors.New("at least one struct type is required")
+	}
+
+	customValidationMessages[coordinatePairTag] = coordinatePairMessage
+	validate.RegisterStructValidation(validateCoordinatePair, types...)
+	return nil
+}
+
 // Create initializes the validator with default rules and translations.
 //
 // It uses a sync.Once to ensure that the initialization is performed only once.
@@ -280,8 +314,7 @@ func GenericStringValidation(tagSuffix string, minLength, maxLength uint, char .
 
 	pattern, err := generateDynamicStringValidationPattern(minLength, maxLength, char...)
 	if err != nil {
-		fmt.Println("Error compiling regex:", err)
-		return err
+		return fmt.Errorf("compiling the pattern of validation tag string_%s: %w", tagSuffix, err)
 	}
 
 	tag := fmt.Sprintf("string_%s", tagSuffix)
@@ -1,14 +1,24 @@
 package bootstrap
 
 import (
-	v1 "MgApplication/gen/smsrequest/v1/MgApplicationconnect"
+	"context"
+
+	// v1 "MgApplication/gen/smsrequest/v1/MgApplicationconnect" // Commented out - only used in commented AddHandlers
 	handler "MgApplication/handler"
 	repo "MgApplication/repo/postgres"
 
-	g "MgApplication/grpc-server"
+	config "MgApplication/api-config"
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+	dbmigrations "MgApplication/db/migrations"
+
+	// g "MgApplication/grpc-server" // Commented out - grpc-server not implemented yet
 
+	fxmetrics "MgApplication/api-metrics"
 	server "MgApplication/api-server"
 	serverHandler "MgApplication/api-server/handler"
+	"MgApplication/api-server/middlewares"
+	"MgApplication/api-server/sse"
 
 	"go.uber.org/fx"
 )
@@ -17,6 +27,7 @@ import (
 var Fxvalidator = fx.Module(
 	"validator",
 	fx.Invoke(handler.NewValidatorService),
+	fx.Invoke(handler.CheckValidatorTags),
 )
 
 /*
@@ -30,14 +41,63 @@ var FxRepo = fx.Module(
 	"Repomodule",
 	fx.Provide(
 		// repo.NewUserRepository,
-		// repo.NewMgApplicationRepository,
 		repo.NewApplicationRepository,
+		repo.NewMgApplicationRepository,
+		repo.NewOTPRepository,
+		repo.NewDNDRepository,
 		// repo.NewProviderRepository,
-		// repo.NewTemplateRepository,
-		// repo.NewReportsRepository,
+		repo.NewTemplateRepository,
+		repo.NewReportsRepository,
+		repo.NewGatewayCodeRepository,
+		repo.NewPrivacyRepository,
+		repo.NewErrorSampleRepository,
+		repo.NewDiagnosticsRepository,
+		repo.NewAuditRepository,
+		repo.NewConfigBundleRepository,
+		repo.NewCacheNotifyRepository,
+		newReadRouter,
 	),
+	fx.Invoke(runMigrations, routeReads),
+	fxmetrics.AsMetricsCollectors(dblib.ConsistentReadsTotal),
 )
 
+// runMigrations applies the pending migrations of db/migrations to the primary before the
+// repositories serve, when db.migrate.onstart is set, failing startup when one fails
+func runMigrations(db *dblib.DB, c *config.Config) error {
+	if !c.GetBool("db.migrate.onstart") {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.GetDuration("db.migrate.timeout"))
+	defer cancel()
+	_, version, err := dblib.Migrate(ctx, db, dbmigrations.FS, ".", c.GetString("db.migrate.table"))
+	if err != nil {
+		return err
+	}
+	log.Info(nil, "Schema at migration version %d", version)
+	return nil
+}
+
+type readRouterParams struct {
+	fx.In
+	Primary *dblib.DB
+	// Replica is provided once the read/write split is deployed, FxReadDB
+	Replica *dblib.DB `name:"read_db" optional:"true"`
+	Cfg     *config.Config
+}
+
+// newReadRouter routes the reads to the read replica, waiting up to db.consistency.maxwait for
+// the replica to replay the consistency token of a read before running it on the primary
+func newReadRouter(p readRouterParams) *dblib.ReadRouter {
+	return dblib.NewReadRouter(p.Primary, p.Replica, p.Cfg.GetDuration("db.consistency.maxwait"), p.Cfg.GetDuration("db.consistency.pollinterval"))
+}
+
+// routeReads routes the fetches of the templates and applications, the reads following their
+// creation
+func routeReads(reads *dblib.ReadRouter, templates *repo.TemplateRepository, applications *repo.ApplicationRepository) {
+	templates.Reads = reads
+	applications.Reads = reads
+}
+
 // var FxHandler = fx.Module(
 // 	"Handlermodule",
 // 	fx.Provide(
@@ -51,6 +111,28 @@ var FxRepo = fx.Module(
 // 	),
 // )
 
+// handlerRequiredConfig lists the keys the handlers cannot start without, in every environment
+var handlerRequiredConfig = RequiredConfig{
+	Module: "Handlermodule",
+	Keys: []string{
+		"sms.dltEntityID",
+		"sms.cdac.url",
+		"sms.cdac.username",
+		"sms.cdac.password",
+		"sms.cdac.securekey",
+		"sms.nic.url",
+		"sms.nic.INPOSTUserName",
+		"sms.nic.INPOSTPassword",
+		"sms.nic.DOPBNKUserName",
+		"sms.nic.DOPBNKPassword",
+		"sms.nic.DOPPLIUserName",
+		"sms.nic.DOPPLIPassword",
+		"sms.otp.templateid",
+		"admin.scope",
+		"privacy.erasure.tombstonekey",
+	},
+}
+
 // Group tag used for aggregating server handlers via Fx's group injection.
 // Fx expects tags in the form key:"value" (e.g., group:"name").
 const serverControllersGroupTag = `group:"servercontrollers"`
@@ -72,9 +154,177 @@ var FxHandler = fx.Module(
 			fx.As(new(serverHandler.Handler)),
 			fx.ResultTags(serverControllersGroupTag),
 		),
+		fx.Annotate(
+			handler.NewTemplateHandler,
+			fx.As(new(serverHandler.Handler)),
+			fx.ResultTags(serverControllersGroupTag),
+		),
+		fx.Annotate(
+			handler.NewOTPHandler,
+			fx.As(new(serverHandler.Handler)),
+			fx.ResultTags(serverControllersGroupTag),
+		),
+		fx.Annotate(
+			handler.NewAdminHandler,
+			fx.As(new(serverHandler.Handler)),
+			fx.ResultTags(serverControllersGroupTag),
+		),
+		fx.Annotate(
+			handler.NewEventsHandler,
+			fx.As(new(serverHandler.Handler)),
+			fx.ResultTags(serverControllersGroupTag),
+		),
+		fx.Annotate(
+			handler.NewCampaignHandler,
+			fx.As(new(serverHandler.Handler)),
+			fx.ResultTags(serverControllersGroupTag),
+		),
+		fx.Annotate(
+			handler.NewMetaHandler,
+			fx.As(new(serverHandler.Handler)),
+			fx.ResultTags(serverControllersGroupTag),
+		),
+		handler.NewProgressHub,
+		handler.NewCacheRegistry,
+		handler.NewDeliveryCallbackAuth,
+		handler.NewDNDChecker,
+		handler.NewDNDFilter,
+		handler.NewMgApplicationHandler,
 	),
+	fx.Invoke(handler.ConfigureResponseIDs, handler.ConfigureProblemDetails, handler.ConfigureUploadLimits, handler.CheckGatewayErrorSimulation, handler.CheckSecretKeyLength, handler.CheckMessageTransformers, handler.CheckCommunicationIDGenerator, handler.CheckGatewayRouting, handler.CheckGatewayMessageTypes),
+	requireConfig(handlerRequiredConfig),
+	requireConfig(RequiredConfig{
+		Module: "Handlermodule",
+		Keys:   []string{"sms.dnd.checkurl"},
+		Enabled: func(c *config.Config) bool {
+			return c.GetBool("sms.dnd.enabled") && c.GetString("sms.dnd.checker") == "http"
+		},
+	}),
+	fxmetrics.AsMetricsCollectors(handler.DNDSkippedTotal, handler.DNDCheckFailuresTotal, handler.GatewayCodeUndocumentedTotal, handler.ShadowSendsTotal, handler.ShadowDroppedTotal, handler.BrandingEnforcedTotal, handler.TemplateFallbackTotal, handler.OTPCacheHitsTotal, handler.CacheInvalidationsTotal, handler.GatewayLastSuccessSeconds, handler.GatewayFailedSendsSinceSuccess, handler.MessageTransformationsTotal, handler.SimulatedSendsTotal, handler.GatewayRoutedTotal, handler.SendQuotaExceededTotal, handler.SendCapThrottledTotal, handler.SendCapReservedTotal, handler.GatewayMaintenanceTotal, handler.GatewayMessageTypeUnsupportedTotal, handler.MessageTypeMismatchesTotal, handler.DeliveryWaitsTotal, sse.DroppedSubscribersTotal),
 )
 
+// FxJobs runs the background jobs
+var FxJobs = fx.Module(
+	"Jobsmodule",
+	fx.Provide(
+		handler.NewDeliveryStatusPoller,
+		handler.NewStatsRollupJob,
+		handler.NewSystemStatusMonitor,
+		handler.NewResponseBufferFlusher,
+		handler.NewErrorSampleWriter,
+		handler.NewCacheInvalidationListener,
+		handler.NewCredentialExpiryMonitor,
+	),
+	fx.Invoke(startDeliveryStatusPoller, startStatsRollupJob, startSystemStatusMonitor, startResponseBufferFlusher, startErrorSampleWriter, startCacheInvalidationListener, startCredentialExpiryMonitor),
+	requireConfig(RequiredConfig{
+		Module: "Jobsmodule",
+		Keys: []string{
+			"sms.cdac.deliverystatusurl",
+			"sms.cdac.username",
+			"sms.cdac.password",
+		},
+		Enabled: func(c *config.Config) bool {
+			return c.GetBool("sms.statuspoll.enabled")
+		},
+	}),
+	fxmetrics.AsMetricsCollectors(handler.StatusPollUpdatesTotal, handler.StatusPollFailuresTotal, handler.StatusPollFetchesTotal,
+		handler.StatusPollThroughput, handler.StatusPollBacklog, handler.StatusPollCatchUpSeconds, handler.StatusPollBatchSize, handler.StatusPollBreakerOpen,
+		handler.StatusWebhookFailuresTotal, handler.StatsRollupFailuresTotal,
+		handler.ResponseBufferEntries, handler.ResponseBufferUsage, handler.ResponseBufferFlushedTotal, handler.ResponseBufferDroppedTotal,
+		handler.ErrorSamplesWrittenTotal, handler.ErrorSamplesDroppedTotal, handler.CacheListenerReconnectsTotal,
+		handler.CredentialExpiryDaysRemaining, handler.CredentialExpiryAlertsTotal),
+)
+
+// startDeliveryStatusPoller runs the delivery status poll job for the lifetime of the app when
+// sms.statuspoll.enabled is set
+func startDeliveryStatusPoller(lc fx.Lifecycle, poller *handler.DeliveryStatusPoller, c *config.Config) {
+	if !c.GetBool("sms.statuspoll.enabled") {
+		return
+	}
+	startJob(lc, poller.Run)
+}
+
+// startStatsRollupJob runs the hourly stats rollup job for the lifetime of the app when
+// stats.rollup.mode is job
+func startStatsRollupJob(lc fx.Lifecycle, job *handler.StatsRollupJob, c *config.Config) {
+	if c.GetString("stats.rollup.mode") != repo.StatsRollupJob {
+		return
+	}
+	startJob(lc, job.Run)
+}
+
+// startSystemStatusMonitor probes the dependencies for the system status for the lifetime of the app
+func startSystemStatusMonitor(lc fx.Lifecycle, monitor *handler.SystemStatusMonitor) {
+	startJob(lc, monitor.Run)
+}
+
+// startResponseBufferFlusher stores the buffered send outcomes for the lifetime of the app when
+// sms.responsebuffer.path is set
+func startResponseBufferFlusher(lc fx.Lifecycle, flusher *handler.ResponseBufferFlusher, c *config.Config) {
+	if c.GetString("sms.responsebuffer.path") == "" {
+		return
+	}
+	startJob(lc, flusher.Run)
+}
+
+// startErrorSampleWriter samples the panics and 5xx responses into msg_error_sample for the
+// lifetime of the app when admin.errorsamples.enabled is set
+func startErrorSampleWriter(lc fx.Lifecycle, writer *handler.ErrorSampleWriter, c *config.Config) {
+	if !c.GetBool("admin.errorsamples.enabled") {
+		return
+	}
+	middlewares.SetErrorSampleSink(writer)
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			middlewares.SetErrorSampleSink(nil)
+			return nil
+		},
+	})
+	startJob(lc, writer.Run)
+}
+
+// startCacheInvalidationListener evicts the entities changed by any replica from the caches for
+// the lifetime of the app when db.notify.enabled is set
+func startCacheInvalidationListener(lc fx.Lifecycle, listener *handler.CacheInvalidationListener, c *config.Config) {
+	if !c.GetBool("db.notify.enabled") {
+		return
+	}
+	startJob(lc, listener.Run)
+}
+
+// startCredentialExpiryMonitor checks the expiry of the gateway passwords for the lifetime of the
+// app when sms.credentialexpiry.enabled is set
+func startCredentialExpiryMonitor(lc fx.Lifecycle, monitor *handler.CredentialExpiryMonitor, c *config.Config) {
+	if !c.GetBool("sms.credentialexpiry.enabled") {
+		return
+	}
+	startJob(lc, monitor.Run)
+}
+
+// startJob runs a background job from app start until app stop
+func startJob(lc fx.Lifecycle, run func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				run(ctx)
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	})
+}
+
 var FxParseController = fx.Module(
 	"ParseControllermodule",
 	fx.Provide(
@@ -82,6 +332,8 @@ var FxParseController = fx.Module(
 	),
 )
 
+// AddHandlers - Commented out until grpc-server package is implemented
+/*
 func AddHandlers(registry *g.HandlerRegistry, msgapplicationhandler *handler.MgApplicationHandlergrpc) {
 	registry.AddHandlers([]g.HandlerDefinition{
 		{
@@ -90,3 +342,4 @@ func AddHandlers(registry *g.HandlerRegistry, msgapplicationhandler *handler.MgA
 		},
 	})
 }
+*/
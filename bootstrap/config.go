@@ -0,0 +1,70 @@
+package bootstrap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	config "MgApplication/api-config"
+
+	"go.uber.org/fx"
+)
+
+// Group tag used for aggregating the required config keys of the modules
+const requiredConfigGroupTag = `group:"requiredconfig"`
+
+// RequiredConfig declares config keys a module cannot work without. Enabled, when set,
+// limits the check to configurations in which the module's feature is turned on.
+type RequiredConfig struct {
+	Module  string
+	Keys    []string
+	Enabled func(c *config.Config) bool
+}
+
+// requireConfig adds the keys of rc to the keys checked at startup
+func requireConfig(rc RequiredConfig) fx.Option {
+	return fx.Provide(
+		fx.Annotate(
+			func() RequiredConfig { return rc },
+			fx.ResultTags(requiredConfigGroupTag),
+		),
+	)
+}
+
+type requiredConfigParams struct {
+	fx.In
+	Config   *config.Config
+	Required []RequiredConfig `group:"requiredconfig"`
+}
+
+// FxRequiredConfig fails the startup when a key required by one of the enabled modules is
+// missing or empty. Include it before the other modules so it runs ahead of their invokes.
+var FxRequiredConfig = fx.Module(
+	"RequiredConfigmodule",
+	fx.Invoke(validateRequiredConfig),
+)
+
+// validateRequiredConfig returns one error listing every missing key with the modules
+// requiring it
+func validateRequiredConfig(p requiredConfigParams) error {
+	missing := map[string][]string{}
+	for _, rc := range p.Required {
+		if rc.Enabled != nil && !rc.Enabled(p.Config) {
+			continue
+		}
+		for _, key := range p.Config.MissingKeys(rc.Keys...) {
+			missing[key] = append(missing[key], rc.Module)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(missing))
+	for key, modules := range missing {
+		sort.Strings(modules)
+		keys = append(keys, fmt.Sprintf("%s (%s)", key, strings.Join(modules, ", ")))
+	}
+	sort.Strings(keys)
+	return fmt.Errorf("missing required config keys: %s", strings.Join(keys, "; "))
+}
@@ -0,0 +1,72 @@
+package bootstrap
+
+import (
+	"path/filepath"
+	"testing"
+
+	config "MgApplication/api-config"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRequiredConfigListsEveryMissingKey(t *testing.T) {
+	c := config.NewConfig(viper.New())
+	c.Set("a.present", "value")
+	c.Set("a.blank", " ")
+	c.Set("feature.enabled", false)
+
+	err := validateRequiredConfig(requiredConfigParams{
+		Config: c,
+		Required: []RequiredConfig{
+			{Module: "Handlermodule", Keys: []string{"a.present", "a.blank", "a.missing"}},
+			{Module: "Jobsmodule", Keys: []string{"a.missing"}},
+			{
+				Module:  "Featuremodule",
+				Keys:    []string{"feature.url"},
+				Enabled: func(c *config.Config) bool { return c.GetBool("feature.enabled") },
+			},
+		},
+	})
+	require.Error(t, err)
+	assert.Equal(t, "missing required config keys: a.blank (Handlermodule); a.missing (Handlermodule, Jobsmodule)", err.Error())
+
+	c.Set("a.blank", "value")
+	c.Set("a.missing", "value")
+	c.Set("feature.enabled", true)
+	err = validateRequiredConfig(requiredConfigParams{
+		Config: c,
+		Required: []RequiredConfig{
+			{Module: "Handlermodule", Keys: []string{"a.present", "a.blank", "a.missing"}},
+			{
+				Module:  "Featuremodule",
+				Keys:    []string{"feature.url"},
+				Enabled: func(c *config.Config) bool { return c.GetBool("feature.enabled") },
+			},
+		},
+	})
+	require.Error(t, err)
+	assert.Equal(t, "missing required config keys: feature.url (Featuremodule)", err.Error())
+}
+
+// Every environment loads its own config file instead of config.yaml, so each must carry
+// the keys the handlers require
+func TestEnvironmentConfigsHaveTheRequiredKeys(t *testing.T) {
+	files, err := filepath.Glob("../configs/config*.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			v := viper.New()
+			v.SetConfigFile(file)
+			require.NoError(t, v.ReadInConfig())
+
+			assert.NoError(t, validateRequiredConfig(requiredConfigParams{
+				Config:   config.NewConfig(v),
+				Required: []RequiredConfig{handlerRequiredConfig},
+			}))
+		})
+	}
+}
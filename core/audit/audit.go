@@ -0,0 +1,279 @@
+// Package audit builds the entries of the tamper-evident admin audit log: the redacted diff of a
+// change, the canonical serialization of an entry and the SHA-256 chain linking every entry to
+// the previous one, and walks the chain to find the first broken link.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"MgApplication/core/domain"
+)
+
+// RedactedValue replaces the values of the secret fields in the diffs
+const RedactedValue = "[REDACTED]"
+
+// SystemActor is the actor of the changes made outside a request of an identified caller
+const SystemActor = "system"
+
+// defaultSecretFields are the words of the field names whose values are never written to the
+// audit log, compared without case, underscores and dashes
+var defaultSecretFields = []string{"secret", "password", "passwd", "token", "apikey", "privatekey"}
+
+// Request identifies the caller of the changes audited: the subject of its verified bearer
+// token and the correlation ID of its request
+type Request struct {
+	Actor         string
+	CorrelationID string
+}
+
+type requestKey struct{}
+
+// WithRequest returns a copy of ctx carrying the caller of the changes made with it
+func WithRequest(ctx context.Context, request Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, request)
+}
+
+// RequestFrom returns the caller of ctx, SystemActor when ctx carries none
+func RequestFrom(ctx context.Context) Request {
+	request, _ := ctx.Value(requestKey{}).(Request)
+	if request.Actor == "" {
+		request.Actor = SystemActor
+	}
+	return request
+}
+
+// Change is a change to audit: the action made on an entity, with the entity as JSON before and
+// after it, null or nil for an entity created or deleted
+type Change struct {
+	Action     string
+	EntityType string
+	EntityID   string
+	Before     json.RawMessage
+	After      json.RawMessage
+}
+
+// Redactor writes the diffs of the changes, the values of the secret fields redacted
+type Redactor struct {
+	words []string
+}
+
+// NewRedactor creates a new Redactor redacting the fields whose name contains a default secret
+// word or one of extra
+func NewRedactor(extra ...string) *Redactor {
+	r := &Redactor{}
+	for _, word := range append(defaultSecretFields, extra...) {
+		if word = normalizeField(word); word != "" {
+			r.words = append(r.words, word)
+		}
+	}
+	return r
+}
+
+func normalizeField(name string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+}
+
+// IsSecret reports whether the value of the field name is redacted
+func (r *Redactor) IsSecret(name string) bool {
+	name = normalizeField(name)
+	for _, word := range r.words {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact returns v with the values of its secret fields, at any depth, redacted
+func (r *Redactor) redact(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for name, value := range v {
+			if r.IsSecret(name) {
+				redacted[name] = RedactedValue
+				continue
+			}
+			redacted[name] = r.redact(value)
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, len(v))
+		for i, value := range v {
+			redacted[i] = r.redact(value)
+		}
+		return redacted
+	}
+	return v
+}
+
+// fieldChange is the before and after values of a field in a diff
+type fieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Diff returns the fields of the entity that changed from before to after, an object of the
+// field names to their before and after values. The values of the secret fields are redacted,
+// a change of them still being recorded. A value that is not an object is diffed as a whole,
+// under the field "value".
+func (r *Redactor) Diff(before json.RawMessage, after json.RawMessage) (json.RawMessage, error) {
+	b, err := decodeFields(before)
+	if err != nil {
+		return nil, fmt.Errorf("decoding the entity before the change: %w", err)
+	}
+	a, err := decodeFields(after)
+	if err != nil {
+		return nil, fmt.Errorf("decoding the entity after the change: %w", err)
+	}
+	diff := make(map[string]fieldChange)
+	for name, value := range b {
+		if other, ok := a[name]; !ok || !reflect.DeepEqual(value, other) {
+			diff[name] = fieldChange{Before: value, After: other}
+		}
+	}
+	for name, value := range a {
+		if _, ok := b[name]; !ok {
+			diff[name] = fieldChange{After: value}
+		}
+	}
+	for name, change := range diff {
+		if r.IsSecret(name) {
+			change = fieldChange{Before: redactedOrNil(change.Before), After: redactedOrNil(change.After)}
+		} else {
+			change = fieldChange{Before: r.redact(change.Before), After: r.redact(change.After)}
+		}
+		diff[name] = change
+	}
+	return json.Marshal(diff)
+}
+
+// redactedOrNil redacts a secret value, keeping the absence of a value visible
+func redactedOrNil(v any) any {
+	if v == nil {
+		return nil
+	}
+	return RedactedValue
+}
+
+// decodeFields decodes an entity as JSON into its fields, none for an empty or null entity
+func decodeFields(entity json.RawMessage) (map[string]any, error) {
+	if len(bytes.TrimSpace(entity)) == 0 {
+		return nil, nil
+	}
+	var v any
+	if err := json.Unmarshal(entity, &v); err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		return v, nil
+	default:
+		return map[string]any{"value": v}, nil
+	}
+}
+
+// canonicalEntry is the serialization of an entry the chain hash is computed over, its fields in
+// a fixed order. The audit id is left out: the chain orders the entries itself.
+type canonicalEntry struct {
+	Actor         string          `json:"actor"`
+	Action        string          `json:"action"`
+	EntityType    string          `json:"entity_type"`
+	EntityID      string          `json:"entity_id"`
+	Diff          json.RawMessage `json:"diff"`
+	CorrelationID string          `json:"correlation_id"`
+	CreatedAt     string          `json:"created_at"`
+	PrevHash      string          `json:"prev_hash"`
+}
+
+// Canonical returns the canonical serialization of entry. The diff is re-encoded with its keys
+// sorted, so that the serialization does not depend on how the database stored it, and the
+// time is in UTC with microseconds, the precision of the database.
+func Canonical(entry domain.AuditEntry) ([]byte, error) {
+	diff := json.RawMessage("null")
+	if len(bytes.TrimSpace(entry.Diff)) > 0 {
+		var v any
+		if err := json.Unmarshal(entry.Diff, &v); err != nil {
+			return nil, fmt.Errorf("decoding the diff of audit entry %d: %w", entry.AuditID, err)
+		}
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		diff = encoded
+	}
+	return json.Marshal(canonicalEntry{
+		Actor:         entry.Actor,
+		Action:        entry.Action,
+		EntityType:    entry.EntityType,
+		EntityID:      entry.EntityID,
+		Diff:          diff,
+		CorrelationID: entry.CorrelationID,
+		CreatedAt:     entry.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+		PrevHash:      entry.PrevHash,
+	})
+}
+
+// ChainHash returns the hash of entry, the hex SHA-256 of the hash of the previous entry,
+// entry.PrevHash, followed by the canonical serialization of entry
+func ChainHash(entry domain.AuditEntry) (string, error) {
+	canonical, err := Canonical(entry)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.New()
+	sum.Write([]byte(entry.PrevHash))
+	sum.Write(canonical)
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// BrokenLinkError is the first entry of the audit chain that does not chain to the previous one
+type BrokenLinkError struct {
+	AuditID int64
+	Reason  string
+}
+
+func (e *BrokenLinkError) Error() string {
+	return fmt.Sprintf("audit chain broken at entry %d: %s", e.AuditID, e.Reason)
+}
+
+// Verifier walks the audit chain from its first entry, in the order of the chain
+type Verifier struct {
+	prevHash string
+	checked  int64
+}
+
+// Verify checks that entry chains to the entry verified before it, returning a
+// *BrokenLinkError when it does not: when its previous hash is not the hash of that entry, as
+// when an entry was removed, or when its hash is not the hash of its content, as when it was
+// edited.
+func (v *Verifier) Verify(entry domain.AuditEntry) error {
+	if entry.PrevHash != v.prevHash {
+		return &BrokenLinkError{AuditID: entry.AuditID, Reason: "prev_hash is not the hash of the previous entry"}
+	}
+	hash, err := ChainHash(entry)
+	if err != nil {
+		return &BrokenLinkError{AuditID: entry.AuditID, Reason: err.Error()}
+	}
+	if hash != entry.Hash {
+		return &BrokenLinkError{AuditID: entry.AuditID, Reason: "hash does not match the content of the entry"}
+	}
+	v.prevHash = entry.Hash
+	v.checked++
+	return nil
+}
+
+// Checked returns the number of entries verified
+func (v *Verifier) Checked() int64 {
+	return v.checked
+}
@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"MgApplication/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffRedactsSecretFields(t *testing.T) {
+	r := NewRedactor("msisdn")
+	before := json.RawMessage(`{"application_id":4,"application_name":"otp","secret_key":"s3cr3t","status":true,"owner":{"api_token":"t1","name":"ops"}}`)
+	after := json.RawMessage(`{"application_id":4,"application_name":"otp-v2","secret_key":"n3w","status":true,"owner":{"api_token":"t2","name":"ops"},"msisdn":"9999999999"}`)
+
+	diff, err := r.Diff(before, after)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"application_name": {"before": "otp", "after": "otp-v2"},
+		"secret_key": {"before": "[REDACTED]", "after": "[REDACTED]"},
+		"owner": {"before": {"api_token": "[REDACTED]", "name": "ops"}, "after": {"api_token": "[REDACTED]", "name": "ops"}},
+		"msisdn": {"before": null, "after": "[REDACTED]"}
+	}`, string(diff))
+	assert.NotContains(t, string(diff), "s3cr3t")
+	assert.NotContains(t, string(diff), "n3w")
+
+	// an entity created has no before
+	diff, err = r.Diff(nil, json.RawMessage(`{"Password":"p","name":"x"}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Password":{"before":null,"after":"[REDACTED]"},"name":{"before":null,"after":"x"}}`, string(diff))
+
+	_, err = r.Diff(json.RawMessage(`{`), nil)
+	assert.Error(t, err)
+}
+
+func TestIsSecret(t *testing.T) {
+	r := NewRedactor()
+	for _, name := range []string{"secret_key", "SecretKey", "password", "access-token", "api_key", "private_key"} {
+		assert.True(t, r.IsSecret(name), name)
+	}
+	for _, name := range []string{"application_name", "template_id", "status"} {
+		assert.False(t, r.IsSecret(name), name)
+	}
+}
+
+func TestRequestFrom(t *testing.T) {
+	assert.Equal(t, Request{Actor: SystemActor}, RequestFrom(context.Background()))
+	ctx := WithRequest(context.Background(), Request{Actor: "ops-1", CorrelationID: "c-1"})
+	assert.Equal(t, Request{Actor: "ops-1", CorrelationID: "c-1"}, RequestFrom(ctx))
+}
+
+// chain returns n entries chained from the genesis entry
+func chain(t *testing.T, n int) []domain.AuditEntry {
+	t.Helper()
+	entries := make([]domain.AuditEntry, 0, n)
+	prevHash := ""
+	for i := 0; i < n; i++ {
+		entry := domain.AuditEntry{
+			AuditID:       int64(i + 1),
+			Actor:         "ops-1",
+			Action:        domain.AuditActionUpdate,
+			EntityType:    domain.AuditEntityTemplate,
+			EntityID:      "42",
+			Diff:          json.RawMessage(`{"status":{"before":true,"after":false}}`),
+			CorrelationID: "c-1",
+			CreatedAt:     time.Date(2024, 9, 15, 10, 0, i, 123456789, time.UTC),
+			PrevHash:      prevHash,
+		}
+		hash, err := ChainHash(entry)
+		require.NoError(t, err)
+		entry.Hash = hash
+		prevHash = hash
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// verify walks entries, returning the number verified and the first broken link
+func verify(entries []domain.AuditEntry) (int64, error) {
+	v := &Verifier{}
+	for _, entry := range entries {
+		if err := v.Verify(entry); err != nil {
+			return v.Checked(), err
+		}
+	}
+	return v.Checked(), nil
+}
+
+func TestVerifyChain(t *testing.T) {
+	entries := chain(t, 4)
+	checked, err := verify(entries)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, checked)
+
+	// the hash does not depend on the key order of the stored diff, nor on the time zone
+	entry := entries[1]
+	entry.Diff = json.RawMessage(`{ "status": { "after": false, "before": true } }`)
+	entry.CreatedAt = entry.CreatedAt.In(time.FixedZone("IST", 19800)).Truncate(time.Microsecond)
+	hash, err := ChainHash(entry)
+	require.NoError(t, err)
+	assert.Equal(t, entries[1].Hash, hash)
+}
+
+func TestVerifyDetectsCorruptedEntry(t *testing.T) {
+	entries := chain(t, 4)
+	entries[2].Diff = json.RawMessage(`{"status":{"before":true,"after":true}}`)
+
+	checked, err := verify(entries)
+	var broken *BrokenLinkError
+	require.True(t, errors.As(err, &broken), err)
+	assert.EqualValues(t, 3, broken.AuditID)
+	assert.Contains(t, broken.Reason, "hash does not match")
+	assert.EqualValues(t, 2, checked)
+
+	// rehashing the edited entry breaks the link of the next one
+	entries[2].Hash, err = ChainHash(entries[2])
+	require.NoError(t, err)
+	_, err = verify(entries)
+	require.True(t, errors.As(err, &broken), err)
+	assert.EqualValues(t, 4, broken.AuditID)
+	assert.Contains(t, broken.Reason, "prev_hash")
+}
+
+func TestVerifyDetectsRemovedEntry(t *testing.T) {
+	entries := chain(t, 3)
+	_, err := verify(append(entries[:1:1], entries[2]))
+	var broken *BrokenLinkError
+	require.True(t, errors.As(err, &broken), err)
+	assert.EqualValues(t, 3, broken.AuditID)
+}
@@ -0,0 +1,51 @@
+// Package clock is the single place the gateway reads the time and computes business days.
+// Instants are kept in UTC; the business day of India Post runs from midnight to midnight in
+// Asia/Kolkata, which is 18:30 UTC the day before.
+package clock
+
+import "time"
+
+// ZoneName is the IANA name of the business time zone, for time zone conversions in SQL
+const ZoneName = "Asia/Kolkata"
+
+// IST is the business time zone. India has no daylight saving time, so a fixed zone matches
+// Asia/Kolkata without depending on the tz database of the host.
+var IST = time.FixedZone("IST", 5*60*60+30*60)
+
+// Now returns the current time in UTC.
+func Now() time.Time {
+	return time.Now().UTC()
+}
+
+// InIST returns t in the business time zone.
+func InIST(t time.Time) time.Time {
+	return t.In(IST)
+}
+
+// BusinessDay returns the start of the business day t falls on, as a UTC instant.
+func BusinessDay(t time.Time) time.Time {
+	ist := t.In(IST)
+	return time.Date(ist.Year(), ist.Month(), ist.Day(), 0, 0, 0, 0, IST).UTC()
+}
+
+// NextBusinessDay returns the start of the business day after the one t falls on.
+func NextBusinessDay(t time.Time) time.Time {
+	ist := t.In(IST)
+	return time.Date(ist.Year(), ist.Month(), ist.Day()+1, 0, 0, 0, 0, IST).UTC()
+}
+
+// ParseDate parses a calendar date, such as the "02-01-2006" dates of the report requests, as
+// the start of that business day.
+func ParseDate(layout string, value string) (time.Time, error) {
+	t, err := time.ParseInLocation(layout, value, IST)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return BusinessDay(t), nil
+}
+
+// DayRange returns the instants bounding the business days from the one of fromDate to the one
+// of toDate, both included: the start of the first day and the start of the day after the last.
+func DayRange(fromDate time.Time, toDate time.Time) (start time.Time, end time.Time) {
+	return BusinessDay(fromDate), NextBusinessDay(toDate)
+}
@@ -0,0 +1,49 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBusinessDayBoundary(t *testing.T) {
+	// 18:30 UTC is midnight in Asia/Kolkata
+	before := time.Date(2024, 8, 26, 18, 29, 59, 0, time.UTC)
+	at := time.Date(2024, 8, 26, 18, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2024, 8, 25, 18, 30, 0, 0, time.UTC), BusinessDay(before))
+	assert.Equal(t, at, BusinessDay(at))
+	assert.Equal(t, at, NextBusinessDay(before))
+	assert.Equal(t, time.Date(2024, 8, 27, 18, 30, 0, 0, time.UTC), NextBusinessDay(at))
+	assert.Equal(t, 26, InIST(before).Day())
+	assert.Equal(t, 27, InIST(at).Day())
+}
+
+func TestParseDate(t *testing.T) {
+	day, err := ParseDate("02-01-2006", "27-08-2024")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 8, 26, 18, 30, 0, 0, time.UTC), day)
+	assert.Equal(t, time.UTC, day.Location())
+
+	_, err = ParseDate("02-01-2006", "2024-08-27")
+	assert.Error(t, err)
+}
+
+func TestDayRange(t *testing.T) {
+	from, _ := ParseDate("02-01-2006", "01-08-2024")
+	to, _ := ParseDate("02-01-2006", "31-08-2024")
+	start, end := DayRange(from, to)
+	assert.Equal(t, time.Date(2024, 7, 31, 18, 30, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 8, 31, 18, 30, 0, 0, time.UTC), end)
+
+	// the UTC midnight of a calendar date falls on the same business day
+	start, end = DayRange(time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 8, 31, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 7, 31, 18, 30, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 8, 31, 18, 30, 0, 0, time.UTC), end)
+}
+
+func TestNowIsUTC(t *testing.T) {
+	assert.Equal(t, time.UTC, Now().Location())
+}
@@ -0,0 +1,114 @@
+// Package dlt checks message templates against the rules of the DLT (Distributed Ledger
+// Technology) platform that registers them, so that templates the platform would reject are
+// caught when they are created.
+package dlt
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rules of the checks, the Rule of a Violation
+const (
+	RuleVarCount   = "var_count"
+	RuleVarContext = "var_context"
+	RuleLength     = "length"
+	RuleBranding   = "branding"
+)
+
+// varPattern matches the {#var#} placeholders that are filled when a message is sent
+var varPattern = regexp.MustCompile(`\{#var#\}`)
+
+// Rules are the limits a template format is checked against. Zero values disable a check.
+type Rules struct {
+	MaxVars   int    // most {#var#} placeholders in a template
+	VarLength int    // most characters of a variable value
+	MaxLength int    // most characters of a message with every variable VarLength characters long
+	Branding  string // the template must end with it, e.g. "- INDPOST"
+
+	// AppendBranding is set when the branding is appended to messages missing it, MaxLength
+	// then counts it
+	AppendBranding bool
+}
+
+// Violation is a rule a template format breaks
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Lint checks format against rules and returns the violations, nil when there are none.
+// Placeholders need fixed text between them, the platform cannot tell adjacent variables apart.
+func Lint(format string, rules Rules) []Violation {
+	var violations []Violation
+	vars := varPattern.FindAllStringIndex(format, -1)
+
+	if rules.MaxVars > 0 && len(vars) > rules.MaxVars {
+		violations = append(violations, Violation{
+			Rule:    RuleVarCount,
+			Message: fmt.Sprintf("template has %d {#var#} placeholders, at most %d are allowed", len(vars), rules.MaxVars),
+		})
+	}
+
+	if len(vars) > 0 && !hasFixedText(varPattern.ReplaceAllString(format, "")) {
+		violations = append(violations, Violation{
+			Rule:    RuleVarContext,
+			Message: "template has no fixed text around its {#var#} placeholders",
+		})
+	} else {
+		for i := 1; i < len(vars); i++ {
+			if !hasFixedText(format[vars[i-1][1]:vars[i][0]]) {
+				violations = append(violations, Violation{
+					Rule:    RuleVarContext,
+					Message: fmt.Sprintf("{#var#} placeholders %d and %d are not separated by fixed text", i, i+1),
+				})
+			}
+		}
+	}
+
+	if rules.MaxLength > 0 {
+		if length := MaxLength(format, rules); length > rules.MaxLength {
+			violations = append(violations, Violation{
+				Rule: RuleLength,
+				Message: fmt.Sprintf("template is %d characters long with every variable %d characters long, at most %d are allowed",
+					length, rules.VarLength, rules.MaxLength),
+			})
+		}
+	}
+
+	if rules.Branding != "" && !EndsWithBranding(format, rules.Branding) {
+		violations = append(violations, Violation{
+			Rule:    RuleBranding,
+			Message: fmt.Sprintf("template must end with the branding %q", rules.Branding),
+		})
+	}
+	return violations
+}
+
+// MaxLength estimates the length of the longest message of format: its fixed text, every
+// variable rules.VarLength characters long and the branding when it gets appended
+func MaxLength(format string, rules Rules) int {
+	if rules.AppendBranding && rules.Branding != "" && !EndsWithBranding(format, rules.Branding) {
+		format = strings.TrimRight(format, " \t\r\n") + " " + rules.Branding
+	}
+	vars := len(varPattern.FindAllStringIndex(format, -1))
+	return utf8.RuneCountInString(format) - vars*utf8.RuneCountInString("{#var#}") + vars*rules.VarLength
+}
+
+// EndsWithBranding reports whether text ends with branding, ignoring case, trailing whitespace
+// and a trailing full stop
+func EndsWithBranding(text string, branding string) bool {
+	text = strings.TrimRight(text, " \t\r\n.")
+	return len(text) >= len(branding) && strings.EqualFold(text[len(text)-len(branding):], branding)
+}
+
+// hasFixedText reports whether s has a letter or digit, whitespace and punctuation alone give a
+// variable no context
+func hasFixedText(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}) >= 0
+}
@@ -0,0 +1,72 @@
+package dlt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func rulesOf(violations []Violation) []string {
+	var rules []string
+	for _, v := range violations {
+		rules = append(rules, v.Rule)
+	}
+	return rules
+}
+
+func TestLint(t *testing.T) {
+	rules := Rules{MaxVars: 3, VarLength: 10, MaxLength: 80, Branding: "- INDPOST"}
+	cases := []struct {
+		name   string
+		format string
+		rules  Rules
+		want   []string
+	}{
+		{"valid", "Your OTP is {#var#} for booking {#var#} - INDPOST", rules, nil},
+		{"no placeholders", "Your article is delivered - INDPOST", rules, nil},
+		{"too many placeholders", "A {#var#} B {#var#} C {#var#} D {#var#} - INDPOST", rules, []string{RuleVarCount}},
+		{"adjacent placeholders", "Your OTP is {#var#}{#var#} - INDPOST", rules, []string{RuleVarContext}},
+		{"placeholders separated by punctuation", "Your OTP is {#var#}, {#var#} - INDPOST", rules, []string{RuleVarContext}},
+		{"only placeholders", "{#var#} {#var#}", Rules{}, []string{RuleVarContext}},
+		{"too long", "Your parcel {#var#} is out for delivery from the office {#var#} today - INDPOST", rules, []string{RuleLength}},
+		{"missing branding", "Your OTP is {#var#}", rules, []string{RuleBranding}},
+		{"branding ignoring case and full stop", "Your OTP is {#var#} - IndPost. ", rules, nil},
+		{"several violations", "{#var#}{#var#}{#var#}{#var#}", rules, []string{RuleVarCount, RuleVarContext, RuleBranding}},
+		{"zero rules disable the limits", "Your OTP is {#var#} for {#var#} and {#var#} or {#var#}", Rules{}, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, rulesOf(Lint(tc.format, tc.rules)))
+		})
+	}
+}
+
+func TestLintLengthCountsVariables(t *testing.T) {
+	// 13 fixed characters and 2 variables of 10
+	format := "OTP {#var#} ref {#var#} ok."
+	assert.Empty(t, Lint(format, Rules{VarLength: 10, MaxLength: 33}))
+	violations := Lint(format, Rules{VarLength: 10, MaxLength: 32})
+	assert.Equal(t, []string{RuleLength}, rulesOf(violations))
+	assert.Contains(t, violations[0].Message, "33 characters")
+}
+
+func TestMaxLength(t *testing.T) {
+	rules := Rules{VarLength: 10, Branding: "- INDPOST"}
+	// 13 fixed characters and 2 variables of 10
+	assert.Equal(t, 33, MaxLength("OTP {#var#} ref {#var#} ok.", rules))
+
+	// an appended branding counts with the space before it
+	rules.AppendBranding = true
+	assert.Equal(t, 43, MaxLength("OTP {#var#} ref {#var#} ok.", rules))
+	assert.Equal(t, 43, MaxLength("OTP {#var#} ref {#var#} ok. \n", rules))
+	assert.Equal(t, 39, MaxLength("OTP {#var#} ref {#var#} - INDPOST", rules), "the branding is not appended twice")
+
+	assert.Equal(t, []string{RuleLength, RuleBranding}, rulesOf(Lint("OTP {#var#} ref {#var#} ok.", Rules{VarLength: 10, MaxLength: 40, Branding: "- INDPOST", AppendBranding: true})))
+}
+
+func TestEndsWithBranding(t *testing.T) {
+	assert.True(t, EndsWithBranding("Delivered - INDPOST", "- INDPOST"))
+	assert.True(t, EndsWithBranding("Delivered - indpost.\n", "- INDPOST"))
+	assert.False(t, EndsWithBranding("Delivered - INDPOST Bank", "- INDPOST"))
+	assert.False(t, EndsWithBranding("POST", "- INDPOST"))
+}
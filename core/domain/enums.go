@@ -0,0 +1,170 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The enums below are stored and sent to the gateways by value, so the structs read from and
+// written to the database keep plain int and string fields and convert at the comparison,
+// e.g. Priority(msgreq.Priority).Immediate(). String returns the name for logs.
+
+// Priority is the priority of a message request, which decides how it is dispatched
+type Priority int
+
+const (
+	PriorityOTP           Priority = 1
+	PriorityTransactional Priority = 2
+	PriorityPromotional   Priority = 3
+	PriorityBulk          Priority = 4
+)
+
+// Priorities lists the valid priorities
+var Priorities = []Priority{PriorityOTP, PriorityTransactional, PriorityPromotional, PriorityBulk}
+
+var priorityNames = map[Priority]string{
+	PriorityOTP:           "OTP",
+	PriorityTransactional: "transactional",
+	PriorityPromotional:   "promotional",
+	PriorityBulk:          "bulk",
+}
+
+func (p Priority) String() string {
+	if name, ok := priorityNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
+}
+
+// Valid reports whether p is one of Priorities
+func (p Priority) Valid() bool {
+	_, ok := priorityNames[p]
+	return ok
+}
+
+// Immediate reports whether messages of priority p are sent to the gateway within the request.
+// Promotional and bulk messages are queued in Kafka instead.
+func (p Priority) Immediate() bool {
+	return p == PriorityOTP || p == PriorityTransactional
+}
+
+// Promotional reports whether p is the promotional or bulk priority. These messages are queued,
+// always stored in msg_request and not sent to numbers registered as DND.
+func (p Priority) Promotional() bool {
+	return p == PriorityPromotional || p == PriorityBulk
+}
+
+// ParsePriority parses a priority given by its number, e.g. "1"
+func ParsePriority(s string) (Priority, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || !Priority(n).Valid() {
+		return 0, fmt.Errorf("invalid priority %q, must be one of %s", s, AllowedPriorities())
+	}
+	return Priority(n), nil
+}
+
+// AllowedPriorities describes the valid priorities for error messages, e.g. "1 (OTP), 2 (transactional), ..."
+func AllowedPriorities() string {
+	allowed := make([]string, len(Priorities))
+	for i, p := range Priorities {
+		allowed[i] = fmt.Sprintf("%d (%s)", int(p), p)
+	}
+	return strings.Join(allowed, ", ")
+}
+
+// GatewayID identifies the SMS gateway a message is sent through, the msg_request.gateway and
+// msg_template.gateway value
+type GatewayID string
+
+const (
+	GatewayCDAC GatewayID = "1"
+	GatewayNIC  GatewayID = "2"
+)
+
+// Gateways lists the valid gateways
+var Gateways = []GatewayID{GatewayCDAC, GatewayNIC}
+
+var gatewayNames = map[GatewayID]string{
+	GatewayCDAC: "CDAC",
+	GatewayNIC:  "NIC",
+}
+
+func (g GatewayID) String() string {
+	if name, ok := gatewayNames[g]; ok {
+		return name
+	}
+	return fmt.Sprintf("GatewayID(%q)", string(g))
+}
+
+// Valid reports whether g is one of Gateways
+func (g GatewayID) Valid() bool {
+	_, ok := gatewayNames[g]
+	return ok
+}
+
+// ParseGatewayID parses a gateway given by its id, e.g. "1"
+func ParseGatewayID(s string) (GatewayID, error) {
+	g := GatewayID(strings.TrimSpace(s))
+	if !g.Valid() {
+		return "", fmt.Errorf("invalid gateway %q, must be one of %s", s, AllowedGateways())
+	}
+	return g, nil
+}
+
+// AllowedGateways describes the valid gateways for error messages, e.g. "1 (CDAC), 2 (NIC)"
+func AllowedGateways() string {
+	allowed := make([]string, len(Gateways))
+	for i, g := range Gateways {
+		allowed[i] = fmt.Sprintf("%s (%s)", string(g), g)
+	}
+	return strings.Join(allowed, ", ")
+}
+
+// MessageType is the encoding of a message text, plain or Unicode
+type MessageType string
+
+const (
+	MessageTypePlain   MessageType = "PM"
+	MessageTypeUnicode MessageType = "UC"
+)
+
+// MessageTypes lists the valid message types
+var MessageTypes = []MessageType{MessageTypePlain, MessageTypeUnicode}
+
+func (mt MessageType) String() string {
+	return string(mt)
+}
+
+// Valid reports whether mt is one of MessageTypes
+func (mt MessageType) Valid() bool {
+	return mt == MessageTypePlain || mt == MessageTypeUnicode
+}
+
+// IsUnicode reports whether the message text has to be converted for the gateway. Requests
+// without a message type are sent as plain messages.
+func (mt MessageType) IsUnicode() bool {
+	return mt == MessageTypeUnicode
+}
+
+// ParseMessageType parses a message type, e.g. "UC". An empty message type is a plain message.
+func ParseMessageType(s string) (MessageType, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return MessageTypePlain, nil
+	}
+	mt := MessageType(strings.ToUpper(s))
+	if !mt.Valid() {
+		return "", fmt.Errorf("invalid message type %q, must be one of %s", s, AllowedMessageTypes())
+	}
+	return mt, nil
+}
+
+// AllowedMessageTypes describes the valid message types for error messages, e.g. "PM, UC"
+func AllowedMessageTypes() string {
+	allowed := make([]string, len(MessageTypes))
+	for i, mt := range MessageTypes {
+		allowed[i] = string(mt)
+	}
+	return strings.Join(allowed, ", ")
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEnums(t *testing.T) {
+	p, err := ParsePriority("2")
+	require.NoError(t, err)
+	assert.Equal(t, PriorityTransactional, p)
+	assert.Equal(t, "transactional", p.String())
+	_, err = ParsePriority("5")
+	assert.ErrorContains(t, err, "1 (OTP), 2 (transactional), 3 (promotional), 4 (bulk)")
+
+	g, err := ParseGatewayID("2")
+	require.NoError(t, err)
+	assert.Equal(t, GatewayNIC, g)
+	assert.Equal(t, "NIC", g.String())
+	_, err = ParseGatewayID("3")
+	assert.ErrorContains(t, err, "1 (CDAC), 2 (NIC)")
+
+	mt, err := ParseMessageType("uc")
+	require.NoError(t, err)
+	assert.True(t, mt.IsUnicode())
+	mt, err = ParseMessageType("")
+	require.NoError(t, err)
+	assert.Equal(t, MessageTypePlain, mt)
+	_, err = ParseMessageType("XX")
+	assert.ErrorContains(t, err, "PM, UC")
+
+	assert.True(t, PriorityOTP.Immediate() && !PriorityOTP.Promotional())
+	assert.True(t, PriorityBulk.Promotional() && !PriorityBulk.Immediate())
+}
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"encoding/json"
+	"strings"
 	"time"
 )
 
@@ -44,6 +45,26 @@ type MaintainTemplate struct {
 	TotalCount      uint64
 }
 
+// TemplateFilter selects the listed templates, its zero value selecting them all. OrderBy is a
+// column of the list, the templates being ordered by their local id otherwise.
+type TemplateFilter struct {
+	ApplicationID string
+	Gateway       string
+	Status        *int
+	MessageType   string
+	SenderID      string
+	// Search is a substring of the name or format, case insensitive
+	Search     string
+	OrderBy    string
+	Descending bool
+}
+
+// TemplateFacets counts the templates matching a TemplateFilter by status and by gateway id
+type TemplateFacets struct {
+	Status  map[string]uint64
+	Gateway map[string]uint64
+}
+
 type InitiateBulkSMS struct {
 	File          uint64 `json:"file_id"`
 	ReferenceID   string `json:"reference_id" db:"reference_id"`
@@ -60,6 +81,9 @@ type InitiateBulkSMS struct {
 type GetTemplatebyAPPID struct {
 	TemplateLocalID uint64 `json:"template_local_id" db:"template_local_id"`
 	TemplateName    string `json:"template_name" db:"template_name"`
+	TemplateID      string `json:"template_id" db:"template_id"`
+	Gateway         string `json:"gateway" db:"gateway"`
+	MessageType     string `json:"message_type" db:"message_type"`
 }
 type GetTemplateformatbyID struct {
 	TemplateLocalID uint64 `json:"template_local_id" db:"template_local_id"`
@@ -112,14 +136,38 @@ type MsgRequest struct {
 	CommunicationID string `json:"communication_id" db:"communication_id"`
 	Gateway         string `json:"gateway" db:"gateway"`
 	MessageType     string `json:"message_type" db:"message_type"`
+	// ClientReference and Metadata are the caller's own references. They are stored with the
+	// request and never sent to a gateway.
+	ClientReference string            `json:"-" db:"client_reference"`
+	Metadata        map[string]string `json:"-" db:"metadata"`
+	// CredentialSet names the tenant credentials the request is sent with instead of the
+	// global gateway credentials. It is neither stored nor queued.
+	CredentialSet string `json:"-" db:"-"`
+	// Transformations are the message transformers that changed the text before it was sent,
+	// in order, stored with the request
+	Transformations []string `json:"-" db:"transformations"`
+	// DoNotStore sends the request without storing it nor the gateway response, only an audit
+	// entry without the text and the mobile numbers being written. It is never queued.
+	DoNotStore bool `json:"-" db:"-"`
+	// RouteGateway is the gateway the time of day routing sends the request through, instead of
+	// the gateway of its template when set
+	RouteGateway string `json:"-" db:"-"`
+	// Warnings tell the caller how the request was changed before it was sent, e.g. its
+	// message type corrected, returned in the response
+	Warnings []string `json:"-" db:"-"`
+	// WaitForDelivery is how long an OTP request waits for the delivery of its message once
+	// the gateway accepted it, 0 answering at once
+	WaitForDelivery time.Duration `json:"-" db:"-"`
 }
 
 type MsgResponse struct {
-	CommunicationID  string `json:"communication_id"`
-	CompleteResponse string `json:"complete_response"`
-	ReferenceID      string `jsong:"reference_id"`
-	ResponseCode     string `json:"status"`
-	ResponseText     string `json:"response_text"`
+	CommunicationID  string `json:"communication_id" db:"communication_id"`
+	CompleteResponse string `json:"complete_response" db:"complete_response"`
+	ReferenceID      string `jsong:"reference_id" db:"reference_id"`
+	ResponseCode     string `json:"status" db:"response_code"`
+	ResponseText     string `json:"response_text" db:"response_message"`
+	// CreatedAt is when the response was stored, set when it is read back
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
 type CDACSMSDeliveryStatusRequest struct {
@@ -150,6 +198,45 @@ type StatusProvider struct {
 }
 type StatusTemplate struct {
 	TemplateLocalID uint64 `json:"template_local_id"`
+	// CorrelationID is the request recorded with the change in the template history
+	CorrelationID string `json:"-"`
+}
+
+// Template history actions, the changes of template status recorded in msg_template_history
+const (
+	TemplateActionToggleStatus = "toggle_status"
+	TemplateActionBulkStatus   = "bulk_status"
+)
+
+// BulkTemplateStatus sets the status of the templates listed in TemplateLocalIDs or, when none
+// are listed, of the templates matching Filter. A filter changing more than ConfirmAbove
+// templates is applied only with Confirm.
+type BulkTemplateStatus struct {
+	TemplateLocalIDs []uint64
+	Filter           TemplateFilter
+	Status           int
+	Confirm          bool
+	ConfirmAbove     int64
+	CorrelationID    string
+}
+
+// Results of a template of a bulk status update
+const (
+	TemplateStatusUpdated   = "updated"
+	TemplateStatusNotFound  = "not_found"
+	TemplateStatusUnchanged = "already_in_state"
+)
+
+// BulkTemplateStatusResult counts the templates updated by a BulkTemplateStatus, with the result
+// of each template listed
+type BulkTemplateStatusResult struct {
+	Updated int64
+	Results []TemplateStatusResult
+}
+
+type TemplateStatusResult struct {
+	TemplateLocalID uint64
+	Result          string
 }
 
 type ValidateTestSMS struct {
@@ -171,16 +258,22 @@ type ValidateTestSMS struct {
 // }
 
 type SMSReport struct {
-	SerialNo        uint64    `json:"serial_no" db:"serial_number"`
-	CreatedDate     time.Time `json:"created_date" db:"created_date"`
-	CommunicationID *string    `json:"comm_id" db:"communication_id"`
-	ApplicationID   *string    `json:"application_id" db:"application_id"`
-	FacilityID      *string    `json:"facility_id" db:"facility_id"`
-	MessagePriority *int64     `json:"message_priority" db:"priority"`
-	MessageText     *string    `json:"message_text" db:"message_text"`
-	MobileNumber    *int64     `json:"mobile_number" db:"mobile_number"`
-	GatewayID       *string    `json:"gateway_id" db:"gateway"`
-	Status          string    `json:"status" db:"status"`
+	SerialNo            uint64            `json:"serial_no" db:"serial_number"`
+	CreatedDate         time.Time         `json:"created_date" db:"created_date"`
+	CommunicationID     *string           `json:"comm_id" db:"communication_id"`
+	ApplicationID       *string           `json:"application_id" db:"application_id"`
+	FacilityID          *string           `json:"facility_id" db:"facility_id"`
+	MessagePriority     *int64            `json:"message_priority" db:"priority"`
+	MessageText         *string           `json:"message_text" db:"message_text"`
+	MobileNumber        *int64            `json:"mobile_number" db:"mobile_number"`
+	GatewayID           *string           `json:"gateway_id" db:"gateway"`
+	Status              string            `json:"status" db:"status"`
+	ResponseCode        *string           `json:"response_code" db:"response_code"`
+	ResponseDescription *string           `json:"response_description" db:"response_description"`
+	ResponseSeverity    *string           `json:"response_severity" db:"response_severity"`
+	RecommendedAction   *string           `json:"recommended_action" db:"recommended_action"`
+	ClientReference     *string           `json:"client_reference" db:"client_reference"`
+	Metadata            map[string]string `json:"metadata" db:"metadata"`
 }
 
 type SMSAggregateReport struct {
@@ -231,6 +324,162 @@ type NicResponseXml struct {
 	Info      string `xml:"info"`
 }
 
+// RecipientStatus is the outcome of a message for one recipient
+type RecipientStatus struct {
+	MobileNumber string
+	Status       string
+}
+
+// PendingDeliveryStatus is a message accepted by its gateway whose delivery status is not yet known
+type PendingDeliveryStatus struct {
+	RequestID       uint64 `db:"request_id"`
+	ApplicationID   string `db:"application_id"`
+	CommunicationID string `db:"communication_id"`
+	ReferenceID     string `db:"reference_id"`
+	Gateway         string `db:"gateway"`
+}
+
+// WebhookOutboxEvent is a delivery status webhook waiting in msg_webhook_outbox to be posted
+type WebhookOutboxEvent struct {
+	OutboxID        uint64 `db:"outbox_id"`
+	CommunicationID string `db:"communication_id"`
+	Payload         []byte `db:"payload"`
+	Attempts        int    `db:"attempts"`
+}
+
+// DeliveryProgress counts the messages of an application or campaign by delivery outcome
+type DeliveryProgress struct {
+	Sent      int64 `json:"sent" db:"sent"`
+	Delivered int64 `json:"delivered" db:"delivered"`
+	Failed    int64 `json:"failed" db:"failed"`
+}
+
+// RecipientUpload is the progress of a resumable upload of the recipients of a campaign (bulk
+// upload). Offset is the byte offset in the file after the last row saved, where a continuation
+// of the upload starts, and Rows the number of rows read up to it.
+type RecipientUpload struct {
+	ReferenceID   string            `json:"campaign_id" db:"reference_id"`
+	UploadToken   string            `json:"upload_token" db:"upload_token"`
+	Offset        int64             `json:"offset" db:"upload_offset"`
+	Rows          int64             `json:"rows" db:"upload_rows"`
+	RowsAccepted  int64             `json:"rows_accepted" db:"rows_accepted"`
+	RowsRejected  int64             `json:"rows_rejected" db:"rows_rejected"`
+	RowsDuplicate int64             `json:"rows_duplicate" db:"rows_duplicate"`
+	Completed     bool              `json:"completed" db:"upload_completed"`
+	Rejects       []RecipientReject `json:"rejects" db:"upload_rejects"`
+}
+
+// RecipientReject is a row of a recipient upload without a valid mobile number
+type RecipientReject struct {
+	Row    int64  `json:"row"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// StatsHourlyMismatch is an hourly rollup row whose counters differ from the raw aggregation
+type StatsHourlyMismatch struct {
+	ApplicationID   string    `json:"application_id" db:"application_id"`
+	Gateway         string    `json:"gateway" db:"gateway"`
+	Priority        int       `json:"priority" db:"priority"`
+	Hour            time.Time `json:"hour" db:"hour"`
+	RollupSent      int64     `json:"rollup_sent" db:"rollup_sent"`
+	RawSent         int64     `json:"raw_sent" db:"raw_sent"`
+	RollupFailed    int64     `json:"rollup_failed" db:"rollup_failed"`
+	RawFailed       int64     `json:"raw_failed" db:"raw_failed"`
+	RollupDelivered int64     `json:"rollup_delivered" db:"rollup_delivered"`
+	RawDelivered    int64     `json:"raw_delivered" db:"raw_delivered"`
+}
+
+// OrphanedRequest is a request stored to be sent right away whose gateway response was never
+// stored, left by the process stopping between the two writes before they shared a transaction
+type OrphanedRequest struct {
+	RequestID       int64     `json:"request_id" db:"request_id"`
+	CommunicationID string    `json:"communication_id" db:"communication_id"`
+	ApplicationID   string    `json:"application_id" db:"application_id"`
+	Gateway         string    `json:"gateway" db:"gateway"`
+	Priority        int       `json:"priority" db:"priority"`
+	CreatedDate     time.Time `json:"created_date" db:"created_date"`
+}
+
+// GatewayCode describes a response code of an SMS gateway
+type GatewayCode struct {
+	Gateway           string     `json:"gateway" db:"gateway"`
+	Code              string     `json:"code" db:"code"`
+	Description       string     `json:"description" db:"description"`
+	Severity          string     `json:"severity" db:"severity"`
+	RecommendedAction *string    `json:"recommended_action" db:"recommended_action"`
+	UpdatedDate       *time.Time `json:"updated_date" db:"updated_date"`
+}
+
+// ShadowResult is the outcome of sending a copy of a request to the shadow gateway of its
+// application, next to the outcome of the primary gateway
+type ShadowResult struct {
+	CommunicationID     string `json:"communication_id" db:"communication_id"`
+	ApplicationID       string `json:"application_id" db:"application_id"`
+	TemplateID          string `json:"template_id" db:"template_id"`
+	Recipients          int    `json:"recipients" db:"recipients"`
+	PrimaryGateway      string `json:"primary_gateway" db:"primary_gateway"`
+	PrimaryResponseCode string `json:"primary_response_code" db:"primary_response_code"`
+	PrimaryAccepted     bool   `json:"primary_accepted" db:"primary_accepted"`
+	ShadowGateway       string `json:"shadow_gateway" db:"shadow_gateway"`
+	ShadowResponseCode  string `json:"shadow_response_code" db:"shadow_response_code"`
+	ShadowResponseText  string `json:"shadow_response_text" db:"shadow_response_text"`
+	ShadowAccepted      bool   `json:"shadow_accepted" db:"shadow_accepted"`
+}
+
+// ShadowComparison summarizes the acceptance of the shadowed requests of an application by
+// the primary and the shadow gateway
+type ShadowComparison struct {
+	ApplicationID         string  `json:"application_id" db:"application_id"`
+	PrimaryGateway        string  `json:"primary_gateway" db:"primary_gateway"`
+	ShadowGateway         string  `json:"shadow_gateway" db:"shadow_gateway"`
+	Requests              int64   `json:"requests" db:"requests"`
+	PrimaryAccepted       int64   `json:"primary_accepted" db:"primary_accepted"`
+	ShadowAccepted        int64   `json:"shadow_accepted" db:"shadow_accepted"`
+	PrimaryAcceptanceRate float64 `json:"primary_acceptance_rate" db:"primary_acceptance_rate"`
+	ShadowAcceptanceRate  float64 `json:"shadow_acceptance_rate" db:"shadow_acceptance_rate"`
+}
+
+// ShadowCodeDifference counts the shadowed requests of an application rejected by the primary or
+// the shadow gateway, by the response codes of both gateways
+type ShadowCodeDifference struct {
+	ApplicationID       string `json:"application_id" db:"application_id"`
+	PrimaryGateway      string `json:"primary_gateway" db:"primary_gateway"`
+	PrimaryResponseCode string `json:"primary_response_code" db:"primary_response_code"`
+	ShadowGateway       string `json:"shadow_gateway" db:"shadow_gateway"`
+	ShadowResponseCode  string `json:"shadow_response_code" db:"shadow_response_code"`
+	Requests            int64  `json:"requests" db:"requests"`
+}
+
+// PrivacyErasure is the audit entry of the erasure of the messages sent to a mobile number.
+// The number itself is never stored; the erasure is identified by its tombstone, the value
+// that replaces the number in the erased rows.
+type PrivacyErasure struct {
+	ErasureID     uint64                `json:"erasure_id" db:"erasure_id"`
+	Tombstone     int64                 `json:"tombstone" db:"tombstone"`
+	FromDate      *time.Time            `json:"from_date" db:"from_date"`
+	ToDate        *time.Time            `json:"to_date" db:"to_date"`
+	RequestedBy   string                `json:"requested_by" db:"requested_by"`
+	Status        string                `json:"status" db:"status"`
+	CreatedDate   time.Time             `json:"created_date" db:"created_date"`
+	CompletedDate *time.Time            `json:"completed_date" db:"completed_date"`
+	Tables        []PrivacyErasureTable `json:"tables" db:"-"`
+}
+
+// PrivacyErasureTable is the progress of an erasure in one table
+type PrivacyErasureTable struct {
+	TableName    string `json:"table_name" db:"table_name"`
+	RowsAffected int64  `json:"rows_affected" db:"rows_affected"`
+	Completed    bool   `json:"completed" db:"completed"`
+}
+
+// MessageTextAccess records a caller receiving the unmasked message text of a request
+type MessageTextAccess struct {
+	UserID          string    `json:"user_id"`
+	CommunicationID string    `json:"communication_id"`
+	AccessedAt      time.Time `json:"accessed_at"`
+}
+
 type ListApplications struct {
 	ApplicationID   uint64    `json:"application_id" db:"application_id"`
 	ApplicationName string    `json:"application_name" db:"application_name"`
@@ -250,3 +499,294 @@ type ListMessageProviders struct {
 	//ConfigurationKeys interface{} `json:"configuration_keys" db:"configuration_key"`
 	Status bool `json:"status" db:"status_cd"`
 }
+
+type MsgOTP struct {
+	OTPID         uint64     `json:"otp_id" db:"otp_id"`
+	OTPReference  string     `json:"otp_reference" db:"otp_reference"`
+	ApplicationID string     `json:"application_id" db:"application_id"`
+	MobileNumber  string     `json:"mobile_number" db:"mobile_number"`
+	OTPHash       string     `json:"-" db:"otp_hash"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	MaxAttempts   int        `json:"max_attempts" db:"max_attempts"`
+	ExpiresAt     time.Time  `json:"expires_at" db:"expires_at"`
+	VerifiedDate  *time.Time `json:"verified_date" db:"verified_date"`
+	CreatedDate   time.Time  `json:"created_date" db:"created_date"`
+	Expired       bool       `json:"expired" db:"expired"`
+}
+
+// Statuses of the components of the system status
+const (
+	ComponentUp       = "up"
+	ComponentDegraded = "degraded"
+	ComponentDown     = "down"
+	ComponentUnknown  = "unknown"  // not probed yet or the last probe result is stale
+	ComponentDisabled = "disabled" // not enabled in the configuration, not counted
+)
+
+// Overall statuses of the system status, from the severity of the components that are not up
+const (
+	SystemStatusGreen = "green"
+	SystemStatusAmber = "amber"
+	SystemStatusRed   = "red"
+)
+
+// DependencyStatus is the last probe result of a dependency
+type DependencyStatus struct {
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	Message     string     `json:"message"`
+	LastChecked *time.Time `json:"last_checked"`
+	LatencyMs   int64      `json:"latency_ms"`
+	// LastSuccess is the last send accepted, for the sends of a gateway
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+}
+
+// States of a background job; jobs not enabled in the configuration are ComponentDisabled
+const (
+	WorkerRunning = "running"
+	WorkerStopped = "stopped"
+)
+
+// WorkerStatus is the state of a background job
+type WorkerStatus struct {
+	Name      string     `json:"name"`
+	Status    string     `json:"status"`
+	LastCycle *time.Time `json:"last_cycle"`
+	LastError string     `json:"last_error,omitempty"`
+	Backlog   *int64     `json:"backlog"` // nil when the job has no backlog or it is not known yet
+}
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open" // the cooldown has passed, the next call decides
+	BreakerDisabled = "disabled"
+)
+
+// BreakerStatus is the state of a circuit breaker
+type BreakerStatus struct {
+	Name           string `json:"name"`
+	State          string `json:"state"`
+	RetryInSeconds int64  `json:"retry_in_seconds"`
+}
+
+// SystemStatus aggregates the state of the dependencies, the background jobs and the circuit
+// breakers, with an overall status computed from the weighted severity of the components
+type SystemStatus struct {
+	Status       string             `json:"status"`
+	Score        float64            `json:"score"`
+	GeneratedAt  time.Time          `json:"generated_at"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+	Workers      []WorkerStatus     `json:"workers"`
+	Breakers     []BreakerStatus    `json:"breakers"`
+}
+
+// ErrorSample is a request that panicked or was answered with a 5xx status, kept for post-incident
+// review. RequestSummary is the method and URL of the request, truncated and with its mobile
+// numbers and sensitive parameters masked.
+type ErrorSample struct {
+	ErrorID        string    `json:"error_id" db:"error_id"`
+	Route          string    `json:"route" db:"route"`
+	Method         string    `json:"method" db:"method"`
+	Status         int       `json:"status" db:"status"`
+	CorrelationID  string    `json:"correlation_id" db:"correlation_id"`
+	RequestSummary string    `json:"request_summary" db:"request_summary"`
+	Panicked       bool      `json:"panicked" db:"panicked"`
+	Stack          *string   `json:"stack" db:"stack"` // set for panics only
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// ErrorSampleFilter selects the error samples of a route and period, every field being optional
+type ErrorSampleFilter struct {
+	Route string
+	From  *time.Time
+	To    *time.Time
+}
+
+// ConfigBundleVersion is the schema version of the configuration bundles written and read by
+// the service
+const ConfigBundleVersion = 1
+
+// ConfigBundle is the configuration of the service that is promoted between environments: the
+// applications, keyed by name and without their secret keys, and their DLT templates, keyed by
+// DLT template id
+type ConfigBundle struct {
+	SchemaVersion int                 `json:"schema_version"`
+	ExportedAt    time.Time           `json:"exported_at"`
+	Applications  []BundleApplication `json:"applications"`
+	Templates     []BundleTemplate    `json:"templates"`
+}
+
+// BundleApplication is an application of a ConfigBundle
+type BundleApplication struct {
+	ApplicationName string  `json:"application_name" db:"application_name"`
+	RequestType     string  `json:"request_type" db:"request_type"`
+	Status          bool    `json:"status" db:"status"`
+	ShadowGateway   *string `json:"shadow_gateway" db:"shadow_gateway"`
+}
+
+// BundleTemplate is a template of a ConfigBundle, owned by the application of ApplicationName
+type BundleTemplate struct {
+	TemplateID      string `json:"template_id" db:"template_id"`
+	ApplicationName string `json:"application_name" db:"application_name"`
+	TemplateName    string `json:"template_name" db:"template_name"`
+	TemplateFormat  string `json:"template_format" db:"template_format"`
+	SenderID        string `json:"sender_id" db:"sender_id"`
+	EntityID        string `json:"entity_id" db:"entity_id"`
+	Gateway         string `json:"gateway" db:"gateway"`
+	MessageType     string `json:"message_type" db:"message_type"`
+	Status          bool   `json:"status" db:"status"`
+}
+
+// Actions of a bundle import on an entity
+const (
+	BundleCreated = "created"
+	BundleUpdated = "updated"
+	BundleSkipped = "skipped" // the entity is unchanged
+	BundlePruned  = "pruned"  // the entity is not in the bundle and is deactivated
+)
+
+// BundleApplicationChange is the action of a bundle import on an application, Fields naming the
+// fields updated. SecretKey is the key of a created application, never exported.
+type BundleApplicationChange struct {
+	Action      string            `json:"action"`
+	Fields      []string          `json:"fields,omitempty"`
+	Application BundleApplication `json:"application"`
+	SecretKey   string            `json:"-"`
+}
+
+// BundleTemplateChange is the action of a bundle import on a template, Fields naming the fields
+// updated
+type BundleTemplateChange struct {
+	Action   string         `json:"action"`
+	Fields   []string       `json:"fields,omitempty"`
+	Template BundleTemplate `json:"template"`
+}
+
+// BundleImportPlan is the change of every entity of a bundle import
+type BundleImportPlan struct {
+	Applications []BundleApplicationChange `json:"applications"`
+	Templates    []BundleTemplateChange    `json:"templates"`
+}
+
+// Entities whose changes are notified to the caches of every replica
+const (
+	CacheEntityTemplate = "template"
+)
+
+// CacheChange is a change of the entity of ID, Entity being a CacheEntity, notified by the
+// replica that made it
+type CacheChange struct {
+	Entity string
+	ID     string
+}
+
+// String returns the payload of the notification of the change
+func (cc CacheChange) String() string {
+	return cc.Entity + ":" + cc.ID
+}
+
+// ParseCacheChange returns the change notified with payload
+func ParseCacheChange(payload string) (CacheChange, bool) {
+	entity, id, ok := strings.Cut(payload, ":")
+	if !ok || entity == "" {
+		return CacheChange{}, false
+	}
+	return CacheChange{Entity: entity, ID: id}, true
+}
+
+// Credential expiry statuses
+const (
+	CredentialExpiryUnknown  = "unknown"
+	CredentialExpiryValid    = "valid"
+	CredentialExpiryExpiring = "expiring"
+	CredentialExpiryExpired  = "expired"
+)
+
+// CredentialExpiry is the expiry of the password of a gateway account, the global one of a
+// gateway or one of a tenant credential set. DaysRemaining counts the business days until
+// ExpiresAt, negative once expired; both are nil when no expiry is configured.
+type CredentialExpiry struct {
+	Credential    string     `json:"credential"`
+	Gateway       string     `json:"gateway"`
+	CredentialSet string     `json:"credential_set,omitempty"`
+	Username      string     `json:"username"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	DaysRemaining *int       `json:"days_remaining"`
+	Status        string     `json:"status"`
+	Alerted       bool       `json:"alerted"`
+}
+
+// QueryPlan is the EXPLAIN (ANALYZE, BUFFERS) output of a query registered for the admin
+// diagnostics, run with its sample parameters in a transaction that is always rolled back. Plan
+// is the plan tree of the JSON format of EXPLAIN, times are in milliseconds.
+type QueryPlan struct {
+	Name               string          `json:"name"`
+	Description        string          `json:"description"`
+	Query              string          `json:"query"`
+	Args               []any           `json:"args"`
+	StatementTimeoutMs int64           `json:"statement_timeout_ms"`
+	PlanningTimeMs     float64         `json:"planning_time_ms"`
+	ExecutionTimeMs    float64         `json:"execution_time_ms"`
+	Plan               json.RawMessage `json:"plan" swaggertype:"object"`
+}
+
+// Actions and entity types of the admin audit log
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+	AuditActionStatus = "status"
+	AuditActionImport = "import"
+	AuditActionRun    = "run"
+
+	AuditEntityApplication    = "application"
+	AuditEntityTemplate       = "template"
+	AuditEntityGatewayCode    = "gateway_code"
+	AuditEntityConfigBundle   = "config_bundle"
+	AuditEntityLogLevel       = "log_level"
+	AuditEntityDNDRegistry    = "dnd_registry"
+	AuditEntityStatsBackfill  = "stats_backfill"
+	AuditEntityPrivacyErasure = "privacy_erasure"
+
+	// AuditEntityIDBulk is the entity id of a change of several entities, its diff keyed by
+	// their ids
+	AuditEntityIDBulk = "bulk"
+)
+
+// AuditEntry is a row of the append-only admin audit log: who changed which entity, how and
+// when, with the before and after values of the fields changed, the secret ones redacted. Hash
+// chains the entry to the previous one, whose hash is PrevHash, so that an entry edited or
+// removed afterwards breaks the chain.
+type AuditEntry struct {
+	AuditID       int64           `json:"audit_id" db:"audit_id"`
+	Actor         string          `json:"actor" db:"actor"`
+	Action        string          `json:"action" db:"action"`
+	EntityType    string          `json:"entity_type" db:"entity_type"`
+	EntityID      string          `json:"entity_id" db:"entity_id"`
+	Diff          json.RawMessage `json:"diff" db:"diff" swaggertype:"object"`
+	CorrelationID string          `json:"correlation_id" db:"correlation_id"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	PrevHash      string          `json:"prev_hash" db:"prev_hash"`
+	Hash          string          `json:"hash" db:"hash"`
+}
+
+// AuditFilter selects the entries of the audit log, every field being optional
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   string
+	From       *time.Time
+	To         *time.Time
+}
+
+// AuditVerification is the result of the walk of the audit chain: the entries checked and, when
+// the chain is broken, the first entry that does not chain to the previous one and why
+type AuditVerification struct {
+	Verified bool   `json:"verified"`
+	Checked  int64  `json:"checked"`
+	BrokenAt *int64 `json:"broken_at"`
+	Reason   string `json:"reason,omitempty"`
+}
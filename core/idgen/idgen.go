@@ -0,0 +1,106 @@
+// Package idgen generates the communication IDs of the message requests. The IDs are assigned
+// when a request is received, before anything is stored, so that the requests queued on Kafka or
+// stored after they are sent are known by the same ID from the start. ULIDs are generated unless
+// a deployment selects a generator it registered with Register.
+//
+// The requests stored before the IDs were generated by the gateway carry the IDs generated by
+// the database, 20 lowercase letters and digits. They are kept as they are: the column holding
+// the IDs was widened rather than the IDs rewritten, and a legacy ID cannot be mistaken for a
+// ULID, which is 26 uppercase characters long.
+package idgen
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ULIDName is the name of the ULID generator, the default one
+const ULIDName = "ulid"
+
+// MaxLength is the length of the longest ID the msg_request table holds, the IDs of the
+// generators registered by a deployment cannot be longer
+const MaxLength = 64
+
+// LegacyLength is the length of the IDs generated by the database
+const LegacyLength = 20
+
+// Generator generates unique IDs. Its methods are called concurrently.
+type Generator interface {
+	// NewID returns an ID never returned before, at most MaxLength characters long
+	NewID() string
+	// Valid reports whether id has the format of the IDs of the generator
+	Valid(id string) bool
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Generator{
+		ULIDName: NewULIDGenerator(),
+	}
+)
+
+// Register makes a custom generator available under name. It is meant to be called at startup,
+// before the handlers are created, and fails when name is taken.
+func Register(name string, g Generator) error {
+	if name == "" || g == nil {
+		return errors.New("ID generator needs a name and a generator")
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; ok {
+		return fmt.Errorf("ID generator %s is already registered", name)
+	}
+	registry[name] = g
+	return nil
+}
+
+// New returns the generator registered under name, the ULID generator when name is empty,
+// failing on unknown names
+func New(name string) (Generator, error) {
+	if name == "" {
+		name = ULIDName
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	g, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown ID generator %s", name)
+	}
+	return g, nil
+}
+
+// Default returns the ULID generator
+func Default() Generator {
+	g, _ := New(ULIDName)
+	return g
+}
+
+// Valid reports whether id is a legacy ID or has the format of the IDs of one of the registered
+// generators
+func Valid(id string) bool {
+	if validLegacy(id) {
+		return true
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for _, g := range registry {
+		if g.Valid(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// validLegacy reports whether id has the format of the IDs generated by the database
+func validLegacy(id string) bool {
+	if len(id) != LegacyLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if c := id[i]; (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
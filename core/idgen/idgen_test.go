@@ -0,0 +1,149 @@
+package idgen
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedULIDGenerator returns a ULIDGenerator whose clock is stuck at now, drawing random bits
+// from entropy
+func fixedULIDGenerator(now time.Time, entropy []byte) *ULIDGenerator {
+	return &ULIDGenerator{now: func() time.Time { return now }, entropy: bytes.NewReader(entropy)}
+}
+
+func TestULIDMonotonicWithinAMillisecond(t *testing.T) {
+	now := time.Date(2024, 9, 15, 10, 30, 0, 0, time.UTC)
+	g := fixedULIDGenerator(now, bytes.Repeat([]byte{0x5a}, 10))
+
+	ids := make([]string, 1000)
+	for i := range ids {
+		ids[i] = g.NewID()
+		require.Len(t, ids[i], ULIDLength)
+		at, err := ParseULID(ids[i])
+		require.NoError(t, err)
+		assert.True(t, now.Equal(at), "%s generated at %s", ids[i], at)
+	}
+	assert.True(t, sort.StringsAreSorted(ids))
+	assert.Equal(t, ids[0][:10], ids[len(ids)-1][:10])
+	assert.Equal(t, "01J7TKPX20", ids[0][:10])
+	assert.Equal(t, "B9D5MPJTB9D5MPJT", ids[0][10:])
+	assert.Equal(t, "B9D5MPJTB9D5MPJV", ids[1][10:])
+}
+
+// A clock set back does not make the IDs go backwards, and an overflow of the random bits moves
+// the timestamp a millisecond ahead
+func TestULIDMonotonicAcrossClockChanges(t *testing.T) {
+	now := time.Date(2024, 9, 15, 10, 30, 0, 0, time.UTC)
+	g := fixedULIDGenerator(now, append(bytes.Repeat([]byte{0xff}, 10), make([]byte, 10)...))
+
+	first := g.NewID()
+	assert.Equal(t, "01J7TKPX20ZZZZZZZZZZZZZZZZ", first)
+
+	g.now = func() time.Time { return now.Add(-time.Second) }
+	second := g.NewID()
+	assert.Greater(t, second, first)
+	at, err := ParseULID(second)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Millisecond), at)
+	assert.Equal(t, "01J7TKPX210000000000000000", second)
+}
+
+func TestULIDUniqueUnderConcurrentGeneration(t *testing.T) {
+	g := NewULIDGenerator()
+	const workers, perWorker = 16, 2000
+
+	results := make([][]string, workers)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWorker {
+				results[w] = append(results[w], g.NewID())
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool, workers*perWorker)
+	for _, ids := range results {
+		// the IDs generated by a worker follow the ones it generated before
+		assert.True(t, sort.StringsAreSorted(ids))
+		for _, id := range ids {
+			require.False(t, seen[id], "%s generated twice", id)
+			seen[id] = true
+		}
+	}
+	assert.Len(t, seen, workers*perWorker)
+}
+
+func TestParseULID(t *testing.T) {
+	at, err := ParseULID("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1469922850259), at.UnixMilli())
+
+	for _, id := range []string{
+		"",
+		"01ARZ3NDEKTSV4RRFFQ69G5FA",   // too short
+		"01ARZ3NDEKTSV4RRFFQ69G5FAVX", // too long
+		"01arz3ndektsv4rrffq69g5fav",  // lowercase
+		"01ARZ3NDEKTSV4RRFFQ69G5FAU",  // U is not in the alphabet
+		"01ARZ3NDEKTSV4RRFFQ69G5FAI",  // I neither
+		"81ARZ3NDEKTSV4RRFFQ69G5FAV",  // timestamp beyond 48 bits
+	} {
+		_, err := ParseULID(id)
+		assert.Error(t, err, "ParseULID(%q)", id)
+	}
+}
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid(Default().NewID()))
+	assert.True(t, Valid("01ARZ3NDEKTSV4RRFFQ69G5FAV"))
+	// IDs generated by the database before the gateway generated them
+	assert.True(t, Valid("p4w15qumdd8o1apiyqwp"))
+
+	assert.False(t, Valid(""))
+	assert.False(t, Valid("Not Applicable"))
+	assert.False(t, Valid("P4W15QUMDD8O1APIYQWP"))
+	assert.False(t, Valid("p4w15qumdd8o1apiyqw"))
+	assert.False(t, Valid("12345"))
+}
+
+// stubGenerator generates the IDs of a deployment, prefixed with its name
+type stubGenerator struct{}
+
+func (stubGenerator) NewID() string { return "stub-1" }
+
+func (stubGenerator) Valid(id string) bool { return len(id) > 5 && id[:5] == "stub-" }
+
+func TestRegister(t *testing.T) {
+	g, err := New("")
+	require.NoError(t, err)
+	assert.IsType(t, &ULIDGenerator{}, g)
+
+	_, err = New("stub")
+	assert.EqualError(t, err, "unknown ID generator stub")
+	assert.False(t, Valid("stub-1"))
+
+	require.NoError(t, Register("stub", stubGenerator{}))
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, "stub")
+		registryMu.Unlock()
+	})
+	g, err = New("stub")
+	require.NoError(t, err)
+	assert.Equal(t, "stub-1", g.NewID())
+	assert.True(t, Valid("stub-1"))
+
+	assert.EqualError(t, Register("stub", stubGenerator{}), "ID generator stub is already registered")
+	assert.EqualError(t, Register(ULIDName, stubGenerator{}), "ID generator ulid is already registered")
+	assert.Error(t, Register("", stubGenerator{}))
+	assert.Error(t, Register("nil", nil))
+}
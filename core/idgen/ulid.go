@@ -0,0 +1,122 @@
+package idgen
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// ULIDLength is the length of a ULID
+const ULIDLength = 26
+
+// crockford is the Crockford base32 alphabet ULIDs are written in, without I, L, O and U
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var errInvalidULID = errors.New("invalid ULID")
+
+// ULIDGenerator generates ULIDs: 128 bits written as 26 characters of Crockford base32, a
+// timestamp in milliseconds of 48 bits followed by 80 random bits, so that the IDs sort by the
+// time they were generated. The IDs of a generator are monotonic: the IDs generated within the
+// same millisecond, or while the clock is set back, keep the timestamp of the last ID and
+// increment its random bits. Should they overflow, the timestamp is moved a millisecond ahead.
+type ULIDGenerator struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	entropy io.Reader
+	// the timestamp and the random bits of the last ID
+	lastMs uint64
+	hi     uint16
+	lo     uint64
+}
+
+// NewULIDGenerator creates a ULIDGenerator drawing its random bits from crypto/rand
+func NewULIDGenerator() *ULIDGenerator {
+	return &ULIDGenerator{now: time.Now, entropy: crand.Reader}
+}
+
+// NewID returns a new ULID
+func (g *ULIDGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(g.now().UnixMilli())
+	if ms > g.lastMs {
+		g.lastMs = ms
+		g.random()
+	} else {
+		g.lo++
+		if g.lo == 0 {
+			g.hi++
+			if g.hi == 0 {
+				g.lastMs++
+				g.random()
+			}
+		}
+	}
+	return encodeULID(g.lastMs, g.hi, g.lo)
+}
+
+// random draws new random bits. crypto/rand is not expected to fail; should it, the bits are
+// drawn from math/rand rather than failing the request.
+func (g *ULIDGenerator) random() {
+	var b [10]byte
+	if _, err := io.ReadFull(g.entropy, b[:]); err != nil {
+		binary.BigEndian.PutUint16(b[:2], uint16(rand.Uint32()))
+		binary.BigEndian.PutUint64(b[2:], rand.Uint64())
+	}
+	g.hi = binary.BigEndian.Uint16(b[:2])
+	g.lo = binary.BigEndian.Uint64(b[2:])
+}
+
+// Valid reports whether id is a ULID in its canonical, uppercase form
+func (g *ULIDGenerator) Valid(id string) bool {
+	_, err := ParseULID(id)
+	return err == nil
+}
+
+func encodeULID(ms uint64, hi uint16, lo uint64) string {
+	var id [ULIDLength]byte
+	for i := 9; i >= 0; i-- {
+		id[i] = crockford[ms&31]
+		ms >>= 5
+	}
+	for i := ULIDLength - 1; i >= 10; i-- {
+		id[i] = crockford[lo&31]
+		lo = lo>>5 | uint64(hi)<<59
+		hi >>= 5
+	}
+	return string(id[:])
+}
+
+// ParseULID returns the time a ULID in its canonical, uppercase form was generated at, failing
+// when id is not one
+func ParseULID(id string) (time.Time, error) {
+	if len(id) != ULIDLength || id[0] > '7' {
+		// a first character above 7 is a timestamp beyond 48 bits
+		return time.Time{}, errInvalidULID
+	}
+	var ms uint64
+	for i := 0; i < ULIDLength; i++ {
+		v := decodeCrockford(id[i])
+		if v < 0 {
+			return time.Time{}, errInvalidULID
+		}
+		if i < 10 {
+			ms = ms<<5 | uint64(v)
+		}
+	}
+	return time.UnixMilli(int64(ms)).UTC(), nil
+}
+
+func decodeCrockford(c byte) int {
+	for i := 0; i < len(crockford); i++ {
+		if crockford[i] == c {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,12 @@
+package port
+
+import (
+	"context"
+
+	"MgApplication/core/domain"
+)
+
+// MessageTextAuditor records the accesses to unmasked message text
+type MessageTextAuditor interface {
+	AuditMessageTextAccess(ctx context.Context, access domain.MessageTextAccess)
+}
@@ -0,0 +1,10 @@
+package port
+
+import "context"
+
+// DNDChecker looks up mobile numbers on the national DND (NCPR) registry
+type DNDChecker interface {
+	// CheckDND returns the numbers among mobileNumbers that are registered as DND.
+	// Numbers missing from the returned map are not registered.
+	CheckDND(ctx context.Context, mobileNumbers []string) (map[string]bool, error)
+}
@@ -0,0 +1,94 @@
+package port
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+// stringIDs controls whether ID values are emitted as JSON strings (api.stringids)
+var stringIDs atomic.Bool
+
+// SetStringIDs enables or disables emitting ID values as JSON strings in responses
+func SetStringIDs(enabled bool) {
+	stringIDs.Store(enabled)
+}
+
+// StringIDsEnabled reports whether ID values are emitted as JSON strings in responses
+func StringIDsEnabled() bool {
+	return stringIDs.Load()
+}
+
+// ID is a numeric identifier that is accepted as either a JSON number or a numeric
+// string, and emitted as a string when api.stringids is enabled. Clients that decode
+// JSON numbers as float64 lose precision above 2^53, so strings are the safe form.
+type ID uint64
+
+// MarshalJSON emits the ID as a string when api.stringids is enabled and as a number otherwise
+func (id ID) MarshalJSON() ([]byte, error) {
+	s := strconv.FormatUint(uint64(id), 10)
+	if StringIDsEnabled() {
+		return []byte(`"` + s + `"`), nil
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalJSON accepts the ID as a JSON number or a numeric string, without going through float64
+func (id *ID) UnmarshalJSON(data []byte) error {
+	raw, err := rawIDValue(data)
+	if err != nil || raw == "" {
+		return err
+	}
+	v, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid id %s: must be an unsigned integer", data)
+	}
+	*id = ID(v)
+	return nil
+}
+
+func (id ID) String() string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// StringID is an identifier kept as a string, such as a DLT template or entity ID, that
+// is accepted as either a JSON string or a JSON number. Numbers keep their exact digits.
+type StringID string
+
+// UnmarshalJSON accepts the ID as a JSON string or a JSON number
+func (id *StringID) UnmarshalJSON(data []byte) error {
+	raw, err := rawIDValue(data)
+	if err != nil {
+		return err
+	}
+	*id = StringID(raw)
+	return nil
+}
+
+func (id StringID) String() string {
+	return string(id)
+}
+
+// rawIDValue returns the literal digits of a JSON number or the contents of a JSON
+// string; null yields an empty value
+func rawIDValue(data []byte) (string, error) {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		return "", nil
+	case len(data) > 0 && data[0] == '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return "", err
+		}
+		return s, nil
+	default:
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return "", fmt.Errorf("invalid id %s: must be a number or a string", data)
+		}
+		return n.String(), nil
+	}
+}
@@ -0,0 +1,151 @@
+package port
+
+import (
+	"encoding/json"
+	"testing"
+
+	gojson "github.com/goccy/go-json"
+)
+
+// largeID is above 2^53, where float64 decoding loses precision
+const largeID = 1007344609998507114
+
+type idPayload struct {
+	ApplicationID ID       `json:"application_id"`
+	TemplateID    StringID `json:"template_id"`
+}
+
+// codecs covers encoding/json and the goccy encoder used by the request binding
+var codecs = []struct {
+	name      string
+	marshal   func(any) ([]byte, error)
+	unmarshal func([]byte, any) error
+}{
+	{name: "encoding/json", marshal: json.Marshal, unmarshal: json.Unmarshal},
+	{name: "go-json", marshal: gojson.Marshal, unmarshal: gojson.Unmarshal},
+}
+
+func setStringIDs(t *testing.T, enabled bool) {
+	t.Helper()
+	previous := StringIDsEnabled()
+	SetStringIDs(enabled)
+	t.Cleanup(func() { SetStringIDs(previous) })
+}
+
+func TestIDUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    idPayload
+		wantErr bool
+	}{
+		{
+			name:  "numbers",
+			input: `{"application_id":1007344609998507114,"template_id":1007344609998507114}`,
+			want:  idPayload{ApplicationID: largeID, TemplateID: "1007344609998507114"},
+		},
+		{
+			name:  "strings",
+			input: `{"application_id":"1007344609998507114","template_id":"1007344609998507114"}`,
+			want:  idPayload{ApplicationID: largeID, TemplateID: "1007344609998507114"},
+		},
+		{
+			name:  "max uint64",
+			input: `{"application_id":"18446744073709551615","template_id":18446744073709551615}`,
+			want:  idPayload{ApplicationID: 18446744073709551615, TemplateID: "18446744073709551615"},
+		},
+		{
+			name:  "null",
+			input: `{"application_id":null,"template_id":null}`,
+			want:  idPayload{},
+		},
+		{name: "negative id", input: `{"application_id":-4}`, wantErr: true},
+		{name: "fractional id", input: `{"application_id":4.5}`, wantErr: true},
+		{name: "non numeric id", input: `{"application_id":"4abc"}`, wantErr: true},
+		{name: "boolean template id", input: `{"template_id":true}`, wantErr: true},
+	}
+
+	for _, codec := range codecs {
+		for _, tt := range tests {
+			t.Run(codec.name+"/"+tt.name, func(t *testing.T) {
+				var got idPayload
+				err := codec.unmarshal([]byte(tt.input), &got)
+				if tt.wantErr {
+					if err == nil {
+						t.Fatalf("expected error, got %+v", got)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("expected %+v, got %+v", tt.want, got)
+				}
+			})
+		}
+	}
+}
+
+func TestIDMarshal(t *testing.T) {
+	payload := idPayload{ApplicationID: largeID, TemplateID: "1007344609998507114"}
+
+	tests := []struct {
+		name      string
+		stringIDs bool
+		want      string
+	}{
+		{
+			name:      "string ids enabled",
+			stringIDs: true,
+			want:      `{"application_id":"1007344609998507114","template_id":"1007344609998507114"}`,
+		},
+		{
+			name:      "string ids disabled",
+			stringIDs: false,
+			want:      `{"application_id":1007344609998507114,"template_id":"1007344609998507114"}`,
+		},
+	}
+
+	for _, codec := range codecs {
+		for _, tt := range tests {
+			t.Run(codec.name+"/"+tt.name, func(t *testing.T) {
+				setStringIDs(t, tt.stringIDs)
+
+				data, err := codec.marshal(payload)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if string(data) != tt.want {
+					t.Errorf("expected %s, got %s", tt.want, data)
+				}
+			})
+		}
+	}
+}
+
+// TestIDRoundTrip tests that IDs above 2^53 survive encoding and decoding in both directions
+func TestIDRoundTrip(t *testing.T) {
+	ids := []uint64{1<<53 + 1, largeID, 18446744073709551615}
+
+	for _, codec := range codecs {
+		for _, stringIDs := range []bool{true, false} {
+			setStringIDs(t, stringIDs)
+			for _, id := range ids {
+				in := idPayload{ApplicationID: ID(id), TemplateID: StringID(ID(id).String())}
+
+				data, err := codec.marshal(in)
+				if err != nil {
+					t.Fatalf("%s: marshal %d: %v", codec.name, id, err)
+				}
+				var out idPayload
+				if err := codec.unmarshal(data, &out); err != nil {
+					t.Fatalf("%s: unmarshal %s: %v", codec.name, data, err)
+				}
+				if out != in {
+					t.Errorf("%s (stringids=%t): expected %+v, got %+v", codec.name, stringIDs, in, out)
+				}
+			}
+		}
+	}
+}
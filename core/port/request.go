@@ -1,8 +1,14 @@
 package port
 
+// MaxLimit is the largest page a list request can ask for, see MetaDataRequest
+const MaxLimit = 1000
+
+// MetaDataRequest is the paging of a list request. Skip and Limit are signed so that a negative
+// value reaches validation and is answered with 422 rather than failing to bind; Limit is at most
+// MaxLimit, skip=0&limit=0 listing everything.
 type MetaDataRequest struct {
-	Skip     uint64 `form:"skip,default=0" validate:"omitempty"`
-	Limit    uint64 `form:"limit,default=10" validate:"omitempty"`
+	Skip     int64  `form:"skip,default=0" validate:"min=0" example:"0"`
+	Limit    int64  `form:"limit,default=10" validate:"min=0,max=1000" example:"10"`
 	OrderBy  string `form:"orderBy" validate:"omitempty"`
 	SortType string `form:"sortType" validate:"omitempty"`
 }
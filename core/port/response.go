@@ -1,6 +1,9 @@
 package port
 
-import "io"
+import (
+	"io"
+	"time"
+)
 
 var (
 	ListSuccess   StatusCodeAndMessage = StatusCodeAndMessage{StatusCode: 200, Message: "list retrieved successfully", Success: true}
@@ -8,12 +11,18 @@ var (
 	CreateSuccess StatusCodeAndMessage = StatusCodeAndMessage{StatusCode: 201, Message: "resource created successfully", Success: true}
 	UpdateSuccess StatusCodeAndMessage = StatusCodeAndMessage{StatusCode: 200, Message: "resource updated successfully", Success: true}
 	DeleteSuccess StatusCodeAndMessage = StatusCodeAndMessage{StatusCode: 200, Message: "resource deleted successfully", Success: true}
+	VerifySuccess StatusCodeAndMessage = StatusCodeAndMessage{StatusCode: 200, Message: "verified successfully", Success: true}
 )
 
 type StatusCodeAndMessage struct {
 	StatusCode int    `json:"status_code"`
 	Success    bool   `json:"success"`
 	Message    string `json:"message"`
+	// CorrelationID and Timestamp are stamped when the response is written
+	CorrelationID string `json:"correlation_id,omitempty"`
+	Timestamp     string `json:"timestamp,omitempty"`
+	// location is the URL the resource created is fetched from, written as the Location header
+	location string
 }
 
 type FileResponse struct {
@@ -30,6 +39,30 @@ func (s StatusCodeAndMessage) Status() int {
 	return s.StatusCode
 }
 
+// SetStatus replaces the status of a response embedding StatusCodeAndMessage
+func (s *StatusCodeAndMessage) SetStatus(status StatusCodeAndMessage) {
+	location := s.location
+	*s = status
+	s.location = location
+}
+
+// SetLocation sets the URL the resource created is fetched from
+func (s *StatusCodeAndMessage) SetLocation(location string) {
+	s.location = location
+}
+
+// Location returns the URL the resource created is fetched from, empty when not set
+func (s StatusCodeAndMessage) Location() string {
+	return s.location
+}
+
+// SetTrace stamps a response embedding StatusCodeAndMessage with the correlation ID of the
+// request it answers and the time it was written
+func (s *StatusCodeAndMessage) SetTrace(correlationID string, at time.Time) {
+	s.CorrelationID = correlationID
+	s.Timestamp = at.UTC().Format(time.RFC3339)
+}
+
 func (s StatusCodeAndMessage) ResponseType() string {
 	return "standard"
 }
@@ -97,6 +130,11 @@ func NewMetaDataResponse(skip uint64, limit uint64, total int) MetaDataResponse
 	}
 }
 
+// SetMeta replaces the metadata of a list response embedding MetaDataResponse
+func (m *MetaDataResponse) SetMeta(meta MetaDataResponse) {
+	*m = meta
+}
+
 func GetPredefinedStatusDetails(status string) StatusCodeAndMessage {
 
 	PredefinedStatusDetailsMap := map[string]StatusCodeAndMessage{
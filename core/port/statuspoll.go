@@ -0,0 +1,14 @@
+package port
+
+import (
+	"context"
+
+	"MgApplication/core/domain"
+)
+
+// DeliveryStatusFetcher fetches delivery reports from an SMS gateway
+type DeliveryStatusFetcher interface {
+	// FetchDeliveryStatus returns the delivery status of each recipient of the message the
+	// gateway accepted under referenceID
+	FetchDeliveryStatus(ctx context.Context, referenceID string) ([]domain.RecipientStatus, error)
+}
@@ -0,0 +1,198 @@
+// Package transform cleans up message text before it is sent: upstream systems send messages
+// with double spaces, trailing newlines, smart quotes and emoji that some operator routes strip
+// or reject. A Pipeline applies named transformers in order, the built-in ones and those
+// registered by a deployment with Register.
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Names of the built-in transformers
+const (
+	Whitespace  = "whitespace"
+	SmartQuotes = "smart_quotes"
+	ZeroWidth   = "zero_width"
+	EmojiStrip  = "emoji_strip"
+	EmojiReject = "emoji_reject"
+)
+
+// ErrRejected is the error of the transformers refusing a message, e.g. EmojiReject
+var ErrRejected = errors.New("message rejected")
+
+// Transformer returns text transformed, or an error wrapping ErrRejected when the message cannot
+// be sent. Transformers must be pure: the same text always gives the same result.
+type Transformer func(text string) (string, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Transformer{
+		Whitespace:  NormalizeWhitespace,
+		SmartQuotes: ReplaceSmartQuotes,
+		ZeroWidth:   RemoveZeroWidth,
+		EmojiStrip:  StripEmoji,
+		EmojiReject: RejectEmoji,
+	}
+)
+
+// Register makes a custom transformer available to pipelines under name. It is meant to be
+// called at startup, before the pipelines are created, and fails when name is taken.
+func Register(name string, t Transformer) error {
+	if name == "" || t == nil {
+		return errors.New("transformer needs a name and a function")
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; ok {
+		return fmt.Errorf("transformer %s is already registered", name)
+	}
+	registry[name] = t
+	return nil
+}
+
+// Error is the error of a transformer of a pipeline
+type Error struct {
+	Transformer string
+	Err         error
+}
+
+func (e *Error) Error() string {
+	return e.Transformer + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+type step struct {
+	name string
+	fn   Transformer
+}
+
+// Pipeline applies transformers in order. A nil Pipeline leaves the text unchanged.
+type Pipeline struct {
+	steps []step
+}
+
+// New creates a Pipeline applying the transformers registered under names in order, failing on
+// unknown names
+func New(names []string) (*Pipeline, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	p := &Pipeline{}
+	for _, name := range names {
+		fn, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown transformer %s", name)
+		}
+		p.steps = append(p.steps, step{name: name, fn: fn})
+	}
+	return p, nil
+}
+
+// Apply runs text through the pipeline. It returns the text sent with the names of the
+// transformers that changed it, in order, or the *Error of the first transformer failing.
+func (p *Pipeline) Apply(text string) (string, []string, error) {
+	if p == nil {
+		return text, nil, nil
+	}
+	var applied []string
+	for _, s := range p.steps {
+		transformed, err := s.fn(text)
+		if err != nil {
+			return "", applied, &Error{Transformer: s.name, Err: err}
+		}
+		if transformed != text {
+			applied = append(applied, s.name)
+			text = transformed
+		}
+	}
+	return text, applied, nil
+}
+
+// NormalizeWhitespace turns CRLF and CR line breaks into LF, collapses runs of spaces, tabs and
+// other horizontal whitespace into one space, and trims the whitespace around every line and
+// around the message. Line breaks inside the message are kept.
+func NormalizeWhitespace(text string) (string, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.FieldsFunc(line, unicode.IsSpace), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
+
+// smartQuotes replaces the typographic quotes with their ASCII counterparts
+var smartQuotes = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'", "′", "'",
+	"“", `"`, "”", `"`, "„", `"`, "‟", `"`, "″", `"`,
+	"«", `"`, "»", `"`,
+)
+
+// ReplaceSmartQuotes replaces curly quotes, primes and guillemets with straight ASCII quotes,
+// which the GSM 7-bit alphabet has
+func ReplaceSmartQuotes(text string) (string, error) {
+	return smartQuotes.Replace(text), nil
+}
+
+// RemoveZeroWidth removes the zero-width space, word joiner, byte order mark, soft hyphen and
+// Mongolian vowel separator.
+// The zero-width joiner and non-joiner are kept, Indic scripts need them to shape conjuncts.
+func RemoveZeroWidth(text string) (string, error) {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '\u200b', '\u2060', '\ufeff', '\u00ad', '\u180e':
+			return -1
+		}
+		return r
+	}, text), nil
+}
+
+// StripEmoji removes emoji with their variation selectors, skin tone modifiers and the joiners
+// between them
+func StripEmoji(text string) (string, error) {
+	runes := []rune(text)
+	var b strings.Builder
+	for i, r := range runes {
+		if isEmoji(r) {
+			continue
+		}
+		if r == '\u200d' && ((i > 0 && isEmoji(runes[i-1])) || (i+1 < len(runes) && isEmoji(runes[i+1]))) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), nil
+}
+
+// RejectEmoji rejects messages with emoji
+func RejectEmoji(text string) (string, error) {
+	for _, r := range text {
+		if isEmoji(r) {
+			return "", fmt.Errorf("%w, it contains the emoji %q", ErrRejected, r)
+		}
+	}
+	return text, nil
+}
+
+// isEmoji reports whether r is an emoji or a character only used to compose them
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // pictographs, emoticons, transport, flags, skin tones
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // miscellaneous symbols and dingbats
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // arrows and stars
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // tags of subdivision flags
+		return true
+	case r == 0xFE0F || r == 0x20E3: // emoji presentation selector and keycap
+		return true
+	}
+	return false
+}
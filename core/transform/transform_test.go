@@ -0,0 +1,130 @@
+package transform
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeWhitespace(t *testing.T) {
+	cases := map[string]string{
+		"Your OTP is 1234":                    "Your OTP is 1234",
+		"Your  OTP\tis   1234":                "Your OTP is 1234",
+		"  Your OTP is 1234 \n\n":             "Your OTP is 1234",
+		"Dear Customer, \r\nYour OTP is 1234": "Dear Customer,\nYour OTP is 1234",
+		"Line one\rLine two":                  "Line one\nLine two",
+		" \t\r\n ":                            "",
+	}
+	for text, want := range cases {
+		got, err := NormalizeWhitespace(text)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "NormalizeWhitespace(%q)", text)
+	}
+}
+
+func TestReplaceSmartQuotes(t *testing.T) {
+	got, err := ReplaceSmartQuotes("Don’t share your ‘OTP’, “1234” or «PIN» with anyone")
+	require.NoError(t, err)
+	assert.Equal(t, `Don't share your 'OTP', "1234" or "PIN" with anyone`, got)
+}
+
+func TestRemoveZeroWidth(t *testing.T) {
+	got, err := RemoveZeroWidth("\ufeffYour\u200b OTP\u2060 is 12\u00ad34")
+	require.NoError(t, err)
+	assert.Equal(t, "Your OTP is 1234", got)
+
+	// the joiners shape Devanagari conjuncts and are kept
+	hindi := "क्\u200dष"
+	got, err = RemoveZeroWidth(hindi)
+	require.NoError(t, err)
+	assert.Equal(t, hindi, got)
+}
+
+func TestStripEmoji(t *testing.T) {
+	cases := map[string]string{
+		"Your parcel is delivered 📦":               "Your parcel is delivered ",
+		"Thank you 👍🏽!":                            "Thank you !",
+		"Family 👨\u200d👩\u200d👧 plan":              "Family  plan",
+		"Rated ⭐⭐⭐ ☀\ufe0f":                        "Rated  ",
+		"Press 1\ufe0f\u20e3 to confirm":           "Press 1 to confirm",
+		"क्\u200dष is kept, as are ©, ® and ₹ 100": "क्\u200dष is kept, as are ©, ® and ₹ 100",
+		"No emoji here":                            "No emoji here",
+	}
+	for text, want := range cases {
+		got, err := StripEmoji(text)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "StripEmoji(%q)", text)
+	}
+}
+
+func TestRejectEmoji(t *testing.T) {
+	got, err := RejectEmoji("Your OTP is 1234")
+	require.NoError(t, err)
+	assert.Equal(t, "Your OTP is 1234", got)
+
+	_, err = RejectEmoji("Your OTP is 1234 🔐")
+	assert.ErrorIs(t, err, ErrRejected)
+}
+
+func TestPipeline(t *testing.T) {
+	p, err := New([]string{ZeroWidth, EmojiStrip, SmartQuotes, Whitespace})
+	require.NoError(t, err)
+
+	got, applied, err := p.Apply("Your OTP is “1234”\u200b 🔐  \n")
+	require.NoError(t, err)
+	assert.Equal(t, `Your OTP is "1234"`, got)
+	assert.Equal(t, []string{ZeroWidth, EmojiStrip, SmartQuotes, Whitespace}, applied)
+
+	// only the transformers that changed the text are recorded
+	got, applied, err = p.Apply("Your OTP is  1234")
+	require.NoError(t, err)
+	assert.Equal(t, "Your OTP is 1234", got)
+	assert.Equal(t, []string{Whitespace}, applied)
+
+	got, applied, err = p.Apply("Your OTP is 1234")
+	require.NoError(t, err)
+	assert.Equal(t, "Your OTP is 1234", got)
+	assert.Nil(t, applied)
+}
+
+func TestPipelineRejects(t *testing.T) {
+	p, err := New([]string{Whitespace, EmojiReject})
+	require.NoError(t, err)
+
+	_, applied, err := p.Apply("Your  OTP is 1234 🔐")
+	assert.ErrorIs(t, err, ErrRejected)
+	var failed *Error
+	require.True(t, errors.As(err, &failed))
+	assert.Equal(t, EmojiReject, failed.Transformer)
+	assert.Equal(t, []string{Whitespace}, applied)
+}
+
+func TestNilPipeline(t *testing.T) {
+	var p *Pipeline
+	got, applied, err := p.Apply("Your  OTP 🔐")
+	require.NoError(t, err)
+	assert.Equal(t, "Your  OTP 🔐", got)
+	assert.Nil(t, applied)
+}
+
+func TestRegister(t *testing.T) {
+	_, err := New([]string{Whitespace, "test_upper"})
+	assert.EqualError(t, err, "unknown transformer test_upper")
+
+	require.NoError(t, Register("test_upper", func(text string) (string, error) {
+		return strings.ToUpper(text), nil
+	}))
+	assert.Error(t, Register("test_upper", func(text string) (string, error) { return text, nil }))
+	assert.Error(t, Register(Whitespace, func(text string) (string, error) { return text, nil }))
+	assert.Error(t, Register("", nil))
+
+	p, err := New([]string{Whitespace, "test_upper"})
+	require.NoError(t, err)
+	got, applied, err := p.Apply(" your otp ")
+	require.NoError(t, err)
+	assert.Equal(t, "YOUR OTP", got)
+	assert.Equal(t, []string{Whitespace, "test_upper"}, applied)
+}
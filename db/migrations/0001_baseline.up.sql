@@ -0,0 +1,6 @@
+-- Baseline: the msggateway schema as created by the scripts of db/schema.
+--
+-- The schema predates the migrations, this version records it so the later migrations apply
+-- on top of it. Every change of the schema from now on is a new migration, named
+-- <version>_<name>.up.sql, the version following the last one, alongside the migration of
+-- the test database in tests/migration.
@@ -0,0 +1,7 @@
+-- The communication IDs are generated by the gateway when the requests are received, ULIDs of 26
+-- characters. The column is widened from char(20) rather than the IDs rewritten: the IDs of 20
+-- lowercase letters and digits generated by the database so far are kept, and its default still
+-- serves the writers that do not set one. The communication ID is the business key of a request.
+ALTER TABLE msggateway.msg_request ALTER COLUMN communication_id TYPE varchar(64) USING rtrim(communication_id);
+DROP INDEX IF EXISTS msggateway.idx_msg_request_communication_id;
+CREATE UNIQUE INDEX idx_msg_request_communication_id ON msggateway.msg_request USING btree (communication_id);
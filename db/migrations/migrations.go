@@ -0,0 +1,11 @@
+// Package migrations embeds the migrations of the msggateway schema, applied at startup when
+// db.migrate.onstart is set
+package migrations
+
+import "embed"
+
+// FS holds the migrations, golang-migrate files named <version>_<name>.up.sql run in version
+// order
+//
+//go:embed *.sql
+var FS embed.FS
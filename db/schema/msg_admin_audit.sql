@@ -0,0 +1,29 @@
+-- msggateway.msg_admin_audit definition
+
+-- Drop table
+
+-- DROP TABLE msggateway.msg_admin_audit;
+
+CREATE TABLE msggateway.msg_admin_audit (
+	audit_id bigserial NOT NULL,
+	actor varchar NOT NULL,
+	"action" varchar NOT NULL,
+	entity_type varchar NOT NULL,
+	entity_id varchar NOT NULL,
+	diff jsonb NOT NULL,
+	correlation_id varchar DEFAULT ''::character varying NOT NULL,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	prev_hash varchar NOT NULL,
+	hash varchar NOT NULL,
+	CONSTRAINT msg_admin_audit_pkey PRIMARY KEY (audit_id)
+);
+CREATE INDEX idx_msg_admin_audit_entity ON msggateway.msg_admin_audit USING btree (entity_type, entity_id, created_at);
+CREATE INDEX idx_msg_admin_audit_actor ON msggateway.msg_admin_audit USING btree (actor, created_at);
+
+-- Permissions
+
+ALTER TABLE msggateway.msg_admin_audit OWNER TO msggateway_admin;
+GRANT ALL ON TABLE msggateway.msg_admin_audit TO msggateway_admin;
+GRANT SELECT ON TABLE msggateway.msg_admin_audit TO msggateway_ro;
+GRANT INSERT, SELECT ON TABLE msggateway.msg_admin_audit TO msggateway_rw;
+GRANT ALL ON SEQUENCE msggateway.msg_admin_audit_audit_id_seq TO msggateway_rw;
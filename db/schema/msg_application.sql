@@ -9,10 +9,12 @@ CREATE TABLE msggateway.msg_application (
 	application_name varchar NULL,
 	request_type varchar NULL,
 	secret_key varchar NULL,
-	created_date timestamp DEFAULT CURRENT_TIMESTAMP NULL,
-	updated_date timestamp DEFAULT CURRENT_TIMESTAMP NULL,
+	created_date timestamptz DEFAULT CURRENT_TIMESTAMP NULL,
+	updated_date timestamptz DEFAULT CURRENT_TIMESTAMP NULL,
 	status_cd int4 NULL,
-	CONSTRAINT pg_applications_pkey_new PRIMARY KEY (application_id)
+	shadow_gateway varchar NULL,
+	CONSTRAINT pg_applications_pkey_new PRIMARY KEY (application_id),
+	CONSTRAINT msg_application_shadow_gateway_check CHECK (shadow_gateway IN ('1', '2'))
 );
 CREATE UNIQUE INDEX idx_msg_application_application_id ON msggateway.msg_application USING btree (application_id);
 CREATE INDEX idx_msg_application_application_name ON msggateway.msg_application USING btree (application_name);
@@ -22,10 +22,18 @@ CREATE TABLE msggateway.msg_bulk_file (
 	no_of_sms_pending int8 NULL,
 	scheduled varchar NULL,
 	status_cd int4 DEFAULT 0 NULL,
-	uploaded_time timestamp DEFAULT CURRENT_TIMESTAMP NULL,
-	updated_time timestamp NULL,
+	uploaded_time timestamptz DEFAULT CURRENT_TIMESTAMP NULL,
+	updated_time timestamptz NULL,
 	mobile_number _int8 NULL,
 	message_type varchar(2) NULL,
+	rows_accepted int8 DEFAULT 0 NOT NULL,
+	rows_rejected int8 DEFAULT 0 NOT NULL,
+	rows_duplicate int8 DEFAULT 0 NOT NULL,
+	upload_token varchar(32) NULL,
+	upload_offset int8 DEFAULT 0 NOT NULL,
+	upload_rows int8 DEFAULT 0 NOT NULL,
+	upload_completed bool DEFAULT false NOT NULL,
+	upload_rejects jsonb NULL,
 	CONSTRAINT msg_bulk_files_file_id PRIMARY KEY (file_id)
 );
 CREATE INDEX idx_msg_bulk_file_reference_id ON msggateway.msg_bulk_file USING btree (reference_id);
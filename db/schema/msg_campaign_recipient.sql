@@ -0,0 +1,19 @@
+-- msggateway.msg_campaign_recipient definition
+
+-- Drop table
+
+-- DROP TABLE msggateway.msg_campaign_recipient;
+
+-- Recipients uploaded for a campaign (bulk upload), one row per distinct mobile number
+CREATE TABLE msggateway.msg_campaign_recipient (
+	reference_id bpchar(32) NOT NULL,
+	mobile_number varchar(10) NOT NULL,
+	CONSTRAINT msg_campaign_recipient_pkey PRIMARY KEY (reference_id, mobile_number)
+);
+
+-- Permissions
+
+ALTER TABLE msggateway.msg_campaign_recipient OWNER TO msggateway_admin;
+GRANT ALL ON TABLE msggateway.msg_campaign_recipient TO msggateway_admin;
+GRANT SELECT ON TABLE msggateway.msg_campaign_recipient TO msggateway_ro;
+GRANT INSERT, UPDATE, DELETE, SELECT ON TABLE msggateway.msg_campaign_recipient TO msggateway_rw;
@@ -6,7 +6,7 @@
 
 CREATE TABLE msggateway.msg_counter (
 	counter_id int4 DEFAULT nextval('msggateway.msg_counter_counterid_seq'::regclass) NOT NULL,
-	request_date timestamp NULL,
+	request_date timestamptz NULL,
 	gateway varchar NULL,
 	count int4 NULL,
 	CONSTRAINT msg_counter_key PRIMARY KEY (counter_id)
@@ -0,0 +1,18 @@
+-- msggateway.msg_dnd_registry definition
+
+-- Drop table
+
+-- DROP TABLE msggateway.msg_dnd_registry;
+
+CREATE TABLE msggateway.msg_dnd_registry (
+	mobile_number varchar(15) NOT NULL,
+	created_date timestamptz DEFAULT CURRENT_TIMESTAMP NULL,
+	CONSTRAINT msg_dnd_registry_pkey PRIMARY KEY (mobile_number)
+);
+
+-- Permissions
+
+ALTER TABLE msggateway.msg_dnd_registry OWNER TO msggateway_admin;
+GRANT ALL ON TABLE msggateway.msg_dnd_registry TO msggateway_admin;
+GRANT SELECT ON TABLE msggateway.msg_dnd_registry TO msggateway_ro;
+GRANT INSERT, UPDATE, DELETE, SELECT ON TABLE msggateway.msg_dnd_registry TO msggateway_rw;
@@ -0,0 +1,29 @@
+-- msggateway.msg_error_sample definition
+
+-- Drop table
+
+-- DROP TABLE msggateway.msg_error_sample;
+
+CREATE TABLE msggateway.msg_error_sample (
+	sample_id bigserial NOT NULL,
+	error_id varchar NOT NULL,
+	route varchar NOT NULL,
+	method varchar NOT NULL,
+	status int4 NOT NULL,
+	correlation_id varchar NULL,
+	request_summary varchar NULL,
+	panicked bool DEFAULT false NOT NULL,
+	stack text NULL,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	CONSTRAINT msg_error_sample_pkey PRIMARY KEY (sample_id)
+);
+CREATE INDEX idx_msg_error_sample_created_at ON msggateway.msg_error_sample USING btree (created_at);
+CREATE INDEX idx_msg_error_sample_route_created_at ON msggateway.msg_error_sample USING btree (route, created_at);
+
+-- Permissions
+
+ALTER TABLE msggateway.msg_error_sample OWNER TO msggateway_admin;
+GRANT ALL ON TABLE msggateway.msg_error_sample TO msggateway_admin;
+GRANT SELECT ON TABLE msggateway.msg_error_sample TO msggateway_ro;
+GRANT INSERT, UPDATE, DELETE, SELECT ON TABLE msggateway.msg_error_sample TO msggateway_rw;
+GRANT ALL ON SEQUENCE msggateway.msg_error_sample_sample_id_seq TO msggateway_rw;
@@ -0,0 +1,46 @@
+-- msggateway.msg_gateway_code definition
+
+-- Drop table
+
+-- DROP TABLE msggateway.msg_gateway_code;
+
+CREATE TABLE msggateway.msg_gateway_code (
+	gateway varchar NOT NULL,
+	code varchar NOT NULL,
+	description varchar NOT NULL,
+	severity varchar NOT NULL,
+	recommended_action varchar NULL,
+	created_date timestamptz DEFAULT CURRENT_TIMESTAMP NULL,
+	updated_date timestamptz NULL,
+	CONSTRAINT msg_gateway_code_pkey PRIMARY KEY (gateway, code),
+	CONSTRAINT msg_gateway_code_severity_check CHECK (severity IN ('info', 'warning', 'error'))
+);
+
+-- Seed data, from the CDAC and NIC gateway documentation
+
+INSERT INTO msggateway.msg_gateway_code (gateway, code, description, severity, recommended_action) VALUES
+	('1', '402', 'Message submitted successfully', 'info', 'None'),
+	('1', '401', 'Credentials error, invalid username or password', 'error', 'Check sms.cdac.username and sms.cdac.password'),
+	('1', '403', 'Credits not available', 'error', 'Recharge the CDAC account'),
+	('1', '404', 'Internal database error at the gateway', 'error', 'Retry later, contact CDAC support if it persists'),
+	('1', '405', 'Internal networking error at the gateway', 'error', 'Retry later, contact CDAC support if it persists'),
+	('1', '406', 'Invalid or duplicate mobile numbers', 'warning', 'Check the recipient mobile numbers'),
+	('1', '407', 'Network error on the SMSC', 'error', 'Retry later'),
+	('1', '408', 'SMSC response timed out, the message will still be submitted', 'warning', 'None, check the delivery status later'),
+	('1', '409', 'Internal limit exceeded', 'error', 'Contact CDAC support'),
+	('1', '410', 'Sender ID not approved', 'error', 'Register the sender ID with CDAC'),
+	('1', '411', 'Sender ID not specified', 'error', 'Send the request with a sender ID'),
+	('1', '02', 'The gateway could not be reached', 'error', 'Check the connectivity to sms.cdac.url, the message can be resent'),
+	('1', '400', 'The gateway response could not be interpreted', 'error', 'Check the raw gateway response'),
+	('2', 'API000', 'Message submitted successfully', 'info', 'None'),
+	('2', 'ES-TM-BLOCKED', 'Message blocked by the telecom operator scrubbing (DLT)', 'error', 'Check the template and sender ID registration on the DLT platform'),
+	('2', '02', 'The gateway could not be reached', 'error', 'Check the connectivity to sms.nic.url, the message can be resent'),
+	('2', '400', 'The gateway response could not be interpreted', 'error', 'Check the raw gateway response')
+ON CONFLICT (gateway, code) DO NOTHING;
+
+-- Permissions
+
+ALTER TABLE msggateway.msg_gateway_code OWNER TO msggateway_admin;
+GRANT ALL ON TABLE msggateway.msg_gateway_code TO msggateway_admin;
+GRANT SELECT ON TABLE msggateway.msg_gateway_code TO msggateway_ro;
+GRANT INSERT, UPDATE, DELETE, SELECT ON TABLE msggateway.msg_gateway_code TO msggateway_rw;
@@ -8,7 +8,7 @@ CREATE TABLE msggateway.msg_log (
 	log_id int4 DEFAULT nextval('msggateway.pg_log_log_id_seq'::regclass) NOT NULL,
 	payload varchar NULL,
 	remarks varchar NULL,
-	created_date timestamp DEFAULT CURRENT_TIMESTAMP NULL,
+	created_date timestamptz DEFAULT CURRENT_TIMESTAMP NULL,
 	CONSTRAINT pg_log_pkey PRIMARY KEY (log_id)
 );
 
@@ -0,0 +1,29 @@
+-- msggateway.msg_otp definition
+
+-- Drop table
+
+-- DROP TABLE msggateway.msg_otp;
+
+CREATE TABLE msggateway.msg_otp (
+	otp_id bigserial NOT NULL,
+	otp_reference varchar(64) NOT NULL,
+	application_id varchar(20) NOT NULL,
+	mobile_number varchar(15) NOT NULL,
+	otp_hash varchar(100) NOT NULL,
+	attempts int4 DEFAULT 0 NOT NULL,
+	max_attempts int4 NOT NULL,
+	expires_at timestamptz NOT NULL,
+	verified_date timestamptz NULL,
+	created_date timestamptz DEFAULT CURRENT_TIMESTAMP NULL,
+	CONSTRAINT msg_otp_pkey PRIMARY KEY (otp_id),
+	CONSTRAINT msg_otp_reference_key UNIQUE (otp_reference)
+);
+CREATE INDEX msg_otp_mobile_created_idx ON msggateway.msg_otp USING btree (mobile_number, created_date);
+CREATE INDEX msg_otp_expires_at_idx ON msggateway.msg_otp USING btree (expires_at);
+
+-- Permissions
+
+ALTER TABLE msggateway.msg_otp OWNER TO msggateway_admin;
+GRANT ALL ON TABLE msggateway.msg_otp TO msggateway_admin;
+GRANT SELECT ON TABLE msggateway.msg_otp TO msggateway_ro;
+GRANT INSERT, UPDATE, DELETE, SELECT ON TABLE msggateway.msg_otp TO msggateway_rw;
@@ -0,0 +1,45 @@
+-- msggateway.msg_privacy_erasure definition
+
+-- Drop table
+
+-- DROP TABLE msggateway.msg_privacy_erasure_table;
+-- DROP TABLE msggateway.msg_privacy_erasure;
+
+-- Audit and progress of the erasures of the messages sent to a mobile number. The number is
+-- not stored, erasures are identified by the tombstone that replaces it in the erased rows.
+CREATE TABLE msggateway.msg_privacy_erasure (
+	erasure_id bigserial NOT NULL,
+	tombstone int8 NOT NULL,
+	from_date date NULL,
+	to_date date NULL,
+	requested_by varchar NOT NULL,
+	status varchar DEFAULT 'running' NOT NULL,
+	created_date timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	completed_date timestamptz NULL,
+	CONSTRAINT msg_privacy_erasure_pkey PRIMARY KEY (erasure_id),
+	CONSTRAINT msg_privacy_erasure_status_check CHECK (status IN ('running', 'completed'))
+);
+-- One running erasure per number and period, an interrupted erasure is resumed
+CREATE UNIQUE INDEX idx_msg_privacy_erasure_running ON msggateway.msg_privacy_erasure USING btree (tombstone, COALESCE(from_date, '-infinity'::date), COALESCE(to_date, 'infinity'::date)) WHERE ((status)::text = 'running'::text);
+
+CREATE TABLE msggateway.msg_privacy_erasure_table (
+	erasure_id int8 NOT NULL,
+	table_name varchar NOT NULL,
+	rows_affected int8 DEFAULT 0 NOT NULL,
+	completed bool DEFAULT false NOT NULL,
+	CONSTRAINT msg_privacy_erasure_table_pkey PRIMARY KEY (erasure_id, table_name),
+	CONSTRAINT msg_privacy_erasure_table_erasure_id_fkey FOREIGN KEY (erasure_id) REFERENCES msggateway.msg_privacy_erasure(erasure_id)
+);
+
+-- Permissions
+
+ALTER TABLE msggateway.msg_privacy_erasure OWNER TO msggateway_admin;
+GRANT ALL ON TABLE msggateway.msg_privacy_erasure TO msggateway_admin;
+GRANT SELECT ON TABLE msggateway.msg_privacy_erasure TO msggateway_ro;
+GRANT INSERT, UPDATE, DELETE, SELECT ON TABLE msggateway.msg_privacy_erasure TO msggateway_rw;
+GRANT ALL ON SEQUENCE msggateway.msg_privacy_erasure_erasure_id_seq TO msggateway_rw;
+
+ALTER TABLE msggateway.msg_privacy_erasure_table OWNER TO msggateway_admin;
+GRANT ALL ON TABLE msggateway.msg_privacy_erasure_table TO msggateway_admin;
+GRANT SELECT ON TABLE msggateway.msg_privacy_erasure_table TO msggateway_ro;
+GRANT INSERT, UPDATE, DELETE, SELECT ON TABLE msggateway.msg_privacy_erasure_table TO msggateway_rw;
@@ -0,0 +1,24 @@
+-- msggateway.msg_response definition
+
+-- Drop table
+
+-- DROP TABLE msggateway.msg_response;
+
+CREATE TABLE msggateway.msg_response (
+	response_id bigserial NOT NULL,
+	communication_id varchar NOT NULL,
+	reference_id varchar NULL,
+	response_code varchar NULL,
+	response_message varchar NULL,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	CONSTRAINT msg_response_pkey PRIMARY KEY (response_id)
+);
+CREATE INDEX idx_msg_response_communication_id_created_at ON msggateway.msg_response USING btree (communication_id, created_at DESC);
+
+-- Permissions
+
+ALTER TABLE msggateway.msg_response OWNER TO msggateway_admin;
+GRANT ALL ON TABLE msggateway.msg_response TO msggateway_admin;
+GRANT SELECT ON TABLE msggateway.msg_response TO msggateway_ro;
+GRANT INSERT, UPDATE, DELETE, SELECT ON TABLE msggateway.msg_response TO msggateway_rw;
+GRANT ALL ON SEQUENCE msggateway.msg_response_response_id_seq TO msggateway_rw;
@@ -0,0 +1,21 @@
+-- msggateway.msg_send_cap definition
+
+-- Drop table
+
+-- DROP TABLE msggateway.msg_send_cap;
+
+CREATE TABLE msggateway.msg_send_cap (
+	kind varchar NOT NULL,
+	cap_key varchar NOT NULL,
+	window_start timestamptz NOT NULL,
+	sent int8 DEFAULT 0 NOT NULL,
+	CONSTRAINT msg_send_cap_pkey PRIMARY KEY (kind, cap_key, window_start)
+);
+CREATE INDEX idx_msg_send_cap_window_start ON msggateway.msg_send_cap USING btree (window_start);
+
+-- Permissions
+
+ALTER TABLE msggateway.msg_send_cap OWNER TO msggateway_admin;
+GRANT ALL ON TABLE msggateway.msg_send_cap TO msggateway_admin;
+GRANT SELECT ON TABLE msggateway.msg_send_cap TO msggateway_ro;
+GRANT INSERT, UPDATE, DELETE, SELECT ON TABLE msggateway.msg_send_cap TO msggateway_rw;
@@ -0,0 +1,21 @@
+-- msggateway.msg_send_quota definition
+
+-- Drop table
+
+-- DROP TABLE msggateway.msg_send_quota;
+
+CREATE TABLE msggateway.msg_send_quota (
+	application_id varchar NOT NULL,
+	kind varchar NOT NULL,
+	day timestamptz NOT NULL,
+	sent int8 DEFAULT 0 NOT NULL,
+	CONSTRAINT msg_send_quota_pkey PRIMARY KEY (application_id, kind, day)
+);
+CREATE INDEX idx_msg_send_quota_day ON msggateway.msg_send_quota USING btree (day);
+
+-- Permissions
+
+ALTER TABLE msggateway.msg_send_quota OWNER TO msggateway_admin;
+GRANT ALL ON TABLE msggateway.msg_send_quota TO msggateway_admin;
+GRANT SELECT ON TABLE msggateway.msg_send_quota TO msggateway_ro;
+GRANT INSERT, UPDATE, DELETE, SELECT ON TABLE msggateway.msg_send_quota TO msggateway_rw;
@@ -0,0 +1,31 @@
+-- msggateway.msg_shadow_result definition
+
+-- Drop table
+
+-- DROP TABLE msggateway.msg_shadow_result;
+
+CREATE TABLE msggateway.msg_shadow_result (
+	shadow_id bigserial NOT NULL,
+	communication_id varchar NOT NULL,
+	application_id varchar NOT NULL,
+	template_id varchar NULL,
+	recipients int4 DEFAULT 0 NOT NULL,
+	primary_gateway varchar NOT NULL,
+	primary_response_code varchar NULL,
+	primary_accepted bool DEFAULT false NOT NULL,
+	shadow_gateway varchar NOT NULL,
+	shadow_response_code varchar NULL,
+	shadow_response_text varchar NULL,
+	shadow_accepted bool DEFAULT false NOT NULL,
+	created_date timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	CONSTRAINT msg_shadow_result_pkey PRIMARY KEY (shadow_id)
+);
+CREATE INDEX idx_msg_shadow_result_created_date ON msggateway.msg_shadow_result USING btree (created_date, application_id);
+
+-- Permissions
+
+ALTER TABLE msggateway.msg_shadow_result OWNER TO msggateway_admin;
+GRANT ALL ON TABLE msggateway.msg_shadow_result TO msggateway_admin;
+GRANT SELECT ON TABLE msggateway.msg_shadow_result TO msggateway_ro;
+GRANT INSERT, UPDATE, DELETE, SELECT ON TABLE msggateway.msg_shadow_result TO msggateway_rw;
+GRANT ALL ON SEQUENCE msggateway.msg_shadow_result_shadow_id_seq TO msggateway_rw;
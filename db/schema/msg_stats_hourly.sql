@@ -0,0 +1,25 @@
+-- msggateway.msg_stats_hourly definition
+
+-- Drop table
+
+-- DROP TABLE msggateway.msg_stats_hourly;
+
+CREATE TABLE msggateway.msg_stats_hourly (
+	application_id varchar NOT NULL,
+	gateway varchar NOT NULL,
+	priority int4 NOT NULL,
+	hour timestamptz NOT NULL,
+	sent int8 DEFAULT 0 NOT NULL,
+	failed int8 DEFAULT 0 NOT NULL,
+	delivered int8 DEFAULT 0 NOT NULL,
+	total_cost numeric(14, 4) DEFAULT 0 NOT NULL,
+	CONSTRAINT msg_stats_hourly_pkey PRIMARY KEY (application_id, gateway, priority, hour)
+);
+CREATE INDEX idx_msg_stats_hourly_hour ON msggateway.msg_stats_hourly USING btree (hour);
+
+-- Permissions
+
+ALTER TABLE msggateway.msg_stats_hourly OWNER TO msggateway_admin;
+GRANT ALL ON TABLE msggateway.msg_stats_hourly TO msggateway_admin;
+GRANT SELECT ON TABLE msggateway.msg_stats_hourly TO msggateway_ro;
+GRANT INSERT, UPDATE, DELETE, SELECT ON TABLE msggateway.msg_stats_hourly TO msggateway_rw;
@@ -14,7 +14,7 @@ CREATE TABLE msggateway.msg_template (
 	template_id varchar NULL,
 	gateway varchar NULL,
 	status_cd int4 NULL,
-	created_date timestamp DEFAULT CURRENT_TIMESTAMP NULL,
+	created_date timestamptz DEFAULT CURRENT_TIMESTAMP NULL,
 	message_type varchar(2) NULL,
 	CONSTRAINT mg_templates_pkey PRIMARY KEY (template_local_id)
 );
@@ -0,0 +1,24 @@
+-- msggateway.msg_template_history definition
+
+-- Drop table
+
+-- DROP TABLE msggateway.msg_template_history;
+
+CREATE TABLE msggateway.msg_template_history (
+	history_id bigserial NOT NULL,
+	template_local_id int4 NOT NULL,
+	"action" varchar NOT NULL,
+	status_cd int4 NULL,
+	correlation_id varchar NULL,
+	changed_date timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	CONSTRAINT msg_template_history_pkey PRIMARY KEY (history_id)
+);
+CREATE INDEX idx_msg_template_history_template_local_id ON msggateway.msg_template_history USING btree (template_local_id, changed_date);
+
+-- Permissions
+
+ALTER TABLE msggateway.msg_template_history OWNER TO msggateway_admin;
+GRANT ALL ON TABLE msggateway.msg_template_history TO msggateway_admin;
+GRANT SELECT ON TABLE msggateway.msg_template_history TO msggateway_ro;
+GRANT INSERT, SELECT ON TABLE msggateway.msg_template_history TO msggateway_rw;
+GRANT ALL ON SEQUENCE msggateway.msg_template_history_history_id_seq TO msggateway_rw;
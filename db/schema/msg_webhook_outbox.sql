@@ -0,0 +1,25 @@
+-- msggateway.msg_webhook_outbox definition
+
+-- Drop table
+
+-- DROP TABLE msggateway.msg_webhook_outbox;
+
+CREATE TABLE msggateway.msg_webhook_outbox (
+	outbox_id bigserial NOT NULL,
+	communication_id varchar NOT NULL,
+	payload jsonb NOT NULL,
+	attempts int4 DEFAULT 0 NOT NULL,
+	next_attempt_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	last_error varchar NULL,
+	created_date timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	CONSTRAINT msg_webhook_outbox_pkey PRIMARY KEY (outbox_id)
+);
+CREATE INDEX idx_msg_webhook_outbox_next_attempt_at ON msggateway.msg_webhook_outbox USING btree (next_attempt_at);
+
+-- Permissions
+
+ALTER TABLE msggateway.msg_webhook_outbox OWNER TO msggateway_admin;
+GRANT ALL ON TABLE msggateway.msg_webhook_outbox TO msggateway_admin;
+GRANT SELECT ON TABLE msggateway.msg_webhook_outbox TO msggateway_ro;
+GRANT INSERT, UPDATE, DELETE, SELECT ON TABLE msggateway.msg_webhook_outbox TO msggateway_rw;
+GRANT ALL ON SEQUENCE msggateway.msg_webhook_outbox_outbox_id_seq TO msggateway_rw;
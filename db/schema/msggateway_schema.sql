@@ -131,8 +131,8 @@ CREATE TABLE msggateway.msg_application (
 	application_name varchar NULL,
 	request_type varchar NULL,
 	secret_key varchar NULL,
-	created_date timestamp DEFAULT CURRENT_TIMESTAMP NULL,
-	updated_date timestamp DEFAULT CURRENT_TIMESTAMP NULL,
+	created_date timestamptz DEFAULT CURRENT_TIMESTAMP NULL,
+	updated_date timestamptz DEFAULT CURRENT_TIMESTAMP NULL,
 	status_cd int4 NULL,
 	CONSTRAINT pg_applications_pkey_new PRIMARY KEY (application_id)
 );
@@ -172,10 +172,18 @@ CREATE TABLE msggateway.msg_bulk_file (
 	no_of_sms_pending int8 NULL,
 	scheduled varchar NULL,
 	status_cd int4 DEFAULT 0 NULL,
-	uploaded_time timestamp DEFAULT CURRENT_TIMESTAMP NULL,
-	updated_time timestamp NULL,
+	uploaded_time timestamptz DEFAULT CURRENT_TIMESTAMP NULL,
+	updated_time timestamptz NULL,
 	mobile_number _int8 NULL,
 	message_type varchar(2) NULL,
+	rows_accepted int8 DEFAULT 0 NOT NULL,
+	rows_rejected int8 DEFAULT 0 NOT NULL,
+	rows_duplicate int8 DEFAULT 0 NOT NULL,
+	upload_token varchar(32) NULL,
+	upload_offset int8 DEFAULT 0 NOT NULL,
+	upload_rows int8 DEFAULT 0 NOT NULL,
+	upload_completed bool DEFAULT false NOT NULL,
+	upload_rejects jsonb NULL,
 	CONSTRAINT msg_bulk_files_file_id PRIMARY KEY (file_id)
 );
 CREATE INDEX idx_msg_bulk_file_reference_id ON msggateway.msg_bulk_file USING btree (reference_id);
@@ -197,7 +205,7 @@ GRANT INSERT, UPDATE, DELETE, SELECT ON TABLE msggateway.msg_bulk_file TO msggat
 
 CREATE TABLE msggateway.msg_counter (
 	counter_id int4 DEFAULT nextval('msggateway.msg_counter_counterid_seq'::regclass) NOT NULL,
-	request_date timestamp NULL,
+	request_date timestamptz NULL,
 	gateway varchar NULL,
 	count int4 NULL,
 	CONSTRAINT msg_counter_key PRIMARY KEY (counter_id)
@@ -221,7 +229,7 @@ CREATE TABLE msggateway.msg_log (
 	log_id int4 DEFAULT nextval('msggateway.pg_log_log_id_seq'::regclass) NOT NULL,
 	payload varchar NULL,
 	remarks varchar NULL,
-	created_date timestamp DEFAULT CURRENT_TIMESTAMP NULL,
+	created_date timestamptz DEFAULT CURRENT_TIMESTAMP NULL,
 	CONSTRAINT pg_log_pkey PRIMARY KEY (log_id)
 );
 
@@ -275,7 +283,7 @@ GRANT INSERT, UPDATE, DELETE, SELECT ON TABLE msggateway.msg_provider TO msggate
 CREATE TABLE msggateway.msg_request (
 	request_id int4 DEFAULT nextval('msggateway.msg_request_req_id_seq'::regclass) NOT NULL,
 	application_id varchar NULL,
-	communication_id bpchar(20) DEFAULT msggateway.generate_random_string(20) NULL,
+	communication_id varchar(64) DEFAULT msggateway.generate_random_string(20) NULL,
 	facility_id varchar(13) NULL,
 	priority int4 NULL,
 	message_text varchar NULL,
@@ -289,12 +297,18 @@ CREATE TABLE msggateway.msg_request (
 	response_code varchar NULL,
 	response_message varchar NULL,
 	complete_response varchar NULL,
-	created_date timestamp DEFAULT CURRENT_TIMESTAMP NULL,
-	updated_date timestamp NULL,
+	created_date timestamptz DEFAULT CURRENT_TIMESTAMP NULL,
+	updated_date timestamptz NULL,
 	mobile_number _int8 NULL,
+	status_polled_at timestamptz NULL,
+	client_reference varchar(64) NULL,
+	metadata jsonb NULL,
+	transformations _text NULL,
 	CONSTRAINT msg_indent_pkey_new PRIMARY KEY (request_id)
 );
-CREATE INDEX idx_msg_request_communication_id ON msggateway.msg_request USING btree (communication_id);
+CREATE UNIQUE INDEX idx_msg_request_communication_id ON msggateway.msg_request USING btree (communication_id);
+CREATE INDEX idx_msg_request_status_poll ON msggateway.msg_request USING btree (status_polled_at NULLS FIRST, request_id) WHERE ((status)::text = 'submitted'::text);
+CREATE INDEX idx_msg_request_client_reference ON msggateway.msg_request USING btree (client_reference) WHERE (client_reference IS NOT NULL);
 CREATE INDEX idx_msg_request_created_date ON msggateway.msg_request USING btree (created_date);
 CREATE INDEX idx_msg_request_req_id ON msggateway.msg_request USING btree (request_id);
 
@@ -342,7 +356,7 @@ CREATE TABLE msggateway.msg_template (
 	template_id varchar NULL,
 	gateway varchar NULL,
 	status_cd int4 NULL,
-	created_date timestamp DEFAULT CURRENT_TIMESTAMP NULL,
+	created_date timestamptz DEFAULT CURRENT_TIMESTAMP NULL,
 	message_type varchar(2) NULL,
 	CONSTRAINT mg_templates_pkey PRIMARY KEY (template_local_id)
 );
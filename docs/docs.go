@@ -47,6 +47,8 @@ const docTemplate = `{
                         "required": true
                     },
                     {
+                        "maximum": 1000,
+                        "minimum": 0,
                         "type": "integer",
                         "name": "limit",
                         "in": "query"
@@ -64,6 +66,7 @@ const docTemplate = `{
                         "required": true
                     },
                     {
+                        "minimum": 0,
                         "type": "integer",
                         "name": "skip",
                         "in": "query"
@@ -158,6 +161,8 @@ const docTemplate = `{
                 "operationId": "ListMessageApplicationsHandler",
                 "parameters": [
                     {
+                        "maximum": 1000,
+                        "minimum": 0,
                         "type": "integer",
                         "name": "limit",
                         "in": "query"
@@ -168,6 +173,7 @@ const docTemplate = `{
                         "in": "query"
                     },
                     {
+                        "minimum": 0,
                         "type": "integer",
                         "name": "skip",
                         "in": "query"
@@ -356,6 +362,12 @@ const docTemplate = `{
                         "name": "application-id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previously fetched Message Application",
+                        "name": "If-None-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -363,8 +375,17 @@ const docTemplate = `{
                         "description": "Message Application is retrieved",
                         "schema": {
                             "$ref": "#/definitions/response.FetchMsgApplicationAPIResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Entity tag of the Message Application"
+                            }
                         }
                     },
+                    "304": {
+                        "description": "Message Application is unchanged"
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
@@ -605,7 +626,7 @@ const docTemplate = `{
         },
         "/bulk-sms": {
             "post": {
-                "description": "This API reads the output Excel file, constructs messages, and sends them to the NIC service in XML format.",
+                "description": "This API reads the output Excel file, constructs messages, and sends them to the NIC service in XML format. With the DND check enabled, numbers registered as DND are not sent to and are reported with status SKIPPED_DND.",
                 "consumes": [
                     "application/json"
                 ],
@@ -878,6 +899,178 @@ const docTemplate = `{
                 }
             }
         },
+        "/otp/generate": {
+            "post": {
+                "description": "Generates a numeric OTP, renders it into the configured OTP template and sends it through the OTP (priority 1) path. Only the otp_reference is returned, never the OTP itself",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "OTP"
+                ],
+                "summary": "Generates and sends an OTP",
+                "operationId": "GenerateOTPHandler",
+                "parameters": [
+                    {
+                        "description": "Generate OTP Request",
+                        "name": "generateOTPRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.generateOTPRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "OTP is generated and sent",
+                        "schema": {
+                            "$ref": "#/definitions/response.GenerateOTPAPIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Application is not mapped to the OTP template",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "OTP template not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "An identical OTP request was sent moments ago",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Binding or Validation error",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "RATE_LIMITED: too many OTPs generated for the mobile number",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/otp/verify": {
+            "post": {
+                "description": "Verifies the supplied OTP against the otp_reference returned by generate. Each call consumes an attempt; a verified OTP cannot be reused",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "OTP"
+                ],
+                "summary": "Verifies an OTP",
+                "operationId": "VerifyOTPHandler",
+                "parameters": [
+                    {
+                        "description": "Verify OTP Request",
+                        "name": "verifyOTPRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.verifyOTPRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OTP is verified",
+                        "schema": {
+                            "$ref": "#/definitions/response.VerifyOTPAPIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "MISMATCH: OTP does not match",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "OTP reference not found or already verified",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "EXPIRED: OTP has expired",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Binding or Validation error",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "TOO_MANY_ATTEMPTS: verification attempts exhausted",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/sms-dashboard": {
             "get": {
                 "description": "Fetches SMS Dashboard data",
@@ -966,6 +1159,8 @@ const docTemplate = `{
                 "operationId": "ListMessageProvidersHandler",
                 "parameters": [
                     {
+                        "maximum": 1000,
+                        "minimum": 0,
                         "type": "integer",
                         "name": "limit",
                         "in": "query"
@@ -976,6 +1171,7 @@ const docTemplate = `{
                         "in": "query"
                     },
                     {
+                        "minimum": 0,
                         "type": "integer",
                         "name": "skip",
                         "in": "query"
@@ -1507,7 +1703,8 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/x-ndjson"
                 ],
                 "tags": [
                     "Reports"
@@ -1515,6 +1712,24 @@ const docTemplate = `{
                 "summary": "Get all SMS requests",
                 "operationId": "SentSMSStatusReportHandler",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "maxLength": 64,
+                        "example": "BKG20240001",
+                        "name": "client-reference",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "example": "json",
+                        "description": "Format ndjson answers the whole report, unpaginated, one JSON object per line written as it is read",
+                        "name": "format",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "example": "01-01-2008",
@@ -1523,6 +1738,8 @@ const docTemplate = `{
                         "required": true
                     },
                     {
+                        "maximum": 1000,
+                        "minimum": 0,
                         "type": "integer",
                         "name": "limit",
                         "in": "query"
@@ -1533,6 +1750,7 @@ const docTemplate = `{
                         "in": "query"
                     },
                     {
+                        "minimum": 0,
                         "type": "integer",
                         "name": "skip",
                         "in": "query"
@@ -1616,7 +1834,7 @@ const docTemplate = `{
         },
         "/sms-templates": {
             "get": {
-                "description": "Lists all message templates",
+                "description": "Lists the templates matching all the filters given, ordered by orderBy (template_local_id, template_name, sender_id, template_id, message_type, gateway or status) in the sortType (asc or desc) order.\nThe facets count the matching templates by status and by gateway id, facets=false skips the count.\nWith stream=true the whole list, ignoring skip and limit, is answered as a bare JSON array of templates written as it is read. An error after the first template ends the array with an {\"error\": \"...\"} element and sets the X-Stream-Status trailer to 500.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1630,16 +1848,60 @@ const docTemplate = `{
                 "operationId": "ListTemplatesHandler",
                 "parameters": [
                     {
+                        "type": "string",
+                        "name": "application_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": true,
+                        "description": "Facets counts the matching templates by status and gateway, false skips the count",
+                        "name": "facets",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "1",
+                            "2"
+                        ],
+                        "type": "string",
+                        "name": "gateway",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 1000,
+                        "minimum": 0,
                         "type": "integer",
                         "name": "limit",
                         "in": "query"
                     },
+                    {
+                        "enum": [
+                            "PM",
+                            "UC"
+                        ],
+                        "type": "string",
+                        "name": "message_type",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "name": "orderBy",
                         "in": "query"
                     },
                     {
+                        "type": "string",
+                        "description": "Search matches a substring of the template name or format, case insensitive",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "sender_id",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
                         "type": "integer",
                         "name": "skip",
                         "in": "query"
@@ -1648,6 +1910,21 @@ const docTemplate = `{
                         "type": "string",
                         "name": "sortType",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "0",
+                            "1"
+                        ],
+                        "type": "string",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Stream answers the whole list, unpaginated, as a bare JSON array written as it is read",
+                        "name": "stream",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -1801,9 +2078,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/sms-templates/details": {
+        "/sms-templates/by-template-id/{template-id}": {
             "get": {
-                "description": "Fetch template details based on the provided query parameters such as TemplateLocalID, ApplicationID, and Templateformat.",
+                "description": "Fetches Message Template by the DLT TemplateID, for senders that only know the DLT id",
                 "consumes": [
                     "application/json"
                 ],
@@ -1813,35 +2090,39 @@ const docTemplate = `{
                 "tags": [
                     "Templates"
                 ],
-                "summary": "Get Template Details",
-                "operationId": "FetchTemplateDetailsHandler",
+                "summary": "Get Message Template by TemplateID",
+                "operationId": "FetchTemplateByTemplateIDHandler",
                 "parameters": [
                     {
                         "type": "string",
-                        "example": "4",
-                        "name": "application-id",
-                        "in": "query"
+                        "example": "1007188452935484904",
+                        "name": "template-id",
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "example": "Dear {#var#}, your {#var#} is scheduled on {#var#}. Pl check details over {#var#} - INDPOST",
-                        "name": "template-format",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "example": 355,
-                        "name": "template-local-id",
-                        "in": "query"
+                        "description": "ETag of a previously fetched Message Template",
+                        "name": "If-None-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Fetched successfully",
+                        "description": "Message Template is retrieved by TemplateID",
                         "schema": {
-                            "$ref": "#/definitions/response.FetchTemplateDetailsAPIResponse"
+                            "$ref": "#/definitions/response.FetchTemplateAPIResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Entity tag of the Message Template"
+                            }
                         }
                     },
+                    "304": {
+                        "description": "Message Template is unchanged"
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
@@ -1866,12 +2147,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/apierrors.APIErrorResponse"
                         }
                     },
-                    "409": {
-                        "description": "Data conflict errpr",
-                        "schema": {
-                            "$ref": "#/definitions/apierrors.APIErrorResponse"
-                        }
-                    },
                     "422": {
                         "description": "Binding or Validation error",
                         "schema": {
@@ -1899,9 +2174,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/sms-templates/name": {
+        "/sms-templates/details": {
             "get": {
-                "description": "Fetches Message Template names by ApplicationID",
+                "description": "Fetch template details based on the provided query parameters such as TemplateLocalID, ApplicationID, and Templateformat.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1911,22 +2186,33 @@ const docTemplate = `{
                 "tags": [
                     "Templates"
                 ],
-                "summary": "Get Message Template names by ApplicationID",
-                "operationId": "FetchTemplateByApplicationHandler",
+                "summary": "Get Template Details",
+                "operationId": "FetchTemplateDetailsHandler",
                 "parameters": [
                     {
                         "type": "string",
                         "example": "4",
                         "name": "application-id",
-                        "in": "query",
-                        "required": true
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Dear {#var#}, your {#var#} is scheduled on {#var#}. Pl check details over {#var#} - INDPOST",
+                        "name": "template-format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 355,
+                        "name": "template-local-id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Message Template names are retrieved by ApplicationID",
+                        "description": "Fetched successfully",
                         "schema": {
-                            "$ref": "#/definitions/response.FetchTemplateNameAPIResponse"
+                            "$ref": "#/definitions/response.FetchTemplateDetailsAPIResponse"
                         }
                     },
                     "400": {
@@ -1986,9 +2272,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/sms-templates/{template-local-id}": {
+        "/sms-templates/name": {
             "get": {
-                "description": "Fetches Message Template by TemplateLocalID",
+                "description": "Fetches the Message Templates of an ApplicationID with their names, template ids, gateway and message type",
                 "consumes": [
                     "application/json"
                 ],
@@ -1998,24 +2284,126 @@ const docTemplate = `{
                 "tags": [
                     "Templates"
                 ],
-                "summary": "Get Message Template by TemplateLocalID",
-                "operationId": "FetchTemplateHandler",
+                "summary": "Get Message Template names by ApplicationID",
+                "operationId": "FetchTemplateByApplicationHandler",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "example": 355,
-                        "name": "template-local-id",
-                        "in": "path",
-                        "required": true
-                    }
+                        "type": "string",
+                        "example": "4",
+                        "name": "application-id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message Template names are retrieved by ApplicationID",
+                        "schema": {
+                            "$ref": "#/definitions/response.FetchTemplateNameAPIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Data not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Data conflict errpr",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Binding or Validation error",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sms-templates/{template-local-id}": {
+            "get": {
+                "description": "Fetches Message Template by TemplateLocalID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Templates"
+                ],
+                "summary": "Get Message Template by TemplateLocalID",
+                "operationId": "FetchTemplateHandler",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 355,
+                        "name": "template-local-id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previously fetched Message Template",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
                 ],
                 "responses": {
                     "200": {
                         "description": "Message Template is retrieved by TemplateLocalID",
                         "schema": {
                             "$ref": "#/definitions/response.FetchTemplateAPIResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Entity tag of the Message Template"
+                            }
                         }
                     },
+                    "304": {
+                        "description": "Message Template is unchanged"
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
@@ -2167,6 +2555,102 @@ const docTemplate = `{
                 }
             }
         },
+        "/sms-templates/{template-local-id}/preview": {
+            "post": {
+                "description": "Renders the template_format of a Message Template with sample values for its {#var#} placeholders, checks the branding of the sender and returns the message with its encoding and segment count. Nothing is sent.\nWhen the values do not match the placeholders, allowFallback=true returns the fallback message configured for the application of the template, with a warning, instead of a 422. Applications without a fallback message still get the 422",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Templates"
+                ],
+                "summary": "Previews a Message Template with sample values",
+                "operationId": "PreviewTemplateHandler",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Preview Message Template Request",
+                        "name": "template-local-id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the fallback message of the application when the values do not match the placeholders",
+                        "name": "allowFallback",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Preview Message Template Request",
+                        "name": "previewTemplateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.previewTemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message Template is rendered",
+                        "schema": {
+                            "$ref": "#/definitions/response.PreviewTemplateAPIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Data not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Binding or Validation error, or the values do not match the placeholders",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/apierrors.APIErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/sms-templates/{template-local-id}/status": {
             "put": {
                 "description": "Modifies the status of Message Template",
@@ -2394,9 +2878,23 @@ const docTemplate = `{
                 "message": {
                     "type": "string"
                 },
+                "tag": {
+                    "type": "string"
+                },
                 "value": {}
             }
         },
+        "dlt.Violation": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "rule": {
+                    "type": "string"
+                }
+            }
+        },
         "handler.createMessageApplicationRequest": {
             "type": "object",
             "required": [
@@ -2406,7 +2904,8 @@ const docTemplate = `{
             ],
             "properties": {
                 "application_id": {
-                    "type": "integer"
+                    "type": "string",
+                    "pattern": "^[0-9]+$"
                 },
                 "application_name": {
                     "type": "string",
@@ -2469,10 +2968,17 @@ const docTemplate = `{
             "properties": {
                 "application_id": {
                     "type": "string",
+                    "pattern": "^[0-9]+$",
                     "example": "4"
                 },
+                "client_reference": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "BKG20240001"
+                },
                 "entity_id": {
                     "type": "string",
+                    "pattern": "^[0-9]+$",
                     "example": "1301157641566214705"
                 },
                 "facility_id": {
@@ -2487,6 +2993,12 @@ const docTemplate = `{
                     "type": "string",
                     "example": "PM"
                 },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
                 "mobile_numbers": {
                     "type": "string",
                     "example": "9000000000"
@@ -2504,6 +3016,7 @@ const docTemplate = `{
                 },
                 "template_id": {
                     "type": "string",
+                    "pattern": "^[0-9]+$",
                     "example": "1307160377410448739"
                 }
             }
@@ -2523,10 +3036,12 @@ const docTemplate = `{
             "properties": {
                 "application_id": {
                     "type": "string",
+                    "pattern": "^[0-9]+$",
                     "example": "4"
                 },
                 "entity_id": {
                     "type": "string",
+                    "pattern": "^[0-9]+$",
                     "example": "1001051725995192803"
                 },
                 "gateway": {
@@ -2551,10 +3066,12 @@ const docTemplate = `{
                 },
                 "template_id": {
                     "type": "string",
+                    "pattern": "^[0-9]+$",
                     "example": "1007188452935484904"
                 },
                 "template_local_id": {
-                    "type": "integer"
+                    "type": "string",
+                    "pattern": "^[0-9]+$"
                 },
                 "template_name": {
                     "type": "string",
@@ -2574,6 +3091,37 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.generateOTPRequest": {
+            "type": "object",
+            "required": [
+                "application_id",
+                "facility_id",
+                "mobile_number"
+            ],
+            "properties": {
+                "application_id": {
+                    "type": "string",
+                    "example": "4"
+                },
+                "facility_id": {
+                    "type": "string",
+                    "example": "facility1"
+                },
+                "mobile_number": {
+                    "type": "string",
+                    "example": "9000000000"
+                },
+                "template_values": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Postal GIS Application"
+                    ]
+                }
+            }
+        },
         "handler.initiateBulkSMSRequest": {
             "type": "object",
             "required": [
@@ -2622,6 +3170,21 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.previewTemplateRequest": {
+            "type": "object",
+            "properties": {
+                "values": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "1234",
+                        "XXXX1234"
+                    ]
+                }
+            }
+        },
         "handler.sendBulkSMSRequest": {
             "type": "object",
             "required": [
@@ -2718,10 +3281,12 @@ const docTemplate = `{
             "properties": {
                 "application_id": {
                     "type": "string",
+                    "pattern": "^[0-9]+$",
                     "example": "4"
                 },
                 "entity_id": {
-                    "type": "string"
+                    "type": "string",
+                    "pattern": "^[0-9]+$"
                 },
                 "gateway": {
                     "type": "string",
@@ -2745,6 +3310,7 @@ const docTemplate = `{
                 },
                 "template_id": {
                     "type": "string",
+                    "pattern": "^[0-9]+$",
                     "example": "1007002656392643880"
                 },
                 "template_name": {
@@ -2753,9 +3319,39 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.verifyOTPRequest": {
+            "type": "object",
+            "required": [
+                "application_id",
+                "mobile_number",
+                "otp",
+                "otp_reference"
+            ],
+            "properties": {
+                "application_id": {
+                    "type": "string",
+                    "example": "4"
+                },
+                "mobile_number": {
+                    "type": "string",
+                    "example": "9000000000"
+                },
+                "otp": {
+                    "type": "string",
+                    "example": "482913"
+                },
+                "otp_reference": {
+                    "type": "string",
+                    "example": "5b0f4c3e-8f5e-4a3c-9a59-2d3c1f1a7b10"
+                }
+            }
+        },
         "response.AggregateSMSReportAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "type": "array",
                     "items": {
@@ -2786,6 +3382,9 @@ const docTemplate = `{
                 "success": {
                     "type": "boolean"
                 },
+                "timestamp": {
+                    "type": "string"
+                },
                 "total_records_count": {
                     "type": "integer"
                 }
@@ -2794,6 +3393,9 @@ const docTemplate = `{
         "response.BulkSMSInitiateAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "$ref": "#/definitions/response.bulkSMSInitiateResponse"
                 },
@@ -2805,12 +3407,18 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
                 }
             }
         },
         "response.CreateMsgApplicationAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "$ref": "#/definitions/response.createMsgApplicationResponse"
                 },
@@ -2822,12 +3430,18 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
                 }
             }
         },
         "response.CreateSMSAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "$ref": "#/definitions/response.createSMSResponse"
                 },
@@ -2839,12 +3453,18 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
                 }
             }
         },
         "response.CreateSMSProviderAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "$ref": "#/definitions/response.createSMSProviderResponse"
                 },
@@ -2856,12 +3476,21 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
                 }
             }
         },
         "response.CreateTemplateAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
+                "data": {
+                    "$ref": "#/definitions/response.TemplateLintResponse"
+                },
                 "message": {
                     "type": "string"
                 },
@@ -2870,12 +3499,18 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
                 }
             }
         },
         "response.FetchMsgApplicationAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "type": "array",
                     "items": {
@@ -2906,6 +3541,9 @@ const docTemplate = `{
                 "success": {
                     "type": "boolean"
                 },
+                "timestamp": {
+                    "type": "string"
+                },
                 "total_records_count": {
                     "type": "integer"
                 }
@@ -2914,6 +3552,9 @@ const docTemplate = `{
         "response.FetchSMSProviderAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "type": "array",
                     "items": {
@@ -2944,6 +3585,9 @@ const docTemplate = `{
                 "success": {
                     "type": "boolean"
                 },
+                "timestamp": {
+                    "type": "string"
+                },
                 "total_records_count": {
                     "type": "integer"
                 }
@@ -2952,6 +3596,9 @@ const docTemplate = `{
         "response.FetchTemplateAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "description": "MetaDataResponse     ` + "`" + `json:\",inline\"` + "`" + `",
                     "type": "array",
@@ -2967,17 +3614,50 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "response.FetchTemplateDetailsAPIResponse": {
+            "type": "object",
+            "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
+                "data": {
+                    "description": "MetaDataResponse     ` + "`" + `json:\",inline\"` + "`" + `",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/response.fetchTemplateDetailsResponse"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "integer"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
                 }
             }
         },
-        "response.FetchTemplateDetailsAPIResponse": {
+        "response.FetchTemplateNameAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "description": "MetaDataResponse     ` + "`" + `json:\",inline\"` + "`" + `",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/response.fetchTemplateDetailsResponse"
+                        "$ref": "#/definitions/response.fetchTemplateNameResponse"
                     }
                 },
                 "message": {
@@ -2988,18 +3668,20 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
                 }
             }
         },
-        "response.FetchTemplateNameAPIResponse": {
+        "response.GenerateOTPAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
-                    "description": "MetaDataResponse     ` + "`" + `json:\",inline\"` + "`" + `",
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/response.fetchTemplateNameResponse"
-                    }
+                    "$ref": "#/definitions/response.generateOTPResponse"
                 },
                 "message": {
                     "type": "string"
@@ -3009,12 +3691,18 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
                 }
             }
         },
         "response.ListMsgApplicationsAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "type": "array",
                     "items": {
@@ -3045,6 +3733,9 @@ const docTemplate = `{
                 "success": {
                     "type": "boolean"
                 },
+                "timestamp": {
+                    "type": "string"
+                },
                 "total_records_count": {
                     "type": "integer"
                 }
@@ -3053,6 +3744,9 @@ const docTemplate = `{
         "response.ListSMSProvidersAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "type": "array",
                     "items": {
@@ -3083,6 +3777,9 @@ const docTemplate = `{
                 "success": {
                     "type": "boolean"
                 },
+                "timestamp": {
+                    "type": "string"
+                },
                 "total_records_count": {
                     "type": "integer"
                 }
@@ -3091,12 +3788,18 @@ const docTemplate = `{
         "response.ListTemplatesAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "type": "array",
                     "items": {
                         "$ref": "#/definitions/response.listTemplatesResponse"
                     }
                 },
+                "facets": {
+                    "$ref": "#/definitions/response.templateFacetsResponse"
+                },
                 "limit": {
                     "type": "integer"
                 },
@@ -3121,14 +3824,83 @@ const docTemplate = `{
                 "success": {
                     "type": "boolean"
                 },
+                "timestamp": {
+                    "type": "string"
+                },
                 "total_records_count": {
                     "type": "integer"
                 }
             }
         },
+        "response.PreviewTemplateAPIResponse": {
+            "type": "object",
+            "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
+                "data": {
+                    "$ref": "#/definitions/response.PreviewTemplateResponse"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "integer"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "response.PreviewTemplateResponse": {
+            "type": "object",
+            "properties": {
+                "branding_appended": {
+                    "type": "boolean"
+                },
+                "encoding": {
+                    "type": "string",
+                    "enum": [
+                        "PM",
+                        "UC"
+                    ]
+                },
+                "fallback": {
+                    "type": "boolean"
+                },
+                "length": {
+                    "type": "integer"
+                },
+                "message_type": {
+                    "type": "string"
+                },
+                "rendered_message": {
+                    "type": "string"
+                },
+                "segment_count": {
+                    "type": "integer"
+                },
+                "template_local_id": {
+                    "type": "string",
+                    "pattern": "^[0-9]+$"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "response.SMSDashboardAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "$ref": "#/definitions/response.smsDashboardResponse"
                 },
@@ -3140,12 +3912,18 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
                 }
             }
         },
         "response.SMSSentStatusReportAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "type": "array",
                     "items": {
@@ -3176,6 +3954,9 @@ const docTemplate = `{
                 "success": {
                     "type": "boolean"
                 },
+                "timestamp": {
+                    "type": "string"
+                },
                 "total_records_count": {
                     "type": "integer"
                 }
@@ -3184,6 +3965,9 @@ const docTemplate = `{
         "response.SendBulkSMSAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "$ref": "#/definitions/response.sendBulkSMSResponse"
                 },
@@ -3195,6 +3979,26 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "response.TemplateLintResponse": {
+            "type": "object",
+            "properties": {
+                "estimated_length": {
+                    "type": "integer"
+                },
+                "length_limit": {
+                    "type": "integer"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dlt.Violation"
+                    }
                 }
             }
         },
@@ -3219,6 +4023,9 @@ const docTemplate = `{
         "response.ToggleAppStatusAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {},
                 "message": {
                     "type": "string"
@@ -3228,12 +4035,18 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
                 }
             }
         },
         "response.ToggleProviderStatusAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {},
                 "message": {
                     "type": "string"
@@ -3243,12 +4056,18 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
                 }
             }
         },
         "response.ToggleTemplateStatusAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {},
                 "message": {
                     "type": "string"
@@ -3258,12 +4077,18 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
                 }
             }
         },
         "response.UpdateMsgApplicationAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "$ref": "#/definitions/response.updateMsgApplicationResponse"
                 },
@@ -3275,12 +4100,18 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
                 }
             }
         },
         "response.UpdateSMSProviderAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "$ref": "#/definitions/response.updateSMSProviderResponse"
                 },
@@ -3292,12 +4123,21 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
                 }
             }
         },
         "response.UpdateTemplatesAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
+                "data": {
+                    "$ref": "#/definitions/response.TemplateLintResponse"
+                },
                 "message": {
                     "type": "string"
                 },
@@ -3306,12 +4146,18 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
                 }
             }
         },
         "response.ValidateBulkSMSOTPAPIResponse": {
             "type": "object",
             "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
                 "data": {
                     "type": "boolean"
                 },
@@ -3323,6 +4169,32 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "response.VerifyOTPAPIResponse": {
+            "type": "object",
+            "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
+                "data": {
+                    "$ref": "#/definitions/response.verifyOTPResponse"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "integer"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
                 }
             }
         },
@@ -3422,12 +4294,24 @@ const docTemplate = `{
         "response.createSMSResponse": {
             "type": "object",
             "properties": {
+                "cache_hit": {
+                    "type": "boolean"
+                },
+                "client_reference": {
+                    "type": "string"
+                },
                 "communication_id": {
                     "type": "string"
                 },
                 "complete_response": {
                     "type": "string"
                 },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
                 "referenceID": {
                     "type": "string"
                 },
@@ -3443,7 +4327,8 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "application_id": {
-                    "type": "integer"
+                    "type": "string",
+                    "pattern": "^[0-9]+$"
                 },
                 "application_name": {
                     "type": "string"
@@ -3502,7 +4387,8 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "template_local_id": {
-                    "type": "integer"
+                    "type": "string",
+                    "pattern": "^[0-9]+$"
                 },
                 "template_name": {
                     "type": "string"
@@ -3512,8 +4398,18 @@ const docTemplate = `{
         "response.fetchTemplateNameResponse": {
             "type": "object",
             "properties": {
+                "gateway": {
+                    "type": "string"
+                },
+                "message_type": {
+                    "type": "string"
+                },
+                "template_id": {
+                    "type": "string"
+                },
                 "template_local_id": {
-                    "type": "integer"
+                    "type": "string",
+                    "pattern": "^[0-9]+$"
                 },
                 "template_name": {
                     "type": "string"
@@ -3548,7 +4444,8 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "template_local_id": {
-                    "type": "integer"
+                    "type": "string",
+                    "pattern": "^[0-9]+$"
                 },
                 "template_name": {
                     "type": "string"
@@ -3558,11 +4455,35 @@ const docTemplate = `{
                 }
             }
         },
+        "response.generateOTPResponse": {
+            "type": "object",
+            "properties": {
+                "cache_hit": {
+                    "type": "boolean"
+                },
+                "communication_id": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "max_attempts": {
+                    "type": "integer"
+                },
+                "mobile_number": {
+                    "type": "string"
+                },
+                "otp_reference": {
+                    "type": "string"
+                }
+            }
+        },
         "response.listMsgApplicationsResponse": {
             "type": "object",
             "properties": {
                 "application_id": {
-                    "type": "integer"
+                    "type": "string",
+                    "pattern": "^[0-9]+$"
                 },
                 "application_name": {
                     "type": "string"
@@ -3630,13 +4551,27 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "template_local_id": {
-                    "type": "integer"
+                    "type": "string",
+                    "pattern": "^[0-9]+$"
                 },
                 "template_name": {
                     "type": "string"
                 }
             }
         },
+        "response.recipientStatusResponse": {
+            "type": "object",
+            "properties": {
+                "mobile_number": {
+                    "type": "string",
+                    "example": "9000000000"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "SUBMITTED"
+                }
+            }
+        },
         "response.sendBulkSMSResponse": {
             "type": "object",
             "properties": {
@@ -3646,6 +4581,12 @@ const docTemplate = `{
                 "info": {
                     "type": "string"
                 },
+                "recipients": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/response.recipientStatusResponse"
+                    }
+                },
                 "request_id": {
                     "type": "string"
                 },
@@ -3689,6 +4630,9 @@ const docTemplate = `{
                 "application_id": {
                     "type": "string"
                 },
+                "client_reference": {
+                    "type": "string"
+                },
                 "comm_id": {
                     "type": "string"
                 },
@@ -3707,6 +4651,12 @@ const docTemplate = `{
                 "message_text": {
                     "type": "string"
                 },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
                 "mobile_number": {
                     "type": "integer"
                 },
@@ -3718,11 +4668,29 @@ const docTemplate = `{
                 }
             }
         },
+        "response.templateFacetsResponse": {
+            "type": "object",
+            "properties": {
+                "gateway": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "status": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
         "response.updateMsgApplicationResponse": {
             "type": "object",
             "properties": {
                 "application_id": {
-                    "type": "integer"
+                    "type": "string",
+                    "pattern": "^[0-9]+$"
                 },
                 "application_name": {
                     "type": "string"
@@ -3764,6 +4732,17 @@ const docTemplate = `{
                     "type": "integer"
                 }
             }
+        },
+        "response.verifyOTPResponse": {
+            "type": "object",
+            "properties": {
+                "otp_reference": {
+                    "type": "string"
+                },
+                "verified": {
+                    "type": "boolean"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,737 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"os"
+	"time"
+
+	auth "MgApplication/api-authz"
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	log "MgApplication/api-log"
+	serverHandler "MgApplication/api-server/handler"
+	serverRoute "MgApplication/api-server/route"
+	"MgApplication/core/audit"
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+	"MgApplication/handler/response"
+	repo "MgApplication/repo/postgres"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler represents the HTTP handler for operational requests restricted to the admin scope
+type AdminHandler struct {
+	*serverHandler.Base
+	dndsvc         *repo.DNDRepository
+	reportssvc     *repo.ReportsRepository
+	codesvc        *repo.GatewayCodeRepository
+	appsvc         *repo.ApplicationRepository
+	privacysvc     *repo.PrivacyRepository
+	errorsamplesvc *repo.ErrorSampleRepository
+	bundlesvc      bundleStore
+	explainsvc     explainStore
+	auditsvc       auditStore
+	monitor        *SystemStatusMonitor
+	expiry         *CredentialExpiryMonitor
+	c              *config.Config
+}
+
+// NewAdminHandler creates a new AdminHandler instance
+func NewAdminHandler(dndsvc *repo.DNDRepository, reportssvc *repo.ReportsRepository, codesvc *repo.GatewayCodeRepository, appsvc *repo.ApplicationRepository, privacysvc *repo.PrivacyRepository, errorsamplesvc *repo.ErrorSampleRepository, bundlesvc *repo.ConfigBundleRepository, diagsvc *repo.DiagnosticsRepository, auditsvc *repo.AuditRepository, monitor *SystemStatusMonitor, expiry *CredentialExpiryMonitor, c *config.Config) *AdminHandler {
+	base := serverHandler.New("Admin").SetDescription("Administration of the service, restricted to the admin scope").SetOrder(7).SetPrefix("/v1").AddPrefix("/admin").AddMiddleware(requireAdminScope(c)).AddMiddleware(auditRequest())
+	return &AdminHandler{
+		base,
+		dndsvc,
+		reportssvc,
+		codesvc,
+		appsvc,
+		privacysvc,
+		errorsamplesvc,
+		bundlesvc,
+		diagsvc,
+		auditsvc,
+		monitor,
+		expiry,
+		c,
+	}
+}
+
+func (ah *AdminHandler) Routes() []serverRoute.Route {
+	return []serverRoute.Route{
+		serverRoute.PUT("/log-level", ah.SetLogLevelHandler).Name("Set log level"),
+		serverRoute.GET("/log-level", ah.GetLogLevelHandler).Name("Get log level"),
+		serverRoute.POST("/dnd-registry", ah.ImportDNDRegistryHandler).Name("Import DND registry"),
+		serverRoute.POST("/stats/backfill", ah.BackfillStatsHandler).Name("Backfill hourly stats"),
+		serverRoute.GET("/stats/consistency", ah.CheckStatsHandler).Name("Check hourly stats"),
+		serverRoute.GET("/requests/orphaned", ah.OrphanedRequestsHandler).Name("List orphaned requests"),
+		serverRoute.GET("/gateway-codes", ah.ListGatewayCodesHandler).Name("List gateway response codes"),
+		serverRoute.PUT("/gateway-codes/:gateway/:code", ah.SaveGatewayCodeHandler).Name("Save gateway response code"),
+		serverRoute.DELETE("/gateway-codes/:gateway/:code", ah.DeleteGatewayCodeHandler).Name("Delete gateway response code"),
+		serverRoute.PUT("/applications/:application-id/shadow-gateway", ah.SetShadowGatewayHandler).Name("Set shadow gateway"),
+		serverRoute.GET("/shadow/comparison", ah.ShadowComparisonHandler).Name("Compare shadow gateway"),
+		serverRoute.POST("/privacy/erasure", ah.PrivacyErasureHandler).Name("Erase the messages of a mobile number"),
+		serverRoute.GET("/system-status", ah.SystemStatusHandler).Name("Get system status"),
+		serverRoute.GET("/credential-expiry", ah.CredentialExpiryHandler).Name("List gateway credential expiries"),
+		serverRoute.GET("/error-samples", ah.ListErrorSamplesHandler).Name("List error samples"),
+		serverRoute.GET("/export-bundle", ah.ExportBundleHandler).Name("Export configuration bundle"),
+		serverRoute.POST("/import-bundle", ah.ImportBundleHandler).Name("Import configuration bundle"),
+		serverRoute.POST("/explain", ah.ExplainQueryHandler).Name("Explain registered query"),
+		serverRoute.GET("/audit-log", ah.ListAuditLogHandler).Name("List admin audit log"),
+		serverRoute.GET("/audit-log/verify", ah.VerifyAuditLogHandler).Name("Verify admin audit log"),
+	}
+}
+
+// requireAdminScope rejects requests whose verified bearer token does not carry the configured
+// admin scope
+func requireAdminScope(c *config.Config) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if auth.ClaimsFrom(ctx.Request.Context()).HasScope(c.GetString("admin.scope")) {
+			ctx.Next()
+			return
+		}
+		log.Warn(ctx, "Admin scope missing for %s %s", ctx.Request.Method, ctx.Request.URL.Path)
+		apierrors.HandleForbiddenError(ctx)
+		ctx.Abort()
+	}
+}
+
+type setLogLevelRequest struct {
+	Level           string `json:"level" validate:"required,oneof=trace debug info warning error" example:"debug"`
+	DurationMinutes int    `json:"duration_minutes" validate:"required,min=1,max=1440" example:"30"`
+}
+
+// SetLogLevelHandler godoc
+//
+//	@Summary		Overrides the log level at runtime
+//	@Description	Switches the log level for the given duration, after which the configured level is restored. Calling it again replaces the level and extends the override
+//	@Tags			Admin
+//	@ID				SetLogLevelHandler
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization		header		string							true	"Bearer token whose scope claim includes the admin scope"
+//	@Param			setLogLevelRequest	body		setLogLevelRequest				true	"Log level override"
+//	@Success		200					{object}	response.LogLevelAPIResponse	"Log level is overridden"
+//	@Failure		400					{object}	apierrors.APIErrorResponse		"Bad Request"
+//	@Failure		403					{object}	apierrors.APIErrorResponse		"Forbidden"
+//	@Failure		422					{object}	apierrors.APIErrorResponse		"Binding or Validation error"
+//	@Failure		500					{object}	apierrors.APIErrorResponse		"Internal server error"
+//	@Router			/admin/log-level [put]
+func (ah *AdminHandler) SetLogLevelHandler(sctx *serverRoute.Context, req setLogLevelRequest) (*response.LogLevelAPIResponse, error) {
+
+	// the override is held in memory by the replica serving the request, its audit entry is
+	// appended before it applies
+	replica, _ := os.Hostname()
+	before, _ := json.Marshal(map[string]any{"level": log.GetLevelStatus().Level.String()})
+	after, _ := json.Marshal(map[string]any{"level": req.Level, "duration_minutes": req.DurationMinutes})
+	err := ah.auditsvc.AppendAuditRepo(sctx.Ctx, audit.Change{Action: domain.AuditActionUpdate, EntityType: domain.AuditEntityLogLevel, EntityID: replica, Before: before, After: after})
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in AppendAuditRepo function: %s", err.Error())
+		return nil, err
+	}
+
+	level := log.FetchLogLevel(req.Level)
+	if err := log.SetLevel(level, time.Duration(req.DurationMinutes)*time.Minute); err != nil {
+		log.Error(sctx.Ctx, "Error while setting log level: %s", err.Error())
+		return nil, err
+	}
+
+	return &response.LogLevelAPIResponse{
+		StatusCodeAndMessage: port.UpdateSuccess,
+		Data:                 response.NewLogLevelResponse(log.GetLevelStatus()),
+	}, nil
+}
+
+// GetLogLevelHandler godoc
+//
+//	@Summary		Fetches the log level
+//	@Description	Returns the current and configured log levels along with the remaining time of any runtime override
+//	@Tags			Admin
+//	@ID				GetLogLevelHandler
+//	@Produce		json
+//	@Param			Authorization	header		string							true	"Bearer token whose scope claim includes the admin scope"
+//	@Success		200				{object}	response.LogLevelAPIResponse	"Log level is fetched"
+//	@Failure		403				{object}	apierrors.APIErrorResponse		"Forbidden"
+//	@Failure		500				{object}	apierrors.APIErrorResponse		"Internal server error"
+//	@Router			/admin/log-level [get]
+func (ah *AdminHandler) GetLogLevelHandler(sctx *serverRoute.Context, req serverRoute.NoParam) (*response.LogLevelAPIResponse, error) {
+	return &response.LogLevelAPIResponse{
+		StatusCodeAndMessage: port.FetchSuccess,
+		Data:                 response.NewLogLevelResponse(log.GetLevelStatus()),
+	}, nil
+}
+
+type importDNDRegistryRequest struct {
+	File    *multipart.FileHeader `form:"file" validate:"required" swaggerignore:"true"`
+	Replace bool                  `form:"replace" example:"false"`
+}
+
+// ImportDNDRegistryHandler godoc
+//
+//	@Summary		Imports the DND registry
+//	@Description	Loads mobile numbers from a CSV file into the local DND (NCPR) registry used by the DND check of promotional and bulk messages. The first column of every row is read; rows without a valid mobile number, such as a header row, are counted as rejected. With replace set, the existing registry is cleared first
+//	@Tags			Admin
+//	@ID				ImportDNDRegistryHandler
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			Authorization	header		string							true	"Bearer token whose scope claim includes the admin scope"
+//	@Param			file			formData	file							true	"CSV file with one mobile number per row"
+//	@Param			replace			formData	bool							false	"Replace the existing registry"
+//	@Success		201				{object}	response.DNDImportAPIResponse	"DND registry is imported"
+//	@Failure		400				{object}	apierrors.APIErrorResponse		"Bad Request"
+//	@Failure		403				{object}	apierrors.APIErrorResponse		"Forbidden"
+//	@Failure		422				{object}	apierrors.APIErrorResponse		"Binding or Validation error"
+//	@Failure		500				{object}	apierrors.APIErrorResponse		"Internal server error"
+//	@Router			/admin/dnd-registry [post]
+func (ah *AdminHandler) ImportDNDRegistryHandler(sctx *serverRoute.Context, req importDNDRegistryRequest) (*response.DNDImportAPIResponse, error) {
+
+	file, err := req.File.Open()
+	if err != nil {
+		log.Error(sctx.Ctx, "Error while opening DND registry file: %s", err.Error())
+		return nil, err
+	}
+	defer file.Close()
+
+	mobileNumbers, rejected, err := readDNDRegistryCSV(file)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error while reading DND registry file: %s", err.Error())
+		return nil, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.HTTPErrorBadRequest, "Invalid DND registry file: "+err.Error(), err)
+	}
+
+	imported, err := ah.dndsvc.ImportDNDRepo(sctx.Ctx, mobileNumbers, req.Replace)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in ImportDNDRepo function: %s", err.Error())
+		return nil, err
+	}
+	log.Info(sctx.Ctx, "Imported %d of %d DND numbers (replace=%t), %d rows rejected", imported, len(mobileNumbers), req.Replace, rejected)
+
+	return &response.DNDImportAPIResponse{
+		StatusCodeAndMessage: port.CreateSuccess,
+		Data:                 response.NewDNDImportResponse(len(mobileNumbers), imported, rejected),
+	}, nil
+}
+
+// readDNDRegistryCSV returns the distinct valid mobile numbers in the first column of the
+// CSV along with the count of rejected rows
+func readDNDRegistryCSV(r io.Reader) ([]string, int, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var mobileNumbers []string
+	seen := make(map[string]bool)
+	rejected := 0
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		mobileNumber := normalizeMobileNumber(record[0])
+		if !dndMobileNumberPattern.MatchString(mobileNumber) {
+			rejected++
+			continue
+		}
+		if !seen[mobileNumber] {
+			seen[mobileNumber] = true
+			mobileNumbers = append(mobileNumbers, mobileNumber)
+		}
+	}
+	if len(mobileNumbers) == 0 {
+		return nil, rejected, errors.New("no valid mobile numbers in file")
+	}
+	return mobileNumbers, rejected, nil
+}
+
+type backfillStatsRequest struct {
+	FromDate string `json:"from_date" validate:"required,date_dd_mm_yyyy" example:"01-01-2024"`
+	ToDate   string `json:"to_date" validate:"required,date_dd_mm_yyyy" example:"31-01-2024"`
+}
+
+// BackfillStatsHandler godoc
+//
+//	@Summary		Backfills the hourly stats rollup
+//	@Description	Rebuilds the hourly stats rollup of the requests created between the dates from msg_request, replacing the existing rollup rows of that period. Rollup increments of requests saved during the backfill are kept
+//	@Tags			Admin
+//	@ID				BackfillStatsHandler
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization			header		string							true	"Bearer token whose scope claim includes the admin scope"
+//	@Param			backfillStatsRequest	body		backfillStatsRequest			true	"Period to backfill"
+//	@Success		201						{object}	response.StatsBackfillAPIResponse	"Hourly stats are backfilled"
+//	@Failure		400						{object}	apierrors.APIErrorResponse		"Bad Request"
+//	@Failure		403						{object}	apierrors.APIErrorResponse		"Forbidden"
+//	@Failure		422						{object}	apierrors.APIErrorResponse		"Binding or Validation error"
+//	@Failure		500						{object}	apierrors.APIErrorResponse		"Internal server error"
+//	@Router			/admin/stats/backfill [post]
+func (ah *AdminHandler) BackfillStatsHandler(sctx *serverRoute.Context, req backfillStatsRequest) (*response.StatsBackfillAPIResponse, error) {
+
+	fromDate, toDate, err := parseStatsPeriod(req.FromDate, req.ToDate)
+	if err != nil {
+		return nil, err
+	}
+
+	rebuilt, err := ah.reportssvc.RebuildStatsHourlyRepo(sctx.Ctx, fromDate, toDate)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in RebuildStatsHourlyRepo function: %s", err.Error())
+		return nil, err
+	}
+	log.Info(sctx.Ctx, "Backfilled %d hourly stats rows from %s to %s", rebuilt, req.FromDate, req.ToDate)
+
+	// the rollup is rebuilt in transactions of its own, shared with the rollup job
+	after, _ := json.Marshal(map[string]any{"from_date": req.FromDate, "to_date": req.ToDate, "rebuilt": rebuilt})
+	err = ah.auditsvc.AppendAuditRepo(sctx.Ctx, audit.Change{Action: domain.AuditActionRun, EntityType: domain.AuditEntityStatsBackfill, EntityID: req.FromDate + "/" + req.ToDate, After: after})
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in AppendAuditRepo function: %s", err.Error())
+		return nil, err
+	}
+
+	return &response.StatsBackfillAPIResponse{
+		StatusCodeAndMessage: port.CreateSuccess,
+		Data:                 response.NewStatsBackfillResponse(rebuilt),
+	}, nil
+}
+
+type checkStatsRequest struct {
+	FromDate string `form:"from-date" validate:"required,date_dd_mm_yyyy" example:"01-01-2024"`
+	ToDate   string `form:"to-date" validate:"required,date_dd_mm_yyyy" example:"31-01-2024"`
+}
+
+// CheckStatsHandler godoc
+//
+//	@Summary		Checks the hourly stats rollup
+//	@Description	Compares the hourly stats rollup of the requests created between the dates with the aggregation of msg_request and lists the rollup rows that differ
+//	@Tags			Admin
+//	@ID				CheckStatsHandler
+//	@Produce		json
+//	@Param			Authorization		header		string								true	"Bearer token whose scope claim includes the admin scope"
+//	@Param			checkStatsRequest	query		checkStatsRequest					true	"Period to check"
+//	@Success		200					{object}	response.StatsConsistencyAPIResponse	"Hourly stats are checked"
+//	@Failure		400					{object}	apierrors.APIErrorResponse			"Bad Request"
+//	@Failure		403					{object}	apierrors.APIErrorResponse			"Forbidden"
+//	@Failure		422					{object}	apierrors.APIErrorResponse			"Binding or Validation error"
+//	@Failure		500					{object}	apierrors.APIErrorResponse			"Internal server error"
+//	@Router			/admin/stats/consistency [get]
+func (ah *AdminHandler) CheckStatsHandler(sctx *serverRoute.Context, req checkStatsRequest) (*response.StatsConsistencyAPIResponse, error) {
+
+	fromDate, toDate, err := parseStatsPeriod(req.FromDate, req.ToDate)
+	if err != nil {
+		return nil, err
+	}
+
+	mismatches, err := ah.reportssvc.CheckStatsHourlyRepo(sctx.Ctx, fromDate, toDate)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in CheckStatsHourlyRepo function: %s", err.Error())
+		return nil, err
+	}
+	if len(mismatches) > 0 {
+		log.Warn(sctx.Ctx, "Hourly stats differ from msg_request in %d rows from %s to %s", len(mismatches), req.FromDate, req.ToDate)
+	}
+
+	return &response.StatsConsistencyAPIResponse{
+		StatusCodeAndMessage: port.FetchSuccess,
+		Data:                 response.NewStatsConsistencyResponse(mismatches),
+	}, nil
+}
+
+type orphanedRequestsRequest struct {
+	FromDate string `form:"from-date" validate:"required,date_dd_mm_yyyy" example:"01-01-2024"`
+	ToDate   string `form:"to-date" validate:"required,date_dd_mm_yyyy" example:"31-01-2024"`
+}
+
+// OrphanedRequestsHandler godoc
+//
+//	@Summary		Lists the orphaned requests
+//	@Description	Lists the OTP and transactional requests created between the dates that were stored but have no gateway response, left by the process stopping between storing a request and its response. Reports and reconciliation count them as neither sent nor failed
+//	@Tags			Admin
+//	@ID				OrphanedRequestsHandler
+//	@Produce		json
+//	@Param			Authorization			header		string								true	"Bearer token whose scope claim includes the admin scope"
+//	@Param			orphanedRequestsRequest	query		orphanedRequestsRequest				true	"Period to check"
+//	@Success		200						{object}	response.OrphanedRequestsAPIResponse	"Orphaned requests are retrieved"
+//	@Failure		400						{object}	apierrors.APIErrorResponse			"Bad Request"
+//	@Failure		403						{object}	apierrors.APIErrorResponse			"Forbidden"
+//	@Failure		422						{object}	apierrors.APIErrorResponse			"Binding or Validation error"
+//	@Failure		500						{object}	apierrors.APIErrorResponse			"Internal server error"
+//	@Router			/admin/requests/orphaned [get]
+func (ah *AdminHandler) OrphanedRequestsHandler(sctx *serverRoute.Context, req orphanedRequestsRequest) (*response.OrphanedRequestsAPIResponse, error) {
+
+	fromDate, toDate, err := parseStatsPeriod(req.FromDate, req.ToDate)
+	if err != nil {
+		return nil, err
+	}
+
+	orphans, err := ah.reportssvc.FindOrphanedRequestsRepo(sctx.Ctx, fromDate, toDate)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in FindOrphanedRequestsRepo function: %s", err.Error())
+		return nil, err
+	}
+	if len(orphans) > 0 {
+		log.Warn(sctx.Ctx, "%d requests from %s to %s have no gateway response", len(orphans), req.FromDate, req.ToDate)
+	}
+
+	return &response.OrphanedRequestsAPIResponse{
+		StatusCodeAndMessage: port.FetchSuccess,
+		Data:                 response.NewOrphanedRequestsResponse(orphans),
+	}, nil
+}
+
+// parseStatsPeriod parses a DD-MM-YYYY period, rejecting periods ending before they start
+func parseStatsPeriod(from string, to string) (time.Time, time.Time, error) {
+	fromDate, _ := time.Parse("02-01-2006", from)
+	toDate, _ := time.Parse("02-01-2006", to)
+	if toDate.Before(fromDate) {
+		return time.Time{}, time.Time{}, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.HTTPErrorBadRequest, "to_date should be after from_date", nil)
+	}
+	return fromDate, toDate, nil
+}
+
+// ListGatewayCodesHandler godoc
+//
+//	@Summary		Lists the gateway response codes
+//	@Description	Lists the response code dictionary of the SMS gateways (1 - CDAC, 2 - NIC)
+//	@Tags			Admin
+//	@ID				AdminListGatewayCodesHandler
+//	@Produce		json
+//	@Param			Authorization			header		string							true	"Bearer token whose scope claim includes the admin scope"
+//	@Param			listGatewayCodesRequest	query		listGatewayCodesRequest			false	"Gateway filter"
+//	@Success		200						{object}	response.GatewayCodesAPIResponse	"Gateway response codes are retrieved"
+//	@Failure		403						{object}	apierrors.APIErrorResponse		"Forbidden"
+//	@Failure		422						{object}	apierrors.APIErrorResponse		"Binding or Validation error"
+//	@Failure		500						{object}	apierrors.APIErrorResponse		"Internal server error"
+//	@Router			/admin/gateway-codes [get]
+func (ah *AdminHandler) ListGatewayCodesHandler(sctx *serverRoute.Context, req listGatewayCodesRequest) (*response.GatewayCodesAPIResponse, error) {
+
+	codes, err := ah.codesvc.ListGatewayCodesRepo(sctx.Ctx, req.Gateway)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in ListGatewayCodesRepo function: %s", err.Error())
+		return nil, err
+	}
+
+	return &response.GatewayCodesAPIResponse{
+		StatusCodeAndMessage: port.ListSuccess,
+		Data:                 response.NewGatewayCodesResponse(codes),
+	}, nil
+}
+
+type saveGatewayCodeRequest struct {
+	Gateway           string  `uri:"gateway" validate:"required,gateway_id" enum:"1,2" example:"1" json:"-"`
+	Code              string  `uri:"code" validate:"required,max=64" example:"406" json:"-"`
+	Description       string  `json:"description" validate:"required" example:"Invalid or duplicate mobile numbers"`
+	Severity          string  `json:"severity" validate:"required,oneof=info warning error" example:"warning"`
+	RecommendedAction *string `json:"recommended_action" example:"Check the recipient mobile numbers"`
+}
+
+// SaveGatewayCodeHandler godoc
+//
+//	@Summary		Saves a gateway response code
+//	@Description	Adds a code to the response code dictionary or replaces the description, severity and recommended action of an existing code
+//	@Tags			Admin
+//	@ID				SaveGatewayCodeHandler
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization			header		string							true	"Bearer token whose scope claim includes the admin scope"
+//	@Param			gateway					path		string							true	"Gateway, 1 - CDAC, 2 - NIC"
+//	@Param			code					path		string							true	"Response code"
+//	@Param			saveGatewayCodeRequest	body		saveGatewayCodeRequest			true	"Description of the code"
+//	@Success		200						{object}	response.GatewayCodeAPIResponse	"Gateway response code is saved"
+//	@Failure		403						{object}	apierrors.APIErrorResponse		"Forbidden"
+//	@Failure		422						{object}	apierrors.APIErrorResponse		"Binding or Validation error"
+//	@Failure		500						{object}	apierrors.APIErrorResponse		"Internal server error"
+//	@Router			/admin/gateway-codes/{gateway}/{code} [put]
+func (ah *AdminHandler) SaveGatewayCodeHandler(sctx *serverRoute.Context, req saveGatewayCodeRequest) (*response.GatewayCodeAPIResponse, error) {
+
+	code, err := ah.codesvc.UpsertGatewayCodeRepo(sctx.Ctx, domain.GatewayCode{
+		Gateway:           req.Gateway,
+		Code:              req.Code,
+		Description:       req.Description,
+		Severity:          req.Severity,
+		RecommendedAction: req.RecommendedAction,
+	})
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in UpsertGatewayCodeRepo function: %s", err.Error())
+		return nil, err
+	}
+	log.Info(sctx.Ctx, "Saved gateway %s response code %s", req.Gateway, req.Code)
+
+	return &response.GatewayCodeAPIResponse{
+		StatusCodeAndMessage: port.UpdateSuccess,
+		Data:                 response.NewGatewayCodeResponse(&code),
+	}, nil
+}
+
+type deleteGatewayCodeRequest struct {
+	Gateway string `uri:"gateway" validate:"required,gateway_id" enum:"1,2" example:"1"`
+	Code    string `uri:"code" validate:"required" example:"406"`
+}
+
+// DeleteGatewayCodeHandler godoc
+//
+//	@Summary		Deletes a gateway response code
+//	@Description	Removes a code from the response code dictionary, after which it is reported as undocumented
+//	@Tags			Admin
+//	@ID				DeleteGatewayCodeHandler
+//	@Produce		json
+//	@Param			Authorization	header		string						true	"Bearer token whose scope claim includes the admin scope"
+//	@Param			gateway			path		string						true	"Gateway, 1 - CDAC, 2 - NIC"
+//	@Param			code			path		string						true	"Response code"
+//	@Success		200				{object}	port.StatusCodeAndMessage	"Gateway response code is deleted"
+//	@Failure		403				{object}	apierrors.APIErrorResponse	"Forbidden"
+//	@Failure		404				{object}	apierrors.APIErrorResponse	"Data not found"
+//	@Failure		422				{object}	apierrors.APIErrorResponse	"Binding or Validation error"
+//	@Failure		500				{object}	apierrors.APIErrorResponse	"Internal server error"
+//	@Router			/admin/gateway-codes/{gateway}/{code} [delete]
+func (ah *AdminHandler) DeleteGatewayCodeHandler(sctx *serverRoute.Context, req deleteGatewayCodeRequest) (*port.StatusCodeAndMessage, error) {
+
+	deleted, err := ah.codesvc.DeleteGatewayCodeRepo(sctx.Ctx, req.Gateway, req.Code)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in DeleteGatewayCodeRepo function: %s", err.Error())
+		return nil, err
+	}
+	if !deleted {
+		return nil, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.HTTPErrorNotFound, "gateway response code not found", nil)
+	}
+	log.Info(sctx.Ctx, "Deleted gateway %s response code %s", req.Gateway, req.Code)
+
+	return &port.DeleteSuccess, nil
+}
+
+type setShadowGatewayRequest struct {
+	ApplicationID uint64 `uri:"application-id" validate:"required" example:"4" json:"-"`
+	ShadowGateway string `json:"shadow_gateway" validate:"omitempty,gateway_id" enum:"1,2" example:"2"`
+}
+
+// SetShadowGatewayHandler godoc
+//
+//	@Summary		Sets the shadow gateway of an application
+//	@Description	Copies of the OTP and transactional requests of the application are sent to the shadow gateway, to test numbers only, and compared in /admin/shadow/comparison. An empty shadow_gateway stops the shadowing
+//	@Tags			Admin
+//	@ID				SetShadowGatewayHandler
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization			header		string							true	"Bearer token whose scope claim includes the admin scope"
+//	@Param			application-id			path		string							true	"Application ID"
+//	@Param			setShadowGatewayRequest	body		setShadowGatewayRequest			true	"Shadow gateway, 1 - CDAC, 2 - NIC"
+//	@Success		200						{object}	response.ShadowGatewayAPIResponse	"Shadow gateway is set"
+//	@Failure		403						{object}	apierrors.APIErrorResponse		"Forbidden"
+//	@Failure		404						{object}	apierrors.APIErrorResponse		"Data not found"
+//	@Failure		422						{object}	apierrors.APIErrorResponse		"Binding or Validation error"
+//	@Failure		500						{object}	apierrors.APIErrorResponse		"Internal server error"
+//	@Router			/admin/applications/{application-id}/shadow-gateway [put]
+func (ah *AdminHandler) SetShadowGatewayHandler(sctx *serverRoute.Context, req setShadowGatewayRequest) (*response.ShadowGatewayAPIResponse, error) {
+
+	found, err := ah.appsvc.SetShadowGatewayRepo(sctx.Ctx, req.ApplicationID, req.ShadowGateway)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in SetShadowGatewayRepo function: %s", err.Error())
+		return nil, err
+	}
+	if !found {
+		return nil, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.HTTPErrorNotFound, "application not found", nil)
+	}
+	log.Info(sctx.Ctx, "Set the shadow gateway of application %d to %q", req.ApplicationID, req.ShadowGateway)
+
+	return &response.ShadowGatewayAPIResponse{
+		StatusCodeAndMessage: port.UpdateSuccess,
+		Data:                 response.NewShadowGatewayResponse(req.ApplicationID, req.ShadowGateway),
+	}, nil
+}
+
+type shadowComparisonRequest struct {
+	FromDate      string `form:"from-date" validate:"required,date_dd_mm_yyyy" example:"01-01-2024"`
+	ToDate        string `form:"to-date" validate:"required,date_dd_mm_yyyy" example:"31-01-2024"`
+	ApplicationID string `form:"application-id" validate:"omitempty,numeric" example:"4"`
+}
+
+// ShadowComparisonHandler godoc
+//
+//	@Summary		Compares the primary and shadow gateways
+//	@Description	Summarizes the acceptance rates of the primary and shadow gateways on the requests shadowed between the dates, and the response codes of the requests rejected by either gateway
+//	@Tags			Admin
+//	@ID				ShadowComparisonHandler
+//	@Produce		json
+//	@Param			Authorization			header		string								true	"Bearer token whose scope claim includes the admin scope"
+//	@Param			shadowComparisonRequest	query		shadowComparisonRequest				true	"Period and application to compare"
+//	@Success		200						{object}	response.ShadowComparisonAPIResponse	"Gateways are compared"
+//	@Failure		400						{object}	apierrors.APIErrorResponse			"Bad Request"
+//	@Failure		403						{object}	apierrors.APIErrorResponse			"Forbidden"
+//	@Failure		422						{object}	apierrors.APIErrorResponse			"Binding or Validation error"
+//	@Failure		500						{object}	apierrors.APIErrorResponse			"Internal server error"
+//	@Router			/admin/shadow/comparison [get]
+func (ah *AdminHandler) ShadowComparisonHandler(sctx *serverRoute.Context, req shadowComparisonRequest) (*response.ShadowComparisonAPIResponse, error) {
+
+	fromDate, toDate, err := parseStatsPeriod(req.FromDate, req.ToDate)
+	if err != nil {
+		return nil, err
+	}
+
+	comparisons, differences, err := ah.reportssvc.ShadowComparisonReportRepo(sctx.Ctx, fromDate, toDate, req.ApplicationID)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in ShadowComparisonReportRepo function: %s", err.Error())
+		return nil, err
+	}
+
+	return &response.ShadowComparisonAPIResponse{
+		StatusCodeAndMessage: port.FetchSuccess,
+		Data:                 response.NewShadowComparisonResponse(comparisons, differences),
+	}, nil
+}
+
+type privacyErasureRequest struct {
+	MobileNumber string `json:"mobile_number" validate:"required,mobile_number" example:"9000000000"`
+	FromDate     string `json:"from_date" validate:"omitempty,date_dd_mm_yyyy" example:"01-01-2024"`
+	ToDate       string `json:"to_date" validate:"omitempty,date_dd_mm_yyyy" example:"31-01-2024"`
+	Confirm      bool   `json:"confirm" example:"true"`
+}
+
+// PrivacyErasureHandler godoc
+//
+//	@Summary		Erases the messages sent to a mobile number
+//	@Description	Anonymizes the requests, bulk uploads and OTPs of a mobile number for a data protection request, optionally limited to the requests created between the dates: the number is replaced with a tombstone and the message text is cleared. The erasure and the rows affected per table are recorded against the subject of the bearer token of the caller. Rows are erased in batches; an interrupted erasure is resumed by repeating the request, and repeating a completed erasure affects no rows. confirm must be set to true
+//	@Tags			Admin
+//	@ID				PrivacyErasureHandler
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization			header		string							true	"Bearer token whose scope claim includes the admin scope"
+//	@Param			privacyErasureRequest	body		privacyErasureRequest			true	"Mobile number and period to erase"
+//	@Success		200						{object}	response.PrivacyErasureAPIResponse	"Messages are erased"
+//	@Failure		400						{object}	apierrors.APIErrorResponse		"Bad Request"
+//	@Failure		403						{object}	apierrors.APIErrorResponse		"Forbidden"
+//	@Failure		422						{object}	apierrors.APIErrorResponse		"Binding or Validation error"
+//	@Failure		500						{object}	apierrors.APIErrorResponse		"Internal server error"
+//	@Router			/admin/privacy/erasure [post]
+func (ah *AdminHandler) PrivacyErasureHandler(sctx *serverRoute.Context, req privacyErasureRequest) (*response.PrivacyErasureAPIResponse, error) {
+
+	if !req.Confirm {
+		return nil, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.HTTPErrorBadRequest, "confirm must be true to erase the messages of the mobile number", nil)
+	}
+	requestedBy := adminUserID(sctx.Ctx)
+	if requestedBy == "" {
+		return nil, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.HTTPErrorBadRequest, "The bearer token must carry a subject to record the erasure", nil)
+	}
+
+	fromDate, toDate, err := parseErasurePeriod(req.FromDate, req.ToDate)
+	if err != nil {
+		return nil, err
+	}
+
+	erasure, resumed, err := ah.privacysvc.StartPrivacyErasureRepo(sctx.Ctx, domain.PrivacyErasure{
+		Tombstone:   erasureTombstone(ah.c.GetString("privacy.erasure.tombstonekey"), req.MobileNumber),
+		FromDate:    fromDate,
+		ToDate:      toDate,
+		RequestedBy: requestedBy,
+	})
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in StartPrivacyErasureRepo function: %s", err.Error())
+		return nil, err
+	}
+	if resumed {
+		log.Info(sctx.Ctx, "Resuming privacy erasure %d requested by %s, on request of %s", erasure.ErasureID, erasure.RequestedBy, requestedBy)
+	}
+
+	erasure, err = ah.privacysvc.RunPrivacyErasureRepo(sctx.Ctx, erasure, storedMobileNumberForms(req.MobileNumber))
+	if err != nil {
+		log.Error(sctx.Ctx, "Privacy erasure %d interrupted, repeat the request to resume it: %s", erasure.ErasureID, err.Error())
+		return nil, err
+	}
+	log.Info(sctx.Ctx, "Completed privacy erasure %d requested by %s: %v", erasure.ErasureID, requestedBy, erasure.Tables)
+
+	return &response.PrivacyErasureAPIResponse{
+		StatusCodeAndMessage: port.UpdateSuccess,
+		Data:                 response.NewPrivacyErasureResponse(erasure, resumed),
+	}, nil
+}
+
+// SystemStatusHandler godoc
+//
+//	@Summary		Fetches the system status
+//	@Description	Returns the state of the dependencies from the last background probes, of the background jobs and of the circuit breakers, with an overall green, amber or red status computed from the configured severity weights. Dependencies are not probed by the request
+//	@Tags			Admin
+//	@ID				SystemStatusHandler
+//	@Produce		json
+//	@Param			Authorization	header		string							true	"Bearer token whose scope claim includes the admin scope"
+//	@Success		200				{object}	response.SystemStatusAPIResponse	"System status is fetched"
+//	@Failure		403				{object}	apierrors.APIErrorResponse		"Forbidden"
+//	@Failure		500				{object}	apierrors.APIErrorResponse		"Internal server error"
+//	@Router			/admin/system-status [get]
+func (ah *AdminHandler) SystemStatusHandler(sctx *serverRoute.Context, req serverRoute.NoParam) (*response.SystemStatusAPIResponse, error) {
+	status := ah.monitor.Status()
+	return &response.SystemStatusAPIResponse{
+		StatusCodeAndMessage: port.FetchSuccess,
+		Data:                 &status,
+	}, nil
+}
+
+// CredentialExpiryHandler godoc
+//
+//	@Summary		Lists the expiry of the gateway credentials
+//	@Description	Lists the global CDAC and NIC accounts and the accounts of the credential sets with the expiry of their password, configured as passwordexpiry next to the password, and the business days remaining. Status is expiring within sms.credentialexpiry.leadtime of the expiry, and unknown without a configured expiry. Alerted tells whether the expiry was alerted to the alert webhook
+//	@Tags			Admin
+//	@ID				CredentialExpiryHandler
+//	@Produce		json
+//	@Param			Authorization	header		string								true	"Bearer token whose scope claim includes the admin scope"
+//	@Success		200				{object}	response.CredentialExpiryAPIResponse	"Credential expiries are retrieved"
+//	@Failure		403				{object}	apierrors.APIErrorResponse			"Forbidden"
+//	@Failure		500				{object}	apierrors.APIErrorResponse			"Internal server error"
+//	@Router			/admin/credential-expiry [get]
+func (ah *AdminHandler) CredentialExpiryHandler(sctx *serverRoute.Context, req serverRoute.NoParam) (*response.CredentialExpiryAPIResponse, error) {
+	return &response.CredentialExpiryAPIResponse{
+		StatusCodeAndMessage: port.ListSuccess,
+		Data:                 ah.expiry.Expiries(),
+	}, nil
+}
+
+type listErrorSamplesRequest struct {
+	Route string `form:"route" example:"/v1/sms-request"`
+	From  string `form:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2024-09-14T00:00:00+05:30"`
+	To    string `form:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2024-09-15T00:00:00+05:30"`
+	port.MetaDataRequest
+}
+
+// ListErrorSamplesHandler godoc
+//
+//	@Summary		Lists the error samples
+//	@Description	Lists the samples of the requests that panicked or were answered with a 5xx status, the latest first, for post-incident review. Every panic is sampled with its stack, and a fraction admin.errorsamples.rate of the 5xx responses. Request summaries have their mobile numbers and sensitive parameters masked. Route is the route pattern, from and to are RFC3339 times, to being excluded
+//	@Tags			Admin
+//	@ID				ListErrorSamplesHandler
+//	@Produce		json
+//	@Param			Authorization			header		string							true	"Bearer token whose scope claim includes the admin scope"
+//	@Param			listErrorSamplesRequest	query		listErrorSamplesRequest			false	"Route and period filters"
+//	@Success		200						{object}	response.ErrorSamplesAPIResponse	"Error samples are retrieved"
+//	@Failure		400						{object}	apierrors.APIErrorResponse		"Bad Request"
+//	@Failure		403						{object}	apierrors.APIErrorResponse		"Forbidden"
+//	@Failure		422						{object}	apierrors.APIErrorResponse		"Binding or Validation error"
+//	@Failure		500						{object}	apierrors.APIErrorResponse		"Internal server error"
+//	@Router			/admin/error-samples [get]
+func (ah *AdminHandler) ListErrorSamplesHandler(sctx *serverRoute.Context, req listErrorSamplesRequest) (*response.ErrorSamplesAPIResponse, error) {
+
+	filter := domain.ErrorSampleFilter{Route: req.Route}
+	if req.From != "" {
+		from, _ := time.Parse(time.RFC3339, req.From)
+		filter.From = &from
+	}
+	if req.To != "" {
+		to, _ := time.Parse(time.RFC3339, req.To)
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return nil, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.HTTPErrorBadRequest, "to should be after from", nil)
+	}
+
+	samples, err := ah.errorsamplesvc.ListErrorSamplesRepo(sctx.Ctx, filter, req.MetaDataRequest)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in ListErrorSamplesRepo function: %s", err.Error())
+		return nil, err
+	}
+
+	return &response.ErrorSamplesAPIResponse{
+		StatusCodeAndMessage: port.ListSuccess,
+		MetaDataResponse:     port.NewMetaDataResponse(uint64(req.Skip), uint64(req.Limit), len(samples)),
+		Data:                 response.NewErrorSamplesResponse(samples),
+	}, nil
+}
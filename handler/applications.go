@@ -12,6 +12,7 @@ import (
 	"MgApplication/handler/response"
 	repo "MgApplication/repo/postgres"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -26,18 +27,49 @@ import (
 // MgApplication Handler represents the HTTP handler for MgApplication related requests
 type ApplicationHandler struct {
 	*serverHandler.Base
-	svc *repo.ApplicationRepository
-	c   *config.Config
+	svc             *repo.ApplicationRepository
+	c               *config.Config
+	secretKeyLength int
 }
 
 // MgApplication Handler creates a new MgApplicatPion Handler instance
 func NewApplicationHandler(svc *repo.ApplicationRepository, c *config.Config) *ApplicationHandler {
-	base := serverHandler.New("Applications").SetPrefix("/v1").AddPrefix("/applications")
+	base := serverHandler.New("Applications").SetDescription("Applications registered to send messages, with their secret keys").SetOrder(1).SetPrefix("/v1").AddPrefix("/applications").AddMiddleware(auditRequest())
+	if svc != nil && c.GetBool("db.consistency.enabled") {
+		base.AddMiddleware(consistencyTokens(primaryLSN(svc.Db)))
+	}
 	return &ApplicationHandler{
 		base,
 		svc,
 		c,
+		secretKeyLength(c),
+	}
+}
+
+// Secret keys of the applications, in characters of 6 random bits
+const (
+	defaultSecretKeyLength = 22 // 132 bits
+	minSecretKeyLength     = 22 // at least 128 bits
+)
+
+var errSecretKeyTooShort = errors.New("applications.secretkey.length is below the minimum")
+
+// secretKeyLength returns the configured length of the generated secret keys,
+// applications.secretkey.length, defaultSecretKeyLength when unset
+func secretKeyLength(c *config.Config) int {
+	if length := c.GetInt("applications.secretkey.length"); length != 0 {
+		return length
+	}
+	return defaultSecretKeyLength
+}
+
+// CheckSecretKeyLength fails startup when applications.secretkey.length is below
+// minSecretKeyLength
+func CheckSecretKeyLength(c *config.Config) error {
+	if length := secretKeyLength(c); length < minSecretKeyLength {
+		return fmt.Errorf("%w of %d characters: %d", errSecretKeyTooShort, minSecretKeyLength, length)
 	}
+	return nil
 }
 
 func (c *ApplicationHandler) Routes() []serverRoute.Route {
@@ -52,26 +84,26 @@ func (c *ApplicationHandler) Routes() []serverRoute.Route {
 		serverRoute.POST("xml", c.CreateMessageApplicationXMLHandler).Name("Create Message Application XML"),
 		serverRoute.GET("", c.ListMessageApplicationsHandler).Name("List all message applications"),
 		serverRoute.GET("/:application-id", c.FetchApplicationHandler).Name("Fetch application by id"),
-		serverRoute.PUT("/:application-id", c.UpdateMessageApplicationHandler).Name("Fetch application by id"),
+		serverRoute.PUT("/:application-id", c.UpdateMessageApplicationHandler).Name("Fetch application by id").Summary("Update application by id"),
 
 		//route.GET("/simulate-error", c.testcustomcode2).Name("Simulate Error"),
 	}
 }
 
 func (c *ApplicationHandler) Middlewares() []gin.HandlerFunc {
-	return []gin.HandlerFunc{
+	return append([]gin.HandlerFunc{
 		func(ctx *gin.Context) {
 			log.Info(ctx, "Inside ApplicationHandler middleware")
 		},
-	}
+	}, c.Base.Middlewares()...)
 }
 
 // create MgApplication  Request represents a request body for creating a MgApplication Handler
 type createMessageApplicationRequest struct {
-	ApplicationID   uint64 `json:"application_id"`
-	ApplicationName string `json:"application_name" validate:"required" example:"Test Application"`
-	RequestType     string `json:"request_type" validate:"required,request_type" example:"1"`
-	Status          bool   `json:"status" validate:"required" example:"true"`
+	ApplicationID   port.ID `json:"application_id" swaggertype:"string" pattern:"^[0-9]+$"`
+	ApplicationName string  `json:"application_name" validate:"required" example:"Test Application"`
+	RequestType     string  `json:"request_type" validate:"required,request_type" example:"1"`
+	Status          bool    `json:"status" validate:"required" example:"true"`
 }
 
 type createMessageApplicationXMLRequest struct {
@@ -111,9 +143,9 @@ func (ah *ApplicationHandler) CreateMessageApplicationXMLHandler(sctx *serverRou
 	// 	log.Error(ctx, "Validation failed for createMessageApplicationRequest: %s", err.Error())
 	// 	return
 	// }
-	fmt.Println("11111111111111111111", req)
+	log.Debug(sctx.Ctx, "CreateMessageApplicationXMLHandler request: application %s, request type %s", req.ApplicationName, req.RequestType)
 
-	SecretKeyGenerated, errSecret := GenerateRandomString(16)
+	SecretKeyGenerated, errSecret := GenerateRandomString(ah.secretKeyLength)
 	if errSecret != nil {
 		// apierrors.HandleError(sctx.Ctx, errSecret)
 		log.Error(sctx.Ctx, "Error while generating secret key: %s", errSecret.Error())
@@ -141,16 +173,14 @@ func (ah *ApplicationHandler) CreateMessageApplicationXMLHandler(sctx *serverRou
 		return nil, err
 	}
 
-	rsp := response.NewCreateMsgApplicationResponse(&msg)
-	apiRsp := response.CreateMsgApplicationAPIResponse{
-		StatusCodeAndMessage: port.CreateSuccess,
-		Data:                 rsp,
-	}
-
+	apiRsp := response.WithCreatedAt(&response.CreateMsgApplicationAPIResponse{Data: response.NewCreateMsgApplicationResponse(&msg)}, ah.applicationLocation(msg.ApplicationID))
 	log.Debug(sctx.Ctx, "CreateMessageApplicationHandler response: %v", apiRsp)
-	// handleCreateSuccess(sctx.Ctx, apiRsp)
+	return apiRsp, nil
+}
 
-	return &apiRsp, nil
+// applicationLocation returns the URL the application with applicationID is fetched from
+func (ah *ApplicationHandler) applicationLocation(applicationID uint64) string {
+	return fmt.Sprintf("%s/%d", ah.Prefix(), applicationID)
 }
 
 func (ah *ApplicationHandler) CreateMessageApplicationHandler(sctx *serverRoute.Context, req createMessageApplicationRequestForm) (*response.CreateMsgApplicationAPIResponse, error) {
@@ -179,32 +209,15 @@ func (ah *ApplicationHandler) CreateMessageApplicationHandler(sctx *serverRoute.
 	// }
 
 	// Removed intentional panic that indexed a nil slice
-	// fmt.Println(req.)
+	// the logo and the attachments are optional, none is stored yet
 	if req.Logo != nil {
-		f, err := req.Logo.Open()
-		if err != nil { /* handle */
-			fmt.Println("Error in opening logo file: ", err)
-		} else {
-			defer f.Close()
-			// Read the first 512 bytes for demonstration (or use io.ReadAll for full content)
-			buf := make([]byte, 512)
-			n, readErr := f.Read(buf)
-			if readErr != nil && readErr.Error() != "EOF" {
-				fmt.Println("Error reading logo file: ", readErr)
-			} else {
-				fmt.Println("*******************", buf[:n])
-			}
-		}
-		// io.Copy(dst, f) ...
+		log.Debug(sctx.Ctx, "CreateMessageApplicationHandler logo %s of %d bytes", req.Logo.Filename, req.Logo.Size)
 	}
-
-	fmt.Println("11111111111111111111", req.Logo.Filename, req.Logo.Size)
-	fmt.Println("222222222222222222", len(req.Attachments))
 	for _, attachment := range req.Attachments {
-		fmt.Println("33333333333333333333", attachment.Filename, attachment.Size)
+		log.Debug(sctx.Ctx, "CreateMessageApplicationHandler attachment %s of %d bytes", attachment.Filename, attachment.Size)
 	}
 
-	SecretKeyGenerated, errSecret := GenerateRandomString(16)
+	SecretKeyGenerated, errSecret := GenerateRandomString(ah.secretKeyLength)
 	if errSecret != nil {
 		// apierrors.HandleError(sctx.Ctx, errSecret)
 		log.Error(sctx.Ctx, "Error while generating secret key: %s", errSecret.Error())
@@ -232,16 +245,9 @@ func (ah *ApplicationHandler) CreateMessageApplicationHandler(sctx *serverRoute.
 		return nil, err
 	}
 
-	rsp := response.NewCreateMsgApplicationResponse(&msg)
-	apiRsp := response.CreateMsgApplicationAPIResponse{
-		StatusCodeAndMessage: port.CreateSuccess,
-		Data:                 rsp,
-	}
-
+	apiRsp := response.WithCreatedAt(&response.CreateMsgApplicationAPIResponse{Data: response.NewCreateMsgApplicationResponse(&msg)}, ah.applicationLocation(msg.ApplicationID))
 	log.Debug(sctx.Ctx, "CreateMessageApplicationHandler response: %v", apiRsp)
-	// handleCreateSuccess(sctx.Ctx, apiRsp)
-
-	return &apiRsp, nil
+	return apiRsp, nil
 }
 
 type updateMessageApplicationRequest struct {
@@ -292,7 +298,7 @@ func (ah *ApplicationHandler) UpdateMessageApplicationHandler(sctx *serverRoute.
 	// 	return
 	// }
 
-	fmt.Println("*******************", req)
+	log.Debug(sctx.Ctx, "UpdateMessageApplicationHandler request: application %d, name %s, request type %s", req.ApplicationID, req.ApplicationName, req.RequestType)
 
 	var aStatus int
 	if req.Status {
@@ -315,17 +321,9 @@ func (ah *ApplicationHandler) UpdateMessageApplicationHandler(sctx *serverRoute.
 		return nil, err
 	}
 
-	rsp := response.NewUpdateMsgApplicationResponse(&msgApp)
-	apiRsp := response.UpdateMsgApplicationAPIResponse{
-		StatusCodeAndMessage: port.UpdateSuccess,
-		Data:                 rsp,
-	}
-
+	apiRsp := response.WithUpdated(&response.UpdateMsgApplicationAPIResponse{Data: response.NewUpdateMsgApplicationResponse(&msgApp)})
 	log.Debug(sctx.Ctx, "UpdateMessageApplicationHandler response: %v", apiRsp)
-
-	return &apiRsp, nil
-
-	// handleSuccess(ctx, apiRsp)
+	return apiRsp, nil
 }
 
 type listMessageApplicationsRequest struct {
@@ -383,16 +381,6 @@ func (ah *ApplicationHandler) ListMessageApplicationsHandler(sctx *serverRoute.C
 		return nil, err
 	}
 
-	// total := len(applications)
-	// rsp := response.NewListMsgApplicationsResponse(applications)
-	// metadata := port.NewMetaDataResponse(req.Skip, req.Limit, total)
-
-	// apiRsp := response.ListMsgApplicationsAPIResponse{
-	// 	StatusCodeAndMessage: port.CreateSuccess,
-	// 	MetaDataResponse:     metadata,
-	// 	Data:                 rsp,
-	// }
-
 	// Stream PDF generation via io.Pipe to avoid large memory usage
 	r, w := io.Pipe()
 	go func() {
@@ -480,7 +468,10 @@ type fetchApplicationRequest struct {
 //	@Accept			json
 //	@Produce		json
 //	@Param			fetchApplicationRequest	path		fetchApplicationRequest					true	"Get Application Request (example:1)"
+//	@Param			If-None-Match			header		string									false	"ETag of a previously fetched Message Application"
 //	@Success		200						{object}	response.FetchMsgApplicationAPIResponse	"Message Application is retrieved"
+//	@Header			200						{string}	ETag									"Entity tag of the Message Application"
+//	@Success		304						"Message Application is unchanged"
 //	@Failure		400						{object}	apierrors.APIErrorResponse				"Bad Request"
 //	@Failure		401						{object}	apierrors.APIErrorResponse				"Unauthorized"
 //	@Failure		403						{object}	apierrors.APIErrorResponse				"Forbidden"
@@ -517,20 +508,9 @@ func (ah *ApplicationHandler) FetchApplicationHandler(sctx *serverRoute.Context,
 		return nil, err
 	}
 
-	// total := len(applications)
-	rsp := response.NewFetchMsgApplicationResponse(applications)
-	// metadata := response.NewMetaDataResponse(0, 0, total)
-
-	apiRsp := response.FetchMsgApplicationAPIResponse{
-		StatusCodeAndMessage: port.FetchSuccess,
-		// MetaDataResponse:     metadata,
-		Data: rsp,
-	}
-
+	apiRsp := response.WithFetched(&response.FetchMsgApplicationAPIResponse{Data: response.NewFetchMsgApplicationResponse(applications)})
 	log.Debug(sctx.Ctx, "FetchApplicationHandler response: %v", apiRsp)
-	// handleSuccess(sctx.Ctx, apiRsp)
-
-	return &apiRsp, nil
+	return apiRsp, nil
 }
 
 type toggleApplicationStatusRequest struct {
@@ -584,12 +564,7 @@ func (ah *ApplicationHandler) ToggleApplicationStatusHandler(ctx *gin.Context) {
 		return
 	}
 
-	apiRsp := response.ToggleAppStatusAPIResponse{
-		StatusCodeAndMessage: port.UpdateSuccess,
-		//MetaDataResponse:     metadata,
-		Data: applications,
-	}
-
+	apiRsp := &response.ToggleAppStatusAPIResponse{Data: applications}
+	response.Updated(ctx, apiRsp)
 	log.Debug(ctx, "ToggleApplicationStatusHandler response: %v", apiRsp)
-	handleSuccess(ctx, apiRsp)
 }
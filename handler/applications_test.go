@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"encoding/base64"
+	"testing"
+
+	config "MgApplication/api-config"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretKeyLength(t *testing.T) {
+	c := config.NewConfig(viper.New())
+	assert.Equal(t, defaultSecretKeyLength, NewApplicationHandler(nil, c).secretKeyLength)
+	assert.NoError(t, CheckSecretKeyLength(c))
+
+	c.Set("applications.secretkey.length", 40)
+	ah := NewApplicationHandler(nil, c)
+	require.Equal(t, 40, ah.secretKeyLength)
+	assert.NoError(t, CheckSecretKeyLength(c))
+
+	key, err := GenerateRandomString(ah.secretKeyLength)
+	require.NoError(t, err)
+	assert.Len(t, key, 40)
+
+	c.Set("applications.secretkey.length", 16)
+	assert.ErrorIs(t, CheckSecretKeyLength(c), errSecretKeyTooShort)
+}
+
+// Every character of a generated string is random, none is base64 padding
+func TestGenerateRandomString(t *testing.T) {
+	for length := 1; length <= 64; length++ {
+		s, err := GenerateRandomString(length)
+		require.NoError(t, err)
+		require.Len(t, s, length)
+		assert.NotContains(t, s, "=")
+		_, err = base64.RawURLEncoding.DecodeString(s + "AAAA"[:(4-length%4)%4])
+		assert.NoError(t, err, s)
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	apierrors "MgApplication/api-errors"
+	log "MgApplication/api-log"
+	"MgApplication/api-server/middlewares/reqid"
+	serverResponse "MgApplication/api-server/response"
+	serverRoute "MgApplication/api-server/route"
+	"MgApplication/core/audit"
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+	"MgApplication/handler/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditStore appends to, lists and verifies the admin audit log, the AuditRepository outside the
+// tests. The mutations of the database append their entry in their own transaction, the store
+// only appends the changes made outside it.
+type auditStore interface {
+	AppendAuditRepo(ctx context.Context, change audit.Change) error
+	ListAuditLogRepo(ctx context.Context, filter domain.AuditFilter, meta port.MetaDataRequest) ([]domain.AuditEntry, error)
+	VerifyAuditLogRepo(ctx context.Context) (domain.AuditVerification, error)
+}
+
+// auditRequest carries the caller of the request in its context to the audit log of the changes
+// it makes: the subject of its verified bearer token as actor and its correlation ID. The
+// correlation ID is kept in the context, so that the response echoes the one audited when the
+// tracing middleware set none.
+func auditRequest() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		correlationID := serverResponse.CorrelationID(ctx)
+		reqCtx := context.WithValue(ctx.Request.Context(), reqid.CtxRequestIdKey{}, correlationID)
+		request := audit.Request{
+			Actor:         adminUserID(reqCtx),
+			CorrelationID: correlationID,
+		}
+		ctx.Request = ctx.Request.WithContext(audit.WithRequest(reqCtx, request))
+		ctx.Next()
+	}
+}
+
+type listAuditLogRequest struct {
+	Actor      string `form:"actor" validate:"omitempty,max=255" example:"ops-admin"`
+	Action     string `form:"action" validate:"omitempty,oneof=create update delete status import run" example:"update"`
+	EntityType string `form:"entity-type" validate:"omitempty,max=64" example:"template"`
+	EntityID   string `form:"entity-id" validate:"omitempty,max=255" example:"42"`
+	From       string `form:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2024-09-14T00:00:00+05:30"`
+	To         string `form:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2024-09-15T00:00:00+05:30"`
+	port.MetaDataRequest
+}
+
+// ListAuditLogHandler godoc
+//
+//	@Summary		Lists the admin audit log
+//	@Description	Lists the entries of the append-only audit log of the admin mutations, the latest first: the actor, the action, the entity changed, the before and after values of the fields changed, secret ones redacted, and the correlation ID of the request. Every entry carries the SHA-256 hash chaining it to the previous one. From and to are RFC3339 times, to being excluded
+//	@Tags			Admin
+//	@ID				ListAuditLogHandler
+//	@Produce		json
+//	@Param			Authorization		header		string						true	"Bearer token whose scope claim includes the admin scope"
+//	@Param			listAuditLogRequest	query		listAuditLogRequest			false	"Actor, action, entity and period filters"
+//	@Success		200					{object}	response.AuditLogAPIResponse	"Audit log entries are retrieved"
+//	@Failure		400					{object}	apierrors.APIErrorResponse	"Bad Request"
+//	@Failure		403					{object}	apierrors.APIErrorResponse	"Forbidden"
+//	@Failure		422					{object}	apierrors.APIErrorResponse	"Binding or Validation error"
+//	@Failure		500					{object}	apierrors.APIErrorResponse	"Internal server error"
+//	@Router			/admin/audit-log [get]
+func (ah *AdminHandler) ListAuditLogHandler(sctx *serverRoute.Context, req listAuditLogRequest) (*response.AuditLogAPIResponse, error) {
+
+	filter := domain.AuditFilter{Actor: req.Actor, Action: req.Action, EntityType: req.EntityType, EntityID: req.EntityID}
+	if req.From != "" {
+		from, _ := time.Parse(time.RFC3339, req.From)
+		filter.From = &from
+	}
+	if req.To != "" {
+		to, _ := time.Parse(time.RFC3339, req.To)
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return nil, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.HTTPErrorBadRequest, "to should be after from", nil)
+	}
+
+	entries, err := ah.auditsvc.ListAuditLogRepo(sctx.Ctx, filter, req.MetaDataRequest)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in ListAuditLogRepo function: %s", err.Error())
+		return nil, err
+	}
+	if entries == nil {
+		entries = []domain.AuditEntry{}
+	}
+
+	return &response.AuditLogAPIResponse{
+		StatusCodeAndMessage: port.ListSuccess,
+		MetaDataResponse:     port.NewMetaDataResponse(uint64(req.Skip), uint64(req.Limit), len(entries)),
+		Data:                 entries,
+	}, nil
+}
+
+// VerifyAuditLogHandler godoc
+//
+//	@Summary		Verifies the admin audit log
+//	@Description	Walks the audit chain from its first entry, recomputing the hash of every entry, and reports the first entry that does not chain to the previous one: an entry edited or removed afterwards breaks the chain. A broken chain is reported with verified false, not as an error
+//	@Tags			Admin
+//	@ID				VerifyAuditLogHandler
+//	@Produce		json
+//	@Param			Authorization	header		string								true	"Bearer token whose scope claim includes the admin scope"
+//	@Success		200				{object}	response.AuditVerificationAPIResponse	"Audit chain is verified"
+//	@Failure		403				{object}	apierrors.APIErrorResponse			"Forbidden"
+//	@Failure		500				{object}	apierrors.APIErrorResponse			"Internal server error"
+//	@Router			/admin/audit-log/verify [get]
+func (ah *AdminHandler) VerifyAuditLogHandler(sctx *serverRoute.Context, req serverRoute.NoParam) (*response.AuditVerificationAPIResponse, error) {
+	verification, err := ah.auditsvc.VerifyAuditLogRepo(sctx.Ctx)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in VerifyAuditLogRepo function: %s", err.Error())
+		return nil, err
+	}
+
+	return &response.AuditVerificationAPIResponse{
+		StatusCodeAndMessage: port.FetchSuccess,
+		Data:                 &verification,
+	}, nil
+}
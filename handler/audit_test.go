@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	log "MgApplication/api-log"
+	"MgApplication/core/audit"
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAuditStore records the changes appended with the caller of their context
+type fakeAuditStore struct {
+	changes      []audit.Change
+	requests     []audit.Request
+	appendErr    error
+	filter       domain.AuditFilter
+	entries      []domain.AuditEntry
+	verification domain.AuditVerification
+}
+
+func (s *fakeAuditStore) AppendAuditRepo(ctx context.Context, change audit.Change) error {
+	if s.appendErr != nil {
+		return s.appendErr
+	}
+	s.changes = append(s.changes, change)
+	s.requests = append(s.requests, audit.RequestFrom(ctx))
+	return nil
+}
+
+func (s *fakeAuditStore) ListAuditLogRepo(ctx context.Context, filter domain.AuditFilter, meta port.MetaDataRequest) ([]domain.AuditEntry, error) {
+	s.filter = filter
+	return s.entries, nil
+}
+
+func (s *fakeAuditStore) VerifyAuditLogRepo(ctx context.Context) (domain.AuditVerification, error) {
+	return s.verification, nil
+}
+
+// auditServer serves the admin routes with store as audit log
+func auditServer(store *fakeAuditStore) *gin.Engine {
+	ah := NewAdminHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, explainConfig())
+	ah.auditsvc = store
+	return adminEngine(ah)
+}
+
+func TestSetLogLevelAudited(t *testing.T) {
+	t.Cleanup(log.ResetLevel)
+	store := &fakeAuditStore{}
+	engine := auditServer(store)
+
+	rec := callBundle(t, engine, http.MethodPut, "/v1/admin/log-level", map[string]any{"level": "debug", "duration_minutes": 5}, "admin")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Len(t, store.changes, 1)
+	assert.Equal(t, domain.AuditActionUpdate, store.changes[0].Action)
+	assert.Equal(t, domain.AuditEntityLogLevel, store.changes[0].EntityType)
+	assert.JSONEq(t, `{"level":"debug","duration_minutes":5}`, string(store.changes[0].After))
+	// the actor is the subject of the bearer token, the correlation ID the one of the response
+	assert.Equal(t, "ops1", store.requests[0].Actor)
+	assert.Equal(t, rec.Header().Get("X-Request-Id"), store.requests[0].CorrelationID)
+	assert.NotEmpty(t, store.requests[0].CorrelationID)
+}
+
+func TestSetLogLevelNotAppliedWithoutAudit(t *testing.T) {
+	t.Cleanup(log.ResetLevel)
+	store := &fakeAuditStore{appendErr: errors.New("audit log unavailable")}
+	engine := auditServer(store)
+
+	rec := callBundle(t, engine, http.MethodPut, "/v1/admin/log-level", map[string]any{"level": "trace", "duration_minutes": 5}, "admin")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code, rec.Body.String())
+	assert.False(t, log.GetLevelStatus().OverrideActive)
+}
+
+func TestListAuditLog(t *testing.T) {
+	store := &fakeAuditStore{entries: []domain.AuditEntry{{AuditID: 7, Actor: "ops1", Action: domain.AuditActionStatus, EntityType: domain.AuditEntityTemplate, EntityID: "42", Diff: json.RawMessage(`{}`)}}}
+	engine := auditServer(store)
+
+	rec := callBundle(t, engine, http.MethodGet, "/v1/admin/audit-log?entity-type=template&entity-id=42&from=2024-09-14T00:00:00%2B05:30&skip=0&limit=10", nil, "admin")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var rsp struct {
+		Data []domain.AuditEntry `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	require.Len(t, rsp.Data, 1)
+	assert.EqualValues(t, 7, rsp.Data[0].AuditID)
+	assert.Equal(t, "template", store.filter.EntityType)
+	assert.Equal(t, "42", store.filter.EntityID)
+	require.NotNil(t, store.filter.From)
+	assert.Nil(t, store.filter.To)
+
+	rec = callBundle(t, engine, http.MethodGet, "/v1/admin/audit-log?action=rename&skip=0&limit=10", nil, "admin")
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+
+	rec = callBundle(t, engine, http.MethodGet, "/v1/admin/audit-log?skip=0&limit=10", nil, "reports")
+	assert.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+}
+
+func TestVerifyAuditLogReportsBrokenLink(t *testing.T) {
+	brokenAt := int64(12)
+	store := &fakeAuditStore{verification: domain.AuditVerification{Checked: 11, BrokenAt: &brokenAt, Reason: "hash does not match the content of the entry"}}
+	engine := auditServer(store)
+
+	rec := callBundle(t, engine, http.MethodGet, "/v1/admin/audit-log/verify", nil, "admin")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var rsp struct {
+		Data domain.AuditVerification `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.False(t, rsp.Data.Verified)
+	require.NotNil(t, rsp.Data.BrokenAt)
+	assert.EqualValues(t, 12, *rsp.Data.BrokenAt)
+	assert.EqualValues(t, 11, rsp.Data.Checked)
+}
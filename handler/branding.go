@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/dlt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Actions taken on messages without their sender branding, the action label of BrandingEnforcedTotal
+const (
+	brandingAppended = "appended"
+	brandingRejected = "rejected"
+)
+
+var BrandingEnforcedTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "sms_branding_enforced_total",
+		Help: "Total number of messages without the DLT branding of their sender, by sender and action taken",
+	},
+	[]string{"sender", "action"},
+)
+
+var (
+	errBrandingMissing  = errors.New("message does not end with the branding of the sender")
+	errBrandingConflict = errors.New("message ends with the branding of another sender")
+)
+
+// SenderBranding enforces the DLT branding of the senders configured in sms.branding, e.g.
+// "- INDPOST" for INPOST: DLT rejects messages that do not carry the branding registered for
+// their sender. A message without the branding gets it appended when sms.brandingappend is set
+// and is rejected otherwise; a message ending with the branding of another sender is always
+// rejected. Senders without a branding are not checked.
+type SenderBranding struct {
+	brandings map[string]string
+	append    bool
+}
+
+// NewSenderBranding creates a new SenderBranding instance using the sms.branding configuration
+func NewSenderBranding(c *config.Config) *SenderBranding {
+	brandings := make(map[string]string)
+	for senderID, branding := range c.GetStringMapString("sms.branding") {
+		if branding = strings.TrimSpace(branding); branding != "" {
+			brandings[strings.ToUpper(senderID)] = branding
+		}
+	}
+	return &SenderBranding{
+		brandings: brandings,
+		append:    c.GetBool("sms.brandingappend"),
+	}
+}
+
+// Branding returns the branding configured for senderID, empty when it has none
+func (b *SenderBranding) Branding(senderID string) string {
+	if b == nil {
+		return ""
+	}
+	return b.brandings[strings.ToUpper(senderID)]
+}
+
+// Apply returns text carrying the branding of senderID, or an error when the message has to be
+// rejected
+func (b *SenderBranding) Apply(senderID string, text string) (string, error) {
+	if b == nil {
+		return text, nil
+	}
+	senderID = strings.ToUpper(senderID)
+	branding, ok := b.brandings[senderID]
+	if !ok || dlt.EndsWithBranding(text, branding) {
+		return text, nil
+	}
+
+	for otherSenderID, other := range b.brandings {
+		if otherSenderID != senderID && dlt.EndsWithBranding(text, other) {
+			BrandingEnforcedTotal.WithLabelValues(senderID, brandingRejected).Inc()
+			return "", fmt.Errorf("%w %s, expected %q", errBrandingConflict, otherSenderID, branding)
+		}
+	}
+	if !b.append {
+		BrandingEnforcedTotal.WithLabelValues(senderID, brandingRejected).Inc()
+		return "", fmt.Errorf("%w %s, expected %q", errBrandingMissing, senderID, branding)
+	}
+
+	BrandingEnforcedTotal.WithLabelValues(senderID, brandingAppended).Inc()
+	return strings.TrimRight(text, " \t\r\n") + " " + branding, nil
+}
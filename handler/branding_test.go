@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	config "MgApplication/api-config"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func brandingConfig(appendMissing bool) *config.Config {
+	c := config.NewConfig(viper.New())
+	c.Set("sms.branding", map[string]string{"INPOST": "- INDPOST", "DOPBNK": "- IndiaPost Bank"})
+	c.Set("sms.brandingappend", appendMissing)
+	return c
+}
+
+func TestSenderBranding(t *testing.T) {
+	b := NewSenderBranding(brandingConfig(true))
+
+	text, err := b.Apply("INPOST", "Your article is delivered - INDPOST.")
+	assert.NoError(t, err)
+	assert.Equal(t, "Your article is delivered - INDPOST.", text)
+
+	text, err = b.Apply("INPOST", "Your article is delivered ")
+	assert.NoError(t, err)
+	assert.Equal(t, "Your article is delivered - INDPOST", text)
+
+	_, err = b.Apply("INPOST", "Your account is credited - IndiaPost Bank")
+	assert.ErrorContains(t, err, "branding of another sender DOPBNK")
+
+	// Senders without a branding are not checked
+	text, err = b.Apply("DOPPLI", "Your premium is due")
+	assert.NoError(t, err)
+	assert.Equal(t, "Your premium is due", text)
+
+	b = NewSenderBranding(brandingConfig(false))
+	_, err = b.Apply("INPOST", "Your article is delivered")
+	assert.ErrorContains(t, err, "does not end with the branding of the sender INPOST")
+}
+
+// sendBrandedSMS sends messageText as INPOST through a CDAC server and returns the response code
+// and the message text received by the gateway
+func sendBrandedSMS(t *testing.T, messageText string, appendMissing bool) (int, string) {
+	t.Helper()
+	var content string
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content = r.FormValue("content")
+		_, _ = w.Write([]byte("402,MsgID = 060320251741252969160appostsms"))
+	}))
+	defer cdac.Close()
+
+	c := brandingConfig(appendMissing)
+	c.Set("sms.msgstorerequest", 1)
+	c.Set("sms.cdac.url", cdac.URL)
+	c.Set("sms.cdac.username", "appostsms")
+	c.Set("sms.cdac.password", "cdacsecret")
+	c.Set("sms.cdac.securekey", "c7d427c9-63e7-4eec-a227-3ef840a75269")
+
+	ch, _ := newTestSMSHandler(c)
+	body := otpRequestBody("9000000001")
+	body["message_text"] = messageText
+	return postSMSRequest(ch, body).Code, content
+}
+
+func TestCreateSMSAppendsMissingBranding(t *testing.T) {
+	code, content := sendBrandedSMS(t, "Dear Customer, OTP for booking is 1234, please do not share it with anyone", true)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "Dear Customer, OTP for booking is 1234, please do not share it with anyone - INDPOST", content)
+}
+
+func TestCreateSMSRejectsMissingBranding(t *testing.T) {
+	code, content := sendBrandedSMS(t, "Dear Customer, OTP for booking is 1234, please do not share it with anyone", false)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "", content)
+}
+
+func TestCreateSMSRejectsConflictingBranding(t *testing.T) {
+	code, content := sendBrandedSMS(t, "Dear Customer, OTP for booking is 1234 - IndiaPost Bank", true)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "", content)
+}
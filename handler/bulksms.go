@@ -2,11 +2,9 @@ package handler
 
 import (
 	"MgApplication/core/domain"
-	"MgApplication/core/port"
 	"MgApplication/handler/response"
 	"bytes"
 	"encoding/xml"
-	"fmt"
 	"io"
 	"net/http"
 	"strings"
@@ -103,21 +101,24 @@ func (ch *MgApplicationHandler) InitiateBulkSMSHandler(ctx *gin.Context) {
 		return
 	}
 
-	if req.MessageType == "UC" {
+	if domain.MessageType(req.MessageType).IsUnicode() {
 		req.TestMessage = UnicodemsgConvertCDAC(req.TestMessage)
 	} else {
-		req.MessageType = "PM"
+		req.MessageType = string(domain.MessageTypePlain)
 	}
 
 	if req.TemplateID != "" && req.SenderID != "" {
+		// Bulk messages are never OTPs, so they carry no priority
 		Bulkrsp, err := ch.SendSMSCDAC(SMSParams{
-			ch.c.GetString("sms.cdac.username"),
-			ch.c.GetString("sms.cdac.password"),
-			req.TestMessage, req.SenderID,
-			req.MobileNo,
-			ch.c.GetString("sms.cdac.securekey"),
-			req.TemplateID,
-			req.MessageType})
+			Username:     ch.c.GetString("sms.cdac.username"),
+			Password:     ch.c.GetString("sms.cdac.password"),
+			Message:      req.TestMessage,
+			SenderID:     req.SenderID,
+			MobileNumber: req.MobileNo,
+			SecureKey:    ch.c.GetString("sms.cdac.securekey"),
+			TemplateID:   req.TemplateID,
+			MessageType:  req.MessageType,
+		})
 		if err != nil {
 			log.Error(ctx, "Error sending SMS using SendSMSCDAC: %s", err.Error())
 			// ch.vs.handleError(ctx, err)
@@ -131,11 +132,10 @@ func (ch *MgApplicationHandler) InitiateBulkSMSHandler(ctx *gin.Context) {
 
 		rsp := response.NewBulkSMSInitiateResponse(Bulkrsp, req.ReferenceID)
 		apiRsp := response.BulkSMSInitiateAPIResponse{
-			StatusCodeAndMessage: port.CreateSuccess,
-			Data:                 rsp,
+			Data: rsp,
 		}
 
-		handleCreateSuccess(ctx, apiRsp)
+		response.Created(ctx, &apiRsp)
 	} else {
 		// ch.vs.handleError(ctx, errors.New("could not initiate bulk sms"))
 		// apperror :=apierrors.AppError("could not initiate bulk sms", apierrors.HTTPErrorBadRequest.StatusCode, "")
@@ -205,12 +205,11 @@ func (ch *MgApplicationHandler) ValidateTestSMSHandler(ctx *gin.Context) {
 	//handleSuccess(ctx, isvalid)
 
 	apiRsp := response.ValidateBulkSMSOTPAPIResponse{
-		StatusCodeAndMessage: port.UpdateSuccess,
-		Data:                 isvalid,
+		Data: isvalid,
 	}
 
 	log.Debug(ctx, "ValidateTestSMSHandler response: %v", apiRsp)
-	handleSuccess(ctx, apiRsp)
+	response.Updated(ctx, &apiRsp)
 }
 
 type FileInfo struct {
@@ -268,13 +267,20 @@ type SendBulkSMSRequestOld struct {
 	MessageType string `json:"message_type" validate:"required" `
 }
 
+// Bulk SMSes are sent with the bulk priority, and every recipient is reported as submitted
+// unless skipped before sending
+const (
+	bulkSMSPriority        = int(domain.PriorityBulk)
+	bulkSMSStatusSubmitted = "SUBMITTED"
+)
+
 type sendBulkSMSRequest struct {
 	SenderID     string `json:"sender_id" validate:"required"`
 	MobileNumber string `json:"mobile_number" validate:"required"`
-	MessageType  string `json:"message_type" validate:"required"`
-	MessageText  string `json:"message_text" validate:"required"`
+	MessageType  string `json:"message_type" validate:"required,message_type" enum:"PM,UC"`
+	MessageText  string `json:"message_text" validate:"required,message_text"`
 	TemplateID   string `json:"template_id" validate:"required"`
-	EntityID     string `json:"entity_id" validate:"required"`
+	EntityID     string `json:"entity_id" validate:"required,entity_id"`
 }
 
 /*
@@ -338,10 +344,10 @@ func (ch *MgApplicationHandler) SendBulkSMSOld(gctx *gin.Context) {
 		if i == 0 {
 			continue // Skip the first row
 		}
-		if req.MessageType == "UC" {
+		if domain.MessageType(req.MessageType).IsUnicode() {
 			row[1] = UnicodemsgConvertNIC(row[1])
 		} else {
-			req.MessageType = "PM"
+			req.MessageType = string(domain.MessageTypePlain)
 		}
 
 		messageList = append(messageList, MessageList{
@@ -371,9 +377,8 @@ func (ch *MgApplicationHandler) SendBulkSMSOld(gctx *gin.Context) {
 		return
 	}
 
-	// Print the generated XML data for inspection
-	fmt.Println("Generated XML:")
-	fmt.Println(string(xmlData))
+	// the XML carries the NIC pin, only its size is logged
+	log.Debug(gctx, "Generated NIC bulk XML of %d messages, %d bytes", len(messageList), len(xmlData))
 
 	// Send the XML data to the NIC URL
 	// NICBulkURL := ch.c.NICBulkURL()
@@ -412,18 +417,17 @@ func (ch *MgApplicationHandler) SendBulkSMSOld(gctx *gin.Context) {
 
 	rsp := response.NewSendBulkSMSResponseOld(responseJSON)
 	apiRsp := response.SendBulkSMSAPIResponse{
-		StatusCodeAndMessage: port.CreateSuccess,
-		Data:                 rsp,
+		Data: rsp,
 	}
 
-	handleSuccess(gctx, apiRsp)
+	response.Created(gctx, &apiRsp)
 }
 */
 
 // SendBulkSMS godoc.
 //
 //	@Summary		Send processed Excel file data
-//	@Description	This API reads the output Excel file, constructs messages, and sends them to the NIC service in XML format.
+//	@Description	This API reads the output Excel file, constructs messages, and sends them to the NIC service in XML format. With the DND check enabled, numbers registered as DND are not sent to and are reported with status SKIPPED_DND.
 //	@Tags			BulkSMS
 //	@ID				SendBulkSMSHandler
 //	@Accept			json
@@ -448,13 +452,8 @@ func (ch *MgApplicationHandler) SendBulkSMSHandler(gctx *gin.Context) {
 		return
 	}
 
-	// if !ch.vs.handleValidation(ctx, req) {
-	// 	fmt.Println("Validation failed for the request.") // Debugging log
-	// 	return
-	// }
 	for _, sms := range req {
 		if err := validation.ValidateStruct(sms); err != nil {
-			// fmt.Printf("sms request is %v", sms)
 			apierrors.HandleValidationError(gctx, err)
 			log.Error(gctx, "Validation failed for sendBulkSMSRequest: %s", err.Error())
 			return
@@ -468,10 +467,20 @@ func (ch *MgApplicationHandler) SendBulkSMSHandler(gctx *gin.Context) {
 		return
 	}
 
+	for i := range req {
+		messageText, err := ch.branding.Apply(req[0].SenderID, req[i].MessageText)
+		if err != nil {
+			log.Error(gctx, "Branding check failed for sendBulkSMSRequest: %s", err.Error())
+			apierrors.ErrorResponseWithStatusCodeAndMessage(gctx, apierrors.HTTPErrorBadRequest, err.Error(), err)
+			return
+		}
+		req[i].MessageText = messageText
+	}
+
 	//Setting NIC Credentials Based on SenderID
 	var NICUsername, NICPassword string
 	senderID := req[0].SenderID
-	fmt.Println("SenderID:", senderID)
+	log.Debug(gctx, "Sending bulk SMS of sender %s", senderID)
 
 	switch senderID {
 	case "INPOST":
@@ -493,14 +502,49 @@ func (ch *MgApplicationHandler) SendBulkSMSHandler(gctx *gin.Context) {
 		log.Error(gctx, "Unknown SenderID provided: %s", senderID)
 	}
 
+	// Bulk messages are not sent to numbers registered as DND
+	mobileNumbers := make([]string, 0, len(req))
+	for _, row := range req {
+		mobileNumbers = append(mobileNumbers, row.MobileNumber)
+	}
+	_, skipped, err := ch.dnd.Filter(gctx, bulkSMSPriority, mobileNumbers)
+	if err != nil {
+		apierrors.HandleError(gctx, err)
+		return
+	}
+	skippedNumbers := make(map[string]bool, len(skipped))
+	for _, mobileNumber := range skipped {
+		skippedNumbers[mobileNumber] = true
+	}
+
+	recipients := make([]domain.RecipientStatus, 0, len(req))
+	for _, row := range req {
+		status := bulkSMSStatusSubmitted
+		if skippedNumbers[row.MobileNumber] {
+			status = DNDStatusSkipped
+		}
+		recipients = append(recipients, domain.RecipientStatus{MobileNumber: row.MobileNumber, Status: status})
+	}
+	if len(skipped) == len(req) {
+		rsp := response.NewSendBulkSMSResponse(&domain.NicResponseXml{}, recipients)
+		apiRsp := response.SendBulkSMSAPIResponse{
+			Data: rsp,
+		}
+		response.Created(gctx, &apiRsp)
+		return
+	}
+
 	// Constructing Message List (Skipping First Row)
 	var messageList []MessageList
 	for _, row := range req {
+		if skippedNumbers[row.MobileNumber] {
+			continue
+		}
 		messageType := req[0].MessageType // Assuming MessageType is the same for all entries
-		if messageType == "UC" {
+		if domain.MessageType(messageType).IsUnicode() {
 			row.MessageText = UnicodemsgConvertNIC(row.MessageText)
 		} else {
-			messageType = "PM"
+			messageType = string(domain.MessageTypePlain)
 		}
 
 		messageList = append(messageList, MessageList{
@@ -529,7 +573,8 @@ func (ch *MgApplicationHandler) SendBulkSMSHandler(gctx *gin.Context) {
 		gctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert data to XML"})
 		return
 	}
-	fmt.Println("Generated XML:", string(xmlData))
+	// the XML carries the NIC pin, only its size is logged
+	log.Debug(gctx, "Generated NIC bulk XML of %d messages, %d bytes", len(messageList), len(xmlData))
 
 	// Sending XML Data to NIC Bulk URL
 	// NICBulkURL := ch.c.NICBulkURL()
@@ -541,7 +586,6 @@ func (ch *MgApplicationHandler) SendBulkSMSHandler(gctx *gin.Context) {
 		apierrors.HandleWithMessage(gctx, "Failed to send data to NIC")
 		return
 	}
-	// fmt.Println("response is", resp)
 	defer resp.Body.Close()
 
 	// Read and Parse the NIC Response
@@ -553,11 +597,11 @@ func (ch *MgApplicationHandler) SendBulkSMSHandler(gctx *gin.Context) {
 
 	var nicResponse domain.NicResponseXml
 	if err := xml.Unmarshal(responseData, &nicResponse); err != nil {
-		fmt.Println("XML Unmarshal Error:", err)
+		log.Error(gctx, "Unable to parse the NIC response: %s", err.Error())
 		apierrors.HandleWithMessage(gctx, "Failed to parse NIC response")
 		return
 	}
-	fmt.Println("Parsed NIC response:", nicResponse)
+	log.Debug(gctx, "Parsed NIC response: %v", nicResponse)
 
 	// Construct and Send the Final JSON Response
 	// responseJSON := domain.NicResponse{
@@ -574,13 +618,12 @@ func (ch *MgApplicationHandler) SendBulkSMSHandler(gctx *gin.Context) {
 	// 	Data:                 rsp1,
 	// }
 
-	rsp := response.NewSendBulkSMSResponse(&nicResponse)
+	rsp := response.NewSendBulkSMSResponse(&nicResponse, recipients)
 	apiRsp := response.SendBulkSMSAPIResponse{
-		StatusCodeAndMessage: port.CreateSuccess,
-		Data:                 rsp,
+		Data: rsp,
 	}
 
-	handleCreateSuccess(gctx, apiRsp)
+	response.Created(gctx, &apiRsp)
 }
 
 // IsShuttingDown checks if the application is in the process of shutting down
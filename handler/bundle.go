@@ -0,0 +1,286 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	apierrors "MgApplication/api-errors"
+	log "MgApplication/api-log"
+	serverRoute "MgApplication/api-server/route"
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+	"MgApplication/handler/response"
+	repo "MgApplication/repo/postgres"
+)
+
+// bundleStore reads and writes the configuration bundles, the ConfigBundleRepository outside the
+// tests
+type bundleStore interface {
+	ExportBundleRepo(ctx context.Context) (domain.ConfigBundle, error)
+	ImportBundleRepo(ctx context.Context, plan repo.BundlePlanner, dryRun bool) (domain.BundleImportPlan, error)
+}
+
+// ExportBundleHandler godoc
+//
+//	@Summary		Exports the configuration bundle
+//	@Description	Returns the applications, without their secret keys, and the templates as a versioned JSON bundle, to be imported in another environment by the import-bundle request. Applications are keyed by name and templates by DLT template id
+//	@Tags			Admin
+//	@ID				ExportBundleHandler
+//	@Produce		json
+//	@Param			Authorization	header		string							true	"Bearer token whose scope claim includes the admin scope"
+//	@Success		200				{object}	response.ConfigBundleAPIResponse	"Configuration bundle is exported"
+//	@Failure		403				{object}	apierrors.APIErrorResponse		"Forbidden"
+//	@Failure		500				{object}	apierrors.APIErrorResponse		"Internal server error"
+//	@Router			/admin/export-bundle [get]
+func (ah *AdminHandler) ExportBundleHandler(sctx *serverRoute.Context, req serverRoute.NoParam) (*response.ConfigBundleAPIResponse, error) {
+	bundle, err := ah.bundlesvc.ExportBundleRepo(sctx.Ctx)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in ExportBundleRepo function: %s", err.Error())
+		return nil, err
+	}
+	return &response.ConfigBundleAPIResponse{
+		StatusCodeAndMessage: port.FetchSuccess,
+		Data:                 &bundle,
+	}, nil
+}
+
+type importBundleRequest struct {
+	DryRun        bool                      `form:"dry_run" json:"-" example:"true"`
+	Prune         bool                      `form:"prune" json:"-" example:"false"`
+	SchemaVersion int                       `json:"schema_version" validate:"required" example:"1"`
+	Applications  []importBundleApplication `json:"applications" validate:"dive"`
+	Templates     []importBundleTemplate    `json:"templates" validate:"dive"`
+}
+
+type importBundleApplication struct {
+	ApplicationName string  `json:"application_name" validate:"required" example:"Test Application"`
+	RequestType     string  `json:"request_type" validate:"required,request_type" example:"1"`
+	Status          bool    `json:"status" example:"true"`
+	ShadowGateway   *string `json:"shadow_gateway" validate:"omitempty,gateway_id" enum:"1,2" example:"2"`
+}
+
+type importBundleTemplate struct {
+	TemplateID      string `json:"template_id" validate:"required,numeric" pattern:"^[0-9]+$" example:"1007188452935484904"`
+	ApplicationName string `json:"application_name" validate:"required" example:"Test Application"`
+	TemplateName    string `json:"template_name" validate:"required" example:"Test Template"`
+	TemplateFormat  string `json:"template_format" validate:"required" example:"Dear {#var#}, Greetings from India Post on the occasion of {#var#} - Indiapost"`
+	SenderID        string `json:"sender_id" validate:"required" example:"INPOST"`
+	EntityID        string `json:"entity_id" validate:"omitempty,entity_id" pattern:"^[0-9]{19}$" example:"1001051725995192803"`
+	Gateway         string `json:"gateway" validate:"required,gateway_id" enum:"1,2" example:"1"`
+	MessageType     string `json:"message_type" validate:"required,message_type" enum:"PM,UC" example:"PM"`
+	Status          bool   `json:"status" example:"true"`
+}
+
+// bundle returns the configuration bundle of the request
+func (req importBundleRequest) bundle() domain.ConfigBundle {
+	bundle := domain.ConfigBundle{SchemaVersion: req.SchemaVersion}
+	for _, app := range req.Applications {
+		// an empty shadow gateway is none, as stored by SetShadowGatewayHandler
+		if app.ShadowGateway != nil && *app.ShadowGateway == "" {
+			app.ShadowGateway = nil
+		}
+		bundle.Applications = append(bundle.Applications, domain.BundleApplication(app))
+	}
+	for _, tmpl := range req.Templates {
+		bundle.Templates = append(bundle.Templates, domain.BundleTemplate(tmpl))
+	}
+	return bundle
+}
+
+// ImportBundleHandler godoc
+//
+//	@Summary		Imports a configuration bundle
+//	@Description	Creates and updates the applications and templates of a bundle exported by the export-bundle request, in one transaction. Applications are matched by name and templates by DLT template id. The secret keys of existing applications are never changed, created applications get a new one. Applications and templates missing from the bundle are left as they are, or deactivated with prune=true.
+//	@Description	The import is refused as a whole when the bundle has another schema version, repeats a key, has templates of unknown applications or conflicts with the configuration in place: an application of another request type, or a template of another application, entity id or sender id. With dry_run=true nothing is written and the response previews the changes
+//	@Tags			Admin
+//	@ID				ImportBundleHandler
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization		header		string							true	"Bearer token whose scope claim includes the admin scope"
+//	@Param			dry_run				query		bool							false	"Preview the changes without writing them"
+//	@Param			prune				query		bool							false	"Deactivate the applications and templates missing from the bundle"
+//	@Param			importBundleRequest	body		importBundleRequest				true	"Configuration bundle"
+//	@Success		200					{object}	response.BundleImportAPIResponse	"Configuration bundle is imported, or previewed with dry_run"
+//	@Failure		400					{object}	apierrors.APIErrorResponse		"Bad Request"
+//	@Failure		403					{object}	apierrors.APIErrorResponse		"Forbidden"
+//	@Failure		422					{object}	apierrors.APIErrorResponse		"Binding, validation or conflict error"
+//	@Failure		500					{object}	apierrors.APIErrorResponse		"Internal server error"
+//	@Router			/admin/import-bundle [post]
+func (ah *AdminHandler) ImportBundleHandler(sctx *serverRoute.Context, req importBundleRequest) (*response.BundleImportAPIResponse, error) {
+	if req.SchemaVersion != domain.ConfigBundleVersion {
+		msg := fmt.Sprintf("unsupported bundle schema version %d, expected %d", req.SchemaVersion, domain.ConfigBundleVersion)
+		appErr := apierrors.NewAppError(msg, http.StatusUnprocessableEntity, errors.New(msg))
+		appErr.SetFieldErrors([]apierrors.FieldError{appErr.NewFieldError("schema_version", req.SchemaVersion, msg, "eq")})
+		return nil, &appErr
+	}
+
+	incoming := req.bundle()
+	plan, err := ah.bundlesvc.ImportBundleRepo(sctx.Ctx, func(current domain.ConfigBundle) (domain.BundleImportPlan, error) {
+		plan, err := planBundleImport(current, incoming, req.Prune)
+		if err != nil || req.DryRun {
+			return plan, err
+		}
+		for i := range plan.Applications {
+			if plan.Applications[i].Action != domain.BundleCreated {
+				continue
+			}
+			if plan.Applications[i].SecretKey, err = GenerateRandomString(secretKeyLength(ah.c)); err != nil {
+				return domain.BundleImportPlan{}, err
+			}
+		}
+		return plan, nil
+	}, req.DryRun)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in ImportBundleRepo function: %s", err.Error())
+		return nil, err
+	}
+	if !req.DryRun {
+		log.Info(sctx.Ctx, "Imported configuration bundle by %s: %v", adminUserID(sctx.Ctx), response.BundleImportSummary(plan))
+	}
+
+	return &response.BundleImportAPIResponse{
+		StatusCodeAndMessage: port.UpdateSuccess,
+		Data:                 response.NewBundleImportResponse(plan, req.DryRun),
+	}, nil
+}
+
+// planBundleImport returns the change of every entity of incoming against current, the entities
+// of current missing from incoming being pruned when prune is set. Every key repeated, template
+// of an unknown application and conflict on an immutable field is reported as a field error of
+// a 422 AppError, and nothing is planned.
+func planBundleImport(current, incoming domain.ConfigBundle, prune bool) (domain.BundleImportPlan, error) {
+	appErr := apierrors.NewAppError("configuration bundle conflicts with the configuration in place", http.StatusUnprocessableEntity, errors.New("configuration bundle conflicts with the configuration in place"))
+	var fieldErrors []apierrors.FieldError
+
+	currentApps := make(map[string]domain.BundleApplication, len(current.Applications))
+	for _, app := range current.Applications {
+		currentApps[app.ApplicationName] = app
+	}
+	currentTemplates := make(map[string]domain.BundleTemplate, len(current.Templates))
+	for _, tmpl := range current.Templates {
+		currentTemplates[tmpl.TemplateID] = tmpl
+	}
+
+	var plan domain.BundleImportPlan
+	incomingApps := make(map[string]bool, len(incoming.Applications))
+	for i, app := range incoming.Applications {
+		field := "applications[" + strconv.Itoa(i) + "]"
+		if incomingApps[app.ApplicationName] {
+			fieldErrors = append(fieldErrors, appErr.NewFieldError(field+".application_name", app.ApplicationName, "application "+app.ApplicationName+" is repeated in the bundle", "unique"))
+			continue
+		}
+		incomingApps[app.ApplicationName] = true
+
+		existing, ok := currentApps[app.ApplicationName]
+		if !ok {
+			plan.Applications = append(plan.Applications, domain.BundleApplicationChange{Action: domain.BundleCreated, Application: app})
+			continue
+		}
+		if existing.RequestType != app.RequestType {
+			fieldErrors = append(fieldErrors, appErr.NewFieldError(field+".request_type", app.RequestType,
+				fmt.Sprintf("application %s has request type %s in place, which cannot be changed to %s", app.ApplicationName, existing.RequestType, app.RequestType), "immutable"))
+			continue
+		}
+		var fields []string
+		if existing.Status != app.Status {
+			fields = append(fields, "status")
+		}
+		if !equalGateway(existing.ShadowGateway, app.ShadowGateway) {
+			fields = append(fields, "shadow_gateway")
+		}
+		plan.Applications = append(plan.Applications, domain.BundleApplicationChange{Action: bundleAction(fields), Fields: fields, Application: app})
+	}
+
+	incomingTemplates := make(map[string]bool, len(incoming.Templates))
+	for i, tmpl := range incoming.Templates {
+		field := "templates[" + strconv.Itoa(i) + "]"
+		if incomingTemplates[tmpl.TemplateID] {
+			fieldErrors = append(fieldErrors, appErr.NewFieldError(field+".template_id", tmpl.TemplateID, "template "+tmpl.TemplateID+" is repeated in the bundle", "unique"))
+			continue
+		}
+		incomingTemplates[tmpl.TemplateID] = true
+
+		// a pruned application cannot keep templates
+		if _, ok := currentApps[tmpl.ApplicationName]; !incomingApps[tmpl.ApplicationName] && (prune || !ok) {
+			fieldErrors = append(fieldErrors, appErr.NewFieldError(field+".application_name", tmpl.ApplicationName,
+				fmt.Sprintf("template %s belongs to application %s, which is not in the bundle", tmpl.TemplateID, tmpl.ApplicationName), "exists"))
+			continue
+		}
+
+		existing, ok := currentTemplates[tmpl.TemplateID]
+		if !ok {
+			plan.Templates = append(plan.Templates, domain.BundleTemplateChange{Action: domain.BundleCreated, Template: tmpl})
+			continue
+		}
+		conflicts := len(fieldErrors)
+		for _, immutable := range []struct{ name, inPlace, imported string }{
+			{"application_name", existing.ApplicationName, tmpl.ApplicationName},
+			{"entity_id", existing.EntityID, tmpl.EntityID},
+			{"sender_id", existing.SenderID, tmpl.SenderID},
+		} {
+			if immutable.inPlace != immutable.imported {
+				fieldErrors = append(fieldErrors, appErr.NewFieldError(field+"."+immutable.name, immutable.imported,
+					fmt.Sprintf("template %s has %s %s in place, which cannot be changed to %s", tmpl.TemplateID, immutable.name, immutable.inPlace, immutable.imported), "immutable"))
+			}
+		}
+		if len(fieldErrors) > conflicts {
+			continue
+		}
+		var fields []string
+		for _, mutable := range []struct {
+			name    string
+			changed bool
+		}{
+			{"template_name", existing.TemplateName != tmpl.TemplateName},
+			{"template_format", existing.TemplateFormat != tmpl.TemplateFormat},
+			{"gateway", existing.Gateway != tmpl.Gateway},
+			{"message_type", existing.MessageType != tmpl.MessageType},
+			{"status", existing.Status != tmpl.Status},
+		} {
+			if mutable.changed {
+				fields = append(fields, mutable.name)
+			}
+		}
+		plan.Templates = append(plan.Templates, domain.BundleTemplateChange{Action: bundleAction(fields), Fields: fields, Template: tmpl})
+	}
+
+	if len(fieldErrors) > 0 {
+		appErr.SetFieldErrors(fieldErrors)
+		return domain.BundleImportPlan{}, &appErr
+	}
+
+	if prune {
+		for _, app := range current.Applications {
+			if !incomingApps[app.ApplicationName] && app.Status {
+				app.Status = false
+				plan.Applications = append(plan.Applications, domain.BundleApplicationChange{Action: domain.BundlePruned, Fields: []string{"status"}, Application: app})
+			}
+		}
+		for _, tmpl := range current.Templates {
+			if !incomingTemplates[tmpl.TemplateID] && tmpl.Status {
+				tmpl.Status = false
+				plan.Templates = append(plan.Templates, domain.BundleTemplateChange{Action: domain.BundlePruned, Fields: []string{"status"}, Template: tmpl})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// bundleAction returns the action on an entity of which fields changed
+func bundleAction(fields []string) string {
+	if len(fields) == 0 {
+		return domain.BundleSkipped
+	}
+	return domain.BundleUpdated
+}
+
+// equalGateway reports whether two optional gateways are the same
+func equalGateway(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
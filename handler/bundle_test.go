@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	auth "MgApplication/api-authz"
+	config "MgApplication/api-config"
+	"MgApplication/api-server/middlewares"
+	"MgApplication/core/domain"
+	repo "MgApplication/repo/postgres"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBundleStore keeps the configuration in memory, applying the plans as the
+// ConfigBundleRepository does
+type fakeBundleStore struct {
+	bundle     domain.ConfigBundle
+	secretKeys map[string]string
+}
+
+func (s *fakeBundleStore) ExportBundleRepo(ctx context.Context) (domain.ConfigBundle, error) {
+	bundle := s.bundle
+	bundle.SchemaVersion, bundle.ExportedAt = domain.ConfigBundleVersion, time.Now().UTC()
+	return bundle, nil
+}
+
+func (s *fakeBundleStore) ImportBundleRepo(ctx context.Context, plan repo.BundlePlanner, dryRun bool) (domain.BundleImportPlan, error) {
+	planned, err := plan(s.bundle)
+	if err != nil || dryRun {
+		return planned, err
+	}
+	for _, change := range planned.Applications {
+		switch change.Action {
+		case domain.BundleCreated:
+			s.bundle.Applications = append(s.bundle.Applications, change.Application)
+			s.secretKeys[change.Application.ApplicationName] = change.SecretKey
+		case domain.BundleUpdated, domain.BundlePruned:
+			for i := range s.bundle.Applications {
+				if s.bundle.Applications[i].ApplicationName == change.Application.ApplicationName {
+					s.bundle.Applications[i] = change.Application
+				}
+			}
+		}
+	}
+	for _, change := range planned.Templates {
+		switch change.Action {
+		case domain.BundleCreated:
+			s.bundle.Templates = append(s.bundle.Templates, change.Template)
+		case domain.BundleUpdated, domain.BundlePruned:
+			for i := range s.bundle.Templates {
+				if s.bundle.Templates[i].TemplateID == change.Template.TemplateID {
+					s.bundle.Templates[i] = change.Template
+				}
+			}
+		}
+	}
+	return planned, nil
+}
+
+// bundleServer serves the admin routes over store, for callers authenticated by bundleBearer
+func bundleServer(t *testing.T, store *fakeBundleStore) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c := config.NewConfig(viper.New())
+	c.Set("admin.scope", "admin")
+	ah := NewAdminHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, c)
+	ah.bundlesvc = store
+
+	engine := gin.New()
+	engine.Use(middlewares.ErrorHandler(), auth.Authenticate([]byte("secret")))
+	group := engine.Group(ah.Prefix(), ah.Middlewares()...)
+	for _, route := range ah.Routes() {
+		meta := route.Meta()
+		group.Handle(meta.Method, meta.Path, meta.Func)
+	}
+	return engine
+}
+
+func bundleBearer(scope string) string {
+	return "Bearer " + auth.SignToken(auth.Claims{Subject: "ops1", Scope: scope, ExpiresAt: time.Now().Add(time.Hour).Unix()}, []byte("secret"))
+}
+
+func callBundle(t *testing.T, engine *gin.Engine, method, target string, body any, scope string) *httptest.ResponseRecorder {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&payload).Encode(body))
+	}
+	req := httptest.NewRequest(method, target, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", bundleBearer(scope))
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+func exportBundle(t *testing.T, engine *gin.Engine) domain.ConfigBundle {
+	t.Helper()
+	rec := callBundle(t, engine, http.MethodGet, "/v1/admin/export-bundle", nil, "admin")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var rsp struct {
+		Data domain.ConfigBundle `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	return rsp.Data
+}
+
+type bundleImportResult struct {
+	Data struct {
+		DryRun       bool                             `json:"dry_run"`
+		Summary      map[string]map[string]int        `json:"summary"`
+		Applications []domain.BundleApplicationChange `json:"applications"`
+		Templates    []domain.BundleTemplateChange    `json:"templates"`
+	} `json:"data"`
+	Error struct {
+		FieldErrors []struct {
+			Field string `json:"field"`
+			Tag   string `json:"tag"`
+		} `json:"field_errors"`
+	} `json:"error"`
+}
+
+func importBundle(t *testing.T, engine *gin.Engine, query string, bundle domain.ConfigBundle) (int, bundleImportResult) {
+	t.Helper()
+	rec := callBundle(t, engine, http.MethodPost, "/v1/admin/import-bundle"+query, bundle, "admin")
+	var rsp bundleImportResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp), rec.Body.String())
+	return rec.Code, rsp
+}
+
+func testBundleSource() *fakeBundleStore {
+	shadow := "2"
+	return &fakeBundleStore{
+		bundle: domain.ConfigBundle{
+			Applications: []domain.BundleApplication{
+				{ApplicationName: "Booking", RequestType: "1", Status: true, ShadowGateway: &shadow},
+				{ApplicationName: "Tracking", RequestType: "2", Status: false},
+			},
+			Templates: []domain.BundleTemplate{
+				{TemplateID: "1007188452935484904", ApplicationName: "Booking", TemplateName: "Booked", TemplateFormat: "Article {#var#} is booked - INDPOST",
+					SenderID: "INPOST", EntityID: "1001051725995192803", Gateway: "1", MessageType: "PM", Status: true},
+				{TemplateID: "1007344609998507114", ApplicationName: "Tracking", TemplateName: "Delivered", TemplateFormat: "Your article is delivered - INDPOST",
+					SenderID: "INPOST", Gateway: "2", MessageType: "UC", Status: false},
+			},
+		},
+		secretKeys: map[string]string{"Booking": "booking-key", "Tracking": "tracking-key"},
+	}
+}
+
+// An exported bundle imported into an empty environment exports the same bundle
+func TestConfigBundleRoundTrip(t *testing.T) {
+	exported := exportBundle(t, bundleServer(t, testBundleSource()))
+	assert.Equal(t, domain.ConfigBundleVersion, exported.SchemaVersion)
+
+	target := &fakeBundleStore{secretKeys: map[string]string{}}
+	engine := bundleServer(t, target)
+	code, rsp := importBundle(t, engine, "", exported)
+	require.Equal(t, http.StatusOK, code)
+	assert.False(t, rsp.Data.DryRun)
+	assert.Equal(t, map[string]int{domain.BundleCreated: 2}, rsp.Data.Summary["applications"])
+	assert.Equal(t, map[string]int{domain.BundleCreated: 2}, rsp.Data.Summary["templates"])
+
+	reexported := exportBundle(t, engine)
+	reexported.ExportedAt = exported.ExportedAt
+	assert.Equal(t, exported, reexported)
+
+	// created applications get their own secret key, which is never exported
+	assert.Len(t, target.secretKeys["Booking"], secretKeyLength(config.NewConfig(viper.New())))
+	assert.NotEqual(t, target.secretKeys["Booking"], target.secretKeys["Tracking"])
+
+	// importing it again changes nothing
+	code, rsp = importBundle(t, engine, "", exported)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]int{domain.BundleSkipped: 2}, rsp.Data.Summary["applications"])
+	assert.Equal(t, map[string]int{domain.BundleSkipped: 2}, rsp.Data.Summary["templates"])
+}
+
+// A dry run previews the changes and writes nothing
+func TestConfigBundleDryRun(t *testing.T) {
+	store := testBundleSource()
+	engine := bundleServer(t, store)
+	bundle := exportBundle(t, engine)
+	bundle.Applications[1].Status = true
+	bundle.Templates[0].TemplateFormat = "Article {#var#} is booked at {#var#} - INDPOST"
+	bundle.Templates = append(bundle.Templates, domain.BundleTemplate{TemplateID: "1007000000000000001", ApplicationName: "Tracking",
+		TemplateName: "Returned", TemplateFormat: "Your article is returned - INDPOST", SenderID: "INPOST", Gateway: "2", MessageType: "UC"})
+
+	code, rsp := importBundle(t, engine, "?dry_run=true", bundle)
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, rsp.Data.DryRun)
+	assert.Equal(t, map[string]int{domain.BundleSkipped: 1, domain.BundleUpdated: 1}, rsp.Data.Summary["applications"])
+	assert.Equal(t, map[string]int{domain.BundleCreated: 1, domain.BundleSkipped: 1, domain.BundleUpdated: 1}, rsp.Data.Summary["templates"])
+	assert.Equal(t, []string{"status"}, rsp.Data.Applications[1].Fields)
+	assert.Equal(t, []string{"template_format"}, rsp.Data.Templates[0].Fields)
+
+	assert.Equal(t, testBundleSource().bundle, store.bundle)
+	assert.Equal(t, testBundleSource().secretKeys, store.secretKeys)
+}
+
+// Conflicts on immutable fields abort the whole import with a report of every conflict
+func TestConfigBundleConflicts(t *testing.T) {
+	store := testBundleSource()
+	engine := bundleServer(t, store)
+	bundle := exportBundle(t, engine)
+	bundle.Applications[0].Status = false
+	bundle.Applications[1].RequestType = "3"
+	bundle.Templates[0].EntityID = "1001000000000000000"
+	bundle.Templates[1].ApplicationName = "Booking"
+	bundle.Templates = append(bundle.Templates, bundle.Templates[0], domain.BundleTemplate{TemplateID: "1007000000000000001",
+		ApplicationName: "Unknown", TemplateName: "Returned", TemplateFormat: "Returned - INDPOST", SenderID: "INPOST", Gateway: "2", MessageType: "UC"})
+
+	code, rsp := importBundle(t, engine, "", bundle)
+	require.Equal(t, http.StatusUnprocessableEntity, code)
+	var fields, tags []string
+	for _, e := range rsp.Error.FieldErrors {
+		fields, tags = append(fields, e.Field), append(tags, e.Tag)
+	}
+	assert.Equal(t, []string{"applications[1].request_type", "templates[0].entity_id", "templates[1].application_name", "templates[2].template_id", "templates[3].application_name"}, fields)
+	assert.Equal(t, []string{"immutable", "immutable", "immutable", "unique", "exists"}, tags)
+	assert.Equal(t, testBundleSource().bundle, store.bundle)
+}
+
+// Entities missing from the bundle are left alone, or deactivated with prune
+func TestConfigBundlePrune(t *testing.T) {
+	store := testBundleSource()
+	store.bundle.Applications[1].Status = true
+	engine := bundleServer(t, store)
+	bundle := exportBundle(t, engine)
+	bundle.Applications, bundle.Templates = bundle.Applications[:1], bundle.Templates[:1]
+
+	code, rsp := importBundle(t, engine, "", bundle)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]int{domain.BundleSkipped: 1}, rsp.Data.Summary["applications"])
+	assert.True(t, store.bundle.Applications[1].Status)
+
+	code, rsp = importBundle(t, engine, "?prune=true", bundle)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]int{domain.BundleSkipped: 1, domain.BundlePruned: 1}, rsp.Data.Summary["applications"])
+	// the template was inactive already
+	assert.Equal(t, map[string]int{domain.BundleSkipped: 1}, rsp.Data.Summary["templates"])
+	assert.False(t, store.bundle.Applications[1].Status)
+	assert.Len(t, store.bundle.Applications, 2)
+	assert.Equal(t, "tracking-key", store.secretKeys["Tracking"])
+}
+
+func TestConfigBundleRejected(t *testing.T) {
+	store := testBundleSource()
+	engine := bundleServer(t, store)
+	bundle := exportBundle(t, engine)
+
+	unsupported := bundle
+	unsupported.SchemaVersion = domain.ConfigBundleVersion + 1
+	code, rsp := importBundle(t, engine, "", unsupported)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	if assert.Len(t, rsp.Error.FieldErrors, 1) {
+		assert.Equal(t, "schema_version", rsp.Error.FieldErrors[0].Field)
+	}
+
+	invalid := bundle
+	invalid.Templates = []domain.BundleTemplate{{TemplateID: "1007000000000000001", ApplicationName: "Booking", Gateway: "9"}}
+	code, _ = importBundle(t, engine, "", invalid)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+
+	// both routes need the admin scope
+	assert.Equal(t, http.StatusForbidden, callBundle(t, engine, http.MethodGet, "/v1/admin/export-bundle", nil, "reports").Code)
+	assert.Equal(t, http.StatusForbidden, callBundle(t, engine, http.MethodPost, "/v1/admin/import-bundle", bundle, "reports").Code)
+	assert.Equal(t, testBundleSource().bundle, store.bundle)
+}
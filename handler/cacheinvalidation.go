@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	config "MgApplication/api-config"
+	log "MgApplication/api-log"
+	"MgApplication/core/domain"
+	repo "MgApplication/repo/postgres"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var CacheInvalidationsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cache_invalidations_total",
+		Help: "Total number of cache entries evicted, by cache and by reason (notify for a change notified by a replica, flush for a reconnection of the listener)",
+	},
+	[]string{"cache", "reason"},
+)
+
+var CacheListenerReconnectsTotal = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "cache_listener_reconnects_total",
+		Help: "Total number of times the cache change listener lost its connection and reconnected",
+	},
+)
+
+// invalidatingCache is a cache of database entities evicted on their change
+type invalidatingCache interface {
+	// Invalidate evicts the entries of the entity changed, returning how many were evicted
+	Invalidate(change domain.CacheChange) int
+	// Flush evicts every entry, returning how many were evicted
+	Flush() int
+}
+
+// CacheRegistry holds the caches of database entities of the replica, so that the changes
+// notified by any replica evict their entries
+type CacheRegistry struct {
+	mu     sync.RWMutex
+	caches map[string]invalidatingCache
+}
+
+// NewCacheRegistry creates a new, empty CacheRegistry instance
+func NewCacheRegistry() *CacheRegistry {
+	return &CacheRegistry{caches: make(map[string]invalidatingCache)}
+}
+
+// Register adds cache under name, replacing the cache registered under the same name
+func (cr *CacheRegistry) Register(name string, cache invalidatingCache) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.caches[name] = cache
+}
+
+// Invalidate evicts the entries of the entity changed from every cache
+func (cr *CacheRegistry) Invalidate(change domain.CacheChange) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	for name, cache := range cr.caches {
+		if evicted := cache.Invalidate(change); evicted > 0 {
+			CacheInvalidationsTotal.WithLabelValues(name, "notify").Add(float64(evicted))
+		}
+	}
+}
+
+// Flush evicts every entry of every cache
+func (cr *CacheRegistry) Flush() {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	for name, cache := range cr.caches {
+		if evicted := cache.Flush(); evicted > 0 {
+			CacheInvalidationsTotal.WithLabelValues(name, "flush").Add(float64(evicted))
+		}
+	}
+}
+
+// cacheChangeSource delivers the changes of the cached entities notified by every replica
+type cacheChangeSource interface {
+	ListenCacheChangesRepo(ctx context.Context, connected func(), changed func(domain.CacheChange)) error
+}
+
+// CacheInvalidationListener evicts the changes notified by every replica from the caches of the
+// registry, on a database connection of its own. The changes notified while it is disconnected
+// are lost, so the caches are flushed whenever it (re)connects. It reconnects after
+// db.notify.minbackoff, doubling the wait on every failure up to db.notify.maxbackoff.
+type CacheInvalidationListener struct {
+	source     cacheChangeSource
+	registry   *CacheRegistry
+	enabled    bool
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	state      jobState
+	wait       func(ctx context.Context, d time.Duration) bool
+}
+
+// NewCacheInvalidationListener creates a new CacheInvalidationListener instance using the
+// db.notify configuration. The backoff defaults to 1 second, up to 1 minute.
+func NewCacheInvalidationListener(svc *repo.CacheNotifyRepository, registry *CacheRegistry, c *config.Config) *CacheInvalidationListener {
+	return newCacheInvalidationListener(svc, registry, c)
+}
+
+func newCacheInvalidationListener(source cacheChangeSource, registry *CacheRegistry, c *config.Config) *CacheInvalidationListener {
+	minBackoff := c.GetDuration("db.notify.minbackoff")
+	if minBackoff <= 0 {
+		minBackoff = time.Second
+	}
+	maxBackoff := c.GetDuration("db.notify.maxbackoff")
+	if maxBackoff < minBackoff {
+		maxBackoff = max(time.Minute, minBackoff)
+	}
+	return &CacheInvalidationListener{
+		source:     source,
+		registry:   registry,
+		enabled:    c.GetBool("db.notify.enabled"),
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+		wait:       sleepContext,
+	}
+}
+
+// sleepContext waits for d, returning false when ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// Run listens to the cache changes until ctx is cancelled, reconnecting with backoff
+func (l *CacheInvalidationListener) Run(ctx context.Context) {
+	l.state.setRunning(true)
+	defer l.state.setRunning(false)
+
+	backoff := l.minBackoff
+	for {
+		err := l.source.ListenCacheChangesRepo(ctx,
+			func() {
+				l.registry.Flush()
+				backoff = l.minBackoff
+				l.state.cycle(nil)
+			},
+			func(change domain.CacheChange) {
+				l.registry.Invalidate(change)
+				l.state.cycle(nil)
+			})
+		if ctx.Err() != nil {
+			return
+		}
+		l.state.cycle(err)
+		CacheListenerReconnectsTotal.Inc()
+		log.Warn(ctx, "Cache change listener disconnected, reconnecting in %s: %v", backoff, err)
+		if !l.wait(ctx, backoff) {
+			return
+		}
+		backoff = min(2*backoff, l.maxBackoff)
+	}
+}
+
+// WorkerStatus returns the state of the listener for the system status
+func (l *CacheInvalidationListener) WorkerStatus() domain.WorkerStatus {
+	return l.state.status("cache_invalidation", l.enabled)
+}
@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingTemplateLoader loads templates named after their id, counting the loads
+type countingTemplateLoader struct {
+	mu    sync.Mutex
+	loads map[string]int
+	fail  error
+}
+
+func (l *countingTemplateLoader) load(ctx context.Context, templateID string) (domain.MaintainTemplate, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.loads == nil {
+		l.loads = make(map[string]int)
+	}
+	l.loads[templateID]++
+	if l.fail != nil {
+		return domain.MaintainTemplate{}, l.fail
+	}
+	return domain.MaintainTemplate{TemplateID: templateID, TemplateName: "template " + templateID}, nil
+}
+
+func (l *countingTemplateLoader) count(templateID string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.loads[templateID]
+}
+
+func cacheConfig(notify bool) *config.Config {
+	c := config.NewConfig(viper.New())
+	c.Set("db.notify.enabled", notify)
+	c.Set("cache.templates.ttl", "10m")
+	c.Set("cache.templates.fallbackttl", "30s")
+	return c
+}
+
+func TestTemplateCacheTTL(t *testing.T) {
+	for _, notify := range []bool{true, false} {
+		loader := &countingTemplateLoader{}
+		cache := NewTemplateCache(loader.load, cacheConfig(notify))
+		now := time.Now()
+		cache.now = func() time.Time { return now }
+
+		_, err := cache.Get(context.Background(), "1007")
+		require.NoError(t, err)
+		now = now.Add(time.Minute)
+		_, err = cache.Get(context.Background(), "1007")
+		require.NoError(t, err)
+
+		// the short fallback ttl applies without notifications
+		expected := 1
+		if !notify {
+			expected = 2
+		}
+		assert.Equal(t, expected, loader.count("1007"), "notify %v", notify)
+	}
+}
+
+func TestTemplateCacheDoesNotCacheFailures(t *testing.T) {
+	loader := &countingTemplateLoader{fail: errors.New("no rows in result set")}
+	cache := NewTemplateCache(loader.load, cacheConfig(true))
+
+	_, err := cache.Get(context.Background(), "1007")
+	assert.Error(t, err)
+	loader.fail = nil
+	template, err := cache.Get(context.Background(), "1007")
+	require.NoError(t, err)
+	assert.Equal(t, "1007", template.TemplateID)
+	assert.Equal(t, 2, loader.count("1007"))
+}
+
+func TestCacheRegistryInvalidatesTheChangedTemplate(t *testing.T) {
+	loader := &countingTemplateLoader{}
+	cache := NewTemplateCache(loader.load, cacheConfig(true))
+	registry := NewCacheRegistry()
+	registry.Register("test_invalidate", cache)
+
+	for _, id := range []string{"1007", "1008"} {
+		_, err := cache.Get(context.Background(), id)
+		require.NoError(t, err)
+	}
+	registry.Invalidate(domain.CacheChange{Entity: "application", ID: "1007"})
+	registry.Invalidate(domain.CacheChange{Entity: domain.CacheEntityTemplate, ID: "1007"})
+	for _, id := range []string{"1007", "1008"} {
+		_, err := cache.Get(context.Background(), id)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 2, loader.count("1007"))
+	assert.Equal(t, 1, loader.count("1008"))
+	assert.Equal(t, 1.0, testutil.ToFloat64(CacheInvalidationsTotal.WithLabelValues("test_invalidate", "notify")))
+
+	registry.Flush()
+	assert.Equal(t, 2.0, testutil.ToFloat64(CacheInvalidationsTotal.WithLabelValues("test_invalidate", "flush")))
+}
+
+func TestTemplateCacheDropsLoadsRacingAnInvalidation(t *testing.T) {
+	var cache *TemplateCache
+	loads := 0
+	cache = NewTemplateCache(func(ctx context.Context, templateID string) (domain.MaintainTemplate, error) {
+		loads++
+		if loads == 1 {
+			// the template changes while the first load reads it
+			cache.Invalidate(domain.CacheChange{Entity: domain.CacheEntityTemplate, ID: templateID})
+		}
+		return domain.MaintainTemplate{TemplateID: templateID}, nil
+	}, cacheConfig(true))
+
+	for range 3 {
+		_, err := cache.Get(context.Background(), "1007")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, loads)
+}
+
+// fakeCacheChangeSource connects once per session, failing the sessions beyond the changes
+type fakeCacheChangeSource struct {
+	sessions [][]domain.CacheChange
+	attempts int
+	cancel   context.CancelFunc
+}
+
+func (s *fakeCacheChangeSource) ListenCacheChangesRepo(ctx context.Context, connected func(), changed func(domain.CacheChange)) error {
+	s.attempts++
+	if s.attempts > len(s.sessions) {
+		s.cancel()
+		return ctx.Err()
+	}
+	changes := s.sessions[s.attempts-1]
+	if changes == nil {
+		return errors.New("connection refused")
+	}
+	connected()
+	for _, change := range changes {
+		changed(change)
+	}
+	return errors.New("connection reset by peer")
+}
+
+func TestCacheInvalidationListenerReconnects(t *testing.T) {
+	loader := &countingTemplateLoader{}
+	cache := NewTemplateCache(loader.load, cacheConfig(true))
+	registry := NewCacheRegistry()
+	registry.Register("test_listener", cache)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	source := &fakeCacheChangeSource{
+		sessions: [][]domain.CacheChange{
+			{{Entity: domain.CacheEntityTemplate, ID: "1007"}},
+			nil,
+			nil,
+			{},
+		},
+		cancel: cancel,
+	}
+	c := cacheConfig(true)
+	c.Set("db.notify.minbackoff", "1s")
+	c.Set("db.notify.maxbackoff", "3s")
+	listener := newCacheInvalidationListener(source, registry, c)
+	var waits []time.Duration
+	listener.wait = func(ctx context.Context, d time.Duration) bool {
+		waits = append(waits, d)
+		// the cache fills while the listener is disconnected
+		_, err := cache.Get(ctx, "1008")
+		require.NoError(t, err)
+		return true
+	}
+
+	listener.Run(ctx)
+
+	// the backoff doubles up to the max and is reset by a connection
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, time.Second}, waits)
+	assert.Equal(t, 1.0, testutil.ToFloat64(CacheInvalidationsTotal.WithLabelValues("test_listener", "flush")))
+	_, err := cache.Get(ctx, "1008")
+	require.NoError(t, err)
+	assert.Equal(t, 2, loader.count("1008"))
+
+	status := listener.WorkerStatus()
+	assert.Equal(t, "cache_invalidation", status.Name)
+	assert.Equal(t, domain.WorkerStopped, status.Status)
+}
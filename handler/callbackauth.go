@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	log "MgApplication/api-log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authentication mechanisms of the delivery callbacks of a gateway
+const (
+	CallbackAuthSecret = "secret" // the shared secret is sent as is in the header
+	CallbackAuthHMAC   = "hmac"   // the header carries the HMAC of the body keyed with the shared secret
+)
+
+// callbackHashes are the HMAC algorithms supported for the delivery callbacks
+var callbackHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+var errCallbackAuthConfig = errors.New("invalid sms.callback configuration")
+
+// callbackVerifier verifies the delivery callbacks of a gateway
+type callbackVerifier struct {
+	mode      string
+	header    string
+	secret    []byte
+	algorithm string
+	newHash   func() hash.Hash
+}
+
+// DeliveryCallbackAuth authenticates the delivery callbacks posted by the gateways, configured
+// per gateway in sms.callback.gateways: auth is secret (default) to compare the header with the
+// shared secret, or hmac to check the HMAC of the body keyed with the secret, computed with
+// algorithm (sha256 by default) and sent hex or base64 encoded, optionally prefixed with
+// "<algorithm>=". The header defaults to X-Callback-Secret and X-Callback-Signature
+// respectively. Callbacks of gateways not configured are rejected.
+type DeliveryCallbackAuth struct {
+	verifiers map[string]callbackVerifier
+}
+
+// NewDeliveryCallbackAuth creates a new DeliveryCallbackAuth instance using the
+// sms.callback.gateways configuration, failing on gateways without secret or with an unknown
+// mechanism or algorithm
+func NewDeliveryCallbackAuth(c *config.Config) (*DeliveryCallbackAuth, error) {
+	verifiers := make(map[string]callbackVerifier)
+	for gateway := range c.GetStringMap("sms.callback.gateways") {
+		key := "sms.callback.gateways." + gateway
+		verifier := callbackVerifier{
+			mode:   strings.ToLower(c.GetString(key + ".auth")),
+			header: c.GetString(key + ".header"),
+			secret: []byte(c.GetString(key + ".secret")),
+		}
+		if len(verifier.secret) == 0 {
+			return nil, fmt.Errorf("%w: no secret for gateway %s", errCallbackAuthConfig, gateway)
+		}
+		switch verifier.mode {
+		case "", CallbackAuthSecret:
+			verifier.mode = CallbackAuthSecret
+			if verifier.header == "" {
+				verifier.header = "X-Callback-Secret"
+			}
+		case CallbackAuthHMAC:
+			algorithm := strings.ToLower(c.GetString(key + ".algorithm"))
+			if algorithm == "" {
+				algorithm = "sha256"
+			}
+			newHash, ok := callbackHashes[algorithm]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown algorithm %s for gateway %s", errCallbackAuthConfig, algorithm, gateway)
+			}
+			verifier.algorithm = algorithm
+			verifier.newHash = newHash
+			if verifier.header == "" {
+				verifier.header = "X-Callback-Signature"
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown auth %s for gateway %s", errCallbackAuthConfig, verifier.mode, gateway)
+		}
+		verifiers[strings.ToLower(gateway)] = verifier
+	}
+	return &DeliveryCallbackAuth{verifiers: verifiers}, nil
+}
+
+// Verify returns the middleware rejecting the callbacks of the gateway named by the gateway path
+// parameter that do not carry its secret or a valid signature. The body stays readable by the
+// handler.
+func (a *DeliveryCallbackAuth) Verify() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		gateway := strings.ToLower(ctx.Param("gateway"))
+		verifier, ok := a.verifiers[gateway]
+		if !ok {
+			log.Warn(ctx, "Delivery callback rejected, gateway %q is not configured", gateway)
+			apierrors.HandleUnauthorizedError(ctx)
+			ctx.Abort()
+			return
+		}
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			// a callback that cannot be read cannot be verified either
+			apierrors.HandleUnauthorizedErrorWithDetail(ctx, err)
+			ctx.Abort()
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		if !verifier.verify(ctx.GetHeader(verifier.header), body) {
+			log.Warn(ctx, "Delivery callback of gateway %s rejected, invalid %s", gateway, verifier.header)
+			apierrors.HandleUnauthorizedError(ctx)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// verify checks the header value of a callback with body
+func (v callbackVerifier) verify(value string, body []byte) bool {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false
+	}
+	if v.mode == CallbackAuthSecret {
+		return subtle.ConstantTimeCompare([]byte(value), v.secret) == 1
+	}
+
+	mac := hmac.New(v.newHash, v.secret)
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	value = strings.TrimPrefix(value, v.algorithm+"=")
+	if signature, err := hex.DecodeString(value); err == nil && hmac.Equal(signature, expected) {
+		return true
+	}
+	signature, err := base64.StdEncoding.DecodeString(value)
+	return err == nil && hmac.Equal(signature, expected)
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	config "MgApplication/api-config"
+	"MgApplication/api-server/middlewares"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const callbackBody = `{"msgid":"1234567890","status":"DELIVRD"}`
+
+func callbackAuthConfig() *config.Config {
+	c := config.NewConfig(viper.New())
+	c.Set("sms.callback.gateways", map[string]any{
+		"cdac":    map[string]any{"auth": "secret", "secret": "cdac-callback-secret"},
+		"nic":     map[string]any{"auth": "hmac", "secret": "nic-signing-key", "header": "X-NIC-Signature", "algorithm": "sha512"},
+		"kaleyra": map[string]any{"auth": "hmac", "secret": "kaleyra-signing-key"},
+	})
+	return c
+}
+
+// callbackServer answers the callbacks verified by auth with their body
+func callbackServer(t *testing.T, c *config.Config) *gin.Engine {
+	t.Helper()
+	auth, err := NewDeliveryCallbackAuth(c)
+	require.NoError(t, err)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.ErrorHandler())
+	r.POST("/callback/:gateway", auth.Verify(), func(ctx *gin.Context) {
+		body, _ := io.ReadAll(ctx.Request.Body)
+		ctx.String(http.StatusOK, string(body))
+	})
+	return r
+}
+
+func postCallback(r *gin.Engine, gateway string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/callback/"+gateway, strings.NewReader(callbackBody))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDeliveryCallbackAuth(t *testing.T) {
+	r := callbackServer(t, callbackAuthConfig())
+
+	sha512MAC := hmac.New(sha512.New, []byte("nic-signing-key"))
+	sha512MAC.Write([]byte(callbackBody))
+	sha256MAC := hmac.New(sha256.New, []byte("kaleyra-signing-key"))
+	sha256MAC.Write([]byte(callbackBody))
+	wrongMAC := hmac.New(sha256.New, []byte("another-key"))
+	wrongMAC.Write([]byte(callbackBody))
+
+	tests := []struct {
+		name    string
+		gateway string
+		headers map[string]string
+		status  int
+	}{
+		{"valid shared secret", "cdac", map[string]string{"X-Callback-Secret": "cdac-callback-secret"}, http.StatusOK},
+		{"invalid shared secret", "cdac", map[string]string{"X-Callback-Secret": "guessed"}, http.StatusUnauthorized},
+		{"missing shared secret", "cdac", nil, http.StatusUnauthorized},
+		{"valid hex signature", "nic", map[string]string{"X-NIC-Signature": hex.EncodeToString(sha512MAC.Sum(nil))}, http.StatusOK},
+		{"valid prefixed signature", "NIC", map[string]string{"X-NIC-Signature": "sha512=" + hex.EncodeToString(sha512MAC.Sum(nil))}, http.StatusOK},
+		{"signature in the default header", "nic", map[string]string{"X-Callback-Signature": hex.EncodeToString(sha512MAC.Sum(nil))}, http.StatusUnauthorized},
+		{"valid base64 signature", "kaleyra", map[string]string{"X-Callback-Signature": base64.StdEncoding.EncodeToString(sha256MAC.Sum(nil))}, http.StatusOK},
+		{"signature of another key", "kaleyra", map[string]string{"X-Callback-Signature": hex.EncodeToString(wrongMAC.Sum(nil))}, http.StatusUnauthorized},
+		{"shared secret instead of signature", "kaleyra", map[string]string{"X-Callback-Signature": "kaleyra-signing-key"}, http.StatusUnauthorized},
+		{"gateway not configured", "smscountry", map[string]string{"X-Callback-Secret": "cdac-callback-secret"}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postCallback(r, tt.gateway, tt.headers)
+			assert.Equal(t, tt.status, rec.Code, rec.Body.String())
+			if tt.status == http.StatusOK {
+				// the handler still reads the verified body
+				assert.Equal(t, callbackBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeliveryCallbackAuthTamperedBody(t *testing.T) {
+	r := callbackServer(t, callbackAuthConfig())
+	mac := hmac.New(sha256.New, []byte("kaleyra-signing-key"))
+	mac.Write([]byte(strings.Replace(callbackBody, "DELIVRD", "UNDELIV", 1)))
+
+	rec := postCallback(r, "kaleyra", map[string]string{"X-Callback-Signature": hex.EncodeToString(mac.Sum(nil))})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestDeliveryCallbackAuthConfig(t *testing.T) {
+	for name, gateway := range map[string]map[string]any{
+		"no secret":         {"auth": "hmac"},
+		"unknown auth":      {"auth": "basic", "secret": "s"},
+		"unknown algorithm": {"auth": "hmac", "secret": "s", "algorithm": "md5"},
+	} {
+		c := config.NewConfig(viper.New())
+		c.Set("sms.callback.gateways", map[string]any{"cdac": gateway})
+		_, err := NewDeliveryCallbackAuth(c)
+		assert.ErrorIs(t, err, errCallbackAuthConfig, name)
+	}
+}
@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	log "MgApplication/api-log"
+	serverHandler "MgApplication/api-server/handler"
+	serverRoute "MgApplication/api-server/route"
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+	"MgApplication/handler/response"
+	repo "MgApplication/repo/postgres"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Reasons of the rejected rows of a recipient upload
+const (
+	recipientRejectMissing = "missing mobile number"
+	recipientRejectInvalid = "invalid mobile number"
+)
+
+// recipientRejectValueLimit truncates the values of the rejected rows kept in the upload summary
+const recipientRejectValueLimit = 32
+
+// recipientUploadTokenLength is the length of the upload tokens returned to resume uploads
+const recipientUploadTokenLength = 32
+
+// errRecipientFileRead is returned when the recipient file could not be read to the end, either
+// because it is not a valid CSV or because the connection was dropped
+var errRecipientFileRead = errors.New("recipient file could not be read")
+
+// recipientStore saves the recipients of campaigns
+type recipientStore interface {
+	StartRecipientUploadRepo(ctx context.Context, referenceID string, uploadToken string) (domain.RecipientUpload, error)
+	FetchRecipientUploadRepo(ctx context.Context, referenceID string, uploadToken string) (domain.RecipientUpload, error)
+	SaveRecipientBatchRepo(ctx context.Context, upload domain.RecipientUpload, fromOffset int64, mobileNumbers []string) (int64, error)
+}
+
+// recipientUploader streams the rows of a recipient file into a recipientStore in batches of
+// campaign.upload.chunksize numbers, saving the progress of the upload with every batch. Only one
+// batch is held in memory whatever the size of the file; numbers repeated across batches are
+// deduplicated by the store.
+type recipientUploader struct {
+	store      recipientStore
+	chunkSize  int
+	maxRejects int
+}
+
+func newRecipientUploader(store recipientStore, c *config.Config) *recipientUploader {
+	chunkSize := c.GetInt("campaign.upload.chunksize")
+	if chunkSize <= 0 {
+		chunkSize = 5000
+	}
+	maxRejects := c.GetInt("campaign.upload.maxrejects")
+	if maxRejects < 0 {
+		maxRejects = 0
+	}
+	return &recipientUploader{store: store, chunkSize: chunkSize, maxRejects: maxRejects}
+}
+
+// upload reads the recipient rows of r, the file from upload.Offset on, and saves them. upload is
+// updated as the batches are saved. When r cannot be read to the end, the rows read before are
+// saved so that the upload can be continued from upload.Offset, and an error wrapping
+// errRecipientFileRead is returned.
+func (ru *recipientUploader) upload(ctx context.Context, upload *domain.RecipientUpload, r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+
+	start := upload.Offset
+	pending := *upload
+	mobileNumbers := make([]string, 0, ru.chunkSize)
+	save := func(ctx context.Context) error {
+		inserted, err := ru.store.SaveRecipientBatchRepo(ctx, pending, upload.Offset, mobileNumbers)
+		if err != nil {
+			return err
+		}
+		pending.RowsAccepted += inserted
+		pending.RowsDuplicate += int64(len(mobileNumbers)) - inserted
+		*upload = pending
+		mobileNumbers = mobileNumbers[:0]
+		return nil
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			pending.Completed = true
+			pending.Offset = start + reader.InputOffset()
+			return save(ctx)
+		}
+		if err != nil {
+			// the connection may be gone along with the request context
+			if saveErr := save(context.WithoutCancel(ctx)); saveErr != nil {
+				return saveErr
+			}
+			return fmt.Errorf("%w at byte %d: %w", errRecipientFileRead, upload.Offset, err)
+		}
+
+		pending.Rows++
+		pending.Offset = start + reader.InputOffset()
+		mobileNumber := normalizeMobileNumber(record[0])
+		switch {
+		case mobileNumber == "":
+			ru.reject(&pending, record[0], recipientRejectMissing)
+		case !dndMobileNumberPattern.MatchString(mobileNumber):
+			ru.reject(&pending, record[0], recipientRejectInvalid)
+		default:
+			mobileNumbers = append(mobileNumbers, mobileNumber)
+			if len(mobileNumbers) == ru.chunkSize {
+				if err := save(ctx); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// reject counts a rejected row, keeping the details of the first campaign.upload.maxrejects
+func (ru *recipientUploader) reject(upload *domain.RecipientUpload, value string, reason string) {
+	upload.RowsRejected++
+	if len(upload.Rejects) >= ru.maxRejects {
+		return
+	}
+	if len(value) > recipientRejectValueLimit {
+		value = value[:recipientRejectValueLimit]
+	}
+	// a copy, the record is reused by the reader
+	upload.Rejects = append(upload.Rejects, domain.RecipientReject{Row: upload.Rows, Value: strings.Clone(value), Reason: reason})
+}
+
+// CampaignHandler receives the recipients of campaigns (bulk uploads). An upload is started
+// first, returning the token under which the recipient file is then sent, streamed into the
+// database as it is received. An upload interrupted by a dropped connection is continued from the
+// last batch saved by sending the rest of the file under the same token.
+type CampaignHandler struct {
+	*serverHandler.Base
+	uploads     *recipientUploader
+	readTimeout time.Duration
+}
+
+// NewCampaignHandler creates a new CampaignHandler instance
+func NewCampaignHandler(svc *repo.MgApplicationRepository, c *config.Config) *CampaignHandler {
+	base := serverHandler.New("Campaigns").SetDescription("Promotional campaigns and the upload of their recipients").SetOrder(4).SetPrefix("/v1")
+	return &CampaignHandler{
+		base,
+		newRecipientUploader(svc, c),
+		c.GetDuration("campaign.upload.readtimeout"),
+	}
+}
+
+func (ch *CampaignHandler) Routes() []serverRoute.Route {
+	return []serverRoute.Route{
+		serverRoute.Raw(http.MethodPost, "/campaigns/:campaign-id/recipients", ch.StartRecipientUploadHandler).Name("Start campaign recipient upload"),
+		serverRoute.Raw(http.MethodPatch, "/campaigns/:campaign-id/recipients/:upload-token", ch.UploadRecipientsHandler).Name("Upload campaign recipients"),
+		serverRoute.Raw(http.MethodGet, "/campaigns/:campaign-id/recipients/:upload-token", ch.GetRecipientUploadHandler).Name("Get campaign recipient upload"),
+	}
+}
+
+// StartRecipientUploadHandler starts an upload of the recipients of a campaign, replacing any
+// recipients uploaded before, and returns its upload token
+func (ch *CampaignHandler) StartRecipientUploadHandler(gctx *gin.Context) {
+	token, err := GenerateRandomString(recipientUploadTokenLength)
+	if err != nil {
+		apierrors.HandleError(gctx, err)
+		return
+	}
+	upload, err := ch.uploads.store.StartRecipientUploadRepo(gctx.Request.Context(), gctx.Param("campaign-id"), token)
+	if err != nil {
+		log.Error(gctx, "Error in StartRecipientUploadRepo function: %s", err.Error())
+		apierrors.HandleDBError(gctx, err)
+		return
+	}
+	gctx.JSON(http.StatusCreated, &response.RecipientUploadAPIResponse{
+		StatusCodeAndMessage: port.CreateSuccess,
+		Data:                 &upload,
+	})
+}
+
+// UploadRecipientsHandler receives the recipient file of an upload from the CSV file in the
+// "file" part of a multipart form, one mobile number in the first column of every row. The part
+// holds the file from the offset of the upload on, given as the first byte of a
+// "Content-Range: bytes <offset>-" header: 0 at first, the offset returned by
+// GetRecipientUploadHandler to continue an interrupted upload. Any other offset is rejected with a
+// conflict. Rows without a valid mobile number, such as a header row, are rejected; the response
+// lists the first rejected rows, numbered from 1 and skipping empty lines.
+func (ch *CampaignHandler) UploadRecipientsHandler(gctx *gin.Context) {
+	campaignID, token := gctx.Param("campaign-id"), gctx.Param("upload-token")
+	offset, err := parseContentRangeStart(gctx.GetHeader("Content-Range"))
+	if err != nil {
+		apierrors.ErrorResponseWithStatusCodeAndMessage(gctx, apierrors.HTTPErrorBadRequest, "Invalid Content-Range: "+err.Error(), err)
+		return
+	}
+
+	upload, err := ch.uploads.store.FetchRecipientUploadRepo(gctx.Request.Context(), campaignID, token)
+	if err != nil {
+		log.Error(gctx, "Error in FetchRecipientUploadRepo function: %s", err.Error())
+		apierrors.HandleDBError(gctx, err)
+		return
+	}
+	switch {
+	case upload.Completed:
+		apierrors.ErrorResponseWithStatusCodeAndMessage(gctx, apierrors.HTTPErrorConflict, "Recipient upload is already completed", nil)
+		return
+	case offset != upload.Offset:
+		apierrors.ErrorResponseWithStatusCodeAndMessage(gctx, apierrors.HTTPErrorConflict, fmt.Sprintf("Recipient upload continues at byte %d", upload.Offset), nil)
+		return
+	}
+
+	file, err := ch.recipientFile(gctx)
+	if err != nil {
+		apierrors.ErrorResponseWithStatusCodeAndMessage(gctx, apierrors.HTTPErrorBadRequest, "Invalid recipient upload: "+err.Error(), err)
+		return
+	}
+
+	// large files take longer to receive than the server read timeout allows
+	if ch.readTimeout > 0 {
+		_ = http.NewResponseController(gctx.Writer).SetReadDeadline(time.Now().Add(ch.readTimeout))
+	}
+
+	err = ch.uploads.upload(gctx.Request.Context(), &upload, file)
+	switch {
+	case errors.Is(err, errRecipientFileRead):
+		log.Warn(gctx, "Recipient upload %s of campaign %s interrupted: %s", token, campaignID, err.Error())
+		apierrors.ErrorResponseWithStatusCodeAndMessage(gctx, apierrors.HTTPErrorBadRequest,
+			fmt.Sprintf("Recipient upload interrupted, continue it from byte %d", upload.Offset), err)
+		return
+	case errors.Is(err, pgx.ErrNoRows):
+		apierrors.ErrorResponseWithStatusCodeAndMessage(gctx, apierrors.HTTPErrorConflict, "Recipient upload was continued or restarted by another request", err)
+		return
+	case err != nil:
+		log.Error(gctx, "Error in SaveRecipientBatchRepo function: %s", err.Error())
+		apierrors.HandleDBError(gctx, err)
+		return
+	}
+
+	log.Info(gctx, "Received %d rows of campaign %s recipients: %d accepted, %d rejected, %d duplicates",
+		upload.Rows, campaignID, upload.RowsAccepted, upload.RowsRejected, upload.RowsDuplicate)
+	gctx.JSON(http.StatusOK, &response.RecipientUploadAPIResponse{
+		StatusCodeAndMessage: port.UpdateSuccess,
+		Data:                 &upload,
+	})
+}
+
+// GetRecipientUploadHandler returns the progress of a recipient upload, including the offset an
+// interrupted upload continues at
+func (ch *CampaignHandler) GetRecipientUploadHandler(gctx *gin.Context) {
+	upload, err := ch.uploads.store.FetchRecipientUploadRepo(gctx.Request.Context(), gctx.Param("campaign-id"), gctx.Param("upload-token"))
+	if err != nil {
+		log.Error(gctx, "Error in FetchRecipientUploadRepo function: %s", err.Error())
+		apierrors.HandleDBError(gctx, err)
+		return
+	}
+	gctx.JSON(http.StatusOK, &response.RecipientUploadAPIResponse{
+		StatusCodeAndMessage: port.FetchSuccess,
+		Data:                 &upload,
+	})
+}
+
+// recipientFile returns the "file" part of the multipart request, without reading the request
+// body beyond the part headers
+func (ch *CampaignHandler) recipientFile(gctx *gin.Context) (*multipart.Part, error) {
+	reader, err := gctx.Request.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("file part is missing")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+	}
+}
+
+// parseContentRangeStart returns the first byte of a "bytes <first>-[<last>][/<length>]"
+// Content-Range header
+func parseContentRangeStart(contentRange string) (int64, error) {
+	if contentRange == "" {
+		return 0, errors.New("header is missing")
+	}
+	spec, ok := strings.CutPrefix(strings.TrimSpace(contentRange), "bytes ")
+	if !ok {
+		return 0, errors.New("unit must be bytes")
+	}
+	first, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, errors.New("range must be <first>-[<last>]")
+	}
+	offset, err := strconv.ParseInt(strings.TrimSpace(first), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid first byte %q", first)
+	}
+	return offset, nil
+}
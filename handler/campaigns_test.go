@@ -0,0 +1,327 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+	"MgApplication/handler/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRecipientStore keeps the progress of one upload. With numbers nil the recipients are only
+// counted, not deduplicated.
+type fakeRecipientStore struct {
+	upload   domain.RecipientUpload
+	numbers  map[string]bool
+	batches  int
+	peakHeap uint64
+}
+
+func (s *fakeRecipientStore) StartRecipientUploadRepo(ctx context.Context, referenceID string, uploadToken string) (domain.RecipientUpload, error) {
+	if referenceID != "campaign-1" {
+		return domain.RecipientUpload{}, pgx.ErrNoRows
+	}
+	s.upload = domain.RecipientUpload{ReferenceID: referenceID, UploadToken: uploadToken}
+	if s.numbers != nil {
+		s.numbers = make(map[string]bool)
+	}
+	return s.upload, nil
+}
+
+func (s *fakeRecipientStore) FetchRecipientUploadRepo(ctx context.Context, referenceID string, uploadToken string) (domain.RecipientUpload, error) {
+	if referenceID != s.upload.ReferenceID || uploadToken != s.upload.UploadToken {
+		return domain.RecipientUpload{}, pgx.ErrNoRows
+	}
+	return s.upload, nil
+}
+
+func (s *fakeRecipientStore) SaveRecipientBatchRepo(ctx context.Context, upload domain.RecipientUpload, fromOffset int64, mobileNumbers []string) (int64, error) {
+	if upload.UploadToken != s.upload.UploadToken || fromOffset != s.upload.Offset || s.upload.Completed {
+		return 0, pgx.ErrNoRows
+	}
+	inserted := int64(len(mobileNumbers))
+	if s.numbers != nil {
+		inserted = 0
+		for _, mobileNumber := range mobileNumbers {
+			if !s.numbers[mobileNumber] {
+				s.numbers[mobileNumber] = true
+				inserted++
+			}
+		}
+	}
+	upload.RowsAccepted += inserted
+	upload.RowsDuplicate += int64(len(mobileNumbers)) - inserted
+	upload.Rejects = append([]domain.RecipientReject(nil), upload.Rejects...)
+	s.upload = upload
+	s.batches++
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	s.peakHeap = max(s.peakHeap, stats.HeapAlloc)
+	return inserted, nil
+}
+
+func recipientUploadConfig(chunkSize int, maxRejects int) *config.Config {
+	c := config.NewConfig(viper.New())
+	c.Set("campaign.upload.chunksize", chunkSize)
+	c.Set("campaign.upload.maxrejects", maxRejects)
+	return c
+}
+
+func newFakeRecipientUpload(t *testing.T, store *fakeRecipientStore) domain.RecipientUpload {
+	upload, err := store.StartRecipientUploadRepo(context.Background(), "campaign-1", "token")
+	require.NoError(t, err)
+	return upload
+}
+
+const recipientFile = "mobile_number\n9876543210\n+919876543211\n\n98765\n09876543212,ignored\n9876543210\n,9876543213\n9876543214\n"
+
+func TestRecipientUploaderRejectsAndDuplicates(t *testing.T) {
+	store := &fakeRecipientStore{numbers: map[string]bool{}}
+	uploader := newRecipientUploader(store, recipientUploadConfig(2, 2))
+	upload := newFakeRecipientUpload(t, store)
+
+	require.NoError(t, uploader.upload(context.Background(), &upload, strings.NewReader(recipientFile)))
+
+	assert.True(t, upload.Completed)
+	assert.Equal(t, int64(len(recipientFile)), upload.Offset)
+	assert.Equal(t, int64(8), upload.Rows, "the empty line is not a row")
+	assert.Equal(t, int64(4), upload.RowsAccepted)
+	assert.Equal(t, int64(1), upload.RowsDuplicate)
+	assert.Equal(t, int64(3), upload.RowsRejected)
+	assert.Equal(t, []domain.RecipientReject{
+		{Row: 1, Value: "mobile_number", Reason: recipientRejectInvalid},
+		{Row: 4, Value: "98765", Reason: recipientRejectInvalid},
+	}, upload.Rejects, "only the first rejects are listed")
+	assert.Equal(t, upload, store.upload, "the final progress is saved")
+	assert.Equal(t, map[string]bool{"9876543210": true, "9876543211": true, "9876543212": true, "9876543214": true}, store.numbers)
+	assert.Equal(t, 3, store.batches)
+}
+
+func TestRecipientUploaderResumesAfterInterruption(t *testing.T) {
+	store := &fakeRecipientStore{numbers: map[string]bool{}}
+	uploader := newRecipientUploader(store, recipientUploadConfig(2, 10))
+	upload := newFakeRecipientUpload(t, store)
+
+	// the connection drops in the middle of the 7th row
+	cut := strings.Index(recipientFile, ",9876543213") + 4
+	dropped := io.MultiReader(strings.NewReader(recipientFile[:cut]), iotest.ErrReader(io.ErrUnexpectedEOF))
+	err := uploader.upload(context.Background(), &upload, dropped)
+	require.ErrorIs(t, err, errRecipientFileRead)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	// the rows read before the interruption are saved
+	assert.False(t, store.upload.Completed)
+	assert.Equal(t, int64(strings.Index(recipientFile, ",9876543213")), store.upload.Offset)
+	assert.Equal(t, int64(6), store.upload.Rows)
+	assert.Equal(t, int64(3), store.upload.RowsAccepted)
+	assert.Equal(t, upload, store.upload)
+
+	resumed, err := store.FetchRecipientUploadRepo(context.Background(), "campaign-1", "token")
+	require.NoError(t, err)
+	require.NoError(t, uploader.upload(context.Background(), &resumed, strings.NewReader(recipientFile[resumed.Offset:])))
+
+	assert.True(t, resumed.Completed)
+	assert.Equal(t, int64(len(recipientFile)), resumed.Offset)
+	assert.Equal(t, int64(8), resumed.Rows)
+	assert.Equal(t, int64(4), resumed.RowsAccepted)
+	assert.Equal(t, int64(1), resumed.RowsDuplicate)
+	assert.Equal(t, int64(3), resumed.RowsRejected)
+	assert.Equal(t, domain.RecipientReject{Row: 7, Value: "", Reason: recipientRejectMissing}, resumed.Rejects[2], "rows are numbered across the interruption")
+}
+
+func TestRecipientUploaderConflict(t *testing.T) {
+	store := &fakeRecipientStore{numbers: map[string]bool{}}
+	uploader := newRecipientUploader(store, recipientUploadConfig(2, 10))
+	upload := newFakeRecipientUpload(t, store)
+
+	// another request continued the upload in the meantime
+	store.upload.Offset = 100
+	err := uploader.upload(context.Background(), &upload, strings.NewReader(recipientFile))
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+	assert.Equal(t, int64(0), upload.Offset)
+}
+
+// TestRecipientUploaderBoundedMemory uploads a generated file of a million rows, about 11MB, and
+// checks that the heap stays well below what holding the file or its numbers would take
+func TestRecipientUploaderBoundedMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("uploads a million rows")
+	}
+	const rows = 1000000
+	const heapCeiling = 16 << 20
+
+	reader, writer := io.Pipe()
+	go func() {
+		w := bufio.NewWriter(writer)
+		for i := range rows {
+			if i%1000 == 999 {
+				fmt.Fprintln(w, "not a number")
+				continue
+			}
+			fmt.Fprintf(w, "9%09d\n", i)
+		}
+		writer.CloseWithError(w.Flush())
+	}()
+
+	store := &fakeRecipientStore{}
+	uploader := newRecipientUploader(store, recipientUploadConfig(5000, 100))
+	upload := newFakeRecipientUpload(t, store)
+
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	baseline := stats.HeapAlloc
+
+	require.NoError(t, uploader.upload(context.Background(), &upload, reader))
+
+	assert.True(t, upload.Completed)
+	assert.Equal(t, int64(rows), upload.Rows)
+	assert.Equal(t, int64(rows-rows/1000), upload.RowsAccepted)
+	assert.Equal(t, int64(rows/1000), upload.RowsRejected)
+	assert.Len(t, upload.Rejects, 100)
+	assert.Equal(t, int64(1000), upload.Rejects[0].Row)
+	// 199 full batches and the last one
+	assert.Equal(t, 200, store.batches)
+	assert.Less(t, store.peakHeap-min(baseline, store.peakHeap), uint64(heapCeiling),
+		"heap grew by %d bytes during the upload", store.peakHeap-min(baseline, store.peakHeap))
+}
+
+func TestParseContentRangeStart(t *testing.T) {
+	cases := []struct {
+		header string
+		offset int64
+		valid  bool
+	}{
+		{"bytes 1024-", 1024, true},
+		{"bytes 1024-2047/4096", 1024, true},
+		{"bytes 0-*/*", 0, true},
+		{"", 0, false},
+		{"items 10-", 0, false},
+		{"bytes 1024", 0, false},
+		{"bytes -10-", 0, false},
+		{"bytes abc-", 0, false},
+	}
+	for _, tc := range cases {
+		offset, err := parseContentRangeStart(tc.header)
+		if tc.valid {
+			assert.NoError(t, err, tc.header)
+			assert.Equal(t, tc.offset, offset, tc.header)
+		} else {
+			assert.Error(t, err, tc.header)
+		}
+	}
+}
+
+func newRecipientUploadRouter(store *fakeRecipientStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	ch := &CampaignHandler{uploads: newRecipientUploader(store, recipientUploadConfig(2, 10))}
+	router := gin.New()
+	for _, route := range ch.Routes() {
+		meta := route.Meta()
+		router.Handle(meta.Method, "/v1"+meta.Path, meta.Func)
+	}
+	return router
+}
+
+// recipientUploadRequest sends file in a multipart form. With dropped set, the body ends after
+// file, as when the connection is lost.
+func recipientUploadRequest(t *testing.T, path string, contentRange string, file string, dropped bool) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "recipients.csv")
+	require.NoError(t, err)
+	_, err = io.WriteString(part, file)
+	require.NoError(t, err)
+	if !dropped {
+		require.NoError(t, writer.Close())
+	}
+
+	req := httptest.NewRequest(http.MethodPatch, path, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if contentRange != "" {
+		req.Header.Set("Content-Range", contentRange)
+	}
+	return req
+}
+
+func serveRecipientUpload(t *testing.T, router *gin.Engine, req *http.Request, status int) domain.RecipientUpload {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, status, w.Code, w.Body.String())
+	var rsp response.RecipientUploadAPIResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rsp))
+	if rsp.Data == nil {
+		return domain.RecipientUpload{}
+	}
+	return *rsp.Data
+}
+
+func TestRecipientUploadHandlers(t *testing.T) {
+	store := &fakeRecipientStore{numbers: map[string]bool{}}
+	router := newRecipientUploadRouter(store)
+
+	serveRecipientUpload(t, router, httptest.NewRequest(http.MethodPost, "/v1/campaigns/unknown/recipients", nil), http.StatusNotFound)
+	started := serveRecipientUpload(t, router, httptest.NewRequest(http.MethodPost, "/v1/campaigns/campaign-1/recipients", nil), http.StatusCreated)
+	assert.Len(t, started.UploadToken, recipientUploadTokenLength)
+	assert.Zero(t, started.Offset)
+
+	path := "/v1/campaigns/campaign-1/recipients/" + started.UploadToken
+	serveRecipientUpload(t, router, recipientUploadRequest(t, "/v1/campaigns/campaign-1/recipients/other", "bytes 0-", recipientFile, false), http.StatusNotFound)
+	serveRecipientUpload(t, router, recipientUploadRequest(t, path, "", recipientFile, false), http.StatusBadRequest)
+	serveRecipientUpload(t, router, recipientUploadRequest(t, path, "bytes 10-", recipientFile, false), http.StatusConflict)
+
+	// the connection drops in the middle of the 5th row
+	half := strings.Index(recipientFile, "98765\n") + 2
+	serveRecipientUpload(t, router, recipientUploadRequest(t, path, "bytes 0-", recipientFile[:half], true), http.StatusBadRequest)
+
+	interrupted := serveRecipientUpload(t, router, httptest.NewRequest(http.MethodGet, path, nil), http.StatusOK)
+	assert.False(t, interrupted.Completed)
+	assert.Equal(t, int64(strings.Index(recipientFile, "\n\n98765")+1), interrupted.Offset)
+	assert.Equal(t, int64(2), interrupted.RowsAccepted)
+
+	contentRange := fmt.Sprintf("bytes %d-%d/%d", interrupted.Offset, len(recipientFile)-1, len(recipientFile))
+	completed := serveRecipientUpload(t, router, recipientUploadRequest(t, path, contentRange, recipientFile[interrupted.Offset:], false), http.StatusOK)
+	assert.True(t, completed.Completed)
+	assert.Equal(t, int64(8), completed.Rows)
+	assert.Equal(t, int64(4), completed.RowsAccepted)
+	assert.Equal(t, int64(3), completed.RowsRejected)
+	assert.Equal(t, int64(4), completed.Rejects[1].Row)
+
+	serveRecipientUpload(t, router, recipientUploadRequest(t, path, fmt.Sprintf("bytes %d-", len(recipientFile)), "", false), http.StatusConflict)
+	assert.Equal(t, completed, serveRecipientUpload(t, router, httptest.NewRequest(http.MethodGet, path, nil), http.StatusOK))
+}
+
+func TestRecipientUploadHandlerRequiresFile(t *testing.T) {
+	store := &fakeRecipientStore{}
+	router := newRecipientUploadRouter(store)
+	upload := newFakeRecipientUpload(t, store)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	require.NoError(t, writer.WriteField("name", "recipients"))
+	require.NoError(t, writer.Close())
+	req := httptest.NewRequest(http.MethodPatch, "/v1/campaigns/campaign-1/recipients/"+upload.UploadToken, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Content-Range", "bytes 0-")
+
+	serveRecipientUpload(t, router, req, http.StatusBadRequest)
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"sync"
+	"time"
+
+	"MgApplication/core/domain"
+)
+
+// circuitBreaker stops calls to a degraded dependency. It opens after threshold consecutive
+// failures and rejects calls for cooldown. Once the cooldown has passed calls are let through
+// again; the first failure reopens it and the first success closes it.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+}
+
+// newCircuitBreaker creates a circuit breaker, disabled when threshold is not positive
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether a call may be made
+func (b *circuitBreaker) Allow() bool {
+	return b.RetryIn() == 0
+}
+
+// RetryIn returns how long the breaker stays open, 0 when calls are allowed
+func (b *circuitBreaker) RetryIn() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if wait := time.Until(b.openUntil); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// Success records a successful call and closes the breaker
+func (b *circuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+}
+
+// Failure records a failed call and reports whether the breaker is open
+func (b *circuitBreaker) Failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold <= 0 {
+		return false
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+		return true
+	}
+	return false
+}
+
+// OpenFor opens the breaker for at least d, whatever the failures recorded
+func (b *circuitBreaker) OpenFor(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until := time.Now().Add(d); until.After(b.openUntil) {
+		b.openUntil = until
+	}
+}
+
+// Status returns the state of the breaker for the system status
+func (b *circuitBreaker) Status(name string) domain.BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := domain.BreakerStatus{Name: name, State: domain.BreakerClosed}
+	switch wait := time.Until(b.openUntil); {
+	case b.threshold <= 0:
+		status.State = domain.BreakerDisabled
+	case wait > 0:
+		status.State = domain.BreakerOpen
+		status.RetryInSeconds = int64(wait.Round(time.Second).Seconds())
+	case b.failures >= b.threshold:
+		status.State = domain.BreakerHalfOpen
+	}
+	return status
+}
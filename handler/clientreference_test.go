@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	config "MgApplication/api-config"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postClientReference sends an OTP with the given client_reference and metadata through a CDAC
+// server and returns the response with the number of gateway calls
+func postClientReference(t *testing.T, clientReference string, metadata any) (*httptest.ResponseRecorder, *fakeMsgStore, int32) {
+	t.Helper()
+	var sends atomic.Int32
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sends.Add(1)
+		_, _ = w.Write([]byte("402,MsgID = 150920241726381202115hpgovsms"))
+	}))
+	defer cdac.Close()
+
+	c := config.NewConfig(viper.New())
+	c.Set("sms.msgstorerequest", 1)
+	c.Set("sms.cdac.url", cdac.URL)
+	c.Set("sms.cdac.username", "appostsms")
+	c.Set("sms.cdac.password", "cdacsecret")
+	c.Set("sms.cdac.securekey", "c7d427c9-63e7-4eec-a227-3ef840a75269")
+
+	ch, store := newTestSMSHandler(c)
+	body := otpRequestBody("9000000001")
+	body["client_reference"] = clientReference
+	body["metadata"] = metadata
+	rec := postSMSRequest(ch, body)
+	return rec, store, sends.Load()
+}
+
+func TestCreateSMSRequestClientReferenceLength(t *testing.T) {
+	rec, _, sends := postClientReference(t, strings.Repeat("B", 64), map[string]string{"case_number": "CASE7781"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, int32(1), sends)
+
+	var rsp struct {
+		Data struct {
+			ClientReference string            `json:"client_reference"`
+			Metadata        map[string]string `json:"metadata"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, strings.Repeat("B", 64), rsp.Data.ClientReference)
+	assert.Equal(t, map[string]string{"case_number": "CASE7781"}, rsp.Data.Metadata)
+
+	rec, store, sends := postClientReference(t, strings.Repeat("B", 65), map[string]string{})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	assert.Zero(t, sends)
+	assert.Zero(t, store.savedRequests)
+}
+
+func TestCreateSMSRequestMetadataTooLarge(t *testing.T) {
+	rec, store, sends := postClientReference(t, "BKG20240002", map[string]string{"notes": strings.Repeat("x", 2048)})
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+	assert.Zero(t, sends)
+	assert.Zero(t, store.savedRequests)
+}
+
+func TestCreateSMSRequestMetadataNotFlat(t *testing.T) {
+	for _, metadata := range []any{
+		map[string]any{"booking": map[string]string{"id": "42"}},
+		map[string]any{"attempt": 2},
+	} {
+		rec, _, sends := postClientReference(t, "BKG20240003", metadata)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+		assert.Zero(t, sends)
+	}
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	config "MgApplication/api-config"
+	log "MgApplication/api-log"
+	"MgApplication/core/domain"
+	"MgApplication/core/idgen"
+)
+
+// newCommunicationIDs returns the generator of the communication IDs named by
+// sms.communicationid.generator, ULIDs when it is not set. An unknown generator falls back to
+// ULIDs, CheckCommunicationIDGenerator failing startup.
+func newCommunicationIDs(c *config.Config) idgen.Generator {
+	ids, err := idgen.New(c.GetString("sms.communicationid.generator"))
+	if err != nil {
+		log.Error(nil, "Invalid sms.communicationid.generator, ULIDs are generated: %s", err.Error())
+		return idgen.Default()
+	}
+	return ids
+}
+
+// CheckCommunicationIDGenerator fails startup when sms.communicationid.generator names a
+// generator that is not built in nor registered with idgen.Register
+func CheckCommunicationIDGenerator(c *config.Config) error {
+	_, err := idgen.New(c.GetString("sms.communicationid.generator"))
+	return err
+}
+
+// assignCommunicationID gives msgreq its communication ID when it is received, before it is
+// queued, stored or sent, unless it has one. The ID is stored with the request and answered to
+// the client whichever path the request takes.
+func (ch *MgApplicationHandler) assignCommunicationID(msgreq *domain.MsgRequest) {
+	if msgreq.CommunicationID != "" {
+		return
+	}
+	ids := ch.ids
+	if ids == nil {
+		ids = idgen.Default()
+	}
+	msgreq.CommunicationID = ids.NewID()
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"testing"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+	"MgApplication/core/idgen"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCommunicationIDGenerator(t *testing.T) {
+	c := config.NewConfig(viper.New())
+	require.NoError(t, CheckCommunicationIDGenerator(c))
+	assert.IsType(t, &idgen.ULIDGenerator{}, newCommunicationIDs(c))
+
+	c.Set("sms.communicationid.generator", "uuid")
+	assert.EqualError(t, CheckCommunicationIDGenerator(c), "unknown ID generator uuid")
+	// requests are still given ULIDs
+	assert.IsType(t, &idgen.ULIDGenerator{}, newCommunicationIDs(c))
+}
+
+// A request is given its communication ID once, the ID it already has being kept
+func TestAssignCommunicationID(t *testing.T) {
+	ch := &MgApplicationHandler{ids: newCommunicationIDs(config.NewConfig(viper.New()))}
+
+	var msgreq domain.MsgRequest
+	ch.assignCommunicationID(&msgreq)
+	assertULID(t, msgreq.CommunicationID)
+	assigned := msgreq.CommunicationID
+	ch.assignCommunicationID(&msgreq)
+	assert.Equal(t, assigned, msgreq.CommunicationID)
+
+	next := domain.MsgRequest{}
+	ch.assignCommunicationID(&next)
+	assert.Greater(t, next.CommunicationID, assigned)
+}
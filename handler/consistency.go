@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConsistencyTokenHeader carries the LSN of the successful writes, which a following read sends
+// back to see them even on a lagging read replica
+const ConsistencyTokenHeader = "X-Consistency-Token"
+
+// consistencyTokens answers the successful writes with the current LSN of the primary in the
+// X-Consistency-Token header, and passes the token sent with a read to the read router of its
+// queries through the request context. A malformed token is ignored.
+func consistencyTokens(currentLSN func(ctx context.Context) (dblib.LSN, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			if token := c.GetHeader(ConsistencyTokenHeader); token != "" {
+				lsn, err := dblib.ParseLSN(token)
+				if err != nil {
+					log.Warn(c, "Ignoring the consistency token of the request: %s", err.Error())
+				} else {
+					c.Request = c.Request.WithContext(dblib.WithConsistencyToken(c.Request.Context(), lsn))
+				}
+			}
+			c.Next()
+			return
+		}
+
+		w := &consistencyTokenWriter{ResponseWriter: c.Writer, ctx: c.Request.Context(), currentLSN: currentLSN}
+		c.Writer = w
+		c.Next()
+		// the headers of the responses without a body are written after the handlers
+		w.stamp()
+	}
+}
+
+// primaryLSN returns the function reading the current LSN of the primary db
+func primaryLSN(db *dblib.DB) func(ctx context.Context) (dblib.LSN, error) {
+	return func(ctx context.Context) (dblib.LSN, error) {
+		return dblib.CurrentLSN(ctx, db)
+	}
+}
+
+// consistencyTokenWriter sets the consistency token header of a successful response before its
+// headers are written
+type consistencyTokenWriter struct {
+	gin.ResponseWriter
+	ctx        context.Context
+	currentLSN func(ctx context.Context) (dblib.LSN, error)
+	stamped    bool
+}
+
+func (w *consistencyTokenWriter) stamp() {
+	if w.stamped || w.ResponseWriter.Written() {
+		return
+	}
+	w.stamped = true
+	if status := w.Status(); status < 200 || status > 299 {
+		return
+	}
+	lsn, err := w.currentLSN(w.ctx)
+	if err != nil {
+		log.Warn(w.ctx, "Reading the LSN of the consistency token failed: %s", err.Error())
+		return
+	}
+	w.Header().Set(ConsistencyTokenHeader, lsn.String())
+}
+
+func (w *consistencyTokenWriter) WriteHeaderNow() {
+	w.stamp()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *consistencyTokenWriter) Write(b []byte) (int, error) {
+	w.stamp()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *consistencyTokenWriter) WriteString(s string) (int, error) {
+	w.stamp()
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	dblib "MgApplication/api-db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func consistencyTokenEngine(currentLSN func(ctx context.Context) (dblib.LSN, error)) (*gin.Engine, *[]any) {
+	var tokens []any
+	engine := gin.New()
+	engine.Use(consistencyTokens(currentLSN))
+	engine.POST("/templates", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"template_local_id": 7})
+	})
+	engine.POST("/invalid", func(c *gin.Context) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "invalid"})
+	})
+	engine.PUT("/templates/7/status", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	engine.GET("/templates/7", func(c *gin.Context) {
+		if lsn, ok := dblib.ConsistencyToken(c.Request.Context()); ok {
+			tokens = append(tokens, lsn)
+		} else {
+			tokens = append(tokens, nil)
+		}
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	return engine, &tokens
+}
+
+func TestConsistencyTokenOfWrites(t *testing.T) {
+	engine, _ := consistencyTokenEngine(func(context.Context) (dblib.LSN, error) {
+		return 0x16B374D848, nil
+	})
+
+	for _, tc := range []struct {
+		method, path string
+		want         string
+	}{
+		{http.MethodPost, "/templates", "16/B374D848"},
+		{http.MethodPut, "/templates/7/status", "16/B374D848"},
+		{http.MethodPost, "/invalid", ""},
+		{http.MethodGet, "/templates/7", ""},
+	} {
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Equal(t, tc.want, rec.Header().Get(ConsistencyTokenHeader), "%s %s", tc.method, tc.path)
+	}
+
+	// the write succeeded, only its token is missing
+	engine, _ = consistencyTokenEngine(func(context.Context) (dblib.LSN, error) {
+		return 0, errors.New("connection reset by peer")
+	})
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/templates", nil))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Empty(t, rec.Header().Get(ConsistencyTokenHeader))
+}
+
+func TestConsistencyTokenOfReads(t *testing.T) {
+	engine, tokens := consistencyTokenEngine(func(context.Context) (dblib.LSN, error) {
+		t.Error("a read should not read the LSN")
+		return 0, nil
+	})
+
+	for _, token := range []string{"16/B374D848", "", "not-an-lsn"} {
+		req := httptest.NewRequest(http.MethodGet, "/templates/7", nil)
+		if token != "" {
+			req.Header.Set(ConsistencyTokenHeader, token)
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.Equal(t, []any{dblib.LSN(0x16B374D848), nil, nil}, *tokens)
+}
@@ -0,0 +1,341 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	config "MgApplication/api-config"
+	log "MgApplication/api-log"
+	"MgApplication/core/clock"
+	"MgApplication/core/domain"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// credentialExpiryLayout is the layout of the password expiry dates, the business day from which
+// the gateway no longer accepts the password
+const credentialExpiryLayout = "2006-01-02"
+
+// credentialExpiryEventName is the event name of the credential expiry alert webhook
+const credentialExpiryEventName = "sms.credential_expiry"
+
+var (
+	CredentialExpiryDaysRemaining = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sms_credential_expiry_days_remaining",
+			Help: "Business days until the password of a gateway account expires, negative once expired",
+		},
+		[]string{"credential"},
+	)
+	CredentialExpiryAlertsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sms_credential_expiry_alerts_total",
+			Help: "Total number of credential expiry alerts posted to the alert webhook, by outcome",
+		},
+		[]string{"outcome"},
+	)
+)
+
+// gatewayCredential is a gateway account whose password may expire, the global account of a
+// gateway or the account of a tenant credential set
+type gatewayCredential struct {
+	name          string
+	gateway       domain.GatewayID
+	credentialSet string
+	username      string
+	password      string
+	expiresAt     *time.Time
+}
+
+// fingerprint identifies the version of the credential: it changes when the credential is
+// updated, its password or its expiry
+func (gc gatewayCredential) fingerprint() string {
+	expiresAt := ""
+	if gc.expiresAt != nil {
+		expiresAt = gc.expiresAt.Format(time.RFC3339)
+	}
+	sum := sha256.Sum256([]byte(gc.username + "\x00" + gc.password + "\x00" + expiresAt))
+	return hex.EncodeToString(sum[:8])
+}
+
+// expiryDate returns the business day the password expires on, formatted as configured
+func (gc gatewayCredential) expiryDate() string {
+	return clock.InIST(*gc.expiresAt).Format(credentialExpiryLayout)
+}
+
+// expiry returns the expiry of the credential at now, expiring within leadTime of its expiry
+func (gc gatewayCredential) expiry(now time.Time, leadTime time.Duration) domain.CredentialExpiry {
+	expiry := domain.CredentialExpiry{
+		Credential:    gc.name,
+		Gateway:       string(gc.gateway),
+		CredentialSet: gc.credentialSet,
+		Username:      gc.username,
+		Status:        domain.CredentialExpiryUnknown,
+	}
+	if gc.expiresAt == nil {
+		return expiry
+	}
+	days := int(clock.BusinessDay(*gc.expiresAt).Sub(clock.BusinessDay(now)).Hours() / 24)
+	expiry.ExpiresAt = gc.expiresAt
+	expiry.DaysRemaining = &days
+	switch {
+	case !now.Before(*gc.expiresAt):
+		expiry.Status = domain.CredentialExpiryExpired
+	case gc.expiresAt.Sub(now) <= leadTime:
+		expiry.Status = domain.CredentialExpiryExpiring
+	default:
+		expiry.Status = domain.CredentialExpiryValid
+	}
+	return expiry
+}
+
+// gatewayCredentials lists the gateway accounts configured with their password expiry:
+// sms.cdac.passwordexpiry for CDAC, sms.nic.<account>passwordexpiry for the NIC accounts and
+// passwordexpiry next to the password of the credential sets. An account without a username is
+// not listed, an invalid expiry is logged and left unknown.
+func gatewayCredentials(c *config.Config) []gatewayCredential {
+	var credentials []gatewayCredential
+	add := func(name string, gateway domain.GatewayID, credentialSet string, username string, password string, expiryKey string) {
+		if username == "" {
+			return
+		}
+		credential := gatewayCredential{
+			name:          name,
+			gateway:       gateway,
+			credentialSet: credentialSet,
+			username:      username,
+			password:      password,
+		}
+		if raw := strings.TrimSpace(c.GetString(expiryKey)); raw != "" {
+			expiresAt, err := clock.ParseDate(credentialExpiryLayout, raw)
+			if err != nil {
+				log.Warn(nil, "Ignoring the invalid %s %q, expected a %s date", expiryKey, raw, credentialExpiryLayout)
+			} else {
+				credential.expiresAt = &expiresAt
+			}
+		}
+		credentials = append(credentials, credential)
+	}
+
+	add("cdac", domain.GatewayCDAC, "", c.GetString("sms.cdac.username"), c.GetString("sms.cdac.password"), "sms.cdac.passwordexpiry")
+	for _, account := range nicAccounts {
+		key := "sms.nic." + account
+		add("nic."+account, domain.GatewayNIC, "", c.GetString(key+"UserName"), c.GetString(key+"Password"), key+"PasswordExpiry")
+	}
+	ids := make([]string, 0)
+	for id := range c.GetStringMap("sms.credentialsets") {
+		ids = append(ids, strings.ToLower(id))
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		key := "sms.credentialsets." + id
+		add("credentialsets."+id+".cdac", domain.GatewayCDAC, id, c.GetString(key+".cdac.username"), c.GetString(key+".cdac.password"), key+".cdac.passwordexpiry")
+		add("credentialsets."+id+".nic", domain.GatewayNIC, id, c.GetString(key+".nic.username"), c.GetString(key+".nic.password"), key+".nic.passwordexpiry")
+	}
+	return credentials
+}
+
+// sendCredential returns the gateway account msgreq is sent with through gateway
+func sendCredential(c *config.Config, gateway domain.GatewayID, msgreq domain.MsgRequest) (gatewayCredential, bool) {
+	name := ""
+	switch {
+	case msgreq.CredentialSet != "" && gateway == domain.GatewayCDAC:
+		name = "credentialsets." + strings.ToLower(msgreq.CredentialSet) + ".cdac"
+	case msgreq.CredentialSet != "" && gateway == domain.GatewayNIC:
+		name = "credentialsets." + strings.ToLower(msgreq.CredentialSet) + ".nic"
+	case gateway == domain.GatewayCDAC:
+		name = "cdac"
+	case gateway == domain.GatewayNIC:
+		account, ok := nicAccount(msgreq.SenderID)
+		if !ok {
+			return gatewayCredential{}, false
+		}
+		name = "nic." + account
+	}
+	for _, credential := range gatewayCredentials(c) {
+		if credential.name == name {
+			return credential, true
+		}
+	}
+	return gatewayCredential{}, false
+}
+
+// isGatewayAuthFailure reports whether a failed send was rejected for its credentials: the
+// gateway answered with a 401 or 403 status or error code
+func isGatewayAuthFailure(msgresponse domain.MsgResponse) bool {
+	switch msgresponse.ResponseCode {
+	case "401", "403":
+		return true
+	}
+	return strings.Contains(msgresponse.ResponseText, "status: 401") || strings.Contains(msgresponse.ResponseText, "status: 403")
+}
+
+// credentialExpiryHint returns the hint added to an authentication failure of msgreq through
+// gateway when the password it was sent with is past its expiry, empty otherwise
+func credentialExpiryHint(c *config.Config, gateway domain.GatewayID, msgreq domain.MsgRequest, msgresponse domain.MsgResponse, now time.Time) string {
+	if !isGatewayAuthFailure(msgresponse) {
+		return ""
+	}
+	credential, ok := sendCredential(c, gateway, msgreq)
+	if !ok || credential.expiresAt == nil || now.Before(*credential.expiresAt) {
+		return ""
+	}
+	return "credential may be expired, expires_at=" + credential.expiryDate()
+}
+
+// CredentialExpiryMonitor checks every sms.credentialexpiry.interval, daily by default, the
+// expiry of the gateway passwords. A password expiring within sms.credentialexpiry.leadtime, or
+// expired, is logged as a warning on every check and alerted once to the
+// sms.credentialexpiry.alertwebhookurl webhook. The alert state is kept per version of the
+// credential, so updating its password or its expiry clears it.
+type CredentialExpiryMonitor struct {
+	c          *config.Config
+	interval   time.Duration
+	leadTime   time.Duration
+	webhookURL string
+	client     *http.Client
+	now        func() time.Time
+
+	mu sync.Mutex
+	// alerted holds the fingerprint of the alerted version of each credential
+	alerted map[string]string
+}
+
+// NewCredentialExpiryMonitor creates a new CredentialExpiryMonitor instance using the
+// sms.credentialexpiry configuration
+func NewCredentialExpiryMonitor(c *config.Config) *CredentialExpiryMonitor {
+	interval := c.GetDuration("sms.credentialexpiry.interval")
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	leadTime := c.GetDuration("sms.credentialexpiry.leadtime")
+	if leadTime <= 0 {
+		leadTime = 14 * 24 * time.Hour
+	}
+	return &CredentialExpiryMonitor{
+		c:          c,
+		interval:   interval,
+		leadTime:   leadTime,
+		webhookURL: c.GetString("sms.credentialexpiry.alertwebhookurl"),
+		client:     &http.Client{Timeout: 10 * time.Second},
+		now:        clock.Now,
+		alerted:    make(map[string]string),
+	}
+}
+
+// Run checks the expiries every sms.credentialexpiry.interval until ctx is cancelled
+func (cm *CredentialExpiryMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(cm.interval)
+	defer ticker.Stop()
+
+	for {
+		cm.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check checks the expiry of every gateway password, alerting those expiring within the lead
+// time that were not alerted yet
+func (cm *CredentialExpiryMonitor) Check(ctx context.Context) {
+	now := cm.now()
+	for _, credential := range gatewayCredentials(cm.c) {
+		expiry := credential.expiry(now, cm.leadTime)
+		if expiry.DaysRemaining == nil {
+			continue
+		}
+		CredentialExpiryDaysRemaining.WithLabelValues(credential.name).Set(float64(*expiry.DaysRemaining))
+		if expiry.Status != domain.CredentialExpiryExpiring && expiry.Status != domain.CredentialExpiryExpired {
+			continue
+		}
+		log.Warn(ctx, "Password of gateway credential %s (%s) %s on %s, %d days remaining", credential.name, credential.username,
+			expiry.Status, credential.expiryDate(), *expiry.DaysRemaining)
+
+		if cm.isAlerted(credential) {
+			continue
+		}
+		if err := cm.alert(ctx, expiry); err != nil {
+			CredentialExpiryAlertsTotal.WithLabelValues("failed").Inc()
+			log.Error(ctx, "Credential expiry alert of %s failed, retried on the next check: %s", credential.name, err.Error())
+			continue
+		}
+		cm.mu.Lock()
+		cm.alerted[credential.name] = credential.fingerprint()
+		cm.mu.Unlock()
+	}
+}
+
+// isAlerted reports whether the current version of credential was alerted, clearing the alert
+// state of a previous version
+func (cm *CredentialExpiryMonitor) isAlerted(credential gatewayCredential) bool {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	fingerprint, ok := cm.alerted[credential.name]
+	if ok && fingerprint != credential.fingerprint() {
+		log.Info(nil, "Gateway credential %s was updated, clearing its expiry alert", credential.name)
+		delete(cm.alerted, credential.name)
+		return false
+	}
+	return ok
+}
+
+// alert posts the expiry to the alert webhook, only logged without one
+func (cm *CredentialExpiryMonitor) alert(ctx context.Context, expiry domain.CredentialExpiry) error {
+	if cm.webhookURL == "" {
+		return nil
+	}
+	payload, err := json.Marshal(struct {
+		Event string `json:"event"`
+		domain.CredentialExpiry
+	}{credentialExpiryEventName, expiry})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cm.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := cm.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	CredentialExpiryAlertsTotal.WithLabelValues("posted").Inc()
+	return nil
+}
+
+// Expiries lists the expiry of every gateway password
+func (cm *CredentialExpiryMonitor) Expiries() []domain.CredentialExpiry {
+	now := cm.now()
+	credentials := gatewayCredentials(cm.c)
+	expiries := make([]domain.CredentialExpiry, 0, len(credentials))
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	for _, credential := range credentials {
+		expiry := credential.expiry(now, cm.leadTime)
+		expiry.Alerted = cm.alerted[credential.name] == credential.fingerprint()
+		expiries = append(expiries, expiry)
+	}
+	return expiries
+}
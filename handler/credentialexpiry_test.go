@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func credentialExpiryConfig(expiry string) *config.Config {
+	c := config.NewConfig(viper.New())
+	c.Set("sms.cdac.username", "appostsms")
+	c.Set("sms.cdac.password", "secret")
+	c.Set("sms.cdac.passwordexpiry", expiry)
+	c.Set("sms.nic.INPOSTusername", "speedpost.sms")
+	c.Set("sms.nic.INPOSTpassword", "secret")
+	c.Set("sms.credentialsets.tenant1.nic.username", "tenant.sms")
+	c.Set("sms.credentialsets.tenant1.nic.passwordexpiry", "2026-10-20")
+	c.Set("sms.credentialexpiry.leadtime", "72h")
+	return c
+}
+
+func TestCredentialExpiryLeadTime(t *testing.T) {
+	// 2026-10-16 10:00 IST
+	now := time.Date(2026, 10, 16, 4, 30, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		expiry string
+		status string
+		days   int
+	}{
+		{"2026-11-30", domain.CredentialExpiryValid, 45},
+		// the 72h lead time ends 2026-10-19 10:00 IST, a password expiring 2026-10-20 00:00 IST
+		// is 86h away
+		{"2026-10-20", domain.CredentialExpiryValid, 4},
+		{"2026-10-19", domain.CredentialExpiryExpiring, 3},
+		{"2026-10-17", domain.CredentialExpiryExpiring, 1},
+		{"2026-10-16", domain.CredentialExpiryExpired, 0},
+		{"2026-10-01", domain.CredentialExpiryExpired, -15},
+	} {
+		cm := NewCredentialExpiryMonitor(credentialExpiryConfig(tc.expiry))
+		cm.now = func() time.Time { return now }
+		expiries := cm.Expiries()
+		require.Len(t, expiries, 3)
+		assert.Equal(t, "cdac", expiries[0].Credential)
+		assert.Equal(t, tc.status, expiries[0].Status, tc.expiry)
+		require.NotNil(t, expiries[0].DaysRemaining)
+		assert.Equal(t, tc.days, *expiries[0].DaysRemaining, tc.expiry)
+	}
+
+	// invalid and missing expiries are unknown
+	cm := NewCredentialExpiryMonitor(credentialExpiryConfig("not-a-date"))
+	cm.now = func() time.Time { return now }
+	expiries := cm.Expiries()
+	assert.Equal(t, domain.CredentialExpiryUnknown, expiries[0].Status)
+	assert.Nil(t, expiries[0].ExpiresAt)
+	assert.Equal(t, "nic.INPOST", expiries[1].Credential)
+	assert.Equal(t, domain.CredentialExpiryUnknown, expiries[1].Status)
+	assert.Equal(t, "credentialsets.tenant1.nic", expiries[2].Credential)
+	assert.Equal(t, "tenant1", expiries[2].CredentialSet)
+	assert.Equal(t, domain.CredentialExpiryValid, expiries[2].Status)
+}
+
+func TestCredentialExpiryAlertsOncePerCredential(t *testing.T) {
+	var alerts []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&alert))
+		alerts = append(alerts, alert)
+	}))
+	defer server.Close()
+
+	c := credentialExpiryConfig("2026-10-18")
+	c.Set("sms.credentialexpiry.alertwebhookurl", server.URL)
+	cm := NewCredentialExpiryMonitor(c)
+	cm.now = func() time.Time { return time.Date(2026, 10, 16, 4, 30, 0, 0, time.UTC) }
+
+	cm.Check(context.Background())
+	cm.Check(context.Background())
+	require.Len(t, alerts, 1)
+	assert.Equal(t, credentialExpiryEventName, alerts[0]["event"])
+	assert.Equal(t, "cdac", alerts[0]["credential"])
+	assert.Equal(t, float64(2), alerts[0]["days_remaining"])
+	assert.True(t, cm.Expiries()[0].Alerted)
+
+	// a rotated password clears the alert state, the new one is alerted in turn
+	c.Set("sms.cdac.password", "rotated")
+	assert.False(t, cm.Expiries()[0].Alerted)
+	cm.Check(context.Background())
+	assert.Len(t, alerts, 2)
+
+	// an extended expiry out of the lead time is no longer alerted
+	c.Set("sms.cdac.passwordexpiry", "2027-01-31")
+	cm.Check(context.Background())
+	assert.Len(t, alerts, 2)
+	assert.False(t, cm.Expiries()[0].Alerted)
+}
+
+func TestCredentialExpiryHint(t *testing.T) {
+	now := time.Date(2026, 10, 16, 4, 30, 0, 0, time.UTC)
+	authFailure := domain.MsgResponse{ResponseCode: "401", ResponseText: "Invalid username or password"}
+
+	c := credentialExpiryConfig("2026-10-01")
+	hint := credentialExpiryHint(c, domain.GatewayCDAC, domain.MsgRequest{}, authFailure, now)
+	assert.Equal(t, "credential may be expired, expires_at=2026-10-01", hint)
+
+	// other failures, or passwords not past their expiry, are not hinted
+	assert.Empty(t, credentialExpiryHint(c, domain.GatewayCDAC, domain.MsgRequest{}, domain.MsgResponse{ResponseCode: "02", ResponseText: "timeout"}, now))
+	assert.Empty(t, credentialExpiryHint(credentialExpiryConfig("2026-10-30"), domain.GatewayCDAC, domain.MsgRequest{}, authFailure, now))
+	assert.Empty(t, credentialExpiryHint(c, domain.GatewayNIC, domain.MsgRequest{SenderID: "INPOST"}, authFailure, now))
+
+	// the account of the credential set of the request is checked
+	nicFailure := domain.MsgResponse{ResponseCode: "02", ResponseText: "SMS Gateway returned non-OK status: 401 401 Unauthorized"}
+	hint = credentialExpiryHint(c, domain.GatewayNIC, domain.MsgRequest{CredentialSet: "Tenant1"}, nicFailure, time.Date(2026, 10, 21, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, "credential may be expired, expires_at=2026-10-20", hint)
+}
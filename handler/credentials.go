@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	log "MgApplication/api-log"
+	"MgApplication/core/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errCredentialSetUnknown   = errors.New("unknown credential set")
+	errCredentialSetForbidden = errors.New("application is not allowed to use the credential set")
+	errCredentialSetQueued    = errors.New("credential sets are only supported for OTP and transactional requests")
+	errCredentialSetGateway   = errors.New("credential set has no credentials for the gateway")
+)
+
+// credentialSet is the gateway credentials of a tenant sending with its own CDAC or NIC account
+type credentialSet struct {
+	applications  map[string]bool
+	cdacUsername  string
+	cdacPassword  string
+	cdacSecureKey string
+	nicUsername   string
+	nicPassword   string
+}
+
+// CredentialSets resolves the credential sets configured in sms.credentialsets. A request naming
+// a set in credential_set is sent with the credentials of that set instead of the global ones,
+// provided its application is listed in the applications of the set.
+type CredentialSets struct {
+	sets map[string]credentialSet
+}
+
+// NewCredentialSets creates a new CredentialSets instance using the sms.credentialsets configuration
+func NewCredentialSets(c *config.Config) *CredentialSets {
+	sets := make(map[string]credentialSet)
+	for id := range c.GetStringMap("sms.credentialsets") {
+		key := "sms.credentialsets." + id
+		set := credentialSet{
+			applications:  make(map[string]bool),
+			cdacUsername:  c.GetString(key + ".cdac.username"),
+			cdacPassword:  c.GetString(key + ".cdac.password"),
+			cdacSecureKey: c.GetString(key + ".cdac.securekey"),
+			nicUsername:   c.GetString(key + ".nic.username"),
+			nicPassword:   c.GetString(key + ".nic.password"),
+		}
+		for _, applicationID := range c.GetStringSlice(key + ".applications") {
+			for _, id := range strings.Split(applicationID, ",") {
+				if id = strings.TrimSpace(id); id != "" {
+					set.applications[id] = true
+				}
+			}
+		}
+		sets[strings.ToLower(id)] = set
+	}
+	return &CredentialSets{sets: sets}
+}
+
+// Authorize checks that applicationID may send with the credential set id
+func (cs *CredentialSets) Authorize(id string, applicationID string) error {
+	set, ok := cs.lookup(id)
+	if !ok {
+		return fmt.Errorf("%w %q", errCredentialSetUnknown, id)
+	}
+	if !set.applications[applicationID] {
+		return errCredentialSetForbidden
+	}
+	return nil
+}
+
+// secrets returns the passwords and keys of every set, redacted from raw gateway responses
+func (cs *CredentialSets) secrets() []string {
+	if cs == nil {
+		return nil
+	}
+	var secrets []string
+	for _, set := range cs.sets {
+		secrets = append(secrets, set.cdacPassword, set.cdacSecureKey, set.nicPassword)
+	}
+	return secrets
+}
+
+func (cs *CredentialSets) lookup(id string) (credentialSet, bool) {
+	if cs == nil {
+		return credentialSet{}, false
+	}
+	set, ok := cs.sets[strings.ToLower(id)]
+	return set, ok
+}
+
+// gatewayCredentials returns the username, password and secure key msgreq is sent with through
+// gateway: those of its credential set when it names one, else the global ones
+func (ch *MgApplicationHandler) gatewayCredentials(gateway domain.GatewayID, msgreq domain.MsgRequest) (string, string, string, error) {
+	if msgreq.CredentialSet == "" {
+		switch gateway {
+		case domain.GatewayCDAC:
+			return ch.c.GetString("sms.cdac.username"), ch.c.GetString("sms.cdac.password"), ch.c.GetString("sms.cdac.securekey"), nil
+		case domain.GatewayNIC:
+			username, password, err := nicCredentials(ch.c, msgreq.SenderID)
+			return username, password, "", err
+		}
+		return "", "", "", fmt.Errorf("invalid gateway %s", gateway)
+	}
+
+	set, ok := ch.credentials.lookup(msgreq.CredentialSet)
+	if !ok {
+		return "", "", "", fmt.Errorf("%w %q", errCredentialSetUnknown, msgreq.CredentialSet)
+	}
+	switch {
+	case gateway == domain.GatewayCDAC && set.cdacUsername != "":
+		return set.cdacUsername, set.cdacPassword, set.cdacSecureKey, nil
+	case gateway == domain.GatewayNIC && set.nicUsername != "":
+		return set.nicUsername, set.nicPassword, "", nil
+	}
+	return "", "", "", fmt.Errorf("%w %s", errCredentialSetGateway, gateway)
+}
+
+// authorizeCredentialSet checks that the application of msgreq may use the credential set it
+// names, answering the request when it may not
+func (ch *MgApplicationHandler) authorizeCredentialSet(ctx *gin.Context, msgreq domain.MsgRequest) bool {
+	if msgreq.CredentialSet == "" {
+		return true
+	}
+	err := ch.credentials.Authorize(msgreq.CredentialSet, msgreq.ApplicationID)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errCredentialSetForbidden):
+		log.Error(ctx, "Application %s is not allowed to use credential set %s", msgreq.ApplicationID, msgreq.CredentialSet)
+		apierrors.ErrorResponseWithStatusCodeAndMessage(ctx, apierrors.HTTPErrorForbidden, err.Error(), err)
+	default:
+		log.Error(ctx, "Credential set check failed: %s", err.Error())
+		apierrors.ErrorResponseWithStatusCodeAndMessage(ctx, apierrors.HTTPErrorBadRequest, err.Error(), err)
+	}
+	return false
+}
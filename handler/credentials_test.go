@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	config "MgApplication/api-config"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCredentialSetConfig configures a CDAC gateway at url with global credentials and the
+// credential set tenant1, allowed to application 7
+func newCredentialSetConfig(url string) *config.Config {
+	c := config.NewConfig(viper.New())
+	c.Set("sms.cdac.url", url)
+	c.Set("sms.cdac.username", "appostsms")
+	c.Set("sms.cdac.password", "global-secret")
+	c.Set("sms.cdac.securekey", "global-key")
+	c.Set("sms.credentialsets.tenant1.applications", []string{"7"})
+	c.Set("sms.credentialsets.tenant1.cdac.username", "tenant1sms")
+	c.Set("sms.credentialsets.tenant1.cdac.password", "tenant-secret")
+	c.Set("sms.credentialsets.tenant1.cdac.securekey", "tenant-key")
+	return c
+}
+
+func TestCreateSMSRequestCredentialSet(t *testing.T) {
+	var usernames []string
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		usernames = append(usernames, r.PostForm.Get("username"))
+		_, _ = w.Write([]byte("402,MsgID = 060320251741252969158appostsms"))
+	}))
+	defer cdac.Close()
+
+	tests := []struct {
+		name          string
+		applicationID string
+		credentialSet string
+		priority      int
+		wantStatus    int
+		wantUsername  string
+	}{
+		{"default credentials", "7", "", 1, http.StatusCreated, "appostsms"},
+		{"credential set", "7", "tenant1", 1, http.StatusCreated, "tenant1sms"},
+		{"credential set id is case insensitive", "7", "TENANT1", 2, http.StatusCreated, "tenant1sms"},
+		{"application not allowed", "8", "tenant1", 1, http.StatusForbidden, ""},
+		{"unknown credential set", "7", "tenant2", 1, http.StatusBadRequest, ""},
+		{"queued priority", "7", "tenant1", 3, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usernames = nil
+			ch, _ := newTestSMSHandler(newCredentialSetConfig(cdac.URL))
+			body := otpRequestBody("9000000001")
+			body["application_id"] = tt.applicationID
+			body["priority"] = tt.priority
+			if tt.credentialSet != "" {
+				body["credential_set"] = tt.credentialSet
+			}
+
+			rec := postSMSRequest(ch, body)
+			require.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantUsername == "" {
+				assert.Empty(t, usernames)
+				return
+			}
+			assert.Equal(t, []string{tt.wantUsername}, usernames)
+		})
+	}
+}
+
+func TestCredentialSetSecretsRedacted(t *testing.T) {
+	c := newCredentialSetConfig("")
+	c.Set("sms.debug.includeRaw", true)
+	ch, _ := newTestSMSHandler(c)
+
+	raw := ch.rawGatewayResponse("402 for tenant1sms/tenant-secret/tenant-key")
+	assert.Equal(t, "402 for tenant1sms/[REDACTED]/[REDACTED]", raw)
+
+	params := SMSParams{Username: "tenant1sms", Password: "tenant-secret", SecureKey: "tenant-key", SenderID: "INPOST"}
+	assert.NotContains(t, params.String(), "tenant-secret")
+	assert.NotContains(t, params.String(), "tenant-key")
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	config "MgApplication/api-config"
+	log "MgApplication/api-log"
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var DeliveryWaitsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "sms_delivery_waits_total",
+		Help: "Total number of OTP requests waiting for the delivery of their message, by outcome",
+	},
+	[]string{"outcome"},
+)
+
+// Delivery statuses answered to a request waiting for the delivery of its message
+const (
+	WaitDeliveryDelivered = "DELIVERED"
+	WaitDeliveryPending   = "PENDING"
+	WaitDeliveryFailed    = "FAILED"
+)
+
+// Outcomes of a wait not reaching a delivery status, the other outcomes of DeliveryWaitsTotal
+const (
+	deliveryWaitOverflow    = "overflow"
+	deliveryWaitUnsupported = "unsupported"
+)
+
+// errDeliveryWaitPriority rejects wait_for_delivery_ms on the requests other than OTP
+var errDeliveryWaitPriority = errors.New("wait_for_delivery_ms is only supported for OTP requests")
+
+// DeliveryWait holds an OTP request once its message is accepted by the gateway, polling the
+// delivery report until the message is delivered or failed or the wait is over. Nothing is held
+// open but the request: the send is stored before the wait starts. At most
+// sms.deliverywait.concurrency requests wait at once, the others being answered at once without
+// a delivery status.
+type DeliveryWait struct {
+	fetchers map[string]port.DeliveryStatusFetcher
+	max      time.Duration
+	interval time.Duration
+	slots    chan struct{}
+}
+
+// NewDeliveryWait creates a new DeliveryWait instance using the sms.deliverywait configuration.
+// A max of 0 disables the wait.
+func NewDeliveryWait(c *config.Config) *DeliveryWait {
+	interval := c.GetDuration("sms.deliverywait.interval")
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	concurrency := c.GetInt("sms.deliverywait.concurrency")
+	if concurrency <= 0 {
+		concurrency = 50
+	}
+	w := &DeliveryWait{
+		fetchers: make(map[string]port.DeliveryStatusFetcher),
+		max:      c.GetDuration("sms.deliverywait.max"),
+		interval: interval,
+		slots:    make(chan struct{}, concurrency),
+	}
+	cdac, err := NewCDACStatusFetcher(c)
+	if err != nil {
+		log.Error(nil, "Delivery wait disabled for gateway %s: %s", domain.GatewayCDAC, err.Error())
+		return w
+	}
+	w.fetchers[string(domain.GatewayCDAC)] = cdac
+	return w
+}
+
+// Limit returns the wait requested in milliseconds capped by sms.deliverywait.max
+func (w *DeliveryWait) Limit(requestedMs int) time.Duration {
+	if w == nil || requestedMs <= 0 {
+		return 0
+	}
+	return min(time.Duration(requestedMs)*time.Millisecond, w.max)
+}
+
+// Wait polls the delivery report of the message of referenceID sent through gateway every
+// interval for at most wait, returning WaitDeliveryDelivered once every recipient is reached or
+// some are and the others failed, WaitDeliveryFailed once they all failed and WaitDeliveryPending
+// when the wait is over or ctx is done first. An empty status is returned when the message
+// cannot be waited for, its gateway reporting no delivery or every slot being taken.
+func (w *DeliveryWait) Wait(ctx context.Context, gateway domain.GatewayID, referenceID string, wait time.Duration) string {
+	if w == nil || wait <= 0 || referenceID == "" {
+		return ""
+	}
+	fetcher, ok := w.fetchers[string(gateway)]
+	if !ok {
+		DeliveryWaitsTotal.WithLabelValues(deliveryWaitUnsupported).Inc()
+		return ""
+	}
+	select {
+	case w.slots <- struct{}{}:
+		defer func() { <-w.slots }()
+	default:
+		DeliveryWaitsTotal.WithLabelValues(deliveryWaitOverflow).Inc()
+		log.Warn(ctx, "Every delivery wait slot taken, %s answered without waiting", referenceID)
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			DeliveryWaitsTotal.WithLabelValues(strings.ToLower(WaitDeliveryPending)).Inc()
+			return WaitDeliveryPending
+		case <-ticker.C:
+		}
+		recipients, err := fetcher.FetchDeliveryStatus(ctx, referenceID)
+		if err != nil {
+			log.Warn(ctx, "Delivery status of %s not fetched: %s", referenceID, err.Error())
+			continue
+		}
+		status := ""
+		switch messageDeliveryStatus(recipients) {
+		case DeliveryStatusDelivered, DeliveryStatusPartiallyDelivered:
+			status = WaitDeliveryDelivered
+		case DeliveryStatusFailed:
+			status = WaitDeliveryFailed
+		}
+		if status != "" {
+			DeliveryWaitsTotal.WithLabelValues(strings.ToLower(status)).Inc()
+			return status
+		}
+	}
+}
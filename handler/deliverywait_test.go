@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStatusFetcher answers the delivery reports of statuses in turn, the last one repeated
+type fakeStatusFetcher struct {
+	statuses []string
+	calls    atomic.Int64
+	block    chan struct{}
+}
+
+func (f *fakeStatusFetcher) FetchDeliveryStatus(ctx context.Context, referenceID string) ([]domain.RecipientStatus, error) {
+	call := int(f.calls.Add(1))
+	if f.block != nil {
+		select {
+		case <-f.block:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	status := f.statuses[min(call, len(f.statuses))-1]
+	if status == "" {
+		return nil, errors.New("report not available")
+	}
+	return []domain.RecipientStatus{{MobileNumber: "9000000001", Status: status}}, nil
+}
+
+func newTestDeliveryWait(fetcher port.DeliveryStatusFetcher, concurrency int) *DeliveryWait {
+	return &DeliveryWait{
+		fetchers: map[string]port.DeliveryStatusFetcher{string(domain.GatewayCDAC): fetcher},
+		max:      10 * time.Second,
+		interval: 10 * time.Millisecond,
+		slots:    make(chan struct{}, concurrency),
+	}
+}
+
+func TestDeliveryWait(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		wait     time.Duration
+		want     string
+		calls    int64
+	}{
+		{name: "delivered on the second report", statuses: []string{DeliveryStatusSubmitted, DeliveryStatusDelivered}, wait: 5 * time.Second, want: WaitDeliveryDelivered, calls: 2},
+		{name: "failed before the wait is over", statuses: []string{"", DeliveryStatusFailed}, wait: 5 * time.Second, want: WaitDeliveryFailed, calls: 2},
+		{name: "pending when the wait is over", statuses: []string{DeliveryStatusSubmitted}, wait: 100 * time.Millisecond, want: WaitDeliveryPending},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := &fakeStatusFetcher{statuses: tt.statuses}
+			w := newTestDeliveryWait(fetcher, 1)
+
+			start := time.Now()
+			assert.Equal(t, tt.want, w.Wait(context.Background(), domain.GatewayCDAC, "150920241726381202115", tt.wait))
+			assert.Less(t, time.Since(start), tt.wait+time.Second)
+			if tt.calls > 0 {
+				assert.Equal(t, tt.calls, fetcher.calls.Load())
+			}
+		})
+	}
+}
+
+func TestDeliveryWaitDegrades(t *testing.T) {
+	fetcher := &fakeStatusFetcher{statuses: []string{DeliveryStatusDelivered}, block: make(chan struct{})}
+	w := newTestDeliveryWait(fetcher, 1)
+
+	// the only slot is taken by a wait blocked on its report
+	done := make(chan string)
+	go func() { done <- w.Wait(context.Background(), domain.GatewayCDAC, "1", 5*time.Second) }()
+	require.Eventually(t, func() bool { return fetcher.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	overflow := testutil.ToFloat64(DeliveryWaitsTotal.WithLabelValues(deliveryWaitOverflow))
+	assert.Equal(t, "", w.Wait(context.Background(), domain.GatewayCDAC, "2", 5*time.Second))
+	assert.Equal(t, overflow+1, testutil.ToFloat64(DeliveryWaitsTotal.WithLabelValues(deliveryWaitOverflow)))
+	close(fetcher.block)
+	assert.Equal(t, WaitDeliveryDelivered, <-done)
+
+	// gateways without delivery reports are not waited for
+	assert.Equal(t, "", w.Wait(context.Background(), domain.GatewayNIC, "3", 5*time.Second))
+
+	// a request cancelled by the client stops waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	fetcher = &fakeStatusFetcher{statuses: []string{DeliveryStatusSubmitted}}
+	w = newTestDeliveryWait(fetcher, 1)
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	assert.Equal(t, WaitDeliveryPending, w.Wait(ctx, domain.GatewayCDAC, "4", 5*time.Second))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestCreateSMSWaitForDelivery(t *testing.T) {
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("402,MsgID = 150920241726381202115"))
+	}))
+	defer cdac.Close()
+	var reports atomic.Int64
+	report := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if reports.Add(1) == 1 {
+			_, _ = w.Write([]byte("9000000001,SUBMITTED,16-10-2026 10:00:00\n"))
+			return
+		}
+		_, _ = w.Write([]byte("9000000001,DELIVRD,16-10-2026 10:00:01\n"))
+	}))
+	defer report.Close()
+	c := brandingConfig(true)
+	c.Set("sms.cdac.url", cdac.URL)
+	c.Set("sms.cdac.deliverystatusurl", report.URL)
+	c.Set("sms.deliverywait.max", "2s")
+	c.Set("sms.deliverywait.interval", "10ms")
+
+	ch, _ := newTestSMSHandler(c)
+	ch.deliveryWait = NewDeliveryWait(c)
+
+	body := otpRequestBody("9000000001")
+	body["wait_for_delivery_ms"] = 60000
+	rec := postSMSRequest(ch, body)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var rsp struct {
+		Data struct {
+			ReferenceID    string `json:"reference_id"`
+			DeliveryStatus string `json:"delivery_status"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, WaitDeliveryDelivered, rsp.Data.DeliveryStatus)
+	assert.EqualValues(t, 2, reports.Load())
+
+	// without the option the request is answered at once, without delivery_status
+	rec = postSMSRequest(ch, otpRequestBody("9000000001"))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "delivery_status")
+
+	// only OTP requests wait
+	body["priority"] = 2
+	rec = postSMSRequest(ch, body)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), errDeliveryWaitPriority.Error())
+}
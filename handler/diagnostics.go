@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	log "MgApplication/api-log"
+	serverRoute "MgApplication/api-server/route"
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+	"MgApplication/handler/response"
+	repo "MgApplication/repo/postgres"
+)
+
+// defaultExplainStatementTimeout is the statement timeout of an EXPLAIN without
+// admin.explain.statementtimeout
+const defaultExplainStatementTimeout = 5 * time.Second
+
+var errExplainInProd = errors.New("EXPLAIN of the registered queries is refused in production")
+
+// explainStore explains the queries registered for the diagnostics, the DiagnosticsRepository
+// outside the tests
+type explainStore interface {
+	ExplainQueryRepo(ctx context.Context, query repo.DiagnosticQuery, timeout time.Duration) (domain.QueryPlan, error)
+}
+
+// explainStatementTimeout returns the statement timeout of an EXPLAIN,
+// admin.explain.statementtimeout
+func explainStatementTimeout(c *config.Config) time.Duration {
+	if timeout := c.GetDuration("admin.explain.statementtimeout"); timeout > 0 {
+		return timeout
+	}
+	return defaultExplainStatementTimeout
+}
+
+type explainQueryRequest struct {
+	Name string `json:"name" validate:"required,max=64" example:"sms_sent_status_report"`
+}
+
+// ExplainQueryHandler godoc
+//
+//	@Summary		Explains a registered query
+//	@Description	Returns the EXPLAIN (ANALYZE, BUFFERS) plan of a query a repository registered for the diagnostics, run with its sample parameters, as JSON. ANALYZE executes the query, so it runs with a statement timeout of admin.explain.statementtimeout in a transaction that is always rolled back, writes included. Only registered queries are explained, never SQL from the request. Refused with 403 in production
+//	@Tags			Admin
+//	@ID				ExplainQueryHandler
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization		header		string						true	"Bearer token whose scope claim includes the admin scope"
+//	@Param			explainQueryRequest	body		explainQueryRequest			true	"Name of the registered query"
+//	@Success		200					{object}	response.QueryPlanAPIResponse	"Query plan is fetched"
+//	@Failure		403					{object}	apierrors.APIErrorResponse	"Forbidden, or the service runs in production"
+//	@Failure		404					{object}	apierrors.APIErrorResponse	"No query is registered with the name"
+//	@Failure		422					{object}	apierrors.APIErrorResponse	"Binding or Validation error"
+//	@Failure		500					{object}	apierrors.APIErrorResponse	"Internal server error, the statement timeout included"
+//	@Router			/admin/explain [post]
+func (ah *AdminHandler) ExplainQueryHandler(sctx *serverRoute.Context, req explainQueryRequest) (*response.QueryPlanAPIResponse, error) {
+	if isProduction(ah.c) {
+		log.Warn(sctx.Ctx, "EXPLAIN of %s refused in production", req.Name)
+		return nil, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.HTTPErrorForbidden, errExplainInProd.Error(), errExplainInProd)
+	}
+	query, ok := repo.LookupDiagnosticQuery(req.Name)
+	if !ok {
+		message := fmt.Sprintf("no query named %s is registered, registered queries: %s", req.Name, strings.Join(repo.DiagnosticQueryNames(), ", "))
+		return nil, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.HTTPErrorNotFound, message, nil)
+	}
+
+	plan, err := ah.explainsvc.ExplainQueryRepo(sctx.Ctx, query, explainStatementTimeout(ah.c))
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in ExplainQueryRepo function: %s", err.Error())
+		return nil, err
+	}
+	log.Info(sctx.Ctx, "Explained query %s for %s, executed in %.3f ms", query.Name, adminUserID(sctx.Ctx), plan.ExecutionTimeMs)
+
+	return &response.QueryPlanAPIResponse{
+		StatusCodeAndMessage: port.FetchSuccess,
+		Data:                 &plan,
+	}, nil
+}
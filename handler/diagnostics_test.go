@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	auth "MgApplication/api-authz"
+	config "MgApplication/api-config"
+	"MgApplication/api-server/middlewares"
+	"MgApplication/core/domain"
+	repo "MgApplication/repo/postgres"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeExplainStore records the queries explained instead of running them
+type fakeExplainStore struct {
+	explained []string
+	timeout   time.Duration
+}
+
+func (s *fakeExplainStore) ExplainQueryRepo(ctx context.Context, query repo.DiagnosticQuery, timeout time.Duration) (domain.QueryPlan, error) {
+	s.explained = append(s.explained, query.Name)
+	s.timeout = timeout
+	sql, args, err := query.Build().ToSql()
+	if err != nil {
+		return domain.QueryPlan{}, err
+	}
+	return domain.QueryPlan{Name: query.Name, Query: sql, Args: args, StatementTimeoutMs: timeout.Milliseconds(), Plan: json.RawMessage(`{"Node Type":"Result"}`)}, nil
+}
+
+// explainServer serves the admin routes with store explaining the queries
+func explainServer(c *config.Config, store *fakeExplainStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	ah := NewAdminHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, c)
+	ah.explainsvc = store
+	return adminEngine(ah)
+}
+
+// adminEngine serves the admin routes of ah, authenticating the bearer tokens of bundleBearer
+func adminEngine(ah *AdminHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middlewares.ErrorHandler(), auth.Authenticate([]byte("secret")))
+	group := engine.Group(ah.Prefix(), ah.Middlewares()...)
+	for _, route := range ah.Routes() {
+		meta := route.Meta()
+		group.Handle(meta.Method, meta.Path, meta.Func)
+	}
+	return engine
+}
+
+func explainConfig() *config.Config {
+	c := config.NewConfig(viper.New())
+	c.Set("admin.scope", "admin")
+	c.Set("admin.explain.statementtimeout", "2s")
+	return c
+}
+
+func TestExplainQuery(t *testing.T) {
+	store := &fakeExplainStore{}
+	engine := explainServer(explainConfig(), store)
+
+	rec := callBundle(t, engine, http.MethodPost, "/v1/admin/explain", map[string]any{"name": "send_cap_consume"}, "admin")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var rsp struct {
+		Data domain.QueryPlan `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, "send_cap_consume", rsp.Data.Name)
+	assert.Contains(t, rsp.Data.Query, "INSERT INTO msg_send_cap")
+	assert.EqualValues(t, 2000, rsp.Data.StatementTimeoutMs)
+	assert.JSONEq(t, `{"Node Type":"Result"}`, string(rsp.Data.Plan))
+	assert.Equal(t, 2*time.Second, store.timeout)
+
+	// only registered queries are explained
+	rec = callBundle(t, engine, http.MethodPost, "/v1/admin/explain", map[string]any{"name": "SELECT 1"}, "admin")
+	require.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "sms_sent_status_report")
+
+	rec = callBundle(t, engine, http.MethodPost, "/v1/admin/explain", map[string]any{"name": "send_cap_consume"}, "reports")
+	assert.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+	assert.Equal(t, []string{"send_cap_consume"}, store.explained)
+}
+
+func TestExplainQueryRefusedInProduction(t *testing.T) {
+	t.Setenv("APP_ENV", "production")
+	store := &fakeExplainStore{}
+	engine := explainServer(explainConfig(), store)
+
+	rec := callBundle(t, engine, http.MethodPost, "/v1/admin/explain", map[string]any{"name": "send_cap_consume"}, "admin")
+	require.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "refused in production")
+	assert.Empty(t, store.explained)
+}
@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	config "MgApplication/api-config"
+	log "MgApplication/api-log"
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+	repo "MgApplication/repo/postgres"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DNDStatusSkipped is the per-recipient status of numbers that were not sent a promotional
+// or bulk message because they are registered on the DND (NCPR) registry
+const DNDStatusSkipped = "SKIPPED_DND"
+
+// defaultDNDCacheSize is the number of lookup results cached when sms.dnd.cachesize is not set
+const defaultDNDCacheSize = 100000
+
+var dndMobileNumberPattern = regexp.MustCompile(`^[6-9][0-9]{9}$`)
+
+var (
+	DNDSkippedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "sms_dnd_skipped_total",
+			Help: "Total number of promotional and bulk recipients skipped as DND",
+		},
+	)
+
+	DNDCheckFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sms_dnd_check_failures_total",
+			Help: "Total number of failed DND checks, by the policy applied",
+		},
+		[]string{"policy"},
+	)
+)
+
+// normalizeMobileNumber reduces a mobile number to the 10 digit form stored in the registry,
+// dropping the +91/91/0 prefixes
+func normalizeMobileNumber(mobileNumber string) string {
+	mobileNumber = strings.TrimPrefix(strings.TrimSpace(mobileNumber), "+")
+	switch {
+	case len(mobileNumber) == 12 && strings.HasPrefix(mobileNumber, "91"):
+		return mobileNumber[2:]
+	case len(mobileNumber) == 11 && strings.HasPrefix(mobileNumber, "0"):
+		return mobileNumber[1:]
+	}
+	return mobileNumber
+}
+
+// NewDNDChecker returns the DND checker selected by sms.dnd.checker
+func NewDNDChecker(dndsvc *repo.DNDRepository, c *config.Config) port.DNDChecker {
+	if c.GetString("sms.dnd.checker") == "http" {
+		return NewHTTPDNDChecker(c)
+	}
+	return dndsvc
+}
+
+// DNDFilter applies the DND check to promotional (3) and bulk (4) messages. OTP and
+// transactional messages are never checked.
+type DNDFilter struct {
+	checker  port.DNDChecker
+	enabled  bool
+	failOpen bool
+	timeout  time.Duration
+}
+
+// NewDNDFilter creates a new DNDFilter instance using the sms.dnd configuration
+func NewDNDFilter(checker port.DNDChecker, c *config.Config) *DNDFilter {
+	return &DNDFilter{
+		checker:  checker,
+		enabled:  c.GetBool("sms.dnd.enabled"),
+		failOpen: c.GetBool("sms.dnd.failopen"),
+		timeout:  c.GetDuration("sms.dnd.timeout"),
+	}
+}
+
+// Filter splits mobileNumbers into the numbers that may be sent to and the numbers skipped
+// as DND. When the check fails, all numbers are allowed with sms.dnd.failopen set and an
+// error is returned otherwise.
+func (f *DNDFilter) Filter(ctx context.Context, priority int, mobileNumbers []string) ([]string, []string, error) {
+	if f == nil || !f.enabled || !domain.Priority(priority).Promotional() || len(mobileNumbers) == 0 {
+		return mobileNumbers, nil, nil
+	}
+
+	lookup := make([]string, 0, len(mobileNumbers))
+	seen := make(map[string]bool, len(mobileNumbers))
+	for _, mobileNumber := range mobileNumbers {
+		normalized := normalizeMobileNumber(mobileNumber)
+		if !seen[normalized] {
+			seen[normalized] = true
+			lookup = append(lookup, normalized)
+		}
+	}
+
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+
+	dnd, err := f.checker.CheckDND(ctx, lookup)
+	if err != nil {
+		if f.failOpen {
+			DNDCheckFailuresTotal.WithLabelValues("open").Inc()
+			log.Warn(ctx, "DND check failed, sending to all %d recipients: %s", len(mobileNumbers), err.Error())
+			return mobileNumbers, nil, nil
+		}
+		DNDCheckFailuresTotal.WithLabelValues("closed").Inc()
+		log.Error(ctx, "DND check failed, rejecting %d recipients: %s", len(mobileNumbers), err.Error())
+		return nil, nil, fmt.Errorf("DND check failed: %w", err)
+	}
+
+	var allowed, skipped []string
+	for _, mobileNumber := range mobileNumbers {
+		if dnd[normalizeMobileNumber(mobileNumber)] {
+			skipped = append(skipped, mobileNumber)
+		} else {
+			allowed = append(allowed, mobileNumber)
+		}
+	}
+	DNDSkippedTotal.Add(float64(len(skipped)))
+	return allowed, skipped, nil
+}
+
+type dndCacheEntry struct {
+	mobileNumber string
+	dnd          bool
+	expires      time.Time
+}
+
+type dndLookupRequest struct {
+	MobileNumbers []string `json:"mobile_numbers"`
+}
+
+type dndLookupResponse struct {
+	Results []struct {
+		MobileNumber string `json:"mobile_number"`
+		DND          bool   `json:"dnd"`
+	} `json:"results"`
+}
+
+// HTTPDNDChecker looks up numbers with the external DND service at sms.dnd.checkurl.
+// Numbers are sent in batches of sms.dnd.batchsize and the results, including numbers
+// that are not registered, are cached for sms.dnd.cachettl. At most sms.dnd.cachesize
+// results are kept, evicting the least recently used.
+type HTTPDNDChecker struct {
+	url       string
+	batchSize int
+	ttl       time.Duration
+	cacheSize int
+	client    *http.Client
+
+	mu    sync.Mutex
+	cache map[string]*list.Element
+	lru   *list.List // of *dndCacheEntry, most recently used first
+	now   func() time.Time
+}
+
+// NewHTTPDNDChecker creates a new HTTPDNDChecker instance using the sms.dnd configuration
+func NewHTTPDNDChecker(c *config.Config) *HTTPDNDChecker {
+	batchSize := c.GetInt("sms.dnd.batchsize")
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	cacheSize := c.GetInt("sms.dnd.cachesize")
+	if cacheSize <= 0 {
+		cacheSize = defaultDNDCacheSize
+	}
+	return &HTTPDNDChecker{
+		url:       c.GetString("sms.dnd.checkurl"),
+		batchSize: batchSize,
+		ttl:       c.GetDuration("sms.dnd.cachettl"),
+		cacheSize: cacheSize,
+		client:    &http.Client{Timeout: c.GetDuration("sms.dnd.timeout")},
+		cache:     make(map[string]*list.Element),
+		lru:       list.New(),
+		now:       time.Now,
+	}
+}
+
+// CheckDND implements port.DNDChecker. Cached numbers are answered without a lookup.
+func (hc *HTTPDNDChecker) CheckDND(ctx context.Context, mobileNumbers []string) (map[string]bool, error) {
+	dnd := make(map[string]bool)
+	pending := hc.fromCache(mobileNumbers, dnd)
+
+	for start := 0; start < len(pending); start += hc.batchSize {
+		batch := pending[start:min(start+hc.batchSize, len(pending))]
+		registered, err := hc.lookup(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		hc.store(batch, registered)
+		for _, mobileNumber := range batch {
+			if registered[mobileNumber] {
+				dnd[mobileNumber] = true
+			}
+		}
+	}
+	return dnd, nil
+}
+
+// fromCache records the cached DND numbers in dnd and returns the numbers still to be looked up
+func (hc *HTTPDNDChecker) fromCache(mobileNumbers []string, dnd map[string]bool) []string {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	now := hc.now()
+	var pending []string
+	for _, mobileNumber := range mobileNumbers {
+		elem, ok := hc.cache[mobileNumber]
+		if !ok {
+			pending = append(pending, mobileNumber)
+			continue
+		}
+		entry := elem.Value.(*dndCacheEntry)
+		if !now.Before(entry.expires) {
+			hc.lru.Remove(elem)
+			delete(hc.cache, mobileNumber)
+			pending = append(pending, mobileNumber)
+			continue
+		}
+		hc.lru.MoveToFront(elem)
+		if entry.dnd {
+			dnd[mobileNumber] = true
+		}
+	}
+	return pending
+}
+
+func (hc *HTTPDNDChecker) store(mobileNumbers []string, registered map[string]bool) {
+	if hc.ttl <= 0 {
+		return
+	}
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	expires := hc.now().Add(hc.ttl)
+	for _, mobileNumber := range mobileNumbers {
+		if elem, ok := hc.cache[mobileNumber]; ok {
+			elem.Value = &dndCacheEntry{mobileNumber: mobileNumber, dnd: registered[mobileNumber], expires: expires}
+			hc.lru.MoveToFront(elem)
+			continue
+		}
+		hc.cache[mobileNumber] = hc.lru.PushFront(&dndCacheEntry{mobileNumber: mobileNumber, dnd: registered[mobileNumber], expires: expires})
+		if hc.lru.Len() > hc.cacheSize {
+			oldest := hc.lru.Back()
+			hc.lru.Remove(oldest)
+			delete(hc.cache, oldest.Value.(*dndCacheEntry).mobileNumber)
+		}
+	}
+}
+
+// lookup posts one batch of numbers to the DND service and returns the registered numbers
+func (hc *HTTPDNDChecker) lookup(ctx context.Context, mobileNumbers []string) (map[string]bool, error) {
+	body, err := json.Marshal(dndLookupRequest{MobileNumbers: mobileNumbers})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hc.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := hc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("DND lookup returned status %d", resp.StatusCode)
+	}
+
+	var rsp dndLookupResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rsp); err != nil {
+		return nil, fmt.Errorf("decoding DND lookup response: %w", err)
+	}
+
+	registered := make(map[string]bool, len(rsp.Results))
+	for _, result := range rsp.Results {
+		if result.DND {
+			registered[normalizeMobileNumber(result.MobileNumber)] = true
+		}
+	}
+	return registered, nil
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingDNDServer answers every lookup with no registered numbers and counts the numbers looked up
+func countingDNDServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var looked atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req dndLookupRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		looked.Add(int32(len(req.MobileNumbers)))
+		_, _ = w.Write([]byte(`{"results":[]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &looked
+}
+
+func TestHTTPDNDCheckerCacheIsBounded(t *testing.T) {
+	srv, looked := countingDNDServer(t)
+	c := config.NewConfig(viper.New())
+	c.Set("sms.dnd.checkurl", srv.URL)
+	c.Set("sms.dnd.cachettl", time.Hour)
+	c.Set("sms.dnd.cachesize", 2)
+	checker := NewHTTPDNDChecker(c)
+	ctx := context.Background()
+
+	_, _ = checker.CheckDND(ctx, []string{"9000000001", "9000000002"})
+	// 9000000001 becomes the most recently used, so 9000000002 is evicted for 9000000003
+	_, _ = checker.CheckDND(ctx, []string{"9000000001"})
+	_, _ = checker.CheckDND(ctx, []string{"9000000003"})
+	assert.Equal(t, int32(3), looked.Load())
+	assert.Equal(t, 2, checker.lru.Len())
+	assert.Len(t, checker.cache, 2)
+
+	_, _ = checker.CheckDND(ctx, []string{"9000000001", "9000000003"})
+	assert.Equal(t, int32(3), looked.Load())
+	_, _ = checker.CheckDND(ctx, []string{"9000000002"})
+	assert.Equal(t, int32(4), looked.Load())
+}
+
+func TestHTTPDNDCheckerEvictsExpiredResults(t *testing.T) {
+	srv, looked := countingDNDServer(t)
+	c := config.NewConfig(viper.New())
+	c.Set("sms.dnd.checkurl", srv.URL)
+	c.Set("sms.dnd.cachettl", time.Minute)
+	checker := NewHTTPDNDChecker(c)
+	now := time.Now()
+	checker.now = func() time.Time { return now }
+
+	_, _ = checker.CheckDND(context.Background(), []string{"9000000001"})
+	now = now.Add(2 * time.Minute)
+	_, _ = checker.CheckDND(context.Background(), []string{"9000000001"})
+	assert.Equal(t, int32(2), looked.Load())
+	assert.Equal(t, 1, checker.lru.Len())
+}
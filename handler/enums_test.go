@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	config "MgApplication/api-config"
+	validation "MgApplication/api-validation"
+	"MgApplication/core/domain"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSMSRequestInvalidEnums(t *testing.T) {
+	tests := []struct {
+		priority    int
+		messageType string
+		allowed     string
+	}{
+		{5, "PM", domain.AllowedPriorities()},
+		{1, "XX", domain.AllowedMessageTypes()},
+	}
+	ch, store := newTestSMSHandler(config.NewConfig(viper.New()))
+	for _, tt := range tests {
+		body := otpRequestBody("9000000001")
+		body["priority"] = tt.priority
+		body["message_type"] = tt.messageType
+		rec := postSMSRequest(ch, body)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, body)
+		assert.Contains(t, rec.Body.String(), tt.allowed)
+	}
+	assert.Zero(t, store.savedRequests)
+}
+
+func TestCreateTemplateInvalidGateway(t *testing.T) {
+	rec := postTemplateRequest(NewTemplateHandler(nil, config.NewConfig(viper.New())), map[string]any{
+		"application_id":  "69",
+		"template_name":   "Test Template gateway",
+		"template_format": "Your OTP is {#val} for {#val} for",
+		"sender_id":       "INPOST",
+		"entity_id":       "1001081725895192800",
+		"template_id":     "165071603777774104478740",
+		"message_type":    "PM",
+		"gateway":         "3",
+		"status":          true,
+	})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), domain.AllowedGateways())
+}
+
+// The gateway of a template is one of domain.Gateways on create and on update, anything else is
+// answered with a 422 before the template is linted or stored
+func TestTemplateGatewayValidation(t *testing.T) {
+	th := NewTemplateHandler(nil, config.NewConfig(viper.New()))
+	template := func(gateway string) map[string]any {
+		return map[string]any{
+			"application_id":  "69",
+			"template_name":   "Test Template gateway",
+			"template_format": "Your OTP is {#var#} for booking {#var#} - INDPOST",
+			"sender_id":       "INPOST",
+			"template_id":     "1007188452935484904",
+			"message_type":    "PM",
+			"gateway":         gateway,
+			"status":          true,
+		}
+	}
+	for _, gateway := range []string{"3", "0", "abc", "01", " 1", "CDAC", ""} {
+		rec := postTemplateRequest(th, template(gateway))
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, "create with gateway %q", gateway)
+		assert.Contains(t, rec.Body.String(), `"field":"gateway"`, "create with gateway %q", gateway)
+
+		rec = putTemplateRequest(th, 355, template(gateway))
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, "update with gateway %q", gateway)
+		assert.Contains(t, rec.Body.String(), `"field":"gateway"`, "update with gateway %q", gateway)
+	}
+
+	for _, gateway := range domain.Gateways {
+		create := createTemplateRequest{ApplicationID: "69", TemplateName: "Test Template gateway", TemplateFormat: "Your OTP is {#var#}",
+			SenderID: "INPOST", TemplateID: "1007188452935484904", Gateway: string(gateway), Status: true, MessageType: "PM"}
+		assert.NoError(t, validation.ValidateStruct(create), "create with gateway %s", gateway)
+
+		update := updateTemplateRequest{TemplateLocalID: 355, ApplicationID: "69", TemplateName: "Test Template gateway", TemplateFormat: "Your OTP is {#var#}",
+			SenderID: "INPOST", TemplateID: "1007188452935484904", Gateway: string(gateway), Status: true, MessageType: "PM"}
+		assert.NoError(t, validation.ValidateStruct(update), "update with gateway %s", gateway)
+	}
+}
+
+// dispatchFiles are the handler files that decide how a message is dispatched
+var dispatchFiles = []string{
+	"msgrequest.go",
+	"msgrequestgrpc.go",
+	"otp.go",
+	"bulksms.go",
+	"templates.go",
+	"shadow.go",
+	"statuspoll.go",
+	"dnd.go",
+}
+
+// rawEnumLiterals are the gateway ids and message types that must be compared through the
+// domain enums
+var rawEnumLiterals = map[string]bool{`"1"`: true, `"2"`: true, `"PM"`: true, `"UC"`: true}
+
+// TestDispatchPathUsesEnums fails on comparisons of gateways, message types and priorities
+// against raw literals in the dispatch path, such as gateway == "1" or msgreq.Priority == 3
+func TestDispatchPathUsesEnums(t *testing.T) {
+	fset := token.NewFileSet()
+	for _, name := range dispatchFiles {
+		f, err := parser.ParseFile(fset, name, nil, 0)
+		require.NoError(t, err)
+
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.BinaryExpr:
+				if n.Op != token.EQL && n.Op != token.NEQ {
+					return true
+				}
+				for _, pair := range [][2]ast.Expr{{n.X, n.Y}, {n.Y, n.X}} {
+					if isRawEnumLiteral(pair[0]) || (isIntLiteral(pair[0]) && isPriority(pair[1])) {
+						t.Errorf("%s: raw comparison %s, use the core/domain enums", fset.Position(n.Pos()), exprString(n))
+					}
+				}
+			case *ast.CaseClause:
+				for _, e := range n.List {
+					if isRawEnumLiteral(e) {
+						t.Errorf("%s: raw case %s, use the core/domain enums", fset.Position(e.Pos()), exprString(e))
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+func isRawEnumLiteral(e ast.Expr) bool {
+	lit, ok := e.(*ast.BasicLit)
+	return ok && lit.Kind == token.STRING && rawEnumLiterals[lit.Value]
+}
+
+func isIntLiteral(e ast.Expr) bool {
+	lit, ok := e.(*ast.BasicLit)
+	if !ok || lit.Kind != token.INT {
+		return false
+	}
+	_, err := strconv.Atoi(lit.Value)
+	return err == nil
+}
+
+func isPriority(e ast.Expr) bool {
+	return strings.Contains(strings.ToLower(exprString(e)), "priority")
+}
+
+func exprString(e ast.Node) string {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return exprString(e.X) + "." + e.Sel.Name
+	case *ast.BasicLit:
+		return e.Value
+	case *ast.BinaryExpr:
+		return exprString(e.X) + " " + e.Op.String() + " " + exprString(e.Y)
+	case *ast.CallExpr:
+		return exprString(e.Fun) + "(...)"
+	}
+	return "?"
+}
@@ -0,0 +1,267 @@
+package handler
+
+import (
+	"context"
+	"maps"
+	"math/rand"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	config "MgApplication/api-config"
+	log "MgApplication/api-log"
+	"MgApplication/api-server/middlewares"
+	"MgApplication/core/domain"
+	repo "MgApplication/repo/postgres"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	ErrorSamplesWrittenTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "error_samples_written_total",
+			Help: "Total number of error samples stored in msg_error_sample",
+		},
+	)
+
+	ErrorSamplesDroppedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "error_samples_dropped_total",
+			Help: "Total number of error samples lost, because the queue was full (full) or the samples could not be stored (store)",
+		},
+		[]string{"reason"},
+	)
+)
+
+// redactedSummaryValue replaces the values of the sensitive parameters in request summaries
+const redactedSummaryValue = "[REDACTED]"
+
+// summaryDigitsRegexp matches the digit runs of a request summary that may be mobile numbers
+var summaryDigitsRegexp = regexp.MustCompile(`\+?\d+`)
+
+// errorSampleStore stores the error samples, the ErrorSampleRepository outside the tests
+type errorSampleStore interface {
+	SaveErrorSamplesRepo(ctx context.Context, samples []domain.ErrorSample) error
+	PurgeErrorSamplesRepo(ctx context.Context, retention time.Duration) (int64, error)
+}
+
+// errorSampler keeps every panic and a fraction rate of the 5xx responses
+type errorSampler struct {
+	rate float64
+	mu   sync.Mutex // guards rand, which is not safe for concurrent use
+	rand *rand.Rand
+}
+
+func (s *errorSampler) keep(sample middlewares.ErrorSample) bool {
+	switch {
+	case sample.Panicked || s.rate >= 1:
+		return true
+	case s.rate <= 0:
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rand.Float64() < s.rate
+}
+
+// ErrorSampleWriter stores every panic and a fraction admin.errorsamples.rate of the 5xx
+// responses in msg_error_sample for post-incident review. Samples are queued without blocking
+// the request and stored in batches by Run, so a failing database never slows requests down:
+// samples that do not fit the queue or cannot be stored are dropped and counted by
+// error_samples_dropped_total. Run also deletes the samples older than
+// admin.errorsamples.retention.
+type ErrorSampleWriter struct {
+	store           errorSampleStore
+	sampler         *errorSampler
+	queue           chan domain.ErrorSample
+	enabled         bool
+	batchSize       int
+	flushInterval   time.Duration
+	retention       time.Duration
+	sweepInterval   time.Duration
+	summaryMaxBytes int
+	redact          map[string]bool
+	state           jobState
+}
+
+// NewErrorSampleWriter creates the ErrorSampleWriter configured by admin.errorsamples
+func NewErrorSampleWriter(store *repo.ErrorSampleRepository, c *config.Config) *ErrorSampleWriter {
+	return newErrorSampleWriter(store, c)
+}
+
+func newErrorSampleWriter(store errorSampleStore, c *config.Config) *ErrorSampleWriter {
+	bufferSize := c.GetInt("admin.errorsamples.buffersize")
+	if bufferSize <= 0 {
+		bufferSize = 1000
+	}
+	batchSize := c.GetInt("admin.errorsamples.batchsize")
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	flushInterval := c.GetDuration("admin.errorsamples.flushinterval")
+	if flushInterval <= 0 {
+		flushInterval = 5 * time.Second
+	}
+	retention := c.GetDuration("admin.errorsamples.retention")
+	if retention <= 0 {
+		retention = 90 * 24 * time.Hour
+	}
+	sweepInterval := c.GetDuration("admin.errorsamples.sweepinterval")
+	if sweepInterval <= 0 {
+		sweepInterval = time.Hour
+	}
+	summaryMaxBytes := c.GetInt("admin.errorsamples.summarymaxbytes")
+	if summaryMaxBytes <= 0 {
+		summaryMaxBytes = 512
+	}
+	redact := make(map[string]bool)
+	for _, field := range log.DefaultBodySampleConfig().RedactFields {
+		redact[strings.ToLower(field)] = true
+	}
+	return &ErrorSampleWriter{
+		store:           store,
+		sampler:         &errorSampler{rate: c.GetFloat64("admin.errorsamples.rate"), rand: rand.New(rand.NewSource(time.Now().UnixNano()))},
+		queue:           make(chan domain.ErrorSample, bufferSize),
+		enabled:         c.GetBool("admin.errorsamples.enabled"),
+		batchSize:       batchSize,
+		flushInterval:   flushInterval,
+		retention:       retention,
+		sweepInterval:   sweepInterval,
+		summaryMaxBytes: summaryMaxBytes,
+		redact:          redact,
+	}
+}
+
+// SampleError queues the sample of a request kept by the sampler, dropping it when the queue is full
+func (w *ErrorSampleWriter) SampleError(c *gin.Context, sample middlewares.ErrorSample) {
+	if !w.sampler.keep(sample) {
+		return
+	}
+	row := domain.ErrorSample{
+		ErrorID:        uuid.New().String(),
+		Route:          sample.Route,
+		Method:         sample.Method,
+		Status:         sample.Status,
+		CorrelationID:  sample.CorrelationID,
+		RequestSummary: w.requestSummary(c.Request.Method, c.Request.URL),
+		Panicked:       sample.Panicked,
+		CreatedAt:      sample.At,
+	}
+	if sample.Panicked {
+		row.Stack = &sample.Stack
+	}
+	select {
+	case w.queue <- row:
+	default:
+		ErrorSamplesDroppedTotal.WithLabelValues("full").Inc()
+	}
+}
+
+// requestSummary returns the method and URL of a request, with the sensitive query parameters
+// redacted and the mobile numbers masked, truncated to summaryMaxBytes
+func (w *ErrorSampleWriter) requestSummary(method string, u *url.URL) string {
+	summary := method + " " + u.Path
+	if query, err := url.ParseQuery(u.RawQuery); err == nil && len(query) > 0 {
+		params := make([]string, 0, len(query))
+		for _, name := range slices.Sorted(maps.Keys(query)) {
+			for _, value := range query[name] {
+				if w.redact[strings.ToLower(name)] {
+					value = redactedSummaryValue
+				}
+				params = append(params, name+"="+value)
+			}
+		}
+		summary += "?" + strings.Join(params, "&")
+	} else if u.RawQuery != "" {
+		summary += "?" + u.RawQuery
+	}
+	summary = maskMobileNumbers(summary)
+	if len(summary) > w.summaryMaxBytes {
+		summary = strings.ToValidUTF8(summary[:w.summaryMaxBytes], "")
+	}
+	return summary
+}
+
+// maskMobileNumbers replaces the mobile numbers of text, with or without the 91, +91 and 0
+// prefixes, by X
+func maskMobileNumbers(text string) string {
+	return summaryDigitsRegexp.ReplaceAllStringFunc(text, func(digits string) string {
+		number := normalizeMobileNumber(digits)
+		if len(number) != 10 || number[0] < '6' {
+			return digits
+		}
+		return strings.Repeat("X", utf8.RuneCountInString(digits))
+	})
+}
+
+// Run stores the queued samples every flushInterval, or as soon as a batch is full, and deletes
+// the expired samples every sweepInterval, until ctx is cancelled
+func (w *ErrorSampleWriter) Run(ctx context.Context) {
+	w.state.setRunning(true)
+	defer w.state.setRunning(false)
+
+	flush := time.NewTicker(w.flushInterval)
+	defer flush.Stop()
+	sweep := time.NewTicker(w.sweepInterval)
+	defer sweep.Stop()
+
+	w.sweep(ctx)
+	batch := make([]domain.ErrorSample, 0, w.batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sample := <-w.queue:
+			batch = append(batch, sample)
+			if len(batch) < w.batchSize {
+				continue
+			}
+		case <-flush.C:
+		case <-sweep.C:
+			w.sweep(ctx)
+			continue
+		}
+		batch = w.flush(ctx, batch)
+	}
+}
+
+// flush stores batch, dropping it when it cannot be stored, and returns it emptied
+func (w *ErrorSampleWriter) flush(ctx context.Context, batch []domain.ErrorSample) []domain.ErrorSample {
+	if len(batch) > 0 {
+		err := w.store.SaveErrorSamplesRepo(ctx, batch)
+		if err != nil {
+			ErrorSamplesDroppedTotal.WithLabelValues("store").Add(float64(len(batch)))
+			log.Warn(ctx, "Dropped %d error samples that could not be stored: %s", len(batch), err.Error())
+		} else {
+			ErrorSamplesWrittenTotal.Add(float64(len(batch)))
+		}
+		w.state.cycle(err)
+	}
+	w.state.setBacklog(int64(len(w.queue)))
+	return batch[:0]
+}
+
+// sweep deletes the samples older than retention
+func (w *ErrorSampleWriter) sweep(ctx context.Context) {
+	purged, err := w.store.PurgeErrorSamplesRepo(ctx, w.retention)
+	if err != nil {
+		log.Warn(ctx, "Failed to delete the expired error samples: %s", err.Error())
+		return
+	}
+	if purged > 0 {
+		log.Info(ctx, "Deleted %d error samples older than %s", purged, w.retention)
+	}
+}
+
+// WorkerStatus returns the state of the writer for the system status, the backlog being the
+// samples waiting in the queue
+func (w *ErrorSampleWriter) WorkerStatus() domain.WorkerStatus {
+	return w.state.status("error_samples", w.enabled)
+}
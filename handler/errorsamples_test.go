@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+	"MgApplication/api-server/middlewares"
+	"MgApplication/core/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeErrorSampleStore struct {
+	mu      sync.Mutex
+	saved   []domain.ErrorSample
+	failure error
+}
+
+func (s *fakeErrorSampleStore) SaveErrorSamplesRepo(ctx context.Context, samples []domain.ErrorSample) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failure != nil {
+		return s.failure
+	}
+	s.saved = append(s.saved, samples...)
+	return nil
+}
+
+func (s *fakeErrorSampleStore) PurgeErrorSamplesRepo(ctx context.Context, retention time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func (s *fakeErrorSampleStore) savedSamples() []domain.ErrorSample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]domain.ErrorSample(nil), s.saved...)
+}
+
+// newTestErrorSampleWriter creates an ErrorSampleWriter of rate queueing at most bufferSize samples
+func newTestErrorSampleWriter(rate float64, bufferSize int) (*ErrorSampleWriter, *fakeErrorSampleStore) {
+	c := config.NewConfig(viper.New())
+	c.Set("admin.errorsamples.enabled", true)
+	c.Set("admin.errorsamples.rate", rate)
+	c.Set("admin.errorsamples.buffersize", bufferSize)
+	c.Set("admin.errorsamples.batchsize", 2)
+	c.Set("admin.errorsamples.flushinterval", "10ms")
+	store := &fakeErrorSampleStore{}
+	w := newErrorSampleWriter(store, c)
+	w.sampler.rand = rand.New(rand.NewSource(42))
+	return w, store
+}
+
+func errorSampleContext(target string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, target, nil)
+	return ctx
+}
+
+func TestErrorSamplerRate(t *testing.T) {
+	tests := []struct {
+		rate     float64
+		min, max int
+	}{
+		{0, 0, 0},
+		{0.1, 900, 1100},
+		{0.5, 4700, 5300},
+		{1, 10000, 10000},
+	}
+	for _, tt := range tests {
+		sampler := &errorSampler{rate: tt.rate, rand: rand.New(rand.NewSource(42))}
+		kept := 0
+		for i := 0; i < 10000; i++ {
+			if sampler.keep(middlewares.ErrorSample{Status: http.StatusInternalServerError}) {
+				kept++
+			}
+		}
+		assert.GreaterOrEqual(t, kept, tt.min, "rate %v", tt.rate)
+		assert.LessOrEqual(t, kept, tt.max, "rate %v", tt.rate)
+	}
+
+	// panics are kept whatever the rate
+	sampler := &errorSampler{rate: 0, rand: rand.New(rand.NewSource(42))}
+	assert.True(t, sampler.keep(middlewares.ErrorSample{Status: http.StatusInternalServerError, Panicked: true}))
+}
+
+// A full queue drops the samples and counts them without blocking the request
+func TestErrorSampleWriterDropsWhenFull(t *testing.T) {
+	w, _ := newTestErrorSampleWriter(1, 2)
+	dropped := testutil.ToFloat64(ErrorSamplesDroppedTotal.WithLabelValues("full"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 5; i++ {
+			w.SampleError(errorSampleContext("/v1/sms-request"), middlewares.ErrorSample{Method: http.MethodPost, Route: "/v1/sms-request", Status: http.StatusBadGateway})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("SampleError blocked on a full queue")
+	}
+
+	assert.Len(t, w.queue, 2)
+	assert.Equal(t, float64(3), testutil.ToFloat64(ErrorSamplesDroppedTotal.WithLabelValues("full"))-dropped)
+}
+
+// Samples that cannot be stored are dropped and counted, the writer keeps going
+func TestErrorSampleWriterStoreFailure(t *testing.T) {
+	w, store := newTestErrorSampleWriter(1, 10)
+	store.failure = errors.New("too many connections")
+	dropped := testutil.ToFloat64(ErrorSamplesDroppedTotal.WithLabelValues("store"))
+
+	batch := w.flush(context.Background(), []domain.ErrorSample{{ErrorID: "1"}, {ErrorID: "2"}})
+	assert.Empty(t, batch)
+	assert.Equal(t, float64(2), testutil.ToFloat64(ErrorSamplesDroppedTotal.WithLabelValues("store"))-dropped)
+	assert.Equal(t, "too many connections", w.WorkerStatus().LastError)
+
+	store.failure = nil
+	w.flush(context.Background(), []domain.ErrorSample{{ErrorID: "3"}})
+	assert.Len(t, store.savedSamples(), 1)
+	assert.Empty(t, w.WorkerStatus().LastError)
+}
+
+func TestErrorSampleRequestSummaryMasksMobileNumbers(t *testing.T) {
+	w, _ := newTestErrorSampleWriter(1, 10)
+	targets := []string{
+		"/v1/sms-request?to=9000000001",
+		"/v1/sms-request?to=9000000001,8000000002",
+		"/v1/sms-request?to=9000000001%2C8000000002",
+		"/v1/sms-request?to=%2B919000000001",
+		"/v1/sms-request?to=+919000000001",
+		"/v1/sms-request?to=919000000001&to=09000000001",
+		"/v1/otp/9000000001/verify",
+		"/v1/sms-request?mobile_numbers=9000000001&template_id=1007344609998507114",
+		"/v1/sms-request?to=9000000001;7000000003",
+		"/v1/sms-request?%zz=9000000001",
+	}
+	for _, target := range targets {
+		summary := w.requestSummary(http.MethodPost, errorSampleContext(target).Request.URL)
+		for _, mobile := range []string{"9000000001", "8000000002", "7000000003"} {
+			assert.NotContains(t, summary, mobile, target)
+		}
+		assert.NotContains(t, summary, "000000", target)
+	}
+
+	summary := w.requestSummary(http.MethodPost, errorSampleContext("/v1/sms-request?mobile_numbers=9000000001&template_id=1007344609998507114&to=9000000001").Request.URL)
+	assert.Equal(t, "POST /v1/sms-request?mobile_numbers=[REDACTED]&template_id=1007344609998507114&to=XXXXXXXXXX", summary)
+
+	w.summaryMaxBytes = 20
+	summary = w.requestSummary(http.MethodPost, errorSampleContext("/v1/sms-request?to=9000000001").Request.URL)
+	assert.Equal(t, "POST /v1/sms-request", summary)
+}
+
+// A panic recovered by Recover reaches the writer with its stack and route and is stored
+func TestErrorSampleMiddlewareStoresPanics(t *testing.T) {
+	w, store := newTestErrorSampleWriter(0, 10)
+	middlewares.SetErrorSampleSink(w)
+	t.Cleanup(func() { middlewares.SetErrorSampleSink(nil) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.ErrorSampleMiddleware(), middlewares.Recover(config.NewConfig(viper.New())))
+	r.GET("/v1/otp/:mobile", func(c *gin.Context) { panic("nil template") })
+	r.GET("/v1/fail", func(c *gin.Context) { c.Status(http.StatusBadGateway) })
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/otp/9000000001", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	// a 5xx without a panic is not kept at rate 0
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/fail", nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+	require.Eventually(t, func() bool { return len(store.savedSamples()) == 1 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+
+	sample := store.savedSamples()[0]
+	assert.NotEmpty(t, sample.ErrorID)
+	assert.Equal(t, "/v1/otp/:mobile", sample.Route)
+	assert.Equal(t, http.StatusInternalServerError, sample.Status)
+	assert.Equal(t, "req-1", sample.CorrelationID)
+	assert.Equal(t, "GET /v1/otp/XXXXXXXXXX", sample.RequestSummary)
+	assert.True(t, sample.Panicked)
+	require.NotNil(t, sample.Stack)
+	assert.Contains(t, *sample.Stack, "TestErrorSampleMiddlewareStoresPanics")
+	assert.False(t, sample.CreatedAt.IsZero())
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	config "MgApplication/api-config"
+	log "MgApplication/api-log"
+	validation "MgApplication/api-validation"
+	"MgApplication/core/domain"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Outcomes of a simulated send, the last three characters of a reserved mobile number
+const (
+	simulatedSuccess         = "000" // accepted by the gateway
+	simulatedAuthError       = "401" // credentials refused by the gateway
+	simulatedCreditExhausted = "418" // gateway account out of credits
+	simulatedTimeout         = "TMO" // no answer, the send times out after the delay of the number
+)
+
+// simulatedOutcomes are the outcome label of SimulatedSendsTotal of each outcome code
+var simulatedOutcomes = map[string]string{
+	simulatedSuccess:         "success",
+	simulatedAuthError:       "auth_error",
+	simulatedCreditExhausted: "credit_exhausted",
+	simulatedTimeout:         "timeout",
+}
+
+// Reserved mobile numbers of the simulation
+const (
+	defaultSimulationPrefix     = "5000"
+	defaultSimulationMaxTimeout = 30 * time.Second // the timeout of the gateway clients
+	simulatedNumberLength       = 10
+	simulatedCodeLength         = 3
+)
+
+var SimulatedSendsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "sms_simulated_sends_total",
+		Help: "Total number of sends to reserved mobile numbers answered by the gateway error simulation, by gateway and outcome",
+	},
+	[]string{"gateway", "outcome"},
+)
+
+var errSimulationInProd = errors.New("sms.simulation.enabled is refused in production")
+
+// simulatedTimeoutError is the error of an HTTP client giving up on a gateway
+type simulatedTimeoutError struct{}
+
+func (simulatedTimeoutError) Error() string {
+	return "context deadline exceeded (Client.Timeout exceeded while awaiting headers)"
+}
+func (simulatedTimeoutError) Timeout() bool   { return true }
+func (simulatedTimeoutError) Temporary() bool { return true }
+
+// GatewayErrorSimulation answers sends to reserved mobile numbers with the outcome encoded in
+// the number, so that QA can exercise the handling of gateway errors on demand. It is enabled
+// with sms.simulation.enabled and refused in production. A reserved number is the prefix
+// sms.simulation.prefix (5000 by default), digits and an outcome code, ten characters in all:
+//
+//	5000000000  success
+//	5000000401  CDAC authentication error
+//	5000000418  credit exhausted
+//	5000005TMO  timeout after 5 seconds, the digits before TMO, at most sms.simulation.maxtimeout
+//
+// A request with a reserved number among its numbers is answered with the outcome of the first
+// one, in the format of its gateway, and stored as a real send; none of its numbers are sent.
+type GatewayErrorSimulation struct {
+	prefix     string
+	maxTimeout time.Duration
+	urls       map[domain.GatewayID]string
+
+	sleep func(time.Duration)
+	seq   atomic.Uint64
+}
+
+// NewGatewayErrorSimulation creates a new GatewayErrorSimulation instance using the
+// sms.simulation configuration, nil unless sms.simulation.enabled is set outside production.
+// The reserved numbers pass the mobile number validation while it is enabled.
+func NewGatewayErrorSimulation(c *config.Config) *GatewayErrorSimulation {
+	if !c.GetBool("sms.simulation.enabled") {
+		return nil
+	}
+	if isProduction(c) {
+		log.Error(nil, "%s, messages to reserved numbers are sent through the gateways", errSimulationInProd.Error())
+		return nil
+	}
+
+	prefix := c.GetString("sms.simulation.prefix")
+	if !validSimulationPrefix(prefix) {
+		if prefix != "" {
+			log.Warn(nil, "sms.simulation.prefix %q is not 1 to 7 digits starting with 0 to 5, using %s", prefix, defaultSimulationPrefix)
+		}
+		prefix = defaultSimulationPrefix
+	}
+	s := &GatewayErrorSimulation{
+		prefix:     prefix,
+		maxTimeout: c.GetDuration("sms.simulation.maxtimeout"),
+		urls: map[domain.GatewayID]string{
+			domain.GatewayCDAC: c.GetString("sms.cdac.url"),
+			domain.GatewayNIC:  c.GetString("sms.nic.url"),
+		},
+		sleep: time.Sleep,
+	}
+	if s.maxTimeout <= 0 {
+		s.maxTimeout = defaultSimulationMaxTimeout
+	}
+	validation.ReserveMobileNumbers(s.Reserved)
+	log.Warn(nil, "Gateway error simulation enabled, messages to numbers starting with %s are not sent", prefix)
+	return s
+}
+
+// CheckGatewayErrorSimulation fails startup when the gateway error simulation is enabled in
+// production
+func CheckGatewayErrorSimulation(c *config.Config) error {
+	if c.GetBool("sms.simulation.enabled") && isProduction(c) {
+		return errSimulationInProd
+	}
+	return nil
+}
+
+// isProduction reports whether the service runs in production, by its configured environment
+// or APP_ENV
+func isProduction(c *config.Config) bool {
+	switch strings.ToLower(os.Getenv("APP_ENV")) {
+	case "prod", "production":
+		return true
+	}
+	return c.IsProdEnv()
+}
+
+// validSimulationPrefix reports whether prefix leaves room for an outcome code and can never
+// start a real mobile number, which starts with 6 to 9
+func validSimulationPrefix(prefix string) bool {
+	if prefix == "" || len(prefix) > simulatedNumberLength-simulatedCodeLength || prefix[0] > '5' {
+		return false
+	}
+	_, err := strconv.ParseUint(prefix, 10, 64)
+	return err == nil
+}
+
+// Reserved reports whether number is a reserved number with a known outcome
+func (s *GatewayErrorSimulation) Reserved(number string) bool {
+	_, _, ok := s.parse(number)
+	return ok
+}
+
+// parse returns the outcome code and the timeout delay of a reserved number
+func (s *GatewayErrorSimulation) parse(number string) (string, time.Duration, bool) {
+	if len(number) != simulatedNumberLength || !strings.HasPrefix(number, s.prefix) {
+		return "", 0, false
+	}
+	digits, code := number[len(s.prefix):simulatedNumberLength-simulatedCodeLength], number[simulatedNumberLength-simulatedCodeLength:]
+	seconds := uint64(0)
+	if digits != "" {
+		var err error
+		if seconds, err = strconv.ParseUint(digits, 10, 64); err != nil {
+			return "", 0, false
+		}
+	}
+	if _, ok := simulatedOutcomes[code]; !ok {
+		return "", 0, false
+	}
+	var delay time.Duration
+	if code == simulatedTimeout {
+		delay = min(time.Duration(seconds)*time.Second, s.maxTimeout)
+	}
+	return code, delay, true
+}
+
+// Outcome returns the outcome code and timeout delay of the first reserved number of the
+// comma-separated mobileNumbers, false when none is reserved or the simulation is disabled
+func (s *GatewayErrorSimulation) Outcome(mobileNumbers string) (string, time.Duration, bool) {
+	if s == nil {
+		return "", 0, false
+	}
+	for _, number := range strings.Split(mobileNumbers, ",") {
+		if code, delay, ok := s.parse(strings.TrimSpace(number)); ok {
+			return code, delay, true
+		}
+	}
+	return "", 0, false
+}
+
+// Send answers a send through gateway with the outcome code, returning the response and error
+// the gateway client would
+func (s *GatewayErrorSimulation) Send(gateway domain.GatewayID, code string, delay time.Duration) (string, error) {
+	SimulatedSendsTotal.WithLabelValues(string(gateway), simulatedOutcomes[code]).Inc()
+	log.Debug(nil, "Simulated %s outcome %s", gateway, simulatedOutcomes[code])
+	id := fmt.Sprintf("%d%09d", time.Now().Unix(), s.seq.Add(1)%1e9)
+
+	if code == simulatedTimeout {
+		s.sleep(delay)
+		op := "Post"
+		if gateway == domain.GatewayNIC {
+			op = "Get"
+		}
+		return "", &url.Error{Op: op, URL: s.urls[gateway], Err: simulatedTimeoutError{}}
+	}
+
+	switch gateway {
+	case domain.GatewayCDAC:
+		switch code {
+		case simulatedAuthError:
+			return "Error 401 : Authentication Failed", nil
+		case simulatedCreditExhausted:
+			return "Error 418 : Insufficient Credits", nil
+		}
+		return "402,MsgID = " + id + "simulation", nil
+	case domain.GatewayNIC:
+		switch code {
+		case simulatedAuthError:
+			return "", fmt.Errorf("unexpected response from sms gateway: Request ID=%s~code=API401~Authentication Failed", id)
+		case simulatedCreditExhausted:
+			return "", fmt.Errorf("unexpected response from sms gateway: Request ID=%s~code=API418~Insufficient Credits", id)
+		}
+		return "Message Accepted for Request ID=" + id + "~code=API000", nil
+	}
+	return "", fmt.Errorf("invalid gateway %s", gateway)
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+	validation "MgApplication/api-validation"
+	"MgApplication/core/domain"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSimulationHandler creates an MgApplicationHandler with the gateway error simulation enabled
+// and gateways failing the test when called, the delays of simulated timeouts being recorded
+func newSimulationHandler(t *testing.T) (*MgApplicationHandler, *fakeMsgStore, *[]time.Duration) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("gateway called for a reserved number: %s", r.URL)
+	}))
+	t.Cleanup(gateway.Close)
+	t.Cleanup(func() { validation.ReserveMobileNumbers(nil) })
+
+	c := config.NewConfig(viper.New())
+	c.Set("sms.cdac.url", gateway.URL)
+	c.Set("sms.nic.url", gateway.URL)
+	c.Set("sms.msgstorerequest", 1)
+	c.Set("sms.simulation.enabled", true)
+	c.Set("sms.simulation.maxtimeout", "10s")
+
+	ch, store := newTestSMSHandler(c)
+	ch.errorSimulation = NewGatewayErrorSimulation(c)
+	require.NotNil(t, ch.errorSimulation)
+	var delays []time.Duration
+	ch.errorSimulation.sleep = func(d time.Duration) { delays = append(delays, d) }
+	return ch, store, &delays
+}
+
+func TestGatewayErrorSimulationOutcomes(t *testing.T) {
+	tests := []struct {
+		name        string
+		gateway     domain.GatewayID
+		number      string
+		wantCode    string
+		wantErr     bool
+		wantTimeout bool
+		wantDelay   time.Duration
+		wantOutcome string
+	}{
+		{"cdac success", domain.GatewayCDAC, "5000000000", "402", false, false, 0, "success"},
+		{"cdac auth error", domain.GatewayCDAC, "5000000401", "401", true, false, 0, "auth_error"},
+		{"cdac credit exhausted", domain.GatewayCDAC, "5000000418", "418", true, false, 0, "credit_exhausted"},
+		{"cdac timeout", domain.GatewayCDAC, "5000005TMO", "504", true, true, 5 * time.Second, "timeout"},
+		{"nic success", domain.GatewayNIC, "5000000000", "API000", false, false, 0, "success"},
+		{"nic auth error", domain.GatewayNIC, "5000000401", "API401", true, false, 0, "auth_error"},
+		{"nic credit exhausted", domain.GatewayNIC, "5000000418", "API418", true, false, 0, "credit_exhausted"},
+		{"nic timeout capped", domain.GatewayNIC, "5000120TMO", "504", true, true, 10 * time.Second, "timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch, _, delays := newSimulationHandler(t)
+			msgreq := domain.MsgRequest{SenderID: "INPOST", MobileNumbers: "9000000001," + tt.number, Priority: 1}
+
+			sends := testutil.ToFloat64(SimulatedSendsTotal.WithLabelValues(string(tt.gateway), tt.wantOutcome))
+			rsp, err := ch.sendSMS(string(tt.gateway), msgreq)
+			assert.Equal(t, sends+1, testutil.ToFloat64(SimulatedSendsTotal.WithLabelValues(string(tt.gateway), tt.wantOutcome)))
+			msgresponse, err := parseGatewayResponse(tt.gateway, rsp, err)
+			assert.Equal(t, tt.wantCode, msgresponse.ResponseCode)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			if tt.wantTimeout {
+				var urlErr *url.Error
+				require.ErrorAs(t, err, &urlErr)
+				assert.True(t, urlErr.Timeout())
+				assert.Equal(t, []time.Duration{tt.wantDelay}, *delays)
+			} else {
+				assert.Empty(t, *delays)
+			}
+		})
+	}
+}
+
+// A simulated outcome is stored and answered as the outcome of a real send
+func TestCreateSMSRequestSimulatedOutcomeStored(t *testing.T) {
+	ch, store, _ := newSimulationHandler(t)
+
+	rec := postSMSRequest(ch, otpRequestBody("5000000418"))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code, rec.Body.String())
+	require.Len(t, store.savedResponses, 1)
+	assert.Equal(t, "418", store.savedResponses[0].ResponseCode)
+	assert.Equal(t, "Insufficient Credits", store.savedResponses[0].ResponseText)
+
+	rec = postSMSRequest(ch, otpRequestBody("5000000000"))
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.Len(t, store.savedResponses, 2)
+	assert.Equal(t, "402", store.savedResponses[1].ResponseCode)
+}
+
+func TestGatewayErrorSimulationReservedNumbers(t *testing.T) {
+	s := &GatewayErrorSimulation{prefix: defaultSimulationPrefix, maxTimeout: defaultSimulationMaxTimeout}
+
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{"5000000000", true},
+		{"5000999401", true},
+		{"5000030TMO", true},
+		{"5000000404", false},
+		{"5001000401", false},
+		{"50000000401", false},
+		{"5000x00401", false},
+		{"9000000401", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, s.Reserved(tt.number), tt.number)
+	}
+
+	assert.True(t, validSimulationPrefix("0"))
+	assert.True(t, validSimulationPrefix("5000000"))
+	assert.False(t, validSimulationPrefix("50000000"))
+	assert.False(t, validSimulationPrefix("6000"))
+	assert.False(t, validSimulationPrefix("50a0"))
+}
+
+// Reserved numbers are invalid mobile numbers while the simulation is disabled
+func TestGatewayErrorSimulationDisabled(t *testing.T) {
+	c := config.NewConfig(viper.New())
+	assert.Nil(t, NewGatewayErrorSimulation(c))
+	assert.NoError(t, CheckGatewayErrorSimulation(c))
+
+	ch, store := newTestSMSHandler(c)
+	rec := postSMSRequest(ch, otpRequestBody("5000000401"))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	assert.Empty(t, store.savedResponses)
+}
+
+func TestGatewayErrorSimulationRefusedInProduction(t *testing.T) {
+	t.Cleanup(func() { validation.ReserveMobileNumbers(nil) })
+
+	prod := config.NewConfig(viper.New())
+	prod.Set("info.env", "prod")
+	prod.Set("sms.simulation.enabled", true)
+	assert.Nil(t, NewGatewayErrorSimulation(prod))
+	assert.ErrorIs(t, CheckGatewayErrorSimulation(prod), errSimulationInProd)
+
+	t.Setenv("APP_ENV", "production")
+	c := config.NewConfig(viper.New())
+	c.Set("sms.simulation.enabled", true)
+	assert.Nil(t, NewGatewayErrorSimulation(c))
+	assert.ErrorIs(t, CheckGatewayErrorSimulation(c), errSimulationInProd)
+	assert.Error(t, validation.ValidateStruct(struct {
+		MobileNumbers string `validate:"mobile_number_list"`
+	}{"5000000401"}))
+}
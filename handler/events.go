@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	log "MgApplication/api-log"
+	serverHandler "MgApplication/api-server/handler"
+	serverRoute "MgApplication/api-server/route"
+	"MgApplication/api-server/sse"
+	"MgApplication/core/domain"
+	repo "MgApplication/repo/postgres"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Names of the events sent on progress streams. A stream starts with a snapshot of the counters
+// followed by one progress event per change, carrying the increments.
+const (
+	progressSnapshotEvent = "snapshot"
+	progressDeltaEvent    = "progress"
+)
+
+func applicationTopic(applicationID string) string {
+	return "application:" + applicationID
+}
+
+func campaignTopic(campaignID string) string {
+	return "campaign:" + campaignID
+}
+
+// ProgressHub publishes delivery progress to the event stream subscribers. A nil ProgressHub,
+// returned when events.sse.enabled is not set, discards everything published to it.
+type ProgressHub struct {
+	hub *sse.Hub
+}
+
+// NewProgressHub creates a new ProgressHub buffering events.sse.buffersize events per subscriber
+func NewProgressHub(c *config.Config) *ProgressHub {
+	if !c.GetBool("events.sse.enabled") {
+		return nil
+	}
+	return &ProgressHub{hub: sse.NewHub(c.GetInt("events.sse.buffersize"))}
+}
+
+// PublishApplicationProgress publishes the increments of the application counters
+func (ph *ProgressHub) PublishApplicationProgress(applicationID string, delta domain.DeliveryProgress) {
+	ph.publish(applicationTopic(applicationID), delta)
+}
+
+// PublishCampaignProgress publishes the increments of the counters of the bulk upload campaignID
+func (ph *ProgressHub) PublishCampaignProgress(campaignID string, delta domain.DeliveryProgress) {
+	ph.publish(campaignTopic(campaignID), delta)
+}
+
+func (ph *ProgressHub) publish(topic string, delta domain.DeliveryProgress) {
+	if ph == nil || ph.hub.Subscribers(topic) == 0 {
+		return
+	}
+	data, err := json.Marshal(delta)
+	if err != nil {
+		return
+	}
+	ph.hub.Publish(topic, progressDeltaEvent, data)
+}
+
+// EventsHandler streams the delivery progress of applications and campaigns (bulk uploads) as
+// server-sent events. The routes are only registered when events.sse.enabled is set.
+type EventsHandler struct {
+	*serverHandler.Base
+	svc *repo.MgApplicationRepository
+	hub *ProgressHub
+	c   *config.Config
+}
+
+// NewEventsHandler creates a new EventsHandler instance
+func NewEventsHandler(svc *repo.MgApplicationRepository, hub *ProgressHub, c *config.Config) *EventsHandler {
+	base := serverHandler.New("Events").SetDescription("Server-sent events of the delivery progress of messages").SetOrder(5).SetPrefix("/v1")
+	return &EventsHandler{
+		base,
+		svc,
+		hub,
+		c,
+	}
+}
+
+func (eh *EventsHandler) Routes() []serverRoute.Route {
+	if eh.hub == nil {
+		return nil
+	}
+	return []serverRoute.Route{
+		serverRoute.Raw(http.MethodGet, "/campaigns/:campaign-id/events", eh.StreamCampaignProgressHandler).Name("Stream campaign progress"),
+		serverRoute.Raw(http.MethodGet, "/applications/:application-id/events", eh.StreamApplicationProgressHandler).Name("Stream application progress"),
+	}
+}
+
+// StreamCampaignProgressHandler streams the sent, delivered and failed counters of a bulk upload,
+// identified by the reference id returned when it was initiated
+func (eh *EventsHandler) StreamCampaignProgressHandler(gctx *gin.Context) {
+	campaignID := gctx.Param("campaign-id")
+	eh.stream(gctx, campaignTopic(campaignID), func() (domain.DeliveryProgress, error) {
+		return eh.svc.FetchCampaignProgressRepo(gctx.Request.Context(), campaignID)
+	})
+}
+
+// StreamApplicationProgressHandler streams the sent, delivered and failed counters of the
+// messages an application sent in the current business day (IST)
+func (eh *EventsHandler) StreamApplicationProgressHandler(gctx *gin.Context) {
+	applicationID := gctx.Param("application-id")
+	eh.stream(gctx, applicationTopic(applicationID), func() (domain.DeliveryProgress, error) {
+		return eh.svc.FetchApplicationProgressRepo(gctx.Request.Context(), applicationID)
+	})
+}
+
+// stream subscribes to topic before taking the snapshot, so no change is lost between the two
+func (eh *EventsHandler) stream(gctx *gin.Context, topic string, snapshot func() (domain.DeliveryProgress, error)) {
+	sub := eh.hub.hub.Subscribe(topic)
+	defer eh.hub.hub.Unsubscribe(sub)
+
+	progress, err := snapshot()
+	if err != nil {
+		apierrors.HandleDBError(gctx, err)
+		return
+	}
+	data, err := json.Marshal(progress)
+	if err != nil {
+		apierrors.HandleMarshalError(gctx, err)
+		return
+	}
+
+	heartbeat := eh.c.GetDuration("events.sse.heartbeat")
+	if heartbeat <= 0 {
+		heartbeat = sse.DefaultHeartbeat
+	}
+
+	err = sse.Stream(gctx.Writer, gctx.Request, sub, sse.Event{Name: progressSnapshotEvent, Data: data}, heartbeat)
+	if err != nil {
+		log.Warn(gctx, "Progress stream %s closed: %s", topic, err.Error())
+	}
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"strings"
+
+	config "MgApplication/api-config"
+	log "MgApplication/api-log"
+	serverHandler "MgApplication/api-server/handler"
+	serverRoute "MgApplication/api-server/route"
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+	"MgApplication/handler/response"
+	repo "MgApplication/repo/postgres"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// gatewayCodeUndocumented is the description of response codes missing from msg_gateway_code
+const gatewayCodeUndocumented = "undocumented"
+
+var GatewayCodeUndocumentedTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "sms_gateway_code_undocumented_total",
+		Help: "Total number of responses carrying a gateway response code missing from the code dictionary, by gateway and code",
+	},
+	[]string{"gateway", "code"},
+)
+
+// describeGatewayCodes marks the response codes of reports without a dictionary entry as
+// undocumented and counts each such code once per call
+func describeGatewayCodes(reports []domain.SMSReport) {
+	undocumented := undocumentedGatewayCodes{}
+	for i := range reports {
+		undocumented.describe(&reports[i])
+	}
+	undocumented.count()
+}
+
+// undocumentedGatewayCodes are the gateway and code pairs without description met in a report,
+// counted once per report by GatewayCodeUndocumentedTotal
+type undocumentedGatewayCodes map[[2]string]bool
+
+// describe sets the description of the undocumented response code of report
+func (undocumented undocumentedGatewayCodes) describe(report *domain.SMSReport) {
+	if report.ResponseCode == nil || strings.TrimSpace(*report.ResponseCode) == "" || report.ResponseDescription != nil {
+		return
+	}
+	description := gatewayCodeUndocumented
+	report.ResponseDescription = &description
+
+	var gateway string
+	if report.GatewayID != nil {
+		gateway = *report.GatewayID
+	}
+	undocumented[[2]string{gateway, *report.ResponseCode}] = true
+}
+
+func (undocumented undocumentedGatewayCodes) count() {
+	for code := range undocumented {
+		GatewayCodeUndocumentedTotal.WithLabelValues(code[0], code[1]).Inc()
+	}
+}
+
+// MetaHandler serves reference data for client SDKs
+type MetaHandler struct {
+	*serverHandler.Base
+	codesvc *repo.GatewayCodeRepository
+	c       *config.Config
+}
+
+// NewMetaHandler creates a new MetaHandler instance
+func NewMetaHandler(codesvc *repo.GatewayCodeRepository, c *config.Config) *MetaHandler {
+	base := serverHandler.New("Meta").SetDescription("Reference data of the gateways, such as their response codes").SetOrder(6).SetPrefix("/v1").AddPrefix("/meta")
+	return &MetaHandler{
+		base,
+		codesvc,
+		c,
+	}
+}
+
+func (mh *MetaHandler) Routes() []serverRoute.Route {
+	return []serverRoute.Route{
+		serverRoute.GET("/gateway-codes", mh.ListGatewayCodesHandler).Name("List gateway response codes"),
+	}
+}
+
+type listGatewayCodesRequest struct {
+	Gateway string `form:"gateway" validate:"omitempty,gateway_id" enum:"1,2" example:"1"`
+}
+
+// ListGatewayCodesHandler godoc
+//
+//	@Summary		Lists the gateway response codes
+//	@Description	Lists the response code dictionary of the SMS gateways (1 - CDAC, 2 - NIC) with the description, severity and recommended action of every code. Codes missing from the dictionary are reported as undocumented
+//	@Tags			Meta
+//	@ID				ListGatewayCodesHandler
+//	@Produce		json
+//	@Param			listGatewayCodesRequest	query		listGatewayCodesRequest			false	"Gateway filter"
+//	@Success		200						{object}	response.GatewayCodesAPIResponse	"Gateway response codes are retrieved"
+//	@Failure		422						{object}	apierrors.APIErrorResponse		"Binding or Validation error"
+//	@Failure		500						{object}	apierrors.APIErrorResponse		"Internal server error"
+//	@Router			/meta/gateway-codes [get]
+func (mh *MetaHandler) ListGatewayCodesHandler(sctx *serverRoute.Context, req listGatewayCodesRequest) (*response.GatewayCodesAPIResponse, error) {
+
+	codes, err := mh.codesvc.ListGatewayCodesRepo(sctx.Ctx, req.Gateway)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in ListGatewayCodesRepo function: %s", err.Error())
+		return nil, err
+	}
+
+	return &response.GatewayCodesAPIResponse{
+		StatusCodeAndMessage: port.ListSuccess,
+		Data:                 response.NewGatewayCodesResponse(codes),
+	}, nil
+}
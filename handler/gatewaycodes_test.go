@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"testing"
+
+	validation "MgApplication/api-validation"
+	"MgApplication/core/domain"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribeGatewayCodes(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+	reports := []domain.SMSReport{
+		{GatewayID: strPtr("1"), ResponseCode: strPtr("406"), ResponseDescription: strPtr("Invalid or duplicate mobile numbers")},
+		{GatewayID: strPtr("2"), ResponseCode: strPtr("ES-UNIT-CODE")},
+		{GatewayID: strPtr("2"), ResponseCode: strPtr("ES-UNIT-CODE")},
+		{GatewayID: strPtr("1"), ResponseCode: strPtr(" ")},
+		{GatewayID: strPtr("1")},
+	}
+	before := testutil.ToFloat64(GatewayCodeUndocumentedTotal.WithLabelValues("2", "ES-UNIT-CODE"))
+
+	describeGatewayCodes(reports)
+
+	assert.Equal(t, "Invalid or duplicate mobile numbers", *reports[0].ResponseDescription)
+	assert.Equal(t, gatewayCodeUndocumented, *reports[1].ResponseDescription)
+	assert.Equal(t, gatewayCodeUndocumented, *reports[2].ResponseDescription)
+	assert.Nil(t, reports[3].ResponseDescription)
+	assert.Nil(t, reports[4].ResponseDescription)
+	// Counted once per report rather than once per row
+	assert.Equal(t, before+1, testutil.ToFloat64(GatewayCodeUndocumentedTotal.WithLabelValues("2", "ES-UNIT-CODE")))
+}
+
+func TestSaveGatewayCodeRequestValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   saveGatewayCodeRequest
+		valid bool
+	}{
+		{"valid", saveGatewayCodeRequest{Gateway: "1", Code: "418", Description: "x", Severity: "error"}, true},
+		{"unknown severity", saveGatewayCodeRequest{Gateway: "1", Code: "418", Description: "x", Severity: "fatal"}, false},
+		{"unknown gateway", saveGatewayCodeRequest{Gateway: "3", Code: "418", Description: "x", Severity: "error"}, false},
+		{"no description", saveGatewayCodeRequest{Gateway: "2", Code: "418", Severity: "info"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.valid, validation.ValidateStruct(tt.req) == nil)
+		})
+	}
+	assert.Error(t, validation.ValidateStruct(listGatewayCodesRequest{Gateway: "3"}))
+	assert.NoError(t, validation.ValidateStruct(listGatewayCodesRequest{}))
+}
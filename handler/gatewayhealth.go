@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var GatewayLastSuccessSeconds = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "sms_gateway_last_success_timestamp_seconds",
+		Help: "Unix time of the last send accepted by the gateway",
+	},
+	[]string{"gateway"},
+)
+
+var GatewayFailedSendsSinceSuccess = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "sms_gateway_failed_sends_since_success",
+		Help: "Number of sends through the gateway that failed since the last send it accepted",
+	},
+	[]string{"gateway"},
+)
+
+// gatewaySends are the outcomes of the sends through a gateway
+type gatewaySends struct {
+	lastAttempt  time.Time
+	lastSuccess  time.Time
+	failingSince time.Time // first failed send since the last success, zero after a success
+	failures     int64     // failed sends since the last success
+}
+
+// GatewayHealth tracks the last send accepted by each gateway, to catch the gateways that are
+// reachable but fail every send. A gateway whose sends have all failed for longer than
+// sms.gatewayhealth.window (default 15 minutes) is reported degraded in the system status until
+// it accepts a send again.
+type GatewayHealth struct {
+	window time.Duration
+
+	mu       sync.Mutex
+	gateways map[domain.GatewayID]*gatewaySends
+	now      func() time.Time
+}
+
+// NewGatewayHealth creates a new GatewayHealth instance using the sms.gatewayhealth configuration
+func NewGatewayHealth(c *config.Config) *GatewayHealth {
+	window := c.GetDuration("sms.gatewayhealth.window")
+	if window <= 0 {
+		window = 15 * time.Minute
+	}
+	return &GatewayHealth{
+		window:   window,
+		gateways: make(map[domain.GatewayID]*gatewaySends),
+		now:      time.Now,
+	}
+}
+
+// Record records the outcome of a send through gateway
+func (gh *GatewayHealth) Record(gateway domain.GatewayID, success bool) {
+	if gh == nil {
+		return
+	}
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	sends, ok := gh.gateways[gateway]
+	if !ok {
+		sends = &gatewaySends{}
+		gh.gateways[gateway] = sends
+	}
+	now := gh.now()
+	sends.lastAttempt = now
+	if success {
+		sends.lastSuccess = now
+		sends.failingSince = time.Time{}
+		sends.failures = 0
+		GatewayLastSuccessSeconds.WithLabelValues(gateway.String()).Set(float64(now.Unix()))
+		GatewayFailedSendsSinceSuccess.WithLabelValues(gateway.String()).Set(0)
+		return
+	}
+	if sends.failures == 0 {
+		sends.failingSince = now
+	}
+	sends.failures++
+	GatewayFailedSendsSinceSuccess.WithLabelValues(gateway.String()).Set(float64(sends.failures))
+}
+
+// DependencyStatuses returns the state of the sends of every gateway used since the start, named
+// <gateway>_sends
+func (gh *GatewayHealth) DependencyStatuses() []domain.DependencyStatus {
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	now := gh.now()
+	statuses := make([]domain.DependencyStatus, 0, len(gh.gateways))
+	for gateway, sends := range gh.gateways {
+		lastAttempt := sends.lastAttempt
+		status := domain.DependencyStatus{
+			Name:        strings.ToLower(gateway.String()) + "_sends",
+			Status:      domain.ComponentUp,
+			LastChecked: &lastAttempt,
+		}
+		if !sends.lastSuccess.IsZero() {
+			lastSuccess := sends.lastSuccess
+			status.LastSuccess = &lastSuccess
+		}
+
+		switch {
+		case sends.failures > 0 && now.Sub(sends.failingSince) > gh.window:
+			status.Status = domain.ComponentDegraded
+			status.Message = fmt.Sprintf("%d sends failed since %s, none accepted within %s", sends.failures, sends.failingSince.Format(time.RFC3339), gh.window)
+		case sends.failures > 0:
+			status.Message = fmt.Sprintf("%d sends failed since %s", sends.failures, sends.failingSince.Format(time.RFC3339))
+		default:
+			status.Message = "last send accepted"
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGatewayHealth(t *testing.T) {
+	c := config.NewConfig(viper.New())
+	c.Set("sms.gatewayhealth.window", "10m")
+	health := NewGatewayHealth(c)
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	health.now = func() time.Time { return now }
+
+	assert.Empty(t, health.DependencyStatuses())
+
+	health.Record(domain.GatewayCDAC, true)
+	accepted := now
+	assert.Equal(t, float64(accepted.Unix()), testutil.ToFloat64(GatewayLastSuccessSeconds.WithLabelValues("CDAC")))
+
+	// failures within the window leave the gateway up
+	now = now.Add(time.Minute)
+	health.Record(domain.GatewayCDAC, false)
+	now = now.Add(5 * time.Minute)
+	health.Record(domain.GatewayCDAC, false)
+	health.Record(domain.GatewayNIC, true)
+	statuses := health.DependencyStatuses()
+	if assert.Len(t, statuses, 2) {
+		assert.Equal(t, "cdac_sends", statuses[0].Name)
+		assert.Equal(t, domain.ComponentUp, statuses[0].Status)
+		assert.Equal(t, accepted, *statuses[0].LastSuccess)
+		assert.Equal(t, now, *statuses[0].LastChecked)
+		assert.Equal(t, "nic_sends", statuses[1].Name)
+		assert.Equal(t, domain.ComponentUp, statuses[1].Status)
+	}
+	assert.Equal(t, 2.0, testutil.ToFloat64(GatewayFailedSendsSinceSuccess.WithLabelValues("CDAC")))
+
+	// failing for longer than the window, even without new attempts, degrades the gateway
+	now = accepted.Add(12 * time.Minute)
+	statuses = health.DependencyStatuses()
+	assert.Equal(t, domain.ComponentDegraded, statuses[0].Status)
+	assert.Contains(t, statuses[0].Message, "2 sends failed")
+	assert.Equal(t, accepted, *statuses[0].LastSuccess)
+
+	// the system status reports the degraded gateway
+	monitor := newSystemStatusMonitor(nil, nil, nil, systemStatusConfig())
+	monitor.tracked = []dependencyStatusSource{health}
+	status := monitor.Status()
+	assert.Contains(t, status.Dependencies, statuses[0])
+	assert.Equal(t, domain.SystemStatusGreen, status.Status)
+	assert.Equal(t, 0.5, status.Score)
+
+	// an accepted send restores the gateway
+	health.Record(domain.GatewayCDAC, true)
+	statuses = health.DependencyStatuses()
+	assert.Equal(t, domain.ComponentUp, statuses[0].Status)
+	assert.Equal(t, now, *statuses[0].LastSuccess)
+	assert.Equal(t, 0.0, testutil.ToFloat64(GatewayFailedSendsSinceSuccess.WithLabelValues("CDAC")))
+}
+
+func TestGatewayHealthNeverAccepted(t *testing.T) {
+	health := NewGatewayHealth(config.NewConfig(viper.New()))
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	health.now = func() time.Time { return now }
+
+	health.Record(domain.GatewayNIC, false)
+	now = now.Add(16 * time.Minute)
+	statuses := health.DependencyStatuses()
+	if assert.Len(t, statuses, 1) {
+		assert.Equal(t, domain.ComponentDegraded, statuses[0].Status)
+		assert.Nil(t, statuses[0].LastSuccess)
+	}
+
+	var unset *GatewayHealth
+	unset.Record(domain.GatewayNIC, true)
+}
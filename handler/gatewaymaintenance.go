@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	log "MgApplication/api-log"
+	"MgApplication/core/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var GatewayMaintenanceTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "sms_gateway_maintenance_total",
+		Help: "Total number of sends a gateway answered with a maintenance page instead of its plain text response",
+	},
+	[]string{"gateway"},
+)
+
+// gatewayMaintenanceResponseCode is the response code stored for a send answered with a
+// maintenance page
+const gatewayMaintenanceResponseCode = "GATEWAY_MAINTENANCE"
+
+// defaultGatewayMaintenanceCooldown is how long the sends through a gateway that answered with a
+// maintenance page are held back, without a Retry-After or sms.gatewaymaintenance.cooldown
+const defaultGatewayMaintenanceCooldown = time.Minute
+
+// maintenancePageEvidenceLength is the length of the maintenance page stored with the response
+const maintenancePageEvidenceLength = 512
+
+// GatewayMaintenanceError is the failure of a send the gateway answered with 200 and an HTML
+// page, as it does during its maintenance windows, rather than with its plain text response.
+// The message was not sent. RetryAfter is the time the sends through the gateway are held back.
+type GatewayMaintenanceError struct {
+	Gateway    domain.GatewayID
+	RetryAfter time.Duration
+}
+
+func (e *GatewayMaintenanceError) Error() string {
+	return fmt.Sprintf("SMS Gateway %s is under maintenance, the message was not sent, retry after %s", e.Gateway, e.RetryAfter.Round(time.Second))
+}
+
+// AppError returns the rejection as a 503 backpressure error of the GATEWAY_MAINTENANCE source,
+// waiting the time the sends through the gateway are held back
+func (e *GatewayMaintenanceError) AppError() *apierrors.AppError {
+	return apierrors.NewBackpressureError(apierrors.SourceGatewayMaintenance, time.Now().Add(e.RetryAfter), e.Error(), e)
+}
+
+// isMaintenancePage reports whether a 200 response of a gateway, whose plain text responses are
+// all the gateways send, is an HTML page: served as HTML or starting with a tag
+func isMaintenancePage(contentType string, body string) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml") {
+		return true
+	}
+	return strings.HasPrefix(strings.TrimSpace(body), "<")
+}
+
+// checkGatewayBody checks the body of a 200 response of gateway before it is parsed. A
+// maintenance page fails the send with a *GatewayMaintenanceError, the start of the page being
+// returned as the response to store for evidence.
+func checkGatewayBody(c *config.Config, gateway domain.GatewayID, resp *http.Response, body string) (string, error) {
+	contentType := resp.Header.Get("Content-Type")
+	if !isMaintenancePage(contentType, body) {
+		return body, nil
+	}
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if retryAfter <= 0 {
+		retryAfter = gatewayMaintenanceCooldown(c)
+	}
+	GatewayMaintenanceTotal.WithLabelValues(string(gateway)).Inc()
+	log.Error(nil, "SMS Gateway %s answered with a maintenance page (%s), sends held back for %s", gateway, contentType, retryAfter)
+	if len(body) > maintenancePageEvidenceLength {
+		body = strings.ToValidUTF8(body[:maintenancePageEvidenceLength], "")
+	}
+	return body, &GatewayMaintenanceError{Gateway: gateway, RetryAfter: retryAfter}
+}
+
+// gatewayMaintenanceCooldown returns how long the sends through a gateway that answered with a
+// maintenance page without Retry-After are held back, sms.gatewaymaintenance.cooldown
+func gatewayMaintenanceCooldown(c *config.Config) time.Duration {
+	if cooldown := c.GetDuration("sms.gatewaymaintenance.cooldown"); cooldown > 0 {
+		return cooldown
+	}
+	return defaultGatewayMaintenanceCooldown
+}
+
+// respondGatewayMaintenance answers a send the gateway could not take during its maintenance with
+// 503, the GATEWAY_MAINTENANCE error id and the time the sends are held back in Retry-After
+func respondGatewayMaintenance(ctx *gin.Context, err *GatewayMaintenanceError) {
+	apierrors.HandleBackpressureError(ctx, err.AppError())
+}
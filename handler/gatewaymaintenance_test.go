@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apierrors "MgApplication/api-errors"
+	"MgApplication/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// maintenancePage reads a maintenance page captured from a gateway
+func maintenancePage(t *testing.T, name string) string {
+	page, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return string(page)
+}
+
+func TestIsMaintenancePage(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        bool
+	}{
+		{name: "CDAC page", contentType: "text/html; charset=utf-8", body: maintenancePage(t, "cdac_maintenance.html"), want: true},
+		{name: "NIC page served as text", contentType: "text/plain", body: maintenancePage(t, "nic_maintenance.html"), want: true},
+		{name: "HTML without body", contentType: "text/html", want: true},
+		{name: "XHTML", contentType: "application/xhtml+xml", body: "Service unavailable", want: true},
+		{name: "CDAC accepted", contentType: "text/plain", body: "402,MsgID = 150920241726381202115"},
+		{name: "CDAC rejected", body: "Error 401 : Credentials Error, may be invalid username or password"},
+		{name: "NIC accepted", contentType: "text/plain;charset=UTF-8", body: "Message Accepted for Request ID=123456789~code=API000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isMaintenancePage(tt.contentType, tt.body))
+		})
+	}
+}
+
+// A maintenance page of CDAC fails the request with 503 instead of recording it as submitted,
+// the start of the page being stored, and holds back the next sends
+func TestCreateSMSGatewayMaintenance(t *testing.T) {
+	page := maintenancePage(t, "cdac_maintenance.html")
+	var calls atomic.Int32
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(page))
+	}))
+	defer cdac.Close()
+	c := brandingConfig(true)
+	c.Set("sms.cdac.url", cdac.URL)
+	c.Set("sms.msgstorerequest", 1)
+	c.Set("sms.gatewaymaintenance.cooldown", "90s")
+
+	ch, store := newTestSMSHandler(c)
+	rec := postSMSRequest(ch, otpRequestBody("9000000001"))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code, rec.Body.String())
+	assertRetryAfter(t, rec, 90, 9)
+	var body apierrors.APIErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Contains(t, body.AppError.Message, "SMS Gateway CDAC is under maintenance")
+
+	require.Len(t, store.savedResponses, 1)
+	stored := store.savedResponses[0]
+	assert.Equal(t, gatewayMaintenanceResponseCode, stored.ResponseCode)
+	assert.NotContains(t, stored.ResponseText, "Submitted Successfully")
+	assert.Empty(t, stored.ReferenceID)
+	assert.True(t, strings.HasPrefix(page, stored.CompleteResponse), "the start of the page is stored")
+	assert.Len(t, stored.CompleteResponse, maintenancePageEvidenceLength)
+
+	// the gateway is not called again during the cooldown
+	rec = postSMSRequest(ch, otpRequestBody("9000000002"))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code, rec.Body.String())
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestSendSMSNICMaintenance(t *testing.T) {
+	page := maintenancePage(t, "nic_maintenance.html")
+	nic := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Retry-After", "300")
+		_, _ = w.Write([]byte(page))
+	}))
+	defer nic.Close()
+	c := brandingConfig(true)
+	c.Set("sms.nic.url", nic.URL)
+	ch := &MgApplicationHandler{c: c}
+
+	rsp, err := ch.SendSMSNIC(SMSParams{Username: "user", Password: "pin", SenderID: "INPOST", MobileNumber: "9000000001"})
+	maintenance, ok := apierrors.Find[*GatewayMaintenanceError](err)
+	require.True(t, ok, err)
+	assert.Equal(t, domain.GatewayNIC, maintenance.Gateway)
+	assert.Equal(t, 300*time.Second, maintenance.RetryAfter)
+	assert.Equal(t, page, rsp)
+
+	msgresponse, err := parseGatewayResponse(domain.GatewayNIC, rsp, err)
+	require.Error(t, err)
+	assert.Equal(t, gatewayMaintenanceResponseCode, msgresponse.ResponseCode)
+	assert.Empty(t, msgresponse.ReferenceID)
+	assert.Equal(t, page, msgresponse.CompleteResponse)
+}
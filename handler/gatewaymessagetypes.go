@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	log "MgApplication/api-log"
+	"MgApplication/core/domain"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var GatewayMessageTypeUnsupportedTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "sms_gateway_message_type_unsupported_total",
+		Help: "Total number of requests whose gateway does not support their message type, by gateway, message type and outcome",
+	},
+	[]string{"gateway", "message_type", "outcome"},
+)
+
+// Outcomes of a request whose gateway does not support its message type, the outcome label of
+// GatewayMessageTypeUnsupportedTotal
+const (
+	messageTypeRerouted    = "rerouted"
+	messageTypeUnsupported = "rejected"
+)
+
+var errGatewayMessageTypesConfig = errors.New("invalid sms.messagetype.gateways")
+
+// GatewayMessageTypes declares the message types each gateway supports, configured per gateway
+// id in sms.messagetype.gateways, e.g. "2": [PM] for a gateway that mangles Unicode; a gateway not
+// listed supports every message type. A request whose resolved gateway does not support its
+// message type is rejected with a 422, unless sms.messagetype.failover is set and another gateway
+// supports it: the request is then sent through the lowest gateway id that does.
+type GatewayMessageTypes struct {
+	supported map[domain.GatewayID]map[domain.MessageType]bool
+	failover  bool
+}
+
+// NewGatewayMessageTypes creates a new GatewayMessageTypes instance using the sms.messagetype
+// configuration, failing on an unknown gateway or message type
+func NewGatewayMessageTypes(c *config.Config) (*GatewayMessageTypes, error) {
+	types := &GatewayMessageTypes{
+		supported: make(map[domain.GatewayID]map[domain.MessageType]bool),
+		failover:  c.GetBool("sms.messagetype.failover"),
+	}
+	for gateway := range c.GetStringMap("sms.messagetype.gateways") {
+		id, err := domain.ParseGatewayID(gateway)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errGatewayMessageTypesConfig, err.Error())
+		}
+		supported := make(map[domain.MessageType]bool)
+		for _, raw := range c.GetStringSlice("sms.messagetype.gateways." + gateway) {
+			mt, err := domain.ParseMessageType(raw)
+			if err != nil || raw == "" {
+				return nil, fmt.Errorf("%w: gateway %s: invalid message type %q, must be one of %s", errGatewayMessageTypesConfig, gateway, raw, domain.AllowedMessageTypes())
+			}
+			supported[mt] = true
+		}
+		types.supported[id] = supported
+	}
+	return types, nil
+}
+
+// newGatewayMessageTypes returns the message types of sms.messagetype.gateways, every gateway
+// supporting every type when it is invalid, which CheckGatewayMessageTypes reports at startup
+func newGatewayMessageTypes(c *config.Config) *GatewayMessageTypes {
+	types, err := NewGatewayMessageTypes(c)
+	if err != nil {
+		log.Error(nil, "Message types are not checked against the gateways: %s", err.Error())
+		return nil
+	}
+	return types
+}
+
+// CheckGatewayMessageTypes fails startup when sms.messagetype.gateways is invalid
+func CheckGatewayMessageTypes(c *config.Config) error {
+	_, err := NewGatewayMessageTypes(c)
+	return err
+}
+
+// Supports reports whether gateway supports the message type mt
+func (t *GatewayMessageTypes) Supports(gateway domain.GatewayID, mt domain.MessageType) bool {
+	if t == nil {
+		return true
+	}
+	supported, ok := t.supported[gateway]
+	return !ok || supported[mt]
+}
+
+// Failover returns the gateway a message of type mt that gateway does not support is sent
+// through instead, empty when failover is not enabled or no other gateway supports it
+func (t *GatewayMessageTypes) Failover(gateway domain.GatewayID, mt domain.MessageType) domain.GatewayID {
+	if t == nil || !t.failover {
+		return ""
+	}
+	for _, other := range domain.Gateways {
+		if other != gateway && t.Supports(other, mt) {
+			return other
+		}
+	}
+	return ""
+}
+
+// checkGatewayMessageType checks the message type of msgreq against its resolved gateway,
+// rerouting msgreq through the failover gateway when there is one, rerouted being set. The
+// failover is set as the route of msgreq too, so that the request is stored with it. It returns
+// a 422 AppError when the message type is not supported and msgreq is not rerouted.
+func (ch *MgApplicationHandler) checkGatewayMessageType(msgreq *domain.MsgRequest) (rerouted bool, err error) {
+	gateway := domain.GatewayID(msgreq.Gateway)
+	mt := domain.MessageTypePlain
+	if domain.MessageType(msgreq.MessageType).IsUnicode() {
+		mt = domain.MessageTypeUnicode
+	}
+	if ch.messageTypes.Supports(gateway, mt) {
+		return false, nil
+	}
+	if failover := ch.messageTypes.Failover(gateway, mt); failover != "" {
+		GatewayMessageTypeUnsupportedTotal.WithLabelValues(string(gateway), string(mt), messageTypeRerouted).Inc()
+		log.Warn(nil, "Gateway %s does not support message type %s, request of application %s sent through gateway %s", gateway, mt, msgreq.ApplicationID, failover)
+		msgreq.Gateway = string(failover)
+		msgreq.RouteGateway = string(failover)
+		return true, nil
+	}
+	GatewayMessageTypeUnsupportedTotal.WithLabelValues(string(gateway), string(mt), messageTypeUnsupported).Inc()
+	err = fmt.Errorf("message_type %s is not supported by gateway %s (%s)", mt, string(gateway), gateway)
+	return false, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.AppErrorValidationError, err.Error(), err)
+}
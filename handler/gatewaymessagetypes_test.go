@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGatewayMessageTypes(t *testing.T) {
+	c := config.NewConfig(viper.New())
+	c.Set("sms.messagetype.gateways", map[string]any{"2": []string{"pm"}})
+	types, err := NewGatewayMessageTypes(c)
+	require.NoError(t, err)
+	assert.True(t, types.Supports(domain.GatewayNIC, domain.MessageTypePlain))
+	assert.False(t, types.Supports(domain.GatewayNIC, domain.MessageTypeUnicode))
+	assert.True(t, types.Supports(domain.GatewayCDAC, domain.MessageTypeUnicode), "gateways not listed support every type")
+	assert.Equal(t, domain.GatewayID(""), types.Failover(domain.GatewayNIC, domain.MessageTypeUnicode), "failover not enabled")
+
+	c.Set("sms.messagetype.failover", true)
+	types, err = NewGatewayMessageTypes(c)
+	require.NoError(t, err)
+	assert.Equal(t, domain.GatewayCDAC, types.Failover(domain.GatewayNIC, domain.MessageTypeUnicode))
+
+	c.Set("sms.messagetype.gateways", map[string]any{"9": []string{"PM"}})
+	assert.ErrorIs(t, CheckGatewayMessageTypes(c), errGatewayMessageTypesConfig)
+	c.Set("sms.messagetype.gateways", map[string]any{"2": []string{"GSM"}})
+	assert.ErrorIs(t, CheckGatewayMessageTypes(c), errGatewayMessageTypesConfig)
+}
+
+// unicodeRequestBody is an OTP request with a Hindi text, sent with message_type UC
+func unicodeRequestBody(mobileNumber string) map[string]any {
+	body := otpRequestBody(mobileNumber)
+	body["message_text"] = "प्रिय ग्राहक, बुकिंग के लिए ओटीपी 1234 है - INDPOST"
+	body["message_type"] = "UC"
+	return body
+}
+
+// A Unicode request whose gateway only supports plain messages is rejected with a 422 before
+// anything is stored or sent, or sent through the failover gateway when it is enabled
+func TestCreateSMSUnicodeToPlainOnlyGateway(t *testing.T) {
+	var cdacCalls, nicCalls atomic.Int32
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cdacCalls.Add(1)
+		_, _ = w.Write([]byte("402,MsgID = 150920241726381202115"))
+	}))
+	defer cdac.Close()
+	nic := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nicCalls.Add(1)
+		_, _ = w.Write([]byte("Message Accepted for Request ID=123456789~code=API000"))
+	}))
+	defer nic.Close()
+
+	for _, persist := range []int{0, 1} {
+		c := brandingConfig(true)
+		c.Set("sms.cdac.url", cdac.URL)
+		c.Set("sms.nic.url", nic.URL)
+		c.Set("sms.msgstorerequest", persist)
+		c.Set("sms.messagetype.gateways", map[string]any{"1": []string{"PM"}})
+		ch, store := newTestSMSHandler(c)
+		ch.messageTypes = newGatewayMessageTypes(c)
+		cdacCalls.Store(0)
+
+		rec := postSMSRequest(ch, unicodeRequestBody("9000000001"))
+		require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+		assert.Contains(t, rec.Body.String(), "message_type UC is not supported by gateway 1 (CDAC)")
+		assert.Zero(t, cdacCalls.Load())
+		assert.Zero(t, store.savedRequests)
+		assert.Empty(t, store.savedResponses)
+
+		// plain messages are sent through the gateway as usual
+		rec = postSMSRequest(ch, otpRequestBody("9000000002"))
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		assert.Equal(t, int32(1), cdacCalls.Load())
+
+		// with failover the request is sent through NIC, and stored with it
+		c.Set("sms.messagetype.failover", true)
+		ch.messageTypes = newGatewayMessageTypes(c)
+		rec = postSMSRequest(ch, unicodeRequestBody("9000000003"))
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		assert.Equal(t, int32(1), cdacCalls.Load())
+		if persist == 1 {
+			assert.Equal(t, string(domain.GatewayNIC), store.lastRequest.Gateway)
+		}
+	}
+	assert.Equal(t, int32(2), nicCalls.Load())
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	apierrors "MgApplication/api-errors"
+	"MgApplication/core/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GatewayRateLimitedError is the failure of a send refused by the gateway with 429 Too Many
+// Requests, RetryAfter being the wait the gateway asked for, 0 when it gave none
+type GatewayRateLimitedError struct {
+	Gateway    domain.GatewayID
+	RetryAfter time.Duration
+}
+
+func (e *GatewayRateLimitedError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("SMS Gateway %s rate limited the request, retry after %s", e.Gateway, e.RetryAfter)
+	}
+	return fmt.Sprintf("SMS Gateway %s rate limited the request", e.Gateway)
+}
+
+// AppError returns the rejection as a 429 backpressure error of the GATEWAY_RATE_LIMITED source,
+// waiting the RetryAfter of the gateway
+func (e *GatewayRateLimitedError) AppError() *apierrors.AppError {
+	return apierrors.NewBackpressureError(apierrors.SourceGatewayRateLimited, time.Now().Add(e.RetryAfter), e.Error(), e)
+}
+
+// newGatewayRateLimitedError reads the Retry-After header of a 429 response of gateway
+func newGatewayRateLimitedError(gateway domain.GatewayID, resp *http.Response) *GatewayRateLimitedError {
+	return &GatewayRateLimitedError{Gateway: gateway, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+}
+
+// parseRetryAfter reads a Retry-After header, in seconds or as an HTTP date, 0 when it is
+// missing, invalid or in the past
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseInt(header, 10, 64); err == nil {
+		if seconds <= 0 || seconds > int64(math.MaxInt64/time.Second) {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// gatewayThrottle holds back the sends through a gateway that rate limited a send until the
+// Retry-After it gave has passed, so that the gateway is not called while it refuses calls.
+// The sends held back fail with a GatewayRateLimitedError carrying the remaining wait. A gateway
+// that answered with a maintenance page is held back alike, the sends failing with a
+// GatewayMaintenanceError.
+type gatewayThrottle struct {
+	breakers    map[domain.GatewayID]*circuitBreaker
+	maintenance map[domain.GatewayID]*circuitBreaker
+}
+
+func newGatewayThrottle() *gatewayThrottle {
+	return &gatewayThrottle{
+		breakers: map[domain.GatewayID]*circuitBreaker{
+			domain.GatewayCDAC: newCircuitBreaker(0, 0),
+			domain.GatewayNIC:  newCircuitBreaker(0, 0),
+		},
+		maintenance: map[domain.GatewayID]*circuitBreaker{
+			domain.GatewayCDAC: newCircuitBreaker(0, 0),
+			domain.GatewayNIC:  newCircuitBreaker(0, 0),
+		},
+	}
+}
+
+// Check returns the error of a send through gateway held back, nil when it may be sent
+func (t *gatewayThrottle) Check(gateway domain.GatewayID) error {
+	if t == nil || t.breakers[gateway] == nil {
+		return nil
+	}
+	if wait := t.maintenance[gateway].RetryIn(); wait > 0 {
+		return &GatewayMaintenanceError{Gateway: gateway, RetryAfter: wait}
+	}
+	if wait := t.breakers[gateway].RetryIn(); wait > 0 {
+		return &GatewayRateLimitedError{Gateway: gateway, RetryAfter: wait}
+	}
+	return nil
+}
+
+// Observe holds back the sends through gateway when err rate limited a send with a Retry-After
+// or the gateway answered with a maintenance page
+func (t *gatewayThrottle) Observe(gateway domain.GatewayID, err error) {
+	if t == nil || t.breakers[gateway] == nil {
+		return
+	}
+	switch err := err.(type) {
+	case *GatewayRateLimitedError:
+		if err.RetryAfter > 0 {
+			t.breakers[gateway].OpenFor(err.RetryAfter)
+		}
+	case *GatewayMaintenanceError:
+		t.maintenance[gateway].OpenFor(err.RetryAfter)
+	}
+}
+
+// respondGatewayRateLimited answers a send rate limited by the gateway with 429, the
+// GATEWAY_RATE_LIMITED error id and the wait of the gateway in Retry-After
+func respondGatewayRateLimited(ctx *gin.Context, err *GatewayRateLimitedError) {
+	apierrors.HandleBackpressureError(ctx, err.AppError())
+}
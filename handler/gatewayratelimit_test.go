@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	"MgApplication/core/domain"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRateLimitingGateway starts a gateway answering every call with 429 and retryAfter, counting
+// the calls
+func newRateLimitingGateway(t *testing.T, retryAfter string) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+	}))
+	t.Cleanup(gateway.Close)
+	return gateway, &calls
+}
+
+// assertRetryAfter asserts that the Retry-After of rec, also the retry_after_seconds of its body,
+// is at least wait seconds and at most jitter seconds more
+func assertRetryAfter(t *testing.T, rec *httptest.ResponseRecorder, wait int64, jitter int64) {
+	t.Helper()
+	retryAfter, err := strconv.ParseInt(rec.Header().Get("Retry-After"), 10, 64)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, retryAfter, wait)
+	assert.LessOrEqual(t, retryAfter, wait+jitter)
+	var body apierrors.APIErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, retryAfter, body.AppError.RetryAfterSeconds)
+}
+
+// A 429 of the gateway is answered with 429 and its Retry-After, and the gateway is not called
+// again before the Retry-After has passed
+func TestCreateSMSRequestGatewayRateLimited(t *testing.T) {
+	gateway, calls := newRateLimitingGateway(t, "120")
+	c := config.NewConfig(viper.New())
+	c.Set("sms.cdac.url", gateway.URL)
+	c.Set("sms.msgstorerequest", 1)
+	ch, store := newTestSMSHandler(c)
+
+	rec := postSMSRequest(ch, otpRequestBody("9000000001"))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, rec.Body.String())
+	// a tenth of the wait of jitter
+	assertRetryAfter(t, rec, 120, 12)
+	require.Len(t, store.savedResponses, 1)
+	assert.Equal(t, "429", store.savedResponses[0].ResponseCode)
+
+	rec = postSMSRequest(ch, otpRequestBody("9000000002"))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, rec.Body.String())
+	assertRetryAfter(t, rec, 119, 13)
+	assert.Equal(t, int32(1), calls.Load(), "gateway called while rate limiting")
+}
+
+// A 429 without Retry-After is answered with 429 and the shortest Retry-After, and does not hold
+// back the next sends
+func TestCreateSMSRequestGatewayRateLimitedWithoutRetryAfter(t *testing.T) {
+	gateway, calls := newRateLimitingGateway(t, "")
+	c := config.NewConfig(viper.New())
+	c.Set("sms.cdac.url", gateway.URL)
+	ch, _ := newTestSMSHandler(c)
+
+	for i := 0; i < 2; i++ {
+		rec := postSMSRequest(ch, otpRequestBody("9000000001"))
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code, rec.Body.String())
+		assertRetryAfter(t, rec, 1, 1)
+	}
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestSendSMSNICRateLimited(t *testing.T) {
+	gateway, _ := newRateLimitingGateway(t, time.Now().Add(90*time.Second).UTC().Format(http.TimeFormat))
+	c := config.NewConfig(viper.New())
+	c.Set("sms.nic.url", gateway.URL)
+	ch := &MgApplicationHandler{c: c}
+
+	_, err := ch.SendSMSNIC(SMSParams{Username: "user", Password: "pin", SenderID: "INPOST", MobileNumber: "9000000001"})
+	rateLimited, ok := apierrors.Find[*GatewayRateLimitedError](err)
+	require.True(t, ok, err)
+	assert.Equal(t, domain.GatewayNIC, rateLimited.Gateway)
+	assert.InDelta(t, 90, rateLimited.RetryAfter.Seconds(), 2)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 8, 27, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{" 5 ", 5 * time.Second},
+		{"0", 0},
+		{"-10", 0},
+		{"soon", 0},
+		{"99999999999999999999", 0},
+		{now.Add(2 * time.Minute).Format(http.TimeFormat), 2 * time.Minute},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, parseRetryAfter(tt.header, now), tt.header)
+	}
+}
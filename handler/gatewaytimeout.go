@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+)
+
+// defaultGatewayTimeout is the time the gateway clients wait for an answer without
+// sms.gatewaytimeout
+const defaultGatewayTimeout = 30 * time.Second
+
+// gatewayTimeoutResponseCode is the response code stored for a send the gateway did not answer
+// in time, the status of the response of the request
+var gatewayTimeoutResponseCode = strconv.Itoa(http.StatusGatewayTimeout)
+
+// GatewayTimeoutError is the failure of a send the gateway did not answer before the deadline of
+// the call, whether the timeout of the client or the deadline of the request. The message may or
+// may not have been sent.
+type GatewayTimeoutError struct {
+	Gateway domain.GatewayID
+	Err     error
+}
+
+func (e *GatewayTimeoutError) Error() string {
+	return fmt.Sprintf("SMS Gateway %s did not answer in time, the message may not have been sent: %s", e.Gateway, e.Err.Error())
+}
+
+func (e *GatewayTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// isGatewayTimeout reports whether err is the failure of a call reaching its deadline: a
+// context deadline, a connection deadline or a client timeout
+func isGatewayTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// gatewayTimeout returns the time the gateway clients wait for an answer, sms.gatewaytimeout
+func gatewayTimeout(c *config.Config) time.Duration {
+	if timeout := c.GetDuration("sms.gatewaytimeout"); timeout > 0 {
+		return timeout
+	}
+	return defaultGatewayTimeout
+}
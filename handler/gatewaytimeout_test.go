@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	apierrors "MgApplication/api-errors"
+	"MgApplication/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsGatewayTimeout(t *testing.T) {
+	assert.True(t, isGatewayTimeout(context.DeadlineExceeded))
+	assert.True(t, isGatewayTimeout(fmt.Errorf("send: %w", os.ErrDeadlineExceeded)))
+	assert.True(t, isGatewayTimeout(&net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}))
+	assert.False(t, isGatewayTimeout(context.Canceled))
+	assert.False(t, isGatewayTimeout(errors.New("connection refused")))
+}
+
+// A gateway answering after sms.gatewaytimeout fails the request with 504, its response being
+// stored with the timeout code
+func TestCreateSMSGatewayTimeout(t *testing.T) {
+	release := make(chan struct{})
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+		_, _ = w.Write([]byte("402,MsgID = 150920241726381202115"))
+	}))
+	defer cdac.Close()
+	defer close(release)
+	c := brandingConfig(true)
+	c.Set("sms.cdac.url", cdac.URL)
+	c.Set("sms.gatewaytimeout", "50ms")
+	c.Set("sms.msgstorerequest", 1)
+
+	ch, store := newTestSMSHandler(c)
+	start := time.Now()
+	rec := postSMSRequest(ch, otpRequestBody("9000000001"))
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	require.Equal(t, http.StatusGatewayTimeout, rec.Code, rec.Body.String())
+	var body apierrors.APIErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Contains(t, body.AppError.Message, "SMS Gateway CDAC did not answer in time")
+	require.Len(t, store.savedResponses, 1)
+	assert.Equal(t, gatewayTimeoutResponseCode, store.savedResponses[0].ResponseCode)
+
+	msgresponse, err := parseGatewayResponse(domain.GatewayCDAC, "", context.DeadlineExceeded)
+	var timeout *GatewayTimeoutError
+	require.ErrorAs(t, err, &timeout)
+	assert.Equal(t, domain.GatewayCDAC, timeout.Gateway)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, "504", msgresponse.ResponseCode)
+}
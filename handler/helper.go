@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"iter"
 	"strings"
 	"unicode"
 
@@ -25,6 +26,21 @@ import (
 // 	}
 // }
 
+// mapSeq returns the elements of seq converted by fn, the errors of seq being passed on as is
+func mapSeq[T, R any](seq iter.Seq2[T, error], fn func(T) R) iter.Seq2[R, error] {
+	return func(yield func(R, error) bool) {
+		for item, err := range seq {
+			var converted R
+			if err == nil {
+				converted = fn(item)
+			}
+			if !yield(converted, err) {
+				return
+			}
+		}
+	}
+}
+
 // ****Normalisation functions****//
 // NormalizeAndClean - Normalizes Unicode, replaces special characters, and cleans up spaces.
 func NormalizeAndClean(input string) string {
@@ -155,7 +171,6 @@ func NormalizeAndClean2(input string) string {
 	normalizedCleantext := strings.TrimSpace(builder.String())
 	ctx := context.Background()
 	log.Debug(ctx, "Normalized Cleantext: %s", string(normalizedCleantext))
-	// fmt.Println(ctx, "Normalized Cleantext:", normalizedCleantext)
 
 	return normalizedCleantext
 }
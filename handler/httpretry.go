@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	config "MgApplication/api-config"
+	log "MgApplication/api-log"
+)
+
+// maxRetryAfterWait is the longest Retry-After a retried call waits for. A response asking for a
+// longer wait is returned to the caller, e.g. to hold back the gateway with gatewayThrottle.
+const maxRetryAfterWait = 5 * time.Second
+
+// defaultRetryBackoff is the base wait between the attempts of an outbound call
+const defaultRetryBackoff = 200 * time.Millisecond
+
+// outboundRetry is the retry policy of the outbound HTTP calls, sms.retry
+type outboundRetry struct {
+	attempts int
+	backoff  time.Duration
+}
+
+// newOutboundRetry reads sms.retry: attempts, 1 when unset so that calls are not retried, and
+// backoff, defaultRetryBackoff when unset
+func newOutboundRetry(c *config.Config) outboundRetry {
+	retry := outboundRetry{attempts: c.GetInt("sms.retry.attempts"), backoff: c.GetDuration("sms.retry.backoff")}
+	if retry.attempts < 1 {
+		retry.attempts = 1
+	}
+	if retry.backoff <= 0 {
+		retry.backoff = defaultRetryBackoff
+	}
+	return retry
+}
+
+// do calls fn with doWithRetry following the policy
+func (r outboundRetry) do(ctx context.Context, fn func() (*http.Response, error)) (*http.Response, error) {
+	return doWithRetry(ctx, r.attempts, r.backoff, fn)
+}
+
+// doWithRetry calls fn up to attempts times while it fails with a transport error or a
+// retryable status, isRetryableStatus. Attempt n waits n*backoff plus up to the same amount
+// of jitter before the next, or the Retry-After of the response when it is longer, up to
+// maxRetryAfterWait. The bodies of the responses retried are drained and closed.
+//
+// It returns the last response or error, the response of a retryable status included, so
+// that callers handle the final outcome as they would without retries. It stops once ctx is
+// done, returning the last outcome.
+func doWithRetry(ctx context.Context, attempts int, backoff time.Duration, fn func() (*http.Response, error)) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := fn()
+		if attempt >= attempts || !shouldRetry(ctx, resp, err) {
+			return resp, err
+		}
+
+		wait := backoff * time.Duration(attempt)
+		wait += rand.N(wait + 1)
+		if resp != nil {
+			retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+			if retryAfter > maxRetryAfterWait {
+				return resp, err
+			}
+			wait = max(wait, retryAfter)
+			log.Warn(ctx, "Retrying the call answered %s (attempt %d of %d) in %s", resp.Status, attempt, attempts, wait)
+		} else {
+			log.Warn(ctx, "Retrying the call failing with %s (attempt %d of %d) in %s", err.Error(), attempt, attempts, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, err
+		case <-timer.C:
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}
+}
+
+// shouldRetry reports whether the outcome of an attempt is worth another: a transport error
+// that is not caused by ctx, or a retryable status
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return isRetryableStatus(resp.StatusCode)
+}
+
+// isRetryableStatus reports whether a response of status may succeed when repeated: the
+// request timed out, was rate limited, or an upstream was unavailable. A 500 is not retried,
+// the request may have been processed.
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
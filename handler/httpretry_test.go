@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFlakyServer answers the first calls with the statuses given, then with 200 OK
+func newFlakyServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(calls.Add(1))
+		if call <= len(statuses) {
+			w.WriteHeader(statuses[call-1])
+			_, _ = w.Write([]byte("failed"))
+			return
+		}
+		_, _ = w.Write([]byte("402,MsgID = 150920241726381202115"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestDoWithRetrySucceedsOnRetry(t *testing.T) {
+	server, calls := newFlakyServer(t, http.StatusServiceUnavailable, http.StatusBadGateway)
+
+	resp, err := doWithRetry(context.Background(), 3, time.Millisecond, func() (*http.Response, error) {
+		return http.Get(server.URL)
+	})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "402,MsgID = 150920241726381202115", string(body))
+	assert.Equal(t, int32(3), calls.Load())
+
+	// transport errors are retried too
+	attempts := 0
+	resp, err = doWithRetry(context.Background(), 3, time.Millisecond, func() (*http.Response, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, errors.New("connection reset by peer")
+		}
+		return http.Get(server.URL)
+	})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 2, attempts)
+}
+
+func TestDoWithRetryExhausted(t *testing.T) {
+	server, calls := newFlakyServer(t, http.StatusGatewayTimeout, http.StatusGatewayTimeout, http.StatusGatewayTimeout)
+
+	// the last response is returned for the caller to handle
+	resp, err := doWithRetry(context.Background(), 3, time.Millisecond, func() (*http.Response, error) {
+		return http.Get(server.URL)
+	})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	assert.Equal(t, "failed", string(body))
+	assert.Equal(t, int32(3), calls.Load())
+
+	attempts := 0
+	_, err = doWithRetry(context.Background(), 2, time.Millisecond, func() (*http.Response, error) {
+		attempts++
+		return nil, errors.New("connection refused")
+	})
+	assert.EqualError(t, err, "connection refused")
+	assert.Equal(t, 2, attempts)
+}
+
+func TestDoWithRetryStopsOnFinalOutcomes(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError} {
+		server, calls := newFlakyServer(t, status)
+		resp, err := doWithRetry(context.Background(), 3, time.Millisecond, func() (*http.Response, error) {
+			return http.Get(server.URL)
+		})
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, int32(1), calls.Load(), "status %d", status)
+	}
+
+	// a Retry-After beyond maxRetryAfterWait is left to the caller
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	resp, err := doWithRetry(context.Background(), 3, time.Millisecond, func() (*http.Response, error) {
+		return http.Get(server.URL)
+	})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestDoWithRetryContextCancellation(t *testing.T) {
+	server, calls := newFlakyServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	resp, err := doWithRetry(ctx, 3, time.Minute, func() (*http.Response, error) {
+		return http.Get(server.URL)
+	})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Less(t, time.Since(start), time.Second, "the wait should end with the context")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// errors of the context are not retried
+	attempts := 0
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = doWithRetry(cancelled, 3, time.Millisecond, func() (*http.Response, error) {
+		attempts++
+		return nil, context.Canceled
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestSendSMSCDACRetries(t *testing.T) {
+	server, calls := newFlakyServer(t, http.StatusServiceUnavailable)
+
+	c := brandingConfig(true)
+	c.Set("sms.cdac.url", server.URL)
+	c.Set("sms.retry.attempts", 2)
+	c.Set("sms.retry.backoff", time.Millisecond)
+	ch, _ := newTestSMSHandler(c)
+	ch.retry = newOutboundRetry(c)
+
+	rsp, err := ch.SendSMSCDAC(SMSParams{Username: "appostsms", Password: "cdacsecret", MobileNumber: "9000000001"})
+	require.NoError(t, err)
+	assert.Equal(t, "402,MsgID = 150920241726381202115", rsp)
+	assert.Equal(t, int32(2), calls.Load())
+}
@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// The custom validators can only be registered once per process
+	if err := NewValidatorService(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+
+	auth "MgApplication/api-authz"
+	config "MgApplication/api-config"
+	log "MgApplication/api-log"
+	"MgApplication/core/clock"
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+	"MgApplication/handler/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// messageTextReadScope lets the caller read the unmasked message text of stored requests
+const messageTextReadScope = "sms.text.read"
+
+// MessageTextGuard builds the message text policy of a request. Message text is masked
+// unless the verified bearer token of the caller carries the sms.text.read scope, or sms.textmasking.enabled is false in
+// the dev environment. Every unmasked access is audited.
+type MessageTextGuard struct {
+	auditor port.MessageTextAuditor
+	enabled bool
+}
+
+// NewMessageTextGuard creates a new MessageTextGuard instance using the sms.textmasking configuration
+func NewMessageTextGuard(auditor port.MessageTextAuditor, c *config.Config) *MessageTextGuard {
+	return &MessageTextGuard{
+		auditor: auditor,
+		// Masking can only be turned off in dev, a missing key keeps it on
+		enabled: !c.IsDevEnv() || !c.Exists("sms.textmasking.enabled") || c.GetBool("sms.textmasking.enabled"),
+	}
+}
+
+// Policy returns the message text policy for the caller of ctx
+func (mg *MessageTextGuard) Policy(ctx *gin.Context) response.MessageTextPolicy {
+	claims := auth.ClaimsFrom(ctx.Request.Context())
+	policy := &messageTextPolicy{
+		ctx:     ctx,
+		auditor: mg.auditor,
+		reveal:  !mg.enabled || claims.HasScope(messageTextReadScope),
+	}
+	if claims != nil {
+		policy.userID = claims.Subject
+	}
+	return policy
+}
+
+type messageTextPolicy struct {
+	ctx     context.Context
+	auditor port.MessageTextAuditor
+	reveal  bool
+	userID  string
+}
+
+func (mp *messageTextPolicy) MessageText(communicationID string, text string) string {
+	if !mp.reveal {
+		return response.MaskMessageText(text)
+	}
+	mp.auditor.AuditMessageTextAccess(mp.ctx, domain.MessageTextAccess{
+		UserID:          mp.userID,
+		CommunicationID: communicationID,
+		AccessedAt:      clock.Now(),
+	})
+	return text
+}
+
+// LogMessageTextAuditor writes the accesses to unmasked message text to the log as audit entries
+type LogMessageTextAuditor struct{}
+
+// NewLogMessageTextAuditor creates a new LogMessageTextAuditor instance
+func NewLogMessageTextAuditor() *LogMessageTextAuditor {
+	return &LogMessageTextAuditor{}
+}
+
+func (LogMessageTextAuditor) AuditMessageTextAccess(ctx context.Context, access domain.MessageTextAccess) {
+	log.InfoEvent(ctx).
+		Str("audit", "message_text_read").
+		Str("user_id", access.UserID).
+		Str("communication_id", access.CommunicationID).
+		Time("accessed_at", access.AccessedAt).
+		Msg("Unmasked message text returned")
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	auth "MgApplication/api-authz"
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+	"MgApplication/handler/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingAuditor keeps the audited message text accesses
+type recordingAuditor struct {
+	accesses []domain.MessageTextAccess
+}
+
+func (ra *recordingAuditor) AuditMessageTextAccess(_ context.Context, access domain.MessageTextAccess) {
+	ra.accesses = append(ra.accesses, access)
+}
+
+// statusReport builds the sent status report of one request for a caller with the given verified claims
+func statusReport(t *testing.T, c *config.Config, claims *auth.Claims, headers map[string]string) (string, *recordingAuditor) {
+	t.Helper()
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/v1/sms-sent-status-report", nil)
+	for k, v := range headers {
+		ctx.Request.Header.Set(k, v)
+	}
+	if claims != nil {
+		ctx.Request = ctx.Request.WithContext(auth.WithClaims(ctx.Request.Context(), claims))
+	}
+
+	auditor := &recordingAuditor{}
+	communicationID := "AbCdEfGhIjKlMnOpQrSt"
+	text := "Your OTP for login is 482913. Do not share it."
+	rsp := response.NewSMSSentStatusReportResponse([]domain.SMSReport{
+		{CommunicationID: &communicationID, MessageText: &text},
+	}, NewMessageTextGuard(auditor, c).Policy(ctx))
+	require.Len(t, rsp, 1)
+	return *rsp[0].MessageText, auditor
+}
+
+func TestMaskMessageText(t *testing.T) {
+	assert.Equal(t, "Your OTP f*******************************e it.", response.MaskMessageText("Your OTP for login is 482913. Do not share it."))
+	assert.Equal(t, "******", response.MaskMessageText("482913"))
+	assert.Equal(t, "***************", response.MaskMessageText("Your OTP 482913"))
+	assert.Equal(t, "äöüäöüäöüä****äöüäö", response.MaskMessageText("äöüäöüäöüä1234äöüäö"))
+	assert.Equal(t, "", response.MaskMessageText(""))
+}
+
+func TestMessageTextMasked(t *testing.T) {
+	c := config.NewConfig(viper.New())
+
+	text, auditor := statusReport(t, c, &auth.Claims{Subject: "ops1", Scope: "admin sms.read"}, nil)
+	assert.Equal(t, "Your OTP f*******************************e it.", text)
+	assert.Empty(t, auditor.accesses)
+
+	// Scopes the caller claims for itself in a header are never trusted
+	text, auditor = statusReport(t, c, nil, map[string]string{"X-User-Scope": "sms.text.read", "X-User-ID": "ops1"})
+	assert.Equal(t, "Your OTP f*******************************e it.", text)
+	assert.Empty(t, auditor.accesses)
+}
+
+func TestMessageTextRevealedWithScope(t *testing.T) {
+	c := config.NewConfig(viper.New())
+
+	before := time.Now()
+	text, auditor := statusReport(t, c, &auth.Claims{Subject: "ops1", Scope: "admin sms.text.read"}, nil)
+	assert.Equal(t, "Your OTP for login is 482913. Do not share it.", text)
+
+	require.Len(t, auditor.accesses, 1)
+	assert.Equal(t, "ops1", auditor.accesses[0].UserID)
+	assert.Equal(t, "AbCdEfGhIjKlMnOpQrSt", auditor.accesses[0].CommunicationID)
+	assert.False(t, auditor.accesses[0].AccessedAt.Before(before))
+}
+
+func TestMessageTextMaskingDisabled(t *testing.T) {
+	c := config.NewConfig(viper.New())
+	c.Set("sms.textmasking.enabled", false)
+
+	// Masking stays on outside the dev environment
+	c.Set("info.env", "prod")
+	text, _ := statusReport(t, c, nil, nil)
+	assert.Equal(t, "Your OTP f*******************************e it.", text)
+
+	c.Set("info.env", "dev")
+	text, auditor := statusReport(t, c, &auth.Claims{Subject: "dev1"}, nil)
+	assert.Equal(t, "Your OTP for login is 482913. Do not share it.", text)
+	require.Len(t, auditor.accesses, 1)
+	assert.Equal(t, "dev1", auditor.accesses[0].UserID)
+}
+
+func TestRequireAdminScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c := config.NewConfig(viper.New())
+	c.Set("admin.scope", "admin")
+	secret := []byte("secret")
+
+	engine := gin.New()
+	engine.Use(auth.Authenticate(secret))
+	engine.GET("/v1/admin/ping", requireAdminScope(c), func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, adminUserID(ctx.Request.Context()))
+	})
+	call := func(headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/admin/ping", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+	bearer := func(scope string, key []byte) string {
+		return "Bearer " + auth.SignToken(auth.Claims{Subject: "ops1", Scope: scope, ExpiresAt: time.Now().Add(time.Hour).Unix()}, key)
+	}
+
+	rec := call(map[string]string{"Authorization": bearer("reports admin", secret)})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ops1", rec.Body.String())
+
+	assert.Equal(t, http.StatusForbidden, call(map[string]string{"X-User-Scope": "admin", "X-User-ID": "ops1"}).Code)
+	assert.Equal(t, http.StatusForbidden, call(map[string]string{"Authorization": bearer("reports", secret)}).Code)
+	assert.Equal(t, http.StatusUnauthorized, call(map[string]string{"Authorization": bearer("admin", []byte("forged"))}).Code)
+}
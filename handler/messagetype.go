@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"fmt"
+
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	"MgApplication/core/domain"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var MessageTypeMismatchesTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "sms_message_type_mismatches_total",
+		Help: "Total number of requests whose message_type did not match the characters of their text, by declared type and outcome",
+	},
+	[]string{"declared", "outcome"},
+)
+
+// Outcomes of a message type mismatch, the outcome label of MessageTypeMismatchesTotal
+const (
+	messageTypeCorrected = "corrected"
+	messageTypeRejected  = "rejected"
+)
+
+// checkMessageType checks the declared message type of msgreq against the characters of its
+// text, detected like messageSegments counts the segments: Unicode when a character is outside
+// the GSM 7-bit alphabet, its extension table included, plain otherwise. A message type that does
+// not match, a plain message with Hindi text that the gateway would mangle or a Unicode message
+// of plain text paying twice the segments, is replaced by the detected one with
+// sms.messagetype.autocorrect, the correction being added to the warnings of msgreq, and rejected
+// with a 422 naming the first offending character otherwise. A request without a message type is
+// left as is.
+func checkMessageType(c *config.Config, msgreq *domain.MsgRequest) error {
+	detected, _, _ := messageSegments(msgreq.MessageText)
+	declared := domain.MessageType(msgreq.MessageType)
+	if msgreq.MessageType == "" || declared == detected {
+		return nil
+	}
+
+	var reason string
+	if r, position, ok := firstNonGSM7(msgreq.MessageText); ok {
+		reason = fmt.Sprintf("character %q (%U) at position %d is outside the GSM 7-bit alphabet", r, r, position)
+	} else {
+		reason = "every character is in the GSM 7-bit alphabet"
+	}
+	if !c.GetBool("sms.messagetype.autocorrect") {
+		MessageTypeMismatchesTotal.WithLabelValues(string(declared), messageTypeRejected).Inc()
+		err := fmt.Errorf("message_type %s does not match the message text, %s: use message_type %s", declared, reason, detected)
+		return apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.AppErrorValidationError, err.Error(), err)
+	}
+	MessageTypeMismatchesTotal.WithLabelValues(string(declared), messageTypeCorrected).Inc()
+	msgreq.MessageType = string(detected)
+	msgreq.Warnings = append(msgreq.Warnings, fmt.Sprintf("message_type %s changed to %s, %s", declared, detected, reason))
+	return nil
+}
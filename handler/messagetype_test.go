@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	"MgApplication/core/domain"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckMessageType(t *testing.T) {
+	const (
+		ascii       = "Your article EG123456789IN is delivered - INDPOST"
+		devanagari  = "आपका पार्सल पहुँच गया है - INDPOST"
+		gsmExtended = "{Order} [42] ~ 100€ ^ price | total \\ INDPOST"
+		// the Devanagari character comes after plain and extended characters
+		mixed = "Price 100€ - धन्यवाद"
+	)
+	tests := []struct {
+		name        string
+		text        string
+		declared    string
+		want        domain.MessageType
+		mismatch    bool
+		wantMessage string // of the rejection
+	}{
+		{"ascii declared PM", ascii, "PM", domain.MessageTypePlain, false, ""},
+		{"ascii declared UC", ascii, "UC", domain.MessageTypePlain, true, "every character is in the GSM 7-bit alphabet"},
+		// an undeclared message type is left as is
+		{"ascii undeclared", ascii, "", domain.MessageTypePlain, false, ""},
+		{"devanagari declared UC", devanagari, "UC", domain.MessageTypeUnicode, false, ""},
+		{"devanagari declared PM", devanagari, "PM", domain.MessageTypeUnicode, true, `character 'आ' (U+0906) at position 1`},
+		{"devanagari undeclared", devanagari, "", domain.MessageTypeUnicode, false, ""},
+		{"gsm extended declared PM", gsmExtended, "PM", domain.MessageTypePlain, false, ""},
+		{"gsm extended declared UC", gsmExtended, "UC", domain.MessageTypePlain, true, "every character is in the GSM 7-bit alphabet"},
+		{"mixed declared PM", mixed, "PM", domain.MessageTypeUnicode, true, `character 'ध' (U+0927) at position 14`},
+	}
+
+	for _, autocorrect := range []bool{true, false} {
+		c := config.NewConfig(viper.New())
+		c.Set("sms.messagetype.autocorrect", autocorrect)
+		for _, tt := range tests {
+			name := tt.name + "/reject"
+			if autocorrect {
+				name = tt.name + "/autocorrect"
+			}
+			t.Run(name, func(t *testing.T) {
+				msgreq := &domain.MsgRequest{MessageText: tt.text, MessageType: tt.declared}
+				err := checkMessageType(c, msgreq)
+
+				// the detection agrees with the segment calculator
+				encoding, _, _ := messageSegments(tt.text)
+				assert.Equal(t, tt.want, encoding)
+
+				switch {
+				case !tt.mismatch:
+					require.NoError(t, err)
+					assert.Equal(t, tt.declared, msgreq.MessageType)
+					assert.Empty(t, msgreq.Warnings)
+				case autocorrect:
+					require.NoError(t, err)
+					assert.Equal(t, string(tt.want), msgreq.MessageType)
+					if assert.Len(t, msgreq.Warnings, 1) {
+						assert.Contains(t, msgreq.Warnings[0], "message_type "+tt.declared+" changed to "+string(tt.want))
+						assert.Contains(t, msgreq.Warnings[0], tt.wantMessage)
+					}
+				default:
+					appErr, ok := apierrors.Find[*apierrors.AppError](err)
+					require.True(t, ok, "expected an AppError, got %v", err)
+					assert.Equal(t, apierrors.AppErrorValidationError.StatusCode, appErr.Code)
+					assert.Contains(t, appErr.Message, tt.wantMessage)
+					assert.Contains(t, appErr.Message, "use message_type "+string(tt.want))
+					assert.Equal(t, tt.declared, msgreq.MessageType)
+				}
+			})
+		}
+	}
+}
+
+// A Hindi message declared PM is sent as Unicode with a warning, or rejected before it is sent
+func TestCreateSMSMessageTypeMismatch(t *testing.T) {
+	var messages []string
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		messages = append(messages, r.PostForm.Get("content"))
+		_, _ = w.Write([]byte("402,MsgID = 150920241726381202115"))
+	}))
+	defer cdac.Close()
+
+	body := otpRequestBody("9000000001")
+	body["priority"] = 2
+	body["message_type"] = "PM"
+	body["message_text"] = "आपका पार्सल पहुँच गया है - INDPOST"
+
+	c := brandingConfig(true)
+	c.Set("sms.cdac.url", cdac.URL)
+	c.Set("sms.messagetype.autocorrect", false)
+	ch, _ := newTestSMSHandler(c)
+	rec := postSMSRequest(ch, body)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "outside the GSM 7-bit alphabet")
+	assert.Empty(t, messages)
+
+	c.Set("sms.messagetype.autocorrect", true)
+	rec = postSMSRequest(ch, body)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var rsp struct {
+		Data struct {
+			Warnings []string `json:"warnings"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	if assert.Len(t, rsp.Data.Warnings, 1) {
+		assert.Contains(t, rsp.Data.Warnings[0], "message_type PM changed to UC")
+	}
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, UnicodemsgConvertCDAC("आपका पार्सल पहुँच गया है - INDPOST"), messages[0])
+	}
+}
@@ -0,0 +1,326 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "MgApplication/api-log"
+	"MgApplication/core/clock"
+	"MgApplication/core/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// msgStore stores message requests and the gateway responses to them, implemented by
+// repo.MgApplicationRepository. The writes of a request are grouped in short transactions opened
+// with WithMsgTx, none of which is open while the gateway is called.
+type msgStore interface {
+	SaveMsgRequestTx(gctx *context.Context, msgreq *domain.MsgRequest) (*domain.MsgRequest, error)
+	GetGateway(gctx *context.Context, msgreq *domain.MsgRequest) (*domain.MsgRequest, error)
+	WithMsgTx(fn func(tx pgx.Tx) error) error
+	SaveMsgRequestInTx(tx pgx.Tx, msgreq *domain.MsgRequest) error
+	SaveResponseInTx(tx pgx.Tx, msgRsp *domain.MsgResponse) error
+	SaveMsgRequestGatewayInTx(tx pgx.Tx, msgreq *domain.MsgRequest) error
+}
+
+var (
+	cdacErrorPattern   = regexp.MustCompile(`Error (\d+) : (.+)`)
+	cdacSuccessPattern = regexp.MustCompile(`^(\d{3}),MsgID = (\d+)`)
+	nicResponsePattern = regexp.MustCompile(`Request ID=(\d+)~code=([A-Z0-9-]+)`)
+)
+
+// errInvalidResponse is the failure of a CDAC error response that cannot be read
+var errInvalidResponse = errors.New("Invalid Response")
+
+// shouldPersist reports whether a request of priority is stored before it is sent, evaluated
+// once per request. Requests are sent and stored following this matrix:
+//
+//	                 sms.msgstorerequest=1 or priority 3, 4   otherwise
+//	sent             request and response stored              nothing stored
+//	failed           request and response stored              request and response stored after the failure
+//
+// Every outcome is answered, with the gateway response when the gateway accepted the message
+// and with the failure otherwise. A request with DoNotStore set is never stored, whatever its
+// outcome, only audited; the queued priorities 3 and 4 do not accept it.
+func (ch *MgApplicationHandler) shouldPersist(priority int) bool {
+	return ch.c.GetInt("sms.msgstorerequest") == 1 || domain.Priority(priority).Promotional()
+}
+
+// dispatchRequest sends msgreq through dispatch, once it is given its communication ID. An OTP
+// (priority 1) request repeating a send that succeeded within sms.otpcache.windowseconds, e.g.
+// the customer retrying while the gateway was slow, is answered with the response of that send
+// instead of a second OTP, cacheHit being set. Every path sending OTPs goes through it, so they share the cache. The text of msgreq is
+// first run through the message transformers, a rejected message being neither stored nor sent,
+// then its message type is checked against the transformed text by checkMessageType. The
+// recipients of a request about to be sent are counted against the hourly caps of its sender ID
+// and template, a request beyond them failing with a *SendCapThrottledError.
+//
+// ctx is the context of the request of the client. A request whose client closed the connection
+// before the send is neither stored nor sent, returning the cancellation: the client never learns
+// of the send and retries it. Once the send is issued it runs to completion without ctx, the
+// response being stored as usual, so that no message reaches the gateway untracked.
+func (ch *MgApplicationHandler) dispatchRequest(ctx context.Context, msgreq *domain.MsgRequest, persist bool) (msgresponse *domain.MsgResponse, cacheHit bool, err error) {
+	ch.assignCommunicationID(msgreq)
+	if err := transformMessage(ch.transforms, msgreq); err != nil {
+		log.Error(ctx, "Message of application %s rejected: %s", msgreq.ApplicationID, err.Error())
+		return nil, false, err
+	}
+	if err := checkMessageType(ch.c, msgreq); err != nil {
+		log.Warn(ctx, "Message of application %s rejected: %s", msgreq.ApplicationID, err.Error())
+		return nil, false, err
+	}
+	if domain.Priority(msgreq.Priority) == domain.PriorityOTP {
+		cached, claim, claimErr := ch.otpCache.Claim(ctx, msgreq)
+		if errors.Is(claimErr, context.Canceled) {
+			log.Warn(ctx, "Client of application %s closed the request while it waited for an identical OTP request", msgreq.ApplicationID)
+			return nil, false, claimErr
+		}
+		if claimErr != nil {
+			log.Error(ctx, "Waiting for an identical OTP request failed: %s", claimErr.Error())
+			return nil, false, claimErr
+		}
+		if cached != nil {
+			log.Debug(ctx, "OTP request answered with the send of %s", cached.CommunicationID)
+			return cached, true, nil
+		}
+		defer func() {
+			if err != nil {
+				claim.Done(nil)
+				return
+			}
+			claim.Done(msgresponse)
+		}()
+	}
+	if err := ctx.Err(); errors.Is(err, context.Canceled) {
+		log.Warn(ctx, "Client of application %s closed the request before it was sent, not sending it", msgreq.ApplicationID)
+		return nil, false, err
+	}
+	if err := ch.caps.Consume(ctx, *msgreq); err != nil {
+		log.Warn(ctx, "Request of application %s rejected: %s", msgreq.ApplicationID, err.Error())
+		return nil, false, err
+	}
+	msgresponse, err = ch.dispatch(msgreq, persist)
+	if msgresponse != nil && errors.Is(ctx.Err(), context.Canceled) {
+		log.Warn(ctx, "Client of application %s closed the request while %s was sent, its outcome is recorded all the same", msgreq.ApplicationID, msgreq.CommunicationID)
+	}
+	return msgresponse, false, err
+}
+
+// dispatch sends msgreq and stores it following shouldPersist, persist being its value for
+// msgreq. It returns the gateway response, with the error of the send when the gateway did not
+// accept the message. A nil response means the request was not sent, because it could not be
+// stored or its gateway could not be found. The time of day routing applies to every send, and
+// the message type is checked against the resolved gateway by checkGatewayMessageType, a request
+// rejected by it being neither stored nor sent.
+func (ch *MgApplicationHandler) dispatch(msgreq *domain.MsgRequest, persist bool) (*domain.MsgResponse, error) {
+	ch.routeRequest(msgreq)
+	if msgreq.DoNotStore {
+		return ch.sendUnstored(msgreq)
+	}
+	if persist {
+		return ch.sendStored(msgreq, func() (*domain.MsgResponse, error) {
+			return ch.sendRequest(msgreq)
+		})
+	}
+
+	gctx := context.Background()
+	if _, err := ch.store.GetGateway(&gctx, msgreq); err != nil {
+		log.Error(nil, "DB Error in GetGateway: %s", err.Error())
+		return nil, err
+	}
+	if _, err := ch.checkGatewayMessageType(msgreq); err != nil {
+		return nil, err
+	}
+	msgresponse, sendErr := ch.sendRequest(msgreq)
+	if sendErr == nil {
+		return msgresponse, nil
+	}
+	// failures are always stored, with the request stored after the failure
+	if err := ch.storeSent(msgreq, msgresponse); err != nil {
+		log.Error(nil, "DB Error storing the failed request: %s", err.Error())
+		ch.bufferOutcome(bufferedOutcome{
+			Request:  &bufferedRequest{MsgRequest: *msgreq, ClientReference: msgreq.ClientReference, Metadata: msgreq.Metadata, Transformations: msgreq.Transformations},
+			Response: *msgresponse,
+		})
+	}
+	return msgresponse, sendErr
+}
+
+// sendStored stores msgreq as pending, runs send and stores the response it returns as the outcome
+// of the request. The request and the response are stored in separate short transactions, so
+// that no transaction is held open during the gateway call. The pending request records the
+// intent to send, and a request whose response could not be stored stays pending, listed by the
+// orphaned requests report.
+//
+// It returns the response with the error of the send. A nil response means nothing was sent, the
+// error being the reason. When the request could not be stored it is not sent, nor when the
+// gateway it is stored with does not support its message type; a request rerouted by the message
+// type failover is stored with the failover gateway, in the same transaction. When send returns
+// no response, the request is closed with the failure. A response that could not be stored once
+// sent is kept in the response buffer until it is stored, and the response is still returned so
+// that a client is never told to retry a message the gateway accepted.
+func (ch *MgApplicationHandler) sendStored(msgreq *domain.MsgRequest, send func() (*domain.MsgResponse, error)) (*domain.MsgResponse, error) {
+	if err := ch.store.WithMsgTx(func(tx pgx.Tx) error {
+		if err := ch.store.SaveMsgRequestInTx(tx, msgreq); err != nil {
+			return err
+		}
+		rerouted, err := ch.checkGatewayMessageType(msgreq)
+		if err != nil || !rerouted {
+			return err
+		}
+		return ch.store.SaveMsgRequestGatewayInTx(tx, msgreq)
+	}); err != nil {
+		log.Error(nil, "DB Error in SaveMsgRequestInTx: %s", err.Error())
+		return nil, err
+	}
+
+	msgresponse, sendErr := send()
+	outcome := msgresponse
+	if outcome == nil {
+		failed, _ := parseGatewayResponse(domain.GatewayID(msgreq.Gateway), "", sendErr)
+		outcome = &failed
+	}
+	outcome.CommunicationID = msgreq.CommunicationID
+	if err := ch.store.WithMsgTx(func(tx pgx.Tx) error {
+		return ch.store.SaveResponseInTx(tx, outcome)
+	}); err != nil {
+		log.Error(nil, "DB Error storing the response of gateway %s to %s, left pending: %s", msgreq.Gateway, msgreq.CommunicationID, err.Error())
+		ch.bufferOutcome(bufferedOutcome{Response: *outcome})
+	}
+	return msgresponse, sendErr
+}
+
+// sendUnstored sends msgreq without storing it nor the response, whatever the outcome, the
+// caller having asked for it. The send is audited without the text and the mobile numbers.
+func (ch *MgApplicationHandler) sendUnstored(msgreq *domain.MsgRequest) (*domain.MsgResponse, error) {
+	gctx := context.Background()
+	if _, err := ch.store.GetGateway(&gctx, msgreq); err != nil {
+		log.Error(nil, "DB Error in GetGateway: %s", err.Error())
+		return nil, err
+	}
+	if _, err := ch.checkGatewayMessageType(msgreq); err != nil {
+		return nil, err
+	}
+	msgresponse, sendErr := ch.sendRequest(msgreq)
+	auditUnstoredSend(msgreq, msgresponse)
+	return msgresponse, sendErr
+}
+
+// auditUnstoredSend writes the audit entry of a send that was not stored, without the text and
+// the mobile numbers
+func auditUnstoredSend(msgreq *domain.MsgRequest, msgresponse *domain.MsgResponse) {
+	log.InfoEvent(nil).
+		Str("audit", "message_not_stored").
+		Str("application_id", msgreq.ApplicationID).
+		Str("template_id", msgreq.TemplateID).
+		Str("gateway", msgreq.Gateway).
+		Int("priority", msgreq.Priority).
+		Int("recipients", len(strings.Split(msgreq.MobileNumbers, ","))).
+		Str("response_code", msgresponse.ResponseCode).
+		Str("reference_id", msgresponse.ReferenceID).
+		Time("sent_at", clock.Now()).
+		Msg("Message sent without being stored")
+}
+
+// bufferOutcome keeps the outcome of a send that could not be stored in the response buffer
+func (ch *MgApplicationHandler) bufferOutcome(outcome bufferedOutcome) {
+	outcome.BufferedAt = time.Now()
+	if err := ch.buffer.Add(outcome); err != nil {
+		log.Error(nil, "Response of %s lost, it could not be buffered: %s", outcome.Response.CommunicationID, err.Error())
+	}
+}
+
+// storeSent stores msgreq, sent before it was stored, with the response to it in one transaction
+func (ch *MgApplicationHandler) storeSent(msgreq *domain.MsgRequest, msgresponse *domain.MsgResponse) error {
+	return ch.store.WithMsgTx(func(tx pgx.Tx) error {
+		if err := ch.store.SaveMsgRequestInTx(tx, msgreq); err != nil {
+			return err
+		}
+		msgresponse.CommunicationID = msgreq.CommunicationID
+		return ch.store.SaveResponseInTx(tx, msgresponse)
+	})
+}
+
+// sendRequest sends msgreq through its gateway, without storing anything. It returns the gateway
+// response, with the error of the send when the gateway did not accept the message.
+func (ch *MgApplicationHandler) sendRequest(msgreq *domain.MsgRequest) (*domain.MsgResponse, error) {
+	rsp, sendErr := ch.sendSMS(msgreq.Gateway, *msgreq)
+	if sendErr == nil {
+		ch.shadow.Dispatch(*msgreq, msgreq.Gateway, rsp)
+	}
+	log.Debug(nil, "Response from gateway %s is : %s", msgreq.Gateway, rsp)
+	msgresponse, sendErr := parseGatewayResponse(domain.GatewayID(msgreq.Gateway), rsp, sendErr)
+	msgresponse.CommunicationID = msgreq.CommunicationID
+	if sendErr != nil {
+		if hint := credentialExpiryHint(ch.c, domain.GatewayID(msgreq.Gateway), *msgreq, msgresponse, clock.Now()); hint != "" {
+			log.Error(nil, "Gateway %s rejected the credentials of %s, %s", msgreq.Gateway, msgreq.CommunicationID, hint)
+			msgresponse.ResponseText += " (" + hint + ")"
+			sendErr = fmt.Errorf("%w (%s)", sendErr, hint)
+		}
+	}
+	ch.health.Record(domain.GatewayID(msgreq.Gateway), sendErr == nil)
+	return &msgresponse, sendErr
+}
+
+// parseGatewayResponse reads the outcome of a send from the raw response of gateway. The error
+// is set when the send failed or the gateway did not accept the message. It is the only reader
+// of gateway responses, so that sends, OTPs and shadow copies are recorded alike.
+func parseGatewayResponse(gateway domain.GatewayID, rsp string, sendErr error) (domain.MsgResponse, error) {
+	msgresponse := domain.MsgResponse{CompleteResponse: rsp}
+	if sendErr != nil {
+		if isGatewayTimeout(sendErr) {
+			sendErr = &GatewayTimeoutError{Gateway: gateway, Err: sendErr}
+		}
+		msgresponse.ResponseCode = "02"
+		msgresponse.ResponseText = sendErr.Error()
+		switch sendErr.(type) {
+		case *GatewayRateLimitedError:
+			msgresponse.ResponseCode = strconv.Itoa(http.StatusTooManyRequests)
+		case *GatewayTimeoutError:
+			msgresponse.ResponseCode = gatewayTimeoutResponseCode
+		case *GatewayMaintenanceError:
+			msgresponse.ResponseCode = gatewayMaintenanceResponseCode
+		}
+		// NIC returns rejections as errors carrying the gateway response
+		if matches := nicResponsePattern.FindStringSubmatch(sendErr.Error()); gateway == domain.GatewayNIC && len(matches) >= 3 {
+			msgresponse.ResponseCode = matches[2]
+		}
+		return msgresponse, sendErr
+	}
+
+	switch gateway {
+	case domain.GatewayCDAC:
+		if strings.HasPrefix(rsp, "Error") {
+			matches := cdacErrorPattern.FindStringSubmatch(rsp)
+			if len(matches) < 3 {
+				msgresponse.ResponseCode = "400"
+				msgresponse.ResponseText = errInvalidResponse.Error()
+				return msgresponse, errInvalidResponse
+			}
+			msgresponse.ResponseCode = matches[1]
+			msgresponse.ResponseText = matches[2]
+			return msgresponse, CustomError{Message: "401, " + matches[2]}
+		}
+		// accepted, without a message id when the response has an unexpected format
+		msgresponse.ResponseCode = "402"
+		msgresponse.ResponseText = "Submitted Successfully"
+		if matches := cdacSuccessPattern.FindStringSubmatch(rsp); len(matches) >= 3 {
+			msgresponse.ResponseCode = matches[1]
+			msgresponse.ReferenceID = matches[2]
+		}
+	case domain.GatewayNIC:
+		// SendSMSNIC fails unless NIC accepted the message
+		msgresponse.ResponseText = "Submitted Successfully"
+		if matches := nicResponsePattern.FindStringSubmatch(rsp); len(matches) >= 3 {
+			msgresponse.ReferenceID = matches[1]
+			msgresponse.ResponseCode = matches[2]
+		}
+	}
+	return msgresponse, nil
+}
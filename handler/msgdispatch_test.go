@@ -0,0 +1,449 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	config "MgApplication/api-config"
+	serverResponse "MgApplication/api-server/response"
+	"MgApplication/core/domain"
+	"MgApplication/core/idgen"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMsgStore records the calls of the handler instead of storing. The writes of a WithMsgTx
+// transaction are kept only when it commits.
+type fakeMsgStore struct {
+	savedRequests  int
+	savedResponses []domain.MsgResponse
+	// lastRequest is the last request stored
+	lastRequest domain.MsgRequest
+
+	// failures injected at the steps of a WithMsgTx transaction, failCommit failing the commit of
+	// the transactions storing a response
+	failRequest  error
+	failResponse error
+	failCommit   error
+}
+
+func (s *fakeMsgStore) SaveMsgRequestTx(gctx *context.Context, msgreq *domain.MsgRequest) (*domain.MsgRequest, error) {
+	s.savedRequests++
+	msgreq.Gateway = templateGateway(msgreq)
+	if msgreq.CommunicationID == "" {
+		msgreq.CommunicationID = fmt.Sprintf("COMM%d", s.savedRequests)
+	}
+	return msgreq, nil
+}
+
+// assertULID asserts that id is a communication ID of the ULID generator, the one a request is
+// given when received
+func assertULID(t *testing.T, id string) {
+	t.Helper()
+	_, err := idgen.ParseULID(id)
+	assert.NoError(t, err, "communication ID %q", id)
+}
+
+// templateGateway returns the gateway of the template of msgreq, CDAC, unless it is routed
+func templateGateway(msgreq *domain.MsgRequest) string {
+	if msgreq.RouteGateway != "" {
+		return msgreq.RouteGateway
+	}
+	return string(domain.GatewayCDAC)
+}
+
+func (s *fakeMsgStore) GetGateway(gctx *context.Context, msgreq *domain.MsgRequest) (*domain.MsgRequest, error) {
+	msgreq.Gateway = templateGateway(msgreq)
+	return msgreq, nil
+}
+
+func (s *fakeMsgStore) WithMsgTx(fn func(tx pgx.Tx) error) error {
+	requests, responses := s.savedRequests, len(s.savedResponses)
+	committed := false
+	defer func() {
+		if !committed {
+			s.savedRequests, s.savedResponses = requests, s.savedResponses[:responses]
+		}
+	}()
+	if err := fn(nil); err != nil {
+		return err
+	}
+	if s.failCommit != nil && len(s.savedResponses) > responses {
+		return s.failCommit
+	}
+	committed = true
+	return nil
+}
+
+func (s *fakeMsgStore) SaveMsgRequestGatewayInTx(tx pgx.Tx, msgreq *domain.MsgRequest) error {
+	s.lastRequest.Gateway = msgreq.Gateway
+	return nil
+}
+
+func (s *fakeMsgStore) SaveMsgRequestInTx(tx pgx.Tx, msgreq *domain.MsgRequest) error {
+	if s.failRequest != nil {
+		return s.failRequest
+	}
+	_, err := s.SaveMsgRequestTx(nil, msgreq)
+	s.lastRequest = *msgreq
+	return err
+}
+
+func (s *fakeMsgStore) SaveResponseInTx(tx pgx.Tx, msgRsp *domain.MsgResponse) error {
+	if s.failResponse != nil {
+		return s.failResponse
+	}
+	s.savedResponses = append(s.savedResponses, *msgRsp)
+	return nil
+}
+
+func TestDispatchSMSPersistenceMatrix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	outcomes := []struct {
+		name     string
+		status   int    // of the CDAC server
+		reply    string // of the CDAC server
+		code     int    // of the handler
+		rspCode  string // stored response code
+		accepted bool
+	}{
+		{"accepted", http.StatusOK, "402,MsgID = 150920241726381202115", http.StatusCreated, "402", true},
+		{"accepted without message id", http.StatusOK, "402,Submitted", http.StatusCreated, "402", true},
+		{"rejected", http.StatusOK, "Error 401 : Invalid credentials", http.StatusInternalServerError, "401", false},
+		{"invalid response", http.StatusOK, "Error", http.StatusInternalServerError, "400", false},
+		{"unavailable", http.StatusServiceUnavailable, "", http.StatusInternalServerError, "02", false},
+	}
+
+	for _, outcome := range outcomes {
+		cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(outcome.status)
+			_, _ = w.Write([]byte(outcome.reply))
+		}))
+		for _, priority := range domain.Priorities {
+			for _, flag := range []int{0, 1} {
+				t.Run(fmt.Sprintf("%s/priority %d/msgstorerequest %d", outcome.name, priority, flag), func(t *testing.T) {
+					c := config.NewConfig(viper.New())
+					c.Set("sms.msgstorerequest", flag)
+					c.Set("sms.cdac.url", cdac.URL)
+					store := &fakeMsgStore{}
+					ch := &MgApplicationHandler{c: c, store: store}
+
+					rec := httptest.NewRecorder()
+					ctx, _ := gin.CreateTestContext(rec)
+					ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/sms-request", nil)
+					ch.dispatchSMS(ctx, domain.MsgRequest{
+						ApplicationID: "7",
+						Priority:      int(priority),
+						MessageText:   "Your article is delivered - INDPOST",
+						SenderID:      "INPOST",
+						MobileNumbers: "9000000001",
+						TemplateID:    "1007344609998507114",
+					})
+
+					persist := flag == 1 || priority.Promotional()
+					assert.Equal(t, outcome.code, rec.Code, rec.Body.String())
+					if persist || !outcome.accepted {
+						assert.Equal(t, 1, store.savedRequests)
+						if assert.Len(t, store.savedResponses, 1) {
+							assertULID(t, store.savedResponses[0].CommunicationID)
+							assert.Equal(t, store.lastRequest.CommunicationID, store.savedResponses[0].CommunicationID)
+							assert.Equal(t, outcome.rspCode, store.savedResponses[0].ResponseCode)
+						}
+					} else {
+						assert.Zero(t, store.savedRequests)
+						assert.Empty(t, store.savedResponses)
+					}
+				})
+			}
+		}
+		cdac.Close()
+	}
+}
+
+func TestCreateSMSDoNotStore(t *testing.T) {
+	for reply, code := range map[string]int{
+		"402,MsgID = 150920241726381202115": http.StatusCreated,
+		"Error 401 : Invalid credentials":   http.StatusInternalServerError,
+	} {
+		cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(reply))
+		}))
+		c := brandingConfig(true)
+		c.Set("sms.msgstorerequest", 1)
+		c.Set("sms.cdac.url", cdac.URL)
+
+		for _, priority := range []int{1, 2} {
+			ch, store := newTestSMSHandler(c)
+			body := otpRequestBody("9000000001")
+			body["priority"] = priority
+			body["do_not_store"] = true
+			rec := postSMSRequest(ch, body)
+			assert.Equal(t, code, rec.Code, rec.Body.String())
+			assert.Equal(t, 0, store.savedRequests, "%s, priority %d", reply, priority)
+			assert.Empty(t, store.savedResponses, "%s, priority %d", reply, priority)
+		}
+		cdac.Close()
+	}
+
+	// queued priorities are always stored
+	ch, store := newTestSMSHandler(brandingConfig(true))
+	body := otpRequestBody("9000000001")
+	body["priority"] = 3
+	body["do_not_store"] = true
+	rec := postSMSRequest(ch, body)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), errDoNotStoreQueued.Error())
+	assert.Equal(t, 0, store.savedRequests)
+}
+
+// TestDispatchRequestFailuresLeaveTheRequestPending stops the storing of a request at each of its
+// steps. A request is only sent once it is stored, and stays pending when its response cannot be
+// stored.
+func TestDispatchRequestFailuresLeaveTheRequestPending(t *testing.T) {
+	injected := errors.New("injected failure")
+	cases := []struct {
+		name      string
+		reply     string // of the CDAC server
+		fail      func(s *fakeMsgStore)
+		sent      bool // whether the request reached the gateway
+		responses int  // stored, the request being pending without one
+	}{
+		{"accepted", "402,MsgID = 150920241726381202115", func(s *fakeMsgStore) {}, true, 1},
+		{"rejected", "Error 401 : Invalid credentials", func(s *fakeMsgStore) {}, true, 1},
+		{"request not stored", "402,MsgID = 150920241726381202115", func(s *fakeMsgStore) { s.failRequest = injected }, false, 0},
+		{"response not stored", "402,MsgID = 150920241726381202115", func(s *fakeMsgStore) { s.failResponse = injected }, true, 0},
+		{"response of a rejection not stored", "Error 401 : Invalid credentials", func(s *fakeMsgStore) { s.failResponse = injected }, true, 0},
+		{"commit of the response failed", "402,MsgID = 150920241726381202115", func(s *fakeMsgStore) { s.failCommit = injected }, true, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				_, _ = w.Write([]byte(tc.reply))
+			}))
+			defer cdac.Close()
+
+			c := config.NewConfig(viper.New())
+			c.Set("sms.cdac.url", cdac.URL)
+			store := &fakeMsgStore{}
+			tc.fail(store)
+			ch := &MgApplicationHandler{c: c, store: store}
+
+			msgresponse, _, err := ch.dispatchRequest(context.Background(), &domain.MsgRequest{
+				ApplicationID: "7",
+				Priority:      int(domain.PriorityOTP),
+				MessageText:   "Your article is delivered - INDPOST",
+				SenderID:      "INPOST",
+				MobileNumbers: "9000000001",
+				TemplateID:    "1007344609998507114",
+			}, true)
+
+			assert.Equal(t, tc.sent, calls == 1)
+			assert.Equal(t, tc.sent, msgresponse != nil)
+			if !tc.sent {
+				assert.ErrorIs(t, err, injected)
+				assert.Zero(t, store.savedRequests)
+			} else {
+				assert.Equal(t, 1, store.savedRequests)
+			}
+			assert.Len(t, store.savedResponses, tc.responses)
+		})
+	}
+}
+
+func TestDispatchRequestAnswersRepeatedOTPsFromTheCache(t *testing.T) {
+	calls := 0
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte("402,MsgID = 150920241726381202115"))
+	}))
+	defer cdac.Close()
+
+	c := config.NewConfig(viper.New())
+	c.Set("sms.cdac.url", cdac.URL)
+	ch := &MgApplicationHandler{c: c, store: &fakeMsgStore{}, otpCache: NewOTPSendCache(c)}
+	request := func(priority domain.Priority) domain.MsgRequest {
+		return domain.MsgRequest{
+			ApplicationID: "7",
+			Priority:      int(priority),
+			MessageText:   "Your OTP is 482913 - INDPOST",
+			SenderID:      "INPOST",
+			MobileNumbers: "9000000001",
+			TemplateID:    "1007344609998507114",
+		}
+	}
+
+	first := request(domain.PriorityOTP)
+	sent, cacheHit, err := ch.dispatchRequest(context.Background(), &first, false)
+	assert.NoError(t, err)
+	assert.False(t, cacheHit)
+
+	repeated := request(domain.PriorityOTP)
+	cached, cacheHit, err := ch.dispatchRequest(context.Background(), &repeated, false)
+	assert.NoError(t, err)
+	assert.True(t, cacheHit)
+	assert.Same(t, sent, cached)
+	assert.Equal(t, 1, calls)
+
+	// only OTPs are answered from the cache
+	transactional := request(domain.PriorityTransactional)
+	_, cacheHit, err = ch.dispatchRequest(context.Background(), &transactional, false)
+	assert.NoError(t, err)
+	assert.False(t, cacheHit)
+	assert.Equal(t, 2, calls)
+}
+
+func TestSendStoredLeavesTheRequestPendingWhenTheSendPanics(t *testing.T) {
+	store := &fakeMsgStore{}
+	ch := &MgApplicationHandler{c: config.NewConfig(viper.New()), store: store}
+
+	assert.Panics(t, func() {
+		_, _ = ch.sendStored(&domain.MsgRequest{ApplicationID: "7"}, func() (*domain.MsgResponse, error) {
+			panic("process killed")
+		})
+	})
+	assert.Equal(t, 1, store.savedRequests)
+	assert.Empty(t, store.savedResponses)
+
+	// nothing sent, the request is closed with the failure
+	msgresponse, err := ch.sendStored(&domain.MsgRequest{ApplicationID: "7"}, func() (*domain.MsgResponse, error) {
+		return nil, errors.New("invalid gateway")
+	})
+	assert.Nil(t, msgresponse)
+	assert.EqualError(t, err, "invalid gateway")
+	assert.Equal(t, 2, store.savedRequests)
+	if assert.Len(t, store.savedResponses, 1) {
+		assert.Equal(t, "COMM2", store.savedResponses[0].CommunicationID)
+		assert.Equal(t, "02", store.savedResponses[0].ResponseCode)
+	}
+}
+
+func TestParseGatewayResponse(t *testing.T) {
+	cases := []struct {
+		gateway     domain.GatewayID
+		rsp         string
+		code        string
+		referenceID string
+		err         string
+	}{
+		{domain.GatewayCDAC, "402,MsgID = 150920241726381202115hpgovsms", "402", "150920241726381202115", ""},
+		{domain.GatewayCDAC, "Error 401 : Invalid credentials", "401", "", "{Message: 401, Invalid credentials}"},
+		{domain.GatewayCDAC, "Err", "402", "", ""},
+		{domain.GatewayCDAC, "Error", "400", "", "Invalid Response"},
+		{domain.GatewayNIC, "Message Accepted for Request ID=123020250306~code=API000", "API000", "123020250306", ""},
+	}
+	for _, tc := range cases {
+		msgresponse, err := parseGatewayResponse(tc.gateway, tc.rsp, nil)
+		assert.Equal(t, tc.code, msgresponse.ResponseCode, tc.rsp)
+		assert.Equal(t, tc.referenceID, msgresponse.ReferenceID, tc.rsp)
+		assert.Equal(t, tc.rsp, msgresponse.CompleteResponse)
+		if tc.err == "" {
+			assert.NoError(t, err, tc.rsp)
+		} else {
+			assert.EqualError(t, err, tc.err, tc.rsp)
+		}
+	}
+
+	msgresponse, err := parseGatewayResponse(domain.GatewayNIC, "", fmt.Errorf("timeout"))
+	assert.EqualError(t, err, "timeout")
+	assert.Equal(t, "02", msgresponse.ResponseCode)
+	assert.Equal(t, "timeout", msgresponse.ResponseText)
+
+	// NIC rejections are errors carrying the gateway response
+	rejection := fmt.Errorf("unexpected response from sms gateway: Request ID=123020250306~code=API-401")
+	msgresponse, err = parseGatewayResponse(domain.GatewayNIC, "", rejection)
+	assert.Equal(t, rejection, err)
+	assert.Equal(t, "API-401", msgresponse.ResponseCode)
+	assert.Empty(t, msgresponse.ReferenceID)
+}
+
+// TestDispatchSMSClientAborted closes the connection of the client before the gateway call and
+// during it. A request is not sent once its client left, and a send already issued is recorded.
+func TestDispatchSMSClientAborted(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newAbortedRequest := func(c *gin.Context) context.CancelFunc {
+		ctx, cancel := context.WithCancel(context.Background())
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/sms-request", nil).WithContext(ctx)
+		return cancel
+	}
+	msgreq := domain.MsgRequest{
+		ApplicationID: "7",
+		Priority:      int(domain.PriorityOTP),
+		MessageText:   "Your article is delivered - INDPOST",
+		SenderID:      "INPOST",
+		MobileNumbers: "9000000001",
+		TemplateID:    "1007344609998507114",
+	}
+
+	t.Run("before the gateway call", func(t *testing.T) {
+		calls := 0
+		cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			_, _ = w.Write([]byte("402,MsgID = 150920241726381202115"))
+		}))
+		defer cdac.Close()
+		c := config.NewConfig(viper.New())
+		c.Set("sms.msgstorerequest", 1)
+		c.Set("sms.cdac.url", cdac.URL)
+		store := &fakeMsgStore{}
+		ch := &MgApplicationHandler{c: c, store: store}
+
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		newAbortedRequest(ctx)()
+		ch.dispatchSMS(ctx, msgreq)
+
+		assert.Zero(t, calls)
+		assert.Zero(t, store.savedRequests)
+		assert.Empty(t, store.savedResponses)
+		assert.True(t, serverResponse.ClientAborted(ctx))
+		assert.Equal(t, serverResponse.StatusClientClosedRequest, rec.Code)
+	})
+
+	for _, tc := range []struct {
+		name    string
+		flag    int    // sms.msgstorerequest
+		reply   string // of the CDAC server
+		rspCode string
+	}{
+		{"during the gateway call/stored", 1, "402,MsgID = 150920241726381202115", "402"},
+		{"during the gateway call/rejected", 0, "Error 401 : Invalid credentials", "401"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(rec)
+			cancel := newAbortedRequest(ctx)
+			calls := 0
+			cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				cancel()
+				_, _ = w.Write([]byte(tc.reply))
+			}))
+			defer cdac.Close()
+			c := config.NewConfig(viper.New())
+			c.Set("sms.msgstorerequest", tc.flag)
+			c.Set("sms.cdac.url", cdac.URL)
+			store := &fakeMsgStore{}
+			ch := &MgApplicationHandler{c: c, store: store}
+
+			ch.dispatchSMS(ctx, msgreq)
+
+			assert.Equal(t, 1, calls)
+			assert.Equal(t, 1, store.savedRequests)
+			if assert.Len(t, store.savedResponses, 1) {
+				assertULID(t, store.savedResponses[0].CommunicationID)
+				assert.Equal(t, tc.rspCode, store.savedResponses[0].ResponseCode)
+			}
+			assert.True(t, serverResponse.ClientAborted(ctx))
+			assert.Equal(t, serverResponse.StatusClientClosedRequest, serverResponse.ResponseStatus(ctx))
+		})
+	}
+}
@@ -18,7 +18,9 @@ package handler
 
 import (
 	"MgApplication/core/domain"
+	"MgApplication/core/idgen"
 	"MgApplication/core/port"
+	"MgApplication/core/transform"
 	"MgApplication/handler/response"
 	repo "MgApplication/repo/postgres"
 	"bytes"
@@ -35,6 +37,7 @@ import (
 	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/url"
@@ -44,6 +47,7 @@ import (
 	config "MgApplication/api-config"
 	apierrors "MgApplication/api-errors"
 	log "MgApplication/api-log"
+	serverResponse "MgApplication/api-server/response"
 	validation "MgApplication/api-validation"
 
 	"github.com/gin-gonic/gin"
@@ -51,16 +55,77 @@ import (
 
 // MgApplication Handler represents the HTTP handler for MgApplication related requests
 type MgApplicationHandler struct {
-	svc *repo.MgApplicationRepository
-	c   *config.Config
+	svc       *repo.MgApplicationRepository
+	c         *config.Config
+	dnd       *DNDFilter
+	shadow    *ShadowDispatcher
+	branding  *SenderBranding
+	otpCache  *OTPSendCache
+	simulator *GatewaySimulator
+	// errorSimulation answers sends to reserved mobile numbers with simulated gateway errors
+	errorSimulation *GatewayErrorSimulation
+	// credentials are the tenant credential sets requests may be sent with
+	credentials *CredentialSets
+	// throttle holds back the sends through a gateway that rate limited a send
+	throttle *gatewayThrottle
+	// health tracks the last send accepted by each gateway
+	health *GatewayHealth
+	// transforms clean up the text of the messages before they are sent
+	transforms *transform.Pipeline
+	// retry is the retry policy of the calls to the gateways and of the test sends
+	retry outboundRetry
+	// routing sends the requests through a gateway by the time of day
+	routing *GatewayRouting
+	// buffer keeps the outcomes of sends that could not be stored until they are
+	buffer *ResponseBuffer
+	// quota caps the messages sent per application and day
+	quota *SendQuota
+	// caps caps the messages sent per sender ID and template and hour
+	caps *SendCaps
+	// messageTypes are the message types each gateway supports
+	messageTypes *GatewayMessageTypes
+	// deliveryWait holds the OTP requests asking for it until their message is delivered
+	deliveryWait *DeliveryWait
+	store        msgStore
+	// statuses reads the latest responses of the requests for the bulk status checks
+	statuses statusStore
+	// ids generates the communication IDs of the requests
+	ids idgen.Generator
+}
+
+// statusStore reads the latest gateway responses of many requests at once, implemented by
+// repo.MgApplicationRepository
+type statusStore interface {
+	FetchLatestResponsesByCommunicationIDsRepo(gctx *gin.Context, communicationIDs []string) (map[string]domain.MsgResponse, error)
 }
 
 // MgApplication Handler creates a new MgApplicatPion Handler instance
-func NewMgApplicationHandler(svc *repo.MgApplicationRepository, c *config.Config) *MgApplicationHandler {
-	return &MgApplicationHandler{
-		svc,
-		c,
+func NewMgApplicationHandler(svc *repo.MgApplicationRepository, dnd *DNDFilter, c *config.Config) *MgApplicationHandler {
+	ch := &MgApplicationHandler{
+		svc:             svc,
+		c:               c,
+		dnd:             dnd,
+		branding:        NewSenderBranding(c),
+		otpCache:        NewOTPSendCache(c),
+		simulator:       NewGatewaySimulator(c),
+		errorSimulation: NewGatewayErrorSimulation(c),
+		credentials:     NewCredentialSets(c),
+		throttle:        newGatewayThrottle(),
+		health:          NewGatewayHealth(c),
+		transforms:      newMessagePipeline(c),
+		retry:           newOutboundRetry(c),
+		routing:         newGatewayRouting(c),
+		buffer:          NewResponseBuffer(c),
+		quota:           NewSendQuota(svc, c),
+		caps:            NewSendCaps(svc, c),
+		messageTypes:    newGatewayMessageTypes(c),
+		deliveryWait:    NewDeliveryWait(c),
+		store:           svc,
+		statuses:        svc,
+		ids:             newCommunicationIDs(c),
 	}
+	ch.shadow = NewShadowDispatcher(svc, ch.sendSMS, c)
+	return ch
 }
 
 // HTML numeric character references
@@ -82,36 +147,86 @@ func UnicodemsgConvertNIC(message string) string {
 }
 
 type createSMSRequest struct {
-	RequestID     uint64 `json:"reqid"`
-	ApplicationID string `json:"application_id" validate:"required" example:"4"`
-	FacilityID    string `json:"facility_id" validate:"required" example:"facility1"`
-	Priority      int    `json:"priority" validate:"required" example:"1"`
-	MessageText   string `json:"message_text" validate:"required" example:"Your OTP is : 1342789 for Account_Creation. Please keep it for further references"`
-	SenderID      string `json:"sender_id" validate:"required" example:"INPOST"`
-	MobileNumbers string `json:"mobile_numbers" validate:"required" example:"9000000000"`
-	EntityId      string `json:"entity_id" example:"1301157641566214705"`
-	TemplateID    string `json:"template_id" validate:"required" example:"1307160377410448739"`
-	MessageType   string `json:"message_type" example:"PM"`
+	RequestID     uint64        `json:"reqid"`
+	ApplicationID port.StringID `json:"application_id" validate:"required" swaggertype:"string" pattern:"^[0-9]+$" example:"4"`
+	FacilityID    string        `json:"facility_id" validate:"required" example:"facility1"`
+	Priority      int           `json:"priority" validate:"required,priority" enum:"1,2,3,4" example:"1"`
+	// MessageText must have content once normalized for every priority: OTP and transactional
+	// requests are sent at once, promotional and bulk ones by the Kafka consumer, none is only stored
+	MessageText     string        `json:"message_text" validate:"required,message_text" example:"Your OTP is : 1342789 for Account_Creation. Please keep it for further references"`
+	SenderID        string        `json:"sender_id" validate:"required" example:"INPOST"`
+	MobileNumbers   string        `json:"mobile_numbers" validate:"required,mobile_number_list" example:"9000000000"`
+	EntityId        port.StringID `json:"entity_id" swaggertype:"string" pattern:"^[0-9]+$" example:"1301157641566214705"`
+	TemplateID      port.StringID `json:"template_id" validate:"required" swaggertype:"string" pattern:"^[0-9]+$" example:"1307160377410448739"`
+	MessageType     string        `json:"message_type" validate:"omitempty,message_type" enum:"PM,UC" example:"PM"`
+	ClientReference string        `json:"client_reference" validate:"omitempty,max=64,string_field=64" example:"BKG20240001"`
+	Metadata        smsMetadata   `json:"metadata" swaggertype:"object,string"`
+	// CredentialSet sends the request with the credentials of a tenant configured in
+	// sms.credentialsets, for OTP and transactional priorities only
+	CredentialSet string `json:"credential_set" validate:"omitempty,max=64" example:"tenant1"`
+	// DoNotStore sends a privacy sensitive message without storing the request nor the gateway
+	// response, even with sms.msgstorerequest set to 1, an audit entry without the text and the
+	// mobile numbers being logged instead. It applies to the failed sends too. Promotional and bulk
+	// requests are always stored, they are rejected with it.
+	DoNotStore bool `json:"do_not_store" example:"false"`
+	// WaitForDeliveryMs holds an OTP request once the gateway accepted its message, until the
+	// message is delivered or failed, for at most these milliseconds capped by
+	// sms.deliverywait.max. The response gives the delivery_status reached.
+	WaitForDeliveryMs int `json:"wait_for_delivery_ms" validate:"omitempty,min=0" example:"3000"`
+}
+
+// errDoNotStoreQueued rejects do_not_store on the queued priorities, which are always stored
+var errDoNotStoreQueued = errors.New("do_not_store is only supported for OTP and transactional requests")
+
+// maxMetadataSize is the largest metadata object accepted on an SMS request, in bytes
+const maxMetadataSize = 2048
+
+// smsMetadata is a flat object of string values attached to an SMS request by the caller. It is
+// stored with the request and never sent to a gateway. Its size is checked while the request is
+// bound, so an oversized object is rejected as a bad request.
+type smsMetadata map[string]string
+
+// UnmarshalJSON accepts a JSON object of string values no larger than maxMetadataSize
+func (m *smsMetadata) UnmarshalJSON(data []byte) error {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return err
+	}
+	if compact.Len() > maxMetadataSize {
+		return fmt.Errorf("metadata must not exceed %d bytes", maxMetadataSize)
+	}
+	var values map[string]string
+	if err := json.Unmarshal(compact.Bytes(), &values); err != nil {
+		return errors.New("metadata must be an object of string values")
+	}
+	*m = values
+	return nil
 }
 
 // CreateMessageRequest godoc
 //
 //	@Summary		Creates a message request
 //	@Description	Creates message requests for application for registered templates
+//	@Description	Outside production, with sms.simulation.enabled, mobile numbers starting with sms.simulation.prefix (5000 by default) are not sent: the request is answered and stored with the gateway outcome encoded by the last three characters of the first such number.
+//	@Description	000 - success, 401 - CDAC authentication error, 418 - credit exhausted, TMO - timeout after the seconds given by the digits before it (5000005TMO - 5 seconds).
+//	@Description	An OTP request with wait_for_delivery_ms is answered once its message is delivered or failed, or after that many milliseconds (at most sms.deliverywait.max) with delivery_status DELIVERED, FAILED or PENDING. Without a free wait slot it is answered at once, without delivery_status.
 //	@Tags			SMS Request
 //	@ID				CreateSMSRequestHandler
 //	@Accept			json
 //	@Produce		json
 //	@Param			createSMSRequest	body		createSMSRequest				true	"Creates Message request"
 //	@Success		201					{object}	response.CreateSMSAPIResponse	"Success"
+//	@Header			201					{string}	Location						"URL of the status of the request, when it is stored"
 //	@Failure		400					{object}	apierrors.APIErrorResponse		"Bad Request"
 //	@Failure		401					{object}	apierrors.APIErrorResponse		"Unauthorized"
 //	@Failure		403					{object}	apierrors.APIErrorResponse		"Forbidden"
 //	@Failure		404					{object}	apierrors.APIErrorResponse		"Data not found"
 //	@Failure		409					{object}	apierrors.APIErrorResponse		"Data conflict errpr"
 //	@Failure		422					{object}	apierrors.APIErrorResponse		"Binding or Validation error"
+//	@Failure		429					{object}	apierrors.APIErrorResponse		"Rate limited by the gateway (GATEWAY_RATE_LIMITED), the daily send quota of the application exceeded (SEND_QUOTA_EXCEEDED, X-Quota-Remaining gives the messages left), or the hourly cap of the sender ID or template reached (SENDER_THROTTLED, TEMPLATE_THROTTLED, X-Cap-Reset gives the window reset), Retry-After and retry_after_seconds give the wait, with jitter"
 //	@Failure		500					{object}	apierrors.APIErrorResponse		"Internal server error"
 //	@Failure		502					{object}	apierrors.APIErrorResponse		"Bad Gateway"
+//	@Failure		503					{object}	apierrors.APIErrorResponse		"Gateway under maintenance (GATEWAY_MAINTENANCE), Retry-After and retry_after_seconds give the wait, with jitter"
 //	@Failure		504					{object}	apierrors.APIErrorResponse		"Gateway Timeout"
 //	@Router			/sms-request [post]
 func (ch *MgApplicationHandler) CreateSMSRequestHandler(ctx *gin.Context) {
@@ -131,17 +246,38 @@ func (ch *MgApplicationHandler) CreateSMSRequestHandler(ctx *gin.Context) {
 
 	msgreq := domain.MsgRequest{
 		FacilityID:    req.FacilityID,
-		ApplicationID: req.ApplicationID,
+		ApplicationID: string(req.ApplicationID),
 		Priority:      req.Priority,
 		// MessageText:   NormalizeAndClean2(req.MessageText),
-		MessageText:   req.MessageText,
-		SenderID:      req.SenderID,
-		MobileNumbers: req.MobileNumbers,
-		EntityId:      req.EntityId,
-		TemplateID:    req.TemplateID,
-		MessageType:   req.MessageType,
+		MessageText:     req.MessageText,
+		SenderID:        req.SenderID,
+		MobileNumbers:   req.MobileNumbers,
+		EntityId:        string(req.EntityId),
+		TemplateID:      string(req.TemplateID),
+		MessageType:     req.MessageType,
+		ClientReference: req.ClientReference,
+		Metadata:        req.Metadata,
+		CredentialSet:   req.CredentialSet,
+		DoNotStore:      req.DoNotStore,
+		WaitForDelivery: ch.deliveryWait.Limit(req.WaitForDeliveryMs),
+	}
+	if req.WaitForDeliveryMs > 0 && domain.Priority(msgreq.Priority) != domain.PriorityOTP {
+		log.Error(ctx, "wait_for_delivery_ms requested for priority %d", msgreq.Priority)
+		apierrors.ErrorResponseWithStatusCodeAndMessage(ctx, apierrors.HTTPErrorBadRequest, errDeliveryWaitPriority.Error(), errDeliveryWaitPriority)
+		return
+	}
+	if !ch.authorizeCredentialSet(ctx, msgreq) {
+		return
 	}
 
+	messageText, err := ch.branding.Apply(msgreq.SenderID, msgreq.MessageText)
+	if err != nil {
+		log.Error(ctx, "Branding check failed for CreateSMSRequestHandler: %s", err.Error())
+		apierrors.ErrorResponseWithStatusCodeAndMessage(ctx, apierrors.HTTPErrorBadRequest, err.Error(), err)
+		return
+	}
+	msgreq.MessageText = messageText
+
 	//Fetch Entity ID from config, if not assigned
 	msgreq.EntityId = ch.c.GetString("sms.dltEntityID")
 	// log.Debug(ctx, "Entity ID is : %s", msgreq.EntityId)
@@ -150,7 +286,36 @@ func (ch *MgApplicationHandler) CreateSMSRequestHandler(ctx *gin.Context) {
 	//**********************************************************************************
 	//added by phani for sending msg to kafka topic if Priority is not 1(Other than OTP)
 	//**********************************************************************************
-	if msgreq.Priority != 1 && msgreq.Priority != 2 {
+	if !domain.Priority(msgreq.Priority).Immediate() {
+		if msgreq.CredentialSet != "" {
+			log.Error(ctx, "Credential set %s requested for queued priority %d", msgreq.CredentialSet, msgreq.Priority)
+			apierrors.ErrorResponseWithStatusCodeAndMessage(ctx, apierrors.HTTPErrorBadRequest, errCredentialSetQueued.Error(), errCredentialSetQueued)
+			return
+		}
+		if msgreq.DoNotStore {
+			log.Error(ctx, "do_not_store requested for queued priority %d", msgreq.Priority)
+			apierrors.ErrorResponseWithStatusCodeAndMessage(ctx, apierrors.HTTPErrorBadRequest, errDoNotStoreQueued.Error(), errDoNotStoreQueued)
+			return
+		}
+
+		// Promotional and bulk messages are not sent to numbers registered as DND
+		allowed, skipped, err := ch.dnd.Filter(ctx, msgreq.Priority, strings.Split(msgreq.MobileNumbers, ","))
+		if err != nil {
+			apierrors.HandleError(ctx, err)
+			return
+		}
+		if len(allowed) == 0 {
+			apiRsp := response.CreateSMSAPIResponseKafka{
+				Data: map[string]interface{}{"status": DNDStatusSkipped, "skipped_mobile_numbers": skipped},
+			}
+			response.Created(ctx, &apiRsp)
+			return
+		}
+		msgreq.MobileNumbers = strings.Join(allowed, ",")
+		if !ch.consumeSendQuota(ctx, msgreq) {
+			return
+		}
+		ch.assignCommunicationID(&msgreq)
 
 		log.Debug(ctx, "Pushing Data to Kafka : %s", msgreq)
 		resp, err := ch.svc.SendMsgToKafka(&gctx, ch.c.GetString("sms.kafka.url"), ch.c.GetString("sms.kafka.schema"), &msgreq)
@@ -161,253 +326,39 @@ func (ch *MgApplicationHandler) CreateSMSRequestHandler(ctx *gin.Context) {
 		}
 		log.Debug(ctx, "Push Data to Kafka : %s", msgreq)
 		log.Debug(ctx, "Response from Kafka is : %s", resp)
+		if resp == nil {
+			resp = map[string]interface{}{}
+		}
+		resp["communication_id"] = msgreq.CommunicationID
+		if len(skipped) > 0 {
+			resp["skipped_mobile_numbers"] = skipped
+		}
 		apiRsp := response.CreateSMSAPIResponseKafka{
-			StatusCodeAndMessage: port.CreateSuccess,
-			Data:                 resp,
+			Data: resp,
 		}
-		handleCreateSuccess(ctx, apiRsp)
+		response.Created(ctx, &apiRsp)
 		return
 	}
 	//**********************************************************************************
 	//End- added by phani for sending msg to kafka topic if Priority is not 1(Other than OTP)
 	//**********************************************************************************
 
-	var gateway string
-	msgStoreRequest := ch.c.GetInt("sms.msgstorerequest")
-	// log.Debug(ctx, "Message Store Request ID is : %d", msgStoreRequest)
-	if msgStoreRequest == 1 || msgreq.Priority == 3 || msgreq.Priority == 4 {
-		//priorites are 1-OTP, 2-Transactional, 3-Promotional, 4-Bulk. If store is true or for Promotional and Bulk info will be saved.
-		savedresponse, err := ch.svc.SaveMsgRequestTx(&gctx, &msgreq)
-		if err != nil {
-			log.Error(ctx, "DB Error in SaveMsgRequestTx: %s", err.Error())
-			apierrors.HandleDBError(ctx, err)
-			return
-		}
-		gateway = savedresponse.Gateway
-	} else {
-		savedresponse, err := ch.svc.GetGateway(&gctx, &msgreq)
-		if err != nil {
-			log.Error(ctx, "DB Error in GetGateway: %s", err.Error())
-			apierrors.HandleDBError(ctx, err)
-			return
-		}
-		gateway = savedresponse.Gateway
-
+	if !ch.consumeSendQuota(ctx, msgreq) {
+		return
 	}
-	// log.Debug(ctx, "Gateway is : %s", gateway)
-
-	//UC - Unicode message ; PM - Plaintext message
-	if msgreq.MessageType == "UC" {
-		if msgreq.Gateway == "1" {
-			msgreq.MessageText = UnicodemsgConvertCDAC(msgreq.MessageText)
-		} else {
-			msgreq.MessageText = UnicodemsgConvertNIC(msgreq.MessageText)
-		}
-	} else {
-		msgreq.MessageType = "PM"
-	}
-	// log.Debug(ctx, "Message Type is : %s", msgreq.MessageType)
-
-	if msgreq.Priority == 1 || msgreq.Priority == 2 {
-		if gateway == "1" {
-			rsp, err := ch.SendSMSCDAC(SMSParams{
-				Username:     ch.c.GetString("sms.cdac.username"),
-				Password:     ch.c.GetString("sms.cdac.password"),
-				Message:      msgreq.MessageText,
-				SenderID:     msgreq.SenderID,
-				MobileNumber: msgreq.MobileNumbers,
-				SecureKey:    ch.c.GetString("sms.cdac.securekey"),
-				TemplateID:   msgreq.TemplateID,
-				MessageType:  msgreq.MessageType,
-			})
-			if err != nil {
-				msgresponse := domain.MsgResponse{
-					CommunicationID:  msgreq.CommunicationID,
-					CompleteResponse: rsp,
-					ResponseCode:     "02",
-					ResponseText:     err.Error(),
-					ReferenceID:      "",
-				}
-				_, _ = ch.svc.SaveResponseTx(&gctx, &msgresponse)
-				apierrors.HandleError(ctx, err)
-				return
-			}
-			log.Debug(ctx, "Response from SendSMSCDAC is : %s", rsp)
-
-			SMSResponse := rsp[:5]
-
-			if SMSResponse == "Error" {
-				pattern := `Error (\d+) : (.+)`
-				re := regexp.MustCompile(pattern)
-				matches := re.FindStringSubmatch(rsp)
-				if len(matches) < 3 {
-					msgStoreRequest := ch.c.GetInt("sms.msgstorerequest")
-					if msgStoreRequest == 1 || msgreq.Priority == 3 || msgreq.Priority == 4 {
-						msgresponse := domain.MsgResponse{
-							CommunicationID:  msgreq.CommunicationID,
-							CompleteResponse: rsp,
-							ResponseCode:     "400",
-							ResponseText:     "Invalid Response",
-							ReferenceID:      "",
-						}
-						_, _ = ch.svc.SaveResponseTx(&gctx, &msgresponse)
-						apierrors.HandleWithMessage(ctx, "Invalid Response")
-						return
-					}
-
-				} else {
-					errorNumber := matches[1]
-					errorMessage := matches[2]
-					customError := CustomError{Message: "401, " + errorMessage}
-					msgStoreRequest := ch.c.GetInt("sms.msgstorerequest")
-					if msgStoreRequest == 1 || msgreq.Priority == 3 || msgreq.Priority == 4 {
-						msgresponse := domain.MsgResponse{
-							CommunicationID:  msgreq.CommunicationID,
-							CompleteResponse: rsp,
-							ResponseCode:     errorNumber,
-							ResponseText:     errorMessage,
-							ReferenceID:      "",
-						}
-						_, _ = ch.svc.SaveResponseTx(&gctx, &msgresponse)
-					}
-					apierrors.HandleError(ctx, customError)
-					return
-				}
-			} else {
-
-				pattern := `^(\d{3}),MsgID = (\d+)`
-				re := regexp.MustCompile(pattern)
-				matches := re.FindStringSubmatch(rsp)
-				if len(matches) >= 3 {
-					responseCode := matches[1]
-					referenceID := matches[2]
-					msgStoreRequest := ch.c.GetInt("sms.msgstorerequest")
-					if msgStoreRequest == 1 || msgreq.Priority == 3 || msgreq.Priority == 4 {
-						msgresponse := domain.MsgResponse{
-							CommunicationID:  msgreq.CommunicationID,
-							CompleteResponse: rsp,
-							ResponseCode:     responseCode,
-							ResponseText:     "Submitted Successfully",
-							ReferenceID:      referenceID,
-						}
-						_, _ = ch.svc.SaveResponseTx(&gctx, &msgresponse)
-						rsp := response.NewCreateSMSResponse(&msgresponse)
-						apiRsp := response.CreateSMSAPIResponse{
-							StatusCodeAndMessage: port.CreateSuccess,
-							Data:                 rsp,
-						}
-						handleCreateSuccess(ctx, apiRsp)
-						return
-					}
-
-				} else {
-					msgStoreRequest := ch.c.GetInt("sms.msgstorerequest")
-					if msgStoreRequest == 1 || msgreq.Priority == 3 || msgreq.Priority == 4 {
-						msgresponse := domain.MsgResponse{
-							CommunicationID:  msgreq.CommunicationID,
-							CompleteResponse: rsp,
-							ResponseCode:     "402",
-							ResponseText:     "Submitted Successfully",
-							ReferenceID:      "",
-						}
-						_, _ = ch.svc.SaveResponseTx(&gctx, &msgresponse)
-						rsp := response.NewCreateSMSResponse(&msgresponse)
-						apiRsp := response.CreateSMSAPIResponse{
-							StatusCodeAndMessage: port.CreateSuccess,
-							Data:                 rsp,
-						}
-						handleCreateSuccess(ctx, apiRsp)
-						return
-					}
-
-				}
-
-			}
-		} else if gateway == "2" {
-			var NICUsername, NICPassword string
-			switch msgreq.SenderID {
-			case "INPOST":
-				NICUsername = ch.c.GetString("sms.nic.INPOSTUserName")
-				NICPassword = ch.c.GetString("sms.nic.INPOSTPassword")
-			case "DOPBNK", "DOPCBS":
-				NICUsername = ch.c.GetString("sms.nic.DOPBNKUserName")
-				NICPassword = ch.c.GetString("sms.nic.DOPBNKPassword")
-			case "DOPPLI":
-				NICUsername = ch.c.GetString("sms.nic.DOPPLIUserName")
-				NICPassword = ch.c.GetString("sms.nic.DOPPLIPassword")
-			default:
-				log.Error(ctx, "Invalid SenderID: %s", msgreq.SenderID)
-				apierrors.HandleWithMessage(ctx, "Invalid SenderID")
-				return
-			}
-
-			// rsp, err := ch.SendSMSNIC(NICUsername, NICPassword, msgreq.MessageText, msgreq.SenderID, msgreq.MobileNumbers, msgreq.EntityId, msgreq.TemplateID, msgreq.MessageType)
-			rsp, err := ch.SendSMSNIC(SMSParams{
-				Username:     NICUsername,
-				Password:     NICPassword,
-				Message:      msgreq.MessageText,
-				SenderID:     msgreq.SenderID,
-				MobileNumber: msgreq.MobileNumbers,
-				TemplateID:   msgreq.TemplateID,
-				MessageType:  msgreq.MessageType,
-			})
-
-			if err != nil {
-				msgresponse := domain.MsgResponse{
-					CommunicationID:  msgreq.CommunicationID,
-					CompleteResponse: rsp,
-					ResponseCode:     "02",
-					ResponseText:     err.Error(),
-					ReferenceID:      "",
-				}
-				_, _ = ch.svc.SaveResponseTx(&gctx, &msgresponse)
-				// ch.vs.handleError(ctx, err)
-				apierrors.HandleError(ctx, err)
-				return
-			}
-			pattern := `Request ID=(\d+)~code=([A-Z0-9]+)`
-			re := regexp.MustCompile(pattern)
-			matches := re.FindStringSubmatch(rsp)
-			if len(matches) >= 3 {
-				// If success and format is good
-				requestID := matches[1]
-				responseCode := matches[2]
-				// msgStoreRequest := ch.c.MessageStoreRequest()
-				msgStoreRequest := ch.c.GetInt("sms.msgstorerequest")
-				if msgStoreRequest == 1 || msgreq.Priority == 3 || msgreq.Priority == 4 {
-					msgresponse := domain.MsgResponse{
-						CommunicationID:  msgreq.CommunicationID,
-						CompleteResponse: rsp,
-						ResponseCode:     responseCode,
-						ResponseText:     "Submitted Successfully",
-						ReferenceID:      requestID,
-					}
-					_, _ = ch.svc.SaveResponseTx(&gctx, &msgresponse)
-					// handleSuccess(ctx, msgresponse)
-					rsp := response.NewCreateSMSResponse(&msgresponse)
-					apiRsp := response.CreateSMSAPIResponse{
-						StatusCodeAndMessage: port.CreateSuccess,
-						Data:                 rsp,
-					}
-					handleCreateSuccess(ctx, apiRsp)
-					return
-				}
-			}
+	ch.dispatchSMS(ctx, msgreq)
+}
 
-		} else {
-			// customError := CustomError{Message: "Invalid Gateway"}
-			// ch.vs.handleError(ctx, customError)
-			log.Error(ctx, "Invalid Gateway: %s", gateway)
-			apierrors.HandleWithMessage(ctx, "Invalid Gateway")
-		}
-	} else {
-		// handleSuccess(ctx, "Stored Successfully")
-		apiRsp := response.CreateSMSAPIResponse{
-			StatusCodeAndMessage: port.CreateSuccess,
-			// Data:                 rsp,
-		}
-		handleCreateSuccess(ctx, apiRsp)
+// consumeSendQuota counts the recipients of msgreq against the daily send quota of its
+// application, answering the request with 429 when they exceed it
+func (ch *MgApplicationHandler) consumeSendQuota(ctx *gin.Context, msgreq domain.MsgRequest) bool {
+	err := ch.quota.Consume(ctx.Request.Context(), msgreq)
+	if exceeded, ok := apierrors.Find[*SendQuotaExceededError](err); ok {
+		log.Warn(ctx, "Request rejected: %s", exceeded.Error())
+		respondSendQuotaExceeded(ctx, exceeded)
+		return false
 	}
+	return true
 }
 
 func (ch *MgApplicationHandler) CreateSMSRequestHandlerKafka(ctx *gin.Context) {
@@ -427,254 +378,86 @@ func (ch *MgApplicationHandler) CreateSMSRequestHandlerKafka(ctx *gin.Context) {
 	}
 
 	msgreq := domain.MsgRequest{
-		FacilityID:    req.FacilityID,
-		ApplicationID: req.ApplicationID,
-		Priority:      req.Priority,
-		MessageText:   req.MessageText,
-		SenderID:      req.SenderID,
-		MobileNumbers: req.MobileNumbers,
-		EntityId:      req.EntityId,
-		TemplateID:    req.TemplateID,
-		MessageType:   req.MessageType,
+		FacilityID:      req.FacilityID,
+		ApplicationID:   string(req.ApplicationID),
+		Priority:        req.Priority,
+		MessageText:     req.MessageText,
+		SenderID:        req.SenderID,
+		MobileNumbers:   req.MobileNumbers,
+		EntityId:        string(req.EntityId),
+		TemplateID:      string(req.TemplateID),
+		MessageType:     req.MessageType,
+		ClientReference: req.ClientReference,
+		Metadata:        req.Metadata,
+		CredentialSet:   req.CredentialSet,
+	}
+	if !ch.authorizeCredentialSet(ctx, msgreq) {
+		return
 	}
 
+	messageText, err := ch.branding.Apply(msgreq.SenderID, msgreq.MessageText)
+	if err != nil {
+		log.Error(ctx, "Branding check failed for CreateSMSRequestHandler: %s", err.Error())
+		apierrors.ErrorResponseWithStatusCodeAndMessage(ctx, apierrors.HTTPErrorBadRequest, err.Error(), err)
+		return
+	}
+	msgreq.MessageText = messageText
+
 	//Fetch Entity ID from config, if not assigned
 	// msgreq.EntityId = ch.c.DltEntityID()
 	msgreq.EntityId = ch.c.GetString("sms.dltEntityID")
 	log.Debug(ctx, "Entity ID is : %s", msgreq.EntityId)
-	gctx := context.Background()
 
-	var gateway string
-	// msgStoreRequest := ch.c.MessageStoreRequest()
-	msgStoreRequest := ch.c.GetInt("sms.msgstorerequest")
-	log.Debug(ctx, "Message Store Request ID is : %d", msgStoreRequest)
+	ch.dispatchSMS(ctx, msgreq)
+}
 
-	//priorites are 1-OTP, 2-Transactional, 3-Promotional, 4-Bulk. If store is true or for Promotional and Bulk info will be saved.
-	savedresponse, err := ch.svc.SaveMsgRequestTx(&gctx, &msgreq)
-	if err != nil {
-		log.Error(ctx, "DB Error in SaveMsgRequestTx: %s", err.Error())
-		// ch.vs.handledbError(ctx, err)
+// dispatchSMS stores, sends and answers a request following shouldPersist
+func (ch *MgApplicationHandler) dispatchSMS(ctx *gin.Context, msgreq domain.MsgRequest) {
+	msgresponse, cacheHit, err := ch.dispatchRequest(ctx.Request.Context(), &msgreq, ch.shouldPersist(msgreq.Priority))
+	if msgresponse == nil {
+		if throttled, ok := apierrors.Find[*SendCapThrottledError](err); ok {
+			respondSendCapThrottled(ctx, throttled)
+			return
+		}
 		apierrors.HandleDBError(ctx, err)
 		return
 	}
-	gateway = savedresponse.Gateway
-
-	// log.Debug(ctx, "Gateway is : %s", gateway)
-
-	//UC - Unicode message ; PM - Plaintext message
-	if msgreq.MessageType == "UC" {
-		if msgreq.Gateway == "1" {
-			msgreq.MessageText = UnicodemsgConvertCDAC(msgreq.MessageText)
-		} else {
-			msgreq.MessageText = UnicodemsgConvertNIC(msgreq.MessageText)
-		}
-	} else {
-		msgreq.MessageType = "PM"
-	}
-	// log.Debug(ctx, "Message Type is : %s", msgreq.MessageType)
-
-	if gateway == "1" {
-		// rsp, err := SendSMSCDAC(ch.c.CDACUserName(), ch.c.CDACPassword(), msgreq.MessageText, msgreq.SenderID, msgreq.MobileNumbers, ch.c.CDACSecureKey(), msgreq.TemplateID, msgreq.MessageType)
-		rsp, err := ch.SendSMSCDAC(SMSParams{
-			ch.c.GetString("sms.cdac.username"),
-			ch.c.GetString("sms.cdac.password"),
-			msgreq.MessageText,
-			msgreq.SenderID,
-			msgreq.MobileNumbers,
-			ch.c.GetString("sms.cdac.securekey"),
-			msgreq.TemplateID,
-			msgreq.MessageType})
-		if err != nil {
-			msgresponse := domain.MsgResponse{
-				CommunicationID:  msgreq.CommunicationID,
-				CompleteResponse: rsp,
-				ResponseCode:     "02",
-				ResponseText:     err.Error(),
-				ReferenceID:      "",
-			}
-			_, _ = ch.svc.SaveResponseTx(&gctx, &msgresponse)
-			// ch.vs.handleError(ctx, err)
-			apierrors.HandleError(ctx, err)
+	if err != nil {
+		log.Error(ctx, "Sending %s through gateway %s failed: %s", msgreq.CommunicationID, msgreq.Gateway, err.Error())
+		if rateLimited, ok := apierrors.Find[*GatewayRateLimitedError](err); ok {
+			respondGatewayRateLimited(ctx, rateLimited)
 			return
 		}
-		log.Debug(ctx, "Response from SendSMSCDAC is : %s", rsp)
-
-		SMSResponse := rsp[:5]
-
-		if SMSResponse == "Error" {
-			pattern := `Error (\d+) : (.+)`
-			re := regexp.MustCompile(pattern)
-			matches := re.FindStringSubmatch(rsp)
-			if len(matches) < 3 {
-				//if error and format of the message is good
-				// fmt.Println("No matches found.")
-				//  customError := CustomError{Message: "Invalid Response"}
-				msgStoreRequest := ch.c.GetInt("sms.msgstorerequest")
-				if msgStoreRequest == 1 || msgreq.Priority == 3 || msgreq.Priority == 4 {
-					msgresponse := domain.MsgResponse{
-						CommunicationID:  msgreq.CommunicationID,
-						CompleteResponse: rsp,
-						ResponseCode:     "400",
-						ResponseText:     "Invalid Response",
-						ReferenceID:      "",
-					}
-					_, _ = ch.svc.SaveResponseTx(&gctx, &msgresponse)
-					// ch.vs.handleError(ctx, customError)
-					apierrors.HandleWithMessage(ctx, "Invalid Response")
-					return
-				}
-
-			} else {
-				//if error and format is not good
-				errorNumber := matches[1]
-				errorMessage := matches[2]
-				customError := CustomError{Message: "401, " + errorMessage}
-				msgStoreRequest := ch.c.GetInt("sms.msgstorerequest")
-				if msgStoreRequest == 1 || msgreq.Priority == 3 || msgreq.Priority == 4 {
-					msgresponse := domain.MsgResponse{
-						CommunicationID:  msgreq.CommunicationID,
-						CompleteResponse: rsp,
-						ResponseCode:     errorNumber,
-						ResponseText:     errorMessage,
-						ReferenceID:      "",
-					}
-					_, _ = ch.svc.SaveResponseTx(&gctx, &msgresponse)
-				}
-				// ch.vs.handleError(ctx, customError)
-				apierrors.HandleError(ctx, customError)
-				return
-			}
-		} else {
-
-			pattern := `^(\d{3}),MsgID = (\d+)`
-			re := regexp.MustCompile(pattern)
-			matches := re.FindStringSubmatch(rsp)
-			if len(matches) >= 3 {
-				//if success and format is good
-				responseCode := matches[1]
-				referenceID := matches[2]
-				msgStoreRequest := ch.c.GetInt("sms.msgstorerequest")
-				if msgStoreRequest == 1 || msgreq.Priority == 3 || msgreq.Priority == 4 {
-					msgresponse := domain.MsgResponse{
-						CommunicationID:  msgreq.CommunicationID,
-						CompleteResponse: rsp,
-						ResponseCode:     responseCode,
-						ResponseText:     "Submitted Successfully",
-						ReferenceID:      referenceID,
-					}
-					_, _ = ch.svc.SaveResponseTx(&gctx, &msgresponse)
-					// handleSuccess(ctx, msgresponse)
-					rsp := response.NewCreateSMSResponse(&msgresponse)
-					apiRsp := response.CreateSMSAPIResponse{
-						StatusCodeAndMessage: port.CreateSuccess,
-						Data:                 rsp,
-					}
-					handleCreateSuccess(ctx, apiRsp)
-					return
-				}
-
-			} else {
-				// msgStoreRequest := ch.c.MessageStoreRequest()
-				msgStoreRequest := ch.c.GetInt("sms.msgstorerequest")
-				if msgStoreRequest == 1 || msgreq.Priority == 3 || msgreq.Priority == 4 {
-					msgresponse := domain.MsgResponse{
-						CommunicationID:  msgreq.CommunicationID,
-						CompleteResponse: rsp,
-						ResponseCode:     "402",
-						ResponseText:     "Submitted Successfully",
-						ReferenceID:      "",
-					}
-					_, _ = ch.svc.SaveResponseTx(&gctx, &msgresponse)
-					// handleSuccess(ctx, msgresponse)
-					rsp := response.NewCreateSMSResponse(&msgresponse)
-					apiRsp := response.CreateSMSAPIResponse{
-						StatusCodeAndMessage: port.CreateSuccess,
-						Data:                 rsp,
-					}
-					handleCreateSuccess(ctx, apiRsp)
-					return
-				}
-
-			}
-
-		}
-	} else if gateway == "2" {
-		var NICUsername, NICPassword string
-		switch msgreq.SenderID {
-		case "INPOST":
-			NICUsername = ch.c.GetString("sms.nic.INPOSTUserName")
-			NICPassword = ch.c.GetString("sms.nic.INPOSTPassword")
-		case "DOPBNK", "DOPCBS":
-			NICUsername = ch.c.GetString("sms.nic.DOPBNKUserName")
-			NICPassword = ch.c.GetString("sms.nic.DOPBNKPassword")
-		case "DOPPLI":
-			NICUsername = ch.c.GetString("sms.nic.DOPPLIUserName")
-			NICPassword = ch.c.GetString("sms.nic.DOPPLIPassword")
-		default:
-			log.Error(ctx, "Invalid SenderID: %s", msgreq.SenderID)
-			apierrors.HandleWithMessage(ctx, "Invalid SenderID")
+		if timeout, ok := apierrors.Find[*GatewayTimeoutError](err); ok {
+			apierrors.HandleGatewayTimeoutErrorWithMessage(ctx, timeout.Error(), err)
 			return
 		}
-
-		// rsp, err := SendSMSNIC(NICUsername, NICPassword, msgreq.MessageText, msgreq.SenderID, msgreq.MobileNumbers, msgreq.EntityId, msgreq.TemplateID, msgreq.MessageType)
-		rsp, err := ch.SendSMSNIC(SMSParams{
-			Username:     NICUsername,
-			Password:     NICPassword,
-			Message:      msgreq.MessageText,
-			SenderID:     msgreq.SenderID,
-			MobileNumber: msgreq.MobileNumbers,
-			TemplateID:   msgreq.TemplateID,
-			MessageType:  msgreq.MessageType,
-		})
-
-		if err != nil {
-			msgresponse := domain.MsgResponse{
-				CommunicationID:  msgreq.CommunicationID,
-				CompleteResponse: rsp,
-				ResponseCode:     "02",
-				ResponseText:     err.Error(),
-				ReferenceID:      "",
-			}
-			_, _ = ch.svc.SaveResponseTx(&gctx, &msgresponse)
-			// ch.vs.handleError(ctx, err)
-			apierrors.HandleError(ctx, err)
+		if maintenance, ok := apierrors.Find[*GatewayMaintenanceError](err); ok {
+			respondGatewayMaintenance(ctx, maintenance)
 			return
 		}
-		pattern := `Request ID=(\d+)~code=([A-Z0-9]+)`
-		re := regexp.MustCompile(pattern)
-		matches := re.FindStringSubmatch(rsp)
-		if len(matches) >= 3 {
-			// If success and format is good
-			requestID := matches[1]
-			responseCode := matches[2]
-			// msgStoreRequest := ch.c.MessageStoreRequest()
-			msgStoreRequest := ch.c.GetInt("sms.msgstorerequest")
-			if msgStoreRequest == 1 || msgreq.Priority == 3 || msgreq.Priority == 4 {
-				msgresponse := domain.MsgResponse{
-					CommunicationID:  msgreq.CommunicationID,
-					CompleteResponse: rsp,
-					ResponseCode:     responseCode,
-					ResponseText:     "Submitted Successfully",
-					ReferenceID:      requestID,
-				}
-				_, _ = ch.svc.SaveResponseTx(&gctx, &msgresponse)
-				// handleSuccess(ctx, msgresponse)
-				rsp := response.NewCreateSMSResponse(&msgresponse)
-				apiRsp := response.CreateSMSAPIResponse{
-					StatusCodeAndMessage: port.CreateSuccess,
-					Data:                 rsp,
-				}
-				handleCreateSuccess(ctx, apiRsp)
-				return
-			}
-		}
-
-	} else {
-		// customError := CustomError{Message: "Invalid Gateway"}
-		// ch.vs.handleError(ctx, customError)
-		apierrors.HandleWithMessage(ctx, "Invalid Gateway")
+		apierrors.HandleError(ctx, err)
+		return
 	}
+	rsp := response.NewCreateSMSResponse(&msgreq, msgresponse, ch.rawGatewayResponse(msgresponse.CompleteResponse))
+	rsp.CacheHit = cacheHit
+	if msgreq.WaitForDelivery > 0 && !ch.simulator.Simulates(domain.GatewayID(msgreq.Gateway)) {
+		rsp.DeliveryStatus = ch.deliveryWait.Wait(ctx.Request.Context(), domain.GatewayID(msgreq.Gateway), msgresponse.ReferenceID, msgreq.WaitForDelivery)
+	}
+	apiRsp := response.CreateSMSAPIResponse{
+		Data: rsp,
+	}
+	if msgreq.DoNotStore || !ch.shouldPersist(msgreq.Priority) {
+		response.Created(ctx, &apiRsp)
+		return
+	}
+	response.CreatedAt(ctx, &apiRsp, smsRequestLocation(rsp.CommunicationID))
+}
 
+// smsRequestLocation returns the URL the status of the request with communicationID is fetched
+// from
+func smsRequestLocation(communicationID string) string {
+	return "/v1/sms-request/" + url.PathEscape(communicationID) + "/status"
 }
 
 func (ch *MgApplicationHandler) SendTestMessage(ctx *gin.Context, payload map[string]interface{}) (map[string]interface{}, error) {
@@ -702,7 +485,9 @@ func (ch *MgApplicationHandler) SendTestMessage(ctx *gin.Context, payload map[st
 		Timeout:   30 * time.Second,
 	}
 
-	SMSResponse, err := client.Post(url, "application/json", bytes.NewBuffer(jsonPayload))
+	SMSResponse, err := ch.retry.do(ctx, func() (*http.Response, error) {
+		return client.Post(url, "application/json", bytes.NewBuffer(jsonPayload))
+	})
 	if err != nil {
 		log.Error(ctx, "Error calling SMS Provider URL %s", err.Error())
 		// apierrors.HandleErrorWithCustomMessage(ctx, "Error calling SMS Provider URL", err)
@@ -843,8 +628,8 @@ func (ch *MgApplicationHandler) CreateTestSMSHandler(ctx *gin.Context) {
 		"mobile_numbers": req.MobileNumber,
 		"entity_id":      "1001081725895192800",
 		"template_id":    "1007344609998507114",
-		"gateway":        "1",
-		"message_type":   "PM",
+		"gateway":        string(domain.GatewayCDAC),
+		"message_type":   string(domain.MessageTypePlain),
 	}
 
 	// Send the SMS using SendTestMessage and capture the response or error
@@ -864,7 +649,7 @@ func (ch *MgApplicationHandler) CreateTestSMSHandler(ctx *gin.Context) {
 	// log.Debug(ctx, "CreateTestSMSHandler response: %v", apiRsp)
 	// handleSuccess(ctx, apiRsp)
 	log.Debug(ctx, "CreateTestSMSHandler response: %v", rsp)
-	handleSuccess(ctx, rsp)
+	serverResponse.Respond(ctx, http.StatusOK, rsp)
 }
 
 type EditMgApplicationRequest struct {
@@ -883,14 +668,16 @@ type CustomError struct {
 	Status  string `json:"status"`
 }
 
+// GenerateRandomString returns length random URL-safe base64 characters, each carrying 6 bits
+// of entropy: a string of length n carries 6n random bits, not the 8n of n random bytes.
 func GenerateRandomString(length int) (string, error) {
-	randomBytes := make([]byte, length)
+	randomBytes := make([]byte, base64.RawURLEncoding.DecodedLen(length)+1)
 	_, err := rand.Read(randomBytes)
 	if err != nil {
 		return "", err
 	}
 
-	randomString := base64.URLEncoding.EncodeToString(randomBytes)[:length]
+	randomString := base64.RawURLEncoding.EncodeToString(randomBytes)[:length]
 	return randomString, nil
 }
 
@@ -903,15 +690,154 @@ type SMSParams struct {
 	SecureKey    string
 	TemplateID   string
 	MessageType  string
+	Priority     int
+}
+
+// String formats the parameters for logging, without the password and secure key
+func (p SMSParams) String() string {
+	return fmt.Sprintf("{Username:%s SenderID:%s MobileNumber:%s TemplateID:%s MessageType:%s Priority:%d}",
+		p.Username, p.SenderID, p.MobileNumber, p.TemplateID, p.MessageType, p.Priority)
+}
+
+// CDAC service types, selecting the route the message is delivered on
+const (
+	cdacServiceTypeOTP     = "otpmsg"
+	cdacServiceTypeUnicode = "unicodemsg"
+	cdacServiceTypeSingle  = "singlemsg"
+)
+
+// cdacServiceType returns the CDAC service type of a message from its priority and message type:
+// priority 1 (OTP) messages go on the OTP route, unicode (UC) messages on the unicode route and
+// everything else on the single message route
+func cdacServiceType(priority int, messageType string) string {
+	switch {
+	case domain.Priority(priority) == domain.PriorityOTP:
+		return cdacServiceTypeOTP
+	case domain.MessageType(messageType).IsUnicode():
+		return cdacServiceTypeUnicode
+	}
+	return cdacServiceTypeSingle
+}
+
+// nicCredentials returns the NIC username and password configured for a sender id
+func nicCredentials(c *config.Config, senderID string) (string, string, error) {
+	account, ok := nicAccount(senderID)
+	if !ok {
+		return "", "", fmt.Errorf("no NIC credentials configured for sender id %s", senderID)
+	}
+	return c.GetString("sms.nic." + account + "UserName"), c.GetString("sms.nic." + account + "Password"), nil
+}
+
+// nicAccounts are the NIC accounts of the sender ids, configured as sms.nic.<account>username
+// and sms.nic.<account>password
+var nicAccounts = []string{"INPOST", "DOPBNK", "DOPPLI"}
+
+// nicAccount returns the NIC account senderID is sent with
+func nicAccount(senderID string) (string, bool) {
+	switch senderID {
+	case "INPOST", "DOPBNK", "DOPPLI":
+		return senderID, true
+	case "DOPCBS":
+		return "DOPBNK", true
+	}
+	return "", false
+}
+
+// gatewaySecretKeys are the config keys of the gateway credentials redacted from raw responses
+var gatewaySecretKeys = []string{
+	"sms.cdac.password",
+	"sms.cdac.securekey",
+	"sms.nic.INPOSTPassword",
+	"sms.nic.DOPBNKPassword",
+	"sms.nic.DOPPLIPassword",
+	"sms.bulk.password",
+}
+
+// gatewaySecretParam matches credentials echoed by a gateway as request parameters
+var gatewaySecretParam = regexp.MustCompile(`(?i)\b(pin|password|pwd|securekey|key)=[^&\s"]*`)
+
+// rawGatewayResponse returns the raw gateway response to include in the API response.
+// It is empty unless sms.debug.includeRaw is set outside prod, and redacted of credentials.
+func (ch *MgApplicationHandler) rawGatewayResponse(raw string) string {
+	if !ch.c.GetBool("sms.debug.includeRaw") || ch.c.IsProdEnv() {
+		return ""
+	}
+	for _, key := range gatewaySecretKeys {
+		if secret := ch.c.GetString(key); secret != "" {
+			raw = strings.ReplaceAll(raw, secret, "[REDACTED]")
+		}
+	}
+	for _, secret := range ch.credentials.secrets() {
+		if secret != "" {
+			raw = strings.ReplaceAll(raw, secret, "[REDACTED]")
+		}
+	}
+	return gatewaySecretParam.ReplaceAllString(raw, "$1=[REDACTED]")
+}
+
+// sendSMS sends a message through a gateway with the credentials configured for that gateway,
+// or those of the credential set of msgreq, and returns the raw gateway response. A gateway that
+// rate limited a send is not called again before the Retry-After it gave has passed.
+func (ch *MgApplicationHandler) sendSMS(gateway string, msgreq domain.MsgRequest) (rsp string, err error) {
+	if !domain.MessageType(msgreq.MessageType).IsUnicode() {
+		msgreq.MessageType = string(domain.MessageTypePlain)
+	}
+	if code, delay, ok := ch.errorSimulation.Outcome(msgreq.MobileNumbers); ok {
+		return ch.errorSimulation.Send(domain.GatewayID(gateway), code, delay)
+	}
+	if err := ch.throttle.Check(domain.GatewayID(gateway)); err != nil {
+		return "", err
+	}
+	username, password, secureKey, err := ch.gatewayCredentials(domain.GatewayID(gateway), msgreq)
+	if err != nil {
+		return "", err
+	}
+	defer func() { ch.throttle.Observe(domain.GatewayID(gateway), err) }()
+	switch domain.GatewayID(gateway) {
+	case domain.GatewayCDAC:
+		message := msgreq.MessageText
+		if domain.MessageType(msgreq.MessageType).IsUnicode() {
+			message = UnicodemsgConvertCDAC(message)
+		}
+		return ch.SendSMSCDAC(SMSParams{
+			Username:     username,
+			Password:     password,
+			Message:      message,
+			SenderID:     msgreq.SenderID,
+			MobileNumber: msgreq.MobileNumbers,
+			SecureKey:    secureKey,
+			TemplateID:   msgreq.TemplateID,
+			MessageType:  msgreq.MessageType,
+			Priority:     msgreq.Priority,
+		})
+	case domain.GatewayNIC:
+		message := msgreq.MessageText
+		if domain.MessageType(msgreq.MessageType).IsUnicode() {
+			message = UnicodemsgConvertNIC(message)
+		}
+		return ch.SendSMSNIC(SMSParams{
+			Username:     username,
+			Password:     password,
+			Message:      message,
+			SenderID:     msgreq.SenderID,
+			MobileNumber: msgreq.MobileNumbers,
+			TemplateID:   msgreq.TemplateID,
+			MessageType:  msgreq.MessageType,
+		})
+	}
+	return "", fmt.Errorf("invalid gateway %s", gateway)
 }
 
 func (ch *MgApplicationHandler) SendSMSCDAC(req SMSParams) (string, error) {
 	log.Debug(nil, "Inside SendSMSCDAC function")
+	if ch.simulator.Simulates(domain.GatewayCDAC) {
+		return ch.simulator.Send(domain.GatewayCDAC)
+	}
 	log.Debug(nil, "req is : %v", req)
 	var responseString string
 
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Timeout: gatewayTimeout(ch.c),
 		Transport: &http.Transport{
 			TLSClientConfig: &tls.Config{
 				MinVersion:         tls.VersionTLS12,
@@ -924,7 +850,6 @@ func (ch *MgApplicationHandler) SendSMSCDAC(req SMSParams) (string, error) {
 	encryptedPassword, err := MD5(req.Password)
 	if err != nil {
 		log.Error(nil, "CDAC password encryption failed: %s", err.Error())
-		apierrors.HandleErrorWithCustomMessage(nil, "CDAC password encryption failed", err)
 		return "", err
 	}
 	// log.Debug(nil, "CDAC encryptedPassword is : %s", encryptedPassword)
@@ -940,13 +865,7 @@ func (ch *MgApplicationHandler) SendSMSCDAC(req SMSParams) (string, error) {
 	data.Set("mobileno", req.MobileNumber)
 	data.Set("senderid", req.SenderID)
 	data.Set("content", req.Message)
-	if req.MessageType == "UC" {
-		data.Set("smsservicetype", "unicodemsg")
-	} else if strings.Contains(req.Message, "otp") || strings.Contains(req.Message, "OTP") {
-		data.Set("smsservicetype", "otpmsg")
-	} else {
-		data.Set("smsservicetype", "singlemsg")
-	}
+	data.Set("smsservicetype", cdacServiceType(req.Priority, req.MessageType))
 	data.Set("key", hashKey)
 	data.Set("templateid", req.TemplateID)
 
@@ -954,10 +873,11 @@ func (ch *MgApplicationHandler) SendSMSCDAC(req SMSParams) (string, error) {
 	url := ch.c.GetString("sms.cdac.url")
 	log.Debug(nil, "CDAC URL is : %s", url)
 
-	resp, err := client.PostForm(url, data)
+	resp, err := ch.retry.do(context.Background(), func() (*http.Response, error) {
+		return client.PostForm(url, data)
+	})
 	if err != nil {
 		log.Error(nil, "CDAC API Call failed: %s", err.Error())
-		apierrors.HandleErrorWithCustomMessage(nil, "CDAC sendSMS API Call failed", err)
 		return "", err
 	}
 	defer resp.Body.Close()
@@ -966,15 +886,17 @@ func (ch *MgApplicationHandler) SendSMSCDAC(req SMSParams) (string, error) {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		log.Error(nil, "Error reading response body: %s", err.Error())
-		apierrors.HandleErrorWithCustomMessage(nil, "Error reading CDAC sendSMS response body", err)
 		return "", err
 	}
 
 	// Check the HTTP response status
 	//sample response: 402,MsgID = 060320251741252969158appostsms
+	if resp.StatusCode == http.StatusTooManyRequests {
+		log.Error(nil, "CDAC sendSMS API rate limited the request, Retry-After %q", resp.Header.Get("Retry-After"))
+		return "", newGatewayRateLimitedError(domain.GatewayCDAC, resp)
+	}
 	if resp.StatusCode != http.StatusOK {
 		log.Error(nil, "CDAC sendSMS API returned non-OK status: %s", resp.Status)
-		apierrors.HandleErrorWithCustomMessage(nil, "CDAC sendSMS API call failed", err)
 		return "", fmt.Errorf("CDAC SMS Gateway returned non-OK status: %s", resp.Status)
 	} else {
 		log.Debug(nil, "CDAC sendSMS API call success: %s", resp.Status)
@@ -983,13 +905,16 @@ func (ch *MgApplicationHandler) SendSMSCDAC(req SMSParams) (string, error) {
 	// Convert the response body to a string
 	responseString = string(body)
 	log.Debug(nil, "CDAC responseString is : %s", responseString)
-	return responseString, nil
+	return checkGatewayBody(ch.c, domain.GatewayCDAC, resp, responseString)
 }
 
 // func SendSMSNIC(username string, password string, message string, senderId string, mobileNumber string, entityId string, templateId string, messageType string) (string, error) {
 func (ch *MgApplicationHandler) SendSMSNIC(smsreq SMSParams) (string, error) {
 
 	log.Debug(nil, "Inside SendSMSNIC function")
+	if ch.simulator.Simulates(domain.GatewayNIC) {
+		return ch.simulator.Send(domain.GatewayNIC)
+	}
 	// log.Debug(nil, "smsreq is : %+v", smsreq)
 
 	// baseURL := "https://smsgw.sms.gov.in/failsafe/HttpLink"
@@ -1009,16 +934,16 @@ func (ch *MgApplicationHandler) SendSMSNIC(smsreq SMSParams) (string, error) {
 	req, err := http.NewRequest("GET", fullURL, nil)
 	if err != nil {
 		log.Error(nil, "Failed to create NIC HTTP request: %s", err.Error())
-		apierrors.HandleErrorWithCustomMessage(nil, "Failed to create HTTP request", err)
 		return "", err
 	}
-	log.Debug(nil, "NIC HTTP request is : %+v", req)
+	// the request URL carries the pin, only the parameters are logged
+	log.Debug(nil, "NIC HTTP request is : %s", smsreq)
 
 	// Set the Content-Type header to application/x-www-form-urlencoded
 
 	// Execute the HTTP request
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Timeout: gatewayTimeout(ch.c),
 		Transport: &http.Transport{
 			TLSClientConfig: &tls.Config{
 				MinVersion:         tls.VersionTLS12,
@@ -1027,7 +952,14 @@ func (ch *MgApplicationHandler) SendSMSNIC(smsreq SMSParams) (string, error) {
 			// Proxy: http.ProxyFromEnvironment,
 		},
 	}
-	resp, err := client.Do(req)
+	resp, err := ch.retry.do(context.Background(), func() (*http.Response, error) {
+		resp, err := client.Do(req)
+		if urlErr, ok := err.(*url.Error); ok {
+			// the URL of the error carries the pin, also logged by the retries
+			urlErr.URL = baseURL
+		}
+		return resp, err
+	})
 	if err != nil {
 		log.Error(nil, "NIC sendSMS API call failed: %s", err.Error())
 		// apierrors.HandleErrorWithCustomMessage(nil, "Failed to execute HTTP request", err)
@@ -1038,6 +970,10 @@ func (ch *MgApplicationHandler) SendSMSNIC(smsreq SMSParams) (string, error) {
 	defer resp.Body.Close()
 
 	// Check the HTTP response status
+	if resp.StatusCode == http.StatusTooManyRequests {
+		log.Error(nil, "NIC sendSMS API rate limited the request, Retry-After %q", resp.Header.Get("Retry-After"))
+		return "", newGatewayRateLimitedError(domain.GatewayNIC, resp)
+	}
 	if resp.StatusCode != http.StatusOK {
 		log.Info(nil, "NIC sendSMS API call failed: %s", resp.Status)
 		return "", fmt.Errorf("SMS Gateway returned non-OK status: %d %s", resp.StatusCode, resp.Status)
@@ -1051,7 +987,10 @@ func (ch *MgApplicationHandler) SendSMSNIC(smsreq SMSParams) (string, error) {
 	log.Debug(nil, "NIC response body is : %s", string(body))
 
 	// Convert the body to a string for further processing
-	responseString := string(body)
+	responseString, err := checkGatewayBody(ch.c, domain.GatewayNIC, resp, string(body))
+	if err != nil {
+		return responseString, err
+	}
 
 	if strings.Contains(responseString, "Message Accepted") {
 		return responseString, nil
@@ -1141,42 +1080,106 @@ func (ce CustomError) Error() string {
 	return fmt.Sprintf("{Message: %s}", ce.Message)
 }
 
-// type FetchSMSRequestStatusHandlerRequest struct {
-// 	MessageID uint64 `json:"message_id" validate:"required" example:"250220251740500435482appostsms"`
-// }
+type fetchSMSRequestStatusRequest struct {
+	CommunicationID string `uri:"communication-id" validate:"required,communication_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`
+}
 
-// func (ch *MgApplicationHandler) FetchSMSRequestStatusHandler (gctx *gin.Context){
-// 	var req FetchSMSRequestStatusHandlerRequest
-// 	if err := gctx.ShouldBindJSON(&req); err != nil {
-// 		apierrors.HandleBindingError(gctx, err)
-// 		log.Error(gctx, "JSON Binding failed for FetchSMSRequestStatusHandlerRequest: %s", err.Error())
-// 		return
-// 	}
-
-// 	if err := validation.ValidateStruct(req); err != nil {
-// 		apierrors.HandleValidationError(gctx, err)
-// 		log.Error(gctx, "Validation failed for FetchSMSRequestStatusHandlerRequest: %s", err.Error())
-// 		return
-// 	}
-
-// 	// Fetch the SMS request status
-// 	status, err := ch.svc.FetchSMSRequestStatusRepo(gctx, req.MessageID)
-// 	if err != nil {
-// 		apierrors.HandleDBError(gctx, err)
-// 		log.Error(gctx, "Failed to fetch SMS request status: %s", err.Error())
-// 		return
-// 	}
-
-// 	// Return the status in the response
-// 	apiRsp := response.FetchSMSRequestStatusAPIResponse{
-// 		StatusCodeAndMessage: port.FetchSuccess,
-// 		Data:                 status,
-// 	}
-
-// 	log.Debug(gctx, "FetchSMSRequestStatusHandler response: %v", apiRsp)
-// 	handleSuccess(gctx, apiRsp)
+// FetchSMSRequestStatusHandler godoc
+//
+//	@Summary		Get the status of a message request
+//	@Description	Fetches the latest gateway response stored for a message request, such as the response of its last retry or gateway fallback
+//	@Tags			SMS Request
+//	@ID				FetchSMSRequestStatusHandler
+//	@Accept			json
+//	@Produce		json
+//	@Param			fetchSMSRequestStatusRequest	path		fetchSMSRequestStatusRequest				true	"Communication id of the request"
+//	@Success		200								{object}	response.FetchSMSRequestStatusAPIResponse	"Latest response of the request"
+//	@Failure		400								{object}	apierrors.APIErrorResponse					"Bad Request"
+//	@Failure		401								{object}	apierrors.APIErrorResponse					"Unauthorized"
+//	@Failure		403								{object}	apierrors.APIErrorResponse					"Forbidden"
+//	@Failure		404								{object}	apierrors.APIErrorResponse					"Data not found"
+//	@Failure		422								{object}	apierrors.APIErrorResponse					"Binding or Validation error"
+//	@Failure		500								{object}	apierrors.APIErrorResponse					"Internal server error"
+//	@Router			/sms-request/{communication-id}/status [get]
+func (ch *MgApplicationHandler) FetchSMSRequestStatusHandler(gctx *gin.Context) {
+	var req fetchSMSRequestStatusRequest
+	if err := gctx.ShouldBindUri(&req); err != nil {
+		apierrors.HandleBindingError(gctx, err)
+		log.Error(gctx, "Binding failed for fetchSMSRequestStatusRequest: %s", err.Error())
+		return
+	}
 
-// }
+	if err := validation.ValidateStruct(req); err != nil {
+		apierrors.HandleValidationError(gctx, err)
+		log.Error(gctx, "Validation failed for fetchSMSRequestStatusRequest: %s", err.Error())
+		return
+	}
+
+	msgRsp, err := ch.svc.FetchLatestResponseByCommunicationIDRepo(gctx, req.CommunicationID)
+	if err != nil {
+		apierrors.HandleDBError(gctx, err)
+		log.Error(gctx, "Failed to fetch SMS request status: %s", err.Error())
+		return
+	}
+
+	apiRsp := response.FetchSMSRequestStatusAPIResponse{
+		Data: response.NewFetchSMSRequestStatusResponse(&msgRsp),
+	}
+
+	log.Debug(gctx, "FetchSMSRequestStatusHandler response: %v", apiRsp)
+	response.OK(gctx, &apiRsp)
+}
+
+type fetchSMSRequestStatusBulkRequest struct {
+	// CommunicationIDs lists the requests to check, at most 100
+	CommunicationIDs []string `json:"communication_ids" validate:"required,min=1,max=100,unique,dive,required,communication_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV,01ARZ3NDEKTSV4RRFFQ69G5FAW"`
+}
+
+// FetchSMSRequestStatusBulkHandler godoc
+//
+//	@Summary		Get the status of message requests in bulk
+//	@Description	Fetches the latest gateway response stored for each of the requests listed in communication_ids (at most 100), in a single query.
+//	@Description	The statuses are keyed by communication id. A request without a stored response is returned with the result not_found rather than failing the call.
+//	@Tags			SMS Request
+//	@ID				FetchSMSRequestStatusBulkHandler
+//	@Accept			json
+//	@Produce		json
+//	@Param			fetchSMSRequestStatusBulkRequest	body		fetchSMSRequestStatusBulkRequest				true	"Communication ids of the requests"
+//	@Success		200									{object}	response.FetchSMSRequestStatusBulkAPIResponse	"Latest response of each request"
+//	@Failure		400									{object}	apierrors.APIErrorResponse						"Bad Request"
+//	@Failure		401									{object}	apierrors.APIErrorResponse						"Unauthorized"
+//	@Failure		403									{object}	apierrors.APIErrorResponse						"Forbidden"
+//	@Failure		422									{object}	apierrors.APIErrorResponse						"Binding or Validation error"
+//	@Failure		500									{object}	apierrors.APIErrorResponse						"Internal server error"
+//	@Router			/sms-request/status/bulk [post]
+func (ch *MgApplicationHandler) FetchSMSRequestStatusBulkHandler(gctx *gin.Context) {
+	var req fetchSMSRequestStatusBulkRequest
+	if err := gctx.ShouldBindJSON(&req); err != nil {
+		apierrors.HandleBindingError(gctx, err)
+		log.Error(gctx, "Binding failed for fetchSMSRequestStatusBulkRequest: %s", err.Error())
+		return
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		apierrors.HandleValidationError(gctx, err)
+		log.Error(gctx, "Validation failed for fetchSMSRequestStatusBulkRequest: %s", err.Error())
+		return
+	}
+
+	latest, err := ch.statuses.FetchLatestResponsesByCommunicationIDsRepo(gctx, req.CommunicationIDs)
+	if err != nil {
+		apierrors.HandleDBError(gctx, err)
+		log.Error(gctx, "Failed to fetch SMS request statuses: %s", err.Error())
+		return
+	}
+
+	apiRsp := response.FetchSMSRequestStatusBulkAPIResponse{
+		Data: response.NewFetchSMSRequestStatusBulkResponse(req.CommunicationIDs, latest),
+	}
+
+	log.Debug(gctx, "FetchSMSRequestStatusBulkHandler found %d of %d requests", len(latest), len(req.CommunicationIDs))
+	response.OK(gctx, &apiRsp)
+}
 
 type FetchCDACSMSDeliveryStatusRequest struct {
 	// UserName string `json:"username" validate:"required" example:"appostsms"`
@@ -1224,7 +1227,8 @@ func (ch *MgApplicationHandler) FetchCDACSMSDeliveryStatusHandler(gctx *gin.Cont
 		MessageID:      req.ReferenceID + cdacUserName,
 		IsPwdEncrypted: IsPwdEncrypted,
 	}
-	log.Debug(gctx, "FetchCDACSMSDeliveryStatusHandler request: %v", smsDeliveryStatus)
+	// the request carries the encrypted password, only the message id is logged
+	log.Debug(gctx, "FetchCDACSMSDeliveryStatusHandler request for message %s", smsDeliveryStatus.MessageID)
 
 	//API call to fetch the SMS delivery status
 
@@ -1236,8 +1240,7 @@ func (ch *MgApplicationHandler) FetchCDACSMSDeliveryStatusHandler(gctx *gin.Cont
 	params.Add("pwd_encrypted", strconv.FormatBool(smsDeliveryStatus.IsPwdEncrypted))
 
 	url := fmt.Sprintf("%s?%s", baseURL, params.Encode())
-
-	fmt.Println("delivery status url is:", url) // url := "https://msdgweb.mgov.gov.in/ReportAPI/csvreport
+	log.Debug(gctx, "Delivery status url is : %s", baseURL) // url := "https://msdgweb.mgov.gov.in/ReportAPI/csvreport
 	method := "GET"
 
 	client := &http.Client{}
@@ -1293,16 +1296,15 @@ func (ch *MgApplicationHandler) FetchCDACSMSDeliveryStatusHandler(gctx *gin.Cont
 		statusResponse := &response.FetchCDACSMSDeliveryStatusResponse{
 			MobileNumber: status[0],
 			SMSStatus:    status[1],
-			TimeStamp:    status[2],
+			TimeStamp:    cdacTimestamp(status[2]),
 		}
 		statusResponses = append(statusResponses, statusResponse)
 	}
 
 	apiRsp := response.FetchCDACSMSDeliveryStatusAPIResponse{
-		StatusCodeAndMessage: port.FetchSuccess,
-		Data:                 statusResponses,
+		Data: statusResponses,
 	}
 
 	log.Debug(gctx, "FetchCDACSMSDeliveryStatusHandler response: %v", apiRsp)
-	handleSuccess(gctx, apiRsp)
+	response.OK(gctx, &apiRsp)
 }
@@ -0,0 +1,286 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSMSRequestLocation(t *testing.T) {
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("402,MsgID = 150920241726381202115hpgovsms"))
+	}))
+	defer cdac.Close()
+
+	for _, tt := range []struct {
+		name    string
+		store   int
+		located bool
+	}{
+		{"stored request", 1, true},
+		{"request not stored", 0, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := config.NewConfig(viper.New())
+			c.Set("sms.msgstorerequest", tt.store)
+			c.Set("sms.cdac.url", cdac.URL)
+			c.Set("sms.cdac.username", "appostsms")
+			c.Set("sms.cdac.password", "cdacsecret")
+			c.Set("sms.cdac.securekey", "c7d427c9-63e7-4eec-a227-3ef840a75269")
+			ch, _ := newTestSMSHandler(c)
+
+			rec := postSMSRequest(ch, otpRequestBody("9000000001"))
+			require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+			var rsp struct {
+				Data struct {
+					CommunicationID string `json:"communication_id"`
+				} `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+			if !tt.located {
+				// the status of a request not stored cannot be fetched
+				assert.Empty(t, rec.Header().Get("Location"))
+				return
+			}
+			require.NotEmpty(t, rsp.Data.CommunicationID)
+			assert.Equal(t, "/v1/sms-request/"+rsp.Data.CommunicationID+"/status", rec.Header().Get("Location"))
+		})
+	}
+}
+
+func TestCDACServiceType(t *testing.T) {
+	tests := []struct {
+		name        string
+		priority    int
+		messageType string
+		want        string
+	}{
+		{"otp", 1, "PM", cdacServiceTypeOTP},
+		{"unicode otp", 1, "UC", cdacServiceTypeOTP},
+		{"otp without message type", 1, "", cdacServiceTypeOTP},
+		{"transactional", 2, "PM", cdacServiceTypeSingle},
+		{"transactional unicode", 2, "UC", cdacServiceTypeUnicode},
+		{"promotional", 3, "PM", cdacServiceTypeSingle},
+		{"promotional unicode", 3, "UC", cdacServiceTypeUnicode},
+		{"bulk", 4, "PM", cdacServiceTypeSingle},
+		{"bulk unicode", 4, "UC", cdacServiceTypeUnicode},
+		{"no priority", 0, "PM", cdacServiceTypeSingle},
+		{"no priority unicode", 0, "UC", cdacServiceTypeUnicode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, cdacServiceType(tt.priority, tt.messageType))
+		})
+	}
+}
+
+// The service type is chosen from the priority and the message type, never from the message text
+func TestSendSMSCDACServiceType(t *testing.T) {
+	var serviceType string
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		serviceType = r.PostForm.Get("smsservicetype")
+		_, _ = w.Write([]byte("402,MsgID = 060320251741252969158appostsms"))
+	}))
+	defer cdac.Close()
+
+	c := config.NewConfig(viper.New())
+	c.Set("sms.cdac.url", cdac.URL)
+	ch := &MgApplicationHandler{c: c}
+
+	tests := []struct {
+		name        string
+		priority    int
+		messageType string
+		message     string
+		want        string
+	}{
+		{"otp", 1, "PM", "Your OTP is 123456", "otpmsg"},
+		{"otp without otp in content", 1, "PM", "Your code is 123456", "otpmsg"},
+		{"unicode otp", 1, "UC", "&#2310;&#2346;", "otpmsg"},
+		{"transactional mentioning otp", 2, "PM", "Never share your OTP with anyone", "singlemsg"},
+		{"transactional", 2, "PM", "Your parcel has been delivered", "singlemsg"},
+		{"transactional unicode", 2, "UC", "&#2310;&#2346;", "unicodemsg"},
+		{"promotional", 3, "PM", "Visit your nearest post office", "singlemsg"},
+		{"promotional unicode", 3, "UC", "&#2310;&#2346;", "unicodemsg"},
+		{"bulk", 0, "PM", "otp", "singlemsg"},
+		{"bulk unicode", 0, "UC", "&#2310;&#2346;", "unicodemsg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceType = ""
+			_, err := ch.SendSMSCDAC(SMSParams{
+				Username:     "appostsms",
+				Password:     "secret",
+				Message:      tt.message,
+				SenderID:     "INPOST",
+				MobileNumber: "9000000001",
+				TemplateID:   "1007527152539240260",
+				MessageType:  tt.messageType,
+				Priority:     tt.priority,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, serviceType)
+		})
+	}
+}
+
+// newTestSMSHandler creates an MgApplicationHandler sending through the gateways configured in c
+// and keeping its requests in a fakeMsgStore
+func newTestSMSHandler(c *config.Config) (*MgApplicationHandler, *fakeMsgStore) {
+	store := &fakeMsgStore{}
+	return &MgApplicationHandler{
+		c:           c,
+		branding:    NewSenderBranding(c),
+		otpCache:    NewOTPSendCache(c),
+		credentials: NewCredentialSets(c),
+		throttle:    newGatewayThrottle(),
+		store:       store,
+	}, store
+}
+
+// otpRequestBody is an OTP request for mobileNumber as sent to CreateSMSRequestHandler
+func otpRequestBody(mobileNumber string) map[string]any {
+	return map[string]any{
+		"application_id": "7",
+		"facility_id":    "facility1",
+		"priority":       1,
+		"message_text":   "Dear Customer, OTP for booking is 1234, please do not share it with anyone - INDPOST",
+		"sender_id":      "INPOST",
+		"mobile_numbers": mobileNumber,
+		"entity_id":      "1001081725895192800",
+		"template_id":    "1007344609998507114",
+	}
+}
+
+// postSMSRequest sends body to the CreateSMSRequestHandler of ch
+func postSMSRequest(ch *MgApplicationHandler, body map[string]any) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/v1/sms-request", ch.CreateSMSRequestHandler)
+
+	input, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/v1/sms-request", bytes.NewBuffer(input))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+// fakeStatusStore answers the bulk status checks with its responses, recording the queries
+type fakeStatusStore struct {
+	responses map[string]domain.MsgResponse
+	queries   [][]string
+}
+
+func (s *fakeStatusStore) FetchLatestResponsesByCommunicationIDsRepo(gctx *gin.Context, communicationIDs []string) (map[string]domain.MsgResponse, error) {
+	s.queries = append(s.queries, communicationIDs)
+	latest := make(map[string]domain.MsgResponse)
+	for _, id := range communicationIDs {
+		if msgRsp, ok := s.responses[id]; ok {
+			latest[id] = msgRsp
+		}
+	}
+	return latest, nil
+}
+
+// postSMSRequestStatusBulk sends ids to the FetchSMSRequestStatusBulkHandler of ch
+func postSMSRequestStatusBulk(ch *MgApplicationHandler, ids []string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/v1/sms-request/status/bulk", ch.FetchSMSRequestStatusBulkHandler)
+
+	input, _ := json.Marshal(map[string]any{"communication_ids": ids})
+	req := httptest.NewRequest(http.MethodPost, "/v1/sms-request/status/bulk", bytes.NewBuffer(input))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+// The known requests are answered with their latest response and the unknown ones marked not
+// found, from a single query
+func TestFetchSMSRequestStatusBulk(t *testing.T) {
+	sentAt := time.Date(2024, 9, 15, 10, 30, 0, 0, time.UTC)
+	store := &fakeStatusStore{responses: map[string]domain.MsgResponse{
+		"01J7TKPX20B9D5MPJTB9D5MPJT": {CommunicationID: "01J7TKPX20B9D5MPJTB9D5MPJT", ReferenceID: "5718473651", ResponseCode: "API000", ResponseText: "Message accepted", CreatedAt: sentAt},
+		"p4w15qumdd8o1apiyqwp":       {CommunicationID: "p4w15qumdd8o1apiyqwp", ResponseCode: "401", ResponseText: "Authentication failed", CreatedAt: sentAt},
+	}}
+	ch := &MgApplicationHandler{c: config.NewConfig(viper.New()), statuses: store}
+
+	ids := []string{"01J7TKPX20B9D5MPJTB9D5MPJT", "01J7TKPX20B9D5MPJTB9D5MPJV", "p4w15qumdd8o1apiyqwp"}
+	rec := postSMSRequestStatusBulk(ch, ids)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, [][]string{ids}, store.queries)
+
+	var rsp struct {
+		Data map[string]struct {
+			Result       string     `json:"result"`
+			ReferenceID  string     `json:"reference_id"`
+			Status       string     `json:"status"`
+			ResponseText string     `json:"response_text"`
+			CreatedAt    *time.Time `json:"created_at"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	require.Len(t, rsp.Data, 3)
+
+	known := rsp.Data["01J7TKPX20B9D5MPJTB9D5MPJT"]
+	assert.Equal(t, "found", known.Result)
+	assert.Equal(t, "5718473651", known.ReferenceID)
+	assert.Equal(t, "API000", known.Status)
+	if assert.NotNil(t, known.CreatedAt) {
+		assert.True(t, sentAt.Equal(*known.CreatedAt))
+	}
+	assert.Equal(t, "found", rsp.Data["p4w15qumdd8o1apiyqwp"].Result)
+	assert.Equal(t, "401", rsp.Data["p4w15qumdd8o1apiyqwp"].Status)
+
+	unknown := rsp.Data["01J7TKPX20B9D5MPJTB9D5MPJV"]
+	assert.Equal(t, "not_found", unknown.Result)
+	assert.Empty(t, unknown.Status)
+	assert.Nil(t, unknown.CreatedAt)
+}
+
+// Invalid bulk status checks are answered with 422 before the store is queried
+func TestFetchSMSRequestStatusBulkValidation(t *testing.T) {
+	tooMany := make([]string, 101)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("01J7TKPX20B9D5MPJTB9D5M%03d", i)
+	}
+	tests := []struct {
+		name string
+		ids  []string
+	}{
+		{"no ids", nil},
+		{"empty list", []string{}},
+		{"more than 100 ids", tooMany},
+		{"duplicate ids", []string{"01J7TKPX20B9D5MPJTB9D5MPJT", "01J7TKPX20B9D5MPJTB9D5MPJT"}},
+		{"empty id", []string{"01J7TKPX20B9D5MPJTB9D5MPJT", ""}},
+		{"id too long", []string{"01J7TKPX20B9D5MPJTB9D5MPJTV"}},
+		{"not a communication id", []string{"01J7TKPX20B9D5MPJTB9D5MPJT", "Not Applicable"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStatusStore{}
+			ch := &MgApplicationHandler{c: config.NewConfig(viper.New()), statuses: store}
+
+			rec := postSMSRequestStatusBulk(ch, tt.ids)
+			assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+			assert.Empty(t, store.queries)
+		})
+	}
+}
@@ -4,11 +4,11 @@ import (
 	"MgApplication/core/domain"
 	repo "MgApplication/repo/postgres"
 	"context"
-	"regexp"
 
 	v1 "MgApplication/gen/smsrequest/v1"
 
 	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
 	log "MgApplication/api-log"
 
 	"connectrpc.com/connect"
@@ -44,258 +44,49 @@ func (mh *MgApplicationHandlergrpc) CreateSMSRequestHandler(ctx context.Context,
 		MessageType:   req.Msg.MessageType,
 	}
 
+	msgreq.MessageText, err = mh.ch.branding.Apply(msgreq.SenderID, msgreq.MessageText)
+	if err != nil {
+		log.Error(ctx, "Branding check failed for CreateSMSRequestHandler: %s", err.Error())
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
 	//Fetch Entity ID from config, if not assigned
 	// msgreq.EntityId = ch.c.DltEntityID()
 	msgreq.EntityId = mh.c.GetString("sms.dltEntityID")
 	log.Debug(ctx, "Entity ID is : %s", msgreq.EntityId)
 
-	var gateway string
-	// msgStoreRequest := ch.c.MessageStoreRequest()
-	msgStoreRequest := mh.c.GetInt("sms.msgstorerequest")
-	log.Debug(ctx, "Message Store Request ID is : %d", msgStoreRequest)
-
-	if msgStoreRequest == 1 || msgreq.Priority == 3 || msgreq.Priority == 4 {
-		//priorites are 1-OTP, 2-Transactional, 3-Promotional, 4-Bulk. If store is true or for Promotional and Bulk info will be saved.
-		savedresponse, err := mh.svc.SaveMsgRequest(&ctx, &msgreq)
-		if err != nil {
-			log.Error(ctx, "DB Error in SaveMsgRequest: %s", err.Error())
-			// apierrors.HandleDBError(ctx, err)
+	if !domain.Priority(msgreq.Priority).Immediate() {
+		// stored only, not sent
+		gctx := context.Background()
+		mh.ch.routeRequest(&msgreq)
+		mh.ch.assignCommunicationID(&msgreq)
+		if _, err := mh.ch.store.SaveMsgRequestTx(&gctx, &msgreq); err != nil {
+			log.Error(ctx, "DB Error in SaveMsgRequestTx: %s", err.Error())
 			return nil, err
 		}
-		gateway = savedresponse.Gateway
-	} else {
-		savedresponse, err := mh.svc.GetGateway(&ctx, &msgreq)
-		if err != nil {
-			log.Error(ctx, "DB Error in GetGateway: %s", err.Error())
-			// apierrors.HandleDBError(ctx, err)
-			return nil, err
-		}
-		gateway = savedresponse.Gateway
-
+		return connect.NewResponse(&v1.CreateSMSRequestHandlerResponse{CommunicationId: msgreq.CommunicationID}), nil
 	}
-	// log.Debug(ctx, "Gateway is : %s", gateway)
-
-	//UC - Unicode message ; PM - Plaintext message
-	if msgreq.MessageType == "UC" {
-		if msgreq.Gateway == "1" {
-			msgreq.MessageText = UnicodemsgConvertCDAC(msgreq.MessageText)
-		} else {
-			msgreq.MessageText = UnicodemsgConvertNIC(msgreq.MessageText)
+	msgresponse, _, err := mh.ch.dispatchRequest(ctx, &msgreq, mh.ch.shouldPersist(msgreq.Priority))
+	if msgresponse == nil {
+		if _, ok := apierrors.Find[*SendCapThrottledError](err); ok {
+			return nil, connect.NewError(connect.CodeResourceExhausted, err)
 		}
-	} else {
-		msgreq.MessageType = "PM"
+		return nil, err
 	}
-	// log.Debug(ctx, "Message Type is : %s", msgreq.MessageType)
-
-	if msgreq.Priority == 1 || msgreq.Priority == 2 {
-		if gateway == "1" {
-
-			rsp, err := mh.ch.SendSMSCDAC(SMSParams{
-				mh.c.GetString("sms.cdac.username"),
-				mh.c.GetString("sms.cdac.password"),
-				msgreq.MessageText,
-				msgreq.SenderID,
-				msgreq.MobileNumbers,
-				mh.c.GetString("sms.cdac.securekey"),
-				msgreq.TemplateID, msgreq.MessageType})
-			if err != nil {
-				msgresponse := domain.MsgResponse{
-					CommunicationID:  msgreq.CommunicationID,
-					CompleteResponse: rsp,
-					ResponseCode:     "02",
-					ResponseText:     err.Error(),
-					ReferenceID:      "",
-				}
-				_, _ = mh.svc.SaveResponse(&ctx, &msgresponse)
-				// ch.vs.handleError(ctx, err)
-				// apierrors.HandleError(ctx, err)
-				return nil, err
-			}
-			log.Debug(ctx, "Response from SendSMSCDAC is : %s", rsp)
-
-			SMSResponse := rsp[:5]
-
-			if SMSResponse == "Error" {
-				pattern := `Error (\d+) : (.+)`
-				re := regexp.MustCompile(pattern)
-				matches := re.FindStringSubmatch(rsp)
-				if len(matches) < 3 {
-					//if error and format of the message is good
-					// fmt.Println("No matches found.")
-					//  customError := CustomError{Message: "Invalid Response"}
-					msgStoreRequest := mh.c.GetInt("sms.msgstorerequest")
-					if msgStoreRequest == 1 || msgreq.Priority == 3 || msgreq.Priority == 4 {
-						msgresponse := domain.MsgResponse{
-							CommunicationID:  msgreq.CommunicationID,
-							CompleteResponse: rsp,
-							ResponseCode:     "400",
-							ResponseText:     "Invalid Response",
-							ReferenceID:      "",
-						}
-						_, _ = mh.svc.SaveResponse(&ctx, &msgresponse)
-						// apierrors.HandleWithMessage(ctx, "Invalid Response")
-						return nil, err
-					}
-
-				} else {
-					//if error and format is not good
-					errorNumber := matches[1]
-					errorMessage := matches[2]
-					// customError := CustomError{Message: "401, " + errorMessage}
-					msgStoreRequest := mh.c.GetInt("sms.msgstorerequest")
-					if msgStoreRequest == 1 || msgreq.Priority == 3 || msgreq.Priority == 4 {
-						msgresponse := domain.MsgResponse{
-							CommunicationID:  msgreq.CommunicationID,
-							CompleteResponse: rsp,
-							ResponseCode:     errorNumber,
-							ResponseText:     errorMessage,
-							ReferenceID:      "",
-						}
-						_, _ = mh.svc.SaveResponse(&ctx, &msgresponse)
-					}
-					// ch.vs.handleError(ctx, customError)
-					// apierrors.HandleError(ctx, customError)
-					return nil, err
-				}
-			} else {
-
-				pattern := `^(\d{3}),MsgID = (\d+)`
-				re := regexp.MustCompile(pattern)
-				matches := re.FindStringSubmatch(rsp)
-				if len(matches) >= 3 {
-					//if success and format is good
-					responseCode := matches[1]
-					referenceID := matches[2]
-					msgStoreRequest := mh.c.GetInt("sms.msgstorerequest")
-					if msgStoreRequest == 1 || msgreq.Priority == 3 || msgreq.Priority == 4 {
-						msgresponse := domain.MsgResponse{
-							CommunicationID:  msgreq.CommunicationID,
-							CompleteResponse: rsp,
-							ResponseCode:     responseCode,
-							ResponseText:     "Submitted Successfully",
-							ReferenceID:      referenceID,
-						}
-						_, _ = mh.svc.SaveResponse(&ctx, &msgresponse)
-						// handleSuccess(ctx, msgresponse)
-						// rsp := response.NewCreateSMSResponse(&msgresponse)
-						// apiRsp := response.CreateSMSAPIResponse{
-						// 	StatusCodeAndMessage: port.CreateSuccess,
-						// 	Data:                 rsp,
-						// }
-						// handleCreateSuccess(ctx, apiRsp)
-						// return nil, err
-						return connect.NewResponse(
-							&v1.CreateSMSRequestHandlerResponse{}), nil
-					}
-
-				} else {
-					// msgStoreRequest := mh.c.MessageStoreRequest()
-					msgStoreRequest := mh.c.GetInt("sms.msgstorerequest")
-					if msgStoreRequest == 1 || msgreq.Priority == 3 || msgreq.Priority == 4 {
-						msgresponse := domain.MsgResponse{
-							CommunicationID:  msgreq.CommunicationID,
-							CompleteResponse: rsp,
-							ResponseCode:     "402",
-							ResponseText:     "Submitted Successfully",
-							ReferenceID:      "",
-						}
-						_, _ = mh.svc.SaveResponse(&ctx, &msgresponse)
-						// handleSuccess(ctx, msgresponse)
-						// rsp := response.NewCreateSMSResponse(&msgresponse)
-						// apiRsp := response.CreateSMSAPIResponse{
-						// 	StatusCodeAndMessage: port.CreateSuccess,
-						// 	Data:                 rsp,
-						// }
-						// handleCreateSuccess(ctx, apiRsp)
-						return connect.NewResponse(
-							&v1.CreateSMSRequestHandlerResponse{}), nil
-					}
-
-				}
-
-			}
-		} else if gateway == "2" {
-			var NICUsername, NICPassword string
-			if msgreq.SenderID == "INPOST" {
-				NICUsername = mh.c.GetString("sms.nic.INPOSTUserName")
-				NICPassword = mh.c.GetString("sms.nic.INPOSTPassword")
-			} else if (msgreq.SenderID == "DOPBNK") || (msgreq.SenderID == "DOPCBS") {
-				NICUsername = mh.c.GetString("sms.nic.DOPBNKUserName")
-				NICPassword = mh.c.GetString("sms.nic.DOPBNKPassword")
-			} else if msgreq.SenderID == "DOPPLI" {
-				NICUsername = mh.c.GetString("sms.nic.DOPPLIUserName")
-				NICPassword = mh.c.GetString("sms.nic.DOPPLIPassword")
-			}
-
-			// rsp, err := SendSMSNIC(NICUsername, NICPassword, msgreq.MessageText, msgreq.SenderID, msgreq.MobileNumbers, msgreq.EntityId, msgreq.TemplateID, msgreq.MessageType)
-			rsp, err := mh.ch.SendSMSNIC(SMSParams{
-				Username:     NICUsername,
-				Password:     NICPassword,
-				Message:      msgreq.MessageText,
-				SenderID:     msgreq.SenderID,
-				MobileNumber: msgreq.MobileNumbers,
-				TemplateID:   msgreq.TemplateID,
-				MessageType:  msgreq.MessageType,
-			})
-
-			if err != nil {
-				msgresponse := domain.MsgResponse{
-					CommunicationID:  msgreq.CommunicationID,
-					CompleteResponse: rsp,
-					ResponseCode:     "02",
-					ResponseText:     err.Error(),
-					ReferenceID:      "",
-				}
-				_, _ = mh.svc.SaveResponse(&ctx, &msgresponse)
-				// apierrors.HandleError(ctx, err)
-				return nil, err
-			}
-			pattern := `Request ID=(\d+)~code=([A-Z0-9]+)`
-			re := regexp.MustCompile(pattern)
-			matches := re.FindStringSubmatch(rsp)
-			if len(matches) >= 3 {
-				// If success and format is good
-				requestID := matches[1]
-				responseCode := matches[2]
-				// msgStoreRequest := mh.c.MessageStoreRequest()
-				msgStoreRequest := mh.c.GetInt("sms.msgstorerequest")
-				if msgStoreRequest == 1 || msgreq.Priority == 3 || msgreq.Priority == 4 {
-					msgresponse := domain.MsgResponse{
-						CommunicationID:  msgreq.CommunicationID,
-						CompleteResponse: rsp,
-						ResponseCode:     responseCode,
-						ResponseText:     "Submitted Successfully",
-						ReferenceID:      requestID,
-					}
-					_, _ = mh.svc.SaveResponse(&ctx, &msgresponse)
-					// handleSuccess(ctx, msgresponse)
-					// rsp := response.NewCreateSMSResponse(&msgresponse)
-					// apiRsp := response.CreateSMSAPIResponse{
-					// 	StatusCodeAndMessage: port.CreateSuccess,
-					// 	Data:                 rsp,
-					// }
-					// handleCreateSuccess(ctx, apiRsp)
-					// return nil, err
-					return connect.NewResponse(
-						&v1.CreateSMSRequestHandlerResponse{}), nil
-
-				}
-			}
-
-		} else {
-			// customError := CustomError{Message: "Invalid Gateway"}
-			// ch.vs.handleError(ctx, customError)
-			// apierrors.HandleWithMessage(ctx, "Invalid Gateway")
+	if err != nil {
+		log.Error(ctx, "Sending %s through gateway %s failed: %s", msgreq.CommunicationID, msgreq.Gateway, err.Error())
+		if _, ok := apierrors.Find[*GatewayTimeoutError](err); ok {
+			return nil, connect.NewError(connect.CodeDeadlineExceeded, err)
+		}
+		if _, ok := apierrors.Find[*GatewayMaintenanceError](err); ok {
+			return nil, connect.NewError(connect.CodeUnavailable, err)
 		}
-	} else {
-		// handleSuccess(ctx, "Stored Successfully")
-		// apiRsp := response.CreateSMSAPIResponse{
-		// 	StatusCodeAndMessage: port.CreateSuccess,
-		// 	// Data:                 rsp,
-		// }
-		// handleCreateSuccess(ctx, apiRsp)
-		return connect.NewResponse(
-			&v1.CreateSMSRequestHandlerResponse{}), nil
+		return nil, err
 	}
-	return
+	return connect.NewResponse(&v1.CreateSMSRequestHandlerResponse{
+		CommunicationId: msgresponse.CommunicationID,
+		ReferenceId:     msgresponse.ReferenceID,
+		ResponseCode:    msgresponse.ResponseCode,
+		ResponseText:    msgresponse.ResponseText,
+	}), nil
 }
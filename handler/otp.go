@@ -0,0 +1,352 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	log "MgApplication/api-log"
+	serverHandler "MgApplication/api-server/handler"
+	serverRoute "MgApplication/api-server/route"
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+	"MgApplication/handler/response"
+	repo "MgApplication/repo/postgres"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Error ids returned in the error.id field of OTP responses, so that callers can
+// tell the failure reasons apart without parsing messages.
+const (
+	OTPErrorExpired         = "EXPIRED"
+	OTPErrorTooManyAttempts = "TOO_MANY_ATTEMPTS"
+	OTPErrorMismatch        = "MISMATCH"
+	OTPErrorRateLimited     = apierrors.SourceRateLimited
+)
+
+// OTPHandler represents the HTTP handler for OTP generation and verification requests
+type OTPHandler struct {
+	*serverHandler.Base
+	svc       *repo.OTPRepository
+	sms       *MgApplicationHandler
+	templates *TemplateCache
+	c         *config.Config
+}
+
+// NewOTPHandler creates a new OTPHandler instance, its OTP template cache being registered in
+// registry
+func NewOTPHandler(svc *repo.OTPRepository, sms *MgApplicationHandler, registry *CacheRegistry, c *config.Config) *OTPHandler {
+	base := serverHandler.New("OTP").SetDescription("One time passwords generated, sent and verified by the gateway").SetOrder(3).SetPrefix("/v1").AddPrefix("/otp")
+	templates := NewTemplateCache(svc.FetchOTPTemplateRepo, c)
+	registry.Register("otp_templates", templates)
+	return &OTPHandler{
+		base,
+		svc,
+		sms,
+		templates,
+		c,
+	}
+}
+
+func (oh *OTPHandler) Routes() []serverRoute.Route {
+	return []serverRoute.Route{
+		serverRoute.POST("/generate", oh.GenerateOTPHandler).Name("Generate OTP"),
+		serverRoute.POST("/verify", oh.VerifyOTPHandler).Name("Verify OTP"),
+	}
+}
+
+type generateOTPRequest struct {
+	ApplicationID  string   `json:"application_id" validate:"required,numeric" example:"4"`
+	FacilityID     string   `json:"facility_id" validate:"required" example:"facility1"`
+	MobileNumber   string   `json:"mobile_number" validate:"required,mobile_number" example:"9000000000"`
+	TemplateValues []string `json:"template_values" validate:"omitempty" example:"Postal GIS Application"`
+}
+
+// GenerateOTPHandler godoc
+//
+//	@Summary		Generates and sends an OTP
+//	@Description	Generates a numeric OTP, renders it into the configured OTP template and sends it through the OTP (priority 1) path. Only the otp_reference is returned, never the OTP itself
+//	@Tags			OTP
+//	@ID				GenerateOTPHandler
+//	@Accept			json
+//	@Produce		json
+//	@Param			generateOTPRequest	body		generateOTPRequest				true	"Generate OTP Request"
+//	@Success		201					{object}	response.GenerateOTPAPIResponse	"OTP is generated and sent"
+//	@Failure		400					{object}	apierrors.APIErrorResponse		"Bad Request"
+//	@Failure		401					{object}	apierrors.APIErrorResponse		"Unauthorized"
+//	@Failure		403					{object}	apierrors.APIErrorResponse		"Application is not mapped to the OTP template"
+//	@Failure		404					{object}	apierrors.APIErrorResponse		"OTP template not found"
+//	@Failure		409					{object}	apierrors.APIErrorResponse		"An identical OTP request was sent moments ago"
+//	@Failure		422					{object}	apierrors.APIErrorResponse		"Binding or Validation error"
+//	@Failure		429					{object}	apierrors.APIErrorResponse		"RATE_LIMITED: too many OTPs generated for the mobile number"
+//	@Failure		500					{object}	apierrors.APIErrorResponse		"Internal server error"
+//	@Failure		502					{object}	apierrors.APIErrorResponse		"Bad Gateway"
+//	@Failure		504					{object}	apierrors.APIErrorResponse		"Gateway Timeout"
+//	@Router			/otp/generate [post]
+func (oh *OTPHandler) GenerateOTPHandler(sctx *serverRoute.Context, req generateOTPRequest) (*response.GenerateOTPAPIResponse, error) {
+
+	count, err := oh.svc.CountRecentOTPRepo(sctx.Ctx, req.MobileNumber, oh.c.GetDuration("sms.otp.ratelimitwindow"))
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in CountRecentOTPRepo function: %s", err.Error())
+		return nil, err
+	}
+	if count >= oh.c.GetInt("sms.otp.ratelimitcount") {
+		log.Warn(sctx.Ctx, "OTP generation rate limit reached for mobile number %s", req.MobileNumber)
+		// the window slides, so the oldest OTP counted may leave it sooner
+		resetAt := time.Now().Add(oh.c.GetDuration("sms.otp.ratelimitwindow"))
+		return nil, apierrors.NewBackpressureError(OTPErrorRateLimited, resetAt, "too many OTPs generated for this mobile number, try again later", nil)
+	}
+
+	// Expired OTPs are purged opportunistically; a failure here must not block generation
+	if purged, err := oh.svc.PurgeExpiredOTPRepo(sctx.Ctx, oh.c.GetDuration("sms.otp.purgeafter")); err != nil {
+		log.Error(sctx.Ctx, "Error in PurgeExpiredOTPRepo function: %s", err.Error())
+	} else if purged > 0 {
+		log.Debug(sctx.Ctx, "Purged %d expired OTPs", purged)
+	}
+
+	template, err := oh.templates.Get(sctx.Ctx, oh.c.GetString("sms.otp.templateid"))
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in FetchOTPTemplateRepo function: %s", err.Error())
+		return nil, err
+	}
+	if !isApplicationMapped(template.ApplicationID, req.ApplicationID) {
+		log.Error(sctx.Ctx, "Application %s is not mapped to the OTP template %s", req.ApplicationID, template.TemplateID)
+		return nil, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.HTTPErrorForbidden, "application is not mapped to the OTP template", nil)
+	}
+
+	code, err := generateOTPCode(oh.c.GetInt("sms.otp.length"))
+	if err != nil {
+		log.Error(sctx.Ctx, "Error while generating OTP: %s", err.Error())
+		return nil, err
+	}
+
+	message, err := renderOTPTemplate(template.TemplateFormat, code, oh.c.GetInt("sms.otp.varposition"), req.TemplateValues)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error while rendering OTP template: %s", err.Error())
+		return nil, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.HTTPErrorBadRequest, err.Error(), err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), oh.c.GetInt("sms.otp.bcryptcost"))
+	if err != nil {
+		log.Error(sctx.Ctx, "Error while hashing OTP: %s", err.Error())
+		return nil, err
+	}
+
+	msgotp, err := oh.svc.CreateOTPRepo(sctx.Ctx, &domain.MsgOTP{
+		OTPReference:  uuid.NewString(),
+		ApplicationID: req.ApplicationID,
+		MobileNumber:  req.MobileNumber,
+		OTPHash:       string(hash),
+		MaxAttempts:   oh.c.GetInt("sms.otp.maxattempts"),
+	}, oh.c.GetDuration("sms.otp.ttl"))
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in CreateOTPRepo function: %s", err.Error())
+		return nil, err
+	}
+
+	msgreq := domain.MsgRequest{
+		ApplicationID: req.ApplicationID,
+		FacilityID:    req.FacilityID,
+		Priority:      1,
+		MessageText:   message,
+		SenderID:      template.SenderID,
+		MobileNumbers: req.MobileNumber,
+		EntityId:      oh.c.GetString("sms.dltEntityID"),
+		TemplateID:    template.TemplateID,
+		Gateway:       template.Gateway,
+		MessageType:   template.MessageType,
+	}
+	msgresponse, cacheHit, err := oh.dispatchOTP(sctx.Ctx, msgotp.OTPReference, &msgreq)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error while dispatching OTP %s: %s", msgotp.OTPReference, err.Error())
+		return nil, err
+	}
+	if cacheHit {
+		// the OTP sent for the earlier request is the one the customer has to verify
+		msgotp, err = oh.svc.FetchActiveOTPRepo(sctx.Ctx, req.ApplicationID, req.MobileNumber)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.HTTPErrorConflict, "an identical OTP request was sent moments ago, try again later", err)
+		}
+		if err != nil {
+			log.Error(sctx.Ctx, "Error in FetchActiveOTPRepo function: %s", err.Error())
+			return nil, err
+		}
+	}
+
+	rsp := response.NewGenerateOTPResponse(&msgotp, msgresponse.CommunicationID)
+	rsp.CacheHit = cacheHit
+	apiRsp := response.GenerateOTPAPIResponse{
+		StatusCodeAndMessage: port.CreateSuccess,
+		Data:                 rsp,
+	}
+
+	log.Debug(sctx.Ctx, "GenerateOTPHandler response: %v", apiRsp)
+	return &apiRsp, nil
+}
+
+type verifyOTPRequest struct {
+	ApplicationID string `json:"application_id" validate:"required,numeric" example:"4"`
+	OTPReference  string `json:"otp_reference" validate:"required,uuid4" example:"5b0f4c3e-8f5e-4a3c-9a59-2d3c1f1a7b10"`
+	MobileNumber  string `json:"mobile_number" validate:"required,mobile_number" example:"9000000000"`
+	OTP           string `json:"otp" validate:"required,numeric" example:"482913"`
+}
+
+// VerifyOTPHandler godoc
+//
+//	@Summary		Verifies an OTP
+//	@Description	Verifies the supplied OTP against the otp_reference returned by generate. Each call consumes an attempt; a verified OTP cannot be reused
+//	@Tags			OTP
+//	@ID				VerifyOTPHandler
+//	@Accept			json
+//	@Produce		json
+//	@Param			verifyOTPRequest	body		verifyOTPRequest				true	"Verify OTP Request"
+//	@Success		200					{object}	response.VerifyOTPAPIResponse	"OTP is verified"
+//	@Failure		400					{object}	apierrors.APIErrorResponse		"Bad Request"
+//	@Failure		401					{object}	apierrors.APIErrorResponse		"MISMATCH: OTP does not match"
+//	@Failure		404					{object}	apierrors.APIErrorResponse		"OTP reference not found or already verified"
+//	@Failure		410					{object}	apierrors.APIErrorResponse		"EXPIRED: OTP has expired"
+//	@Failure		422					{object}	apierrors.APIErrorResponse		"Binding or Validation error"
+//	@Failure		429					{object}	apierrors.APIErrorResponse		"TOO_MANY_ATTEMPTS: verification attempts exhausted"
+//	@Failure		500					{object}	apierrors.APIErrorResponse		"Internal server error"
+//	@Router			/otp/verify [post]
+func (oh *OTPHandler) VerifyOTPHandler(sctx *serverRoute.Context, req verifyOTPRequest) (*response.VerifyOTPAPIResponse, error) {
+
+	msgotp, err := oh.svc.FetchOTPRepo(sctx.Ctx, req.OTPReference)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in FetchOTPRepo function: %s", err.Error())
+		return nil, err
+	}
+	if msgotp.ApplicationID != req.ApplicationID {
+		log.Error(sctx.Ctx, "OTP %s does not belong to application %s", req.OTPReference, req.ApplicationID)
+		return nil, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.HTTPErrorNotFound, "OTP reference not found", nil)
+	}
+	if msgotp.Expired {
+		return nil, otpError(http.StatusGone, OTPErrorExpired, "OTP has expired, generate a new OTP")
+	}
+
+	consumed, err := oh.svc.ConsumeOTPAttemptRepo(sctx.Ctx, req.OTPReference)
+	if err != nil {
+		log.Error(sctx.Ctx, "Error in ConsumeOTPAttemptRepo function: %s", err.Error())
+		return nil, err
+	}
+	if !consumed {
+		return nil, otpError(http.StatusTooManyRequests, OTPErrorTooManyAttempts, "maximum OTP verification attempts exceeded, generate a new OTP")
+	}
+
+	// bcrypt compares in constant time; the mobile number is compared the same way
+	// so that a mismatch on either cannot be told apart by timing.
+	mobileMatched := subtle.ConstantTimeCompare([]byte(msgotp.MobileNumber), []byte(req.MobileNumber)) == 1
+	otpMatched := bcrypt.CompareHashAndPassword([]byte(msgotp.OTPHash), []byte(req.OTP)) == nil
+	if !mobileMatched || !otpMatched {
+		return nil, otpError(http.StatusUnauthorized, OTPErrorMismatch, "OTP does not match")
+	}
+
+	if err := oh.svc.MarkOTPVerifiedRepo(sctx.Ctx, req.OTPReference); err != nil {
+		log.Error(sctx.Ctx, "Error in MarkOTPVerifiedRepo function: %s", err.Error())
+		return nil, err
+	}
+
+	apiRsp := response.VerifyOTPAPIResponse{
+		StatusCodeAndMessage: port.VerifySuccess,
+		Data:                 response.NewVerifyOTPResponse(req.OTPReference),
+	}
+
+	log.Debug(sctx.Ctx, "VerifyOTPHandler response: %v", apiRsp)
+	return &apiRsp, nil
+}
+
+// dispatchOTP sends the rendered OTP through the priority 1 path shared with the SMS request
+// handlers, stored following shouldPersist. An OTP that was not sent is deleted, so that it
+// neither counts towards the generation rate limit nor can be verified. That includes an OTP
+// answered from the OTP send cache, cacheHit being set: the customer was sent the OTP of the
+// earlier request instead.
+func (oh *OTPHandler) dispatchOTP(ctx context.Context, otpReference string, msgreq *domain.MsgRequest) (*domain.MsgResponse, bool, error) {
+	msgresponse, cacheHit, err := oh.sms.dispatchRequest(ctx, msgreq, oh.sms.shouldPersist(msgreq.Priority))
+	if msgresponse != nil && err == nil && !cacheHit {
+		return msgresponse, false, nil
+	}
+	// deleted even when the client left, which is why the OTP was not sent
+	if delErr := oh.svc.DeleteOTPRepo(context.WithoutCancel(ctx), otpReference); delErr != nil {
+		log.Error(ctx, "Error in DeleteOTPRepo function for unsent OTP %s: %s", otpReference, delErr.Error())
+	}
+	if throttled, ok := apierrors.Find[*SendCapThrottledError](err); ok {
+		return nil, false, throttled.AppError()
+	}
+	if msgresponse == nil {
+		return nil, false, err
+	}
+	if rateLimited, ok := apierrors.Find[*GatewayRateLimitedError](err); ok {
+		return nil, false, apierrors.NewBackpressureError(apierrors.SourceGatewayRateLimited, time.Now().Add(rateLimited.RetryAfter), "OTP could not be sent, the gateway is rate limiting requests", rateLimited)
+	}
+	if timeout, ok := apierrors.Find[*GatewayTimeoutError](err); ok {
+		return nil, false, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.HTTPErrorGatewayTimeout, "OTP could not be sent, the gateway did not answer in time", timeout)
+	}
+	if maintenance, ok := apierrors.Find[*GatewayMaintenanceError](err); ok {
+		return nil, false, apierrors.NewBackpressureError(apierrors.SourceGatewayMaintenance, time.Now().Add(maintenance.RetryAfter), "OTP could not be sent, the gateway is under maintenance", maintenance)
+	}
+	if err != nil {
+		return nil, false, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.HTTPErrorBadGateway, "OTP could not be sent", err)
+	}
+	return msgresponse, true, nil
+}
+
+// otpError builds an AppError whose id carries one of the OTPError* reasons
+func otpError(statusCode int, id string, message string) error {
+	appError := apierrors.NewAppErrorWithId(message, statusCode, nil, id)
+	return &appError
+}
+
+// generateOTPCode returns a random numeric code of the given length
+func generateOTPCode(length int) (string, error) {
+	if length <= 0 {
+		return "", errors.New("OTP length must be greater than zero")
+	}
+	var code strings.Builder
+	for i := 0; i < length; i++ {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code.WriteString(digit.String())
+	}
+	return code.String(), nil
+}
+
+// renderOTPTemplate fills the {#var#} placeholders of a template. The placeholder at
+// otpPosition (1-based) receives the OTP and the others are filled from values in order.
+func renderOTPTemplate(format string, otp string, otpPosition int, values []string) (string, error) {
+	placeholders := templateVarCount(format)
+	if otpPosition < 1 || otpPosition > placeholders {
+		return "", fmt.Errorf("OTP template has %d placeholders, cannot place OTP at position %d", placeholders, otpPosition)
+	}
+	if len(values) != placeholders-1 {
+		return "", fmt.Errorf("OTP template expects %d template_values, but received %d", placeholders-1, len(values))
+	}
+
+	withOTP := make([]string, 0, placeholders)
+	withOTP = append(withOTP, values[:otpPosition-1]...)
+	withOTP = append(withOTP, otp)
+	withOTP = append(withOTP, values[otpPosition-1:]...)
+	return renderTemplate(format, withOTP)
+}
+
+// isApplicationMapped checks whether applicationID is one of the comma separated ids of a template
+func isApplicationMapped(templateApplicationIDs string, applicationID string) bool {
+	for _, id := range strings.Split(templateApplicationIDs, ",") {
+		if strings.TrimSpace(id) == applicationID {
+			return true
+		}
+	}
+	return false
+}
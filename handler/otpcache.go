@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var OTPCacheHitsTotal = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "sms_otp_cache_hits_total",
+		Help: "Total number of OTP requests answered with an earlier successful send",
+	},
+)
+
+// OTPSendCache remembers successful OTP sends for sms.otpcache.windowseconds, keyed by application,
+// mobile number and template. An identical request within the window is answered with the earlier
+// result instead of sending the customer a second OTP, and a request arriving while the first is
+// still waiting for the gateway waits for its result. Failed sends are not remembered. The least
+// recently used sends are evicted above sms.otpcache.maxentries.
+type OTPSendCache struct {
+	window     time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+	now     func() time.Time
+}
+
+type otpSendEntry struct {
+	key      string
+	done     chan struct{}       // closed once the send has finished
+	response *domain.MsgResponse // nil while sending and when the send failed
+	sentAt   time.Time
+}
+
+// otpSendClaim is held by the request sending an OTP until it has the result of the gateway
+type otpSendClaim struct {
+	cache *OTPSendCache
+	entry *otpSendEntry
+}
+
+// NewOTPSendCache creates a new OTPSendCache instance using the sms.otpcache configuration. The
+// window defaults to 30 seconds, a window of 0 disables the cache.
+func NewOTPSendCache(c *config.Config) *OTPSendCache {
+	window := 30 * time.Second
+	if c.Exists("sms.otpcache.windowseconds") {
+		window = time.Duration(c.GetInt("sms.otpcache.windowseconds")) * time.Second
+	}
+	maxEntries := c.GetInt("sms.otpcache.maxentries")
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	return &OTPSendCache{
+		window:     window,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// otpSendKey identifies identical OTP requests
+func otpSendKey(req *domain.MsgRequest) string {
+	return strings.Join([]string{req.ApplicationID, req.MobileNumbers, req.TemplateID}, "|")
+}
+
+// Claim returns the response of an identical send that succeeded within the window. Otherwise the
+// caller sends the OTP and must report the result with Done on the returned claim. When an
+// identical send is in progress, Claim waits for it until ctx is done. Both results are nil when
+// the cache is disabled.
+func (oc *OTPSendCache) Claim(ctx context.Context, req *domain.MsgRequest) (*domain.MsgResponse, *otpSendClaim, error) {
+	if oc == nil || oc.window <= 0 {
+		return nil, nil, nil
+	}
+	key := otpSendKey(req)
+	for {
+		oc.mu.Lock()
+		if el, ok := oc.entries[key]; ok {
+			entry := el.Value.(*otpSendEntry)
+			select {
+			case <-entry.done:
+				if entry.response != nil && oc.now().Sub(entry.sentAt) < oc.window {
+					oc.order.MoveToFront(el)
+					oc.mu.Unlock()
+					OTPCacheHitsTotal.Inc()
+					return entry.response, nil, nil
+				}
+				oc.remove(el)
+			default:
+				oc.mu.Unlock()
+				select {
+				case <-entry.done:
+					continue
+				case <-ctx.Done():
+					return nil, nil, ctx.Err()
+				}
+			}
+		}
+
+		entry := &otpSendEntry{key: key, done: make(chan struct{})}
+		oc.entries[key] = oc.order.PushFront(entry)
+		for oc.order.Len() > oc.maxEntries {
+			oc.remove(oc.order.Back())
+		}
+		oc.mu.Unlock()
+		return nil, &otpSendClaim{cache: oc, entry: entry}, nil
+	}
+}
+
+// remove evicts the entry of el, the caller holds mu
+func (oc *OTPSendCache) remove(el *list.Element) {
+	entry := oc.order.Remove(el).(*otpSendEntry)
+	if oc.entries[entry.key] == el {
+		delete(oc.entries, entry.key)
+	}
+}
+
+// Done records the result of the send, nil when it failed, and releases the requests waiting for
+// it. A failed send is forgotten so the next request sends again.
+func (cl *otpSendClaim) Done(response *domain.MsgResponse) {
+	if cl == nil {
+		return
+	}
+	oc := cl.cache
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	cl.entry.response = response
+	cl.entry.sentAt = oc.now()
+	if el, ok := oc.entries[cl.entry.key]; ok && response == nil && el.Value == cl.entry {
+		oc.remove(el)
+	}
+	close(cl.entry.done)
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newOTPCacheHandler creates a handler caching OTP sends for windowSeconds, sending through a CDAC
+// server answering with the responses in turn, after delay. It returns the number of sends the
+// server received.
+func newOTPCacheHandler(t *testing.T, windowSeconds int, delay time.Duration, responses ...string) (*MgApplicationHandler, *atomic.Int32) {
+	t.Helper()
+	var sends atomic.Int32
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := sends.Add(1)
+		time.Sleep(delay)
+		_, _ = w.Write([]byte(responses[min(int(n), len(responses))-1]))
+	}))
+	t.Cleanup(cdac.Close)
+
+	c := config.NewConfig(viper.New())
+	c.Set("sms.msgstorerequest", 1)
+	c.Set("sms.cdac.url", cdac.URL)
+	c.Set("sms.cdac.username", "appostsms")
+	c.Set("sms.cdac.password", "cdacsecret")
+	c.Set("sms.cdac.securekey", "c7d427c9-63e7-4eec-a227-3ef840a75269")
+	c.Set("sms.otpcache.windowseconds", windowSeconds)
+
+	ch, _ := newTestSMSHandler(c)
+	return ch, &sends
+}
+
+type otpCacheResponse struct {
+	Data struct {
+		CommunicationID string `json:"communication_id"`
+		ReferenceID     string `json:"reference_id"`
+		CacheHit        bool   `json:"cache_hit"`
+	} `json:"data"`
+}
+
+// postOTPRequest sends an OTP for mobileNumber and returns the status with the response
+func postOTPRequest(t *testing.T, ch *MgApplicationHandler, mobileNumber string) (int, otpCacheResponse) {
+	rec := postSMSRequest(ch, otpRequestBody(mobileNumber))
+	var rsp otpCacheResponse
+	if rec.Code == http.StatusCreated {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	}
+	return rec.Code, rsp
+}
+
+func TestOTPCacheHit(t *testing.T) {
+	ch, sends := newOTPCacheHandler(t, 30, 0, "402,MsgID = 150920241726381202115")
+
+	code, first := postOTPRequest(t, ch, "9000000011")
+	require.Equal(t, http.StatusCreated, code)
+	assert.False(t, first.Data.CacheHit)
+
+	code, retry := postOTPRequest(t, ch, "9000000011")
+	require.Equal(t, http.StatusCreated, code)
+	assert.True(t, retry.Data.CacheHit)
+	assert.Equal(t, first.Data.CommunicationID, retry.Data.CommunicationID)
+	assert.Equal(t, "150920241726381202115", retry.Data.ReferenceID)
+	assert.Equal(t, int32(1), sends.Load())
+
+	// another recipient is not an identical request
+	code, other := postOTPRequest(t, ch, "9000000012")
+	require.Equal(t, http.StatusCreated, code)
+	assert.False(t, other.Data.CacheHit)
+	assert.Equal(t, int32(2), sends.Load())
+}
+
+func TestOTPCacheMissAfterWindow(t *testing.T) {
+	ch, sends := newOTPCacheHandler(t, 30, 0, "402,MsgID = 150920241726381202116", "402,MsgID = 150920241726381202117")
+	now := time.Now()
+	ch.otpCache.now = func() time.Time { return now }
+
+	code, first := postOTPRequest(t, ch, "9000000013")
+	require.Equal(t, http.StatusCreated, code)
+
+	now = now.Add(31 * time.Second)
+	code, retry := postOTPRequest(t, ch, "9000000013")
+	require.Equal(t, http.StatusCreated, code)
+	assert.False(t, retry.Data.CacheHit)
+	assert.NotEqual(t, first.Data.CommunicationID, retry.Data.CommunicationID)
+	assert.Equal(t, "150920241726381202117", retry.Data.ReferenceID)
+	assert.Equal(t, int32(2), sends.Load())
+}
+
+func TestOTPCacheBypassedAfterFailure(t *testing.T) {
+	ch, sends := newOTPCacheHandler(t, 30, 0, "Error 401 : Invalid credentials", "402,MsgID = 150920241726381202118")
+
+	code, _ := postOTPRequest(t, ch, "9000000014")
+	assert.NotEqual(t, http.StatusCreated, code)
+
+	code, retry := postOTPRequest(t, ch, "9000000014")
+	require.Equal(t, http.StatusCreated, code)
+	assert.False(t, retry.Data.CacheHit)
+	assert.Equal(t, int32(2), sends.Load())
+}
+
+func TestOTPCacheDisabled(t *testing.T) {
+	ch, sends := newOTPCacheHandler(t, 0, 0, "402,MsgID = 150920241726381202119")
+
+	for range 2 {
+		code, rsp := postOTPRequest(t, ch, "9000000015")
+		require.Equal(t, http.StatusCreated, code)
+		assert.False(t, rsp.Data.CacheHit)
+	}
+	assert.Equal(t, int32(2), sends.Load())
+}
+
+func TestOTPCacheConcurrentFirstRequests(t *testing.T) {
+	// the gateway is slow, the retries arrive while the first request is waiting for it
+	ch, sends := newOTPCacheHandler(t, 30, 300*time.Millisecond, "402,MsgID = 150920241726381202120")
+
+	const requests = 5
+	var wg sync.WaitGroup
+	codes := make([]int, requests)
+	rsps := make([]otpCacheResponse, requests)
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i], rsps[i] = postOTPRequest(t, ch, "9000000016")
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), sends.Load())
+	hits := 0
+	for i := range requests {
+		assert.Equal(t, http.StatusCreated, codes[i])
+		assert.Equal(t, rsps[0].Data.CommunicationID, rsps[i].Data.CommunicationID)
+		if rsps[i].Data.CacheHit {
+			hits++
+		}
+	}
+	assert.Equal(t, requests-1, hits)
+}
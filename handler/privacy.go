@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"time"
+
+	auth "MgApplication/api-authz"
+	apierrors "MgApplication/api-errors"
+)
+
+// adminUserID returns the id of the admin caller of ctx, the subject of its verified bearer token
+func adminUserID(ctx context.Context) string {
+	if claims := auth.ClaimsFrom(ctx); claims != nil {
+		return claims.Subject
+	}
+	return ""
+}
+
+// erasureTombstone derives the value that replaces an erased mobile number. The HMAC keyed
+// with privacy.erasure.tombstonekey keeps erased numbers from being recovered by hashing every
+// possible number, while the same number always gets the same tombstone, which identifies its
+// erasures. Tombstones are negative so that they can never be mistaken for a mobile number.
+func erasureTombstone(key string, mobileNumber string) int64 {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(normalizeMobileNumber(mobileNumber)))
+	sum := mac.Sum(nil)
+	// 40 bits keep the tombstone within the 15 characters of msg_otp.mobile_number
+	return -int64(binary.BigEndian.Uint64(sum[:8])>>24) - 1
+}
+
+// storedMobileNumberForms returns the forms a mobile number may be stored in, with and without
+// the 91, +91 and 0 prefixes
+func storedMobileNumberForms(mobileNumber string) []string {
+	mobileNumber = normalizeMobileNumber(mobileNumber)
+	return []string{mobileNumber, "91" + mobileNumber, "+91" + mobileNumber, "0" + mobileNumber}
+}
+
+// parseErasurePeriod parses the optional DD-MM-YYYY bounds of an erasure, rejecting periods
+// ending before they start
+func parseErasurePeriod(from string, to string) (*time.Time, *time.Time, error) {
+	var fromDate, toDate *time.Time
+	if from != "" {
+		date, _ := time.Parse("02-01-2006", from)
+		fromDate = &date
+	}
+	if to != "" {
+		date, _ := time.Parse("02-01-2006", to)
+		toDate = &date
+	}
+	if fromDate != nil && toDate != nil && toDate.Before(*fromDate) {
+		return nil, nil, apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.HTTPErrorBadRequest, "to_date should be after from_date", nil)
+	}
+	return fromDate, toDate, nil
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	auth "MgApplication/api-authz"
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	serverRoute "MgApplication/api-server/route"
+	validation "MgApplication/api-validation"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErasureTombstone(t *testing.T) {
+	tombstone := erasureTombstone("key", "9300000031")
+	assert.Negative(t, tombstone)
+	// msg_otp.mobile_number holds 15 characters
+	assert.LessOrEqual(t, len(strconv.FormatInt(tombstone, 10)), 15)
+
+	for _, form := range storedMobileNumberForms("9300000031") {
+		assert.Equal(t, tombstone, erasureTombstone("key", form), form)
+	}
+	assert.NotEqual(t, tombstone, erasureTombstone("other key", "9300000031"))
+	assert.NotEqual(t, tombstone, erasureTombstone("key", "9300000032"))
+}
+
+func TestParseErasurePeriod(t *testing.T) {
+	from, to, err := parseErasurePeriod("01-09-2024", "10-09-2024")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-09-01", from.Format("2006-01-02"))
+	assert.Equal(t, "2024-09-10", to.Format("2006-01-02"))
+
+	from, to, err = parseErasurePeriod("", "")
+	require.NoError(t, err)
+	assert.Nil(t, from)
+	assert.Nil(t, to)
+
+	_, _, err = parseErasurePeriod("10-09-2024", "01-09-2024")
+	assert.Error(t, err)
+}
+
+// The erasures rejected before anything is erased
+func TestPrivacyErasureRejected(t *testing.T) {
+	ah := &AdminHandler{c: config.NewConfig(viper.New())}
+	admin := auth.WithClaims(context.Background(), &auth.Claims{Subject: "dpo-1", Scope: "admin"})
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		req  privacyErasureRequest
+	}{
+		{"without confirm", admin, privacyErasureRequest{MobileNumber: "9300000031"}},
+		{"without user", auth.WithClaims(context.Background(), &auth.Claims{Scope: "admin"}), privacyErasureRequest{MobileNumber: "9300000031", Confirm: true}},
+		{"period ending before it starts", admin, privacyErasureRequest{MobileNumber: "9300000031", FromDate: "10-09-2024", ToDate: "01-09-2024", Confirm: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rsp, err := ah.PrivacyErasureHandler(&serverRoute.Context{Ctx: tt.ctx}, tt.req)
+			assert.Nil(t, rsp)
+			var appErr *apierrors.AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Equal(t, http.StatusBadRequest, appErr.Code)
+		})
+	}
+
+	assert.Error(t, validation.ValidateStruct(privacyErasureRequest{MobileNumber: "12345", Confirm: true}))
+	assert.NoError(t, validation.ValidateStruct(privacyErasureRequest{MobileNumber: "9300000031", FromDate: "01-09-2024", Confirm: true}))
+}
@@ -108,12 +108,11 @@ func (ph *ProviderHandler) CreateMessageProviderHandler(ctx *gin.Context) {
 
 	rsp := response.NewCreateSMSProviderResponse(&provider)
 	apiRsp := response.CreateSMSProviderAPIResponse{
-		StatusCodeAndMessage: port.CreateSuccess,
-		Data:                 rsp,
+		Data: rsp,
 	}
 
 	log.Debug(ctx, "CreateMessageProviderHandler response: %v", apiRsp)
-	handleCreateSuccess(ctx, apiRsp)
+	response.Created(ctx, &apiRsp)
 }
 
 type listMessageProviderRequest struct {
@@ -172,16 +171,14 @@ func (ph *ProviderHandler) ListMessageProvidersHandler(ctx *gin.Context) {
 
 	total := len(providers)
 	rsp := response.NewListSMSProvidersResponse(providers)
-	metadata := port.NewMetaDataResponse(req.Skip, req.Limit, total)
+	metadata := port.NewMetaDataResponse(uint64(req.Skip), uint64(req.Limit), total)
 
 	apiRsp := response.ListSMSProvidersAPIResponse{
-		StatusCodeAndMessage: port.ListSuccess,
-		MetaDataResponse:     metadata,
-		Data:                 rsp,
+		Data: rsp,
 	}
 
 	log.Debug(ctx, "ListMessageProvidersHandler response: %v", apiRsp)
-	handleSuccess(ctx, apiRsp)
+	response.List(ctx, &apiRsp, metadata)
 }
 
 type fetchMessageProviderRequest struct {
@@ -240,13 +237,12 @@ func (ph *ProviderHandler) FetchMessageProviderHandler(ctx *gin.Context) {
 	metadata := port.NewMetaDataResponse(0, 0, total)
 
 	apiRsp := response.FetchSMSProviderAPIResponse{
-		StatusCodeAndMessage: port.FetchSuccess,
-		MetaDataResponse:     metadata,
-		Data:                 rsp,
+		MetaDataResponse: metadata,
+		Data:             rsp,
 	}
 
 	log.Debug(ctx, "FetchMessageProviderHandler response: %v", apiRsp)
-	handleSuccess(ctx, apiRsp)
+	response.OK(ctx, &apiRsp)
 }
 
 type updateMessageProviderRequest struct {
@@ -323,12 +319,11 @@ func (ph *ProviderHandler) UpdateMessageProviderHandler(ctx *gin.Context) {
 
 	rsp := response.NewUpdateSMSProviderResponse(&provider)
 	apiRsp := response.UpdateSMSProviderAPIResponse{
-		StatusCodeAndMessage: port.UpdateSuccess,
-		Data:                 rsp,
+		Data: rsp,
 	}
 
 	log.Debug(ctx, "UpdateMessageProviderHandler response: %v", apiRsp)
-	handleSuccess(ctx, apiRsp)
+	response.Updated(ctx, &apiRsp)
 }
 
 type toggleMessageProviderStatusRequest struct {
@@ -383,11 +378,10 @@ func (ch *ProviderHandler) ToggleMessageProviderStatusHandler(ctx *gin.Context)
 	}
 
 	apiRsp := response.ToggleProviderStatusAPIResponse{
-		StatusCodeAndMessage: port.UpdateSuccess,
 		//MetaDataResponse:     metadata,
 		Data: rsp,
 	}
 
 	log.Debug(ctx, "ToggleMessageProviderStatusHandler response: %v", apiRsp)
-	handleSuccess(ctx, apiRsp)
+	response.Updated(ctx, &apiRsp)
 }
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	config "MgApplication/api-config"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sendOTPThroughCDAC sends an OTP through a CDAC server answering with gatewayResponse and
+// returns the data of the API response
+func sendOTPThroughCDAC(t *testing.T, gatewayResponse string, configure func(c *config.Config)) map[string]any {
+	t.Helper()
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(gatewayResponse))
+	}))
+	defer cdac.Close()
+
+	c := config.NewConfig(viper.New())
+	c.Set("sms.msgstorerequest", 1)
+	c.Set("sms.cdac.url", cdac.URL)
+	c.Set("sms.cdac.username", "appostsms")
+	c.Set("sms.cdac.password", "cdacsecret")
+	c.Set("sms.cdac.securekey", "c7d427c9-63e7-4eec-a227-3ef840a75269")
+	configure(c)
+
+	ch, store := newTestSMSHandler(c)
+	rec := postSMSRequest(ch, otpRequestBody("9000000001"))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, 1, store.savedRequests)
+
+	var rsp struct {
+		Data map[string]any `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	return rsp.Data
+}
+
+func TestCreateSMSRawResponseOmittedByDefault(t *testing.T) {
+	data := sendOTPThroughCDAC(t, "402,MsgID = 060320251741252969158appostsms", func(c *config.Config) {})
+
+	assert.Equal(t, "060320251741252969158", data["reference_id"])
+	assert.NotContains(t, data, "complete_response")
+}
+
+func TestCreateSMSRawResponseIncludedInDebug(t *testing.T) {
+	data := sendOTPThroughCDAC(t, "402,MsgID = 060320251741252969158appostsms key=c7d427c9-63e7-4eec-a227-3ef840a75269 auth=cdacsecret", func(c *config.Config) {
+		c.Set("sms.debug.includeRaw", true)
+	})
+
+	assert.Equal(t, "402,MsgID = 060320251741252969158appostsms key=[REDACTED] auth=[REDACTED]", data["complete_response"])
+}
+
+func TestCreateSMSRawResponseOmittedInProd(t *testing.T) {
+	data := sendOTPThroughCDAC(t, "402,MsgID = 060320251741252969158appostsms", func(c *config.Config) {
+		c.Set("sms.debug.includeRaw", true)
+		c.Set("info.env", "prod")
+	})
+
+	assert.NotContains(t, data, "complete_response")
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	apierrors "MgApplication/api-errors"
+)
+
+// referenceTag is the tag of the field error of a request field referencing an entity that does
+// not exist
+const referenceTag = "exists"
+
+// referenceExists reports whether the entity with id exists
+type referenceExists func(ctx context.Context, id string) (bool, error)
+
+// reference is a field of a request that references an entity by its id, such as the
+// application_id of a template
+type reference struct {
+	Field  string
+	Entity string
+	ID     string
+	Exists referenceExists
+}
+
+// checkReferences checks, in order, that the entities the fields of a request reference exist.
+// It is run after the validation of the request, the format of the ids being checked by their
+// validate tags. A reference to an entity that does not exist fails with a 422 AppError whose
+// field error names the field with the exists tag. A failed lookup is returned as is. References
+// without an id are left to the required tag.
+func checkReferences(ctx context.Context, refs ...reference) error {
+	for _, ref := range refs {
+		if ref.ID == "" {
+			continue
+		}
+		exists, err := ref.Exists(ctx, ref.ID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			msg := fmt.Sprintf("%s %s does not reference an existing %s", ref.Field, ref.ID, ref.Entity)
+			appErr := apierrors.NewAppError(msg, http.StatusUnprocessableEntity, errors.New(msg))
+			appErr.SetFieldErrors([]apierrors.FieldError{appErr.NewFieldError(ref.Field, ref.ID, msg, referenceTag)})
+			return &appErr
+		}
+	}
+	return nil
+}
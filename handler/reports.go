@@ -13,19 +13,22 @@ import (
 	config "MgApplication/api-config"
 	apierrors "MgApplication/api-errors"
 	log "MgApplication/api-log"
+	serverResponse "MgApplication/api-server/response"
 	validation "MgApplication/api-validation"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ReportsHandler struct {
-	svc *repo.ReportsRepository
-	c   *config.Config
+	svc  *repo.ReportsRepository
+	text *MessageTextGuard
+	c    *config.Config
 }
 
 func NewReportsHandler(svc *repo.ReportsRepository, c *config.Config) *ReportsHandler {
 	return &ReportsHandler{
 		svc,
+		NewMessageTextGuard(NewLogMessageTextAuditor(), c),
 		c,
 	}
 }
@@ -59,30 +62,38 @@ func (ch *ReportsHandler) SMSDashboardHandler(ctx *gin.Context) {
 
 	rsp := response.NewSMSDashboardResponse(&data)
 	apiRsp := response.SMSDashboardAPIResponse{
-		StatusCodeAndMessage: port.FetchSuccess,
 		//MetaDataResponse:     metadata,
 		Data: rsp,
 	}
 
 	log.Debug(ctx, "SMSDashboardHandler Response: %v ", apiRsp)
-	handleSuccess(ctx, apiRsp)
+	response.OK(ctx, &apiRsp)
 }
 
 type sentSMSStatusReportRequest struct {
-	FromDate string `form:"from-date" validate:"required,date_dd_mm_yyyy" example:"01-01-2008"`
-	ToDate   string `form:"to-date" validate:"required,date_dd_mm_yyyy" example:"18-06-2024"`
+	FromDate        string `form:"from-date" validate:"required,date_dd_mm_yyyy" example:"01-01-2008"`
+	ToDate          string `form:"to-date" validate:"required,date_dd_mm_yyyy" example:"18-06-2024"`
+	ClientReference string `form:"client-reference" validate:"omitempty,max=64,string_field=64" example:"BKG20240001"`
 	port.MetaDataRequest
+	// Stream answers the whole report, unpaginated, as a bare JSON array written as it is read
+	Stream bool `form:"stream" example:"false"`
+	// Format ndjson answers the whole report, unpaginated, one JSON object per line written as it is read
+	Format string `form:"format" validate:"omitempty,oneof=json ndjson" enum:"json,ndjson" example:"json"`
 }
 
 // SentSMSStatusReport godoc
 //
 //	@Summary		Get all SMS requests
-//	@Description	Fetches all SMS requests
+//	@Description	Fetches all SMS requests with the description of their gateway response code, optionally only those with a client reference. Message text is masked unless the caller has the sms.text.read scope.
+//	@Description	With stream=true the whole report, ignoring skip and limit, is answered as a bare JSON array of rows written as they are read. An error after the first row ends the array with an {"error": "..."} element and sets the X-Stream-Status trailer to 500.
+//	@Description	With format=ndjson the whole report, ignoring skip and limit, is answered as application/x-ndjson, one row per line written as it is read, so that large exports can be processed incrementally. An error after the first row ends the lines with an {"error": "..."} line and sets the X-Stream-Status trailer to 500.
 //	@Tags			Reports
 //	@ID				SentSMSStatusReportHandler
 //	@Accept			json
 //	@Produce		json
+//	@Produce		application/x-ndjson
 //	@Param			sentSMSStatusReportRequest	query		sentSMSStatusReportRequest				true	"SMS Report Request"
+//	@Param			Authorization				header		string									false	"Bearer token, the sms.text.read scope reveals the message text"
 //	@Success		200							{object}	response.SMSSentStatusReportAPIResponse	"All message requests are retrieved"
 //	@Failure		400							{object}	apierrors.APIErrorResponse				"Bad Request"
 //	@Failure		401							{object}	apierrors.APIErrorResponse				"Unauthorized"
@@ -122,25 +133,53 @@ func (ch *ReportsHandler) SentSMSStatusReportHandler(ctx *gin.Context) {
 		return
 	}
 
-	smsreport, err := ch.svc.SMSSentStatusReportRepo(ctx, fromDate, toDate, req.MetaDataRequest)
+	if req.Stream || req.Format == "ndjson" {
+		ch.streamSentSMSStatusReport(ctx, fromDate, toDate, req.ClientReference, req.Format)
+		return
+	}
+
+	smsreport, err := ch.svc.SMSSentStatusReportRepo(ctx, fromDate, toDate, req.ClientReference, req.MetaDataRequest)
 	if err != nil {
 		apierrors.HandleDBError(ctx, err)
 		log.Error(ctx, "Error in SMSSentStatusReportRepo function: %s", err.Error())
 		return
 	}
 
+	describeGatewayCodes(smsreport)
+
 	total := len(smsreport)
-	rsp := response.NewSMSSentStatusReportResponse(smsreport)
-	metadata := port.NewMetaDataResponse(req.Skip, req.Limit, total)
+	rsp := response.NewSMSSentStatusReportResponse(smsreport, ch.text.Policy(ctx))
+	metadata := port.NewMetaDataResponse(uint64(req.Skip), uint64(req.Limit), total)
 
 	apiRsp := response.SMSSentStatusReportAPIResponse{
-		StatusCodeAndMessage: port.ListSuccess,
-		MetaDataResponse:     metadata,
-		Data:                 rsp,
+		Data: rsp,
 	}
 
 	log.Debug(ctx, "SentSMSStatusReportHandler Response: %v ", apiRsp)
-	handleSuccess(ctx, apiRsp)
+	response.List(ctx, &apiRsp, metadata)
+}
+
+// streamSentSMSStatusReport answers all the rows of the sent status report streamed as they are
+// read, as a JSON array or, with format ndjson, as one JSON object per line
+func (ch *ReportsHandler) streamSentSMSStatusReport(ctx *gin.Context, fromDate, toDate time.Time, clientReference string, format string) {
+	undocumented := undocumentedGatewayCodes{}
+	defer undocumented.count()
+
+	reports := mapSeq(ch.svc.StreamSMSSentStatusReportRepo(ctx, fromDate, toDate, clientReference), func(report domain.SMSReport) domain.SMSReport {
+		undocumented.describe(&report)
+		return report
+	})
+	rows := mapSeq(reports, response.SMSSentStatusReportRow(ch.text.Policy(ctx)))
+	var err error
+	if format == "ndjson" {
+		err = serverResponse.StreamNDJSON(ctx, rows)
+	} else {
+		err = serverResponse.StreamJSONArray(ctx, rows)
+	}
+	if err != nil && !ctx.Writer.Written() {
+		apierrors.HandleDBError(ctx, err)
+		log.Error(ctx, "Error in StreamSMSSentStatusReportRepo function: %s", err.Error())
+	}
 }
 
 type aggregateSMSUsageReportRequest struct {
@@ -233,14 +272,12 @@ func (ch *ReportsHandler) AggregateSMSUsageReportHandler(ctx *gin.Context) {
 
 	total := uint64(len(smsreport))
 	rsp := response.NewAggregateSMSReportResponse(smsreport)
-	metadata := port.NewMetaDataResponse(req.Skip, req.Limit, int(total))
+	metadata := port.NewMetaDataResponse(uint64(req.Skip), uint64(req.Limit), int(total))
 
 	apiRsp := response.AggregateSMSReportAPIResponse{
-		StatusCodeAndMessage: port.ListSuccess,
-		MetaDataResponse:     metadata,
-		Data:                 rsp,
+		Data: rsp,
 	}
 
 	log.Debug(ctx, "AggregateSMSUsageReportHandler Response: %v ", apiRsp)
-	handleSuccess(ctx, apiRsp)
+	response.List(ctx, &apiRsp, metadata)
 }
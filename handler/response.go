@@ -1,10 +1,12 @@
 package handler
 
 import (
-	"net/http"
 	"time"
 
-	"github.com/gin-gonic/gin"
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	serverRoute "MgApplication/api-server/route"
+	"MgApplication/core/port"
 )
 
 /*
@@ -247,15 +249,31 @@ func validationError(ctx *gin.Context, err error) {
 // 	ctx.AbortWithStatusJSON(statusCode, rsp)
 // }
 
-// handleSuccess sends a success response with the specified status code and optional data
-func handleSuccess(ctx *gin.Context, data any) {
-	// rsp := newResponse(true, "Success", data)
-	ctx.JSON(http.StatusOK, data)
+// ConfigureResponseIDs applies api.stringids, emitting numeric IDs in responses as JSON
+// strings so that clients decoding numbers as float64 do not lose precision
+func ConfigureResponseIDs(c *config.Config) {
+	port.SetStringIDs(c.GetBool("api.stringids"))
 }
 
-func handleCreateSuccess(ctx *gin.Context, data any) {
-	// rsp := newResponse(true, "Success", data)
-	ctx.JSON(http.StatusCreated, data)
+// ConfigureProblemDetails applies api.problemtypebase, the base URI of the type of the
+// application/problem+json error documents
+func ConfigureProblemDetails(c *config.Config) {
+	apierrors.SetProblemTypeBase(c.GetString("api.problemtypebase"))
+}
+
+// ConfigureUploadLimits applies server.uploads to the files of the multipart requests
+func ConfigureUploadLimits(c *config.Config) {
+	limits := serverRoute.UploadLimits{
+		MaxFiles:          c.GetInt("server.uploads.maxfiles"),
+		MaxFileSize:       c.GetInt64("server.uploads.maxfilesize"),
+		MaxTotalSize:      c.GetInt64("server.uploads.maxtotalsize"),
+		MaxFilenameLength: c.GetInt("server.uploads.maxfilenamelength"),
+		AllowedTypes:      serverRoute.DefaultUploadLimits.AllowedTypes,
+	}
+	if c.Exists("server.uploads.allowedtypes") {
+		limits.AllowedTypes = c.GetStringSlice("server.uploads.allowedtypes")
+	}
+	serverRoute.SetUploadLimits(limits)
 }
 
 /*
@@ -300,9 +318,10 @@ func NewSMSReportResponse(sr []domain.SMSReport) []SMSReportResponse2 {
 		date := value.CreatedDate.Format("02-01-2006")
 		time := value.CreatedDate.Format("15:04:05")
 		var status string
-		if value.Status == "submitted" {
+		switch value.Status {
+		case "submitted", "delivered", "partially_delivered":
 			status = "Success"
-		} else {
+		default:
 			status = "Failed"
 		}
 		rsp[i] = SMSReportResponse2{
@@ -0,0 +1,294 @@
+package response
+
+import (
+	log "MgApplication/api-log"
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+	"time"
+)
+
+type logLevelResponse struct {
+	Level            string `json:"level"`
+	ConfiguredLevel  string `json:"configured_level"`
+	OverrideActive   bool   `json:"override_active"`
+	RemainingSeconds int64  `json:"remaining_seconds"`
+}
+
+func NewLogLevelResponse(status log.LevelStatus) *logLevelResponse {
+	response := logLevelResponse{
+		Level:            status.Level.String(),
+		ConfiguredLevel:  status.ConfiguredLevel.String(),
+		OverrideActive:   status.OverrideActive,
+		RemainingSeconds: int64(status.Remaining.Round(time.Second).Seconds()),
+	}
+	return &response
+}
+
+type LogLevelAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *logLevelResponse `json:"data"`
+}
+
+type dndImportResponse struct {
+	Received int   `json:"received"`
+	Imported int64 `json:"imported"`
+	Rejected int   `json:"rejected"`
+}
+
+func NewDNDImportResponse(received int, imported int64, rejected int) *dndImportResponse {
+	response := dndImportResponse{
+		Received: received,
+		Imported: imported,
+		Rejected: rejected,
+	}
+	return &response
+}
+
+type DNDImportAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *dndImportResponse `json:"data"`
+}
+
+type statsBackfillResponse struct {
+	Rebuilt int64 `json:"rebuilt"`
+}
+
+func NewStatsBackfillResponse(rebuilt int64) *statsBackfillResponse {
+	response := statsBackfillResponse{
+		Rebuilt: rebuilt,
+	}
+	return &response
+}
+
+type StatsBackfillAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *statsBackfillResponse `json:"data"`
+}
+
+type statsConsistencyResponse struct {
+	Consistent bool                         `json:"consistent"`
+	Mismatches []domain.StatsHourlyMismatch `json:"mismatches"`
+}
+
+func NewStatsConsistencyResponse(mismatches []domain.StatsHourlyMismatch) *statsConsistencyResponse {
+	if mismatches == nil {
+		mismatches = []domain.StatsHourlyMismatch{}
+	}
+	response := statsConsistencyResponse{
+		Consistent: len(mismatches) == 0,
+		Mismatches: mismatches,
+	}
+	return &response
+}
+
+type StatsConsistencyAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *statsConsistencyResponse `json:"data"`
+}
+
+type orphanedRequestsResponse struct {
+	Count    int                      `json:"count"`
+	Requests []domain.OrphanedRequest `json:"requests"`
+}
+
+func NewOrphanedRequestsResponse(orphans []domain.OrphanedRequest) *orphanedRequestsResponse {
+	if orphans == nil {
+		orphans = []domain.OrphanedRequest{}
+	}
+	response := orphanedRequestsResponse{
+		Count:    len(orphans),
+		Requests: orphans,
+	}
+	return &response
+}
+
+type OrphanedRequestsAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *orphanedRequestsResponse `json:"data"`
+}
+
+type shadowGatewayResponse struct {
+	ApplicationID uint64 `json:"application_id"`
+	ShadowGateway string `json:"shadow_gateway"`
+}
+
+func NewShadowGatewayResponse(applicationID uint64, shadowGateway string) *shadowGatewayResponse {
+	response := shadowGatewayResponse{
+		ApplicationID: applicationID,
+		ShadowGateway: shadowGateway,
+	}
+	return &response
+}
+
+type ShadowGatewayAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *shadowGatewayResponse `json:"data"`
+}
+
+type shadowComparisonResponse struct {
+	Gateways        []domain.ShadowComparison     `json:"gateways"`
+	CodeDifferences []domain.ShadowCodeDifference `json:"code_differences"`
+}
+
+func NewShadowComparisonResponse(comparisons []domain.ShadowComparison, differences []domain.ShadowCodeDifference) *shadowComparisonResponse {
+	if comparisons == nil {
+		comparisons = []domain.ShadowComparison{}
+	}
+	if differences == nil {
+		differences = []domain.ShadowCodeDifference{}
+	}
+	response := shadowComparisonResponse{
+		Gateways:        comparisons,
+		CodeDifferences: differences,
+	}
+	return &response
+}
+
+type ShadowComparisonAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *shadowComparisonResponse `json:"data"`
+}
+
+type SystemStatusAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *domain.SystemStatus `json:"data"`
+}
+
+type privacyErasureTableResponse struct {
+	TableName    string `json:"table_name"`
+	RowsAffected int64  `json:"rows_affected"`
+}
+
+type privacyErasureResponse struct {
+	ErasureID     uint64                        `json:"erasure_id"`
+	Resumed       bool                          `json:"resumed"`
+	RequestedBy   string                        `json:"requested_by"`
+	CompletedDate *time.Time                    `json:"completed_date"`
+	RowsAffected  int64                         `json:"rows_affected"`
+	Tables        []privacyErasureTableResponse `json:"tables"`
+}
+
+func NewPrivacyErasureResponse(erasure domain.PrivacyErasure, resumed bool) *privacyErasureResponse {
+	response := privacyErasureResponse{
+		ErasureID:     erasure.ErasureID,
+		Resumed:       resumed,
+		RequestedBy:   erasure.RequestedBy,
+		CompletedDate: erasure.CompletedDate,
+		Tables:        make([]privacyErasureTableResponse, 0, len(erasure.Tables)),
+	}
+	for _, table := range erasure.Tables {
+		response.RowsAffected += table.RowsAffected
+		response.Tables = append(response.Tables, privacyErasureTableResponse{
+			TableName:    table.TableName,
+			RowsAffected: table.RowsAffected,
+		})
+	}
+	return &response
+}
+
+type PrivacyErasureAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *privacyErasureResponse `json:"data"`
+}
+
+type errorSampleResponse struct {
+	ErrorID        string    `json:"error_id"`
+	Route          string    `json:"route"`
+	Method         string    `json:"method"`
+	Status         int       `json:"status"`
+	CorrelationID  string    `json:"correlation_id"`
+	RequestSummary string    `json:"request_summary"`
+	Panicked       bool      `json:"panicked"`
+	Stack          *string   `json:"stack"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func NewErrorSamplesResponse(samples []domain.ErrorSample) []errorSampleResponse {
+	response := make([]errorSampleResponse, 0, len(samples))
+	for _, sample := range samples {
+		response = append(response, errorSampleResponse{
+			ErrorID:        sample.ErrorID,
+			Route:          sample.Route,
+			Method:         sample.Method,
+			Status:         sample.Status,
+			CorrelationID:  sample.CorrelationID,
+			RequestSummary: sample.RequestSummary,
+			Panicked:       sample.Panicked,
+			Stack:          sample.Stack,
+			CreatedAt:      sample.CreatedAt,
+		})
+	}
+	return response
+}
+
+type ErrorSamplesAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	port.MetaDataResponse     `json:",inline"`
+	Data                      []errorSampleResponse `json:"data"`
+}
+
+type ConfigBundleAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *domain.ConfigBundle `json:"data"`
+}
+
+type bundleImportResponse struct {
+	DryRun       bool                             `json:"dry_run"`
+	Summary      map[string]map[string]int        `json:"summary"`
+	Applications []domain.BundleApplicationChange `json:"applications"`
+	Templates    []domain.BundleTemplateChange    `json:"templates"`
+}
+
+// BundleImportSummary counts the changes of plan by entity and action
+func BundleImportSummary(plan domain.BundleImportPlan) map[string]map[string]int {
+	summary := map[string]map[string]int{"applications": {}, "templates": {}}
+	for _, change := range plan.Applications {
+		summary["applications"][change.Action]++
+	}
+	for _, change := range plan.Templates {
+		summary["templates"][change.Action]++
+	}
+	return summary
+}
+
+func NewBundleImportResponse(plan domain.BundleImportPlan, dryRun bool) *bundleImportResponse {
+	response := bundleImportResponse{
+		DryRun:       dryRun,
+		Summary:      BundleImportSummary(plan),
+		Applications: plan.Applications,
+		Templates:    plan.Templates,
+	}
+	if response.Applications == nil {
+		response.Applications = []domain.BundleApplicationChange{}
+	}
+	if response.Templates == nil {
+		response.Templates = []domain.BundleTemplateChange{}
+	}
+	return &response
+}
+
+type BundleImportAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *bundleImportResponse `json:"data"`
+}
+
+type CredentialExpiryAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      []domain.CredentialExpiry `json:"data"`
+}
+
+type QueryPlanAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *domain.QueryPlan `json:"data"`
+}
+
+type AuditLogAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	port.MetaDataResponse     `json:",inline"`
+	Data                      []domain.AuditEntry `json:"data"`
+}
+
+type AuditVerificationAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *domain.AuditVerification `json:"data"`
+}
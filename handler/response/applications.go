@@ -1,6 +1,7 @@
 package response
 
 import (
+	serverResponse "MgApplication/api-server/response"
 	"MgApplication/core/domain"
 	"MgApplication/core/port"
 	"time"
@@ -9,7 +10,7 @@ import (
 )
 
 type CreateMsgApplicationResponse struct {
-	ApplicationID   null.Uint64 `json:"application_id" db:"application_id"`
+	ApplicationID   port.ID     `json:"application_id" db:"application_id" swaggertype:"string" pattern:"^[0-9]+$"`
 	ApplicationName null.String `json:"application_name" db:"application_name"`
 	RequestType     null.String `json:"request_type" db:"request_type"`
 	SecretKey       null.String `json:"secret_key" db:"secret_key"`
@@ -20,7 +21,7 @@ type CreateMsgApplicationResponse struct {
 
 func NewCreateMsgApplicationResponse(appln *domain.MsgApplications) *CreateMsgApplicationResponse {
 	response := CreateMsgApplicationResponse{
-		ApplicationID:   null.Uint64From(appln.ApplicationID),
+		ApplicationID:   port.ID(appln.ApplicationID),
 		ApplicationName: null.StringFrom(appln.ApplicationName),
 		RequestType:     null.StringFrom(appln.RequestType),
 		SecretKey:       null.StringFrom(appln.SecretKey),
@@ -37,17 +38,17 @@ type CreateMsgApplicationAPIResponse struct {
 }
 
 type listMsgApplicationsResponse struct {
-	ApplicationID   uint64 `json:"application_id" db:"application_id"`
-	ApplicationName string `json:"application_name" db:"application_name"`
-	RequestType     string `json:"request_type" db:"request_type"`
-	Status          int    `json:"status" db:"status_cd"`
+	ApplicationID   port.ID `json:"application_id" db:"application_id" swaggertype:"string" pattern:"^[0-9]+$"`
+	ApplicationName string  `json:"application_name" db:"application_name"`
+	RequestType     string  `json:"request_type" db:"request_type"`
+	Status          int     `json:"status" db:"status_cd"`
 }
 
 func NewListMsgApplicationsResponse(applications []domain.MsgApplicationsGet) []listMsgApplicationsResponse {
 	var response []listMsgApplicationsResponse
 	for _, application := range applications {
 		applicationResponse := listMsgApplicationsResponse{
-			ApplicationID:   application.ApplicationID,
+			ApplicationID:   port.ID(application.ApplicationID),
 			ApplicationName: application.ApplicationName,
 			RequestType:     application.RequestType,
 			Status:          application.Status,
@@ -65,17 +66,17 @@ type ListMsgApplicationsAPIResponse struct {
 }
 
 type fetchMsgApplicationResponse struct {
-	ApplicationID   uint64 `json:"application_id" db:"application_id"`
-	ApplicationName string `json:"application_name" db:"application_name"`
-	RequestType     string `json:"request_type" db:"request_type"`
-	Status          int    `json:"status" db:"status_cd"`
+	ApplicationID   port.ID `json:"application_id" db:"application_id" swaggertype:"string" pattern:"^[0-9]+$"`
+	ApplicationName string  `json:"application_name" db:"application_name"`
+	RequestType     string  `json:"request_type" db:"request_type"`
+	Status          int     `json:"status" db:"status_cd"`
 }
 
 func NewFetchMsgApplicationResponse(applications []domain.MsgApplicationsGet) []fetchMsgApplicationResponse {
 	var response []fetchMsgApplicationResponse
 	for _, application := range applications {
 		applicationResponse := fetchMsgApplicationResponse{
-			ApplicationID:   application.ApplicationID,
+			ApplicationID:   port.ID(application.ApplicationID),
 			ApplicationName: application.ApplicationName,
 			RequestType:     application.RequestType,
 			Status:          application.Status,
@@ -92,6 +93,11 @@ type FetchMsgApplicationAPIResponse struct {
 	Data []fetchMsgApplicationResponse `json:"data"`
 }
 
+// ETag identifies the fetched application, so that unchanged applications are answered with 304
+func (r FetchMsgApplicationAPIResponse) ETag() string {
+	return serverResponse.NewETag(r.Data)
+}
+
 /*
 type fetchActiveMsgApplicationResponse struct {
 	ApplicationID   uint64 `json:"application_id" db:"application_id"`
@@ -123,7 +129,7 @@ type FetchActiveMsgApplicationAPIResponse struct {
 */
 
 type updateMsgApplicationResponse struct {
-	ApplicationID   uint64    `json:"application_id" db:"application_id"`
+	ApplicationID   port.ID   `json:"application_id" db:"application_id" swaggertype:"string" pattern:"^[0-9]+$"`
 	ApplicationName string    `json:"application_name" db:"application_name"`
 	RequestType     string    `json:"request_type" db:"request_type"`
 	UpdatedDate     time.Time `json:"updated_date" db:"updated_date"`
@@ -132,7 +138,7 @@ type updateMsgApplicationResponse struct {
 
 func NewUpdateMsgApplicationResponse(appln *domain.EditApplication) *updateMsgApplicationResponse {
 	response := updateMsgApplicationResponse{
-		ApplicationID:   appln.ApplicationID,
+		ApplicationID:   port.ID(appln.ApplicationID),
 		ApplicationName: appln.ApplicationName,
 		RequestType:     appln.RequestType,
 		UpdatedDate:     appln.UpdatedDate,
@@ -65,10 +65,16 @@ type BuildTargetFileAPIResponse struct {
 */
 
 type sendBulkSMSResponse struct {
-	Timestamp string `json:"timestamp"`
-	RequestID string `json:"request_id"`
-	Code      string `json:"code"`
-	Info      string `json:"info"`
+	Timestamp  string                    `json:"timestamp"`
+	RequestID  string                    `json:"request_id"`
+	Code       string                    `json:"code"`
+	Info       string                    `json:"info"`
+	Recipients []recipientStatusResponse `json:"recipients"`
+}
+
+type recipientStatusResponse struct {
+	MobileNumber string `json:"mobile_number" example:"9000000000"`
+	Status       string `json:"status" example:"SUBMITTED"`
 }
 
 // func NewSendBulkSMSResponseOld(bulk *domain.NicResponse) *sendBulkSMSResponse {
@@ -91,12 +97,19 @@ type NicXmlResponse struct {
 	Info      string   `xml:"response>info"`
 }
 
-func NewSendBulkSMSResponse(bulk *domain.NicResponseXml) *sendBulkSMSResponse {
+func NewSendBulkSMSResponse(bulk *domain.NicResponseXml, recipients []domain.RecipientStatus) *sendBulkSMSResponse {
 	response := sendBulkSMSResponse{
-		Timestamp: bulk.Timestamp,
-		RequestID: bulk.RequestID,
-		Code:      bulk.Code,
-		Info:      bulk.Info,
+		Timestamp:  bulk.Timestamp,
+		RequestID:  bulk.RequestID,
+		Code:       bulk.Code,
+		Info:       bulk.Info,
+		Recipients: make([]recipientStatusResponse, 0, len(recipients)),
+	}
+	for _, recipient := range recipients {
+		response.Recipients = append(response.Recipients, recipientStatusResponse{
+			MobileNumber: recipient.MobileNumber,
+			Status:       recipient.Status,
+		})
 	}
 	return &response
 
@@ -106,3 +119,8 @@ type SendBulkSMSAPIResponse struct {
 	port.StatusCodeAndMessage `json:",inline"`
 	Data                      *sendBulkSMSResponse `json:"data"`
 }
+
+type RecipientUploadAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *domain.RecipientUpload `json:"data"`
+}
@@ -0,0 +1,46 @@
+package response
+
+import (
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+	"time"
+)
+
+type gatewayCodeResponse struct {
+	Gateway           string     `json:"gateway"`
+	Code              string     `json:"code"`
+	Description       string     `json:"description"`
+	Severity          string     `json:"severity"`
+	RecommendedAction *string    `json:"recommended_action"`
+	UpdatedDate       *time.Time `json:"updated_date,omitempty"`
+}
+
+func NewGatewayCodeResponse(code *domain.GatewayCode) *gatewayCodeResponse {
+	response := gatewayCodeResponse{
+		Gateway:           code.Gateway,
+		Code:              code.Code,
+		Description:       code.Description,
+		Severity:          code.Severity,
+		RecommendedAction: code.RecommendedAction,
+		UpdatedDate:       code.UpdatedDate,
+	}
+	return &response
+}
+
+func NewGatewayCodesResponse(codes []domain.GatewayCode) []*gatewayCodeResponse {
+	response := make([]*gatewayCodeResponse, 0, len(codes))
+	for i := range codes {
+		response = append(response, NewGatewayCodeResponse(&codes[i]))
+	}
+	return response
+}
+
+type GatewayCodeAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *gatewayCodeResponse `json:"data"`
+}
+
+type GatewayCodesAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      []*gatewayCodeResponse `json:"data"`
+}
@@ -0,0 +1,39 @@
+package response
+
+import "strings"
+
+const (
+	maskedTextKeepPrefix = 10
+	maskedTextKeepSuffix = 5
+	maskedTextRune       = "*"
+)
+
+// MessageTextPolicy decides how the stored message text of a request is returned to the
+// caller. Every response carrying message text takes one, so no endpoint can return it as is.
+type MessageTextPolicy interface {
+	MessageText(communicationID string, text string) string
+}
+
+// MaskMessageText keeps the first 10 and last 5 characters of text and masks the rest.
+// Texts too short to hide anything that way are masked completely.
+func MaskMessageText(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maskedTextKeepPrefix+maskedTextKeepSuffix {
+		return strings.Repeat(maskedTextRune, len(runes))
+	}
+	masked := len(runes) - maskedTextKeepPrefix - maskedTextKeepSuffix
+	return string(runes[:maskedTextKeepPrefix]) + strings.Repeat(maskedTextRune, masked) + string(runes[len(runes)-maskedTextKeepSuffix:])
+}
+
+// messageText applies policy to an optional text
+func messageText(policy MessageTextPolicy, communicationID *string, text *string) *string {
+	if text == nil {
+		return nil
+	}
+	var id string
+	if communicationID != nil {
+		id = strings.TrimSpace(*communicationID)
+	}
+	applied := policy.MessageText(id, *text)
+	return &applied
+}
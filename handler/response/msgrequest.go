@@ -1,25 +1,42 @@
 package response
 
 import (
+	"strings"
+	"time"
+
 	"MgApplication/core/domain"
 	"MgApplication/core/port"
 )
 
 type createSMSResponse struct {
-	CommunicationID  string `json:"communication_id"`
-	CompleteResponse string `json:"complete_response"`
-	ReferenceID      string `json:"reference_id"`
-	ResponseCode     string `json:"status"`
-	ResponseText     string `json:"response_text"`
+	CommunicationID  string            `json:"communication_id"`
+	CompleteResponse string            `json:"complete_response,omitempty"`
+	ReferenceID      string            `json:"reference_id"`
+	ResponseCode     string            `json:"status"`
+	ResponseText     string            `json:"response_text"`
+	ClientReference  string            `json:"client_reference,omitempty"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+	CacheHit         bool              `json:"cache_hit,omitempty"`
+	// Warnings tell how the request was changed before it was sent
+	Warnings []string `json:"warnings,omitempty"`
+	// DeliveryStatus is the delivery status reached while the request waited for it, DELIVERED,
+	// FAILED or PENDING, absent when the request did not wait
+	DeliveryStatus string `json:"delivery_status,omitempty" enums:"DELIVERED,PENDING,FAILED"`
 }
 
-func NewCreateSMSResponse(msg *domain.MsgResponse) *createSMSResponse {
+// NewCreateSMSResponse returns the response to a message request. The raw gateway response is
+// only included when raw is not empty; the caller's reference and metadata are echoed back,
+// with the warnings of the request.
+func NewCreateSMSResponse(req *domain.MsgRequest, msg *domain.MsgResponse, raw string) *createSMSResponse {
 	response := createSMSResponse{
 		CommunicationID:  msg.CommunicationID,
-		CompleteResponse: msg.CompleteResponse,
+		CompleteResponse: raw,
 		ReferenceID:      msg.ReferenceID,
 		ResponseCode:     msg.ResponseCode,
 		ResponseText:     msg.ResponseText,
+		ClientReference:  req.ClientReference,
+		Metadata:         req.Metadata,
+		Warnings:         req.Warnings,
 	}
 	return &response
 }
@@ -37,10 +54,75 @@ type TestSMSAPIResponse struct {
 	Data map[string]interface{} `json:"data"`
 }
 
+type fetchSMSRequestStatusResponse struct {
+	CommunicationID string    `json:"communication_id"`
+	ReferenceID     string    `json:"reference_id"`
+	ResponseCode    string    `json:"status"`
+	ResponseText    string    `json:"response_text"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// NewFetchSMSRequestStatusResponse returns the status of a request, its latest gateway response
+func NewFetchSMSRequestStatusResponse(msg *domain.MsgResponse) *fetchSMSRequestStatusResponse {
+	return &fetchSMSRequestStatusResponse{
+		CommunicationID: strings.TrimSpace(msg.CommunicationID),
+		ReferenceID:     msg.ReferenceID,
+		ResponseCode:    msg.ResponseCode,
+		ResponseText:    msg.ResponseText,
+		CreatedAt:       msg.CreatedAt,
+	}
+}
+
+type FetchSMSRequestStatusAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *fetchSMSRequestStatusResponse `json:"data"`
+}
+
+// Results of a request of a bulk status check
+const (
+	SMSRequestStatusFound    = "found"
+	SMSRequestStatusNotFound = "not_found"
+)
+
+type smsRequestStatusResult struct {
+	// Result is not_found for the requests without a stored response, which have no status
+	Result       string     `json:"result" enums:"found,not_found" example:"found"`
+	ReferenceID  string     `json:"reference_id,omitempty"`
+	ResponseCode string     `json:"status,omitempty"`
+	ResponseText string     `json:"response_text,omitempty"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+}
+
+// NewFetchSMSRequestStatusBulkResponse returns the status of each of the requests
+// communicationIDs, its latest gateway response in latest, keyed by communication id
+func NewFetchSMSRequestStatusBulkResponse(communicationIDs []string, latest map[string]domain.MsgResponse) map[string]*smsRequestStatusResult {
+	statuses := make(map[string]*smsRequestStatusResult, len(communicationIDs))
+	for _, id := range communicationIDs {
+		msg, ok := latest[id]
+		if !ok {
+			statuses[id] = &smsRequestStatusResult{Result: SMSRequestStatusNotFound}
+			continue
+		}
+		statuses[id] = &smsRequestStatusResult{
+			Result:       SMSRequestStatusFound,
+			ReferenceID:  msg.ReferenceID,
+			ResponseCode: msg.ResponseCode,
+			ResponseText: msg.ResponseText,
+			CreatedAt:    &msg.CreatedAt,
+		}
+	}
+	return statuses
+}
+
+type FetchSMSRequestStatusBulkAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      map[string]*smsRequestStatusResult `json:"data"`
+}
+
 type FetchCDACSMSDeliveryStatusResponse struct {
 	MobileNumber string `json:"mobile_number" validate:"required" example:"919999999999"`
 	SMSStatus    string `json:"sms_status" validate:"required" example:"DELIVRD"`
-	TimeStamp    string `json:"timestamp" validate:"required" example:"2022-02-25 17:40:50.0435482"`
+	TimeStamp    string `json:"timestamp" validate:"required" example:"2022-02-25T12:10:50Z"`
 }
 
 func NewFetchCDACSMSDeliveryStatusResponse(msg []*domain.CDACSMSDeliveryStatusResponse) []*FetchCDACSMSDeliveryStatusResponse {
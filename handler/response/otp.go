@@ -0,0 +1,50 @@
+package response
+
+import (
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+	"time"
+)
+
+type generateOTPResponse struct {
+	OTPReference    string    `json:"otp_reference"`
+	CommunicationID string    `json:"communication_id"`
+	MobileNumber    string    `json:"mobile_number"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	MaxAttempts     int       `json:"max_attempts"`
+	CacheHit        bool      `json:"cache_hit,omitempty"`
+}
+
+func NewGenerateOTPResponse(otp *domain.MsgOTP, communicationID string) *generateOTPResponse {
+	response := generateOTPResponse{
+		OTPReference:    otp.OTPReference,
+		CommunicationID: communicationID,
+		MobileNumber:    otp.MobileNumber,
+		ExpiresAt:       otp.ExpiresAt,
+		MaxAttempts:     otp.MaxAttempts,
+	}
+	return &response
+}
+
+type GenerateOTPAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *generateOTPResponse `json:"data"`
+}
+
+type verifyOTPResponse struct {
+	OTPReference string `json:"otp_reference"`
+	Verified     bool   `json:"verified"`
+}
+
+func NewVerifyOTPResponse(otpReference string) *verifyOTPResponse {
+	response := verifyOTPResponse{
+		OTPReference: otpReference,
+		Verified:     true,
+	}
+	return &response
+}
+
+type VerifyOTPAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *verifyOTPResponse `json:"data"`
+}
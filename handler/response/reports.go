@@ -37,38 +37,61 @@ type SMSDashboardAPIResponse struct {
 }
 
 type smsSentStatusReportResponse struct {
-	SerialNo        uint64    `json:"serial_no" db:"serial_number"`
-	CreatedDate     time.Time `json:"created_date" db:"created_date"`
-	CommunicationID *string   `json:"comm_id" db:"communication_id"`
-	ApplicationID   *string   `json:"application_id" db:"application_id"`
-	FacilityID      *string   `json:"facility_id" db:"facility_id"`
-	MessagePriority *int64    `json:"message_priority" db:"priority"`
-	MessageText     *string   `json:"message_text" db:"message_text"`
-	MobileNumber    *int64    `json:"mobile_number" db:"mobile_number"`
-	GatewayID       *string   `json:"gateway_id" db:"gateway"`
-	Status          string    `json:"status" db:"status"`
+	SerialNo            uint64            `json:"serial_no" db:"serial_number"`
+	CreatedDate         time.Time         `json:"created_date" db:"created_date"`
+	CommunicationID     *string           `json:"comm_id" db:"communication_id"`
+	ApplicationID       *string           `json:"application_id" db:"application_id"`
+	FacilityID          *string           `json:"facility_id" db:"facility_id"`
+	MessagePriority     *int64            `json:"message_priority" db:"priority"`
+	MessageText         *string           `json:"message_text" db:"message_text"`
+	MobileNumber        *int64            `json:"mobile_number" db:"mobile_number"`
+	GatewayID           *string           `json:"gateway_id" db:"gateway"`
+	Status              string            `json:"status" db:"status"`
+	ResponseCode        *string           `json:"response_code" db:"response_code"`
+	ResponseDescription *string           `json:"response_description" db:"response_description"`
+	ResponseSeverity    *string           `json:"response_severity" db:"response_severity"`
+	RecommendedAction   *string           `json:"recommended_action" db:"recommended_action"`
+	ClientReference     *string           `json:"client_reference" db:"client_reference"`
+	Metadata            map[string]string `json:"metadata" db:"metadata"`
 }
 
-func NewSMSSentStatusReportResponse(reports []domain.SMSReport) []smsSentStatusReportResponse {
+func NewSMSSentStatusReportResponse(reports []domain.SMSReport, text MessageTextPolicy) []smsSentStatusReportResponse {
 	var response []smsSentStatusReportResponse
 	for _, report := range reports {
-		ReportResponse := smsSentStatusReportResponse{
-			SerialNo:        report.SerialNo,
-			CreatedDate:     report.CreatedDate,
-			CommunicationID: report.CommunicationID,
-			ApplicationID:   report.ApplicationID,
-			FacilityID:      report.FacilityID,
-			MessagePriority: report.MessagePriority,
-			MessageText:     report.MessageText,
-			MobileNumber:    report.MobileNumber,
-			GatewayID:       report.GatewayID,
-			Status:          report.Status,
-		}
-		response = append(response, ReportResponse)
+		response = append(response, newSMSSentStatusReportRow(report, text))
 	}
 	return response
 }
 
+// SMSSentStatusReportRow returns the conversion of a report to a row of the sent status report,
+// showing the message text following text, for the reports streamed one row at a time
+func SMSSentStatusReportRow(text MessageTextPolicy) func(domain.SMSReport) smsSentStatusReportResponse {
+	return func(report domain.SMSReport) smsSentStatusReportResponse {
+		return newSMSSentStatusReportRow(report, text)
+	}
+}
+
+func newSMSSentStatusReportRow(report domain.SMSReport, text MessageTextPolicy) smsSentStatusReportResponse {
+	return smsSentStatusReportResponse{
+		SerialNo:            report.SerialNo,
+		CreatedDate:         report.CreatedDate,
+		CommunicationID:     report.CommunicationID,
+		ApplicationID:       report.ApplicationID,
+		FacilityID:          report.FacilityID,
+		MessagePriority:     report.MessagePriority,
+		MessageText:         messageText(text, report.CommunicationID, report.MessageText),
+		MobileNumber:        report.MobileNumber,
+		GatewayID:           report.GatewayID,
+		Status:              report.Status,
+		ResponseCode:        report.ResponseCode,
+		ResponseDescription: report.ResponseDescription,
+		ResponseSeverity:    report.ResponseSeverity,
+		RecommendedAction:   report.RecommendedAction,
+		ClientReference:     report.ClientReference,
+		Metadata:            report.Metadata,
+	}
+}
+
 type SMSSentStatusReportAPIResponse struct {
 	port.StatusCodeAndMessage `json:",inline"`
 	port.MetaDataResponse     `json:",inline"`
@@ -0,0 +1,99 @@
+package response
+
+import (
+	serverResponse "MgApplication/api-server/response"
+	"MgApplication/core/port"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Envelope is implemented by the API responses embedding port.StatusCodeAndMessage, whose
+// status the builders below fill in. The correlation ID and the timestamp are stamped when
+// the envelope is written.
+type Envelope interface {
+	serverResponse.Traced
+	SetStatus(status port.StatusCodeAndMessage)
+	Status() int
+}
+
+// LocatedEnvelope is implemented by the envelopes also carrying the URL of the resource
+// they describe
+type LocatedEnvelope interface {
+	Envelope
+	SetLocation(location string)
+}
+
+// ListEnvelope is implemented by the API responses also embedding port.MetaDataResponse
+type ListEnvelope interface {
+	Envelope
+	SetMeta(meta port.MetaDataResponse)
+}
+
+// WithCreated sets the status of rsp for a created resource. It is meant for the handlers
+// returning their response to serverRoute.
+func WithCreated[E Envelope](rsp E) E {
+	rsp.SetStatus(port.CreateSuccess)
+	return rsp
+}
+
+// WithCreatedAt sets the status of rsp for a resource created, fetched from location. The
+// location is written as the Location header of the 201 response.
+func WithCreatedAt[E LocatedEnvelope](rsp E, location string) E {
+	rsp.SetStatus(port.CreateSuccess)
+	rsp.SetLocation(location)
+	return rsp
+}
+
+// WithFetched sets the status of rsp for fetched data
+func WithFetched[E Envelope](rsp E) E {
+	rsp.SetStatus(port.FetchSuccess)
+	return rsp
+}
+
+// WithUpdated sets the status of rsp for an updated resource
+func WithUpdated[E Envelope](rsp E) E {
+	rsp.SetStatus(port.UpdateSuccess)
+	return rsp
+}
+
+// WithList sets the status and the metadata of rsp for a list
+func WithList[E ListEnvelope](rsp E, meta port.MetaDataResponse) E {
+	rsp.SetStatus(port.ListSuccess)
+	rsp.SetMeta(meta)
+	return rsp
+}
+
+// Created writes rsp as a created resource, with 201. Like the other writers it renders
+// JSON or XML as negotiated from the Accept header.
+func Created(ctx *gin.Context, rsp Envelope) {
+	write(ctx, WithCreated(rsp))
+}
+
+// CreatedAt writes rsp as a created resource fetched from location, with 201 and the
+// Location header
+func CreatedAt(ctx *gin.Context, rsp LocatedEnvelope, location string) {
+	write(ctx, WithCreatedAt(rsp, location))
+}
+
+// OK writes rsp as fetched data, with 200. Responses carrying an entity tag are answered
+// with 304 when the client already has them.
+func OK(ctx *gin.Context, rsp Envelope) {
+	write(ctx, WithFetched(rsp))
+}
+
+// Updated writes rsp as an updated resource, with 200
+func Updated(ctx *gin.Context, rsp Envelope) {
+	write(ctx, WithUpdated(rsp))
+}
+
+// List writes rsp as a list described by meta, with 200
+func List(ctx *gin.Context, rsp ListEnvelope, meta port.MetaDataResponse) {
+	write(ctx, WithList(rsp, meta))
+}
+
+func write(ctx *gin.Context, rsp Envelope) {
+	if tagger, ok := rsp.(serverResponse.Tagger); ok && serverResponse.NotModified(ctx, tagger.ETag()) {
+		return
+	}
+	serverResponse.Respond(ctx, rsp.Status(), rsp)
+}
@@ -0,0 +1,251 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	serverResponse "MgApplication/api-server/response"
+	"MgApplication/core/dlt"
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// record runs write against a test context and returns the recorded response
+func record(t *testing.T, header http.Header, write func(ctx *gin.Context)) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	for name, values := range header {
+		ctx.Request.Header[name] = values
+	}
+	write(ctx)
+	return w
+}
+
+var (
+	testTemplates = []domain.MaintainTemplate{{
+		TemplateLocalID: 355, ApplicationID: "4", TemplateName: "Std. Instruction CANCELLATION",
+		TemplateFormat: "Standing Instruction {#var#} was cancelled.", SenderID: "INPOST",
+		EntityID: "1001051725995192803", TemplateID: "1007002656392643880", Gateway: "1", MessageType: "PM", Status: 1,
+	}}
+	testTemplateNames   = []domain.GetTemplatebyAPPID{{TemplateLocalID: 355, TemplateName: "Std. Instruction CANCELLATION", TemplateID: "1007002656392643880", Gateway: "1", MessageType: "PM"}}
+	testTemplateFormats = []domain.GetTemplateformatbyID{{TemplateLocalID: 355, TemplateName: "Std. Instruction CANCELLATION", TemplateID: "1007002656392643880", SenderID: "INPOST", MessageType: "PM"}}
+	testApplications    = []domain.MsgApplicationsGet{{ApplicationID: 4, ApplicationName: "Test Application", RequestType: "1", Status: 1}}
+	testWarnings        = []dlt.Violation{{Rule: "header", Message: "sender header is not registered"}}
+)
+
+// TestBuildersMatchHandBuiltResponses compares the responses written by the builders with
+// the ones the handlers used to build by hand and write with handleSuccess/handleCreateSuccess
+func TestBuildersMatchHandBuiltResponses(t *testing.T) {
+	meta := port.NewMetaDataResponse(0, 10, 1)
+	tests := []struct {
+		name   string
+		status int
+		before any
+		write  func(ctx *gin.Context)
+	}{
+		{
+			name:   "create template",
+			status: http.StatusCreated,
+			before: CreateTemplateAPIResponse{StatusCodeAndMessage: port.CreateSuccess, Data: NewTemplateLintResponse(92, 2000, testWarnings)},
+			write: func(ctx *gin.Context) {
+				Created(ctx, &CreateTemplateAPIResponse{Data: NewTemplateLintResponse(92, 2000, testWarnings)})
+			},
+		},
+		{
+			name:   "create template without warnings",
+			status: http.StatusCreated,
+			before: CreateTemplateAPIResponse{StatusCodeAndMessage: port.CreateSuccess, Data: NewTemplateLintResponse(92, 2000, nil)},
+			write: func(ctx *gin.Context) {
+				Created(ctx, &CreateTemplateAPIResponse{Data: NewTemplateLintResponse(92, 2000, nil)})
+			},
+		},
+		{
+			name:   "list templates",
+			status: http.StatusOK,
+			before: ListTemplatesAPIResponse{StatusCodeAndMessage: port.ListSuccess, MetaDataResponse: meta, Data: NewListTemplatesResponse(testTemplates)},
+			write: func(ctx *gin.Context) {
+				List(ctx, &ListTemplatesAPIResponse{Data: NewListTemplatesResponse(testTemplates)}, meta)
+			},
+		},
+		{
+			name:   "list no templates",
+			status: http.StatusOK,
+			before: ListTemplatesAPIResponse{StatusCodeAndMessage: port.ListSuccess, MetaDataResponse: meta, Data: NewListTemplatesResponse(nil)},
+			write: func(ctx *gin.Context) {
+				List(ctx, &ListTemplatesAPIResponse{Data: NewListTemplatesResponse(nil)}, meta)
+			},
+		},
+		{
+			name:   "fetch template",
+			status: http.StatusOK,
+			before: FetchTemplateAPIResponse{StatusCodeAndMessage: port.FetchSuccess, Data: NewFetchTemplateResponse(testTemplates)},
+			write: func(ctx *gin.Context) {
+				OK(ctx, &FetchTemplateAPIResponse{Data: NewFetchTemplateResponse(testTemplates)})
+			},
+		},
+		{
+			name:   "fetch template names",
+			status: http.StatusOK,
+			before: FetchTemplateNameAPIResponse{StatusCodeAndMessage: port.FetchSuccess, Data: NewFetchTemplateNameResponse(testTemplateNames)},
+			write: func(ctx *gin.Context) {
+				OK(ctx, &FetchTemplateNameAPIResponse{Data: NewFetchTemplateNameResponse(testTemplateNames)})
+			},
+		},
+		{
+			name:   "fetch template details",
+			status: http.StatusOK,
+			before: FetchTemplateDetailsAPIResponse{StatusCodeAndMessage: port.FetchSuccess, Data: NewFetchTemplateDetailsResponse(testTemplateFormats)},
+			write: func(ctx *gin.Context) {
+				OK(ctx, &FetchTemplateDetailsAPIResponse{Data: NewFetchTemplateDetailsResponse(testTemplateFormats)})
+			},
+		},
+		{
+			name:   "preview template",
+			status: http.StatusOK,
+			before: PreviewTemplateAPIResponse{StatusCodeAndMessage: port.FetchSuccess, Data: PreviewTemplateResponse{TemplateLocalID: 355, RenderedMessage: "Hello"}},
+			write: func(ctx *gin.Context) {
+				OK(ctx, &PreviewTemplateAPIResponse{Data: PreviewTemplateResponse{TemplateLocalID: 355, RenderedMessage: "Hello"}})
+			},
+		},
+		{
+			name:   "update template",
+			status: http.StatusOK,
+			before: UpdateTemplatesAPIResponse{StatusCodeAndMessage: port.UpdateSuccess, Data: NewTemplateLintResponse(92, 2000, testWarnings)},
+			write: func(ctx *gin.Context) {
+				Updated(ctx, &UpdateTemplatesAPIResponse{Data: NewTemplateLintResponse(92, 2000, testWarnings)})
+			},
+		},
+		{
+			name:   "toggle template status",
+			status: http.StatusOK,
+			before: ToggleTemplateStatusAPIResponse{StatusCodeAndMessage: port.UpdateSuccess, Data: 0},
+			write: func(ctx *gin.Context) {
+				Updated(ctx, &ToggleTemplateStatusAPIResponse{Data: 0})
+			},
+		},
+		{
+			name:   "toggle application status",
+			status: http.StatusOK,
+			before: ToggleAppStatusAPIResponse{StatusCodeAndMessage: port.UpdateSuccess, Data: 1},
+			write: func(ctx *gin.Context) {
+				Updated(ctx, &ToggleAppStatusAPIResponse{Data: 1})
+			},
+		},
+	}
+
+	header := http.Header{serverResponse.HeaderRequestID: {"req-1"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := record(t, header, func(ctx *gin.Context) {
+				if tagger, ok := tt.before.(serverResponse.Tagger); ok {
+					serverResponse.NotModified(ctx, tagger.ETag())
+				}
+				ctx.Header(serverResponse.HeaderRequestID, "req-1")
+				ctx.JSON(tt.status, tt.before)
+			})
+			got := record(t, header, tt.write)
+
+			assert.Equal(t, want.Code, got.Code)
+			assert.Equal(t, want.Header(), got.Header())
+			assert.JSONEq(t, want.Body.String(), untraced(t, got.Body.Bytes(), "req-1"))
+		})
+	}
+}
+
+// untraced checks the correlation ID and the timestamp stamped on a written envelope and
+// returns the envelope without them
+func untraced(t *testing.T, body []byte, correlationID string) string {
+	t.Helper()
+	var envelope map[string]any
+	require.NoError(t, json.Unmarshal(body, &envelope))
+	assert.Equal(t, correlationID, envelope["correlation_id"])
+	timestamp, _ := envelope["timestamp"].(string)
+	_, err := time.Parse(time.RFC3339, timestamp)
+	assert.NoError(t, err, "timestamp %q", timestamp)
+
+	delete(envelope, "correlation_id")
+	delete(envelope, "timestamp")
+	data, err := json.Marshal(envelope)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestWritersNegotiateXML(t *testing.T) {
+	rsp := FetchTemplateAPIResponse{Data: NewFetchTemplateResponse(testTemplates)}
+	w := record(t, http.Header{"Accept": {"application/xml"}}, func(ctx *gin.Context) { OK(ctx, &rsp) })
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), serverResponse.MediaTypeXML))
+	assert.Contains(t, w.Body.String(), "<template_name>Std. Instruction CANCELLATION</template_name>")
+	assert.Contains(t, w.Body.String(), "<correlation_id>"+w.Header().Get(serverResponse.HeaderRequestID)+"</correlation_id>")
+}
+
+// TestReturningBuildersMatchHandBuiltResponses covers the variants used by the handlers
+// returning their response to serverRoute
+func TestReturningBuildersMatchHandBuiltResponses(t *testing.T) {
+	created := NewCreateMsgApplicationResponse(&domain.MsgApplications{ApplicationID: 4, ApplicationName: "Test Application", RequestType: "1", Status: 1})
+	assert.Equal(t,
+		&CreateMsgApplicationAPIResponse{StatusCodeAndMessage: port.CreateSuccess, Data: created},
+		WithCreated(&CreateMsgApplicationAPIResponse{Data: created}))
+
+	updated := NewUpdateMsgApplicationResponse(&domain.EditApplication{ApplicationID: 4, ApplicationName: "Test Application", RequestType: "1", Status: 1})
+	assert.Equal(t,
+		&UpdateMsgApplicationAPIResponse{StatusCodeAndMessage: port.UpdateSuccess, Data: updated},
+		WithUpdated(&UpdateMsgApplicationAPIResponse{Data: updated}))
+
+	fetched := NewFetchMsgApplicationResponse(testApplications)
+	assert.Equal(t,
+		&FetchMsgApplicationAPIResponse{StatusCodeAndMessage: port.FetchSuccess, Data: fetched},
+		WithFetched(&FetchMsgApplicationAPIResponse{Data: fetched}))
+
+	meta := port.NewMetaDataResponse(0, 10, 1)
+	listed := NewListMsgApplicationsResponse(testApplications)
+	assert.Equal(t,
+		&ListMsgApplicationsAPIResponse{StatusCodeAndMessage: port.ListSuccess, MetaDataResponse: meta, Data: listed},
+		WithList(&ListMsgApplicationsAPIResponse{Data: listed}, meta))
+}
+
+func TestOKAnswersMatchingETagWithNotModified(t *testing.T) {
+	rsp := FetchTemplateAPIResponse{Data: NewFetchTemplateResponse(testTemplates)}
+
+	fresh := record(t, nil, func(ctx *gin.Context) { OK(ctx, &rsp) })
+	assert.Equal(t, http.StatusOK, fresh.Code)
+	etag := fresh.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	cached := record(t, http.Header{"If-None-Match": {etag}}, func(ctx *gin.Context) { OK(ctx, &rsp) })
+	assert.Equal(t, http.StatusNotModified, cached.Code)
+	assert.Empty(t, cached.Body.String())
+}
+
+// TestFetchTemplateNameResponse checks that the template names of an application come with what
+// a send request of each template needs
+func TestFetchTemplateNameResponse(t *testing.T) {
+	got := record(t, nil, func(ctx *gin.Context) {
+		OK(ctx, &FetchTemplateNameAPIResponse{Data: NewFetchTemplateNameResponse(testTemplateNames)})
+	})
+	require.Equal(t, http.StatusOK, got.Code)
+
+	var rsp struct {
+		Data []map[string]any `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(got.Body.Bytes(), &rsp))
+	assert.Equal(t, []map[string]any{{
+		"template_local_id": 355.0,
+		"template_name":     "Std. Instruction CANCELLATION",
+		"template_id":       "1007002656392643880",
+		"gateway":           "1",
+		"message_type":      "PM",
+	}}, rsp.Data)
+}
@@ -1,65 +1,98 @@
 package response
 
 import (
+	serverResponse "MgApplication/api-server/response"
+	"MgApplication/core/dlt"
 	"MgApplication/core/domain"
 	"MgApplication/core/port"
 )
 
+// TemplateLintResponse is the estimated length of the longest message of a stored template and
+// the DLT rules it breaks, returned when the rules are not enforced
+type TemplateLintResponse struct {
+	EstimatedLength int             `json:"estimated_length"`
+	LengthLimit     int             `json:"length_limit,omitempty"`
+	Warnings        []dlt.Violation `json:"warnings,omitempty"`
+}
+
+func NewTemplateLintResponse(estimatedLength int, lengthLimit int, violations []dlt.Violation) *TemplateLintResponse {
+	return &TemplateLintResponse{
+		EstimatedLength: estimatedLength,
+		LengthLimit:     lengthLimit,
+		Warnings:        violations,
+	}
+}
+
 type CreateTemplateAPIResponse struct {
 	port.StatusCodeAndMessage `json:",inline"`
-	// Data                 *CreateSMSProviderResponse `json:"data"`
+	Data                      *TemplateLintResponse `json:"data,omitempty"`
 }
 
 type listTemplatesResponse struct {
-	TemplateLocalID uint64 `json:"template_local_id" db:"template_local_id"`
-	ApplicationID   string `json:"application_id" db:"application_id"`
-	TemplateName    string `json:"template_name" db:"template_name"`
-	TemplateFormat  string `json:"template_format" db:"template_format"`
-	SenderID        string `json:"sender_id" db:"sender_id"`
-	EntityID        string `json:"entity_id" db:"entity_id"`
-	TemplateID      string `json:"template_id" db:"template_id"`
-	Gateway         string `json:"gateway" db:"gateway"`
-	MessageType     string `json:"message_type" db:"message_type"`
-	Status          int    `json:"status" db:"status_cd"`
+	TemplateLocalID port.ID `json:"template_local_id" db:"template_local_id" swaggertype:"string" pattern:"^[0-9]+$"`
+	ApplicationID   string  `json:"application_id" db:"application_id"`
+	TemplateName    string  `json:"template_name" db:"template_name"`
+	TemplateFormat  string  `json:"template_format" db:"template_format"`
+	SenderID        string  `json:"sender_id" db:"sender_id"`
+	EntityID        string  `json:"entity_id" db:"entity_id"`
+	TemplateID      string  `json:"template_id" db:"template_id"`
+	Gateway         string  `json:"gateway" db:"gateway"`
+	MessageType     string  `json:"message_type" db:"message_type"`
+	Status          int     `json:"status" db:"status_cd"`
 }
 
 func NewListTemplatesResponse(templates []domain.MaintainTemplate) []listTemplatesResponse {
 	var response []listTemplatesResponse
 	for _, template := range templates {
-		templateResponse := listTemplatesResponse{
-			TemplateLocalID: template.TemplateLocalID,
-			ApplicationID:   template.ApplicationID,
-			TemplateName:    template.TemplateName,
-			TemplateFormat:  template.TemplateFormat,
-			SenderID:        template.SenderID,
-			EntityID:        template.EntityID,
-			TemplateID:      template.TemplateID,
-			Gateway:         template.Gateway,
-			MessageType:     template.MessageType,
-			Status:          template.Status,
-		}
-		response = append(response, templateResponse)
+		response = append(response, NewListTemplateResponse(template))
 	}
 	return response
 }
 
+// NewListTemplateResponse is an element of the template list, also streamed one at a time
+func NewListTemplateResponse(template domain.MaintainTemplate) listTemplatesResponse {
+	return listTemplatesResponse{
+		TemplateLocalID: port.ID(template.TemplateLocalID),
+		ApplicationID:   template.ApplicationID,
+		TemplateName:    template.TemplateName,
+		TemplateFormat:  template.TemplateFormat,
+		SenderID:        template.SenderID,
+		EntityID:        template.EntityID,
+		TemplateID:      template.TemplateID,
+		Gateway:         template.Gateway,
+		MessageType:     template.MessageType,
+		Status:          template.Status,
+	}
+}
+
+// templateFacetsResponse counts the listed templates by status (0, 1) and by gateway id
+type templateFacetsResponse struct {
+	Status  map[string]uint64 `json:"status"`
+	Gateway map[string]uint64 `json:"gateway"`
+}
+
+func NewTemplateFacetsResponse(facets domain.TemplateFacets) *templateFacetsResponse {
+	return &templateFacetsResponse{Status: facets.Status, Gateway: facets.Gateway}
+}
+
 type ListTemplatesAPIResponse struct {
 	port.StatusCodeAndMessage `json:",inline"`
 	port.MetaDataResponse     `json:",inline"`
 	Data                      []listTemplatesResponse `json:"data"`
+	Facets                    *templateFacetsResponse `json:"facets,omitempty"`
 }
 
 type fetchTemplateResponse struct {
-	TemplateLocalID uint64 `json:"template_local_id" db:"template_local_id"`
-	ApplicationID   string `json:"application_id" db:"application_id"`
-	TemplateName    string `json:"template_name" db:"template_name"`
-	TemplateFormat  string `json:"template_format" db:"template_format"`
-	SenderID        string `json:"sender_id" db:"sender_id"`
-	EntityID        string `json:"entity_id" db:"entity_id"`
-	TemplateID      string `json:"template_id" db:"template_id"`
-	Gateway         string `json:"gateway" db:"gateway"`
-	MessageType     string `json:"message_type" db:"message_type"`
-	Status          int    `json:"status" db:"status_cd"`
+	TemplateLocalID port.ID `json:"template_local_id" db:"template_local_id" swaggertype:"string" pattern:"^[0-9]+$"`
+	ApplicationID   string  `json:"application_id" db:"application_id"`
+	TemplateName    string  `json:"template_name" db:"template_name"`
+	TemplateFormat  string  `json:"template_format" db:"template_format"`
+	SenderID        string  `json:"sender_id" db:"sender_id"`
+	EntityID        string  `json:"entity_id" db:"entity_id"`
+	TemplateID      string  `json:"template_id" db:"template_id"`
+	Gateway         string  `json:"gateway" db:"gateway"`
+	MessageType     string  `json:"message_type" db:"message_type"`
+	Status          int     `json:"status" db:"status_cd"`
 	TotalCount      uint64
 }
 
@@ -67,10 +100,10 @@ func NewFetchTemplateResponse(templates []domain.MaintainTemplate) []fetchTempla
 	var response []fetchTemplateResponse
 	for _, template := range templates {
 		templateResponse := fetchTemplateResponse{
-			TemplateLocalID: template.TemplateLocalID,
+			TemplateLocalID: port.ID(template.TemplateLocalID),
 			ApplicationID:   template.ApplicationID,
 			TemplateName:    template.TemplateName,
-			TemplateFormat: template.TemplateFormat,
+			TemplateFormat:  template.TemplateFormat,
 			SenderID:        template.SenderID,
 			EntityID:        template.EntityID,
 			TemplateID:      template.TemplateID,
@@ -89,17 +122,30 @@ type FetchTemplateAPIResponse struct {
 	Data []fetchTemplateResponse `json:"data"`
 }
 
+// ETag identifies the fetched template, so that unchanged templates are answered with 304
+func (r FetchTemplateAPIResponse) ETag() string {
+	return serverResponse.NewETag(r.Data)
+}
+
+// fetchTemplateNameResponse is a template of an application, with the ids and the gateway a
+// send request of the template needs
 type fetchTemplateNameResponse struct {
-	TemplateLocalID uint64 `json:"template_local_id" db:"template_local_id"`
-	TemplateName    string `json:"template_name" db:"template_name"`
+	TemplateLocalID port.ID `json:"template_local_id" db:"template_local_id" swaggertype:"string" pattern:"^[0-9]+$"`
+	TemplateName    string  `json:"template_name" db:"template_name"`
+	TemplateID      string  `json:"template_id" db:"template_id"`
+	Gateway         string  `json:"gateway" db:"gateway"`
+	MessageType     string  `json:"message_type" db:"message_type"`
 }
 
 func NewFetchTemplateNameResponse(templateNames []domain.GetTemplatebyAPPID) []fetchTemplateNameResponse {
 	var response []fetchTemplateNameResponse
 	for _, template := range templateNames {
 		templateResponse := fetchTemplateNameResponse{
-			TemplateLocalID: template.TemplateLocalID,
+			TemplateLocalID: port.ID(template.TemplateLocalID),
 			TemplateName:    template.TemplateName,
+			TemplateID:      template.TemplateID,
+			Gateway:         template.Gateway,
+			MessageType:     template.MessageType,
 		}
 		response = append(response, templateResponse)
 	}
@@ -113,20 +159,20 @@ type FetchTemplateNameAPIResponse struct {
 }
 
 type fetchTemplateDetailsResponse struct {
-	TemplateLocalID uint64 `json:"template_local_id" db:"template_local_id"`
-	TemplateName    string `json:"template_name" db:"template_name"`
-	TemplateFormat  string `json:"template_format" db:"template_format"`
-	TemplateID      string `json:"template_id" db:"template_id"`
-	EntityID        string `json:"entity_id" db:"entity_id"`
-	SenderID        string `json:"sender_id" db:"sender_id"`
-	MessageType     string `json:"message_type" db:"message_type"`
+	TemplateLocalID port.ID `json:"template_local_id" db:"template_local_id" swaggertype:"string" pattern:"^[0-9]+$"`
+	TemplateName    string  `json:"template_name" db:"template_name"`
+	TemplateFormat  string  `json:"template_format" db:"template_format"`
+	TemplateID      string  `json:"template_id" db:"template_id"`
+	EntityID        string  `json:"entity_id" db:"entity_id"`
+	SenderID        string  `json:"sender_id" db:"sender_id"`
+	MessageType     string  `json:"message_type" db:"message_type"`
 }
 
 func NewFetchTemplateDetailsResponse(templateDetails []domain.GetTemplateformatbyID) []fetchTemplateDetailsResponse {
 	var response []fetchTemplateDetailsResponse
 	for _, template := range templateDetails {
 		templateResponse := fetchTemplateDetailsResponse{
-			TemplateLocalID: template.TemplateLocalID,
+			TemplateLocalID: port.ID(template.TemplateLocalID),
 			TemplateName:    template.TemplateName,
 			TemplateFormat:  template.TemplateFormat,
 			TemplateID:      template.TemplateID,
@@ -150,6 +196,33 @@ type ToggleTemplateStatusAPIResponse struct {
 	Data                      interface{} `json:"data"`
 }
 
+// templateStatusResult is the result of a template listed in a bulk status update: updated,
+// not_found or already_in_state
+type templateStatusResult struct {
+	TemplateLocalID port.ID `json:"template_local_id" swaggertype:"string" pattern:"^[0-9]+$"`
+	Result          string  `json:"result" enums:"updated,not_found,already_in_state"`
+}
+
+// bulkTemplateStatusResponse counts the templates updated, with the result of each template
+// when they were listed
+type bulkTemplateStatusResponse struct {
+	Updated int64                  `json:"updated"`
+	Results []templateStatusResult `json:"results,omitempty"`
+}
+
+func NewBulkTemplateStatusResponse(result domain.BulkTemplateStatusResult) *bulkTemplateStatusResponse {
+	rsp := &bulkTemplateStatusResponse{Updated: result.Updated}
+	for _, status := range result.Results {
+		rsp.Results = append(rsp.Results, templateStatusResult{TemplateLocalID: port.ID(status.TemplateLocalID), Result: status.Result})
+	}
+	return rsp
+}
+
+type BulkTemplateStatusAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      *bulkTemplateStatusResponse `json:"data"`
+}
+
 // func EditTemplateResponse(provider *domain.MsgProvider) *EditTemplateResponse {
 
 // 	response := EditSMSProviderResponse{
@@ -165,5 +238,28 @@ type ToggleTemplateStatusAPIResponse struct {
 
 type UpdateTemplatesAPIResponse struct {
 	port.StatusCodeAndMessage `json:",inline"`
-	//Data                 *EditTemplateResponse `json:"data"`
+	Data                      *TemplateLintResponse `json:"data,omitempty"`
+}
+
+// PreviewTemplateResponse is a template rendered with sample values. Encoding is detected from
+// the rendered message and may differ from the message_type the template is registered with.
+// Fallback is set when the values did not match and the fallback message of the application was
+// rendered instead, Warnings saying why. Transformations are the message transformers that
+// changed the rendered message.
+type PreviewTemplateResponse struct {
+	TemplateLocalID  port.ID  `json:"template_local_id" swaggertype:"string" pattern:"^[0-9]+$"`
+	RenderedMessage  string   `json:"rendered_message"`
+	BrandingAppended bool     `json:"branding_appended"`
+	Encoding         string   `json:"encoding" enum:"PM,UC"`
+	MessageType      string   `json:"message_type"`
+	Length           int      `json:"length"`
+	SegmentCount     int      `json:"segment_count"`
+	Fallback         bool     `json:"fallback"`
+	Warnings         []string `json:"warnings,omitempty"`
+	Transformations  []string `json:"transformations,omitempty"`
+}
+
+type PreviewTemplateAPIResponse struct {
+	port.StatusCodeAndMessage `json:",inline"`
+	Data                      PreviewTemplateResponse `json:"data"`
 }
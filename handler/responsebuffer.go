@@ -0,0 +1,353 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	config "MgApplication/api-config"
+	log "MgApplication/api-log"
+	"MgApplication/core/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	ResponseBufferEntries = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "sms_response_buffer_entries",
+			Help: "Number of send outcomes waiting in the local response buffer to be stored",
+		},
+	)
+
+	ResponseBufferUsage = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "sms_response_buffer_usage_ratio",
+			Help: "Share of the local response buffer in use, 0 to 1, alerting above sms.responsebuffer.warnusage",
+		},
+	)
+
+	ResponseBufferFlushedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "sms_response_buffer_flushed_total",
+			Help: "Total number of buffered send outcomes stored by the response buffer flusher",
+		},
+	)
+
+	ResponseBufferDroppedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "sms_response_buffer_dropped_total",
+			Help: "Total number of send outcomes lost because the response buffer was full or could not be written",
+		},
+	)
+)
+
+// errResponseBufferFull is the failure of an outcome buffered beyond sms.responsebuffer.maxentries
+var errResponseBufferFull = errors.New("response buffer is full")
+
+// bufferedOutcome is the outcome of a send that could not be stored
+type bufferedOutcome struct {
+	// Request is set when the request was sent before it was stored, it is then stored with the
+	// response. Otherwise the request is stored and pending.
+	Request    *bufferedRequest   `json:"request,omitempty"`
+	Response   domain.MsgResponse `json:"response"`
+	BufferedAt time.Time          `json:"buffered_at"`
+}
+
+// bufferedRequest is a request as buffered, with the references it keeps out of its JSON
+type bufferedRequest struct {
+	domain.MsgRequest
+	ClientReference string            `json:"client_reference,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	Transformations []string          `json:"transformations,omitempty"`
+}
+
+// ResponseBuffer keeps the outcomes of sends that could not be stored, one JSON line each, in the
+// local file sms.responsebuffer.path, so that a gateway response is not lost to a database
+// failure and the client is answered with it. The file holds at most
+// sms.responsebuffer.maxentries outcomes, it survives restarts and a ResponseBufferFlusher
+// stores its outcomes once the database is back. A nil ResponseBuffer, when no path is
+// configured, buffers nothing.
+type ResponseBuffer struct {
+	// mu guards the file and entries, flushMu serializes the flushes
+	mu         sync.Mutex
+	flushMu    sync.Mutex
+	path       string
+	maxEntries int
+	warnUsage  float64
+	entries    int
+}
+
+// NewResponseBuffer creates the ResponseBuffer configured by sms.responsebuffer, counting the
+// outcomes left in its file by an earlier run. It returns nil when sms.responsebuffer.path is not set.
+func NewResponseBuffer(c *config.Config) *ResponseBuffer {
+	path := c.GetString("sms.responsebuffer.path")
+	if path == "" {
+		return nil
+	}
+	maxEntries := c.GetInt("sms.responsebuffer.maxentries")
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	warnUsage := c.GetFloat64("sms.responsebuffer.warnusage")
+	if warnUsage <= 0 || warnUsage > 1 {
+		warnUsage = 0.8
+	}
+	rb := &ResponseBuffer{path: path, maxEntries: maxEntries, warnUsage: warnUsage}
+	outcomes, err := rb.read()
+	if err != nil {
+		log.Error(nil, "Failed to read the response buffer %s: %s", path, err.Error())
+	}
+	rb.setEntries(len(outcomes))
+	// rewriting drops a line torn by a crash, which the next outcome would be appended to
+	if _, statErr := os.Stat(path); err == nil && statErr == nil {
+		if err := rb.rewrite(outcomes); err != nil {
+			log.Error(nil, "Failed to rewrite the response buffer %s: %s", path, err.Error())
+		}
+	}
+	return rb
+}
+
+// Add appends outcome to the buffer and syncs it to disk. It fails when the buffer is full or
+// cannot be written, the outcome being lost.
+func (rb *ResponseBuffer) Add(outcome bufferedOutcome) error {
+	if rb == nil {
+		return errors.New("no response buffer configured")
+	}
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	if rb.entries >= rb.maxEntries {
+		ResponseBufferDroppedTotal.Inc()
+		return fmt.Errorf("%w, %d outcomes waiting", errResponseBufferFull, rb.entries)
+	}
+	line, err := json.Marshal(outcome)
+	if err != nil {
+		ResponseBufferDroppedTotal.Inc()
+		return err
+	}
+	if err := rb.append(append(line, '\n')); err != nil {
+		ResponseBufferDroppedTotal.Inc()
+		return err
+	}
+	rb.setEntries(rb.entries + 1)
+	return nil
+}
+
+// Len returns the number of outcomes waiting in the buffer
+func (rb *ResponseBuffer) Len() int {
+	if rb == nil {
+		return 0
+	}
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	return rb.entries
+}
+
+// Flush stores the buffered outcomes through store, keeping those that fail in the buffer. The
+// database is called without holding the buffer, so sends can buffer their outcomes meanwhile.
+// It returns the number of outcomes stored, with the error of the last that failed.
+func (rb *ResponseBuffer) Flush(ctx context.Context, store msgStore) (int, error) {
+	if rb == nil {
+		return 0, nil
+	}
+	rb.flushMu.Lock()
+	defer rb.flushMu.Unlock()
+
+	rb.mu.Lock()
+	outcomes, err := rb.read()
+	rb.mu.Unlock()
+	if err != nil || len(outcomes) == 0 {
+		return 0, err
+	}
+
+	var kept []bufferedOutcome
+	var lastErr error
+	for i, outcome := range outcomes {
+		if ctx.Err() != nil {
+			kept = append(kept, outcomes[i:]...)
+			lastErr = ctx.Err()
+			break
+		}
+		if err := storeOutcome(store, outcome); err != nil {
+			log.Warn(ctx, "Failed to store the buffered response of %s, buffered at %s: %s", outcome.Response.CommunicationID, outcome.BufferedAt.Format(time.RFC3339), err.Error())
+			kept = append(kept, outcome)
+			lastErr = err
+		}
+	}
+	stored := len(outcomes) - len(kept)
+	ResponseBufferFlushedTotal.Add(float64(stored))
+
+	// outcomes added during the flush follow the ones read
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	current, err := rb.read()
+	if err != nil {
+		return stored, err
+	}
+	if err := rb.rewrite(append(kept, current[len(outcomes):]...)); err != nil {
+		return stored, err
+	}
+	return stored, lastErr
+}
+
+// storeOutcome stores outcome as sendStored or storeSent would have
+func storeOutcome(store msgStore, outcome bufferedOutcome) error {
+	return store.WithMsgTx(func(tx pgx.Tx) error {
+		if outcome.Request != nil {
+			msgreq := outcome.Request.MsgRequest
+			msgreq.ClientReference, msgreq.Metadata = outcome.Request.ClientReference, outcome.Request.Metadata
+			msgreq.Transformations = outcome.Request.Transformations
+			if err := store.SaveMsgRequestInTx(tx, &msgreq); err != nil {
+				return err
+			}
+			outcome.Response.CommunicationID = msgreq.CommunicationID
+		}
+		return store.SaveResponseInTx(tx, &outcome.Response)
+	})
+}
+
+// read returns the outcomes of the file, skipping the lines that cannot be read
+func (rb *ResponseBuffer) read() ([]bufferedOutcome, error) {
+	f, err := os.Open(rb.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var outcomes []bufferedOutcome
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var outcome bufferedOutcome
+		if err := json.Unmarshal(scanner.Bytes(), &outcome); err != nil {
+			log.Error(nil, "Dropping an unreadable line of the response buffer %s: %s", rb.path, err.Error())
+			continue
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes, scanner.Err()
+}
+
+// append writes line at the end of the file and syncs it
+func (rb *ResponseBuffer) append(line []byte) error {
+	if err := os.MkdirAll(filepath.Dir(rb.path), 0o750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(rb.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rewrite replaces the file with outcomes, through a synced temporary file renamed over it
+func (rb *ResponseBuffer) rewrite(outcomes []bufferedOutcome) error {
+	tmp := rb.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, outcome := range outcomes {
+		if err = enc.Encode(outcome); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, rb.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	rb.setEntries(len(outcomes))
+	return nil
+}
+
+// setEntries records the number of buffered outcomes, warning when the usage reaches warnUsage
+func (rb *ResponseBuffer) setEntries(entries int) {
+	rb.entries = entries
+	usage := float64(entries) / float64(rb.maxEntries)
+	ResponseBufferEntries.Set(float64(entries))
+	ResponseBufferUsage.Set(usage)
+	if usage >= rb.warnUsage {
+		log.Warn(nil, "Response buffer %s holds %d of %d outcomes waiting to be stored", rb.path, entries, rb.maxEntries)
+	}
+}
+
+// ResponseBufferFlusher stores the outcomes of the response buffer every
+// sms.responsebuffer.flushinterval
+type ResponseBufferFlusher struct {
+	buffer   *ResponseBuffer
+	store    msgStore
+	interval time.Duration
+	state    jobState
+}
+
+// NewResponseBufferFlusher creates the flusher of the response buffer of ch
+func NewResponseBufferFlusher(ch *MgApplicationHandler, c *config.Config) *ResponseBufferFlusher {
+	interval := c.GetDuration("sms.responsebuffer.flushinterval")
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &ResponseBufferFlusher{buffer: ch.buffer, store: ch.store, interval: interval}
+}
+
+// Run flushes the buffer every interval until ctx is cancelled
+func (f *ResponseBufferFlusher) Run(ctx context.Context) {
+	f.state.setRunning(true)
+	defer f.state.setRunning(false)
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		stored, err := f.buffer.Flush(ctx, f.store)
+		f.state.cycle(err)
+		f.state.setBacklog(int64(f.buffer.Len()))
+		if stored > 0 {
+			log.Info(ctx, "Stored %d buffered responses, %d left", stored, f.buffer.Len())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// WorkerStatus returns the state of the flusher for the system status, the backlog being the
+// outcomes waiting in the buffer
+func (f *ResponseBufferFlusher) WorkerStatus() domain.WorkerStatus {
+	return f.state.status("response_buffer", f.buffer != nil)
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBufferedSMSHandler creates an MgApplicationHandler sending through a CDAC server answering
+// reply, with a response buffer of maxEntries outcomes in a temporary directory
+func newBufferedSMSHandler(t *testing.T, reply string, maxEntries int) (*MgApplicationHandler, *fakeMsgStore, string) {
+	t.Helper()
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(reply))
+	}))
+	t.Cleanup(cdac.Close)
+
+	path := filepath.Join(t.TempDir(), "buffer", "response-buffer.jsonl")
+	c := config.NewConfig(viper.New())
+	c.Set("sms.cdac.url", cdac.URL)
+	c.Set("sms.responsebuffer.path", path)
+	c.Set("sms.responsebuffer.maxentries", maxEntries)
+	store := &fakeMsgStore{}
+	return &MgApplicationHandler{c: c, store: store, buffer: NewResponseBuffer(c)}, store, path
+}
+
+// dispatchTestSMS answers a transactional request through dispatchSMS, stored before it is sent
+// when persist is set
+func dispatchTestSMS(ch *MgApplicationHandler, persist bool) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	if persist {
+		ch.c.Set("sms.msgstorerequest", 1)
+	}
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/sms-request", nil)
+	ch.dispatchSMS(ctx, domain.MsgRequest{
+		ApplicationID:   "7",
+		Priority:        int(domain.PriorityTransactional),
+		MessageText:     "Your article is delivered - INDPOST",
+		SenderID:        "INPOST",
+		MobileNumbers:   "9000000001",
+		TemplateID:      "1007344609998507114",
+		ClientReference: "BKG20240001",
+	})
+	return rec
+}
+
+func bufferedLines(t *testing.T, path string) []bufferedOutcome {
+	t.Helper()
+	rb := &ResponseBuffer{path: path, maxEntries: 1}
+	outcomes, err := rb.read()
+	require.NoError(t, err)
+	return outcomes
+}
+
+// The gateway accepted the message but its response could not be stored: the client gets the
+// gateway response and the response is stored by a later flush
+func TestResponseBufferKeepsTheAcceptedResponseOfAFailedStore(t *testing.T) {
+	ch, store, path := newBufferedSMSHandler(t, "402,MsgID = 150920241726381202115", 10)
+	store.failResponse = errors.New("connection reset by peer")
+
+	rec := dispatchTestSMS(ch, true)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var rsp struct {
+		Data struct {
+			CommunicationID string `json:"communication_id"`
+			ReferenceID     string `json:"reference_id"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, "150920241726381202115", rsp.Data.ReferenceID)
+	assertULID(t, rsp.Data.CommunicationID)
+
+	assert.Empty(t, store.savedResponses)
+	assert.Equal(t, 1, ch.buffer.Len())
+	outcomes := bufferedLines(t, path)
+	require.Len(t, outcomes, 1)
+	assert.Nil(t, outcomes[0].Request)
+	assert.Equal(t, rsp.Data.CommunicationID, outcomes[0].Response.CommunicationID)
+	assert.Equal(t, "150920241726381202115", outcomes[0].Response.ReferenceID)
+	assert.False(t, outcomes[0].BufferedAt.IsZero())
+
+	// still failing, the response stays buffered
+	stored, err := ch.buffer.Flush(context.Background(), store)
+	assert.ErrorIs(t, err, store.failResponse)
+	assert.Zero(t, stored)
+	assert.Equal(t, 1, ch.buffer.Len())
+
+	store.failResponse = nil
+	stored, err = ch.buffer.Flush(context.Background(), store)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored)
+	assert.Zero(t, ch.buffer.Len())
+	assert.Empty(t, bufferedLines(t, path))
+	if assert.Len(t, store.savedResponses, 1) {
+		assert.Equal(t, rsp.Data.CommunicationID, store.savedResponses[0].CommunicationID)
+		assert.Equal(t, "402", store.savedResponses[0].ResponseCode)
+		assert.Equal(t, "150920241726381202115", store.savedResponses[0].ReferenceID)
+	}
+	assert.Equal(t, 1, store.savedRequests)
+}
+
+// Both the gateway and the store failed: the client gets the error and the failed request is
+// stored with its response by a later flush
+func TestResponseBufferKeepsTheRequestSentBeforeItWasStored(t *testing.T) {
+	ch, store, path := newBufferedSMSHandler(t, "Error 401 : Invalid credentials", 10)
+	store.failRequest = errors.New("too many connections")
+
+	rec := dispatchTestSMS(ch, false)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code, rec.Body.String())
+
+	outcomes := bufferedLines(t, path)
+	require.Len(t, outcomes, 1)
+	require.NotNil(t, outcomes[0].Request)
+	assert.Equal(t, "9000000001", outcomes[0].Request.MobileNumbers)
+	assert.Equal(t, "BKG20240001", outcomes[0].Request.ClientReference)
+	assert.Equal(t, "401", outcomes[0].Response.ResponseCode)
+
+	store.failRequest = nil
+	stored, err := ch.buffer.Flush(context.Background(), store)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored)
+	assert.Equal(t, 1, store.savedRequests)
+	if assert.Len(t, store.savedResponses, 1) {
+		assertULID(t, store.savedResponses[0].CommunicationID)
+		assert.Equal(t, outcomes[0].Request.CommunicationID, store.savedResponses[0].CommunicationID)
+	}
+}
+
+// A full buffer loses the response but the client still gets the gateway response
+func TestResponseBufferFull(t *testing.T) {
+	ch, store, path := newBufferedSMSHandler(t, "402,MsgID = 150920241726381202115", 1)
+	store.failResponse = errors.New("connection reset by peer")
+
+	assert.Equal(t, http.StatusCreated, dispatchTestSMS(ch, true).Code)
+	assert.Equal(t, http.StatusCreated, dispatchTestSMS(ch, true).Code)
+	assert.Len(t, bufferedLines(t, path), 1)
+	assert.ErrorIs(t, ch.buffer.Add(bufferedOutcome{}), errResponseBufferFull)
+}
+
+// Outcomes buffered before a restart are counted and flushed by the next run
+func TestResponseBufferSurvivesARestart(t *testing.T) {
+	ch, store, path := newBufferedSMSHandler(t, "402,MsgID = 150920241726381202115", 10)
+	store.failResponse = errors.New("connection reset by peer")
+	dispatchTestSMS(ch, true)
+	dispatchTestSMS(ch, true)
+
+	// a torn last line, as left by a crash, is skipped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"response":{"communication_id":"COMM`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	restarted := NewResponseBuffer(ch.c)
+	assert.Equal(t, 2, restarted.Len())
+	require.NoError(t, restarted.Add(bufferedOutcome{Response: domain.MsgResponse{CommunicationID: "COMM3"}}))
+	assert.Len(t, bufferedLines(t, path), 3)
+
+	store.failResponse = nil
+	stored, err := restarted.Flush(context.Background(), store)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stored)
+	assert.Empty(t, bufferedLines(t, path))
+}
+
+func TestResponseBufferDisabled(t *testing.T) {
+	rb := NewResponseBuffer(config.NewConfig(viper.New()))
+	assert.Nil(t, rb)
+	assert.Error(t, rb.Add(bufferedOutcome{}))
+	stored, err := rb.Flush(context.Background(), &fakeMsgStore{})
+	assert.NoError(t, err)
+	assert.Zero(t, stored)
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	config "MgApplication/api-config"
+	log "MgApplication/api-log"
+	"MgApplication/core/clock"
+	"MgApplication/core/domain"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var GatewayRoutedTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "sms_gateway_routed_total",
+		Help: "Total number of requests routed by the time of day routing instead of the gateway of their template",
+	},
+	[]string{"gateway"},
+)
+
+var errGatewayRoutingConfig = errors.New("invalid sms.routing")
+
+// routeWindowLayout is the layout of the bounds of the routing hours
+const routeWindowLayout = "15:04"
+
+// gatewayRoute routes the requests of priorities through gateway from the minute of the day
+// from, included, to the minute to, excluded, wrapping around midnight when to is before from
+type gatewayRoute struct {
+	gateway    domain.GatewayID
+	priorities map[int]bool
+	from       int
+	to         int
+}
+
+// matches reports whether a request of priority created at minute of the day is routed
+func (gr gatewayRoute) matches(priority int, minute int) bool {
+	if !gr.priorities[priority] {
+		return false
+	}
+	if gr.from <= gr.to {
+		return minute >= gr.from && minute < gr.to
+	}
+	return minute >= gr.from || minute < gr.to
+}
+
+// GatewayRouting routes the requests through a gateway by the time of day, configured per
+// gateway in sms.routing.gateways: hours, the "HH:MM-HH:MM" business time window, and
+// priorities, the priorities routed. A request of a listed priority created within the window
+// of a gateway is sent through it instead of the gateway of its template, e.g. the bulk requests
+// through a cheaper gateway at night. When the windows of several gateways match, the lowest
+// gateway id wins; when none matches the gateway of the template is kept.
+type GatewayRouting struct {
+	routes []gatewayRoute
+}
+
+// NewGatewayRouting creates a new GatewayRouting instance using the sms.routing configuration,
+// failing on an unknown gateway, an invalid window or a gateway without priorities
+func NewGatewayRouting(c *config.Config) (*GatewayRouting, error) {
+	routing := &GatewayRouting{}
+	if !c.GetBool("sms.routing.enabled") {
+		return routing, nil
+	}
+	for gateway := range c.GetStringMap("sms.routing.gateways") {
+		key := "sms.routing.gateways." + gateway
+		id, err := domain.ParseGatewayID(gateway)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errGatewayRoutingConfig, err.Error())
+		}
+		route := gatewayRoute{gateway: id, priorities: make(map[int]bool)}
+		route.from, route.to, err = parseRouteWindow(c.GetString(key + ".hours"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: hours of gateway %s: %s", errGatewayRoutingConfig, gateway, err.Error())
+		}
+		for _, priority := range c.GetIntSlice(key + ".priorities") {
+			if !domain.Priority(priority).Valid() {
+				return nil, fmt.Errorf("%w: invalid priority %d for gateway %s", errGatewayRoutingConfig, priority, gateway)
+			}
+			route.priorities[priority] = true
+		}
+		if len(route.priorities) == 0 {
+			return nil, fmt.Errorf("%w: no priorities for gateway %s", errGatewayRoutingConfig, gateway)
+		}
+		routing.routes = append(routing.routes, route)
+	}
+	sort.Slice(routing.routes, func(i, j int) bool { return routing.routes[i].gateway < routing.routes[j].gateway })
+	return routing, nil
+}
+
+// newGatewayRouting returns the routing of sms.routing, none when it is invalid, which
+// CheckGatewayRouting reports at startup
+func newGatewayRouting(c *config.Config) *GatewayRouting {
+	routing, err := NewGatewayRouting(c)
+	if err != nil {
+		log.Error(nil, "Requests are sent through the gateway of their template: %s", err.Error())
+		return nil
+	}
+	return routing
+}
+
+// CheckGatewayRouting fails startup when sms.routing is invalid
+func CheckGatewayRouting(c *config.Config) error {
+	_, err := NewGatewayRouting(c)
+	return err
+}
+
+// parseRouteWindow parses a "HH:MM-HH:MM" window into its bounds in minutes of the day
+func parseRouteWindow(window string) (int, int, error) {
+	fromRaw, toRaw, ok := strings.Cut(window, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("%q is not a HH:MM-HH:MM window", window)
+	}
+	from, err := time.Parse(routeWindowLayout, strings.TrimSpace(fromRaw))
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is not a HH:MM-HH:MM window", window)
+	}
+	to, err := time.Parse(routeWindowLayout, strings.TrimSpace(toRaw))
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is not a HH:MM-HH:MM window", window)
+	}
+	if from.Equal(to) {
+		return 0, 0, fmt.Errorf("%q is an empty window", window)
+	}
+	return from.Hour()*60 + from.Minute(), to.Hour()*60 + to.Minute(), nil
+}
+
+// Route returns the gateway a request of priority created at now is routed through, empty to
+// keep the gateway of its template
+func (gr *GatewayRouting) Route(priority int, now time.Time) domain.GatewayID {
+	if gr == nil {
+		return ""
+	}
+	ist := clock.InIST(now)
+	minute := ist.Hour()*60 + ist.Minute()
+	for _, route := range gr.routes {
+		if route.matches(priority, minute) {
+			return route.gateway
+		}
+	}
+	return ""
+}
+
+// routeRequest sets the gateway msgreq is routed through, consulted by the store when it
+// resolves the gateway of the template of msgreq
+func (ch *MgApplicationHandler) routeRequest(msgreq *domain.MsgRequest) {
+	gateway := ch.routing.Route(msgreq.Priority, clock.Now())
+	if gateway == "" {
+		return
+	}
+	log.Debug(nil, "Request of priority %d routed through gateway %s", msgreq.Priority, gateway)
+	GatewayRoutedTotal.WithLabelValues(string(gateway)).Inc()
+	msgreq.RouteGateway = string(gateway)
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/clock"
+	"MgApplication/core/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func routingConfig(routes map[string]any) *config.Config {
+	c := config.NewConfig(viper.New())
+	c.Set("sms.routing.enabled", true)
+	c.Set("sms.routing.gateways", routes)
+	return c
+}
+
+func TestGatewayRoutingWindows(t *testing.T) {
+	routing, err := NewGatewayRouting(routingConfig(map[string]any{
+		"2": map[string]any{"hours": "22:00-06:00", "priorities": []int{2, 4}},
+		"1": map[string]any{"hours": "06:00-22:00", "priorities": []int{4}},
+	}))
+	require.NoError(t, err)
+
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 10, 16, hour, minute, 0, 0, clock.IST)
+	}
+	for _, tc := range []struct {
+		priority int
+		at       time.Time
+		want     domain.GatewayID
+	}{
+		{4, at(23, 0), domain.GatewayNIC},
+		{4, at(2, 30), domain.GatewayNIC},
+		{4, at(5, 59), domain.GatewayNIC},
+		{4, at(6, 0), domain.GatewayCDAC},
+		{4, at(21, 59), domain.GatewayCDAC},
+		{4, at(22, 0), domain.GatewayNIC},
+		{2, at(23, 0), domain.GatewayNIC},
+		// no window of the priority, the gateway of the template is kept
+		{2, at(12, 0), ""},
+		{1, at(23, 0), ""},
+	} {
+		assert.Equal(t, tc.want, routing.Route(tc.priority, tc.at.UTC()), "priority %d at %s", tc.priority, tc.at.Format("15:04"))
+	}
+
+	// overlapping windows are resolved by the lowest gateway id
+	routing, err = NewGatewayRouting(routingConfig(map[string]any{
+		"2": map[string]any{"hours": "00:00-12:00", "priorities": []int{2}},
+		"1": map[string]any{"hours": "10:00-14:00", "priorities": []int{2}},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, domain.GatewayNIC, routing.Route(2, at(9, 0)))
+	assert.Equal(t, domain.GatewayCDAC, routing.Route(2, at(11, 0)))
+
+	// disabled and missing routing keeps the gateway of the template
+	c := routingConfig(map[string]any{"2": map[string]any{"hours": "00:00-12:00", "priorities": []int{2}}})
+	c.Set("sms.routing.enabled", false)
+	routing, err = NewGatewayRouting(c)
+	require.NoError(t, err)
+	assert.Empty(t, routing.Route(2, at(9, 0)))
+	assert.Empty(t, (*GatewayRouting)(nil).Route(2, at(9, 0)))
+}
+
+func TestGatewayRoutingConfig(t *testing.T) {
+	for name, route := range map[string]map[string]any{
+		"unknown gateway":  {"3": map[string]any{"hours": "22:00-06:00", "priorities": []int{2}}},
+		"invalid hours":    {"2": map[string]any{"hours": "22:00", "priorities": []int{2}}},
+		"empty window":     {"2": map[string]any{"hours": "06:00-06:00", "priorities": []int{2}}},
+		"invalid priority": {"2": map[string]any{"hours": "22:00-06:00", "priorities": []int{5}}},
+		"no priorities":    {"2": map[string]any{"hours": "22:00-06:00"}},
+	} {
+		err := CheckGatewayRouting(routingConfig(route))
+		assert.ErrorIs(t, err, errGatewayRoutingConfig, name)
+	}
+}
+
+func TestDispatchSMSRoutedGateway(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var cdacCalls, nicCalls int
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cdacCalls++
+		_, _ = w.Write([]byte("402,MsgID = 150920241726381202115"))
+	}))
+	defer cdac.Close()
+	nic := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nicCalls++
+		_, _ = w.Write([]byte("Message Accepted for Request ID=123020250306~code=API000"))
+	}))
+	defer nic.Close()
+
+	// a window around the current time
+	now := clock.InIST(clock.Now())
+	hours := now.Add(-time.Hour).Format(routeWindowLayout) + "-" + now.Add(time.Hour).Format(routeWindowLayout)
+	c := routingConfig(map[string]any{"2": map[string]any{"hours": hours, "priorities": []int{2}}})
+	c.Set("sms.msgstorerequest", 1)
+	c.Set("sms.cdac.url", cdac.URL)
+	c.Set("sms.nic.url", nic.URL)
+	c.Set("sms.nic.INPOSTusername", "speedpost.sms")
+
+	for _, tc := range []struct {
+		priority int
+		gateway  domain.GatewayID
+	}{
+		{2, domain.GatewayNIC},
+		{1, domain.GatewayCDAC},
+	} {
+		store := &fakeMsgStore{}
+		ch := &MgApplicationHandler{c: c, store: store, routing: newGatewayRouting(c)}
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/sms-request", nil)
+		ch.dispatchSMS(ctx, domain.MsgRequest{
+			ApplicationID: "7",
+			Priority:      tc.priority,
+			MessageText:   "Delivered",
+			SenderID:      "INPOST",
+			MobileNumbers: "9000000001",
+			TemplateID:    "1007344609998507114",
+		})
+		assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		assert.Equal(t, string(tc.gateway), store.lastRequest.Gateway, "priority %d", tc.priority)
+	}
+	assert.Equal(t, 1, nicCalls)
+	assert.Equal(t, 1, cdacCalls)
+}
@@ -0,0 +1,317 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	log "MgApplication/api-log"
+	"MgApplication/core/clock"
+	"MgApplication/core/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	SendCapThrottledTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sms_send_cap_throttled_total",
+			Help: "Total number of requests rejected by the hourly send cap of their sender ID or template",
+		},
+		[]string{"kind", "key"},
+	)
+	SendCapReservedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sms_send_cap_reserved_total",
+			Help: "Total number of messages this instance reserved against the hourly send cap of a sender ID or template",
+		},
+		[]string{"kind", "key"},
+	)
+)
+
+// The kinds of hourly send cap, counted apart
+const (
+	SendCapSender   = "sender"
+	SendCapTemplate = "template"
+)
+
+// The error ids of the requests rejected by the hourly send caps
+const (
+	ErrorIDSenderThrottled   = apierrors.SourceSenderThrottled
+	ErrorIDTemplateThrottled = apierrors.SourceTemplateThrottled
+)
+
+// sendCapWindow is the window the send caps are counted in, the caps sliding over two of them
+const sendCapWindow = time.Hour
+
+// The caps and the reservation size applying without sms.sendcaps.senderhourly,
+// sms.sendcaps.templatehourly and sms.sendcaps.batch
+const (
+	defaultSenderHourlyCap   = 50000
+	defaultTemplateHourlyCap = 20000
+	defaultSendCapBatch      = 20
+)
+
+// sendCapStore counts the messages sent per sender ID and template and hour, implemented by
+// repo.MgApplicationRepository
+type sendCapStore interface {
+	ConsumeSendCapRepo(ctx context.Context, kind string, key string, window time.Time, count int64, limit int64) (int64, bool, error)
+	GetSendCapRepo(ctx context.Context, kind string, key string, window time.Time) (int64, error)
+}
+
+// SendCapThrottledError rejects a request that would take the messages of its sender ID or of
+// its template beyond their hourly cap
+type SendCapThrottledError struct {
+	Kind string
+	Key  string
+	// Limit is the cap applying to the request, less the OTP headroom for the other priorities
+	Limit int64
+	// Sent is the number of messages counted in the sliding hour, Requested the number of the request
+	Sent      int64
+	Requested int64
+	// ResetAt is the end of the current window, when its messages start to weigh less
+	ResetAt time.Time
+}
+
+func (e *SendCapThrottledError) Error() string {
+	return fmt.Sprintf("hourly cap of %d messages of %s %s reached: %d sent in the last hour, %d requested, window reset at %s",
+		e.Limit, e.Kind, e.Key, e.Sent, e.Requested, clock.InIST(e.ResetAt).Format(time.RFC3339))
+}
+
+// ErrorID returns the error id the request is rejected with, SENDER_THROTTLED or TEMPLATE_THROTTLED
+func (e *SendCapThrottledError) ErrorID() string {
+	if e.Kind == SendCapTemplate {
+		return ErrorIDTemplateThrottled
+	}
+	return ErrorIDSenderThrottled
+}
+
+// AppError returns the rejection as a 429 backpressure error of the source of the error id,
+// waiting until the window resets
+func (e *SendCapThrottledError) AppError() *apierrors.AppError {
+	return apierrors.NewBackpressureError(e.ErrorID(), e.ResetAt, e.Error(), e)
+}
+
+// SendCaps caps the number of messages sent per hour with each sender ID and each template,
+// apart from the daily quotas of the applications. The caps are sms.sendcaps.senderhourly and
+// sms.sendcaps.templatehourly, overridden per sender ID in sms.sendcaps.senders and per template
+// ID in sms.sendcaps.templates; a cap of 0 does not limit.
+//
+// The messages are counted per recipient and hour in msg_send_cap, shared by the replicas, and
+// checked over a sliding hour: the messages of the previous hour weigh by the share of it still
+// within the last 60 minutes. Each replica reserves sms.sendcaps.batch messages at a time with an
+// atomic increment and hands them out from memory, so most requests do not reach the database;
+// close to the cap the reservations shrink to the messages of the request. Reserved messages a
+// replica did not send by the end of the hour are lost, the replicas together sending slightly
+// fewer messages than the cap rather than more.
+//
+// sms.sendcaps.otpheadroom percent of every cap is reserved for OTP (priority 1) requests: the
+// other priorities are capped at the rest, OTPs borrowing the headroom once it is reached.
+type SendCaps struct {
+	store          sendCapStore
+	enabled        bool
+	senderHourly   int64
+	templateHourly int64
+	senders        map[string]int64
+	templates      map[string]int64
+	headroom       int64
+	batch          int64
+	now            func() time.Time
+
+	mu    sync.Mutex
+	pools map[sendCapPoolKey]*sendCapPool
+}
+
+// sendCapPoolKey identifies the messages a replica reserved for a cap, OTPs holding their own
+// since they are reserved against the headroom too
+type sendCapPoolKey struct {
+	kind string
+	key  string
+	otp  bool
+}
+
+// sendCapPool holds the messages reserved for a cap in the current window
+type sendCapPool struct {
+	mu     sync.Mutex
+	window time.Time
+	tokens int64
+	// previous is the count of the window before, read once per window
+	previous     int64
+	previousRead bool
+}
+
+// NewSendCaps creates a new SendCaps instance using the sms.sendcaps configuration
+func NewSendCaps(store sendCapStore, c *config.Config) *SendCaps {
+	s := &SendCaps{
+		store:          store,
+		enabled:        c.GetBool("sms.sendcaps.enabled"),
+		senderHourly:   defaultSenderHourlyCap,
+		templateHourly: defaultTemplateHourlyCap,
+		senders:        make(map[string]int64),
+		templates:      make(map[string]int64),
+		headroom:       min(max(c.GetInt64("sms.sendcaps.otpheadroom"), 0), 100),
+		batch:          defaultSendCapBatch,
+		now:            clock.Now,
+		pools:          make(map[sendCapPoolKey]*sendCapPool),
+	}
+	if c.IsSet("sms.sendcaps.senderhourly") {
+		s.senderHourly = c.GetInt64("sms.sendcaps.senderhourly")
+	}
+	if c.IsSet("sms.sendcaps.templatehourly") {
+		s.templateHourly = c.GetInt64("sms.sendcaps.templatehourly")
+	}
+	if batch := c.GetInt64("sms.sendcaps.batch"); batch > 0 {
+		s.batch = batch
+	}
+	for sender := range c.GetStringMap("sms.sendcaps.senders") {
+		s.senders[sender] = c.GetInt64("sms.sendcaps.senders." + sender)
+	}
+	for template := range c.GetStringMap("sms.sendcaps.templates") {
+		s.templates[template] = c.GetInt64("sms.sendcaps.templates." + template)
+	}
+	return s
+}
+
+// Limit returns the hourly cap of the messages of kind with key, 0 for none
+func (s *SendCaps) Limit(kind string, key string) int64 {
+	if kind == SendCapTemplate {
+		if limit, ok := s.templates[strings.ToLower(key)]; ok {
+			return limit
+		}
+		return s.templateHourly
+	}
+	if limit, ok := s.senders[strings.ToLower(key)]; ok {
+		return limit
+	}
+	return s.senderHourly
+}
+
+// Consume counts the recipients of msgreq against the hourly caps of its sender ID and of its
+// template, returning a *SendCapThrottledError when they do not fit either. The messages are
+// counted once accepted for sending, whatever the outcome of the send. When the count cannot be
+// read the request is allowed, the caps protecting the sender reputation rather than guarding
+// the sends.
+func (s *SendCaps) Consume(ctx context.Context, msgreq domain.MsgRequest) error {
+	if s == nil || !s.enabled {
+		return nil
+	}
+	requested := int64(len(strings.Split(msgreq.MobileNumbers, ",")))
+	otp := domain.Priority(msgreq.Priority) == domain.PriorityOTP
+	now := s.now()
+	if err := s.consume(ctx, SendCapSender, msgreq.SenderID, otp, requested, now); err != nil {
+		return err
+	}
+	if err := s.consume(ctx, SendCapTemplate, msgreq.TemplateID, otp, requested, now); err != nil {
+		// the messages taken from the sender cap are handed out to the next request
+		s.release(SendCapSender, msgreq.SenderID, otp, requested, now)
+		return err
+	}
+	return nil
+}
+
+// consume takes count messages from the cap of kind and key, reserving more in msg_send_cap
+// when the ones held do not suffice
+func (s *SendCaps) consume(ctx context.Context, kind string, key string, otp bool, count int64, now time.Time) error {
+	limit := s.Limit(kind, key)
+	if key == "" || limit <= 0 {
+		return nil
+	}
+	if !otp {
+		limit -= limit * s.headroom / 100
+	}
+	window := sendCapWindowStart(now)
+	pool := s.pool(sendCapPoolKey{kind: kind, key: key, otp: otp})
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if !pool.window.Equal(window) {
+		pool.window, pool.tokens, pool.previousRead = window, 0, false
+	}
+	if pool.tokens >= count {
+		pool.tokens -= count
+		return nil
+	}
+
+	if !pool.previousRead {
+		previous, err := s.store.GetSendCapRepo(ctx, kind, key, window.Add(-sendCapWindow))
+		if err != nil {
+			log.Error(ctx, "Hourly cap of %s %s not checked: %s", kind, key, err.Error())
+			return nil
+		}
+		pool.previous, pool.previousRead = previous, true
+	}
+	// the messages of the previous window still within the sliding hour
+	carried := int64(float64(pool.previous) * float64(window.Add(sendCapWindow).Sub(now)) / float64(sendCapWindow))
+
+	needed := count - pool.tokens
+	reserved := max(needed, s.batch)
+	sent, ok, err := s.store.ConsumeSendCapRepo(ctx, kind, key, window, reserved, limit-carried)
+	if err == nil && !ok && reserved > needed {
+		reserved = needed
+		sent, ok, err = s.store.ConsumeSendCapRepo(ctx, kind, key, window, reserved, limit-carried)
+	}
+	if err != nil {
+		log.Error(ctx, "Hourly cap of %s %s not checked: %s", kind, key, err.Error())
+		return nil
+	}
+	if !ok {
+		SendCapThrottledTotal.WithLabelValues(kind, key).Inc()
+		return &SendCapThrottledError{
+			Kind:      kind,
+			Key:       key,
+			Limit:     limit,
+			Sent:      sent + carried,
+			Requested: count,
+			ResetAt:   window.Add(sendCapWindow),
+		}
+	}
+	SendCapReservedTotal.WithLabelValues(kind, key).Add(float64(reserved))
+	pool.tokens += reserved - count
+	return nil
+}
+
+// release hands count messages taken from the cap of kind and key back to its pool, unless the
+// window they were taken in is over
+func (s *SendCaps) release(kind string, key string, otp bool, count int64, now time.Time) {
+	if key == "" || s.Limit(kind, key) <= 0 {
+		return
+	}
+	pool := s.pool(sendCapPoolKey{kind: kind, key: key, otp: otp})
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if pool.window.Equal(sendCapWindowStart(now)) {
+		pool.tokens += count
+	}
+}
+
+// pool returns the messages held for key, created on first use
+func (s *SendCaps) pool(key sendCapPoolKey) *sendCapPool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pool, ok := s.pools[key]
+	if !ok {
+		pool = &sendCapPool{}
+		s.pools[key] = pool
+	}
+	return pool
+}
+
+// sendCapWindowStart returns the start of the IST hour t falls in, as a UTC instant
+func sendCapWindowStart(t time.Time) time.Time {
+	ist := clock.InIST(t)
+	return time.Date(ist.Year(), ist.Month(), ist.Day(), ist.Hour(), 0, 0, 0, clock.IST).UTC()
+}
+
+// respondSendCapThrottled answers a request rejected by an hourly send cap with 429, the
+// SENDER_THROTTLED or TEMPLATE_THROTTLED error id and the cap in the X-Cap-Limit and X-Cap-Reset
+// headers, Retry-After giving the seconds until the window resets
+func respondSendCapThrottled(ctx *gin.Context, err *SendCapThrottledError) {
+	ctx.Header("X-Cap-Limit", strconv.FormatInt(err.Limit, 10))
+	ctx.Header("X-Cap-Reset", err.ResetAt.Format(time.RFC3339))
+	apierrors.HandleBackpressureError(ctx, err.AppError())
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	"MgApplication/core/clock"
+	"MgApplication/core/domain"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSendCapStore counts the messages in memory like msg_send_cap, shared by the replicas of a test
+type fakeSendCapStore struct {
+	mu   sync.Mutex
+	sent map[string]int64
+}
+
+func (s *fakeSendCapStore) ConsumeSendCapRepo(ctx context.Context, kind string, key string, window time.Time, count int64, limit int64) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := kind + "/" + key + "/" + window.Format(time.RFC3339)
+	if s.sent[k]+count > limit {
+		return s.sent[k], false, nil
+	}
+	s.sent[k] += count
+	return s.sent[k], true, nil
+}
+
+func (s *fakeSendCapStore) GetSendCapRepo(ctx context.Context, kind string, key string, window time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sent[kind+"/"+key+"/"+window.Format(time.RFC3339)], nil
+}
+
+func sendCapConfig(senderHourly int64, headroom int64, batch int64) *config.Config {
+	c := config.NewConfig(viper.New())
+	c.Set("sms.sendcaps.enabled", true)
+	c.Set("sms.sendcaps.senderhourly", senderHourly)
+	c.Set("sms.sendcaps.templatehourly", 0)
+	c.Set("sms.sendcaps.otpheadroom", headroom)
+	c.Set("sms.sendcaps.batch", batch)
+	return c
+}
+
+// sendCapRequest is a request of priority to one recipient with sender INPOST
+func sendCapRequest(priority domain.Priority) domain.MsgRequest {
+	return domain.MsgRequest{ApplicationID: "7", Priority: int(priority), SenderID: "INPOST", TemplateID: "1007344609998507114", MobileNumbers: "9000000001"}
+}
+
+func TestSendCapsWindowRollover(t *testing.T) {
+	store := &fakeSendCapStore{sent: make(map[string]int64)}
+	caps := NewSendCaps(store, sendCapConfig(10, 0, 4))
+	now := time.Date(2026, 10, 16, 10, 5, 0, 0, clock.IST)
+	caps.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, caps.Consume(ctx, sendCapRequest(domain.PriorityTransactional)), "message %d", i+1)
+	}
+	err := caps.Consume(ctx, sendCapRequest(domain.PriorityTransactional))
+	throttled, ok := apierrors.Find[*SendCapThrottledError](err)
+	require.True(t, ok, err)
+	assert.Equal(t, ErrorIDSenderThrottled, throttled.ErrorID())
+	assert.EqualValues(t, 10, throttled.Sent)
+	assert.Equal(t, time.Date(2026, 10, 16, 11, 0, 0, 0, clock.IST), throttled.ResetAt.In(clock.IST))
+
+	// half way through the next hour half of the previous one is still counted
+	now = time.Date(2026, 10, 16, 11, 30, 0, 0, clock.IST)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, caps.Consume(ctx, sendCapRequest(domain.PriorityTransactional)), "message %d", i+1)
+	}
+	require.Error(t, caps.Consume(ctx, sendCapRequest(domain.PriorityTransactional)))
+
+	// two hours later nothing is carried over
+	now = time.Date(2026, 10, 16, 13, 0, 0, 0, clock.IST)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, caps.Consume(ctx, sendCapRequest(domain.PriorityTransactional)), "message %d", i+1)
+	}
+	require.Error(t, caps.Consume(ctx, sendCapRequest(domain.PriorityTransactional)))
+}
+
+func TestSendCapsOTPHeadroom(t *testing.T) {
+	store := &fakeSendCapStore{sent: make(map[string]int64)}
+	caps := NewSendCaps(store, sendCapConfig(10, 20, 1))
+	ctx := context.Background()
+
+	// transactional messages stop at 80% of the cap
+	for i := 0; i < 8; i++ {
+		require.NoError(t, caps.Consume(ctx, sendCapRequest(domain.PriorityTransactional)))
+	}
+	err := caps.Consume(ctx, sendCapRequest(domain.PriorityTransactional))
+	throttled, ok := apierrors.Find[*SendCapThrottledError](err)
+	require.True(t, ok, err)
+	assert.EqualValues(t, 8, throttled.Limit)
+
+	// OTPs borrow the headroom, up to the whole cap
+	for i := 0; i < 2; i++ {
+		require.NoError(t, caps.Consume(ctx, sendCapRequest(domain.PriorityOTP)))
+	}
+	require.Error(t, caps.Consume(ctx, sendCapRequest(domain.PriorityOTP)))
+}
+
+func TestSendCapsReplicas(t *testing.T) {
+	const limit, batch = 50, 4
+	store := &fakeSendCapStore{sent: make(map[string]int64)}
+	replicas := []*SendCaps{
+		NewSendCaps(store, sendCapConfig(limit, 0, batch)),
+		NewSendCaps(store, sendCapConfig(limit, 0, batch)),
+	}
+	now := clock.Now()
+	for _, caps := range replicas {
+		caps.now = func() time.Time { return now }
+	}
+
+	// the replicas racing for the cap never send more than it together
+	var wg sync.WaitGroup
+	var accepted atomic.Int64
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(caps *SendCaps) {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				if caps.Consume(context.Background(), sendCapRequest(domain.PriorityTransactional)) == nil {
+					accepted.Add(1)
+				}
+			}
+		}(replicas[i%2])
+	}
+	wg.Wait()
+
+	reserved, err := store.GetSendCapRepo(context.Background(), SendCapSender, "INPOST", sendCapWindowStart(now))
+	require.NoError(t, err)
+	var held int64
+	for _, caps := range replicas {
+		held += caps.pool(sendCapPoolKey{kind: SendCapSender, key: "INPOST"}).tokens
+	}
+	assert.LessOrEqual(t, reserved, int64(limit))
+	assert.Equal(t, reserved, accepted.Load()+held, "every message reserved is sent or still held")
+	assert.GreaterOrEqual(t, accepted.Load(), int64(limit-len(replicas)*(batch-1)), "at most a batch per replica goes unsent")
+}
+
+func TestCreateSMSSendCaps(t *testing.T) {
+	var calls atomic.Int64
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_, _ = w.Write([]byte("402,MsgID = 150920241726381202115"))
+	}))
+	defer cdac.Close()
+	c := brandingConfig(true)
+	c.Set("sms.cdac.url", cdac.URL)
+	c.Set("sms.sendcaps.enabled", true)
+	c.Set("sms.sendcaps.senderhourly", 3)
+	c.Set("sms.sendcaps.templates", map[string]any{"1007344609998507115": 1})
+
+	ch, _ := newTestSMSHandler(c)
+	ch.caps = NewSendCaps(&fakeSendCapStore{sent: make(map[string]int64)}, c)
+
+	for i := 1; i <= 3; i++ {
+		rec := postSMSRequest(ch, otpRequestBody("900000000"+strconv.Itoa(i)))
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	rec := postSMSRequest(ch, otpRequestBody("9000000004"))
+	require.Equal(t, http.StatusTooManyRequests, rec.Code, rec.Body.String())
+	assert.Equal(t, "3", rec.Header().Get("X-Cap-Limit"))
+	assert.Equal(t, sendCapWindowStart(clock.Now()).Add(time.Hour).Format(time.RFC3339), rec.Header().Get("X-Cap-Reset"))
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+	var body apierrors.APIErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, ErrorIDSenderThrottled, body.AppError.ID)
+	assert.EqualValues(t, 3, calls.Load(), "the rejected requests are not sent")
+
+	// the template cap applies to the messages of other senders too
+	templated := otpRequestBody("9000000005")
+	templated["sender_id"] = "DOPBNK"
+	templated["message_text"] = "Dear Customer, OTP for login is 1234 - IndiaPost Bank"
+	templated["template_id"] = "1007344609998507115"
+	rec = postSMSRequest(ch, templated)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	templated["mobile_numbers"] = "9000000006"
+	rec = postSMSRequest(ch, templated)
+	require.Equal(t, http.StatusTooManyRequests, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, ErrorIDTemplateThrottled, body.AppError.ID)
+}
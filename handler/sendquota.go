@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	log "MgApplication/api-log"
+	"MgApplication/core/clock"
+	"MgApplication/core/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var SendQuotaExceededTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "sms_send_quota_exceeded_total",
+		Help: "Total number of requests rejected because their application exceeded its daily send quota",
+	},
+	[]string{"kind"},
+)
+
+// The kinds of daily send quota, counted apart
+const (
+	SendQuotaSMS = "sms"
+	SendQuotaOTP = "otp"
+)
+
+// ErrorIDSendQuotaExceeded is the error id of the requests rejected by the daily send quota
+const ErrorIDSendQuotaExceeded = apierrors.SourceSendQuotaExceeded
+
+// sendQuotaStore counts the messages sent per application and business day, implemented by
+// repo.MgApplicationRepository
+type sendQuotaStore interface {
+	ConsumeSendQuotaRepo(ctx context.Context, applicationID string, kind string, day time.Time, count int64, limit int64) (int64, bool, error)
+}
+
+// SendQuotaExceededError rejects a request that would take the messages sent by its application
+// on the business day beyond its daily quota
+type SendQuotaExceededError struct {
+	ApplicationID string
+	Kind          string
+	Limit         int64
+	// Sent is the number of messages counted that day, Requested the number of the request
+	Sent      int64
+	Requested int64
+	// ResetAt is the start of the next business day, when the count starts over
+	ResetAt time.Time
+}
+
+func (e *SendQuotaExceededError) Error() string {
+	return fmt.Sprintf("daily %s quota of %d messages of application %s exceeded: %d sent, %d remaining, %d requested, reset at %s",
+		e.Kind, e.Limit, e.ApplicationID, e.Sent, e.Remaining(), e.Requested, clock.InIST(e.ResetAt).Format(time.RFC3339))
+}
+
+// Remaining returns the number of messages the application may still send that day
+func (e *SendQuotaExceededError) Remaining() int64 {
+	return max(e.Limit-e.Sent, 0)
+}
+
+// SendQuota caps the number of messages each application sends per business day, counted per
+// recipient in msg_send_quota and starting over at midnight IST. OTP (priority 1) messages count
+// against their own quota, sms.quota.otpdaily, the other priorities against sms.quota.daily; an
+// application overrides either in sms.quota.applications. A quota of 0 does not limit.
+type SendQuota struct {
+	store        sendQuotaStore
+	daily        int64
+	otpDaily     int64
+	applications map[string]sendQuotaLimits
+}
+
+// sendQuotaLimits are the quotas of an application, the defaults applying to the ones not set
+type sendQuotaLimits struct {
+	daily    *int64
+	otpDaily *int64
+}
+
+// NewSendQuota creates a new SendQuota instance using the sms.quota configuration
+func NewSendQuota(store sendQuotaStore, c *config.Config) *SendQuota {
+	q := &SendQuota{
+		store:        store,
+		daily:        c.GetInt64("sms.quota.daily"),
+		otpDaily:     c.GetInt64("sms.quota.otpdaily"),
+		applications: make(map[string]sendQuotaLimits),
+	}
+	for application := range c.GetStringMap("sms.quota.applications") {
+		key := "sms.quota.applications." + application
+		var limits sendQuotaLimits
+		if c.IsSet(key + ".daily") {
+			daily := c.GetInt64(key + ".daily")
+			limits.daily = &daily
+		}
+		if c.IsSet(key + ".otpdaily") {
+			otpDaily := c.GetInt64(key + ".otpdaily")
+			limits.otpDaily = &otpDaily
+		}
+		q.applications[application] = limits
+	}
+	return q
+}
+
+// Limit returns the quota kind and the daily quota of the requests of priority of applicationID,
+// 0 for none
+func (q *SendQuota) Limit(applicationID string, priority int) (string, int64) {
+	limits := q.applications[strings.ToLower(applicationID)]
+	if domain.Priority(priority) == domain.PriorityOTP {
+		if limits.otpDaily != nil {
+			return SendQuotaOTP, *limits.otpDaily
+		}
+		return SendQuotaOTP, q.otpDaily
+	}
+	if limits.daily != nil {
+		return SendQuotaSMS, *limits.daily
+	}
+	return SendQuotaSMS, q.daily
+}
+
+// Consume counts the recipients of msgreq against the daily quota of its application, returning
+// a *SendQuotaExceededError when they do not fit. The messages are counted once accepted for
+// sending, whatever the outcome of the send. When the count cannot be read the request is
+// allowed, the quota bounding costs rather than guarding the sends.
+func (q *SendQuota) Consume(ctx context.Context, msgreq domain.MsgRequest) error {
+	if q == nil {
+		return nil
+	}
+	kind, limit := q.Limit(msgreq.ApplicationID, msgreq.Priority)
+	if limit <= 0 {
+		return nil
+	}
+	requested := int64(len(strings.Split(msgreq.MobileNumbers, ",")))
+	now := clock.Now()
+	sent, ok, err := q.store.ConsumeSendQuotaRepo(ctx, msgreq.ApplicationID, kind, clock.BusinessDay(now), requested, limit)
+	if err != nil {
+		log.Error(ctx, "Daily %s quota of application %s not checked: %s", kind, msgreq.ApplicationID, err.Error())
+		return nil
+	}
+	if ok {
+		return nil
+	}
+	SendQuotaExceededTotal.WithLabelValues(kind).Inc()
+	return &SendQuotaExceededError{
+		ApplicationID: msgreq.ApplicationID,
+		Kind:          kind,
+		Limit:         limit,
+		Sent:          sent,
+		Requested:     requested,
+		ResetAt:       clock.NextBusinessDay(now),
+	}
+}
+
+// respondSendQuotaExceeded answers a request rejected by the daily send quota with 429, the
+// SEND_QUOTA_EXCEEDED error id and the remaining quota in the X-Quota-Limit, X-Quota-Remaining
+// and X-Quota-Reset headers, Retry-After giving the seconds until the quota starts over
+func respondSendQuotaExceeded(ctx *gin.Context, err *SendQuotaExceededError) {
+	ctx.Header("X-Quota-Limit", strconv.FormatInt(err.Limit, 10))
+	ctx.Header("X-Quota-Remaining", strconv.FormatInt(err.Remaining(), 10))
+	ctx.Header("X-Quota-Reset", err.ResetAt.Format(time.RFC3339))
+	apierrors.HandleBackpressureError(ctx, apierrors.NewBackpressureError(ErrorIDSendQuotaExceeded, err.ResetAt, err.Error(), err))
+}
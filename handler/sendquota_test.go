@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apierrors "MgApplication/api-errors"
+	"MgApplication/core/clock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSendQuotaStore counts the messages in memory like msg_send_quota
+type fakeSendQuotaStore struct {
+	mu   sync.Mutex
+	sent map[string]int64
+}
+
+func (s *fakeSendQuotaStore) ConsumeSendQuotaRepo(ctx context.Context, applicationID string, kind string, day time.Time, count int64, limit int64) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := applicationID + "/" + kind + "/" + day.Format(time.RFC3339)
+	if s.sent[key]+count > limit {
+		return s.sent[key], false, nil
+	}
+	s.sent[key] += count
+	return s.sent[key], true, nil
+}
+
+func TestCreateSMSDailySendQuota(t *testing.T) {
+	var calls atomic.Int64
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_, _ = w.Write([]byte("402,MsgID = 150920241726381202115"))
+	}))
+	defer cdac.Close()
+	c := brandingConfig(true)
+	c.Set("sms.cdac.url", cdac.URL)
+	c.Set("sms.quota.daily", 100)
+	c.Set("sms.quota.otpdaily", 10)
+	c.Set("sms.quota.applications", map[string]any{"7": map[string]any{"otpdaily": 3}})
+
+	ch, _ := newTestSMSHandler(c)
+	ch.quota = NewSendQuota(&fakeSendQuotaStore{sent: make(map[string]int64)}, c)
+
+	// the OTP quota of application 7 is overridden to 3 messages
+	for i := 1; i <= 2; i++ {
+		rec := postSMSRequest(ch, otpRequestBody("900000000"+strconv.Itoa(i)))
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	// 1 message left, a request of 2 recipients crosses the quota
+	rec := postSMSRequest(ch, otpRequestBody("9000000003,9000000004"))
+	require.Equal(t, http.StatusTooManyRequests, rec.Code, rec.Body.String())
+	assert.Equal(t, "3", rec.Header().Get("X-Quota-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("X-Quota-Remaining"))
+	assert.Equal(t, clock.NextBusinessDay(clock.Now()).Format(time.RFC3339), rec.Header().Get("X-Quota-Reset"))
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+	var body apierrors.APIErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, ErrorIDSendQuotaExceeded, body.AppError.ID)
+	assert.Contains(t, body.AppError.Message, "2 sent, 1 remaining, 2 requested")
+
+	// the last message fits, the next one does not
+	rec = postSMSRequest(ch, otpRequestBody("9000000003"))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rec = postSMSRequest(ch, otpRequestBody("9000000005"))
+	require.Equal(t, http.StatusTooManyRequests, rec.Code, rec.Body.String())
+	assert.Equal(t, "0", rec.Header().Get("X-Quota-Remaining"))
+	assert.EqualValues(t, 3, calls.Load(), "the rejected requests are not sent")
+
+	// the transactional messages and the other applications have their own quotas
+	body2 := otpRequestBody("9000000006")
+	body2["priority"] = 2
+	rec = postSMSRequest(ch, body2)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	other := otpRequestBody("9000000007")
+	other["application_id"] = "8"
+	rec = postSMSRequest(ch, other)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+}
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	config "MgApplication/api-config"
+	log "MgApplication/api-log"
+	"MgApplication/core/domain"
+	repo "MgApplication/repo/postgres"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Reasons for not sending a shadow copy, the reason label of ShadowDroppedTotal
+const (
+	shadowDropBusy        = "busy"
+	shadowDropRateLimited = "ratelimited"
+	shadowDropNoNumbers   = "nonumbers"
+)
+
+var (
+	ShadowSendsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sms_shadow_sends_total",
+			Help: "Total number of shadow copies sent, by shadow gateway and whether the gateway accepted them",
+		},
+		[]string{"gateway", "accepted"},
+	)
+
+	ShadowDroppedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sms_shadow_dropped_total",
+			Help: "Total number of shadow copies not sent, by reason",
+		},
+		[]string{"reason"},
+	)
+)
+
+// ShadowSender sends a message through a gateway and returns the raw gateway response
+type ShadowSender func(gateway string, msgreq domain.MsgRequest) (string, error)
+
+// ShadowDispatcher sends a copy of the requests of applications with a shadow_gateway to that
+// gateway, to compare the gateways before moving an application. Copies are sent in the
+// background after the primary gateway answered and never to the real recipients: every
+// recipient is replaced by its sms.shadow.numbermap entry, or else by one of the
+// sms.shadow.testnumbers. Requests with a recipient that cannot be replaced are not copied.
+//
+// At most sms.shadow.ratelimit copies are sent per second and sms.shadow.maxinflight at a time,
+// further copies are dropped. The results are stored in msg_shadow_result only, so shadow
+// traffic is not counted in the stats rollup or the usage reports.
+type ShadowDispatcher struct {
+	svc         *repo.MgApplicationRepository
+	send        ShadowSender
+	enabled     bool
+	testNumbers []string
+	numberMap   map[string]string
+	interval    time.Duration
+	timeout     time.Duration
+	inflight    chan struct{}
+
+	mu       sync.Mutex
+	nextSend time.Time
+}
+
+// NewShadowDispatcher creates a new ShadowDispatcher instance using the sms.shadow configuration
+func NewShadowDispatcher(svc *repo.MgApplicationRepository, send ShadowSender, c *config.Config) *ShadowDispatcher {
+	rateLimit := c.GetFloat64("sms.shadow.ratelimit")
+	if rateLimit <= 0 {
+		rateLimit = 1
+	}
+	maxInflight := c.GetInt("sms.shadow.maxinflight")
+	if maxInflight <= 0 {
+		maxInflight = 10
+	}
+	timeout := c.GetDuration("sms.shadow.timeout")
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	var testNumbers []string
+	for _, mobileNumber := range c.GetStringSlice("sms.shadow.testnumbers") {
+		if mobileNumber = strings.TrimSpace(mobileNumber); mobileNumber != "" {
+			testNumbers = append(testNumbers, mobileNumber)
+		}
+	}
+	numberMap := make(map[string]string)
+	for mobileNumber, testNumber := range c.GetStringMapString("sms.shadow.numbermap") {
+		if testNumber = strings.TrimSpace(testNumber); testNumber != "" {
+			numberMap[normalizeMobileNumber(mobileNumber)] = testNumber
+		}
+	}
+
+	return &ShadowDispatcher{
+		svc:         svc,
+		send:        send,
+		enabled:     c.GetBool("sms.shadow.enabled"),
+		testNumbers: testNumbers,
+		numberMap:   numberMap,
+		interval:    time.Duration(float64(time.Second) / rateLimit),
+		timeout:     timeout,
+		inflight:    make(chan struct{}, maxInflight),
+	}
+}
+
+// SubstituteNumbers replaces the recipients of a shadow copy by test numbers. The second
+// result is false when a recipient has no test number, the copy must not be sent then.
+func (d *ShadowDispatcher) SubstituteNumbers(mobileNumbers []string) ([]string, bool) {
+	substituted := make([]string, 0, len(mobileNumbers))
+	seen := make(map[string]bool, len(mobileNumbers))
+	for i, mobileNumber := range mobileNumbers {
+		testNumber, ok := d.numberMap[normalizeMobileNumber(mobileNumber)]
+		if !ok {
+			if len(d.testNumbers) == 0 {
+				return nil, false
+			}
+			testNumber = d.testNumbers[i%len(d.testNumbers)]
+		}
+		if !seen[testNumber] {
+			seen[testNumber] = true
+			substituted = append(substituted, testNumber)
+		}
+	}
+	return substituted, len(substituted) > 0
+}
+
+// allow reports whether a copy may be sent now under the sms.shadow.ratelimit
+func (d *ShadowDispatcher) allow() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	if now.Before(d.nextSend) {
+		return false
+	}
+	d.nextSend = now.Add(d.interval)
+	return true
+}
+
+// Dispatch sends a copy of msgreq to the shadow gateway of its application in the background.
+// primaryGateway and primaryResponse are the gateway that sent msgreq and its raw response;
+// msgreq carries the message text before any gateway specific conversion.
+func (d *ShadowDispatcher) Dispatch(msgreq domain.MsgRequest, primaryGateway string, primaryResponse string) {
+	if d == nil || !d.enabled {
+		return
+	}
+	select {
+	case d.inflight <- struct{}{}:
+	default:
+		ShadowDroppedTotal.WithLabelValues(shadowDropBusy).Inc()
+		return
+	}
+
+	go func() {
+		defer func() { <-d.inflight }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+		defer cancel()
+		d.shadow(ctx, msgreq, primaryGateway, primaryResponse)
+	}()
+}
+
+// shadow sends one copy and stores its result
+func (d *ShadowDispatcher) shadow(ctx context.Context, msgreq domain.MsgRequest, primaryGateway string, primaryResponse string) {
+	shadowGateway, err := d.svc.GetShadowGatewayRepo(ctx, msgreq.ApplicationID)
+	if err != nil || shadowGateway == "" || shadowGateway == primaryGateway {
+		return
+	}
+
+	recipients := strings.Split(msgreq.MobileNumbers, ",")
+	testNumbers, ok := d.SubstituteNumbers(recipients)
+	if !ok {
+		ShadowDroppedTotal.WithLabelValues(shadowDropNoNumbers).Inc()
+		log.Warn(ctx, "Shadow copy of %s not sent, no test numbers configured for its recipients", msgreq.CommunicationID)
+		return
+	}
+	if !d.allow() {
+		ShadowDroppedTotal.WithLabelValues(shadowDropRateLimited).Inc()
+		return
+	}
+	msgreq.MobileNumbers = strings.Join(testNumbers, ",")
+
+	primary, primaryErr := parseGatewayResponse(domain.GatewayID(primaryGateway), primaryResponse, nil)
+	result := domain.ShadowResult{
+		CommunicationID:     msgreq.CommunicationID,
+		ApplicationID:       msgreq.ApplicationID,
+		TemplateID:          msgreq.TemplateID,
+		Recipients:          len(recipients),
+		PrimaryGateway:      primaryGateway,
+		PrimaryResponseCode: primary.ResponseCode,
+		PrimaryAccepted:     primaryErr == nil,
+		ShadowGateway:       shadowGateway,
+	}
+
+	rsp, err := d.send(shadowGateway, msgreq)
+	shadowed, shadowErr := parseGatewayResponse(domain.GatewayID(shadowGateway), rsp, err)
+	result.ShadowResponseCode = shadowed.ResponseCode
+	result.ShadowAccepted = shadowErr == nil
+	result.ShadowResponseText = rsp
+	if err != nil {
+		result.ShadowResponseText = err.Error()
+	}
+	result.ShadowResponseText = gatewaySecretParam.ReplaceAllString(result.ShadowResponseText, "$1=[REDACTED]")
+	ShadowSendsTotal.WithLabelValues(shadowGateway, strconv.FormatBool(result.ShadowAccepted)).Inc()
+
+	if err := d.svc.SaveShadowResultRepo(ctx, &result); err != nil {
+		log.Error(ctx, "Failed to store the shadow result of %s: %s", msgreq.CommunicationID, err.Error())
+	}
+}
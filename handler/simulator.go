@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	config "MgApplication/api-config"
+	log "MgApplication/api-log"
+	"MgApplication/core/domain"
+)
+
+// gatewaySimulatorName is the sms.gateway value that replaces the gateways with the simulator
+const gatewaySimulatorName = "simulator"
+
+// Latency distributions of the simulator
+const (
+	latencyUniform     = "uniform"     // between min and max
+	latencyExponential = "exponential" // min plus an exponential delay so that sends take mean on average, capped at max when above min
+)
+
+// GatewaySimulator answers sends in place of the SMS gateways, so that load tests exercise the
+// handlers, the database and Kafka without sending messages. It is enabled with
+// sms.gateway=simulator outside prod and replaces the gateways listed in sms.simulator.gateways,
+// every gateway when the list is empty. A send waits for a latency drawn from
+// sms.simulator.latency and fails with probability sms.simulator.failurerate, answering in the
+// format of the replaced gateway.
+type GatewaySimulator struct {
+	gateways     map[domain.GatewayID]bool // nil - every gateway
+	failureRate  float64
+	distribution string
+	minLatency   time.Duration
+	meanLatency  time.Duration
+	maxLatency   time.Duration
+
+	random func() float64 // in [0, 1)
+	sleep  func(time.Duration)
+	seq    atomic.Uint64
+}
+
+// NewGatewaySimulator creates a new GatewaySimulator instance using the sms.simulator
+// configuration, nil unless sms.gateway is simulator. The simulator is never enabled in prod.
+func NewGatewaySimulator(c *config.Config) *GatewaySimulator {
+	if c.GetString("sms.gateway") != gatewaySimulatorName {
+		return nil
+	}
+	if c.IsProdEnv() {
+		log.Warn(nil, "sms.gateway=simulator is ignored in prod, messages are sent through the gateways")
+		return nil
+	}
+
+	s := &GatewaySimulator{
+		failureRate:  math.Min(math.Max(c.GetFloat64("sms.simulator.failurerate"), 0), 1),
+		distribution: c.GetString("sms.simulator.latency.distribution"),
+		minLatency:   c.GetDuration("sms.simulator.latency.min"),
+		meanLatency:  c.GetDuration("sms.simulator.latency.mean"),
+		maxLatency:   c.GetDuration("sms.simulator.latency.max"),
+		random:       rand.Float64,
+		sleep:        time.Sleep,
+	}
+	if s.distribution == "" {
+		s.distribution = latencyUniform
+	}
+	if s.maxLatency < s.minLatency {
+		s.maxLatency = s.minLatency
+	}
+	ids := c.GetStringSlice("sms.simulator.gateways")
+	if len(ids) > 0 {
+		s.gateways = make(map[domain.GatewayID]bool, len(ids))
+		for _, id := range ids {
+			s.gateways[domain.GatewayID(id)] = true
+		}
+	}
+	log.Warn(nil, "SMS gateway simulator enabled for gateways %v (empty - all), messages are not sent", ids)
+	return s
+}
+
+// Simulates reports whether sends through gateway are answered by the simulator
+func (s *GatewaySimulator) Simulates(gateway domain.GatewayID) bool {
+	return s != nil && (s.gateways == nil || s.gateways[gateway])
+}
+
+// Latency draws the time a simulated send takes
+func (s *GatewaySimulator) Latency() time.Duration {
+	spread := s.maxLatency - s.minLatency
+	switch s.distribution {
+	case latencyExponential:
+		mean := s.meanLatency - s.minLatency
+		if mean <= 0 {
+			return s.minLatency
+		}
+		delay := time.Duration(-math.Log(1-s.random()) * float64(mean))
+		if s.maxLatency > s.minLatency && delay > spread {
+			delay = spread
+		}
+		return s.minLatency + delay
+	default:
+		return s.minLatency + time.Duration(s.random()*float64(spread))
+	}
+}
+
+// Send simulates a send through gateway and returns the response that gateway would give
+func (s *GatewaySimulator) Send(gateway domain.GatewayID) (string, error) {
+	s.sleep(s.Latency())
+	failed := s.random() < s.failureRate
+	id := fmt.Sprintf("%d%09d", time.Now().Unix(), s.seq.Add(1)%1e9)
+
+	switch gateway {
+	case domain.GatewayCDAC:
+		if failed {
+			return "Error 401 : Simulated failure", nil
+		}
+		return "402,MsgID = " + id + "simulator", nil
+	case domain.GatewayNIC:
+		if failed {
+			return "", fmt.Errorf("unexpected response from sms gateway: Simulated failure")
+		}
+		return "Message Accepted for Request ID=" + id + "~code=API000", nil
+	}
+	return "", fmt.Errorf("invalid gateway %s", gateway)
+}
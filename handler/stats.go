@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	config "MgApplication/api-config"
+	log "MgApplication/api-log"
+	"MgApplication/core/domain"
+	repo "MgApplication/repo/postgres"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var StatsRollupFailuresTotal = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "sms_stats_rollup_failures_total",
+		Help: "Total number of failed hourly stats rollup rebuilds",
+	},
+)
+
+// StatsRollupJob maintains the hourly stats rollup when stats.rollup.mode is job. Every
+// stats.rollup.interval it rebuilds the hours with requests created or updated within the last
+// stats.rollup.lookback from msg_request. Rebuilds are idempotent, so the job can run on every
+// instance.
+type StatsRollupJob struct {
+	svc      *repo.ReportsRepository
+	interval time.Duration
+	lookback time.Duration
+	enabled  bool
+	state    jobState
+}
+
+// NewStatsRollupJob creates a new StatsRollupJob instance using the stats.rollup configuration
+func NewStatsRollupJob(svc *repo.ReportsRepository, c *config.Config) *StatsRollupJob {
+	interval := c.GetDuration("stats.rollup.interval")
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	lookback := c.GetDuration("stats.rollup.lookback")
+	if lookback < interval {
+		lookback = 3 * interval
+	}
+	return &StatsRollupJob{
+		svc:      svc,
+		interval: interval,
+		lookback: lookback,
+		enabled:  c.GetString("stats.rollup.mode") == repo.StatsRollupJob,
+	}
+}
+
+// Run rebuilds the changed hours every stats.rollup.interval until ctx is cancelled
+func (sj *StatsRollupJob) Run(ctx context.Context) {
+	sj.state.setRunning(true)
+	defer sj.state.setRunning(false)
+
+	ticker := time.NewTicker(sj.interval)
+	defer ticker.Stop()
+
+	for {
+		rebuilt, err := sj.svc.RebuildChangedStatsHourlyRepo(ctx, sj.lookback)
+		sj.state.cycle(err)
+		if err != nil {
+			StatsRollupFailuresTotal.Inc()
+			log.Error(ctx, "Stats rollup rebuild failed: %s", err.Error())
+		} else {
+			log.Debug(ctx, "Stats rollup rebuilt %d rows", rebuilt)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// WorkerStatus returns the state of the job for the system status
+func (sj *StatsRollupJob) WorkerStatus() domain.WorkerStatus {
+	return sj.state.status("stats_rollup", sj.enabled)
+}
@@ -0,0 +1,847 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	config "MgApplication/api-config"
+	log "MgApplication/api-log"
+	"MgApplication/core/clock"
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+	repo "MgApplication/repo/postgres"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Delivery statuses of a message and of its recipients. Messages stay submitted until every
+// recipient reached a final status.
+const (
+	DeliveryStatusSubmitted          = "submitted"
+	DeliveryStatusDelivered          = "delivered"
+	DeliveryStatusFailed             = "failed"
+	DeliveryStatusPartiallyDelivered = "partially_delivered"
+)
+
+// deliveryStatusEventName is the event name of the delivery status webhook
+const deliveryStatusEventName = "sms.delivery_status"
+
+var (
+	StatusPollUpdatesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sms_status_poll_updates_total",
+			Help: "Total number of messages moved to a final delivery status by the status poll job, by gateway and status",
+		},
+		[]string{"gateway", "status"},
+	)
+
+	StatusPollFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sms_status_poll_failures_total",
+			Help: "Total number of failed delivery status fetches, by gateway",
+		},
+		[]string{"gateway"},
+	)
+
+	StatusPollFetchesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sms_status_poll_fetches_total",
+			Help: "Total number of delivery statuses fetched by the status poll job, by gateway",
+		},
+		[]string{"gateway"},
+	)
+
+	StatusPollThroughput = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sms_status_poll_throughput",
+			Help: "Delivery statuses fetched per second over the recent batches, by gateway",
+		},
+		[]string{"gateway"},
+	)
+
+	StatusPollBacklog = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sms_status_poll_backlog",
+			Help: "Number of submitted messages awaiting their delivery status, by gateway",
+		},
+		[]string{"gateway"},
+	)
+
+	StatusPollCatchUpSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sms_status_poll_catch_up_seconds",
+			Help: "Estimated time to fetch the delivery status of the backlog at the current throughput, by gateway",
+		},
+		[]string{"gateway"},
+	)
+
+	StatusPollBatchSize = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sms_status_poll_batch_size",
+			Help: "Current batch size of the status poll job, by gateway",
+		},
+		[]string{"gateway"},
+	)
+
+	StatusPollBreakerOpen = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sms_status_poll_breaker_open",
+			Help: "1 while the status poll job stopped polling a gateway after consecutive failures, by gateway",
+		},
+		[]string{"gateway"},
+	)
+
+	StatusWebhookFailuresTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "sms_status_webhook_failures_total",
+			Help: "Total number of delivery status webhooks that could not be delivered",
+		},
+	)
+)
+
+// cdacRecipientStatus maps the status of a CDAC delivery report line to a recipient delivery status
+func cdacRecipientStatus(status string) string {
+	switch strings.ToUpper(strings.TrimSpace(status)) {
+	case "DELIVRD", "DELIVERED":
+		return DeliveryStatusDelivered
+	case "UNDELIV", "UNDELIVERED", "EXPIRED", "REJECTD", "REJECTED", "FAILED":
+		return DeliveryStatusFailed
+	}
+	return DeliveryStatusSubmitted
+}
+
+// cdacTimestampLayouts are the layouts of the timestamps of the CDAC delivery report lines,
+// "DD-MM-YYYY HH:MM:SS" and the ISO date variant some report endpoints answer with
+var cdacTimestampLayouts = []string{"02-01-2006 15:04:05", "2006-01-02 15:04:05"}
+
+// cdacTimestamp converts the timestamp of a CDAC delivery report line, given in IST, to RFC3339 in UTC.
+// Timestamps in an unknown layout are returned as received.
+func cdacTimestamp(raw string) string {
+	raw = strings.TrimSpace(raw)
+	for _, layout := range cdacTimestampLayouts {
+		if t, err := time.ParseInLocation(layout, raw, clock.IST); err == nil {
+			return t.UTC().Format(time.RFC3339)
+		}
+	}
+	return raw
+}
+
+// messageDeliveryStatus returns the final status of a message from the status of its recipients,
+// or DeliveryStatusSubmitted while a recipient is still pending
+func messageDeliveryStatus(recipients []domain.RecipientStatus) string {
+	if len(recipients) == 0 {
+		return DeliveryStatusSubmitted
+	}
+	var delivered, failed int
+	for _, recipient := range recipients {
+		switch recipient.Status {
+		case DeliveryStatusDelivered:
+			delivered++
+		case DeliveryStatusFailed:
+			failed++
+		default:
+			return DeliveryStatusSubmitted
+		}
+	}
+	switch {
+	case failed == 0:
+		return DeliveryStatusDelivered
+	case delivered == 0:
+		return DeliveryStatusFailed
+	}
+	return DeliveryStatusPartiallyDelivered
+}
+
+// progressDelta returns the change of the application counters when a message reaches status.
+// Partially delivered messages count as delivered.
+func progressDelta(status string) domain.DeliveryProgress {
+	if status == DeliveryStatusFailed {
+		return domain.DeliveryProgress{Failed: 1}
+	}
+	return domain.DeliveryProgress{Delivered: 1}
+}
+
+// CDACStatusFetcher fetches delivery reports from the CDAC report API at sms.cdac.deliverystatusurl
+type CDACStatusFetcher struct {
+	url      string
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewCDACStatusFetcher creates a new CDACStatusFetcher instance using the sms.cdac configuration
+func NewCDACStatusFetcher(c *config.Config) (*CDACStatusFetcher, error) {
+	password, err := MD5(c.GetString("sms.cdac.password"))
+	if err != nil {
+		return nil, err
+	}
+	return &CDACStatusFetcher{
+		url:      c.GetString("sms.cdac.deliverystatusurl"),
+		username: c.GetString("sms.cdac.username"),
+		password: password,
+		client:   &http.Client{Timeout: c.GetDuration("sms.statuspoll.timeout")},
+	}, nil
+}
+
+// FetchDeliveryStatus implements port.DeliveryStatusFetcher. The report API answers with one
+// "mobile number,status,timestamp" line per recipient.
+func (cf *CDACStatusFetcher) FetchDeliveryStatus(ctx context.Context, referenceID string) ([]domain.RecipientStatus, error) {
+	params := url.Values{}
+	params.Add("userid", cf.username)
+	params.Add("password", cf.password)
+	params.Add("msgid", referenceID+cf.username)
+	params.Add("pwd_encrypted", strconv.FormatBool(true))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s?%s", cf.url, params.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := cf.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("CDAC delivery status API returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var recipients []domain.RecipientStatus
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("invalid CDAC delivery status line: %q", line)
+		}
+		recipients = append(recipients, domain.RecipientStatus{
+			MobileNumber: strings.TrimSpace(fields[0]),
+			Status:       cdacRecipientStatus(fields[1]),
+		})
+	}
+	return recipients, nil
+}
+
+type deliveryStatusRecipient struct {
+	MobileNumber string `json:"mobile_number"`
+	Status       string `json:"status"`
+}
+
+type deliveryStatusEvent struct {
+	Event           string                    `json:"event"`
+	CommunicationID string                    `json:"communication_id"`
+	ApplicationID   string                    `json:"application_id"`
+	ReferenceID     string                    `json:"reference_id"`
+	Status          string                    `json:"status"`
+	Recipients      []deliveryStatusRecipient `json:"recipients"`
+	UpdatedAt       time.Time                 `json:"updated_at"`
+}
+
+// checkpointSize is the number of fetched messages checkpointed at once
+const checkpointSize = 50
+
+// DeliveryStatusPoller periodically fetches the delivery status of submitted messages from their
+// gateway, stores the final status, posts it to the sms.statuspoll.webhookurl webhook and
+// publishes it to the application progress streams.
+//
+// Webhooks are queued in msg_webhook_outbox with the status change and posted from there every
+// sms.statuspoll.webhookinterval, so they survive webhook outages and restarts. A failed webhook
+// is retried with exponential backoff up to sms.statuspoll.webhookmaxattempts times and then left
+// in the outbox.
+//
+// Each gateway is polled on its own: a pass claims a batch of its messages with SKIP LOCKED, so
+// the job can run on every instance, and fetches them with sms.statuspoll.concurrency fetchers
+// sharing the limit of sms.statuspoll.ratelimit requests per second. Passes follow each other
+// without waiting while batches come back full, so a backlog is worked off at the rate the
+// gateway allows. Only gateways with a delivery report API (CDAC) are polled: NIC has none, so
+// its messages are neither claimed nor counted in the backlog and stay submitted.
+//
+// The batch size adapts to the gateway latency: it is halved when the average fetch of a batch
+// takes longer than sms.statuspoll.slowlatency and grows by half when it takes less than a
+// quarter of it, within sms.statuspoll.minbatchsize and sms.statuspoll.maxbatchsize. After
+// sms.statuspoll.breakerthreshold consecutive failed fetches the gateway is not polled for
+// sms.statuspoll.breakercooldown.
+type DeliveryStatusPoller struct {
+	svc              deliveryStatusStore
+	hub              *ProgressHub
+	fetchers         map[string]port.DeliveryStatusFetcher
+	gateways         map[string]*gatewayPoll
+	interval         time.Duration
+	lease            time.Duration
+	concurrency      int
+	minBatchSize     uint64
+	maxBatchSize     uint64
+	slowLatency      time.Duration
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	maxAge           time.Duration
+	webhookURL       string
+	webhookInterval  time.Duration
+	webhookAttempts  int
+	client           *http.Client
+	enabled          bool
+	state            jobState
+}
+
+// deliveryStatusStore claims the sent messages awaiting their delivery status, records their
+// final status and queues the webhooks, implemented by repo.MgApplicationRepository
+type deliveryStatusStore interface {
+	ClaimPendingDeliveryStatusRepo(ctx context.Context, gateways []string, limit uint64, interval time.Duration, lease time.Duration, maxAge time.Duration) ([]domain.PendingDeliveryStatus, error)
+	MarkDeliveryStatusPolledRepo(ctx context.Context, requestIDs []uint64) error
+	CountPendingDeliveryStatusRepo(ctx context.Context, gateways []string, maxAge time.Duration) (map[string]int64, error)
+	UpdateDeliveryStatusRepo(ctx context.Context, requestID uint64, status string, webhook *domain.WebhookOutboxEvent) (bool, error)
+	ClaimWebhookOutboxRepo(ctx context.Context, limit uint64, lease time.Duration, maxAttempts int) ([]domain.WebhookOutboxEvent, error)
+	DeleteWebhookOutboxRepo(ctx context.Context, outboxID uint64) error
+	RetryWebhookOutboxRepo(ctx context.Context, outboxID uint64, retryIn time.Duration, lastError string) error
+}
+
+// gatewayPoll is the polling state of one gateway
+type gatewayPoll struct {
+	mu         sync.Mutex
+	batchSize  uint64
+	throughput float64 // messages fetched per second, moving average over the recent batches
+	pacer      *pacer
+	breaker    *circuitBreaker
+}
+
+// NewDeliveryStatusPoller creates a new DeliveryStatusPoller instance using the sms.statuspoll configuration
+func NewDeliveryStatusPoller(svc *repo.MgApplicationRepository, hub *ProgressHub, c *config.Config) (*DeliveryStatusPoller, error) {
+	cdac, err := NewCDACStatusFetcher(c)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := c.GetDuration("sms.statuspoll.interval")
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	lease := c.GetDuration("sms.statuspoll.claimlease")
+	if lease <= 0 {
+		lease = 2 * time.Minute
+	}
+	concurrency := c.GetInt("sms.statuspoll.concurrency")
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	batchSize := c.GetInt("sms.statuspoll.batchsize")
+	if batchSize <= 0 {
+		batchSize = 200
+	}
+	minBatchSize := c.GetInt("sms.statuspoll.minbatchsize")
+	if minBatchSize <= 0 || minBatchSize > batchSize {
+		minBatchSize = batchSize
+	}
+	maxBatchSize := c.GetInt("sms.statuspoll.maxbatchsize")
+	if maxBatchSize < batchSize {
+		maxBatchSize = batchSize
+	}
+	slowLatency := c.GetDuration("sms.statuspoll.slowlatency")
+	if slowLatency <= 0 {
+		slowLatency = 2 * time.Second
+	}
+	maxAge := c.GetDuration("sms.statuspoll.maxage")
+	if maxAge <= 0 {
+		maxAge = 72 * time.Hour
+	}
+	webhookInterval := c.GetDuration("sms.statuspoll.webhookinterval")
+	if webhookInterval <= 0 {
+		webhookInterval = 10 * time.Second
+	}
+	webhookAttempts := c.GetInt("sms.statuspoll.webhookmaxattempts")
+	if webhookAttempts <= 0 {
+		webhookAttempts = 10
+	}
+
+	p := &DeliveryStatusPoller{
+		svc:              svc,
+		hub:              hub,
+		fetchers:         map[string]port.DeliveryStatusFetcher{string(domain.GatewayCDAC): cdac},
+		gateways:         make(map[string]*gatewayPoll),
+		interval:         interval,
+		lease:            lease,
+		concurrency:      concurrency,
+		minBatchSize:     uint64(minBatchSize),
+		maxBatchSize:     uint64(maxBatchSize),
+		slowLatency:      slowLatency,
+		breakerThreshold: c.GetInt("sms.statuspoll.breakerthreshold"),
+		breakerCooldown:  c.GetDuration("sms.statuspoll.breakercooldown"),
+		maxAge:           maxAge,
+		webhookURL:       c.GetString("sms.statuspoll.webhookurl"),
+		webhookInterval:  webhookInterval,
+		webhookAttempts:  webhookAttempts,
+		client:           &http.Client{Timeout: c.GetDuration("sms.statuspoll.timeout")},
+		enabled:          c.GetBool("sms.statuspoll.enabled"),
+	}
+	for gateway := range p.fetchers {
+		p.gateways[gateway] = &gatewayPoll{
+			batchSize: uint64(batchSize),
+			pacer:     newPacer(c.GetFloat64("sms.statuspoll.ratelimit")),
+			breaker:   newCircuitBreaker(p.breakerThreshold, p.breakerCooldown),
+		}
+		StatusPollBatchSize.WithLabelValues(gateway).Set(float64(batchSize))
+	}
+	return p, nil
+}
+
+// BatchSize returns the current batch size of gateway
+func (p *DeliveryStatusPoller) BatchSize(gateway string) uint64 {
+	gp, ok := p.gateways[gateway]
+	if !ok {
+		return 0
+	}
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+	return gp.batchSize
+}
+
+// Run polls every gateway until ctx is cancelled. A gateway is polled again right away after a
+// full batch, otherwise after sms.statuspoll.interval or once its circuit breaker closes.
+func (p *DeliveryStatusPoller) Run(ctx context.Context) {
+	p.state.setRunning(true)
+	defer p.state.setRunning(false)
+
+	var wg sync.WaitGroup
+	for gateway := range p.fetchers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.runGateway(ctx, gateway)
+		}()
+	}
+	if p.webhookURL != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.runWebhooks(ctx)
+		}()
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.updateBacklog(ctx)
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *DeliveryStatusPoller) runGateway(ctx context.Context, gateway string) {
+	breaker := p.gateways[gateway].breaker
+	for {
+		updated, more, err := p.pollGateway(ctx, gateway)
+		p.state.cycle(err)
+		if err != nil {
+			log.Error(ctx, "Delivery status poll of gateway %s failed: %s", gateway, err.Error())
+		} else if updated > 0 {
+			log.Info(ctx, "Delivery status poll moved %d messages of gateway %s to a final status", updated, gateway)
+		}
+		if more {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		wait := p.interval
+		if retryIn := breaker.RetryIn(); retryIn > 0 && retryIn < wait {
+			wait = retryIn
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Poll polls one batch of every gateway and returns the number of messages moved to a final status
+func (p *DeliveryStatusPoller) Poll(ctx context.Context) (int, error) {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		updated  int
+		firstErr error
+	)
+	for gateway := range p.fetchers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, _, err := p.pollGateway(ctx, gateway)
+			mu.Lock()
+			defer mu.Unlock()
+			updated += n
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}()
+	}
+	wg.Wait()
+	p.updateBacklog(ctx)
+	p.postWebhooks(ctx)
+	return updated, firstErr
+}
+
+// pollGateway claims one batch of messages of gateway and fetches their delivery status. It
+// returns the number of messages moved to a final status and whether more messages are likely
+// waiting, that is the batch was full and the gateway is healthy.
+func (p *DeliveryStatusPoller) pollGateway(ctx context.Context, gateway string) (int, bool, error) {
+	gp := p.gateways[gateway]
+	if !gp.breaker.Allow() {
+		return 0, false, nil
+	}
+
+	batchSize := p.BatchSize(gateway)
+	claimed, err := p.svc.ClaimPendingDeliveryStatusRepo(ctx, []string{gateway}, batchSize, p.interval, p.lease, p.maxAge)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(claimed) == 0 {
+		return 0, false, nil
+	}
+
+	msgs := make(chan domain.PendingDeliveryStatus)
+	go func() {
+		defer close(msgs)
+		for _, msg := range claimed {
+			select {
+			case <-ctx.Done():
+				return
+			case msgs <- msg:
+			}
+		}
+	}()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		updated   int
+		fetched   int
+		latency   time.Duration
+		polled    []uint64
+		brokenOff bool
+	)
+	// checkpoint stores the fetched messages still pending; shutdown does not cancel it so a
+	// restart does not fetch them again
+	checkpoint := func(all bool) {
+		mu.Lock()
+		if len(polled) == 0 || (!all && len(polled) < checkpointSize) {
+			mu.Unlock()
+			return
+		}
+		ids := polled
+		polled = nil
+		mu.Unlock()
+		if err := p.svc.MarkDeliveryStatusPolledRepo(context.WithoutCancel(ctx), ids); err != nil {
+			log.Warn(ctx, "Failed to checkpoint the delivery status poll of gateway %s: %s", gateway, err.Error())
+		}
+	}
+
+	started := time.Now()
+	for range min(p.concurrency, len(claimed)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range msgs {
+				if !gp.breaker.Allow() || gp.pacer.wait(ctx) != nil {
+					// The remaining messages are claimed again once their lease expires
+					mu.Lock()
+					brokenOff = true
+					mu.Unlock()
+					continue
+				}
+
+				fetchStarted := time.Now()
+				recipients, err := p.fetchers[gateway].FetchDeliveryStatus(ctx, msg.ReferenceID)
+				if err != nil {
+					if ctx.Err() != nil {
+						continue
+					}
+					StatusPollFailuresTotal.WithLabelValues(gateway).Inc()
+					log.Warn(ctx, "Failed to fetch delivery status of %s from gateway %s: %s", msg.CommunicationID, gateway, err.Error())
+					if gp.breaker.Failure() {
+						StatusPollBreakerOpen.WithLabelValues(gateway).Set(1)
+					}
+					continue
+				}
+				gp.breaker.Success()
+				StatusPollBreakerOpen.WithLabelValues(gateway).Set(0)
+				StatusPollFetchesTotal.WithLabelValues(gateway).Inc()
+
+				status := messageDeliveryStatus(recipients)
+				mu.Lock()
+				fetched++
+				latency += time.Since(fetchStarted)
+				if status == DeliveryStatusSubmitted {
+					polled = append(polled, msg.RequestID)
+				}
+				mu.Unlock()
+				if status == DeliveryStatusSubmitted {
+					checkpoint(false)
+					continue
+				}
+
+				ok, err := p.svc.UpdateDeliveryStatusRepo(ctx, msg.RequestID, status, p.webhookEvent(ctx, msg, status, recipients))
+				if err != nil || !ok {
+					continue
+				}
+				mu.Lock()
+				updated++
+				mu.Unlock()
+				StatusPollUpdatesTotal.WithLabelValues(gateway, status).Inc()
+				p.hub.PublishApplicationProgress(msg.ApplicationID, progressDelta(status))
+			}
+		}()
+	}
+	wg.Wait()
+	checkpoint(true)
+
+	full := uint64(len(claimed)) == batchSize
+	p.adapt(gateway, fetched, latency, time.Since(started), full)
+	return updated, full && !brokenOff && gp.breaker.Allow(), nil
+}
+
+// adapt sizes the next batch of gateway from the average fetch latency of the last one and
+// updates the throughput estimate
+func (p *DeliveryStatusPoller) adapt(gateway string, fetched int, latency time.Duration, elapsed time.Duration, full bool) {
+	gp := p.gateways[gateway]
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+
+	switch {
+	case fetched == 0 || latency/time.Duration(fetched) > p.slowLatency:
+		gp.batchSize = max(gp.batchSize/2, p.minBatchSize)
+	case latency/time.Duration(fetched) < p.slowLatency/4 && full:
+		gp.batchSize = min(gp.batchSize+max(gp.batchSize/2, 1), p.maxBatchSize)
+	}
+	StatusPollBatchSize.WithLabelValues(gateway).Set(float64(gp.batchSize))
+
+	if fetched > 0 && elapsed > 0 {
+		rate := float64(fetched) / elapsed.Seconds()
+		if gp.throughput == 0 {
+			gp.throughput = rate
+		} else {
+			gp.throughput = 0.7*gp.throughput + 0.3*rate
+		}
+		StatusPollThroughput.WithLabelValues(gateway).Set(gp.throughput)
+	}
+}
+
+// updateBacklog refreshes the backlog and the estimated catch-up time of every gateway
+func (p *DeliveryStatusPoller) updateBacklog(ctx context.Context) {
+	gateways := make([]string, 0, len(p.fetchers))
+	for gateway := range p.fetchers {
+		gateways = append(gateways, gateway)
+	}
+	backlog, err := p.svc.CountPendingDeliveryStatusRepo(ctx, gateways, p.maxAge)
+	if err != nil {
+		return
+	}
+	var total int64
+	for gateway, pending := range backlog {
+		total += pending
+		StatusPollBacklog.WithLabelValues(gateway).Set(float64(pending))
+		gp, ok := p.gateways[gateway]
+		if !ok {
+			continue
+		}
+		gp.mu.Lock()
+		throughput := gp.throughput
+		gp.mu.Unlock()
+		if throughput > 0 {
+			StatusPollCatchUpSeconds.WithLabelValues(gateway).Set(float64(pending) / throughput)
+		}
+	}
+	p.state.setBacklog(total)
+}
+
+// WorkerStatus returns the state of the poller for the system status, the backlog being the
+// number of submitted messages awaiting their delivery status
+func (p *DeliveryStatusPoller) WorkerStatus() domain.WorkerStatus {
+	return p.state.status("status_poller", p.enabled)
+}
+
+// BreakerStatuses returns the state of the circuit breaker of every polled gateway
+func (p *DeliveryStatusPoller) BreakerStatuses() []domain.BreakerStatus {
+	statuses := make([]domain.BreakerStatus, 0, len(p.gateways))
+	for gateway, gp := range p.gateways {
+		statuses = append(statuses, gp.breaker.Status("status_poll_"+strings.ToLower(domain.GatewayID(gateway).String())))
+	}
+	return statuses
+}
+
+// pacer spaces the requests to a gateway at most rate per second, across all of its fetchers
+type pacer struct {
+	mu    sync.Mutex
+	every time.Duration
+	next  time.Time
+}
+
+// newPacer creates a pacer, which does not wait when rate is not positive
+func newPacer(rate float64) *pacer {
+	if rate <= 0 {
+		return &pacer{}
+	}
+	return &pacer{every: time.Duration(float64(time.Second) / rate)}
+}
+
+// wait blocks until the next request may be made or ctx is cancelled
+func (pc *pacer) wait(ctx context.Context) error {
+	if pc.every == 0 {
+		return ctx.Err()
+	}
+	pc.mu.Lock()
+	now := time.Now()
+	at := pc.next
+	if at.Before(now) {
+		at = now
+	}
+	pc.next = at.Add(pc.every)
+	pc.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// webhookEvent builds the delivery status webhook of the final status of a message, queued with
+// the status change. It is nil when no webhook is configured.
+func (p *DeliveryStatusPoller) webhookEvent(ctx context.Context, msg domain.PendingDeliveryStatus, status string, recipients []domain.RecipientStatus) *domain.WebhookOutboxEvent {
+	if p.webhookURL == "" {
+		return nil
+	}
+
+	event := deliveryStatusEvent{
+		Event:           deliveryStatusEventName,
+		CommunicationID: strings.TrimSpace(msg.CommunicationID),
+		ApplicationID:   msg.ApplicationID,
+		ReferenceID:     msg.ReferenceID,
+		Status:          status,
+		Recipients:      make([]deliveryStatusRecipient, 0, len(recipients)),
+		UpdatedAt:       clock.Now(),
+	}
+	for _, recipient := range recipients {
+		event.Recipients = append(event.Recipients, deliveryStatusRecipient{MobileNumber: recipient.MobileNumber, Status: recipient.Status})
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Error(ctx, "Failed to encode delivery status webhook for %s: %s", event.CommunicationID, err.Error())
+		return nil
+	}
+	return &domain.WebhookOutboxEvent{CommunicationID: event.CommunicationID, Payload: payload}
+}
+
+// runWebhooks posts the queued webhooks every webhookInterval until ctx is cancelled
+func (p *DeliveryStatusPoller) runWebhooks(ctx context.Context) {
+	ticker := time.NewTicker(p.webhookInterval)
+	defer ticker.Stop()
+	for {
+		p.postWebhooks(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// webhookBatchSize is the number of queued webhooks claimed at once
+const webhookBatchSize = 100
+
+// postWebhooks posts the queued webhooks due for an attempt. Delivered webhooks are removed from
+// the outbox, failed ones are rescheduled with webhookRetryIn.
+func (p *DeliveryStatusPoller) postWebhooks(ctx context.Context) {
+	if p.webhookURL == "" {
+		return
+	}
+	for ctx.Err() == nil {
+		claimed, err := p.svc.ClaimWebhookOutboxRepo(ctx, webhookBatchSize, p.client.Timeout+p.webhookInterval, p.webhookAttempts)
+		if err != nil || len(claimed) == 0 {
+			return
+		}
+		for _, event := range claimed {
+			err := p.postWebhook(ctx, event.Payload)
+			if err == nil {
+				if err := p.svc.DeleteWebhookOutboxRepo(context.WithoutCancel(ctx), event.OutboxID); err != nil {
+					log.Warn(ctx, "Delivered delivery status webhook for %s is still queued: %s", event.CommunicationID, err.Error())
+				}
+				continue
+			}
+			StatusWebhookFailuresTotal.Inc()
+			if event.Attempts+1 >= p.webhookAttempts {
+				log.Error(ctx, "Giving up on the delivery status webhook for %s after %d attempts: %s", event.CommunicationID, event.Attempts+1, err.Error())
+			} else {
+				log.Warn(ctx, "Failed to post delivery status webhook for %s, retrying: %s", event.CommunicationID, err.Error())
+			}
+			if err := p.svc.RetryWebhookOutboxRepo(context.WithoutCancel(ctx), event.OutboxID, webhookRetryIn(p.webhookInterval, event.Attempts), err.Error()); err != nil {
+				log.Warn(ctx, "Failed to reschedule the delivery status webhook for %s: %s", event.CommunicationID, err.Error())
+			}
+		}
+		if len(claimed) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// webhookRetryIn is the backoff after the failed attempt following attempts earlier failures,
+// doubling from interval up to an hour
+func webhookRetryIn(interval time.Duration, attempts int) time.Duration {
+	retryIn := interval
+	for range attempts {
+		retryIn *= 2
+		if retryIn >= time.Hour {
+			return time.Hour
+		}
+	}
+	return retryIn
+}
+
+// postWebhook posts the payload of a delivery status webhook
+func (p *DeliveryStatusPoller) postWebhook(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// NIC has no delivery report API, its messages are left out of the claim and backlog queries
+// which only select the gateways of the fetchers
+func TestDeliveryStatusPollerPollsOnlyCDAC(t *testing.T) {
+	p, err := NewDeliveryStatusPoller(nil, nil, config.NewConfig(viper.New()))
+	assert.NoError(t, err)
+
+	gateways := make([]string, 0, len(p.fetchers))
+	for gateway := range p.fetchers {
+		gateways = append(gateways, gateway)
+	}
+	assert.Equal(t, []string{string(domain.GatewayCDAC)}, gateways)
+	assert.NotContains(t, p.gateways, string(domain.GatewayNIC))
+}
+
+func TestWebhookEventIsQueuedOnlyWithAWebhook(t *testing.T) {
+	msg := domain.PendingDeliveryStatus{RequestID: 1, ApplicationID: "7", CommunicationID: "abc123   ", ReferenceID: "SP0000001"}
+	recipients := []domain.RecipientStatus{{MobileNumber: "9000000001", Status: DeliveryStatusDelivered}}
+
+	p := &DeliveryStatusPoller{}
+	assert.Nil(t, p.webhookEvent(context.Background(), msg, DeliveryStatusDelivered, recipients))
+
+	p.webhookURL = "http://localhost/webhook"
+	event := p.webhookEvent(context.Background(), msg, DeliveryStatusDelivered, recipients)
+	if assert.NotNil(t, event) {
+		assert.Equal(t, "abc123", event.CommunicationID)
+		var payload deliveryStatusEvent
+		assert.NoError(t, json.Unmarshal(event.Payload, &payload))
+		assert.Equal(t, deliveryStatusEventName, payload.Event)
+		assert.Equal(t, DeliveryStatusDelivered, payload.Status)
+		assert.Len(t, payload.Recipients, 1)
+	}
+}
+
+func TestWebhookRetryIn(t *testing.T) {
+	assert.Equal(t, 10*time.Second, webhookRetryIn(10*time.Second, 0))
+	assert.Equal(t, 40*time.Second, webhookRetryIn(10*time.Second, 2))
+	assert.Equal(t, time.Hour, webhookRetryIn(10*time.Second, 20))
+}
+
+func TestCDACTimestamp(t *testing.T) {
+	assert.Equal(t, "2024-08-27T04:30:00Z", cdacTimestamp("27-08-2024 10:00:00"))
+	assert.Equal(t, "2024-08-26T19:00:05Z", cdacTimestamp(" 27-08-2024 00:30:05\r"))
+	assert.Equal(t, "2024-08-27T04:30:00Z", cdacTimestamp("2024-08-27 10:00:00"))
+	assert.Equal(t, "not a timestamp", cdacTimestamp("not a timestamp"))
+}
+
+func TestFetchCDACSMSDeliveryStatusConvertsTimestamps(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("919999999999,DELIVRD,27-08-2024 10:00:00"))
+	}))
+	defer srv.Close()
+	c := config.NewConfig(viper.New())
+	c.Set("sms.cdac.deliverystatusurl", srv.URL)
+	ch, _ := newTestSMSHandler(c)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/v1/sms-delivery-status", ch.FetchCDACSMSDeliveryStatusHandler)
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/sms-delivery-status?reference_id=250220251740480271265", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var body struct {
+		Data []struct {
+			TimeStamp string `json:"timestamp"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.Data, 1)
+	assert.Equal(t, "2024-08-27T04:30:00Z", body.Data[0].TimeStamp)
+}
+
+// fakeDeliveryStatusStore keeps submitted CDAC messages in memory and claims them as
+// ClaimPendingDeliveryStatusRepo does: a claimed message is claimed again once its lease
+// expired, a checkpointed one once the poll interval passed.
+type fakeDeliveryStatusStore struct {
+	mu       sync.Mutex
+	now      time.Time
+	messages []*fakePendingMessage
+}
+
+type fakePendingMessage struct {
+	msg      domain.PendingDeliveryStatus
+	status   string
+	polledAt *time.Time
+}
+
+// newFakeDeliveryStatusStore stores n submitted CDAC messages accepted under reference ids
+// starting with prefix
+func newFakeDeliveryStatusStore(prefix string, n int) *fakeDeliveryStatusStore {
+	store := &fakeDeliveryStatusStore{now: time.Now()}
+	for i := 1; i <= n; i++ {
+		store.messages = append(store.messages, &fakePendingMessage{
+			msg: domain.PendingDeliveryStatus{
+				RequestID:       uint64(i),
+				ApplicationID:   "7",
+				CommunicationID: fmt.Sprintf("COMM%d", i),
+				ReferenceID:     fmt.Sprintf("%s%07d", prefix, i),
+				Gateway:         string(domain.GatewayCDAC),
+			},
+			status: DeliveryStatusSubmitted,
+		})
+	}
+	return store
+}
+
+// advance moves the clock of the store, for leases and poll intervals to pass
+func (s *fakeDeliveryStatusStore) advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = s.now.Add(d)
+}
+
+func (s *fakeDeliveryStatusStore) ClaimPendingDeliveryStatusRepo(ctx context.Context, gateways []string, limit uint64, interval time.Duration, lease time.Duration, maxAge time.Duration) ([]domain.PendingDeliveryStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	leaseOffset := time.Duration(0)
+	if lease > 0 && lease < interval {
+		leaseOffset = interval - lease
+	}
+	var due []*fakePendingMessage
+	for _, m := range s.messages {
+		if m.status == DeliveryStatusSubmitted && (m.polledAt == nil || !m.polledAt.After(s.now.Add(-interval))) {
+			due = append(due, m)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		if (due[i].polledAt == nil) != (due[j].polledAt == nil) {
+			return due[i].polledAt == nil
+		}
+		return due[i].polledAt != nil && due[i].polledAt.Before(*due[j].polledAt)
+	})
+	var claimed []domain.PendingDeliveryStatus
+	for _, m := range due[:min(uint64(len(due)), limit)] {
+		polledAt := s.now.Add(-leaseOffset)
+		m.polledAt = &polledAt
+		claimed = append(claimed, m.msg)
+	}
+	return claimed, nil
+}
+
+func (s *fakeDeliveryStatusStore) MarkDeliveryStatusPolledRepo(ctx context.Context, requestIDs []uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range requestIDs {
+		if m := s.messages[id-1]; m.status == DeliveryStatusSubmitted {
+			polledAt := s.now
+			m.polledAt = &polledAt
+		}
+	}
+	return nil
+}
+
+func (s *fakeDeliveryStatusStore) CountPendingDeliveryStatusRepo(ctx context.Context, gateways []string, maxAge time.Duration) (map[string]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := map[string]int64{}
+	for _, m := range s.messages {
+		if m.status == DeliveryStatusSubmitted {
+			pending[m.msg.Gateway]++
+		}
+	}
+	return pending, nil
+}
+
+func (s *fakeDeliveryStatusStore) UpdateDeliveryStatusRepo(ctx context.Context, requestID uint64, status string, webhook *domain.WebhookOutboxEvent) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.messages[requestID-1]
+	if m.status != DeliveryStatusSubmitted {
+		return false, nil
+	}
+	m.status = status
+	return true, nil
+}
+
+func (s *fakeDeliveryStatusStore) ClaimWebhookOutboxRepo(ctx context.Context, limit uint64, lease time.Duration, maxAttempts int) ([]domain.WebhookOutboxEvent, error) {
+	return nil, nil
+}
+
+func (s *fakeDeliveryStatusStore) DeleteWebhookOutboxRepo(ctx context.Context, outboxID uint64) error {
+	return nil
+}
+
+func (s *fakeDeliveryStatusStore) RetryWebhookOutboxRepo(ctx context.Context, outboxID uint64, retryIn time.Duration, lastError string) error {
+	return nil
+}
+
+// pendingReportServer is a fake CDAC report API reporting every recipient as pending after
+// latency. fetched is called with each message id before answering.
+func pendingReportServer(t *testing.T, latency *atomic.Int64, fetched func(msgid string)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched(r.URL.Query().Get("msgid"))
+		time.Sleep(time.Duration(latency.Load()))
+		_, _ = w.Write([]byte("9000000001,Submitted,2024-08-27 10:00:00\n"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// statusPollConfig returns a configuration polling the CDAC report API at cdacURL
+func statusPollConfig(cdacURL string) *config.Config {
+	c := config.NewConfig(viper.New())
+	c.Set("sms.cdac.deliverystatusurl", cdacURL)
+	c.Set("sms.cdac.username", "appostsms")
+	c.Set("sms.cdac.password", "secret")
+	c.Set("sms.statuspoll.interval", time.Hour)
+	c.Set("sms.statuspoll.ratelimit", 0)
+	c.Set("sms.statuspoll.maxage", time.Hour)
+	c.Set("sms.statuspoll.timeout", time.Second)
+	return c
+}
+
+// newTestDeliveryStatusPoller creates a DeliveryStatusPoller working on store
+func newTestDeliveryStatusPoller(t *testing.T, store deliveryStatusStore, c *config.Config) *DeliveryStatusPoller {
+	t.Helper()
+	poller, err := NewDeliveryStatusPoller(nil, nil, c)
+	require.NoError(t, err)
+	poller.svc = store
+	return poller
+}
+
+func TestDeliveryStatusPollerAdaptsBatchSize(t *testing.T) {
+	var latency atomic.Int64
+	var fetches atomic.Int64
+	cdac := pendingReportServer(t, &latency, func(string) { fetches.Add(1) })
+	store := newFakeDeliveryStatusStore("LA", 400)
+
+	c := statusPollConfig(cdac.URL)
+	c.Set("sms.statuspoll.batchsize", 20)
+	c.Set("sms.statuspoll.minbatchsize", 5)
+	c.Set("sms.statuspoll.maxbatchsize", 60)
+	c.Set("sms.statuspoll.slowlatency", 40*time.Millisecond)
+	c.Set("sms.statuspoll.concurrency", 4)
+	poller := newTestDeliveryStatusPoller(t, store, c)
+	ctx := context.Background()
+
+	// Fast responses grow the batches up to the maximum
+	for _, want := range []uint64{30, 45, 60, 60} {
+		_, err := poller.Poll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, want, poller.BatchSize("1"))
+	}
+	assert.Equal(t, int64(20+30+45+60), fetches.Load())
+
+	// Slow responses shrink them down to the minimum
+	latency.Store(int64(60 * time.Millisecond))
+	for _, want := range []uint64{30, 15, 7, 5} {
+		_, err := poller.Poll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, want, poller.BatchSize("1"))
+	}
+
+	// The backlog and the catch-up time at the observed throughput are exposed
+	assert.Positive(t, testutil.ToFloat64(StatusPollThroughput.WithLabelValues("1")))
+	assert.Equal(t, float64(400), testutil.ToFloat64(StatusPollBacklog.WithLabelValues("1")))
+	assert.Positive(t, testutil.ToFloat64(StatusPollCatchUpSeconds.WithLabelValues("1")))
+	assert.Equal(t, float64(5), testutil.ToFloat64(StatusPollBatchSize.WithLabelValues("1")))
+}
+
+func TestDeliveryStatusPollerResumesAfterRestart(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	var mu sync.Mutex
+	fetches := make(map[string]int)
+	var latency atomic.Int64
+	cdac := pendingReportServer(t, &latency, func(msgid string) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches[msgid]++
+		// The instance stops after fetching 10 messages
+		if len(fetches) == 10 {
+			stop()
+		}
+	})
+	store := newFakeDeliveryStatusStore("LR", 40)
+
+	c := statusPollConfig(cdac.URL)
+	c.Set("sms.statuspoll.batchsize", 40)
+	c.Set("sms.statuspoll.claimlease", time.Minute)
+	poller := newTestDeliveryStatusPoller(t, store, c)
+	_, err := poller.Poll(ctx)
+	require.NoError(t, err)
+	mu.Lock()
+	fetchedBefore := len(fetches)
+	mu.Unlock()
+	assert.True(t, fetchedBefore >= 10 && fetchedBefore < 40, "%d messages fetched", fetchedBefore)
+
+	// The restarted instance leaves the claimed messages alone until their lease expires
+	restarted := newTestDeliveryStatusPoller(t, store, c)
+	_, err = restarted.Poll(context.Background())
+	require.NoError(t, err)
+	mu.Lock()
+	assert.Equal(t, fetchedBefore, len(fetches))
+	mu.Unlock()
+
+	// It then fetches the messages not fetched yet, and not the checkpointed ones
+	store.advance(90 * time.Second)
+	_, err = restarted.Poll(context.Background())
+	require.NoError(t, err)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, fetches, 40)
+	refetched := 0
+	for _, n := range fetches {
+		refetched += n - 1
+	}
+	// Only a fetch in flight when the instance stopped may be repeated
+	assert.LessOrEqual(t, refetched, 1, "%d messages fetched again", refetched)
+}
@@ -0,0 +1,349 @@
+package handler
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	config "MgApplication/api-config"
+	db "MgApplication/api-db"
+	healthcheck "MgApplication/api-healthcheck"
+	log "MgApplication/api-log"
+	"MgApplication/core/domain"
+	repo "MgApplication/repo/postgres"
+)
+
+// defaultWeightKey is the admin.systemstatus.weights entry used for the components not listed
+const defaultWeightKey = "default"
+
+// jobState records the state of a background job for the system status
+type jobState struct {
+	mu        sync.Mutex
+	running   bool
+	lastCycle time.Time
+	lastErr   error
+	backlog   *int64
+}
+
+func (js *jobState) setRunning(running bool) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	js.running = running
+}
+
+// cycle records the end of a cycle of the job, err being its failure
+func (js *jobState) cycle(err error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	js.lastCycle = time.Now()
+	js.lastErr = err
+}
+
+func (js *jobState) setBacklog(backlog int64) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	js.backlog = &backlog
+}
+
+// status returns the state of the job name, disabled unless enabled in the configuration
+func (js *jobState) status(name string, enabled bool) domain.WorkerStatus {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	status := domain.WorkerStatus{Name: name, Status: domain.WorkerStopped, Backlog: js.backlog}
+	switch {
+	case !enabled:
+		status.Status = domain.ComponentDisabled
+	case js.running:
+		status.Status = domain.WorkerRunning
+	}
+	if !js.lastCycle.IsZero() {
+		lastCycle := js.lastCycle
+		status.LastCycle = &lastCycle
+	}
+	if js.lastErr != nil {
+		status.LastError = js.lastErr.Error()
+	}
+	return status
+}
+
+// workerStatusSource is a background job reporting its state to the system status
+type workerStatusSource interface {
+	WorkerStatus() domain.WorkerStatus
+}
+
+// dependencyStatusSource reports the state of dependencies tracked without probing them
+type dependencyStatusSource interface {
+	DependencyStatuses() []domain.DependencyStatus
+}
+
+// breakerStatusSource reports the state of the circuit breakers of a component
+type breakerStatusSource interface {
+	BreakerStatuses() []domain.BreakerStatus
+}
+
+// dialProbe checks that a dependency reached over the network accepts connections, without
+// sending it a request
+type dialProbe struct {
+	name    string
+	address string
+}
+
+// newDialProbe creates a dialProbe for the host of rawURL, nil when rawURL has no host
+func newDialProbe(name string, rawURL string) *dialProbe {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return nil
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return &dialProbe{name: name, address: net.JoinHostPort(u.Hostname(), port)}
+}
+
+// Name implements healthcheck.CheckerProbe
+func (p *dialProbe) Name() string {
+	return p.name
+}
+
+// Check implements healthcheck.CheckerProbe
+func (p *dialProbe) Check(ctx context.Context) *healthcheck.CheckerProbeResult {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", p.address)
+	if err != nil {
+		return healthcheck.NewCheckerProbeResult(false, err.Error())
+	}
+	_ = conn.Close()
+	return healthcheck.NewCheckerProbeResult(true, "connected to "+p.address)
+}
+
+// systemStatusWeights are the severity weights of the components and the score thresholds of
+// the overall status
+type systemStatusWeights struct {
+	weights map[string]float64
+	amber   float64
+	red     float64
+}
+
+func (w systemStatusWeights) weight(name string) float64 {
+	if weight, ok := w.weights[name]; ok {
+		return weight
+	}
+	if weight, ok := w.weights[defaultWeightKey]; ok {
+		return weight
+	}
+	return 1
+}
+
+// SystemStatusMonitor collects the system status for the NOC dashboards. The dependencies are
+// probed in the background every admin.systemstatus.probeinterval and the status is built from
+// the cached results, so reading it never waits on a dependency; results older than
+// admin.systemstatus.staleafter are reported unknown. The overall status is green below a score
+// of admin.systemstatus.amber, amber below admin.systemstatus.red and red otherwise, the score
+// adding up the weight (admin.systemstatus.weights) of every component that is down and half the
+// weight of every component degraded or unknown.
+type SystemStatusMonitor struct {
+	probes     []healthcheck.CheckerProbe
+	tracked    []dependencyStatusSource
+	workers    []workerStatusSource
+	breakers   []breakerStatusSource
+	interval   time.Duration
+	timeout    time.Duration
+	staleAfter time.Duration
+	weights    systemStatusWeights
+
+	mu      sync.RWMutex
+	results map[string]domain.DependencyStatus
+}
+
+// NewSystemStatusMonitor creates a new SystemStatusMonitor instance using the admin.systemstatus
+// configuration. It probes the write database, the Kafka REST proxy and the CDAC and NIC gateways,
+// reports the sends of the gateways tracked by ch and the state of the delivery status poller, of
+// the stats rollup job, of the response buffer flusher, of the error sampler and of the cache
+// invalidation listener.
+func NewSystemStatusMonitor(svc *repo.MgApplicationRepository, ch *MgApplicationHandler, poller *DeliveryStatusPoller, rollup *StatsRollupJob, flusher *ResponseBufferFlusher, sampler *ErrorSampleWriter, listener *CacheInvalidationListener, c *config.Config) *SystemStatusMonitor {
+	probes := []healthcheck.CheckerProbe{db.NewSQLProbe(svc.Db).SetName("write_db")}
+	for name, key := range map[string]string{"kafka": "sms.kafka.url", "cdac": "sms.cdac.url", "nic": "sms.nic.url"} {
+		if probe := newDialProbe(name, c.GetString(key)); probe != nil {
+			probes = append(probes, probe)
+		}
+	}
+	monitor := newSystemStatusMonitor(probes, []workerStatusSource{poller, rollup, flusher, sampler, listener}, []breakerStatusSource{poller}, c)
+	monitor.tracked = []dependencyStatusSource{ch.health}
+	return monitor
+}
+
+func newSystemStatusMonitor(probes []healthcheck.CheckerProbe, workers []workerStatusSource, breakers []breakerStatusSource, c *config.Config) *SystemStatusMonitor {
+	interval := c.GetDuration("admin.systemstatus.probeinterval")
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	timeout := c.GetDuration("admin.systemstatus.probetimeout")
+	if timeout <= 0 || timeout > interval {
+		timeout = min(5*time.Second, interval)
+	}
+	staleAfter := c.GetDuration("admin.systemstatus.staleafter")
+	if staleAfter < interval {
+		staleAfter = 3 * interval
+	}
+
+	weights := systemStatusWeights{
+		weights: make(map[string]float64),
+		amber:   c.GetFloat64("admin.systemstatus.amber"),
+		red:     c.GetFloat64("admin.systemstatus.red"),
+	}
+	for name := range c.GetStringMap("admin.systemstatus.weights") {
+		weights.weights[name] = c.GetFloat64("admin.systemstatus.weights." + name)
+	}
+	if weights.amber <= 0 {
+		weights.amber = 1
+	}
+	if weights.red < weights.amber {
+		weights.red = weights.amber
+	}
+
+	return &SystemStatusMonitor{
+		probes:     probes,
+		workers:    workers,
+		breakers:   breakers,
+		interval:   interval,
+		timeout:    timeout,
+		staleAfter: staleAfter,
+		weights:    weights,
+		results:    make(map[string]domain.DependencyStatus),
+	}
+}
+
+// Run probes the dependencies every admin.systemstatus.probeinterval until ctx is cancelled
+func (m *SystemStatusMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		m.Probe(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Probe probes every dependency concurrently and caches the results
+func (m *SystemStatusMonitor) Probe(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, probe := range m.probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, m.timeout)
+			defer cancel()
+
+			started := time.Now()
+			result := probe.Check(probeCtx)
+			status := domain.DependencyStatus{
+				Name:        probe.Name(),
+				Status:      domain.ComponentUp,
+				Message:     result.Message,
+				LastChecked: &started,
+				LatencyMs:   time.Since(started).Milliseconds(),
+			}
+			if !result.Success {
+				status.Status = domain.ComponentDown
+				log.Warn(ctx, "System status probe %s failed: %s", probe.Name(), result.Message)
+			}
+
+			m.mu.Lock()
+			m.results[probe.Name()] = status
+			m.mu.Unlock()
+		}()
+	}
+	wg.Wait()
+}
+
+// Status returns the system status from the cached probe results and the current state of the
+// tracked dependencies, jobs and circuit breakers
+func (m *SystemStatusMonitor) Status() domain.SystemStatus {
+	now := time.Now()
+	dependencies := make([]domain.DependencyStatus, 0, len(m.probes))
+	m.mu.RLock()
+	for _, probe := range m.probes {
+		status, ok := m.results[probe.Name()]
+		switch {
+		case !ok:
+			status = domain.DependencyStatus{Name: probe.Name(), Status: domain.ComponentUnknown, Message: "not probed yet"}
+		case now.Sub(*status.LastChecked) > m.staleAfter:
+			status.Status = domain.ComponentUnknown
+			status.Message = "last probe result is stale: " + status.Message
+		}
+		dependencies = append(dependencies, status)
+	}
+	m.mu.RUnlock()
+	for _, source := range m.tracked {
+		dependencies = append(dependencies, source.DependencyStatuses()...)
+	}
+
+	workers := make([]domain.WorkerStatus, 0, len(m.workers))
+	for _, worker := range m.workers {
+		workers = append(workers, worker.WorkerStatus())
+	}
+	breakers := []domain.BreakerStatus{}
+	for _, source := range m.breakers {
+		breakers = append(breakers, source.BreakerStatuses()...)
+	}
+	return aggregateSystemStatus(now, dependencies, workers, breakers, m.weights)
+}
+
+// aggregateSystemStatus computes the overall status of the components
+func aggregateSystemStatus(now time.Time, dependencies []domain.DependencyStatus, workers []domain.WorkerStatus, breakers []domain.BreakerStatus, weights systemStatusWeights) domain.SystemStatus {
+	sort.Slice(dependencies, func(i, j int) bool { return dependencies[i].Name < dependencies[j].Name })
+	sort.Slice(workers, func(i, j int) bool { return workers[i].Name < workers[j].Name })
+	sort.Slice(breakers, func(i, j int) bool { return breakers[i].Name < breakers[j].Name })
+
+	var score float64
+	add := func(name string, status string) {
+		switch status {
+		case domain.ComponentDown:
+			score += weights.weight(name)
+		case domain.ComponentDegraded, domain.ComponentUnknown:
+			score += weights.weight(name) / 2
+		}
+	}
+	for _, dependency := range dependencies {
+		add(dependency.Name, dependency.Status)
+	}
+	for _, worker := range workers {
+		if worker.Status == domain.WorkerStopped {
+			add(worker.Name, domain.ComponentDown)
+		}
+	}
+	for _, breaker := range breakers {
+		switch breaker.State {
+		case domain.BreakerOpen:
+			add(breaker.Name, domain.ComponentDown)
+		case domain.BreakerHalfOpen:
+			add(breaker.Name, domain.ComponentDegraded)
+		}
+	}
+
+	status := domain.SystemStatusGreen
+	switch {
+	case score >= weights.red:
+		status = domain.SystemStatusRed
+	case score >= weights.amber:
+		status = domain.SystemStatusAmber
+	}
+	return domain.SystemStatus{
+		Status:       status,
+		Score:        score,
+		GeneratedAt:  now,
+		Dependencies: dependencies,
+		Workers:      workers,
+		Breakers:     breakers,
+	}
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+	healthcheck "MgApplication/api-healthcheck"
+	"MgApplication/core/domain"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeProbe answers with a fixed result and counts its checks
+type fakeProbe struct {
+	name    string
+	success bool
+	checks  atomic.Int32
+}
+
+func (p *fakeProbe) Name() string {
+	return p.name
+}
+
+func (p *fakeProbe) Check(ctx context.Context) *healthcheck.CheckerProbeResult {
+	p.checks.Add(1)
+	if p.success {
+		return healthcheck.NewCheckerProbeResult(true, "ok")
+	}
+	return healthcheck.NewCheckerProbeResult(false, "connection refused")
+}
+
+type fakeWorker domain.WorkerStatus
+
+func (w fakeWorker) WorkerStatus() domain.WorkerStatus {
+	return domain.WorkerStatus(w)
+}
+
+type fakeBreakers []domain.BreakerStatus
+
+func (b fakeBreakers) BreakerStatuses() []domain.BreakerStatus {
+	return b
+}
+
+func systemStatusConfig() *config.Config {
+	c := config.NewConfig(viper.New())
+	c.Set("admin.systemstatus.amber", 1)
+	c.Set("admin.systemstatus.red", 10)
+	c.Set("admin.systemstatus.weights", map[string]any{"default": 1, "write_db": 10, "cdac": 5, "status_poller": 2})
+	return c
+}
+
+func TestAggregateSystemStatus(t *testing.T) {
+	weights := newSystemStatusMonitor(nil, nil, nil, systemStatusConfig()).weights
+	cases := []struct {
+		name         string
+		dependencies map[string]string
+		workers      map[string]string
+		breakers     map[string]string
+		score        float64
+		status       string
+	}{
+		{"all up", map[string]string{"write_db": domain.ComponentUp, "cdac": domain.ComponentUp}, map[string]string{"status_poller": domain.WorkerRunning}, map[string]string{"status_poll_cdac": domain.BreakerClosed}, 0, domain.SystemStatusGreen},
+		{"disabled job", map[string]string{"write_db": domain.ComponentUp}, map[string]string{"stats_rollup": domain.ComponentDisabled}, nil, 0, domain.SystemStatusGreen},
+		{"gateway unknown", map[string]string{"cdac": domain.ComponentUnknown}, nil, nil, 2.5, domain.SystemStatusAmber},
+		{"job stopped", nil, map[string]string{"status_poller": domain.WorkerStopped}, nil, 2, domain.SystemStatusAmber},
+		{"breaker half open", nil, nil, map[string]string{"status_poll_cdac": domain.BreakerHalfOpen}, 0.5, domain.SystemStatusGreen},
+		{"breaker open", nil, nil, map[string]string{"status_poll_cdac": domain.BreakerOpen}, 1, domain.SystemStatusAmber},
+		{"write db down", map[string]string{"write_db": domain.ComponentDown}, nil, nil, 10, domain.SystemStatusRed},
+		{"several down", map[string]string{"cdac": domain.ComponentDown, "kafka": domain.ComponentDown}, map[string]string{"status_poller": domain.WorkerStopped}, map[string]string{"status_poll_cdac": domain.BreakerOpen}, 9, domain.SystemStatusAmber},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var dependencies []domain.DependencyStatus
+			for name, status := range tc.dependencies {
+				dependencies = append(dependencies, domain.DependencyStatus{Name: name, Status: status})
+			}
+			var workers []domain.WorkerStatus
+			for name, status := range tc.workers {
+				workers = append(workers, domain.WorkerStatus{Name: name, Status: status})
+			}
+			var breakers []domain.BreakerStatus
+			for name, state := range tc.breakers {
+				breakers = append(breakers, domain.BreakerStatus{Name: name, State: state})
+			}
+
+			status := aggregateSystemStatus(time.Now(), dependencies, workers, breakers, weights)
+			assert.Equal(t, tc.score, status.Score)
+			assert.Equal(t, tc.status, status.Status)
+		})
+	}
+}
+
+func TestSystemStatusMonitorUsesCachedProbeResults(t *testing.T) {
+	writeDB := &fakeProbe{name: "write_db", success: true}
+	cdac := &fakeProbe{name: "cdac"}
+	lastCycle := time.Now().Add(-time.Minute)
+	backlog := int64(42)
+	monitor := newSystemStatusMonitor(
+		[]healthcheck.CheckerProbe{writeDB, cdac},
+		[]workerStatusSource{fakeWorker{Name: "status_poller", Status: domain.WorkerRunning, LastCycle: &lastCycle, Backlog: &backlog}},
+		[]breakerStatusSource{fakeBreakers{{Name: "status_poll_cdac", State: domain.BreakerOpen, RetryInSeconds: 30}}},
+		systemStatusConfig(),
+	)
+
+	// not probed yet
+	status := monitor.Status()
+	assert.Equal(t, []string{domain.ComponentUnknown, domain.ComponentUnknown}, []string{status.Dependencies[0].Status, status.Dependencies[1].Status})
+	assert.Zero(t, writeDB.checks.Load())
+
+	monitor.Probe(context.Background())
+	for range 3 {
+		status = monitor.Status()
+	}
+	assert.Equal(t, int32(1), writeDB.checks.Load(), "the status is built from the cached results")
+	assert.Equal(t, int32(1), cdac.checks.Load())
+
+	if assert.Len(t, status.Dependencies, 2) {
+		assert.Equal(t, "cdac", status.Dependencies[0].Name)
+		assert.Equal(t, domain.ComponentDown, status.Dependencies[0].Status)
+		assert.Equal(t, "connection refused", status.Dependencies[0].Message)
+		assert.Equal(t, "write_db", status.Dependencies[1].Name)
+		assert.Equal(t, domain.ComponentUp, status.Dependencies[1].Status)
+		assert.NotNil(t, status.Dependencies[1].LastChecked)
+	}
+	if assert.Len(t, status.Workers, 1) {
+		assert.Equal(t, int64(42), *status.Workers[0].Backlog)
+	}
+	assert.Equal(t, domain.BreakerOpen, status.Breakers[0].State)
+	// cdac down (5) and its breaker open (1)
+	assert.Equal(t, 6.0, status.Score)
+	assert.Equal(t, domain.SystemStatusAmber, status.Status)
+
+	// stale results
+	monitor.mu.Lock()
+	for name, result := range monitor.results {
+		checked := result.LastChecked.Add(-monitor.staleAfter - time.Second)
+		result.LastChecked = &checked
+		monitor.results[name] = result
+	}
+	monitor.mu.Unlock()
+	status = monitor.Status()
+	assert.Equal(t, domain.ComponentUnknown, status.Dependencies[1].Status)
+	// both unknown (2.5 + 5) and the breaker open (1)
+	assert.Equal(t, 8.5, status.Score)
+}
+
+func TestJobStateStatus(t *testing.T) {
+	var js jobState
+	assert.Equal(t, domain.ComponentDisabled, js.status("stats_rollup", false).Status)
+
+	status := js.status("stats_rollup", true)
+	assert.Equal(t, domain.WorkerStopped, status.Status)
+	assert.Nil(t, status.LastCycle)
+	assert.Nil(t, status.Backlog)
+
+	js.setRunning(true)
+	js.cycle(errors.New("rebuild failed"))
+	js.setBacklog(7)
+	status = js.status("stats_rollup", true)
+	assert.Equal(t, domain.WorkerRunning, status.Status)
+	assert.NotNil(t, status.LastCycle)
+	assert.Equal(t, "rebuild failed", status.LastError)
+	assert.Equal(t, int64(7), *status.Backlog)
+}
+
+func TestCircuitBreakerStatus(t *testing.T) {
+	assert.Equal(t, domain.BreakerDisabled, newCircuitBreaker(0, time.Minute).Status("b").State)
+
+	b := newCircuitBreaker(1, time.Minute)
+	assert.Equal(t, domain.BreakerClosed, b.Status("b").State)
+	b.Failure()
+	status := b.Status("b")
+	assert.Equal(t, domain.BreakerOpen, status.State)
+	assert.Equal(t, int64(60), status.RetryInSeconds)
+
+	b.openUntil = time.Now()
+	assert.Equal(t, domain.BreakerHalfOpen, b.Status("b").State)
+	b.Success()
+	assert.Equal(t, domain.BreakerClosed, b.Status("b").State)
+}
+
+func TestNewDialProbe(t *testing.T) {
+	assert.Equal(t, "msdgweb.mgov.gov.in:443", newDialProbe("cdac", "https://msdgweb.mgov.gov.in/esms/sendsmsrequestDLT").address)
+	assert.Equal(t, "10.20.30.22:8082", newDialProbe("kafka", "http://10.20.30.22:8082/topics/x").address)
+	assert.Nil(t, newDialProbe("nic", ""))
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+)
+
+// templateLoader loads a template by its DLT template id
+type templateLoader func(ctx context.Context, templateID string) (domain.MaintainTemplate, error)
+
+// TemplateCache keeps the templates loaded by their DLT template id, so that the hot paths do not
+// read msg_template on every request. With db.notify.enabled the entries are evicted by the
+// change notifications of the templates and kept for cache.templates.ttl; without it they expire
+// after the short cache.templates.fallbackttl, which bounds how long a replica serves a changed
+// template. Failed loads are not cached, nor loads that raced with an invalidation.
+type TemplateCache struct {
+	load templateLoader
+	ttl  time.Duration
+
+	mu         sync.Mutex
+	entries    map[string]templateCacheEntry
+	generation uint64 // incremented on every invalidation
+	now        func() time.Time
+}
+
+type templateCacheEntry struct {
+	template domain.MaintainTemplate
+	loadedAt time.Time
+}
+
+// NewTemplateCache creates a new TemplateCache instance loading the templates with load, using
+// the cache.templates configuration. The ttl defaults to 10 minutes and the fallback ttl to 30
+// seconds.
+func NewTemplateCache(load templateLoader, c *config.Config) *TemplateCache {
+	ttl := c.GetDuration("cache.templates.ttl")
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	if !c.GetBool("db.notify.enabled") {
+		ttl = c.GetDuration("cache.templates.fallbackttl")
+		if ttl <= 0 {
+			ttl = 30 * time.Second
+		}
+	}
+	return &TemplateCache{
+		load:    load,
+		ttl:     ttl,
+		entries: make(map[string]templateCacheEntry),
+		now:     time.Now,
+	}
+}
+
+// Get returns the template templateID, loading it when it is not cached or has expired
+func (tc *TemplateCache) Get(ctx context.Context, templateID string) (domain.MaintainTemplate, error) {
+	tc.mu.Lock()
+	entry, ok := tc.entries[templateID]
+	generation := tc.generation
+	tc.mu.Unlock()
+	if ok && tc.now().Sub(entry.loadedAt) < tc.ttl {
+		return entry.template, nil
+	}
+
+	loadedAt := tc.now()
+	template, err := tc.load(ctx, templateID)
+	if err != nil {
+		return template, err
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	// the template may have changed while loading, the next Get loads it again
+	if tc.generation == generation {
+		tc.entries[templateID] = templateCacheEntry{template: template, loadedAt: loadedAt}
+	}
+	return template, nil
+}
+
+// Invalidate implements invalidatingCache, evicting the template changed
+func (tc *TemplateCache) Invalidate(change domain.CacheChange) int {
+	if change.Entity != domain.CacheEntityTemplate {
+		return 0
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.generation++
+	if _, ok := tc.entries[change.ID]; !ok {
+		return 0
+	}
+	delete(tc.entries, change.ID)
+	return 1
+}
+
+// Flush implements invalidatingCache, evicting every template
+func (tc *TemplateCache) Flush() int {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.generation++
+	flushed := len(tc.entries)
+	tc.entries = make(map[string]templateCacheEntry)
+	return flushed
+}
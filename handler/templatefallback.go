@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"strings"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var TemplateFallbackTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "sms_template_fallback_total",
+		Help: "Total number of template renders that failed and were answered with the fallback message of the application",
+	},
+	[]string{"application"},
+)
+
+// TemplateFallbacks are the fallback messages of the applications configured in
+// applications.fallbackmessages, used in place of a template that cannot be rendered when the
+// caller allows it. Applications without a fallback message always fail such renders.
+type TemplateFallbacks struct {
+	messages map[string]string
+}
+
+// NewTemplateFallbacks creates a new TemplateFallbacks instance using the
+// applications.fallbackmessages configuration
+func NewTemplateFallbacks(c *config.Config) *TemplateFallbacks {
+	messages := make(map[string]string)
+	for applicationID, message := range c.GetStringMapString("applications.fallbackmessages") {
+		if message = strings.TrimSpace(message); message != "" {
+			messages[applicationID] = message
+		}
+	}
+	return &TemplateFallbacks{messages: messages}
+}
+
+// Render fills the placeholders of template with values. When they do not match and
+// allowFallback is set, the fallback message of the application of template is returned instead
+// with a warning naming the failure; otherwise the failure is returned.
+func (f *TemplateFallbacks) Render(template domain.MaintainTemplate, values []string, allowFallback bool) (string, string, error) {
+	rendered, err := renderTemplate(template.TemplateFormat, values)
+	if err == nil || !allowFallback || f == nil {
+		return rendered, "", err
+	}
+	fallback, ok := f.messages[template.ApplicationID]
+	if !ok {
+		return "", "", err
+	}
+	TemplateFallbackTotal.WithLabelValues(template.ApplicationID).Inc()
+	return fallback, "fallback message of the application used: " + err.Error(), nil
+}
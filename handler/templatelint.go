@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	"MgApplication/core/dlt"
+)
+
+// TemplateLinter checks template formats against the DLT rules in sms.dlt.lint when templates are
+// created or updated. The limits under sms.dlt.lint.gateways.<gateway> override the general ones
+// for that gateway; the branding rule is the branding of the sender in sms.branding. With
+// sms.dlt.lint.enforce set, templates breaking a rule are rejected, otherwise they are stored and
+// the violations are returned as warnings.
+type TemplateLinter struct {
+	c        *config.Config
+	enabled  bool
+	enforce  bool
+	branding *SenderBranding
+}
+
+// NewTemplateLinter creates a new TemplateLinter instance using the sms.dlt.lint configuration
+func NewTemplateLinter(c *config.Config, branding *SenderBranding) *TemplateLinter {
+	return &TemplateLinter{
+		c:        c,
+		enabled:  c.GetBool("sms.dlt.lint.enabled"),
+		enforce:  c.GetBool("sms.dlt.lint.enforce"),
+		branding: branding,
+	}
+}
+
+// Rules returns the DLT rules of templates of senderID sent through gateway
+func (l *TemplateLinter) Rules(gateway string, senderID string) dlt.Rules {
+	return dlt.Rules{
+		MaxVars:        l.limit(gateway, "maxvars"),
+		VarLength:      l.limit(gateway, "varlength"),
+		MaxLength:      l.limit(gateway, "maxlength"),
+		Branding:       l.branding.Branding(senderID),
+		AppendBranding: l.branding != nil && l.branding.append,
+	}
+}
+
+// MaxLength estimates the length of the longest message of the template format, with every
+// variable sms.dlt.lint.varlength characters long, and returns it with the maxlength limit of
+// gateway, zero when there is none
+func (l *TemplateLinter) MaxLength(gateway string, senderID string, format string) (int, int) {
+	if l == nil {
+		return 0, 0
+	}
+	rules := l.Rules(gateway, senderID)
+	return dlt.MaxLength(format, rules), rules.MaxLength
+}
+
+func (l *TemplateLinter) limit(gateway string, key string) int {
+	if gatewayKey := "sms.dlt.lint.gateways." + gateway + "." + key; l.c.Exists(gatewayKey) {
+		return l.c.GetInt(gatewayKey)
+	}
+	return l.c.GetInt("sms.dlt.lint." + key)
+}
+
+// Lint returns the rules the template format breaks. The error lists them as field errors when
+// the template has to be rejected.
+func (l *TemplateLinter) Lint(gateway string, senderID string, format string) ([]dlt.Violation, error) {
+	if l == nil || !l.enabled {
+		return nil, nil
+	}
+	violations := dlt.Lint(format, l.Rules(gateway, senderID))
+	if len(violations) == 0 || !l.enforce {
+		return violations, nil
+	}
+
+	appErr := apierrors.NewAppError("template breaks DLT rules", http.StatusUnprocessableEntity, errors.New("template breaks DLT rules"))
+	fieldErrors := make([]apierrors.FieldError, 0, len(violations))
+	for _, v := range violations {
+		fieldErrors = append(fieldErrors, appErr.NewFieldError("template_format", format, v.Message, v.Rule))
+	}
+	appErr.SetFieldErrors(fieldErrors)
+	return violations, &appErr
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"MgApplication/core/domain"
+)
+
+// DLT templates carry {#var#} placeholders that are filled in order when a message is rendered
+var templateVarPattern = regexp.MustCompile(`\{#var#\}`)
+
+// GSM 03.38 characters. Characters of the extension table are sent as an escape and the
+// character, and take two septets.
+const (
+	gsm7BasicChars     = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+	gsm7ExtensionChars = "\f^{}\\[~]|€"
+)
+
+// Characters per segment of single and concatenated (multipart) messages
+const (
+	gsm7SingleSegmentLength = 160
+	gsm7MultiSegmentLength  = 153
+	ucs2SingleSegmentLength = 70
+	ucs2MultiSegmentLength  = 67
+)
+
+// templateVarCount returns the number of {#var#} placeholders in a template format
+func templateVarCount(format string) int {
+	return len(templateVarPattern.FindAllStringIndex(format, -1))
+}
+
+// renderTemplate fills the {#var#} placeholders of a template format with values in order
+func renderTemplate(format string, values []string) (string, error) {
+	placeholders := templateVarCount(format)
+	if len(values) != placeholders {
+		return "", fmt.Errorf("template expects %d values, but received %d", placeholders, len(values))
+	}
+
+	next := 0
+	rendered := templateVarPattern.ReplaceAllStringFunc(format, func(string) string {
+		value := values[next]
+		next++
+		return value
+	})
+	return rendered, nil
+}
+
+// gsm7Septets returns the number of septets r takes in the GSM 7-bit alphabet, false when it is
+// not in the alphabet
+func gsm7Septets(r rune) (int, bool) {
+	switch {
+	case strings.ContainsRune(gsm7BasicChars, r):
+		return 1, true
+	case strings.ContainsRune(gsm7ExtensionChars, r):
+		return 2, true
+	}
+	return 0, false
+}
+
+// firstNonGSM7 returns the first character of text outside the GSM 7-bit alphabet and its
+// position in characters, counted from 1, ok being false when there is none
+func firstNonGSM7(text string) (rune, int, bool) {
+	position := 0
+	for _, r := range text {
+		position++
+		if _, gsm7 := gsm7Septets(r); !gsm7 {
+			return r, position, true
+		}
+	}
+	return 0, 0, false
+}
+
+// messageSegments detects the encoding a message is sent with and returns its length in
+// characters of that encoding and the number of SMS segments it is split into. Messages
+// with characters outside the GSM 7-bit alphabet are sent as UCS-2 Unicode messages.
+func messageSegments(text string) (domain.MessageType, int, int) {
+	if _, _, unicode := firstNonGSM7(text); unicode {
+		length := len(utf16.Encode([]rune(text)))
+		return domain.MessageTypeUnicode, length, segmentCount(length, ucs2SingleSegmentLength, ucs2MultiSegmentLength)
+	}
+	length := 0
+	for _, r := range text {
+		septets, _ := gsm7Septets(r)
+		length += septets
+	}
+	return domain.MessageTypePlain, length, segmentCount(length, gsm7SingleSegmentLength, gsm7MultiSegmentLength)
+}
+
+func segmentCount(length, single, multi int) int {
+	if length <= single {
+		return 1
+	}
+	return (length + multi - 1) / multi
+}
@@ -3,15 +3,25 @@ package handler
 import (
 	"MgApplication/core/domain"
 	"MgApplication/core/port"
+	"MgApplication/core/transform"
 	"MgApplication/handler/response"
 	repo "MgApplication/repo/postgres"
+	"errors"
+	"maps"
 	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 
 	// _ "time"
 
 	config "MgApplication/api-config"
 	apierrors "MgApplication/api-errors"
 	log "MgApplication/api-log"
+	serverHandler "MgApplication/api-server/handler"
+	serverResponse "MgApplication/api-server/response"
+	serverRoute "MgApplication/api-server/route"
 	validation "MgApplication/api-validation"
 
 	"github.com/gin-gonic/gin"
@@ -19,41 +29,81 @@ import (
 
 // MgApplication Handler represents the HTTP handler for MgApplication related requests
 type TemplateHandler struct {
-	svc *repo.TemplateRepository
-	c   *config.Config
+	*serverHandler.Base
+	svc       *repo.TemplateRepository
+	c         *config.Config
+	branding  *SenderBranding
+	lint      *TemplateLinter
+	fallbacks *TemplateFallbacks
+	// applicationExists checks the application_id of the templates created
+	applicationExists referenceExists
+	// transforms are the message transformers applied to the previews as to the messages sent
+	transforms *transform.Pipeline
 }
 
 // MgApplication Handler creates a new MgApplicatPion Handler instance
 func NewTemplateHandler(svc *repo.TemplateRepository, c *config.Config) *TemplateHandler {
-	return &TemplateHandler{
-		svc,
-		c,
+	branding := NewSenderBranding(c)
+	base := serverHandler.New("Templates").SetDescription("DLT registered SMS templates of the applications").SetOrder(2).SetPrefix("/v1").AddPrefix("/sms-templates").AddMiddleware(auditRequest())
+	if svc != nil && c.GetBool("db.consistency.enabled") {
+		base.AddMiddleware(consistencyTokens(primaryLSN(svc.Db)))
+	}
+	th := &TemplateHandler{
+		Base:       base,
+		svc:        svc,
+		c:          c,
+		branding:   branding,
+		lint:       NewTemplateLinter(c, branding),
+		fallbacks:  NewTemplateFallbacks(c),
+		transforms: newMessagePipeline(c),
+	}
+	if svc != nil {
+		th.applicationExists = svc.ApplicationExistsRepo
+	}
+	return th
+}
+
+func (ch *TemplateHandler) Routes() []serverRoute.Route {
+	return []serverRoute.Route{
+		serverRoute.Raw(http.MethodPost, "", ch.CreateTemplateHandler).Name("Create template"),
+		serverRoute.Raw(http.MethodGet, "", ch.ListTemplatesHandler).Name("List templates"),
+		serverRoute.Raw(http.MethodGet, "/name", ch.FetchTemplateByApplicationHandler).Name("Fetch template names by application"),
+		serverRoute.Raw(http.MethodGet, "/details", ch.FetchTemplateDetailsHandler).Name("Fetch template details"),
+		serverRoute.Raw(http.MethodGet, "/by-template-id/:template-id", ch.FetchTemplateByTemplateIDHandler).Name("Fetch template by DLT template id"),
+		serverRoute.Raw(http.MethodGet, "/:template-local-id", ch.FetchTemplateHandler).Name("Fetch template"),
+		serverRoute.Raw(http.MethodPut, "/:template-local-id", ch.UpdateTemplateHandler).Name("Update template"),
+		serverRoute.Raw(http.MethodPut, "/:template-local-id/status", ch.ToggleTemplateStatusHandler).Name("Toggle template status"),
+		serverRoute.Raw(http.MethodPut, "/bulk-status", ch.BulkTemplateStatusHandler).Name("Bulk update template status"),
+		serverRoute.Raw(http.MethodPost, "/:template-local-id/preview", ch.PreviewTemplateHandler).Name("Preview template"),
 	}
 }
 
 type createTemplateRequest struct {
-	TemplateLocalID uint64 `json:"template_local_id"`
-	ApplicationID   string `json:"application_id" validate:"required,numeric" example:"4"`
-	TemplateName    string `json:"template_name" validate:"required" example:"Test Template"`
-	TemplateFormat  string `json:"template_format" validate:"required" example:"Dear {#var#}, Greetings from India Post on the occasion of {#var#} - Indiapost"`
-	SenderID        string `json:"sender_id" validate:"required" example:"INPOST"`
-	EntityID        string `json:"entity_id" example:"1001051725995192803"`
-	TemplateID      string `json:"template_id" validate:"required,numeric" example:"1007188452935484904"`
-	Gateway         string `json:"gateway" validate:"required" example:"1"`
-	Status          bool   `json:"status" validate:"required" example:"true"`
-	MessageType     string `json:"message_type" validate:"required" example:"PM"`
+	TemplateLocalID port.ID       `json:"template_local_id" swaggertype:"string" pattern:"^[0-9]+$"`
+	ApplicationID   port.StringID `json:"application_id" validate:"required,numeric" swaggertype:"string" pattern:"^[0-9]+$" example:"4"`
+	TemplateName    string        `json:"template_name" validate:"required" example:"Test Template"`
+	TemplateFormat  string        `json:"template_format" validate:"required" example:"Dear {#var#}, Greetings from India Post on the occasion of {#var#} - Indiapost"`
+	SenderID        string        `json:"sender_id" validate:"required" example:"INPOST"`
+	EntityID        port.StringID `json:"entity_id" validate:"omitempty,entity_id" swaggertype:"string" pattern:"^[0-9]{19}$" example:"1001051725995192803"`
+	TemplateID      port.StringID `json:"template_id" validate:"required,numeric" swaggertype:"string" pattern:"^[0-9]+$" example:"1007188452935484904"`
+	Gateway         string        `json:"gateway" validate:"required,gateway_id" enum:"1,2" example:"1"`
+	Status          bool          `json:"status" validate:"required" example:"true"`
+	MessageType     string        `json:"message_type" validate:"required,message_type" enum:"PM,UC" example:"PM"`
 }
 
 // CreateTemplateHandler godoc
 //
 //	@Summary		Creates a new message template
 //	@Description	Creates a new Message template for message applications
+//	@Description	An application_id that does not reference an existing application is a 422 naming the field with the exists tag
+//	@Description	A DLT template_id belongs to a single gateway: a template_id already registered under another gateway is a 409
 //	@Tags			Templates
 //	@ID				CreateTemplateHandler
 //	@Accept			json
 //	@Produce		json
 //	@Param			createTemplateRequest	body		createTemplateRequest				true	"Create new Message Template"
 //	@Success		201						{object}	response.CreateTemplateAPIResponse	"Message Template is created"
+//	@Header			201						{string}	Location							"URL of the Message Template created"
 //	@Failure		400						{object}	apierrors.APIErrorResponse			"Bad Request"
 //	@Failure		401						{object}	apierrors.APIErrorResponse			"Unauthorized"
 //	@Failure		403						{object}	apierrors.APIErrorResponse			"Forbidden"
@@ -80,6 +130,25 @@ func (ch *TemplateHandler) CreateTemplateHandler(ctx *gin.Context) {
 		return
 	}
 
+	warnings, err := ch.lint.Lint(req.Gateway, req.SenderID, req.TemplateFormat)
+	if err != nil {
+		apierrors.HandleValidationError(ctx, err)
+		log.Error(ctx, "DLT lint failed for createTemplateRequest: %s", err.Error())
+		return
+	}
+
+	err = checkReferences(ctx, reference{Field: "application_id", Entity: "application", ID: string(req.ApplicationID), Exists: ch.applicationExists})
+	if _, ok := apierrors.Find[*apierrors.AppError](err); ok {
+		apierrors.HandleValidationError(ctx, err)
+		log.Error(ctx, "Reference check failed for createTemplateRequest: %s", err.Error())
+		return
+	}
+	if err != nil {
+		apierrors.HandleDBError(ctx, err)
+		log.Error(ctx, "Error in ApplicationExistsRepo function: %s", err.Error())
+		return
+	}
+
 	var aStatus int
 	if req.Status {
 		aStatus = 1
@@ -88,18 +157,23 @@ func (ch *TemplateHandler) CreateTemplateHandler(ctx *gin.Context) {
 	}
 
 	maintaintemplate := domain.MaintainTemplate{
-		ApplicationID:  req.ApplicationID,
+		ApplicationID:  string(req.ApplicationID),
 		TemplateName:   req.TemplateName,
 		TemplateFormat: req.TemplateFormat,
 		SenderID:       req.SenderID,
-		EntityID:       req.EntityID,
-		TemplateID:     req.TemplateID,
+		EntityID:       string(req.EntityID),
+		TemplateID:     string(req.TemplateID),
 		Gateway:        req.Gateway,
 		MessageType:    req.MessageType,
 		Status:         aStatus,
 	}
 
-	err := ch.svc.CreateTemplateRepo(ctx, &maintaintemplate)
+	err = ch.svc.CreateTemplateRepo(ctx, &maintaintemplate)
+	if conflict, ok := apierrors.Find[*repo.TemplateGatewayConflictError](err); ok {
+		apierrors.ErrorResponseWithStatusCodeAndMessage(ctx, apierrors.HTTPErrorConflict, conflict.Error(), err)
+		log.Warn(ctx, "Template not created: %s", conflict.Error())
+		return
+	}
 	if err != nil {
 		if err.Error() == "given template_id and template already exists, cannot continue" {
 			apierrors.HandleDuplicateEntryError(ctx)
@@ -112,23 +186,68 @@ func (ch *TemplateHandler) CreateTemplateHandler(ctx *gin.Context) {
 		}
 	}
 
-	apiRsp := response.CreateTemplateAPIResponse{
-		StatusCodeAndMessage: port.CreateSuccess,
-		// Data:                 rsp,
-	}
-
+	estimatedLength, lengthLimit := ch.lint.MaxLength(req.Gateway, req.SenderID, req.TemplateFormat)
+	apiRsp := &response.CreateTemplateAPIResponse{Data: response.NewTemplateLintResponse(estimatedLength, lengthLimit, warnings)}
+	response.CreatedAt(ctx, apiRsp, ch.Prefix()+"/"+strconv.FormatUint(maintaintemplate.TemplateLocalID, 10))
 	log.Debug(ctx, "CreateTemplateHandler response: %v", apiRsp)
-	handleCreateSuccess(ctx, apiRsp)
 }
 
 type listTemplatesRequest struct {
 	port.MetaDataRequest
+	ApplicationID string `form:"application_id" validate:"omitempty,numeric" example:"4"`
+	Gateway       string `form:"gateway" validate:"omitempty,gateway_id" enum:"1,2" example:"1"`
+	Status        string `form:"status" validate:"omitempty,oneof=0 1" enum:"0,1" example:"1"`
+	MessageType   string `form:"message_type" validate:"omitempty,message_type" enum:"PM,UC" example:"PM"`
+	SenderID      string `form:"sender_id" validate:"omitempty,max=6,alphanum" example:"INPOST"`
+	// Search matches a substring of the template name or format, case insensitive
+	Search string `form:"search" validate:"omitempty,max=100" example:"OTP"`
+	// Facets counts the matching templates by status and gateway, false skips the count
+	Facets bool `form:"facets,default=true" example:"true"`
+	// Stream answers the whole list, unpaginated, as a bare JSON array written as it is read
+	Stream bool `form:"stream" example:"false"`
+}
+
+// filter returns the template filter of the request, failing with a field error when the list
+// cannot be ordered by orderBy or sortType is neither asc nor desc
+func (req listTemplatesRequest) filter() (domain.TemplateFilter, error) {
+	filter := domain.TemplateFilter{
+		ApplicationID: req.ApplicationID,
+		Gateway:       req.Gateway,
+		MessageType:   req.MessageType,
+		SenderID:      req.SenderID,
+		Search:        req.Search,
+		OrderBy:       req.OrderBy,
+	}
+	if req.Status != "" {
+		status, _ := strconv.Atoi(req.Status)
+		filter.Status = &status
+	}
+
+	appErr := apierrors.NewAppError("invalid template list order", http.StatusUnprocessableEntity, errors.New("invalid template list order"))
+	var fieldErrors []apierrors.FieldError
+	if _, ok := repo.TemplateOrderColumns[req.OrderBy]; req.OrderBy != "" && !ok {
+		fieldErrors = append(fieldErrors, appErr.NewFieldError("orderBy", req.OrderBy, "orderBy must be one of "+strings.Join(slices.Sorted(maps.Keys(repo.TemplateOrderColumns)), ", "), "oneof"))
+	}
+	switch strings.ToLower(req.SortType) {
+	case "", "asc":
+	case "desc":
+		filter.Descending = true
+	default:
+		fieldErrors = append(fieldErrors, appErr.NewFieldError("sortType", req.SortType, "sortType must be one of asc, desc", "oneof"))
+	}
+	if len(fieldErrors) > 0 {
+		appErr.SetFieldErrors(fieldErrors)
+		return filter, &appErr
+	}
+	return filter, nil
 }
 
 // ListTemplates godoc
 //
 //	@Summary		Get all Message Templates
-//	@Description	Lists all message templates
+//	@Description	Lists the templates matching all the filters given, ordered by orderBy (template_local_id, template_name, sender_id, template_id, message_type, gateway or status) in the sortType (asc or desc) order.
+//	@Description	The facets count the matching templates by status and by gateway id, facets=false skips the count.
+//	@Description	With stream=true the whole list, ignoring skip and limit, is answered as a bare JSON array of templates written as it is read. An error after the first template ends the array with an {"error": "..."} element and sets the X-Stream-Status trailer to 500.
 //	@Tags			Templates
 //	@ID				ListTemplatesHandler
 //	@Accept			json
@@ -160,32 +279,55 @@ func (ch *TemplateHandler) ListTemplatesHandler(ctx *gin.Context) {
 		return
 	}
 
+	filter, err := req.filter()
+	if err != nil {
+		apierrors.HandleValidationError(ctx, err)
+		log.Error(ctx, "Validation failed for ListTemplatesRequest: %s", err.Error())
+		return
+	}
+
+	if req.Stream {
+		ch.streamTemplates(ctx, filter)
+		return
+	}
+
 	if req.Limit == 0 && req.Skip == 0 {
 		req.Limit = math.MaxInt32
 	}
 
 	listTemplate := domain.Meta{
-		Skip:  req.Skip,
-		Limit: req.Limit,
+		Skip:  uint64(req.Skip),
+		Limit: uint64(req.Limit),
 	}
 
-	templates, totalCount, err := ch.svc.ListTemplatesRepo(ctx, &listTemplate)
+	templates, totalCount, err := ch.svc.ListTemplatesRepo(ctx, &listTemplate, filter)
 	if err != nil {
 		apierrors.HandleDBError(ctx, err)
 		log.Error(ctx, "Error in ListTemplatesRepo function: %s", err.Error())
 		return
 	}
 
-	rsp := response.NewListTemplatesResponse(templates)
-	metadata := port.NewMetaDataResponse(req.Skip, req.Limit, int(totalCount))
-	apiRsp := response.ListTemplatesAPIResponse{
-		StatusCodeAndMessage: port.ListSuccess,
-		MetaDataResponse:     metadata,
-		Data:                 rsp,
+	apiRsp := &response.ListTemplatesAPIResponse{Data: response.NewListTemplatesResponse(templates)}
+	if req.Facets {
+		facets, err := ch.svc.ListTemplateFacetsRepo(ctx, filter)
+		if err != nil {
+			apierrors.HandleDBError(ctx, err)
+			log.Error(ctx, "Error in ListTemplateFacetsRepo function: %s", err.Error())
+			return
+		}
+		apiRsp.Facets = response.NewTemplateFacetsResponse(facets)
 	}
-
+	response.List(ctx, apiRsp, port.NewMetaDataResponse(uint64(req.Skip), uint64(req.Limit), int(totalCount)))
 	log.Debug(ctx, "ListTemplatesHandler response: %v", apiRsp)
-	handleSuccess(ctx, apiRsp)
+}
+
+// streamTemplates answers all the templates matching filter as a JSON array streamed as they are read
+func (ch *TemplateHandler) streamTemplates(ctx *gin.Context, filter domain.TemplateFilter) {
+	templates := ch.svc.StreamTemplatesRepo(ctx, &domain.Meta{Limit: math.MaxInt32}, filter)
+	if err := serverResponse.StreamJSONArray(ctx, mapSeq(templates, response.NewListTemplateResponse)); err != nil && !ctx.Writer.Written() {
+		apierrors.HandleDBError(ctx, err)
+		log.Error(ctx, "Error in StreamTemplatesRepo function: %s", err.Error())
+	}
 }
 
 type toggleTemplateStatusRequest struct {
@@ -230,6 +372,7 @@ func (ch *TemplateHandler) ToggleTemplateStatusHandler(ctx *gin.Context) {
 
 	msgtemplatereq := domain.StatusTemplate{
 		TemplateLocalID: req.TemplateLocalID,
+		CorrelationID:   serverResponse.CorrelationID(ctx),
 	}
 
 	rsp, err := ch.svc.ToggleTemplateStatusRepo(ctx, &msgtemplatereq)
@@ -239,14 +382,9 @@ func (ch *TemplateHandler) ToggleTemplateStatusHandler(ctx *gin.Context) {
 		return
 	}
 
-	apiRsp := response.ToggleTemplateStatusAPIResponse{
-		StatusCodeAndMessage: port.UpdateSuccess,
-		//MetaDataResponse:     metadata,
-		Data: rsp,
-	}
-
+	apiRsp := &response.ToggleTemplateStatusAPIResponse{Data: rsp}
+	response.Updated(ctx, apiRsp)
 	log.Debug(ctx, "ToggleTemplateStatusHandler response: %v", apiRsp)
-	handleSuccess(ctx, apiRsp)
 }
 
 type fetchTemplateRequest struct {
@@ -262,7 +400,10 @@ type fetchTemplateRequest struct {
 //	@Accept			json
 //	@Produce		json
 //	@Param			fetchTemplateRequest	path		fetchTemplateRequest				true	"Get Message Template Request"
+//	@Param			If-None-Match			header		string								false	"ETag of a previously fetched Message Template"
 //	@Success		200						{object}	response.FetchTemplateAPIResponse	"Message Template is retrieved by TemplateLocalID"
+//	@Header			200						{string}	ETag								"Entity tag of the Message Template"
+//	@Success		304						"Message Template is unchanged"
 //	@Failure		400						{object}	apierrors.APIErrorResponse			"Bad Request"
 //	@Failure		401						{object}	apierrors.APIErrorResponse			"Unauthorized"
 //	@Failure		403						{object}	apierrors.APIErrorResponse			"Forbidden"
@@ -300,34 +441,87 @@ func (ch *TemplateHandler) FetchTemplateHandler(ctx *gin.Context) {
 		return
 	}
 
-	rsp := response.NewFetchTemplateResponse(template)
-	apiRsp := response.FetchTemplateAPIResponse{
-		StatusCodeAndMessage: port.FetchSuccess,
-		//MetaDataResponse:     metadata,
-		Data: rsp,
+	apiRsp := &response.FetchTemplateAPIResponse{Data: response.NewFetchTemplateResponse(template)}
+	response.OK(ctx, apiRsp)
+	log.Debug(ctx, "FetchTemplateHandler response: %v", apiRsp)
+}
+
+type fetchTemplateByTemplateIDRequest struct {
+	TemplateID string `uri:"template-id" validate:"required,numeric" example:"1007188452935484904"`
+}
+
+// FetchTemplateByTemplateID godoc
+//
+//	@Summary		Get Message Template by TemplateID
+//	@Description	Fetches Message Template by the DLT TemplateID, for senders that only know the DLT id
+//	@Tags			Templates
+//	@ID				FetchTemplateByTemplateIDHandler
+//	@Accept			json
+//	@Produce		json
+//	@Param			fetchTemplateByTemplateIDRequest	path		fetchTemplateByTemplateIDRequest	true	"Get Message Template by TemplateID Request"
+//	@Param			If-None-Match						header		string								false	"ETag of a previously fetched Message Template"
+//	@Success		200									{object}	response.FetchTemplateAPIResponse	"Message Template is retrieved by TemplateID"
+//	@Header			200									{string}	ETag								"Entity tag of the Message Template"
+//	@Success		304									"Message Template is unchanged"
+//	@Failure		400									{object}	apierrors.APIErrorResponse			"Bad Request"
+//	@Failure		401									{object}	apierrors.APIErrorResponse			"Unauthorized"
+//	@Failure		403									{object}	apierrors.APIErrorResponse			"Forbidden"
+//	@Failure		404									{object}	apierrors.APIErrorResponse			"Data not found"
+//	@Failure		422									{object}	apierrors.APIErrorResponse			"Binding or Validation error"
+//	@Failure		500									{object}	apierrors.APIErrorResponse			"Internal server error"
+//	@Failure		502									{object}	apierrors.APIErrorResponse			"Bad Gateway"
+//	@Failure		504									{object}	apierrors.APIErrorResponse			"Gateway Timeout"
+//	@Router			/sms-templates/by-template-id/{template-id} [get]
+func (ch *TemplateHandler) FetchTemplateByTemplateIDHandler(ctx *gin.Context) {
+
+	var req fetchTemplateByTemplateIDRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		apierrors.HandleBindingError(ctx, err)
+		log.Error(ctx, "Binding failed for fetchTemplateByTemplateIDRequest: %s", err.Error())
+		return
 	}
 
-	log.Debug(ctx, "FetchTemplateHandler response: %v", apiRsp)
-	handleSuccess(ctx, apiRsp)
+	if err := validation.ValidateStruct(req); err != nil {
+		apierrors.HandleValidationError(ctx, err)
+		log.Error(ctx, "Validation failed for fetchTemplateByTemplateIDRequest: %s", err.Error())
+		return
+	}
+
+	msgtemplatereq := domain.MaintainTemplate{
+		TemplateID: req.TemplateID,
+	}
+
+	template, err := ch.svc.FetchTemplateByTemplateIDRepo(ctx, &msgtemplatereq)
+	if err != nil {
+		apierrors.HandleDBError(ctx, err)
+		log.Error(ctx, "Error in FetchTemplateByTemplateIDRepo function: %s", err.Error())
+		return
+	}
+
+	apiRsp := &response.FetchTemplateAPIResponse{Data: response.NewFetchTemplateResponse([]domain.MaintainTemplate{template})}
+	response.OK(ctx, apiRsp)
+	log.Debug(ctx, "FetchTemplateByTemplateIDHandler response: %v", apiRsp)
 }
 
 type updateTemplateRequest struct {
-	TemplateLocalID uint64 `uri:"template-local-id" validate:"required" example:"355" json:"-"`
-	ApplicationID   string `json:"application_id" validate:"required" example:"4"`
-	TemplateName    string `json:"template_name" validate:"required" example:"Std. Instruction CANCELLATION"`
-	TemplateFormat  string `json:"template_format" validate:"required" example:"Standing Instruction {#var#} on Account No {#var#} was cancelled."`
-	SenderID        string `json:"sender_id" validate:"required" example:"INPOST"`
-	EntityID        string `json:"entity_id"`
-	TemplateID      string `json:"template_id" validate:"required" example:"1007002656392643880"`
-	Gateway         string `json:"gateway" validate:"required" example:"1"`
-	MessageType     string `json:"message_type" validate:"required" example:"PM"`
-	Status          bool   `json:"status" validate:"required" example:"true"`
+	TemplateLocalID uint64        `uri:"template-local-id" validate:"required" example:"355" json:"-"`
+	ApplicationID   port.StringID `json:"application_id" validate:"required" swaggertype:"string" pattern:"^[0-9]+$" example:"4"`
+	TemplateName    string        `json:"template_name" validate:"required" example:"Std. Instruction CANCELLATION"`
+	TemplateFormat  string        `json:"template_format" validate:"required" example:"Standing Instruction {#var#} on Account No {#var#} was cancelled."`
+	SenderID        string        `json:"sender_id" validate:"required" example:"INPOST"`
+	EntityID        port.StringID `json:"entity_id" validate:"omitempty,entity_id" swaggertype:"string" pattern:"^[0-9]{19}$" example:"1001051725995192803"`
+	TemplateID      port.StringID `json:"template_id" validate:"required" swaggertype:"string" pattern:"^[0-9]+$" example:"1007002656392643880"`
+	Gateway         string        `json:"gateway" validate:"required,gateway_id" enum:"1,2" example:"1"`
+	MessageType     string        `json:"message_type" validate:"required,message_type" enum:"PM,UC" example:"PM"`
+	Status          bool          `json:"status" validate:"required" example:"true"`
 }
 
 // UpdateTemplate godoc
 //
 //	@Summary		Edits an existing Message Template
 //	@Description	Allows editing of an existing Message Template
+//	@Description	A DLT template_id belongs to a single gateway: a template_id registered under another gateway by another template is a 409
 //	@Tags			Templates
 //	@ID				UpdateTemplateHandler
 //	@Accept			json
@@ -367,6 +561,13 @@ func (ch *TemplateHandler) UpdateTemplateHandler(ctx *gin.Context) {
 		return
 	}
 
+	warnings, err := ch.lint.Lint(req.Gateway, req.SenderID, req.TemplateFormat)
+	if err != nil {
+		apierrors.HandleValidationError(ctx, err)
+		log.Error(ctx, "DLT lint failed for updateTemplateRequest: %s", err.Error())
+		return
+	}
+
 	var aStatus int
 	if req.Status {
 		aStatus = 1
@@ -376,31 +577,149 @@ func (ch *TemplateHandler) UpdateTemplateHandler(ctx *gin.Context) {
 
 	msgtemplatereq := domain.MaintainTemplate{
 		TemplateLocalID: req.TemplateLocalID,
-		ApplicationID:   req.ApplicationID,
+		ApplicationID:   string(req.ApplicationID),
 		TemplateName:    req.TemplateName,
 		TemplateFormat:  req.TemplateFormat,
 		SenderID:        req.SenderID,
-		EntityID:        req.EntityID,
-		TemplateID:      req.TemplateID,
+		EntityID:        string(req.EntityID),
+		TemplateID:      string(req.TemplateID),
 		Gateway:         req.Gateway,
 		MessageType:     req.MessageType,
 		Status:          aStatus,
 	}
 
-	err := ch.svc.UpdateTemplateRepo(ctx, &msgtemplatereq)
+	err = ch.svc.UpdateTemplateRepo(ctx, &msgtemplatereq)
+	if conflict, ok := apierrors.Find[*repo.TemplateGatewayConflictError](err); ok {
+		apierrors.ErrorResponseWithStatusCodeAndMessage(ctx, apierrors.HTTPErrorConflict, conflict.Error(), err)
+		log.Warn(ctx, "Template %d not updated: %s", msgtemplatereq.TemplateLocalID, conflict.Error())
+		return
+	}
 	if err != nil {
 		apierrors.HandleDBError(ctx, err)
 		log.Error(ctx, "Error in EditTemplateRepo function: %s", err.Error())
 		return
 	}
 
-	apiRsp := response.UpdateTemplatesAPIResponse{
-		StatusCodeAndMessage: port.UpdateSuccess,
-		//Data:                 rsp,
+	estimatedLength, lengthLimit := ch.lint.MaxLength(req.Gateway, req.SenderID, req.TemplateFormat)
+	apiRsp := &response.UpdateTemplatesAPIResponse{Data: response.NewTemplateLintResponse(estimatedLength, lengthLimit, warnings)}
+	response.Updated(ctx, apiRsp)
+	log.Debug(ctx, "UpdateTemplateHandler response: %v", apiRsp)
+}
+
+type previewTemplateRequest struct {
+	TemplateLocalID uint64   `uri:"template-local-id" validate:"required" example:"355" json:"-"`
+	Values          []string `json:"values" example:"1234,XXXX1234"`
+	AllowFallback   bool     `form:"allowFallback" json:"-" example:"true"`
+}
+
+// PreviewTemplate godoc
+//
+//	@Summary		Previews a Message Template with sample values
+//	@Description	Renders the template_format of a Message Template with sample values for its {#var#} placeholders, checks the branding of the sender, runs the message through the transformers of sms.transformers and returns it with its encoding and segment count. Nothing is sent.
+//	@Description	When the values do not match the placeholders, allowFallback=true returns the fallback message configured for the application of the template, with a warning, instead of a 422. Applications without a fallback message still get the 422
+//	@Tags			Templates
+//	@ID				PreviewTemplateHandler
+//	@Accept			json
+//	@Produce		json
+//	@Param			template-local-id		path		uint64								true	"Preview Message Template Request"
+//	@Param			allowFallback			query		bool								false	"Return the fallback message of the application when the values do not match the placeholders"
+//	@Param			previewTemplateRequest	body		previewTemplateRequest				true	"Preview Message Template Request"
+//	@Success		200						{object}	response.PreviewTemplateAPIResponse	"Message Template is rendered"
+//	@Failure		400						{object}	apierrors.APIErrorResponse			"Bad Request"
+//	@Failure		401						{object}	apierrors.APIErrorResponse			"Unauthorized"
+//	@Failure		403						{object}	apierrors.APIErrorResponse			"Forbidden"
+//	@Failure		404						{object}	apierrors.APIErrorResponse			"Data not found"
+//	@Failure		422						{object}	apierrors.APIErrorResponse			"Binding or Validation error, or the values do not match the placeholders"
+//	@Failure		500						{object}	apierrors.APIErrorResponse			"Internal server error"
+//	@Failure		502						{object}	apierrors.APIErrorResponse			"Bad Gateway"
+//	@Failure		504						{object}	apierrors.APIErrorResponse			"Gateway Timeout"
+//	@Router			/sms-templates/{template-local-id}/preview [post]
+func (ch *TemplateHandler) PreviewTemplateHandler(ctx *gin.Context) {
+
+	var req previewTemplateRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		apierrors.HandleBindingError(ctx, err)
+		log.Error(ctx, "URI Binding failed for previewTemplateRequest: %s", err.Error())
+		return
 	}
 
-	log.Debug(ctx, "UpdateTemplateHandler response: %v", apiRsp)
-	handleSuccess(ctx, apiRsp)
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		apierrors.HandleBindingError(ctx, err)
+		log.Error(ctx, "Query Binding failed for previewTemplateRequest: %s", err.Error())
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		apierrors.HandleBindingError(ctx, err)
+		log.Error(ctx, "JSON Binding failed for previewTemplateRequest: %s", err.Error())
+		return
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		apierrors.HandleValidationError(ctx, err)
+		log.Error(ctx, "Validation failed for previewTemplateRequest: %s", err.Error())
+		return
+	}
+
+	templates, err := ch.svc.FetchTemplateRepo(ctx, &domain.MaintainTemplate{TemplateLocalID: req.TemplateLocalID})
+	if err != nil {
+		apierrors.HandleDBError(ctx, err)
+		log.Error(ctx, "Error in FetchTemplateRepo function: %s", err.Error())
+		return
+	}
+	if len(templates) == 0 {
+		apierrors.ErrorResponseWithStatusCodeAndMessage(ctx, apierrors.HTTPErrorNotFound, "template not found", nil)
+		log.Error(ctx, "Template %d not found for preview", req.TemplateLocalID)
+		return
+	}
+	template := templates[0]
+
+	rendered, warning, err := ch.fallbacks.Render(template, req.Values, req.AllowFallback)
+	if err != nil {
+		apierrors.HandleValidationError(ctx, err)
+		log.Error(ctx, "Error while rendering template %d: %s", req.TemplateLocalID, err.Error())
+		return
+	}
+	var warnings []string
+	if warning != "" {
+		warnings = append(warnings, warning)
+		log.Warn(ctx, "Template %d of application %s answered with its fallback message: %s", req.TemplateLocalID, template.ApplicationID, warning)
+	}
+
+	branded, err := ch.branding.Apply(template.SenderID, rendered)
+	if err != nil {
+		apierrors.HandleValidationError(ctx, err)
+		log.Error(ctx, "Branding check failed for template %d: %s", req.TemplateLocalID, err.Error())
+		return
+	}
+
+	// transformed as the messages sent are, once branded
+	transformed, transformations, err := ch.transforms.Apply(branded)
+	if err != nil {
+		apierrors.HandleValidationError(ctx, err)
+		log.Error(ctx, "Message transformers rejected template %d: %s", req.TemplateLocalID, err.Error())
+		return
+	}
+
+	encoding, length, segments := messageSegments(transformed)
+	apiRsp := &response.PreviewTemplateAPIResponse{
+		Data: response.PreviewTemplateResponse{
+			TemplateLocalID:  port.ID(template.TemplateLocalID),
+			RenderedMessage:  transformed,
+			BrandingAppended: branded != rendered,
+			Encoding:         string(encoding),
+			MessageType:      template.MessageType,
+			Length:           length,
+			SegmentCount:     segments,
+			Fallback:         warning != "",
+			Warnings:         warnings,
+			Transformations:  transformations,
+		},
+	}
+
+	response.OK(ctx, apiRsp)
+	log.Debug(ctx, "PreviewTemplateHandler response: %v", apiRsp)
 }
 
 type fetchTemplateByApplicationRequest struct {
@@ -410,7 +729,7 @@ type fetchTemplateByApplicationRequest struct {
 // FetchTemplateByApplication godoc
 //
 //	@Summary		Get Message Template names by ApplicationID
-//	@Description	Fetches Message Template names by ApplicationID
+//	@Description	Fetches the Message Templates of an ApplicationID with their names, template ids, gateway and message type
 //	@Tags			Templates
 //	@ID				FetchTemplateByApplicationHandler
 //	@Accept			json
@@ -454,15 +773,9 @@ func (ch *TemplateHandler) FetchTemplateByApplicationHandler(ctx *gin.Context) {
 		return
 	}
 
-	rsp := response.NewFetchTemplateNameResponse(template)
-	apiRsp := response.FetchTemplateNameAPIResponse{
-		StatusCodeAndMessage: port.FetchSuccess,
-		//MetaDataResponse:     metadata,
-		Data: rsp,
-	}
-
+	apiRsp := &response.FetchTemplateNameAPIResponse{Data: response.NewFetchTemplateNameResponse(template)}
+	response.OK(ctx, apiRsp)
 	log.Debug(ctx, "FetchTemplateByApplicationHandler response: %v", apiRsp)
-	handleSuccess(ctx, apiRsp)
 }
 
 type fetchTemplateDetailsRequest struct {
@@ -525,13 +838,7 @@ func (ch *TemplateHandler) FetchTemplateDetailsHandler(ctx *gin.Context) {
 		return
 	}
 
-	rsp := response.NewFetchTemplateDetailsResponse(template)
-	apiRsp := response.FetchTemplateDetailsAPIResponse{
-		StatusCodeAndMessage: port.FetchSuccess,
-		//MetaDataResponse:     metadata,
-		Data: rsp,
-	}
-
+	apiRsp := &response.FetchTemplateDetailsAPIResponse{Data: response.NewFetchTemplateDetailsResponse(template)}
+	response.OK(ctx, apiRsp)
 	log.Debug(ctx, "FetchTemplateDetailsHandler response: %v", apiRsp)
-	handleSuccess(ctx, apiRsp)
 }
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	"MgApplication/core/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateHandlerRoutes(t *testing.T) {
+	th := NewTemplateHandler(nil, config.NewConfig(viper.New()))
+	assert.Equal(t, "/v1/sms-templates", th.Prefix())
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	var routes []string
+	for _, r := range th.Routes() {
+		meta := r.Meta()
+		routes = append(routes, meta.Method+" "+meta.Path)
+		assert.True(t, meta.Raw, meta.Path)
+		// gin panics on conflicting routes
+		engine.Handle(meta.Method, th.Prefix()+meta.Path, meta.Func)
+	}
+	assert.Contains(t, routes, "GET /by-template-id/:template-id")
+	assert.Contains(t, routes, "GET /:template-local-id")
+	assert.Contains(t, routes, "GET /name")
+	assert.Contains(t, routes, "POST /:template-local-id/preview")
+	assert.Contains(t, routes, "PUT /bulk-status")
+}
+
+// postTemplateRequest sends body to the CreateTemplateHandler of th
+func postTemplateRequest(th *TemplateHandler, body map[string]any) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/v1/sms-templates", th.CreateTemplateHandler)
+
+	input, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/v1/sms-templates", bytes.NewBuffer(input))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+// putTemplateRequest sends body to the UpdateTemplateHandler of th for templateLocalID
+func putTemplateRequest(th *TemplateHandler, templateLocalID uint64, body map[string]any) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.PUT("/v1/sms-templates/:template-local-id", th.UpdateTemplateHandler)
+
+	input, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/v1/sms-templates/%d", templateLocalID), bytes.NewBuffer(input))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+// Paging out of bounds is answered with 422 naming the failing field, before the store is queried
+func TestListTemplatesHandlerPagingValidation(t *testing.T) {
+	th := NewTemplateHandler(nil, config.NewConfig(viper.New()))
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/v1/sms-templates", th.ListTemplatesHandler)
+
+	for query, field := range map[string]string{
+		"skip=-1&limit=10":  "skip",
+		"skip=0&limit=-5":   "limit",
+		"skip=0&limit=1001": "limit",
+	} {
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/sms-templates?"+query, nil))
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, query)
+
+		var rsp struct {
+			Error struct {
+				FieldErrors []struct {
+					Field string `json:"field"`
+					Tag   string `json:"tag"`
+				} `json:"field_errors"`
+			} `json:"error"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp), query)
+		if assert.Len(t, rsp.Error.FieldErrors, 1, rec.Body.String()) {
+			assert.Equal(t, field, rsp.Error.FieldErrors[0].Field, query)
+		}
+	}
+}
+
+func TestTemplateFallbacksRender(t *testing.T) {
+	c := config.NewConfig(viper.New())
+	c.Set("applications.fallbackmessages", map[string]any{"4": "Your request is being processed - INDPOST", "5": " "})
+	fallbacks := NewTemplateFallbacks(c)
+	template := domain.MaintainTemplate{ApplicationID: "4", TemplateFormat: "Article {#var#} is booked at {#var#} - INDPOST"}
+
+	rendered, warning, err := fallbacks.Render(template, []string{"EK123456789IN", "Delhi GPO"}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "Article EK123456789IN is booked at Delhi GPO - INDPOST", rendered)
+	assert.Empty(t, warning)
+
+	// strict by default
+	_, _, err = fallbacks.Render(template, []string{"EK123456789IN"}, false)
+	assert.EqualError(t, err, "template expects 2 values, but received 1")
+
+	fallbackCount := testutil.ToFloat64(TemplateFallbackTotal.WithLabelValues("4"))
+	rendered, warning, err = fallbacks.Render(template, []string{"EK123456789IN"}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "Your request is being processed - INDPOST", rendered)
+	assert.Contains(t, warning, "template expects 2 values, but received 1")
+	assert.Equal(t, float64(1), testutil.ToFloat64(TemplateFallbackTotal.WithLabelValues("4"))-fallbackCount)
+
+	// applications without a fallback message stay strict
+	for _, applicationID := range []string{"5", "6"} {
+		template.ApplicationID = applicationID
+		_, _, err = fallbacks.Render(template, []string{"EK123456789IN"}, true)
+		assert.Error(t, err, applicationID)
+	}
+}
+
+// A template whose application_id does not reference an existing application is answered with a
+// 422 naming the field, before it is stored
+func TestCreateTemplateUnknownApplication(t *testing.T) {
+	th := NewTemplateHandler(nil, config.NewConfig(viper.New()))
+	var looked []string
+	th.applicationExists = func(ctx context.Context, id string) (bool, error) {
+		looked = append(looked, id)
+		return false, nil
+	}
+
+	rec := postTemplateRequest(th, map[string]any{
+		"application_id":  "4242",
+		"template_name":   "Test Template reference",
+		"template_format": "Your OTP is {#var#} for booking {#var#} - INDPOST",
+		"sender_id":       "INPOST",
+		"template_id":     "1007188452935484904",
+		"message_type":    "PM",
+		"gateway":         "1",
+		"status":          true,
+	})
+
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	var body apierrors.APIErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "application_id 4242 does not reference an existing application", body.AppError.Message)
+	require.Len(t, body.AppError.FieldErrors, 1)
+	assert.Equal(t, "application_id", body.AppError.FieldErrors[0].Field)
+	assert.Equal(t, referenceTag, body.AppError.FieldErrors[0].Tag)
+	assert.Equal(t, []string{"4242"}, looked)
+}
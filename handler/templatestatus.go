@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	apierrors "MgApplication/api-errors"
+	log "MgApplication/api-log"
+	serverResponse "MgApplication/api-server/response"
+	validation "MgApplication/api-validation"
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+	"MgApplication/handler/response"
+	repo "MgApplication/repo/postgres"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bulkStatusConfirmAbove is the number of templates a filter changes without confirm
+const bulkStatusConfirmAbove = 50
+
+type bulkTemplateStatusRequest struct {
+	// TemplateLocalIDs lists the templates to update, at most 500, the filter selecting them otherwise
+	TemplateLocalIDs []port.ID `json:"template_local_ids" validate:"omitempty,max=500,unique,dive,required" swaggertype:"array,string" example:"355,356"`
+	ApplicationID    string    `json:"application_id" validate:"omitempty,numeric" example:"4"`
+	SenderID         string    `json:"sender_id" validate:"omitempty,max=6,alphanum" example:"INPOST"`
+	Gateway          string    `json:"gateway" validate:"omitempty,gateway_id" enum:"1,2" example:"1"`
+	Status           *int      `json:"status" validate:"required,oneof=0 1" enums:"0,1" example:"0"`
+	// Confirm is required when the filter would change more than 50 templates
+	Confirm bool `json:"confirm" example:"false"`
+}
+
+// bulk returns the bulk status update of the request, failing with a field error unless it
+// lists templates or filters them, but not both
+func (req bulkTemplateStatusRequest) bulk() (domain.BulkTemplateStatus, error) {
+	bulk := domain.BulkTemplateStatus{
+		Filter: domain.TemplateFilter{
+			ApplicationID: req.ApplicationID,
+			SenderID:      req.SenderID,
+			Gateway:       req.Gateway,
+		},
+		Status:       *req.Status,
+		Confirm:      req.Confirm,
+		ConfirmAbove: bulkStatusConfirmAbove,
+	}
+	for _, id := range req.TemplateLocalIDs {
+		bulk.TemplateLocalIDs = append(bulk.TemplateLocalIDs, uint64(id))
+	}
+
+	filtered := req.ApplicationID != "" || req.SenderID != "" || req.Gateway != ""
+	appErr := apierrors.NewAppError("invalid bulk template status update", http.StatusUnprocessableEntity, errors.New("invalid bulk template status update"))
+	switch {
+	case len(req.TemplateLocalIDs) > 0 && filtered:
+		appErr.SetFieldErrors([]apierrors.FieldError{appErr.NewFieldError("template_local_ids", req.TemplateLocalIDs,
+			"template_local_ids cannot be combined with a filter of application_id, sender_id or gateway", "excluded_with")})
+		return bulk, &appErr
+	case len(req.TemplateLocalIDs) == 0 && !filtered:
+		appErr.SetFieldErrors([]apierrors.FieldError{appErr.NewFieldError("template_local_ids", req.TemplateLocalIDs,
+			"template_local_ids or a filter of application_id, sender_id or gateway is required", "required_without")})
+		return bulk, &appErr
+	}
+	return bulk, nil
+}
+
+// BulkTemplateStatus godoc
+//
+//	@Summary		Sets the status of Message Templates in bulk
+//	@Description	Sets the status of the templates listed in template_local_ids (at most 500) or of the templates matching a filter of application_id, sender_id and gateway, in a single update.
+//	@Description	The templates already in the status are left alone. The number of templates updated is returned, with the result of each listed template: updated, not_found or already_in_state.
+//	@Description	A filter that would change more than 50 templates is a 409 unless confirm is true. The changes are recorded in the template history and invalidate the cached templates.
+//	@Tags			Templates
+//	@ID				BulkTemplateStatusHandler
+//	@Accept			json
+//	@Produce		json
+//	@Param			bulkTemplateStatusRequest	body		bulkTemplateStatusRequest				true	"Bulk Message Template Status Request"
+//	@Success		200							{object}	response.BulkTemplateStatusAPIResponse	"Message Template statuses are modified"
+//	@Failure		400							{object}	apierrors.APIErrorResponse				"Bad Request"
+//	@Failure		401							{object}	apierrors.APIErrorResponse				"Unauthorized"
+//	@Failure		403							{object}	apierrors.APIErrorResponse				"Forbidden"
+//	@Failure		409							{object}	apierrors.APIErrorResponse				"The filter would change more than 50 templates without confirm"
+//	@Failure		422							{object}	apierrors.APIErrorResponse				"Binding or Validation error"
+//	@Failure		500							{object}	apierrors.APIErrorResponse				"Internal server error"
+//	@Failure		502							{object}	apierrors.APIErrorResponse				"Bad Gateway"
+//	@Failure		504							{object}	apierrors.APIErrorResponse				"Gateway Timeout"
+//	@Router			/sms-templates/bulk-status [put]
+func (ch *TemplateHandler) BulkTemplateStatusHandler(ctx *gin.Context) {
+
+	var req bulkTemplateStatusRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		apierrors.HandleBindingError(ctx, err)
+		log.Error(ctx, "Binding failed for bulkTemplateStatusRequest: %s", err.Error())
+		return
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		apierrors.HandleValidationError(ctx, err)
+		log.Error(ctx, "Validation failed for bulkTemplateStatusRequest: %s", err.Error())
+		return
+	}
+
+	bulk, err := req.bulk()
+	if err != nil {
+		apierrors.HandleValidationError(ctx, err)
+		log.Error(ctx, "Validation failed for bulkTemplateStatusRequest: %s", err.Error())
+		return
+	}
+	bulk.CorrelationID = serverResponse.CorrelationID(ctx)
+
+	result, err := ch.svc.BulkTemplateStatusRepo(ctx, &bulk)
+	if confirm, ok := apierrors.Find[*repo.BulkStatusConfirmationError](err); ok {
+		apierrors.ErrorResponseWithStatusCodeAndMessage(ctx, apierrors.HTTPErrorConflict, confirm.Error(), err)
+		log.Warn(ctx, "Template statuses not changed: %s", confirm.Error())
+		return
+	}
+	if err != nil {
+		apierrors.HandleDBError(ctx, err)
+		log.Error(ctx, "Error in BulkTemplateStatusRepo function: %s", err.Error())
+		return
+	}
+
+	apiRsp := &response.BulkTemplateStatusAPIResponse{Data: response.NewBulkTemplateStatusResponse(result)}
+	response.Updated(ctx, apiRsp)
+	log.Info(ctx, "Status of %d templates set to %d", result.Updated, bulk.Status)
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	config "MgApplication/api-config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// Invalid bulk status updates are answered with 422 naming the failing field, before the store
+// is queried
+func TestBulkTemplateStatusHandlerValidation(t *testing.T) {
+	th := NewTemplateHandler(nil, config.NewConfig(viper.New()))
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.PUT("/v1/sms-templates/bulk-status", th.BulkTemplateStatusHandler)
+
+	tooMany := make([]int, 501)
+	for i := range tooMany {
+		tooMany[i] = i + 1
+	}
+	tests := []struct {
+		name  string
+		body  map[string]any
+		field string
+	}{
+		{"neither list nor filter", map[string]any{"status": 0}, "template_local_ids"},
+		{"list and filter", map[string]any{"template_local_ids": []int{355}, "sender_id": "INPOST", "status": 0}, "template_local_ids"},
+		{"more than 500 templates", map[string]any{"template_local_ids": tooMany, "status": 0}, "template_local_ids"},
+		{"duplicate templates", map[string]any{"template_local_ids": []int{355, 355}, "status": 1}, "template_local_ids"},
+		{"no status", map[string]any{"template_local_ids": []int{355}}, "status"},
+		{"unknown status", map[string]any{"sender_id": "INPOST", "status": 2}, "status"},
+		{"invalid gateway", map[string]any{"gateway": "9", "status": 0}, "gateway"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest(http.MethodPut, "/v1/sms-templates/bulk-status", bytes.NewBuffer(input))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+
+			var rsp struct {
+				Error struct {
+					FieldErrors []struct {
+						Field string `json:"field"`
+					} `json:"field_errors"`
+				} `json:"error"`
+			}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+			if assert.Len(t, rsp.Error.FieldErrors, 1, rec.Body.String()) {
+				assert.Equal(t, tt.field, rsp.Error.FieldErrors[0].Field)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	config "MgApplication/api-config"
+	apierrors "MgApplication/api-errors"
+	log "MgApplication/api-log"
+	"MgApplication/core/domain"
+	"MgApplication/core/transform"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var MessageTransformationsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "sms_message_transformations_total",
+		Help: "Total number of messages changed or rejected by a transformer before they were sent, by transformer and outcome",
+	},
+	[]string{"transformer", "outcome"},
+)
+
+// Outcomes of a transformer, the outcome label of MessageTransformationsTotal
+const (
+	transformationApplied  = "applied"
+	transformationRejected = "rejected"
+)
+
+// newMessagePipeline creates the pipeline of the transformers listed in sms.transformers, applied
+// in order to the text of every message before it is sent. An invalid list leaves messages
+// unchanged, CheckMessageTransformers failing startup.
+func newMessagePipeline(c *config.Config) *transform.Pipeline {
+	pipeline, err := transform.New(c.GetStringSlice("sms.transformers"))
+	if err != nil {
+		log.Error(nil, "Invalid sms.transformers, messages are sent unchanged: %s", err.Error())
+		return nil
+	}
+	return pipeline
+}
+
+// CheckMessageTransformers fails startup when sms.transformers lists a transformer that is not
+// built in nor registered with transform.Register
+func CheckMessageTransformers(c *config.Config) error {
+	_, err := transform.New(c.GetStringSlice("sms.transformers"))
+	return err
+}
+
+// transformMessage applies the message pipeline to the text of msgreq, recording the
+// transformers that changed it in msgreq.Transformations. A message a transformer rejects is
+// answered with a 422.
+func transformMessage(pipeline *transform.Pipeline, msgreq *domain.MsgRequest) error {
+	text, applied, err := pipeline.Apply(msgreq.MessageText)
+	if err != nil {
+		if failed, ok := apierrors.Find[*transform.Error](err); ok {
+			MessageTransformationsTotal.WithLabelValues(failed.Transformer, transformationRejected).Inc()
+		}
+		return apierrors.HandleErrorWithStatusCodeAndMessage(apierrors.AppErrorValidationError, err.Error(), err)
+	}
+	for _, name := range applied {
+		MessageTransformationsTotal.WithLabelValues(name, transformationApplied).Inc()
+	}
+	msgreq.MessageText = text
+	msgreq.Transformations = applied
+	return nil
+}
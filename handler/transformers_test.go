@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"MgApplication/core/transform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sendTransformedSMS sends messageText as INPOST through a CDAC server with the transformers
+// listed, and returns the response, the store and the message text received by the gateway
+func sendTransformedSMS(t *testing.T, messageText string, transformers ...string) (*httptest.ResponseRecorder, *fakeMsgStore, string) {
+	t.Helper()
+	var content string
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content = r.FormValue("content")
+		_, _ = w.Write([]byte("402,MsgID = 060320251741252969161appostsms"))
+	}))
+	defer cdac.Close()
+
+	c := brandingConfig(true)
+	c.Set("sms.msgstorerequest", 1)
+	c.Set("sms.cdac.url", cdac.URL)
+	c.Set("sms.cdac.username", "appostsms")
+	c.Set("sms.cdac.password", "cdacsecret")
+	c.Set("sms.cdac.securekey", "c7d427c9-63e7-4eec-a227-3ef840a75269")
+	c.Set("sms.transformers", transformers)
+	require.NoError(t, CheckMessageTransformers(c))
+
+	ch, store := newTestSMSHandler(c)
+	ch.transforms = newMessagePipeline(c)
+	body := otpRequestBody("9000000001")
+	body["message_text"] = messageText
+	return postSMSRequest(ch, body), store, content
+}
+
+func TestCreateSMSTransformsTheMessage(t *testing.T) {
+	messy := "Dear  Customer,\u200b OTP for booking is “1234” 🔐\r\nPlease don’t share it with anyone  \n"
+
+	rec, store, content := sendTransformedSMS(t, messy, transform.ZeroWidth, transform.EmojiStrip, transform.SmartQuotes, transform.Whitespace)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, "Dear Customer, OTP for booking is \"1234\"\nPlease don't share it with anyone - INDPOST", content)
+	assert.Equal(t, content, store.lastRequest.MessageText)
+	assert.Equal(t, []string{transform.ZeroWidth, transform.EmojiStrip, transform.SmartQuotes, transform.Whitespace}, store.lastRequest.Transformations)
+
+	// without transformers the message is sent as received, branded
+	rec, store, content = sendTransformedSMS(t, messy)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, "Dear  Customer,\u200b OTP for booking is “1234” 🔐\r\nPlease don’t share it with anyone - INDPOST", content)
+	assert.Nil(t, store.lastRequest.Transformations)
+}
+
+func TestCreateSMSRejectsEmoji(t *testing.T) {
+	rec, store, content := sendTransformedSMS(t, "Dear Customer, OTP for booking is 1234 🔐 - INDPOST", transform.Whitespace, transform.EmojiReject)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "emoji_reject")
+	assert.Equal(t, "", content)
+	assert.Equal(t, 0, store.savedRequests)
+}
+
+func TestCheckMessageTransformers(t *testing.T) {
+	c := brandingConfig(true)
+	c.Set("sms.transformers", []string{transform.Whitespace, "rot13"})
+	assert.EqualError(t, CheckMessageTransformers(c), "unknown transformer rot13")
+	assert.Nil(t, newMessagePipeline(c))
+}
@@ -3,11 +3,26 @@ package handler
 import (
 	"regexp"
 
+	config "MgApplication/api-config"
 	validation "MgApplication/api-validation"
 
 	"github.com/go-playground/validator/v10"
 )
 
+// dltEntityIDPattern matches the 19 digit principal entity id issued on the DLT platform
+var dltEntityIDPattern = regexp.MustCompile(`^[0-9]{19}$`)
+
+// DLTEntityID validates the DLT principal entity id, such as 1001081725895192800
+func DLTEntityID(f1 validator.FieldLevel) bool {
+	return dltEntityIDPattern.MatchString(f1.Field().String())
+}
+
+// MessageText validates that a message text keeps content once normalized: a text of spaces,
+// control or zero-width characters only would be sent to the gateway as an empty message
+func MessageText(f1 validator.FieldLevel) bool {
+	return NormalizeAndClean(f1.Field().String()) != ""
+}
+
 func ServiceRequestType(f1 validator.FieldLevel) bool {
 	// Define the regex pattern for a comma-separated list of numbers between 1 and 4
 	requestTypePattern := "^[1-4](,[1-4])*$"
@@ -42,9 +57,82 @@ func NewValidatorService() error {
 		return err
 	}
 
+	err = validation.RegisterCustomValidation("entity_id", DLTEntityID, "field %s must be the 19 digit DLT principal entity id, but received %v")
+	if err != nil {
+		return err
+	}
+
+	err = validation.RegisterCustomValidation("message_text", MessageText, "field %s must not be empty or only spaces, control or zero-width characters, but received %q")
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// requestStructs are the request structs of the handlers, their validate tags are checked at
+// startup by CheckValidatorTags
+var requestStructs = []any{
+	setLogLevelRequest{},
+	importDNDRegistryRequest{},
+	backfillStatsRequest{},
+	checkStatsRequest{},
+	orphanedRequestsRequest{},
+	saveGatewayCodeRequest{},
+	deleteGatewayCodeRequest{},
+	setShadowGatewayRequest{},
+	shadowComparisonRequest{},
+	privacyErasureRequest{},
+	listErrorSamplesRequest{},
+	importBundleRequest{},
+	createMessageApplicationRequest{},
+	createMessageApplicationXMLRequest{},
+	createMessageApplicationRequestForm{},
+	createMessageApplicationRequestFormTest{},
+	updateMessageApplicationRequest{},
+	listMessageApplicationsRequest{},
+	fetchApplicationRequest{},
+	toggleApplicationStatusRequest{},
+	initiateBulkSMSRequest{},
+	validateTestSMSRequest{},
+	SendBulkSMSRequestOld{},
+	sendBulkSMSRequest{},
+	listGatewayCodesRequest{},
+	createSMSRequest{},
+	FetchCDACSMSDeliveryStatusRequest{},
+	fetchSMSRequestStatusRequest{},
+	generateOTPRequest{},
+	verifyOTPRequest{},
+	createMessageProviderRequest{},
+	listMessageProviderRequest{},
+	fetchMessageProviderRequest{},
+	updateMessageProviderRequest{},
+	toggleMessageProviderStatusRequest{},
+	sentSMSStatusReportRequest{},
+	aggregateSMSUsageReportRequest{},
+	createTemplateRequest{},
+	toggleTemplateStatusRequest{},
+	fetchTemplateRequest{},
+	fetchTemplateByTemplateIDRequest{},
+	updateTemplateRequest{},
+	previewTemplateRequest{},
+	fetchTemplateByApplicationRequest{},
+}
+
+func init() {
+	validation.RegisterStructs(requestStructs...)
+}
+
+// CheckValidatorTags fails startup when a validate tag of a registered request struct has no
+// registered rule, instead of the validator panicking at the first request using it. The check
+// runs with validation.strictstartup.
+func CheckValidatorTags(c *config.Config) error {
+	if !c.GetBool("validation.strictstartup") {
+		return nil
+	}
+	return validation.CheckRegisteredStructs()
+}
+
 // errorValidResponse represents a JSON response for validation errors.
 // type errorValidResponse struct {
 // 	Success bool     `json:"success" example:"false"`
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	config "MgApplication/api-config"
+	validation "MgApplication/api-validation"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntityIDValidation(t *testing.T) {
+	type entityRequest struct {
+		EntityID string `json:"entity_id" validate:"entity_id"`
+	}
+	tests := []struct {
+		entityID string
+		valid    bool
+	}{
+		{"1001081725895192800", true},
+		{"1301157641566214705", true},
+		{"", false},
+		{"100108172589519280", false},
+		{"10010817258951928001", false},
+		{"1001081725895192.80", false},
+		{" 1001081725895192800", false},
+		{"1001O81725895192800", false},
+	}
+	for _, tt := range tests {
+		err := validation.ValidateStruct(entityRequest{EntityID: tt.entityID})
+		assert.Equal(t, tt.valid, err == nil, tt.entityID)
+	}
+}
+
+func TestCreateTemplateHandlerInvalidEntityID(t *testing.T) {
+	th := NewTemplateHandler(nil, config.NewConfig(viper.New()))
+	for _, entityID := range []string{"16507160377410448739", "100108172589519280", "10010817258951928AB"} {
+		rec := postTemplateRequest(th, map[string]any{
+			"application_id":  "69",
+			"template_name":   "Test Template entity",
+			"template_format": "Your OTP is {#val} for {#val} for",
+			"sender_id":       "INPOST",
+			"entity_id":       entityID,
+			"template_id":     "165071603777774104478739",
+			"message_type":    "PM",
+			"gateway":         "1",
+			"status":          true,
+		})
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, entityID)
+	}
+}
+
+func TestCreateSMSRequestInvalidMobileNumbers(t *testing.T) {
+	ch, store := newTestSMSHandler(config.NewConfig(viper.New()))
+	for mobileNumbers, detail := range map[string]string{
+		"9000000001,5000000002":  `index 1 is \"5000000002\"`,
+		"9000000001,,9000000003": `index 1 is \"\"`,
+		"9000000001,":            `index 1 is \"\"`,
+		"1111111111":             `index 0 is \"1111111111\"`,
+	} {
+		rec := postSMSRequest(ch, otpRequestBody(mobileNumbers))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, mobileNumbers)
+		assert.Contains(t, rec.Body.String(), detail, mobileNumbers)
+	}
+	assert.Zero(t, store.savedRequests)
+}
+
+func TestCreateSMSRequestEmptyMessageText(t *testing.T) {
+	ch, store := newTestSMSHandler(config.NewConfig(viper.New()))
+	for _, priority := range []int{1, 2, 3, 4} {
+		for _, messageText := range []string{"", "   ", "\t\n", "\u200B\u00A0"} {
+			body := otpRequestBody("9000000001")
+			body["priority"] = priority
+			body["message_text"] = messageText
+			rec := postSMSRequest(ch, body)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, "priority %d, message %q", priority, messageText)
+			assert.Contains(t, rec.Body.String(), "message_text", "priority %d, message %q", priority, messageText)
+		}
+	}
+	assert.Zero(t, store.savedRequests)
+}
+
+func TestMessageTextValidation(t *testing.T) {
+	type messageRequest struct {
+		MessageText string `json:"message_text" validate:"message_text"`
+	}
+	for messageText, valid := range map[string]bool{
+		"Your OTP is 1234 - INDPOST": true,
+		" a ":                        true,
+		"":                           false,
+		" \t\r\n":                    false,
+		"\u200B\u200C\uFEFF":         false,
+		"\u00A0\u2003":               false,
+	} {
+		err := validation.ValidateStruct(messageRequest{MessageText: messageText})
+		assert.Equal(t, valid, err == nil, "%q", messageText)
+	}
+}
+
+func TestRequestStructTagsRegistered(t *testing.T) {
+	validation.AssertAllTagsRegistered(t, requestStructs...)
+}
+
+func TestCheckValidatorTags(t *testing.T) {
+	c := config.NewConfig(viper.New())
+	assert.NoError(t, CheckValidatorTags(c))
+
+	c.Set("validation.strictstartup", true)
+	assert.NoError(t, CheckValidatorTags(c))
+}
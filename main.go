@@ -29,10 +29,12 @@ func main() {
 		// bootstrapper.FxDB,
 		// bootstrapper.Fxclient,
 		// bootstrap.FxParseController,
+		bootstrap.FxRequiredConfig,
 		bootstrap.Fxvalidator,
 		// bootstrapper.Fxrouter,
 		bootstrap.FxHandler,
 		bootstrap.FxRepo,
+		bootstrap.FxJobs,
 		// fx.Invoke(routes.Routes),
 		// bootstrapper.FxGrpc,
 		// fx.Invoke(bootstrap.AddHandlers),
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// printAllowedDirs are the names of the directories whose sources may print to stdout with
+// fmt.Print*: the command line tools and the generated docs
+var printAllowedDirs = []string{"cmd", "docs"}
+
+// TestNoFmtPrint fails when a source outside printAllowedDirs calls fmt.Print, fmt.Printf or
+// fmt.Println, which bypass the structured logger and its levels. Tests are not checked.
+func TestNoFmtPrint(t *testing.T) {
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != "." && (strings.HasPrefix(name, ".") || name == "testdata" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			for _, dir := range printAllowedDirs {
+				if name == dir {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		for _, call := range fmtPrintCalls(file) {
+			t.Errorf("%s: fmt.%s writes to stdout, use MgApplication/api-log instead", fset.Position(call.Pos()), call.Sel.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fmtPrintCalls returns the fmt.Print, fmt.Printf and fmt.Println calls of file, the fmt
+// package being imported under any name
+func fmtPrintCalls(file *ast.File) []*ast.SelectorExpr {
+	name := ""
+	for _, spec := range file.Imports {
+		if path, _ := strconv.Unquote(spec.Path.Value); path == "fmt" {
+			name = "fmt"
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+		}
+	}
+	if name == "" || name == "_" {
+		return nil
+	}
+	var calls []*ast.SelectorExpr
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == name {
+			switch sel.Sel.Name {
+			case "Print", "Printf", "Println":
+				calls = append(calls, sel)
+			}
+		}
+		return true
+	})
+	return calls
+}
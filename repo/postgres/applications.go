@@ -19,13 +19,15 @@ import (
 type ApplicationRepository struct {
 	Db  *dblib.DB
 	Cfg *config.Config
+	// Reads routes the fetches of an application, to the primary when nil
+	Reads *dblib.ReadRouter
 }
 
 // NewOfficeRepository creates a new Office repository instance
 func NewApplicationRepository(Db *dblib.DB, Cfg *config.Config) *ApplicationRepository {
 	return &ApplicationRepository{
-		Db,
-		Cfg,
+		Db:  Db,
+		Cfg: Cfg,
 	}
 }
 
@@ -59,6 +61,10 @@ func (ar *ApplicationRepository) CreateMsgApplicationRepo(ctx context.Context, m
 			log.Error(ctx, "Error executing insert query in CreateMsgApplication repo function: %s", err.Error())
 			return err
 		}
+		if err := auditApplicationTx(ctx, tx, ar.Cfg, domain.AuditActionCreate, msgapplication.ApplicationID, nil); err != nil {
+			log.Error(ctx, "Error appending to the audit log in CreateMsgApplication repo function: %s", err.Error())
+			return err
+		}
 		return nil
 	})
 	if TxDB != nil {
@@ -109,7 +115,7 @@ func (ar *ApplicationRepository) ListApplicationsOld(gctx *gin.Context) ([]domai
 		Join("msg_request_type mr ON rt.rt_value::integer = mr.request_code").
 		GroupBy("ma.application_id", "ma.application_name", "ma.status_cd").
 		OrderBy("ma.application_id")
-	return dblib.SelectRows(ctx, ar.Db, query, pgx.RowToStructByNameLax[domain.MsgApplicationsGet])
+	return dblib.SelectRows(ctx, ar.Db, query, pgx.RowToStructByNameLax[domain.MsgApplicationsGet], dblib.WithReadRetry())
 }
 */
 
@@ -129,7 +135,7 @@ func (ar *ApplicationRepository) FetchApplicationRepo(ctx context.Context, msgap
 		GroupBy("ma.application_id", "ma.application_name", "ma.status_cd").
 		OrderBy("ma.application_id")
 
-	listApplications, err := dblib.SelectRows(ctx, ar.Db, query, pgx.RowToStructByNameLax[domain.MsgApplicationsGet])
+	listApplications, err := dblib.SelectRows(ctx, readDB(ctx, ar.Reads, ar.Db), query, pgx.RowToStructByNameLax[domain.MsgApplicationsGet], dblib.WithReadRetry())
 	if err != nil {
 		log.Error(ctx, "Error executing query in GetAppbyID repo function:  %s", err.Error())
 		return nil, err
@@ -155,7 +161,7 @@ func (ar *ApplicationRepository) ListActiveApplications(gctx *gin.Context) ([]do
 		GroupBy("ma.application_id", "ma.application_name", "ma.status_cd").
 		Where(squirrel.Eq{"status_cd": 1}).
 		OrderBy("ma.application_id")
-	return dblib.SelectRows(ctx, ar.Db, query, pgx.RowToStructByNameLax[domain.MsgApplicationsGet])
+	return dblib.SelectRows(ctx, ar.Db, query, pgx.RowToStructByNameLax[domain.MsgApplicationsGet], dblib.WithReadRetry())
 }
 
 func (ar *ApplicationRepository) FetchApplications(gctx *gin.Context, applicationID uint64, activeOnly bool) ([]domain.MsgApplicationsGet, error) {
@@ -182,7 +188,7 @@ func (ar *ApplicationRepository) FetchApplications(gctx *gin.Context, applicatio
 		OrderBy("ma.application_id")
 
 	// Execute the query and return the results using dblib.SelectRows
-	collectedRows, err := dblib.SelectRows(ctx, ar.Db, query, pgx.RowToStructByNameLax[domain.MsgApplicationsGet])
+	collectedRows, err := dblib.SelectRows(ctx, ar.Db, query, pgx.RowToStructByNameLax[domain.MsgApplicationsGet], dblib.WithReadRetry())
 	if err != nil {
 		log.Error(ctx, "Error executing query in FetchApplications repo function:  %s", err.Error())
 		return nil, err
@@ -225,6 +231,11 @@ func (ar *ApplicationRepository) UpdateMsgApplicationRepo(ctx context.Context, m
 			log.Error(ctx, "Already One application with the selected details already exists")
 			return errors.New("already one application with these selected details is available")
 		}
+		before, err := auditRowTx(ctx, tx, "msg_application", squirrel.Eq{"application_id": msgapp.ApplicationID})
+		if err != nil {
+			log.Error(ctx, "Error reading the application before the change in EditMsgApplication repo function:  %s", err.Error())
+			return err
+		}
 		query3 := dblib.Psql.Update("msg_application").
 			Set("application_name", msgapp.ApplicationName).
 			Set("request_type", msgapp.RequestType).
@@ -237,6 +248,10 @@ func (ar *ApplicationRepository) UpdateMsgApplicationRepo(ctx context.Context, m
 			log.Error(ctx, "Error executing update query in EditMsgApplication repo function:  %s", err.Error())
 			return err
 		}
+		if err := auditApplicationTx(ctx, tx, ar.Cfg, domain.AuditActionUpdate, msgapp.ApplicationID, before); err != nil {
+			log.Error(ctx, "Error appending to the audit log in EditMsgApplication repo function:  %s", err.Error())
+			return err
+		}
 		return nil
 	})
 	if TxDB != nil {
@@ -265,6 +280,11 @@ func (ar *ApplicationRepository) ToggleApplicationStatusRepo(gctx *gin.Context,
 		if Counter.Count == 0 {
 			return errors.New("no application with selected details available")
 		}
+		before, err := auditRowTx(ctx, tx, "msg_application", squirrel.Eq{"application_id": msgapp.ApplicationID})
+		if err != nil {
+			log.Error(ctx, "Error reading the application before the change in StatusMsgApplication repo function:  %s", err.Error())
+			return err
+		}
 		query2 := dblib.Psql.Update("msg_application").
 			Set("status_cd", squirrel.Expr("CASE WHEN status_cd = 0 THEN 1 ELSE 0 END")).
 			Set("updated_date", squirrel.Expr("current_timestamp")).
@@ -274,6 +294,10 @@ func (ar *ApplicationRepository) ToggleApplicationStatusRepo(gctx *gin.Context,
 			log.Error(ctx, "Error executing update query in StatusMsgApplication repo function:  %s", err.Error())
 			return err
 		}
+		if err := auditApplicationTx(ctx, tx, ar.Cfg, domain.AuditActionStatus, msgapp.ApplicationID, before); err != nil {
+			log.Error(ctx, "Error appending to the audit log in StatusMsgApplication repo function:  %s", err.Error())
+			return err
+		}
 		return nil
 	})
 	if TxDB != nil {
@@ -326,7 +350,7 @@ func (ar *ApplicationRepository) ListActiveProviders(gctx *gin.Context) ([]domai
 		Where(squirrel.Eq{"status_cd": 1}).
 		GroupBy("mp.provider_id", "mp.provider_name", "mp.short_name", "mp.status_cd").
 		OrderBy("mp.provider_id")
-	return dblib.SelectRows(ctx, ar.Db, query, pgx.RowToStructByNameLax[domain.MsgProvider])
+	return dblib.SelectRows(ctx, ar.Db, query, pgx.RowToStructByNameLax[domain.MsgProvider], dblib.WithReadRetry())
 }
 */
 
@@ -362,8 +386,8 @@ func (ar *ApplicationRepository) ListApplicationsRepo(ctx context.Context, msgap
 
 	query = query.GroupBy("ma.application_id", "ma.application_name", "ma.status_cd").
 		OrderBy("ma.application_id").
-		Offset(meta.Skip * meta.Limit).
-		Limit(meta.Limit)
+		Offset(uint64(meta.Skip * meta.Limit)).
+		Limit(uint64(meta.Limit))
 
 	// Convert query to SQL string and log it
 	sql, args, err := query.ToSql()
@@ -374,7 +398,7 @@ func (ar *ApplicationRepository) ListApplicationsRepo(ctx context.Context, msgap
 	log.Debug(ctx, "SQL Query in ListApplicationsRepo: %s, Args: %v", sql, args)
 
 	// Execute the query and collect the rows
-	collectedRows, err := dblib.SelectRows(ctx, ar.Db, query, pgx.RowToStructByNameLax[domain.MsgApplicationsGet])
+	collectedRows, err := dblib.SelectRows(ctx, ar.Db, query, pgx.RowToStructByNameLax[domain.MsgApplicationsGet], dblib.WithReadRetry())
 	if err != nil {
 		log.Error(ctx, "Error executing query in GetApplications repo function:  %s", err.Error())
 		return nil, err
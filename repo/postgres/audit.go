@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"MgApplication/core/audit"
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+
+	config "MgApplication/api-config"
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// auditChainLock is the transaction advisory lock serializing the appends to the audit chain,
+// so that every entry chains to the one committed before it
+const auditChainLock = 0x6d67617564697401
+
+// auditColumns are the columns of msg_admin_audit read into domain.AuditEntry
+var auditColumns = []string{"audit_id", "actor", "action", "entity_type", "entity_id", "diff", "correlation_id", "created_at", "prev_hash", "hash"}
+
+// auditRowTx returns the row of table matching where as a JSON object, null when there is none
+func auditRowTx(ctx context.Context, tx pgx.Tx, table string, where squirrel.Sqlizer) (json.RawMessage, error) {
+	query := dblib.Psql.Select("to_jsonb(t)").
+		From(table + " t").
+		Where(where).
+		Limit(1)
+	var row json.RawMessage
+	err := dblib.TxReturnRow(ctx, tx, query, pgx.RowTo[json.RawMessage], &row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return row, err
+}
+
+// auditRowsTx returns the rows of table, aliased alias in where, matching where as a JSON object
+// of the rows keyed by their column key, null when there are none
+func auditRowsTx(ctx context.Context, tx pgx.Tx, table string, alias string, key string, where squirrel.Sqlizer) (json.RawMessage, error) {
+	query := dblib.Psql.Select("jsonb_object_agg(" + alias + "." + key + "::text, to_jsonb(" + alias + "))").
+		From(table + " " + alias).
+		Where(where)
+	var rows json.RawMessage
+	err := dblib.TxReturnRow(ctx, tx, query, pgx.RowTo[json.RawMessage], &rows)
+	return rows, err
+}
+
+// appendAuditTx appends change to the audit log in tx, the transaction of the change, with the
+// caller of ctx as actor. The fields of audit.redactfields are redacted from the diff along with
+// the secret ones. The appends are serialized by auditChainLock until tx ends.
+func appendAuditTx(ctx context.Context, tx pgx.Tx, cfg *config.Config, change audit.Change) error {
+	diff, err := audit.NewRedactor(cfg.GetStringSlice("audit.redactfields")...).Diff(change.Before, change.After)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", auditChainLock); err != nil {
+		return err
+	}
+
+	last := dblib.Psql.Select("hash").
+		From("msg_admin_audit").
+		OrderBy("audit_id DESC").
+		Limit(1)
+	var prevHash string
+	if err := dblib.TxReturnRow(ctx, tx, last, pgx.RowTo[string], &prevHash); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	request := audit.RequestFrom(ctx)
+	entry := domain.AuditEntry{
+		Actor:         request.Actor,
+		Action:        change.Action,
+		EntityType:    change.EntityType,
+		EntityID:      change.EntityID,
+		Diff:          diff,
+		CorrelationID: request.CorrelationID,
+		// the precision of timestamptz, so that the entry read back hashes the same
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		PrevHash:  prevHash,
+	}
+	if entry.Hash, err = audit.ChainHash(entry); err != nil {
+		return err
+	}
+
+	query := dblib.Psql.Insert("msg_admin_audit").
+		Columns("actor", "action", "entity_type", "entity_id", "diff", "correlation_id", "created_at", "prev_hash", "hash").
+		Values(entry.Actor, entry.Action, entry.EntityType, entry.EntityID, string(entry.Diff), entry.CorrelationID, entry.CreatedAt, entry.PrevHash, entry.Hash)
+	return dblib.TxExec(ctx, tx, query)
+}
+
+// auditTemplateTx appends the change of the template with templateLocalID to the audit log in
+// tx, before being its row before the change, nil for a template created
+func auditTemplateTx(ctx context.Context, tx pgx.Tx, cfg *config.Config, action string, templateLocalID uint64, before json.RawMessage) error {
+	after, err := auditRowTx(ctx, tx, "msg_template", squirrel.Eq{"template_local_id": templateLocalID})
+	if err != nil {
+		return err
+	}
+	return appendAuditTx(ctx, tx, cfg, audit.Change{
+		Action:     action,
+		EntityType: domain.AuditEntityTemplate,
+		EntityID:   strconv.FormatUint(templateLocalID, 10),
+		Before:     before,
+		After:      after,
+	})
+}
+
+// auditApplicationTx appends the change of the application with applicationID to the audit log
+// in tx, before being its row before the change, nil for an application created. Its secret key
+// is redacted from the diff.
+func auditApplicationTx(ctx context.Context, tx pgx.Tx, cfg *config.Config, action string, applicationID uint64, before json.RawMessage) error {
+	after, err := auditRowTx(ctx, tx, "msg_application", squirrel.Eq{"application_id": applicationID})
+	if err != nil {
+		return err
+	}
+	return appendAuditTx(ctx, tx, cfg, audit.Change{
+		Action:     action,
+		EntityType: domain.AuditEntityApplication,
+		EntityID:   strconv.FormatUint(applicationID, 10),
+		Before:     before,
+		After:      after,
+	})
+}
+
+type AuditRepository struct {
+	Db  *dblib.DB
+	Cfg *config.Config
+}
+
+// NewAuditRepository creates a new admin audit log repository instance
+func NewAuditRepository(Db *dblib.DB, Cfg *config.Config) *AuditRepository {
+	return &AuditRepository{
+		Db,
+		Cfg,
+	}
+}
+
+// AppendAuditRepo appends change to the audit log in its own transaction, for the changes made
+// outside the database
+func (ar *AuditRepository) AppendAuditRepo(ctx context.Context, change audit.Change) error {
+
+	ctx, cancel := context.WithTimeout(ctx, ar.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	err := ar.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		return appendAuditTx(ctx, tx, ar.Cfg, change)
+	})
+	if err != nil {
+		log.Error(ctx, "Error appending to the audit log in AppendAudit repo function: %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// ListAuditLogRepo returns the page meta of the audit entries selected by filter, the latest
+// first
+func (ar *AuditRepository) ListAuditLogRepo(ctx context.Context, filter domain.AuditFilter, meta port.MetaDataRequest) ([]domain.AuditEntry, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, ar.Cfg.GetDuration("db.querytimeoutmed"))
+	defer cancel()
+
+	query := dblib.Psql.Select(auditColumns...).
+		From("msg_admin_audit").
+		OrderBy("audit_id DESC").
+		Offset(uint64(meta.Skip * meta.Limit)).
+		Limit(uint64(meta.Limit))
+	eq := squirrel.Eq{}
+	for column, value := range map[string]string{"actor": filter.Actor, "action": filter.Action, "entity_type": filter.EntityType, "entity_id": filter.EntityID} {
+		if value != "" {
+			eq[column] = value
+		}
+	}
+	if len(eq) > 0 {
+		query = query.Where(eq)
+	}
+	if filter.From != nil {
+		query = query.Where(squirrel.GtOrEq{"created_at": *filter.From})
+	}
+	if filter.To != nil {
+		query = query.Where(squirrel.Lt{"created_at": *filter.To})
+	}
+
+	entries, err := dblib.SelectRows(ctx, ar.Db, query, pgx.RowToStructByNameLax[domain.AuditEntry], dblib.WithReadRetry())
+	if err != nil {
+		log.Error(ctx, "Error executing query in ListAuditLog repo function: %s", err.Error())
+		return nil, err
+	}
+	return entries, nil
+}
+
+// VerifyAuditLogRepo walks the audit chain from its first entry and reports the first entry
+// that does not chain to the previous one
+func (ar *AuditRepository) VerifyAuditLogRepo(ctx context.Context) (domain.AuditVerification, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, ar.Cfg.GetDuration("audit.verifytimeout"))
+	defer cancel()
+
+	query := dblib.Psql.Select(auditColumns...).
+		From("msg_admin_audit")
+	verifier := &audit.Verifier{}
+	err := dblib.Iterate(ctx, ar.Db, query, "audit_id", ar.Cfg.GetInt("audit.verifybatchsize"), verifier.Verify)
+
+	var broken *audit.BrokenLinkError
+	if errors.As(err, &broken) {
+		log.Warn(ctx, "Audit chain broken at entry %d: %s", broken.AuditID, broken.Reason)
+		return domain.AuditVerification{Checked: verifier.Checked(), BrokenAt: &broken.AuditID, Reason: broken.Reason}, nil
+	}
+	if err != nil {
+		log.Error(ctx, "Error executing query in VerifyAuditLog repo function: %s", err.Error())
+		return domain.AuditVerification{}, err
+	}
+	return domain.AuditVerification{Verified: true, Checked: verifier.Checked()}, nil
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"MgApplication/core/audit"
+	"MgApplication/core/domain"
+
+	config "MgApplication/api-config"
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+type ConfigBundleRepository struct {
+	Db  *dblib.DB
+	Cfg *config.Config
+}
+
+// NewConfigBundleRepository creates a new configuration bundle repository instance
+func NewConfigBundleRepository(Db *dblib.DB, Cfg *config.Config) *ConfigBundleRepository {
+	return &ConfigBundleRepository{
+		Db,
+		Cfg,
+	}
+}
+
+// BundlePlanner decides the change of every entity of a bundle import from the configuration
+// in place
+type BundlePlanner func(current domain.ConfigBundle) (domain.BundleImportPlan, error)
+
+// ExportBundleRepo returns the applications and templates as a configuration bundle. Templates
+// without a DLT template id or whose application is unknown cannot be keyed and are left out.
+func (br *ConfigBundleRepository) ExportBundleRepo(ctx context.Context) (domain.ConfigBundle, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, br.Cfg.GetDuration("db.querytimeoutmed"))
+	defer cancel()
+
+	var bundle domain.ConfigBundle
+	err := br.Db.ReadTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		bundle, err = readBundle(ctx, tx)
+		return err
+	})
+	if err != nil {
+		log.Error(ctx, "Error executing query in ExportBundle repo function: %s", err.Error())
+		return domain.ConfigBundle{}, err
+	}
+	return bundle, nil
+}
+
+// ImportBundleRepo plans a bundle import with plan from the configuration in place and, unless
+// dryRun, applies the plan, in one transaction. The applications and templates are locked
+// against other writes meanwhile, so that the plan applied is the plan returned. An error of
+// plan aborts the import.
+func (br *ConfigBundleRepository) ImportBundleRepo(ctx context.Context, plan BundlePlanner, dryRun bool) (domain.BundleImportPlan, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, br.Cfg.GetDuration("db.querytimeoutmed"))
+	defer cancel()
+
+	var planned domain.BundleImportPlan
+	err := br.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "LOCK TABLE msg_application, msg_template IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+			return err
+		}
+		current, err := readBundle(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if planned, err = plan(current); err != nil {
+			return err
+		}
+		if dryRun {
+			return nil
+		}
+		if err := applyBundlePlan(ctx, tx, br.Cfg, planned); err != nil {
+			return err
+		}
+		// the plan lists the change of every entity, the secret keys of the applications created
+		// being left out of its JSON
+		after, err := json.Marshal(planned)
+		if err != nil {
+			return err
+		}
+		return appendAuditTx(ctx, tx, br.Cfg, audit.Change{
+			Action:     domain.AuditActionImport,
+			EntityType: domain.AuditEntityConfigBundle,
+			EntityID:   domain.AuditEntityIDBulk,
+			After:      after,
+		})
+	})
+	if err != nil {
+		log.Error(ctx, "Bundle import rolled back in ImportBundle repo function: %s", err.Error())
+		return domain.BundleImportPlan{}, err
+	}
+	return planned, nil
+}
+
+// readBundle reads the configuration in place, ordered by natural key
+func readBundle(ctx context.Context, tx pgx.Tx) (domain.ConfigBundle, error) {
+	bundle := domain.ConfigBundle{SchemaVersion: domain.ConfigBundleVersion, ExportedAt: time.Now().UTC()}
+
+	applications := dblib.Psql.Select("application_name", "COALESCE(request_type, '') AS request_type",
+		"COALESCE(status_cd, 0) = 1 AS status", "NULLIF(shadow_gateway, '') AS shadow_gateway").
+		From("msg_application").
+		Where(squirrel.NotEq{"application_name": nil}).
+		OrderBy("application_name")
+	if err := dblib.TxRows(ctx, tx, applications, pgx.RowToStructByNameLax[domain.BundleApplication], &bundle.Applications); err != nil {
+		return domain.ConfigBundle{}, err
+	}
+
+	templates := dblib.Psql.Select("mt.template_id", "ma.application_name", "COALESCE(mt.template_name, '') AS template_name",
+		"COALESCE(mt.template_format, '') AS template_format", "COALESCE(mt.sender_id, '') AS sender_id",
+		"COALESCE(mt.entity_id, '') AS entity_id", "COALESCE(mt.gateway, '') AS gateway",
+		"COALESCE(mt.message_type, '') AS message_type", "COALESCE(mt.status_cd, 0) = 1 AS status").
+		From("msg_template mt").
+		Join("msg_application ma ON ma.application_id::varchar = mt.application_id").
+		Where(squirrel.NotEq{"mt.template_id": nil}).
+		Where(squirrel.NotEq{"ma.application_name": nil}).
+		OrderBy("mt.template_id")
+	if err := dblib.TxRows(ctx, tx, templates, pgx.RowToStructByNameLax[domain.BundleTemplate], &bundle.Templates); err != nil {
+		return domain.ConfigBundle{}, err
+	}
+	return bundle, nil
+}
+
+// applyBundlePlan writes the created, updated and pruned entities of planned, the applications
+// first so that the templates find their application, and notifies the template changes
+func applyBundlePlan(ctx context.Context, tx pgx.Tx, cfg *config.Config, planned domain.BundleImportPlan) error {
+	for _, change := range planned.Applications {
+		app := change.Application
+		var query squirrel.Sqlizer
+		switch change.Action {
+		case domain.BundleCreated:
+			query = dblib.Psql.Insert("msg_application").
+				Columns("application_name", "request_type", "secret_key", "status_cd", "shadow_gateway").
+				Values(app.ApplicationName, app.RequestType, change.SecretKey, statusCode(app.Status), app.ShadowGateway)
+		case domain.BundleUpdated:
+			query = dblib.Psql.Update("msg_application").
+				Set("status_cd", statusCode(app.Status)).
+				Set("shadow_gateway", app.ShadowGateway).
+				Set("updated_date", squirrel.Expr("CURRENT_TIMESTAMP")).
+				Where(squirrel.Eq{"application_name": app.ApplicationName})
+		case domain.BundlePruned:
+			query = dblib.Psql.Update("msg_application").
+				Set("status_cd", 0).
+				Set("updated_date", squirrel.Expr("CURRENT_TIMESTAMP")).
+				Where(squirrel.Eq{"application_name": app.ApplicationName})
+		default:
+			continue
+		}
+		if err := dblib.TxExec(ctx, tx, query); err != nil {
+			return err
+		}
+	}
+
+	var changed []string
+	for _, change := range planned.Templates {
+		tmpl := change.Template
+		var query squirrel.Sqlizer
+		switch change.Action {
+		case domain.BundleCreated:
+			query = dblib.Psql.Insert("msg_template").
+				Columns("application_id", "template_name", "template_format", "entity_id", "sender_id", "template_id", "gateway", "message_type", "status_cd").
+				// the nested select keeps ? placeholders, numbered by the insert
+				Select(squirrel.Select("application_id::varchar").
+					Column("?::varchar, ?::varchar, ?::varchar, ?::varchar, ?::varchar, ?::varchar, ?::varchar, ?::int4", tmpl.TemplateName, tmpl.TemplateFormat, tmpl.EntityID, tmpl.SenderID, tmpl.TemplateID, tmpl.Gateway, tmpl.MessageType, statusCode(tmpl.Status)).
+					From("msg_application").
+					Where(squirrel.Eq{"application_name": tmpl.ApplicationName}))
+		case domain.BundleUpdated:
+			query = dblib.Psql.Update("msg_template").
+				Set("template_name", tmpl.TemplateName).
+				Set("template_format", tmpl.TemplateFormat).
+				Set("gateway", tmpl.Gateway).
+				Set("message_type", tmpl.MessageType).
+				Set("status_cd", statusCode(tmpl.Status)).
+				Where(squirrel.Eq{"template_id": tmpl.TemplateID})
+		case domain.BundlePruned:
+			query = dblib.Psql.Update("msg_template").
+				Set("status_cd", 0).
+				Where(squirrel.Eq{"template_id": tmpl.TemplateID})
+		default:
+			continue
+		}
+		if err := dblib.TxExec(ctx, tx, query); err != nil {
+			return err
+		}
+		changed = append(changed, tmpl.TemplateID)
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	return notifyTemplateChanges(ctx, tx, cfg, squirrel.Eq{"template_id": changed})
+}
+
+// statusCode returns the status_cd of an active or inactive entity
+func statusCode(active bool) int {
+	if active {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"time"
+
+	"MgApplication/core/clock"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// businessHour truncates a timestamptz expression to the hour in the business time zone. IST
+// hours start at half past the UTC hour, so a business hour never straddles two business days.
+func businessHour(expr string) string {
+	return "date_trunc('hour', " + expr + ", '" + clock.ZoneName + "')"
+}
+
+// businessDate returns the business day of a timestamptz expression as a date
+func businessDate(expr string) string {
+	return "(" + expr + " AT TIME ZONE '" + clock.ZoneName + "')::date"
+}
+
+// businessDays matches the values of column within the business days from fromDate to toDate,
+// both included
+func businessDays(column string, fromDate time.Time, toDate time.Time) squirrel.Sqlizer {
+	start, end := clock.DayRange(fromDate, toDate)
+	return squirrel.Expr(column+" >= ? AND "+column+" < ?", start, end)
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBusinessDays(t *testing.T) {
+	day := time.Date(2024, 8, 27, 0, 0, 0, 0, time.UTC)
+	sql, args, err := businessDays("created_date", day, day).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "created_date >= ? AND created_date < ?", sql)
+	// 18:29:59Z on the 26th is the 26th in IST, 18:30:00Z the 27th
+	assert.Equal(t, []any{time.Date(2024, 8, 26, 18, 30, 0, 0, time.UTC), time.Date(2024, 8, 27, 18, 30, 0, 0, time.UTC)}, args)
+}
+
+func TestBusinessHourAndDate(t *testing.T) {
+	assert.Equal(t, "date_trunc('hour', created_date, 'Asia/Kolkata')", businessHour("created_date"))
+	assert.Equal(t, "(s.hour AT TIME ZONE 'Asia/Kolkata')::date", businessDate("s.hour"))
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+
+	"MgApplication/core/domain"
+
+	config "MgApplication/api-config"
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// CacheChangeChannel is the channel the changes of the cached entities are notified on, the
+// payload being a domain.CacheChange
+const CacheChangeChannel = "msg_cache_change"
+
+// notifyTemplateChanges notifies the change of the templates matching where within tx, so that
+// the notifications are delivered when tx commits and dropped when it rolls back. Templates
+// whose DLT template id changes are notified before and after the change.
+func notifyTemplateChanges(ctx context.Context, tx pgx.Tx, cfg *config.Config, where squirrel.Sqlizer) error {
+	if !cfg.GetBool("db.notify.enabled") {
+		return nil
+	}
+	query := dblib.Psql.Select("pg_notify(?, ? || template_id)", CacheChangeChannel, domain.CacheEntityTemplate+":").
+		From("msg_template").
+		Where(where).
+		Where(squirrel.NotEq{"template_id": nil})
+	return dblib.TxExec(ctx, tx, query)
+}
+
+type CacheNotifyRepository struct {
+	Db  *dblib.DB
+	Cfg *config.Config
+}
+
+// NewCacheNotifyRepository creates a new cache change notification repository instance
+func NewCacheNotifyRepository(Db *dblib.DB, Cfg *config.Config) *CacheNotifyRepository {
+	return &CacheNotifyRepository{
+		Db,
+		Cfg,
+	}
+}
+
+// ListenCacheChangesRepo listens to the cache changes on a connection of its own, outside the
+// pool, calling connected once listening and changed for every change notified. It returns when
+// ctx is done or the connection fails, the changes notified meanwhile being lost.
+func (cr *CacheNotifyRepository) ListenCacheChangesRepo(ctx context.Context, connected func(), changed func(domain.CacheChange)) error {
+	conn, err := pgx.ConnectConfig(ctx, cr.Db.Config().ConnConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{CacheChangeChannel}.Sanitize()); err != nil {
+		return err
+	}
+	connected()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		change, ok := domain.ParseCacheChange(notification.Payload)
+		if !ok {
+			log.Warn(ctx, "Ignoring the cache change %q notified on %s", notification.Payload, CacheChangeChannel)
+			continue
+		}
+		changed(change)
+	}
+}
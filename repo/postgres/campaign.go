@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"MgApplication/core/domain"
+
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// recipientUploadColumns are the msg_bulk_file columns of a domain.RecipientUpload
+var recipientUploadColumns = []string{
+	"rtrim(reference_id) AS reference_id",
+	"upload_token",
+	"upload_offset",
+	"upload_rows",
+	"rows_accepted",
+	"rows_rejected",
+	"rows_duplicate",
+	"upload_completed",
+	"COALESCE(upload_rejects, '[]'::jsonb) AS upload_rejects",
+}
+
+// StartRecipientUploadRepo starts a new upload of the recipients of the campaign (bulk upload)
+// referenceID under uploadToken, discarding the recipients and progress of any previous upload.
+// It returns pgx.ErrNoRows when the campaign does not exist.
+func (cr *MgApplicationRepository) StartRecipientUploadRepo(ctx context.Context, referenceID string, uploadToken string) (domain.RecipientUpload, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("campaign.upload.batchtimeout"))
+	defer cancel()
+
+	query := dblib.Psql.Update("msg_bulk_file").
+		SetMap(map[string]any{
+			"upload_token":     uploadToken,
+			"upload_offset":    0,
+			"upload_rows":      0,
+			"rows_accepted":    0,
+			"rows_rejected":    0,
+			"rows_duplicate":   0,
+			"upload_completed": false,
+			"upload_rejects":   nil,
+			"updated_time":     squirrel.Expr("CURRENT_TIMESTAMP"),
+		}).
+		Where(squirrel.Eq{"reference_id": referenceID}).
+		Suffix("RETURNING " + strings.Join(recipientUploadColumns, ", "))
+
+	var upload domain.RecipientUpload
+	err := cr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := dblib.TxReturnRow(ctx, tx, query, pgx.RowToStructByNameLax[domain.RecipientUpload], &upload); err != nil {
+			return err
+		}
+		return dblib.TxExec(ctx, tx, dblib.Psql.Delete("msg_campaign_recipient").Where(squirrel.Eq{"reference_id": referenceID}))
+	})
+	if err != nil {
+		log.Error(ctx, "Error executing query in StartRecipientUpload repo function: %s", err.Error())
+		return domain.RecipientUpload{}, err
+	}
+	return upload, nil
+}
+
+// FetchRecipientUploadRepo returns the progress of the recipient upload uploadToken of the
+// campaign referenceID, pgx.ErrNoRows when it is not the current upload of the campaign
+func (cr *MgApplicationRepository) FetchRecipientUploadRepo(ctx context.Context, referenceID string, uploadToken string) (domain.RecipientUpload, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Select(recipientUploadColumns...).
+		From("msg_bulk_file").
+		Where(squirrel.Eq{"reference_id": referenceID, "upload_token": uploadToken})
+
+	upload, err := dblib.SelectOne(ctx, cr.Db, query, pgx.RowToStructByNameLax[domain.RecipientUpload])
+	if err != nil {
+		log.Error(ctx, "Error executing query in FetchRecipientUpload repo function: %s", err.Error())
+		return domain.RecipientUpload{}, err
+	}
+	return upload, nil
+}
+
+// SaveRecipientBatchRepo adds a batch of recipients to the upload and saves its progress in one
+// transaction, so that an interrupted upload resumes after the last batch saved. The recipients
+// are sent in one round trip as a batch limited to campaign.upload.batchtimeout. Numbers already
+// uploaded are ignored and counted as duplicates. The progress is only saved if the upload is
+// still at fromOffset, otherwise nothing is saved and pgx.ErrNoRows is returned, as another
+// request has continued or restarted the upload. It returns the number of recipients added.
+func (cr *MgApplicationRepository) SaveRecipientBatchRepo(ctx context.Context, upload domain.RecipientUpload, fromOffset int64, mobileNumbers []string) (int64, error) {
+
+	timeout := cr.Cfg.GetDuration("campaign.upload.batchtimeout")
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	batch := dblib.NewTimedBatch(int(timeout.Milliseconds()))
+	var added []string
+	if len(mobileNumbers) > 0 {
+		insert := dblib.Psql.Insert("msg_campaign_recipient").
+			Columns("reference_id", "mobile_number").
+			Select(dblib.Psql.Select().Column("?::bpchar", upload.ReferenceID).Column("unnest(?::varchar[])", mobileNumbers)).
+			Suffix("ON CONFLICT (reference_id, mobile_number) DO NOTHING RETURNING mobile_number")
+		if err := dblib.TimedQueueReturn(batch, insert, pgx.RowTo[string], &added); err != nil {
+			return 0, err
+		}
+	}
+
+	var inserted int64
+	err := cr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		if batch.Len() > 0 {
+			if err := tx.SendBatch(ctx, batch.Batch).Close(); err != nil {
+				return err
+			}
+		}
+		inserted = int64(len(added))
+
+		progress := map[string]any{
+			"upload_offset":    upload.Offset,
+			"upload_rows":      upload.Rows,
+			"rows_accepted":    upload.RowsAccepted + inserted,
+			"rows_rejected":    upload.RowsRejected,
+			"rows_duplicate":   upload.RowsDuplicate + int64(len(mobileNumbers)) - inserted,
+			"upload_completed": upload.Completed,
+			"upload_rejects":   upload.Rejects,
+			"updated_time":     squirrel.Expr("CURRENT_TIMESTAMP"),
+		}
+		if upload.Completed {
+			progress["no_of_sms_uploaded"] = upload.RowsAccepted + inserted
+		}
+		update := dblib.Psql.Update("msg_bulk_file").
+			SetMap(progress).
+			Where(squirrel.Eq{"reference_id": upload.ReferenceID, "upload_token": upload.UploadToken, "upload_offset": fromOffset, "upload_completed": false}).
+			Suffix("RETURNING upload_offset")
+
+		// pgx.ErrNoRows when the upload is no longer at fromOffset
+		var savedOffset int64
+		return dblib.TxReturnRow(ctx, tx, update, pgx.RowTo[int64], &savedOffset)
+	})
+	if err != nil {
+		log.Error(ctx, "Error executing query in SaveRecipientBatch repo function: %s", err.Error())
+		return 0, err
+	}
+	return inserted, nil
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	dblib "MgApplication/api-db"
+)
+
+// readDB returns the db of a read query of ctx: the replica picked by reads, honouring the
+// consistency token of ctx, or primary when the reads are not routed
+func readDB(ctx context.Context, reads *dblib.ReadRouter, primary *dblib.DB) *dblib.DB {
+	if reads == nil {
+		return primary
+	}
+	return reads.DB(ctx)
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"MgApplication/core/domain"
+
+	config "MgApplication/api-config"
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// DiagnosticQuery is a query of a repository registered for the EXPLAIN of the admin
+// diagnostics. Build returns the query with sample parameters, so that only the queries the
+// repositories run can be explained, never SQL coming from a request.
+type DiagnosticQuery struct {
+	Name        string
+	Description string
+	Build       func() squirrel.Sqlizer
+}
+
+var (
+	diagnosticQueriesMu sync.RWMutex
+	diagnosticQueries   = make(map[string]DiagnosticQuery)
+)
+
+// registerDiagnosticQuery registers the query built by build under name, from the init function
+// of the repository running it. Registering a name twice panics.
+func registerDiagnosticQuery(name string, description string, build func() squirrel.Sqlizer) {
+	diagnosticQueriesMu.Lock()
+	defer diagnosticQueriesMu.Unlock()
+	if _, ok := diagnosticQueries[name]; ok {
+		panic("diagnostic query " + name + " registered twice")
+	}
+	diagnosticQueries[name] = DiagnosticQuery{Name: name, Description: description, Build: build}
+}
+
+// LookupDiagnosticQuery returns the query registered under name
+func LookupDiagnosticQuery(name string) (DiagnosticQuery, bool) {
+	diagnosticQueriesMu.RLock()
+	defer diagnosticQueriesMu.RUnlock()
+	query, ok := diagnosticQueries[name]
+	return query, ok
+}
+
+// DiagnosticQueryNames returns the names of the registered queries, sorted
+func DiagnosticQueryNames() []string {
+	diagnosticQueriesMu.RLock()
+	defer diagnosticQueriesMu.RUnlock()
+	names := make([]string, 0, len(diagnosticQueries))
+	for name := range diagnosticQueries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// errExplainRollback ends the transaction of an EXPLAIN, which is never committed
+var errExplainRollback = errors.New("explain transaction rolled back")
+
+type DiagnosticsRepository struct {
+	Db  *dblib.DB
+	Cfg *config.Config
+}
+
+// NewDiagnosticsRepository creates a new diagnostics repository instance
+func NewDiagnosticsRepository(Db *dblib.DB, Cfg *config.Config) *DiagnosticsRepository {
+	return &DiagnosticsRepository{
+		Db,
+		Cfg,
+	}
+}
+
+// ExplainQueryRepo runs EXPLAIN (ANALYZE, BUFFERS) of query with its sample parameters and
+// returns the plan. ANALYZE executes the query, so it runs in a transaction that is always rolled
+// back, writes included, with a local statement_timeout of timeout.
+func (dr *DiagnosticsRepository) ExplainQueryRepo(ctx context.Context, query DiagnosticQuery, timeout time.Duration) (domain.QueryPlan, error) {
+
+	// the statement timeout ends the query first, the context only guards the connection
+	ctx, cancel := context.WithTimeout(ctx, timeout+dr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	sql, args, err := query.Build().ToSql()
+	if err != nil {
+		log.Error(ctx, "Error building diagnostic query %s in ExplainQuery repo function: %s", query.Name, err.Error())
+		return domain.QueryPlan{}, err
+	}
+	plan := domain.QueryPlan{
+		Name:               query.Name,
+		Description:        query.Description,
+		Query:              sql,
+		Args:               args,
+		StatementTimeoutMs: timeout.Milliseconds(),
+	}
+
+	var output []byte
+	err = dr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT set_config('statement_timeout', $1, true)", strconv.FormatInt(timeout.Milliseconds(), 10)); err != nil {
+			return err
+		}
+		if err := tx.QueryRow(ctx, "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) "+sql, args...).Scan(&output); err != nil {
+			return err
+		}
+		return errExplainRollback
+	})
+	// anything but errExplainRollback itself, a failed rollback included, fails the EXPLAIN
+	if err != errExplainRollback {
+		log.Error(ctx, "Error explaining diagnostic query %s in ExplainQuery repo function: %s", query.Name, err.Error())
+		return domain.QueryPlan{}, err
+	}
+
+	var explained []struct {
+		Plan          json.RawMessage `json:"Plan"`
+		PlanningTime  float64         `json:"Planning Time"`
+		ExecutionTime float64         `json:"Execution Time"`
+	}
+	if err := json.Unmarshal(output, &explained); err != nil || len(explained) != 1 {
+		log.Error(ctx, "Unexpected EXPLAIN output of diagnostic query %s in ExplainQuery repo function: %s", query.Name, output)
+		return domain.QueryPlan{}, fmt.Errorf("unexpected EXPLAIN output of %s", query.Name)
+	}
+	plan.PlanningTimeMs = explained[0].PlanningTime
+	plan.ExecutionTimeMs = explained[0].ExecutionTime
+	plan.Plan = explained[0].Plan
+	return plan, nil
+}
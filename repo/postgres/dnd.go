@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"MgApplication/core/audit"
+	"MgApplication/core/domain"
+
+	config "MgApplication/api-config"
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type DNDRepository struct {
+	Db  *dblib.DB
+	Cfg *config.Config
+}
+
+// NewDNDRepository creates a new DND registry repository instance
+func NewDNDRepository(Db *dblib.DB, Cfg *config.Config) *DNDRepository {
+	return &DNDRepository{
+		Db,
+		Cfg,
+	}
+}
+
+// CheckDND returns the numbers registered in the locally imported DND registry
+func (dr *DNDRepository) CheckDND(ctx context.Context, mobileNumbers []string) (map[string]bool, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, dr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Select("mobile_number").
+		From("msg_dnd_registry").
+		Where("mobile_number = ANY(?)", mobileNumbers)
+
+	registered, err := dblib.SelectRows(ctx, dr.Db, query, pgx.RowTo[string], dblib.WithReadRetry())
+	if err != nil {
+		log.Error(ctx, "Error executing query in CheckDND repo function: %s", err.Error())
+		return nil, err
+	}
+
+	dnd := make(map[string]bool, len(registered))
+	for _, mobileNumber := range registered {
+		dnd[mobileNumber] = true
+	}
+	return dnd, nil
+}
+
+// ImportDNDRepo adds the numbers to the local DND registry, ignoring numbers already present.
+// With replace set, the existing registry is cleared first in the same transaction, so that
+// numbers removed from the NCPR list stop being skipped. It returns the number of rows added.
+func (dr *DNDRepository) ImportDNDRepo(ctx context.Context, mobileNumbers []string, replace bool) (int64, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, dr.Cfg.GetDuration("sms.dnd.importtimeout"))
+	defer cancel()
+
+	query := dblib.Psql.Insert("msg_dnd_registry").
+		Columns("mobile_number").
+		Select(dblib.Psql.Select().Column("unnest(?::varchar[])", mobileNumbers)).
+		Suffix("ON CONFLICT (mobile_number) DO NOTHING")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var imported int64
+	err = dr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		if replace {
+			if err := dblib.TxExec(ctx, tx, dblib.Psql.Delete("msg_dnd_registry")); err != nil {
+				return err
+			}
+		}
+		tag, err := tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return err
+		}
+		imported = tag.RowsAffected()
+		// the numbers themselves are not audited, only the size of the import
+		after, err := json.Marshal(map[string]any{"replace": replace, "mobile_numbers": len(mobileNumbers), "imported": imported})
+		if err != nil {
+			return err
+		}
+		return appendAuditTx(ctx, tx, dr.Cfg, audit.Change{
+			Action:     domain.AuditActionImport,
+			EntityType: domain.AuditEntityDNDRegistry,
+			EntityID:   domain.AuditEntityIDBulk,
+			After:      after,
+		})
+	})
+	if err != nil {
+		log.Error(ctx, "Error executing insert query in ImportDND repo function: %s", err.Error())
+		return 0, err
+	}
+	return imported, nil
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+
+	config "MgApplication/api-config"
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+type ErrorSampleRepository struct {
+	Db  *dblib.DB
+	Cfg *config.Config
+}
+
+// NewErrorSampleRepository creates a new error sample repository instance
+func NewErrorSampleRepository(Db *dblib.DB, Cfg *config.Config) *ErrorSampleRepository {
+	return &ErrorSampleRepository{
+		Db,
+		Cfg,
+	}
+}
+
+// SaveErrorSamplesRepo stores a batch of error samples in one insert
+func (er *ErrorSampleRepository) SaveErrorSamplesRepo(ctx context.Context, samples []domain.ErrorSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, er.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Insert("msg_error_sample").
+		Columns("error_id", "route", "method", "status", "correlation_id", "request_summary", "panicked", "stack", "created_at")
+	for _, sample := range samples {
+		query = query.Values(sample.ErrorID, sample.Route, sample.Method, sample.Status, sample.CorrelationID, sample.RequestSummary, sample.Panicked, sample.Stack, sample.CreatedAt)
+	}
+
+	_, err := dblib.Insert(ctx, er.Db, query)
+	if err != nil {
+		log.Error(ctx, "Error executing query in SaveErrorSamples repo function: %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// ListErrorSamplesRepo returns the error samples selected by filter, the latest first
+func (er *ErrorSampleRepository) ListErrorSamplesRepo(ctx context.Context, filter domain.ErrorSampleFilter, meta port.MetaDataRequest) ([]domain.ErrorSample, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, er.Cfg.GetDuration("db.querytimeoutmed"))
+	defer cancel()
+
+	query := errorSamplesQuery(filter, meta)
+	samples, err := dblib.SelectRows(ctx, er.Db, query, pgx.RowToStructByNameLax[domain.ErrorSample], dblib.WithReadRetry())
+	if err != nil {
+		log.Error(ctx, "Error executing query in ListErrorSamples repo function: %s", err.Error())
+		return nil, err
+	}
+	return samples, nil
+}
+
+// errorSamplesQuery selects the page meta of the error samples selected by filter, the latest first
+func errorSamplesQuery(filter domain.ErrorSampleFilter, meta port.MetaDataRequest) squirrel.SelectBuilder {
+	query := dblib.Psql.Select("error_id", "route", "method", "status", "COALESCE(correlation_id, '') AS correlation_id",
+		"COALESCE(request_summary, '') AS request_summary", "panicked", "stack", "created_at").
+		From("msg_error_sample").
+		OrderBy("created_at DESC", "sample_id DESC").
+		Offset(uint64(meta.Skip * meta.Limit)).
+		Limit(uint64(meta.Limit))
+	if filter.Route != "" {
+		query = query.Where(squirrel.Eq{"route": filter.Route})
+	}
+	if filter.From != nil {
+		query = query.Where(squirrel.GtOrEq{"created_at": *filter.From})
+	}
+	if filter.To != nil {
+		query = query.Where(squirrel.Lt{"created_at": *filter.To})
+	}
+	return query
+}
+
+func init() {
+	registerDiagnosticQuery("error_samples", "Error samples of a route in the last day, the first page",
+		func() squirrel.Sqlizer {
+			from := time.Now().AddDate(0, 0, -1)
+			return errorSamplesQuery(domain.ErrorSampleFilter{Route: "/v1/sms-request", From: &from}, port.MetaDataRequest{Limit: 10})
+		})
+}
+
+// PurgeErrorSamplesRepo deletes the error samples older than retention
+func (er *ErrorSampleRepository) PurgeErrorSamplesRepo(ctx context.Context, retention time.Duration) (int64, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, er.Cfg.GetDuration("db.querytimeoutmed"))
+	defer cancel()
+
+	query := dblib.Psql.Delete("msg_error_sample").
+		Where("created_at < CURRENT_TIMESTAMP - make_interval(secs => ?)", retention.Seconds())
+
+	tag, err := dblib.Delete(ctx, er.Db, query)
+	if err != nil {
+		log.Error(ctx, "Error executing delete query in PurgeErrorSamples repo function: %s", err.Error())
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
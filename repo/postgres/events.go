@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+
+	"MgApplication/core/clock"
+	"MgApplication/core/domain"
+
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// FetchApplicationProgressRepo counts the messages the application sent in the current business day by
+// delivery outcome.
+// Messages count as sent once their gateway accepted them.
+func (cr *MgApplicationRepository) FetchApplicationProgressRepo(ctx context.Context, applicationID string) (domain.DeliveryProgress, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("db.querytimeoutmed"))
+	defer cancel()
+
+	query := dblib.Psql.Select(
+		"COUNT(*) FILTER (WHERE status IN ('submitted', 'delivered', 'failed', 'partially_delivered')) AS sent",
+		"COUNT(*) FILTER (WHERE status IN ('delivered', 'partially_delivered')) AS delivered",
+		"COUNT(*) FILTER (WHERE status = 'failed') AS failed",
+	).
+		From("msg_request").
+		Where(squirrel.Eq{"application_id": applicationID}).
+		Where(squirrel.GtOrEq{"created_date": clock.BusinessDay(clock.Now())})
+
+	progress, err := dblib.SelectOne(ctx, cr.Db, query, pgx.RowToStructByNameLax[domain.DeliveryProgress])
+	if err != nil {
+		log.Error(ctx, "Error executing query in FetchApplicationProgress repo function: %s", err.Error())
+		return domain.DeliveryProgress{}, err
+	}
+	return progress, nil
+}
+
+// FetchCampaignProgressRepo returns the progress of the bulk upload identified by referenceID.
+// Bulk uploads only track sent and failed counts.
+func (cr *MgApplicationRepository) FetchCampaignProgressRepo(ctx context.Context, referenceID string) (domain.DeliveryProgress, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Select(
+		"COALESCE(no_of_sms_sent, 0) AS sent",
+		"0::int8 AS delivered",
+		"COALESCE(no_of_sms_failed, 0) AS failed",
+	).
+		From("msg_bulk_file").
+		Where(squirrel.Eq{"reference_id": referenceID})
+
+	progress, err := dblib.SelectOne(ctx, cr.Db, query, pgx.RowToStructByNameLax[domain.DeliveryProgress])
+	if err != nil {
+		log.Error(ctx, "Error executing query in FetchCampaignProgress repo function: %s", err.Error())
+		return domain.DeliveryProgress{}, err
+	}
+	return progress, nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"MgApplication/core/audit"
+	"MgApplication/core/domain"
+
+	config "MgApplication/api-config"
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+type GatewayCodeRepository struct {
+	Db  *dblib.DB
+	Cfg *config.Config
+}
+
+// NewGatewayCodeRepository creates a new gateway response code dictionary repository instance
+func NewGatewayCodeRepository(Db *dblib.DB, Cfg *config.Config) *GatewayCodeRepository {
+	return &GatewayCodeRepository{
+		Db,
+		Cfg,
+	}
+}
+
+// gatewayCodeColumns are the columns of msg_gateway_code read into domain.GatewayCode
+var gatewayCodeColumns = []string{"gateway", "code", "description", "severity", "recommended_action", "updated_date"}
+
+// ListGatewayCodesRepo returns the dictionary entries, of one gateway when gateway is set
+func (gr *GatewayCodeRepository) ListGatewayCodesRepo(ctx context.Context, gateway string) ([]domain.GatewayCode, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, gr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Select(gatewayCodeColumns...).
+		From("msg_gateway_code").
+		OrderBy("gateway", "code")
+	if gateway != "" {
+		query = query.Where(squirrel.Eq{"gateway": gateway})
+	}
+
+	codes, err := dblib.SelectRows(ctx, gr.Db, query, pgx.RowToStructByNameLax[domain.GatewayCode], dblib.WithReadRetry())
+	if err != nil {
+		log.Error(ctx, "Error executing query in ListGatewayCodes repo function: %s", err.Error())
+		return nil, err
+	}
+	return codes, nil
+}
+
+// auditGatewayCodeTx appends the change of the dictionary entry of gateway and code to the audit
+// log in tx, before being the entry before the change, nil for an entry added
+func auditGatewayCodeTx(ctx context.Context, tx pgx.Tx, cfg *config.Config, action string, gateway string, code string, before json.RawMessage) error {
+	after, err := auditRowTx(ctx, tx, "msg_gateway_code", squirrel.Eq{"gateway": gateway, "code": code})
+	if err != nil {
+		return err
+	}
+	return appendAuditTx(ctx, tx, cfg, audit.Change{
+		Action:     action,
+		EntityType: domain.AuditEntityGatewayCode,
+		EntityID:   gateway + "/" + code,
+		Before:     before,
+		After:      after,
+	})
+}
+
+// UpsertGatewayCodeRepo adds a dictionary entry or replaces the entry of the same gateway and code
+func (gr *GatewayCodeRepository) UpsertGatewayCodeRepo(ctx context.Context, code domain.GatewayCode) (domain.GatewayCode, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, gr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Insert("msg_gateway_code").
+		Columns("gateway", "code", "description", "severity", "recommended_action").
+		Values(code.Gateway, code.Code, code.Description, code.Severity, code.RecommendedAction).
+		Suffix(`ON CONFLICT (gateway, code) DO UPDATE SET description = EXCLUDED.description, severity = EXCLUDED.severity,
+			recommended_action = EXCLUDED.recommended_action, updated_date = CURRENT_TIMESTAMP`).
+		Suffix("RETURNING " + strings.Join(gatewayCodeColumns, ", "))
+
+	var saved domain.GatewayCode
+	err := gr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		before, err := auditRowTx(ctx, tx, "msg_gateway_code", squirrel.Eq{"gateway": code.Gateway, "code": code.Code})
+		if err != nil {
+			return err
+		}
+		if err := dblib.TxReturnRow(ctx, tx, query, pgx.RowToStructByNameLax[domain.GatewayCode], &saved); err != nil {
+			return err
+		}
+		action := domain.AuditActionUpdate
+		if before == nil {
+			action = domain.AuditActionCreate
+		}
+		return auditGatewayCodeTx(ctx, tx, gr.Cfg, action, code.Gateway, code.Code, before)
+	})
+	if err != nil {
+		log.Error(ctx, "Error executing query in UpsertGatewayCode repo function: %s", err.Error())
+		return domain.GatewayCode{}, err
+	}
+	return saved, nil
+}
+
+// DeleteGatewayCodeRepo removes a dictionary entry and reports whether it existed
+func (gr *GatewayCodeRepository) DeleteGatewayCodeRepo(ctx context.Context, gateway string, code string) (bool, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, gr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Delete("msg_gateway_code").
+		Where(squirrel.Eq{"gateway": gateway, "code": code})
+
+	var deleted bool
+	err := gr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		before, err := auditRowTx(ctx, tx, "msg_gateway_code", squirrel.Eq{"gateway": gateway, "code": code})
+		if err != nil || before == nil {
+			return err
+		}
+		deleted = true
+		if err := dblib.TxExec(ctx, tx, query); err != nil {
+			return err
+		}
+		return auditGatewayCodeTx(ctx, tx, gr.Cfg, domain.AuditActionDelete, gateway, code, before)
+	})
+	if err != nil {
+		log.Error(ctx, "Error executing query in DeleteGatewayCode repo function: %s", err.Error())
+		return false, err
+	}
+	return deleted, nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	neturl "net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -35,7 +36,6 @@ func NewMgApplicationRepository(Db *dblib.DB, Cfg *config.Config) *MgApplication
 }
 func CallAPI(url string, method string, headers map[string]string, params map[string]interface{}) (map[string]interface{}, error) {
 
-	// fmt.Print(params)
 	// tr := &http.Transport{
 	// 	TLSClientConfig: &tls.Config{
 	// 		MinVersion:         tls.VersionTLS12,
@@ -67,7 +67,6 @@ func CallAPI(url string, method string, headers map[string]string, params map[st
 		return nil, err
 	}
 
-	// fmt.Print(response)
 	var responseBody map[string]interface{}
 	err = json.Unmarshal(response.Body(), &responseBody)
 	if err != nil {
@@ -75,12 +74,21 @@ func CallAPI(url string, method string, headers map[string]string, params map[st
 	}
 	if errorCode, exists := responseBody["error_code"]; exists {
 		errorMessage := responseBody["message"].(string)
-		fmt.Printf("Error: Code %v - %v\n", errorCode, errorMessage)
+		log.Error(nil, "API %s answered with error code %v: %s", redactedURL(url), errorCode, errorMessage)
 		return map[string]interface{}{}, errors.New(errorMessage)
 	}
 
 	return responseBody, nil
 }
+
+// redactedURL returns rawURL with the password of its user info masked, for the logs
+func redactedURL(rawURL string) string {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return "<invalid url>"
+	}
+	return u.Redacted()
+}
 func ConvertMapToStringMap(params map[string]interface{}) map[string]string {
 	stringParams := make(map[string]string)
 	for key, value := range params {
@@ -100,8 +108,7 @@ func interfaceToString(value interface{}) string {
 	}
 }
 func (cr *MgApplicationRepository) SendMsgToKafka(gctx *context.Context, url string, schema string, msgreq *domain.MsgRequest) (map[string]interface{}, error) {
-	fmt.Println("kafka url is:", url)
-	fmt.Println("kafka schema is:", schema)
+	log.Debug(*gctx, "Kafka url is : %s, schema is : %s", redactedURL(url), schema)
 	// Define Headers
 	headers := map[string]string{
 		"Content-Type": "application/vnd.kafka.avro.v2+json",
@@ -109,7 +116,7 @@ func (cr *MgApplicationRepository) SendMsgToKafka(gctx *context.Context, url str
 	}
 	schemaint64, err := strconv.Atoi(schema)
 	if err != nil {
-		fmt.Println("Error:", err)
+		log.Error(*gctx, "Invalid Kafka schema id %q: %s", schema, err.Error())
 		return map[string]interface{}{}, err
 	}
 	// Define Payload
@@ -118,16 +125,18 @@ func (cr *MgApplicationRepository) SendMsgToKafka(gctx *context.Context, url str
 		"records": []map[string]interface{}{
 			{
 				"value": map[string]interface{}{
-					"reqid":          msgreq.RequestID,
-					"application_id": msgreq.ApplicationID,
-					"facility_id":    msgreq.FacilityID,
-					"priority":       msgreq.Priority,
-					"message_text":   msgreq.MessageText,
-					"sender_id":      msgreq.SenderID,
-					"mobile_numbers": msgreq.MobileNumbers,
-					"entity_id":      msgreq.EntityId,
-					"template_id":    msgreq.TemplateID,
-					"message_type":   msgreq.MessageType,
+					"reqid": msgreq.RequestID,
+					// the ID the request was given when received, the consumer stores it with it
+					"communication_id": msgreq.CommunicationID,
+					"application_id":   msgreq.ApplicationID,
+					"facility_id":      msgreq.FacilityID,
+					"priority":         msgreq.Priority,
+					"message_text":     msgreq.MessageText,
+					"sender_id":        msgreq.SenderID,
+					"mobile_numbers":   msgreq.MobileNumbers,
+					"entity_id":        msgreq.EntityId,
+					"template_id":      msgreq.TemplateID,
+					"message_type":     msgreq.MessageType,
 				},
 			},
 		},
@@ -136,12 +145,45 @@ func (cr *MgApplicationRepository) SendMsgToKafka(gctx *context.Context, url str
 	// Call the API
 	response, err := CallAPI(url, "POST", headers, params)
 	if err != nil {
-		fmt.Println("Error calling API:", err)
+		log.Error(*gctx, "Error calling the Kafka REST proxy: %s", err.Error())
 		return map[string]interface{}{}, err
 	}
-	fmt.Println("Response from callAPI:", response)
+	log.Debug(*gctx, "Response from the Kafka REST proxy: %v", response)
 	return response, nil
 }
+
+// nullIfEmpty stores an empty optional string as NULL
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// transformationsArray returns the transformers applied to the request text for a text[] column,
+// NULL when there are none
+func transformationsArray(transformations []string) any {
+	if len(transformations) == 0 {
+		return nil
+	}
+	return transformations
+}
+
+// communicationIDColumn selects the communication id a request is stored with: the one it was
+// given when received, or one generated as the column default does for the callers leaving it
+// to the database
+func communicationIDColumn(communicationID string) squirrel.Sqlizer {
+	return squirrel.Expr("COALESCE(NULLIF(?, ''), msggateway.generate_random_string(20)) as communication_id", communicationID)
+}
+
+// metadataJSON returns the request metadata for a jsonb column, NULL when there is none
+func metadataJSON(metadata map[string]string) any {
+	if len(metadata) == 0 {
+		return nil
+	}
+	return metadata
+}
+
 func (cr *MgApplicationRepository) SaveMsgRequestTx(gctx *context.Context, msgapp *domain.MsgRequest) (*domain.MsgRequest, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), cr.Cfg.GetDuration("db.querytimeoutmed"))
@@ -149,81 +191,132 @@ func (cr *MgApplicationRepository) SaveMsgRequestTx(gctx *context.Context, msgap
 
 	log.Debug(nil, "Inside SaveMsgRequest Repo function")
 
+	TxDB := cr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		return cr.saveMsgRequest(ctx, tx, msgapp)
+	})
+	if TxDB != nil {
+		log.Error(ctx, "Transaction rolling back in SaveMsgRequest repo function:  %s", TxDB.Error())
+		return &domain.MsgRequest{}, TxDB
+	}
+	return msgapp, nil
+}
+
+// WithMsgTx runs fn in a transaction storing a message request or the gateway response to it,
+// with SaveMsgRequestInTx and SaveResponseInTx. Gateways are never called inside it.
+func (cr *MgApplicationRepository) WithMsgTx(fn func(tx pgx.Tx) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cr.Cfg.GetDuration("db.querytimeoutmed"))
+	defer cancel()
+
+	return cr.Db.WithTx(ctx, fn)
+}
+
+// SaveMsgRequestInTx stores msgapp in tx, opened with WithMsgTx, and sets its gateway and
+// communication id
+func (cr *MgApplicationRepository) SaveMsgRequestInTx(tx pgx.Tx, msgapp *domain.MsgRequest) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), cr.Cfg.GetDuration("db.querytimeoutmed"))
+	defer cancel()
+
+	return cr.saveMsgRequest(ctx, tx, msgapp)
+}
+
+// SaveResponseInTx stores the gateway response to a request stored in the same tx
+func (cr *MgApplicationRepository) SaveResponseInTx(tx pgx.Tx, msgRsp *domain.MsgResponse) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), cr.Cfg.GetDuration("db.querytimeoutmed"))
+	defer cancel()
+
+	return cr.saveResponse(ctx, tx, msgRsp)
+}
+
+// SaveMsgRequestGatewayInTx sets the gateway of a request stored in the same tx to the one it is
+// sent through, when it was rerouted once stored
+func (cr *MgApplicationRepository) SaveMsgRequestGatewayInTx(tx pgx.Tx, msgapp *domain.MsgRequest) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), cr.Cfg.GetDuration("db.querytimeoutmed"))
+	defer cancel()
+
+	query := dblib.Psql.Update("msg_request").
+		Set("gateway", msgapp.Gateway).
+		Where(squirrel.Eq{"communication_id": msgapp.CommunicationID})
+	if err := dblib.TxExec(ctx, tx, query); err != nil {
+		log.Error(ctx, "Error executing update query in SaveMsgRequestGateway repo function:  %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// saveMsgRequest stores msgapp as pending, once its application and template are checked
+func (cr *MgApplicationRepository) saveMsgRequest(ctx context.Context, tx pgx.Tx, msgapp *domain.MsgRequest) error {
 	var Counter domain.Counter
 	var msgreq1 domain.MsgRequest
 
-	TxDB := cr.Db.WithTx(ctx, func(tx pgx.Tx) error {
-		//checking whether applicaiton exists in the database
-		query1 := dblib.Psql.Select("COUNT(1) as count").
-			From("msg_application").
-			Where(squirrel.Eq{"application_id": msgapp.ApplicationID})
-		err := dblib.TxReturnRow(ctx, tx, query1, pgx.RowToStructByNameLax[domain.Counter], &Counter)
-		if err != nil {
-			log.Error(ctx, "Error checking existence of application in msg_application table in SaveMsgRequest: %s", err.Error())
-			return err
-		}
-		if Counter.Count == 0 {
-			return errors.New("application does not exists")
-		}
-		//checking whether application and templateid combination available or not
-		// query2 := dblib.Psql.Select("COUNT(1) as count").
-		// 	From("msg_template").
-		// 	Where(squirrel.And{squirrel.Eq{"ANY(string_to_array(application_id,','))": msgapp.ApplicationID}, squirrel.Eq{"template_id": msgapp.TemplateID}})
+	//checking whether applicaiton exists in the database
+	query1 := dblib.Psql.Select("COUNT(1) as count").
+		From("msg_application").
+		Where(squirrel.Eq{"application_id": msgapp.ApplicationID})
+	err := dblib.TxReturnRow(ctx, tx, query1, pgx.RowToStructByNameLax[domain.Counter], &Counter)
+	if err != nil {
+		log.Error(ctx, "Error checking existence of application in msg_application table in SaveMsgRequest: %s", err.Error())
+		return err
+	}
+	if Counter.Count == 0 {
+		return errors.New("application does not exists")
+	}
+	//checking whether application and templateid combination available or not
+	// query2 := dblib.Psql.Select("COUNT(1) as count").
+	// 	From("msg_template").
+	// 	Where(squirrel.And{squirrel.Eq{"ANY(string_to_array(application_id,','))": msgapp.ApplicationID}, squirrel.Eq{"template_id": msgapp.TemplateID}})
 
-		query2 := dblib.Psql.Select("COUNT(1) AS count").
-			From("msg_template").
-			Where(
-				squirrel.Expr(
-					"EXISTS (SELECT 1 FROM unnest(string_to_array(application_id, ',')) AS app_id WHERE app_id = ?)",
-					msgapp.ApplicationID,
-				),
-			).
-			Where("template_id = ?", msgapp.TemplateID)
-		err = dblib.TxReturnRow(ctx, tx, query2, pgx.RowToStructByNameLax[domain.Counter], &Counter)
-		if err != nil {
-			log.Error(ctx, "Error checking whether a template registered for an application in SaveMsgRequest function: %s", err.Error())
-			return err
-		}
-		if Counter.Count == 0 {
-			return errors.New("application and template are not mapped. Contact CEPT")
-		}
-		numbers := strings.Split(msgapp.MobileNumbers, ",")
-		var mobileNumbers []int64
-		for _, numStr := range numbers {
-			num, err := strconv.ParseInt(numStr, 10, 64)
-			if err != nil {
-				log.Error(ctx, "Error converting %s to int64: %v\n", numStr, err)
-				continue
-			}
-			mobileNumbers = append(mobileNumbers, num)
-		}
-		// Check if data already exists
-		// Insert into msg_request and retrieve the gateway
-		query3 := dblib.Psql.Insert("msg_request").
-			Columns("gateway", "application_id", "facility_id", "message_text", "sender_id", "entity_id", "template_id", "status", "priority", "mobile_number").
-			Select(dblib.Psql.Select("mt.gateway").
-				Column(squirrel.Expr("? as application_id, ? as facility_id, ? as message_text, ? as sender_id, ? as entity_id, ? as template_id, ? as status, ? as priority, ? as mobile_number",
-					msgapp.ApplicationID, msgapp.FacilityID, msgapp.MessageText, msgapp.SenderID, msgapp.EntityId, msgapp.TemplateID, "pending", msgapp.Priority, mobileNumbers)).
-				From("msg_template mt").
-				Where(squirrel.Eq{"mt.template_id": msgapp.TemplateID})).
-			Suffix(`RETURNING "request_id", "communication_id", "gateway"`)
-
-		msgreq1, err = dblib.InsertReturning(ctx, cr.Db, query3, pgx.RowToStructByNameLax[domain.MsgRequest])
+	query2 := dblib.Psql.Select("COUNT(1) AS count").
+		From("msg_template").
+		Where(
+			squirrel.Expr(
+				"EXISTS (SELECT 1 FROM unnest(string_to_array(application_id, ',')) AS app_id WHERE app_id = ?)",
+				msgapp.ApplicationID,
+			),
+		).
+		Where("template_id = ?", msgapp.TemplateID)
+	err = dblib.TxReturnRow(ctx, tx, query2, pgx.RowToStructByNameLax[domain.Counter], &Counter)
+	if err != nil {
+		log.Error(ctx, "Error checking whether a template registered for an application in SaveMsgRequest function: %s", err.Error())
+		return err
+	}
+	if Counter.Count == 0 {
+		return errors.New("application and template are not mapped. Contact CEPT")
+	}
+	numbers := strings.Split(msgapp.MobileNumbers, ",")
+	var mobileNumbers []int64
+	for _, numStr := range numbers {
+		num, err := strconv.ParseInt(numStr, 10, 64)
 		if err != nil {
-			log.Error(ctx, "error executing insert query in SaveMsgRequest repo function: %w", err)
-			return err
+			log.Error(ctx, "Error converting %s to int64: %v\n", numStr, err)
+			continue
 		}
+		mobileNumbers = append(mobileNumbers, num)
+	}
+	// Check if data already exists
+	// Insert into msg_request and retrieve the gateway
+	query3 := dblib.Psql.Insert("msg_request").
+		Columns("gateway", "application_id", "facility_id", "message_text", "sender_id", "entity_id", "template_id", "status", "priority", "mobile_number", "client_reference", "metadata", "transformations", "communication_id").
+		Select(dblib.Psql.Select().
+			Column(squirrel.Expr("COALESCE(NULLIF(?, ''), mt.gateway) as gateway", msgapp.RouteGateway)).
+			Column(squirrel.Expr("? as application_id, ? as facility_id, ? as message_text, ? as sender_id, ? as entity_id, ? as template_id, ? as status, ? as priority, ? as mobile_number, ? as client_reference, ?::jsonb as metadata, ?::text[] as transformations",
+				msgapp.ApplicationID, msgapp.FacilityID, msgapp.MessageText, msgapp.SenderID, msgapp.EntityId, msgapp.TemplateID, "pending", msgapp.Priority, mobileNumbers, nullIfEmpty(msgapp.ClientReference), metadataJSON(msgapp.Metadata), transformationsArray(msgapp.Transformations))).
+			Column(communicationIDColumn(msgapp.CommunicationID)).
+			From("msg_template mt").
+			Where(squirrel.Eq{"mt.template_id": msgapp.TemplateID})).
+		Suffix(`RETURNING "request_id", "communication_id", "gateway"`)
 
-		return nil
-	})
-	if TxDB != nil {
-		log.Error(ctx, "Transaction rolling back in SaveMsgRequest repo function:  %s", TxDB.Error())
-		return &domain.MsgRequest{}, TxDB
+	err = dblib.TxReturnRow(ctx, tx, query3, pgx.RowToStructByNameLax[domain.MsgRequest], &msgreq1)
+	if err != nil {
+		log.Error(ctx, "error executing insert query in SaveMsgRequest repo function: %w", err)
+		return err
 	}
 	msgapp.Gateway = msgreq1.Gateway
 	msgapp.CommunicationID = msgreq1.CommunicationID
 	msgapp.RequestID = msgreq1.RequestID
-	return msgapp, nil
+	return nil
 }
 
 func (cr *MgApplicationRepository) SaveMsgRequest(gctx *context.Context, msgapp *domain.MsgRequest) (*domain.MsgRequest, error) {
@@ -240,7 +333,7 @@ func (cr *MgApplicationRepository) SaveMsgRequest(gctx *context.Context, msgapp
 	query1 := dblib.Psql.Select("COUNT(1) as count").
 		From("msg_application").
 		Where(squirrel.Eq{"application_id": msgapp.ApplicationID})
-	Counter, err := dblib.SelectOne(ctx, cr.Db, query1, pgx.RowToStructByNameLax[domain.Counter])
+	Counter, err := dblib.SelectOne(ctx, cr.Db, query1, pgx.RowToStructByNameLax[domain.Counter], dblib.WithReadRetry())
 	// err := dblib.ReturnRow(ctx, cr.Db, query1, pgx.RowToStructByNameLax[domain.Counter], &Counter)
 	if err != nil {
 		log.Error(ctx, "Error checking existence of application in msg_application table in SaveMsgRequest: %s", err.Error())
@@ -261,7 +354,7 @@ func (cr *MgApplicationRepository) SaveMsgRequest(gctx *context.Context, msgapp
 		).
 		Where("template_id = ?", msgapp.TemplateID)
 	// err = dblib.ReturnRow(ctx, cr.Db, query2, pgx.RowToStructByNameLax[domain.Counter], &Counter)
-	Counter, err = dblib.SelectOne(ctx, cr.Db, query2, pgx.RowToStructByNameLax[domain.Counter])
+	Counter, err = dblib.SelectOne(ctx, cr.Db, query2, pgx.RowToStructByNameLax[domain.Counter], dblib.WithReadRetry())
 	if err != nil {
 		log.Error(ctx, "Error checking whether a template registered for an application in SaveMsgRequest function: %s", err.Error())
 		return &domain.MsgRequest{}, err
@@ -283,10 +376,12 @@ func (cr *MgApplicationRepository) SaveMsgRequest(gctx *context.Context, msgapp
 
 	// Insert into msg_request and retrieve the gateway
 	query3 := dblib.Psql.Insert("msg_request").
-		Columns("gateway", "application_id", "facility_id", "message_text", "sender_id", "entity_id", "template_id", "status", "priority", "mobile_number").
-		Select(dblib.Psql.Select("mt.gateway").
-			Column(squirrel.Expr("? as application_id, ? as facility_id, ? as message_text, ? as sender_id, ? as entity_id, ? as template_id, ? as status, ? as priority, ? as mobile_number",
-				msgapp.ApplicationID, msgapp.FacilityID, msgapp.MessageText, msgapp.SenderID, msgapp.EntityId, msgapp.TemplateID, "pending", msgapp.Priority, mobileNumbers)).
+		Columns("gateway", "application_id", "facility_id", "message_text", "sender_id", "entity_id", "template_id", "status", "priority", "mobile_number", "client_reference", "metadata", "transformations", "communication_id").
+		Select(dblib.Psql.Select().
+			Column(squirrel.Expr("COALESCE(NULLIF(?, ''), mt.gateway) as gateway", msgapp.RouteGateway)).
+			Column(squirrel.Expr("? as application_id, ? as facility_id, ? as message_text, ? as sender_id, ? as entity_id, ? as template_id, ? as status, ? as priority, ? as mobile_number, ? as client_reference, ?::jsonb as metadata, ?::text[] as transformations",
+				msgapp.ApplicationID, msgapp.FacilityID, msgapp.MessageText, msgapp.SenderID, msgapp.EntityId, msgapp.TemplateID, "pending", msgapp.Priority, mobileNumbers, nullIfEmpty(msgapp.ClientReference), metadataJSON(msgapp.Metadata), transformationsArray(msgapp.Transformations))).
+			Column(communicationIDColumn(msgapp.CommunicationID)).
 			From("msg_template mt").
 			Where(squirrel.Eq{"mt.template_id": msgapp.TemplateID})).
 		Suffix(`RETURNING "request_id", "communication_id", "gateway"`)
@@ -302,6 +397,8 @@ func (cr *MgApplicationRepository) SaveMsgRequest(gctx *context.Context, msgapp
 	return msgapp, nil
 }
 
+// GetGateway resolves the gateway, entity id and message type of the template of msgreq, the
+// gateway msgreq is routed through, RouteGateway, taking precedence over the one of the template
 func (cr *MgApplicationRepository) GetGateway(gctx *context.Context, msgreq *domain.MsgRequest) (*domain.MsgRequest, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), cr.Cfg.GetDuration("db.querytimeoutlow"))
@@ -336,8 +433,13 @@ func (cr *MgApplicationRepository) GetGateway(gctx *context.Context, msgreq *dom
 		return &domain.MsgRequest{}, TxDB
 	}
 	msgreq.RequestID = msgreq1.RequestID
-	msgreq.CommunicationID = msgreq1.CommunicationID
+	if msgreq.CommunicationID == "" {
+		msgreq.CommunicationID = msgreq1.CommunicationID
+	}
 	msgreq.Gateway = msgreq1.Gateway
+	if msgreq.RouteGateway != "" {
+		msgreq.Gateway = msgreq.RouteGateway
+	}
 	msgreq.EntityId = msgreq1.EntityId
 	msgreq.MessageType = msgreq1.MessageType
 	return msgreq, nil
@@ -391,20 +493,7 @@ func (cr *MgApplicationRepository) SaveResponseTx(gctx *context.Context, msgRsp
 	defer cancel()
 
 	TxDB := cr.Db.WithTx(ctx, func(tx pgx.Tx) error {
-		query := dblib.Psql.Update("msg_request").
-			Set("status", "submitted").
-			Set("updated_date", squirrel.Expr("current_timestamp")).
-			Set("reference_id", msgRsp.ReferenceID).
-			Set("response_code", msgRsp.ResponseCode).
-			Set("response_message", msgRsp.ResponseText).
-			Set("complete_response", msgRsp.CompleteResponse).
-			Where(squirrel.Eq{"communication_id": msgRsp.CommunicationID})
-		err := dblib.TxExec(ctx, tx, query)
-		if err != nil {
-			log.Error(ctx, "Error executing update query in SaveResponse repo function:  %s", err.Error())
-			return err
-		}
-		return nil
+		return cr.saveResponse(ctx, tx, msgRsp)
 	})
 	if TxDB != nil {
 		log.Error(ctx, "Error initiating transaction in SaveResponse repo function:  %s", TxDB.Error())
@@ -418,6 +507,81 @@ func (cr *MgApplicationRepository) SaveResponse(gctx *context.Context, msgRsp *d
 	ctx, cancel := context.WithTimeout(context.Background(), cr.Cfg.GetDuration("db.querytimeoutmed"))
 	defer cancel()
 
+	err := cr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		return cr.saveResponse(ctx, tx, msgRsp)
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// FetchLatestResponseByCommunicationIDRepo returns the most recent gateway response stored for
+// the request communicationID, such as the response of its last retry or gateway fallback
+func (cr *MgApplicationRepository) FetchLatestResponseByCommunicationIDRepo(gctx *gin.Context, communicationID string) (domain.MsgResponse, error) {
+
+	ctx, cancel := context.WithTimeout(gctx.Request.Context(), cr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Select("communication_id", "reference_id", "response_code", "response_message", "created_at").
+		From("msg_response").
+		Where(squirrel.Eq{"communication_id": communicationID}).
+		OrderBy("created_at DESC", "response_id DESC").
+		Limit(1)
+
+	msgRsp, err := dblib.SelectOne(ctx, cr.Db, query, pgx.RowToStructByNameLax[domain.MsgResponse], dblib.WithReadRetry())
+	if err != nil {
+		log.Error(ctx, "Error executing query in FetchLatestResponseByCommunicationIDRepo function:  %s", err.Error())
+		return domain.MsgResponse{}, err
+	}
+	return msgRsp, nil
+}
+
+// FetchLatestResponsesByCommunicationIDsRepo returns the most recent gateway response stored for
+// each of the requests communicationIDs in a single query, keyed by communication id. The requests
+// without a stored response are absent from the result.
+func (cr *MgApplicationRepository) FetchLatestResponsesByCommunicationIDsRepo(gctx *gin.Context, communicationIDs []string) (map[string]domain.MsgResponse, error) {
+
+	ctx, cancel := context.WithTimeout(gctx.Request.Context(), cr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Select("communication_id", "reference_id", "response_code", "response_message", "created_at").
+		Options("DISTINCT ON (communication_id)").
+		From("msg_response").
+		Where(squirrel.Eq{"communication_id": communicationIDs}).
+		OrderBy("communication_id", "created_at DESC", "response_id DESC")
+
+	msgRsps, err := dblib.SelectRows(ctx, cr.Db, query, pgx.RowToStructByNameLax[domain.MsgResponse], dblib.WithReadRetry())
+	if err != nil {
+		log.Error(ctx, "Error executing query in FetchLatestResponsesByCommunicationIDsRepo function:  %s", err.Error())
+		return nil, err
+	}
+
+	latest := make(map[string]domain.MsgResponse, len(msgRsps))
+	for _, msgRsp := range msgRsps {
+		latest[strings.TrimSpace(msgRsp.CommunicationID)] = msgRsp
+	}
+	return latest, nil
+}
+
+// saveResponse stores the gateway response of a request. With the inline stats rollup, the
+// rollup moves the request recipients from their previous outcome to the new one, so that
+// saving a response again, such as after a gateway fallback, counts the request once.
+func (cr *MgApplicationRepository) saveResponse(ctx context.Context, tx pgx.Tx, msgRsp *domain.MsgResponse) error {
+	inline := statsRollupInline(cr.Cfg)
+
+	var previous []statsRow
+	if inline {
+		query := dblib.Psql.Select(statsRowColumns...).
+			From("msg_request").
+			Where(squirrel.Eq{"communication_id": msgRsp.CommunicationID}).
+			Suffix("FOR UPDATE")
+		if err := dblib.TxRows(ctx, tx, query, pgx.RowToStructByNameLax[statsRow], &previous); err != nil {
+			log.Error(ctx, "Error executing select query in SaveResponse repo function:  %s", err.Error())
+			return err
+		}
+	}
+
 	query := dblib.Psql.Update("msg_request").
 		Set("status", "submitted").
 		Set("updated_date", squirrel.Expr("current_timestamp")).
@@ -426,11 +590,42 @@ func (cr *MgApplicationRepository) SaveResponse(gctx *context.Context, msgRsp *d
 		Set("response_message", msgRsp.ResponseText).
 		Set("complete_response", msgRsp.CompleteResponse).
 		Where(squirrel.Eq{"communication_id": msgRsp.CommunicationID})
-
-	_, err := dblib.Update(ctx, cr.Db, query)
-	if err != nil {
+	if err := dblib.TxExec(ctx, tx, query); err != nil {
 		log.Error(ctx, "Error executing update query in SaveResponse repo function:  %s", err.Error())
-		return false, err
+		return err
 	}
-	return true, nil
+
+	// msg_request holds the last response only, msg_response keeps every response of the
+	// request. The raw response may hold mobile numbers and is only kept in msg_request, where
+	// privacy erasures clear it.
+	history := dblib.Psql.Insert("msg_response").
+		Columns("communication_id", "reference_id", "response_code", "response_message").
+		Values(msgRsp.CommunicationID, msgRsp.ReferenceID, msgRsp.ResponseCode, msgRsp.ResponseText)
+	if err := dblib.TxExec(ctx, tx, history); err != nil {
+		log.Error(ctx, "Error executing insert query in SaveResponse repo function:  %s", err.Error())
+		return err
+	}
+
+	accepted := statsAccepted(msgRsp.ReferenceID, msgRsp.ResponseCode)
+	for _, row := range previous {
+		var sent, failed, delivered int64
+		if row.Responded && row.Accepted {
+			sent -= row.Recipients
+		} else if row.Responded {
+			failed -= row.Recipients
+		}
+		if row.Delivered {
+			delivered -= row.Recipients
+		}
+		if accepted {
+			sent += row.Recipients
+		} else {
+			failed += row.Recipients
+		}
+		if err := incrementStatsHourly(ctx, tx, cr.Cfg, row, sent, failed, delivered); err != nil {
+			log.Error(ctx, "Error executing upsert query in SaveResponse repo function:  %s", err.Error())
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"MgApplication/core/domain"
+
+	config "MgApplication/api-config"
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+type OTPRepository struct {
+	Db  *dblib.DB
+	Cfg *config.Config
+}
+
+// NewOTPRepository creates a new OTP repository instance
+func NewOTPRepository(Db *dblib.DB, Cfg *config.Config) *OTPRepository {
+	return &OTPRepository{
+		Db,
+		Cfg,
+	}
+}
+
+// FetchOTPTemplateRepo fetches the active template registered against the given DLT template id
+func (otr *OTPRepository) FetchOTPTemplateRepo(ctx context.Context, templateID string) (domain.MaintainTemplate, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, otr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Select("template_local_id", "application_id", "template_name", "template_format", "sender_id", "entity_id", "template_id", "gateway", "message_type", "status_cd").
+		From("msg_template").
+		Where(squirrel.Eq{"template_id": templateID}).
+		Where(squirrel.Eq{"status_cd": 1})
+
+	template, err := dblib.SelectOne(ctx, otr.Db, query, pgx.RowToStructByNameLax[domain.MaintainTemplate], dblib.WithReadRetry())
+	if err != nil {
+		log.Error(ctx, "Error executing query in FetchOTPTemplate repo function: %s", err.Error())
+		return domain.MaintainTemplate{}, err
+	}
+	return template, nil
+}
+
+// CountRecentOTPRepo counts the OTPs generated for a mobile number within the given window
+func (otr *OTPRepository) CountRecentOTPRepo(ctx context.Context, mobileNumber string, window time.Duration) (int, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, otr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Select("COUNT(1) as count").
+		From("msg_otp").
+		Where(squirrel.Eq{"mobile_number": mobileNumber}).
+		Where("created_date >= CURRENT_TIMESTAMP - make_interval(secs => ?)", window.Seconds())
+
+	counter, err := dblib.SelectOne(ctx, otr.Db, query, pgx.RowToStructByNameLax[domain.Counter], dblib.WithReadRetry())
+	if err != nil {
+		log.Error(ctx, "Error executing query in CountRecentOTP repo function: %s", err.Error())
+		return 0, err
+	}
+	return counter.Count, nil
+}
+
+// CreateOTPRepo stores the hashed OTP along with its attempt limit; the OTP expires ttl after insertion
+func (otr *OTPRepository) CreateOTPRepo(ctx context.Context, otp *domain.MsgOTP, ttl time.Duration) (domain.MsgOTP, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, otr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Insert("msg_otp").
+		Columns("otp_reference", "application_id", "mobile_number", "otp_hash", "max_attempts", "expires_at").
+		Values(otp.OTPReference, otp.ApplicationID, otp.MobileNumber, otp.OTPHash, otp.MaxAttempts, squirrel.Expr("CURRENT_TIMESTAMP + make_interval(secs => ?)", ttl.Seconds())).
+		Suffix("RETURNING otp_id, otp_reference, application_id, mobile_number, attempts, max_attempts, expires_at, created_date")
+
+	msgotp, err := dblib.InsertReturning(ctx, otr.Db, query, pgx.RowToStructByNameLax[domain.MsgOTP])
+	if err != nil {
+		log.Error(ctx, "Error executing insert query in CreateOTP repo function: %s", err.Error())
+		return domain.MsgOTP{}, err
+	}
+	return msgotp, nil
+}
+
+// FetchOTPRepo fetches an unverified OTP by its reference
+func (otr *OTPRepository) FetchOTPRepo(ctx context.Context, otpReference string) (domain.MsgOTP, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, otr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Select("otp_id", "otp_reference", "application_id", "mobile_number", "otp_hash", "attempts", "max_attempts", "expires_at", "verified_date", "created_date", "expires_at < CURRENT_TIMESTAMP AS expired").
+		From("msg_otp").
+		Where(squirrel.Eq{"otp_reference": otpReference}).
+		Where(squirrel.Eq{"verified_date": nil})
+
+	msgotp, err := dblib.SelectOne(ctx, otr.Db, query, pgx.RowToStructByNameLax[domain.MsgOTP])
+	if err != nil {
+		log.Error(ctx, "Error executing query in FetchOTP repo function: %s", err.Error())
+		return domain.MsgOTP{}, err
+	}
+	return msgotp, nil
+}
+
+// ConsumeOTPAttemptRepo records a verification attempt. It reports false when the
+// attempt limit has already been reached, so concurrent verifications cannot exceed it.
+func (otr *OTPRepository) ConsumeOTPAttemptRepo(ctx context.Context, otpReference string) (bool, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, otr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Update("msg_otp").
+		Set("attempts", squirrel.Expr("attempts + 1")).
+		Where(squirrel.Eq{"otp_reference": otpReference}).
+		Where(squirrel.Eq{"verified_date": nil}).
+		Where("attempts < max_attempts")
+
+	tag, err := dblib.Update(ctx, otr.Db, query)
+	if err != nil {
+		log.Error(ctx, "Error executing update query in ConsumeOTPAttempt repo function: %s", err.Error())
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// MarkOTPVerifiedRepo marks the OTP as verified so that it cannot be reused
+func (otr *OTPRepository) MarkOTPVerifiedRepo(ctx context.Context, otpReference string) error {
+
+	ctx, cancel := context.WithTimeout(ctx, otr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Update("msg_otp").
+		Set("verified_date", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where(squirrel.Eq{"otp_reference": otpReference}).
+		Where(squirrel.Eq{"verified_date": nil})
+
+	tag, err := dblib.Update(ctx, otr.Db, query)
+	if err != nil {
+		log.Error(ctx, "Error executing update query in MarkOTPVerified repo function: %s", err.Error())
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// PurgeExpiredOTPRepo deletes OTP rows which expired more than retention ago
+func (otr *OTPRepository) PurgeExpiredOTPRepo(ctx context.Context, retention time.Duration) (int64, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, otr.Cfg.GetDuration("db.querytimeoutmed"))
+	defer cancel()
+
+	query := dblib.Psql.Delete("msg_otp").
+		Where("expires_at < CURRENT_TIMESTAMP - make_interval(secs => ?)", retention.Seconds())
+
+	tag, err := dblib.Delete(ctx, otr.Db, query)
+	if err != nil {
+		log.Error(ctx, "Error executing delete query in PurgeExpiredOTP repo function: %s", err.Error())
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DeleteOTPRepo deletes an unverified OTP, used when the OTP could not be sent so that it
+// neither counts towards the generation rate limit nor can be verified
+func (otr *OTPRepository) DeleteOTPRepo(ctx context.Context, otpReference string) error {
+
+	ctx, cancel := context.WithTimeout(ctx, otr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Delete("msg_otp").
+		Where(squirrel.Eq{"otp_reference": otpReference}).
+		Where(squirrel.Eq{"verified_date": nil})
+
+	if _, err := dblib.Delete(ctx, otr.Db, query); err != nil {
+		log.Error(ctx, "Error executing delete query in DeleteOTP repo function: %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// FetchActiveOTPRepo fetches the latest unverified, unexpired OTP generated for a mobile number
+// by an application
+func (otr *OTPRepository) FetchActiveOTPRepo(ctx context.Context, applicationID string, mobileNumber string) (domain.MsgOTP, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, otr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Select("otp_id", "otp_reference", "application_id", "mobile_number", "attempts", "max_attempts", "expires_at", "created_date").
+		From("msg_otp").
+		Where(squirrel.Eq{"application_id": applicationID}).
+		Where(squirrel.Eq{"mobile_number": mobileNumber}).
+		Where(squirrel.Eq{"verified_date": nil}).
+		Where("expires_at >= CURRENT_TIMESTAMP").
+		OrderBy("created_date DESC").
+		Limit(1)
+
+	msgotp, err := dblib.SelectOne(ctx, otr.Db, query, pgx.RowToStructByNameLax[domain.MsgOTP])
+	if err != nil {
+		log.Error(ctx, "Error executing query in FetchActiveOTP repo function: %s", err.Error())
+		return domain.MsgOTP{}, err
+	}
+	return msgotp, nil
+}
@@ -0,0 +1,311 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"MgApplication/core/audit"
+	"MgApplication/core/clock"
+	"MgApplication/core/domain"
+
+	config "MgApplication/api-config"
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// Statuses of msg_privacy_erasure
+const (
+	ErasureRunning   = "running"
+	ErasureCompleted = "completed"
+)
+
+type PrivacyRepository struct {
+	Db  *dblib.DB
+	Cfg *config.Config
+}
+
+// NewPrivacyRepository creates a new privacy erasure repository instance
+func NewPrivacyRepository(Db *dblib.DB, Cfg *config.Config) *PrivacyRepository {
+	return &PrivacyRepository{
+		Db,
+		Cfg,
+	}
+}
+
+// erasureTable describes how the rows of a mobile number are found and anonymized in one table
+type erasureTable struct {
+	name       string
+	key        string
+	dateColumn string
+	// numberArray is set for the bigint[] mobile_number columns, in which only the erased
+	// number is replaced and the other recipients are kept
+	numberArray bool
+	// textColumns hold message text and are cleared
+	textColumns []string
+	// rejectsColumn is a jsonb array of upload rejects, whose values holding the number are
+	// replaced with the tombstone
+	rejectsColumn string
+	// deleteRows is set for the tables whose rows hold nothing but the number, which are deleted
+	// rather than anonymized
+	deleteRows bool
+}
+
+// erasureTables are the tables holding mobile numbers, erased in this order. Raw responses and
+// delivery statuses are stored in msg_request along with the request, msg_response keeps no
+// raw response. Campaign recipients are
+// dated by the upload of their campaign.
+var erasureTables = []erasureTable{
+	{name: "msg_request", key: "request_id", dateColumn: "created_date", numberArray: true, textColumns: []string{"message_text", "complete_response"}},
+	{name: "msg_bulk_file", key: "file_id", dateColumn: "uploaded_time", numberArray: true, textColumns: []string{"test_msg"}, rejectsColumn: "upload_rejects"},
+	{name: "msg_campaign_recipient", key: "ctid", dateColumn: "(SELECT min(uploaded_time) FROM msg_bulk_file WHERE msg_bulk_file.reference_id = msg_campaign_recipient.reference_id)", deleteRows: true},
+	{name: "msg_otp", key: "otp_id", dateColumn: "created_date"},
+}
+
+// privacyErasureColumns are the columns of msg_privacy_erasure read into domain.PrivacyErasure
+var privacyErasureColumns = []string{"erasure_id", "tombstone", "from_date", "to_date", "requested_by", "status", "created_date", "completed_date"}
+
+// StartPrivacyErasureRepo records a new erasure along with the progress of every table. When
+// an earlier erasure of the same tombstone and period was interrupted, that erasure is
+// returned instead and the second result is true.
+func (pr *PrivacyRepository) StartPrivacyErasureRepo(ctx context.Context, erasure domain.PrivacyErasure) (domain.PrivacyErasure, bool, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, pr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	insert := dblib.Psql.Insert("msg_privacy_erasure").
+		Columns("tombstone", "from_date", "to_date", "requested_by").
+		Values(erasure.Tombstone, erasure.FromDate, erasure.ToDate, erasure.RequestedBy).
+		Suffix("ON CONFLICT (tombstone, COALESCE(from_date, '-infinity'::date), COALESCE(to_date, 'infinity'::date)) WHERE status = 'running' DO NOTHING")
+	running := dblib.Psql.Select(privacyErasureColumns...).
+		From("msg_privacy_erasure").
+		Where(squirrel.Eq{"tombstone": erasure.Tombstone, "status": ErasureRunning}).
+		Where("from_date IS NOT DISTINCT FROM ?::date AND to_date IS NOT DISTINCT FROM ?::date", erasure.FromDate, erasure.ToDate).
+		Suffix("FOR UPDATE")
+
+	var started domain.PrivacyErasure
+	var resumed bool
+	err := pr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		sql, args, err := insert.ToSql()
+		if err != nil {
+			return err
+		}
+		tag, err := tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return err
+		}
+		resumed = tag.RowsAffected() == 0
+
+		if err := dblib.TxReturnRow(ctx, tx, running, pgx.RowToStructByNameLax[domain.PrivacyErasure], &started); err != nil {
+			return err
+		}
+		if !resumed {
+			after, err := auditRowTx(ctx, tx, "msg_privacy_erasure", squirrel.Eq{"erasure_id": started.ErasureID})
+			if err != nil {
+				return err
+			}
+			err = appendAuditTx(ctx, tx, pr.Cfg, audit.Change{
+				Action:     domain.AuditActionCreate,
+				EntityType: domain.AuditEntityPrivacyErasure,
+				EntityID:   strconv.FormatUint(started.ErasureID, 10),
+				After:      after,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		tables := dblib.Psql.Insert("msg_privacy_erasure_table").
+			Columns("erasure_id", "table_name").
+			Suffix("ON CONFLICT (erasure_id, table_name) DO NOTHING")
+		for _, table := range erasureTables {
+			tables = tables.Values(started.ErasureID, table.name)
+		}
+		if err := dblib.TxExec(ctx, tx, tables); err != nil {
+			return err
+		}
+
+		progress := dblib.Psql.Select("table_name", "rows_affected", "completed").
+			From("msg_privacy_erasure_table").
+			Where(squirrel.Eq{"erasure_id": started.ErasureID})
+		return dblib.TxRows(ctx, tx, progress, pgx.RowToStructByName[domain.PrivacyErasureTable], &started.Tables)
+	})
+	if err != nil {
+		log.Error(ctx, "Error executing query in StartPrivacyErasure repo function: %s", err.Error())
+		return domain.PrivacyErasure{}, false, err
+	}
+	return started, resumed, nil
+}
+
+// RunPrivacyErasureRepo anonymizes the rows of mobileNumbers, the stored forms of the erased
+// number, in the tables not completed yet: the number is replaced with the tombstone and the
+// message text is cleared. Rows are erased in batches of privacy.erasure.batchsize, each
+// committed with the progress of its table, so an interrupted erasure is resumed by running
+// it again. The completed erasure is returned with the rows affected per table.
+func (pr *PrivacyRepository) RunPrivacyErasureRepo(ctx context.Context, erasure domain.PrivacyErasure, mobileNumbers []string) (domain.PrivacyErasure, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, pr.Cfg.GetDuration("privacy.erasure.timeout"))
+	defer cancel()
+
+	batchSize := pr.Cfg.GetInt("privacy.erasure.batchsize")
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	progress := make(map[string]*domain.PrivacyErasureTable, len(erasure.Tables))
+	for i := range erasure.Tables {
+		progress[erasure.Tables[i].TableName] = &erasure.Tables[i]
+	}
+
+	for _, table := range erasureTables {
+		tp, ok := progress[table.name]
+		if !ok {
+			return erasure, fmt.Errorf("privacy erasure %d has no progress for %s", erasure.ErasureID, table.name)
+		}
+		for !tp.Completed {
+			erased, err := pr.eraseBatch(ctx, erasure, table, mobileNumbers, batchSize)
+			if err != nil {
+				log.Error(ctx, "Error executing query in RunPrivacyErasure repo function on %s: %s", table.name, err.Error())
+				return erasure, err
+			}
+			tp.RowsAffected += erased
+			tp.Completed = erased < int64(batchSize)
+		}
+	}
+
+	complete := dblib.Psql.Update("msg_privacy_erasure").
+		Set("status", ErasureCompleted).
+		Set("completed_date", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where(squirrel.Eq{"erasure_id": erasure.ErasureID}).
+		Suffix("RETURNING " + strings.Join(privacyErasureColumns, ", "))
+
+	completed, err := dblib.UpdateReturning(ctx, pr.Db, complete, pgx.RowToStructByNameLax[domain.PrivacyErasure])
+	if err != nil {
+		log.Error(ctx, "Error executing update query in RunPrivacyErasure repo function: %s", err.Error())
+		return erasure, err
+	}
+	completed.Tables = erasure.Tables
+	return completed, nil
+}
+
+// eraseBatch anonymizes or deletes up to batchSize rows of table and adds them to the progress
+// of the table in the same transaction. Erased rows no longer match, so the next batch
+// continues where this one ended.
+func (pr *PrivacyRepository) eraseBatch(ctx context.Context, erasure domain.PrivacyErasure, table erasureTable, mobileNumbers []string, batchSize int) (int64, error) {
+
+	sql, args, err := erasureStatement(erasure, table, mobileNumbers, batchSize).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var erased int64
+	err = pr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return err
+		}
+		erased = tag.RowsAffected()
+
+		return dblib.TxExec(ctx, tx, dblib.Psql.Update("msg_privacy_erasure_table").
+			Set("rows_affected", squirrel.Expr("rows_affected + ?", erased)).
+			Set("completed", erased < int64(batchSize)).
+			Where(squirrel.Eq{"erasure_id": erasure.ErasureID, "table_name": table.name}))
+	})
+	return erased, err
+}
+
+// erasureStatement anonymizes or deletes up to batchSize rows of table matching the erasure
+func erasureStatement(erasure domain.PrivacyErasure, table erasureTable, mobileNumbers []string, batchSize int) squirrel.Sqlizer {
+	match := squirrel.And{erasureMatch(table, mobileNumbers)}
+	if erasure.FromDate != nil {
+		match = append(match, squirrel.Expr(table.dateColumn+" >= ?", clock.BusinessDay(*erasure.FromDate)))
+	}
+	if erasure.ToDate != nil {
+		match = append(match, squirrel.Expr(table.dateColumn+" < ?", clock.NextBusinessDay(*erasure.ToDate)))
+	}
+	keys := squirrel.Select(table.key).
+		From(table.name).
+		Where(match).
+		OrderBy(table.key).
+		Limit(uint64(batchSize)).
+		Suffix("FOR UPDATE SKIP LOCKED")
+
+	// The match is repeated so that only the matched rows are erased should a key not be unique
+	if table.deleteRows {
+		return dblib.Psql.Delete(table.name).
+			Where(squirrel.Expr(table.key+" IN (?)", keys)).
+			Where(match)
+	}
+	return dblib.Psql.Update(table.name).
+		SetMap(erasureSet(table, mobileNumbers, erasure.Tombstone)).
+		Where(squirrel.Expr(table.key+" IN (?)", keys)).
+		Where(match)
+}
+
+// erasureMatch matches the rows of table holding one of mobileNumbers
+func erasureMatch(table erasureTable, mobileNumbers []string) squirrel.Sqlizer {
+	var match squirrel.Sqlizer = squirrel.Expr("mobile_number = ANY(?::varchar[])", mobileNumbers)
+	if table.numberArray {
+		match = squirrel.Expr("mobile_number && ?::bigint[]", erasureNumbers(mobileNumbers))
+	}
+	if table.rejectsColumn != "" {
+		match = squirrel.Or{match, squirrel.Expr("EXISTS (SELECT 1 FROM jsonb_array_elements("+table.rejectsColumn+") AS reject WHERE reject->>'value' LIKE ANY(?::varchar[]))",
+			erasurePatterns(mobileNumbers))}
+	}
+	return match
+}
+
+// erasureSet replaces mobileNumbers with the tombstone and clears the message text of table
+func erasureSet(table erasureTable, mobileNumbers []string, tombstone int64) map[string]any {
+	set := make(map[string]any, len(table.textColumns)+1)
+	if table.numberArray {
+		column := "mobile_number"
+		var args []any
+		for _, number := range erasureNumbers(mobileNumbers) {
+			column = "array_replace(" + column + ", ?::bigint, ?::bigint)"
+			args = append(args, number, tombstone)
+		}
+		set["mobile_number"] = squirrel.Expr(column, args...)
+	} else {
+		set["mobile_number"] = strconv.FormatInt(tombstone, 10)
+	}
+	if table.rejectsColumn != "" {
+		set[table.rejectsColumn] = squirrel.Expr("(SELECT jsonb_agg(CASE WHEN reject->>'value' LIKE ANY(?::varchar[]) THEN jsonb_set(reject, '{value}', to_jsonb(?::text)) ELSE reject END ORDER BY n)"+
+			" FROM jsonb_array_elements("+table.rejectsColumn+") WITH ORDINALITY AS rejects(reject, n))",
+			erasurePatterns(mobileNumbers), strconv.FormatInt(tombstone, 10))
+	}
+	for _, column := range table.textColumns {
+		set[column] = nil
+	}
+	return set
+}
+
+// erasurePatterns returns the LIKE patterns of the rejected values holding one of mobileNumbers,
+// rejected values being stored as uploaded
+func erasurePatterns(mobileNumbers []string) []string {
+	patterns := make([]string, len(mobileNumbers))
+	for i, mobileNumber := range mobileNumbers {
+		patterns[i] = "%" + mobileNumber + "%"
+	}
+	return patterns
+}
+
+// erasureNumbers returns the distinct numeric values of mobileNumbers, as stored in the
+// bigint[] mobile_number columns
+func erasureNumbers(mobileNumbers []string) []int64 {
+	var numbers []int64
+	seen := make(map[int64]bool, len(mobileNumbers))
+	for _, mobileNumber := range mobileNumbers {
+		number, err := strconv.ParseInt(mobileNumber, 10, 64)
+		if err != nil || seen[number] {
+			continue
+		}
+		seen[number] = true
+		numbers = append(numbers, number)
+	}
+	return numbers
+}
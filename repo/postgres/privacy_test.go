@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"MgApplication/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// erasureTableNamed returns the entry of erasureTables for name
+func erasureTableNamed(t *testing.T, name string) erasureTable {
+	t.Helper()
+	for _, table := range erasureTables {
+		if table.name == name {
+			return table
+		}
+	}
+	t.Fatalf("%s is not erased", name)
+	return erasureTable{}
+}
+
+func TestErasureStatement(t *testing.T) {
+	erasure := domain.PrivacyErasure{ErasureID: 1, Tombstone: -123456789}
+	forms := []string{"9300000001", "919300000001"}
+
+	sql, args, err := erasureStatement(erasure, erasureTableNamed(t, "msg_campaign_recipient"), forms, 500).ToSql()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sql, "DELETE FROM msg_campaign_recipient WHERE ctid IN (SELECT ctid FROM msg_campaign_recipient"), sql)
+	assert.Contains(t, args, forms)
+
+	sql, args, err = erasureStatement(erasure, erasureTableNamed(t, "msg_bulk_file"), forms, 500).ToSql()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sql, "UPDATE msg_bulk_file SET"), sql)
+	assert.Contains(t, sql, "upload_rejects = (SELECT jsonb_agg(")
+	assert.Contains(t, sql, "OR EXISTS (SELECT 1 FROM jsonb_array_elements(upload_rejects)")
+	assert.Contains(t, args, []string{"%9300000001%", "%919300000001%"})
+	assert.Contains(t, args, "-123456789")
+}
@@ -326,8 +326,8 @@ func (pr *ProviderRepository) ListMessageProvidersRepo(gctx *gin.Context, msgreq
 	// Group and order the results
 	query = query.GroupBy("mp.provider_id", "mp.provider_name", "mp.short_name", "mp.status_cd").
 		OrderBy("mp.provider_id").
-		Offset(meta.Skip * meta.Limit).
-		Limit(meta.Limit)
+		Offset(uint64(meta.Skip * meta.Limit)).
+		Limit(uint64(meta.Limit))
 
 	// Execute the query and collect the rows
 	collectedRows, err := dblib.SelectRows(ctx, pr.Db, query, pgx.RowToStructByNameLax[domain.MsgProvider])
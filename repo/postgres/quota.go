@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+	"MgApplication/core/domain"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// ConsumeSendQuotaRepo counts count messages of kind sent by an application on the business day
+// starting at day, unless the messages sent that day would exceed limit. It returns the messages
+// counted that day, this request included when it fits, and whether it fits. The check and the
+// increment are one upsert, so concurrent requests never exceed limit together.
+func (cr *MgApplicationRepository) ConsumeSendQuotaRepo(ctx context.Context, applicationID string, kind string, day time.Time, count int64, limit int64) (int64, bool, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	if count <= limit {
+		query := dblib.Psql.Insert("msg_send_quota").
+			Columns("application_id", "kind", "day", "sent").
+			Values(applicationID, kind, day, count).
+			Suffix(`ON CONFLICT (application_id, kind, day) DO UPDATE SET sent = msg_send_quota.sent + EXCLUDED.sent
+				WHERE msg_send_quota.sent + EXCLUDED.sent <= ?`, limit).
+			Suffix("RETURNING sent AS count")
+
+		counter, err := dblib.InsertReturning(ctx, cr.Db, query, pgx.RowToStructByNameLax[domain.Counter])
+		if err == nil {
+			return int64(counter.Count), true, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Error(ctx, "Error executing query in ConsumeSendQuota repo function: %s", err.Error())
+			return 0, false, err
+		}
+	}
+
+	// over the limit, the messages counted so far are reported
+	query := dblib.Psql.Select("sent AS count").
+		From("msg_send_quota").
+		Where(squirrel.Eq{"application_id": applicationID, "kind": kind, "day": day})
+
+	counter, _, err := dblib.SelectOneOK(ctx, cr.Db, query, pgx.RowToStructByNameLax[domain.Counter])
+	if err != nil {
+		log.Error(ctx, "Error executing query in ConsumeSendQuota repo function: %s", err.Error())
+		return 0, false, err
+	}
+	return int64(counter.Count), false, nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"iter"
 	"time"
 
 	"MgApplication/core/domain"
@@ -29,19 +30,19 @@ func NewReportsRepository(Db *dblib.DB, Cfg *config.Config) *ReportsRepository {
 	}
 }
 
-func (cr *ReportsRepository) SMSSentStatusReportRepo(gctx *gin.Context, fromDate time.Time, toDate time.Time, meta port.MetaDataRequest) ([]domain.SMSReport, error) {
+// SMSSentStatusReportRepo returns the requests created between fromDate and toDate, one row per
+// recipient, with the description of the gateway response code from msg_gateway_code. A non-empty
+// clientReference only returns the requests carrying that reference.
+func (cr *ReportsRepository) SMSSentStatusReportRepo(gctx *gin.Context, fromDate time.Time, toDate time.Time, clientReference string, meta port.MetaDataRequest) ([]domain.SMSReport, error) {
 
 	ctx, cancel := context.WithTimeout(gctx.Request.Context(), cr.Cfg.GetDuration("db.querytimeoutmed"))
 	defer cancel()
 
 	var sms []domain.SMSReport
 	TxDB := cr.Db.WithTx(ctx, func(tx pgx.Tx) error {
-		query := dblib.Psql.Select("row_number() over(ORDER BY created_date ASC) as serial_number", "created_date", "communication_id", "application_id", "facility_id", "priority", "message_text", "unnest(mr.mobile_number) AS mobile_number", "gateway", "status").
-			From("msg_request mr").
-			Where(squirrel.And{squirrel.GtOrEq{"created_date::date": fromDate}, squirrel.LtOrEq{"created_date::date": toDate}}).
-			OrderBy("created_date ASC").
-			Offset(meta.Skip * meta.Limit).
-			Limit(meta.Limit)
+		query := smsSentStatusReportQuery(fromDate, toDate, clientReference).
+			Offset(uint64(meta.Skip * meta.Limit)).
+			Limit(uint64(meta.Limit))
 
 		err := dblib.TxRows(ctx, tx, query, pgx.RowToStructByNameLax[domain.SMSReport], &sms)
 		if err != nil {
@@ -57,6 +58,47 @@ func (cr *ReportsRepository) SMSSentStatusReportRepo(gctx *gin.Context, fromDate
 	return sms, nil
 }
 
+// StreamSMSSentStatusReportRepo yields all the rows of SMSSentStatusReportRepo, unpaginated, one
+// at a time as they are read, see dblib.SelectSeq. The query runs under db.querytimeoutstream.
+func (cr *ReportsRepository) StreamSMSSentStatusReportRepo(gctx *gin.Context, fromDate time.Time, toDate time.Time, clientReference string) iter.Seq2[domain.SMSReport, error] {
+	return func(yield func(domain.SMSReport, error) bool) {
+		ctx, cancel := streamQueryContext(gctx.Request.Context(), cr.Cfg)
+		defer cancel()
+		query := smsSentStatusReportQuery(fromDate, toDate, clientReference)
+		for report, err := range dblib.SelectSeq(ctx, cr.Db, query, pgx.RowToStructByNameLax[domain.SMSReport]) {
+			if err != nil {
+				log.Error(gctx, "Error in StreamSMSSentStatusReport repo function:  %s", err.Error())
+			}
+			if !yield(report, err) {
+				return
+			}
+		}
+	}
+}
+
+// smsSentStatusReportQuery selects the requests of the sent status report, one row per recipient
+func smsSentStatusReportQuery(fromDate time.Time, toDate time.Time, clientReference string) squirrel.SelectBuilder {
+	where := squirrel.And{businessDays("mr.created_date", fromDate, toDate)}
+	if clientReference != "" {
+		where = append(where, squirrel.Eq{"mr.client_reference": clientReference})
+	}
+	return dblib.Psql.Select("row_number() over(ORDER BY mr.created_date ASC) as serial_number", "mr.created_date", "mr.communication_id", "mr.application_id", "mr.facility_id", "mr.priority", "mr.message_text", "unnest(mr.mobile_number) AS mobile_number", "mr.gateway", "mr.status",
+		"mr.response_code", "gc.description AS response_description", "gc.severity AS response_severity", "gc.recommended_action", "mr.client_reference", "mr.metadata").
+		From("msg_request mr").
+		LeftJoin("msg_gateway_code gc ON gc.gateway = mr.gateway AND gc.code = mr.response_code").
+		Where(where).
+		OrderBy("mr.created_date ASC")
+}
+
+func init() {
+	registerDiagnosticQuery("sms_sent_status_report", "Sent status report of the last seven days, one row per recipient",
+		func() squirrel.Sqlizer {
+			now := time.Now()
+			return smsSentStatusReportQuery(now.AddDate(0, 0, -7), now, "")
+		})
+}
+
+// AppwiseSMSUsageReportRepo reads the daily usage per application from the hourly stats rollup
 func (cr *ReportsRepository) AppwiseSMSUsageReportRepo(gctx *gin.Context, fromDate time.Time, toDate time.Time, meta port.MetaDataRequest) ([]domain.SMSAggregateReport, error) {
 
 	ctx, cancel := context.WithTimeout(gctx.Request.Context(), cr.Cfg.GetDuration("db.querytimeoutmed"))
@@ -64,18 +106,16 @@ func (cr *ReportsRepository) AppwiseSMSUsageReportRepo(gctx *gin.Context, fromDa
 
 	var sms []domain.SMSAggregateReport
 	TxDB := cr.Db.WithTx(ctx, func(tx pgx.Tx) error {
-		query := dblib.Psql.Select("row_number() over(ORDER BY mr.created_date::date ASC) as serial_number",
+		query := dblib.Psql.Select("row_number() over(ORDER BY "+businessDate("s.hour")+" ASC) as serial_number",
 			"ma.application_name",
-			"mr.created_date::date",
-			"COUNT(*) AS total_sms, COUNT(CASE WHEN mr.status = 'submitted' THEN 1 END) AS success, COUNT(CASE WHEN mr.status <> 'submitted' THEN 1 END) AS failed").
-			From("msg_request mr").
-			Join("msg_application ma ON mr.application_id::int = ma.application_id").
-			Join("unnest(mr.mobile_number) AS mobile_number ON true").
-			Where(squirrel.And{squirrel.GtOrEq{"mr.created_date::date": fromDate}, squirrel.LtOrEq{"mr.created_date::date": toDate}}).
-			GroupBy("ma.application_name,mr.created_date::date").
-			OrderBy("mr.created_date::date ASC").
-			Offset(meta.Skip * meta.Limit).
-			Limit(meta.Limit)
+			businessDate("s.hour")+" AS created_date",
+			"SUM(s.sent + s.failed)::int8 AS total_sms, SUM(s.sent)::int8 AS success, SUM(s.failed)::int8 AS failed").
+			FromSelect(statsHourly(cr.Cfg, &fromDate, &toDate), "s").
+			Join("msg_application ma ON NULLIF(s.application_id, '')::int = ma.application_id").
+			GroupBy("ma.application_name", businessDate("s.hour")).
+			OrderBy(businessDate("s.hour") + " ASC").
+			Offset(uint64(meta.Skip * meta.Limit)).
+			Limit(uint64(meta.Limit))
 
 		err := dblib.TxRows(ctx, tx, query, pgx.RowToStructByNameLax[domain.SMSAggregateReport], &sms)
 		if err != nil {
@@ -92,6 +132,11 @@ func (cr *ReportsRepository) AppwiseSMSUsageReportRepo(gctx *gin.Context, fromDa
 	return sms, nil
 }
 
+// successStatuses are the msg_request statuses counted as successful sends in the usage reports:
+// accepted by the gateway, whether or not the delivery status is known yet
+const successStatuses = "('submitted', 'delivered', 'partially_delivered')"
+
+// TemplatewiseSMSUsageReportRepo aggregates msg_request, the hourly stats rollup has no template dimension
 func (cr *ReportsRepository) TemplatewiseSMSUsageReportRepo(gctx *gin.Context, fromDate time.Time, toDate time.Time, meta port.MetaDataRequest) ([]domain.SMSAggregateReport, error) {
 
 	ctx, cancel := context.WithTimeout(gctx.Request.Context(), cr.Cfg.GetDuration("db.querytimeoutmed"))
@@ -99,15 +144,15 @@ func (cr *ReportsRepository) TemplatewiseSMSUsageReportRepo(gctx *gin.Context, f
 
 	var sms []domain.SMSAggregateReport
 	TxDB := cr.Db.WithTx(ctx, func(tx pgx.Tx) error {
-		query := dblib.Psql.Select("row_number() over(ORDER BY mr.created_date::date ASC) as serial_number", "ma.template_name", "mr.created_date::date", "COUNT(*) AS total_sms, COUNT(CASE WHEN mr.status = 'submitted' THEN 1 END) AS success, COUNT(CASE WHEN mr.status <> 'submitted' THEN 1 END) AS failed").
+		query := dblib.Psql.Select("row_number() over(ORDER BY "+businessDate("mr.created_date")+" ASC) as serial_number", "ma.template_name", businessDate("mr.created_date")+" AS created_date", "COUNT(*) AS total_sms, COUNT(CASE WHEN mr.status IN "+successStatuses+" THEN 1 END) AS success, COUNT(CASE WHEN mr.status NOT IN "+successStatuses+" THEN 1 END) AS failed").
 			From("msg_request mr").
 			Join("msg_template ma ON mr.template_id = ma.template_id").
 			Join("unnest(mr.mobile_number) AS mobile_number ON true").
-			Where(squirrel.And{squirrel.GtOrEq{"mr.created_date::date": fromDate}, squirrel.LtOrEq{"mr.created_date::date": toDate}}).
-			GroupBy("ma.template_name,mr.created_date::date").
-			OrderBy("mr.created_date::date ASC").
-			Offset(meta.Skip * meta.Limit).
-			Limit(meta.Limit)
+			Where(businessDays("mr.created_date", fromDate, toDate)).
+			GroupBy("ma.template_name", businessDate("mr.created_date")).
+			OrderBy(businessDate("mr.created_date") + " ASC").
+			Offset(uint64(meta.Skip * meta.Limit)).
+			Limit(uint64(meta.Limit))
 
 		err := dblib.TxRows(ctx, tx, query, pgx.RowToStructByNameLax[domain.SMSAggregateReport], &sms)
 		if err != nil {
@@ -124,6 +169,7 @@ func (cr *ReportsRepository) TemplatewiseSMSUsageReportRepo(gctx *gin.Context, f
 	return sms, nil
 }
 
+// ProviderwiseSMSUsageReportRepo reads the daily usage per gateway from the hourly stats rollup
 func (cr *ReportsRepository) ProviderwiseSMSUsageReportRepo(gctx *gin.Context, fromDate time.Time, toDate time.Time, meta port.MetaDataRequest) ([]domain.SMSAggregateReport, error) {
 
 	ctx, cancel := context.WithTimeout(gctx.Request.Context(), cr.Cfg.GetDuration("db.querytimeoutmed"))
@@ -131,15 +177,16 @@ func (cr *ReportsRepository) ProviderwiseSMSUsageReportRepo(gctx *gin.Context, f
 
 	var sms []domain.SMSAggregateReport
 	TxDB := cr.Db.WithTx(ctx, func(tx pgx.Tx) error {
-		query := dblib.Psql.Select("row_number() over(ORDER BY mr.created_date::date ASC) as serial_number", "ma.provider_name", "mr.created_date::date", "COUNT(*) AS total_sms, COUNT(CASE WHEN mr.status = 'submitted' THEN 1 END) AS success, COUNT(CASE WHEN mr.status <> 'submitted' THEN 1 END) AS failed").
-			From("msg_request mr").
-			Join("msg_provider ma ON mr.gateway::int = ma.provider_id").
-			Join("unnest(mr.mobile_number) AS mobile_number ON true").
-			Where(squirrel.And{squirrel.GtOrEq{"mr.created_date::date": fromDate}, squirrel.LtOrEq{"mr.created_date::date": toDate}}).
-			GroupBy("ma.provider_name,mr.created_date::date").
-			OrderBy("mr.created_date::date ASC").
-			Offset(meta.Skip * meta.Limit).
-			Limit(meta.Limit)
+		query := dblib.Psql.Select("row_number() over(ORDER BY "+businessDate("s.hour")+" ASC) as serial_number",
+			"ma.provider_name",
+			businessDate("s.hour")+" AS created_date",
+			"SUM(s.sent + s.failed)::int8 AS total_sms, SUM(s.sent)::int8 AS success, SUM(s.failed)::int8 AS failed").
+			FromSelect(statsHourly(cr.Cfg, &fromDate, &toDate), "s").
+			Join("msg_provider ma ON NULLIF(s.gateway, '')::int = ma.provider_id").
+			GroupBy("ma.provider_name", businessDate("s.hour")).
+			OrderBy(businessDate("s.hour") + " ASC").
+			Offset(uint64(meta.Skip * meta.Limit)).
+			Limit(uint64(meta.Limit))
 
 		err := dblib.TxRows(ctx, tx, query, pgx.RowToStructByNameLax[domain.SMSAggregateReport], &sms)
 		if err != nil {
@@ -198,27 +245,21 @@ func (cr *ReportsRepository) SMSDashboard2(gctx *gin.Context) (domain.SMSDashboa
 }
 */
 
-// without transaction model
+// without transaction model, reading the totals from the hourly stats rollup
 func (cr *ReportsRepository) SMSDashboardRepo(gctx *gin.Context) (domain.SMSDashboard, error) {
 
 	ctx, cancel := context.WithTimeout(gctx.Request.Context(), cr.Cfg.GetDuration("db.querytimeoutlow"))
 	defer cancel()
 
 	query := dblib.Psql.Select(
-		//"COUNT(*) as total_requests",
-		//"count(*) as total_sms_sent",
-		"SUM(COALESCE(array_length(mr.mobile_number, 1), 0)) as total_sms_sent",
-		//"sum(case when mr.priority=1 then 1 else 0 end) as total_otps",
-		"SUM(CASE WHEN mr.priority=1 THEN COALESCE(array_length(mr.mobile_number, 1), 0) ELSE 0 END) as total_otps",
-		//"sum(case when mr.priority=2 then 1 else 0 end) as total_transactions",
-		"SUM(CASE WHEN mr.priority=2 THEN COALESCE(array_length(mr.mobile_number, 1), 0) ELSE 0 END) as total_transactions",
-		//"sum(case when mr.priority=3 then 1 else 0 end) as total_bulk_sms",
-		"SUM(CASE WHEN mr.priority=3 THEN COALESCE(array_length(mr.mobile_number, 1), 0) ELSE 0 END) as total_bulk_sms",
-		//"sum(case when mr.priority=4 then 1 else 0 end) as total_promotional_sms",
-		"SUM(CASE WHEN mr.priority=4 THEN COALESCE(array_length(mr.mobile_number, 1), 0) ELSE 0 END) as total_promotional_sms",
+		"COALESCE(SUM(s.sent + s.failed), 0)::int8 as total_sms_sent",
+		"COALESCE(SUM(s.sent + s.failed) FILTER (WHERE s.priority=1), 0)::int8 as total_otps",
+		"COALESCE(SUM(s.sent + s.failed) FILTER (WHERE s.priority=2), 0)::int8 as total_transactions",
+		"COALESCE(SUM(s.sent + s.failed) FILTER (WHERE s.priority=3), 0)::int8 as total_bulk_sms",
+		"COALESCE(SUM(s.sent + s.failed) FILTER (WHERE s.priority=4), 0)::int8 as total_promotional_sms",
 		"(select Count(*) from msg_template as mt where mt.status_cd=1)as total_templates",
 		"(select count(*) from msg_provider mp where mp.status_cd=1) as total_providers",
 		"(select count(*) from msg_application ma where ma.status_cd=1) as total_applications").
-		From("msg_request as mr")
+		FromSelect(statsHourly(cr.Cfg, nil, nil), "s")
 	return dblib.SelectOne(ctx, cr.Db, query, pgx.RowToStructByNameLax[domain.SMSDashboard])
 }
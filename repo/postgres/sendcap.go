@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+	"MgApplication/core/clock"
+	"MgApplication/core/domain"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// ConsumeSendCapRepo counts count messages against the hourly send cap of kind (a sender ID or a
+// template) and key in the window starting at window, unless the messages counted in that window
+// would exceed limit. It returns the messages counted in the window, this request included when
+// it fits, and whether it fits. The check and the increment are one upsert, so the replicas
+// reserving messages together never exceed limit.
+func (cr *MgApplicationRepository) ConsumeSendCapRepo(ctx context.Context, kind string, key string, window time.Time, count int64, limit int64) (int64, bool, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	if count <= limit {
+		query := sendCapConsumeQuery(kind, key, window, count, limit)
+		counter, err := dblib.InsertReturning(ctx, cr.Db, query, pgx.RowToStructByNameLax[domain.Counter])
+		if err == nil {
+			return int64(counter.Count), true, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Error(ctx, "Error executing query in ConsumeSendCap repo function: %s", err.Error())
+			return 0, false, err
+		}
+	}
+
+	// over the limit, the messages counted so far are reported
+	sent, err := cr.GetSendCapRepo(ctx, kind, key, window)
+	return sent, false, err
+}
+
+// sendCapConsumeQuery counts count messages in the window of kind and key, returning the messages
+// counted, unless they would exceed limit: no row is returned then
+func sendCapConsumeQuery(kind string, key string, window time.Time, count int64, limit int64) squirrel.InsertBuilder {
+	return dblib.Psql.Insert("msg_send_cap").
+		Columns("kind", "cap_key", "window_start", "sent").
+		Values(kind, key, window, count).
+		Suffix(`ON CONFLICT (kind, cap_key, window_start) DO UPDATE SET sent = msg_send_cap.sent + EXCLUDED.sent
+			WHERE msg_send_cap.sent + EXCLUDED.sent <= ?`, limit).
+		Suffix("RETURNING sent AS count")
+}
+
+func init() {
+	registerDiagnosticQuery("send_cap_consume", "Reservation of messages against the hourly cap of a sender ID, an upsert returning the messages counted",
+		func() squirrel.Sqlizer {
+			return sendCapConsumeQuery("sender", "INPOST", time.Date(2024, 9, 15, 10, 0, 0, 0, clock.IST), 20, 50000)
+		})
+}
+
+// GetSendCapRepo returns the messages counted against the hourly send cap of kind and key in the
+// window starting at window, 0 when none were
+func (cr *MgApplicationRepository) GetSendCapRepo(ctx context.Context, kind string, key string, window time.Time) (int64, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Select("sent AS count").
+		From("msg_send_cap").
+		Where(squirrel.Eq{"kind": kind, "cap_key": key, "window_start": window})
+
+	counter, _, err := dblib.SelectOneOK(ctx, cr.Db, query, pgx.RowToStructByNameLax[domain.Counter])
+	if err != nil {
+		log.Error(ctx, "Error executing query in GetSendCap repo function: %s", err.Error())
+		return 0, err
+	}
+	return int64(counter.Count), nil
+}
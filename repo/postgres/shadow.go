@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"MgApplication/core/domain"
+
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetShadowGatewayRepo returns the shadow gateway of an application, empty when its requests are
+// not shadowed
+func (cr *MgApplicationRepository) GetShadowGatewayRepo(ctx context.Context, applicationID string) (string, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Select("COALESCE(shadow_gateway, '')").
+		From("msg_application").
+		Where(squirrel.Eq{"application_id": applicationID})
+
+	gateway, _, err := dblib.SelectOneOK(ctx, cr.Db, query, pgx.RowTo[string], dblib.WithReadRetry())
+	if err != nil {
+		log.Error(ctx, "Error executing query in GetShadowGateway repo function: %s", err.Error())
+		return "", err
+	}
+	return gateway, nil
+}
+
+// SaveShadowResultRepo stores the outcome of a shadow send. Shadow results are kept out of
+// msg_request, so they are never counted in the stats rollup or the usage reports.
+func (cr *MgApplicationRepository) SaveShadowResultRepo(ctx context.Context, result *domain.ShadowResult) error {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Insert("msg_shadow_result").
+		Columns("communication_id", "application_id", "template_id", "recipients", "primary_gateway", "primary_response_code", "primary_accepted",
+			"shadow_gateway", "shadow_response_code", "shadow_response_text", "shadow_accepted").
+		Values(result.CommunicationID, result.ApplicationID, result.TemplateID, result.Recipients, result.PrimaryGateway, result.PrimaryResponseCode, result.PrimaryAccepted,
+			result.ShadowGateway, result.ShadowResponseCode, result.ShadowResponseText, result.ShadowAccepted)
+
+	_, err := dblib.Insert(ctx, cr.Db, query)
+	if err != nil {
+		log.Error(ctx, "Error executing query in SaveShadowResult repo function: %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// SetShadowGatewayRepo sets the shadow gateway of an application, an empty gateway stops the
+// shadowing. It reports whether the application exists.
+func (ar *ApplicationRepository) SetShadowGatewayRepo(ctx context.Context, applicationID uint64, gateway string) (bool, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, ar.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Update("msg_application").
+		Set("shadow_gateway", squirrel.Expr("NULLIF(?, '')", gateway)).
+		Set("updated_date", squirrel.Expr("current_timestamp")).
+		Where(squirrel.Eq{"application_id": applicationID})
+
+	var found bool
+	err := ar.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		before, err := auditRowTx(ctx, tx, "msg_application", squirrel.Eq{"application_id": applicationID})
+		if err != nil || before == nil {
+			return err
+		}
+		found = true
+		if err := dblib.TxExec(ctx, tx, query); err != nil {
+			return err
+		}
+		return auditApplicationTx(ctx, tx, ar.Cfg, domain.AuditActionUpdate, applicationID, before)
+	})
+	if err != nil {
+		log.Error(ctx, "Error executing query in SetShadowGateway repo function: %s", err.Error())
+		return false, err
+	}
+	return found, nil
+}
+
+// ShadowComparisonReportRepo compares the primary and shadow gateways on the requests shadowed
+// between fromDate and toDate, of one application when applicationID is set. It returns the
+// acceptance per application and gateway pair and the response code pairs of the requests
+// rejected by either gateway. The gateways use different codes, so requests accepted by both
+// are not listed.
+func (cr *ReportsRepository) ShadowComparisonReportRepo(ctx context.Context, fromDate time.Time, toDate time.Time, applicationID string) ([]domain.ShadowComparison, []domain.ShadowCodeDifference, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("db.querytimeoutmed"))
+	defer cancel()
+
+	where := squirrel.And{businessDays("created_date", fromDate, toDate)}
+	if applicationID != "" {
+		where = append(where, squirrel.Eq{"application_id": applicationID})
+	}
+
+	summary := dblib.Psql.Select(
+		"application_id",
+		"primary_gateway",
+		"shadow_gateway",
+		"COUNT(1) AS requests",
+		"COUNT(1) FILTER (WHERE primary_accepted) AS primary_accepted",
+		"COUNT(1) FILTER (WHERE shadow_accepted) AS shadow_accepted",
+		"ROUND(100.0 * COUNT(1) FILTER (WHERE primary_accepted) / COUNT(1), 2)::float8 AS primary_acceptance_rate",
+		"ROUND(100.0 * COUNT(1) FILTER (WHERE shadow_accepted) / COUNT(1), 2)::float8 AS shadow_acceptance_rate",
+	).
+		From("msg_shadow_result").
+		Where(where).
+		GroupBy("application_id", "primary_gateway", "shadow_gateway").
+		OrderBy("application_id", "primary_gateway", "shadow_gateway")
+
+	differences := dblib.Psql.Select(
+		"application_id",
+		"primary_gateway",
+		"COALESCE(primary_response_code, '') AS primary_response_code",
+		"shadow_gateway",
+		"COALESCE(shadow_response_code, '') AS shadow_response_code",
+		"COUNT(1) AS requests",
+	).
+		From("msg_shadow_result").
+		Where(where).
+		Where("NOT (primary_accepted AND shadow_accepted)").
+		GroupBy("application_id", "primary_gateway", "primary_response_code", "shadow_gateway", "shadow_response_code").
+		OrderBy("application_id", "requests DESC")
+
+	var comparisons []domain.ShadowComparison
+	var codes []domain.ShadowCodeDifference
+	err := cr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := dblib.TxRows(ctx, tx, summary, pgx.RowToStructByNameLax[domain.ShadowComparison], &comparisons); err != nil {
+			return err
+		}
+		return dblib.TxRows(ctx, tx, differences, pgx.RowToStructByNameLax[domain.ShadowCodeDifference], &codes)
+	}, pgx.RepeatableRead)
+	if err != nil {
+		log.Error(ctx, "Error executing query in ShadowComparisonReport repo function: %s", err.Error())
+		return nil, nil, err
+	}
+	return comparisons, codes, nil
+}
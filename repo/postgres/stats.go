@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"MgApplication/core/domain"
+
+	config "MgApplication/api-config"
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// Modes of maintaining the msg_stats_hourly rollup, selected by stats.rollup.mode. Inline
+// increments the rollup in the same transaction as the msg_request writes; job rebuilds the
+// recently changed hours periodically.
+const (
+	StatsRollupInline = "inline"
+	StatsRollupJob    = "job"
+)
+
+// The rollup counts recipients, by the business hour (IST) the request was created in:
+//   - sent: requests accepted by their gateway
+//   - failed: requests the gateway call failed for or the gateway rejected
+//   - delivered: requests confirmed delivered by a delivery report
+//
+// Requests still waiting for the gateway response are not counted.
+const (
+	statsRecipientsExpr = "COALESCE(array_length(mobile_number, 1), 0)"
+	statsAcceptedExpr   = "(btrim(COALESCE(reference_id, '')) <> '' OR response_code = '402')"
+)
+
+// statsAccepted reports whether a gateway response accepted the request, like statsAcceptedExpr
+func statsAccepted(referenceID string, responseCode string) bool {
+	return referenceID != "" || responseCode == "402"
+}
+
+// statsRollupInline reports whether the rollup is maintained along with the msg_request writes
+func statsRollupInline(c *config.Config) bool {
+	mode := c.GetString("stats.rollup.mode")
+	return mode == "" || mode == StatsRollupInline
+}
+
+// statsCostPerSMS returns the configured cost of one SMS sent through gateway
+func statsCostPerSMS(c *config.Config, gateway string) float64 {
+	return c.GetFloat64("stats.costpersms." + gateway)
+}
+
+// statsCostExpr returns the cost per SMS of the gateway column as a SQL expression
+func statsCostExpr(c *config.Config) squirrel.Sqlizer {
+	rates := c.GetStringMap("stats.costpersms")
+	gateways := make([]string, 0, len(rates))
+	for gateway := range rates {
+		gateways = append(gateways, gateway)
+	}
+	sort.Strings(gateways)
+
+	sql := "CASE gateway"
+	args := make([]any, 0, 2*len(gateways))
+	for _, gateway := range gateways {
+		sql += " WHEN ? THEN ?::numeric"
+		args = append(args, gateway, statsCostPerSMS(c, gateway))
+	}
+	sql += " ELSE 0 END"
+	return squirrel.Expr(sql, args...)
+}
+
+// rawStatsHourly aggregates the msg_request rows matching requests into rollup rows. The builder
+// uses ? placeholders, so that it can be nested in other queries.
+func rawStatsHourly(c *config.Config, requests squirrel.Sqlizer) squirrel.SelectBuilder {
+	inner := squirrel.Select(statsRowColumns...).
+		From("msg_request").
+		Where(requests)
+
+	cost, costArgs, _ := statsCostExpr(c).ToSql()
+	return squirrel.Select(
+		"application_id",
+		"gateway",
+		"priority",
+		"hour",
+		"COALESCE(SUM(recipients) FILTER (WHERE responded AND accepted), 0)::int8 AS sent",
+		"COALESCE(SUM(recipients) FILTER (WHERE responded AND NOT accepted), 0)::int8 AS failed",
+		"COALESCE(SUM(recipients) FILTER (WHERE delivered), 0)::int8 AS delivered",
+	).
+		Column("COALESCE(SUM(recipients) FILTER (WHERE responded AND accepted), 0) * "+cost+" AS total_cost", costArgs...).
+		FromSelect(inner, "r").
+		GroupBy("application_id", "gateway", "priority", "hour")
+}
+
+// statsHourly returns the rollup rows of the completed hours with the raw aggregation of the
+// current hour, created between fromDate and toDate when both are set
+func statsHourly(c *config.Config, fromDate *time.Time, toDate *time.Time) squirrel.SelectBuilder {
+	rollup := squirrel.Select("application_id", "gateway", "priority", "hour", "sent", "failed", "delivered", "total_cost").
+		From("msg_stats_hourly").
+		Where("hour < " + businessHour("CURRENT_TIMESTAMP"))
+
+	current := squirrel.And{squirrel.Expr("created_date >= " + businessHour("CURRENT_TIMESTAMP"))}
+
+	if fromDate != nil && toDate != nil {
+		rollup = rollup.Where(businessDays("hour", *fromDate, *toDate))
+		current = append(current, businessDays("created_date", *fromDate, *toDate))
+	}
+	return rollup.SuffixExpr(rawStatsHourly(c, current).Prefix("UNION ALL"))
+}
+
+// statsRow is a msg_request row as counted in the rollup
+type statsRow struct {
+	ApplicationID string    `db:"application_id"`
+	Gateway       string    `db:"gateway"`
+	Priority      int       `db:"priority"`
+	Hour          time.Time `db:"hour"`
+	Recipients    int64     `db:"recipients"`
+	Responded     bool      `db:"responded"`
+	Accepted      bool      `db:"accepted"`
+	Delivered     bool      `db:"delivered"`
+}
+
+// statsRowColumns selects the statsRow of msg_request rows
+var statsRowColumns = []string{
+	"COALESCE(application_id, '') AS application_id",
+	"COALESCE(gateway, '') AS gateway",
+	"COALESCE(priority, 0) AS priority",
+	businessHour("created_date") + " AS hour",
+	statsRecipientsExpr + " AS recipients",
+	"response_code IS NOT NULL AS responded",
+	statsAcceptedExpr + " AS accepted",
+	"COALESCE(status = 'delivered', false) AS delivered",
+}
+
+// incrementStatsHourly adds the increments to the rollup row of the application, gateway,
+// priority and hour of row
+func incrementStatsHourly(ctx context.Context, tx pgx.Tx, c *config.Config, row statsRow, sent int64, failed int64, delivered int64) error {
+	if sent == 0 && failed == 0 && delivered == 0 {
+		return nil
+	}
+
+	query := dblib.Psql.Insert("msg_stats_hourly").
+		Columns("application_id", "gateway", "priority", "hour", "sent", "failed", "delivered", "total_cost").
+		Values(row.ApplicationID, row.Gateway, row.Priority, row.Hour, sent, failed, delivered, float64(sent)*statsCostPerSMS(c, row.Gateway)).
+		Suffix(`ON CONFLICT (application_id, gateway, priority, hour) DO UPDATE SET
+			sent = msg_stats_hourly.sent + EXCLUDED.sent,
+			failed = msg_stats_hourly.failed + EXCLUDED.failed,
+			delivered = msg_stats_hourly.delivered + EXCLUDED.delivered,
+			total_cost = msg_stats_hourly.total_cost + EXCLUDED.total_cost`)
+
+	return dblib.TxExec(ctx, tx, query)
+}
+
+// rebuildStatsHourly replaces the rollup rows matching stale with the raw aggregation of the
+// msg_request rows matching requests, in one snapshot. The rollup is locked against inline
+// increments before the snapshot is taken, so increments committed later apply on top of it.
+func (cr *ReportsRepository) rebuildStatsHourly(ctx context.Context, stale squirrel.Sqlizer, requests squirrel.Sqlizer) (int64, error) {
+	var rebuilt int64
+	err := cr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "LOCK TABLE msg_stats_hourly IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+			return err
+		}
+		if err := dblib.TxExec(ctx, tx, dblib.Psql.Delete("msg_stats_hourly").Where(stale)); err != nil {
+			return err
+		}
+
+		query := dblib.Psql.Insert("msg_stats_hourly").
+			Columns("application_id", "gateway", "priority", "hour", "sent", "failed", "delivered", "total_cost").
+			Select(rawStatsHourly(cr.Cfg, requests))
+		sql, args, err := query.ToSql()
+		if err != nil {
+			return err
+		}
+		tag, err := tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return err
+		}
+		rebuilt = tag.RowsAffected()
+		return nil
+	}, pgx.RepeatableRead)
+	return rebuilt, err
+}
+
+// RebuildStatsHourlyRepo backfills the rollup of the requests created between fromDate and
+// toDate from msg_request and returns the number of rollup rows written
+func (cr *ReportsRepository) RebuildStatsHourlyRepo(ctx context.Context, fromDate time.Time, toDate time.Time) (int64, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("stats.rollup.rebuildtimeout"))
+	defer cancel()
+
+	rebuilt, err := cr.rebuildStatsHourly(ctx,
+		businessDays("hour", fromDate, toDate),
+		businessDays("created_date", fromDate, toDate))
+	if err != nil {
+		log.Error(ctx, "Error executing query in RebuildStatsHourly repo function: %s", err.Error())
+		return 0, err
+	}
+	return rebuilt, nil
+}
+
+// RebuildChangedStatsHourlyRepo rebuilds the rollup of the hours with requests created or
+// updated within the last lookback and returns the number of rollup rows written
+func (cr *ReportsRepository) RebuildChangedStatsHourlyRepo(ctx context.Context, lookback time.Duration) (int64, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("stats.rollup.rebuildtimeout"))
+	defer cancel()
+
+	changed := squirrel.Select("DISTINCT "+businessHour("created_date")).
+		From("msg_request").
+		Where("(created_date >= CURRENT_TIMESTAMP - make_interval(secs => ?) OR updated_date >= CURRENT_TIMESTAMP - make_interval(secs => ?))", lookback.Seconds(), lookback.Seconds())
+	oldest := squirrel.Select("MIN(created_date)").
+		From("msg_request").
+		Where("(created_date >= CURRENT_TIMESTAMP - make_interval(secs => ?) OR updated_date >= CURRENT_TIMESTAMP - make_interval(secs => ?))", lookback.Seconds(), lookback.Seconds())
+
+	rebuilt, err := cr.rebuildStatsHourly(ctx,
+		squirrel.Expr("hour IN (?)", changed),
+		squirrel.And{
+			squirrel.Expr("created_date >= "+businessHour("(?)"), oldest),
+			squirrel.Expr(businessHour("created_date")+" IN (?)", changed),
+		})
+	if err != nil {
+		log.Error(ctx, "Error executing query in RebuildChangedStatsHourly repo function: %s", err.Error())
+		return 0, err
+	}
+	return rebuilt, nil
+}
+
+// CheckStatsHourlyRepo compares the rollup with the raw aggregation of the requests created
+// between fromDate and toDate and returns the rollup rows that differ
+func (cr *ReportsRepository) CheckStatsHourlyRepo(ctx context.Context, fromDate time.Time, toDate time.Time) ([]domain.StatsHourlyMismatch, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("stats.rollup.rebuildtimeout"))
+	defer cancel()
+
+	rollup := squirrel.Select("application_id", "gateway", "priority", "hour", "sent", "failed", "delivered").
+		From("msg_stats_hourly").
+		Where(businessDays("hour", fromDate, toDate))
+	raw := rawStatsHourly(cr.Cfg, businessDays("created_date", fromDate, toDate))
+
+	query := dblib.Psql.Select(
+		"COALESCE(s.application_id, r.application_id) AS application_id",
+		"COALESCE(s.gateway, r.gateway) AS gateway",
+		"COALESCE(s.priority, r.priority) AS priority",
+		"COALESCE(s.hour, r.hour) AS hour",
+		"COALESCE(s.sent, 0) AS rollup_sent",
+		"COALESCE(r.sent, 0) AS raw_sent",
+		"COALESCE(s.failed, 0) AS rollup_failed",
+		"COALESCE(r.failed, 0) AS raw_failed",
+		"COALESCE(s.delivered, 0) AS rollup_delivered",
+		"COALESCE(r.delivered, 0) AS raw_delivered",
+	).
+		FromSelect(rollup, "s").
+		JoinClause(raw.Prefix("FULL OUTER JOIN (").Suffix(") r ON r.application_id = s.application_id AND r.gateway = s.gateway AND r.priority = s.priority AND r.hour = s.hour")).
+		Where("(COALESCE(s.sent, 0) <> COALESCE(r.sent, 0) OR COALESCE(s.failed, 0) <> COALESCE(r.failed, 0) OR COALESCE(s.delivered, 0) <> COALESCE(r.delivered, 0))").
+		OrderBy("hour", "application_id", "gateway", "priority")
+
+	var mismatches []domain.StatsHourlyMismatch
+	err := cr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		return dblib.TxRows(ctx, tx, query, pgx.RowToStructByNameLax[domain.StatsHourlyMismatch], &mismatches)
+	}, pgx.RepeatableRead)
+	if err != nil {
+		log.Error(ctx, "Error executing query in CheckStatsHourly repo function: %s", err.Error())
+		return nil, err
+	}
+	return mismatches, nil
+}
+
+// FindOrphanedRequestsRepo lists the requests created between the dates that were stored to be
+// sent right away but have no gateway response. Promotional and bulk requests wait in the queue
+// without a response, so only the OTP and transactional priorities are considered.
+func (cr *ReportsRepository) FindOrphanedRequestsRepo(ctx context.Context, fromDate time.Time, toDate time.Time) ([]domain.OrphanedRequest, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("db.querytimeoutmed"))
+	defer cancel()
+
+	query := dblib.Psql.Select(
+		"request_id",
+		"COALESCE(communication_id, '') AS communication_id",
+		"COALESCE(application_id, '') AS application_id",
+		"COALESCE(gateway, '') AS gateway",
+		"priority",
+		"created_date",
+	).
+		From("msg_request").
+		Where(businessDays("created_date", fromDate, toDate)).
+		Where(squirrel.Eq{"status": "pending", "priority": []int{int(domain.PriorityOTP), int(domain.PriorityTransactional)}}).
+		Where("response_code IS NULL").
+		OrderBy("request_id")
+
+	orphans, err := dblib.SelectRows(ctx, cr.Db, query, pgx.RowToStructByNameLax[domain.OrphanedRequest])
+	if err != nil {
+		log.Error(ctx, "Error executing query in FindOrphanedRequests repo function: %s", err.Error())
+		return nil, err
+	}
+	return orphans, nil
+}
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"MgApplication/core/domain"
+
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// ClaimPendingDeliveryStatusRepo claims up to limit submitted messages of the given gateways for
+// a delivery status fetch. A message is claimed at most once per interval and only while it is
+// younger than maxAge. Rows locked by another instance are skipped, so several instances can
+// poll concurrently without fetching the same message twice.
+//
+// A claim is a lease: claimed messages become claimable again after lease rather than after the
+// full interval, so the messages of an instance stopped mid-batch are picked up first once the
+// lease expires. MarkDeliveryStatusPolledRepo checkpoints the fetched ones for the full interval.
+func (cr *MgApplicationRepository) ClaimPendingDeliveryStatusRepo(ctx context.Context, gateways []string, limit uint64, interval time.Duration, lease time.Duration, maxAge time.Duration) ([]domain.PendingDeliveryStatus, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("db.querytimeoutmed"))
+	defer cancel()
+
+	leaseOffset := time.Duration(0)
+	if lease > 0 && lease < interval {
+		leaseOffset = interval - lease
+	}
+
+	pending := dblib.Psql.Select("request_id").
+		From("msg_request").
+		Where(squirrel.Eq{"status": "submitted"}).
+		Where(squirrel.Eq{"gateway": gateways}).
+		Where(squirrel.NotEq{"reference_id": ""}).
+		Where("created_date >= CURRENT_TIMESTAMP - make_interval(secs => ?)", maxAge.Seconds()).
+		Where("(status_polled_at IS NULL OR status_polled_at <= CURRENT_TIMESTAMP - make_interval(secs => ?))", interval.Seconds()).
+		OrderBy("status_polled_at NULLS FIRST", "request_id").
+		Limit(limit).
+		Suffix("FOR UPDATE SKIP LOCKED")
+
+	query := dblib.Psql.Update("msg_request").
+		Set("status_polled_at", squirrel.Expr("CURRENT_TIMESTAMP - make_interval(secs => ?)", leaseOffset.Seconds())).
+		Where(squirrel.Expr("request_id IN (?)", pending)).
+		Suffix("RETURNING request_id, application_id, communication_id, reference_id, gateway")
+
+	var claimed []domain.PendingDeliveryStatus
+	err := cr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		return dblib.TxRows(ctx, tx, query, pgx.RowToStructByNameLax[domain.PendingDeliveryStatus], &claimed)
+	})
+	if err != nil {
+		log.Error(ctx, "Error executing update query in ClaimPendingDeliveryStatus repo function: %s", err.Error())
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// MarkDeliveryStatusPolledRepo checkpoints the messages whose delivery status was fetched and is
+// still pending, so they are not claimed again before the poll interval has passed
+func (cr *MgApplicationRepository) MarkDeliveryStatusPolledRepo(ctx context.Context, requestIDs []uint64) error {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Update("msg_request").
+		Set("status_polled_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where(squirrel.Eq{"request_id": requestIDs}).
+		Where(squirrel.Eq{"status": "submitted"})
+
+	if _, err := dblib.Update(ctx, cr.Db, query); err != nil {
+		log.Error(ctx, "Error executing update query in MarkDeliveryStatusPolled repo function: %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+type pendingDeliveryStatusCount struct {
+	Gateway string `db:"gateway"`
+	Pending int64  `db:"pending"`
+}
+
+// CountPendingDeliveryStatusRepo returns the number of submitted messages younger than maxAge
+// awaiting their delivery status, by gateway
+func (cr *MgApplicationRepository) CountPendingDeliveryStatusRepo(ctx context.Context, gateways []string, maxAge time.Duration) (map[string]int64, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("db.querytimeoutmed"))
+	defer cancel()
+
+	query := dblib.Psql.Select("gateway", "count(*) AS pending").
+		From("msg_request").
+		Where(squirrel.Eq{"status": "submitted"}).
+		Where(squirrel.Eq{"gateway": gateways}).
+		Where(squirrel.NotEq{"reference_id": ""}).
+		Where("created_date >= CURRENT_TIMESTAMP - make_interval(secs => ?)", maxAge.Seconds()).
+		GroupBy("gateway")
+
+	counts, err := dblib.SelectRows(ctx, cr.Db, query, pgx.RowToStructByName[pendingDeliveryStatusCount])
+	if err != nil {
+		log.Error(ctx, "Error executing query in CountPendingDeliveryStatus repo function: %s", err.Error())
+		return nil, err
+	}
+	pending := make(map[string]int64, len(gateways))
+	for _, gateway := range gateways {
+		pending[gateway] = 0
+	}
+	for _, count := range counts {
+		pending[count.Gateway] = count.Pending
+	}
+	return pending, nil
+}
+
+// UpdateDeliveryStatusRepo moves a submitted message to its final delivery status. It reports
+// false when the message already left the submitted state, so the transition is applied once.
+// With the inline stats rollup, delivered messages are counted in the same transaction. A
+// webhook, when given, is queued in msg_webhook_outbox in the same transaction, so a
+// status change is never left without its webhook.
+func (cr *MgApplicationRepository) UpdateDeliveryStatusRepo(ctx context.Context, requestID uint64, status string, webhook *domain.WebhookOutboxEvent) (bool, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Update("msg_request").
+		Set("status", status).
+		Set("updated_date", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where(squirrel.Eq{"request_id": requestID}).
+		Where(squirrel.Eq{"status": "submitted"}).
+		Suffix("RETURNING " + strings.Join(statsRowColumns, ", "))
+
+	var updated []statsRow
+	err := cr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := dblib.TxRows(ctx, tx, query, pgx.RowToStructByNameLax[statsRow], &updated); err != nil {
+			return err
+		}
+		if len(updated) == 1 && webhook != nil {
+			outbox := dblib.Psql.Insert("msg_webhook_outbox").
+				Columns("communication_id", "payload").
+				Values(webhook.CommunicationID, webhook.Payload)
+			if err := dblib.TxExec(ctx, tx, outbox); err != nil {
+				return err
+			}
+		}
+		if !statsRollupInline(cr.Cfg) {
+			return nil
+		}
+		for _, row := range updated {
+			if row.Delivered {
+				if err := incrementStatsHourly(ctx, tx, cr.Cfg, row, 0, 0, row.Recipients); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error(ctx, "Error executing update query in UpdateDeliveryStatus repo function: %s", err.Error())
+		return false, err
+	}
+	return len(updated) == 1, nil
+}
+
+// ClaimWebhookOutboxRepo claims up to limit queued webhooks due for an attempt, skipping those
+// which failed maxAttempts times. A claim is a lease: the webhooks are claimable again after lease
+// unless they are delivered or rescheduled with RetryWebhookOutboxRepo. Rows locked by another
+// instance are skipped.
+func (cr *MgApplicationRepository) ClaimWebhookOutboxRepo(ctx context.Context, limit uint64, lease time.Duration, maxAttempts int) ([]domain.WebhookOutboxEvent, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("db.querytimeoutmed"))
+	defer cancel()
+
+	due := dblib.Psql.Select("outbox_id").
+		From("msg_webhook_outbox").
+		Where("next_attempt_at <= CURRENT_TIMESTAMP").
+		Where(squirrel.Lt{"attempts": maxAttempts}).
+		OrderBy("next_attempt_at", "outbox_id").
+		Limit(limit).
+		Suffix("FOR UPDATE SKIP LOCKED")
+
+	query := dblib.Psql.Update("msg_webhook_outbox").
+		Set("next_attempt_at", squirrel.Expr("CURRENT_TIMESTAMP + make_interval(secs => ?)", lease.Seconds())).
+		Where(squirrel.Expr("outbox_id IN (?)", due)).
+		Suffix("RETURNING outbox_id, communication_id, payload, attempts")
+
+	var claimed []domain.WebhookOutboxEvent
+	err := cr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		return dblib.TxRows(ctx, tx, query, pgx.RowToStructByNameLax[domain.WebhookOutboxEvent], &claimed)
+	})
+	if err != nil {
+		log.Error(ctx, "Error executing update query in ClaimWebhookOutbox repo function: %s", err.Error())
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// DeleteWebhookOutboxRepo removes a delivered webhook from the outbox
+func (cr *MgApplicationRepository) DeleteWebhookOutboxRepo(ctx context.Context, outboxID uint64) error {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Delete("msg_webhook_outbox").
+		Where(squirrel.Eq{"outbox_id": outboxID})
+
+	if _, err := dblib.Delete(ctx, cr.Db, query); err != nil {
+		log.Error(ctx, "Error executing delete query in DeleteWebhookOutbox repo function: %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// RetryWebhookOutboxRepo records a failed attempt of a webhook and schedules the next one after retryIn
+func (cr *MgApplicationRepository) RetryWebhookOutboxRepo(ctx context.Context, outboxID uint64, retryIn time.Duration, lastError string) error {
+
+	ctx, cancel := context.WithTimeout(ctx, cr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Update("msg_webhook_outbox").
+		Set("attempts", squirrel.Expr("attempts + 1")).
+		Set("next_attempt_at", squirrel.Expr("CURRENT_TIMESTAMP + make_interval(secs => ?)", retryIn.Seconds())).
+		Set("last_error", lastError).
+		Where(squirrel.Eq{"outbox_id": outboxID})
+
+	if _, err := dblib.Update(ctx, cr.Db, query); err != nil {
+		log.Error(ctx, "Error executing update query in RetryWebhookOutbox repo function: %s", err.Error())
+		return err
+	}
+	return nil
+}
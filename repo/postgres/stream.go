@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	config "MgApplication/api-config"
+)
+
+// streamQueryContext bounds a streamed query by db.querytimeoutstream, by ctx alone when it is
+// not set. Streamed lists are read while they are written to the client, they take longer than
+// the queries of a page.
+func streamQueryContext(ctx context.Context, cfg *config.Config) (context.Context, context.CancelFunc) {
+	if timeout := cfg.GetDuration("db.querytimeoutstream"); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
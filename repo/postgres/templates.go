@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"iter"
+	"strconv"
+	"strings"
 
 	"MgApplication/core/domain"
 
@@ -19,15 +22,74 @@ import (
 type TemplateRepository struct {
 	Db  *dblib.DB
 	Cfg *config.Config
+	// Reads routes the fetches of a template, to the primary when nil
+	Reads *dblib.ReadRouter
 }
 
 func NewTemplateRepository(Db *dblib.DB, Cfg *config.Config) *TemplateRepository {
 	return &TemplateRepository{
-		Db,
-		Cfg,
+		Db:  Db,
+		Cfg: Cfg,
 	}
 }
 
+// TemplateGatewayConflictError is returned when a template is saved with a DLT template id
+// registered under another gateway: a template id is issued for a single gateway, the sends of
+// the template being routed by it.
+type TemplateGatewayConflictError struct {
+	TemplateID string
+	Gateway    string
+}
+
+func (e *TemplateGatewayConflictError) Error() string {
+	return fmt.Sprintf("template_id %s is already registered under gateway %s", e.TemplateID, e.Gateway)
+}
+
+// ApplicationExistsRepo reports whether an application with applicationID exists, for the
+// templates referencing it. It reads the primary, so that an application created just before is
+// found.
+func (tr *TemplateRepository) ApplicationExistsRepo(ctx context.Context, applicationID string) (bool, error) {
+
+	// application_id is an int4, an id out of its range cannot exist
+	id, err := strconv.ParseInt(applicationID, 10, 32)
+	if err != nil {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, tr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := dblib.Psql.Select("COUNT(1) AS count").
+		From("msg_application").
+		Where(squirrel.Eq{"application_id": id})
+
+	counter, err := dblib.SelectOne(ctx, tr.Db, query, pgx.RowToStructByNameLax[domain.Counter])
+	if err != nil {
+		log.Error(ctx, "Error executing query in ApplicationExists repo function: %s", err.Error())
+		return false, err
+	}
+	return counter.Count > 0, nil
+}
+
+// checkTemplateGateway fails with a *TemplateGatewayConflictError when the template id of
+// mtemplate is registered under another gateway by a template other than mtemplate
+func checkTemplateGateway(ctx context.Context, tx pgx.Tx, mtemplate *domain.MaintainTemplate) error {
+	query := dblib.Psql.Select("gateway").
+		From("msg_template").
+		Where(squirrel.Eq{"template_id": mtemplate.TemplateID}).
+		Where(squirrel.NotEq{"gateway": mtemplate.Gateway}).
+		Where(squirrel.NotEq{"template_local_id": mtemplate.TemplateLocalID}).
+		Limit(1)
+	var gateways []string
+	if err := dblib.TxRows(ctx, tx, query, pgx.RowTo[string], &gateways); err != nil {
+		return err
+	}
+	if len(gateways) > 0 {
+		return &TemplateGatewayConflictError{TemplateID: mtemplate.TemplateID, Gateway: gateways[0]}
+	}
+	return nil
+}
+
 func (tr *TemplateRepository) CreateTemplateRepo(gctx *gin.Context, mtemplate *domain.MaintainTemplate) error {
 
 	ctx, cancel := context.WithTimeout(gctx.Request.Context(), tr.Cfg.GetDuration("db.querytimeoutlow"))
@@ -35,6 +97,9 @@ func (tr *TemplateRepository) CreateTemplateRepo(gctx *gin.Context, mtemplate *d
 
 	var Counter domain.Counter
 	TxDB := tr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := checkTemplateGateway(ctx, tx, mtemplate); err != nil {
+			return err
+		}
 		// Check if data already exists
 		query := dblib.Psql.Select("COUNT(1) as count").
 			From("msg_template").
@@ -50,13 +115,20 @@ func (tr *TemplateRepository) CreateTemplateRepo(gctx *gin.Context, mtemplate *d
 		}
 		uquery := dblib.Psql.Insert("msg_template").
 			Columns("application_id", "template_name", "template_format", "entity_id", "sender_id", "template_id", "gateway", "message_type", "status_cd").
-			Values(mtemplate.ApplicationID, mtemplate.TemplateName, mtemplate.TemplateFormat, mtemplate.EntityID, mtemplate.SenderID, mtemplate.TemplateID, mtemplate.Gateway, mtemplate.MessageType, mtemplate.Status)
-		err = dblib.TxExec(ctx, tx, uquery)
+			Values(mtemplate.ApplicationID, mtemplate.TemplateName, mtemplate.TemplateFormat, mtemplate.EntityID, mtemplate.SenderID, mtemplate.TemplateID, mtemplate.Gateway, mtemplate.MessageType, mtemplate.Status).
+			Suffix("RETURNING template_local_id")
+		var templateLocalID uint64
+		err = dblib.TxReturnRow(ctx, tx, uquery, pgx.RowTo[uint64], &templateLocalID)
 		if err != nil {
 			log.Error(gctx, "Error executing insert query in MaintainTemplate repo function:  %s", err.Error())
 			return err
 		}
-		return nil
+		mtemplate.TemplateLocalID = templateLocalID
+		if err := auditTemplateTx(ctx, tx, tr.Cfg, domain.AuditActionCreate, templateLocalID, nil); err != nil {
+			log.Error(gctx, "Error appending to the audit log in MaintainTemplate repo function:  %s", err.Error())
+			return err
+		}
+		return notifyTemplateChanges(ctx, tx, tr.Cfg, squirrel.Eq{"template_id": mtemplate.TemplateID})
 	})
 	if TxDB != nil {
 		log.Error(gctx, "Transaction rolling back in MaintainTemplate repo function:  %s", TxDB.Error())
@@ -108,7 +180,7 @@ func (tr *TemplateRepository) ListTemplatesOld(gctx *gin.Context) ([]domain.Main
 		Join("msg_provider mp on mp.provider_id=mt.gateway::integer").
 		GroupBy("mt.template_local_id", "mt.template_name", "mt.template_format", "mt.sender_id", "mt.entity_id", "mt.template_id", "mt.message_type", "mp.provider_name", "mt.status_cd").
 		OrderBy("mt.template_local_id")
-	return dblib.SelectRows(ctx, tr.Db, query, pgx.RowToStructByNameLax[domain.MaintainTemplate])
+	return dblib.SelectRows(ctx, tr.Db, query, pgx.RowToStructByNameLax[domain.MaintainTemplate], dblib.WithReadRetry())
 }
 
 func (tr *TemplateRepository) ListTemplatesLimit(gctx *gin.Context, listTemplate *domain.Meta) ([]domain.MaintainTemplate, error) {
@@ -128,38 +200,19 @@ func (tr *TemplateRepository) ListTemplatesLimit(gctx *gin.Context, listTemplate
 		Limit(listTemplate.Limit).
 		Offset(listTemplate.Skip)
 
-	return dblib.SelectRows(ctx, tr.Db, query, pgx.RowToStructByNameLax[domain.MaintainTemplate])
+	return dblib.SelectRows(ctx, tr.Db, query, pgx.RowToStructByNameLax[domain.MaintainTemplate], dblib.WithReadRetry())
 }
 */
 
-func (tr *TemplateRepository) ListTemplatesRepo(gctx *gin.Context, listTemplate *domain.Meta) ([]domain.MaintainTemplate, uint64, error) {
+func (tr *TemplateRepository) ListTemplatesRepo(gctx *gin.Context, listTemplate *domain.Meta, filter domain.TemplateFilter) ([]domain.MaintainTemplate, uint64, error) {
 
 	ctx, cancel := context.WithTimeout(gctx.Request.Context(), tr.Cfg.GetDuration("db.querytimeoutmed"))
 	defer cancel()
 
 	var totalCount uint64
 
-	// Create the subquery for counting total templates
-	subquery, _, _ := dblib.Psql.Select("COUNT(*) AS total_count").
-		From("msg_template").
-		ToSql()
-
-	// Build the main query to fetch the templates with pagination and total_count from the subquery
-	query := dblib.Psql.Select("mt.template_local_id", "STRING_AGG(ma.application_name, ', ') AS application_id",
-		"mt.template_name", "mt.template_format", "mt.sender_id", "mt.entity_id", "mt.template_id",
-		"mt.message_type", "mp.provider_name AS gateway", "mt.status_cd", fmt.Sprintf("(%s) AS total_count", subquery)).
-		From("msg_template mt").
-		Join("LATERAL unnest(string_to_array(mt.application_id, ',')) AS rt(rt_value) ON true").
-		Join("msg_application ma ON rt.rt_value::integer = ma.application_id").
-		Join("msg_provider mp on mp.provider_id=mt.gateway::integer").
-		GroupBy("mt.template_local_id", "mt.template_name", "mt.template_format", "mt.sender_id", "mt.entity_id",
-			"mt.template_id", "mt.message_type", "mp.provider_name", "mt.status_cd").
-		OrderBy("mt.template_local_id").
-		Limit(uint64(listTemplate.Limit)).
-		Offset(uint64(listTemplate.Skip))
-
 	// Execute the main query to fetch templates and total count
-	templates, err := dblib.SelectRows(ctx, tr.Db, query, pgx.RowToStructByNameLax[domain.MaintainTemplate])
+	templates, err := dblib.SelectRows(ctx, tr.Db, listTemplatesQuery(listTemplate, filter), pgx.RowToStructByNameLax[domain.MaintainTemplate], dblib.WithReadRetry())
 	if err != nil {
 		log.Error(gctx, "DB Error in ListTemplatesLimit: %s", err.Error())
 		return nil, 0, err
@@ -174,6 +227,129 @@ func (tr *TemplateRepository) ListTemplatesRepo(gctx *gin.Context, listTemplate
 	return templates, totalCount, nil
 }
 
+// templateFacetRow is the count of the templates of one status and gateway
+type templateFacetRow struct {
+	Status  int    `db:"status_cd"`
+	Gateway string `db:"gateway"`
+	Count   uint64 `db:"count"`
+}
+
+// ListTemplateFacetsRepo counts the templates listed by ListTemplatesRepo for filter by status
+// and by gateway, with a single query grouped by both
+func (tr *TemplateRepository) ListTemplateFacetsRepo(gctx *gin.Context, filter domain.TemplateFilter) (domain.TemplateFacets, error) {
+
+	ctx, cancel := context.WithTimeout(gctx.Request.Context(), tr.Cfg.GetDuration("db.querytimeoutmed"))
+	defer cancel()
+
+	query := dblib.Psql.Select("status_cd", "gateway", "COUNT(*) AS count").
+		FromSelect(filteredTemplatesQuery(filter, "mt.status_cd", "mt.gateway"), "t").
+		GroupBy("status_cd", "gateway")
+
+	rows, err := dblib.SelectRows(ctx, tr.Db, query, pgx.RowToStructByNameLax[templateFacetRow], dblib.WithReadRetry())
+	if err != nil {
+		log.Error(gctx, "DB Error in ListTemplateFacetsRepo: %s", err.Error())
+		return domain.TemplateFacets{}, err
+	}
+
+	facets := domain.TemplateFacets{Status: map[string]uint64{}, Gateway: map[string]uint64{}}
+	for _, row := range rows {
+		facets.Status[strconv.Itoa(row.Status)] += row.Count
+		facets.Gateway[row.Gateway] += row.Count
+	}
+	return facets, nil
+}
+
+// StreamTemplatesRepo yields the templates listed by ListTemplatesRepo one at a time as they are
+// read, see dblib.SelectSeq. The query runs under db.querytimeoutstream.
+func (tr *TemplateRepository) StreamTemplatesRepo(gctx *gin.Context, listTemplate *domain.Meta, filter domain.TemplateFilter) iter.Seq2[domain.MaintainTemplate, error] {
+	return func(yield func(domain.MaintainTemplate, error) bool) {
+		ctx, cancel := streamQueryContext(gctx.Request.Context(), tr.Cfg)
+		defer cancel()
+		for template, err := range dblib.SelectSeq(ctx, tr.Db, listTemplatesQuery(listTemplate, filter), pgx.RowToStructByNameLax[domain.MaintainTemplate]) {
+			if err != nil {
+				log.Error(gctx, "DB Error in StreamTemplatesRepo: %s", err.Error())
+			}
+			if !yield(template, err) {
+				return
+			}
+		}
+	}
+}
+
+// TemplateOrderColumns are the columns the template list can be ordered by, with the
+// expression ordering them
+var TemplateOrderColumns = map[string]string{
+	"template_local_id": "mt.template_local_id",
+	"template_name":     "mt.template_name",
+	"sender_id":         "mt.sender_id",
+	"template_id":       "mt.template_id",
+	"message_type":      "mt.message_type",
+	"gateway":           "mp.provider_name",
+	"status":            "mt.status_cd",
+}
+
+// listTemplatesQuery selects a page of the templates matching filter with the names of their
+// applications and gateway, and the count of the templates matching filter
+func listTemplatesQuery(listTemplate *domain.Meta, filter domain.TemplateFilter) squirrel.SelectBuilder {
+	direction := " ASC"
+	if filter.Descending {
+		direction = " DESC"
+	}
+	order := []string{"mt.template_local_id" + direction}
+	if column, ok := TemplateOrderColumns[filter.OrderBy]; ok && column != "mt.template_local_id" {
+		order = []string{column + direction, "mt.template_local_id"}
+	}
+
+	return filteredTemplatesQuery(filter, "mt.template_local_id", "STRING_AGG(ma.application_name, ', ') AS application_id",
+		"mt.template_name", "mt.template_format", "mt.sender_id", "mt.entity_id", "mt.template_id",
+		"mt.message_type", "mp.provider_name AS gateway", "mt.status_cd", "COUNT(*) OVER () AS total_count").
+		OrderBy(order...).
+		Limit(uint64(listTemplate.Limit)).
+		Offset(uint64(listTemplate.Skip))
+}
+
+// filteredTemplatesQuery selects columns of the templates matching filter, one row per
+// template joined with its applications and gateway
+func filteredTemplatesQuery(filter domain.TemplateFilter, columns ...string) squirrel.SelectBuilder {
+	return dblib.Psql.Select(columns...).
+		From("msg_template mt").
+		Join("LATERAL unnest(string_to_array(mt.application_id, ',')) AS rt(rt_value) ON true").
+		Join("msg_application ma ON rt.rt_value::integer = ma.application_id").
+		Join("msg_provider mp on mp.provider_id=mt.gateway::integer").
+		Where(templateFilterPredicate(filter)).
+		GroupBy("mt.template_local_id", "mt.template_name", "mt.template_format", "mt.sender_id", "mt.entity_id",
+			"mt.template_id", "mt.message_type", "mt.gateway", "mp.provider_name", "mt.status_cd")
+}
+
+// templateFilterPredicate matches the templates selected by filter. The application is matched
+// on the template itself, so that the listed templates keep the names of all their applications.
+func templateFilterPredicate(filter domain.TemplateFilter) squirrel.And {
+	where := squirrel.And{}
+	if filter.ApplicationID != "" {
+		where = append(where, squirrel.Expr("EXISTS (SELECT 1 FROM unnest(string_to_array(mt.application_id, ',')) AS app_id WHERE app_id = ?)", filter.ApplicationID))
+	}
+	if filter.Gateway != "" {
+		where = append(where, squirrel.Eq{"mt.gateway": filter.Gateway})
+	}
+	if filter.Status != nil {
+		where = append(where, squirrel.Eq{"mt.status_cd": *filter.Status})
+	}
+	if filter.MessageType != "" {
+		where = append(where, squirrel.Eq{"mt.message_type": filter.MessageType})
+	}
+	if filter.SenderID != "" {
+		where = append(where, squirrel.Eq{"mt.sender_id": filter.SenderID})
+	}
+	if filter.Search != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Search) + "%"
+		where = append(where, squirrel.Or{squirrel.ILike{"mt.template_name": pattern}, squirrel.ILike{"mt.template_format": pattern}})
+	}
+	return where
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern, backslash being the escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 func (tr *TemplateRepository) ToggleTemplateStatusRepo(gctx *gin.Context, msgtemplate *domain.StatusTemplate) (interface{}, error) {
 
 	ctx, cancel := context.WithTimeout(gctx.Request.Context(), tr.Cfg.GetDuration("db.querytimeoutlow"))
@@ -194,6 +370,11 @@ func (tr *TemplateRepository) ToggleTemplateStatusRepo(gctx *gin.Context, msgtem
 		if Counter.Count == 0 {
 			return errors.New("no template with selected details is available")
 		}
+		before, err := auditRowTx(ctx, tx, "msg_template", squirrel.Eq{"template_local_id": msgtemplate.TemplateLocalID})
+		if err != nil {
+			log.Error(gctx, "Error reading the template before the change in StatusTemplate repo function: %s", err.Error())
+			return err
+		}
 		uquery := dblib.Psql.Update("msg_template").
 			Set("status_cd", squirrel.Expr("CASE WHEN status_cd = 0 THEN 1 ELSE 0 END")).
 			Where(squirrel.Eq{"template_local_id": msgtemplate.TemplateLocalID})
@@ -202,7 +383,19 @@ func (tr *TemplateRepository) ToggleTemplateStatusRepo(gctx *gin.Context, msgtem
 			log.Error(gctx, "Error executing update query in StatusTemplate repo function: %s", err.Error())
 			return err
 		}
-		return nil
+		if err := auditTemplateTx(ctx, tx, tr.Cfg, domain.AuditActionStatus, msgtemplate.TemplateLocalID, before); err != nil {
+			log.Error(gctx, "Error appending to the audit log in StatusTemplate repo function: %s", err.Error())
+			return err
+		}
+		changed := squirrel.Select("template_local_id", "status_cd").
+			From("msg_template").
+			Where(squirrel.Eq{"template_local_id": msgtemplate.TemplateLocalID})
+		err = dblib.TxExec(ctx, tx, insertTemplateHistory(changed, domain.TemplateActionToggleStatus, msgtemplate.CorrelationID))
+		if err != nil {
+			log.Error(gctx, "Error recording the template history in StatusTemplate repo function: %s", err.Error())
+			return err
+		}
+		return notifyTemplateChanges(ctx, tx, tr.Cfg, squirrel.Eq{"template_local_id": msgtemplate.TemplateLocalID})
 	})
 	if TxDB != nil {
 		log.Error(gctx, "Transaction rolling back in Status Template repo function:  %s", TxDB.Error())
@@ -244,20 +437,35 @@ func (tr *TemplateRepository) GetTemplatebyID2(gctx *gin.Context, msgtemplate *d
 }
 */
 
+// fetchTemplateQuery selects the templates matching where, with the names of their applications
+// in application_id
+func fetchTemplateQuery(where squirrel.Sqlizer) squirrel.SelectBuilder {
+	return dblib.Psql.Select("mt.template_local_id", "STRING_AGG(ma.application_name, ', ') AS application_id", "mt.template_name", "mt.template_format", "mt.sender_id", "mt.entity_id", "mt.template_id", "mt.message_type", "mt.gateway", "mt.status_cd").
+		From("msg_template mt").
+		Join("LATERAL unnest(string_to_array(mt.application_id, ',')) AS rt(rt_value) ON true").
+		Join("msg_application ma ON rt.rt_value::integer = ma.application_id").
+		Where(where).
+		GroupBy("mt.template_local_id", "mt.template_name", "mt.template_format", "mt.sender_id", "mt.entity_id", "mt.template_id", "mt.message_type", "mt.gateway", "mt.status_cd").
+		OrderBy("mt.template_local_id")
+}
+
 func (tr *TemplateRepository) FetchTemplateRepo(gctx *gin.Context, msgtemplate *domain.MaintainTemplate) ([]domain.MaintainTemplate, error) {
 
 	ctx, cancel := context.WithTimeout(gctx.Request.Context(), tr.Cfg.GetDuration("db.querytimeoutlow"))
 	defer cancel()
 
-	query := dblib.Psql.Select("mt.template_local_id", "STRING_AGG(ma.application_name, ', ') AS application_id", "mt.template_name", "mt.template_format", "mt.sender_id", "mt.entity_id", "mt.template_id", "mt.message_type", "mt.gateway", "mt.status_cd").
-		From("msg_template mt").
-		Join("LATERAL unnest(string_to_array(mt.application_id, ',')) AS rt(rt_value) ON true").
-		Join("msg_application ma ON rt.rt_value::integer = ma.application_id").
-		Join("msg_provider mp on mp.provider_id=mt.gateway::integer").
-		Where(squirrel.Eq{"template_local_id": msgtemplate.TemplateLocalID}).
-		GroupBy("mt.template_local_id", "mt.template_name", "mt.template_format", "mt.sender_id", "mt.entity_id", "mt.template_id", "mt.message_type", "mp.provider_name", "mt.status_cd").
-		OrderBy("mt.template_local_id")
-	return dblib.SelectRows(ctx, tr.Db, query, pgx.RowToStructByNameLax[domain.MaintainTemplate])
+	query := fetchTemplateQuery(squirrel.Eq{"mt.template_local_id": msgtemplate.TemplateLocalID})
+	return dblib.SelectRows(ctx, readDB(ctx, tr.Reads, tr.Db), query, pgx.RowToStructByNameLax[domain.MaintainTemplate], dblib.WithReadRetry())
+}
+
+// FetchTemplateByTemplateIDRepo fetches the template registered against the given DLT template id
+func (tr *TemplateRepository) FetchTemplateByTemplateIDRepo(gctx *gin.Context, msgtemplate *domain.MaintainTemplate) (domain.MaintainTemplate, error) {
+
+	ctx, cancel := context.WithTimeout(gctx.Request.Context(), tr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	query := fetchTemplateQuery(squirrel.Eq{"mt.template_id": msgtemplate.TemplateID}).Limit(1)
+	return dblib.SelectOne(ctx, readDB(ctx, tr.Reads, tr.Db), query, pgx.RowToStructByNameLax[domain.MaintainTemplate], dblib.WithReadRetry())
 }
 
 func (tr *TemplateRepository) UpdateTemplateRepo(gctx *gin.Context, msgtemplate *domain.MaintainTemplate) error {
@@ -280,6 +488,18 @@ func (tr *TemplateRepository) UpdateTemplateRepo(gctx *gin.Context, msgtemplate
 		if Counter.Count == 0 {
 			return errors.New("template does not exists, cannot update")
 		}
+		if err := checkTemplateGateway(ctx, tx, msgtemplate); err != nil {
+			return err
+		}
+		before, err := auditRowTx(ctx, tx, "msg_template", squirrel.Eq{"template_local_id": msgtemplate.TemplateLocalID})
+		if err != nil {
+			log.Error(gctx, "Error reading the template before the change in EditTemplate repo function: %s", err.Error())
+			return err
+		}
+		// the DLT template id may change, the caches holding it under its former id
+		if err := notifyTemplateChanges(ctx, tx, tr.Cfg, squirrel.Eq{"template_local_id": msgtemplate.TemplateLocalID}); err != nil {
+			return err
+		}
 		uquery := dblib.Psql.Update("msg_template").
 			Set("application_id", msgtemplate.ApplicationID).
 			Set("template_name", msgtemplate.TemplateName).
@@ -296,7 +516,11 @@ func (tr *TemplateRepository) UpdateTemplateRepo(gctx *gin.Context, msgtemplate
 			log.Error(gctx, "Error executing update query in EditTemplate repo function: %s", err.Error())
 			return err
 		}
-		return nil
+		if err := auditTemplateTx(ctx, tx, tr.Cfg, domain.AuditActionUpdate, msgtemplate.TemplateLocalID, before); err != nil {
+			log.Error(gctx, "Error appending to the audit log in EditTemplate repo function: %s", err.Error())
+			return err
+		}
+		return notifyTemplateChanges(ctx, tx, tr.Cfg, squirrel.Eq{"template_local_id": msgtemplate.TemplateLocalID})
 	})
 	if TxDB != nil {
 		log.Error(gctx, "Transaction rolling back in EditTemplate repo function:  %s", TxDB.Error())
@@ -313,14 +537,14 @@ func (tr *TemplateRepository) FetchTemplateByApplicationRepo(gctx *gin.Context,
 	var listTemplates []domain.GetTemplatebyAPPID
 
 	TxDB := tr.Db.WithTx(ctx, func(tx pgx.Tx) error {
-		query := dblib.Psql.Select("mt.template_local_id", "mt.template_name").
+		query := dblib.Psql.Select("mt.template_local_id", "mt.template_name", "mt.template_id", "mt.gateway", "mt.message_type").
 			From("msg_template mt").
 			Join("LATERAL unnest(string_to_array(mt.application_id, ',')) AS rt(rt_value) ON true").
 			Join("msg_application ma ON rt.rt_value::integer = ma.application_id").
 			Join("msg_provider mp on mp.provider_id=mt.gateway::integer").
 			Where("mt.status_cd = 1").                                                               // Add condition for status=1
 			Where("ARRAY["+msgtemplate.ApplicationID+"]::integer[] @> ARRAY[rt.rt_value::integer]"). // Check if given application_id exists in the array
-			GroupBy("mt.template_local_id", "mt.template_name").
+			GroupBy("mt.template_local_id", "mt.template_name", "mt.template_id", "mt.gateway", "mt.message_type").
 			OrderBy("mt.template_local_id")
 
 		err := dblib.TxRows(ctx, tx, query, pgx.RowToStructByNameLax[domain.GetTemplatebyAPPID], &listTemplates)
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"MgApplication/core/audit"
+	"MgApplication/core/domain"
+
+	dblib "MgApplication/api-db"
+	log "MgApplication/api-log"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// BulkStatusConfirmationError is returned when a filter would change the status of more
+// templates than a bulk status update changes without confirmation
+type BulkStatusConfirmationError struct {
+	Matched      int64
+	ConfirmAbove int64
+}
+
+func (e *BulkStatusConfirmationError) Error() string {
+	return fmt.Sprintf("the filter would change the status of %d templates, more than %d: set confirm to true to change them", e.Matched, e.ConfirmAbove)
+}
+
+// insertTemplateHistory records in msg_template_history the templates selected by changed, a
+// query of their template_local_id and status_cd named changed, returning their local ids. An
+// update returning its rows records them in the same statement.
+func insertTemplateHistory(changed squirrel.Sqlizer, action string, correlationID string) squirrel.InsertBuilder {
+	return dblib.Psql.Insert("msg_template_history").
+		PrefixExpr(squirrel.Expr("WITH changed AS (?)", changed)).
+		Columns("template_local_id", "action", "status_cd", "correlation_id").
+		// the nested select keeps ? placeholders, numbered by the insert
+		Select(squirrel.Select("template_local_id").
+			Column("?::varchar", action).
+			Column("status_cd").
+			Column("?::varchar", correlationID).
+			From("changed")).
+		Suffix("RETURNING template_local_id")
+}
+
+// BulkTemplateStatusRepo sets the status of the templates of bulk in a single update, recording
+// the templates changed in the template history and notifying their change to the template
+// caches. The templates already in the status are left alone. A filter matching more than
+// bulk.ConfirmAbove templates to change fails with a *BulkStatusConfirmationError unless
+// bulk.Confirm is set, nothing being changed.
+func (tr *TemplateRepository) BulkTemplateStatusRepo(gctx *gin.Context, bulk *domain.BulkTemplateStatus) (domain.BulkTemplateStatusResult, error) {
+
+	ctx, cancel := context.WithTimeout(gctx.Request.Context(), tr.Cfg.GetDuration("db.querytimeoutlow"))
+	defer cancel()
+
+	var where squirrel.Sqlizer = templateFilterPredicate(bulk.Filter)
+	if len(bulk.TemplateLocalIDs) > 0 {
+		where = squirrel.Eq{"mt.template_local_id": bulk.TemplateLocalIDs}
+	}
+	changing := squirrel.Expr("mt.status_cd IS DISTINCT FROM ?", bulk.Status)
+
+	var result domain.BulkTemplateStatusResult
+	TxDB := tr.Db.WithTx(ctx, func(tx pgx.Tx) error {
+		if len(bulk.TemplateLocalIDs) == 0 && !bulk.Confirm {
+			query := dblib.Psql.Select("COUNT(1) as count").
+				From("msg_template mt").
+				Where(where).
+				Where(changing)
+			var counter domain.Counter
+			if err := dblib.TxReturnRow(ctx, tx, query, pgx.RowToStructByPos[domain.Counter], &counter); err != nil {
+				log.Error(gctx, "Error counting the templates to change in BulkTemplateStatus repo function: %s", err.Error())
+				return err
+			}
+			if int64(counter.Count) > bulk.ConfirmAbove {
+				return &BulkStatusConfirmationError{Matched: int64(counter.Count), ConfirmAbove: bulk.ConfirmAbove}
+			}
+		}
+
+		before, err := auditRowsTx(ctx, tx, "msg_template", "mt", "template_local_id", squirrel.And{where, changing})
+		if err != nil {
+			log.Error(gctx, "Error reading the templates before the change in BulkTemplateStatus repo function: %s", err.Error())
+			return err
+		}
+
+		updated := squirrel.Update("msg_template mt").
+			Set("status_cd", bulk.Status).
+			Where(where).
+			Where(changing).
+			Suffix("RETURNING mt.template_local_id, mt.status_cd")
+		var changed []uint64
+		err = dblib.TxRows(ctx, tx, insertTemplateHistory(updated, domain.TemplateActionBulkStatus, bulk.CorrelationID), pgx.RowTo[uint64], &changed)
+		if err != nil {
+			log.Error(gctx, "Error executing update query in BulkTemplateStatus repo function: %s", err.Error())
+			return err
+		}
+		result.Updated = int64(len(changed))
+
+		if len(bulk.TemplateLocalIDs) > 0 {
+			query := dblib.Psql.Select("template_local_id").
+				From("msg_template").
+				Where(squirrel.Eq{"template_local_id": bulk.TemplateLocalIDs})
+			var existing []uint64
+			if err := dblib.TxRows(ctx, tx, query, pgx.RowTo[uint64], &existing); err != nil {
+				log.Error(gctx, "Error executing query in BulkTemplateStatus repo function: %s", err.Error())
+				return err
+			}
+			for _, id := range bulk.TemplateLocalIDs {
+				status := domain.TemplateStatusResult{TemplateLocalID: id, Result: domain.TemplateStatusNotFound}
+				switch {
+				case slices.Contains(changed, id):
+					status.Result = domain.TemplateStatusUpdated
+				case slices.Contains(existing, id):
+					status.Result = domain.TemplateStatusUnchanged
+				}
+				result.Results = append(result.Results, status)
+			}
+		}
+
+		if len(changed) == 0 {
+			return nil
+		}
+		after, err := auditRowsTx(ctx, tx, "msg_template", "mt", "template_local_id", squirrel.Eq{"mt.template_local_id": changed})
+		if err != nil {
+			log.Error(gctx, "Error reading the templates after the change in BulkTemplateStatus repo function: %s", err.Error())
+			return err
+		}
+		// one entry for the update, its diff keyed by template_local_id
+		err = appendAuditTx(ctx, tx, tr.Cfg, audit.Change{
+			Action:     domain.AuditActionStatus,
+			EntityType: domain.AuditEntityTemplate,
+			EntityID:   domain.AuditEntityIDBulk,
+			Before:     before,
+			After:      after,
+		})
+		if err != nil {
+			log.Error(gctx, "Error appending to the audit log in BulkTemplateStatus repo function: %s", err.Error())
+			return err
+		}
+		return notifyTemplateChanges(ctx, tx, tr.Cfg, squirrel.Eq{"template_local_id": changed})
+	})
+	if TxDB != nil {
+		log.Error(gctx, "Transaction rolling back in BulkTemplateStatus repo function: %s", TxDB.Error())
+		return domain.BulkTemplateStatusResult{}, TxDB
+	}
+	return result, nil
+}
@@ -71,10 +71,12 @@ package routes
 // 		// 	Template.POST("", templateHandler.CreateTemplateHandler)
 // 		// 	Template.GET("", templateHandler.ListTemplatesHandler)
 // 		// 	Template.GET("/:template-local-id", templateHandler.FetchTemplateHandler)
+// 		// 	Template.GET("/by-template-id/:template-id", templateHandler.FetchTemplateByTemplateIDHandler)
 // 		// 	Template.GET("/name", templateHandler.FetchTemplateByApplicationHandler) //by appID query param
 // 		// 	Template.GET("/details", templateHandler.FetchTemplateDetailsHandler)    //takes query param, by template-format is yet to be tested
 // 		// 	Template.PUT("/:template-local-id/status", templateHandler.ToggleTemplateStatusHandler)
 // 		// 	Template.PUT("/:template-local-id", templateHandler.UpdateTemplateHandler)
+// 		// 	Template.POST("/:template-local-id/preview", templateHandler.PreviewTemplateHandler)
 // 		// }
 
 // 		// v1.POST("/msgrequest/create", msgappHandler.CreateSMSRequestHandler)
@@ -84,6 +86,8 @@ package routes
 // 		// v1.POST("/test-sms-request", msgappHandler.CreateTestSMSHandler)
 
 // 		// v1.GET("/sms-delivery-status", msgappHandler.FetchCDACSMSDeliveryStatusHandler) //CDAC Delivery report
+// 		// v1.GET("/sms-request/:communication-id/status", msgappHandler.FetchSMSRequestStatusHandler)
+// 		// v1.POST("/sms-request/status/bulk", msgappHandler.FetchSMSRequestStatusBulkHandler)
 
 // 		// //reports
 // 		// v1.GET("/sms-dashboard", reportsHandler.SMSDashboardHandler)
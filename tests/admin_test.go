@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	log "MgApplication/api-log"
+
+	"gotest.tools/v3/assert"
+)
+
+func logLevelRequest(method string, scope string, body map[string]any) *httptest.ResponseRecorder {
+	input, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, "/v1/admin/log-level", bytes.NewBuffer(input))
+	req.Header.Set("Content-Type", "application/json")
+	if scope != "" {
+		setBearerToken(req, "", scope)
+	}
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+	return rec
+}
+
+type logLevelResponse struct {
+	Data struct {
+		Level            string `json:"level"`
+		ConfiguredLevel  string `json:"configured_level"`
+		OverrideActive   bool   `json:"override_active"`
+		RemainingSeconds int64  `json:"remaining_seconds"`
+	} `json:"data"`
+}
+
+// SetLogLevelHandler
+func TestSetLogLevelHandler(t *testing.T) {
+	t.Cleanup(log.ResetLevel)
+
+	rec := logLevelRequest("PUT", "reports,admin", map[string]any{"level": "debug", "duration_minutes": 30})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var rsp logLevelResponse
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, "debug", rsp.Data.Level)
+	assert.Assert(t, rsp.Data.OverrideActive)
+	assert.Assert(t, rsp.Data.RemainingSeconds > 0 && rsp.Data.RemainingSeconds <= 1800)
+
+	rec = logLevelRequest("GET", "admin", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, "debug", rsp.Data.Level)
+}
+
+func TestSetLogLevelHandler_Forbidden(t *testing.T) {
+	rec := logLevelRequest("PUT", "", map[string]any{"level": "debug", "duration_minutes": 30})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = logLevelRequest("GET", "reports", nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestSetLogLevelHandler_ValidationError(t *testing.T) {
+	rec := logLevelRequest("PUT", "admin", map[string]any{"level": "verbose", "duration_minutes": 0})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
@@ -2,6 +2,7 @@ package tests
 
 import (
 	"bytes"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -19,9 +20,22 @@ func TestCreateMessageApplicationHandlerSuccess(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/applications", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusCreated, rec.Code)
+	var rsp struct {
+		Data struct {
+			ApplicationID string `json:"application_id"`
+		} `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, "/v1/applications/"+rsp.Data.ApplicationID, rec.Header().Get("Location"))
+
+	// the Location header is the URL the application created is fetched from
+	req = httptest.NewRequest("GET", rec.Header().Get("Location"), nil)
+	rec = httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
 }
 
 func TestCreateMessageApplicationHandlerBindingError(t *testing.T) {
@@ -33,7 +47,7 @@ func TestCreateMessageApplicationHandlerBindingError(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/applications", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -47,7 +61,7 @@ func TestCreateMessageApplicationHandlerValidationError(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/applications", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
@@ -58,7 +72,7 @@ func Test_Create_Message_Application_MissingParam(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/applications", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
@@ -69,7 +83,7 @@ func TestListMessageApplicationsHandlerSuccess(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/applications", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -78,7 +92,7 @@ func TestListMessageApplicationsHandlerBindingError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/applications?status=false", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -87,7 +101,7 @@ func TestListMessageApplicationsHandlerValidationError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/applications", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
@@ -96,7 +110,7 @@ func TestListMessageApplicationsHandlerInvalidParam(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/applications", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -105,7 +119,7 @@ func TestListMessageApplicationsHandlerActiveSuccess(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/applications?status=true", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -115,7 +129,7 @@ func TestFetchApplicationHandlerSuccess(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/applications/5", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -124,7 +138,7 @@ func TestFetchApplicationHandlerBindingError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/applications/", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -133,7 +147,7 @@ func TestFetchApplicationHandlerValidationError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/applications/4a", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
@@ -142,7 +156,7 @@ func TestFetchApplicationHandlerInvalidParam(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/applications/5y", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -158,7 +172,7 @@ func TestUpdateMessageApplicationHandlerSuccess(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/applications/3", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -172,7 +186,7 @@ func TestUpdateMessageApplicationHandlerBindingError(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/applications/2", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -186,7 +200,7 @@ func TestUpdateMessageApplicationHandlerValidationError(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/applications/3", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
@@ -196,7 +210,7 @@ func TestToggleApplicationStatusHandlerSuccess(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/applications/4/status", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -205,7 +219,7 @@ func TestToggleApplicationStatusHandlerBindingError(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/applications//status", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -214,7 +228,7 @@ func TestToggleApplicationStatusHandlerValidationError(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/applications/a/status", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
@@ -223,7 +237,7 @@ func TestToggleApplicationStatusHandlerInvalidParam(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/applications/4/status", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
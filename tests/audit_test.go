@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"MgApplication/core/audit"
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+	repo "MgApplication/repo/postgres"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestAuditLogChain(t *testing.T) {
+	ctx := audit.WithRequest(context.Background(), audit.Request{Actor: "ops-audit", CorrelationID: "audit-corr-1"})
+	auditRepo := repo.NewAuditRepository(MgAppRepo.Db, MgAppRepo.Cfg)
+	codeRepo := repo.NewGatewayCodeRepository(MgAppRepo.Db, MgAppRepo.Cfg)
+
+	// the mutations append their entry in their own transaction
+	_, err := codeRepo.UpsertGatewayCodeRepo(ctx, domain.GatewayCode{Gateway: "CDAC", Code: "AUDIT-1", Description: "Audited", Severity: "warning"})
+	assert.NilError(t, err)
+	_, err = codeRepo.UpsertGatewayCodeRepo(ctx, domain.GatewayCode{Gateway: "CDAC", Code: "AUDIT-1", Description: "Audited again", Severity: "warning"})
+	assert.NilError(t, err)
+	deleted, err := codeRepo.DeleteGatewayCodeRepo(ctx, "CDAC", "AUDIT-1")
+	assert.NilError(t, err)
+	assert.Assert(t, deleted)
+
+	entries, err := auditRepo.ListAuditLogRepo(ctx, domain.AuditFilter{EntityType: domain.AuditEntityGatewayCode, EntityID: "CDAC/AUDIT-1"}, port.MetaDataRequest{Limit: 10})
+	assert.NilError(t, err)
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, domain.AuditActionDelete, entries[0].Action)
+	assert.Equal(t, domain.AuditActionUpdate, entries[1].Action)
+	assert.Equal(t, domain.AuditActionCreate, entries[2].Action)
+	assert.Equal(t, "ops-audit", entries[1].Actor)
+	assert.Equal(t, "audit-corr-1", entries[1].CorrelationID)
+	var diff map[string]struct {
+		Before any `json:"before"`
+		After  any `json:"after"`
+	}
+	assert.NilError(t, json.Unmarshal(entries[1].Diff, &diff))
+	assert.Equal(t, "Audited", diff["description"].Before)
+	assert.Equal(t, "Audited again", diff["description"].After)
+	assert.Equal(t, entries[1].Hash, entries[0].PrevHash)
+
+	verification, err := auditRepo.VerifyAuditLogRepo(ctx)
+	assert.NilError(t, err)
+	assert.Assert(t, verification.Verified, "%+v", verification)
+	assert.Assert(t, verification.Checked >= 3)
+
+	// a row edited afterwards breaks the chain at that row
+	corrupted := entries[1]
+	_, err = MgAppRepo.Db.Exec(context.Background(), `UPDATE msg_admin_audit SET actor = 'mallory' WHERE audit_id = $1`, corrupted.AuditID)
+	assert.NilError(t, err)
+	t.Cleanup(func() {
+		_, err := MgAppRepo.Db.Exec(context.Background(), `UPDATE msg_admin_audit SET actor = $2 WHERE audit_id = $1`, corrupted.AuditID, corrupted.Actor)
+		assert.NilError(t, err)
+	})
+
+	verification, err = auditRepo.VerifyAuditLogRepo(ctx)
+	assert.NilError(t, err)
+	assert.Assert(t, !verification.Verified)
+	assert.Assert(t, verification.BrokenAt != nil)
+	assert.Equal(t, corrupted.AuditID, *verification.BrokenAt)
+	assert.Equal(t, "hash does not match the content of the entry", verification.Reason)
+}
+
+func TestAuditLogRedactsApplicationSecretKey(t *testing.T) {
+	ctx := audit.WithRequest(context.Background(), audit.Request{Actor: "ops-audit"})
+	auditRepo := repo.NewAuditRepository(MgAppRepo.Db, MgAppRepo.Cfg)
+	appRepo := repo.NewApplicationRepository(MgAppRepo.Db, MgAppRepo.Cfg)
+
+	created, err := appRepo.CreateMsgApplicationRepo(ctx, &domain.MsgApplications{ApplicationName: "Audited Application", RequestType: "1", SecretKey: "audit-secret-value", Status: 1})
+	assert.NilError(t, err)
+
+	entries, err := auditRepo.ListAuditLogRepo(ctx, domain.AuditFilter{EntityType: domain.AuditEntityApplication, Action: domain.AuditActionCreate}, port.MetaDataRequest{Limit: 1})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "ops-audit", entries[0].Actor)
+	assert.Equal(t, strconv.FormatUint(created.ApplicationID, 10), entries[0].EntityID)
+	var diff map[string]struct {
+		After any `json:"after"`
+	}
+	assert.NilError(t, json.Unmarshal(entries[0].Diff, &diff))
+	assert.Equal(t, audit.RedactedValue, diff["secret_key"].After)
+	assert.Equal(t, "Audited Application", diff["application_name"].After)
+}
@@ -20,7 +20,7 @@ func TestInitiateBulkSmsSuccess(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/bulk-sms-initiate", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -30,7 +30,7 @@ func TestInitiateBulkSmsMissingParam(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/bulk-sms-initiate", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
@@ -39,7 +39,7 @@ func TestValidateTestSmsSuccess(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/bulk-sms-validate-otp?reference-id=4iq3zb8hzfaod8srftch&test-string=12345", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -48,7 +48,7 @@ func TestValidateTestSmsInvalidParam(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/bulk-sms-validate-otp?reference-id=4iq3zb8hzfaod8srftch&test-string=12345", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -81,7 +81,7 @@ func TestSendFileSuccess(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/bulk-sms", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusCreated, rec.Code)
 }
@@ -91,7 +91,7 @@ func TestSendFileMissingParam(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/bulk-sms", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
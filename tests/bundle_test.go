@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"MgApplication/core/domain"
+	repo "MgApplication/repo/postgres"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestConfigBundleRepo(t *testing.T) {
+	ctx := context.Background()
+	bundleRepo := repo.NewConfigBundleRepository(MgAppRepo.Db, MgAppRepo.Cfg)
+	app := domain.BundleApplication{ApplicationName: "Bundle Test Application", RequestType: "1", Status: true}
+	tmpl := domain.BundleTemplate{TemplateID: "1007999999999999901", ApplicationName: app.ApplicationName, TemplateName: "Bundle Test Template",
+		TemplateFormat: "Bundle test {#var#} - INDPOST", SenderID: "INPOST", Gateway: "1", MessageType: "PM", Status: true}
+
+	plan := domain.BundleImportPlan{
+		Applications: []domain.BundleApplicationChange{{Action: domain.BundleCreated, Application: app, SecretKey: "bundle-test-key"}},
+		Templates:    []domain.BundleTemplateChange{{Action: domain.BundleCreated, Template: tmpl}},
+	}
+	planner := func(current domain.ConfigBundle) (domain.BundleImportPlan, error) { return plan, nil }
+
+	// a dry run writes nothing
+	_, err := bundleRepo.ImportBundleRepo(ctx, planner, true)
+	assert.NilError(t, err)
+	bundle, err := bundleRepo.ExportBundleRepo(ctx)
+	assert.NilError(t, err)
+	for _, exported := range bundle.Applications {
+		assert.Assert(t, exported.ApplicationName != app.ApplicationName)
+	}
+
+	_, err = bundleRepo.ImportBundleRepo(ctx, planner, false)
+	assert.NilError(t, err)
+	bundle, err = bundleRepo.ExportBundleRepo(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, domain.ConfigBundleVersion, bundle.SchemaVersion)
+	var found bool
+	for _, exported := range bundle.Templates {
+		if exported.TemplateID == tmpl.TemplateID {
+			found = true
+			assert.DeepEqual(t, tmpl, exported)
+		}
+	}
+	assert.Assert(t, found)
+
+	// a failed plan rolls the import back
+	tmpl.TemplateName = "Bundle Test Template Renamed"
+	_, err = bundleRepo.ImportBundleRepo(ctx, func(current domain.ConfigBundle) (domain.BundleImportPlan, error) {
+		return domain.BundleImportPlan{}, errors.New("conflict")
+	}, false)
+	assert.ErrorContains(t, err, "conflict")
+
+	plan = domain.BundleImportPlan{
+		Applications: []domain.BundleApplicationChange{{Action: domain.BundlePruned, Application: app}},
+		Templates:    []domain.BundleTemplateChange{{Action: domain.BundleUpdated, Fields: []string{"template_name"}, Template: tmpl}},
+	}
+	_, err = bundleRepo.ImportBundleRepo(ctx, planner, false)
+	assert.NilError(t, err)
+	bundle, err = bundleRepo.ExportBundleRepo(ctx)
+	assert.NilError(t, err)
+	for _, exported := range bundle.Applications {
+		if exported.ApplicationName == app.ApplicationName {
+			assert.Assert(t, !exported.Status)
+		}
+	}
+	for _, exported := range bundle.Templates {
+		if exported.TemplateID == tmpl.TemplateID {
+			assert.Equal(t, "Bundle Test Template Renamed", exported.TemplateName)
+		}
+	}
+}
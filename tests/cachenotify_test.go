@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"MgApplication/core/domain"
+	"MgApplication/handler"
+	repo "MgApplication/repo/postgres"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/poll"
+)
+
+// cacheReplica is the template cache of a replica sharing the database with the others
+type cacheReplica struct {
+	cache    *handler.TemplateCache
+	listener *handler.CacheInvalidationListener
+}
+
+func startCacheReplica(t *testing.T, ctx context.Context) cacheReplica {
+	t.Helper()
+	registry := handler.NewCacheRegistry()
+	cache := handler.NewTemplateCache(OTPRepo.FetchOTPTemplateRepo, OTPRepo.Cfg)
+	registry.Register("templates", cache)
+	listener := handler.NewCacheInvalidationListener(repo.NewCacheNotifyRepository(MgAppRepo.Db, MgAppRepo.Cfg), registry, MgAppRepo.Cfg)
+	go listener.Run(ctx)
+	poll.WaitOn(t, func(poll.LogT) poll.Result {
+		if listener.WorkerStatus().LastCycle == nil {
+			return poll.Continue("listener not connected")
+		}
+		return poll.Success()
+	}, poll.WithTimeout(5*time.Second))
+	return cacheReplica{cache: cache, listener: listener}
+}
+
+func TestTemplateChangeInvalidatesEveryReplica(t *testing.T) {
+	assert.Assert(t, MgAppRepo.Cfg.GetBool("db.notify.enabled"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bundleRepo := repo.NewConfigBundleRepository(MgAppRepo.Db, MgAppRepo.Cfg)
+	app := domain.BundleApplication{ApplicationName: "Cache Notify Test Application", RequestType: "1", Status: true}
+	tmpl := domain.BundleTemplate{TemplateID: "1007999999999999902", ApplicationName: app.ApplicationName, TemplateName: "Cache Notify Template",
+		TemplateFormat: "Cache notify {#var#} - INDPOST", SenderID: "INPOST", Gateway: "1", MessageType: "PM", Status: true}
+	importPlan := func(plan domain.BundleImportPlan) {
+		_, err := bundleRepo.ImportBundleRepo(ctx, func(current domain.ConfigBundle) (domain.BundleImportPlan, error) { return plan, nil }, false)
+		assert.NilError(t, err)
+	}
+	importPlan(domain.BundleImportPlan{
+		Applications: []domain.BundleApplicationChange{{Action: domain.BundleCreated, Application: app, SecretKey: "cache-notify-key"}},
+		Templates:    []domain.BundleTemplateChange{{Action: domain.BundleCreated, Template: tmpl}},
+	})
+
+	replicas := []cacheReplica{startCacheReplica(t, ctx), startCacheReplica(t, ctx)}
+	for _, replica := range replicas {
+		cached, err := replica.cache.Get(ctx, tmpl.TemplateID)
+		assert.NilError(t, err)
+		assert.Equal(t, tmpl.TemplateName, cached.TemplateName)
+	}
+
+	// the change made through one replica reaches the cache of every replica
+	tmpl.TemplateName = "Cache Notify Template Renamed"
+	importPlan(domain.BundleImportPlan{
+		Templates: []domain.BundleTemplateChange{{Action: domain.BundleUpdated, Fields: []string{"template_name"}, Template: tmpl}},
+	})
+	for _, replica := range replicas {
+		poll.WaitOn(t, func(poll.LogT) poll.Result {
+			cached, err := replica.cache.Get(ctx, tmpl.TemplateID)
+			if err != nil {
+				return poll.Error(err)
+			}
+			if cached.TemplateName != tmpl.TemplateName {
+				return poll.Continue("replica still caches %q", cached.TemplateName)
+			}
+			return poll.Success()
+		}, poll.WithTimeout(5*time.Second))
+		assert.Equal(t, "", replica.listener.WorkerStatus().LastError)
+	}
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"MgApplication/handler"
+	"MgApplication/handler/response"
+
+	"github.com/gin-gonic/gin"
+	"gotest.tools/v3/assert"
+)
+
+// campaignServer serves the routes of a CampaignHandler
+func campaignServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	ch := handler.NewCampaignHandler(MgAppRepo, MgAppRepo.Cfg)
+
+	engine := gin.New()
+	for _, r := range ch.Routes() {
+		meta := r.Meta()
+		engine.Handle(meta.Method, "/v1"+meta.Path, meta.Func)
+	}
+	srv := httptest.NewServer(engine)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// sendRecipients sends file from offset on. With dropped set the multipart body ends after file,
+// as when the connection is lost.
+func sendRecipients(t *testing.T, url string, offset int, file string, dropped bool) (int, response.RecipientUploadAPIResponse) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "recipients.csv")
+	assert.NilError(t, err)
+	_, err = io.WriteString(part, file)
+	assert.NilError(t, err)
+	if !dropped {
+		assert.NilError(t, writer.Close())
+	}
+
+	req, err := http.NewRequest(http.MethodPatch, url, &body)
+	assert.NilError(t, err)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-", offset))
+	return doRecipientUpload(t, req)
+}
+
+func doRecipientUpload(t *testing.T, req *http.Request) (int, response.RecipientUploadAPIResponse) {
+	t.Helper()
+	resp, err := http.DefaultClient.Do(req)
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+	var rsp response.RecipientUploadAPIResponse
+	assert.NilError(t, json.NewDecoder(resp.Body).Decode(&rsp))
+	return resp.StatusCode, rsp
+}
+
+func TestCampaignRecipientUpload(t *testing.T) {
+	_, err := MgAppRepo.Db.Exec(context.Background(),
+		`INSERT INTO msg_bulk_file (reference_id, application_id) VALUES ('rcpcampaign0000000001', '7')`)
+	assert.NilError(t, err)
+
+	srv := campaignServer(t)
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/v1/campaigns/rcpcampaign0000000001/recipients", nil)
+	assert.NilError(t, err)
+	status, started := doRecipientUpload(t, req)
+	assert.Equal(t, http.StatusCreated, status)
+	url := srv.URL + "/v1/campaigns/rcpcampaign0000000001/recipients/" + started.Data.UploadToken
+
+	var file strings.Builder
+	file.WriteString("mobile_number\n")
+	for i := range 12000 {
+		fmt.Fprintf(&file, "9%09d\n", i%10000)
+	}
+	csv := file.String()
+
+	// the connection drops in the middle of a row, the rows read before are saved
+	cut := strings.Index(csv, "9000007000\n")
+	status, _ = sendRecipients(t, url, 0, csv[:cut+4], true)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	var offset, accepted int64
+	err = MgAppRepo.Db.QueryRow(context.Background(),
+		`SELECT upload_offset, rows_accepted FROM msg_bulk_file WHERE reference_id = 'rcpcampaign0000000001'`).Scan(&offset, &accepted)
+	assert.NilError(t, err)
+	assert.Equal(t, int64(7000), accepted)
+	assert.Equal(t, int64(cut), offset)
+
+	status, _ = sendRecipients(t, url, 0, csv, false)
+	assert.Equal(t, http.StatusConflict, status, "the upload continues at the saved offset")
+
+	status, rsp := sendRecipients(t, url, int(offset), csv[offset:], false)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Assert(t, rsp.Data.Completed)
+	assert.Equal(t, int64(12001), rsp.Data.Rows)
+	assert.Equal(t, int64(10000), rsp.Data.RowsAccepted)
+	assert.Equal(t, int64(2000), rsp.Data.RowsDuplicate)
+	assert.Equal(t, int64(1), rsp.Data.RowsRejected)
+	assert.Equal(t, int64(1), rsp.Data.Rejects[0].Row)
+
+	var recipients, uploaded int64
+	err = MgAppRepo.Db.QueryRow(context.Background(),
+		`SELECT count(*), max(b.no_of_sms_uploaded) FROM msg_campaign_recipient r JOIN msg_bulk_file b USING (reference_id)
+		 WHERE r.reference_id = 'rcpcampaign0000000001'`).Scan(&recipients, &uploaded)
+	assert.NilError(t, err)
+	assert.Equal(t, int64(10000), recipients)
+	assert.Equal(t, int64(10000), uploaded)
+}
+
+func TestCampaignRecipientUploadUnknownCampaign(t *testing.T) {
+	srv := campaignServer(t)
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/v1/campaigns/nosuchcampaign/recipients", nil)
+	assert.NilError(t, err)
+	status, _ := doRecipientUpload(t, req)
+	assert.Equal(t, http.StatusNotFound, status)
+}
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/clock"
+	"MgApplication/handler"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+// clientReferenceRequest sends an OTP with the given client_reference and metadata through a CDAC
+// server that records the payload it receives
+func clientReferenceRequest(t *testing.T, clientReference string, metadata string) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+	var payload string
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		payload = r.URL.RawQuery + string(body)
+		_, _ = w.Write([]byte("402,MsgID = 150920241726381202115hpgovsms"))
+	}))
+	defer cdac.Close()
+
+	c := config.NewConfig(viper.New())
+	c.Set("sms.msgstorerequest", 1)
+	c.Set("sms.cdac.url", cdac.URL)
+	c.Set("sms.cdac.username", "appostsms")
+	c.Set("sms.cdac.password", "cdacsecret")
+	c.Set("sms.cdac.securekey", "c7d427c9-63e7-4eec-a227-3ef840a75269")
+
+	engine := gin.New()
+	engine.POST("/v1/sms-request", handler.NewMgApplicationHandler(MgAppRepo, handler.NewDNDFilter(DNDRepo, c), c).CreateSMSRequestHandler)
+
+	input := `{
+		"application_id":"7",
+		"facility_id":"facility1",
+		"priority":1,
+		"message_text":"Dear Customer, OTP for booking is 1234, please do not share it with anyone - INDPOST",
+		"sender_id":"INPOST",
+		"mobile_numbers":"9000000001",
+		"entity_id":"1001081725895192800",
+		"template_id":"1007344609998507114",
+		"client_reference":"` + clientReference + `",
+		"metadata":` + metadata + `
+	}`
+	req := httptest.NewRequest("POST", "/v1/sms-request", bytes.NewBufferString(input))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec, payload
+}
+
+// storedClientReference returns the client reference and metadata stored for the request
+func storedClientReference(t *testing.T, communicationID string) (*string, map[string]string) {
+	t.Helper()
+	var clientReference *string
+	var metadata map[string]string
+	err := MgAppRepo.Db.QueryRow(context.Background(),
+		`SELECT client_reference, metadata FROM msg_request WHERE communication_id = $1`, communicationID).
+		Scan(&clientReference, &metadata)
+	assert.NilError(t, err)
+	return clientReference, metadata
+}
+
+func TestCreateSMSRequestStoresClientReference(t *testing.T) {
+	rec, payload := clientReferenceRequest(t, "BKG20240001", `{"case_number":"CASE7781","branch":"north"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var rsp struct {
+		Data struct {
+			CommunicationID string            `json:"communication_id"`
+			ClientReference string            `json:"client_reference"`
+			Metadata        map[string]string `json:"metadata"`
+		} `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, rsp.Data.ClientReference, "BKG20240001")
+	assert.DeepEqual(t, rsp.Data.Metadata, map[string]string{"case_number": "CASE7781", "branch": "north"})
+
+	clientReference, metadata := storedClientReference(t, strings.TrimSpace(rsp.Data.CommunicationID))
+	assert.Assert(t, clientReference != nil)
+	assert.Equal(t, *clientReference, "BKG20240001")
+	assert.DeepEqual(t, metadata, map[string]string{"case_number": "CASE7781", "branch": "north"})
+
+	// the gateway only receives the message
+	assert.Assert(t, payload != "")
+	for _, s := range []string{"BKG20240001", "CASE7781", "case_number", "north"} {
+		assert.Assert(t, !strings.Contains(payload, s), "gateway payload contains %s", s)
+	}
+}
+
+func TestCreateSMSRequestWithoutClientReference(t *testing.T) {
+	rec, _ := clientReferenceRequest(t, "", `null`)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var rsp struct {
+		Data map[string]any `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	_, ok := rsp.Data["client_reference"]
+	assert.Assert(t, !ok)
+
+	clientReference, metadata := storedClientReference(t, strings.TrimSpace(rsp.Data["communication_id"].(string)))
+	assert.Assert(t, clientReference == nil)
+	assert.Assert(t, metadata == nil)
+}
+
+func TestSmsSentStatusFiltersByClientReference(t *testing.T) {
+	for _, ref := range []string{"CASE-FILTER-1", "CASE-FILTER-1", "CASE-FILTER-2"} {
+		_, err := MgAppRepo.Db.Exec(context.Background(),
+			`INSERT INTO msg_request (application_id, priority, gateway, status, message_text, mobile_number, client_reference, metadata)
+			 VALUES ('7', 2, '1', 'submitted', 'Your parcel is delivered - INDPOST', '{9000000002}', $1, $2::jsonb)`,
+			ref, `{"booking_id":"`+ref+`"}`)
+		assert.NilError(t, err)
+	}
+
+	today := clock.InIST(clock.Now()).Format("02-01-2006")
+	req := httptest.NewRequest("GET", "/v1/sms-sent-status-report?from-date="+today+"&to-date="+today+"&client-reference=CASE-FILTER-1", nil)
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var rsp struct {
+		Data []struct {
+			ClientReference *string           `json:"client_reference"`
+			Metadata        map[string]string `json:"metadata"`
+		} `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, len(rsp.Data), 2)
+	for _, report := range rsp.Data {
+		assert.Assert(t, report.ClientReference != nil)
+		assert.Equal(t, *report.ClientReference, "CASE-FILTER-1")
+		assert.DeepEqual(t, report.Metadata, map[string]string{"booking_id": "CASE-FILTER-1"})
+	}
+}
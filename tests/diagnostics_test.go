@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"MgApplication/core/clock"
+	repo "MgApplication/repo/postgres"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestExplainQueryRolledBack(t *testing.T) {
+	ctx := context.Background()
+	diagnosticsRepo := repo.NewDiagnosticsRepository(MgAppRepo.Db, MgAppRepo.Cfg)
+	window := time.Date(2024, 9, 15, 10, 0, 0, 0, clock.IST)
+	before, err := MgAppRepo.GetSendCapRepo(ctx, "sender", "INPOST", window)
+	assert.NilError(t, err)
+
+	// ANALYZE executes the insert of send_cap_consume, which returns the messages counted
+	query, ok := repo.LookupDiagnosticQuery("send_cap_consume")
+	assert.Assert(t, ok)
+	plan, err := diagnosticsRepo.ExplainQueryRepo(ctx, query, 5*time.Second)
+	assert.NilError(t, err)
+	var node struct {
+		NodeType  string `json:"Node Type"`
+		Operation string `json:"Operation"`
+		Rows      int64  `json:"Actual Rows"`
+	}
+	assert.NilError(t, json.Unmarshal(plan.Plan, &node))
+	assert.Equal(t, "ModifyTable", node.NodeType)
+	assert.Equal(t, "Insert", node.Operation)
+	assert.Assert(t, plan.ExecutionTimeMs > 0)
+	assert.Equal(t, int64(5000), plan.StatementTimeoutMs)
+
+	// nothing it wrote is kept
+	after, err := MgAppRepo.GetSendCapRepo(ctx, "sender", "INPOST", window)
+	assert.NilError(t, err)
+	assert.Equal(t, before, after)
+
+	for _, name := range repo.DiagnosticQueryNames() {
+		query, _ := repo.LookupDiagnosticQuery(name)
+		_, err := diagnosticsRepo.ExplainQueryRepo(ctx, query, 5*time.Second)
+		assert.NilError(t, err, name)
+	}
+}
@@ -0,0 +1,270 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+	"MgApplication/handler"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+// dndConfig returns a configuration with the DND check enabled against checkURL
+func dndConfig(checkURL string, failOpen bool) *config.Config {
+	c := config.NewConfig(viper.New())
+	c.Set("sms.dnd.enabled", true)
+	c.Set("sms.dnd.checker", "http")
+	c.Set("sms.dnd.checkurl", checkURL)
+	c.Set("sms.dnd.batchsize", 2)
+	c.Set("sms.dnd.cachettl", time.Hour)
+	c.Set("sms.dnd.timeout", time.Second)
+	c.Set("sms.dnd.failopen", failOpen)
+	return c
+}
+
+// dndLookupServer answers DND lookups, treating the given numbers as registered, and
+// records the batches it was sent
+func dndLookupServer(t *testing.T, registered ...string) (*httptest.Server, *[][]string) {
+	t.Helper()
+	var batches [][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			MobileNumbers []string `json:"mobile_numbers"`
+		}
+		assert.NilError(t, json.NewDecoder(r.Body).Decode(&req))
+		batches = append(batches, req.MobileNumbers)
+
+		type result struct {
+			MobileNumber string `json:"mobile_number"`
+			DND          bool   `json:"dnd"`
+		}
+		var results []result
+		for _, mobileNumber := range req.MobileNumbers {
+			for _, dnd := range registered {
+				if mobileNumber == dnd {
+					results = append(results, result{MobileNumber: "91" + mobileNumber, DND: true})
+				}
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"results": results})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &batches
+}
+
+// HTTPDNDChecker
+func TestHTTPDNDCheckerBatchesLookups(t *testing.T) {
+	srv, batches := dndLookupServer(t, "9000000002", "9000000005")
+	checker := handler.NewHTTPDNDChecker(dndConfig(srv.URL, true))
+
+	dnd, err := checker.CheckDND(context.Background(), []string{"9000000001", "9000000002", "9000000003", "9000000004", "9000000005"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]bool{"9000000002": true, "9000000005": true}, dnd)
+	assert.Equal(t, 3, len(*batches))
+	assert.DeepEqual(t, []string{"9000000005"}, (*batches)[2])
+}
+
+func TestHTTPDNDCheckerCachesResults(t *testing.T) {
+	srv, batches := dndLookupServer(t, "9000000002")
+	checker := handler.NewHTTPDNDChecker(dndConfig(srv.URL, true))
+
+	_, err := checker.CheckDND(context.Background(), []string{"9000000001", "9000000002"})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(*batches))
+
+	// Both the registered and the unregistered number are answered from the cache
+	dnd, err := checker.CheckDND(context.Background(), []string{"9000000002", "9000000001", "9000000003"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]bool{"9000000002": true}, dnd)
+	assert.Equal(t, 2, len(*batches))
+	assert.DeepEqual(t, []string{"9000000003"}, (*batches)[1])
+}
+
+func TestHTTPDNDCheckerCacheExpires(t *testing.T) {
+	srv, batches := dndLookupServer(t)
+	c := dndConfig(srv.URL, true)
+	c.Set("sms.dnd.cachettl", 50*time.Millisecond)
+	checker := handler.NewHTTPDNDChecker(c)
+
+	_, err := checker.CheckDND(context.Background(), []string{"9000000001"})
+	assert.NilError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	_, err = checker.CheckDND(context.Background(), []string{"9000000001"})
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(*batches))
+}
+
+func TestHTTPDNDCheckerLookupError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	checker := handler.NewHTTPDNDChecker(dndConfig(srv.URL, true))
+
+	_, err := checker.CheckDND(context.Background(), []string{"9000000001"})
+	assert.ErrorContains(t, err, "503")
+}
+
+// DNDFilter
+func TestDNDFilterSkipsRegisteredNumbers(t *testing.T) {
+	srv, _ := dndLookupServer(t, "9000000002")
+	c := dndConfig(srv.URL, true)
+	filter := handler.NewDNDFilter(handler.NewHTTPDNDChecker(c), c)
+
+	allowed, skipped, err := filter.Filter(context.Background(), 3, []string{"9000000001", "+919000000002"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"9000000001"}, allowed)
+	assert.DeepEqual(t, []string{"+919000000002"}, skipped)
+}
+
+func TestDNDFilterBypassesOTPAndTransactional(t *testing.T) {
+	var lookups atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		_, _ = w.Write([]byte(`{"results":[{"mobile_number":"9000000002","dnd":true}]}`))
+	}))
+	defer srv.Close()
+	c := dndConfig(srv.URL, true)
+	filter := handler.NewDNDFilter(handler.NewHTTPDNDChecker(c), c)
+
+	for _, priority := range []int{1, 2} {
+		allowed, skipped, err := filter.Filter(context.Background(), priority, []string{"9000000002"})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []string{"9000000002"}, allowed)
+		assert.Equal(t, 0, len(skipped))
+	}
+	assert.Equal(t, int32(0), lookups.Load())
+}
+
+func TestDNDFilterDisabled(t *testing.T) {
+	srv, batches := dndLookupServer(t, "9000000002")
+	c := dndConfig(srv.URL, true)
+	c.Set("sms.dnd.enabled", false)
+	filter := handler.NewDNDFilter(handler.NewHTTPDNDChecker(c), c)
+
+	allowed, _, err := filter.Filter(context.Background(), 4, []string{"9000000002"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"9000000002"}, allowed)
+	assert.Equal(t, 0, len(*batches))
+}
+
+func TestDNDFilterFailurePolicy(t *testing.T) {
+	// The lookup service does not answer within sms.dnd.timeout
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		failOpen bool
+	}{
+		{name: "fail open", failOpen: true},
+		{name: "fail closed", failOpen: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := dndConfig(srv.URL, tt.failOpen)
+			c.Set("sms.dnd.timeout", 50*time.Millisecond)
+			filter := handler.NewDNDFilter(handler.NewHTTPDNDChecker(c), c)
+
+			start := time.Now()
+			allowed, skipped, err := filter.Filter(context.Background(), 3, []string{"9000000001"})
+			assert.Assert(t, time.Since(start) < 500*time.Millisecond)
+			assert.Equal(t, 0, len(skipped))
+			if tt.failOpen {
+				assert.NilError(t, err)
+				assert.DeepEqual(t, []string{"9000000001"}, allowed)
+			} else {
+				assert.ErrorContains(t, err, "DND check failed")
+				assert.Equal(t, 0, len(allowed))
+			}
+		})
+	}
+}
+
+// DNDRepository and ImportDNDRegistryHandler
+func importDNDRegistry(t *testing.T, csv string, replace bool) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "ncpr.csv")
+	assert.NilError(t, err)
+	_, _ = part.Write([]byte(csv))
+	if replace {
+		assert.NilError(t, writer.WriteField("replace", "true"))
+	}
+	assert.NilError(t, writer.Close())
+
+	req := httptest.NewRequest("POST", "/v1/admin/dnd-registry", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	setBearerToken(req, "ops1", "admin")
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestImportDNDRegistryHandler(t *testing.T) {
+	rec := importDNDRegistry(t, "mobile_number\n9100000001\n+919100000002\n9100000001\n12345\n", true)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	var rsp struct {
+		Data struct {
+			Received int   `json:"received"`
+			Imported int64 `json:"imported"`
+			Rejected int   `json:"rejected"`
+		} `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, 2, rsp.Data.Received)
+	assert.Equal(t, int64(2), rsp.Data.Imported)
+	assert.Equal(t, 2, rsp.Data.Rejected)
+
+	dnd, err := DNDRepo.CheckDND(context.Background(), []string{"9100000001", "9100000002", "9100000003"})
+	assert.NilError(t, err)
+	numbers := make([]string, 0, len(dnd))
+	for mobileNumber := range dnd {
+		numbers = append(numbers, mobileNumber)
+	}
+	sort.Strings(numbers)
+	assert.DeepEqual(t, []string{"9100000001", "9100000002"}, numbers)
+
+	// Replacing the registry drops numbers missing from the new file
+	rec = importDNDRegistry(t, "9100000003\n", true)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	dnd, err = DNDRepo.CheckDND(context.Background(), []string{"9100000001", "9100000003"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]bool{"9100000003": true}, dnd)
+}
+
+func TestImportDNDRegistryHandlerNoValidNumbers(t *testing.T) {
+	rec := importDNDRegistry(t, "mobile_number\n", false)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestDNDFilterWithLocalRegistry(t *testing.T) {
+	_, err := DNDRepo.ImportDNDRepo(context.Background(), []string{"9200000001"}, false)
+	assert.NilError(t, err)
+
+	c := dndConfig("", false)
+	c.Set("sms.dnd.checker", "local")
+	filter := handler.NewDNDFilter(DNDRepo, c)
+
+	allowed, skipped, err := filter.Filter(context.Background(), 4, []string{"919200000001", "9200000002"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"9200000002"}, allowed)
+	assert.DeepEqual(t, []string{"919200000001"}, skipped)
+}
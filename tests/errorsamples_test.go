@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"MgApplication/core/domain"
+	"MgApplication/core/port"
+	repo "MgApplication/repo/postgres"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestErrorSamplesRepo(t *testing.T) {
+	ctx := context.Background()
+	samplesRepo := repo.NewErrorSampleRepository(MgAppRepo.Db, MgAppRepo.Cfg)
+	now := time.Now().Truncate(time.Second)
+	stack := "goroutine 1 [running]:"
+
+	err := samplesRepo.SaveErrorSamplesRepo(ctx, []domain.ErrorSample{
+		{ErrorID: "ERRSAMPLE-1", Route: "/v1/errsample/:id", Method: "GET", Status: 500, CorrelationID: "req-1",
+			RequestSummary: "GET /v1/errsample/XXXXXXXXXX", Panicked: true, Stack: &stack, CreatedAt: now.Add(-2 * time.Hour)},
+		{ErrorID: "ERRSAMPLE-2", Route: "/v1/errsample/:id", Method: "GET", Status: 502, CreatedAt: now.Add(-time.Hour)},
+		{ErrorID: "ERRSAMPLE-3", Route: "/v1/errsample-other", Method: "POST", Status: 503, CreatedAt: now},
+		{ErrorID: "ERRSAMPLE-OLD", Route: "/v1/errsample/:id", Method: "GET", Status: 500, CreatedAt: now.Add(-100 * 24 * time.Hour)},
+	})
+	assert.NilError(t, err)
+
+	from := now.Add(-3 * time.Hour)
+	samples, err := samplesRepo.ListErrorSamplesRepo(ctx, domain.ErrorSampleFilter{Route: "/v1/errsample/:id", From: &from}, port.MetaDataRequest{Limit: 10})
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(samples))
+	// latest first
+	assert.Equal(t, "ERRSAMPLE-2", samples[0].ErrorID)
+	assert.Assert(t, samples[0].Stack == nil)
+	assert.Equal(t, "ERRSAMPLE-1", samples[1].ErrorID)
+	assert.Equal(t, "req-1", samples[1].CorrelationID)
+	assert.Assert(t, samples[1].Panicked)
+	assert.Equal(t, stack, *samples[1].Stack)
+
+	to := now.Add(-90 * time.Minute)
+	samples, err = samplesRepo.ListErrorSamplesRepo(ctx, domain.ErrorSampleFilter{Route: "/v1/errsample/:id", From: &from, To: &to}, port.MetaDataRequest{Limit: 10})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(samples))
+	assert.Equal(t, "ERRSAMPLE-1", samples[0].ErrorID)
+
+	purged, err := samplesRepo.PurgeErrorSamplesRepo(ctx, 90*24*time.Hour)
+	assert.NilError(t, err)
+	assert.Assert(t, purged >= 1)
+	samples, err = samplesRepo.ListErrorSamplesRepo(ctx, domain.ErrorSampleFilter{Route: "/v1/errsample/:id"}, port.MetaDataRequest{Limit: 10})
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(samples))
+}
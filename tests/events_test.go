@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+	"MgApplication/handler"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+// eventsServer serves the routes of an EventsHandler publishing through the returned hub
+func eventsServer(t *testing.T) (*httptest.Server, *handler.ProgressHub) {
+	t.Helper()
+	c := config.NewConfig(viper.New())
+	c.Set("events.sse.enabled", true)
+	c.Set("events.sse.buffersize", 8)
+	c.Set("events.sse.heartbeat", time.Minute)
+
+	hub := handler.NewProgressHub(c)
+	eh := handler.NewEventsHandler(MgAppRepo, hub, c)
+
+	engine := gin.New()
+	for _, r := range eh.Routes() {
+		meta := r.Meta()
+		engine.Handle(meta.Method, "/v1"+meta.Path, meta.Func)
+	}
+	srv := httptest.NewServer(engine)
+	t.Cleanup(srv.Close)
+	return srv, hub
+}
+
+// sseClient connects to an event stream and reads its events
+type sseClient struct {
+	resp   *http.Response
+	reader *bufio.Reader
+}
+
+func connectEvents(t *testing.T, url string) *sseClient {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	assert.NilError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NilError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return &sseClient{resp: resp, reader: bufio.NewReader(resp.Body)}
+}
+
+// next returns the name and data of the next event, skipping heartbeats
+func (sc *sseClient) next(t *testing.T) (string, string) {
+	t.Helper()
+	var name, data string
+	for {
+		line, err := sc.reader.ReadString('\n')
+		assert.NilError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && name != "":
+			return name, data
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestCampaignProgressStream(t *testing.T) {
+	_, err := MgAppRepo.Db.Exec(context.Background(),
+		`INSERT INTO msg_bulk_file (reference_id, application_id, no_of_sms_uploaded, no_of_sms_sent, no_of_sms_failed)
+		 VALUES ('evtcampaign0000000001', '7', 10, 6, 1)`)
+	assert.NilError(t, err)
+
+	srv, hub := eventsServer(t)
+	stream := connectEvents(t, srv.URL+"/v1/campaigns/evtcampaign0000000001/events")
+	assert.Equal(t, http.StatusOK, stream.resp.StatusCode)
+	assert.Equal(t, "text/event-stream", stream.resp.Header.Get("Content-Type"))
+
+	name, data := stream.next(t)
+	assert.Equal(t, "snapshot", name)
+	assert.Equal(t, `{"sent":6,"delivered":0,"failed":1}`, data)
+
+	hub.PublishCampaignProgress("evtcampaign0000000001", domain.DeliveryProgress{Sent: 1})
+	hub.PublishCampaignProgress("evtcampaign0000000002", domain.DeliveryProgress{Sent: 5})
+	hub.PublishCampaignProgress("evtcampaign0000000001", domain.DeliveryProgress{Failed: 1})
+
+	name, data = stream.next(t)
+	assert.Equal(t, "progress", name)
+	assert.Equal(t, `{"sent":1,"delivered":0,"failed":0}`, data)
+	_, data = stream.next(t)
+	assert.Equal(t, `{"sent":0,"delivered":0,"failed":1}`, data)
+}
+
+func TestCampaignProgressStreamUnknownCampaign(t *testing.T) {
+	srv, _ := eventsServer(t)
+	resp, err := http.Get(srv.URL + "/v1/campaigns/nosuchcampaign/events")
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestApplicationProgressStreamFromStatusPoll(t *testing.T) {
+	cdac, _ := cdacReportServer(t, map[string]string{
+		"SP0000020appostsms": "9000000001,DELIVRD,2024-08-27 10:00:00\n",
+		"SP0000021appostsms": "9000000002,UNDELIV,2024-08-27 10:00:00\n",
+	})
+	insertSubmittedMessage(t, "SP0000020")
+	insertSubmittedMessage(t, "SP0000021")
+
+	srv, hub := eventsServer(t)
+	stream := connectEvents(t, srv.URL+"/v1/applications/7/events")
+
+	name, _ := stream.next(t)
+	assert.Equal(t, "snapshot", name)
+
+	poller, err := handler.NewDeliveryStatusPoller(MgAppRepo, hub, statusPollConfig(cdac.URL, ""))
+	assert.NilError(t, err)
+	_, err = poller.Poll(context.Background())
+	assert.NilError(t, err)
+
+	// Other messages of the application may be polled as well, so collect until both arrived
+	var delivered, failed int
+	for delivered == 0 || failed == 0 {
+		name, data := stream.next(t)
+		assert.Equal(t, "progress", name)
+		switch data {
+		case `{"sent":0,"delivered":1,"failed":0}`:
+			delivered++
+		case `{"sent":0,"delivered":0,"failed":1}`:
+			failed++
+		}
+	}
+}
+
+func TestProgressStreamDisabled(t *testing.T) {
+	c := config.NewConfig(viper.New())
+	hub := handler.NewProgressHub(c)
+	assert.Assert(t, hub == nil)
+
+	// Publishing to the disabled hub is a no-op
+	hub.PublishApplicationProgress("7", domain.DeliveryProgress{Sent: 1})
+	assert.Equal(t, 0, len(handler.NewEventsHandler(MgAppRepo, hub, c).Routes()))
+}
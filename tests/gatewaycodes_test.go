@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	config "MgApplication/api-config"
+	"MgApplication/handler"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+// insertRespondedMessage stores a message of the application answered by the gateway with responseCode
+func insertRespondedMessage(t *testing.T, applicationID string, gateway string, responseCode string, createdDate string) {
+	t.Helper()
+	_, err := MgAppRepo.Db.Exec(context.Background(),
+		`INSERT INTO msg_request (application_id, priority, gateway, status, mobile_number, response_code, created_date)
+		 VALUES ($1, 2, $2, 'failed', '{9000000001}', $3, $4::timestamp)`, applicationID, gateway, responseCode, createdDate)
+	assert.NilError(t, err)
+}
+
+type statusReportRow struct {
+	ApplicationID       string  `json:"application_id"`
+	ResponseCode        string  `json:"response_code"`
+	ResponseDescription string  `json:"response_description"`
+	ResponseSeverity    *string `json:"response_severity"`
+	RecommendedAction   *string `json:"recommended_action"`
+}
+
+// statusReportRows returns the sent status report rows of one application on day (dd-mm-yyyy)
+func statusReportRows(t *testing.T, applicationID string, day string) []statusReportRow {
+	t.Helper()
+	engine := gin.New()
+	engine.GET("/v1/sms-sent-status-report", handler.NewReportsHandler(ReportsRepo, config.NewConfig(viper.New())).SentSMSStatusReportHandler)
+
+	req := httptest.NewRequest("GET", "/v1/sms-sent-status-report?from-date="+day+"&to-date="+day, nil)
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var rsp struct {
+		Data []statusReportRow `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	var rows []statusReportRow
+	for _, row := range rsp.Data {
+		if row.ApplicationID == applicationID {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func TestStatusReportDescribesGatewayCode(t *testing.T) {
+	insertRespondedMessage(t, "9911", "1", "406", "2024-04-02 10:00:00")
+
+	rows := statusReportRows(t, "9911", "02-04-2024")
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, "406", rows[0].ResponseCode)
+	assert.Assert(t, rows[0].ResponseDescription != "" && rows[0].ResponseDescription != "undocumented")
+	assert.Assert(t, rows[0].ResponseSeverity != nil)
+	assert.Equal(t, "warning", *rows[0].ResponseSeverity)
+}
+
+func TestStatusReportUndocumentedGatewayCode(t *testing.T) {
+	insertRespondedMessage(t, "9912", "2", "ES-NEW-CODE", "2024-04-03 10:00:00")
+	insertRespondedMessage(t, "9912", "2", "ES-NEW-CODE", "2024-04-03 11:00:00")
+	before := testutil.ToFloat64(handler.GatewayCodeUndocumentedTotal.WithLabelValues("2", "ES-NEW-CODE"))
+
+	rows := statusReportRows(t, "9912", "03-04-2024")
+	assert.Equal(t, 2, len(rows))
+	for _, row := range rows {
+		assert.Equal(t, "undocumented", row.ResponseDescription)
+		assert.Assert(t, row.ResponseSeverity == nil)
+	}
+	// Counted once per report rather than once per row
+	assert.Equal(t, before+1, testutil.ToFloat64(handler.GatewayCodeUndocumentedTotal.WithLabelValues("2", "ES-NEW-CODE")))
+}
+
+func gatewayCodeRequest(method string, path string, scope string, body map[string]any) *httptest.ResponseRecorder {
+	input, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewBuffer(input))
+	req.Header.Set("Content-Type", "application/json")
+	if scope != "" {
+		setBearerToken(req, "", scope)
+	}
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+	return rec
+}
+
+type gatewayCodesResponse struct {
+	Data []struct {
+		Gateway           string  `json:"gateway"`
+		Code              string  `json:"code"`
+		Description       string  `json:"description"`
+		Severity          string  `json:"severity"`
+		RecommendedAction *string `json:"recommended_action"`
+	} `json:"data"`
+}
+
+func TestMetaGatewayCodes(t *testing.T) {
+	rec := gatewayCodeRequest("GET", "/v1/meta/gateway-codes?gateway=2", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var rsp gatewayCodesResponse
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Assert(t, len(rsp.Data) > 0)
+	found := false
+	for _, code := range rsp.Data {
+		assert.Equal(t, "2", code.Gateway)
+		if code.Code == "ES-TM-BLOCKED" {
+			found = true
+		}
+	}
+	assert.Assert(t, found)
+
+	rec = gatewayCodeRequest("GET", "/v1/meta/gateway-codes?gateway=3", "", nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestAdminGatewayCodes(t *testing.T) {
+	rec := gatewayCodeRequest("PUT", "/v1/admin/gateway-codes/1/418", "admin", map[string]any{
+		"description":        "Template variable mismatch",
+		"severity":           "error",
+		"recommended_action": "Check the message text against the registered template",
+	})
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	codes, err := GatewayCodeRepo.ListGatewayCodesRepo(context.Background(), "1")
+	assert.NilError(t, err)
+	var description string
+	for _, code := range codes {
+		if code.Code == "418" {
+			description = code.Description
+		}
+	}
+	assert.Equal(t, "Template variable mismatch", description)
+
+	insertRespondedMessage(t, "9913", "1", "418", "2024-04-04 10:00:00")
+	rows := statusReportRows(t, "9913", "04-04-2024")
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, "Template variable mismatch", rows[0].ResponseDescription)
+
+	rec = gatewayCodeRequest("DELETE", "/v1/admin/gateway-codes/1/418", "admin", nil)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = gatewayCodeRequest("DELETE", "/v1/admin/gateway-codes/1/418", "admin", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rows = statusReportRows(t, "9913", "04-04-2024")
+	assert.Equal(t, "undocumented", rows[0].ResponseDescription)
+}
+
+func TestAdminGatewayCodes_Forbidden(t *testing.T) {
+	rec := gatewayCodeRequest("PUT", "/v1/admin/gateway-codes/1/418", "", map[string]any{"description": "x", "severity": "error"})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	dblib "MgApplication/api-db"
+
+	"gotest.tools/v3/assert"
+)
+
+var sampleMigrations = fstest.MapFS{
+	"0001_migrate_sample.up.sql": {Data: []byte(`CREATE TABLE msggateway.migrate_sample (id int PRIMARY KEY, note text);`)},
+	"0002_migrate_sample_row.up.sql": {Data: []byte(`BEGIN;
+INSERT INTO msggateway.migrate_sample (id, note) VALUES (1, 'applied once');
+ALTER TABLE msggateway.migrate_sample ADD COLUMN created_date timestamptz DEFAULT now();
+COMMIT;`)},
+}
+
+func TestMigrateAppliesSampleMigrationOnce(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	t.Cleanup(func() {
+		_, _ = MgAppRepo.Db.Exec(context.Background(), `DROP TABLE IF EXISTS msggateway.migrate_sample, migrate_sample_migrations`)
+	})
+
+	// the instances starting together wait for the advisory lock, one applies the migrations
+	var wg sync.WaitGroup
+	versions := make([]uint, 3)
+	errs := make([]error, 3)
+	for i := range versions {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, versions[i], errs[i] = dblib.Migrate(ctx, MgAppRepo.Db, sampleMigrations, ".", "migrate_sample_migrations")
+		}(i)
+	}
+	wg.Wait()
+	for i := range versions {
+		assert.NilError(t, errs[i])
+		assert.Equal(t, uint(2), versions[i])
+	}
+
+	var rows int
+	err := MgAppRepo.Db.QueryRow(ctx, `SELECT count(*) FROM msggateway.migrate_sample WHERE created_date IS NOT NULL`).Scan(&rows)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, rows)
+
+	from, to, err := dblib.Migrate(ctx, MgAppRepo.Db, sampleMigrations, ".", "migrate_sample_migrations")
+	assert.NilError(t, err)
+	assert.Equal(t, uint(2), from)
+	assert.Equal(t, uint(2), to)
+}
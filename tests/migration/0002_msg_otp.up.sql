@@ -0,0 +1,17 @@
+CREATE TABLE msggateway.msg_otp (
+    otp_id bigserial NOT NULL,
+    otp_reference character varying(64) NOT NULL,
+    application_id character varying(20) NOT NULL,
+    mobile_number character varying(15) NOT NULL,
+    otp_hash character varying(100) NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    max_attempts integer NOT NULL,
+    expires_at timestamp without time zone NOT NULL,
+    verified_date timestamp without time zone,
+    created_date timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT msg_otp_pkey PRIMARY KEY (otp_id),
+    CONSTRAINT msg_otp_reference_key UNIQUE (otp_reference)
+);
+
+CREATE INDEX msg_otp_mobile_created_idx ON msggateway.msg_otp USING btree (mobile_number, created_date);
+CREATE INDEX msg_otp_expires_at_idx ON msggateway.msg_otp USING btree (expires_at);
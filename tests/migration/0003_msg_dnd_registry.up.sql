@@ -0,0 +1,5 @@
+CREATE TABLE msggateway.msg_dnd_registry (
+    mobile_number character varying(15) NOT NULL,
+    created_date timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT msg_dnd_registry_pkey PRIMARY KEY (mobile_number)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE msggateway.msg_request ADD COLUMN status_polled_at timestamp without time zone;
+
+CREATE INDEX idx_msg_request_status_poll ON msggateway.msg_request USING btree (status_polled_at NULLS FIRST, request_id) WHERE ((status)::text = 'submitted'::text);
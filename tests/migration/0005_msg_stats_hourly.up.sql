@@ -0,0 +1,13 @@
+CREATE TABLE msggateway.msg_stats_hourly (
+    application_id character varying NOT NULL,
+    gateway character varying NOT NULL,
+    priority integer NOT NULL,
+    hour timestamp without time zone NOT NULL,
+    sent bigint DEFAULT 0 NOT NULL,
+    failed bigint DEFAULT 0 NOT NULL,
+    delivered bigint DEFAULT 0 NOT NULL,
+    total_cost numeric(14,4) DEFAULT 0 NOT NULL,
+    CONSTRAINT msg_stats_hourly_pkey PRIMARY KEY (application_id, gateway, priority, hour)
+);
+
+CREATE INDEX idx_msg_stats_hourly_hour ON msggateway.msg_stats_hourly USING btree (hour);
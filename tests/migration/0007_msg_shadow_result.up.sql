@@ -0,0 +1,21 @@
+ALTER TABLE msggateway.msg_application ADD COLUMN shadow_gateway character varying;
+ALTER TABLE msggateway.msg_application ADD CONSTRAINT msg_application_shadow_gateway_check CHECK (shadow_gateway IN ('1', '2'));
+
+CREATE TABLE msggateway.msg_shadow_result (
+    shadow_id bigserial NOT NULL,
+    communication_id character varying NOT NULL,
+    application_id character varying NOT NULL,
+    template_id character varying,
+    recipients integer DEFAULT 0 NOT NULL,
+    primary_gateway character varying NOT NULL,
+    primary_response_code character varying,
+    primary_accepted boolean DEFAULT false NOT NULL,
+    shadow_gateway character varying NOT NULL,
+    shadow_response_code character varying,
+    shadow_response_text character varying,
+    shadow_accepted boolean DEFAULT false NOT NULL,
+    created_date timestamp without time zone DEFAULT LOCALTIMESTAMP NOT NULL,
+    CONSTRAINT msg_shadow_result_pkey PRIMARY KEY (shadow_id)
+);
+
+CREATE INDEX idx_msg_shadow_result_created_date ON msggateway.msg_shadow_result USING btree (created_date, application_id);
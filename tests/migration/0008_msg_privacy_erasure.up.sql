@@ -0,0 +1,23 @@
+CREATE TABLE msggateway.msg_privacy_erasure (
+    erasure_id bigserial NOT NULL,
+    tombstone bigint NOT NULL,
+    from_date date,
+    to_date date,
+    requested_by character varying NOT NULL,
+    status character varying DEFAULT 'running' NOT NULL,
+    created_date timestamp without time zone DEFAULT LOCALTIMESTAMP NOT NULL,
+    completed_date timestamp without time zone,
+    CONSTRAINT msg_privacy_erasure_pkey PRIMARY KEY (erasure_id),
+    CONSTRAINT msg_privacy_erasure_status_check CHECK (status IN ('running', 'completed'))
+);
+
+CREATE UNIQUE INDEX idx_msg_privacy_erasure_running ON msggateway.msg_privacy_erasure USING btree (tombstone, COALESCE(from_date, '-infinity'::date), COALESCE(to_date, 'infinity'::date)) WHERE ((status)::text = 'running'::text);
+
+CREATE TABLE msggateway.msg_privacy_erasure_table (
+    erasure_id bigint NOT NULL,
+    table_name character varying NOT NULL,
+    rows_affected bigint DEFAULT 0 NOT NULL,
+    completed boolean DEFAULT false NOT NULL,
+    CONSTRAINT msg_privacy_erasure_table_pkey PRIMARY KEY (erasure_id, table_name),
+    CONSTRAINT msg_privacy_erasure_table_erasure_id_fkey FOREIGN KEY (erasure_id) REFERENCES msggateway.msg_privacy_erasure(erasure_id)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE msggateway.msg_request ADD COLUMN client_reference character varying(64);
+ALTER TABLE msggateway.msg_request ADD COLUMN metadata jsonb;
+
+CREATE INDEX idx_msg_request_client_reference ON msggateway.msg_request USING btree (client_reference) WHERE (client_reference IS NOT NULL);
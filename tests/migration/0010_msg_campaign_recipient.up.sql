@@ -0,0 +1,14 @@
+ALTER TABLE msggateway.msg_bulk_file ADD COLUMN rows_accepted int8 DEFAULT 0 NOT NULL;
+ALTER TABLE msggateway.msg_bulk_file ADD COLUMN rows_rejected int8 DEFAULT 0 NOT NULL;
+ALTER TABLE msggateway.msg_bulk_file ADD COLUMN rows_duplicate int8 DEFAULT 0 NOT NULL;
+ALTER TABLE msggateway.msg_bulk_file ADD COLUMN upload_token varchar(32) NULL;
+ALTER TABLE msggateway.msg_bulk_file ADD COLUMN upload_offset int8 DEFAULT 0 NOT NULL;
+ALTER TABLE msggateway.msg_bulk_file ADD COLUMN upload_rows int8 DEFAULT 0 NOT NULL;
+ALTER TABLE msggateway.msg_bulk_file ADD COLUMN upload_completed bool DEFAULT false NOT NULL;
+ALTER TABLE msggateway.msg_bulk_file ADD COLUMN upload_rejects jsonb NULL;
+
+CREATE TABLE msggateway.msg_campaign_recipient (
+    reference_id bpchar(32) NOT NULL,
+    mobile_number character varying(10) NOT NULL,
+    CONSTRAINT msg_campaign_recipient_pkey PRIMARY KEY (reference_id, mobile_number)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE msggateway.msg_webhook_outbox (
+    outbox_id bigserial NOT NULL,
+    communication_id character varying NOT NULL,
+    payload jsonb NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    next_attempt_at timestamp without time zone DEFAULT LOCALTIMESTAMP NOT NULL,
+    last_error character varying,
+    created_date timestamp without time zone DEFAULT LOCALTIMESTAMP NOT NULL,
+    CONSTRAINT msg_webhook_outbox_pkey PRIMARY KEY (outbox_id)
+);
+
+CREATE INDEX idx_msg_webhook_outbox_next_attempt_at ON msggateway.msg_webhook_outbox USING btree (next_attempt_at);
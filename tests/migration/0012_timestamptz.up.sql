@@ -0,0 +1,64 @@
+-- Store every timestamp as timestamptz. The existing values were written by servers running
+-- in UTC, so they are read as UTC instants. The hourly stats rollup keeps its UTC hours until
+-- the affected days are rebuilt with POST /v1/admin/stats/backfill, new rows are bucketed by IST hour.
+
+ALTER TABLE msggateway.msg_application
+    ALTER COLUMN created_date TYPE timestamp with time zone USING created_date AT TIME ZONE 'UTC',
+    ALTER COLUMN created_date SET DEFAULT CURRENT_TIMESTAMP,
+    ALTER COLUMN updated_date TYPE timestamp with time zone USING updated_date AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_date SET DEFAULT CURRENT_TIMESTAMP;
+
+ALTER TABLE msggateway.msg_bulk_file
+    ALTER COLUMN uploaded_time TYPE timestamp with time zone USING uploaded_time AT TIME ZONE 'UTC',
+    ALTER COLUMN uploaded_time SET DEFAULT CURRENT_TIMESTAMP,
+    ALTER COLUMN updated_time TYPE timestamp with time zone USING updated_time AT TIME ZONE 'UTC';
+
+ALTER TABLE msggateway.msg_counter
+    ALTER COLUMN request_date TYPE timestamp with time zone USING request_date AT TIME ZONE 'UTC';
+
+ALTER TABLE msggateway.msg_log
+    ALTER COLUMN created_date TYPE timestamp with time zone USING created_date AT TIME ZONE 'UTC',
+    ALTER COLUMN created_date SET DEFAULT CURRENT_TIMESTAMP;
+
+ALTER TABLE msggateway.msg_request
+    ALTER COLUMN created_date TYPE timestamp with time zone USING created_date AT TIME ZONE 'UTC',
+    ALTER COLUMN created_date SET DEFAULT CURRENT_TIMESTAMP,
+    ALTER COLUMN updated_date TYPE timestamp with time zone USING updated_date AT TIME ZONE 'UTC',
+    ALTER COLUMN status_polled_at TYPE timestamp with time zone USING status_polled_at AT TIME ZONE 'UTC';
+
+ALTER TABLE msggateway.msg_template
+    ALTER COLUMN created_date TYPE timestamp with time zone USING created_date AT TIME ZONE 'UTC',
+    ALTER COLUMN created_date SET DEFAULT CURRENT_TIMESTAMP;
+
+ALTER TABLE msggateway.msg_otp
+    ALTER COLUMN expires_at TYPE timestamp with time zone USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN verified_date TYPE timestamp with time zone USING verified_date AT TIME ZONE 'UTC',
+    ALTER COLUMN created_date TYPE timestamp with time zone USING created_date AT TIME ZONE 'UTC',
+    ALTER COLUMN created_date SET DEFAULT CURRENT_TIMESTAMP;
+
+ALTER TABLE msggateway.msg_dnd_registry
+    ALTER COLUMN created_date TYPE timestamp with time zone USING created_date AT TIME ZONE 'UTC',
+    ALTER COLUMN created_date SET DEFAULT CURRENT_TIMESTAMP;
+
+ALTER TABLE msggateway.msg_stats_hourly
+    ALTER COLUMN hour TYPE timestamp with time zone USING hour AT TIME ZONE 'UTC';
+
+ALTER TABLE msggateway.msg_gateway_code
+    ALTER COLUMN created_date TYPE timestamp with time zone USING created_date AT TIME ZONE 'UTC',
+    ALTER COLUMN created_date SET DEFAULT CURRENT_TIMESTAMP,
+    ALTER COLUMN updated_date TYPE timestamp with time zone USING updated_date AT TIME ZONE 'UTC';
+
+ALTER TABLE msggateway.msg_shadow_result
+    ALTER COLUMN created_date TYPE timestamp with time zone USING created_date AT TIME ZONE 'UTC',
+    ALTER COLUMN created_date SET DEFAULT CURRENT_TIMESTAMP;
+
+ALTER TABLE msggateway.msg_privacy_erasure
+    ALTER COLUMN created_date TYPE timestamp with time zone USING created_date AT TIME ZONE 'UTC',
+    ALTER COLUMN created_date SET DEFAULT CURRENT_TIMESTAMP,
+    ALTER COLUMN completed_date TYPE timestamp with time zone USING completed_date AT TIME ZONE 'UTC';
+
+ALTER TABLE msggateway.msg_webhook_outbox
+    ALTER COLUMN next_attempt_at TYPE timestamp with time zone USING next_attempt_at AT TIME ZONE 'UTC',
+    ALTER COLUMN next_attempt_at SET DEFAULT CURRENT_TIMESTAMP,
+    ALTER COLUMN created_date TYPE timestamp with time zone USING created_date AT TIME ZONE 'UTC',
+    ALTER COLUMN created_date SET DEFAULT CURRENT_TIMESTAMP;
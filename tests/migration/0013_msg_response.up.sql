@@ -0,0 +1,11 @@
+CREATE TABLE msggateway.msg_response (
+    response_id bigserial NOT NULL,
+    communication_id character varying NOT NULL,
+    reference_id character varying,
+    response_code character varying,
+    response_message character varying,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT msg_response_pkey PRIMARY KEY (response_id)
+);
+
+CREATE INDEX idx_msg_response_communication_id_created_at ON msggateway.msg_response USING btree (communication_id, created_at DESC);
@@ -0,0 +1,16 @@
+CREATE TABLE msggateway.msg_error_sample (
+    sample_id bigserial NOT NULL,
+    error_id character varying NOT NULL,
+    route character varying NOT NULL,
+    method character varying NOT NULL,
+    status integer NOT NULL,
+    correlation_id character varying,
+    request_summary character varying,
+    panicked boolean DEFAULT false NOT NULL,
+    stack text,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT msg_error_sample_pkey PRIMARY KEY (sample_id)
+);
+
+CREATE INDEX idx_msg_error_sample_created_at ON msggateway.msg_error_sample USING btree (created_at);
+CREATE INDEX idx_msg_error_sample_route_created_at ON msggateway.msg_error_sample USING btree (route, created_at);
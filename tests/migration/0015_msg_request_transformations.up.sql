@@ -0,0 +1 @@
+ALTER TABLE msggateway.msg_request ADD COLUMN transformations text[];
@@ -0,0 +1,9 @@
+CREATE TABLE msggateway.msg_send_quota (
+    application_id character varying NOT NULL,
+    kind character varying NOT NULL,
+    day timestamp with time zone NOT NULL,
+    sent bigint DEFAULT 0 NOT NULL,
+    CONSTRAINT msg_send_quota_pkey PRIMARY KEY (application_id, kind, day)
+);
+
+CREATE INDEX idx_msg_send_quota_day ON msggateway.msg_send_quota USING btree (day);
@@ -0,0 +1,11 @@
+CREATE TABLE msggateway.msg_template_history (
+    history_id bigserial NOT NULL,
+    template_local_id integer NOT NULL,
+    action character varying NOT NULL,
+    status_cd integer,
+    correlation_id character varying,
+    changed_date timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT msg_template_history_pkey PRIMARY KEY (history_id)
+);
+
+CREATE INDEX idx_msg_template_history_template_local_id ON msggateway.msg_template_history USING btree (template_local_id, changed_date);
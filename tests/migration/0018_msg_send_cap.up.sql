@@ -0,0 +1,9 @@
+CREATE TABLE msggateway.msg_send_cap (
+    kind character varying NOT NULL,
+    cap_key character varying NOT NULL,
+    window_start timestamp with time zone NOT NULL,
+    sent bigint DEFAULT 0 NOT NULL,
+    CONSTRAINT msg_send_cap_pkey PRIMARY KEY (kind, cap_key, window_start)
+);
+
+CREATE INDEX idx_msg_send_cap_window_start ON msggateway.msg_send_cap USING btree (window_start);
@@ -0,0 +1,16 @@
+CREATE TABLE msggateway.msg_admin_audit (
+    audit_id bigserial NOT NULL,
+    actor character varying NOT NULL,
+    action character varying NOT NULL,
+    entity_type character varying NOT NULL,
+    entity_id character varying NOT NULL,
+    diff jsonb NOT NULL,
+    correlation_id character varying DEFAULT ''::character varying NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    prev_hash character varying NOT NULL,
+    hash character varying NOT NULL,
+    CONSTRAINT msg_admin_audit_pkey PRIMARY KEY (audit_id)
+);
+
+CREATE INDEX idx_msg_admin_audit_entity ON msggateway.msg_admin_audit USING btree (entity_type, entity_id, created_at);
+CREATE INDEX idx_msg_admin_audit_actor ON msggateway.msg_admin_audit USING btree (actor, created_at);
@@ -0,0 +1,3 @@
+ALTER TABLE msggateway.msg_request ALTER COLUMN communication_id TYPE character varying(64) USING rtrim(communication_id);
+DROP INDEX IF EXISTS msggateway.idx_msg_request_communication_id;
+CREATE UNIQUE INDEX idx_msg_request_communication_id ON msggateway.msg_request USING btree (communication_id);
@@ -2,10 +2,18 @@ package tests
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+	"MgApplication/handler"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
 	"gotest.tools/v3/assert"
 )
 
@@ -17,14 +25,14 @@ func TestCreateMessageRequestSuccess(t *testing.T) {
 		"priority":1,
 		"message_text":"Dear Customer, OTP for booking is 1234, please do not share it with anyone - INDPOST",
 		"sender_id":"INPOST",
-		"mobile_numbers":"1111111111",
+		"mobile_numbers":"9111111111",
 		"entity_id":"1001081725895192800",
 		"template_id":"1007344609998507114"
 	}`
 	req := httptest.NewRequest("POST", "/v1/sms-request", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusCreated, rec.Code)
 }
@@ -34,7 +42,7 @@ func TestCreateMessageRequestDbErrorAppTemplateMapping(t *testing.T) {
 		"application_id":"5",
 		"facility_id":"8587808287",
 		"message_text":"Dear Customer, Your contract with ID 123 has been approved.- INDIAPOST",
-		"mobile_numbers":"1111111111",
+		"mobile_numbers":"9111111111",
 		"priority":1,
 		"sender_id":"INPOST",
 		"entity_id":"1001081725895192800",
@@ -43,7 +51,7 @@ func TestCreateMessageRequestDbErrorAppTemplateMapping(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/sms-request", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusCreated, rec.Code)
 }
@@ -53,7 +61,7 @@ func TestCreateMessageRequestMissingParam(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/sms-request", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
@@ -65,7 +73,7 @@ func TestCreateMessageRequestUnicodeCdacSuccess(t *testing.T) {
 		"priority":1,
 		"message_text": "प्रिय Phani, abc ऐप के लिए ओटीपी (OTP) 123456 है। कृपया इसे किसी के साथ साझा न करें। यह 5 मिनट के लिए वैध है। - भारतीय डाक",
 		"sender_id":"INPOST",
-		"mobile_numbers":"1111111111",
+		"mobile_numbers":"9111111111",
 		"entity_id":"1001081725895192800",
 		"template_id":"1007667344967470975",
 		"message_type":"UC"
@@ -73,7 +81,7 @@ func TestCreateMessageRequestUnicodeCdacSuccess(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/sms-request", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusCreated, rec.Code)
 }
@@ -86,7 +94,7 @@ func TestCreateMessageRequestUnicodeNicSuccess(t *testing.T) {
 		"priority":1,
 		"message_text": "प्रिय ग्राहक सुकन्या समृद्धि योजना खाता खोलकर अपनी प्यारी बिटिया का भविष्य सुरक्षित करने के लिए डाक विभाग आपको बधाई देता है।",
 		"sender_id":"DOPBNK",
-		"mobile_numbers":"1111111111",
+		"mobile_numbers":"9111111111",
 		"entity_id":"1001081725895192800",
 		"template_id":"1007340152087500154",
 		"message_type":"UC"
@@ -94,7 +102,7 @@ func TestCreateMessageRequestUnicodeNicSuccess(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/sms-request", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusCreated, rec.Code)
 }
@@ -103,7 +111,7 @@ func TestTestMessageSuccess(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/test-sms-request", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusCreated, rec.Code)
 }
@@ -113,7 +121,85 @@ func TestTestMessageMissingParam(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/test-sms-request", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
-}
\ No newline at end of file
+}
+
+// The status of a request retried through both gateways is its last response
+func TestFetchSMSRequestStatusReturnsLatestResponse(t *testing.T) {
+	const communicationID = "01J7TKPX20B9D5MPJTB9D5MP01"
+	ctx := context.Background()
+	for _, msgRsp := range []domain.MsgResponse{
+		{CommunicationID: communicationID, ResponseCode: "401", ResponseText: "Authentication failed"},
+		{CommunicationID: communicationID, ReferenceID: "150920241726381202115", ResponseCode: "402", ResponseText: "Message accepted"},
+		{CommunicationID: communicationID, ReferenceID: "5718473651", ResponseCode: "API000", ResponseText: "Message accepted"},
+	} {
+		_, err := MgAppRepo.SaveResponse(&ctx, &msgRsp)
+		assert.NilError(t, err)
+	}
+
+	c := config.NewConfig(viper.New())
+	engine := gin.New()
+	engine.GET("/v1/sms-request/:communication-id/status", handler.NewMgApplicationHandler(MgAppRepo, handler.NewDNDFilter(DNDRepo, c), c).FetchSMSRequestStatusHandler)
+
+	req := httptest.NewRequest("GET", "/v1/sms-request/"+communicationID+"/status", nil)
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var rsp struct {
+		Data struct {
+			CommunicationID string `json:"communication_id"`
+			ReferenceID     string `json:"reference_id"`
+			Status          string `json:"status"`
+		} `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, rsp.Data.CommunicationID, communicationID)
+	assert.Equal(t, rsp.Data.ReferenceID, "5718473651")
+	assert.Equal(t, rsp.Data.Status, "API000")
+
+	req = httptest.NewRequest("GET", "/v1/sms-request/01J7TKPX20B9D5MPJTB9D5MP02/status", nil)
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+}
+
+func TestFetchSMSRequestStatusBulkReturnsLatestResponses(t *testing.T) {
+	ctx := context.Background()
+	for _, msgRsp := range []domain.MsgResponse{
+		{CommunicationID: "01J7TKPX20B9D5MPJTB9D5MP03", ResponseCode: "401", ResponseText: "Authentication failed"},
+		{CommunicationID: "01J7TKPX20B9D5MPJTB9D5MP03", ReferenceID: "5718473652", ResponseCode: "API000", ResponseText: "Message accepted"},
+		{CommunicationID: "01J7TKPX20B9D5MPJTB9D5MP04", ReferenceID: "150920241726381202116", ResponseCode: "402", ResponseText: "Message accepted"},
+	} {
+		_, err := MgAppRepo.SaveResponse(&ctx, &msgRsp)
+		assert.NilError(t, err)
+	}
+
+	c := config.NewConfig(viper.New())
+	engine := gin.New()
+	engine.POST("/v1/sms-request/status/bulk", handler.NewMgApplicationHandler(MgAppRepo, handler.NewDNDFilter(DNDRepo, c), c).FetchSMSRequestStatusBulkHandler)
+
+	body := `{"communication_ids": ["01J7TKPX20B9D5MPJTB9D5MP03", "01J7TKPX20B9D5MPJTB9D5MP05", "01J7TKPX20B9D5MPJTB9D5MP04"]}`
+	req := httptest.NewRequest("POST", "/v1/sms-request/status/bulk", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var rsp struct {
+		Data map[string]struct {
+			Result      string `json:"result"`
+			ReferenceID string `json:"reference_id"`
+			Status      string `json:"status"`
+		} `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, len(rsp.Data), 3)
+	assert.Equal(t, rsp.Data["01J7TKPX20B9D5MPJTB9D5MP03"].Result, "found")
+	assert.Equal(t, rsp.Data["01J7TKPX20B9D5MPJTB9D5MP03"].ReferenceID, "5718473652")
+	assert.Equal(t, rsp.Data["01J7TKPX20B9D5MPJTB9D5MP03"].Status, "API000")
+	assert.Equal(t, rsp.Data["01J7TKPX20B9D5MPJTB9D5MP04"].Status, "402")
+	assert.Equal(t, rsp.Data["01J7TKPX20B9D5MPJTB9D5MP05"].Result, "not_found")
+}
@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/clock"
+	"MgApplication/core/domain"
+	"MgApplication/handler"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func orphanTestRequest() *domain.MsgRequest {
+	return &domain.MsgRequest{
+		ApplicationID: "7",
+		FacilityID:    "facility1",
+		Priority:      int(domain.PriorityOTP),
+		MessageText:   "Dear Customer, OTP for booking is 1234, please do not share it with anyone - INDPOST",
+		SenderID:      "INPOST",
+		MobileNumbers: "9000000001",
+		EntityId:      "1001081725895192800",
+		TemplateID:    "1007344609998507114",
+	}
+}
+
+// storedRequests counts the msg_request rows of a communication id and those with a response
+func storedRequests(t *testing.T, communicationID string) (int, int) {
+	t.Helper()
+	var rows, responded int
+	err := MgAppRepo.Db.QueryRow(context.Background(),
+		`SELECT COUNT(*), COUNT(response_code) FROM msg_request WHERE communication_id = $1`, communicationID).
+		Scan(&rows, &responded)
+	assert.NilError(t, err)
+	return rows, responded
+}
+
+// TestMsgTxStoppedBetweenStepsLeavesThePendingRequest stops the storing of a request at each step,
+// as the process being killed would. The request is stored as pending before the gateway call and
+// stays pending, without a response, unless the transaction of its response commits.
+func TestMsgTxStoppedBetweenStepsLeavesThePendingRequest(t *testing.T) {
+	injected := errors.New("process killed")
+	cases := []struct {
+		name          string
+		gatewayCall   func() error
+		afterResponse func() error
+		responded     bool
+	}{
+		{"committed", func() error { return nil }, func() error { return nil }, true},
+		{"failed during the gateway call", func() error { return injected }, func() error { return nil }, false},
+		{"panicked during the gateway call", func() error { panic(injected) }, func() error { return nil }, false},
+		{"failed after the response", func() error { return nil }, func() error { return injected }, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgreq := orphanTestRequest()
+			run := func() error {
+				if err := MgAppRepo.WithMsgTx(func(tx pgx.Tx) error {
+					return MgAppRepo.SaveMsgRequestInTx(tx, msgreq)
+				}); err != nil {
+					return err
+				}
+				if err := tc.gatewayCall(); err != nil {
+					return err
+				}
+				return MgAppRepo.WithMsgTx(func(tx pgx.Tx) error {
+					msgRsp := domain.MsgResponse{CommunicationID: msgreq.CommunicationID, ResponseCode: "02", ResponseText: "gateway unavailable"}
+					if err := MgAppRepo.SaveResponseInTx(tx, &msgRsp); err != nil {
+						return err
+					}
+					return tc.afterResponse()
+				})
+			}
+
+			var err error
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						err = injected
+					}
+				}()
+				err = run()
+			}()
+
+			assert.Assert(t, msgreq.CommunicationID != "")
+			rows, responded := storedRequests(t, msgreq.CommunicationID)
+			assert.Equal(t, rows, 1)
+			if tc.responded {
+				assert.NilError(t, err)
+				assert.Equal(t, responded, 1)
+			} else {
+				assert.ErrorIs(t, err, injected)
+				assert.Equal(t, responded, 0)
+			}
+		})
+	}
+}
+
+func TestCreateSMSRequestGatewayFailureStoresResponse(t *testing.T) {
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer cdac.Close()
+
+	c := config.NewConfig(viper.New())
+	c.Set("sms.msgstorerequest", 1)
+	c.Set("sms.cdac.url", cdac.URL)
+
+	engine := gin.New()
+	engine.POST("/v1/sms-request", handler.NewMgApplicationHandler(MgAppRepo, handler.NewDNDFilter(DNDRepo, c), c).CreateSMSRequestHandler)
+
+	input := `{
+		"application_id":"7",
+		"facility_id":"facility1",
+		"priority":1,
+		"message_text":"Dear Customer, OTP for booking is 1234, please do not share it with anyone - INDPOST",
+		"sender_id":"INPOST",
+		"mobile_numbers":"9000000001",
+		"entity_id":"1001081725895192800",
+		"template_id":"1007344609998507114",
+		"client_reference":"ORPHAN-GATEWAY-DOWN"
+	}`
+	req := httptest.NewRequest("POST", "/v1/sms-request", bytes.NewBufferString(input))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code, rec.Body.String())
+
+	var status, responseCode string
+	err := MgAppRepo.Db.QueryRow(context.Background(),
+		`SELECT status, response_code FROM msg_request WHERE client_reference = 'ORPHAN-GATEWAY-DOWN'`).
+		Scan(&status, &responseCode)
+	assert.NilError(t, err)
+	assert.Equal(t, status, "submitted")
+	assert.Equal(t, responseCode, "02")
+}
+
+func TestOrphanedRequestsReport(t *testing.T) {
+	const applicationID = "9903"
+	_, orphaned := insertPendingMessage(t, applicationID, "")
+	_, responded := insertPendingMessage(t, applicationID, "")
+	saveResponse(t, responded, true)
+	var queued string
+	err := MgAppRepo.Db.QueryRow(context.Background(),
+		`INSERT INTO msg_request (application_id, priority, gateway, status, mobile_number)
+		 VALUES ($1, 4, '1', 'pending', '{9000000001}') RETURNING communication_id`, applicationID).
+		Scan(&queued)
+	assert.NilError(t, err)
+
+	today := clock.InIST(clock.Now()).Format("02-01-2006")
+	req := httptest.NewRequest("GET", "/v1/admin/requests/orphaned?from-date="+today+"&to-date="+today, nil)
+	setBearerToken(req, "ops1", "admin")
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var rsp struct {
+		Data struct {
+			Count    int                      `json:"count"`
+			Requests []domain.OrphanedRequest `json:"requests"`
+		} `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, rsp.Data.Count, len(rsp.Data.Requests))
+	found := map[string]bool{}
+	for _, orphan := range rsp.Data.Requests {
+		found[orphan.CommunicationID] = true
+	}
+	assert.Assert(t, found[orphaned], "pending request without a response is not reported")
+	assert.Assert(t, !found[responded], "request with a response is reported")
+	assert.Assert(t, !found[queued], "queued promotional request is reported")
+
+	// no period ending before it starts
+	req = httptest.NewRequest("GET", "/v1/admin/requests/orphaned?from-date=02-01-2024&to-date=01-01-2024", nil)
+	setBearerToken(req, "ops1", "admin")
+	rec = httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+}
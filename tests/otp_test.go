@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"MgApplication/core/domain"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gotest.tools/v3/assert"
+)
+
+// seedOTP stores an OTP for the given code directly through the repository
+func seedOTP(t *testing.T, code string, maxAttempts int, ttl time.Duration) domain.MsgOTP {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.MinCost)
+	assert.NilError(t, err)
+	otp, err := OTPRepo.CreateOTPRepo(context.Background(), &domain.MsgOTP{
+		OTPReference:  uuid.NewString(),
+		ApplicationID: "12",
+		MobileNumber:  "9000000000",
+		OTPHash:       string(hash),
+		MaxAttempts:   maxAttempts,
+	}, ttl)
+	assert.NilError(t, err)
+	return otp
+}
+
+func verifyOTP(otpReference string, code string) *httptest.ResponseRecorder {
+	input, _ := json.Marshal(map[string]string{
+		"application_id": "12",
+		"otp_reference":  otpReference,
+		"mobile_number":  "9000000000",
+		"otp":            code,
+	})
+	req := httptest.NewRequest("POST", "/v1/otp/verify", bytes.NewBuffer(input))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+	return rec
+}
+
+func otpErrorID(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var rsp struct {
+		Error struct {
+			ID string `json:"id"`
+		} `json:"error"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	return rsp.Error.ID
+}
+
+// GenerateOTPHandler
+func TestGenerateOTPHandlerValidationError(t *testing.T) {
+	input := `{
+		"application_id": "12",
+		"facility_id": "facility1",
+		"mobile_number": "12345"
+		}`
+	req := httptest.NewRequest("POST", "/v1/otp/generate", bytes.NewBuffer([]byte(input)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestGenerateOTPHandlerBindingError(t *testing.T) {
+	input := `{
+		"application_id": "12",
+		"facility_id": "facility1",
+		"mobile_number": "9000000000",
+		}`
+	req := httptest.NewRequest("POST", "/v1/otp/generate", bytes.NewBuffer([]byte(input)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGenerateOTPHandlerRateLimited(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		seedOTP(t, "123456", 3, 5*time.Minute)
+	}
+	input := `{
+		"application_id": "12",
+		"facility_id": "facility1",
+		"mobile_number": "9000000000",
+		"template_values": ["Postal GIS Application"]
+		}`
+	req := httptest.NewRequest("POST", "/v1/otp/generate", bytes.NewBuffer([]byte(input)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "RATE_LIMITED", otpErrorID(t, rec))
+	assert.Assert(t, rec.Header().Get("Retry-After") != "")
+}
+
+// VerifyOTPHandler
+func TestVerifyOTPHandlerSuccess(t *testing.T) {
+	otp := seedOTP(t, "482913", 3, 5*time.Minute)
+
+	rec := verifyOTP(otp.OTPReference, "482913")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// a verified OTP cannot be reused
+	rec = verifyOTP(otp.OTPReference, "482913")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestVerifyOTPHandlerMismatch(t *testing.T) {
+	otp := seedOTP(t, "482913", 3, 5*time.Minute)
+
+	rec := verifyOTP(otp.OTPReference, "111111")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "MISMATCH", otpErrorID(t, rec))
+}
+
+func TestVerifyOTPHandlerTooManyAttempts(t *testing.T) {
+	otp := seedOTP(t, "482913", 2, 5*time.Minute)
+
+	for i := 0; i < 2; i++ {
+		rec := verifyOTP(otp.OTPReference, "111111")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+	rec := verifyOTP(otp.OTPReference, "482913")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "TOO_MANY_ATTEMPTS", otpErrorID(t, rec))
+}
+
+func TestVerifyOTPHandlerExpired(t *testing.T) {
+	otp := seedOTP(t, "482913", 3, -time.Minute)
+
+	rec := verifyOTP(otp.OTPReference, "482913")
+	assert.Equal(t, http.StatusGone, rec.Code)
+	assert.Equal(t, "EXPIRED", otpErrorID(t, rec))
+}
+
+func TestVerifyOTPHandlerUnknownReference(t *testing.T) {
+	rec := verifyOTP(uuid.NewString(), "482913")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestVerifyOTPHandlerValidationError(t *testing.T) {
+	rec := verifyOTP("not-a-reference", "482913")
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+// OTPRepository
+func TestOTPRepositoryConsumeAttempts(t *testing.T) {
+	ctx := context.Background()
+	otp := seedOTP(t, "482913", 2, 5*time.Minute)
+
+	for i := 0; i < 2; i++ {
+		consumed, err := OTPRepo.ConsumeOTPAttemptRepo(ctx, otp.OTPReference)
+		assert.NilError(t, err)
+		assert.Assert(t, consumed)
+	}
+	consumed, err := OTPRepo.ConsumeOTPAttemptRepo(ctx, otp.OTPReference)
+	assert.NilError(t, err)
+	assert.Assert(t, !consumed)
+}
+
+func TestOTPRepositoryPurgeExpired(t *testing.T) {
+	ctx := context.Background()
+	expired := seedOTP(t, "482913", 3, -2*time.Hour)
+	active := seedOTP(t, "482913", 3, 5*time.Minute)
+
+	purged, err := OTPRepo.PurgeExpiredOTPRepo(ctx, time.Hour)
+	assert.NilError(t, err)
+	assert.Assert(t, purged >= 1)
+
+	_, err = OTPRepo.FetchOTPRepo(ctx, expired.OTPReference)
+	assert.ErrorContains(t, err, "no rows")
+	_, err = OTPRepo.FetchOTPRepo(ctx, active.OTPReference)
+	assert.NilError(t, err)
+}
@@ -0,0 +1,237 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"MgApplication/core/domain"
+
+	"gotest.tools/v3/assert"
+)
+
+// insertErasableMessage stores a request to mobileNumbers created on day (yyyy-mm-dd) and
+// returns its communication id
+func insertErasableMessage(t *testing.T, day string, mobileNumbers []int64) string {
+	t.Helper()
+	var communicationID string
+	err := MgAppRepo.Db.QueryRow(context.Background(),
+		`INSERT INTO msg_request (application_id, priority, gateway, status, message_text, complete_response, mobile_number, created_date)
+		 VALUES ('7', 2, '1', 'submitted', 'Your parcel is delivered - INDPOST', '402,MsgID = 1', $1, $2::timestamp) RETURNING communication_id`,
+		mobileNumbers, day+" 10:00:00").Scan(&communicationID)
+	assert.NilError(t, err)
+	return communicationID
+}
+
+// erasableMessageText returns the message text of the request, nil once erased
+func erasableMessageText(t *testing.T, communicationID string) *string {
+	t.Helper()
+	var messageText *string
+	err := MgAppRepo.Db.QueryRow(context.Background(), `SELECT message_text FROM msg_request WHERE communication_id = $1`, communicationID).
+		Scan(&messageText)
+	assert.NilError(t, err)
+	return messageText
+}
+
+func privacyErasureRequest(scope string, userID string, body map[string]any) *httptest.ResponseRecorder {
+	input, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", "/v1/admin/privacy/erasure", bytes.NewBuffer(input))
+	req.Header.Set("Content-Type", "application/json")
+	if scope != "" || userID != "" {
+		setBearerToken(req, userID, scope)
+	}
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+	return rec
+}
+
+type privacyErasureResponse struct {
+	Data struct {
+		ErasureID    uint64 `json:"erasure_id"`
+		Resumed      bool   `json:"resumed"`
+		RequestedBy  string `json:"requested_by"`
+		RowsAffected int64  `json:"rows_affected"`
+		Tables       []struct {
+			TableName    string `json:"table_name"`
+			RowsAffected int64  `json:"rows_affected"`
+		} `json:"tables"`
+	} `json:"data"`
+}
+
+func (r privacyErasureResponse) rowsAffected(table string) int64 {
+	for _, t := range r.Data.Tables {
+		if t.TableName == table {
+			return t.RowsAffected
+		}
+	}
+	return -1
+}
+
+// plaintextRemnants counts the rows of all erasable tables still holding one of the forms of
+// mobileNumber
+func plaintextRemnants(t *testing.T, mobileNumber string) int {
+	t.Helper()
+	var remnants int
+	err := MgAppRepo.Db.QueryRow(context.Background(),
+		`SELECT (SELECT count(*) FROM msg_request WHERE mobile_number && ARRAY[$1::bigint, ('91' || $1)::bigint])
+		      + (SELECT count(*) FROM msg_bulk_file WHERE mobile_number && ARRAY[$1::bigint, ('91' || $1)::bigint])
+		      + (SELECT count(*) FROM msg_bulk_file, jsonb_array_elements(upload_rejects) AS reject WHERE reject->>'value' LIKE '%' || $1 || '%')
+		      + (SELECT count(*) FROM msg_campaign_recipient WHERE mobile_number = $1)
+		      + (SELECT count(*) FROM msg_otp WHERE mobile_number IN ($1, '91' || $1, '+91' || $1, '0' || $1))`,
+		mobileNumber).Scan(&remnants)
+	assert.NilError(t, err)
+	return remnants
+}
+
+func TestPrivacyErasure(t *testing.T) {
+	ctx := context.Background()
+	own := insertErasableMessage(t, "2024-06-01", []int64{9300000001})
+	prefixed := insertErasableMessage(t, "2024-06-02", []int64{919300000001})
+	shared := insertErasableMessage(t, "2024-06-03", []int64{9300000002, 9300000001})
+	other := insertErasableMessage(t, "2024-06-03", []int64{9300000002})
+	_, err := MgAppRepo.Db.Exec(ctx,
+		`INSERT INTO msg_bulk_file (application_id, test_msg, mobile_number) VALUES ('7', 'Your parcel is delivered - INDPOST', '{9300000001,9300000003}')`)
+	assert.NilError(t, err)
+	// A campaign upload with the number among its recipients and its rejects
+	_, err = MgAppRepo.Db.Exec(ctx,
+		`INSERT INTO msg_bulk_file (application_id, reference_id, upload_rejects)
+		 VALUES ('7', 'ERASURECAMPAIGN01', '[{"row":2,"value":"+91 9300000001x","reason":"invalid"},{"row":5,"value":"12345","reason":"invalid"}]')`)
+	assert.NilError(t, err)
+	_, err = MgAppRepo.Db.Exec(ctx,
+		`INSERT INTO msg_campaign_recipient (reference_id, mobile_number) VALUES ('ERASURECAMPAIGN01', '9300000001'), ('ERASURECAMPAIGN01', '9300000002')`)
+	assert.NilError(t, err)
+	_, err = MgAppRepo.Db.Exec(ctx,
+		`INSERT INTO msg_otp (otp_reference, application_id, mobile_number, otp_hash, max_attempts, expires_at)
+		 VALUES (md5(random()::text), '7', '9300000001', 'hash', 3, CURRENT_TIMESTAMP)`)
+	assert.NilError(t, err)
+
+	rec := privacyErasureRequest("admin", "dpo-1", map[string]any{"mobile_number": "9300000001", "confirm": true})
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var rsp privacyErasureResponse
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, "dpo-1", rsp.Data.RequestedBy)
+	assert.Equal(t, int64(3), rsp.rowsAffected("msg_request"))
+	assert.Equal(t, int64(2), rsp.rowsAffected("msg_bulk_file"))
+	assert.Equal(t, int64(1), rsp.rowsAffected("msg_campaign_recipient"))
+	assert.Equal(t, int64(1), rsp.rowsAffected("msg_otp"))
+	assert.Equal(t, int64(7), rsp.Data.RowsAffected)
+
+	assert.Equal(t, 0, plaintextRemnants(t, "9300000001"))
+	for _, communicationID := range []string{own, prefixed, shared} {
+		var messageText, completeResponse *string
+		err := MgAppRepo.Db.QueryRow(ctx, `SELECT message_text, complete_response FROM msg_request WHERE communication_id = $1`, communicationID).
+			Scan(&messageText, &completeResponse)
+		assert.NilError(t, err)
+		assert.Assert(t, messageText == nil && completeResponse == nil, "request %s", communicationID)
+	}
+
+	// The other recipient of a shared request and the requests of other numbers are kept
+	var recipients []int64
+	err = MgAppRepo.Db.QueryRow(ctx, `SELECT mobile_number FROM msg_request WHERE communication_id = $1`, shared).Scan(&recipients)
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(recipients))
+	assert.Equal(t, int64(9300000002), recipients[0])
+	assert.Assert(t, recipients[1] < 0, "number is replaced with a tombstone: %d", recipients[1])
+	assert.Assert(t, erasableMessageText(t, other) != nil)
+	var campaignRecipients int
+	err = MgAppRepo.Db.QueryRow(ctx, `SELECT count(*) FROM msg_campaign_recipient WHERE reference_id = 'ERASURECAMPAIGN01'`).Scan(&campaignRecipients)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, campaignRecipients)
+	var keptReject string
+	err = MgAppRepo.Db.QueryRow(ctx, `SELECT upload_rejects->1->>'value' FROM msg_bulk_file WHERE reference_id = 'ERASURECAMPAIGN01'`).Scan(&keptReject)
+	assert.NilError(t, err)
+	assert.Equal(t, "12345", keptReject)
+
+	// The erasure is audited without the number
+	var requestedBy, status string
+	var tombstone int64
+	err = MgAppRepo.Db.QueryRow(ctx, `SELECT requested_by, status, tombstone FROM msg_privacy_erasure WHERE erasure_id = $1`, rsp.Data.ErasureID).
+		Scan(&requestedBy, &status, &tombstone)
+	assert.NilError(t, err)
+	assert.Equal(t, "dpo-1", requestedBy)
+	assert.Equal(t, "completed", status)
+	assert.Equal(t, recipients[1], tombstone)
+
+	// Repeating the erasure affects no rows
+	rec = privacyErasureRequest("admin", "dpo-1", map[string]any{"mobile_number": "9300000001", "confirm": true})
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var repeated privacyErasureResponse
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &repeated))
+	assert.Equal(t, int64(0), repeated.Data.RowsAffected)
+	assert.Assert(t, !repeated.Data.Resumed)
+}
+
+func TestPrivacyErasurePeriod(t *testing.T) {
+	before := insertErasableMessage(t, "2024-07-01", []int64{9300000011})
+	within := insertErasableMessage(t, "2024-07-10", []int64{9300000011})
+
+	rec := privacyErasureRequest("admin", "dpo-1", map[string]any{
+		"mobile_number": "9300000011", "from_date": "05-07-2024", "to_date": "10-07-2024", "confirm": true})
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var rsp privacyErasureResponse
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, int64(1), rsp.rowsAffected("msg_request"))
+
+	assert.Assert(t, erasableMessageText(t, within) == nil)
+	assert.Assert(t, erasableMessageText(t, before) != nil)
+}
+
+func TestPrivacyErasureResume(t *testing.T) {
+	ctx := context.Background()
+	communicationID := insertErasableMessage(t, "2024-08-01", []int64{9300000021})
+	_, err := MgAppRepo.Db.Exec(ctx,
+		`INSERT INTO msg_otp (otp_reference, application_id, mobile_number, otp_hash, max_attempts, expires_at)
+		 VALUES (md5(random()::text), '7', '9300000021', 'hash', 3, CURRENT_TIMESTAMP)`)
+	assert.NilError(t, err)
+
+	erasure, resumed, err := PrivacyRepo.StartPrivacyErasureRepo(ctx, domain.PrivacyErasure{Tombstone: -21, RequestedBy: "dpo-1"})
+	assert.NilError(t, err)
+	assert.Assert(t, !resumed)
+	assert.Equal(t, 3, len(erasure.Tables))
+
+	// Interrupted after completing msg_request
+	_, err = MgAppRepo.Db.Exec(ctx,
+		`UPDATE msg_privacy_erasure_table SET rows_affected = 4, completed = true WHERE erasure_id = $1 AND table_name = 'msg_request'`, erasure.ErasureID)
+	assert.NilError(t, err)
+
+	again, resumed, err := PrivacyRepo.StartPrivacyErasureRepo(ctx, domain.PrivacyErasure{Tombstone: -21, RequestedBy: "dpo-2"})
+	assert.NilError(t, err)
+	assert.Assert(t, resumed)
+	assert.Equal(t, erasure.ErasureID, again.ErasureID)
+	assert.Equal(t, "dpo-1", again.RequestedBy)
+
+	completed, err := PrivacyRepo.RunPrivacyErasureRepo(ctx, again, []string{"9300000021"})
+	assert.NilError(t, err)
+	assert.Equal(t, "completed", completed.Status)
+	for _, table := range completed.Tables {
+		switch table.TableName {
+		case "msg_request":
+			assert.Equal(t, int64(4), table.RowsAffected)
+		case "msg_otp":
+			assert.Equal(t, int64(1), table.RowsAffected)
+		}
+	}
+
+	// The completed table is not erased again
+	assert.Assert(t, erasableMessageText(t, communicationID) != nil)
+
+	// A completed erasure is not resumed
+	_, resumed, err = PrivacyRepo.StartPrivacyErasureRepo(ctx, domain.PrivacyErasure{Tombstone: -21, RequestedBy: "dpo-1"})
+	assert.NilError(t, err)
+	assert.Assert(t, !resumed)
+}
+
+// TestPrivacyErasureRejected checks that an erasure without the admin scope leaves the messages.
+// The requests rejected by the handler are covered in package handler.
+func TestPrivacyErasureRejected(t *testing.T) {
+	communicationID := insertErasableMessage(t, "2024-09-01", []int64{9300000031})
+
+	rec := privacyErasureRequest("sms.text.read", "dpo-1", map[string]any{"mobile_number": "9300000031", "confirm": true})
+	assert.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+
+	assert.Assert(t, erasableMessageText(t, communicationID) != nil)
+	assert.Equal(t, 1, plaintextRemnants(t, "9300000031"))
+}
@@ -26,7 +26,7 @@ func TestCreateMessageProviderHandlerrSuccess(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/sms-providers", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusCreated, rec.Code)
 }
@@ -47,7 +47,7 @@ func TestCreateMessageProviderHandlerBindingError(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/sms-providers", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -68,7 +68,7 @@ func TestCreateMessageProviderHandlerValidationError(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/sms-providers", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
@@ -79,7 +79,7 @@ func Test_CreateMessageProviderHandler_MissingParam(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/sms-providers", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
@@ -90,7 +90,7 @@ func TestListMessageProvidersHandlerSuccess(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-providers", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -99,7 +99,7 @@ func TestListMessageProvidersHandlerBindingError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-providers?status=false", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -108,7 +108,7 @@ func TestListMessageProvidersHandlerValidationError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-providers", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
@@ -117,7 +117,7 @@ func TestListMessageProvidersHandlerInvalidParam(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-providers", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -127,7 +127,7 @@ func TestFetchMessageProviderHandlerSuccess(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-providers/5", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -136,7 +136,7 @@ func TestFetchMessageProviderHandlerBindingError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-providers/", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -145,7 +145,7 @@ func TestFetchMessageProviderHandlerValidationError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-providers/4a", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
@@ -154,7 +154,7 @@ func TestFetchMessageProviderHandlerInvalidParam(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-providers/5y", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -170,7 +170,7 @@ func TestUpdateMessageProviderHandlerSuccess(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/sms-providers/4", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -184,7 +184,7 @@ func TestUpdateMessageProviderHandlerBindingError(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/sms-providers/4", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -198,7 +198,7 @@ func TestUpdateMessageProviderHandlerValidationError(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/sms-providers/4", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
@@ -208,7 +208,7 @@ func TestToggleMessageProviderStatusHandlerSuccess(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/sms-providers/4/status", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -217,7 +217,7 @@ func TestToggleMessageProviderStatusHandlerBindingError(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/sms-providers//status", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -226,7 +226,7 @@ func TestToggleMessageProviderStatusHandlerValidationError(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/sms-providers/a/status", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
@@ -235,7 +235,7 @@ func TestToggleMessageProviderStatusHandlerInvalidParam(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/sms-providers/4/status", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -1,6 +1,8 @@
 package tests
 
 import (
+	"bytes"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -13,7 +15,7 @@ func TestGetDashboardDataSuccess(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-dashboard", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -22,7 +24,7 @@ func TestGetDashboardDataInvalidParam(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-dashboard", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -32,7 +34,7 @@ func TestSmsSentStatusSuccess(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-sent-status-report?from-date=01-01-2024&to-date=02-08-2024", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -41,17 +43,48 @@ func TestSmsSentStatusInvalidParam(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-sent-status-report?from-date=01-01-2024&to-date=02-08-2024", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
 
+// The ndjson export streams as many lines as the streamed array has rows
+func TestSmsSentStatusNDJSON(t *testing.T) {
+	const report = "/v1/sms-sent-status-report?from-date=01-01-2008&to-date=31-12-2030"
+
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, httptest.NewRequest("GET", report+"&stream=true", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var rows []json.RawMessage
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rows))
+
+	rec = httptest.NewRecorder()
+	Router.ServeHTTP(rec, httptest.NewRequest("GET", report+"&format=ndjson", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+	assert.Equal(t, len(rows), bytes.Count(rec.Body.Bytes(), []byte("\n")))
+	for _, line := range bytes.Split(bytes.TrimSuffix(rec.Body.Bytes(), []byte("\n")), []byte("\n")) {
+		if len(line) > 0 {
+			assert.Assert(t, json.Valid(line), string(line))
+		}
+	}
+	assert.Equal(t, "200", rec.Result().Trailer.Get("X-Stream-Status"))
+}
+
+func TestSmsSentStatusInvalidFormat(t *testing.T) {
+	req := httptest.NewRequest("GET", "/v1/sms-sent-status-report?from-date=01-01-2024&to-date=02-08-2024&format=csv", nil)
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
 //AggregateSMSUsageReportHandler
 func TestAggregateSmsReportAppwiseSuccess(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/aggregate-sms-report?from-date=01-01-2024&to-date=02-09-2024&report-type=1", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -60,7 +93,7 @@ func TestAggregateSmsReportInvalidParam(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/aggregate-sms-report?from-date=01-01-2024&to-date=02-09-2024&report-type=1", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -69,7 +102,7 @@ func TestAggregateSmsReportTemplatewiseSuccess(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/aggregate-sms-report?from-date=01-01-2024&to-date=02-09-2024&report-type=2", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -78,7 +111,7 @@ func TestAggregateSmsReportProviderwiseSuccess(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/aggregate-sms-report?from-date=01-01-2024&to-date=02-09-2024&report-type=3", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -12,7 +12,7 @@ func TestHealthCheck(t *testing.T) {
 	req := httptest.NewRequest("GET", "/healthz", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -21,7 +21,7 @@ func TestHealthCheckNoRoute(t *testing.T) {
 	req := httptest.NewRequest("GET", "/health", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
 }
@@ -30,7 +30,7 @@ func TestHealthCheckNoMethod(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/healthz", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
 }
@@ -39,7 +39,7 @@ func TestSwagger(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/swagger/docs/index.html#/", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
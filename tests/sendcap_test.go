@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"MgApplication/core/clock"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestConsumeSendCapConcurrentReservations(t *testing.T) {
+	ctx := context.Background()
+	window := clock.Now().Truncate(time.Hour)
+	t.Cleanup(func() {
+		_, _ = MgAppRepo.Db.Exec(context.Background(), `DELETE FROM msggateway.msg_send_cap WHERE cap_key = 'cap-test'`)
+	})
+
+	// the replicas racing for batches of the cap never reserve more than it together
+	var wg sync.WaitGroup
+	var reserved atomic.Int64
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok, err := MgAppRepo.ConsumeSendCapRepo(ctx, "sender", "cap-test", window, 3, 10)
+			assert.NilError(t, err)
+			if ok {
+				reserved.Add(3)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(9), reserved.Load())
+
+	sent, ok, err := MgAppRepo.ConsumeSendCapRepo(ctx, "sender", "cap-test", window, 3, 10)
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+	assert.Equal(t, int64(9), sent)
+
+	// the last message fits on its own
+	sent, ok, err = MgAppRepo.ConsumeSendCapRepo(ctx, "sender", "cap-test", window, 1, 10)
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.Equal(t, int64(10), sent)
+
+	// the other kind and the next window are counted apart
+	sent, ok, err = MgAppRepo.ConsumeSendCapRepo(ctx, "template", "cap-test", window, 2, 10)
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.Equal(t, int64(2), sent)
+	sent, err = MgAppRepo.GetSendCapRepo(ctx, "sender", "cap-test", window.Add(time.Hour))
+	assert.NilError(t, err)
+	assert.Equal(t, int64(0), sent)
+}
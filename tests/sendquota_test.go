@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"MgApplication/core/clock"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestConsumeSendQuotaConcurrentRequests(t *testing.T) {
+	ctx := context.Background()
+	day := clock.BusinessDay(clock.Now())
+	t.Cleanup(func() {
+		_, _ = MgAppRepo.Db.Exec(context.Background(), `DELETE FROM msggateway.msg_send_quota WHERE application_id = 'quota-test'`)
+	})
+
+	// the requests racing for the last messages of the quota never exceed it together
+	var wg sync.WaitGroup
+	var accepted atomic.Int64
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok, err := MgAppRepo.ConsumeSendQuotaRepo(ctx, "quota-test", "otp", day, 1, 5)
+			assert.NilError(t, err)
+			if ok {
+				accepted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(5), accepted.Load())
+
+	sent, ok, err := MgAppRepo.ConsumeSendQuotaRepo(ctx, "quota-test", "otp", day, 1, 5)
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+	assert.Equal(t, int64(5), sent)
+
+	// the other kind and the next day are counted apart
+	sent, ok, err = MgAppRepo.ConsumeSendQuotaRepo(ctx, "quota-test", "sms", day, 3, 5)
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.Equal(t, int64(3), sent)
+	sent, ok, err = MgAppRepo.ConsumeSendQuotaRepo(ctx, "quota-test", "otp", day.Add(24*time.Hour), 2, 5)
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.Equal(t, int64(2), sent)
+
+	// a request larger than the quota is refused without counting it
+	sent, ok, err = MgAppRepo.ConsumeSendQuotaRepo(ctx, "quota-test", "sms", day, 6, 5)
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+	assert.Equal(t, int64(3), sent)
+}
@@ -0,0 +1,233 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/domain"
+	"MgApplication/handler"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/poll"
+)
+
+func TestShadowSubstituteNumbers(t *testing.T) {
+	c := config.NewConfig(viper.New())
+	c.Set("sms.shadow.testnumbers", []string{"9999900001", "9999900002"})
+	c.Set("sms.shadow.numbermap", map[string]string{"9000000003": "9999900009"})
+	d := handler.NewShadowDispatcher(nil, nil, c)
+
+	numbers, ok := d.SubstituteNumbers([]string{"9000000001", "9000000002", "+919000000003", "9000000004"})
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, []string{"9999900001", "9999900002", "9999900009"}, numbers)
+
+	// Without test numbers only mapped recipients can be shadowed
+	c = config.NewConfig(viper.New())
+	c.Set("sms.shadow.numbermap", map[string]string{"9000000003": "9999900009"})
+	d = handler.NewShadowDispatcher(nil, nil, c)
+
+	numbers, ok = d.SubstituteNumbers([]string{"9000000003"})
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, []string{"9999900009"}, numbers)
+
+	_, ok = d.SubstituteNumbers([]string{"9000000003", "9000000001"})
+	assert.Assert(t, !ok)
+}
+
+// setShadowGateway sets the shadow gateway of an application for the duration of the test
+func setShadowGateway(t *testing.T, applicationID string, gateway string) {
+	t.Helper()
+	_, err := MgAppRepo.Db.Exec(context.Background(), `UPDATE msg_application SET shadow_gateway = $2 WHERE application_id = $1`, applicationID, gateway)
+	assert.NilError(t, err)
+	t.Cleanup(func() {
+		_, _ = MgAppRepo.Db.Exec(context.Background(), `UPDATE msg_application SET shadow_gateway = NULL WHERE application_id = $1`, applicationID)
+	})
+}
+
+// createSMSRequest sends an OTP through a CDAC server answering with gatewayResponse and
+// returns the data of the API response
+func createSMSRequest(t *testing.T, gatewayResponse string, configure func(c *config.Config)) map[string]any {
+	t.Helper()
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(gatewayResponse))
+	}))
+	defer cdac.Close()
+
+	c := config.NewConfig(viper.New())
+	c.Set("sms.msgstorerequest", 1)
+	c.Set("sms.cdac.url", cdac.URL)
+	c.Set("sms.cdac.username", "appostsms")
+	c.Set("sms.cdac.password", "cdacsecret")
+	c.Set("sms.cdac.securekey", "c7d427c9-63e7-4eec-a227-3ef840a75269")
+	configure(c)
+
+	engine := gin.New()
+	engine.POST("/v1/sms-request", handler.NewMgApplicationHandler(MgAppRepo, handler.NewDNDFilter(DNDRepo, c), c).CreateSMSRequestHandler)
+
+	input := `{
+		"application_id":"7",
+		"facility_id":"facility1",
+		"priority":1,
+		"message_text":"Dear Customer, OTP for booking is 1234, please do not share it with anyone - INDPOST",
+		"sender_id":"INPOST",
+		"mobile_numbers":"9000000001",
+		"entity_id":"1001081725895192800",
+		"template_id":"1007344609998507114"
+	}`
+	req := httptest.NewRequest("POST", "/v1/sms-request", bytes.NewBufferString(input))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var rsp struct {
+		Data map[string]any `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	return rsp.Data
+}
+
+func TestShadowDispatchDoesNotBlock(t *testing.T) {
+	setShadowGateway(t, "7", "2")
+
+	var mu sync.Mutex
+	var shadowNumbers []string
+	release := make(chan struct{})
+	nic := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		shadowNumbers = append(shadowNumbers, r.URL.Query().Get("mnumber"))
+		mu.Unlock()
+		<-release
+		_, _ = w.Write([]byte("Message Accepted for Request ID=123020250306~code=API000"))
+	}))
+	defer nic.Close()
+
+	start := time.Now()
+	data := createSMSRequest(t, "402,MsgID = 060320251741252969159appostsms", func(c *config.Config) {
+		c.Set("sms.shadow.enabled", true)
+		c.Set("sms.shadow.testnumbers", []string{"9999900001"})
+		c.Set("sms.shadow.ratelimit", 100)
+		c.Set("sms.nic.url", nic.URL)
+		c.Set("sms.nic.INPOSTUserName", "speedpost.sms")
+		c.Set("sms.nic.INPOSTPassword", "nicsecret")
+	})
+	// The primary response does not wait for the shadow gateway
+	assert.Assert(t, time.Since(start) < 5*time.Second)
+	assert.Equal(t, "060320251741252969159", data["reference_id"])
+	close(release)
+
+	communicationID := strings.TrimSpace(data["communication_id"].(string))
+	var result domain.ShadowResult
+	poll.WaitOn(t, func(poll.LogT) poll.Result {
+		err := MgAppRepo.Db.QueryRow(context.Background(),
+			`SELECT primary_gateway, primary_response_code, primary_accepted, shadow_gateway, shadow_response_code, shadow_accepted, recipients
+			 FROM msg_shadow_result WHERE communication_id = $1`, communicationID).
+			Scan(&result.PrimaryGateway, &result.PrimaryResponseCode, &result.PrimaryAccepted, &result.ShadowGateway, &result.ShadowResponseCode, &result.ShadowAccepted, &result.Recipients)
+		if err != nil {
+			return poll.Continue("shadow result not stored: %s", err)
+		}
+		return poll.Success()
+	}, poll.WithTimeout(10*time.Second))
+
+	assert.Equal(t, "1", result.PrimaryGateway)
+	assert.Equal(t, "402", result.PrimaryResponseCode)
+	assert.Assert(t, result.PrimaryAccepted)
+	assert.Equal(t, "2", result.ShadowGateway)
+	assert.Equal(t, "API000", result.ShadowResponseCode)
+	assert.Assert(t, result.ShadowAccepted)
+	assert.Equal(t, 1, result.Recipients)
+
+	// The real recipient never gets the copy
+	mu.Lock()
+	defer mu.Unlock()
+	assert.DeepEqual(t, []string{"9999900001"}, shadowNumbers)
+}
+
+// insertShadowResult stores the outcome of a shadow send on day (yyyy-mm-dd)
+func insertShadowResult(t *testing.T, applicationID string, day string, primaryCode string, primaryAccepted bool, shadowCode string, shadowAccepted bool) {
+	t.Helper()
+	_, err := MgAppRepo.Db.Exec(context.Background(),
+		`INSERT INTO msg_shadow_result (communication_id, application_id, recipients, primary_gateway, primary_response_code, primary_accepted,
+			shadow_gateway, shadow_response_code, shadow_accepted, created_date)
+		 VALUES (md5(random()::text), $1, 1, '1', $2, $3, '2', $4, $5, $6::timestamp)`,
+		applicationID, primaryCode, primaryAccepted, shadowCode, shadowAccepted, day+" 10:00:00")
+	assert.NilError(t, err)
+}
+
+func TestShadowComparisonReport(t *testing.T) {
+	insertShadowResult(t, "9921", "2024-05-02", "402", true, "API000", true)
+	insertShadowResult(t, "9921", "2024-05-02", "402", true, "API000", true)
+	insertShadowResult(t, "9921", "2024-05-02", "402", true, "ES-TM-BLOCKED", false)
+	insertShadowResult(t, "9921", "2024-05-02", "407", false, "ES-TM-BLOCKED", false)
+	// Outside the period
+	insertShadowResult(t, "9921", "2024-05-04", "407", false, "API000", true)
+
+	req := httptest.NewRequest("GET", "/v1/admin/shadow/comparison?from-date=01-05-2024&to-date=03-05-2024&application-id=9921", bytes.NewBuffer(nil))
+	setBearerToken(req, "ops1", "admin")
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var rsp struct {
+		Data struct {
+			Gateways        []domain.ShadowComparison     `json:"gateways"`
+			CodeDifferences []domain.ShadowCodeDifference `json:"code_differences"`
+		} `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+
+	assert.Equal(t, 1, len(rsp.Data.Gateways))
+	gateways := rsp.Data.Gateways[0]
+	assert.Equal(t, int64(4), gateways.Requests)
+	assert.Equal(t, int64(3), gateways.PrimaryAccepted)
+	assert.Equal(t, int64(2), gateways.ShadowAccepted)
+	assert.Equal(t, 75.0, gateways.PrimaryAcceptanceRate)
+	assert.Equal(t, 50.0, gateways.ShadowAcceptanceRate)
+
+	assert.Equal(t, 2, len(rsp.Data.CodeDifferences))
+	for _, difference := range rsp.Data.CodeDifferences {
+		assert.Equal(t, "ES-TM-BLOCKED", difference.ShadowResponseCode)
+		assert.Equal(t, int64(1), difference.Requests)
+	}
+}
+
+func TestShadowComparisonReport_Forbidden(t *testing.T) {
+	req := httptest.NewRequest("GET", "/v1/admin/shadow/comparison?from-date=01-05-2024&to-date=03-05-2024", nil)
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestAdminSetShadowGateway(t *testing.T) {
+	t.Cleanup(func() {
+		_, _ = MgAppRepo.Db.Exec(context.Background(), `UPDATE msg_application SET shadow_gateway = NULL WHERE application_id = 7`)
+	})
+
+	rec := gatewayCodeRequest("PUT", "/v1/admin/applications/7/shadow-gateway", "admin", map[string]any{"shadow_gateway": "2"})
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	gateway, err := MgAppRepo.GetShadowGatewayRepo(context.Background(), "7")
+	assert.NilError(t, err)
+	assert.Equal(t, "2", gateway)
+
+	rec = gatewayCodeRequest("PUT", "/v1/admin/applications/7/shadow-gateway", "admin", map[string]any{"shadow_gateway": ""})
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	gateway, err = MgAppRepo.GetShadowGatewayRepo(context.Background(), "7")
+	assert.NilError(t, err)
+	assert.Equal(t, "", gateway)
+
+	rec = gatewayCodeRequest("PUT", "/v1/admin/applications/999999/shadow-gateway", "admin", map[string]any{"shadow_gateway": "2"})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = gatewayCodeRequest("PUT", "/v1/admin/applications/7/shadow-gateway", "admin", map[string]any{"shadow_gateway": "3"})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
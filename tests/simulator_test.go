@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+	"MgApplication/handler"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+// simulatedSMSRequest sends a transactional message with the gateway simulator enabled through a
+// CDAC server that must not be called
+func simulatedSMSRequest(t *testing.T, failureRate float64, env string) (*httptest.ResponseRecorder, bool) {
+	t.Helper()
+	called := false
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		_, _ = w.Write([]byte("402,MsgID = 150920241726381202121"))
+	}))
+	defer cdac.Close()
+
+	c := config.NewConfig(viper.New())
+	c.Set("info.env", env)
+	c.Set("sms.msgstorerequest", 1)
+	c.Set("sms.cdac.url", cdac.URL)
+	c.Set("sms.gateway", "simulator")
+	c.Set("sms.simulator.failurerate", failureRate)
+	c.Set("sms.simulator.latency.min", "10ms")
+	c.Set("sms.simulator.latency.max", "20ms")
+
+	engine := gin.New()
+	engine.POST("/v1/sms-request", handler.NewMgApplicationHandler(MgAppRepo, handler.NewDNDFilter(DNDRepo, c), c).CreateSMSRequestHandler)
+
+	input := `{
+		"application_id":"7",
+		"facility_id":"facility1",
+		"priority":2,
+		"message_text":"Your article is delivered - INDPOST",
+		"sender_id":"INPOST",
+		"mobile_numbers":"9000000021",
+		"entity_id":"1001081725895192800",
+		"template_id":"1007344609998507114"
+	}`
+	req := httptest.NewRequest("POST", "/v1/sms-request", bytes.NewBufferString(input))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec, called
+}
+
+func TestGatewaySimulatorSuccess(t *testing.T) {
+	start := time.Now()
+	rec, called := simulatedSMSRequest(t, 0, "dev")
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Assert(t, !called)
+	assert.Assert(t, time.Since(start) >= 10*time.Millisecond)
+
+	var rsp struct {
+		Data struct {
+			ReferenceID string `json:"reference_id"`
+		} `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Assert(t, rsp.Data.ReferenceID != "")
+}
+
+func TestGatewaySimulatorFailure(t *testing.T) {
+	rec, called := simulatedSMSRequest(t, 1, "dev")
+	assert.Assert(t, rec.Code != http.StatusCreated, rec.Body.String())
+	assert.Assert(t, !called)
+}
+
+func TestGatewaySimulatorIgnoredInProd(t *testing.T) {
+	rec, called := simulatedSMSRequest(t, 1, "prod")
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Assert(t, called)
+}
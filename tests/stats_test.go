@@ -0,0 +1,210 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"MgApplication/core/clock"
+	"MgApplication/core/domain"
+	"MgApplication/handler"
+
+	"gotest.tools/v3/assert"
+)
+
+// insertPendingMessage stores a message to two recipients awaiting its gateway response
+func insertPendingMessage(t *testing.T, applicationID string, createdDate string) (uint64, string) {
+	t.Helper()
+	var requestID uint64
+	var communicationID string
+	err := MgAppRepo.Db.QueryRow(context.Background(),
+		`INSERT INTO msg_request (application_id, priority, gateway, status, mobile_number, created_date)
+		 VALUES ($1, 2, '1', 'pending', '{9000000001,9000000002}', COALESCE(NULLIF($2, '')::timestamptz, CURRENT_TIMESTAMP))
+		 RETURNING request_id, communication_id`, applicationID, createdDate).
+		Scan(&requestID, &communicationID)
+	assert.NilError(t, err)
+	return requestID, strings.TrimSpace(communicationID)
+}
+
+func saveResponse(t *testing.T, communicationID string, accepted bool) {
+	t.Helper()
+	msgRsp := domain.MsgResponse{CommunicationID: communicationID, ResponseCode: "02", ResponseText: "gateway unavailable"}
+	if accepted {
+		msgRsp = domain.MsgResponse{CommunicationID: communicationID, ResponseCode: "402", ResponseText: "Submitted Successfully", ReferenceID: "SP" + communicationID}
+	}
+	ctx := context.Background()
+	_, err := MgAppRepo.SaveResponse(&ctx, &msgRsp)
+	assert.NilError(t, err)
+}
+
+// rollupTotals sums the rollup rows of an application
+func rollupTotals(t *testing.T, applicationID string) (int64, int64, int64) {
+	t.Helper()
+	var sent, failed, delivered int64
+	err := MgAppRepo.Db.QueryRow(context.Background(),
+		`SELECT COALESCE(SUM(sent), 0)::int8, COALESCE(SUM(failed), 0)::int8, COALESCE(SUM(delivered), 0)::int8
+		 FROM msg_stats_hourly WHERE application_id = $1`, applicationID).
+		Scan(&sent, &failed, &delivered)
+	assert.NilError(t, err)
+	return sent, failed, delivered
+}
+
+// assertRollupConsistent checks the rollup of an application against msg_request
+func assertRollupConsistent(t *testing.T, applicationID string, fromDate time.Time, toDate time.Time) {
+	t.Helper()
+	mismatches, err := ReportsRepo.CheckStatsHourlyRepo(context.Background(), fromDate, toDate)
+	assert.NilError(t, err)
+	for _, mismatch := range mismatches {
+		assert.Assert(t, mismatch.ApplicationID != applicationID, "rollup differs from msg_request: %+v", mismatch)
+	}
+}
+
+func TestStatsRollupConcurrentIncrements(t *testing.T) {
+	const applicationID = "9901"
+	const messages = 40
+
+	communicationIDs := make([]string, messages)
+	for i := range communicationIDs {
+		_, communicationIDs[i] = insertPendingMessage(t, applicationID, "")
+	}
+
+	var wg sync.WaitGroup
+	for i, communicationID := range communicationIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Every fourth message is rejected by the first gateway and accepted by the fallback
+			if i%4 == 0 {
+				saveResponse(t, communicationID, false)
+			}
+			saveResponse(t, communicationID, i%2 == 0)
+		}()
+	}
+	wg.Wait()
+
+	sent, failed, delivered := rollupTotals(t, applicationID)
+	assert.Equal(t, int64(messages), sent)
+	assert.Equal(t, int64(messages), failed)
+	assert.Equal(t, int64(0), delivered)
+
+	today := time.Now()
+	assertRollupConsistent(t, applicationID, today, today)
+}
+
+func TestStatsRollupCountsDeliveredMessages(t *testing.T) {
+	const applicationID = "9902"
+
+	requestID, communicationID := insertPendingMessage(t, applicationID, "")
+	saveResponse(t, communicationID, true)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := MgAppRepo.UpdateDeliveryStatusRepo(context.Background(), requestID, handler.DeliveryStatusDelivered, nil)
+			assert.NilError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	sent, failed, delivered := rollupTotals(t, applicationID)
+	assert.Equal(t, int64(2), sent)
+	assert.Equal(t, int64(0), failed)
+	assert.Equal(t, int64(2), delivered)
+
+	today := time.Now()
+	assertRollupConsistent(t, applicationID, today, today)
+}
+
+func TestStatsRollupBackfill(t *testing.T) {
+	const applicationID = "9903"
+
+	for hour := range 3 {
+		_, communicationID := insertPendingMessage(t, applicationID, fmt.Sprintf("2024-03-01 %02d:15:00", 10+hour))
+		_, err := MgAppRepo.Db.Exec(context.Background(),
+			`UPDATE msg_request SET status = 'submitted', response_code = '402', reference_id = 'SP1' WHERE communication_id = $1`, communicationID)
+		assert.NilError(t, err)
+	}
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	sent, _, _ := rollupTotals(t, applicationID)
+	assert.Equal(t, int64(0), sent)
+
+	rebuilt, err := ReportsRepo.RebuildStatsHourlyRepo(context.Background(), day, day)
+	assert.NilError(t, err)
+	assert.Assert(t, rebuilt >= 3)
+
+	sent, failed, _ := rollupTotals(t, applicationID)
+	assert.Equal(t, int64(6), sent)
+	assert.Equal(t, int64(0), failed)
+	assertRollupConsistent(t, applicationID, day, day)
+
+	// A second backfill replaces the rows instead of adding to them
+	_, err = ReportsRepo.RebuildStatsHourlyRepo(context.Background(), day, day)
+	assert.NilError(t, err)
+	sent, _, _ = rollupTotals(t, applicationID)
+	assert.Equal(t, int64(6), sent)
+}
+
+func TestStatsRollupBackfillDuringIncrements(t *testing.T) {
+	const applicationID = "9904"
+	const messages = 30
+
+	communicationIDs := make([]string, messages)
+	for i := range communicationIDs {
+		_, communicationIDs[i] = insertPendingMessage(t, applicationID, "")
+	}
+
+	today := time.Now()
+	var wg sync.WaitGroup
+	for i, communicationID := range communicationIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			saveResponse(t, communicationID, i%3 != 0)
+		}()
+		if i%10 == 0 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := ReportsRepo.RebuildStatsHourlyRepo(context.Background(), today, today)
+				assert.NilError(t, err)
+			}()
+		}
+	}
+	wg.Wait()
+
+	sent, failed, _ := rollupTotals(t, applicationID)
+	assert.Equal(t, int64(40), sent)
+	assert.Equal(t, int64(20), failed)
+	assertRollupConsistent(t, applicationID, today, today)
+}
+
+func TestReportsSplitBusinessDaysAtISTMidnight(t *testing.T) {
+	const applicationID = "9905"
+
+	// 18:30 UTC is midnight in Asia/Kolkata
+	before, _ := insertPendingMessage(t, applicationID, "2024-08-26 18:29:59+00")
+	after, _ := insertPendingMessage(t, applicationID, "2024-08-26 18:30:00+00")
+
+	orphansOf := func(day time.Time) []int64 {
+		orphans, err := ReportsRepo.FindOrphanedRequestsRepo(context.Background(), day, day)
+		assert.NilError(t, err)
+		var ids []int64
+		for _, orphan := range orphans {
+			if orphan.ApplicationID == applicationID {
+				ids = append(ids, orphan.RequestID)
+			}
+		}
+		return ids
+	}
+
+	day26, _ := clock.ParseDate("02-01-2006", "26-08-2024")
+	day27, _ := clock.ParseDate("02-01-2006", "27-08-2024")
+	assert.DeepEqual(t, orphansOf(day26), []int64{int64(before)})
+	assert.DeepEqual(t, orphansOf(day27), []int64{int64(after)})
+}
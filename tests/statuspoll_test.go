@@ -0,0 +1,263 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	config "MgApplication/api-config"
+	"MgApplication/handler"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+// statusPollConfig returns a configuration polling the CDAC report API at cdacURL
+func statusPollConfig(cdacURL string, webhookURL string) *config.Config {
+	c := config.NewConfig(viper.New())
+	c.Set("sms.cdac.deliverystatusurl", cdacURL)
+	c.Set("sms.cdac.username", "appostsms")
+	c.Set("sms.cdac.password", "secret")
+	c.Set("sms.statuspoll.interval", time.Hour)
+	c.Set("sms.statuspoll.batchsize", 100)
+	c.Set("sms.statuspoll.ratelimit", 50)
+	c.Set("sms.statuspoll.maxage", time.Hour)
+	c.Set("sms.statuspoll.timeout", time.Second)
+	c.Set("sms.statuspoll.webhookurl", webhookURL)
+	return c
+}
+
+// cdacReportServer answers CDAC delivery report requests from reports, keyed by message id,
+// and counts the requests per message id
+func cdacReportServer(t *testing.T, reports map[string]string) (*httptest.Server, func(msgid string) int) {
+	t.Helper()
+	var mu sync.Mutex
+	fetches := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msgid := r.URL.Query().Get("msgid")
+		mu.Lock()
+		fetches[msgid]++
+		mu.Unlock()
+		_, _ = w.Write([]byte(reports[msgid]))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func(msgid string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return fetches[msgid]
+	}
+}
+
+// insertSubmittedMessage stores a CDAC message accepted under referenceID
+func insertSubmittedMessage(t *testing.T, referenceID string) (uint64, string) {
+	t.Helper()
+	var requestID uint64
+	var communicationID string
+	err := MgAppRepo.Db.QueryRow(context.Background(),
+		`INSERT INTO msg_request (application_id, priority, gateway, status, reference_id, response_code)
+		 VALUES ('7', 3, '1', 'submitted', $1, '402') RETURNING request_id, communication_id`, referenceID).
+		Scan(&requestID, &communicationID)
+	assert.NilError(t, err)
+	return requestID, strings.TrimSpace(communicationID)
+}
+
+func messageStatus(t *testing.T, requestID uint64) string {
+	t.Helper()
+	var status string
+	err := MgAppRepo.Db.QueryRow(context.Background(), `SELECT status FROM msg_request WHERE request_id = $1`, requestID).Scan(&status)
+	assert.NilError(t, err)
+	return status
+}
+
+func TestDeliveryStatusPoller(t *testing.T) {
+	cdac, fetches := cdacReportServer(t, map[string]string{
+		"SP0000001appostsms": "9000000001,DELIVRD,2024-08-27 10:00:00\n9000000002,DELIVRD,2024-08-27 10:00:01\n",
+		"SP0000002appostsms": "9000000003,DELIVRD,2024-08-27 10:00:00\n9000000004,UNDELIV,2024-08-27 10:00:02\n",
+		"SP0000003appostsms": "9000000005,DELIVRD,2024-08-27 10:00:00\n9000000006,Submitted,2024-08-27 10:00:00\n",
+	})
+
+	var mu sync.Mutex
+	events := make(map[string]map[string]any)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]any
+		assert.NilError(t, json.NewDecoder(r.Body).Decode(&event))
+		mu.Lock()
+		events[event["communication_id"].(string)] = event
+		mu.Unlock()
+	}))
+	defer webhook.Close()
+
+	delivered, deliveredCommID := insertSubmittedMessage(t, "SP0000001")
+	partial, partialCommID := insertSubmittedMessage(t, "SP0000002")
+	pending, pendingCommID := insertSubmittedMessage(t, "SP0000003")
+
+	poller, err := handler.NewDeliveryStatusPoller(MgAppRepo, nil, statusPollConfig(cdac.URL, webhook.URL))
+	assert.NilError(t, err)
+
+	updated, err := poller.Poll(context.Background())
+	assert.NilError(t, err)
+	assert.Assert(t, updated >= 2)
+
+	assert.Equal(t, handler.DeliveryStatusDelivered, messageStatus(t, delivered))
+	assert.Equal(t, handler.DeliveryStatusPartiallyDelivered, messageStatus(t, partial))
+	assert.Equal(t, handler.DeliveryStatusSubmitted, messageStatus(t, pending))
+
+	// Webhooks are only posted on the transition to a final status
+	mu.Lock()
+	assert.Equal(t, "delivered", events[deliveredCommID]["status"])
+	assert.Equal(t, "sms.delivery_status", events[deliveredCommID]["event"])
+	assert.Equal(t, "partially_delivered", events[partialCommID]["status"])
+	assert.Equal(t, 2, len(events[partialCommID]["recipients"].([]any)))
+	_, notified := events[pendingCommID]
+	mu.Unlock()
+	assert.Assert(t, !notified)
+
+	// The pending message was claimed within the poll interval and is not fetched again
+	_, err = poller.Poll(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, 1, fetches("SP0000003appostsms"))
+	assert.Equal(t, 1, fetches("SP0000001appostsms"))
+}
+
+func TestClaimPendingDeliveryStatusSkipsLockedRows(t *testing.T) {
+	requestID, _ := insertSubmittedMessage(t, "SP0000010")
+	ctx := context.Background()
+
+	// Another instance holds the row lock while fetching
+	tx, err := MgAppRepo.Db.Begin(ctx)
+	assert.NilError(t, err)
+	defer func() { _ = tx.Rollback(ctx) }()
+	_, err = tx.Exec(ctx, `SELECT 1 FROM msg_request WHERE request_id = $1 FOR UPDATE`, requestID)
+	assert.NilError(t, err)
+
+	claimed, err := MgAppRepo.ClaimPendingDeliveryStatusRepo(ctx, []string{"1"}, 100, time.Hour, time.Hour, time.Hour)
+	assert.NilError(t, err)
+	for _, msg := range claimed {
+		assert.Assert(t, msg.RequestID != requestID)
+	}
+
+	assert.NilError(t, tx.Rollback(ctx))
+	claimed, err = MgAppRepo.ClaimPendingDeliveryStatusRepo(ctx, []string{"1"}, 100, time.Hour, time.Hour, time.Hour)
+	assert.NilError(t, err)
+	found := false
+	for _, msg := range claimed {
+		found = found || msg.RequestID == requestID
+	}
+	assert.Assert(t, found)
+}
+
+// insertPendingLoad stores n submitted CDAC messages accepted under reference ids starting with
+// prefix. They are moved out of the submitted state when the test ends, so later polls do not
+// claim them.
+func insertPendingLoad(t *testing.T, prefix string, n int) {
+	t.Helper()
+	_, err := MgAppRepo.Db.Exec(context.Background(),
+		`INSERT INTO msg_request (application_id, priority, gateway, status, reference_id, response_code)
+		 SELECT '7', 3, '1', 'submitted', $1 || lpad(n::text, 7, '0'), '402' FROM generate_series(1, $2::int) n`, prefix, n)
+	assert.NilError(t, err)
+	t.Cleanup(func() {
+		_, err := MgAppRepo.Db.Exec(context.Background(),
+			`UPDATE msg_request SET status = 'failed' WHERE status = 'submitted' AND reference_id LIKE $1 || '%'`, prefix)
+		assert.NilError(t, err)
+	})
+}
+
+func TestDeliveryStatusPollerYieldsToBreaker(t *testing.T) {
+	var fetches atomic.Int64
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer cdac.Close()
+	insertPendingLoad(t, "LB", 20)
+
+	c := statusPollConfig(cdac.URL, "")
+	c.Set("sms.statuspoll.batchsize", 20)
+	c.Set("sms.statuspoll.ratelimit", 0)
+	c.Set("sms.statuspoll.breakerthreshold", 3)
+	c.Set("sms.statuspoll.breakercooldown", time.Hour)
+	poller, err := handler.NewDeliveryStatusPoller(MgAppRepo, nil, c)
+	assert.NilError(t, err)
+
+	_, err = poller.Poll(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, int64(3), fetches.Load())
+	assert.Equal(t, float64(1), testutil.ToFloat64(handler.StatusPollBreakerOpen.WithLabelValues("1")))
+
+	// The gateway is not polled while the breaker is open
+	_, err = poller.Poll(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, int64(3), fetches.Load())
+}
+
+func TestDeliveryStatusWebhookRetriedFromTheOutbox(t *testing.T) {
+	cdac, _ := cdacReportServer(t, map[string]string{
+		"SP0000020appostsms": "9000000001,DELIVRD,2024-08-27 10:00:00\n",
+	})
+
+	var attempts atomic.Int32
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer webhook.Close()
+
+	requestID, communicationID := insertSubmittedMessage(t, "SP0000020")
+	c := statusPollConfig(cdac.URL, webhook.URL)
+	c.Set("sms.statuspoll.webhookinterval", time.Millisecond)
+	poller, err := handler.NewDeliveryStatusPoller(MgAppRepo, nil, c)
+	assert.NilError(t, err)
+
+	// The webhook is down when the status changes, the event stays queued
+	_, err = poller.Poll(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, handler.DeliveryStatusDelivered, messageStatus(t, requestID))
+	queued := func() int {
+		var n int
+		err := MgAppRepo.Db.QueryRow(context.Background(),
+			`SELECT COUNT(*) FROM msg_webhook_outbox WHERE communication_id = $1`, communicationID).Scan(&n)
+		assert.NilError(t, err)
+		return n
+	}
+	assert.Equal(t, 1, queued())
+
+	// and is posted again once due
+	time.Sleep(10 * time.Millisecond)
+	_, err = poller.Poll(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, int32(2), attempts.Load())
+	assert.Equal(t, 0, queued())
+}
+
+func TestNICMessagesAreNotPolled(t *testing.T) {
+	var requestID uint64
+	err := MgAppRepo.Db.QueryRow(context.Background(),
+		`INSERT INTO msg_request (application_id, priority, gateway, status, reference_id, response_code)
+		 VALUES ('7', 3, '2', 'submitted', '123020250306', 'API000') RETURNING request_id`).
+		Scan(&requestID)
+	assert.NilError(t, err)
+
+	cdac, _ := cdacReportServer(t, map[string]string{})
+	poller, err := handler.NewDeliveryStatusPoller(MgAppRepo, nil, statusPollConfig(cdac.URL, ""))
+	assert.NilError(t, err)
+	_, err = poller.Poll(context.Background())
+	assert.NilError(t, err)
+
+	var polledAt *time.Time
+	err = MgAppRepo.Db.QueryRow(context.Background(), `SELECT status_polled_at FROM msg_request WHERE request_id = $1`, requestID).Scan(&polledAt)
+	assert.NilError(t, err)
+	assert.Assert(t, polledAt == nil, "NIC message was claimed")
+
+	backlog, err := MgAppRepo.CountPendingDeliveryStatusRepo(context.Background(), []string{"1"}, time.Hour)
+	assert.NilError(t, err)
+	_, counted := backlog["2"]
+	assert.Assert(t, !counted, "NIC messages are counted in the backlog")
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	config "MgApplication/api-config"
+	"MgApplication/core/dlt"
+	"MgApplication/handler"
+	repo "MgApplication/repo/postgres"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func templateLintConfig(enforce bool) *config.Config {
+	c := config.NewConfig(viper.New())
+	c.Set("sms.branding", map[string]string{"INPOST": "- INDPOST"})
+	c.Set("sms.dlt.lint.enabled", true)
+	c.Set("sms.dlt.lint.enforce", enforce)
+	c.Set("sms.dlt.lint.maxvars", 3)
+	c.Set("sms.dlt.lint.varlength", 30)
+	c.Set("sms.dlt.lint.maxlength", 2000)
+	c.Set("sms.dlt.lint.gateways.2.maxvars", 1)
+	return c
+}
+
+func TestTemplateLinter(t *testing.T) {
+	cases := []struct {
+		name    string
+		enforce bool
+		gateway string
+		format  string
+		rules   []string
+	}{
+		{"valid", true, "1", "Dear {#var#}, your article {#var#} is delivered - INDPOST", nil},
+		{"gateway limit", true, "2", "Dear {#var#}, your article {#var#} is delivered - INDPOST", []string{dlt.RuleVarCount}},
+		{"adjacent placeholders", true, "1", "Dear {#var#} {#var#}, your article is delivered - INDPOST", []string{dlt.RuleVarContext}},
+		{"missing branding", true, "1", "Dear {#var#}, your article is delivered", []string{dlt.RuleBranding}},
+		{"warnings only", false, "1", "Dear {#var#} {#var#}, your article is delivered", []string{dlt.RuleVarContext, dlt.RuleBranding}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := templateLintConfig(tc.enforce)
+			lint := handler.NewTemplateLinter(c, handler.NewSenderBranding(c))
+			violations, err := lint.Lint(tc.gateway, "INPOST", tc.format)
+			assert.Equal(t, len(violations), len(tc.rules))
+			for i, rule := range tc.rules {
+				assert.Equal(t, violations[i].Rule, rule)
+			}
+			if tc.enforce && len(tc.rules) > 0 {
+				assert.ErrorContains(t, err, "template breaks DLT rules")
+			} else {
+				assert.NilError(t, err)
+			}
+		})
+	}
+
+	// disabled linting checks nothing
+	c := templateLintConfig(true)
+	c.Set("sms.dlt.lint.enabled", false)
+	violations, err := handler.NewTemplateLinter(c, handler.NewSenderBranding(c)).Lint("1", "INPOST", "{#var#}{#var#}")
+	assert.NilError(t, err)
+	assert.Equal(t, len(violations), 0)
+}
+
+// createLintedTemplate creates a template of INPOST with the given format and returns the response
+func createLintedTemplate(t *testing.T, enforce bool, format string) *httptest.ResponseRecorder {
+	t.Helper()
+	c := templateLintConfig(enforce)
+	engine := gin.New()
+	engine.POST("/v1/sms-templates", handler.NewTemplateHandler(repo.NewTemplateRepository(MgAppRepo.Db, c), c).CreateTemplateHandler)
+
+	input := `{
+		"template_local_id":"571",
+		"application_id":"104",
+		"template_name":"Delivery Template Lint",
+		"template_format":"` + format + `",
+		"sender_id":"INPOST",
+		"entity_id":"1001081725895192800",
+		"template_id":"165071603777774104478741",
+		"message_type":"PM",
+		"gateway":"1",
+		"status":true
+	}`
+	req := httptest.NewRequest("POST", "/v1/sms-templates", bytes.NewBufferString(input))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCreateTemplateLintEnforced(t *testing.T) {
+	rec := createLintedTemplate(t, true, "Dear {#var#}{#var#}, your article is delivered")
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	assert.Assert(t, bytes.Contains(rec.Body.Bytes(), []byte(dlt.RuleVarContext)))
+	assert.Assert(t, bytes.Contains(rec.Body.Bytes(), []byte(dlt.RuleBranding)))
+}
+
+func TestCreateTemplateLintWarnings(t *testing.T) {
+	rec := createLintedTemplate(t, false, "Dear {#var#}{#var#}, your article is delivered")
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var rsp struct {
+		Data struct {
+			EstimatedLength int             `json:"estimated_length"`
+			LengthLimit     int             `json:"length_limit"`
+			Warnings        []dlt.Violation `json:"warnings"`
+		} `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	// 32 fixed characters and 2 variables of 30
+	assert.Equal(t, rsp.Data.EstimatedLength, 92)
+	assert.Equal(t, rsp.Data.LengthLimit, 2000)
+	assert.Equal(t, len(rsp.Data.Warnings), 2)
+	assert.Equal(t, rsp.Data.Warnings[0].Rule, dlt.RuleVarContext)
+	assert.Equal(t, rsp.Data.Warnings[1].Rule, dlt.RuleBranding)
+}
+
+func TestCreateTemplateTooLong(t *testing.T) {
+	// 1947 fixed characters fit the limit of 2000, not with 2 variables of 30
+	format := "Dear {#var#}, " + strings.Repeat("a", 1929) + " {#var#} - INDPOST"
+	rec := createLintedTemplate(t, true, format)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	assert.Assert(t, bytes.Contains(rec.Body.Bytes(), []byte(dlt.RuleLength)))
+	assert.Assert(t, bytes.Contains(rec.Body.Bytes(), []byte("template is 2007 characters long")))
+}
@@ -2,10 +2,18 @@ package tests
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
+	"MgApplication/core/domain"
+	repo "MgApplication/repo/postgres"
+
+	"github.com/gin-gonic/gin"
 	"gotest.tools/v3/assert"
 )
 
@@ -13,11 +21,11 @@ import (
 func TestCreateTemplateHandlerSuccess(t *testing.T) {
 	input := `{
 	"template_local_id":"569",
-	"application_id":"69",
+	"application_id":"104",
 	"template_name":"Test Template safron",
 	"template_format":"Your OTP is {#val} for {#val} for",
 	"sender_id":"INPOST",
-	"entity_id":"16507160377410448739",
+	"entity_id":"1001081725895192800",
 	"template_id":"165071603777774104478739",
 	"message_type":"PM",
 	"gateway":"1",
@@ -26,9 +34,40 @@ func TestCreateTemplateHandlerSuccess(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/sms-templates", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusCreated, rec.Code)
+	var templateLocalID uint64
+	err := MgAppRepo.Db.QueryRow(context.Background(), `SELECT template_local_id FROM msg_template WHERE template_id = '165071603777774104478739'`).Scan(&templateLocalID)
+	assert.NilError(t, err)
+	assert.Equal(t, "/v1/sms-templates/"+strconv.FormatUint(templateLocalID, 10), rec.Header().Get("Location"))
+}
+
+// A template of an application that does not exist is not created
+func TestCreateTemplateHandlerUnknownApplication(t *testing.T) {
+	input := `{
+	"application_id":"69",
+	"template_name":"Test Template orphan",
+	"template_format":"Your OTP is {#var#} - INDPOST",
+	"sender_id":"INPOST",
+	"entity_id":"1001081725895192800",
+	"template_id":"1007000000000000001469",
+	"message_type":"PM",
+	"gateway":"1",
+	"status":true
+	}`
+	req := httptest.NewRequest("POST", "/v1/sms-templates", bytes.NewBuffer([]byte(input)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	assert.Assert(t, strings.Contains(rec.Body.String(), "application_id 69 does not reference an existing application"), rec.Body.String())
+
+	var count int
+	err := MgAppRepo.Db.QueryRow(context.Background(), `SELECT COUNT(1) FROM msg_template WHERE template_id = '1007000000000000001469'`).Scan(&count)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, count)
 }
 
 func TestCreateTemplateHandlerBindingError(t *testing.T) {
@@ -38,7 +77,7 @@ func TestCreateTemplateHandlerBindingError(t *testing.T) {
 		"template_name":"Test Template 1233666",
 		"template_format":"Your OTP is {#val} for {#val} for",
 		"sender_id":"INPOST",
-		"entityid":"16507160377410448739",
+		"entityid":"1001081725895192800",
 		"template_id":"165071603777774104478739",
 		"message_type":"PM",
 		"gateway":"1",
@@ -47,7 +86,7 @@ func TestCreateTemplateHandlerBindingError(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/sms-templates", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -59,7 +98,7 @@ func TestCreateTemplateHandlerValidationError(t *testing.T) {
 		"template_name":"Test Template 1233666",
 		"template_format":"Your OTP is {#val} for {#val} for",
 		"sender_id":"INPOST",
-		"entity_id":"16507160377410448739",
+		"entity_id":"1001081725895192800",
 		"template_id":"165071603777774104478739",
 		"message_type":"PM",
 		"gateway":"1",
@@ -68,7 +107,7 @@ func TestCreateTemplateHandlerValidationError(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/sms-templates", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -78,7 +117,7 @@ func TestCreateTemplateHandlerMissingParam(t *testing.T) {
 	req := httptest.NewRequest("POST", "/v1/sms-templates", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
@@ -88,7 +127,7 @@ func TestListTemplatesHandlerSuccess(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-templates?skip=0&limit=0", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -97,7 +136,7 @@ func TestListTemplatesHandlerBindingError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-templates?sk=0", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -106,7 +145,7 @@ func TestListTemplatesHandlerValidationError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-templates?skip=200abc", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
@@ -115,17 +154,153 @@ func TestListTemplatesHandlerInvalidParam(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-templates?skip=0&limit=0", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
 
+// seedFacetTemplates inserts the templates of sender FACETS the filter and facet tests list
+func seedFacetTemplates(t *testing.T) {
+	t.Helper()
+	_, err := MgAppRepo.Db.Exec(context.Background(), `
+		DELETE FROM msg_template WHERE sender_id = 'FACETS';
+		INSERT INTO msg_template (application_id, template_name, template_format, sender_id, entity_id, template_id, gateway, message_type, status_cd) VALUES
+			('3,5', 'Facet alpha', 'Your OTP is {#var#}', 'FACETS', '1001081725895192800', '1007000000000000001', '1', 'PM', 1),
+			('5', 'Facet beta', 'Your 100% cashback {#var#}', 'FACETS', '1001081725895192800', '1007000000000000002', '2', 'PM', 0),
+			('3', 'Facet gamma', 'Your article {#var#} is booked', 'FACETS', '1001081725895192800', '1007000000000000003', '2', 'UC', 1)`)
+	assert.NilError(t, err)
+}
+
+func listTemplatesContext() *gin.Context {
+	gctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	gctx.Request = httptest.NewRequest("GET", "/v1/sms-templates", nil)
+	return gctx
+}
+
+func TestListTemplatesRepoCombinedFilters(t *testing.T) {
+	seedFacetTemplates(t)
+	tr := repo.NewTemplateRepository(MgAppRepo.Db, MgAppRepo.Cfg)
+	gctx := listTemplatesContext()
+	page := &domain.Meta{Skip: 0, Limit: 10}
+
+	templates, total, err := tr.ListTemplatesRepo(gctx, page, domain.TemplateFilter{SenderID: "FACETS", ApplicationID: "5", Gateway: "2"})
+	assert.NilError(t, err)
+	assert.Equal(t, uint64(1), total)
+	assert.Equal(t, 1, len(templates))
+	assert.Equal(t, "Facet beta", templates[0].TemplateName)
+
+	// the application filter keeps the names of all the applications of a template
+	templates, total, err = tr.ListTemplatesRepo(gctx, page, domain.TemplateFilter{SenderID: "FACETS", ApplicationID: "5", MessageType: "PM", OrderBy: "template_name"})
+	assert.NilError(t, err)
+	assert.Equal(t, uint64(2), total)
+	assert.Equal(t, "Facet alpha", templates[0].TemplateName)
+	assert.Assert(t, strings.Contains(templates[0].ApplicationID, ", "), templates[0].ApplicationID)
+
+	// % is matched literally by the search
+	templates, _, err = tr.ListTemplatesRepo(gctx, page, domain.TemplateFilter{SenderID: "FACETS", Search: "100%"})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(templates))
+	assert.Equal(t, "Facet beta", templates[0].TemplateName)
+
+	templates, _, err = tr.ListTemplatesRepo(gctx, page, domain.TemplateFilter{SenderID: "FACETS", OrderBy: "template_name", Descending: true})
+	assert.NilError(t, err)
+	assert.Equal(t, 3, len(templates))
+	assert.Equal(t, "Facet gamma", templates[0].TemplateName)
+	assert.Equal(t, "Facet alpha", templates[2].TemplateName)
+}
+
+func TestListTemplatesRepoEmptyResult(t *testing.T) {
+	tr := repo.NewTemplateRepository(MgAppRepo.Db, MgAppRepo.Cfg)
+	gctx := listTemplatesContext()
+	filter := domain.TemplateFilter{Search: "no template has this text"}
+
+	templates, total, err := tr.ListTemplatesRepo(gctx, &domain.Meta{Skip: 0, Limit: 10}, filter)
+	assert.NilError(t, err)
+	assert.Equal(t, uint64(0), total)
+	assert.Equal(t, 0, len(templates))
+
+	facets, err := tr.ListTemplateFacetsRepo(gctx, filter)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(facets.Status))
+	assert.Equal(t, 0, len(facets.Gateway))
+}
+
+func TestListTemplateFacetsRepo(t *testing.T) {
+	seedFacetTemplates(t)
+	tr := repo.NewTemplateRepository(MgAppRepo.Db, MgAppRepo.Cfg)
+	gctx := listTemplatesContext()
+
+	facets, err := tr.ListTemplateFacetsRepo(gctx, domain.TemplateFilter{SenderID: "FACETS"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]uint64{"0": 1, "1": 2}, facets.Status)
+	assert.DeepEqual(t, map[string]uint64{"1": 1, "2": 2}, facets.Gateway)
+
+	// a template of two applications is counted once
+	facets, err = tr.ListTemplateFacetsRepo(gctx, domain.TemplateFilter{SenderID: "FACETS", ApplicationID: "3"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]uint64{"1": 2}, facets.Status)
+	assert.DeepEqual(t, map[string]uint64{"1": 1, "2": 1}, facets.Gateway)
+
+	// the facets count all the templates matching the filter, whatever the page
+	_, total, err := tr.ListTemplatesRepo(gctx, &domain.Meta{Skip: 0, Limit: 1}, domain.TemplateFilter{})
+	assert.NilError(t, err)
+	facets, err = tr.ListTemplateFacetsRepo(gctx, domain.TemplateFilter{})
+	assert.NilError(t, err)
+	var statusTotal, gatewayTotal uint64
+	for _, count := range facets.Status {
+		statusTotal += count
+	}
+	for _, count := range facets.Gateway {
+		gatewayTotal += count
+	}
+	assert.Equal(t, total, statusTotal)
+	assert.Equal(t, total, gatewayTotal)
+}
+
+func TestListTemplatesHandlerFacets(t *testing.T) {
+	seedFacetTemplates(t)
+	var rsp struct {
+		Data   []json.RawMessage `json:"data"`
+		Facets *struct {
+			Status  map[string]uint64 `json:"status"`
+			Gateway map[string]uint64 `json:"gateway"`
+		} `json:"facets"`
+	}
+
+	req := httptest.NewRequest("GET", "/v1/sms-templates?skip=0&limit=1&sender_id=FACETS&status=1", nil)
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, 1, len(rsp.Data))
+	assert.Assert(t, rsp.Facets != nil)
+	assert.DeepEqual(t, map[string]uint64{"1": 2}, rsp.Facets.Status)
+
+	rsp.Facets = nil
+	req = httptest.NewRequest("GET", "/v1/sms-templates?skip=0&limit=1&sender_id=FACETS&facets=false", nil)
+	rec = httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Assert(t, rsp.Facets == nil)
+}
+
+func TestListTemplatesHandlerInvalidOrder(t *testing.T) {
+	for _, query := range []string{"orderBy=entity_id", "orderBy=template_name&sortType=sideways"} {
+		req := httptest.NewRequest("GET", "/v1/sms-templates?skip=0&limit=10&"+query, nil)
+		rec := httptest.NewRecorder()
+		Router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, query)
+	}
+}
+
 // FetchTemplateHandler
 func TestFetchTemplateHandlerSuccess(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-templates/312", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -134,7 +309,7 @@ func TestFetchTemplateHandlerBindingError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-templates/312", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -143,9 +318,113 @@ func TestFetchTemplateHandlerValidationError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-templates/312", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+// FetchTemplateByTemplateIDHandler
+func TestFetchTemplateByTemplateIDHandlerSuccess(t *testing.T) {
+	req := httptest.NewRequest("GET", "/v1/sms-templates/by-template-id/1007889888935046401", nil)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var rsp struct {
+		Data []struct {
+			TemplateLocalID json.Number `json:"template_local_id"`
+			TemplateID      string      `json:"template_id"`
+		} `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, 1, len(rsp.Data))
+	assert.Equal(t, "1007889888935046401", rsp.Data[0].TemplateID)
+	assert.Equal(t, json.Number("212"), rsp.Data[0].TemplateLocalID)
+}
+
+func TestFetchTemplateByTemplateIDHandlerNotFound(t *testing.T) {
+	req := httptest.NewRequest("GET", "/v1/sms-templates/by-template-id/1007000000000000000", nil)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestFetchTemplateByTemplateIDHandlerValidationError(t *testing.T) {
+	req := httptest.NewRequest("GET", "/v1/sms-templates/by-template-id/10078abc", nil)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+// PreviewTemplateHandler
+type previewTemplateResponse struct {
+	Data struct {
+		RenderedMessage  string `json:"rendered_message"`
+		BrandingAppended bool   `json:"branding_appended"`
+		Encoding         string `json:"encoding"`
+		Length           int    `json:"length"`
+		SegmentCount     int    `json:"segment_count"`
+	} `json:"data"`
+}
+
+func previewTemplate(t *testing.T, templateLocalID string, input string) (*httptest.ResponseRecorder, previewTemplateResponse) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/v1/sms-templates/"+templateLocalID+"/preview", bytes.NewBufferString(input))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+
+	var rsp previewTemplateResponse
+	if rec.Code == http.StatusOK {
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	}
+	return rec, rsp
+}
+
+func TestPreviewTemplateHandlerSuccess(t *testing.T) {
+	rec, rsp := previewTemplate(t, "212", `{"values":["eParcel","123456"]}`)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "Your OTP code for login into eParcel is 123456 - INDPOST", rsp.Data.RenderedMessage)
+	assert.Equal(t, "PM", rsp.Data.Encoding)
+	assert.Equal(t, 56, rsp.Data.Length)
+	assert.Equal(t, 1, rsp.Data.SegmentCount)
+	assert.Assert(t, !rsp.Data.BrandingAppended)
+}
+
+func TestPreviewTemplateHandlerUnicode(t *testing.T) {
+	rec, rsp := previewTemplate(t, "212", `{"values":["ई-पार्सल","123456"]}`)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "UC", rsp.Data.Encoding)
+	assert.Equal(t, 1, rsp.Data.SegmentCount)
+}
+
+func TestPreviewTemplateHandlerMultipart(t *testing.T) {
+	longValue := strings.Repeat("a", 150)
+	rec, rsp := previewTemplate(t, "212", `{"values":["`+longValue+`","123456"]}`)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "PM", rsp.Data.Encoding)
+	assert.Equal(t, 2, rsp.Data.SegmentCount)
+}
+
+func TestPreviewTemplateHandlerVariableMismatch(t *testing.T) {
+	rec, _ := previewTemplate(t, "212", `{"values":["eParcel"]}`)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Assert(t, strings.Contains(rec.Body.String(), "template expects 2 values, but received 1"), rec.Body.String())
+}
+
+func TestPreviewTemplateHandlerNotFound(t *testing.T) {
+	rec, _ := previewTemplate(t, "999999", `{"values":[]}`)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
 }
 
 // FetchTemplateByApplicationHandler
@@ -153,16 +432,27 @@ func TestFetchTemplateByApplicationHandlerSuccess(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-templates/name?application-id=10", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
+	// every template comes with its ids, gateway and message type
+	var rsp struct {
+		Data []map[string]any `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	for _, template := range rsp.Data {
+		for _, field := range []string{"template_local_id", "template_name", "template_id", "gateway", "message_type"} {
+			_, ok := template[field]
+			assert.Assert(t, ok, "template without %s: %v", field, template)
+		}
+	}
 }
 
 func TestFetchTemplateByApplicationHandlerBindingError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-templates/name?applicationid=10", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -171,7 +461,7 @@ func TestFetchTemplateByApplicationHandlerValidationError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-templates/name?application-id=abc", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -180,7 +470,7 @@ func TestFetchTemplateByApplicationHandlerInvalidParam(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-templates/name?application-id=10", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -190,7 +480,7 @@ func TestFetchTemplateDetailsHandlerSuccess(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-templates/details?application-id=10", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -199,7 +489,7 @@ func TestFetchTemplateDetailsHandlerBindingError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-templates/details?applicationid=10", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -208,7 +498,7 @@ func TestFetchTemplateDetailsHandlerValidationError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-templates/details?application-id=10a", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -217,7 +507,7 @@ func TestFetchTemplateDetailsHandlerInvalidParam(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-templates/details?application-id=10", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -226,7 +516,7 @@ func TestFetchTemplateDetailsHandlerByTemplateLocalIdSuccess(t *testing.T) {
 	req := httptest.NewRequest("GET", "/v1/sms-templates/details?template-local-id=10", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -235,7 +525,7 @@ func TestFetchTemplateDetailsHandlerByTemplateLocalIdInvalidParam(t *testing.T)
 	req := httptest.NewRequest("GET", "/v1/sms-templates/details?template-local-id=10", nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -247,7 +537,7 @@ func TestFetchTemplateDetailsHandlerByTemplateFormatSuccess(t *testing.T) {
 		nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -258,7 +548,7 @@ func TestFetchTemplateDetailsHandlerByTemplateFormatInvalidParam(t *testing.T) {
 		nil)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -268,7 +558,7 @@ func TestToggleTemplateStatusHandlerSuccess(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/sms-templates/312/status", bytes.NewBuffer([]byte(`{"status":true}`)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -277,7 +567,7 @@ func TestToggleTemplateStatusHandlerBindingError(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/sms-templates//status", bytes.NewBuffer([]byte(`{"status":true}`)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -286,7 +576,7 @@ func TestToggleTemplateStatusHandlerValidationError(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/sms-templates/312abc/status", bytes.NewBuffer([]byte(`{"status":true}`)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
 }
@@ -295,7 +585,7 @@ func TestToggleTemplateStatusHandlerInvalidParam(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/sms-templates/312/status", bytes.NewBuffer([]byte(`{"status":true}`)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -308,7 +598,7 @@ func TestUpdateTemplateHandlerSuccess(t *testing.T) {
 	"template_name":"Test Template safron",
 	"template_format":"Your OTP is {#val} for {#val} for",
 	"sender_id":"INPOST",
-	"entity_id":"16507160377410448739",
+	"entity_id":"1001081725895192800",
 	"template_id":"165071603777774104478739",
 	"message_type":"PM",
 	"gateway":"1",
@@ -317,7 +607,7 @@ func TestUpdateTemplateHandlerSuccess(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/sms-templates/312", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
@@ -329,7 +619,7 @@ func TestUpdateTemplateHandlerBindingError(t *testing.T) {
 		"template_name":"Test Template 1233666",
 		"template_format":"Your OTP is {#val} for {#val} for",
 		"sender_id":"INPOST",
-		"entityid":"16507160377410448739",
+		"entityid":"1001081725895192800",
 		"template_id":"165071603777774104478739",
 		"message_type":"PM",
 		"gateway":"1",
@@ -338,7 +628,7 @@ func TestUpdateTemplateHandlerBindingError(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/sms-templates/312", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
@@ -350,7 +640,7 @@ func TestUpdateTemplateHandlerValidationError(t *testing.T) {
 		"template_name":"Test Template 1233666",
 		"template_format":"Your OTP is {#val} for {#val} for",
 		"sender_id":"INPOST",
-		"entity_id":"16507160377410448739",
+		"entity_id":"1001081725895192800",
 		"template_id":"165071603777774104478739",
 		"message_type":"PM",
 		"gateway":"1",
@@ -359,7 +649,49 @@ func TestUpdateTemplateHandlerValidationError(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/v1/sms-templates/312", bytes.NewBuffer([]byte(input)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-	Router.Engine.ServeHTTP(rec, req)
+	Router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
-}
\ No newline at end of file
+}
+
+func TestCreateTemplateHandlerTemplateIDUnderTwoGateways(t *testing.T) {
+	t.Cleanup(func() {
+		_, _ = MgAppRepo.Db.Exec(context.Background(), `DELETE FROM msg_template WHERE template_id = '1007000000000000001468'`)
+	})
+	create := func(gateway string) *httptest.ResponseRecorder {
+		input := `{
+	"application_id":"104",
+	"template_name":"Gateway bound template",
+	"template_format":"Your OTP is {#var#}",
+	"sender_id":"INPOST",
+	"entity_id":"1001081725895192800",
+	"template_id":"1007000000000000001468",
+	"message_type":"PM",
+	"gateway":"` + gateway + `",
+	"status":true
+	}`
+		req := httptest.NewRequest("POST", "/v1/sms-templates", strings.NewReader(input))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		Router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := create("1")
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = create("2")
+	assert.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+	assert.Assert(t, strings.Contains(rec.Body.String(), "template_id 1007000000000000001468 is already registered under gateway 1"), rec.Body.String())
+
+	var gateways []string
+	rows, err := MgAppRepo.Db.Query(context.Background(), `SELECT gateway FROM msg_template WHERE template_id = '1007000000000000001468'`)
+	assert.NilError(t, err)
+	for rows.Next() {
+		var gateway string
+		assert.NilError(t, rows.Scan(&gateway))
+		gateways = append(gateways, gateway)
+	}
+	assert.NilError(t, rows.Err())
+	assert.DeepEqual(t, []string{"1"}, gateways)
+}
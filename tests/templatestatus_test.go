@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"MgApplication/core/domain"
+	repo "MgApplication/repo/postgres"
+
+	"gotest.tools/v3/assert"
+)
+
+// seedBulkStatusTemplates inserts count active templates of sender BULKST, returning their local ids
+func seedBulkStatusTemplates(t *testing.T, count int) []uint64 {
+	t.Helper()
+	t.Cleanup(func() {
+		_, _ = MgAppRepo.Db.Exec(context.Background(), `
+			DELETE FROM msg_template_history WHERE template_local_id IN (SELECT template_local_id FROM msg_template WHERE sender_id = 'BULKST');
+			DELETE FROM msg_template WHERE sender_id = 'BULKST'`)
+	})
+	rows, err := MgAppRepo.Db.Query(context.Background(), `
+		INSERT INTO msg_template (application_id, template_name, template_format, sender_id, entity_id, template_id, gateway, message_type, status_cd)
+		SELECT '3', 'Bulk status ' || n, 'Your OTP is {#var#}', 'BULKST', '1001081725895192800', '10070000000000147' || lpad(n::text, 4, '0'), '1', 'PM', 1
+		FROM generate_series(1, $1) AS n
+		RETURNING template_local_id`, count)
+	assert.NilError(t, err)
+	var ids []uint64
+	for rows.Next() {
+		var id uint64
+		assert.NilError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	assert.NilError(t, rows.Err())
+	return ids
+}
+
+func templateHistoryCount(t *testing.T, templateLocalID uint64, action string) int {
+	t.Helper()
+	var count int
+	err := MgAppRepo.Db.QueryRow(context.Background(),
+		`SELECT COUNT(1) FROM msg_template_history WHERE template_local_id = $1 AND action = $2`, templateLocalID, action).Scan(&count)
+	assert.NilError(t, err)
+	return count
+}
+
+func TestBulkTemplateStatusRepoListed(t *testing.T) {
+	ids := seedBulkStatusTemplates(t, 3)
+	tr := repo.NewTemplateRepository(MgAppRepo.Db, MgAppRepo.Cfg)
+	gctx := listTemplatesContext()
+
+	// the first template is inactive before the update
+	_, err := tr.BulkTemplateStatusRepo(gctx, &domain.BulkTemplateStatus{TemplateLocalIDs: ids[:1], Status: 0})
+	assert.NilError(t, err)
+
+	missing := ids[2] + 1000000
+	result, err := tr.BulkTemplateStatusRepo(gctx, &domain.BulkTemplateStatus{
+		TemplateLocalIDs: []uint64{ids[0], ids[1], missing},
+		Status:           0,
+		CorrelationID:    "bulk-status-test",
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, int64(1), result.Updated)
+	assert.DeepEqual(t, []domain.TemplateStatusResult{
+		{TemplateLocalID: ids[0], Result: domain.TemplateStatusUnchanged},
+		{TemplateLocalID: ids[1], Result: domain.TemplateStatusUpdated},
+		{TemplateLocalID: missing, Result: domain.TemplateStatusNotFound},
+	}, result.Results)
+
+	// the unlisted template is left alone, the changes are in the history
+	var statuses []int
+	rows, err := MgAppRepo.Db.Query(context.Background(), `SELECT status_cd FROM msg_template WHERE sender_id = 'BULKST' ORDER BY template_local_id`)
+	assert.NilError(t, err)
+	for rows.Next() {
+		var status int
+		assert.NilError(t, rows.Scan(&status))
+		statuses = append(statuses, status)
+	}
+	assert.NilError(t, rows.Err())
+	assert.DeepEqual(t, []int{0, 0, 1}, statuses)
+	assert.Equal(t, 1, templateHistoryCount(t, ids[0], domain.TemplateActionBulkStatus))
+	assert.Equal(t, 1, templateHistoryCount(t, ids[1], domain.TemplateActionBulkStatus))
+	assert.Equal(t, 0, templateHistoryCount(t, ids[2], domain.TemplateActionBulkStatus))
+}
+
+func TestBulkTemplateStatusRepoFilterConfirm(t *testing.T) {
+	ids := seedBulkStatusTemplates(t, 51)
+	tr := repo.NewTemplateRepository(MgAppRepo.Db, MgAppRepo.Cfg)
+	gctx := listTemplatesContext()
+	bulk := domain.BulkTemplateStatus{Filter: domain.TemplateFilter{SenderID: "BULKST"}, Status: 0, ConfirmAbove: 50}
+
+	_, err := tr.BulkTemplateStatusRepo(gctx, &bulk)
+	var confirm *repo.BulkStatusConfirmationError
+	assert.Assert(t, errors.As(err, &confirm), "expected a confirmation error, got %v", err)
+	assert.Equal(t, int64(51), confirm.Matched)
+	assert.Equal(t, 0, templateHistoryCount(t, ids[0], domain.TemplateActionBulkStatus))
+
+	bulk.Confirm = true
+	result, err := tr.BulkTemplateStatusRepo(gctx, &bulk)
+	assert.NilError(t, err)
+	assert.Equal(t, int64(51), result.Updated)
+	assert.Equal(t, 0, len(result.Results))
+
+	// re-enabling the templates of a filter counts the templates to change, not the ones matched
+	_, err = tr.BulkTemplateStatusRepo(gctx, &domain.BulkTemplateStatus{TemplateLocalIDs: ids[:50], Status: 1})
+	assert.NilError(t, err)
+	result, err = tr.BulkTemplateStatusRepo(gctx, &domain.BulkTemplateStatus{Filter: domain.TemplateFilter{SenderID: "BULKST"}, Status: 1, ConfirmAbove: 50})
+	assert.NilError(t, err)
+	assert.Equal(t, int64(1), result.Updated)
+}
+
+func TestBulkTemplateStatusHandler(t *testing.T) {
+	ids := seedBulkStatusTemplates(t, 2)
+
+	input, _ := json.Marshal(map[string]any{"template_local_ids": ids, "status": 0})
+	req := httptest.NewRequest("PUT", "/v1/sms-templates/bulk-status", bytes.NewReader(input))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var rsp struct {
+		Data struct {
+			Updated int64 `json:"updated"`
+			Results []struct {
+				Result string `json:"result"`
+			} `json:"results"`
+		} `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	assert.Equal(t, int64(2), rsp.Data.Updated)
+	assert.Equal(t, 2, len(rsp.Data.Results))
+	assert.Equal(t, domain.TemplateStatusUpdated, rsp.Data.Results[0].Result)
+
+	// a filter and a list are not combined
+	req = httptest.NewRequest("PUT", "/v1/sms-templates/bulk-status", strings.NewReader(`{"template_local_ids": [1], "sender_id": "BULKST", "status": 0}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	Router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+}
@@ -4,16 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"path/filepath"
 	"runtime"
 	"testing"
 	"time"
 
 	"MgApplication/bootstrap"
-	"MgApplication/routes"
+	repo "MgApplication/repo/postgres"
 
 	router "MgApplication/api-server"
 
+	auth "MgApplication/api-authz"
 	config "MgApplication/api-config"
 	db "MgApplication/api-db"
 	log "MgApplication/api-log"
@@ -22,13 +24,21 @@ import (
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
+	otelsdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.uber.org/fx"
 	"go.uber.org/fx/fxtest"
 )
 
 var Router *router.Router
+var OTPRepo *repo.OTPRepository
+var DNDRepo *repo.DNDRepository
+var MgAppRepo *repo.MgApplicationRepository
+var ReportsRepo *repo.ReportsRepository
+var GatewayCodeRepo *repo.GatewayCodeRepository
+var PrivacyRepo *repo.PrivacyRepository
 
 var Fxconfig = fx.Module(
 	"configmodule",
@@ -44,7 +54,7 @@ type FxConfigParam struct {
 }
 
 func newFxConfig(p FxConfigParam) (*config.Config, error) {
-	return p.Factory.Create(
+	c, err := p.Factory.Create(
 		config.WithFileName("config"),
 		//config.WithAppEnv(os.Getenv("APP_ENV")),
 		config.WithFilePaths(
@@ -53,8 +63,34 @@ func newFxConfig(p FxConfigParam) (*config.Config, error) {
 			//os.Getenv("APP_CONFIG_PATH"),
 		),
 	)
+	if err != nil {
+		return nil, err
+	}
+	c.Set("server.auth.jwtsecret", testJWTSecret)
+	return c, nil
+}
+
+// testJWTSecret signs the bearer tokens of the test requests
+const testJWTSecret = "test-jwt-secret"
+
+// setBearerToken authorizes req as userID with the given scopes, with a token signed as the API gateway does
+func setBearerToken(req *http.Request, userID string, scope string) {
+	token := auth.SignToken(auth.Claims{Subject: userID, Scope: scope, ExpiresAt: time.Now().Add(time.Hour).Unix()}, []byte(testJWTSecret))
+	req.Header.Set("Authorization", "Bearer "+token)
 }
 
+// FxRouter builds the router from the handlers of bootstrap.FxHandler, without starting the server
+var FxRouter = fx.Module(
+	"routermodule",
+	fx.Provide(
+		func() context.Context { return context.Background() },
+		func() *otelsdktrace.TracerProvider { return otelsdktrace.NewTracerProvider() },
+		prometheus.NewRegistry,
+		router.ParseGroupedControllers,
+		router.Defaultgin,
+	),
+)
+
 var FxDB = fx.Module(
 	"DBModule",
 	fx.Provide(
@@ -196,13 +232,20 @@ func BootstrapTestApp(tb testing.TB, options ...fx.Option) *router.Router {
 		// bootstrapper.Fxlog,
 		FxDB,
 		fx.Populate(&Router),
+		fx.Populate(&OTPRepo),
+		fx.Populate(&DNDRepo),
+		fx.Populate(&MgAppRepo),
+		fx.Populate(&ReportsRepo),
+		fx.Populate(&GatewayCodeRepo),
+		fx.Populate(&PrivacyRepo),
 		//bootstrap.Fxclient,
 		bootstrap.Fxvalidator,
 		// bootstrap.FxMinio,
 		// bootstrapper.Fxrouter,
 		bootstrap.FxHandler,
 		bootstrap.FxRepo,
-		fx.Invoke(routes.Routes),
+		bootstrap.FxJobs,
+		FxRouter,
 	)
 	App.RequireStart()
 
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	config "MgApplication/api-config"
+	"MgApplication/handler"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+// A messy message is sent and stored cleaned up, with the transformers that changed it
+func TestCreateSMSRequestStoresTransformations(t *testing.T) {
+	var content string
+	cdac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		content = r.PostForm.Get("content")
+		_, _ = w.Write([]byte("402,MsgID = 150920241726381202116hpgovsms"))
+	}))
+	defer cdac.Close()
+
+	c := config.NewConfig(viper.New())
+	c.Set("sms.msgstorerequest", 1)
+	c.Set("sms.cdac.url", cdac.URL)
+	c.Set("sms.cdac.username", "appostsms")
+	c.Set("sms.cdac.password", "cdacsecret")
+	c.Set("sms.cdac.securekey", "c7d427c9-63e7-4eec-a227-3ef840a75269")
+	c.Set("sms.transformers", []string{"zero_width", "emoji_strip", "smart_quotes", "whitespace"})
+
+	engine := gin.New()
+	engine.POST("/v1/sms-request", handler.NewMgApplicationHandler(MgAppRepo, handler.NewDNDFilter(DNDRepo, c), c).CreateSMSRequestHandler)
+
+	body, _ := json.Marshal(map[string]any{
+		"application_id": "7",
+		"facility_id":    "facility1",
+		"priority":       1,
+		"message_text":   "Dear  Customer,\u200b OTP for booking is “1234” 🔐\r\nPlease don’t share it with anyone - INDPOST \n",
+		"sender_id":      "INPOST",
+		"mobile_numbers": "9000000001",
+		"entity_id":      "1001081725895192800",
+		"template_id":    "1007344609998507114",
+	})
+	req := httptest.NewRequest("POST", "/v1/sms-request", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	const want = "Dear Customer, OTP for booking is \"1234\"\nPlease don't share it with anyone - INDPOST"
+	assert.Equal(t, content, want)
+
+	var rsp struct {
+		Data struct {
+			CommunicationID string `json:"communication_id"`
+		} `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+
+	var messageText string
+	var transformations []string
+	err := MgAppRepo.Db.QueryRow(context.Background(),
+		`SELECT message_text, transformations FROM msg_request WHERE communication_id = $1`, strings.TrimSpace(rsp.Data.CommunicationID)).
+		Scan(&messageText, &transformations)
+	assert.NilError(t, err)
+	assert.Equal(t, messageText, want)
+	assert.DeepEqual(t, transformations, []string{"zero_width", "emoji_strip", "smart_quotes", "whitespace"})
+}
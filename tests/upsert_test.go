@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	dblib "MgApplication/api-db"
+
+	"gotest.tools/v3/assert"
+)
+
+type upsertGatewayCode struct {
+	Gateway           string  `db:"gateway"`
+	Code              string  `db:"code"`
+	Description       string  `db:"description"`
+	Severity          string  `db:"severity"`
+	RecommendedAction *string `db:"recommended_action"`
+}
+
+func TestUpsertInsertsThenUpdates(t *testing.T) {
+	ctx := context.Background()
+	action := "Retry later"
+	code := upsertGatewayCode{Gateway: "1", Code: "UPSERT-1", Description: "Upsert test", Severity: "warning", RecommendedAction: &action}
+
+	inserted, err := dblib.Upsert(ctx, MgAppRepo.Db, "msg_gateway_code", code, "gateway", "code")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, code, inserted)
+
+	code.Description = "Upsert test, updated"
+	code.Severity = "error"
+	code.RecommendedAction = nil
+	updated, err := dblib.Upsert(ctx, MgAppRepo.Db, "msg_gateway_code", code, "gateway", "code")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, code, updated)
+
+	var rows int
+	var description string
+	err = MgAppRepo.Db.QueryRow(ctx,
+		`SELECT count(*), max(description) FROM msg_gateway_code WHERE gateway = '1' AND code = 'UPSERT-1'`).Scan(&rows, &description)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, rows)
+	assert.Equal(t, "Upsert test, updated", description)
+
+	_, err = MgAppRepo.Db.Exec(ctx, `DELETE FROM msg_gateway_code WHERE gateway = '1' AND code = 'UPSERT-1'`)
+	assert.NilError(t, err)
+}